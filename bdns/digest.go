@@ -0,0 +1,79 @@
+package bdns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// AnswerDigest returns the hex-encoded SHA-256 digest of the canonical form of
+// the given resource record set. Each record is rendered in presentation format
+// with its owner name lowercased and its TTL zeroed, since the TTL counts down
+// between queries. The rendered records are sorted and joined with newlines
+// before hashing, so the digest depends only on the records themselves and not
+// on the order in which a resolver returned them. This allows an auditor who
+// holds a copy of the records to confirm that they are the ones we acted upon.
+func AnswerDigest(rrs []dns.RR) string {
+	lines := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		rr = dns.Copy(rr)
+		hdr := rr.Header()
+		hdr.Name = dns.Fqdn(strings.ToLower(hdr.Name))
+		hdr.Ttl = 0
+		lines = append(lines, rr.String())
+	}
+	slices.Sort(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// TXTAnswerDigest returns the AnswerDigest of the TXT records at the given
+// owner name. It accepts the record values as returned by Client.LookupTXT,
+// where the character-strings of each record have been joined, so each value
+// is treated as a record containing a single string.
+func TXTAnswerDigest(name string, txts []string) string {
+	rrs := make([]dns.RR, 0, len(txts))
+	for _, txt := range txts {
+		rrs = append(rrs, &dns.TXT{
+			Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+			Txt: []string{txt},
+		})
+	}
+	return AnswerDigest(rrs)
+}
+
+// HostAnswerDigest returns the AnswerDigest of the A and AAAA records for the
+// given addresses at the given owner name. It accepts the addresses as returned
+// by Client.LookupHost, so it only reproduces the digest of a HostLookup whose
+// answers contained no aliases and no unusable addresses.
+func HostAnswerDigest(name string, addrs []net.IP) string {
+	rrs := make([]dns.RR, 0, len(addrs))
+	for _, addr := range addrs {
+		if v4 := addr.To4(); v4 != nil {
+			rrs = append(rrs, &dns.A{
+				Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET},
+				A:   v4,
+			})
+		} else {
+			rrs = append(rrs, &dns.AAAA{
+				Hdr:  dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET},
+				AAAA: addr,
+			})
+		}
+	}
+	return AnswerDigest(rrs)
+}
+
+// CAAAnswerDigest returns the AnswerDigest of the given CAA records, as
+// returned by Client.LookupCAA.
+func CAAAnswerDigest(caas []*dns.CAA) string {
+	rrs := make([]dns.RR, 0, len(caas))
+	for _, caa := range caas {
+		rrs = append(rrs, caa)
+	}
+	return AnswerDigest(rrs)
+}
@@ -0,0 +1,71 @@
+package bdns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestAnswerDigestIsStable(t *testing.T) {
+	t.Parallel()
+
+	caa := func(name string, ttl uint32, tag, value string) *dns.CAA {
+		return &dns.CAA{
+			Hdr:   dns.RR_Header{Name: name, Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: ttl},
+			Tag:   tag,
+			Value: value,
+		}
+	}
+
+	digest := CAAAnswerDigest([]*dns.CAA{
+		caa("example.com.", 300, "issue", "letsencrypt.org"),
+		caa("example.com.", 300, "issuewild", ";"),
+		caa("example.com.", 300, "iodef", "mailto:security@example.com"),
+	})
+	test.AssertEquals(t, len(digest), 64)
+
+	// Reordering the records, changing the case of the owner name, omitting the
+	// trailing dot and a lower TTL must not change the digest.
+	reordered := CAAAnswerDigest([]*dns.CAA{
+		caa("EXAMPLE.com", 12, "iodef", "mailto:security@example.com"),
+		caa("example.COM.", 299, "issue", "letsencrypt.org"),
+		caa("Example.Com.", 5, "issuewild", ";"),
+	})
+	test.AssertEquals(t, reordered, digest)
+
+	// But changing any record must.
+	changed := CAAAnswerDigest([]*dns.CAA{
+		caa("example.com.", 300, "issue", "example.net"),
+		caa("example.com.", 300, "issuewild", ";"),
+		caa("example.com.", 300, "iodef", "mailto:security@example.com"),
+	})
+	test.AssertNotEquals(t, changed, digest)
+
+	// Canonicalizing must not modify the records which were passed in.
+	original := caa("EXAMPLE.com.", 300, "issue", "letsencrypt.org")
+	CAAAnswerDigest([]*dns.CAA{original})
+	test.AssertEquals(t, original.Hdr.Name, "EXAMPLE.com.")
+	test.AssertEquals(t, original.Hdr.Ttl, uint32(300))
+
+	// TXT record values are digested along with their owner name.
+	txt := TXTAnswerDigest("_acme-challenge.example.com", []string{"a", "b"})
+	test.AssertEquals(t, TXTAnswerDigest("_ACME-CHALLENGE.example.com.", []string{"b", "a"}), txt)
+	test.AssertNotEquals(t, TXTAnswerDigest("_acme-challenge.example.net", []string{"a", "b"}), txt)
+	test.AssertNotEquals(t, TXTAnswerDigest("_acme-challenge.example.com", []string{"ab"}), txt)
+
+	// Address records are digested along with their owner name, whichever
+	// family they belong to.
+	v4, v6 := net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")
+	host := HostAnswerDigest("example.com", []net.IP{v4, v6})
+	test.AssertEquals(t, HostAnswerDigest("EXAMPLE.com.", []net.IP{v6, v4}), host)
+	test.AssertNotEquals(t, HostAnswerDigest("example.com", []net.IP{v4}), host)
+	test.AssertEquals(t, HostAnswerDigest("example.com", []net.IP{v4.To4(), v6}), host)
+
+	// An empty answer set has a digest too, which proves that nothing was
+	// found.
+	test.AssertEquals(t, TXTAnswerDigest("_acme-challenge.example.com", nil),
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+}
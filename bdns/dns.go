@@ -151,32 +151,87 @@ var (
 	}
 )
 
-// ResolverAddrs contains DNS resolver(s) that were chosen to perform a
-// validation request or CAA recheck. A ResolverAddr will be in the form of
-// host:port, A:host:port, or AAAA:host:port depending on which type of lookup
-// was done.
-type ResolverAddrs []string
+// ResolverAddr describes a single DNS query made while performing a lookup.
+type ResolverAddr struct {
+	// Addr is the host:port of the resolver which answered the query, or of
+	// the last resolver tried if none did.
+	Addr string
+
+	// Qtype is the type of the query, e.g. "A" or "CAA".
+	Qtype string
+
+	// RTT is the round trip time of the query to Addr, or zero if no
+	// response was received.
+	RTT time.Duration
+
+	// TCPFallback is true if the query was retried over TCP, because the UDP
+	// query timed out or its response was truncated. RTT is then the round
+	// trip time of the TCP query.
+	TCPFallback bool
+}
+
+// String returns the query in the form "host:port (qtype, rtt)", or
+// "host:port (qtype, rtt, TCP fallback)" if the query was retried over TCP.
+func (r ResolverAddr) String() string {
+	if r.TCPFallback {
+		return fmt.Sprintf("%s (%s, %s, TCP fallback)", r.Addr, r.Qtype, r.RTT.Round(time.Microsecond))
+	}
+	return fmt.Sprintf("%s (%s, %s)", r.Addr, r.Qtype, r.RTT.Round(time.Microsecond))
+}
+
+// ResolverAddrs contains the DNS queries that were made to perform a
+// validation request or CAA recheck, in the order they were made.
+type ResolverAddrs []ResolverAddr
+
+// Strings returns the resolver of each query, for inclusion in a
+// core.ValidationRecord's ResolverAddrs: "A:host:port" or "AAAA:host:port" for
+// address lookups, and "host:port" otherwise.
+func (r ResolverAddrs) Strings() []string {
+	if len(r) == 0 {
+		return nil
+	}
+	strs := make([]string, 0, len(r))
+	for _, addr := range r {
+		switch addr.Qtype {
+		case "A", "AAAA":
+			strs = append(strs, addr.Qtype+":"+addr.Addr)
+		default:
+			strs = append(strs, addr.Addr)
+		}
+	}
+	return strs
+}
 
 // Client queries for DNS records
 type Client interface {
-	LookupTXT(context.Context, string) (txts []string, resolver ResolverAddrs, err error)
+	LookupTXT(context.Context, string) (txts []string, minTTL time.Duration, resolver ResolverAddrs, err error)
 	LookupHost(context.Context, string) ([]net.IP, ResolverAddrs, error)
+	LookupHostFamilies(context.Context, string) (*HostLookup, error)
 	LookupCAA(context.Context, string) ([]*dns.CAA, string, ResolverAddrs, error)
+	LookupMX(context.Context, string) ([]string, ResolverAddrs, error)
 }
 
 // impl represents a client that talks to an external resolver
 type impl struct {
-	dnsClient                exchanger
+	dnsClient exchanger
+	// tcpClient is used to retry queries whose UDP exchange timed out or was
+	// truncated. It is nil when queries are made using DoH.
+	tcpClient                exchanger
 	servers                  ServerProvider
 	allowRestrictedAddresses bool
 	maxTries                 int
 	clk                      clock.Clock
 	log                      blog.Logger
 
-	queryTime         *prometheus.HistogramVec
-	totalLookupTime   *prometheus.HistogramVec
-	timeoutCounter    *prometheus.CounterVec
-	idMismatchCounter *prometheus.CounterVec
+	// qtypeTimeout bounds each of the A and AAAA queries made by
+	// LookupHostFamilies, independently of one another.
+	qtypeTimeout time.Duration
+
+	queryTime          *prometheus.HistogramVec
+	totalLookupTime    *prometheus.HistogramVec
+	timeoutCounter     *prometheus.CounterVec
+	idMismatchCounter  *prometheus.CounterVec
+	tcpFallbackCounter *prometheus.CounterVec
 }
 
 var _ Client = &impl{}
@@ -188,6 +243,15 @@ type exchanger interface {
 // New constructs a new DNS resolver object that utilizes the
 // provided list of DNS servers for resolution.
 //
+// Unless DoH is enabled, queries are made over UDP and retried over TCP if the
+// UDP exchange times out or its response is truncated. Each of the two
+// exchanges is given half of `readTimeout`, so that a query which falls back
+// to TCP takes no longer than one which doesn't.
+//
+// `hostLookupTimeout` bounds each of the A and AAAA lookups made by
+// LookupHostFamilies, including retries. If it is zero, each lookup is given
+// `readTimeout` for each of its `maxTries` attempts.
+//
 // `tlsConfig` is the configuration used for outbound DoH queries,
 // if applicable.
 func New(
@@ -196,10 +260,11 @@ func New(
 	stats prometheus.Registerer,
 	clk clock.Clock,
 	maxTries int,
+	hostLookupTimeout time.Duration,
 	log blog.Logger,
 	tlsConfig *tls.Config,
 ) Client {
-	var client exchanger
+	var client, tcpClient exchanger
 	if features.Get().DOH {
 		// Clone the default transport because it comes with various settings
 		// that we like, which are different from the zero value of an
@@ -220,9 +285,16 @@ func New(
 	} else {
 		client = &dns.Client{
 			// Set timeout for underlying net.Conn
-			ReadTimeout: readTimeout,
+			ReadTimeout: readTimeout / 2,
 			Net:         "udp",
 		}
+		tcpClient = &dns.Client{
+			// Bound the dial, write and read of the fallback exchange
+			// together, since a server which doesn't answer over UDP may
+			// not accept TCP connections either.
+			Timeout: readTimeout / 2,
+			Net:     "tcp",
+		}
 	}
 
 	queryTime := prometheus.NewHistogramVec(
@@ -255,17 +327,31 @@ func New(
 		},
 		[]string{"qtype", "resolver"},
 	)
-	stats.MustRegister(queryTime, totalLookupTime, timeoutCounter, idMismatchCounter)
+	tcpFallbackCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_tcp_fallback",
+			Help: "Counter of DNS queries retried over TCP, sliced by query type, the reason for the fallback, and whether the TCP query succeeded",
+		},
+		[]string{"qtype", "reason", "result"},
+	)
+	stats.MustRegister(queryTime, totalLookupTime, timeoutCounter, idMismatchCounter, tcpFallbackCounter)
+
+	if hostLookupTimeout == 0 {
+		hostLookupTimeout = readTimeout * time.Duration(max(maxTries, 1))
+	}
 	return &impl{
 		dnsClient:                client,
+		tcpClient:                tcpClient,
 		servers:                  servers,
 		allowRestrictedAddresses: false,
 		maxTries:                 maxTries,
 		clk:                      clk,
+		qtypeTimeout:             hostLookupTimeout,
 		queryTime:                queryTime,
 		totalLookupTime:          totalLookupTime,
 		timeoutCounter:           timeoutCounter,
 		idMismatchCounter:        idMismatchCounter,
+		tcpFallbackCounter:       tcpFallbackCounter,
 		log:                      log,
 	}
 }
@@ -279,19 +365,20 @@ func NewTest(
 	stats prometheus.Registerer,
 	clk clock.Clock,
 	maxTries int,
+	hostLookupTimeout time.Duration,
 	log blog.Logger,
 	tlsConfig *tls.Config,
 ) Client {
-	resolver := New(readTimeout, servers, stats, clk, maxTries, log, tlsConfig)
+	resolver := New(readTimeout, servers, stats, clk, maxTries, hostLookupTimeout, log, tlsConfig)
 	resolver.(*impl).allowRestrictedAddresses = true
 	return resolver
 }
 
 // exchangeOne performs a single DNS exchange with a randomly chosen server
-// out of the server list, returning the response, time, and error (if any).
-// We assume that the upstream resolver requests and validates DNSSEC records
-// itself.
-func (dnsClient *impl) exchangeOne(ctx context.Context, hostname string, qtype uint16) (resp *dns.Msg, resolver string, err error) {
+// out of the server list, returning the response, the resolver which answered
+// it along with the round trip time, and error (if any). We assume that the
+// upstream resolver requests and validates DNSSEC records itself.
+func (dnsClient *impl) exchangeOne(ctx context.Context, hostname string, qtype uint16) (resp *dns.Msg, resolver ResolverAddr, err error) {
 	m := new(dns.Msg)
 	// Set question type
 	m.SetQuestion(dns.Fqdn(hostname), qtype)
@@ -307,13 +394,14 @@ func (dnsClient *impl) exchangeOne(ctx context.Context, hostname string, qtype u
 	// present.
 	m.SetEdns0(4096, false)
 
+	qtypeStr := dns.TypeToString[qtype]
 	servers, err := dnsClient.servers.Addrs()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to list DNS servers: %w", err)
+		return nil, ResolverAddr{}, fmt.Errorf("failed to list DNS servers: %w", err)
 	}
 	chosenServerIndex := 0
 	chosenServer := servers[chosenServerIndex]
-	resolver = chosenServer
+	resolver = ResolverAddr{Addr: chosenServer, Qtype: qtypeStr}
 
 	// Strip off the IP address part of the server address because
 	// we talk to the same server on multiple ports, and don't want
@@ -325,7 +413,6 @@ func (dnsClient *impl) exchangeOne(ctx context.Context, hostname string, qtype u
 
 	start := dnsClient.clk.Now()
 	client := dnsClient.dnsClient
-	qtypeStr := dns.TypeToString[qtype]
 	tries := 1
 	defer func() {
 		result := "failed"
@@ -372,7 +459,11 @@ func (dnsClient *impl) exchangeOne(ctx context.Context, hostname string, qtype u
 				"result":   result,
 				"resolver": chosenServerIP,
 			}).Observe(rtt.Seconds())
-			ch <- dnsResp{m: rsp, err: err}
+			reason := dnsClient.tcpFallbackReason(rsp, err)
+			if reason != "" {
+				rsp, rtt, err = dnsClient.exchangeTCP(m, chosenServer, hostname, reason, err)
+			}
+			ch <- dnsResp{m: rsp, rtt: rtt, err: err, tcpFallback: reason != ""}
 		}()
 		select {
 		case <-ctx.Done():
@@ -422,7 +513,7 @@ func (dnsClient *impl) exchangeOne(ctx context.Context, hostname string, qtype u
 					// list.
 					chosenServerIndex = (chosenServerIndex + 1) % len(servers)
 					chosenServer = servers[chosenServerIndex]
-					resolver = chosenServer
+					resolver = ResolverAddr{Addr: chosenServer, Qtype: qtypeStr}
 					continue
 				} else if isRetryable && !hasRetriesLeft {
 					dnsClient.timeoutCounter.With(prometheus.Labels{
@@ -433,7 +524,7 @@ func (dnsClient *impl) exchangeOne(ctx context.Context, hostname string, qtype u
 					}).Inc()
 				}
 			}
-			resp, err = r.m, r.err
+			resp, resolver.RTT, resolver.TCPFallback, err = r.m, r.rtt, r.tcpFallback, r.err
 			return
 		}
 	}
@@ -452,31 +543,109 @@ func isTLD(hostname string) string {
 }
 
 type dnsResp struct {
-	m   *dns.Msg
-	err error
+	m           *dns.Msg
+	rtt         time.Duration
+	err         error
+	tcpFallback bool
+}
+
+// tcpFallbackReason returns the reason a query which received the provided
+// UDP response and error should be retried over TCP, or the empty string if it
+// shouldn't be.
+func (dnsClient *impl) tcpFallbackReason(resp *dns.Msg, err error) string {
+	if dnsClient.tcpClient == nil {
+		return ""
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	if err == nil && resp != nil && resp.Truncated {
+		return "truncated"
+	}
+	return ""
 }
 
-// LookupTXT sends a DNS query to find all TXT records associated with
-// the provided hostname which it returns along with the returned
-// DNS authority section.
-func (dnsClient *impl) LookupTXT(ctx context.Context, hostname string) ([]string, ResolverAddrs, error) {
+// exchangeTCP retries the query m, whose UDP exchange with server failed for
+// the provided reason, over TCP. If the TCP exchange also fails after the UDP
+// exchange timed out, the returned error is a tcpFallbackError.
+func (dnsClient *impl) exchangeTCP(m *dns.Msg, server, hostname, reason string, udpErr error) (*dns.Msg, time.Duration, error) {
+	qtypeStr := dns.TypeToString[m.Question[0].Qtype]
+	resp, rtt, err := dnsClient.tcpClient.Exchange(m, server)
+	result := "success"
+	if err != nil {
+		result = "failed"
+		logDNSError(dnsClient.log, server, hostname, m, resp, err)
+		if reason == "timeout" {
+			err = tcpFallbackError{udpErr: udpErr, tcpErr: err}
+		}
+	}
+	dnsClient.tcpFallbackCounter.With(prometheus.Labels{
+		"qtype":  qtypeStr,
+		"reason": reason,
+		"result": result,
+	}).Inc()
+	return resp, rtt, err
+}
+
+// LookupTXT sends a DNS query to find all TXT records associated with the
+// provided hostname. It returns their values, along with the lowest TTL among
+// them, which is zero if there are none.
+func (dnsClient *impl) LookupTXT(ctx context.Context, hostname string) ([]string, time.Duration, ResolverAddrs, error) {
 	var txt []string
+	var minTTL uint32
 	dnsType := dns.TypeTXT
 	r, resolver, err := dnsClient.exchangeOne(ctx, hostname, dnsType)
-	errWrap := wrapErr(dnsType, hostname, r, err)
+	errWrap := wrapErr(dnsType, hostname, resolver.Addr, r, err)
 	if errWrap != nil {
-		return nil, ResolverAddrs{resolver}, errWrap
+		return nil, 0, ResolverAddrs{resolver}, errWrap
 	}
 
 	for _, answer := range r.Answer {
 		if answer.Header().Rrtype == dnsType {
 			if txtRec, ok := answer.(*dns.TXT); ok {
+				if len(txt) == 0 || txtRec.Hdr.Ttl < minTTL {
+					minTTL = txtRec.Hdr.Ttl
+				}
 				txt = append(txt, strings.Join(txtRec.Txt, ""))
 			}
 		}
 	}
 
-	return txt, ResolverAddrs{resolver}, err
+	return txt, time.Duration(minTTL) * time.Second, ResolverAddrs{resolver}, err
+}
+
+// LookupMX sends a DNS query to find all MX records associated with the
+// provided hostname and returns the hostnames of their mail exchangers. A
+// domain which has published a "null MX" record (RFC 7505) to indicate that it
+// does not accept mail returns a single mail exchanger of ".".
+func (dnsClient *impl) LookupMX(ctx context.Context, hostname string) ([]string, ResolverAddrs, error) {
+	var mxs []string
+	dnsType := dns.TypeMX
+	r, resolver, err := dnsClient.exchangeOne(ctx, hostname, dnsType)
+	errWrap := wrapErr(dnsType, hostname, resolver.Addr, r, err)
+	if errWrap != nil {
+		return nil, ResolverAddrs{resolver}, errWrap
+	}
+
+	for _, answer := range r.Answer {
+		if answer.Header().Rrtype == dnsType {
+			if mxRec, ok := answer.(*dns.MX); ok {
+				mxs = append(mxs, mxRec.Mx)
+			}
+		}
+	}
+
+	return mxs, ResolverAddrs{resolver}, nil
+}
+
+// IsReservedIP returns true if ip is in one of the private or reserved ranges
+// whose addresses LookupHost discards.
+func IsReservedIP(ip net.IP) bool {
+	if ip.To4() != nil {
+		return isPrivateV4(ip)
+	}
+	return isPrivateV6(ip)
 }
 
 func isPrivateV4(ip net.IP) bool {
@@ -497,82 +666,124 @@ func isPrivateV6(ip net.IP) bool {
 	return false
 }
 
-func (dnsClient *impl) lookupIP(ctx context.Context, hostname string, ipType uint16) ([]dns.RR, string, error) {
+func (dnsClient *impl) lookupIP(ctx context.Context, hostname string, ipType uint16) ([]dns.RR, ResolverAddr, error) {
 	resp, resolver, err := dnsClient.exchangeOne(ctx, hostname, ipType)
-	switch ipType {
-	case dns.TypeA:
-		if resolver != "" {
-			resolver = "A:" + resolver
-		}
-	case dns.TypeAAAA:
-		if resolver != "" {
-			resolver = "AAAA:" + resolver
-		}
-	}
-	errWrap := wrapErr(ipType, hostname, resp, err)
+	errWrap := wrapErr(ipType, hostname, resolver.Addr, resp, err)
 	if errWrap != nil {
 		return nil, resolver, errWrap
 	}
 	return resp.Answer, resolver, nil
 }
 
+// HostLookup is the result of resolving the A and AAAA records for a hostname.
+// The two queries are performed independently, so one may succeed while the
+// other fails.
+type HostLookup struct {
+	// IPv4 and IPv6 contain the usable addresses found by the A and AAAA
+	// queries respectively.
+	IPv4 []net.IP
+	IPv6 []net.IP
+
+	// ErrA and ErrAAAA contain the error encountered by the A and AAAA queries
+	// respectively, if any. A query which found no usable addresses also has
+	// an error.
+	ErrA    error
+	ErrAAAA error
+
+	// Resolvers describes the A and AAAA queries, in that order.
+	Resolvers ResolverAddrs
+
+	// Digest is the AnswerDigest of the answers to the A and AAAA queries,
+	// including any aliases the resolver followed and any addresses which
+	// were discarded as unusable. A query which failed contributes nothing.
+	Digest string
+}
+
+// Addrs returns all of the addresses found, IPv4 addresses first.
+func (l *HostLookup) Addrs() []net.IP {
+	return append(slices.Clone(l.IPv4), l.IPv6...)
+}
+
 // LookupHost sends a DNS query to find all A and AAAA records associated with
 // the provided hostname. This method assumes that the external resolver will
 // chase CNAME/DNAME aliases and return relevant records. It will retry
 // requests in the case of temporary network errors. It returns an error if
 // both the A and AAAA lookups fail or are empty, but succeeds otherwise.
 func (dnsClient *impl) LookupHost(ctx context.Context, hostname string) ([]net.IP, ResolverAddrs, error) {
+	lookup, err := dnsClient.LookupHostFamilies(ctx, hostname)
+	if err != nil {
+		return nil, lookup.Resolvers, err
+	}
+	return lookup.Addrs(), lookup.Resolvers, nil
+}
+
+// LookupHostFamilies is like LookupHost, but reports the results of the A and
+// AAAA queries separately. The queries are sent in parallel and each is
+// bounded by its own timeout, so a slow or failing authoritative server for one
+// address family neither delays nor fails the other. It returns an error only
+// if both queries fail or are empty, in which case the returned HostLookup
+// still describes each failure.
+func (dnsClient *impl) LookupHostFamilies(ctx context.Context, hostname string) (*HostLookup, error) {
 	var recordsA, recordsAAAA []dns.RR
 	var errA, errAAAA error
-	var resolverA, resolverAAAA string
+	var resolverA, resolverAAAA ResolverAddr
 	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		recordsA, resolverA, errA = dnsClient.lookupIP(ctx, hostname, dns.TypeA)
+		recordsA, resolverA, errA = dnsClient.lookupIPWithTimeout(ctx, hostname, dns.TypeA)
 	}()
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		recordsAAAA, resolverAAAA, errAAAA = dnsClient.lookupIP(ctx, hostname, dns.TypeAAAA)
+		recordsAAAA, resolverAAAA, errAAAA = dnsClient.lookupIPWithTimeout(ctx, hostname, dns.TypeAAAA)
 	}()
 	wg.Wait()
 
-	resolvers := ResolverAddrs{resolverA, resolverAAAA}
-	resolvers = slices.DeleteFunc(resolvers, func(a string) bool {
-		return a == ""
+	lookup := &HostLookup{}
+	lookup.Resolvers = slices.DeleteFunc(ResolverAddrs{resolverA, resolverAAAA}, func(a ResolverAddr) bool {
+		return a.Addr == ""
 	})
+	lookup.Digest = AnswerDigest(append(slices.Clone(recordsA), recordsAAAA...))
 
-	var addrsA []net.IP
 	if errA == nil {
+		var found bool
 		for _, answer := range recordsA {
 			if answer.Header().Rrtype == dns.TypeA {
+				found = true
 				a, ok := answer.(*dns.A)
 				if ok && a.A.To4() != nil && (!isPrivateV4(a.A) || dnsClient.allowRestrictedAddresses) {
-					addrsA = append(addrsA, a.A)
+					lookup.IPv4 = append(lookup.IPv4, a.A)
 				}
 			}
 		}
-		if len(addrsA) == 0 {
+		if !found {
+			errA = newNoDataError(dns.TypeA, hostname, resolverA.Addr)
+		} else if len(lookup.IPv4) == 0 {
 			errA = fmt.Errorf("no valid A records found for %s", hostname)
 		}
 	}
+	lookup.ErrA = errA
 
-	var addrsAAAA []net.IP
 	if errAAAA == nil {
+		var found bool
 		for _, answer := range recordsAAAA {
 			if answer.Header().Rrtype == dns.TypeAAAA {
+				found = true
 				aaaa, ok := answer.(*dns.AAAA)
 				if ok && aaaa.AAAA.To16() != nil && (!isPrivateV6(aaaa.AAAA) || dnsClient.allowRestrictedAddresses) {
-					addrsAAAA = append(addrsAAAA, aaaa.AAAA)
+					lookup.IPv6 = append(lookup.IPv6, aaaa.AAAA)
 				}
 			}
 		}
-		if len(addrsAAAA) == 0 {
+		if !found {
+			errAAAA = newNoDataError(dns.TypeAAAA, hostname, resolverAAAA.Addr)
+		} else if len(lookup.IPv6) == 0 {
 			errAAAA = fmt.Errorf("no valid AAAA records found for %s", hostname)
 		}
 	}
+	lookup.ErrAAAA = errAAAA
 
 	if errA != nil && errAAAA != nil {
 		// Construct a new error from both underlying errors. We can only use %w for
@@ -580,16 +791,72 @@ func (dnsClient *impl) LookupHost(ctx context.Context, hostname string) ([]net.I
 		// branching. We don't use ProblemDetails and SubProblemDetails here, because
 		// this error will get wrapped in a DNSError and further munged by higher
 		// layers in the stack.
-		return nil, resolvers, fmt.Errorf("%w; %s", errA, errAAAA)
+		return lookup, fmt.Errorf("%w; %s", errA, errAAAA)
 	}
 
-	return append(addrsA, addrsAAAA...), resolvers, nil
+	return lookup, nil
+}
+
+// lookupIPWithTimeout is like lookupIP, but bounds the query, including any
+// retries, by the client's qtypeTimeout rather than by ctx alone.
+func (dnsClient *impl) lookupIPWithTimeout(ctx context.Context, hostname string, ipType uint16) ([]dns.RR, ResolverAddr, error) {
+	if dnsClient.qtypeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dnsClient.qtypeTimeout)
+		defer cancel()
+	}
+	return dnsClient.lookupIP(ctx, hostname, ipType)
+}
+
+// maxAliasChainLength is the maximum number of CNAME and DNAME records which
+// canonicalName will follow through a single response.
+const maxAliasChainLength = 16
+
+// errAliasLoop is returned by canonicalName when the aliases in a response
+// loop, or form a chain longer than maxAliasChainLength.
+var errAliasLoop = errors.New("CNAME or DNAME chain loops or is too long")
+
+// canonicalName follows the chain of CNAME and DNAME records in answer, starting
+// from qname, and returns the fully-qualified name which any other records in
+// answer must be owned by in order to answer the query for qname.
+func canonicalName(qname string, answer []dns.RR) (string, error) {
+	current := strings.ToLower(dns.Fqdn(qname))
+	seen := map[string]bool{current: true}
+	for followed := 0; ; followed++ {
+		var next string
+		for _, rr := range answer {
+			owner := strings.ToLower(rr.Header().Name)
+			switch rr := rr.(type) {
+			case *dns.CNAME:
+				if owner == current {
+					next = strings.ToLower(rr.Target)
+				}
+			case *dns.DNAME:
+				if owner != current && dns.IsSubDomain(owner, current) {
+					next = strings.TrimSuffix(current, owner) + strings.ToLower(rr.Target)
+				}
+			}
+			if next != "" {
+				break
+			}
+		}
+		if next == "" {
+			return current, nil
+		}
+		if seen[next] || followed == maxAliasChainLength {
+			return "", errAliasLoop
+		}
+		seen[next] = true
+		current = next
+	}
 }
 
 // LookupCAA sends a DNS query to find all CAA records associated with
 // the provided hostname and the complete dig-style RR `response`. This
 // response is quite verbose, however it's only populated when the CAA
-// response is non-empty.
+// response is non-empty. Only CAA records owned by the name which the
+// hostname is an alias of, if any, are returned. It returns an error if the
+// aliases in the response loop.
 func (dnsClient *impl) LookupCAA(ctx context.Context, hostname string) ([]*dns.CAA, string, ResolverAddrs, error) {
 	dnsType := dns.TypeCAA
 	r, resolver, err := dnsClient.exchangeOne(ctx, hostname, dnsType)
@@ -604,14 +871,24 @@ func (dnsClient *impl) LookupCAA(ctx context.Context, hostname string) ([]*dns.C
 		return nil, "", ResolverAddrs{resolver}, nil
 	}
 
-	errWrap := wrapErr(dnsType, hostname, r, err)
+	errWrap := wrapErr(dnsType, hostname, resolver.Addr, r, err)
 	if errWrap != nil {
 		return nil, "", ResolverAddrs{resolver}, errWrap
 	}
 
+	canonical, err := canonicalName(hostname, r.Answer)
+	if err != nil {
+		return nil, "", ResolverAddrs{resolver}, Error{
+			recordType: dnsType,
+			hostname:   hostname,
+			underlying: err,
+			resolver:   resolver.Addr,
+		}
+	}
+
 	var CAAs []*dns.CAA
 	for _, answer := range r.Answer {
-		if caaR, ok := answer.(*dns.CAA); ok {
+		if caaR, ok := answer.(*dns.CAA); ok && strings.ToLower(caaR.Hdr.Name) == canonical {
 			CAAs = append(CAAs, caaR)
 		}
 	}
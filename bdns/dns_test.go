@@ -9,7 +9,6 @@ import (
 	"net/url"
 	"os"
 	"regexp"
-	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -27,6 +26,21 @@ import (
 
 const dnsLoopbackAddr = "127.0.0.1:4053"
 
+// hostResolvers are the queries made by a host lookup against dnsLoopbackAddr,
+// without their RTTs.
+var hostResolvers = ResolverAddrs{{Addr: dnsLoopbackAddr, Qtype: "A"}, {Addr: dnsLoopbackAddr, Qtype: "AAAA"}}
+
+// withoutRTT returns a copy of the queries with their RTTs, which vary from
+// run to run, removed.
+func withoutRTT(resolvers ResolverAddrs) ResolverAddrs {
+	var stripped ResolverAddrs
+	for _, r := range resolvers {
+		r.RTT = 0
+		stripped = append(stripped, r)
+	}
+	return stripped
+}
+
 func mockDNSQuery(w dns.ResponseWriter, r *dns.Msg) {
 	m := new(dns.Msg)
 	m.SetReply(r)
@@ -109,6 +123,12 @@ func mockDNSQuery(w dns.ResponseWriter, r *dns.Msg) {
 			if q.Name == "dualstackerror.letsencrypt.org." {
 				m.SetRcode(r, dns.RcodeRefused)
 			}
+			if q.Name == "private.letsencrypt.org." {
+				record := new(dns.A)
+				record.Hdr = dns.RR_Header{Name: "private.letsencrypt.org.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 0}
+				record.A = net.ParseIP("10.0.0.1")
+				appendAnswer(record)
+			}
 		case dns.TypeCNAME:
 			if q.Name == "cname.letsencrypt.org." {
 				record := new(dns.CNAME)
@@ -139,6 +159,10 @@ func mockDNSQuery(w dns.ResponseWriter, r *dns.Msg) {
 				appendAnswer(record)
 			}
 			if q.Name == "cname.example.com." {
+				cname := new(dns.CNAME)
+				cname.Hdr = dns.RR_Header{Name: "cname.example.com.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 30}
+				cname.Target = "caa.example.com."
+				appendAnswer(cname)
 				record := new(dns.CAA)
 				record.Hdr = dns.RR_Header{Name: "caa.example.com.", Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 0}
 				record.Tag = "issue"
@@ -146,15 +170,67 @@ func mockDNSQuery(w dns.ResponseWriter, r *dns.Msg) {
 				record.Flag = 1
 				appendAnswer(record)
 			}
+			if q.Name == "host.dname.example.com." {
+				dname := new(dns.DNAME)
+				dname.Hdr = dns.RR_Header{Name: "dname.example.com.", Rrtype: dns.TypeDNAME, Class: dns.ClassINET, Ttl: 30}
+				dname.Target = "example.net."
+				appendAnswer(dname)
+				record := new(dns.CAA)
+				record.Hdr = dns.RR_Header{Name: "host.example.net.", Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 0}
+				record.Tag = "issue"
+				record.Value = "letsencrypt.org"
+				appendAnswer(record)
+			}
+			if q.Name == "loop.example.com." {
+				first := new(dns.CNAME)
+				first.Hdr = dns.RR_Header{Name: "loop.example.com.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 30}
+				first.Target = "loop2.example.com."
+				appendAnswer(first)
+				second := new(dns.CNAME)
+				second.Hdr = dns.RR_Header{Name: "loop2.example.com.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 30}
+				second.Target = "loop.example.com."
+				appendAnswer(second)
+			}
+			if q.Name == "stray.example.com." {
+				// A CAA record for an unrelated name, which doesn't answer the
+				// query.
+				record := new(dns.CAA)
+				record.Hdr = dns.RR_Header{Name: "unrelated.example.com.", Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 0}
+				record.Tag = "issue"
+				record.Value = "letsencrypt.org"
+				appendAnswer(record)
+			}
 			if q.Name == "gonetld." {
 				m.SetRcode(r, dns.RcodeNameError)
 			}
+		case dns.TypeMX:
+			if q.Name == "letsencrypt.org." || q.Name == "nullmx.letsencrypt.org." {
+				record := new(dns.MX)
+				record.Hdr = dns.RR_Header{Name: q.Name, Rrtype: dns.TypeMX, Class: dns.ClassINET, Ttl: 0}
+				record.Preference = 10
+				record.Mx = "mail.letsencrypt.org."
+				if q.Name == "nullmx.letsencrypt.org." {
+					record.Preference = 0
+					record.Mx = "."
+				}
+				appendAnswer(record)
+			}
+			if q.Name == "nxdomain.letsencrypt.org." {
+				m.SetRcode(r, dns.RcodeNameError)
+			}
 		case dns.TypeTXT:
 			if q.Name == "split-txt.letsencrypt.org." {
 				record := new(dns.TXT)
 				record.Hdr = dns.RR_Header{Name: "split-txt.letsencrypt.org.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 0}
 				record.Txt = []string{"a", "b", "c"}
 				appendAnswer(record)
+			} else if q.Name == "ttl-txt.letsencrypt.org." {
+				for _, ttl := range []uint32{300, 4, 60} {
+					record := new(dns.TXT)
+					record.Hdr = dns.RR_Header{Name: "ttl-txt.letsencrypt.org.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl}
+					record.Txt = []string{fmt.Sprintf("ttl %d", ttl)}
+					appendAnswer(record)
+				}
 			} else {
 				auth := new(dns.SOA)
 				auth.Hdr = dns.RR_Header{Name: "letsencrypt.org.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 0}
@@ -252,13 +328,13 @@ func TestDNSNoServers(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	obj := NewTest(time.Hour, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, blog.UseMock(), nil)
+	obj := NewTest(time.Hour, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
 
 	_, resolvers, err := obj.LookupHost(context.Background(), "letsencrypt.org")
 	test.AssertEquals(t, len(resolvers), 0)
 	test.AssertError(t, err, "No servers")
 
-	_, _, err = obj.LookupTXT(context.Background(), "letsencrypt.org")
+	_, _, _, err = obj.LookupTXT(context.Background(), "letsencrypt.org")
 	test.AssertError(t, err, "No servers")
 
 	_, _, _, err = obj.LookupCAA(context.Background(), "letsencrypt.org")
@@ -269,12 +345,11 @@ func TestDNSOneServer(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, blog.UseMock(), nil)
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
 
 	_, resolvers, err := obj.LookupHost(context.Background(), "cps.letsencrypt.org")
 	test.AssertEquals(t, len(resolvers), 2)
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 	test.AssertNotError(t, err, "No message")
 }
 
@@ -282,12 +357,11 @@ func TestDNSDuplicateServers(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr, dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, blog.UseMock(), nil)
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
 
 	_, resolvers, err := obj.LookupHost(context.Background(), "cps.letsencrypt.org")
 	test.AssertEquals(t, len(resolvers), 2)
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 	test.AssertNotError(t, err, "No message")
 }
 
@@ -295,10 +369,10 @@ func TestDNSServFail(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, blog.UseMock(), nil)
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
 	bad := "servfail.com"
 
-	_, _, err = obj.LookupTXT(context.Background(), bad)
+	_, _, _, err = obj.LookupTXT(context.Background(), bad)
 	test.AssertError(t, err, "LookupTXT didn't return an error")
 
 	_, _, err = obj.LookupHost(context.Background(), bad)
@@ -313,60 +387,91 @@ func TestDNSLookupTXT(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, blog.UseMock(), nil)
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
 
-	a, _, err := obj.LookupTXT(context.Background(), "letsencrypt.org")
+	a, _, _, err := obj.LookupTXT(context.Background(), "letsencrypt.org")
 	t.Logf("A: %v", a)
 	test.AssertNotError(t, err, "No message")
 
-	a, _, err = obj.LookupTXT(context.Background(), "split-txt.letsencrypt.org")
+	a, minTTL, _, err := obj.LookupTXT(context.Background(), "split-txt.letsencrypt.org")
 	t.Logf("A: %v ", a)
 	test.AssertNotError(t, err, "No message")
 	test.AssertEquals(t, len(a), 1)
 	test.AssertEquals(t, a[0], "abc")
+	test.AssertEquals(t, minTTL, time.Duration(0))
+
+	// The lowest TTL among the answers is returned.
+	a, minTTL, _, err = obj.LookupTXT(context.Background(), "ttl-txt.letsencrypt.org")
+	test.AssertNotError(t, err, "No message")
+	test.AssertEquals(t, len(a), 3)
+	test.AssertEquals(t, minTTL, 4*time.Second)
+}
+
+func TestDNSLookupMX(t *testing.T) {
+	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
+	test.AssertNotError(t, err, "Got error creating StaticProvider")
+
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
+
+	mxs, resolvers, err := obj.LookupMX(context.Background(), "letsencrypt.org")
+	test.AssertNotError(t, err, "LookupMX failed")
+	test.AssertDeepEquals(t, mxs, []string{"mail.letsencrypt.org."})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), ResolverAddrs{{Addr: "127.0.0.1:4053", Qtype: "MX"}})
+	test.Assert(t, resolvers[0].RTT > 0, "RTT should be recorded")
+
+	mxs, _, err = obj.LookupMX(context.Background(), "nullmx.letsencrypt.org")
+	test.AssertNotError(t, err, "LookupMX failed")
+	test.AssertDeepEquals(t, mxs, []string{"."})
+
+	mxs, _, err = obj.LookupMX(context.Background(), "v6.letsencrypt.org")
+	test.AssertNotError(t, err, "LookupMX should not fail for a name without MX records")
+	test.AssertEquals(t, len(mxs), 0)
+
+	_, _, err = obj.LookupMX(context.Background(), "nxdomain.letsencrypt.org")
+	test.AssertError(t, err, "LookupMX should fail for a nonexistent name")
+	test.Assert(t, IsNXDOMAIN(err), "error should be NXDOMAIN")
+
+	_, _, err = obj.LookupMX(context.Background(), "servfail.com")
+	test.AssertError(t, err, "LookupMX should fail on SERVFAIL")
+	test.Assert(t, !IsNXDOMAIN(err), "SERVFAIL should not be NXDOMAIN")
 }
 
 func TestDNSLookupHost(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, blog.UseMock(), nil)
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
 
 	ip, resolvers, err := obj.LookupHost(context.Background(), "servfail.com")
 	t.Logf("servfail.com - IP: %s, Err: %s", ip, err)
 	test.AssertError(t, err, "Server failure")
 	test.Assert(t, len(ip) == 0, "Should not have IPs")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 
 	ip, resolvers, err = obj.LookupHost(context.Background(), "nonexistent.letsencrypt.org")
 	t.Logf("nonexistent.letsencrypt.org - IP: %s, Err: %s", ip, err)
 	test.AssertError(t, err, "No valid A or AAAA records should error")
 	test.Assert(t, len(ip) == 0, "Should not have IPs")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 
 	// Single IPv4 address
 	ip, resolvers, err = obj.LookupHost(context.Background(), "cps.letsencrypt.org")
 	t.Logf("cps.letsencrypt.org - IP: %s, Err: %s", ip, err)
 	test.AssertNotError(t, err, "Not an error to exist")
 	test.Assert(t, len(ip) == 1, "Should have IP")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 	ip, resolvers, err = obj.LookupHost(context.Background(), "cps.letsencrypt.org")
 	t.Logf("cps.letsencrypt.org - IP: %s, Err: %s", ip, err)
 	test.AssertNotError(t, err, "Not an error to exist")
 	test.Assert(t, len(ip) == 1, "Should have IP")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 
 	// Single IPv6 address
 	ip, resolvers, err = obj.LookupHost(context.Background(), "v6.letsencrypt.org")
 	t.Logf("v6.letsencrypt.org - IP: %s, Err: %s", ip, err)
 	test.AssertNotError(t, err, "Not an error to exist")
 	test.Assert(t, len(ip) == 1, "Should not have IPs")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 
 	// Both IPv6 and IPv4 address
 	ip, resolvers, err = obj.LookupHost(context.Background(), "dualstack.letsencrypt.org")
@@ -377,8 +482,7 @@ func TestDNSLookupHost(t *testing.T) {
 	test.Assert(t, ip[0].To4().Equal(expected), "wrong ipv4 address")
 	expected = net.ParseIP("::1")
 	test.Assert(t, ip[1].To16().Equal(expected), "wrong ipv6 address")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 
 	// IPv6 error, IPv4 success
 	ip, resolvers, err = obj.LookupHost(context.Background(), "v6error.letsencrypt.org")
@@ -387,8 +491,7 @@ func TestDNSLookupHost(t *testing.T) {
 	test.Assert(t, len(ip) == 1, "Should have 1 IP")
 	expected = net.ParseIP("127.0.0.1")
 	test.Assert(t, ip[0].To4().Equal(expected), "wrong ipv4 address")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 
 	// IPv6 success, IPv4 error
 	ip, resolvers, err = obj.LookupHost(context.Background(), "v4error.letsencrypt.org")
@@ -397,8 +500,7 @@ func TestDNSLookupHost(t *testing.T) {
 	test.Assert(t, len(ip) == 1, "Should have 1 IP")
 	expected = net.ParseIP("::1")
 	test.Assert(t, ip[0].To16().Equal(expected), "wrong ipv6 address")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
 
 	// IPv6 error, IPv4 error
 	// Should return both the IPv4 error (Refused) and the IPv6 error (NotImplemented)
@@ -408,23 +510,258 @@ func TestDNSLookupHost(t *testing.T) {
 	test.AssertError(t, err, "Should be an error")
 	test.AssertContains(t, err.Error(), "REFUSED looking up A for")
 	test.AssertContains(t, err.Error(), "NOTIMP looking up AAAA for")
-	slices.Sort(resolvers)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"A:127.0.0.1:4053", "AAAA:127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
+}
+
+func TestDNSLookupHostFamilies(t *testing.T) {
+	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
+	test.AssertNotError(t, err, "Got error creating StaticProvider")
+
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
+
+	// Both IPv6 and IPv4 address
+	lookup, err := obj.LookupHostFamilies(context.Background(), "dualstack.letsencrypt.org")
+	test.AssertNotError(t, err, "Not an error to exist")
+	test.AssertEquals(t, len(lookup.IPv4), 1)
+	test.AssertEquals(t, len(lookup.IPv6), 1)
+	test.AssertNotError(t, lookup.ErrA, "A lookup should succeed")
+	test.AssertNotError(t, lookup.ErrAAAA, "AAAA lookup should succeed")
+	test.AssertDeepEquals(t, withoutRTT(lookup.Resolvers), hostResolvers)
+	test.AssertEquals(t, lookup.Digest, HostAnswerDigest("dualstack.letsencrypt.org", lookup.Addrs()))
+
+	// IPv6 error, IPv4 success
+	lookup, err = obj.LookupHostFamilies(context.Background(), "v6error.letsencrypt.org")
+	test.AssertNotError(t, err, "Not an error to exist")
+	test.AssertEquals(t, len(lookup.IPv4), 1)
+	test.AssertEquals(t, len(lookup.IPv6), 0)
+	test.AssertNotError(t, lookup.ErrA, "A lookup should succeed")
+	test.AssertError(t, lookup.ErrAAAA, "AAAA lookup should fail")
+	test.AssertContains(t, lookup.ErrAAAA.Error(), "NOTIMP looking up AAAA for")
+
+	// IPv6 error, IPv4 error
+	lookup, err = obj.LookupHostFamilies(context.Background(), "dualstackerror.letsencrypt.org")
+	test.AssertError(t, err, "Should be an error")
+	test.AssertContains(t, lookup.ErrA.Error(), "REFUSED looking up A for")
+	test.AssertContains(t, lookup.ErrAAAA.Error(), "NOTIMP looking up AAAA for")
+	test.AssertEquals(t, len(lookup.Addrs()), 0)
+	test.AssertDeepEquals(t, withoutRTT(lookup.Resolvers), hostResolvers)
+}
+
+func TestDNSLookupHostFamiliesNoDataAndNXDOMAIN(t *testing.T) {
+	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
+	test.AssertNotError(t, err, "Got error creating StaticProvider")
+
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
+	restricted := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
+	restricted.(*impl).allowRestrictedAddresses = false
+
+	testCases := []struct {
+		hostname         string
+		restrict         bool
+		expectErr        bool
+		expectNXA        bool
+		expectNXAAAA     bool
+		expectNoDataA    bool
+		expectNoDataAAAA bool
+		expectErrA       string
+	}{
+		// NOERROR without any records for either type.
+		{"nonexistent.letsencrypt.org", false, true, false, false, true, true, "DNS problem: no A records exist for nonexistent.letsencrypt.org"},
+		// NXDOMAIN for both types.
+		{"nxdomain.letsencrypt.org", false, true, true, true, false, false, "DNS problem: NXDOMAIN looking up A for nxdomain.letsencrypt.org"},
+		// REFUSED for A and NOTIMP for AAAA are neither.
+		{"dualstackerror.letsencrypt.org", false, true, false, false, false, false, "DNS problem: REFUSED looking up A for dualstackerror.letsencrypt.org"},
+		// NOERROR without any A records, but with an AAAA record.
+		{"v6.letsencrypt.org", false, false, false, false, true, false, "DNS problem: no A records exist for v6.letsencrypt.org"},
+		// An A record whose address is restricted is not NODATA.
+		{"private.letsencrypt.org", true, true, false, false, false, true, "no valid A records found for private.letsencrypt.org"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.hostname, func(t *testing.T) {
+			client := obj
+			if tc.restrict {
+				client = restricted
+			}
+			lookup, err := client.LookupHostFamilies(context.Background(), tc.hostname)
+			test.AssertEquals(t, err != nil, tc.expectErr)
+			test.AssertEquals(t, IsNXDOMAIN(lookup.ErrA), tc.expectNXA)
+			test.AssertEquals(t, IsNXDOMAIN(lookup.ErrAAAA), tc.expectNXAAAA)
+			test.AssertEquals(t, IsNoData(lookup.ErrA), tc.expectNoDataA)
+			test.AssertEquals(t, IsNoData(lookup.ErrAAAA), tc.expectNoDataAAAA)
+			test.AssertContains(t, lookup.ErrA.Error(), tc.expectErrA)
+		})
+	}
+}
+
+// delayExchanger answers A queries with 127.0.0.1 and AAAA queries with ::1,
+// each after a per-qtype delay. If started is non-nil, the qtype of each query
+// is sent to it as the query begins. A query whose qtype has a channel in
+// release is not answered until that channel is closed.
+type delayExchanger struct {
+	delays  map[uint16]time.Duration
+	started chan uint16
+	release map[uint16]chan struct{}
+}
+
+func (de *delayExchanger) Exchange(m *dns.Msg, a string) (*dns.Msg, time.Duration, error) {
+	q := m.Question[0]
+	if de.started != nil {
+		de.started <- q.Qtype
+	}
+	if release, ok := de.release[q.Qtype]; ok {
+		<-release
+	}
+	delay := de.delays[q.Qtype]
+	time.Sleep(delay)
+
+	r := new(dns.Msg)
+	r.SetReply(m)
+	hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 0}
+	switch q.Qtype {
+	case dns.TypeA:
+		r.Answer = append(r.Answer, &dns.A{Hdr: hdr, A: net.ParseIP("127.0.0.1")})
+	case dns.TypeAAAA:
+		r.Answer = append(r.Answer, &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP("::1")})
+	}
+	return r, delay, nil
+}
+
+func newDelayTestClient(t testing.TB, exchanger *delayExchanger, hostLookupTimeout time.Duration) *impl {
+	t.Helper()
+	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
+	if err != nil {
+		t.Fatalf("Got error creating StaticProvider: %s", err)
+	}
+
+	client := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, hostLookupTimeout, blog.NewMock(), nil).(*impl)
+	client.dnsClient = exchanger
+	return client
+}
+
+func TestLookupHostFamiliesParallel(t *testing.T) {
+	t.Parallel()
+
+	// Neither query is answered until both have been sent, so the lookup
+	// can only complete if the A and AAAA queries are made in parallel.
+	exchanger := &delayExchanger{
+		started: make(chan uint16, 2),
+		release: map[uint16]chan struct{}{
+			dns.TypeA:    make(chan struct{}),
+			dns.TypeAAAA: make(chan struct{}),
+		},
+	}
+	client := newDelayTestClient(t, exchanger, 0)
+
+	type result struct {
+		lookup *HostLookup
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		lookup, err := client.LookupHostFamilies(context.Background(), "example.com")
+		done <- result{lookup, err}
+	}()
+
+	seen := map[uint16]bool{}
+	for range 2 {
+		select {
+		case qtype := <-exchanger.started:
+			seen[qtype] = true
+		case <-time.After(10 * time.Second):
+			t.Fatal("A and AAAA queries were not in flight at the same time")
+		}
+	}
+	test.Assert(t, seen[dns.TypeA] && seen[dns.TypeAAAA], "expected one A and one AAAA query")
+	for _, release := range exchanger.release {
+		close(release)
+	}
+
+	res := <-done
+	test.AssertNotError(t, res.err, "LookupHostFamilies failed")
+	test.AssertEquals(t, len(res.lookup.Addrs()), 2)
+}
+
+func TestLookupHostFamiliesSlowQtype(t *testing.T) {
+	t.Parallel()
+
+	// An AAAA query which is never answered fails on its own once the host
+	// lookup timeout passes, without failing the A query.
+	neverAnswered := make(chan struct{})
+	defer close(neverAnswered)
+	client := newDelayTestClient(t, &delayExchanger{
+		release: map[uint16]chan struct{}{dns.TypeAAAA: neverAnswered},
+	}, 250*time.Millisecond)
+	lookup, err := client.LookupHostFamilies(context.Background(), "example.com")
+	test.AssertNotError(t, err, "LookupHostFamilies should succeed with only A records")
+	test.AssertDeepEquals(t, lookup.Addrs(), []net.IP{net.ParseIP("127.0.0.1")})
+	test.AssertNotError(t, lookup.ErrA, "A lookup should succeed")
+	test.AssertError(t, lookup.ErrAAAA, "AAAA lookup should time out")
+	test.AssertContains(t, lookup.ErrAAAA.Error(), "looking up AAAA for example.com")
+	test.AssertDeepEquals(t, withoutRTT(lookup.Resolvers), hostResolvers)
+
+	// LookupHost reports the same partial results.
+	ips, resolvers, err := client.LookupHost(context.Background(), "example.com")
+	test.AssertNotError(t, err, "LookupHost should succeed with only A records")
+	test.AssertDeepEquals(t, ips, []net.IP{net.ParseIP("127.0.0.1")})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), hostResolvers)
+}
+
+func TestNewHostLookupTimeout(t *testing.T) {
+	t.Parallel()
+
+	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
+	test.AssertNotError(t, err, "Got error creating StaticProvider")
+
+	client := NewTest(time.Second, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 3, 0, blog.NewMock(), nil).(*impl)
+	test.AssertEquals(t, client.qtypeTimeout, 3*time.Second)
+
+	client = NewTest(time.Second, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 3, 5*time.Second, blog.NewMock(), nil).(*impl)
+	test.AssertEquals(t, client.qtypeTimeout, 5*time.Second)
+}
+
+func BenchmarkLookupHostFamilies(b *testing.B) {
+	client := newDelayTestClient(b, &delayExchanger{delays: map[uint16]time.Duration{
+		dns.TypeA:    time.Millisecond,
+		dns.TypeAAAA: 5 * time.Millisecond,
+	}}, time.Second)
+	for range b.N {
+		_, err := client.LookupHostFamilies(context.Background(), "example.com")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLookupHostSerial performs the same queries as
+// BenchmarkLookupHostFamilies one after the other, for comparison.
+func BenchmarkLookupHostSerial(b *testing.B) {
+	client := newDelayTestClient(b, &delayExchanger{delays: map[uint16]time.Duration{
+		dns.TypeA:    time.Millisecond,
+		dns.TypeAAAA: 5 * time.Millisecond,
+	}}, time.Second)
+	for range b.N {
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			_, _, err := client.lookupIP(context.Background(), "example.com", qtype)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
 }
 
 func TestDNSNXDOMAIN(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, blog.UseMock(), nil)
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
 
 	hostname := "nxdomain.letsencrypt.org"
 	_, _, err = obj.LookupHost(context.Background(), hostname)
 	test.AssertContains(t, err.Error(), "NXDOMAIN looking up A for")
 	test.AssertContains(t, err.Error(), "NXDOMAIN looking up AAAA for")
 
-	_, _, err = obj.LookupTXT(context.Background(), hostname)
-	expected := Error{dns.TypeTXT, hostname, nil, dns.RcodeNameError, nil}
+	_, _, _, err = obj.LookupTXT(context.Background(), hostname)
+	expected := Error{dns.TypeTXT, hostname, nil, dns.RcodeNameError, nil, dnsLoopbackAddr}
 	test.AssertDeepEquals(t, err, expected)
 }
 
@@ -432,14 +769,14 @@ func TestDNSLookupCAA(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, blog.UseMock(), nil)
+	obj := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
 	removeIDExp := regexp.MustCompile(" id: [[:digit:]]+")
 
 	caas, resp, resolvers, err := obj.LookupCAA(context.Background(), "bracewel.net")
 	test.AssertNotError(t, err, "CAA lookup failed")
 	test.Assert(t, len(caas) > 0, "Should have CAA records")
 	test.AssertEquals(t, len(resolvers), 1)
-	test.AssertDeepEquals(t, resolvers, ResolverAddrs{"127.0.0.1:4053"})
+	test.AssertDeepEquals(t, withoutRTT(resolvers), ResolverAddrs{{Addr: "127.0.0.1:4053", Qtype: "CAA"}})
 	expectedResp := `;; opcode: QUERY, status: NOERROR, id: XXXX
 ;; flags: qr rd; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 0
 
@@ -454,37 +791,134 @@ bracewel.net.	0	IN	CAA	1 issue "letsencrypt.org"
 	caas, resp, resolvers, err = obj.LookupCAA(context.Background(), "nonexistent.letsencrypt.org")
 	test.AssertNotError(t, err, "CAA lookup failed")
 	test.Assert(t, len(caas) == 0, "Shouldn't have CAA records")
-	test.AssertEquals(t, resolvers[0], "127.0.0.1:4053")
+	test.AssertEquals(t, resolvers[0].Addr, "127.0.0.1:4053")
 	expectedResp = ""
 	test.AssertEquals(t, resp, expectedResp)
 
 	caas, resp, resolvers, err = obj.LookupCAA(context.Background(), "nxdomain.letsencrypt.org")
-	slices.Sort(resolvers)
 	test.AssertNotError(t, err, "CAA lookup failed")
 	test.Assert(t, len(caas) == 0, "Shouldn't have CAA records")
-	test.AssertEquals(t, resolvers[0], "127.0.0.1:4053")
+	test.AssertEquals(t, resolvers[0].Addr, "127.0.0.1:4053")
 	expectedResp = ""
 	test.AssertEquals(t, resp, expectedResp)
 
 	caas, resp, resolvers, err = obj.LookupCAA(context.Background(), "cname.example.com")
 	test.AssertNotError(t, err, "CAA lookup failed")
 	test.Assert(t, len(caas) > 0, "Should follow CNAME to find CAA")
-	test.AssertEquals(t, resolvers[0], "127.0.0.1:4053")
+	test.AssertEquals(t, resolvers[0].Addr, "127.0.0.1:4053")
 	expectedResp = `;; opcode: QUERY, status: NOERROR, id: XXXX
-;; flags: qr rd; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 0
+;; flags: qr rd; QUERY: 1, ANSWER: 2, AUTHORITY: 0, ADDITIONAL: 0
 
 ;; QUESTION SECTION:
 ;cname.example.com.	IN	 CAA
 
 ;; ANSWER SECTION:
+cname.example.com.	30	IN	CNAME	caa.example.com.
 caa.example.com.	0	IN	CAA	1 issue "letsencrypt.org"
 `
 	test.AssertEquals(t, removeIDExp.ReplaceAllString(resp, " id: XXXX"), expectedResp)
 
+	caas, _, _, err = obj.LookupCAA(context.Background(), "host.dname.example.com")
+	test.AssertNotError(t, err, "CAA lookup failed")
+	test.AssertEquals(t, len(caas), 1)
+	test.AssertEquals(t, caas[0].Hdr.Name, "host.example.net.")
+
+	_, _, resolvers, err = obj.LookupCAA(context.Background(), "loop.example.com")
+	test.AssertError(t, err, "should fail for a CNAME loop")
+	test.AssertEquals(t, err.Error(), "DNS problem: CNAME or DNAME loop looking up CAA for loop.example.com")
+	test.AssertEquals(t, resolvers[0].Addr, "127.0.0.1:4053")
+
+	caas, _, _, err = obj.LookupCAA(context.Background(), "stray.example.com")
+	test.AssertNotError(t, err, "CAA lookup failed")
+	test.AssertEquals(t, len(caas), 0)
+
 	_, _, resolvers, err = obj.LookupCAA(context.Background(), "gonetld")
 	test.AssertError(t, err, "should fail for TLD NXDOMAIN")
 	test.AssertContains(t, err.Error(), "NXDOMAIN")
-	test.AssertEquals(t, resolvers[0], "127.0.0.1:4053")
+	test.AssertEquals(t, resolvers[0].Addr, "127.0.0.1:4053")
+}
+
+func TestCanonicalName(t *testing.T) {
+	t.Parallel()
+
+	cname := func(owner, target string) dns.RR {
+		return &dns.CNAME{Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeCNAME, Class: dns.ClassINET}, Target: target}
+	}
+	dname := func(owner, target string) dns.RR {
+		return &dns.DNAME{Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeDNAME, Class: dns.ClassINET}, Target: target}
+	}
+	var longChain []dns.RR
+	for i := range maxAliasChainLength + 1 {
+		longChain = append(longChain, cname(fmt.Sprintf("%d.example.com.", i), fmt.Sprintf("%d.example.com.", i+1)))
+	}
+
+	testCases := []struct {
+		name     string
+		qname    string
+		answer   []dns.RR
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "no aliases",
+			qname:    "example.com",
+			expected: "example.com.",
+		},
+		{
+			name:     "CNAME chain crossing zones",
+			qname:    "www.Example.com",
+			answer:   []dns.RR{cname("www.example.com.", "cdn.example.net."), cname("cdn.example.net.", "edge.example.org.")},
+			expected: "edge.example.org.",
+		},
+		{
+			name:     "DNAME rewrites a subtree",
+			qname:    "a.b.old.example.com",
+			answer:   []dns.RR{dname("old.example.com.", "new.example.net.")},
+			expected: "a.b.new.example.net.",
+		},
+		{
+			name:     "DNAME doesn't apply to its owner",
+			qname:    "old.example.com",
+			answer:   []dns.RR{dname("old.example.com.", "new.example.net.")},
+			expected: "old.example.com.",
+		},
+		{
+			name:    "CNAME loop",
+			qname:   "a.example.com",
+			answer:  []dns.RR{cname("a.example.com.", "b.example.com."), cname("b.example.com.", "a.example.com.")},
+			wantErr: true,
+		},
+		{
+			name:    "DNAME loop",
+			qname:   "a.old.example.com",
+			answer:  []dns.RR{cname("a.old.example.com.", "a.new.example.com."), dname("new.example.com.", "old.example.com.")},
+			wantErr: true,
+		},
+		{
+			name:     "longest permitted chain",
+			qname:    "0.example.com",
+			answer:   longChain[:maxAliasChainLength],
+			expected: fmt.Sprintf("%d.example.com.", maxAliasChainLength),
+		},
+		{
+			name:    "chain too long",
+			qname:   "0.example.com",
+			answer:  longChain,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := canonicalName(tc.qname, tc.answer)
+			if tc.wantErr {
+				test.AssertErrorIs(t, err, errAliasLoop)
+				return
+			}
+			test.AssertNotError(t, err, "canonicalName failed")
+			test.AssertEquals(t, got, tc.expected)
+		})
+	}
 }
 
 func TestIsPrivateIP(t *testing.T) {
@@ -518,6 +952,14 @@ func TestIsPrivateIP(t *testing.T) {
 	test.Assert(t, !isPrivateV6(net.ParseIP("0100::0001:0000:0000:0000:0000")), "should be private")
 }
 
+func TestIsReservedIP(t *testing.T) {
+	test.Assert(t, IsReservedIP(net.ParseIP("10.255.0.3")), "should be reserved")
+	test.Assert(t, IsReservedIP(net.ParseIP("::ffff:127.0.0.1")), "should be reserved")
+	test.Assert(t, IsReservedIP(net.ParseIP("fe80::1")), "should be reserved")
+	test.Assert(t, !IsReservedIP(net.ParseIP("128.0.0.1")), "should not be reserved")
+	test.Assert(t, !IsReservedIP(net.ParseIP("2600::1")), "should not be reserved")
+}
+
 type testExchanger struct {
 	sync.Mutex
 	count int
@@ -673,10 +1115,10 @@ func TestRetry(t *testing.T) {
 			staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 			test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-			testClient := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), tc.maxTries, blog.UseMock(), nil)
+			testClient := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), tc.maxTries, 0, blog.UseMock(), nil)
 			dr := testClient.(*impl)
 			dr.dnsClient = tc.te
-			_, _, err = dr.LookupTXT(context.Background(), "example.com")
+			_, _, _, err = dr.LookupTXT(context.Background(), "example.com")
 			if err == errTooManyRequests {
 				t.Errorf("#%d, sent more requests than the test case handles", i)
 			}
@@ -704,12 +1146,12 @@ func TestRetry(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	testClient := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 3, blog.UseMock(), nil)
+	testClient := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 3, 0, blog.UseMock(), nil)
 	dr := testClient.(*impl)
 	dr.dnsClient = &testExchanger{errs: []error{isTempErr, isTempErr, nil}}
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	_, _, err = dr.LookupTXT(ctx, "example.com")
+	_, _, _, err = dr.LookupTXT(ctx, "example.com")
 	if err == nil ||
 		err.Error() != "DNS problem: query timed out (and was canceled) looking up TXT for example.com" {
 		t.Errorf("expected %s, got %s", context.Canceled, err)
@@ -718,7 +1160,7 @@ func TestRetry(t *testing.T) {
 	dr.dnsClient = &testExchanger{errs: []error{isTempErr, isTempErr, nil}}
 	ctx, cancel = context.WithTimeout(context.Background(), -10*time.Hour)
 	defer cancel()
-	_, _, err = dr.LookupTXT(ctx, "example.com")
+	_, _, _, err = dr.LookupTXT(ctx, "example.com")
 	if err == nil ||
 		err.Error() != "DNS problem: query timed out looking up TXT for example.com" {
 		t.Errorf("expected %s, got %s", context.DeadlineExceeded, err)
@@ -727,7 +1169,7 @@ func TestRetry(t *testing.T) {
 	dr.dnsClient = &testExchanger{errs: []error{isTempErr, isTempErr, nil}}
 	ctx, deadlineCancel := context.WithTimeout(context.Background(), -10*time.Hour)
 	deadlineCancel()
-	_, _, err = dr.LookupTXT(ctx, "example.com")
+	_, _, _, err = dr.LookupTXT(ctx, "example.com")
 	if err == nil ||
 		err.Error() != "DNS problem: query timed out looking up TXT for example.com" {
 		t.Errorf("expected %s, got %s", context.DeadlineExceeded, err)
@@ -808,7 +1250,7 @@ func TestRotateServerOnErr(t *testing.T) {
 	fmt.Println(staticProvider.servers)
 
 	maxTries := 5
-	client := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), maxTries, blog.UseMock(), nil)
+	client := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), maxTries, 0, blog.UseMock(), nil)
 
 	// Configure a mock exchanger that will always return a retryable error for
 	// servers A and B. This will force server "[2606:4700:4700::1111]:53" to do
@@ -828,9 +1270,9 @@ func TestRotateServerOnErr(t *testing.T) {
 	// servers *all* queries should eventually succeed by being retried against
 	// server "[2606:4700:4700::1111]:53".
 	for range maxTries * 2 {
-		_, resolvers, err := client.LookupTXT(context.Background(), "example.com")
+		_, _, resolvers, err := client.LookupTXT(context.Background(), "example.com")
 		test.AssertEquals(t, len(resolvers), 1)
-		test.AssertEquals(t, resolvers[0], "[2606:4700:4700::1111]:53")
+		test.AssertEquals(t, resolvers[0].Addr, "[2606:4700:4700::1111]:53")
 		// Any errors are unexpected - server "[2606:4700:4700::1111]:53" should
 		// have responded without error.
 		test.AssertNotError(t, err, "Expected no error from eventual retry with functional server")
@@ -878,7 +1320,7 @@ func TestDOHMetric(t *testing.T) {
 	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
 	test.AssertNotError(t, err, "Got error creating StaticProvider")
 
-	testClient := NewTest(time.Second*11, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 0, blog.UseMock(), nil)
+	testClient := NewTest(time.Second*11, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 0, 0, blog.UseMock(), nil)
 	resolver := testClient.(*impl)
 	resolver.dnsClient = &dohAlwaysRetryExchanger{err: &url.Error{Op: "read", Err: tempError(true)}}
 
@@ -891,3 +1333,128 @@ func TestDOHMetric(t *testing.T) {
 	// Now, we should count 1 "out of retries" errors.
 	test.AssertMetricWithLabelsEquals(t, resolver.timeoutCounter, prometheus.Labels{"qtype": "None", "type": "out of retries", "resolver": "127.0.0.1", "isTLD": "false"}, 1)
 }
+
+// udpExchanger mocks the UDP transport of a server which either drops all UDP
+// queries, or answers them with truncated responses.
+type udpExchanger struct {
+	truncate bool
+}
+
+func (ue *udpExchanger) Exchange(m *dns.Msg, a string) (*dns.Msg, time.Duration, error) {
+	if !ue.truncate {
+		return nil, time.Second, &net.OpError{Op: "read", Net: "udp", Err: timeoutError{}}
+	}
+	resp := new(dns.Msg)
+	resp.SetReply(m)
+	resp.Truncated = true
+	return resp, time.Millisecond, nil
+}
+
+// tcpExchanger mocks the TCP transport of a server, answering TXT queries with
+// a single record, or failing every query if err is set.
+type tcpExchanger struct {
+	sync.Mutex
+	queries int
+	err     error
+}
+
+func (te *tcpExchanger) Exchange(m *dns.Msg, a string) (*dns.Msg, time.Duration, error) {
+	te.Lock()
+	defer te.Unlock()
+	te.queries++
+	if te.err != nil {
+		return nil, time.Second, te.err
+	}
+	resp := new(dns.Msg)
+	resp.SetReply(m)
+	resp.Answer = append(resp.Answer, &dns.TXT{
+		Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+		Txt: []string{"over tcp"},
+	})
+	return resp, 3 * time.Millisecond, nil
+}
+
+func TestTCPFallback(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		udp          *udpExchanger
+		tcp          *tcpExchanger
+		reason       string
+		expectedErr  string
+		expectedTXTs []string
+	}{
+		{
+			name:         "UDP dropped, TCP answers",
+			udp:          &udpExchanger{},
+			tcp:          &tcpExchanger{},
+			reason:       "timeout",
+			expectedTXTs: []string{"over tcp"},
+		},
+		{
+			name:         "UDP truncated, TCP answers",
+			udp:          &udpExchanger{truncate: true},
+			tcp:          &tcpExchanger{},
+			reason:       "truncated",
+			expectedTXTs: []string{"over tcp"},
+		},
+		{
+			name:        "UDP dropped, TCP fails",
+			udp:         &udpExchanger{},
+			tcp:         &tcpExchanger{err: &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}},
+			reason:      "timeout",
+			expectedErr: "DNS problem: DNS over UDP timed out; TCP fallback also failed looking up TXT for example.com",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
+			test.AssertNotError(t, err, "Got error creating StaticProvider")
+
+			testClient := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
+			resolver := testClient.(*impl)
+			resolver.dnsClient = tc.udp
+			resolver.tcpClient = tc.tcp
+
+			txts, _, resolvers, err := resolver.LookupTXT(context.Background(), "example.com")
+			test.AssertEquals(t, tc.tcp.queries, 1)
+			result := "success"
+			if tc.expectedErr != "" {
+				result = "failed"
+				test.AssertError(t, err, "expected LookupTXT to fail")
+				test.AssertEquals(t, err.Error(), tc.expectedErr)
+			} else {
+				test.AssertNotError(t, err, "LookupTXT failed")
+				test.AssertDeepEquals(t, txts, tc.expectedTXTs)
+				test.AssertEquals(t, len(resolvers), 1)
+				test.Assert(t, resolvers[0].TCPFallback, "expected the query to fall back to TCP")
+				test.AssertEquals(t, resolvers[0].String(), fmt.Sprintf("%s (TXT, 3ms, TCP fallback)", dnsLoopbackAddr))
+			}
+			test.AssertMetricWithLabelsEquals(t, resolver.tcpFallbackCounter, prometheus.Labels{
+				"qtype":  "TXT",
+				"reason": tc.reason,
+				"result": result,
+			}, 1)
+		})
+	}
+}
+
+func TestNoTCPFallbackForDOH(t *testing.T) {
+	features.Set(features.Config{DOH: true})
+	defer features.Reset()
+
+	staticProvider, err := NewStaticProvider([]string{dnsLoopbackAddr})
+	test.AssertNotError(t, err, "Got error creating StaticProvider")
+
+	testClient := NewTest(time.Second*10, staticProvider, metrics.NoopRegisterer, clock.NewFake(), 1, 0, blog.UseMock(), nil)
+	resolver := testClient.(*impl)
+	test.AssertEquals(t, resolver.tcpClient, nil)
+	resolver.dnsClient = &udpExchanger{}
+
+	_, _, resolvers, err := resolver.LookupTXT(context.Background(), "example.com")
+	test.AssertError(t, err, "expected LookupTXT to fail")
+	test.AssertEquals(t, err.Error(), "DNS problem: query timed out looking up TXT for example.com")
+	test.Assert(t, !resolvers[0].TCPFallback, "expected no TCP fallback")
+}
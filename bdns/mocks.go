@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/miekg/dns"
 
@@ -17,37 +20,78 @@ type MockClient struct {
 	Log blog.Logger
 }
 
+// MockRTT is the round trip time of every query reported by MockClient.
+const MockRTT = time.Millisecond
+
+// MockTXTTTL is the TTL of the TXT records returned by MockClient, other than
+// those of the ttl-N.com and wrong-ttl-N.com hostnames, whose TTL is N
+// seconds.
+const MockTXTTTL = 300 * time.Second
+
+// mockResolvers returns the queries reported by MockClient for a lookup which
+// made a query of each of the given qtypes.
+func mockResolvers(qtypes ...string) ResolverAddrs {
+	var resolvers ResolverAddrs
+	for _, qtype := range qtypes {
+		resolvers = append(resolvers, ResolverAddr{Addr: "MockClient", Qtype: qtype, RTT: MockRTT})
+	}
+	return resolvers
+}
+
 // LookupTXT is a mock
-func (mock *MockClient) LookupTXT(_ context.Context, hostname string) ([]string, ResolverAddrs, error) {
+func (mock *MockClient) LookupTXT(_ context.Context, hostname string) ([]string, time.Duration, ResolverAddrs, error) {
 	if hostname == "_acme-challenge.servfail.com" {
-		return nil, ResolverAddrs{"MockClient"}, fmt.Errorf("SERVFAIL")
+		return nil, 0, mockResolvers("TXT"), fmt.Errorf("SERVFAIL")
 	}
 	if hostname == "_acme-challenge.good-dns01.com" {
 		// base64(sha256("LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0"
 		//               + "." + "9jg46WB3rR_AHD-EBXdN7cBkH1WOu0tA3M9fm21mqTI"))
 		// expected token + test account jwk thumbprint
-		return []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, ResolverAddrs{"MockClient"}, nil
+		return []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, MockTXTTTL, mockResolvers("TXT"), nil
 	}
 	if hostname == "_acme-challenge.wrong-dns01.com" {
-		return []string{"a"}, ResolverAddrs{"MockClient"}, nil
+		return []string{"a"}, MockTXTTTL, mockResolvers("TXT"), nil
 	}
 	if hostname == "_acme-challenge.wrong-many-dns01.com" {
-		return []string{"a", "b", "c", "d", "e"}, ResolverAddrs{"MockClient"}, nil
+		return []string{"a", "b", "c", "d", "e"}, MockTXTTTL, mockResolvers("TXT"), nil
 	}
 	if hostname == "_acme-challenge.long-dns01.com" {
-		return []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}, ResolverAddrs{"MockClient"}, nil
+		return []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}, MockTXTTTL, mockResolvers("TXT"), nil
 	}
 	if hostname == "_acme-challenge.no-authority-dns01.com" {
 		// base64(sha256("LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0"
 		//               + "." + "9jg46WB3rR_AHD-EBXdN7cBkH1WOu0tA3M9fm21mqTI"))
 		// expected token + test account jwk thumbprint
-		return []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, ResolverAddrs{"MockClient"}, nil
+		return []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, MockTXTTTL, mockResolvers("TXT"), nil
+	}
+	if hostname == "_6g727n6x5dk6qex5._acme-challenge.good-dns-account01.com" ||
+		hostname == "_nc25al42opo24oqd._acme-challenge.other-prefix-dns-account01.com" {
+		// The dns-account-01 labels of the accounts with the URLs
+		// "http://boulder.service.consul:4000/acme/reg/1" and
+		// "http://boulder.service.consul:4001/acme/acct/1" respectively, with
+		// the same value as _acme-challenge.good-dns01.com.
+		return []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, MockTXTTTL, mockResolvers("TXT"), nil
+	}
+	if strings.HasPrefix(hostname, "_acme-challenge.ttl-") {
+		// ttl-N.com returns the value of good-dns01.com with a TTL of N
+		// seconds.
+		ttl, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(hostname, "_acme-challenge.ttl-"), ".com"))
+		if err == nil {
+			return []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, time.Duration(ttl) * time.Second, mockResolvers("TXT"), nil
+		}
+	}
+	if strings.HasPrefix(hostname, "_acme-challenge.wrong-ttl-") {
+		// wrong-ttl-N.com returns an incorrect value with a TTL of N seconds.
+		ttl, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(hostname, "_acme-challenge.wrong-ttl-"), ".com"))
+		if err == nil {
+			return []string{"a"}, time.Duration(ttl) * time.Second, mockResolvers("TXT"), nil
+		}
 	}
 	// empty-txts.com always returns zero TXT records
 	if hostname == "_acme-challenge.empty-txts.com" {
-		return []string{}, ResolverAddrs{"MockClient"}, nil
+		return []string{}, 0, mockResolvers("TXT"), nil
 	}
-	return []string{"hostname"}, ResolverAddrs{"MockClient"}, nil
+	return []string{"hostname"}, MockTXTTTL, mockResolvers("TXT"), nil
 }
 
 // makeTimeoutError returns a a net.OpError for which Timeout() returns true.
@@ -70,10 +114,16 @@ func (t timeoutError) Timeout() bool {
 func (mock *MockClient) LookupHost(_ context.Context, hostname string) ([]net.IP, ResolverAddrs, error) {
 	if hostname == "always.invalid" ||
 		hostname == "invalid.invalid" {
-		return []net.IP{}, ResolverAddrs{"MockClient"}, nil
+		return []net.IP{}, mockResolvers("A", "AAAA"), nil
 	}
 	if hostname == "always.timeout" {
-		return []net.IP{}, ResolverAddrs{"MockClient"}, &Error{dns.TypeA, "always.timeout", makeTimeoutError(), -1, nil}
+		return []net.IP{}, mockResolvers("A", "AAAA"), &Error{dns.TypeA, "always.timeout", makeTimeoutError(), -1, nil, "MockClient"}
+	}
+	if hostname == "always.nxdomain" {
+		return []net.IP{}, mockResolvers("A", "AAAA"), Error{dns.TypeA, hostname, nil, dns.RcodeNameError, nil, "MockClient"}
+	}
+	if hostname == "always.nodata" {
+		return []net.IP{}, mockResolvers("A", "AAAA"), newNoDataError(dns.TypeA, hostname, "MockClient")
 	}
 	if hostname == "always.error" {
 		err := &net.OpError{
@@ -86,7 +136,7 @@ func (mock *MockClient) LookupHost(_ context.Context, hostname string) ([]net.IP
 		m.AuthenticatedData = true
 		m.SetEdns0(4096, false)
 		logDNSError(mock.Log, "mock.server", hostname, m, nil, err)
-		return []net.IP{}, ResolverAddrs{"MockClient"}, &Error{dns.TypeA, hostname, err, -1, nil}
+		return []net.IP{}, mockResolvers("A", "AAAA"), &Error{dns.TypeA, hostname, err, -1, nil, "MockClient"}
 	}
 	if hostname == "id.mismatch" {
 		err := dns.ErrId
@@ -100,25 +150,60 @@ func (mock *MockClient) LookupHost(_ context.Context, hostname string) ([]net.IP
 		record.A = net.ParseIP("127.0.0.1")
 		r.Answer = append(r.Answer, record)
 		logDNSError(mock.Log, "mock.server", hostname, m, r, err)
-		return []net.IP{}, ResolverAddrs{"MockClient"}, &Error{dns.TypeA, hostname, err, -1, nil}
+		return []net.IP{}, mockResolvers("A", "AAAA"), &Error{dns.TypeA, hostname, err, -1, nil, "MockClient"}
 	}
 	// dual-homed host with an IPv6 and an IPv4 address
 	if hostname == "ipv4.and.ipv6.localhost" {
 		return []net.IP{
 			net.ParseIP("::1"),
 			net.ParseIP("127.0.0.1"),
-		}, ResolverAddrs{"MockClient"}, nil
+		}, mockResolvers("A", "AAAA"), nil
 	}
 	if hostname == "ipv6.localhost" {
 		return []net.IP{
 			net.ParseIP("::1"),
-		}, ResolverAddrs{"MockClient"}, nil
+		}, mockResolvers("A", "AAAA"), nil
 	}
 	ip := net.ParseIP("127.0.0.1")
-	return []net.IP{ip}, ResolverAddrs{"MockClient"}, nil
+	return []net.IP{ip}, mockResolvers("A", "AAAA"), nil
+}
+
+// LookupHostFamilies is a mock which splits the results of LookupHost by
+// address family. An error from LookupHost is reported for both families.
+func (mock *MockClient) LookupHostFamilies(ctx context.Context, hostname string) (*HostLookup, error) {
+	addrs, resolvers, err := mock.LookupHost(ctx, hostname)
+	lookup := &HostLookup{Resolvers: resolvers}
+	if err != nil {
+		lookup.ErrA, lookup.ErrAAAA = err, err
+		return lookup, err
+	}
+	lookup.Digest = HostAnswerDigest(hostname, addrs)
+	for _, addr := range addrs {
+		if addr.To4() != nil {
+			lookup.IPv4 = append(lookup.IPv4, addr)
+		} else {
+			lookup.IPv6 = append(lookup.IPv6, addr)
+		}
+	}
+	return lookup, nil
 }
 
 // LookupCAA returns mock records for use in tests.
 func (mock *MockClient) LookupCAA(_ context.Context, domain string) ([]*dns.CAA, string, ResolverAddrs, error) {
-	return nil, "", ResolverAddrs{"MockClient"}, nil
+	return nil, "", mockResolvers("CAA"), nil
+}
+
+// LookupMX is a mock
+func (mock *MockClient) LookupMX(_ context.Context, hostname string) ([]string, ResolverAddrs, error) {
+	switch hostname {
+	case "always.nxdomain":
+		return nil, mockResolvers("MX"), Error{dns.TypeMX, hostname, nil, dns.RcodeNameError, nil, "MockClient"}
+	case "always.timeout":
+		return nil, mockResolvers("MX"), Error{dns.TypeMX, hostname, makeTimeoutError(), -1, nil, "MockClient"}
+	case "null-mx.com":
+		return []string{"."}, mockResolvers("MX"), nil
+	case "no-mx.com":
+		return nil, mockResolvers("MX"), nil
+	}
+	return []string{"mail." + hostname + "."}, mockResolvers("MX"), nil
 }
@@ -14,13 +14,45 @@ import (
 type Error struct {
 	recordType uint16
 	hostname   string
-	// Exactly one of rCode or underlying should be set.
+	// Exactly one of rCode or underlying should be set. An rCode of
+	// dns.RcodeSuccess means that the query succeeded but its answer held no
+	// records of recordType (NODATA).
 	underlying error
 	rCode      int
 
 	// Optional: If the resolver returned extended error information, it will be stored here.
 	// https://www.rfc-editor.org/rfc/rfc8914
 	extended *dns.EDNS0_EDE
+
+	// Optional: The address of the resolver the query was sent to.
+	resolver string
+}
+
+// QueryDetails describes the query which resulted in an Error.
+type QueryDetails struct {
+	QueryName string
+	QueryType string
+	Resolver  string
+	// RCode is empty if no response was received.
+	RCode string
+	// EDE is empty if the response contained no Extended DNS Error.
+	EDE string
+}
+
+// QueryDetails returns the details of the query which resulted in d.
+func (d Error) QueryDetails() QueryDetails {
+	details := QueryDetails{
+		QueryName: d.hostname,
+		QueryType: dns.TypeToString[d.recordType],
+		Resolver:  d.resolver,
+	}
+	if d.underlying == nil {
+		details.RCode = dns.RcodeToString[d.rCode]
+	}
+	if d.extended != nil {
+		details.EDE = extendedErrorSummary(d.extended)
+	}
+	return details
 }
 
 // extendedDNSError returns non-nil if the input message contained an OPT RR
@@ -41,13 +73,14 @@ func extendedDNSError(msg *dns.Msg) *dns.EDNS0_EDE {
 
 // wrapErr returns a non-nil error if err is non-nil or if resp.Rcode is not dns.RcodeSuccess.
 // The error includes appropriate details about the DNS query that failed.
-func wrapErr(queryType uint16, hostname string, resp *dns.Msg, err error) error {
+func wrapErr(queryType uint16, hostname string, resolver string, resp *dns.Msg, err error) error {
 	if err != nil {
 		return Error{
 			recordType: queryType,
 			hostname:   hostname,
 			underlying: err,
 			extended:   nil,
+			resolver:   resolver,
 		}
 	}
 	if resp.Rcode != dns.RcodeSuccess {
@@ -57,11 +90,38 @@ func wrapErr(queryType uint16, hostname string, resp *dns.Msg, err error) error
 			rCode:      resp.Rcode,
 			underlying: nil,
 			extended:   extendedDNSError(resp),
+			resolver:   resolver,
 		}
 	}
 	return nil
 }
 
+// IsNXDOMAIN returns true if err is an Error for a query which received an
+// NXDOMAIN response, meaning that the queried name does not exist.
+func IsNXDOMAIN(err error) bool {
+	var dnsErr Error
+	return errors.As(err, &dnsErr) && dnsErr.rCode == dns.RcodeNameError
+}
+
+// newNoDataError returns an Error for a query which succeeded but whose answer
+// held no records of queryType.
+func newNoDataError(queryType uint16, hostname string, resolver string) error {
+	return Error{
+		recordType: queryType,
+		hostname:   hostname,
+		rCode:      dns.RcodeSuccess,
+		resolver:   resolver,
+	}
+}
+
+// IsNoData returns true if err is an Error for a query which received a
+// NOERROR response without any records of the queried type, meaning that the
+// queried name exists but has no such records.
+func IsNoData(err error) bool {
+	var dnsErr Error
+	return errors.As(err, &dnsErr) && dnsErr.underlying == nil && dnsErr.rCode == dns.RcodeSuccess
+}
+
 // A copy of miekg/dns's mapping of error codes to strings. We tweak it slightly so all DNSSEC-related
 // errors say "DNSSEC" at the beginning.
 // https://pkg.go.dev/github.com/miekg/dns#ExtendedErrorCodeToString
@@ -98,9 +158,12 @@ var extendedErrorCodeToString = map[uint16]string{
 func (d Error) Error() string {
 	var detail, additional string
 	if d.underlying != nil {
+		var fallbackErr tcpFallbackError
 		var netErr *net.OpError
 		var urlErr *url.Error
-		if errors.As(d.underlying, &netErr) {
+		if errors.As(d.underlying, &fallbackErr) {
+			detail = detailTCPFallbackFailed
+		} else if errors.As(d.underlying, &netErr) {
 			if netErr.Timeout() {
 				detail = detailDNSTimeout
 			} else {
@@ -117,6 +180,8 @@ func (d Error) Error() string {
 			detail = detailDNSTimeout
 		} else if errors.Is(d.underlying, context.Canceled) {
 			detail = detailCanceled
+		} else if errors.Is(d.underlying, errAliasLoop) {
+			detail = detailAliasLoop
 		} else {
 			detail = detailServerFailure
 		}
@@ -126,7 +191,8 @@ func (d Error) Error() string {
 			additional = " - " + explanation
 		}
 	} else {
-		detail = detailServerFailure
+		return fmt.Sprintf("DNS problem: no %s records exist for %s",
+			dns.TypeToString[d.recordType], d.hostname)
 	}
 
 	if d.extended == nil {
@@ -134,22 +200,47 @@ func (d Error) Error() string {
 			dns.TypeToString[d.recordType], d.hostname, additional)
 	}
 
-	summary := extendedErrorCodeToString[d.extended.InfoCode]
-	if summary == "" {
-		summary = fmt.Sprintf("Unknown Extended DNS Error code %d", d.extended.InfoCode)
-	}
 	result := fmt.Sprintf("DNS problem: looking up %s for %s: %s",
-		dns.TypeToString[d.recordType], d.hostname, summary)
+		dns.TypeToString[d.recordType], d.hostname, extendedErrorSummary(d.extended))
 	if d.extended.ExtraText != "" {
 		result = result + ": " + d.extended.ExtraText
 	}
 	return result
 }
 
+// extendedErrorSummary returns a short description of the provided Extended
+// DNS Error's info code.
+func extendedErrorSummary(ede *dns.EDNS0_EDE) string {
+	summary := extendedErrorCodeToString[ede.InfoCode]
+	if summary == "" {
+		summary = fmt.Sprintf("Unknown Extended DNS Error code %d", ede.InfoCode)
+	}
+	return summary
+}
+
 const detailDNSTimeout = "query timed out"
 const detailCanceled = "query timed out (and was canceled)"
 const detailDNSNetFailure = "networking error"
 const detailServerFailure = "server failure at resolver"
+const detailAliasLoop = "CNAME or DNAME loop"
+const detailTCPFallbackFailed = "DNS over UDP timed out; TCP fallback also failed"
+
+// tcpFallbackError is returned when a query's UDP exchange timed out and the
+// exchange retried over TCP also failed. It unwraps to the TCP error, so that
+// whether the query can be retried with another server is decided by the
+// outcome of the last attempt.
+type tcpFallbackError struct {
+	udpErr error
+	tcpErr error
+}
+
+func (e tcpFallbackError) Error() string {
+	return fmt.Sprintf("UDP: %s; TCP fallback: %s", e.udpErr, e.tcpErr)
+}
+
+func (e tcpFallbackError) Unwrap() error {
+	return e.tcpErr
+}
 
 // rcodeExplanations provide additional friendly explanatory text to be included in DNS
 // error messages, for select inscrutable RCODEs.
@@ -3,6 +3,7 @@ package bdns
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/url"
 	"testing"
@@ -17,44 +18,47 @@ func TestError(t *testing.T) {
 		expected string
 	}{
 		{
-			&Error{dns.TypeA, "hostname", makeTimeoutError(), -1, nil},
+			&Error{recordType: dns.TypeA, hostname: "hostname", underlying: makeTimeoutError(), rCode: -1},
 			"DNS problem: query timed out looking up A for hostname",
 		}, {
-			&Error{dns.TypeMX, "hostname", &net.OpError{Err: errors.New("some net error")}, -1, nil},
+			&Error{recordType: dns.TypeMX, hostname: "hostname", underlying: &net.OpError{Err: errors.New("some net error")}, rCode: -1},
 			"DNS problem: networking error looking up MX for hostname",
 		}, {
-			&Error{dns.TypeTXT, "hostname", nil, dns.RcodeNameError, nil},
+			&Error{recordType: dns.TypeTXT, hostname: "hostname", rCode: dns.RcodeNameError},
 			"DNS problem: NXDOMAIN looking up TXT for hostname - check that a DNS record exists for this domain",
 		}, {
-			&Error{dns.TypeTXT, "hostname", context.DeadlineExceeded, -1, nil},
+			&Error{recordType: dns.TypeTXT, hostname: "hostname", underlying: context.DeadlineExceeded, rCode: -1},
 			"DNS problem: query timed out looking up TXT for hostname",
 		}, {
-			&Error{dns.TypeTXT, "hostname", context.Canceled, -1, nil},
+			&Error{recordType: dns.TypeTXT, hostname: "hostname", underlying: context.Canceled, rCode: -1},
 			"DNS problem: query timed out (and was canceled) looking up TXT for hostname",
 		}, {
-			&Error{dns.TypeCAA, "hostname", nil, dns.RcodeServerFailure, nil},
+			&Error{recordType: dns.TypeCAA, hostname: "hostname", rCode: dns.RcodeServerFailure},
 			"DNS problem: SERVFAIL looking up CAA for hostname - the domain's nameservers may be malfunctioning",
 		}, {
-			&Error{dns.TypeA, "hostname", nil, dns.RcodeServerFailure, &dns.EDNS0_EDE{InfoCode: 1, ExtraText: "oh no"}},
+			&Error{recordType: dns.TypeA, hostname: "hostname", rCode: dns.RcodeServerFailure, extended: &dns.EDNS0_EDE{InfoCode: 1, ExtraText: "oh no"}},
 			"DNS problem: looking up A for hostname: DNSSEC: Unsupported DNSKEY Algorithm: oh no",
 		}, {
-			&Error{dns.TypeA, "hostname", nil, dns.RcodeServerFailure, &dns.EDNS0_EDE{InfoCode: 6, ExtraText: ""}},
+			&Error{recordType: dns.TypeA, hostname: "hostname", rCode: dns.RcodeServerFailure, extended: &dns.EDNS0_EDE{InfoCode: 6, ExtraText: ""}},
 			"DNS problem: looking up A for hostname: DNSSEC: Bogus",
 		}, {
-			&Error{dns.TypeA, "hostname", nil, dns.RcodeServerFailure, &dns.EDNS0_EDE{InfoCode: 1337, ExtraText: "mysterious"}},
+			&Error{recordType: dns.TypeA, hostname: "hostname", rCode: dns.RcodeServerFailure, extended: &dns.EDNS0_EDE{InfoCode: 1337, ExtraText: "mysterious"}},
 			"DNS problem: looking up A for hostname: Unknown Extended DNS Error code 1337: mysterious",
 		}, {
-			&Error{dns.TypeCAA, "hostname", nil, dns.RcodeServerFailure, nil},
+			&Error{recordType: dns.TypeCAA, hostname: "hostname", rCode: dns.RcodeServerFailure},
 			"DNS problem: SERVFAIL looking up CAA for hostname - the domain's nameservers may be malfunctioning",
 		}, {
-			&Error{dns.TypeCAA, "hostname", nil, dns.RcodeServerFailure, nil},
+			&Error{recordType: dns.TypeCAA, hostname: "hostname", rCode: dns.RcodeServerFailure},
 			"DNS problem: SERVFAIL looking up CAA for hostname - the domain's nameservers may be malfunctioning",
 		}, {
-			&Error{dns.TypeA, "hostname", nil, dns.RcodeFormatError, nil},
+			&Error{recordType: dns.TypeA, hostname: "hostname", rCode: dns.RcodeFormatError},
 			"DNS problem: FORMERR looking up A for hostname",
 		}, {
-			&Error{dns.TypeA, "hostname", &url.Error{Op: "GET", URL: "https://example.com/", Err: dohTimeoutError{}}, -1, nil},
+			&Error{recordType: dns.TypeA, hostname: "hostname", underlying: &url.Error{Op: "GET", URL: "https://example.com/", Err: dohTimeoutError{}}, rCode: -1},
 			"DNS problem: query timed out looking up A for hostname",
+		}, {
+			&Error{recordType: dns.TypeAAAA, hostname: "hostname", rCode: dns.RcodeSuccess},
+			"DNS problem: no AAAA records exist for hostname",
 		},
 	}
 	for _, tc := range testCases {
@@ -75,18 +79,82 @@ func (dohTimeoutError) Timeout() bool {
 }
 
 func TestWrapErr(t *testing.T) {
-	err := wrapErr(dns.TypeA, "hostname", &dns.Msg{
+	err := wrapErr(dns.TypeA, "hostname", "", &dns.Msg{
 		MsgHdr: dns.MsgHdr{Rcode: dns.RcodeSuccess},
 	}, nil)
 	test.AssertNotError(t, err, "expected success")
 
-	err = wrapErr(dns.TypeA, "hostname", &dns.Msg{
+	err = wrapErr(dns.TypeA, "hostname", "", &dns.Msg{
 		MsgHdr: dns.MsgHdr{Rcode: dns.RcodeRefused},
 	}, nil)
 	test.AssertError(t, err, "expected error")
 
-	err = wrapErr(dns.TypeA, "hostname", &dns.Msg{
+	err = wrapErr(dns.TypeA, "hostname", "", &dns.Msg{
 		MsgHdr: dns.MsgHdr{Rcode: dns.RcodeSuccess},
 	}, errors.New("oh no"))
 	test.AssertError(t, err, "expected error")
 }
+
+func TestQueryDetails(t *testing.T) {
+	err := wrapErr(dns.TypeCAA, "example.com", "10.0.0.1:53", &dns.Msg{
+		MsgHdr: dns.MsgHdr{Rcode: dns.RcodeServerFailure},
+		Extra: []dns.RR{
+			&dns.OPT{
+				Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT},
+				Option: []dns.EDNS0{
+					&dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeDNSBogus},
+				},
+			},
+		},
+	}, nil)
+	var dnsErr Error
+	test.Assert(t, errors.As(err, &dnsErr), "expected an Error")
+	test.AssertDeepEquals(t, dnsErr.QueryDetails(), QueryDetails{
+		QueryName: "example.com",
+		QueryType: "CAA",
+		Resolver:  "10.0.0.1:53",
+		RCode:     "SERVFAIL",
+		EDE:       "DNSSEC: Bogus",
+	})
+
+	// A query which received no response has no RCode.
+	err = wrapErr(dns.TypeTXT, "example.com", "10.0.0.1:53", nil, errors.New("oh no"))
+	test.Assert(t, errors.As(err, &dnsErr), "expected an Error")
+	test.AssertDeepEquals(t, dnsErr.QueryDetails(), QueryDetails{
+		QueryName: "example.com",
+		QueryType: "TXT",
+		Resolver:  "10.0.0.1:53",
+	})
+}
+
+func TestIsNXDOMAINAndIsNoData(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expectNX     bool
+		expectNoData bool
+	}{
+		{"NXDOMAIN", wrapErr(dns.TypeA, "hostname", "", &dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError}}, nil), true, false},
+		{"NODATA", newNoDataError(dns.TypeA, "hostname", ""), false, true},
+		{"wrapped NODATA", fmt.Errorf("%w; other", newNoDataError(dns.TypeAAAA, "hostname", "")), false, true},
+		{"SERVFAIL", wrapErr(dns.TypeA, "hostname", "", &dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeServerFailure}}, nil), false, false},
+		{"no response", wrapErr(dns.TypeA, "hostname", "", nil, errors.New("oh no")), false, false},
+		{"not an Error", errors.New("no valid A records found for hostname"), false, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test.AssertEquals(t, IsNXDOMAIN(tc.err), tc.expectNX)
+			test.AssertEquals(t, IsNoData(tc.err), tc.expectNoData)
+		})
+	}
+
+	// A NODATA response is described by its NOERROR RCode.
+	var dnsErr Error
+	test.Assert(t, errors.As(newNoDataError(dns.TypeA, "example.com", "10.0.0.1:53"), &dnsErr), "expected an Error")
+	test.AssertDeepEquals(t, dnsErr.QueryDetails(), QueryDetails{
+		QueryName: "example.com",
+		QueryType: "A",
+		Resolver:  "10.0.0.1:53",
+		RCode:     "NOERROR",
+	})
+}
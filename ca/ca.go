@@ -297,7 +297,8 @@ var ocspStatusToCode = map[string]int{
 // IssuePrecertificate is the first step in the [issuance cycle]. It allocates and stores a serial number,
 // selects a certificate profile, generates and stores a linting certificate, sets the serial's status to
 // "wait", signs and stores a precertificate, updates the serial's status to "good", then returns the
-// precertificate.
+// precertificate. If the linting certificate fails any lints, no precertificate is signed, and the
+// response instead describes the failed lints.
 //
 // Subsequent final issuance based on this precertificate must happen at most once, and must use the same
 // certificate profile. The certificate profile is identified by a hash to ensure an exact match even if
@@ -344,6 +345,15 @@ func (ca *certificateAuthorityImpl) IssuePrecertificate(ctx context.Context, iss
 
 	precertDER, cpwid, err := ca.issuePrecertificateInner(ctx, issueReq, certProfile, serialBigInt, notBefore, notAfter)
 	if err != nil {
+		var lintErr *linter.Error
+		if errors.As(err, &lintErr) {
+			// No precertificate was signed. Describe the failed lints, so that
+			// the RA can tell the subscriber why.
+			return &capb.IssuePrecertificateResponse{
+				CertProfileName: certProfile.name,
+				LintFailures:    lintFailuresToPB(lintErr.Failures),
+			}, nil
+		}
 		return nil, err
 	}
 
@@ -359,6 +369,20 @@ func (ca *certificateAuthorityImpl) IssuePrecertificate(ctx context.Context, iss
 	}, nil
 }
 
+// lintFailuresToPB converts the provided lint failures into their protobuf
+// representation.
+func lintFailuresToPB(failures []linter.Failure) []*capb.LintFailure {
+	pbs := make([]*capb.LintFailure, 0, len(failures))
+	for _, f := range failures {
+		pbs = append(pbs, &capb.LintFailure{
+			Name:        f.Name,
+			Severity:    f.Status.String(),
+			Description: f.Details,
+		})
+	}
+	return pbs
+}
+
 // IssueCertificateForPrecertificate final step in the [issuance cycle].
 //
 // Given a precertificate and a set of SCTs for that precertificate, it generates
@@ -580,7 +604,7 @@ func (ca *certificateAuthorityImpl) issuePrecertificateInner(ctx context.Context
 		DNSNames:          names.SANs,
 		CommonName:        names.CN,
 		IncludeCTPoison:   true,
-		IncludeMustStaple: issuance.ContainsMustStaple(csr.Extensions),
+		IncludeMustStaple: issuance.ContainsMustStaple(csr.Extensions) && !issueReq.OmitMustStaple,
 		NotBefore:         notBefore,
 		NotAfter:          notAfter,
 	}
@@ -588,8 +612,12 @@ func (ca *certificateAuthorityImpl) issuePrecertificateInner(ctx context.Context
 	lintCertBytes, issuanceToken, err := issuer.Prepare(certProfile.profile, req)
 	if err != nil {
 		ca.log.AuditErrf("Preparing precert failed: serial=[%s] err=[%v]", serialHex, err)
-		if errors.Is(err, linter.ErrLinting) {
+		var lintErr *linter.Error
+		if errors.As(err, &lintErr) {
 			ca.metrics.lintErrorCount.Inc()
+			// Return the lint failures themselves, so that IssuePrecertificate
+			// can report them to the RA.
+			return nil, nil, lintErr
 		}
 		return nil, nil, berrors.InternalServerError("failed to prepare precertificate signing: %s", err)
 	}
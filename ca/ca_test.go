@@ -395,6 +395,38 @@ func issueCertificateSubTestIssuePrecertificate(t *testing.T, i *TestCertificate
 	}
 }
 
+func TestIssuePrecertificateLintFailures(t *testing.T) {
+	t.Parallel()
+	testCtx := setup(t)
+	// Without its ignored lints, the modern profile fails a lint because it
+	// omits the Subject Key Identifier. That lint is only effective for
+	// certificates issued after the fake clock's default time.
+	testCtx.certProfiles["modern"].IgnoredLints = nil
+	testCtx.fc.Set(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	ca, err := NewCertificateAuthorityImpl(
+		&mockSA{},
+		testCtx.pa,
+		testCtx.boulderIssuers,
+		testCtx.defaultCertProfileName,
+		testCtx.certProfiles,
+		testCtx.serialPrefix,
+		testCtx.maxNames,
+		testCtx.keyPolicy,
+		testCtx.logger,
+		testCtx.metrics,
+		testCtx.fc)
+	test.AssertNotError(t, err, "Failed to create CA")
+
+	resp, err := ca.IssuePrecertificate(ctx, &capb.IssueCertificateRequest{Csr: CNandSANCSR, RegistrationID: arbitraryRegID, CertProfileName: "modern"})
+	test.AssertNotError(t, err, "Lint failures should be reported in the response")
+	test.AssertEquals(t, len(resp.DER), 0)
+	test.AssertEquals(t, resp.CertProfileName, "modern")
+	test.AssertEquals(t, len(resp.LintFailures), 1)
+	test.AssertEquals(t, resp.LintFailures[0].Name, "w_ext_subject_key_identifier_missing_sub_cert")
+	test.AssertEquals(t, resp.LintFailures[0].Severity, "warn")
+	test.AssertMetricWithLabelsEquals(t, testCtx.metrics.lintErrorCount, prometheus.Labels{}, 1)
+}
+
 // Test failure mode when no issuers are present.
 func TestNoIssuers(t *testing.T) {
 	t.Parallel()
@@ -791,6 +823,21 @@ func issueCertificateSubTestMustStaple(t *testing.T, i *TestCertificateIssuance)
 	test.AssertEquals(t, countMustStaple(t, i.cert), 1)
 }
 
+func TestIssuePrecertificateOmitMustStaple(t *testing.T) {
+	t.Parallel()
+	ca, _ := issueCertificateSubTestSetup(t)
+
+	response, err := ca.IssuePrecertificate(ctx, &capb.IssueCertificateRequest{
+		Csr:            MustStapleCSR,
+		RegistrationID: arbitraryRegID,
+		OmitMustStaple: true,
+	})
+	test.AssertNotError(t, err, "Failed to issue precertificate")
+	cert, err := x509.ParseCertificate(response.DER)
+	test.AssertNotError(t, err, "Certificate failed to parse")
+	test.AssertEquals(t, countMustStaple(t, cert), 0)
+}
+
 func issueCertificateSubTestUnknownExtension(t *testing.T, i *TestCertificateIssuance) {
 	test.AssertMetricWithLabelsEquals(t, i.ca.metrics.signatureCount, prometheus.Labels{"purpose": "precertificate"}, 1)
 
@@ -13,6 +13,8 @@ import (
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	blog "github.com/letsencrypt/boulder/log"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/ratelimits"
+	bredis "github.com/letsencrypt/boulder/redis"
 	"github.com/letsencrypt/boulder/sa"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
@@ -26,6 +28,8 @@ type admin struct {
 	// TODO: Remove this and only use sac and saroc to interact with the db.
 	// We cannot have true dry-run safety as long as we have a direct dbMap.
 	dbMap *db.WrappedMap
+	// tats is nil unless a rate limit Redis is configured.
+	tats ratelimits.TATSnapshotter
 
 	// TODO: Remove this when the dbMap is removed and the dryRunSAC and dryRunRAC
 	// handle all dry-run safety.
@@ -85,11 +89,24 @@ func newAdmin(configFile string, dryRun bool) (*admin, error) {
 		return nil, fmt.Errorf("creating database connection: %w", err)
 	}
 
+	var tats ratelimits.TATSnapshotter
+	if c.Admin.Limiter.Redis != nil {
+		ring, err := bredis.NewRingFromConfig(*c.Admin.Limiter.Redis, scope, logger)
+		if err != nil {
+			return nil, fmt.Errorf("creating rate limit Redis client: %w", err)
+		}
+		tats = ratelimits.NewRedisSource(ring.Ring, clk, scope)
+		if dryRun {
+			tats = dryRunTATs{TATSnapshotter: tats, clk: clk, log: logger}
+		}
+	}
+
 	return &admin{
 		rac:    rac,
 		sac:    sac,
 		saroc:  saroc,
 		dbMap:  dbMap,
+		tats:   tats,
 		dryRun: dryRun,
 		clk:    clk,
 		log:    logger,
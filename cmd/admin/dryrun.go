@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"time"
+
+	"github.com/jmhodges/clock"
 
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/encoding/prototext"
@@ -9,6 +12,7 @@ import (
 
 	blog "github.com/letsencrypt/boulder/log"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/ratelimits"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
 
@@ -39,3 +43,20 @@ func (d dryRunSAC) AddBlockedKey(_ context.Context, req *sapb.AddBlockedKeyReque
 	d.log.Infof("dry-run: %#v", string(b))
 	return &emptypb.Empty{}, nil
 }
+
+type dryRunTATs struct {
+	ratelimits.TATSnapshotter
+	clk clock.Clock
+	log blog.Logger
+}
+
+// ImportTATs imports the batch into an empty in-memory source instead, which
+// verifies its checksum and counts the buckets which haven't refilled.
+func (d dryRunTATs) ImportTATs(ctx context.Context, batch ratelimits.TATBatch, now time.Time) (int, error) {
+	n, err := ratelimits.NewInmemSource(d.clk).ImportTATs(ctx, batch, now)
+	if err != nil {
+		return 0, err
+	}
+	d.log.Infof("dry-run: import %d of a batch of %d rate limit buckets", n, len(batch.Entries))
+	return n, nil
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/features"
+	bredis "github.com/letsencrypt/boulder/redis"
 )
 
 type Config struct {
@@ -31,6 +32,13 @@ type Config struct {
 		RAService *cmd.GRPCClientConfig
 		SAService *cmd.GRPCClientConfig
 
+		// Limiter controls the admin tool's connection to the Redis database
+		// which holds rate limit buckets. It's only needed to export and import
+		// them.
+		Limiter struct {
+			Redis *bredis.Config
+		}
+
 		// Deprecated: DebugAddr is no longer used.
 		DebugAddr string
 
@@ -71,11 +79,13 @@ func main() {
 
 	// This is the registry of all subcommands that the admin tool can run.
 	subcommands := map[string]subcommand{
-		"revoke-cert":      &subcommandRevokeCert{},
-		"block-key":        &subcommandBlockKey{},
-		"update-email":     &subcommandUpdateEmail{},
-		"pause-identifier": &subcommandPauseIdentifier{},
-		"unpause-account":  &subcommandUnpauseAccount{},
+		"revoke-cert":       &subcommandRevokeCert{},
+		"block-key":         &subcommandBlockKey{},
+		"update-email":      &subcommandUpdateEmail{},
+		"pause-identifier":  &subcommandPauseIdentifier{},
+		"unpause-account":   &subcommandUnpauseAccount{},
+		"export-ratelimits": &subcommandExportRateLimits{},
+		"import-ratelimits": &subcommandImportRateLimits{},
 	}
 
 	defaultUsage := flag.Usage
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/letsencrypt/boulder/ratelimits"
+)
+
+// subcommandExportRateLimits encapsulates the "admin export-ratelimits"
+// command.
+type subcommandExportRateLimits struct {
+	file      string
+	cursor    string
+	batchSize int
+}
+
+var _ subcommand = (*subcommandExportRateLimits)(nil)
+
+func (s *subcommandExportRateLimits) Desc() string {
+	return "Export the TATs of the rate limit buckets in Redis to a file, so that they can be restored if Redis is lost"
+}
+
+func (s *subcommandExportRateLimits) Flags(flag *flag.FlagSet) {
+	flag.StringVar(&s.file, "file", "", "Path to the file to write the exported buckets to, one JSON encoded batch per line")
+	flag.StringVar(&s.cursor, "cursor", "", "Resume an interrupted export, appending to -file, from the cursor of the last batch it contains")
+	flag.IntVar(&s.batchSize, "batch-size", 1000, "The approximate number of buckets to export in each batch")
+}
+
+func (s *subcommandExportRateLimits) Run(ctx context.Context, a *admin) error {
+	if s.file == "" {
+		return errors.New("the -file flag is required")
+	}
+	if a.tats == nil {
+		return errors.New("no rate limit Redis is configured")
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if s.cursor != "" {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(s.file, flags, 0600)
+	if err != nil {
+		return fmt.Errorf("opening export file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = a.exportTATs(ctx, f, s.cursor, s.batchSize)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// exportTATs writes each batch of bucket TATs exported from the rate limit
+// source to w as a line of JSON, starting at cursor, until the export is
+// complete. It returns the number of buckets exported.
+func (a *admin) exportTATs(ctx context.Context, w io.Writer, cursor string, batchSize int) (int, error) {
+	enc := json.NewEncoder(w)
+	var exported int
+	for {
+		batch, err := a.tats.ExportTATs(ctx, cursor, batchSize)
+		if err != nil {
+			return exported, fmt.Errorf("exporting rate limit buckets after cursor %q: %w", cursor, err)
+		}
+		if len(batch.Entries) > 0 {
+			err = enc.Encode(batch)
+			if err != nil {
+				return exported, fmt.Errorf("writing rate limit buckets after cursor %q: %w", cursor, err)
+			}
+			exported += len(batch.Entries)
+		}
+		cursor = batch.Cursor
+		if cursor == "" {
+			break
+		}
+	}
+	a.log.Infof("Exported %d rate limit buckets", exported)
+	return exported, nil
+}
+
+// subcommandImportRateLimits encapsulates the "admin import-ratelimits"
+// command.
+type subcommandImportRateLimits struct {
+	file string
+}
+
+var _ subcommand = (*subcommandImportRateLimits)(nil)
+
+func (s *subcommandImportRateLimits) Desc() string {
+	return "Restore the TATs of rate limit buckets to Redis from a file written by export-ratelimits"
+}
+
+func (s *subcommandImportRateLimits) Flags(flag *flag.FlagSet) {
+	flag.StringVar(&s.file, "file", "", "Path to a file written by export-ratelimits")
+}
+
+func (s *subcommandImportRateLimits) Run(ctx context.Context, a *admin) error {
+	if s.file == "" {
+		return errors.New("the -file flag is required")
+	}
+	if a.tats == nil {
+		return errors.New("no rate limit Redis is configured")
+	}
+
+	f, err := os.Open(s.file)
+	if err != nil {
+		return fmt.Errorf("opening import file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = a.importTATs(ctx, f)
+	return err
+}
+
+// importTATs restores each batch of bucket TATs read from r to the rate limit
+// source. Buckets which have fully refilled since they were exported are
+// skipped. It returns the number of buckets restored.
+func (a *admin) importTATs(ctx context.Context, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	var imported, batches int
+	for {
+		var batch ratelimits.TATBatch
+		err := dec.Decode(&batch)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("parsing batch %d of rate limit buckets: %w", batches+1, err)
+		}
+		batches++
+		n, err := a.tats.ImportTATs(ctx, batch, a.clk.Now())
+		if err != nil {
+			return imported, fmt.Errorf("importing batch %d of rate limit buckets: %w", batches, err)
+		}
+		imported += n
+	}
+	a.log.Infof("Imported %d rate limit buckets from %d batches", imported, batches)
+	return imported, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/ratelimits"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestExportImportTATs(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	clk.Set(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	now := clk.Now()
+
+	src := ratelimits.NewInmemSource(clk)
+	err := src.BatchSet(context.Background(), map[string]time.Time{
+		"1:10.0.0.1":  now.Add(time.Hour),
+		"1:10.0.0.2":  now.Add(2 * time.Hour),
+		"3:12345":     now.Add(time.Minute),
+		"4:12345:foo": now.Add(time.Second),
+		"4:12345:bar": now.Add(-time.Minute),
+	})
+	test.AssertNotError(t, err, "setting buckets")
+
+	// Export in batches smaller than the number of buckets, so the cursor is
+	// followed.
+	var snapshot bytes.Buffer
+	exported, err := (&admin{tats: src, clk: clk, log: blog.NewMock()}).exportTATs(context.Background(), &snapshot, "", 2)
+	test.AssertNotError(t, err, "exporting buckets")
+	test.AssertEquals(t, exported, 5)
+	test.AssertEquals(t, strings.Count(snapshot.String(), "\n"), 3)
+
+	// A dry run reports what would be imported without writing anything.
+	clk.Add(30 * time.Second)
+	dst := ratelimits.NewInmemSource(clk)
+	log := blog.NewMock()
+	dryRun := &admin{tats: dryRunTATs{TATSnapshotter: dst, clk: clk, log: log}, clk: clk, log: log}
+	imported, err := dryRun.importTATs(context.Background(), bytes.NewReader(snapshot.Bytes()))
+	test.AssertNotError(t, err, "dry-run importing buckets")
+	test.AssertEquals(t, imported, 3)
+	test.AssertEquals(t, len(log.GetAllMatching("dry-run: import")), 3)
+	_, err = dst.Get(context.Background(), "1:10.0.0.1")
+	test.AssertErrorIs(t, err, ratelimits.ErrBucketNotFound)
+
+	// The buckets which have refilled since the export aren't imported.
+	imported, err = (&admin{tats: dst, clk: clk, log: blog.NewMock()}).importTATs(context.Background(), bytes.NewReader(snapshot.Bytes()))
+	test.AssertNotError(t, err, "importing buckets")
+	test.AssertEquals(t, imported, 3)
+	for bucketKey, expected := range map[string]time.Time{
+		"1:10.0.0.1": now.Add(time.Hour),
+		"1:10.0.0.2": now.Add(2 * time.Hour),
+		"3:12345":    now.Add(time.Minute),
+	} {
+		tat, err := dst.Get(context.Background(), bucketKey)
+		test.AssertNotError(t, err, "getting imported bucket")
+		test.AssertEquals(t, tat, expected)
+	}
+	_, err = dst.Get(context.Background(), "4:12345:foo")
+	test.AssertErrorIs(t, err, ratelimits.ErrBucketNotFound)
+
+	// A corrupted snapshot is rejected.
+	corrupted := strings.Replace(snapshot.String(), "10.0.0.2", "10.0.0.3", 1)
+	_, err = (&admin{tats: dst, clk: clk, log: blog.NewMock()}).importTATs(context.Background(), strings.NewReader(corrupted))
+	test.AssertErrorIs(t, err, ratelimits.ErrChecksumMismatch)
+}
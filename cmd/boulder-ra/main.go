@@ -2,13 +2,17 @@ package notmain
 
 import (
 	"context"
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/zmap/zlint/v3/lint"
+
 	akamaipb "github.com/letsencrypt/boulder/akamai/proto"
 	"github.com/letsencrypt/boulder/allowlist"
+	"github.com/letsencrypt/boulder/bdns"
 	capb "github.com/letsencrypt/boulder/ca/proto"
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/config"
@@ -19,7 +23,9 @@ import (
 	"github.com/letsencrypt/boulder/goodkey"
 	"github.com/letsencrypt/boulder/goodkey/sagoodkey"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/identifier"
 	"github.com/letsencrypt/boulder/issuance"
+	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/policy"
 	pubpb "github.com/letsencrypt/boulder/publisher/proto"
 	"github.com/letsencrypt/boulder/ra"
@@ -50,9 +56,15 @@ type Config struct {
 
 		Limiter struct {
 			// Redis contains the configuration necessary to connect to Redis
-			// for rate limiting. This field is required to enable rate
-			// limiting.
-			Redis *bredis.Config `validate:"required_with=Defaults"`
+			// for rate limiting. Either this field or Inmem is required to
+			// enable rate limiting.
+			Redis *bredis.Config `validate:"excluded_with=Inmem"`
+
+			// Inmem, if set, stores rate limit buckets in memory instead of
+			// in Redis, for deployments with a single RA. Buckets aren't
+			// shared with the WFE, so limits spent by one aren't seen by the
+			// other.
+			Inmem *ratelimits.InmemConfig `validate:"excluded_with=Redis"`
 
 			// Defaults is a path to a YAML file containing default rate limits.
 			// See: ratelimits/README.md for details. This field is required to
@@ -62,7 +74,7 @@ type Config struct {
 			//
 			// Note: At this time, only the Failed Authorizations rate limit is
 			// necessary in the RA.
-			Defaults string `validate:"required_with=Redis"`
+			Defaults string `validate:"required_with=Redis Inmem"`
 
 			// Overrides is a path to a YAML file containing overrides for the
 			// default rate limits. See: ratelimits/README.md for details. If
@@ -73,6 +85,37 @@ type Config struct {
 			// Note: At this time, only the Failed Authorizations overrides are
 			// necessary in the RA.
 			Overrides string
+
+			// ReloadInterval, if set, is how often the Defaults and Overrides
+			// files are reloaded, along with the mode of each limit. If they
+			// fail to load, the error is logged and the previous limits are
+			// kept.
+			ReloadInterval config.Duration `validate:"-"`
+
+			// TrackOverrideUtilization, if set, exports metrics under the
+			// ratelimits_override namespace counting the spends against and
+			// denials by each override, and the fraction of its burst last
+			// remaining, so that dead or undersized overrides can be found.
+			TrackOverrideUtilization bool
+
+			// DenialStreakPauseThreshold, if set, is how long an account's new
+			// orders must have been continuously denied by the
+			// FailedAuthorizationsPerDomainPerAccount limit, as recorded by
+			// WFEs with RecordDenialStreaks set, before the RA proposes pausing
+			// it. Proposals are only logged and counted. A validation which
+			// refunds the limit ends the account's streak.
+			DenialStreakPauseThreshold config.Duration `validate:"-"`
+
+			// OverrideCaps, keyed by limit name, replace the default caps on
+			// the burst and count of overrides of each limit, which exist to
+			// catch typos. A zero MaxBurst or MaxCount is uncapped. Overrides
+			// above their cap are clamped to it, with a warning logged.
+			// Default limits are never capped.
+			OverrideCaps map[string]ratelimits.OverrideCap `validate:"omitempty,dive"`
+
+			// StrictOverrideCaps, if set, causes overrides above their cap to
+			// fail to load rather than being clamped.
+			StrictOverrideCaps bool
 		}
 
 		// MaxNames is the maximum number of subjectAltNames in a single cert.
@@ -86,12 +129,27 @@ type Config struct {
 		// considered valid for. Given a value of 300 days when used with a 90-day
 		// cert lifetime, this allows creation of certs that will cover a whole
 		// year, plus a grace period of a month.
-		AuthorizationLifetimeDays int `validate:"required,min=1,max=397"`
+		//
+		// Deprecated: Use AuthorizationLifetime instead.
+		AuthorizationLifetimeDays int `validate:"omitempty,min=1,max=397"`
+
+		// AuthorizationLifetime defines how long authorizations will be
+		// considered valid for. It takes precedence over
+		// AuthorizationLifetimeDays. If neither is set, it defaults to 30 days.
+		AuthorizationLifetime config.Duration `validate:"-"`
 
 		// PendingAuthorizationLifetimeDays defines how long authorizations may be in
 		// the pending state. If you can't respond to a challenge this quickly, then
 		// you need to request a new challenge.
-		PendingAuthorizationLifetimeDays int `validate:"required,min=1,max=29"`
+		//
+		// Deprecated: Use PendingAuthorizationLifetime instead.
+		PendingAuthorizationLifetimeDays int `validate:"omitempty,min=1,max=29"`
+
+		// PendingAuthorizationLifetime defines how long authorizations may be
+		// in the pending state. It takes precedence over
+		// PendingAuthorizationLifetimeDays. If neither is set, it defaults to 7
+		// days. It must be less than the authorization lifetime.
+		PendingAuthorizationLifetime config.Duration `validate:"-"`
 
 		// ValidationProfiles is a map of validation profiles to their
 		// respective issuance allow lists. If a profile is not included in this
@@ -103,8 +161,38 @@ type Config struct {
 			// specified, the profile is open to all accounts. If the file
 			// exists but is empty, the profile is closed to all accounts.
 			AllowList string `validate:"omitempty"`
+
+			// MustStaplePolicy determines how CSRs which request the OCSP
+			// must-staple extension are handled under this profile: "allow"
+			// passes the extension through, subject to MustStapleAllowList,
+			// "strip" issues the certificate without it, and "reject" fails
+			// the order with a badCSR problem. If omitted, it is "allow".
+			MustStaplePolicy string `validate:"omitempty,oneof=allow strip reject"`
+
+			// Validity is the validity period of certificates issued under
+			// this profile, as advertised by GetIssuanceProfiles. It should
+			// match the CA's certificate profile of the same name. If omitted,
+			// it isn't advertised.
+			Validity config.Duration `validate:"-"`
+
+			// IdentifierTypes are the types of identifier, "dns" or "ip",
+			// which orders using this profile may contain, as advertised by
+			// GetIssuanceProfiles. If omitted, they aren't advertised.
+			IdentifierTypes []identifier.IdentifierType `validate:"omitempty,dive,oneof=dns ip"`
 		}
 
+		// DefaultProfileName is the name of the validation profile which the
+		// CA uses for orders which don't request one, as advertised by
+		// GetIssuanceProfiles. It should match the CA's
+		// DefaultCertificateProfileName.
+		DefaultProfileName string `validate:"omitempty"`
+
+		// ProfilesReloadInterval, if set, is how often ValidationProfiles,
+		// their allow lists, and DefaultProfileName are reloaded from this
+		// configuration file. If they fail to load, the error is logged and
+		// the previous profiles are kept.
+		ProfilesReloadInterval config.Duration `validate:"-"`
+
 		// MustStapleAllowList specifies the path to a YAML file containing a
 		// list of account IDs permitted to request certificates with the OCSP
 		// Must-Staple extension. If no path is specified, the extension is
@@ -112,12 +200,110 @@ type Config struct {
 		// extension is disabled for all accounts.
 		MustStapleAllowList string `validate:"omitempty"`
 
+		// Admins is the allowlist of administrators, as identified by the
+		// adminName of AdministrativelyRevokeCertificate requests, mapped to
+		// their capabilities. Requests from admins not in the allowlist are
+		// rejected. If this field is left empty, any admin may revoke with any
+		// reason and without a cap.
+		Admins map[string]struct {
+			// MaySkipBlockKey permits revoking for keyCompromise without
+			// blocking the certificate's key.
+			MaySkipBlockKey bool
+
+			// MayRevokeKeyCompromise permits revoking with reason
+			// keyCompromise.
+			MayRevokeKeyCompromise bool
+
+			// MaxRevocationsPerHour is the number of revocations the admin may
+			// request in any hour from each RA instance. Zero means there is
+			// no cap.
+			MaxRevocationsPerHour int `validate:"min=0"`
+		} `validate:"omitempty"`
+
+		// ContactDomainCheck configures checking that the domains of contact
+		// email addresses have MX or A/AAAA records. If omitted, contact
+		// domains are not checked.
+		ContactDomainCheck *struct {
+			// DNSProvider and DNSStaticResolvers are mutually exclusive ways
+			// of specifying the DNS resolvers to use, as for the VA.
+			DNSProvider        *cmd.DNSProvider `validate:"required_without=DNSStaticResolvers"`
+			DNSStaticResolvers []string         `validate:"required_without=DNSProvider,dive,hostname_port"`
+			DNSTimeout         config.Duration  `validate:"required"`
+
+			// HardReject rejects contacts whose domains could not be checked,
+			// for instance because of a DNS timeout. By default such contacts
+			// are accepted and audit logged as unverified. Contacts whose
+			// domains do not exist are always rejected.
+			HardReject bool
+		} `validate:"omitempty"`
+
+		// ExternalAccountBinding configures requiring new accounts to include
+		// an External Account Binding (RFC 8555, Section 7.3.4). If omitted,
+		// no binding is required.
+		ExternalAccountBinding *struct {
+			// Keys maps each EAB key identifier to a file containing its
+			// base64url-encoded MAC key. Required unless StoredKeys is set.
+			Keys map[string]cmd.PasswordConfig `validate:"required_without=StoredKeys,excluded_with=StoredKeys"`
+
+			// StoredKeys causes MAC keys to be looked up in the SA's
+			// externalAccountKeys table, instead of in Keys, so that keys
+			// can be provisioned without restarting the RA.
+			StoredKeys bool
+
+			// OneAccountPerKeyID rejects new accounts whose binding uses a
+			// key identifier which has already been used to create an
+			// account.
+			OneAccountPerKeyID bool
+		} `validate:"omitempty"`
+
+		// LintProblemSeverity is the least severe lint failure, reported by
+		// the CA at finalize, which fails the order with a badCSR problem
+		// naming the failed lints. Less severe failures are only logged. One
+		// of "info", "warn", "error" or "fatal". If omitted, every failure is
+		// reported to the subscriber.
+		LintProblemSeverity string `validate:"omitempty,oneof=info warn error fatal"`
+
+		// ValidationPolicyHints maps VA policy hint keys to the accounts
+		// whose validation and CAA requests carry them, so that variants of
+		// VA behavior can be rolled out gradually. Each hint is sent to the
+		// listed AccountIDs, and to the given Percent of all accounts. The VA
+		// ignores keys it doesn't recognize.
+		ValidationPolicyHints map[string]struct {
+			Value      string  `validate:"required"`
+			AccountIDs []int64 `validate:"omitempty,dive,min=1"`
+			Percent    int     `validate:"min=0,max=100"`
+		} `validate:"omitempty,dive"`
+
+		// MaxPendingAuthorizationsPerAccount is the most pending
+		// authorizations an account may hold. Once a new order is stored,
+		// the account's soonest-expiring pending authorizations which no
+		// unexpired order references are deactivated to make room for its
+		// authorizations, and the order is rejected if there aren't enough.
+		// If unset, there is no limit.
+		MaxPendingAuthorizationsPerAccount int `validate:"min=0"`
+
+		// ValidationWorkers is the number of challenge validations the RA
+		// performs at once. If unset, it defaults to 500.
+		ValidationWorkers int `validate:"min=0"`
+
+		// ValidationQueueSize is the number of challenge validations which may
+		// wait for a worker. Further validations are refused until the queue
+		// has room. If unset, it defaults to 5000.
+		ValidationQueueSize int `validate:"min=0"`
+
+		// AbandonedOrdersReportInterval, if set, is how often this RA counts
+		// the orders which have expired without being finalized. It should be
+		// set on only one RA, since each RA which reports counts every order,
+		// and requires the SA's ExpiredOrdersIndex feature.
+		AbandonedOrdersReportInterval config.Duration `validate:"-"`
+
 		// GoodKey is an embedded config stanza for the goodkey library.
 		GoodKey goodkey.Config
 
 		// OrderLifetime is how far in the future an Order's expiration date should
-		// be set when it is first created.
-		OrderLifetime config.Duration
+		// be set when it is first created. If unset, it defaults to 7 days. It
+		// must not exceed the authorization lifetime.
+		OrderLifetime config.Duration `validate:"-"`
 
 		// FinalizeTimeout is how long the RA is willing to wait for the Order
 		// finalization process to take. This config parameter only has an effect
@@ -255,39 +441,31 @@ func main() {
 
 	ctp = ctpolicy.New(pubc, sctLogs, infoLogs, finalLogs, c.RA.CTLogs.Stagger.Duration, logger, scope)
 
-	// Baseline Requirements v1.8.1 section 4.2.1: "any reused data, document,
-	// or completed validation MUST be obtained no more than 398 days prior
-	// to issuing the Certificate". If unconfigured or the configured value is
-	// greater than 397 days, bail out.
-	if c.RA.AuthorizationLifetimeDays <= 0 || c.RA.AuthorizationLifetimeDays > 397 {
-		cmd.Fail("authorizationLifetimeDays value must be greater than 0 and less than 398")
+	authorizationLifetime := ra.DefaultAuthorizationLifetime
+	if c.RA.AuthorizationLifetime.Duration != 0 {
+		authorizationLifetime = c.RA.AuthorizationLifetime.Duration
+	} else if c.RA.AuthorizationLifetimeDays != 0 {
+		authorizationLifetime = time.Duration(c.RA.AuthorizationLifetimeDays) * 24 * time.Hour
 	}
-	authorizationLifetime := time.Duration(c.RA.AuthorizationLifetimeDays) * 24 * time.Hour
-
-	// The Baseline Requirements v1.8.1 state that validation tokens "MUST
-	// NOT be used for more than 30 days from its creation". If unconfigured
-	// or the configured value pendingAuthorizationLifetimeDays is greater
-	// than 29 days, bail out.
-	if c.RA.PendingAuthorizationLifetimeDays <= 0 || c.RA.PendingAuthorizationLifetimeDays > 29 {
-		cmd.Fail("pendingAuthorizationLifetimeDays value must be greater than 0 and less than 30")
+
+	pendingAuthorizationLifetime := ra.DefaultPendingAuthorizationLifetime
+	if c.RA.PendingAuthorizationLifetime.Duration != 0 {
+		pendingAuthorizationLifetime = c.RA.PendingAuthorizationLifetime.Duration
+	} else if c.RA.PendingAuthorizationLifetimeDays != 0 {
+		pendingAuthorizationLifetime = time.Duration(c.RA.PendingAuthorizationLifetimeDays) * 24 * time.Hour
 	}
-	pendingAuthorizationLifetime := time.Duration(c.RA.PendingAuthorizationLifetimeDays) * 24 * time.Hour
-
-	var validationProfiles map[string]*ra.ValidationProfile
-	if c.RA.ValidationProfiles != nil {
-		validationProfiles = make(map[string]*ra.ValidationProfile)
-		for profileName, v := range c.RA.ValidationProfiles {
-			var allowList *allowlist.List[int64]
-			if v.AllowList != "" {
-				data, err := os.ReadFile(v.AllowList)
-				cmd.FailOnError(err, fmt.Sprintf("Failed to read allow list for profile %q", profileName))
-				allowList, err = allowlist.NewFromYAML[int64](data)
-				cmd.FailOnError(err, fmt.Sprintf("Failed to parse allow list for profile %q", profileName))
-			}
-			validationProfiles[profileName] = ra.NewValidationProfile(allowList)
-		}
+
+	orderLifetime := ra.DefaultOrderLifetime
+	if c.RA.OrderLifetime.Duration != 0 {
+		orderLifetime = c.RA.OrderLifetime.Duration
 	}
 
+	err = ra.ValidateLifetimes(authorizationLifetime, pendingAuthorizationLifetime, orderLifetime)
+	cmd.FailOnError(err, "Invalid authorization or order lifetime")
+
+	validationProfiles, err := loadValidationProfiles(&c)
+	cmd.FailOnError(err, "Failed to load validation profiles")
+
 	var mustStapleAllowList *allowlist.List[int64]
 	if c.RA.MustStapleAllowList != "" {
 		data, err := os.ReadFile(c.RA.MustStapleAllowList)
@@ -296,6 +474,18 @@ func main() {
 		cmd.FailOnError(err, "Failed to parse allow list for Must-Staple extension")
 	}
 
+	var admins map[string]*ra.AdminPolicy
+	if len(c.RA.Admins) > 0 {
+		admins = make(map[string]*ra.AdminPolicy)
+		for name, a := range c.RA.Admins {
+			admins[name] = &ra.AdminPolicy{
+				MaySkipBlockKey:        a.MaySkipBlockKey,
+				MayRevokeKeyCompromise: a.MayRevokeKeyCompromise,
+				MaxRevocationsPerHour:  a.MaxRevocationsPerHour,
+			}
+		}
+	}
+
 	if features.Get().AsyncFinalize && c.RA.FinalizeTimeout.Duration == 0 {
 		cmd.Fail("finalizeTimeout must be supplied when AsyncFinalize feature is enabled")
 	}
@@ -312,14 +502,42 @@ func main() {
 	var limiterRedis *bredis.Ring
 	if c.RA.Limiter.Defaults != "" {
 		// Setup rate limiting.
-		limiterRedis, err = bredis.NewRingFromConfig(*c.RA.Limiter.Redis, scope, logger)
-		cmd.FailOnError(err, "Failed to create Redis ring")
-
-		source := ratelimits.NewRedisSource(limiterRedis.Ring, clk, scope)
-		limiter, err = ratelimits.NewLimiter(clk, source, scope)
+		var source ratelimits.Source
+		if c.RA.Limiter.Inmem != nil {
+			var inmemSource *ratelimits.InmemSource
+			inmemSource, err = ratelimits.NewInmemSourceFromConfig(*c.RA.Limiter.Inmem, clk, scope)
+			cmd.FailOnError(err, "Failed to create in-memory rate limit source")
+			if c.RA.Limiter.Inmem.SnapshotFile != "" {
+				go inmemSource.SnapshotEvery(context.Background(), c.RA.Limiter.Inmem.SnapshotFile,
+					c.RA.Limiter.Inmem.SnapshotInterval.Duration, logger)
+			}
+			source = inmemSource
+		} else {
+			if c.RA.Limiter.Redis == nil {
+				cmd.Fail("Either Redis or Inmem must be configured to enable rate limiting")
+			}
+			limiterRedis, err = bredis.NewRingFromConfig(*c.RA.Limiter.Redis, scope, logger)
+			cmd.FailOnError(err, "Failed to create Redis ring")
+			source = ratelimits.NewRedisSource(limiterRedis.Ring, clk, scope)
+		}
+		limiter, err = ratelimits.NewLimiter(clk, source, scope, logger)
 		cmd.FailOnError(err, "Failed to create rate limiter")
+		if c.RA.Limiter.DenialStreakPauseThreshold.Duration > 0 {
+			err = limiter.EnableDenialStreaks()
+			cmd.FailOnError(err, "Failed to enable denial streaks")
+		}
 		txnBuilder, err = ratelimits.NewTransactionBuilderFromFiles(c.RA.Limiter.Defaults, c.RA.Limiter.Overrides)
 		cmd.FailOnError(err, "Failed to create rate limits transaction builder")
+		ratelimits.RegisterNormalizationMetrics(scope)
+		err = txnBuilder.EnforceOverrideCaps(c.RA.Limiter.OverrideCaps, c.RA.Limiter.StrictOverrideCaps, scope, logger)
+		cmd.FailOnError(err, "Failed to apply rate limit override caps")
+		if c.RA.Limiter.TrackOverrideUtilization {
+			limiter.EnableOverrideUtilization(txnBuilder, scope)
+		}
+		if c.RA.Limiter.ReloadInterval.Duration > 0 {
+			go txnBuilder.ReloadFromFilesEvery(context.Background(), c.RA.Limiter.Defaults, c.RA.Limiter.Overrides,
+				c.RA.Limiter.ReloadInterval.Duration, logger)
+		}
 	}
 
 	rai := ra.NewRegistrationAuthorityImpl(
@@ -336,16 +554,77 @@ func main() {
 		validationProfiles,
 		mustStapleAllowList,
 		pubc,
-		c.RA.OrderLifetime.Duration,
+		orderLifetime,
 		c.RA.FinalizeTimeout.Duration,
 		ctp,
 		apc,
 		issuerCerts,
+		admins,
+		c.RA.ValidationWorkers,
+		c.RA.ValidationQueueSize,
 	)
 	defer rai.Drain()
 
+	rai.SetValidationProfiles(validationProfiles, c.RA.DefaultProfileName)
+	if c.RA.ProfilesReloadInterval.Duration > 0 {
+		go reloadValidationProfilesEvery(context.Background(), *configFile, c.RA.ProfilesReloadInterval.Duration, rai, logger)
+	}
+
 	rai.PA = pa
 
+	if c.RA.ContactDomainCheck != nil {
+		var servers bdns.ServerProvider
+		proto := "udp"
+		if features.Get().DOH {
+			proto = "tcp"
+		}
+		if len(c.RA.ContactDomainCheck.DNSStaticResolvers) != 0 {
+			servers, err = bdns.NewStaticProvider(c.RA.ContactDomainCheck.DNSStaticResolvers)
+			cmd.FailOnError(err, "Couldn't start static DNS server resolver")
+		} else {
+			servers, err = bdns.StartDynamicProvider(c.RA.ContactDomainCheck.DNSProvider, 60*time.Second, proto)
+			cmd.FailOnError(err, "Couldn't start dynamic DNS server resolver")
+		}
+		defer servers.Stop()
+
+		resolver := bdns.New(c.RA.ContactDomainCheck.DNSTimeout.Duration, servers, scope, clk, 1, 0, logger, tlsConfig)
+		rai.ContactDomains = ra.NewContactDomainChecker(resolver, c.RA.ContactDomainCheck.HardReject, clk, logger, scope)
+	}
+
+	if c.RA.ExternalAccountBinding != nil {
+		var keys ra.EABKeySource
+		if c.RA.ExternalAccountBinding.StoredKeys {
+			keys = ra.NewSAEABKeys(sac)
+		} else {
+			staticKeys := make(ra.StaticEABKeys)
+			for keyID, pc := range c.RA.ExternalAccountBinding.Keys {
+				encoded, err := pc.Pass()
+				cmd.FailOnError(err, fmt.Sprintf("Failed to read MAC key for EAB key %q", keyID))
+				staticKeys[keyID], err = base64.RawURLEncoding.DecodeString(encoded)
+				cmd.FailOnError(err, fmt.Sprintf("Failed to decode MAC key for EAB key %q", keyID))
+			}
+			keys = staticKeys
+		}
+		rai.EAB = ra.NewEABVerifier(keys, c.RA.ExternalAccountBinding.OneAccountPerKeyID)
+	}
+
+	if c.RA.LintProblemSeverity != "" {
+		rai.LintProblemSeverity = lint.StatusLabelToLintStatus[c.RA.LintProblemSeverity]
+	}
+
+	if len(c.RA.ValidationPolicyHints) > 0 {
+		rai.ValidationPolicyHints = make(ra.PolicyHintRollouts)
+		for key, hint := range c.RA.ValidationPolicyHints {
+			rai.ValidationPolicyHints[key] = ra.PolicyHintRollout{
+				Value:      hint.Value,
+				AccountIDs: hint.AccountIDs,
+				Percent:    hint.Percent,
+			}
+		}
+	}
+
+	rai.MaxPendingAuthorizationsPerAccount = c.RA.MaxPendingAuthorizationsPerAccount
+
 	rai.VA = va.RemoteClients{
 		VAClient:  vac,
 		CAAClient: caaClient,
@@ -354,6 +633,14 @@ func main() {
 	rai.OCSP = ocspc
 	rai.SA = sac
 
+	if c.RA.AbandonedOrdersReportInterval.Duration > 0 {
+		go rai.ReportAbandonedOrdersEvery(context.Background(), c.RA.AbandonedOrdersReportInterval.Duration)
+	}
+
+	if limiter != nil && c.RA.Limiter.DenialStreakPauseThreshold.Duration > 0 {
+		go rai.ProposePausesEvery(context.Background(), time.Hour, c.RA.Limiter.DenialStreakPauseThreshold.Duration)
+	}
+
 	start, err := bgrpc.NewServer(c.RA.GRPC, logger).Add(
 		&rapb.RegistrationAuthority_ServiceDesc, rai).Build(tlsConfig, scope, clk)
 	cmd.FailOnError(err, "Unable to setup RA gRPC server")
@@ -361,6 +648,74 @@ func main() {
 	cmd.FailOnError(start(), "RA gRPC service failed")
 }
 
+// loadValidationProfiles returns the validation profiles configured in c,
+// reading the allow list of each. It returns nil if c configures none.
+func loadValidationProfiles(c *Config) (map[string]*ra.ValidationProfile, error) {
+	if c.RA.ValidationProfiles == nil {
+		return nil, nil
+	}
+	validationProfiles := make(map[string]*ra.ValidationProfile)
+	for profileName, v := range c.RA.ValidationProfiles {
+		var allowList *allowlist.List[int64]
+		if v.AllowList != "" {
+			data, err := os.ReadFile(v.AllowList)
+			if err != nil {
+				return nil, fmt.Errorf("reading allow list for profile %q: %w", profileName, err)
+			}
+			allowList, err = allowlist.NewFromYAML[int64](data)
+			if err != nil {
+				return nil, fmt.Errorf("parsing allow list for profile %q: %w", profileName, err)
+			}
+		}
+		for _, identType := range v.IdentifierTypes {
+			if identType != identifier.TypeDNS && identType != identifier.TypeIP {
+				return nil, fmt.Errorf("unknown identifier type %q for profile %q", identType, profileName)
+			}
+		}
+		validationProfiles[profileName] = ra.NewValidationProfile(allowList, ra.ValidationProfileConfig{
+			MustStaplePolicy: ra.MustStaplePolicy(v.MustStaplePolicy),
+			Validity:         v.Validity.Duration,
+			IdentifierTypes:  v.IdentifierTypes,
+		})
+	}
+	return validationProfiles, nil
+}
+
+// reloadValidationProfiles rereads configFile, and replaces the validation
+// profiles and default profile name of rai with those it configures.
+func reloadValidationProfiles(configFile string, rai *ra.RegistrationAuthorityImpl) error {
+	var c Config
+	err := cmd.ReadConfigFile(configFile, &c)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	validationProfiles, err := loadValidationProfiles(&c)
+	if err != nil {
+		return err
+	}
+	rai.SetValidationProfiles(validationProfiles, c.RA.DefaultProfileName)
+	return nil
+}
+
+// reloadValidationProfilesEvery calls reloadValidationProfiles every interval
+// until the context is done. Errors are logged, and the existing profiles are
+// kept until the next successful reload.
+func reloadValidationProfilesEvery(ctx context.Context, configFile string, interval time.Duration, rai *ra.RegistrationAuthorityImpl, logger blog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := reloadValidationProfiles(configFile, rai)
+			if err != nil {
+				logger.Errf("Failed to reload validation profiles from %q: %s", configFile, err)
+			}
+		}
+	}
+}
+
 func init() {
 	cmd.RegisterCommand("boulder-ra", main, &cmd.ConfigValidator{Config: &Config{}})
 }
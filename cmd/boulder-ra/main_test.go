@@ -1 +1,106 @@
 package notmain
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/letsencrypt/boulder/ra"
+	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestReloadValidationProfiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	allowList := filepath.Join(dir, "allowlist.yaml")
+	err := os.WriteFile(allowList, []byte("- 1337\n"), 0600)
+	test.AssertNotError(t, err, "writing allow list")
+
+	configFile := filepath.Join(dir, "ra.json")
+	writeConfig := func(config string) {
+		t.Helper()
+		err := os.WriteFile(configFile, []byte(config), 0600)
+		test.AssertNotError(t, err, "writing config")
+	}
+	getProfiles := func(rai *ra.RegistrationAuthorityImpl) []*rapb.IssuanceProfile {
+		t.Helper()
+		resp, err := rai.GetIssuanceProfiles(context.Background(), &emptypb.Empty{})
+		test.AssertNotError(t, err, "GetIssuanceProfiles failed")
+		return resp.Profiles
+	}
+
+	writeConfig(`{
+		"ra": {
+			"validationProfiles": {
+				"legacy": {
+					"validity": "2160h",
+					"identifierTypes": ["dns"]
+				},
+				"shortlived": {
+					"allowList": "` + allowList + `",
+					"validity": "160h",
+					"identifierTypes": ["dns", "ip"]
+				}
+			},
+			"defaultProfileName": "legacy"
+		}
+	}`)
+	rai := &ra.RegistrationAuthorityImpl{}
+	err = reloadValidationProfiles(configFile, rai)
+	test.AssertNotError(t, err, "reloadValidationProfiles failed")
+	test.AssertDeepEquals(t, getProfiles(rai), []*rapb.IssuanceProfile{
+		{Name: "legacy", Validity: durationpb.New(2160 * time.Hour), IdentifierTypes: []string{"dns"}, Default: true},
+		{Name: "shortlived", Validity: durationpb.New(160 * time.Hour), IdentifierTypes: []string{"dns", "ip"}},
+	})
+
+	// A profile removed from the config is no longer returned after a reload.
+	writeConfig(`{
+		"ra": {
+			"validationProfiles": {
+				"shortlived": {
+					"validity": "160h",
+					"identifierTypes": ["dns", "ip"]
+				}
+			},
+			"defaultProfileName": "shortlived"
+		}
+	}`)
+	err = reloadValidationProfiles(configFile, rai)
+	test.AssertNotError(t, err, "reloadValidationProfiles failed")
+	want := []*rapb.IssuanceProfile{
+		{Name: "shortlived", Validity: durationpb.New(160 * time.Hour), IdentifierTypes: []string{"dns", "ip"}, Default: true},
+	}
+	test.AssertDeepEquals(t, getProfiles(rai), want)
+
+	// A config which fails to load leaves the previous profiles in place.
+	writeConfig(`{
+		"ra": {
+			"validationProfiles": {
+				"shortlived": {
+					"identifierTypes": ["email"]
+				}
+			}
+		}
+	}`)
+	err = reloadValidationProfiles(configFile, rai)
+	test.AssertError(t, err, "reloadValidationProfiles should fail for an unknown identifier type")
+	writeConfig(`{
+		"ra": {
+			"validationProfiles": {
+				"shortlived": {
+					"allowList": "` + filepath.Join(dir, "missing.yaml") + `"
+				}
+			}
+		}
+	}`)
+	err = reloadValidationProfiles(configFile, rai)
+	test.AssertError(t, err, "reloadValidationProfiles should fail for a missing allow list")
+	test.AssertDeepEquals(t, getProfiles(rai), want)
+}
@@ -3,13 +3,17 @@ package notmain
 import (
 	"context"
 	"flag"
+	netmail "net/mail"
+	"net/netip"
 	"os"
 	"time"
 
 	"github.com/letsencrypt/boulder/bdns"
 	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/config"
 	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	bmail "github.com/letsencrypt/boulder/mail"
 	"github.com/letsencrypt/boulder/va"
 	vaConfig "github.com/letsencrypt/boulder/va/config"
 	vapb "github.com/letsencrypt/boulder/va/proto"
@@ -47,12 +51,104 @@ type RemoteVAGRPCClientConfig struct {
 	//
 	// TODO(#7615): Make mandatory.
 	RIR string `validate:"omitempty,oneof=ARIN RIPE APNIC LACNIC AFRINIC"`
+
+	// ASN is the Autonomous System Number from which this RVA's requests
+	// originate. It is optional, but RVAs without an ASN never count towards
+	// MinDistinctASNs.
+	ASN uint32 `validate:"omitempty"`
+
+	// ExpectedIdentity is a dNSName which this RVA's gRPC server certificate
+	// must contain, and which should identify this perspective's RVAs alone.
+	// If the RVA authenticates without it, which happens when its address was
+	// copied from another perspective's config, an audit error is logged. It
+	// defaults to the name the gRPC client verifies the certificate against,
+	// which is the HostOverride, or the host of the ServerAddress or SRV
+	// lookup.
+	ExpectedIdentity string `validate:"omitempty,hostname"`
 }
 
 type Config struct {
 	VA struct {
 		vaConfig.Common
 		RemoteVAs []RemoteVAGRPCClientConfig `validate:"omitempty,dive"`
+		// MinDistinctASNs is the minimum number of distinct ASNs which must
+		// be present among the RemoteVAs that corroborate a validation, in
+		// addition to the distinct RIR requirement. Defaults to 0, which
+		// imposes no requirement.
+		MinDistinctASNs int `validate:"omitempty,min=0"`
+		// PerspectiveSelection, if its Quorum is set, causes each validation
+		// to query only Quorum+Headroom of the RemoteVAs, chosen at random and
+		// weighted by their recent success rate and latency, rather than all
+		// of them. The chosen RemoteVAs always span enough RIRs (and ASNs) to
+		// satisfy the corroboration requirements, and RemoteVAs which go
+		// unchosen for a while are chosen anyway to keep their stats fresh.
+		PerspectiveSelection struct {
+			// Quorum is the number of RemoteVAs which must corroborate each
+			// validation.
+			Quorum int `validate:"omitempty,min=0"`
+			// Headroom is the number of additional RemoteVAs to query, any
+			// of which may fail without failing the validation.
+			Headroom int `validate:"omitempty,min=0"`
+		}
+		// InternalIdentifiers are identifiers which are only reachable from
+		// this VA's network, for which remote corroboration is skipped and
+		// each skip is audit logged. This does not satisfy the
+		// Multi-Perspective Issuance Corroboration requirements, so it is
+		// only for private deployments, and is refused unless
+		// InsecureInternalIssuance is also set.
+		InternalIdentifiers struct {
+			InsecureInternalIssuance bool
+			// CIDRs are the address ranges which internal identifiers resolve
+			// to. Every address an identifier resolves to must be within one
+			// of them.
+			CIDRs []string `validate:"omitempty,dive,cidr"`
+			// Domains are internal identifiers, along with all of their
+			// subdomains.
+			Domains []string `validate:"omitempty,dive,fqdn"`
+		}
+		// IODEFReports, if its QueueSize is set, causes a report to be sent
+		// to the mailto: and https: iodef URLs of the Relevant RRset whenever
+		// a CAA check refuses issuance. Reports are queued and sent
+		// asynchronously, and failures to send them are only logged.
+		IODEFReports struct {
+			// QueueSize is the number of reports which may be waiting to be
+			// sent. Reports which don't fit are dropped.
+			QueueSize int `validate:"omitempty,min=0"`
+			// MinInterval is the least time between reports to the same
+			// email address or host. Defaults to 1h.
+			MinInterval config.Duration `validate:"-"`
+			// Timeout bounds the sending of each report. Defaults to 10s.
+			Timeout config.Duration `validate:"-"`
+			// SMTP, if set, is the mail server through which reports to
+			// mailto: URLs are sent. Otherwise those reports are dropped.
+			SMTP *cmd.SMTPConfig `validate:"omitempty"`
+			// From is the address from which reports are emailed.
+			From string `validate:"required_with=SMTP"`
+			// AccountIDHashKey is the key with which the ID of the account
+			// requesting issuance is hashed in each report. It's required if
+			// QueueSize is set.
+			AccountIDHashKey cmd.HMACKeyConfig `validate:"required_with=QueueSize,structonly"`
+		}
+		// RiskChecks, if its URL is set, causes each identifier to be
+		// screened by an external risk scoring service before it's
+		// validated. The service may allow the validation, flag it in the
+		// audit log, or deny it. If the service can't be reached, the
+		// validation is allowed.
+		RiskChecks struct {
+			// URL is the http or https URL to which each identifier and the
+			// account validating it are posted as JSON.
+			URL string `validate:"omitempty,url"`
+			// Timeout bounds each request to the service. Defaults to 2s.
+			Timeout config.Duration `validate:"-"`
+			// DenyDetail is the detail of the problem of denied validations.
+			// Defaults to a generic refusal.
+			DenyDetail string `validate:"-"`
+		}
+		// RecheckCAALocalOnly causes CAA rechecks, made when an order is
+		// finalized long after its authorizations were validated, to be
+		// made from this VA's perspective alone rather than corroborated by
+		// the RemoteVAs. The CAA checks made at validation are unaffected.
+		RecheckCAALocalOnly bool
 		// Deprecated and ignored
 		MaxRemoteValidationFailures int `validate:"omitempty,min=0,required_with=RemoteVAs"`
 		Features                    features.Config
@@ -110,6 +206,7 @@ func main() {
 			scope,
 			clk,
 			c.VA.DNSTries,
+			c.VA.DNSHostLookupTimeout.Duration,
 			logger,
 			tlsConfig)
 	} else {
@@ -119,6 +216,7 @@ func main() {
 			scope,
 			clk,
 			c.VA.DNSTries,
+			c.VA.DNSHostLookupTimeout.Duration,
 			logger,
 			tlsConfig)
 	}
@@ -128,6 +226,12 @@ func main() {
 			rva := rva
 			vaConn, err := bgrpc.ClientSetup(&rva.GRPCClientConfig, tlsConfig, scope, clk)
 			cmd.FailOnError(err, "Unable to create remote VA client")
+			target, hostOverride, err := rva.MakeTargetAndHostOverride()
+			cmd.FailOnError(err, "Invalid remote VA config")
+			expectedIdentity := rva.ExpectedIdentity
+			if expectedIdentity == "" {
+				expectedIdentity = hostOverride
+			}
 			remotes = append(
 				remotes,
 				va.RemoteVA{
@@ -135,14 +239,62 @@ func main() {
 						VAClient:  vapb.NewVAClient(vaConn),
 						CAAClient: vapb.NewCAAClient(vaConn),
 					},
-					Address:     rva.ServerAddress,
-					Perspective: rva.Perspective,
-					RIR:         rva.RIR,
+					Address:          target,
+					Perspective:      rva.Perspective,
+					RIR:              rva.RIR,
+					ASN:              rva.ASN,
+					ExpectedIdentity: expectedIdentity,
 				},
 			)
 		}
 	}
 
+	proxyProtocolSource, err := c.VA.ProxyProtocolSource()
+	cmd.FailOnError(err, "Invalid VA config")
+
+	internal := va.InternalIdentifiers{
+		InsecureInternalIssuance: c.VA.InternalIdentifiers.InsecureInternalIssuance,
+		Domains:                  c.VA.InternalIdentifiers.Domains,
+	}
+	for _, cidr := range c.VA.InternalIdentifiers.CIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		cmd.FailOnError(err, "Invalid internal identifier CIDR")
+		internal.Prefixes = append(internal.Prefixes, prefix)
+	}
+
+	iodef := va.IODEFConfig{
+		QueueSize:   c.VA.IODEFReports.QueueSize,
+		MinInterval: c.VA.IODEFReports.MinInterval.Duration,
+		Timeout:     c.VA.IODEFReports.Timeout.Duration,
+	}
+	if iodef.QueueSize > 0 {
+		iodef.AccountIDHashKey, err = c.VA.IODEFReports.AccountIDHashKey.Load()
+		cmd.FailOnError(err, "Failed to load iodef account ID hash key")
+	}
+	if c.VA.IODEFReports.SMTP != nil {
+		from, err := netmail.ParseAddress(c.VA.IODEFReports.From)
+		cmd.FailOnError(err, "Invalid iodef report from address")
+		smtpPassword, err := c.VA.IODEFReports.SMTP.Pass()
+		cmd.FailOnError(err, "Failed to load SMTP password")
+		iodef.Mailer = bmail.New(
+			c.VA.IODEFReports.SMTP.Server,
+			c.VA.IODEFReports.SMTP.Port,
+			c.VA.IODEFReports.SMTP.Username,
+			smtpPassword,
+			nil,
+			*from,
+			logger,
+			scope,
+			time.Second,
+			5*time.Minute)
+	}
+
+	risk := va.RiskConfig{DenyDetail: c.VA.RiskChecks.DenyDetail}
+	if c.VA.RiskChecks.URL != "" {
+		risk.Checker, err = va.NewHTTPRiskChecker(c.VA.RiskChecks.URL, c.VA.RiskChecks.Timeout.Duration)
+		cmd.FailOnError(err, "Invalid risk check config")
+	}
+
 	vai, err := va.NewValidationAuthorityImpl(
 		resolver,
 		remotes,
@@ -153,7 +305,34 @@ func main() {
 		logger,
 		c.VA.AccountURIPrefixes,
 		va.PrimaryPerspective,
-		"")
+		"",
+		va.Options{
+			MinDistinctASNs: c.VA.MinDistinctASNs,
+			PerspectiveSelection: va.PerspectiveSelection{
+				Quorum:   c.VA.PerspectiveSelection.Quorum,
+				Headroom: c.VA.PerspectiveSelection.Headroom,
+			},
+			DevMode:                  c.VA.DevMode,
+			MaxHTTPRetryAfter:        c.VA.MaxHTTPRetryAfter.Duration,
+			CAAValidationMethodsMode: va.CAAValidationMethodsMode(c.VA.CAAValidationMethodsMode),
+			HTTPHeaders:              c.VA.HTTPHeaders,
+			SLOThreshold:             c.VA.SLOThreshold.Duration,
+			ConfirmOverTLSDomains:    c.VA.HTTP01ConfirmOverTLSDomains,
+			DedupWindow:              c.VA.ValidationDedupWindow.Duration,
+			MaxConcurrentValidations: c.VA.MaxConcurrentValidations,
+			MaxQueueWait:             c.VA.MaxValidationQueueWait.Duration,
+			ProxyProtocolSource:      proxyProtocolSource,
+			Internal:                 internal,
+			MinTXTTTL:                c.VA.MinTXTTTL.Duration,
+			MaxCAABatchSize:          c.VA.MaxCAABatchSize,
+			CAABatchParallelism:      c.VA.CAABatchParallelism,
+			IODEF:                    iodef,
+			MaxValidationBytes:       c.VA.MaxValidationBytes,
+			Risk:                     risk,
+			RecheckCAALocalOnly:      c.VA.RecheckCAALocalOnly,
+			MaxConnsPerTargetIP:      c.VA.MaxConnsPerTargetIP,
+			MaxTargetIPConnWait:      c.VA.MaxTargetIPConnWait.Duration,
+		})
 	cmd.FailOnError(err, "Unable to create VA server")
 
 	start, err := bgrpc.NewServer(c.VA.GRPC, logger).Add(
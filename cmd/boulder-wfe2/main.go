@@ -144,16 +144,22 @@ type Config struct {
 
 		Limiter struct {
 			// Redis contains the configuration necessary to connect to Redis
-			// for rate limiting. This field is required to enable rate
-			// limiting.
-			Redis *bredis.Config `validate:"required_with=Defaults"`
+			// for rate limiting. Either this field or Inmem is required to
+			// enable rate limiting.
+			Redis *bredis.Config `validate:"excluded_with=Inmem"`
+
+			// Inmem, if set, stores rate limit buckets in memory instead of
+			// in Redis, for deployments with a single WFE. Buckets aren't
+			// shared with the RA, so limits spent by one aren't seen by the
+			// other.
+			Inmem *ratelimits.InmemConfig `validate:"excluded_with=Redis"`
 
 			// Defaults is a path to a YAML file containing default rate limits.
 			// See: ratelimits/README.md for details. This field is required to
 			// enable rate limiting. If any individual rate limit is not set,
 			// that limit will be disabled. Failed Authorizations limits passed
 			// in this file must be identical to those in the RA.
-			Defaults string `validate:"required_with=Redis"`
+			Defaults string `validate:"required_with=Redis Inmem"`
 
 			// Overrides is a path to a YAML file containing overrides for the
 			// default rate limits. See: ratelimits/README.md for details. If
@@ -162,6 +168,35 @@ type Config struct {
 			// overrides passed in this file must be identical to those in the
 			// RA.
 			Overrides string
+
+			// ReloadInterval, if set, is how often the Defaults and Overrides
+			// files are reloaded, along with the mode of each limit. If they
+			// fail to load, the error is logged and the previous limits are
+			// kept.
+			ReloadInterval config.Duration `validate:"-"`
+
+			// TrackOverrideUtilization, if set, exports metrics under the
+			// ratelimits_override namespace counting the spends against and
+			// denials by each override, and the fraction of its burst last
+			// remaining, so that dead or undersized overrides can be found.
+			TrackOverrideUtilization bool
+
+			// RecordDenialStreaks, if set, records how long each account's new
+			// orders have been continuously denied by the
+			// FailedAuthorizationsPerDomainPerAccount limit, so that the RA can
+			// propose pausing it. See the RA's DenialStreakPauseThreshold.
+			RecordDenialStreaks bool
+
+			// OverrideCaps, keyed by limit name, replace the default caps on
+			// the burst and count of overrides of each limit, which exist to
+			// catch typos. A zero MaxBurst or MaxCount is uncapped. Overrides
+			// above their cap are clamped to it, with a warning logged.
+			// Default limits are never capped.
+			OverrideCaps map[string]ratelimits.OverrideCap `validate:"omitempty,dive"`
+
+			// StrictOverrideCaps, if set, causes overrides above their cap to
+			// fail to load rather than being clamped.
+			StrictOverrideCaps bool
 		}
 
 		// MaxNames is the maximum number of subjectAltNames in a single cert.
@@ -362,14 +397,42 @@ func main() {
 	var limiterRedis *bredis.Ring
 	if c.WFE.Limiter.Defaults != "" {
 		// Setup rate limiting.
-		limiterRedis, err = bredis.NewRingFromConfig(*c.WFE.Limiter.Redis, stats, logger)
-		cmd.FailOnError(err, "Failed to create Redis ring")
-
-		source := ratelimits.NewRedisSource(limiterRedis.Ring, clk, stats)
-		limiter, err = ratelimits.NewLimiter(clk, source, stats)
+		var source ratelimits.Source
+		if c.WFE.Limiter.Inmem != nil {
+			var inmemSource *ratelimits.InmemSource
+			inmemSource, err = ratelimits.NewInmemSourceFromConfig(*c.WFE.Limiter.Inmem, clk, stats)
+			cmd.FailOnError(err, "Failed to create in-memory rate limit source")
+			if c.WFE.Limiter.Inmem.SnapshotFile != "" {
+				go inmemSource.SnapshotEvery(context.Background(), c.WFE.Limiter.Inmem.SnapshotFile,
+					c.WFE.Limiter.Inmem.SnapshotInterval.Duration, logger)
+			}
+			source = inmemSource
+		} else {
+			if c.WFE.Limiter.Redis == nil {
+				cmd.Fail("Either Redis or Inmem must be configured to enable rate limiting")
+			}
+			limiterRedis, err = bredis.NewRingFromConfig(*c.WFE.Limiter.Redis, stats, logger)
+			cmd.FailOnError(err, "Failed to create Redis ring")
+			source = ratelimits.NewRedisSource(limiterRedis.Ring, clk, stats)
+		}
+		limiter, err = ratelimits.NewLimiter(clk, source, stats, logger)
 		cmd.FailOnError(err, "Failed to create rate limiter")
+		if c.WFE.Limiter.RecordDenialStreaks {
+			err = limiter.EnableDenialStreaks()
+			cmd.FailOnError(err, "Failed to enable denial streaks")
+		}
 		txnBuilder, err = ratelimits.NewTransactionBuilderFromFiles(c.WFE.Limiter.Defaults, c.WFE.Limiter.Overrides)
 		cmd.FailOnError(err, "Failed to create rate limits transaction builder")
+		ratelimits.RegisterNormalizationMetrics(stats)
+		err = txnBuilder.EnforceOverrideCaps(c.WFE.Limiter.OverrideCaps, c.WFE.Limiter.StrictOverrideCaps, stats, logger)
+		cmd.FailOnError(err, "Failed to apply rate limit override caps")
+		if c.WFE.Limiter.TrackOverrideUtilization {
+			limiter.EnableOverrideUtilization(txnBuilder, stats)
+		}
+		if c.WFE.Limiter.ReloadInterval.Duration > 0 {
+			go txnBuilder.ReloadFromFilesEvery(context.Background(), c.WFE.Limiter.Defaults, c.WFE.Limiter.Overrides,
+				c.WFE.Limiter.ReloadInterval.Duration, logger)
+		}
 	}
 
 	var accountGetter wfe2.AccountGetter
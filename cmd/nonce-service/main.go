@@ -8,9 +8,11 @@ import (
 	"os"
 
 	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/config"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/nonce"
 	noncepb "github.com/letsencrypt/boulder/nonce/proto"
+	bredis "github.com/letsencrypt/boulder/redis"
 )
 
 type Config struct {
@@ -39,6 +41,18 @@ type Config struct {
 		// Deprecated: Use NonceHMACKey instead.
 		NoncePrefixKey cmd.PasswordConfig `validate:"required_without_all=NonceHMACKey,structonly"`
 
+		// Redis optionally contains the configuration necessary to connect to
+		// Redis for persisting the nonce counter and outstanding nonces. When
+		// set, a restarted instance with the same NonceHMACKey and gRPC
+		// address can redeem the nonces issued before it restarted, and
+		// MaxUsed is ignored. When unset, this state is kept in memory.
+		Redis *bredis.Config
+
+		// MaxAge is how long a nonce remains redeemable. Each nonce carries
+		// its expiry, so an expired nonce is rejected even if MaxUsed hasn't
+		// yet been reached. Defaults to one hour.
+		MaxAge config.Duration `validate:"-"`
+
 		Syslog        cmd.SyslogConfig
 		OpenTelemetry cmd.OpenTelemetryConfig
 	}
@@ -105,8 +119,19 @@ func main() {
 	defer oTelShutdown(context.Background())
 	logger.Info(cmd.VersionString())
 
-	ns, err := nonce.NewNonceService(scope, c.NonceService.MaxUsed, noncePrefix)
-	cmd.FailOnError(err, "Failed to initialize nonce service")
+	var ns *nonce.NonceService
+	if c.NonceService.Redis != nil {
+		nonceRedis, err := bredis.NewRingFromConfig(*c.NonceService.Redis, scope, logger)
+		cmd.FailOnError(err, "Failed to create Redis ring")
+		defer nonceRedis.StopLookups()
+
+		store := nonce.NewRedisStore(nonceRedis.Ring, noncePrefix, cmd.Clock(), scope)
+		ns, err = nonce.NewPersistentNonceService(scope, cmd.Clock(), noncePrefix, key, store, c.NonceService.MaxAge.Duration)
+		cmd.FailOnError(err, "Failed to initialize nonce service")
+	} else {
+		ns, err = nonce.NewNonceService(scope, cmd.Clock(), c.NonceService.MaxUsed, c.NonceService.MaxAge.Duration, noncePrefix)
+		cmd.FailOnError(err, "Failed to initialize nonce service")
+	}
 
 	tlsConfig, err := c.NonceService.TLS.Load(scope)
 	cmd.FailOnError(err, "tlsConfig config")
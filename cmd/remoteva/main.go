@@ -119,6 +119,7 @@ func main() {
 			scope,
 			clk,
 			c.RVA.DNSTries,
+			c.RVA.DNSHostLookupTimeout.Duration,
 			logger,
 			tlsConfig)
 	} else {
@@ -128,10 +129,14 @@ func main() {
 			scope,
 			clk,
 			c.RVA.DNSTries,
+			c.RVA.DNSHostLookupTimeout.Duration,
 			logger,
 			tlsConfig)
 	}
 
+	proxyProtocolSource, err := c.RVA.ProxyProtocolSource()
+	cmd.FailOnError(err, "Invalid Remote-VA config")
+
 	vai, err := va.NewValidationAuthorityImpl(
 		resolver,
 		nil, // Our RVAs will never have RVAs of their own.
@@ -142,7 +147,25 @@ func main() {
 		logger,
 		c.RVA.AccountURIPrefixes,
 		c.RVA.Perspective,
-		c.RVA.RIR)
+		c.RVA.RIR,
+		va.Options{
+			DevMode:                  c.RVA.DevMode,
+			MaxHTTPRetryAfter:        c.RVA.MaxHTTPRetryAfter.Duration,
+			CAAValidationMethodsMode: va.CAAValidationMethodsMode(c.RVA.CAAValidationMethodsMode),
+			HTTPHeaders:              c.RVA.HTTPHeaders,
+			SLOThreshold:             c.RVA.SLOThreshold.Duration,
+			ConfirmOverTLSDomains:    c.RVA.HTTP01ConfirmOverTLSDomains,
+			DedupWindow:              c.RVA.ValidationDedupWindow.Duration,
+			MaxConcurrentValidations: c.RVA.MaxConcurrentValidations,
+			MaxQueueWait:             c.RVA.MaxValidationQueueWait.Duration,
+			ProxyProtocolSource:      proxyProtocolSource,
+			MinTXTTTL:                c.RVA.MinTXTTTL.Duration,
+			MaxCAABatchSize:          c.RVA.MaxCAABatchSize,
+			CAABatchParallelism:      c.RVA.CAABatchParallelism,
+			MaxValidationBytes:       c.RVA.MaxValidationBytes,
+			MaxConnsPerTargetIP:      c.RVA.MaxConnsPerTargetIP,
+			MaxTargetIPConnWait:      c.RVA.MaxTargetIPConnWait.Duration,
+		})
 	cmd.FailOnError(err, "Unable to create Remote-VA server")
 
 	start, err := bgrpc.NewServer(c.RVA.GRPC, logger).Add(
@@ -9,6 +9,7 @@ import (
 	"net"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-jose/go-jose/v4"
 	"golang.org/x/crypto/ocsp"
@@ -53,15 +54,16 @@ type AcmeChallenge string
 
 // These types are the available challenges
 const (
-	ChallengeTypeHTTP01    = AcmeChallenge("http-01")
-	ChallengeTypeDNS01     = AcmeChallenge("dns-01")
-	ChallengeTypeTLSALPN01 = AcmeChallenge("tls-alpn-01")
+	ChallengeTypeHTTP01       = AcmeChallenge("http-01")
+	ChallengeTypeDNS01        = AcmeChallenge("dns-01")
+	ChallengeTypeTLSALPN01    = AcmeChallenge("tls-alpn-01")
+	ChallengeTypeDNSAccount01 = AcmeChallenge("dns-account-01")
 )
 
 // IsValid tests whether the challenge is a known challenge
 func (c AcmeChallenge) IsValid() bool {
 	switch c {
-	case ChallengeTypeHTTP01, ChallengeTypeDNS01, ChallengeTypeTLSALPN01:
+	case ChallengeTypeHTTP01, ChallengeTypeDNS01, ChallengeTypeTLSALPN01, ChallengeTypeDNSAccount01:
 		return true
 	default:
 		return false
@@ -115,6 +117,24 @@ type Registration struct {
 	Status AcmeStatus `json:"status"`
 }
 
+// DNSQuery describes a single DNS query made while validating.
+type DNSQuery struct {
+	// Resolver is the host:port of the resolver which answered the query, or
+	// of the last resolver tried if none did.
+	Resolver string `json:"resolver"`
+
+	// Qtype is the type of the query, e.g. "A" or "TXT".
+	Qtype string `json:"qtype"`
+
+	// RTT is the round trip time of the query, or zero if no response was
+	// received.
+	RTT time.Duration `json:"rtt,omitempty"`
+
+	// TCPFallback is true if the query was retried over TCP, in which case RTT
+	// is the round trip time of the TCP query.
+	TCPFallback bool `json:"tcpFallback,omitempty"`
+}
+
 // ValidationRecord represents a validation attempt against a specific URL/hostname
 // and the IP addresses that were resolved and used.
 type ValidationRecord struct {
@@ -127,6 +147,9 @@ type ValidationRecord struct {
 	AddressesResolved []net.IP `json:"addressesResolved,omitempty"`
 	AddressUsed       net.IP   `json:"addressUsed,omitempty"`
 
+	// AddressFamily is the family of AddressUsed, either "ipv4" or "ipv6".
+	AddressFamily string `json:"addressFamily,omitempty"`
+
 	// AddressesTried contains a list of addresses tried before the `AddressUsed`.
 	// Presently this will only ever be one IP from `AddressesResolved` since the
 	// only retry is in the case of a v6 failure with one v4 fallback. E.g. if
@@ -147,6 +170,59 @@ type ValidationRecord struct {
 	// lookup for AddressUsed. During recursive A and AAAA lookups, a record may
 	// instead look like A:host:port or AAAA:host:port
 	ResolverAddrs []string `json:"resolverAddrs,omitempty"`
+
+	// DNSQueries describes each DNS query made while validating, in the order
+	// they were made.
+	DNSQueries []DNSQuery `json:"dnsQueries,omitempty"`
+
+	// CacheBusted is true if this record describes an HTTP-01 request which was
+	// retried with a cache-busting query parameter because the previous
+	// response appeared to have been served from a stale intermediary cache.
+	CacheBusted bool `json:"cacheBusted,omitempty"`
+
+	// RetriedAfter is true if this record describes an HTTP-01 request which
+	// was retried because the previous response had a 429 or 503 status code
+	// and asked us to try again shortly via a Retry-After header.
+	RetriedAfter bool `json:"retriedAfter,omitempty"`
+
+	// HandshakeBytesSent and HandshakeBytesReceived are the number of bytes
+	// written to and read from the connection during a TLS-ALPN-01 handshake
+	// which the server closed before it completed.
+	HandshakeBytesSent     int64 `json:"handshakeBytesSent,omitempty"`
+	HandshakeBytesReceived int64 `json:"handshakeBytesReceived,omitempty"`
+
+	// BytesReceived is the total number of bytes read from the network by
+	// the validation, across all of its requests, redirects and TLS
+	// handshakes. It's only set on the last record of an HTTP-01 or
+	// TLS-ALPN-01 validation.
+	BytesReceived int64 `json:"bytesReceived,omitempty"`
+
+	// DNSAnswerDigest is the bdns.AnswerDigest of the TXT records found during
+	// a DNS-01 validation, or of the A and AAAA records found during an HTTP-01
+	// or TLS-ALPN-01 validation, and DNSQueriedAt is the time at which they
+	// were looked up. Together with ResolverAddrs they allow us to later prove what
+	// DNS data the validation acted upon without storing the records verbatim.
+	DNSAnswerDigest string     `json:"dnsAnswerDigest,omitempty"`
+	DNSQueriedAt    *time.Time `json:"dnsQueriedAt,omitempty"`
+
+	// Protocol is the protocol of the HTTP-01 response received from URL, as
+	// negotiated with the server: either "HTTP/1.1" or, over TLS, "HTTP/2.0".
+	Protocol string `json:"protocol,omitempty"`
+
+	// TLSALPNFailure is the reason, if any, that a TLS-ALPN-01 handshake
+	// didn't negotiate "acme-tls/1" for the requested name: "alpn_refused",
+	// "alpn_unadvertised", "alpn_not_negotiated" or "sni_unrecognized".
+	TLSALPNFailure string `json:"tlsALPNFailure,omitempty"`
+
+	// WebServerProtocol is the protocol, "h2" or "http/1.1", which the server
+	// negotiated when offered them after a TLS-ALPN-01 handshake failed to
+	// negotiate "acme-tls/1". It shows a standard web server answered instead.
+	WebServerProtocol string `json:"webServerProtocol,omitempty"`
+
+	// ProxyProtocol is true if the connection described by this record was
+	// made through the VA's egress proxy, and so began with a PROXY protocol
+	// header carrying the VA's public address.
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
 }
 
 // Challenge is an aggregate of all data needed for any challenges.
@@ -179,8 +255,99 @@ type Challenge struct {
 	// Contains information about URLs used or redirected to and IPs resolved and
 	// used
 	ValidationRecord []ValidationRecord `json:"validationRecord,omitempty"`
+
+	// Attempts holds a summary of the most recent validation attempts for this
+	// challenge, oldest first, so that earlier problems are not lost when a
+	// later attempt replaces Error.
+	Attempts []ValidationAttempt `json:"attempts,omitempty"`
 }
 
+// ValidationAttempt summarizes a single attempt to validate a challenge.
+type ValidationAttempt struct {
+	// AttemptedAt is the time at which the attempt was made.
+	AttemptedAt time.Time `json:"attemptedAt"`
+
+	// ProblemType and Detail describe the problem which caused the attempt to
+	// fail. Both are empty if the attempt succeeded. Detail may be truncated.
+	ProblemType probs.ProblemType `json:"problemType,omitempty"`
+	Detail      string            `json:"detail,omitempty"`
+
+	// Perspectives summarizes the network perspectives which took part in the
+	// attempt.
+	Perspectives string `json:"perspectives,omitempty"`
+}
+
+// maxAttemptDetailLength is the maximum length, in bytes, of the Detail of a
+// ValidationAttempt.
+const maxAttemptDetailLength = 256
+
+// MaxValidationAttempts is the number of most recent ValidationAttempts kept
+// for each challenge.
+const MaxValidationAttempts = 5
+
+// NewValidationAttempt returns a ValidationAttempt describing an attempt made
+// at attemptedAt which resulted in prob, or succeeded if prob is nil. The
+// problem detail is truncated to maxAttemptDetailLength bytes.
+func NewValidationAttempt(attemptedAt time.Time, prob *probs.ProblemDetails, perspectives string) ValidationAttempt {
+	attempt := ValidationAttempt{
+		AttemptedAt:  attemptedAt.UTC(),
+		Perspectives: perspectives,
+	}
+	if prob != nil {
+		attempt.ProblemType = prob.Type
+		attempt.Detail = prob.Detail
+		if len(attempt.Detail) > maxAttemptDetailLength {
+			// Avoid cutting a multi-byte character in half.
+			cut := maxAttemptDetailLength
+			for cut > 0 && !utf8.RuneStart(attempt.Detail[cut]) {
+				cut--
+			}
+			attempt.Detail = attempt.Detail[:cut] + "..."
+		}
+	}
+	return attempt
+}
+
+// AppendValidationAttempt appends attempt to attempts, discarding the oldest
+// attempts so that at most MaxValidationAttempts remain.
+// The provided slice is not modified.
+func AppendValidationAttempt(attempts []ValidationAttempt, attempt ValidationAttempt) []ValidationAttempt {
+	if len(attempts) >= MaxValidationAttempts {
+		attempts = attempts[len(attempts)-MaxValidationAttempts+1:]
+	}
+	out := make([]ValidationAttempt, 0, len(attempts)+1)
+	out = append(out, attempts...)
+	return append(out, attempt)
+}
+
+// PerspectiveResult is the outcome of a validation attempt at a single network
+// perspective.
+type PerspectiveResult struct {
+	Perspective string `json:"perspective"`
+	RIR         string `json:"rir,omitempty"`
+	Passed      bool   `json:"passed"`
+
+	// ProblemType is the type of the problem encountered by the perspective.
+	// It is empty if the perspective passed.
+	ProblemType probs.ProblemType `json:"problemType,omitempty"`
+
+	// LatencyMS is the time, in milliseconds, the perspective took to respond.
+	LatencyMS int64 `json:"latencyMS"`
+
+	// Operation is the check the result is for, "dcv" or "caa". Results
+	// stored before CAA results were recorded have none, and are for DCV.
+	Operation string `json:"operation,omitempty"`
+}
+
+// MaxPerspectiveResultsPerOperation is the maximum number of
+// PerspectiveResults recorded for each of the DCV and CAA checks of a single
+// validation attempt.
+const MaxPerspectiveResultsPerOperation = 16
+
+// MaxPerspectiveResults is the maximum number of PerspectiveResults recorded
+// for a single validation attempt.
+const MaxPerspectiveResults = 2 * MaxPerspectiveResultsPerOperation
+
 // ExpectedKeyAuthorization computes the expected KeyAuthorization value for
 // the challenge.
 func (ch Challenge) ExpectedKeyAuthorization(key *jose.JSONWebKey) (string, error) {
@@ -226,7 +393,7 @@ func (ch Challenge) RecordsSane() bool {
 			ch.ValidationRecord[0].AddressUsed == nil || len(ch.ValidationRecord[0].AddressesResolved) == 0 {
 			return false
 		}
-	case ChallengeTypeDNS01:
+	case ChallengeTypeDNS01, ChallengeTypeDNSAccount01:
 		if len(ch.ValidationRecord) > 1 {
 			return false
 		}
@@ -322,6 +489,11 @@ type Authorization struct {
 	// order that first resulted in the creation of this authorization. Omitted
 	// from API responses.
 	CertificateProfileName string `json:"-"`
+
+	// FailureCounted is true if a failure of this authorization has been
+	// counted against the FailedAuthorizationsPerDomainPerAccount limit.
+	// Omitted from API responses.
+	FailureCounted bool `json:"-"`
 }
 
 // FindChallengeByStringID will look for a challenge matching the given ID inside
@@ -351,6 +523,14 @@ func (authz *Authorization) SolvedBy() (AcmeChallenge, error) {
 	return "", fmt.Errorf("authorization not solved by any challenge")
 }
 
+// DeactivatedAuthorizationProblem returns the problem recorded on an order
+// which became invalid because the authorization with the given ID, for the
+// given identifier, was deactivated.
+func DeactivatedAuthorizationProblem(authzID int64, ident identifier.ACMEIdentifier) *probs.ProblemDetails {
+	return probs.Unauthorized(fmt.Sprintf(
+		"Authorization %d for %s identifier %q was deactivated", authzID, ident.Type, ident.Value))
+}
+
 // JSONBuffer fields get encoded and decoded JOSE-style, in base64url encoding
 // with stripped padding.
 type JSONBuffer []byte
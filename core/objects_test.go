@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"math/big"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
 
+	"github.com/letsencrypt/boulder/probs"
 	"github.com/letsencrypt/boulder/test"
 )
 
@@ -154,6 +156,53 @@ func TestChallengeStringID(t *testing.T) {
 	test.AssertEquals(t, ch.StringID(), "0Gexug")
 }
 
+func TestNewValidationAttempt(t *testing.T) {
+	now := time.Date(2020, 3, 4, 5, 0, 0, 0, time.FixedZone("UTC+1", 3600))
+
+	attempt := NewValidationAttempt(now, nil, "Primary")
+	test.AssertEquals(t, attempt.AttemptedAt, now.UTC())
+	test.AssertEquals(t, attempt.ProblemType, probs.ProblemType(""))
+	test.AssertEquals(t, attempt.Detail, "")
+	test.AssertEquals(t, attempt.Perspectives, "Primary")
+
+	attempt = NewValidationAttempt(now, probs.Connection("refused"), "Primary")
+	test.AssertEquals(t, attempt.ProblemType, probs.ConnectionProblem)
+	test.AssertEquals(t, attempt.Detail, "refused")
+
+	// Long details are truncated without splitting a multi-byte character.
+	detail := strings.Repeat("a", maxAttemptDetailLength-1) + "é" + "tail"
+	attempt = NewValidationAttempt(now, probs.Connection(detail), "Primary")
+	test.AssertEquals(t, attempt.Detail, strings.Repeat("a", maxAttemptDetailLength-1)+"...")
+}
+
+func TestAppendValidationAttempt(t *testing.T) {
+	now := time.Now().UTC()
+	var attempts []ValidationAttempt
+
+	// Two failed attempts followed by a successful one are all kept, oldest
+	// first.
+	attempts = AppendValidationAttempt(attempts, NewValidationAttempt(now, probs.Connection("refused"), ""))
+	attempts = AppendValidationAttempt(attempts, NewValidationAttempt(now.Add(time.Minute), probs.Unauthorized("wrong"), ""))
+	attempts = AppendValidationAttempt(attempts, NewValidationAttempt(now.Add(2*time.Minute), nil, ""))
+	test.AssertEquals(t, len(attempts), 3)
+	test.AssertEquals(t, attempts[0].ProblemType, probs.ConnectionProblem)
+	test.AssertEquals(t, attempts[1].ProblemType, probs.UnauthorizedProblem)
+	test.AssertEquals(t, attempts[2].ProblemType, probs.ProblemType(""))
+
+	// Only the most recent MaxValidationAttempts are kept.
+	previous := attempts
+	for i := range MaxValidationAttempts {
+		attempts = AppendValidationAttempt(attempts, NewValidationAttempt(now.Add(time.Duration(3+i)*time.Minute), nil, ""))
+	}
+	test.AssertEquals(t, len(attempts), MaxValidationAttempts)
+	test.AssertEquals(t, attempts[0].AttemptedAt, now.Add(3*time.Minute))
+	test.AssertEquals(t, attempts[MaxValidationAttempts-1].AttemptedAt, now.Add(7*time.Minute))
+
+	// The provided slice is never modified.
+	test.AssertEquals(t, len(previous), 3)
+	test.AssertEquals(t, previous[0].ProblemType, probs.ConnectionProblem)
+}
+
 func TestFindChallengeByType(t *testing.T) {
 	authz := Authorization{
 		Challenges: []Challenge{
@@ -129,6 +129,18 @@ func KeyDigestB64(key crypto.PublicKey) (string, error) {
 	return base64.StdEncoding.EncodeToString(digest[:]), nil
 }
 
+// JWKDigestB64 produces the padded, standard Base64-encoded SHA256 digest of
+// the key in a JSON-serialized JWK. Accounts are stored, and looked up, by
+// this digest of their key, so everything which does either must use it.
+func JWKDigestB64(jwkJSON []byte) (string, error) {
+	var jwk jose.JSONWebKey
+	err := jwk.UnmarshalJSON(jwkJSON)
+	if err != nil {
+		return "", fmt.Errorf("parsing JWK: %w", err)
+	}
+	return KeyDigestB64(jwk.Key)
+}
+
 // KeyDigestEquals determines whether two public keys have the same digest.
 func KeyDigestEquals(j, k crypto.PublicKey) bool {
 	digestJ, errJ := KeyDigestB64(j)
@@ -99,6 +99,17 @@ func TestKeyDigest(t *testing.T) {
 	test.Assert(t, err != nil, "Should have rejected unknown key type")
 }
 
+func TestJWKDigestB64(t *testing.T) {
+	digest, err := JWKDigestB64([]byte(JWK1JSON))
+	test.AssertNotError(t, err, "Failed to digest JWK")
+	test.AssertEquals(t, digest, JWK1Digest)
+
+	_, err = JWKDigestB64([]byte("{}"))
+	test.AssertError(t, err, "Should have rejected an empty JWK")
+	_, err = JWKDigestB64([]byte("not json"))
+	test.AssertError(t, err, "Should have rejected a malformed JWK")
+}
+
 func TestKeyDigestEquals(t *testing.T) {
 	var jwk1, jwk2 jose.JSONWebKey
 	err := json.Unmarshal([]byte(JWK1JSON), &jwk1)
@@ -47,6 +47,9 @@ func VerifyCSR(ctx context.Context, csr *x509.CertificateRequest, maxNames int,
 	}
 	err := keyPolicy.GoodKey(ctx, key)
 	if err != nil {
+		if errors.Is(err, goodkey.ErrBlockedKey) {
+			return berrors.BadPublicKeyError("invalid public key in CSR: %s", err)
+		}
 		if errors.Is(err, goodkey.ErrBadKey) {
 			return berrors.BadCSRError("invalid public key in CSR: %s", err)
 		}
@@ -1,6 +1,7 @@
 package csr
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
@@ -280,6 +281,37 @@ func TestSHA1Deprecation(t *testing.T) {
 	test.AssertError(t, err, "SHA1 CSR should not verify")
 }
 
+func TestVerifyCSRKeyErrors(t *testing.T) {
+	blocked, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.AssertNotError(t, err, "error generating test key")
+	blockedDigest, err := core.KeyDigest(blocked.Public())
+	test.AssertNotError(t, err, "computing key digest")
+	keyPolicy, err := goodkey.NewPolicy(nil, func(_ context.Context, keyHash []byte) (bool, error) {
+		return bytes.Equal(keyHash, blockedDigest[:]), nil
+	})
+	test.AssertNotError(t, err, "creating test keypolicy")
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	test.AssertNotError(t, err, "error generating test key")
+
+	verify := func(key *rsa.PrivateKey) error {
+		csrBytes, err := x509.CreateCertificateRequest(rand.Reader,
+			&x509.CertificateRequest{DNSNames: []string{"example.com"}}, key)
+		test.AssertNotError(t, err, "creating test CSR")
+		csr, err := x509.ParseCertificateRequest(csrBytes)
+		test.AssertNotError(t, err, "parsing test CSR")
+		return VerifyCSR(context.Background(), csr, 100, &keyPolicy, &mockPA{})
+	}
+
+	// A key on the blocked list is a bad public key, while a weak key makes
+	// for a bad CSR.
+	err = verify(blocked)
+	test.AssertErrorIs(t, err, berrors.BadPublicKey)
+	test.AssertContains(t, err.Error(), "public key is forbidden")
+	err = verify(weak)
+	test.AssertErrorIs(t, err, berrors.BadCSR)
+	test.AssertContains(t, err.Error(), "key size not supported")
+}
+
 func TestDuplicateExtensionRejection(t *testing.T) {
 	private, err := rsa.GenerateKey(rand.Reader, 2048)
 	test.AssertNotError(t, err, "error generating test key")
@@ -57,6 +57,7 @@ const (
 	Conflict
 	// Defined in https://datatracker.ietf.org/doc/draft-aaron-acme-profiles/00/
 	InvalidProfile
+	ExternalAccountRequired
 )
 
 func (ErrorType) Error() string {
@@ -230,6 +231,14 @@ func FailedAuthorizationsPerDomainPerAccountError(retryAfter time.Duration, msg
 	}
 }
 
+func FailedValidationsPerDomainPerAccountError(retryAfter time.Duration, msg string, args ...interface{}) error {
+	return &BoulderError{
+		Type:       RateLimit,
+		Detail:     fmt.Sprintf(msg+": see https://letsencrypt.org/docs/rate-limits/", args...),
+		RetryAfter: retryAfter,
+	}
+}
+
 func RejectedIdentifierError(msg string, args ...interface{}) error {
 	return New(RejectedIdentifier, msg, args...)
 }
@@ -293,3 +302,7 @@ func ConflictError(msg string, args ...interface{}) error {
 func InvalidProfileError(msg string, args ...interface{}) error {
 	return New(InvalidProfile, msg, args...)
 }
+
+func ExternalAccountRequiredError(msg string, args ...interface{}) error {
+	return New(ExternalAccountRequired, msg, args...)
+}
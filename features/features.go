@@ -106,6 +106,51 @@ type Config struct {
 	// This feature flag also causes CAA checks to happen after all remote VAs
 	// have passed DCV.
 	EnforceMPIC bool
+
+	// HTTP01ConfirmOverTLS causes the VA, after a successful HTTP-01
+	// validation of a name covered by its HTTP01ConfirmOverTLSDomains, to
+	// fetch the challenge response again over HTTPS and require the same
+	// response. This narrows the window for an on-path attacker who can only
+	// intercept plaintext HTTP.
+	HTTP01ConfirmOverTLS bool
+
+	// DNSAccount01Enabled causes the PA to offer the experimental
+	// dns-account-01 challenge, from draft-ietf-acme-dns-account-label, for
+	// non-wildcard DNS identifiers, and the VA to perform it. The challenge
+	// must also be enabled in the PA's challenge configuration.
+	DNSAccount01Enabled bool
+
+	// StoreEABKeyID causes the SA to store the External Account Binding key
+	// identifier of each new registration, and to implement
+	// NewRegistrationClaimingEABKeyID. It must not be enabled until the
+	// RegistrationsEABKeyID migration has been applied.
+	StoreEABKeyID bool
+
+	// RAKeyRollover causes the WFE to have the RA verify the inner JWS of each
+	// key rollover request and change the account's key, rather than
+	// verifying the request itself and asking the RA to change the key. The
+	// RA refuses replayed requests, which the SA records, so this must not be
+	// enabled until every RA and SA supports KeyRollover and the KeyRollovers
+	// migration has been applied.
+	RAKeyRollover bool
+
+	// StoreAuthzValidationDetails causes the SA to read and write the
+	// validationAttempts, perspectiveResults, and failureCounted columns of
+	// the authz2 table, and to implement AddValidationAttempt and
+	// SetAuthorizationFailureCounted. It must not be enabled until the
+	// AuthzValidationAttempts, AuthzPerspectiveResults, and AuthzFailureCounted
+	// migrations have been applied.
+	StoreAuthzValidationDetails bool
+
+	// ExpiredOrdersIndex causes the SA to implement GetAbandonedOrders, which
+	// relies on the expires_idx index of the orders table. It must not be
+	// enabled until the OrdersExpiresIndex migration has been applied.
+	ExpiredOrdersIndex bool
+
+	// StoreOrderFinalizeBy causes the SA to read and write the finalizeBy
+	// column of the orders table. It must not be enabled until the
+	// OrdersFinalizeBy migration has been applied.
+	StoreOrderFinalizeBy bool
 }
 
 var fMu = new(sync.RWMutex)
@@ -93,6 +93,10 @@ func badKey(msg string, args ...interface{}) error {
 	return fmt.Errorf("%w%s", ErrBadKey, fmt.Errorf(msg, args...))
 }
 
+// ErrBlockedKey is returned for a key which is on the blocked list, as distinct
+// from one which is weak. It wraps ErrBadKey.
+var ErrBlockedKey = badKey("public key is forbidden")
+
 // BlockedKeyCheckFunc is used to pass in the sa.BlockedKey functionality to KeyPolicy,
 // rather than storing a full sa.SQLStorageAuthority. This allows external
 // users who don’t want to import all of boulder/sa, and makes testing
@@ -157,7 +161,7 @@ func (policy *KeyPolicy) GoodKey(ctx context.Context, key crypto.PublicKey) erro
 		if err != nil {
 			return err
 		} else if exists {
-			return badKey("public key is forbidden")
+			return ErrBlockedKey
 		}
 	}
 	switch t := key.(type) {
@@ -280,6 +280,7 @@ func TestDBBlocklistReject(t *testing.T) {
 	err = policy.GoodKey(context.Background(), k.Public())
 	test.AssertError(t, err, "GoodKey didn't fail with a blocked key")
 	test.AssertErrorIs(t, err, ErrBadKey)
+	test.AssertErrorIs(t, err, ErrBlockedKey)
 	test.AssertEquals(t, err.Error(), "public key is forbidden")
 }
 
@@ -0,0 +1,25 @@
+package grpc
+
+import "context"
+
+type validationAttemptContextKey struct{}
+
+// ValidationAttemptID returns the ID of the validation attempt which ctx is
+// part of, or "" if it isn't part of one.
+func ValidationAttemptID(ctx context.Context) string {
+	// The below type assertion is safe because this context key can only be
+	// set by this package and is only set to a string.
+	val, ok := ctx.Value(validationAttemptContextKey{}).(string)
+	if !ok {
+		return ""
+	}
+	return val
+}
+
+// WithValidationAttemptID returns a copy of ctx which is part of the
+// validation attempt with the given ID. The ID is sent along with RPCs made
+// with the returned context, and set on the context of unary RPC handlers, so
+// that every perspective consulted for a validation logs the same ID.
+func WithValidationAttemptID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, validationAttemptContextKey{}, id)
+}
@@ -75,7 +75,9 @@ func (tc *clientTransportCredentials) ClientHandshake(ctx context.Context, addr
 		_ = rawConn.Close()
 		return nil, nil, err
 	}
-	return conn, nil, nil
+	// Return the server's certificates, so that callers can tell which server
+	// answered an RPC using grpc.Peer.
+	return conn, credentials.TLSInfo{State: conn.ConnectionState()}, nil
 }
 
 // ServerHandshake is not implemented for a `clientTransportCredentials`, use
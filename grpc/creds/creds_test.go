@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/jmhodges/clock"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/letsencrypt/boulder/test"
 )
@@ -120,9 +121,12 @@ func TestClientTransportCredentials(t *testing.T) {
 		_ = rawConnA.Close()
 	}()
 
-	conn, _, err := tc.ClientHandshake(context.Background(), "A:2020", rawConnA)
+	conn, authInfo, err := tc.ClientHandshake(context.Background(), "A:2020", rawConnA)
 	test.AssertNotError(t, err, "tc.ClientHandshake failed")
 	test.Assert(t, conn != nil, "tc.ClientHandshake returned a nil net.Conn")
+	tlsInfo, ok := authInfo.(credentials.TLSInfo)
+	test.Assert(t, ok, "tc.ClientHandshake didn't return TLS auth info")
+	test.AssertDeepEquals(t, tlsInfo.State.PeerCertificates[0].DNSNames, []string{"A"})
 
 	serverB.StartTLS()
 	defer serverB.Close()
@@ -61,6 +61,13 @@ func wrapError(ctx context.Context, appErr error) error {
 	return appErr
 }
 
+// WrapError is wrapError, for gRPC services which aren't registered with a
+// server built by this package, and so whose handlers' errors are not wrapped
+// by its interceptors.
+func WrapError(ctx context.Context, appErr error) error {
+	return wrapError(ctx, appErr)
+}
+
 // unwrapError unwraps errors returned from gRPC client calls which were wrapped
 // with wrapError to their proper internal error type. If the provided metadata
 // object has an "errortype" field, that will be used to set the type of the
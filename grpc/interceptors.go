@@ -26,6 +26,7 @@ const (
 	meaningfulWorkOverhead = 100 * time.Millisecond
 	clientRequestTimeKey   = "client-request-time"
 	userAgentKey           = "acme-client-user-agent"
+	validationAttemptKey   = "validation-attempt-id"
 )
 
 type serverInterceptor interface {
@@ -94,6 +95,9 @@ func (smi *serverMetadataInterceptor) Unary(
 		if len(md[userAgentKey]) > 0 {
 			ctx = web.WithUserAgent(ctx, md[userAgentKey][0])
 		}
+		if len(md[validationAttemptKey]) > 0 {
+			ctx = WithValidationAttemptID(ctx, md[validationAttemptKey][0])
+		}
 	}
 
 	// Shave 20 milliseconds off the deadline to ensure that if the RPC server times
@@ -281,6 +285,10 @@ func (cmi *clientMetadataInterceptor) Unary(
 		clientRequestTimeKey: nowTS,
 		userAgentKey:         web.UserAgent(ctx),
 	})
+	attemptID := ValidationAttemptID(ctx)
+	if attemptID != "" {
+		reqMD.Set(validationAttemptKey, attemptID)
+	}
 	// Configure the localCtx with the metadata so it gets sent along in the request
 	localCtx = metadata.NewOutgoingContext(localCtx, reqMD)
 
@@ -393,6 +401,10 @@ func (cmi *clientMetadataInterceptor) Stream(
 		clientRequestTimeKey: nowTS,
 		userAgentKey:         web.UserAgent(ctx),
 	})
+	attemptID := ValidationAttemptID(ctx)
+	if attemptID != "" {
+		reqMD.Set(validationAttemptKey, attemptID)
+	}
 	// Configure the localCtx with the metadata so it gets sent along in the request
 	localCtx = metadata.NewOutgoingContext(localCtx, reqMD)
 
@@ -444,6 +444,43 @@ func TestUserAgentMetadata(t *testing.T) {
 	}
 }
 
+// testAttemptIDServer stores the last validation attempt ID it saw in its
+// context.
+type testAttemptIDServer struct {
+	test_proto.UnimplementedChillerServer
+
+	lastSeenID string
+}
+
+// Chill implements ChillerServer.Chill
+func (s *testAttemptIDServer) Chill(ctx context.Context, in *test_proto.Time) (*test_proto.Time, error) {
+	s.lastSeenID = ValidationAttemptID(ctx)
+	return nil, nil
+}
+
+func TestValidationAttemptIDMetadata(t *testing.T) {
+	server := new(testAttemptIDServer)
+	client, _, stop := setup(t, server)
+	defer stop()
+
+	_, err := client.Chill(context.Background(), &test_proto.Time{})
+	if err != nil {
+		t.Fatalf("calling c.Chill: %s", err)
+	}
+	if server.lastSeenID != "" {
+		t.Errorf("last seen attempt ID on server side was %q, want none", server.lastSeenID)
+	}
+
+	testID := "test attempt"
+	_, err = client.Chill(WithValidationAttemptID(context.Background(), testID), &test_proto.Time{})
+	if err != nil {
+		t.Fatalf("calling c.Chill: %s", err)
+	}
+	if server.lastSeenID != testID {
+		t.Errorf("last seen attempt ID on server side was %q, want %q", server.lastSeenID, testID)
+	}
+}
+
 // setup creates a server and client, returning the created client, the running server's port, and a stop function.
 func setup(t *testing.T, server test_proto.ChillerServer, opts ...any) (test_proto.ChillerClient, int, func()) {
 	clk := clock.NewFake()
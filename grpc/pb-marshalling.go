@@ -12,6 +12,7 @@ import (
 
 	"github.com/go-jose/go-jose/v4"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/letsencrypt/boulder/core"
@@ -33,11 +34,39 @@ func ProblemDetailsToPB(prob *probs.ProblemDetails) (*corepb.ProblemDetails, err
 		// nil problemDetails is valid
 		return nil, nil
 	}
-	return &corepb.ProblemDetails{
+	pb := &corepb.ProblemDetails{
 		ProblemType: string(prob.Type),
 		Detail:      prob.Detail,
 		HttpStatus:  int32(prob.HTTPStatus),
-	}, nil
+	}
+	if prob.DNSDetails != nil {
+		pb.DnsDetails = &corepb.DNSDetails{
+			QueryName: prob.DNSDetails.QueryName,
+			QueryType: prob.DNSDetails.QueryType,
+			Rcode:     prob.DNSDetails.RCode,
+			Ede:       prob.DNSDetails.EDE,
+		}
+	}
+	if prob.HTTPDetails != nil {
+		pb.HttpDetails = &corepb.HTTPDetails{
+			FinalURL:   prob.HTTPDetails.FinalURL,
+			StatusCode: int32(prob.HTTPDetails.StatusCode),
+		}
+	}
+	for _, sub := range prob.SubProblems {
+		subPB, err := ProblemDetailsToPB(&sub.ProblemDetails)
+		if err != nil {
+			return nil, err
+		}
+		pb.SubProblems = append(pb.SubProblems, &corepb.SubProblemDetails{
+			Problem: subPB,
+			Identifier: &corepb.Identifier{
+				Type:  string(sub.Identifier.Type),
+				Value: sub.Identifier.Value,
+			},
+		})
+	}
+	return pb, nil
 }
 
 func PBToProblemDetails(in *corepb.ProblemDetails) (*probs.ProblemDetails, error) {
@@ -55,6 +84,39 @@ func PBToProblemDetails(in *corepb.ProblemDetails) (*probs.ProblemDetails, error
 	if in.HttpStatus != 0 {
 		prob.HTTPStatus = int(in.HttpStatus)
 	}
+	if in.DnsDetails != nil {
+		prob.DNSDetails = &probs.DNSDetails{
+			QueryName: in.DnsDetails.QueryName,
+			QueryType: in.DnsDetails.QueryType,
+			RCode:     in.DnsDetails.Rcode,
+			EDE:       in.DnsDetails.Ede,
+		}
+	}
+	if in.HttpDetails != nil {
+		prob.HTTPDetails = &probs.HTTPDetails{
+			FinalURL:   in.HttpDetails.FinalURL,
+			StatusCode: int(in.HttpDetails.StatusCode),
+		}
+	}
+	for _, sub := range in.SubProblems {
+		if sub.Identifier == nil {
+			return nil, ErrMissingParameters
+		}
+		subProb, err := PBToProblemDetails(sub.Problem)
+		if err != nil {
+			return nil, err
+		}
+		if subProb == nil {
+			return nil, ErrMissingParameters
+		}
+		prob.SubProblems = append(prob.SubProblems, probs.SubProblemDetails{
+			ProblemDetails: *subProb,
+			Identifier: identifier.ACMEIdentifier{
+				Type:  identifier.IdentifierType(sub.Identifier.Type),
+				Value: sub.Identifier.Value,
+			},
+		})
+	}
 	return prob, nil
 }
 
@@ -79,6 +141,11 @@ func ChallengeToPB(challenge core.Challenge) (*corepb.Challenge, error) {
 		}
 	}
 
+	var attempts []*corepb.ValidationAttempt
+	for _, a := range challenge.Attempts {
+		attempts = append(attempts, ValidationAttemptToPB(a))
+	}
+
 	return &corepb.Challenge{
 		Type:              string(challenge.Type),
 		Status:            string(challenge.Status),
@@ -86,6 +153,7 @@ func ChallengeToPB(challenge core.Challenge) (*corepb.Challenge, error) {
 		Error:             prob,
 		Validationrecords: recordAry,
 		Validated:         validated,
+		Attempts:          attempts,
 	}, nil
 }
 
@@ -115,6 +183,10 @@ func PBToChallenge(in *corepb.Challenge) (challenge core.Challenge, err error) {
 		val := in.Validated.AsTime()
 		validated = &val
 	}
+	var attempts []core.ValidationAttempt
+	for _, a := range in.Attempts {
+		attempts = append(attempts, PBToValidationAttempt(a))
+	}
 	ch := core.Challenge{
 		Type:             core.AcmeChallenge(in.Type),
 		Status:           core.AcmeStatus(in.Status),
@@ -122,10 +194,55 @@ func PBToChallenge(in *corepb.Challenge) (challenge core.Challenge, err error) {
 		Error:            prob,
 		ValidationRecord: recordAry,
 		Validated:        validated,
+		Attempts:         attempts,
 	}
 	return ch, nil
 }
 
+func ValidationAttemptToPB(attempt core.ValidationAttempt) *corepb.ValidationAttempt {
+	return &corepb.ValidationAttempt{
+		AttemptedAt:  timestamppb.New(attempt.AttemptedAt),
+		ProblemType:  string(attempt.ProblemType),
+		Detail:       attempt.Detail,
+		Perspectives: attempt.Perspectives,
+	}
+}
+
+func PBToValidationAttempt(in *corepb.ValidationAttempt) core.ValidationAttempt {
+	var attemptedAt time.Time
+	if !core.IsAnyNilOrZero(in.GetAttemptedAt()) {
+		attemptedAt = in.AttemptedAt.AsTime()
+	}
+	return core.ValidationAttempt{
+		AttemptedAt:  attemptedAt,
+		ProblemType:  probs.ProblemType(in.GetProblemType()),
+		Detail:       in.GetDetail(),
+		Perspectives: in.GetPerspectives(),
+	}
+}
+
+func PerspectiveResultToPB(result core.PerspectiveResult) *corepb.PerspectiveResult {
+	return &corepb.PerspectiveResult{
+		Perspective: result.Perspective,
+		Rir:         result.RIR,
+		Passed:      result.Passed,
+		ProblemType: string(result.ProblemType),
+		LatencyMs:   result.LatencyMS,
+		Operation:   result.Operation,
+	}
+}
+
+func PBToPerspectiveResult(in *corepb.PerspectiveResult) core.PerspectiveResult {
+	return core.PerspectiveResult{
+		Perspective: in.GetPerspective(),
+		RIR:         in.GetRir(),
+		Passed:      in.GetPassed(),
+		ProblemType: probs.ProblemType(in.GetProblemType()),
+		LatencyMS:   in.GetLatencyMs(),
+		Operation:   in.GetOperation(),
+	}
+}
+
 func ValidationRecordToPB(record core.ValidationRecord) (*corepb.ValidationRecord, error) {
 	addrs := make([][]byte, len(record.AddressesResolved))
 	addrsTried := make([][]byte, len(record.AddressesTried))
@@ -140,14 +257,40 @@ func ValidationRecordToPB(record core.ValidationRecord) (*corepb.ValidationRecor
 	if err != nil {
 		return nil, err
 	}
+	var queriedAt *timestamppb.Timestamp
+	if record.DNSQueriedAt != nil {
+		queriedAt = timestamppb.New(record.DNSQueriedAt.UTC())
+	}
+	var queries []*corepb.DNSQuery
+	for _, q := range record.DNSQueries {
+		queries = append(queries, &corepb.DNSQuery{
+			Resolver:    q.Resolver,
+			Qtype:       q.Qtype,
+			Rtt:         durationpb.New(q.RTT),
+			TcpFallback: q.TCPFallback,
+		})
+	}
 	return &corepb.ValidationRecord{
-		Hostname:          record.DnsName,
-		Port:              record.Port,
-		AddressesResolved: addrs,
-		AddressUsed:       addrUsed,
-		Url:               record.URL,
-		AddressesTried:    addrsTried,
-		ResolverAddrs:     record.ResolverAddrs,
+		Hostname:               record.DnsName,
+		Port:                   record.Port,
+		AddressesResolved:      addrs,
+		AddressUsed:            addrUsed,
+		AddressFamily:          record.AddressFamily,
+		Url:                    record.URL,
+		AddressesTried:         addrsTried,
+		ResolverAddrs:          record.ResolverAddrs,
+		DnsQueries:             queries,
+		CacheBusted:            record.CacheBusted,
+		RetriedAfter:           record.RetriedAfter,
+		HandshakeBytesSent:     record.HandshakeBytesSent,
+		HandshakeBytesReceived: record.HandshakeBytesReceived,
+		BytesReceived:          record.BytesReceived,
+		DnsAnswerDigest:        record.DNSAnswerDigest,
+		DnsQueriedAt:           queriedAt,
+		Protocol:               record.Protocol,
+		ProxyProtocol:          record.ProxyProtocol,
+		TlsALPNFailure:         record.TLSALPNFailure,
+		WebServerProtocol:      record.WebServerProtocol,
 	}, nil
 }
 
@@ -168,14 +311,41 @@ func PBToValidationRecord(in *corepb.ValidationRecord) (record core.ValidationRe
 	if err != nil {
 		return
 	}
+	var queriedAt *time.Time
+	if !core.IsAnyNilOrZero(in.DnsQueriedAt) {
+		val := in.DnsQueriedAt.AsTime()
+		queriedAt = &val
+	}
+	var queries []core.DNSQuery
+	for _, q := range in.DnsQueries {
+		queries = append(queries, core.DNSQuery{
+			Resolver:    q.Resolver,
+			Qtype:       q.Qtype,
+			RTT:         q.Rtt.AsDuration(),
+			TCPFallback: q.TcpFallback,
+		})
+	}
 	return core.ValidationRecord{
-		DnsName:           in.Hostname,
-		Port:              in.Port,
-		AddressesResolved: addrs,
-		AddressUsed:       addrUsed,
-		URL:               in.Url,
-		AddressesTried:    addrsTried,
-		ResolverAddrs:     in.ResolverAddrs,
+		DnsName:                in.Hostname,
+		Port:                   in.Port,
+		AddressesResolved:      addrs,
+		AddressUsed:            addrUsed,
+		AddressFamily:          in.AddressFamily,
+		URL:                    in.Url,
+		AddressesTried:         addrsTried,
+		ResolverAddrs:          in.ResolverAddrs,
+		DNSQueries:             queries,
+		CacheBusted:            in.CacheBusted,
+		RetriedAfter:           in.RetriedAfter,
+		HandshakeBytesSent:     in.HandshakeBytesSent,
+		HandshakeBytesReceived: in.HandshakeBytesReceived,
+		BytesReceived:          in.BytesReceived,
+		DNSAnswerDigest:        in.DnsAnswerDigest,
+		DNSQueriedAt:           queriedAt,
+		Protocol:               in.Protocol,
+		ProxyProtocol:          in.ProxyProtocol,
+		TLSALPNFailure:         in.TlsALPNFailure,
+		WebServerProtocol:      in.WebServerProtocol,
 	}, nil
 }
 
@@ -296,6 +466,7 @@ func AuthzToPB(authz core.Authorization) (*corepb.Authorization, error) {
 		Expires:                expires,
 		Challenges:             challs,
 		CertificateProfileName: authz.CertificateProfileName,
+		FailureCounted:         authz.FailureCounted,
 	}, nil
 }
 
@@ -321,6 +492,7 @@ func PBToAuthz(pb *corepb.Authorization) (core.Authorization, error) {
 		Expires:                expires,
 		Challenges:             challs,
 		CertificateProfileName: pb.CertificateProfileName,
+		FailureCounted:         pb.FailureCounted,
 	}
 	return authz, nil
 }
@@ -7,13 +7,16 @@ import (
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
 	"github.com/letsencrypt/boulder/identifier"
 	"github.com/letsencrypt/boulder/probs"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
 	"github.com/letsencrypt/boulder/test"
+	vapb "github.com/letsencrypt/boulder/va/proto"
 )
 
 const JWK1JSON = `{"kty":"RSA","n":"vuc785P8lBj3fUxyZchF_uZw6WtbxcorqgTyq-qapF5lrO1U82Tp93rpXlmctj6fyFHBVVB5aXnUHJ7LZeVPod7Wnfl8p5OyhlHQHC8BnzdzCqCMKmWZNX5DtETDId0qzU7dPzh0LP0idt5buU7L9QNaabChw3nnaL47iu_1Di5Wp264p2TwACeedv2hfRDjDlJmaQXuS8Rtv9GnRWyC9JBu7XmGvGDziumnJH7Hyzh3VNu-kSPQD3vuAFgMZS6uUzOztCkT0fpOalZI6hqxtWLvXUMj-crXrn-Maavz8qRhpAyp5kcYk3jiHGgQIi7QSK2JIdRJ8APyX9HlmTN5AQ","e":"AQAB"}`
@@ -49,6 +52,48 @@ func TestProblemDetails(t *testing.T) {
 	test.AssertEquals(t, err, ErrMissingParameters)
 }
 
+func TestProblemDetailsSubProblems(t *testing.T) {
+	prob := &probs.ProblemDetails{
+		Type:       probs.CAAProblem,
+		Detail:     "Rechecking CAA failed",
+		HTTPStatus: 403,
+		SubProblems: []probs.SubProblemDetails{
+			{
+				ProblemDetails: probs.ProblemDetails{
+					Type:       probs.CAAProblem,
+					Detail:     "CAA record for example.com prevents issuance",
+					HTTPStatus: 403,
+					SubProblems: []probs.SubProblemDetails{
+						{
+							ProblemDetails: probs.ProblemDetails{Type: probs.DNSProblem, Detail: "SERVFAIL looking up CAA"},
+							Identifier:     identifier.NewDNS("www.example.com"),
+						},
+					},
+				},
+				Identifier: identifier.NewDNS("example.com"),
+			},
+		},
+	}
+	pb, err := ProblemDetailsToPB(prob)
+	test.AssertNotError(t, err, "ProblemDetailsToPB failed")
+	test.AssertEquals(t, len(pb.SubProblems), 1)
+	test.AssertEquals(t, pb.SubProblems[0].Identifier.Value, "example.com")
+	test.AssertEquals(t, len(pb.SubProblems[0].Problem.SubProblems), 1)
+
+	recon, err := PBToProblemDetails(pb)
+	test.AssertNotError(t, err, "PBToProblemDetails failed")
+	test.AssertDeepEquals(t, recon, prob)
+
+	// Subproblems must have an identifier and a problem.
+	pb.SubProblems[0].Identifier = nil
+	_, err = PBToProblemDetails(pb)
+	test.AssertEquals(t, err, ErrMissingParameters)
+	pb.SubProblems[0].Identifier = &corepb.Identifier{Type: "dns", Value: "example.com"}
+	pb.SubProblems[0].Problem = nil
+	_, err = PBToProblemDetails(pb)
+	test.AssertEquals(t, err, ErrMissingParameters)
+}
+
 func TestChallenge(t *testing.T) {
 	var jwk jose.JSONWebKey
 	err := json.Unmarshal([]byte(JWK1JSON), &jwk)
@@ -89,6 +134,18 @@ func TestChallenge(t *testing.T) {
 	test.AssertNotError(t, err, "PBToChallenge failed")
 	test.AssertDeepEquals(t, recon, chall)
 
+	chall.Attempts = []core.ValidationAttempt{
+		{AttemptedAt: validated.Add(-time.Minute), ProblemType: probs.ConnectionProblem, Detail: "refused", Perspectives: "Primary"},
+		{AttemptedAt: validated, Perspectives: "Primary, 3/3 remote perspectives corroborated"},
+	}
+	pb, err = ChallengeToPB(chall)
+	test.AssertNotError(t, err, "ChallengeToPB failed")
+	test.AssertEquals(t, len(pb.Attempts), 2)
+
+	recon, err = PBToChallenge(pb)
+	test.AssertNotError(t, err, "PBToChallenge failed")
+	test.AssertDeepEquals(t, recon, chall)
+
 	_, err = PBToChallenge(nil)
 	test.AssertError(t, err, "PBToChallenge did not fail")
 	test.AssertEquals(t, err, ErrMissingParameters)
@@ -129,6 +186,42 @@ func TestValidationRecord(t *testing.T) {
 	recon, err := PBToValidationRecord(pb)
 	test.AssertNotError(t, err, "PBToValidationRecord failed")
 	test.AssertDeepEquals(t, recon, vr)
+
+	queriedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	dnsVR := core.ValidationRecord{
+		DnsName:       "exampleA.com",
+		ResolverAddrs: []string{"resolver:5353"},
+		DNSQueries: []core.DNSQuery{
+			{Resolver: "resolver:5353", Qtype: "TXT", RTT: 1200 * time.Microsecond},
+			{Resolver: "resolver:5353", Qtype: "TXT", RTT: 3 * time.Millisecond, TCPFallback: true},
+		},
+		DNSAnswerDigest: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		DNSQueriedAt:    &queriedAt,
+	}
+	pb, err = ValidationRecordToPB(dnsVR)
+	test.AssertNotError(t, err, "ValidationRecordToPB failed")
+	recon, err = PBToValidationRecord(pb)
+	test.AssertNotError(t, err, "PBToValidationRecord failed")
+	test.AssertEquals(t, recon.DNSAnswerDigest, dnsVR.DNSAnswerDigest)
+	test.Assert(t, recon.DNSQueriedAt != nil && recon.DNSQueriedAt.Equal(queriedAt), "DNSQueriedAt was not preserved")
+
+	tlsVR := core.ValidationRecord{
+		DnsName:           "exampleA.com",
+		Port:              "443",
+		AddressesResolved: []net.IP{ip},
+		AddressUsed:       ip,
+		AddressFamily:     "ipv4",
+		AddressesTried:    []net.IP{},
+		ResolverAddrs:     []string{"resolver:5353"},
+		TLSALPNFailure:    "alpn_refused",
+		WebServerProtocol: "h2",
+		BytesReceived:     4096,
+	}
+	pb, err = ValidationRecordToPB(tlsVR)
+	test.AssertNotError(t, err, "ValidationRecordToPB failed")
+	recon, err = PBToValidationRecord(pb)
+	test.AssertNotError(t, err, "PBToValidationRecord failed")
+	test.AssertDeepEquals(t, recon, tlsVR)
 }
 
 func TestValidationResult(t *testing.T) {
@@ -164,6 +257,97 @@ func TestValidationResult(t *testing.T) {
 	test.AssertNotError(t, err, "pbToValidationResult failed")
 	test.AssertDeepEquals(t, reconResult, result)
 	test.AssertDeepEquals(t, reconProb, prob)
+
+	// DNS details survive the round trip.
+	prob = &probs.ProblemDetails{
+		Type:       probs.DNSProblem,
+		Detail:     "DNS problem: SERVFAIL looking up CAA for example.com",
+		HTTPStatus: 400,
+		DNSDetails: &probs.DNSDetails{
+			QueryName: "example.com",
+			QueryType: "CAA",
+			RCode:     "SERVFAIL",
+			EDE:       "DNSSEC: Bogus",
+		},
+	}
+	pb, err = ValidationResultToPB(result, prob, "surreal", "ARIN")
+	test.AssertNotError(t, err, "ValidationResultToPB failed")
+	test.AssertEquals(t, pb.Problem.DnsDetails.Rcode, "SERVFAIL")
+	_, reconProb, err = pbToValidationResult(pb)
+	test.AssertNotError(t, err, "pbToValidationResult failed")
+	test.AssertDeepEquals(t, reconProb, prob)
+}
+
+func TestValidationResultHTTPDetails(t *testing.T) {
+	prob := &probs.ProblemDetails{
+		Type:       probs.UnauthorizedProblem,
+		Detail:     "Invalid response from http://example.com/.well-known/acme-challenge/abc: 404",
+		HTTPStatus: 403,
+		HTTPDetails: &probs.HTTPDetails{
+			FinalURL:   "http://example.com/.well-known/acme-challenge/abc",
+			StatusCode: 404,
+		},
+	}
+	pb, err := ValidationResultToPB(nil, prob, "surreal", "ARIN")
+	test.AssertNotError(t, err, "ValidationResultToPB failed")
+	test.AssertEquals(t, pb.Problem.HttpDetails.StatusCode, int32(404))
+	pb.FinalURL = prob.HTTPDetails.FinalURL
+	pb.StatusCode = 404
+
+	// The structured fields survive serialization in the VA's response to the
+	// RA.
+	marshalled, err := proto.Marshal(pb)
+	test.AssertNotError(t, err, "marshalling ValidationResult")
+	var recon vapb.ValidationResult
+	err = proto.Unmarshal(marshalled, &recon)
+	test.AssertNotError(t, err, "unmarshalling ValidationResult")
+	test.AssertEquals(t, recon.FinalURL, prob.HTTPDetails.FinalURL)
+	test.AssertEquals(t, recon.StatusCode, int32(404))
+
+	_, reconProb, err := pbToValidationResult(&recon)
+	test.AssertNotError(t, err, "pbToValidationResult failed")
+	test.AssertDeepEquals(t, reconProb, prob)
+
+	// Results of other challenge types leave them unset.
+	pb, err = ValidationResultToPB(nil, nil, "surreal", "ARIN")
+	test.AssertNotError(t, err, "ValidationResultToPB failed")
+	marshalled, err = proto.Marshal(pb)
+	test.AssertNotError(t, err, "marshalling ValidationResult")
+	recon = vapb.ValidationResult{}
+	err = proto.Unmarshal(marshalled, &recon)
+	test.AssertNotError(t, err, "unmarshalling ValidationResult")
+	test.AssertEquals(t, recon.FinalURL, "")
+	test.AssertEquals(t, recon.StatusCode, int32(0))
+}
+
+func TestPerspectiveResult(t *testing.T) {
+	results := []core.PerspectiveResult{
+		{Perspective: "Primary", RIR: "ARIN", Passed: true, LatencyMS: 12},
+		{Perspective: "surreal", RIR: "RIPE", ProblemType: probs.ConnectionProblem, LatencyMS: 3000},
+	}
+	var resultPBs []*corepb.PerspectiveResult
+	for _, r := range results {
+		resultPBs = append(resultPBs, PerspectiveResultToPB(r))
+	}
+
+	// The results survive serialization in both the VA's response to the RA
+	// and the RA's request to the SA.
+	vaRes, err := proto.Marshal(&vapb.ValidationResult{PerspectiveResults: resultPBs})
+	test.AssertNotError(t, err, "marshalling ValidationResult")
+	var vaRecon vapb.ValidationResult
+	err = proto.Unmarshal(vaRes, &vaRecon)
+	test.AssertNotError(t, err, "unmarshalling ValidationResult")
+
+	saReq, err := proto.Marshal(&sapb.FinalizeAuthorizationRequest{PerspectiveResults: vaRecon.PerspectiveResults})
+	test.AssertNotError(t, err, "marshalling FinalizeAuthorizationRequest")
+	var saRecon sapb.FinalizeAuthorizationRequest
+	err = proto.Unmarshal(saReq, &saRecon)
+	test.AssertNotError(t, err, "unmarshalling FinalizeAuthorizationRequest")
+
+	test.AssertEquals(t, len(saRecon.PerspectiveResults), len(results))
+	for i, r := range saRecon.PerspectiveResults {
+		test.AssertDeepEquals(t, PBToPerspectiveResult(r), results[i])
+	}
 }
 
 func TestRegistration(t *testing.T) {
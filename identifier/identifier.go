@@ -39,6 +39,15 @@ func (i ACMEIdentifier) AsProto() *corepb.Identifier {
 	}
 }
 
+// FromProto returns the ACMEIdentifier represented by the provided proto, the
+// inverse of AsProto.
+func FromProto(ident *corepb.Identifier) ACMEIdentifier {
+	return ACMEIdentifier{
+		Type:  IdentifierType(ident.GetType()),
+		Value: ident.GetValue(),
+	}
+}
+
 // NewDNS is a convenience function for creating an ACMEIdentifier with Type
 // "dns" for a given domain name.
 func NewDNS(domain string) ACMEIdentifier {
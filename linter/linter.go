@@ -8,6 +8,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"fmt"
+	"slices"
 	"strings"
 
 	zlintx509 "github.com/zmap/zcrypto/x509"
@@ -24,6 +25,37 @@ import (
 
 var ErrLinting = fmt.Errorf("failed lint(s)")
 
+// Failure describes a single lint which did not pass.
+type Failure struct {
+	// Name is the name of the lint, e.g. "e_dnsname_not_valid_tld".
+	Name string
+	// Status is the result of the lint, one of lint.Notice, lint.Warn,
+	// lint.Error or lint.Fatal.
+	Status lint.LintStatus
+	// Details is the lint's human-readable description of the failure, and
+	// may be empty.
+	Details string
+}
+
+// Error is returned by Check, CheckCRL and ProcessResultSet when one or more
+// lints did not pass. It wraps ErrLinting.
+type Error struct {
+	// Failures are the lints which did not pass, sorted by name.
+	Failures []Failure
+}
+
+func (e *Error) Error() string {
+	var failedLints []string
+	for _, f := range e.Failures {
+		failedLints = append(failedLints, fmt.Sprintf("%s (%s)", f.Name, f.Details))
+	}
+	return fmt.Sprintf("%s: %s", ErrLinting, strings.Join(failedLints, ", "))
+}
+
+func (e *Error) Unwrap() error {
+	return ErrLinting
+}
+
 // Check accomplishes the entire process of linting: it generates a throwaway
 // signing key, uses that to create a linting cert, and runs a default set of
 // lints (everything except for the ETSI and EV lints) against it. If the
@@ -253,15 +285,20 @@ func makeLintCert(tbs *x509.Certificate, subjectPubKey crypto.PublicKey, issuer
 	return lintCertBytes, lintCert, nil
 }
 
+// ProcessResultSet returns an *Error describing every lint in lintRes which
+// did not pass, or nil if they all passed.
 func ProcessResultSet(lintRes *zlint.ResultSet) error {
 	if lintRes.NoticesPresent || lintRes.WarningsPresent || lintRes.ErrorsPresent || lintRes.FatalsPresent {
-		var failedLints []string
+		var failures []Failure
 		for lintName, result := range lintRes.Results {
 			if result.Status > lint.Pass {
-				failedLints = append(failedLints, fmt.Sprintf("%s (%s)", lintName, result.Details))
+				failures = append(failures, Failure{Name: lintName, Status: result.Status, Details: result.Details})
 			}
 		}
-		return fmt.Errorf("%w: %s", ErrLinting, strings.Join(failedLints, ", "))
+		slices.SortFunc(failures, func(a, b Failure) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		return &Error{Failures: failures}
 	}
 	return nil
 }
@@ -5,9 +5,13 @@ import (
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
+	"errors"
 	"math/big"
 	"testing"
 
+	"github.com/zmap/zlint/v3"
+	"github.com/zmap/zlint/v3/lint"
+
 	"github.com/letsencrypt/boulder/test"
 )
 
@@ -46,3 +50,31 @@ func TestMakeSigner_Unsupported(t *testing.T) {
 func TestMakeIssuer(t *testing.T) {
 
 }
+
+func TestProcessResultSet(t *testing.T) {
+	err := ProcessResultSet(&zlint.ResultSet{
+		Results: map[string]*lint.LintResult{
+			"e_passing": {Status: lint.Pass},
+			"n_na":      {Status: lint.NA},
+		},
+	})
+	test.AssertNotError(t, err, "passing result set should not error")
+
+	err = ProcessResultSet(&zlint.ResultSet{
+		Results: map[string]*lint.LintResult{
+			"w_some_warning": {Status: lint.Warn, Details: "be careful"},
+			"e_passing":      {Status: lint.Pass},
+			"e_some_error":   {Status: lint.Error, Details: "broken"},
+		},
+		WarningsPresent: true,
+		ErrorsPresent:   true,
+	})
+	test.AssertErrorIs(t, err, ErrLinting)
+	test.AssertEquals(t, err.Error(), "failed lint(s): e_some_error (broken), w_some_warning (be careful)")
+	var lintErr *Error
+	test.Assert(t, errors.As(err, &lintErr), "expected a *linter.Error")
+	test.AssertDeepEquals(t, lintErr.Failures, []Failure{
+		{Name: "e_some_error", Status: lint.Error, Details: "broken"},
+		{Name: "w_some_warning", Status: lint.Warn, Details: "be careful"},
+	})
+}
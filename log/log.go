@@ -28,6 +28,7 @@ type Logger interface {
 	Errf(format string, a ...interface{})
 	Warning(msg string)
 	Warningf(format string, a ...interface{})
+	WarningObject(string, interface{})
 	Info(msg string)
 	Infof(format string, a ...interface{})
 	InfoObject(string, interface{})
@@ -293,6 +294,17 @@ func (log *impl) Warningf(format string, a ...interface{}) {
 	log.w.logAtLevel(syslog.LOG_WARNING, format, a...)
 }
 
+// WarningObject logs a WARNING level JSON-serialized object message.
+func (log *impl) WarningObject(msg string, obj interface{}) {
+	jsonObj, err := json.Marshal(obj)
+	if err != nil {
+		log.auditAtLevel(syslog.LOG_ERR, fmt.Sprintf("Object for msg %q could not be serialized to JSON. Raw: %+v", msg, obj))
+		return
+	}
+
+	log.Warningf("%s JSON=%s", msg, jsonObj)
+}
+
 // Info level messages pass through normally.
 func (log *impl) Info(msg string) {
 	log.Infof(msg)
@@ -166,6 +166,19 @@ func TestAuditObject(t *testing.T) {
 	}
 }
 
+func TestWarningObject(t *testing.T) {
+	t.Parallel()
+
+	log := NewMock()
+	log.WarningObject("Prefix", struct{ A string }{A: "B"})
+	test.AssertDeepEquals(t, log.GetAll(), []string{`WARNING: Prefix JSON={"A":"B"}`})
+
+	// An unserializable object is logged as an error instead.
+	log.Clear()
+	log.WarningObject("Prefix", struct{ A chan string }{A: make(chan string)})
+	test.AssertEquals(t, len(log.GetAllMatching("^ERR: ")), 1)
+}
+
 func TestTransmission(t *testing.T) {
 	t.Parallel()
 
@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"errors"
 	"math/rand/v2"
+	"net/http"
 	"os"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	berrors "github.com/letsencrypt/boulder/errors"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/identifier"
+	"github.com/letsencrypt/boulder/probs"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
 
@@ -186,6 +188,11 @@ func (sa *StorageAuthorityReadOnly) GetRegistrationByKey(_ context.Context, req
 	}, nil
 }
 
+// GetEABKey is a mock
+func (sa *StorageAuthorityReadOnly) GetEABKey(_ context.Context, _ *sapb.EABKeyID, _ ...grpc.CallOption) (*sapb.EABKey, error) {
+	return nil, berrors.NotFoundError("EAB key not found")
+}
+
 // GetSerialMetadata is a mock
 func (sa *StorageAuthorityReadOnly) GetSerialMetadata(ctx context.Context, req *sapb.Serial, _ ...grpc.CallOption) (*sapb.SerialMetadata, error) {
 	now := sa.clk.Now()
@@ -411,25 +418,87 @@ func (sa *StorageAuthorityReadOnly) GetOrder(_ context.Context, req *sapb.OrderR
 		validOrder.Status = string(core.StatusProcessing)
 	}
 
+	// Order 11 is invalid, see GetOrderError for its full error
+	if req.Id == 11 {
+		validOrder.Status = string(core.StatusInvalid)
+		validOrder.CertificateSerial = ""
+		validOrder.Error = &corepb.ProblemDetails{
+			ProblemType: string(probs.CAAProblem),
+			Detail:      "Error finalizing order :: Rechecking CAA for \"example.com\" and 1 more identifiers failed. Refer to sub-problems for more information",
+			HttpStatus:  http.StatusForbidden,
+		}
+	}
+
 	return validOrder, nil
 }
 
+// GetOrderError is a mock
+func (sa *StorageAuthorityReadOnly) GetOrderError(ctx context.Context, req *sapb.OrderRequest, _ ...grpc.CallOption) (*corepb.ProblemDetails, error) {
+	if req.Id != 11 {
+		return nil, berrors.NotFoundError("no error found for order ID %d", req.Id)
+	}
+	order, err := sa.GetOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	prob := &corepb.ProblemDetails{
+		ProblemType: order.Error.ProblemType,
+		Detail:      order.Error.Detail,
+		HttpStatus:  order.Error.HttpStatus,
+	}
+	for _, name := range []string{"example.com", "www.example.com"} {
+		prob.SubProblems = append(prob.SubProblems, &corepb.SubProblemDetails{
+			Problem: &corepb.ProblemDetails{
+				ProblemType: string(probs.CAAProblem),
+				Detail:      "CAA record for " + name + " prevents issuance",
+				HttpStatus:  http.StatusForbidden,
+			},
+			Identifier: &corepb.Identifier{Type: string(identifier.TypeDNS), Value: name},
+		})
+	}
+	return prob, nil
+}
+
 func (sa *StorageAuthorityReadOnly) GetOrderForNames(_ context.Context, _ *sapb.GetOrderForNamesRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
 	return nil, nil
 }
 
+// GetPendingOrdersForAuthorization is a mock
+func (sa *StorageAuthorityReadOnly) GetPendingOrdersForAuthorization(_ context.Context, _ *sapb.GetPendingOrdersForAuthorizationRequest, _ ...grpc.CallOption) (*sapb.OrderIDs, error) {
+	return &sapb.OrderIDs{}, nil
+}
+
+// GetAbandonedOrders is a mock
+func (sa *StorageAuthorityReadOnly) GetAbandonedOrders(_ context.Context, _ *sapb.GetAbandonedOrdersRequest, _ ...grpc.CallOption) (*sapb.Orders, error) {
+	return &sapb.Orders{}, nil
+}
+
 func (sa *StorageAuthority) FinalizeAuthorization2(ctx context.Context, req *sapb.FinalizeAuthorizationRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
 	return &emptypb.Empty{}, nil
 }
 
+// AddValidationAttempt is a mock
+func (sa *StorageAuthority) AddValidationAttempt(ctx context.Context, req *sapb.AddValidationAttemptRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
 func (sa *StorageAuthority) DeactivateAuthorization2(ctx context.Context, req *sapb.AuthorizationID2, _ ...grpc.CallOption) (*emptypb.Empty, error) {
 	return nil, nil
 }
 
+// SetAuthorizationFailureCounted is a mock which always reports a change
+func (sa *StorageAuthority) SetAuthorizationFailureCounted(ctx context.Context, req *sapb.SetAuthorizationFailureCountedRequest, _ ...grpc.CallOption) (*sapb.SetAuthorizationFailureCountedResponse, error) {
+	return &sapb.SetAuthorizationFailureCountedResponse{Changed: true}, nil
+}
+
 func (sa *StorageAuthorityReadOnly) CountPendingAuthorizations2(ctx context.Context, req *sapb.RegistrationID, _ ...grpc.CallOption) (*sapb.Count, error) {
 	return &sapb.Count{}, nil
 }
 
+func (sa *StorageAuthorityReadOnly) GetPendingAuthorizationsByExpiry(ctx context.Context, req *sapb.GetPendingAuthorizationsByExpiryRequest, _ ...grpc.CallOption) (*sapb.Authorizations, error) {
+	return &sapb.Authorizations{}, nil
+}
+
 func (sa *StorageAuthorityReadOnly) GetValidOrderAuthorizations2(ctx context.Context, req *sapb.GetValidOrderAuthorizationsRequest, _ ...grpc.CallOption) (*sapb.Authorizations, error) {
 	return nil, nil
 }
@@ -0,0 +1,109 @@
+package nonce
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HMACKey is the long-lived secret from which a persistent nonce service
+// derives its encryption key, as provided to NewPersistentNonceService.
+type HMACKey []byte
+
+// Inspection describes a nonce as decoded by Inspect.
+type Inspection struct {
+	// Prefix is the prefix of the nonce, which identifies the nonce service
+	// that issued it.
+	Prefix string `json:"prefix"`
+
+	// StructurallyValid is true if the nonce has a valid base64url prefix and
+	// body of the expected length, regardless of whether any key verifies it.
+	StructurallyValid bool `json:"structurallyValid"`
+
+	// Key is "current" if the first of the provided keys verifies the nonce,
+	// "previous" if a later one does, and empty if none do.
+	Key string `json:"key,omitempty"`
+
+	// KeyIndex is the index of the key which verifies the nonce, or -1 if none
+	// do.
+	KeyIndex int `json:"keyIndex"`
+
+	// Counter is the counter embedded in the nonce. It is only set if a key
+	// verifies the nonce.
+	Counter int64 `json:"counter,omitempty"`
+
+	// Expires is when the nonce expires. It is only set if a key verifies the
+	// nonce and the nonce carries its expiry.
+	Expires *time.Time `json:"expires,omitempty"`
+
+	// Error describes why the nonce is not structurally valid or why no key
+	// verifies it.
+	Error string `json:"error,omitempty"`
+}
+
+// Inspect decodes a nonce issued by a persistent nonce service, using each of
+// the provided keys in turn, the first of which is the current key. Unlike
+// NonceService.Valid it requires no service or Store and doesn't redeem the
+// nonce, so it's suitable for debugging badNonce reports.
+func Inspect(nonce string, keys []HMACKey) Inspection {
+	result := Inspection{KeyIndex: -1}
+	expectedLen := PrefixLen + base64.RawURLEncoding.EncodedLen(NonceLen)
+	legacyLen := PrefixLen + base64.RawURLEncoding.EncodedLen(LegacyNonceLen)
+	if len(nonce) != expectedLen && len(nonce) != legacyLen {
+		result.Error = fmt.Sprintf("nonce is %d characters, expected %d", len(nonce), expectedLen)
+		if len(nonce) >= PrefixLen {
+			result.Prefix = nonce[:PrefixLen]
+		}
+		return result
+	}
+	result.Prefix = nonce[:PrefixLen]
+	_, err := base64.RawURLEncoding.DecodeString(result.Prefix)
+	if err != nil {
+		result.Error = "nonce prefix is not valid base64url"
+		return result
+	}
+	decoded, err := decodeBody(nonce[PrefixLen:])
+	if err != nil {
+		result.Error = fmt.Sprintf("decoding nonce body: %s", err)
+		return result
+	}
+	result.StructurallyValid = true
+
+	for i, key := range keys {
+		c, err := aes.NewCipher(persistentEncryptionKey(key, result.Prefix))
+		if err != nil {
+			result.Error = fmt.Sprintf("creating cipher for key %d: %s", i, err)
+			return result
+		}
+		gcm, err := cipher.NewGCM(c)
+		if err != nil {
+			result.Error = fmt.Sprintf("creating cipher for key %d: %s", i, err)
+			return result
+		}
+		counter, expires, err := openBody(gcm, decoded)
+		if err != nil {
+			continue
+		}
+		result.KeyIndex = i
+		result.Key = "previous"
+		if i == 0 {
+			result.Key = "current"
+		}
+		result.Counter = counter
+		if !expires.IsZero() {
+			result.Expires = &expires
+		}
+		return result
+	}
+	result.Error = "no key verifies the nonce"
+	return result
+}
+
+// InspectJSON is like Inspect, but returns the result as indented JSON for
+// display by command line tools.
+func InspectJSON(nonce string, keys []HMACKey) ([]byte, error) {
+	return json.MarshalIndent(Inspect(nonce, keys), "", "  ")
+}
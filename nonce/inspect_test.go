@@ -0,0 +1,101 @@
+package nonce
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestInspect(t *testing.T) {
+	current := HMACKey("3b8c758dd85e113ea340ce0b3a99f389d40a308548af94d1730a7692c1874f1f")
+	previous := HMACKey("f1f4781c2967a0371d49fa845803a04d983f99a3b0ec043ae311e58dd857c8b3")
+
+	ns, err := NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", current, newMemStore(clock.NewFake()), time.Hour)
+	test.AssertNotError(t, err, "Could not create nonce service")
+	_, err = ns.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+
+	// Get the nonce the server returns to a Getter, as the WFE does.
+	msg, err := NewServer(ns).Nonce(context.Background(), &emptypb.Empty{})
+	test.AssertNotError(t, err, "Could not get nonce")
+	n := msg.Nonce
+
+	// The first of the provided keys is current.
+	result := Inspect(n, []HMACKey{current})
+	expires := time.Unix(0, 0).Add(time.Hour).UTC()
+	test.AssertDeepEquals(t, result, Inspection{
+		Prefix:            "aluminum",
+		StructurallyValid: true,
+		Key:               "current",
+		KeyIndex:          0,
+		Counter:           2,
+		Expires:           &expires,
+	})
+
+	// Any later key is previous.
+	result = Inspect(n, []HMACKey{previous, current})
+	test.AssertEquals(t, result.Key, "previous")
+	test.AssertEquals(t, result.KeyIndex, 1)
+	test.AssertEquals(t, result.Counter, int64(2))
+
+	// Inspecting the nonce doesn't redeem it.
+	test.Assert(t, ns.Valid(context.Background(), n), "Rejected an inspected nonce")
+
+	// A nonce no key verifies is structurally valid, but has no counter.
+	result = Inspect(n, []HMACKey{previous})
+	test.AssertDeepEquals(t, result, Inspection{
+		Prefix:            "aluminum",
+		StructurallyValid: true,
+		KeyIndex:          -1,
+		Error:             "no key verifies the nonce",
+	})
+
+	// Neither is a nonce with a different prefix.
+	result = Inspect("aluminun"+n[PrefixLen:], []HMACKey{current})
+	test.Assert(t, result.StructurallyValid, "Nonce with another prefix should be structurally valid")
+	test.AssertEquals(t, result.KeyIndex, -1)
+
+	// A truncated nonce isn't structurally valid.
+	result = Inspect(n[:len(n)-1], []HMACKey{current})
+	test.Assert(t, !result.StructurallyValid, "Truncated nonce should not be structurally valid")
+	test.AssertEquals(t, result.Prefix, "aluminum")
+	test.AssertContains(t, result.Error, "nonce is 55 characters, expected 56")
+
+	result = Inspect("alu", []HMACKey{current})
+	test.Assert(t, !result.StructurallyValid, "Truncated nonce should not be structurally valid")
+	test.AssertEquals(t, result.Prefix, "")
+
+	// Nor is a nonce of the right length which isn't base64url.
+	result = Inspect("aluminum"+n[PrefixLen:len(n)-1]+"!", []HMACKey{current})
+	test.Assert(t, !result.StructurallyValid, "Nonce with an invalid body should not be structurally valid")
+	test.AssertContains(t, result.Error, "decoding nonce body")
+}
+
+func TestInspectJSON(t *testing.T) {
+	key := HMACKey("key")
+	ns, err := NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", key, newMemStore(clock.NewFake()), time.Hour)
+	test.AssertNotError(t, err, "Could not create nonce service")
+	n, err := ns.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+
+	out, err := InspectJSON(n, []HMACKey{key})
+	test.AssertNotError(t, err, "Could not format inspection")
+	var decoded map[string]any
+	err = json.Unmarshal(out, &decoded)
+	test.AssertNotError(t, err, "Could not unmarshal inspection")
+	test.AssertDeepEquals(t, decoded, map[string]any{
+		"prefix":            "aluminum",
+		"structurallyValid": true,
+		"key":               "current",
+		"keyIndex":          float64(0),
+		"counter":           float64(1),
+		"expires":           "1970-01-01T01:00:00Z",
+	})
+}
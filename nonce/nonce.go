@@ -11,6 +11,18 @@
 // The MaxUsed value determines how long a generated nonce can be used before it
 // is forgotten. To calculate that period, divide the MaxUsed value by average
 // redemption rate (valid POSTs per second).
+//
+// Each nonce also carries its expiry, encrypted alongside the counter, so that
+// an expired nonce can be rejected without consulting the cross-off list or
+// Store.
+//
+// Alternatively, the counter and the set of outstanding nonces can be kept in a
+// Store which outlives the nonce service, such as Redis. In that case the
+// encryption key is derived from a long-lived secret rather than generated at
+// startup, so that a restarted instance with the same key, prefix, and Store
+// can redeem the nonces issued before it restarted. Each nonce is then
+// forgotten once it reaches its maximum age, rather than after MaxUsed
+// redemptions.
 package nonce
 
 import (
@@ -22,12 +34,14 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
 	"sync"
 	"time"
 
+	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -39,9 +53,26 @@ const (
 	// PrefixLen is the character length of a nonce prefix.
 	PrefixLen = 8
 
-	// NonceLen is the character length of a nonce, excluding the prefix.
-	NonceLen       = 32
+	// NonceLen is the byte length of a decoded nonce, excluding the prefix.
+	//
+	// A WFE from before nonces carried their expiry rejects nonces of this
+	// length as malformed, so when upgrading from such a release, deploy the
+	// WFE before the nonce service. The WFE accepts both lengths.
+	NonceLen = 36
+
+	// LegacyNonceLen is the byte length of a decoded nonce, excluding the
+	// prefix, issued before nonces carried their expiry.
+	//
+	// TODO: Remove once no nonces of this length remain outstanding.
+	LegacyNonceLen = 32
+
 	defaultMaxUsed = 65536
+	defaultMaxAge  = time.Hour
+
+	// expirySkew is how long after its encoded expiry a nonce is still
+	// accepted, to tolerate small differences between the clocks of the nonce
+	// services sharing a Store.
+	expirySkew = 5 * time.Second
 )
 
 var errInvalidNonceLength = errors.New("invalid nonce length")
@@ -61,6 +92,18 @@ func DerivePrefix(grpcAddr string, key []byte) string {
 	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))[:PrefixLen]
 }
 
+// Store persists the counter and the outstanding nonces of a NonceService so
+// that they survive a restart.
+type Store interface {
+	// Issue increments the persisted counter and returns its new value, which
+	// must remain redeemable for ttl.
+	Issue(ctx context.Context, ttl time.Duration) (int64, error)
+
+	// Redeem returns true if counter was returned by Issue, has not expired,
+	// and has not already been redeemed. Otherwise it returns false.
+	Redeem(ctx context.Context, counter int64) (bool, error)
+}
+
 // NonceService generates, cancels, and tracks Nonces.
 type NonceService struct {
 	mu               sync.Mutex
@@ -71,6 +114,10 @@ type NonceService struct {
 	gcm              cipher.AEAD
 	maxUsed          int
 	prefix           string
+	store            Store
+	maxAge           time.Duration
+	clk              clock.Clock
+	legacyCutoff     time.Time
 	nonceCreates     prometheus.Counter
 	nonceEarliest    prometheus.Gauge
 	nonceRedeems     *prometheus.CounterVec
@@ -95,8 +142,50 @@ func (h *int64Heap) Pop() interface{} {
 	return x
 }
 
-// NewNonceService constructs a NonceService with defaults
-func NewNonceService(stats prometheus.Registerer, maxUsed int, prefix string) (*NonceService, error) {
+// NewNonceService constructs a NonceService with defaults. Nonces are
+// redeemable for at most maxLifetime, which defaults to one hour.
+func NewNonceService(stats prometheus.Registerer, clk clock.Clock, maxUsed int, maxLifetime time.Duration, prefix string) (*NonceService, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return newNonceService(stats, clk, maxUsed, maxLifetime, prefix, key)
+}
+
+// NewPersistentNonceService constructs a NonceService which keeps its counter
+// and outstanding nonces in the provided Store. The encryption key is derived
+// from the provided HMAC key and prefix, both of which are required, so that
+// any instance with the same key, prefix, and Store can redeem the nonces
+// issued by another. Nonces are redeemable for at most maxAge.
+func NewPersistentNonceService(stats prometheus.Registerer, clk clock.Clock, prefix string, hmacKey []byte, store Store, maxAge time.Duration) (*NonceService, error) {
+	if prefix == "" || len(hmacKey) == 0 {
+		return nil, errors.New("nonce prefix and HMAC key are required for a persistent nonce service")
+	}
+	if store == nil {
+		return nil, errors.New("store is required for a persistent nonce service")
+	}
+	if maxAge <= 0 {
+		maxAge = defaultMaxAge
+	}
+
+	ns, err := newNonceService(stats, clk, 0, maxAge, prefix, persistentEncryptionKey(hmacKey, prefix))
+	if err != nil {
+		return nil, err
+	}
+	ns.store = store
+	ns.maxAge = maxAge
+	return ns, nil
+}
+
+// persistentEncryptionKey derives the encryption key of a persistent nonce
+// service from its HMAC key and prefix.
+func persistentEncryptionKey(hmacKey []byte, prefix string) []byte {
+	h := hmac.New(sha256.New, hmacKey)
+	h.Write([]byte("nonce-encryption-key:" + prefix))
+	return h.Sum(nil)[:16]
+}
+
+func newNonceService(stats prometheus.Registerer, clk clock.Clock, maxUsed int, maxLifetime time.Duration, prefix string, key []byte) (*NonceService, error) {
 	// If a prefix is provided it must be eight characters and valid base64. The
 	// prefix is required to be base64url as RFC8555 section 6.5.1 requires that
 	// nonces use that encoding. As base64 operates on three byte binary segments
@@ -115,11 +204,6 @@ func NewNonceService(stats prometheus.Registerer, maxUsed int, prefix string) (*
 		}
 	}
 
-	key := make([]byte, 16)
-	if _, err := rand.Read(key); err != nil {
-		return nil, err
-	}
-
 	c, err := aes.NewCipher(key)
 	if err != nil {
 		panic("Failure in NewCipher: " + err.Error())
@@ -132,6 +216,9 @@ func NewNonceService(stats prometheus.Registerer, maxUsed int, prefix string) (*
 	if maxUsed <= 0 {
 		maxUsed = defaultMaxUsed
 	}
+	if maxLifetime <= 0 {
+		maxLifetime = defaultMaxAge
+	}
 
 	nonceCreates := prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "nonce_creates",
@@ -155,13 +242,19 @@ func NewNonceService(stats prometheus.Registerer, maxUsed int, prefix string) (*
 	stats.MustRegister(nonceHeapLatency)
 
 	return &NonceService{
-		earliest:         0,
-		latest:           0,
-		used:             make(map[int64]bool, maxUsed),
-		usedHeap:         &int64Heap{},
-		gcm:              gcm,
-		maxUsed:          maxUsed,
-		prefix:           prefix,
+		earliest: 0,
+		latest:   0,
+		used:     make(map[int64]bool, maxUsed),
+		usedHeap: &int64Heap{},
+		gcm:      gcm,
+		maxUsed:  maxUsed,
+		prefix:   prefix,
+		maxAge:   maxLifetime,
+		clk:      clk,
+		// Nonces issued before nonces carried their expiry are accepted for
+		// one lifetime after startup, after which any still outstanding would
+		// have expired anyway.
+		legacyCutoff:     clk.Now().Add(maxLifetime),
 		nonceCreates:     nonceCreates,
 		nonceEarliest:    nonceEarliest,
 		nonceRedeems:     nonceRedeems,
@@ -169,7 +262,7 @@ func NewNonceService(stats prometheus.Registerer, maxUsed int, prefix string) (*
 	}, nil
 }
 
-func (ns *NonceService) encrypt(counter int64) (string, error) {
+func (ns *NonceService) encrypt(counter int64, expires time.Time) (string, error) {
 	// Generate a nonce with upper 4 bytes zero
 	nonce := make([]byte, 12)
 	for i := range 4 {
@@ -180,11 +273,13 @@ func (ns *NonceService) encrypt(counter int64) (string, error) {
 		return "", err
 	}
 
-	// Encode counter to plaintext
-	pt := make([]byte, 8)
+	// Encode counter and expiry to plaintext. The expiry is rounded up to the
+	// next second.
+	pt := make([]byte, 12)
 	ctr := big.NewInt(counter)
 	pad := 8 - len(ctr.Bytes())
 	copy(pt[pad:], ctr.Bytes())
+	binary.BigEndian.PutUint32(pt[8:], uint32(expires.Add(time.Second-1).Unix()))
 
 	// Encrypt
 	ret := make([]byte, NonceLen)
@@ -195,62 +290,125 @@ func (ns *NonceService) encrypt(counter int64) (string, error) {
 	return ns.prefix + base64.RawURLEncoding.EncodeToString(ret), nil
 }
 
-func (ns *NonceService) decrypt(nonce string) (int64, error) {
+// decrypt returns the counter and expiry of a nonce. The expiry is the zero
+// time for a nonce of LegacyNonceLen.
+func (ns *NonceService) decrypt(nonce string) (int64, time.Time, error) {
 	body := nonce
 	if ns.prefix != "" {
 		var prefix string
 		var err error
 		prefix, body, err = ns.splitNonce(nonce)
 		if err != nil {
-			return 0, err
+			return 0, time.Time{}, err
 		}
 		if ns.prefix != prefix {
-			return 0, fmt.Errorf("nonce contains invalid prefix: expected %q, got %q", ns.prefix, prefix)
+			return 0, time.Time{}, fmt.Errorf("nonce contains invalid prefix: expected %q, got %q", ns.prefix, prefix)
 		}
 	}
+	decoded, err := decodeBody(body)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return openBody(ns.gcm, decoded)
+}
+
+// decodeBody decodes the body of a nonce, excluding its prefix.
+func decodeBody(body string) ([]byte, error) {
 	decoded, err := base64.RawURLEncoding.DecodeString(body)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	if len(decoded) != NonceLen {
-		return 0, errInvalidNonceLength
+	if len(decoded) != NonceLen && len(decoded) != LegacyNonceLen {
+		return nil, errInvalidNonceLength
 	}
+	return decoded, nil
+}
 
+// openBody decrypts a decoded nonce body and returns the counter and expiry
+// it contains. The expiry is the zero time for a nonce of LegacyNonceLen.
+func openBody(gcm cipher.AEAD, decoded []byte) (int64, time.Time, error) {
 	n := make([]byte, 12)
 	for i := range 4 {
 		n[i] = 0
 	}
 	copy(n[4:], decoded[:8])
 
-	pt, err := ns.gcm.Open(nil, n, decoded[8:], nil)
+	pt, err := gcm.Open(nil, n, decoded[8:], nil)
 	if err != nil {
-		return 0, err
+		return 0, time.Time{}, err
 	}
 
+	var expires time.Time
+	if len(pt) == 12 {
+		expires = time.Unix(int64(binary.BigEndian.Uint32(pt[8:])), 0).UTC()
+		pt = pt[:8]
+	}
 	ctr := big.NewInt(0)
 	ctr.SetBytes(pt)
-	return ctr.Int64(), nil
+	return ctr.Int64(), expires, nil
 }
 
 // Nonce provides a new Nonce.
-func (ns *NonceService) Nonce() (string, error) {
+func (ns *NonceService) Nonce(ctx context.Context) (string, error) {
+	if ns.store != nil {
+		counter, err := ns.store.Issue(ctx, ns.maxAge)
+		if err != nil {
+			return "", fmt.Errorf("issuing nonce counter: %w", err)
+		}
+		defer ns.nonceCreates.Inc()
+		return ns.encrypt(counter, ns.clk.Now().Add(ns.maxAge))
+	}
+
 	ns.mu.Lock()
 	ns.latest++
 	latest := ns.latest
 	ns.mu.Unlock()
 	defer ns.nonceCreates.Inc()
-	return ns.encrypt(latest)
+	return ns.encrypt(latest, ns.clk.Now().Add(ns.maxAge))
+}
+
+// expired returns true if a nonce with the given expiry, as returned by
+// decrypt, may no longer be redeemed.
+func (ns *NonceService) expired(expires time.Time) bool {
+	now := ns.clk.Now()
+	if expires.IsZero() {
+		return !now.Before(ns.legacyCutoff)
+	}
+	return now.After(expires.Add(expirySkew))
 }
 
 // Valid determines whether the provided Nonce string is valid, returning
 // true if so.
-func (ns *NonceService) Valid(nonce string) bool {
-	c, err := ns.decrypt(nonce)
+func (ns *NonceService) Valid(ctx context.Context, nonce string) bool {
+	c, expires, err := ns.decrypt(nonce)
 	if err != nil {
 		ns.nonceRedeems.WithLabelValues("invalid", "decrypt").Inc()
 		return false
 	}
 
+	// An expired nonce is rejected before it's looked up, and isn't crossed
+	// off.
+	if ns.expired(expires) {
+		ns.nonceRedeems.WithLabelValues("invalid", "expired").Inc()
+		return false
+	}
+
+	if ns.store != nil {
+		// The Store can't distinguish a nonce which was already used from one
+		// which expired or was never issued.
+		ok, err := ns.store.Redeem(ctx, c)
+		if err != nil {
+			ns.nonceRedeems.WithLabelValues("invalid", "store").Inc()
+			return false
+		}
+		if !ok {
+			ns.nonceRedeems.WithLabelValues("invalid", "already used").Inc()
+			return false
+		}
+		ns.nonceRedeems.WithLabelValues("valid", "").Inc()
+		return true
+	}
+
 	ns.mu.Lock()
 	defer ns.mu.Unlock()
 	if c > ns.latest {
@@ -305,12 +463,12 @@ var _ noncepb.NonceServiceServer = (*Server)(nil)
 
 // Redeem accepts a nonce from a gRPC client and redeems it using the inner nonce service.
 func (ns *Server) Redeem(ctx context.Context, msg *noncepb.NonceMessage) (*noncepb.ValidMessage, error) {
-	return &noncepb.ValidMessage{Valid: ns.inner.Valid(msg.Nonce)}, nil
+	return &noncepb.ValidMessage{Valid: ns.inner.Valid(ctx, msg.Nonce)}, nil
 }
 
 // Nonce generates a nonce and sends it to a gRPC client.
-func (ns *Server) Nonce(_ context.Context, _ *emptypb.Empty) (*noncepb.NonceMessage, error) {
-	nonce, err := ns.inner.Nonce()
+func (ns *Server) Nonce(ctx context.Context, _ *emptypb.Empty) (*noncepb.NonceMessage, error) {
+	nonce, err := ns.inner.Nonce(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -1,106 +1,113 @@
 package nonce
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
+	"math/big"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
 
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/test"
 )
 
 func TestValidNonce(t *testing.T) {
-	ns, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	test.AssertNotError(t, err, "Could not create nonce service")
-	n, err := ns.Nonce()
+	n, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
-	test.Assert(t, ns.Valid(n), fmt.Sprintf("Did not recognize fresh nonce %s", n))
+	test.Assert(t, ns.Valid(context.Background(), n), fmt.Sprintf("Did not recognize fresh nonce %s", n))
 }
 
 func TestAlreadyUsed(t *testing.T) {
-	ns, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	test.AssertNotError(t, err, "Could not create nonce service")
-	n, err := ns.Nonce()
+	n, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
-	test.Assert(t, ns.Valid(n), "Did not recognize fresh nonce")
-	test.Assert(t, !ns.Valid(n), "Recognized the same nonce twice")
+	test.Assert(t, ns.Valid(context.Background(), n), "Did not recognize fresh nonce")
+	test.Assert(t, !ns.Valid(context.Background(), n), "Recognized the same nonce twice")
 }
 
 func TestRejectMalformed(t *testing.T) {
-	ns, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	test.AssertNotError(t, err, "Could not create nonce service")
-	n, err := ns.Nonce()
+	n, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
-	test.Assert(t, !ns.Valid("asdf"+n), "Accepted an invalid nonce")
+	test.Assert(t, !ns.Valid(context.Background(), "asdf"+n), "Accepted an invalid nonce")
 }
 
 func TestRejectShort(t *testing.T) {
-	ns, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	test.AssertNotError(t, err, "Could not create nonce service")
-	test.Assert(t, !ns.Valid("aGkK"), "Accepted an invalid nonce")
+	test.Assert(t, !ns.Valid(context.Background(), "aGkK"), "Accepted an invalid nonce")
 }
 
 func TestRejectUnknown(t *testing.T) {
-	ns1, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns1, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	test.AssertNotError(t, err, "Could not create nonce service")
-	ns2, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns2, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	test.AssertNotError(t, err, "Could not create nonce service")
 
-	n, err := ns1.Nonce()
+	n, err := ns1.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
-	test.Assert(t, !ns2.Valid(n), "Accepted a foreign nonce")
+	test.Assert(t, !ns2.Valid(context.Background(), n), "Accepted a foreign nonce")
 }
 
 func TestRejectTooLate(t *testing.T) {
-	ns, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	test.AssertNotError(t, err, "Could not create nonce service")
 
 	ns.latest = 2
-	n, err := ns.Nonce()
+	n, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
 	ns.latest = 1
-	test.Assert(t, !ns.Valid(n), "Accepted a nonce with a too-high counter")
+	test.Assert(t, !ns.Valid(context.Background(), n), "Accepted a nonce with a too-high counter")
 }
 
 func TestRejectTooEarly(t *testing.T) {
-	ns, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	test.AssertNotError(t, err, "Could not create nonce service")
 
-	n0, err := ns.Nonce()
+	n0, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
 
 	for range ns.maxUsed {
-		n, err := ns.Nonce()
+		n, err := ns.Nonce(context.Background())
 		test.AssertNotError(t, err, "Could not create nonce")
-		if !ns.Valid(n) {
+		if !ns.Valid(context.Background(), n) {
 			t.Errorf("generated invalid nonce")
 		}
 	}
 
-	n1, err := ns.Nonce()
+	n1, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
-	n2, err := ns.Nonce()
+	n2, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
-	n3, err := ns.Nonce()
+	n3, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
 
-	test.Assert(t, ns.Valid(n3), "Rejected a valid nonce")
-	test.Assert(t, ns.Valid(n2), "Rejected a valid nonce")
-	test.Assert(t, ns.Valid(n1), "Rejected a valid nonce")
-	test.Assert(t, !ns.Valid(n0), "Accepted a nonce that we should have forgotten")
+	test.Assert(t, ns.Valid(context.Background(), n3), "Rejected a valid nonce")
+	test.Assert(t, ns.Valid(context.Background(), n2), "Rejected a valid nonce")
+	test.Assert(t, ns.Valid(context.Background(), n1), "Rejected a valid nonce")
+	test.Assert(t, !ns.Valid(context.Background(), n0), "Accepted a nonce that we should have forgotten")
 }
 
 func BenchmarkNonces(b *testing.B) {
-	ns, err := NewNonceService(metrics.NoopRegisterer, 0, "")
+	ns, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "")
 	if err != nil {
 		b.Fatal("creating nonce service", err)
 	}
 
 	for range ns.maxUsed {
-		n, err := ns.Nonce()
+		n, err := ns.Nonce(context.Background())
 		if err != nil {
 			b.Fatal("noncing", err)
 		}
-		if !ns.Valid(n) {
+		if !ns.Valid(context.Background(), n) {
 			b.Fatal("generated invalid nonce")
 		}
 	}
@@ -108,11 +115,11 @@ func BenchmarkNonces(b *testing.B) {
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			n, err := ns.Nonce()
+			n, err := ns.Nonce(context.Background())
 			if err != nil {
 				b.Fatal("noncing", err)
 			}
-			if !ns.Valid(n) {
+			if !ns.Valid(context.Background(), n) {
 				b.Fatal("generated invalid nonce")
 			}
 		}
@@ -120,32 +127,200 @@ func BenchmarkNonces(b *testing.B) {
 }
 
 func TestNoncePrefixing(t *testing.T) {
-	ns, err := NewNonceService(metrics.NoopRegisterer, 0, "aluminum")
+	ns, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "aluminum")
 	test.AssertNotError(t, err, "Could not create nonce service")
 
-	n, err := ns.Nonce()
+	n, err := ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
-	test.Assert(t, ns.Valid(n), "Valid nonce rejected")
+	test.Assert(t, ns.Valid(context.Background(), n), "Valid nonce rejected")
 
-	n, err = ns.Nonce()
+	n, err = ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
 	n = n[1:]
-	test.Assert(t, !ns.Valid(n), "Valid nonce with incorrect prefix accepted")
+	test.Assert(t, !ns.Valid(context.Background(), n), "Valid nonce with incorrect prefix accepted")
 
-	n, err = ns.Nonce()
+	n, err = ns.Nonce(context.Background())
 	test.AssertNotError(t, err, "Could not create nonce")
-	test.Assert(t, !ns.Valid(n[6:]), "Valid nonce without prefix accepted")
+	test.Assert(t, !ns.Valid(context.Background(), n[6:]), "Valid nonce without prefix accepted")
 }
 
 func TestNoncePrefixValidation(t *testing.T) {
-	_, err := NewNonceService(metrics.NoopRegisterer, 0, "whatsup")
+	_, err := NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "whatsup")
 	test.AssertError(t, err, "NewNonceService didn't fail with short prefix")
-	_, err = NewNonceService(metrics.NoopRegisterer, 0, "whatsup!")
+	_, err = NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "whatsup!")
 	test.AssertError(t, err, "NewNonceService didn't fail with invalid base64")
-	_, err = NewNonceService(metrics.NoopRegisterer, 0, "whatsupp")
+	_, err = NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 0, 0, "whatsupp")
 	test.AssertNotError(t, err, "NewNonceService failed with valid nonce prefix")
 }
 
+func TestNonceExpiry(t *testing.T) {
+	clk := clock.NewFake()
+	clk.Set(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	ns, err := NewNonceService(metrics.NoopRegisterer, clk, 0, 10*time.Minute, "")
+	test.AssertNotError(t, err, "Could not create nonce service")
+
+	n1, err := ns.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+	n2, err := ns.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+	_, expires, err := ns.decrypt(n1)
+	test.AssertNotError(t, err, "Could not decrypt nonce")
+	test.AssertEquals(t, expires, clk.Now().Add(10*time.Minute))
+
+	// Nonces remain redeemable until their expiry plus the allowed skew.
+	clk.Add(10*time.Minute + expirySkew)
+	test.Assert(t, ns.Valid(context.Background(), n1), "Rejected a nonce within the allowed skew of its expiry")
+	clk.Add(time.Second)
+	test.Assert(t, !ns.Valid(context.Background(), n2), "Accepted an expired nonce")
+
+	// An expired nonce is rejected without being crossed off.
+	test.AssertEquals(t, len(ns.used), 1)
+}
+
+func TestPersistentNonceExpiryBeforeStore(t *testing.T) {
+	clk := clock.NewFake()
+	store := newMemStore(clk)
+	ns, err := NewPersistentNonceService(metrics.NoopRegisterer, clk, "aluminum", []byte("key"), store, time.Hour)
+	test.AssertNotError(t, err, "Could not create nonce service")
+
+	n, err := ns.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+	clk.Add(time.Hour + expirySkew + time.Second)
+	test.Assert(t, !ns.Valid(context.Background(), n), "Accepted an expired nonce")
+
+	// The Store was never asked to redeem the expired nonce.
+	test.AssertEquals(t, len(store.outstanding), 1)
+}
+
+// encryptLegacy encrypts counter as a nonce of LegacyNonceLen, which doesn't
+// carry its expiry.
+func encryptLegacy(t *testing.T, ns *NonceService, counter int64) string {
+	t.Helper()
+	nonce := make([]byte, 12)
+	ct := ns.gcm.Seal(nil, nonce, big.NewInt(counter).FillBytes(make([]byte, 8)), nil)
+	body := append(nonce[4:], ct...)
+	test.AssertEquals(t, len(body), LegacyNonceLen)
+	return ns.prefix + base64.RawURLEncoding.EncodeToString(body)
+}
+
+func TestLegacyNonceTransition(t *testing.T) {
+	clk := clock.NewFake()
+	key := []byte("key")
+	store := newMemStore(clk)
+	ns, err := NewPersistentNonceService(metrics.NoopRegisterer, clk, "aluminum", key, store, time.Hour)
+	test.AssertNotError(t, err, "Could not create nonce service")
+
+	// Issue counters as an older nonce service sharing the Store would have.
+	c1, err := store.Issue(context.Background(), time.Hour)
+	test.AssertNotError(t, err, "Could not issue counter")
+	c2, err := store.Issue(context.Background(), 2*time.Hour)
+	test.AssertNotError(t, err, "Could not issue counter")
+	legacy1 := encryptLegacy(t, ns, c1)
+	legacy2 := encryptLegacy(t, ns, c2)
+
+	_, expires, err := ns.decrypt(legacy1)
+	test.AssertNotError(t, err, "Could not decrypt legacy nonce")
+	test.Assert(t, expires.IsZero(), "Legacy nonce should have no expiry")
+
+	// Legacy nonces are redeemable for one lifetime after startup.
+	clk.Add(time.Hour - time.Second)
+	test.Assert(t, ns.Valid(context.Background(), legacy1), "Rejected a legacy nonce during the transition")
+	test.Assert(t, !ns.Valid(context.Background(), legacy1), "Recognized the same legacy nonce twice")
+	clk.Add(time.Second)
+	test.Assert(t, !ns.Valid(context.Background(), legacy2), "Accepted a legacy nonce after the transition")
+}
+
+// memStore is a Store for tests which can be shared between NonceServices to
+// simulate a restart.
+type memStore struct {
+	sync.Mutex
+	clk         clock.Clock
+	latest      int64
+	outstanding map[int64]time.Time
+}
+
+func newMemStore(clk clock.Clock) *memStore {
+	return &memStore{clk: clk, outstanding: make(map[int64]time.Time)}
+}
+
+func (m *memStore) Issue(_ context.Context, ttl time.Duration) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.latest++
+	m.outstanding[m.latest] = m.clk.Now().Add(ttl)
+	return m.latest, nil
+}
+
+func (m *memStore) Redeem(_ context.Context, counter int64) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	expires, ok := m.outstanding[counter]
+	if !ok {
+		return false, nil
+	}
+	delete(m.outstanding, counter)
+	return m.clk.Now().Before(expires), nil
+}
+
+func TestPersistentNonceSurvivesRestart(t *testing.T) {
+	key := []byte("3b8c758dd85e113ea340ce0b3a99f389d40a308548af94d1730a7692c1874f1f")
+	store := newMemStore(clock.NewFake())
+
+	ns, err := NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", key, store, time.Hour)
+	test.AssertNotError(t, err, "Could not create nonce service")
+	n1, err := ns.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+	n2, err := ns.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+	test.Assert(t, ns.Valid(context.Background(), n1), "Rejected a valid nonce")
+
+	// "Restart" the service with the same key, prefix, and store.
+	restarted, err := NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", key, store, time.Hour)
+	test.AssertNotError(t, err, "Could not create nonce service")
+	test.Assert(t, restarted.Valid(context.Background(), n2), "Rejected a nonce issued before restart")
+	test.Assert(t, !restarted.Valid(context.Background(), n2), "Recognized the same nonce twice")
+	test.Assert(t, !restarted.Valid(context.Background(), n1), "Accepted a nonce redeemed before restart")
+
+	// New nonces don't reuse counters issued before the restart.
+	n3, err := restarted.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+	c, _, err := restarted.decrypt(n3)
+	test.AssertNotError(t, err, "Could not decrypt nonce")
+	test.AssertEquals(t, c, int64(3))
+	test.Assert(t, restarted.Valid(context.Background(), n3), "Rejected a valid nonce")
+
+	// A service with a different key can't redeem them.
+	other, err := NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", []byte("different"), store, time.Hour)
+	test.AssertNotError(t, err, "Could not create nonce service")
+	n4, err := restarted.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+	test.Assert(t, !other.Valid(context.Background(), n4), "Accepted a nonce encrypted with a different key")
+}
+
+func TestPersistentNonceMaxAge(t *testing.T) {
+	clk := clock.NewFake()
+	ns, err := NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", []byte("key"), newMemStore(clk), time.Hour)
+	test.AssertNotError(t, err, "Could not create nonce service")
+
+	n, err := ns.Nonce(context.Background())
+	test.AssertNotError(t, err, "Could not create nonce")
+	clk.Add(time.Hour)
+	test.Assert(t, !ns.Valid(context.Background(), n), "Accepted a nonce older than maxAge")
+}
+
+func TestNewPersistentNonceServiceValidation(t *testing.T) {
+	store := newMemStore(clock.NewFake())
+	_, err := NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "", []byte("key"), store, time.Hour)
+	test.AssertError(t, err, "NewPersistentNonceService didn't fail without a prefix")
+	_, err = NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", nil, store, time.Hour)
+	test.AssertError(t, err, "NewPersistentNonceService didn't fail without a key")
+	_, err = NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", []byte("key"), nil, time.Hour)
+	test.AssertError(t, err, "NewPersistentNonceService didn't fail without a store")
+	ns, err := NewPersistentNonceService(metrics.NoopRegisterer, clock.NewFake(), "aluminum", []byte("key"), store, 0)
+	test.AssertNotError(t, err, "NewPersistentNonceService failed with default maxAge")
+	test.AssertEquals(t, ns.maxAge, defaultMaxAge)
+}
+
 func TestDerivePrefix(t *testing.T) {
 	prefix := DerivePrefix("192.168.1.1:8080", []byte("3b8c758dd85e113ea340ce0b3a99f389d40a308548af94d1730a7692c1874f1f"))
 	test.AssertEquals(t, prefix, "P9qQaK4o")
@@ -0,0 +1,119 @@
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// Compile-time check that RedisStore implements the Store interface.
+var _ Store = (*RedisStore)(nil)
+
+// RedisStore is a Store backed by sharded Redis. The latest counter value is
+// kept under a single key, and each outstanding nonce is kept under its own
+// key which expires after the nonce's maximum age. Redeeming a nonce deletes
+// its key, so a nonce can only be redeemed once. All of the keys for a prefix
+// share a hash tag, and so are kept on the same shard.
+type RedisStore struct {
+	client  *redis.Ring
+	prefix  string
+	clk     clock.Clock
+	latency *prometheus.HistogramVec
+}
+
+// NewRedisStore returns a new Redis backed Store using the provided
+// *redis.Ring client. All keys are namespaced by the provided nonce prefix so
+// that nonce-service instances may share a Redis ring.
+func NewRedisStore(client *redis.Ring, prefix string, clk clock.Clock, stats prometheus.Registerer) *RedisStore {
+	latency := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "nonce_store_latency",
+			Help: "Histogram of Redis call latencies labeled by call=[issue|redeem] and result=[success|error]",
+			// Exponential buckets ranging from 0.0005s to 3s.
+			Buckets: prometheus.ExponentialBucketsRange(0.0005, 3, 8),
+		},
+		[]string{"call", "result"},
+	)
+	stats.MustRegister(latency)
+
+	return &RedisStore{
+		client:  client,
+		prefix:  prefix,
+		clk:     clk,
+		latency: latency,
+	}
+}
+
+func (r *RedisStore) observeLatency(call string, latency time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	r.latency.With(prometheus.Labels{"call": call, "result": result}).Observe(latency.Seconds())
+}
+
+// keyPrefix returns the prefix of every key for the nonce prefix. It is
+// enclosed in braces so that Redis hashes all of the keys to the same shard.
+func (r *RedisStore) keyPrefix() string {
+	return fmt.Sprintf("{nonce:%s}:", r.prefix)
+}
+
+func (r *RedisStore) latestKey() string {
+	return r.keyPrefix() + "latest"
+}
+
+func (r *RedisStore) counterKey(counter int64) string {
+	return r.keyPrefix() + strconv.FormatInt(counter, 10)
+}
+
+// issueScript increments the counter at KEYS[1] and records the new value as
+// outstanding, under the key formed by appending it to ARGV[1], for ARGV[2]
+// milliseconds. The new value is returned. The outstanding key isn't known
+// until the counter is incremented, and so can't be passed in KEYS, but it
+// shares KEYS[1]'s hash tag.
+var issueScript = redis.NewScript(`
+local counter = redis.call('INCR', KEYS[1])
+redis.call('SET', ARGV[1] .. counter, '', 'PX', ARGV[2])
+return counter
+`)
+
+// Issue implements Store. It increments the persisted counter and records the
+// new value as outstanding for ttl, atomically, so that a counter value is
+// never issued without being redeemable.
+func (r *RedisStore) Issue(ctx context.Context, ttl time.Duration) (int64, error) {
+	start := r.clk.Now()
+
+	if ttl.Milliseconds() < 1 {
+		err := fmt.Errorf("nonce ttl %s is less than 1ms", ttl)
+		r.observeLatency("issue", r.clk.Since(start), err)
+		return 0, err
+	}
+	counter, err := issueScript.Run(ctx, r.client, []string{r.latestKey()}, r.keyPrefix(), ttl.Milliseconds()).Int64()
+	if err != nil {
+		r.observeLatency("issue", r.clk.Since(start), err)
+		return 0, err
+	}
+
+	r.observeLatency("issue", r.clk.Since(start), nil)
+	return counter, nil
+}
+
+// Redeem implements Store. It deletes the key for counter, reporting whether it
+// existed.
+func (r *RedisStore) Redeem(ctx context.Context, counter int64) (bool, error) {
+	start := r.clk.Now()
+
+	deleted, err := r.client.Del(ctx, r.counterKey(counter)).Result()
+	if err != nil {
+		r.observeLatency("redeem", r.clk.Since(start), err)
+		return false, err
+	}
+
+	r.observeLatency("redeem", r.clk.Since(start), nil)
+	return deleted == 1, nil
+}
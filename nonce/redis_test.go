@@ -0,0 +1,105 @@
+package nonce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func newTestRedisStore(t *testing.T, prefix string) *RedisStore {
+	t.Helper()
+	tlsConfig := cmd.TLSConfig{
+		CACertFile: "../test/certs/ipki/minica.pem",
+		CertFile:   "../test/certs/ipki/localhost/cert.pem",
+		KeyFile:    "../test/certs/ipki/localhost/key.pem",
+	}
+	tlsConfig2, err := tlsConfig.Load(metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "loading TLS config")
+
+	client := redis.NewRing(&redis.RingOptions{
+		Addrs: map[string]string{
+			"shard1": "10.33.33.4:4218",
+			"shard2": "10.33.33.5:4218",
+		},
+		Username:  "unittest-rw",
+		Password:  "824968fa490f4ecec1e52d5e34916bdb60d45f8d",
+		TLSConfig: tlsConfig2,
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisStore(client, prefix, clock.NewFake(), metrics.NoopRegisterer)
+}
+
+// testRedisPrefix returns a random nonce prefix, so that tests don't share
+// keys with each other or with earlier runs.
+func testRedisPrefix() string {
+	return core.RandomString(6)
+}
+
+func TestRedisStoreIssueRedeem(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newTestRedisStore(t, testRedisPrefix())
+
+	first, err := store.Issue(ctx, time.Minute)
+	test.AssertNotError(t, err, "issuing first nonce")
+	second, err := store.Issue(ctx, time.Minute)
+	test.AssertNotError(t, err, "issuing second nonce")
+	test.AssertEquals(t, second, first+1)
+
+	ttl, err := store.client.PTTL(ctx, store.counterKey(second)).Result()
+	test.AssertNotError(t, err, "getting TTL of outstanding nonce")
+	test.Assert(t, ttl > 0 && ttl <= time.Minute, "outstanding nonce should expire within its ttl")
+
+	// Each nonce can be redeemed exactly once.
+	ok, err := store.Redeem(ctx, second)
+	test.AssertNotError(t, err, "redeeming second nonce")
+	test.Assert(t, ok, "second nonce should be redeemable")
+	ok, err = store.Redeem(ctx, second)
+	test.AssertNotError(t, err, "redeeming second nonce again")
+	test.Assert(t, !ok, "second nonce should not be redeemable twice")
+	ok, err = store.Redeem(ctx, first)
+	test.AssertNotError(t, err, "redeeming first nonce")
+	test.Assert(t, ok, "first nonce should be redeemable")
+
+	// A counter which was never issued can't be redeemed.
+	ok, err = store.Redeem(ctx, second+1)
+	test.AssertNotError(t, err, "redeeming unissued nonce")
+	test.Assert(t, !ok, "unissued nonce should not be redeemable")
+}
+
+func TestRedisStorePrefixes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	a := newTestRedisStore(t, testRedisPrefix())
+	b := newTestRedisStore(t, testRedisPrefix())
+
+	counter, err := a.Issue(ctx, time.Minute)
+	test.AssertNotError(t, err, "issuing nonce")
+	ok, err := b.Redeem(ctx, counter)
+	test.AssertNotError(t, err, "redeeming nonce with another prefix")
+	test.Assert(t, !ok, "nonce should not be redeemable under another prefix")
+
+	// The counters of the two prefixes are independent.
+	other, err := b.Issue(ctx, time.Minute)
+	test.AssertNotError(t, err, "issuing nonce with another prefix")
+	test.AssertEquals(t, other, int64(1))
+}
+
+func TestRedisStoreIssueTTL(t *testing.T) {
+	t.Parallel()
+	// The ttl is checked before Redis is contacted.
+	client := redis.NewRing(&redis.RingOptions{Addrs: map[string]string{"shard1": "10.33.33.4:4218"}})
+	defer client.Close()
+	store := NewRedisStore(client, testRedisPrefix(), clock.NewFake(), metrics.NoopRegisterer)
+
+	_, err := store.Issue(context.Background(), time.Microsecond)
+	test.AssertError(t, err, "issuing a nonce with a ttl under 1ms should fail")
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"net/mail"
 	"os"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -18,6 +20,7 @@ import (
 
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/iana"
 	"github.com/letsencrypt/boulder/identifier"
 	blog "github.com/letsencrypt/boulder/log"
@@ -409,7 +412,7 @@ func (pa *AuthorityImpl) WillingToIssue(domains []string) error {
 			continue
 		}
 	}
-	return combineSubErrors(subErrors)
+	return combineSubErrors(subErrors, len(domains))
 }
 
 // WellFormedDomainNames returns an error if any of the provided domains do not meet these criteria:
@@ -442,33 +445,86 @@ func WellFormedDomainNames(domains []string) error {
 			subErrors = append(subErrors, subError(domain, err))
 		}
 	}
-	return combineSubErrors(subErrors)
+	return combineSubErrors(subErrors, len(domains))
 }
 
-func combineSubErrors(subErrors []berrors.SubBoulderError) error {
-	if len(subErrors) > 0 {
-		// If there was only one error, then use it as the top level error that is
-		// returned.
-		if len(subErrors) == 1 {
-			return berrors.RejectedIdentifierError(
-				"Cannot issue for %q: %s",
-				subErrors[0].Identifier.Value,
-				subErrors[0].BoulderError.Detail,
-			)
+// NormalizeDomainNames returns the provided names lowercased, with the trailing
+// dot of any fully-qualified name removed, and sorted. Names which are the same
+// once normalized, such as "example.com" and "EXAMPLE.com.", aren't silently
+// merged: a client which requests a name twice has likely misunderstood what
+// it's asking for, so a malformed error listing every such name, and the forms
+// in which it was requested, is returned instead.
+//
+// The names aren't otherwise checked; see WellFormedDomainNames.
+func NormalizeDomainNames(domains []string) ([]string, error) {
+	requestedAs := make(map[string][]string, len(domains))
+	for _, domain := range domains {
+		normalized := strings.ToLower(domain)
+		if len(normalized) > 1 {
+			normalized = strings.TrimSuffix(normalized, ".")
+		}
+		requestedAs[normalized] = append(requestedAs[normalized], domain)
+	}
+
+	normalized := slices.Sorted(maps.Keys(requestedAs))
+	var duplicates []string
+	for _, domain := range normalized {
+		forms := requestedAs[domain]
+		if len(forms) == 1 {
+			continue
+		}
+		quoted := make([]string, len(forms))
+		for i, form := range forms {
+			quoted[i] = strconv.Quote(form)
 		}
+		duplicates = append(duplicates, fmt.Sprintf("%q (requested as %s)", domain, strings.Join(quoted, ", ")))
+	}
+	if len(duplicates) > 0 {
+		return nil, berrors.MalformedError(
+			"Order contains duplicate DNS names, which are compared case-insensitively and without any trailing dot: %s. Request each name only once.",
+			strings.Join(duplicates, "; "))
+	}
+	return normalized, nil
+}
 
-		detail := fmt.Sprintf(
-			"Cannot issue for %q: %s (and %d more problems. Refer to sub-problems for more information.)",
-			subErrors[0].Identifier.Value,
-			subErrors[0].BoulderError.Detail,
+// combineSubErrors combines the errors for each refused name out of
+// nameCount requested names into a single error. Its type is malformed if
+// every name was refused because it isn't well-formed, and rejectedIdentifier
+// otherwise. Unless only a single name was requested, the error carries a
+// suberror for each refused name, so that it's clear which of them caused the
+// refusal.
+func combineSubErrors(subErrors []berrors.SubBoulderError, nameCount int) error {
+	if len(subErrors) == 0 {
+		return nil
+	}
+
+	errType := berrors.Malformed
+	for _, subErr := range subErrors {
+		if subErr.BoulderError.Type != berrors.Malformed {
+			errType = berrors.RejectedIdentifier
+			break
+		}
+	}
+
+	detail := fmt.Sprintf("Cannot issue for %q: %s",
+		subErrors[0].Identifier.Value,
+		subErrors[0].BoulderError.Detail,
+	)
+	if len(subErrors) > 1 {
+		detail = fmt.Sprintf("%s (and %d more problems. Refer to sub-problems for more information.)",
+			detail,
 			len(subErrors)-1,
 		)
-		return (&berrors.BoulderError{
-			Type:   berrors.RejectedIdentifier,
-			Detail: detail,
-		}).WithSubErrors(subErrors)
 	}
-	return nil
+
+	combined := &berrors.BoulderError{
+		Type:   errType,
+		Detail: detail,
+	}
+	if nameCount > 1 {
+		combined = combined.WithSubErrors(subErrors)
+	}
+	return combined
 }
 
 // checkWildcardHostList checks the wildcardExactBlocklist for a given domain.
@@ -527,11 +583,15 @@ func (pa *AuthorityImpl) ChallengeTypesFor(ident identifier.ACMEIdentifier) ([]c
 
 	// Return all challenge types we support for non-wildcard DNS identifiers.
 	if ident.Type == identifier.TypeDNS {
-		return []core.AcmeChallenge{
+		challTypes := []core.AcmeChallenge{
 			core.ChallengeTypeHTTP01,
 			core.ChallengeTypeDNS01,
 			core.ChallengeTypeTLSALPN01,
-		}, nil
+		}
+		if features.Get().DNSAccount01Enabled {
+			challTypes = append(challTypes, core.ChallengeTypeDNSAccount01)
+		}
+		return challTypes, nil
 	}
 
 	// Otherwise return an error because we don't support any challenges for this
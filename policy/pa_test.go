@@ -122,6 +122,81 @@ func TestWellFormedDomainNames(t *testing.T) {
 	}
 }
 
+func TestNormalizeDomainNames(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		domains    []string
+		expected   []string
+		duplicates string
+	}{
+		{
+			name:     "already normalized",
+			domains:  []string{"www.example.com", "example.com"},
+			expected: []string{"example.com", "www.example.com"},
+		},
+		{
+			name:     "mixed case and trailing dots",
+			domains:  []string{"WWW.Example.COM.", "example.com"},
+			expected: []string{"example.com", "www.example.com"},
+		},
+		{
+			name:     "wildcard and its base name are distinct",
+			domains:  []string{"*.example.com", "example.com."},
+			expected: []string{"*.example.com", "example.com"},
+		},
+		{
+			name:     "wildcard and a name it covers are left for the caller",
+			domains:  []string{"*.Example.com", "www.example.com"},
+			expected: []string{"*.example.com", "www.example.com"},
+		},
+		{
+			name:     "only a single trailing dot is removed",
+			domains:  []string{"example.com..", "."},
+			expected: []string{".", "example.com."},
+		},
+		{
+			name:       "exact duplicate",
+			domains:    []string{"example.com", "example.com"},
+			duplicates: `"example.com" (requested as "example.com", "example.com")`,
+		},
+		{
+			name:       "duplicate differing in case",
+			domains:    []string{"example.com", "EXAMPLE.com"},
+			duplicates: `"example.com" (requested as "example.com", "EXAMPLE.com")`,
+		},
+		{
+			name:       "duplicate differing in trailing dot",
+			domains:    []string{"example.com.", "example.com"},
+			duplicates: `"example.com" (requested as "example.com.", "example.com")`,
+		},
+		{
+			name:       "duplicate wildcards",
+			domains:    []string{"*.example.com", "*.EXAMPLE.COM."},
+			duplicates: `"*.example.com" (requested as "*.example.com", "*.EXAMPLE.COM.")`,
+		},
+		{
+			name:       "several duplicates are all listed",
+			domains:    []string{"b.example.com", "a.example.com", "B.example.com.", "A.example.com", "a.example.com."},
+			duplicates: `"a.example.com" (requested as "a.example.com", "A.example.com", "a.example.com."); "b.example.com" (requested as "b.example.com", "B.example.com.")`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			normalized, err := NormalizeDomainNames(tc.domains)
+			if tc.duplicates == "" {
+				test.AssertNotError(t, err, "unexpected error")
+				test.AssertDeepEquals(t, normalized, tc.expected)
+				return
+			}
+			test.AssertErrorIs(t, err, berrors.Malformed)
+			test.AssertContains(t, err.Error(), tc.duplicates+". Request each name only once.")
+		})
+	}
+}
+
 func TestWillingToIssue(t *testing.T) {
 	shouldBeBlocked := []string{
 		`highvalue.website1.org`,
@@ -297,8 +372,9 @@ func TestWillingToIssue_Wildcards(t *testing.T) {
 	}
 }
 
-// TestWillingToIssue_SubErrors tests that more than one rejected identifier
-// results in an error with suberrors.
+// TestWillingToIssue_SubErrors tests that rejecting any of several identifiers
+// results in an error with suberrors, whose top-level type reflects why they
+// were rejected.
 func TestWillingToIssue_SubErrors(t *testing.T) {
 	banned := []string{
 		"letsdecrypt.org",
@@ -328,7 +404,7 @@ func TestWillingToIssue_SubErrors(t *testing.T) {
 	})
 	test.AssertDeepEquals(t, err,
 		&berrors.BoulderError{
-			Type:   berrors.RejectedIdentifier,
+			Type:   berrors.Malformed,
 			Detail: "Cannot issue for \"letsdecrypt_org\": Domain name contains an invalid character (and 1 more problems. Refer to sub-problems for more information.)",
 			SubErrors: []berrors.SubBoulderError{
 				{
@@ -386,6 +462,46 @@ func TestWillingToIssue_SubErrors(t *testing.T) {
 			Type:   berrors.RejectedIdentifier,
 			Detail: "Cannot issue for \"letsdecrypt.org\": The ACME server refuses to issue a certificate for this domain name, because it is forbidden by policy",
 		})
+	// Test one banned domain alongside a fine one; the error identifies which
+	// was banned.
+	err = pa.WillingToIssue([]string{
+		"perfectly-fine.com", // fine
+		"letsdecrypt.org",    // banned
+	})
+	test.AssertDeepEquals(t, err,
+		&berrors.BoulderError{
+			Type:   berrors.RejectedIdentifier,
+			Detail: "Cannot issue for \"letsdecrypt.org\": The ACME server refuses to issue a certificate for this domain name, because it is forbidden by policy",
+			SubErrors: []berrors.SubBoulderError{
+				{
+					BoulderError: &berrors.BoulderError{
+						Type:   berrors.RejectedIdentifier,
+						Detail: "The ACME server refuses to issue a certificate for this domain name, because it is forbidden by policy",
+					},
+					Identifier: identifier.NewDNS("letsdecrypt.org"),
+				},
+			},
+		})
+
+	// Test one malformed domain alongside a fine one.
+	err = pa.WillingToIssue([]string{
+		"perfectly-fine.com", // fine
+		"example.comm",       // malformed
+	})
+	test.AssertDeepEquals(t, err,
+		&berrors.BoulderError{
+			Type:   berrors.Malformed,
+			Detail: "Cannot issue for \"example.comm\": Domain name does not end with a valid public suffix (TLD)",
+			SubErrors: []berrors.SubBoulderError{
+				{
+					BoulderError: &berrors.BoulderError{
+						Type:   berrors.Malformed,
+						Detail: "Domain name does not end with a valid public suffix (TLD)",
+					},
+					Identifier: identifier.NewDNS("example.comm"),
+				},
+			},
+		})
 }
 
 func TestChallengeTypesFor(t *testing.T) {
@@ -437,6 +553,23 @@ func TestChallengeTypesFor(t *testing.T) {
 	}
 }
 
+func TestChallengeTypesForDNSAccount01(t *testing.T) {
+	pa := paImpl(t)
+	features.Set(features.Config{DNSAccount01Enabled: true})
+	defer features.Reset()
+
+	challs, err := pa.ChallengeTypesFor(identifier.NewDNS("example.com"))
+	test.AssertNotError(t, err, "should have succeeded")
+	test.AssertDeepEquals(t, challs, []core.AcmeChallenge{
+		core.ChallengeTypeHTTP01, core.ChallengeTypeDNS01, core.ChallengeTypeTLSALPN01, core.ChallengeTypeDNSAccount01,
+	})
+
+	// Wildcards are still only offered dns-01.
+	challs, err = pa.ChallengeTypesFor(identifier.NewDNS("*.example.com"))
+	test.AssertNotError(t, err, "should have succeeded")
+	test.AssertDeepEquals(t, challs, []core.AcmeChallenge{core.ChallengeTypeDNS01})
+}
+
 // TestMalformedExactBlocklist tests that loading a YAML policy file with an
 // invalid exact blocklist entry will fail as expected.
 func TestMalformedExactBlocklist(t *testing.T) {
@@ -10,8 +10,8 @@ import (
 const (
 	// Error types that can be used in ACME payloads. These are sorted in the
 	// same order as they are defined in RFC8555 Section 6.7. We do not implement
-	// the `compound`, `externalAccountRequired`, or `userActionRequired` errors,
-	// because we have no path that would return them.
+	// the `compound` or `userActionRequired` errors, because we have no path
+	// that would return them.
 	AccountDoesNotExistProblem   = ProblemType("accountDoesNotExist")
 	AlreadyRevokedProblem        = ProblemType("alreadyRevoked")
 	BadCSRProblem                = ProblemType("badCSR")
@@ -21,20 +21,21 @@ const (
 	BadSignatureAlgorithmProblem = ProblemType("badSignatureAlgorithm")
 	CAAProblem                   = ProblemType("caa")
 	// ConflictProblem is a problem type that is not defined in RFC8555.
-	ConflictProblem              = ProblemType("conflict")
-	ConnectionProblem            = ProblemType("connection")
-	DNSProblem                   = ProblemType("dns")
-	InvalidContactProblem        = ProblemType("invalidContact")
-	MalformedProblem             = ProblemType("malformed")
-	OrderNotReadyProblem         = ProblemType("orderNotReady")
-	PausedProblem                = ProblemType("rateLimited")
-	RateLimitedProblem           = ProblemType("rateLimited")
-	RejectedIdentifierProblem    = ProblemType("rejectedIdentifier")
-	ServerInternalProblem        = ProblemType("serverInternal")
-	TLSProblem                   = ProblemType("tls")
-	UnauthorizedProblem          = ProblemType("unauthorized")
-	UnsupportedContactProblem    = ProblemType("unsupportedContact")
-	UnsupportedIdentifierProblem = ProblemType("unsupportedIdentifier")
+	ConflictProblem                = ProblemType("conflict")
+	ConnectionProblem              = ProblemType("connection")
+	DNSProblem                     = ProblemType("dns")
+	ExternalAccountRequiredProblem = ProblemType("externalAccountRequired")
+	InvalidContactProblem          = ProblemType("invalidContact")
+	MalformedProblem               = ProblemType("malformed")
+	OrderNotReadyProblem           = ProblemType("orderNotReady")
+	PausedProblem                  = ProblemType("rateLimited")
+	RateLimitedProblem             = ProblemType("rateLimited")
+	RejectedIdentifierProblem      = ProblemType("rejectedIdentifier")
+	ServerInternalProblem          = ProblemType("serverInternal")
+	TLSProblem                     = ProblemType("tls")
+	UnauthorizedProblem            = ProblemType("unauthorized")
+	UnsupportedContactProblem      = ProblemType("unsupportedContact")
+	UnsupportedIdentifierProblem   = ProblemType("unsupportedIdentifier")
 
 	// Defined in https://datatracker.ietf.org/doc/draft-aaron-acme-profiles/
 	InvalidProfileProblem = ProblemType("invalidProfile")
@@ -56,6 +57,29 @@ type ProblemDetails struct {
 	// SubProblems are optional additional per-identifier problems. See
 	// RFC 8555 Section 6.7.1: https://tools.ietf.org/html/rfc8555#section-6.7.1
 	SubProblems []SubProblemDetails `json:"subproblems,omitempty"`
+	// DNSDetails optionally describes the DNS query which caused the problem.
+	// The same information is included in Detail for human readers.
+	DNSDetails *DNSDetails `json:"dnsDetails,omitempty"`
+	// HTTPDetails optionally describes the HTTP-01 response which caused the
+	// problem.
+	HTTPDetails *HTTPDetails `json:"httpDetails,omitempty"`
+}
+
+// DNSDetails describes a failed DNS query.
+type DNSDetails struct {
+	QueryName string `json:"queryName,omitempty"`
+	QueryType string `json:"queryType,omitempty"`
+	// RCode is empty if no response was received.
+	RCode string `json:"rcode,omitempty"`
+	// EDE describes the Extended DNS Error (RFC 8914) in the response, if any.
+	EDE string `json:"ede,omitempty"`
+}
+
+// HTTPDetails describes the response to a failed HTTP-01 request.
+type HTTPDetails struct {
+	// FinalURL is the URL of the final hop, after following any redirects.
+	FinalURL   string `json:"finalURL,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
 }
 
 // SubProblemDetails represents sub-problems specific to an identifier that are
@@ -78,6 +102,8 @@ func (pd *ProblemDetails) WithSubProblems(subProbs []SubProblemDetails) *Problem
 		Detail:      pd.Detail,
 		HTTPStatus:  pd.HTTPStatus,
 		SubProblems: append(pd.SubProblems, subProbs...),
+		DNSDetails:  pd.DNSDetails,
+		HTTPDetails: pd.HTTPDetails,
 	}
 }
 
@@ -181,6 +207,16 @@ func DNS(detail string) *ProblemDetails {
 	}
 }
 
+// ExternalAccountRequired returns a ProblemDetails representing an
+// ExternalAccountRequiredProblem
+func ExternalAccountRequired(detail string) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       ExternalAccountRequiredProblem,
+		Detail:     detail,
+		HTTPStatus: http.StatusForbidden,
+	}
+}
+
 // InvalidContact returns a ProblemDetails representing an InvalidContactProblem.
 func InvalidContact(detail string) *ProblemDetails {
 	return &ProblemDetails{
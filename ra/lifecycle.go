@@ -0,0 +1,227 @@
+package ra
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/letsencrypt/boulder/core"
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	"github.com/letsencrypt/boulder/identifier"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+// orderStage names a stage of an order's lifecycle, between two transitions.
+type orderStage string
+
+const (
+	// stageValidation runs from an order's creation to the first validation
+	// attempt for any of its authorizations.
+	stageValidation = orderStage("created_to_validation")
+	// stageReady runs from the first validation attempt to the order
+	// becoming ready, when its last authorization was validated.
+	stageReady = orderStage("validation_to_ready")
+	// stageFinalize runs from the order becoming ready to the finalize
+	// request.
+	stageFinalize = orderStage("ready_to_finalize")
+	// stageIssuance runs from the finalize request to the certificate being
+	// issued.
+	stageIssuance = orderStage("finalize_to_issuance")
+)
+
+// The last stage reached by an order which expired without being finalized.
+const (
+	// abandonedPending orders have no valid authorizations.
+	abandonedPending = "pending"
+	// abandonedValidating orders have some, but not all, of their
+	// authorizations valid.
+	abandonedValidating = "validating"
+	// abandonedReady orders have every authorization valid.
+	abandonedReady = "ready"
+)
+
+// orderLifecycle observes how long orders spend in each stage of their
+// lifecycle, and counts orders which expire without being finalized. All
+// methods are safe for concurrent use, and on a nil *orderLifecycle, which
+// records nothing.
+type orderLifecycle struct {
+	clk          clock.Clock
+	stageLatency *prometheus.HistogramVec
+	abandoned    *prometheus.CounterVec
+}
+
+// newOrderLifecycle returns an *orderLifecycle whose metrics are registered
+// with stats.
+func newOrderLifecycle(stats prometheus.Registerer, clk clock.Clock) *orderLifecycle {
+	stageLatency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "order_stage_seconds",
+		Help: "Histogram of time, in seconds, orders spent in each stage of their lifecycle, labelled by stage=[created_to_validation|validation_to_ready|ready_to_finalize|finalize_to_issuance] and certificate profile",
+		// Orders live for at most 7 days, so our buckets are: 1 second, 10
+		// seconds, 1 minute, 10 minutes, 1 hour, 7 hours, 1 day, 2 days, 7
+		// days, +inf.
+		Buckets: []float64{1, 10, 60, 600, 3600, 25200, 86400, 172800, 604800},
+	}, []string{"stage", "profile"})
+	stats.MustRegister(stageLatency)
+
+	abandoned := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_abandoned",
+		Help: "Number of orders which expired without being finalized, labeled by the last stage=[pending|validating|ready] they reached",
+	}, []string{"stage"})
+	stats.MustRegister(abandoned)
+
+	return &orderLifecycle{
+		clk:          clk,
+		stageLatency: stageLatency,
+		abandoned:    abandoned,
+	}
+}
+
+// observe records that an order of the named profile spent d in stage.
+func (l *orderLifecycle) observe(stage orderStage, profile string, d time.Duration) {
+	l.stageLatency.WithLabelValues(string(stage), profile).Observe(max(d, 0).Seconds())
+}
+
+// finalizing observes how long an order which is being finalized spent in
+// each stage before the finalize request. Its first validation attempt is the
+// earliest attempt recorded, after the order was created, for any of authzs,
+// its authorizations, and it became ready when the last of them was
+// validated. Authorizations validated before the order was created were
+// reused; if all of them were, the order was ready when it was created.
+//
+// Only the most recent attempts of each challenge are kept, so the first
+// attempt of an order with many failed attempts may be found later than it was
+// made. SAs which don't store validation attempts return none, in which case
+// only the time before the finalize request is observed.
+func (l *orderLifecycle) finalizing(order *corepb.Order, authzs map[identifier.ACMEIdentifier]*core.Authorization) {
+	if l == nil {
+		return
+	}
+
+	created := order.Created.AsTime()
+	var firstAttempt time.Time
+	readyAt := created
+	for _, authz := range authzs {
+		for _, chall := range authz.Challenges {
+			for _, attempt := range chall.Attempts {
+				if attempt.AttemptedAt.Before(created) {
+					continue
+				}
+				if firstAttempt.IsZero() || attempt.AttemptedAt.Before(firstAttempt) {
+					firstAttempt = attempt.AttemptedAt
+				}
+			}
+			if chall.Status == core.StatusValid && chall.Validated != nil && chall.Validated.After(readyAt) {
+				readyAt = *chall.Validated
+			}
+		}
+	}
+	profile := order.CertificateProfileName
+	if !firstAttempt.IsZero() {
+		l.observe(stageValidation, profile, firstAttempt.Sub(created))
+		l.observe(stageReady, profile, readyAt.Sub(firstAttempt))
+	}
+	l.observe(stageFinalize, profile, l.clk.Since(readyAt))
+}
+
+// issued observes how long issuance took for an order whose finalize request
+// was received at requested.
+func (l *orderLifecycle) issued(order *corepb.Order, requested time.Time) {
+	if l == nil {
+		return
+	}
+	l.observe(stageIssuance, order.CertificateProfileName, l.clk.Since(requested))
+}
+
+// abandonedOrdersPageSize is the number of expired orders requested from the
+// SA at a time.
+const abandonedOrdersPageSize = 1000
+
+// abandonedOrdersCursor is the position, in order of expiry then ID, of the
+// last expired order checked for abandonment.
+type abandonedOrdersCursor struct {
+	expires time.Time
+	id      int64
+}
+
+// ReportAbandonedOrdersEvery counts, every interval until the context is done,
+// the orders which have expired without being finalized. Orders which expired
+// before it was called are not counted. Only one RA should report abandoned
+// orders, since each RA which does counts every order.
+func (ra *RegistrationAuthorityImpl) ReportAbandonedOrdersEvery(ctx context.Context, interval time.Duration) {
+	cursor := abandonedOrdersCursor{expires: ra.clk.Now(), id: math.MaxInt64}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cursor = ra.reportAbandonedOrders(ctx, cursor)
+		}
+	}
+}
+
+// reportAbandonedOrders counts each order which expired after cursor, and no
+// later than now, without being finalized, by the last stage it reached. It
+// returns the position from which to continue. If the SA can't be queried,
+// that's the last order checked, so that no order is skipped. Failures to
+// check an individual order are logged, and the order isn't counted.
+func (ra *RegistrationAuthorityImpl) reportAbandonedOrders(ctx context.Context, cursor abandonedOrdersCursor) abandonedOrdersCursor {
+	now := ra.clk.Now()
+	for {
+		page, err := ra.SA.GetAbandonedOrders(ctx, &sapb.GetAbandonedOrdersRequest{
+			ExpiredAfter:  timestamppb.New(cursor.expires),
+			AfterID:       cursor.id,
+			ExpiredBefore: timestamppb.New(now),
+			Limit:         abandonedOrdersPageSize,
+		})
+		if err != nil {
+			ra.log.Warningf("looking up abandoned orders: %s", err)
+			return cursor
+		}
+		for _, order := range page.Orders {
+			cursor = abandonedOrdersCursor{expires: order.Expires.AsTime(), id: order.Id}
+			stage, err := ra.abandonedOrderStage(ctx, order)
+			if err != nil {
+				ra.log.Warningf("checking the stage reached by abandoned order %d: %s", order.Id, err)
+				continue
+			}
+			ra.lifecycle.abandoned.WithLabelValues(stage).Inc()
+		}
+		if len(page.Orders) < abandonedOrdersPageSize {
+			// Every order which expired no later than now has been checked,
+			// and no more can expire before it.
+			return abandonedOrdersCursor{expires: now, id: math.MaxInt64}
+		}
+	}
+}
+
+// abandonedOrderStage returns the last stage reached by an abandoned order,
+// from its authorizations, of which there is one for each of its identifiers.
+func (ra *RegistrationAuthorityImpl) abandonedOrderStage(ctx context.Context, order *corepb.Order) (string, error) {
+	authzs, err := ra.SA.GetValidOrderAuthorizations2(ctx, &sapb.GetValidOrderAuthorizationsRequest{
+		Id:     order.Id,
+		AcctID: order.RegistrationID,
+	})
+	if err != nil {
+		return "", err
+	}
+	valid := 0
+	for _, authz := range authzs.Authzs {
+		if authz.Status == string(core.StatusValid) {
+			valid++
+		}
+	}
+	switch {
+	case valid == 0:
+		return abandonedPending, nil
+	case valid < len(authzs.Authzs):
+		return abandonedValidating, nil
+	default:
+		return abandonedReady, nil
+	}
+}
@@ -0,0 +1,257 @@
+package ra
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/letsencrypt/boulder/core"
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	berrors "github.com/letsencrypt/boulder/errors"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/identifier"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// stageSamples returns the number and total, in seconds, of the observations
+// of stage for profile.
+func stageSamples(t *testing.T, l *orderLifecycle, stage orderStage, profile string) (uint64, float64) {
+	t.Helper()
+	var m io_prometheus_client.Metric
+	err := l.stageLatency.WithLabelValues(string(stage), profile).(prometheus.Metric).Write(&m)
+	test.AssertNotError(t, err, "collecting stage latency")
+	return m.Histogram.GetSampleCount(), m.Histogram.GetSampleSum()
+}
+
+// validAuthz returns a valid authorization for name, whose HTTP-01 challenge
+// was validated at validated. Its validation attempts were made at attempted,
+// or, if none are given, validated.
+func validAuthz(name string, validated time.Time, attempted ...time.Time) *core.Authorization {
+	if len(attempted) == 0 {
+		attempted = []time.Time{validated}
+	}
+	var attempts []core.ValidationAttempt
+	for _, at := range attempted {
+		attempts = append(attempts, core.ValidationAttempt{AttemptedAt: at})
+	}
+	return &core.Authorization{
+		Identifier: identifier.NewDNS(name),
+		Status:     core.StatusValid,
+		Challenges: []core.Challenge{
+			{Type: core.ChallengeTypeHTTP01, Status: core.StatusValid, Validated: &validated, Attempts: attempts},
+		},
+	}
+}
+
+func TestOrderLifecycleStages(t *testing.T) {
+	t.Parallel()
+
+	fc := clock.NewFake()
+	l := newOrderLifecycle(metrics.NoopRegisterer, fc)
+	created := fc.Now()
+	order := &corepb.Order{
+		Id:                     1,
+		RegistrationID:         1,
+		Created:                timestamppb.New(created),
+		Expires:                timestamppb.New(created.Add(7 * 24 * time.Hour)),
+		CertificateProfileName: "shortlived",
+	}
+
+	// The first authorization fails to validate after one minute, and is
+	// validated after two, and the second, which makes the order ready, after
+	// five. An authorization validated before the order was created was
+	// reused, so its attempt isn't the order's first.
+	authzs := map[identifier.ACMEIdentifier]*core.Authorization{}
+	for _, authz := range []*core.Authorization{
+		validAuthz("reused.example.com", created.Add(-time.Hour)),
+		validAuthz("first.example.com", created.Add(2*time.Minute), created.Add(time.Minute), created.Add(2*time.Minute)),
+		validAuthz("last.example.com", created.Add(5*time.Minute)),
+	} {
+		authzs[authz.Identifier] = authz
+	}
+
+	// The client finalizes ten minutes after creating the order.
+	fc.Add(10 * time.Minute)
+	requested := fc.Now()
+	l.finalizing(order, authzs)
+
+	// Issuance takes thirty seconds.
+	fc.Add(30 * time.Second)
+	l.issued(order, requested)
+
+	for _, tc := range []struct {
+		stage    orderStage
+		expected time.Duration
+	}{
+		{stageValidation, time.Minute},
+		{stageReady, 4 * time.Minute},
+		{stageFinalize, 5 * time.Minute},
+		{stageIssuance, 30 * time.Second},
+	} {
+		count, sum := stageSamples(t, l, tc.stage, "shortlived")
+		test.AssertEquals(t, count, uint64(1))
+		test.AssertEquals(t, sum, tc.expected.Seconds())
+	}
+
+	// An order whose authorizations were all reused was ready when it was
+	// created, so only the time before the finalize request is observed.
+	order.Created = timestamppb.New(fc.Now())
+	fc.Add(time.Minute)
+	l.finalizing(order, map[identifier.ACMEIdentifier]*core.Authorization{
+		identifier.NewDNS("reused.example.com"): validAuthz("reused.example.com", created.Add(-time.Hour)),
+	})
+	count, _ := stageSamples(t, l, stageValidation, "shortlived")
+	test.AssertEquals(t, count, uint64(1))
+	count, sum := stageSamples(t, l, stageFinalize, "shortlived")
+	test.AssertEquals(t, count, uint64(2))
+	test.AssertEquals(t, sum, (6 * time.Minute).Seconds())
+
+	// Without any recorded attempts, the order's first validation attempt is
+	// unknown, so only the time since it became ready is observed.
+	order.Created = timestamppb.New(fc.Now())
+	unrecorded := validAuthz("unrecorded.example.com", fc.Now().Add(time.Minute))
+	unrecorded.Challenges[0].Attempts = nil
+	fc.Add(3 * time.Minute)
+	l.finalizing(order, map[identifier.ACMEIdentifier]*core.Authorization{unrecorded.Identifier: unrecorded})
+	count, _ = stageSamples(t, l, stageValidation, "shortlived")
+	test.AssertEquals(t, count, uint64(1))
+	count, sum = stageSamples(t, l, stageFinalize, "shortlived")
+	test.AssertEquals(t, count, uint64(3))
+	test.AssertEquals(t, sum, (8 * time.Minute).Seconds())
+}
+
+// mockSAWithAbandonedOrders returns its orders, which are sorted by expiry
+// then ID, as abandoned, and their authorizations by order ID.
+type mockSAWithAbandonedOrders struct {
+	sapb.StorageAuthorityClient
+	orders []*corepb.Order
+	authzs map[int64][]*core.Authorization
+	err    error
+}
+
+func (msa *mockSAWithAbandonedOrders) GetAbandonedOrders(_ context.Context, req *sapb.GetAbandonedOrdersRequest, _ ...grpc.CallOption) (*sapb.Orders, error) {
+	if msa.err != nil {
+		return nil, msa.err
+	}
+	after := req.ExpiredAfter.AsTime()
+	resp := &sapb.Orders{}
+	for _, order := range msa.orders {
+		expires := order.Expires.AsTime()
+		if expires.Before(after) || (expires.Equal(after) && order.Id <= req.AfterID) {
+			continue
+		}
+		if expires.After(req.ExpiredBefore.AsTime()) || int64(len(resp.Orders)) == req.Limit {
+			break
+		}
+		resp.Orders = append(resp.Orders, order)
+	}
+	return resp, nil
+}
+
+func (msa *mockSAWithAbandonedOrders) GetValidOrderAuthorizations2(_ context.Context, req *sapb.GetValidOrderAuthorizationsRequest, _ ...grpc.CallOption) (*sapb.Authorizations, error) {
+	authzs, ok := msa.authzs[req.Id]
+	if !ok {
+		return nil, berrors.NotFoundError("no such order")
+	}
+	resp := &sapb.Authorizations{}
+	for _, authz := range authzs {
+		authzPB, err := bgrpc.AuthzToPB(*authz)
+		if err != nil {
+			return nil, err
+		}
+		resp.Authzs = append(resp.Authzs, authzPB)
+	}
+	return resp, nil
+}
+
+func TestReportAbandonedOrders(t *testing.T) {
+	t.Parallel()
+
+	fc := clock.NewFake()
+	now := fc.Now()
+	expires := now.Add(time.Hour)
+	pending := func(name string) *core.Authorization {
+		return &core.Authorization{
+			ID:         "1",
+			Identifier: identifier.NewDNS(name),
+			Status:     core.StatusPending,
+			Expires:    &expires,
+			Challenges: []core.Challenge{{Type: core.ChallengeTypeHTTP01, Status: core.StatusPending, Token: core.NewToken()}},
+		}
+	}
+	valid := func(ident identifier.ACMEIdentifier) *core.Authorization {
+		authz := validAuthz("", now)
+		authz.ID = "2"
+		authz.Identifier = ident
+		authz.Expires = &expires
+		authz.Challenges[0].Token = core.NewToken()
+		return authz
+	}
+	order := func(id int64, expires time.Time) *corepb.Order {
+		return &corepb.Order{
+			Id:             id,
+			RegistrationID: 1,
+			Created:        timestamppb.New(now),
+			Expires:        timestamppb.New(expires),
+		}
+	}
+	sa := &mockSAWithAbandonedOrders{
+		// Order 4 has no authorizations to look up, and order 5 expires later
+		// than the rest.
+		orders: []*corepb.Order{
+			order(1, expires),
+			order(2, expires),
+			order(3, expires),
+			order(4, expires),
+			order(5, expires.Add(time.Hour)),
+		},
+		authzs: map[int64][]*core.Authorization{
+			1: {pending("a.example.com"), pending("b.example.com")},
+			2: {valid(identifier.NewDNS("a.example.com")), pending("b.example.com")},
+			// Order 3 is ready once the authorizations of all its
+			// identifiers, including IP addresses, are valid.
+			3: {valid(identifier.NewDNS("a.example.com")), valid(identifier.NewIP(netip.MustParseAddr("10.0.0.1")))},
+			5: {valid(identifier.NewDNS("a.example.com"))},
+		},
+	}
+
+	l := newOrderLifecycle(metrics.NoopRegisterer, fc)
+	mockLog := blog.NewMock()
+	ra := &RegistrationAuthorityImpl{SA: sa, clk: fc, log: mockLog, lifecycle: l}
+	ctx := context.Background()
+	cursor := abandonedOrdersCursor{expires: now, id: math.MaxInt64}
+
+	// Nothing has expired yet.
+	cursor = ra.reportAbandonedOrders(ctx, cursor)
+	test.AssertMetricWithLabelsEquals(t, l.abandoned, nil, 0)
+
+	fc.Add(90 * time.Minute)
+	cursor = ra.reportAbandonedOrders(ctx, cursor)
+	test.AssertMetricWithLabelsEquals(t, l.abandoned, prometheus.Labels{"stage": abandonedPending}, 1)
+	test.AssertMetricWithLabelsEquals(t, l.abandoned, prometheus.Labels{"stage": abandonedValidating}, 1)
+	test.AssertMetricWithLabelsEquals(t, l.abandoned, prometheus.Labels{"stage": abandonedReady}, 1)
+	test.AssertEquals(t, len(mockLog.GetAllMatching("checking the stage reached by abandoned order 4")), 1)
+
+	// Orders are only counted once, and an SA failure doesn't lose the
+	// position reached.
+	sa.err = errors.New("oops")
+	fc.Add(time.Hour)
+	cursor = ra.reportAbandonedOrders(ctx, cursor)
+	test.AssertMetricWithLabelsEquals(t, l.abandoned, nil, 3)
+	sa.err = nil
+	ra.reportAbandonedOrders(ctx, cursor)
+	test.AssertMetricWithLabelsEquals(t, l.abandoned, prometheus.Labels{"stage": abandonedReady}, 2)
+	test.AssertMetricWithLabelsEquals(t, l.abandoned, nil, 4)
+}
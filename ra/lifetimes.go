@@ -0,0 +1,58 @@
+package ra
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultAuthorizationLifetime is how long valid authorizations are
+	// considered valid for, if not otherwise configured.
+	DefaultAuthorizationLifetime = 30 * 24 * time.Hour
+
+	// DefaultPendingAuthorizationLifetime is how long authorizations may remain
+	// pending, if not otherwise configured.
+	DefaultPendingAuthorizationLifetime = 7 * 24 * time.Hour
+
+	// DefaultOrderLifetime is how far in the future a new order's expiry is
+	// set, if not otherwise configured.
+	DefaultOrderLifetime = 7 * 24 * time.Hour
+
+	// maxAuthorizationLifetime is derived from the Baseline Requirements, which
+	// state that "any reused data, document, or completed validation MUST be
+	// obtained no more than 398 days prior to issuing the Certificate".
+	maxAuthorizationLifetime = 397 * 24 * time.Hour
+
+	// maxPendingAuthorizationLifetime is derived from the Baseline Requirements,
+	// which state that validation tokens "MUST NOT be used for more than 30
+	// days from its creation".
+	maxPendingAuthorizationLifetime = 29 * 24 * time.Hour
+)
+
+// ValidateLifetimes returns an error if the given authorization and order
+// lifetimes are not a coherent expiry policy: each must be positive and within
+// the limits set by the Baseline Requirements, a pending authorization must
+// expire sooner than a valid one, and an order must not outlive the
+// authorizations it would reuse.
+func ValidateLifetimes(authorizationLifetime, pendingAuthorizationLifetime, orderLifetime time.Duration) error {
+	if authorizationLifetime <= 0 || authorizationLifetime > maxAuthorizationLifetime {
+		return fmt.Errorf("authorization lifetime %s must be greater than 0 and at most %s",
+			authorizationLifetime, maxAuthorizationLifetime)
+	}
+	if pendingAuthorizationLifetime <= 0 || pendingAuthorizationLifetime > maxPendingAuthorizationLifetime {
+		return fmt.Errorf("pending authorization lifetime %s must be greater than 0 and at most %s",
+			pendingAuthorizationLifetime, maxPendingAuthorizationLifetime)
+	}
+	if orderLifetime <= 0 {
+		return fmt.Errorf("order lifetime %s must be greater than 0", orderLifetime)
+	}
+	if pendingAuthorizationLifetime >= authorizationLifetime {
+		return fmt.Errorf("pending authorization lifetime %s must be less than authorization lifetime %s",
+			pendingAuthorizationLifetime, authorizationLifetime)
+	}
+	if orderLifetime > authorizationLifetime {
+		return fmt.Errorf("order lifetime %s must not exceed authorization lifetime %s",
+			orderLifetime, authorizationLifetime)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package ra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestValidateLifetimes(t *testing.T) {
+	t.Parallel()
+
+	day := 24 * time.Hour
+	testCases := []struct {
+		name        string
+		authz       time.Duration
+		pending     time.Duration
+		order       time.Duration
+		expectedErr string
+	}{
+		{
+			name:    "defaults",
+			authz:   DefaultAuthorizationLifetime,
+			pending: DefaultPendingAuthorizationLifetime,
+			order:   DefaultOrderLifetime,
+		},
+		{
+			name:    "short-lived pending authorizations",
+			authz:   7 * day,
+			pending: time.Hour,
+			order:   7 * day,
+		},
+		{
+			name:        "zero authorization lifetime",
+			authz:       0,
+			pending:     day,
+			order:       day,
+			expectedErr: "authorization lifetime 0s must be greater than 0",
+		},
+		{
+			name:        "authorization lifetime too long",
+			authz:       398 * day,
+			pending:     day,
+			order:       day,
+			expectedErr: "authorization lifetime 9552h0m0s must be greater than 0 and at most 9528h0m0s",
+		},
+		{
+			name:        "negative pending authorization lifetime",
+			authz:       30 * day,
+			pending:     -time.Hour,
+			order:       day,
+			expectedErr: "pending authorization lifetime -1h0m0s must be greater than 0",
+		},
+		{
+			name:        "pending authorization lifetime too long",
+			authz:       90 * day,
+			pending:     30 * day,
+			order:       day,
+			expectedErr: "pending authorization lifetime 720h0m0s must be greater than 0 and at most 696h0m0s",
+		},
+		{
+			name:        "zero order lifetime",
+			authz:       30 * day,
+			pending:     7 * day,
+			order:       0,
+			expectedErr: "order lifetime 0s must be greater than 0",
+		},
+		{
+			name:        "pending equal to valid",
+			authz:       7 * day,
+			pending:     7 * day,
+			order:       day,
+			expectedErr: "pending authorization lifetime 168h0m0s must be less than authorization lifetime 168h0m0s",
+		},
+		{
+			name:        "order outlives authorizations",
+			authz:       7 * day,
+			pending:     day,
+			order:       8 * day,
+			expectedErr: "order lifetime 192h0m0s must not exceed authorization lifetime 168h0m0s",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateLifetimes(tc.authz, tc.pending, tc.order)
+			if tc.expectedErr == "" {
+				test.AssertNotError(t, err, "ValidateLifetimes failed")
+			} else {
+				test.AssertError(t, err, "ValidateLifetimes should have failed")
+				test.AssertContains(t, err.Error(), tc.expectedErr)
+			}
+		})
+	}
+}
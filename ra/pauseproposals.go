@@ -0,0 +1,41 @@
+package ra
+
+import (
+	"context"
+	"time"
+
+	"github.com/letsencrypt/boulder/ratelimits"
+)
+
+// ProposePausesEvery calls proposePauses every interval until the context is
+// done. The limiter must have had ratelimits.Limiter.EnableDenialStreaks
+// called on it.
+func (ra *RegistrationAuthorityImpl) ProposePausesEvery(ctx context.Context, interval, threshold time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ra.proposePauses(ctx, threshold)
+		}
+	}
+}
+
+// proposePauses proposes pausing each account whose new orders have been
+// denied by the FailedAuthorizationsPerDomainPerAccount limit continuously for
+// at least threshold. For now, proposals are only logged and counted, and an
+// account is proposed again each time until its denial streak ends.
+func (ra *RegistrationAuthorityImpl) proposePauses(ctx context.Context, threshold time.Duration) {
+	regIds, err := ra.limiter.DenialStreaks(ctx, threshold)
+	if err != nil {
+		ra.log.Warningf("getting %s denial streaks: %s", ratelimits.FailedAuthorizationsPerDomainPerAccount, err)
+		return
+	}
+	for _, regId := range regIds {
+		ra.log.AuditInfof("Proposing pause of account: regID=[%d] denied by %s for at least %s",
+			regId, ratelimits.FailedAuthorizationsPerDomainPerAccount, threshold)
+		ra.pauseProposals.Inc()
+	}
+}
@@ -0,0 +1,62 @@
+package ra
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/config"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/ratelimits"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestProposePauses(t *testing.T) {
+	t.Parallel()
+
+	fc := clock.NewFake()
+	limiter, err := ratelimits.NewLimiter(fc, ratelimits.NewInmemSource(fc), metrics.NoopRegisterer, blog.NewMock())
+	test.AssertNotError(t, err, "creating limiter")
+	err = limiter.EnableDenialStreaks()
+	test.AssertNotError(t, err, "enabling denial streaks")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.FailedAuthorizationsPerDomainPerAccount.String(): {
+			Burst:  1,
+			Count:  1,
+			Period: config.Duration{Duration: 7 * 24 * time.Hour},
+		},
+	})
+	test.AssertNotError(t, err, "creating transaction builder")
+
+	mockLog := blog.NewMock()
+	ra := &RegistrationAuthorityImpl{
+		clk:            fc,
+		log:            mockLog,
+		limiter:        limiter,
+		txnBuilder:     txnBuilder,
+		pauseProposals: prometheus.NewCounter(prometheus.CounterOpts{Name: "pause_proposals"}),
+	}
+
+	// Account 1 fails an authorization, and then has a new order denied
+	// every hour for two days.
+	txn, err := txnBuilder.FailedAuthorizationsPerDomainPerAccountSpendOnlyTransaction(1, "example.com")
+	test.AssertNotError(t, err, "building transaction")
+	_, err = limiter.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "spending")
+	for range 49 {
+		txns, err := txnBuilder.FailedAuthorizationsPerDomainPerAccountCheckOnlyTransactions(1, []string{"example.com"})
+		test.AssertNotError(t, err, "building transactions")
+		d, err := limiter.BatchSpend(context.Background(), txns)
+		test.AssertNotError(t, err, "checking")
+		test.Assert(t, d.Result(fc.Now()) != nil, "new order should have been denied")
+
+		ra.proposePauses(context.Background(), 48*time.Hour)
+		fc.Add(time.Hour)
+	}
+	test.AssertMetricWithLabelsEquals(t, ra.pauseProposals, nil, 1)
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`Proposing pause of account: regID=\[1\]`)), 1)
+}
@@ -0,0 +1,49 @@
+package ra
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+// PolicyHintRollout selects the accounts whose validation requests carry a VA
+// policy hint. An account is selected if it is listed in AccountIDs, or if it
+// falls within the first Percent of accounts, as determined by a stable hash
+// of the hint's key and the account ID.
+type PolicyHintRollout struct {
+	Value      string
+	AccountIDs []int64
+	Percent    int
+}
+
+// selects returns true if regID is selected by the rollout of the hint key.
+func (r PolicyHintRollout) selects(key string, regID int64) bool {
+	if slices.Contains(r.AccountIDs, regID) {
+		return true
+	}
+	if r.Percent <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key + ":" + strconv.FormatInt(regID, 10)))
+	return int(h.Sum32()%100) < r.Percent
+}
+
+// PolicyHintRollouts maps each VA policy hint key to its rollout.
+type PolicyHintRollouts map[string]PolicyHintRollout
+
+// hintsFor returns the policy hints to send to the VA with validation requests
+// made on behalf of regID, or nil if there are none.
+func (r PolicyHintRollouts) hintsFor(regID int64) map[string]string {
+	var hints map[string]string
+	for key, rollout := range r {
+		if !rollout.selects(key, regID) {
+			continue
+		}
+		if hints == nil {
+			hints = make(map[string]string)
+		}
+		hints[key] = rollout.Value
+	}
+	return hints
+}
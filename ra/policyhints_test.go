@@ -0,0 +1,39 @@
+package ra
+
+import (
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestPolicyHintRolloutsHintsFor(t *testing.T) {
+	t.Parallel()
+
+	// With no rollouts, no hints are sent.
+	var none PolicyHintRollouts
+	test.Assert(t, none.hintsFor(1) == nil, "expected no hints")
+
+	rollouts := PolicyHintRollouts{
+		"listed":  {Value: "true", AccountIDs: []int64{7}},
+		"all":     {Value: "enforce", Percent: 100},
+		"nobody":  {Value: "true", Percent: 0},
+		"partial": {Value: "true", Percent: 50},
+	}
+	test.AssertDeepEquals(t, rollouts.hintsFor(7)["listed"], "true")
+	_, ok := rollouts.hintsFor(8)["listed"]
+	test.Assert(t, !ok, "unlisted account should not get the hint")
+
+	var partial int
+	for regID := range int64(1000) {
+		hints := rollouts.hintsFor(regID)
+		test.AssertEquals(t, hints["all"], "enforce")
+		_, ok := hints["nobody"]
+		test.Assert(t, !ok, "no account should get a 0 percent hint")
+		if _, ok := hints["partial"]; ok {
+			partial++
+		}
+		// Selection is stable across calls.
+		test.AssertDeepEquals(t, rollouts.hintsFor(regID), hints)
+	}
+	test.Assert(t, partial > 400 && partial < 600, "expected about half of accounts to get a 50 percent hint")
+}
@@ -31,18 +31,24 @@ const (
 	RegistrationAuthority_RevokeCertByApplicant_FullMethodName             = "/ra.RegistrationAuthority/RevokeCertByApplicant"
 	RegistrationAuthority_RevokeCertByKey_FullMethodName                   = "/ra.RegistrationAuthority/RevokeCertByKey"
 	RegistrationAuthority_AdministrativelyRevokeCertificate_FullMethodName = "/ra.RegistrationAuthority/AdministrativelyRevokeCertificate"
+	RegistrationAuthority_RevokeCertificatesByKeyHash_FullMethodName       = "/ra.RegistrationAuthority/RevokeCertificatesByKeyHash"
 	RegistrationAuthority_NewOrder_FullMethodName                          = "/ra.RegistrationAuthority/NewOrder"
 	RegistrationAuthority_GetAuthorization_FullMethodName                  = "/ra.RegistrationAuthority/GetAuthorization"
 	RegistrationAuthority_FinalizeOrder_FullMethodName                     = "/ra.RegistrationAuthority/FinalizeOrder"
+	RegistrationAuthority_PrecheckFinalize_FullMethodName                  = "/ra.RegistrationAuthority/PrecheckFinalize"
 	RegistrationAuthority_GenerateOCSP_FullMethodName                      = "/ra.RegistrationAuthority/GenerateOCSP"
 	RegistrationAuthority_UnpauseAccount_FullMethodName                    = "/ra.RegistrationAuthority/UnpauseAccount"
+	RegistrationAuthority_GetRegistrationByKey_FullMethodName              = "/ra.RegistrationAuthority/GetRegistrationByKey"
+	RegistrationAuthority_GetIssuanceProfiles_FullMethodName               = "/ra.RegistrationAuthority/GetIssuanceProfiles"
+	RegistrationAuthority_KeyRollover_FullMethodName                       = "/ra.RegistrationAuthority/KeyRollover"
+	RegistrationAuthority_CheckWillingness_FullMethodName                  = "/ra.RegistrationAuthority/CheckWillingness"
 )
 
 // RegistrationAuthorityClient is the client API for RegistrationAuthority service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type RegistrationAuthorityClient interface {
-	NewRegistration(ctx context.Context, in *proto.Registration, opts ...grpc.CallOption) (*proto.Registration, error)
+	NewRegistration(ctx context.Context, in *NewRegistrationRequest, opts ...grpc.CallOption) (*proto.Registration, error)
 	UpdateRegistrationContact(ctx context.Context, in *UpdateRegistrationContactRequest, opts ...grpc.CallOption) (*proto.Registration, error)
 	UpdateRegistrationKey(ctx context.Context, in *UpdateRegistrationKeyRequest, opts ...grpc.CallOption) (*proto.Registration, error)
 	PerformValidation(ctx context.Context, in *PerformValidationRequest, opts ...grpc.CallOption) (*proto.Authorization, error)
@@ -51,12 +57,29 @@ type RegistrationAuthorityClient interface {
 	RevokeCertByApplicant(ctx context.Context, in *RevokeCertByApplicantRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	RevokeCertByKey(ctx context.Context, in *RevokeCertByKeyRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	AdministrativelyRevokeCertificate(ctx context.Context, in *AdministrativelyRevokeCertificateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RevokeCertificatesByKeyHash(ctx context.Context, in *RevokeCertificatesByKeyHashRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RevokeCertificatesByKeyHashResponse], error)
 	NewOrder(ctx context.Context, in *NewOrderRequest, opts ...grpc.CallOption) (*proto.Order, error)
 	GetAuthorization(ctx context.Context, in *GetAuthorizationRequest, opts ...grpc.CallOption) (*proto.Authorization, error)
 	FinalizeOrder(ctx context.Context, in *FinalizeOrderRequest, opts ...grpc.CallOption) (*proto.Order, error)
+	// PrecheckFinalize runs the checks FinalizeOrder would run before issuance,
+	// without modifying the order or spending any rate limits.
+	PrecheckFinalize(ctx context.Context, in *FinalizeOrderRequest, opts ...grpc.CallOption) (*PrecheckFinalizeResponse, error)
 	// Generate an OCSP response based on the DB's current status and reason code.
 	GenerateOCSP(ctx context.Context, in *GenerateOCSPRequest, opts ...grpc.CallOption) (*proto1.OCSPResponse, error)
 	UnpauseAccount(ctx context.Context, in *UnpauseAccountRequest, opts ...grpc.CallOption) (*UnpauseAccountResponse, error)
+	// GetRegistrationByKey returns the account with the given key, without
+	// creating one. A deactivated account is returned as an Unauthorized error.
+	GetRegistrationByKey(ctx context.Context, in *GetRegistrationByKeyRequest, opts ...grpc.CallOption) (*proto.Registration, error)
+	// GetIssuanceProfiles returns the certificate profiles which subscribers may
+	// request, for the WFE to advertise in its directory.
+	GetIssuanceProfiles(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetIssuanceProfilesResponse, error)
+	// KeyRollover changes an account's key to the key which signed the inner
+	// JWS of an ACME key-change request, once the request has been verified.
+	KeyRollover(ctx context.Context, in *KeyRolloverRequest, opts ...grpc.CallOption) (*proto.Registration, error)
+	// CheckWillingness reports, for each identifier, whether the RA would be
+	// willing to issue for it, without creating anything or spending any rate
+	// limits other than the caller's own.
+	CheckWillingness(ctx context.Context, in *CheckWillingnessRequest, opts ...grpc.CallOption) (*CheckWillingnessResponse, error)
 }
 
 type registrationAuthorityClient struct {
@@ -67,7 +90,7 @@ func NewRegistrationAuthorityClient(cc grpc.ClientConnInterface) RegistrationAut
 	return &registrationAuthorityClient{cc}
 }
 
-func (c *registrationAuthorityClient) NewRegistration(ctx context.Context, in *proto.Registration, opts ...grpc.CallOption) (*proto.Registration, error) {
+func (c *registrationAuthorityClient) NewRegistration(ctx context.Context, in *NewRegistrationRequest, opts ...grpc.CallOption) (*proto.Registration, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(proto.Registration)
 	err := c.cc.Invoke(ctx, RegistrationAuthority_NewRegistration_FullMethodName, in, out, cOpts...)
@@ -157,6 +180,25 @@ func (c *registrationAuthorityClient) AdministrativelyRevokeCertificate(ctx cont
 	return out, nil
 }
 
+func (c *registrationAuthorityClient) RevokeCertificatesByKeyHash(ctx context.Context, in *RevokeCertificatesByKeyHashRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RevokeCertificatesByKeyHashResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RegistrationAuthority_ServiceDesc.Streams[0], RegistrationAuthority_RevokeCertificatesByKeyHash_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RevokeCertificatesByKeyHashRequest, RevokeCertificatesByKeyHashResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RegistrationAuthority_RevokeCertificatesByKeyHashClient = grpc.ServerStreamingClient[RevokeCertificatesByKeyHashResponse]
+
 func (c *registrationAuthorityClient) NewOrder(ctx context.Context, in *NewOrderRequest, opts ...grpc.CallOption) (*proto.Order, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(proto.Order)
@@ -187,6 +229,16 @@ func (c *registrationAuthorityClient) FinalizeOrder(ctx context.Context, in *Fin
 	return out, nil
 }
 
+func (c *registrationAuthorityClient) PrecheckFinalize(ctx context.Context, in *FinalizeOrderRequest, opts ...grpc.CallOption) (*PrecheckFinalizeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PrecheckFinalizeResponse)
+	err := c.cc.Invoke(ctx, RegistrationAuthority_PrecheckFinalize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *registrationAuthorityClient) GenerateOCSP(ctx context.Context, in *GenerateOCSPRequest, opts ...grpc.CallOption) (*proto1.OCSPResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(proto1.OCSPResponse)
@@ -207,11 +259,51 @@ func (c *registrationAuthorityClient) UnpauseAccount(ctx context.Context, in *Un
 	return out, nil
 }
 
+func (c *registrationAuthorityClient) GetRegistrationByKey(ctx context.Context, in *GetRegistrationByKeyRequest, opts ...grpc.CallOption) (*proto.Registration, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(proto.Registration)
+	err := c.cc.Invoke(ctx, RegistrationAuthority_GetRegistrationByKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registrationAuthorityClient) GetIssuanceProfiles(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetIssuanceProfilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetIssuanceProfilesResponse)
+	err := c.cc.Invoke(ctx, RegistrationAuthority_GetIssuanceProfiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registrationAuthorityClient) KeyRollover(ctx context.Context, in *KeyRolloverRequest, opts ...grpc.CallOption) (*proto.Registration, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(proto.Registration)
+	err := c.cc.Invoke(ctx, RegistrationAuthority_KeyRollover_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registrationAuthorityClient) CheckWillingness(ctx context.Context, in *CheckWillingnessRequest, opts ...grpc.CallOption) (*CheckWillingnessResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckWillingnessResponse)
+	err := c.cc.Invoke(ctx, RegistrationAuthority_CheckWillingness_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RegistrationAuthorityServer is the server API for RegistrationAuthority service.
 // All implementations must embed UnimplementedRegistrationAuthorityServer
 // for forward compatibility
 type RegistrationAuthorityServer interface {
-	NewRegistration(context.Context, *proto.Registration) (*proto.Registration, error)
+	NewRegistration(context.Context, *NewRegistrationRequest) (*proto.Registration, error)
 	UpdateRegistrationContact(context.Context, *UpdateRegistrationContactRequest) (*proto.Registration, error)
 	UpdateRegistrationKey(context.Context, *UpdateRegistrationKeyRequest) (*proto.Registration, error)
 	PerformValidation(context.Context, *PerformValidationRequest) (*proto.Authorization, error)
@@ -220,12 +312,29 @@ type RegistrationAuthorityServer interface {
 	RevokeCertByApplicant(context.Context, *RevokeCertByApplicantRequest) (*emptypb.Empty, error)
 	RevokeCertByKey(context.Context, *RevokeCertByKeyRequest) (*emptypb.Empty, error)
 	AdministrativelyRevokeCertificate(context.Context, *AdministrativelyRevokeCertificateRequest) (*emptypb.Empty, error)
+	RevokeCertificatesByKeyHash(*RevokeCertificatesByKeyHashRequest, grpc.ServerStreamingServer[RevokeCertificatesByKeyHashResponse]) error
 	NewOrder(context.Context, *NewOrderRequest) (*proto.Order, error)
 	GetAuthorization(context.Context, *GetAuthorizationRequest) (*proto.Authorization, error)
 	FinalizeOrder(context.Context, *FinalizeOrderRequest) (*proto.Order, error)
+	// PrecheckFinalize runs the checks FinalizeOrder would run before issuance,
+	// without modifying the order or spending any rate limits.
+	PrecheckFinalize(context.Context, *FinalizeOrderRequest) (*PrecheckFinalizeResponse, error)
 	// Generate an OCSP response based on the DB's current status and reason code.
 	GenerateOCSP(context.Context, *GenerateOCSPRequest) (*proto1.OCSPResponse, error)
 	UnpauseAccount(context.Context, *UnpauseAccountRequest) (*UnpauseAccountResponse, error)
+	// GetRegistrationByKey returns the account with the given key, without
+	// creating one. A deactivated account is returned as an Unauthorized error.
+	GetRegistrationByKey(context.Context, *GetRegistrationByKeyRequest) (*proto.Registration, error)
+	// GetIssuanceProfiles returns the certificate profiles which subscribers may
+	// request, for the WFE to advertise in its directory.
+	GetIssuanceProfiles(context.Context, *emptypb.Empty) (*GetIssuanceProfilesResponse, error)
+	// KeyRollover changes an account's key to the key which signed the inner
+	// JWS of an ACME key-change request, once the request has been verified.
+	KeyRollover(context.Context, *KeyRolloverRequest) (*proto.Registration, error)
+	// CheckWillingness reports, for each identifier, whether the RA would be
+	// willing to issue for it, without creating anything or spending any rate
+	// limits other than the caller's own.
+	CheckWillingness(context.Context, *CheckWillingnessRequest) (*CheckWillingnessResponse, error)
 	mustEmbedUnimplementedRegistrationAuthorityServer()
 }
 
@@ -233,7 +342,7 @@ type RegistrationAuthorityServer interface {
 type UnimplementedRegistrationAuthorityServer struct {
 }
 
-func (UnimplementedRegistrationAuthorityServer) NewRegistration(context.Context, *proto.Registration) (*proto.Registration, error) {
+func (UnimplementedRegistrationAuthorityServer) NewRegistration(context.Context, *NewRegistrationRequest) (*proto.Registration, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NewRegistration not implemented")
 }
 func (UnimplementedRegistrationAuthorityServer) UpdateRegistrationContact(context.Context, *UpdateRegistrationContactRequest) (*proto.Registration, error) {
@@ -260,6 +369,9 @@ func (UnimplementedRegistrationAuthorityServer) RevokeCertByKey(context.Context,
 func (UnimplementedRegistrationAuthorityServer) AdministrativelyRevokeCertificate(context.Context, *AdministrativelyRevokeCertificateRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AdministrativelyRevokeCertificate not implemented")
 }
+func (UnimplementedRegistrationAuthorityServer) RevokeCertificatesByKeyHash(*RevokeCertificatesByKeyHashRequest, grpc.ServerStreamingServer[RevokeCertificatesByKeyHashResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method RevokeCertificatesByKeyHash not implemented")
+}
 func (UnimplementedRegistrationAuthorityServer) NewOrder(context.Context, *NewOrderRequest) (*proto.Order, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NewOrder not implemented")
 }
@@ -269,12 +381,27 @@ func (UnimplementedRegistrationAuthorityServer) GetAuthorization(context.Context
 func (UnimplementedRegistrationAuthorityServer) FinalizeOrder(context.Context, *FinalizeOrderRequest) (*proto.Order, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FinalizeOrder not implemented")
 }
+func (UnimplementedRegistrationAuthorityServer) PrecheckFinalize(context.Context, *FinalizeOrderRequest) (*PrecheckFinalizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrecheckFinalize not implemented")
+}
 func (UnimplementedRegistrationAuthorityServer) GenerateOCSP(context.Context, *GenerateOCSPRequest) (*proto1.OCSPResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GenerateOCSP not implemented")
 }
 func (UnimplementedRegistrationAuthorityServer) UnpauseAccount(context.Context, *UnpauseAccountRequest) (*UnpauseAccountResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UnpauseAccount not implemented")
 }
+func (UnimplementedRegistrationAuthorityServer) GetRegistrationByKey(context.Context, *GetRegistrationByKeyRequest) (*proto.Registration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRegistrationByKey not implemented")
+}
+func (UnimplementedRegistrationAuthorityServer) GetIssuanceProfiles(context.Context, *emptypb.Empty) (*GetIssuanceProfilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIssuanceProfiles not implemented")
+}
+func (UnimplementedRegistrationAuthorityServer) KeyRollover(context.Context, *KeyRolloverRequest) (*proto.Registration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KeyRollover not implemented")
+}
+func (UnimplementedRegistrationAuthorityServer) CheckWillingness(context.Context, *CheckWillingnessRequest) (*CheckWillingnessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckWillingness not implemented")
+}
 func (UnimplementedRegistrationAuthorityServer) mustEmbedUnimplementedRegistrationAuthorityServer() {}
 
 // UnsafeRegistrationAuthorityServer may be embedded to opt out of forward compatibility for this service.
@@ -289,7 +416,7 @@ func RegisterRegistrationAuthorityServer(s grpc.ServiceRegistrar, srv Registrati
 }
 
 func _RegistrationAuthority_NewRegistration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(proto.Registration)
+	in := new(NewRegistrationRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -301,7 +428,7 @@ func _RegistrationAuthority_NewRegistration_Handler(srv interface{}, ctx context
 		FullMethod: RegistrationAuthority_NewRegistration_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(RegistrationAuthorityServer).NewRegistration(ctx, req.(*proto.Registration))
+		return srv.(RegistrationAuthorityServer).NewRegistration(ctx, req.(*NewRegistrationRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -450,6 +577,17 @@ func _RegistrationAuthority_AdministrativelyRevokeCertificate_Handler(srv interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RegistrationAuthority_RevokeCertificatesByKeyHash_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RevokeCertificatesByKeyHashRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RegistrationAuthorityServer).RevokeCertificatesByKeyHash(m, &grpc.GenericServerStream[RevokeCertificatesByKeyHashRequest, RevokeCertificatesByKeyHashResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RegistrationAuthority_RevokeCertificatesByKeyHashServer = grpc.ServerStreamingServer[RevokeCertificatesByKeyHashResponse]
+
 func _RegistrationAuthority_NewOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(NewOrderRequest)
 	if err := dec(in); err != nil {
@@ -504,6 +642,24 @@ func _RegistrationAuthority_FinalizeOrder_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RegistrationAuthority_PrecheckFinalize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinalizeOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationAuthorityServer).PrecheckFinalize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistrationAuthority_PrecheckFinalize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationAuthorityServer).PrecheckFinalize(ctx, req.(*FinalizeOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _RegistrationAuthority_GenerateOCSP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GenerateOCSPRequest)
 	if err := dec(in); err != nil {
@@ -540,6 +696,78 @@ func _RegistrationAuthority_UnpauseAccount_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RegistrationAuthority_GetRegistrationByKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRegistrationByKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationAuthorityServer).GetRegistrationByKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistrationAuthority_GetRegistrationByKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationAuthorityServer).GetRegistrationByKey(ctx, req.(*GetRegistrationByKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistrationAuthority_GetIssuanceProfiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationAuthorityServer).GetIssuanceProfiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistrationAuthority_GetIssuanceProfiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationAuthorityServer).GetIssuanceProfiles(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistrationAuthority_KeyRollover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRolloverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationAuthorityServer).KeyRollover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistrationAuthority_KeyRollover_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationAuthorityServer).KeyRollover(ctx, req.(*KeyRolloverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistrationAuthority_CheckWillingness_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckWillingnessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationAuthorityServer).CheckWillingness(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistrationAuthority_CheckWillingness_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationAuthorityServer).CheckWillingness(ctx, req.(*CheckWillingnessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // RegistrationAuthority_ServiceDesc is the grpc.ServiceDesc for RegistrationAuthority service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -595,6 +823,10 @@ var RegistrationAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "FinalizeOrder",
 			Handler:    _RegistrationAuthority_FinalizeOrder_Handler,
 		},
+		{
+			MethodName: "PrecheckFinalize",
+			Handler:    _RegistrationAuthority_PrecheckFinalize_Handler,
+		},
 		{
 			MethodName: "GenerateOCSP",
 			Handler:    _RegistrationAuthority_GenerateOCSP_Handler,
@@ -603,7 +835,29 @@ var RegistrationAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UnpauseAccount",
 			Handler:    _RegistrationAuthority_UnpauseAccount_Handler,
 		},
+		{
+			MethodName: "GetRegistrationByKey",
+			Handler:    _RegistrationAuthority_GetRegistrationByKey_Handler,
+		},
+		{
+			MethodName: "GetIssuanceProfiles",
+			Handler:    _RegistrationAuthority_GetIssuanceProfiles_Handler,
+		},
+		{
+			MethodName: "KeyRollover",
+			Handler:    _RegistrationAuthority_KeyRollover_Handler,
+		},
+		{
+			MethodName: "CheckWillingness",
+			Handler:    _RegistrationAuthority_CheckWillingness_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RevokeCertificatesByKeyHash",
+			Handler:       _RegistrationAuthority_RevokeCertificatesByKeyHash_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "ra.proto",
 }
@@ -1,13 +1,17 @@
 package ra
 
 import (
+	"bytes"
 	"context"
 	"crypto"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net"
 	"net/url"
 	"slices"
@@ -18,9 +22,14 @@ import (
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
+	"github.com/golang/groupcache/lru"
 	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zmap/zlint/v3/lint"
 	"golang.org/x/crypto/ocsp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -29,6 +38,7 @@ import (
 	"github.com/letsencrypt/boulder/akamai"
 	akamaipb "github.com/letsencrypt/boulder/akamai/proto"
 	"github.com/letsencrypt/boulder/allowlist"
+	"github.com/letsencrypt/boulder/bdns"
 	capb "github.com/letsencrypt/boulder/ca/proto"
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
@@ -58,25 +68,80 @@ import (
 var (
 	errIncompleteGRPCRequest  = errors.New("incomplete gRPC request message")
 	errIncompleteGRPCResponse = errors.New("incomplete gRPC response message")
+	errValidationNotScheduled = errors.New("validation could not be scheduled: too many validations are in progress or the RA is shutting down")
 
 	// caaRecheckDuration is the amount of time after a CAA check that we will
 	// recheck the CAA records for a domain. Per Baseline Requirements, we must
 	// recheck CAA records within 8 hours of issuance. We set this to 7 hours to
 	// stay on the safe side.
 	caaRecheckDuration = -7 * time.Hour
+
+	// renewalWindow is how far back the RA looks for a certificate with the
+	// same set of names when deciding whether an order is a renewal.
+	renewalWindow = 120 * 24 * time.Hour
+)
+
+// MustStaplePolicy determines what FinalizeOrder does with a CSR which requests
+// the OCSP must-staple extension.
+type MustStaplePolicy string
+
+const (
+	// MustStapleAllow passes the extension through to the certificate, subject
+	// to the RA's must-staple allowlist. It is the default.
+	MustStapleAllow MustStaplePolicy = "allow"
+
+	// MustStapleStrip issues the certificate without the extension.
+	MustStapleStrip MustStaplePolicy = "strip"
+
+	// MustStapleReject fails the order with a badCSR problem.
+	MustStapleReject MustStaplePolicy = "reject"
 )
 
-// ValidationProfile holds the allowlist for a given validation profile.
+// ValidationProfile holds the allowlist and must-staple policy for a given
+// validation profile, along with the details advertised to subscribers by
+// GetIssuanceProfiles.
 type ValidationProfile struct {
 	// allowList holds the set of account IDs allowed to use this profile. If
 	// nil, the profile is open to all accounts (everyone is allowed).
 	allowList *allowlist.List[int64]
+	// mustStaplePolicy determines how CSRs which request the OCSP must-staple
+	// extension are handled under this profile.
+	mustStaplePolicy MustStaplePolicy
+	// validity is the advertised validity period of certificates issued under
+	// this profile. Zero if it isn't advertised.
+	validity time.Duration
+	// identifierTypes are the advertised types of identifier which orders
+	// using this profile may contain. Nil if they aren't advertised.
+	identifierTypes []identifier.IdentifierType
+}
+
+// ValidationProfileConfig holds the settings of a ValidationProfile beyond its
+// allowList.
+type ValidationProfileConfig struct {
+	// MustStaplePolicy determines how CSRs which request the OCSP must-staple
+	// extension are handled. If empty, it is MustStapleAllow.
+	MustStaplePolicy MustStaplePolicy
+	// Validity is the advertised validity period of certificates issued under
+	// the profile. If zero, it isn't advertised.
+	Validity time.Duration
+	// IdentifierTypes are the advertised types of identifier which orders
+	// using the profile may contain. If nil, they aren't advertised.
+	IdentifierTypes []identifier.IdentifierType
 }
 
 // NewValidationProfile creates a new ValidationProfile with the provided
-// allowList. A nil allowList is interpreted as open access for all accounts.
-func NewValidationProfile(allowList *allowlist.List[int64]) *ValidationProfile {
-	return &ValidationProfile{allowList: allowList}
+// allowList and settings. A nil allowList is interpreted as open access for
+// all accounts.
+func NewValidationProfile(allowList *allowlist.List[int64], cfg ValidationProfileConfig) *ValidationProfile {
+	if cfg.MustStaplePolicy == "" {
+		cfg.MustStaplePolicy = MustStapleAllow
+	}
+	return &ValidationProfile{
+		allowList:        allowList,
+		mustStaplePolicy: cfg.MustStaplePolicy,
+		validity:         cfg.Validity,
+		identifierTypes:  cfg.IdentifierTypes,
+	}
 }
 
 // RegistrationAuthorityImpl defines an RA.
@@ -92,13 +157,33 @@ type RegistrationAuthorityImpl struct {
 	PA        core.PolicyAuthority
 	publisher pubpb.PublisherClient
 
+	// ContactDomains checks that the domains of contact email addresses can
+	// receive mail. If nil, contact domains are not checked.
+	ContactDomains *ContactDomainChecker
+	// EAB verifies the External Account Binding of new registrations. If nil,
+	// no binding is required.
+	EAB *EABVerifier
+	// LintProblemSeverity is the least severe lint failure, reported by the CA
+	// at finalize, which fails the order with a badCSR problem naming the
+	// failed lints. The zero value includes every failure.
+	LintProblemSeverity lint.LintStatus
+	// ValidationPolicyHints selects the VA policy hints sent with validation
+	// and CAA requests, to roll out variants of VA behavior gradually. If nil,
+	// no hints are sent.
+	ValidationPolicyHints PolicyHintRollouts
+	// MaxPendingAuthorizationsPerAccount is the most pending authorizations
+	// an account may hold. Once a new order is stored, NewOrder deactivates
+	// the account's soonest-expiring pending authorizations which no
+	// unexpired order references to make room for its new ones, and rejects
+	// the order up front if there aren't enough. Zero means no limit.
+	MaxPendingAuthorizationsPerAccount int
+
 	clk       clock.Clock
 	log       blog.Logger
 	keyPolicy goodkey.KeyPolicy
 	// How long before a newly created authorization expires.
 	authorizationLifetime        time.Duration
 	pendingAuthorizationLifetime time.Duration
-	validationProfiles           map[string]*ValidationProfile
 	mustStapleAllowList          *allowlist.List[int64]
 	maxContactsPerReg            int
 	limiter                      *ratelimits.Limiter
@@ -108,6 +193,20 @@ type RegistrationAuthorityImpl struct {
 	finalizeTimeout              time.Duration
 	drainWG                      sync.WaitGroup
 
+	// profilesMu guards validationProfiles and defaultProfileName, which are
+	// replaced wholesale by SetValidationProfiles.
+	profilesMu         sync.RWMutex
+	validationProfiles map[string]*ValidationProfile
+	defaultProfileName string
+
+	// admins is the allowlist of administrators who may call
+	// AdministrativelyRevokeCertificate. If nil, any admin is permitted.
+	admins map[string]*AdminPolicy
+	// adminRevocations holds, for each admin with an hourly revocation cap,
+	// the times of their revocations within the last hour.
+	adminRevocations   map[string][]time.Time
+	adminRevocationsMu sync.Mutex
+
 	issuersByNameID map[issuance.NameID]*issuance.Certificate
 	purger          akamaipb.AkamaiPurgerClient
 
@@ -125,6 +224,13 @@ type RegistrationAuthorityImpl struct {
 	certCSRMismatch           prometheus.Counter
 	pauseCounter              *prometheus.CounterVec
 	mustStapleRequestsCounter *prometheus.CounterVec
+	mustStapleOutcomes        *prometheus.CounterVec
+	vaOverloads               *prometheus.CounterVec
+	pendingAuthzCap           *prometheus.CounterVec
+	pauseProposals            prometheus.Counter
+	newOrderReconciliations   *prometheus.CounterVec
+	lifecycle                 *orderLifecycle
+	validations               *validationQueue
 }
 
 var _ rapb.RegistrationAuthorityServer = (*RegistrationAuthorityImpl)(nil)
@@ -149,6 +255,9 @@ func NewRegistrationAuthorityImpl(
 	ctp *ctpolicy.CTPolicy,
 	purger akamaipb.AkamaiPurgerClient,
 	issuers []*issuance.Certificate,
+	admins map[string]*AdminPolicy,
+	validationWorkers int,
+	validationQueueSize int,
 ) *RegistrationAuthorityImpl {
 	ctpolicyResults := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -245,6 +354,36 @@ func NewRegistrationAuthorityImpl(
 	}, []string{"allowlist"})
 	stats.MustRegister(mustStapleRequestsCounter)
 
+	mustStapleOutcomes := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "must_staple_outcomes",
+		Help: "Number of finalized CSRs which requested must-staple, labeled by the profile's policy=[allow|strip|reject] and outcome=[allowed|stripped|rejected]",
+	}, []string{"policy", "outcome"})
+	stats.MustRegister(mustStapleOutcomes)
+
+	vaOverloads := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "va_overloads",
+		Help: "Number of VA requests shed because the VA was at capacity, labeled by whether the request was then result=[retried|abandoned]",
+	}, []string{"result"})
+	stats.MustRegister(vaOverloads)
+
+	pendingAuthzCap := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pending_authorization_cap",
+		Help: "Number of actions taken to keep accounts under the pending authorization cap, labeled by action=[pruned|rejected]",
+	}, []string{"action"})
+	stats.MustRegister(pendingAuthzCap)
+
+	pauseProposals := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "pause_proposals",
+		Help: "Number of times an account was proposed for pausing because its new orders have been denied by the FailedAuthorizationsPerDomainPerAccount limit for too long",
+	})
+	stats.MustRegister(pauseProposals)
+
+	newOrderReconciliations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "new_order_limit_reconciliations",
+		Help: "Number of new order rate limit reservations which couldn't be committed, labeled by how they were reconciled result=[expired|released|already_resolved|failed]",
+	}, []string{"result"})
+	stats.MustRegister(newOrderReconciliations)
+
 	issuersByNameID := make(map[issuance.NameID]*issuance.Certificate)
 	for _, issuer := range issuers {
 		issuersByNameID[issuer.NameID()] = issuer
@@ -269,6 +408,8 @@ func NewRegistrationAuthorityImpl(
 		ctpolicyResults:              ctpolicyResults,
 		purger:                       purger,
 		issuersByNameID:              issuersByNameID,
+		admins:                       admins,
+		adminRevocations:             make(map[string][]time.Time),
 		namesPerCert:                 namesPerCert,
 		newRegCounter:                newRegCounter,
 		recheckCAACounter:            recheckCAACounter,
@@ -280,6 +421,13 @@ func NewRegistrationAuthorityImpl(
 		certCSRMismatch:              certCSRMismatch,
 		pauseCounter:                 pauseCounter,
 		mustStapleRequestsCounter:    mustStapleRequestsCounter,
+		mustStapleOutcomes:           mustStapleOutcomes,
+		vaOverloads:                  vaOverloads,
+		pendingAuthzCap:              pendingAuthzCap,
+		pauseProposals:               pauseProposals,
+		newOrderReconciliations:      newOrderReconciliations,
+		lifecycle:                    newOrderLifecycle(stats, clk),
+		validations:                  newValidationQueue(validationWorkers, validationQueueSize, stats),
 	}
 	return ra
 }
@@ -338,6 +486,9 @@ type certificateRequestEvent struct {
 	// UserAgent is the User-Agent header from the ACME client (provided to the
 	// RA via gRPC metadata).
 	UserAgent string
+	// MustStapleStripped is true if the CSR requested the OCSP must-staple
+	// extension, and it was left out of the certificate by profile policy.
+	MustStapleStripped bool `json:",omitempty"`
 }
 
 // certificateRevocationEvent is a struct for holding information that is logged
@@ -359,6 +510,9 @@ type certificateRevocationEvent struct {
 	// AdminName is the name of the admin requester.
 	// Will be zero for subscriber revocations.
 	AdminName string `json:",omitempty"`
+	// AdminChecks are the admin capability checks performed, in order.
+	// Will be empty for subscriber revocations.
+	AdminChecks []string `json:",omitempty"`
 	// Error contains any error encountered during revocation.
 	Error string `json:",omitempty"`
 }
@@ -378,7 +532,7 @@ type finalizationCAACheckEvent struct {
 }
 
 // NewRegistration constructs a new Registration from a request.
-func (ra *RegistrationAuthorityImpl) NewRegistration(ctx context.Context, request *corepb.Registration) (*corepb.Registration, error) {
+func (ra *RegistrationAuthorityImpl) NewRegistration(ctx context.Context, request *rapb.NewRegistrationRequest) (*corepb.Registration, error) {
 	// Error if the request is nil, there is no account key or IP address
 	if request == nil || len(request.Key) == 0 {
 		return nil, errIncompleteGRPCRequest
@@ -400,6 +554,15 @@ func (ra *RegistrationAuthorityImpl) NewRegistration(ctx context.Context, reques
 	if err != nil {
 		return nil, err
 	}
+	err = ra.ContactDomains.Check(ctx, request.Contact)
+	if err != nil {
+		return nil, err
+	}
+
+	eabKeyID, err := ra.EAB.Verify(ctx, request.ExternalAccountBinding, request.NewAccountURL, &key)
+	if err != nil {
+		return nil, err
+	}
 
 	// Don't populate ID or CreatedAt because those will be set by the SA.
 	req := &corepb.Registration{
@@ -407,10 +570,18 @@ func (ra *RegistrationAuthorityImpl) NewRegistration(ctx context.Context, reques
 		Contact:   request.Contact,
 		Agreement: request.Agreement,
 		Status:    string(core.StatusValid),
+		EabKeyID:  eabKeyID,
 	}
 
 	// Store the registration object, then return the version that got stored.
-	res, err := ra.SA.NewRegistration(ctx, req)
+	var res *corepb.Registration
+	if eabKeyID != "" && ra.EAB.oneAccountPerKeyID {
+		// The SA refuses to store the registration if another has already
+		// claimed this key identifier.
+		res, err = ra.SA.NewRegistrationClaimingEABKeyID(ctx, req)
+	} else {
+		res, err = ra.SA.NewRegistration(ctx, req)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -419,6 +590,169 @@ func (ra *RegistrationAuthorityImpl) NewRegistration(ctx context.Context, reques
 	return res, nil
 }
 
+// GetRegistrationByKey returns the account with the given key, for flows such
+// as newAccount with onlyReturnExisting which must find an account without
+// ever creating one. If there is no such account a NotFound error is
+// returned, and if it has been deactivated an Unauthorized error.
+func (ra *RegistrationAuthorityImpl) GetRegistrationByKey(ctx context.Context, req *rapb.GetRegistrationByKeyRequest) (*corepb.Registration, error) {
+	if core.IsAnyNilOrZero(req, req.Jwk) {
+		return nil, errIncompleteGRPCRequest
+	}
+
+	// The SA looks the account up by the same digest, so computing it here
+	// rejects unusable keys before querying.
+	sha, err := core.JWKDigestB64(req.Jwk)
+	if err != nil {
+		return nil, berrors.MalformedError("invalid account key: %s", err)
+	}
+
+	reg, err := ra.SA.GetRegistrationByKey(ctx, &sapb.JSONWebKey{Jwk: req.Jwk})
+	if err != nil {
+		if errors.Is(err, berrors.NotFound) {
+			return nil, berrors.NotFoundError("no account exists with public key sha256 %q", sha)
+		}
+		return nil, fmt.Errorf("getting account by key from SA: %w", err)
+	}
+
+	if core.AcmeStatus(reg.Status) == core.StatusDeactivated {
+		return nil, berrors.UnauthorizedError("An account with the provided public key exists but is deactivated")
+	}
+	return reg, nil
+}
+
+// EABKeySource looks up the MAC keys of External Account Bindings.
+type EABKeySource interface {
+	// MACKey returns the MAC key for the provided key identifier, or a
+	// berrors.NotFound error if the key identifier is unknown.
+	MACKey(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// StaticEABKeys is an EABKeySource backed by a fixed map from key identifier
+// to MAC key, typically loaded from configuration.
+type StaticEABKeys map[string][]byte
+
+// MACKey implements EABKeySource.
+func (s StaticEABKeys) MACKey(_ context.Context, keyID string) ([]byte, error) {
+	key, ok := s[keyID]
+	if !ok {
+		return nil, berrors.NotFoundError("unknown external account binding key %q", keyID)
+	}
+	return key, nil
+}
+
+// eabKeyGetter is the subset of the SA's interface used by SAEABKeys.
+type eabKeyGetter interface {
+	GetEABKey(ctx context.Context, req *sapb.EABKeyID, opts ...grpc.CallOption) (*sapb.EABKey, error)
+}
+
+// SAEABKeys is an EABKeySource backed by the SA's externalAccountKeys table,
+// so that keys can be provisioned without reconfiguring the RA.
+type SAEABKeys struct {
+	sa eabKeyGetter
+}
+
+// NewSAEABKeys returns an *SAEABKeys which looks up MAC keys using the
+// provided SA client.
+func NewSAEABKeys(sa eabKeyGetter) *SAEABKeys {
+	return &SAEABKeys{sa: sa}
+}
+
+// MACKey implements EABKeySource.
+func (s *SAEABKeys) MACKey(ctx context.Context, keyID string) ([]byte, error) {
+	key, err := s.sa.GetEABKey(ctx, &sapb.EABKeyID{KeyID: keyID})
+	if err != nil {
+		if errors.Is(err, berrors.NotFound) {
+			return nil, berrors.NotFoundError("unknown external account binding key %q", keyID)
+		}
+		return nil, fmt.Errorf("getting external account binding key %q from SA: %w", keyID, err)
+	}
+	if len(key.MacKey) == 0 {
+		return nil, errIncompleteGRPCResponse
+	}
+	return key.MacKey, nil
+}
+
+// eabAlgs are the MAC algorithms accepted for External Account Bindings.
+var eabAlgs = []jose.SignatureAlgorithm{jose.HS256, jose.HS384, jose.HS512}
+
+// EABVerifier verifies External Account Bindings, as specified in RFC 8555,
+// Section 7.3.4. All methods are safe to call on a nil *EABVerifier, which
+// accepts every registration without a binding.
+type EABVerifier struct {
+	keys EABKeySource
+	// oneAccountPerKeyID rejects new registrations whose binding uses a key
+	// identifier which has already been used to create an account.
+	oneAccountPerKeyID bool
+}
+
+// NewEABVerifier returns an *EABVerifier which looks up MAC keys in the
+// provided source. If oneAccountPerKeyID is true, each key identifier may only
+// be used to create a single account.
+func NewEABVerifier(keys EABKeySource, oneAccountPerKeyID bool) *EABVerifier {
+	return &EABVerifier{keys: keys, oneAccountPerKeyID: oneAccountPerKeyID}
+}
+
+// Verify checks that binding is a JWS over accountKey, MACed with the key
+// named by its "kid" header, and addressed to newAccountURL. It returns the
+// key identifier of the binding.
+func (v *EABVerifier) Verify(ctx context.Context, binding []byte, newAccountURL string, accountKey *jose.JSONWebKey) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	if len(binding) == 0 {
+		return "", berrors.ExternalAccountRequiredError("an external account binding is required to create an account")
+	}
+
+	jws, err := jose.ParseSigned(string(binding), eabAlgs)
+	if err != nil {
+		return "", berrors.MalformedError("failed to parse external account binding: %s", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return "", berrors.MalformedError("external account binding must have exactly one signature")
+	}
+	header := jws.Signatures[0].Protected
+	if header.KeyID == "" {
+		return "", berrors.MalformedError("external account binding has no \"kid\" header")
+	}
+	if header.Nonce != "" {
+		return "", berrors.MalformedError("external account binding must not have a \"nonce\" header")
+	}
+	url, ok := header.ExtraHeaders[jose.HeaderKey("url")].(string)
+	if !ok || newAccountURL == "" || url != newAccountURL {
+		return "", berrors.MalformedError("external account binding \"url\" header does not match the request URL")
+	}
+
+	macKey, err := v.keys.MACKey(ctx, header.KeyID)
+	if err != nil {
+		if errors.Is(err, berrors.NotFound) {
+			return "", berrors.UnauthorizedError("unknown external account binding key %q", header.KeyID)
+		}
+		return "", err
+	}
+	payload, err := jws.Verify(macKey)
+	if err != nil {
+		return "", berrors.UnauthorizedError("external account binding has an invalid signature")
+	}
+
+	var boundKey jose.JSONWebKey
+	err = boundKey.UnmarshalJSON(payload)
+	if err != nil {
+		return "", berrors.MalformedError("external account binding payload is not a JWK: %s", err)
+	}
+	boundThumbprint, err := boundKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", berrors.MalformedError("external account binding payload is not a valid JWK: %s", err)
+	}
+	accountThumbprint, err := accountKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", berrors.InternalServerError("failed to compute account key thumbprint: %s", err)
+	}
+	if !bytes.Equal(boundThumbprint, accountThumbprint) {
+		return "", berrors.UnauthorizedError("external account binding is for a different account key")
+	}
+	return header.KeyID, nil
+}
+
 // validateContacts checks the provided list of contacts, returning an error if
 // any are not acceptable. Unacceptable contacts lists include:
 // * An empty list
@@ -482,6 +816,195 @@ func (ra *RegistrationAuthorityImpl) validateContacts(contacts []string) error {
 	return nil
 }
 
+const (
+	// contactDomainCheckTimeout is the budget for checking all of the contact
+	// domains in a single request.
+	contactDomainCheckTimeout = 2 * time.Second
+
+	// contactDomainCacheTTL is how long the result of a successful contact
+	// domain check is cached.
+	contactDomainCacheTTL = 24 * time.Hour
+
+	// contactDomainCacheSize is the maximum number of contact domain check
+	// results which are cached.
+	contactDomainCacheSize = 100000
+)
+
+// contactDomainResult is the outcome of checking a single contact domain.
+type contactDomainResult string
+
+const (
+	// contactDomainVerified means that the domain has MX or A/AAAA records.
+	contactDomainVerified = contactDomainResult("verified")
+
+	// contactDomainNonexistent means that the domain does not exist, or that
+	// it has published a null MX record stating that it accepts no mail.
+	contactDomainNonexistent = contactDomainResult("nonexistent")
+
+	// contactDomainUnverified means that the domain could not be checked, for
+	// instance because a DNS query timed out.
+	contactDomainUnverified = contactDomainResult("unverified")
+)
+
+// contactDomainEntry is a cached contactDomainResult.
+type contactDomainEntry struct {
+	result  contactDomainResult
+	expires time.Time
+}
+
+// ContactDomainChecker checks that the domain of each contact email address
+// has MX or A/AAAA records, so that mail sent to it will not bounce. Domains
+// which do not exist are always rejected. Domains which cannot be checked
+// within the time budget are accepted, but audit logged as unverified, unless
+// the checker is in hard-reject mode. All methods are safe to call on a nil
+// *ContactDomainChecker, which accepts every contact.
+type ContactDomainChecker struct {
+	resolver   bdns.Client
+	hardReject bool
+	timeout    time.Duration
+	clk        clock.Clock
+	log        blog.Logger
+
+	// Note: This must be a regular mutex, not an RWMutex, because cache.Get()
+	// mutates the lru.Cache.
+	cacheMu  sync.Mutex
+	cache    *lru.Cache
+	cacheTTL time.Duration
+
+	checks *prometheus.CounterVec
+}
+
+// NewContactDomainChecker returns a *ContactDomainChecker which looks up
+// contact domains using the provided resolver. If hardReject is true, contacts
+// whose domains cannot be checked are rejected rather than accepted.
+func NewContactDomainChecker(resolver bdns.Client, hardReject bool, clk clock.Clock, logger blog.Logger, stats prometheus.Registerer) *ContactDomainChecker {
+	checks := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "contact_domain_checks",
+		Help: "A counter of contact email domain checks, labeled by result=[verified|nonexistent|unverified] and cached=[true|false]",
+	}, []string{"result", "cached"})
+	stats.MustRegister(checks)
+
+	return &ContactDomainChecker{
+		resolver:   resolver,
+		hardReject: hardReject,
+		timeout:    contactDomainCheckTimeout,
+		clk:        clk,
+		log:        logger,
+		cache:      lru.New(contactDomainCacheSize),
+		cacheTTL:   contactDomainCacheTTL,
+		checks:     checks,
+	}
+}
+
+// Check checks the domains of the provided mailto contacts, which must
+// already have passed validateContacts. The domains are checked in parallel
+// and all of the checks share a single time budget, so that a slow DNS server
+// cannot hold up the request.
+func (c *ContactDomainChecker) Check(ctx context.Context, contacts []string) error {
+	if c == nil {
+		return nil
+	}
+
+	var domains []string
+	for _, contact := range contacts {
+		_, domain, ok := strings.Cut(strings.TrimPrefix(contact, "mailto:"), "@")
+		if !ok {
+			continue
+		}
+		domain = strings.ToLower(domain)
+		if !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]contactDomainResult, len(domains))
+	errs := make([]error, len(domains))
+	var wg sync.WaitGroup
+	for i, domain := range domains {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = c.checkDomain(ctx, domain)
+		}()
+	}
+	wg.Wait()
+
+	for i, domain := range domains {
+		switch results[i] {
+		case contactDomainNonexistent:
+			return berrors.InvalidEmailError("contact email has a domain which cannot receive mail: %q", domain)
+		case contactDomainUnverified:
+			c.log.AuditInfof("Unable to verify contact email domain %q: %s", domain, errs[i])
+			if c.hardReject {
+				return berrors.InvalidEmailError("unable to verify that contact email domain %q can receive mail", domain)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDomain determines whether the provided domain can receive mail, using
+// a cached result if one is available. An error is returned, for logging,
+// along with contactDomainUnverified if the domain could not be checked.
+func (c *ContactDomainChecker) checkDomain(ctx context.Context, domain string) (contactDomainResult, error) {
+	c.cacheMu.Lock()
+	val, ok := c.cache.Get(domain)
+	c.cacheMu.Unlock()
+	if ok {
+		entry := val.(contactDomainEntry)
+		if c.clk.Now().Before(entry.expires) {
+			c.checks.WithLabelValues(string(entry.result), "true").Inc()
+			return entry.result, nil
+		}
+		c.cacheMu.Lock()
+		c.cache.Remove(domain)
+		c.cacheMu.Unlock()
+	}
+
+	result, err := c.lookupDomain(ctx, domain)
+	c.checks.WithLabelValues(string(result), "false").Inc()
+	if result != contactDomainUnverified {
+		// Only cache definitive results, so that transient DNS failures are
+		// retried on the next request.
+		c.cacheMu.Lock()
+		c.cache.Add(domain, contactDomainEntry{result: result, expires: c.clk.Now().Add(c.cacheTTL)})
+		c.cacheMu.Unlock()
+	}
+	return result, err
+}
+
+// lookupDomain queries the MX records for domain, falling back to its A and
+// AAAA records if it has none, as a sending mail server would (RFC 5321,
+// Section 5.1).
+func (c *ContactDomainChecker) lookupDomain(ctx context.Context, domain string) (contactDomainResult, error) {
+	mxs, _, err := c.resolver.LookupMX(ctx, domain)
+	if err != nil {
+		if bdns.IsNXDOMAIN(err) {
+			return contactDomainNonexistent, nil
+		}
+		return contactDomainUnverified, err
+	}
+	if len(mxs) == 1 && mxs[0] == "." {
+		// A null MX record states that the domain accepts no mail (RFC 7505).
+		return contactDomainNonexistent, nil
+	}
+	if len(mxs) > 0 {
+		return contactDomainVerified, nil
+	}
+
+	_, _, err = c.resolver.LookupHost(ctx, domain)
+	if err != nil {
+		return contactDomainUnverified, err
+	}
+	return contactDomainVerified, nil
+}
+
 // matchesCSR tests the contents of a generated certificate to make sure
 // that the PublicKey, CommonName, and DNSNames match those provided in
 // the CSR that was used to generate the certificate. It also checks the
@@ -553,6 +1076,29 @@ func (ra *RegistrationAuthorityImpl) matchesCSR(parsedCertificate *x509.Certific
 // satisfied the set of names or it returns an error. If it returns an error, it
 // will be of type BoulderError.
 func (ra *RegistrationAuthorityImpl) checkOrderAuthorizations(
+	ctx context.Context,
+	orderID orderID,
+	acctID accountID,
+	names []string,
+	now time.Time) (map[identifier.ACMEIdentifier]*core.Authorization, error) {
+	authzs, err := ra.getOrderAuthorizations(ctx, orderID, acctID, names, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check that the authzs either don't need CAA rechecking, or do the
+	// necessary CAA rechecks right now.
+	err = ra.checkAuthorizationsCAA(ctx, int64(acctID), authzs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return authzs, nil
+}
+
+// getOrderAuthorizations is like checkOrderAuthorizations, but does not check
+// whether the authorizations need their CAA records rechecked.
+func (ra *RegistrationAuthorityImpl) getOrderAuthorizations(
 	ctx context.Context,
 	orderID orderID,
 	acctID accountID,
@@ -627,13 +1173,6 @@ func (ra *RegistrationAuthorityImpl) checkOrderAuthorizations(
 		return nil, berrors.UnauthorizedError("incorrect number of names requested for finalization")
 	}
 
-	// Check that the authzs either don't need CAA rechecking, or do the
-	// necessary CAA rechecks right now.
-	err = ra.checkAuthorizationsCAA(ctx, int64(acctID), authzs, now)
-	if err != nil {
-		return nil, err
-	}
-
 	return authzs, nil
 }
 
@@ -650,6 +1189,26 @@ func validatedBefore(authz *core.Authorization, caaRecheckTime time.Time) (bool,
 	return authz.Challenges[0].Validated.Before(caaRecheckTime), nil
 }
 
+// caaRecheckAfter returns the time after which checkAuthorizationsCAA will
+// recheck CAA for at least one of the provided authorizations. It returns the
+// zero time if any of them isn't valid or has no validated timestamp.
+func caaRecheckAfter(authzs map[identifier.ACMEIdentifier]*core.Authorization) time.Time {
+	var earliest time.Time
+	for _, authz := range authzs {
+		if authz.Status != core.StatusValid || len(authz.Challenges) != 1 || authz.Challenges[0].Validated == nil {
+			return time.Time{}
+		}
+		validated := *authz.Challenges[0].Validated
+		if earliest.IsZero() || validated.Before(earliest) {
+			earliest = validated
+		}
+	}
+	if earliest.IsZero() {
+		return time.Time{}
+	}
+	return earliest.Add(-caaRecheckDuration)
+}
+
 // checkAuthorizationsCAA ensures that we have sufficiently-recent CAA checks
 // for every input identifier/authz. If any authz was validated too long ago, it
 // kicks off a CAA recheck for that identifier If it returns an error, it will
@@ -659,6 +1218,32 @@ func (ra *RegistrationAuthorityImpl) checkAuthorizationsCAA(
 	acctID int64,
 	authzs map[identifier.ACMEIdentifier]*core.Authorization,
 	now time.Time) error {
+	recheckAuthzs, err := staleCAAAuthorizations(authzs, now)
+	if err != nil {
+		return err
+	}
+
+	if len(recheckAuthzs) > 0 {
+		err := ra.recheckCAA(ctx, recheckAuthzs)
+		if err != nil {
+			return err
+		}
+	}
+
+	caaEvent := &finalizationCAACheckEvent{
+		Requester: acctID,
+		Reused:    len(authzs) - len(recheckAuthzs),
+		Rechecked: len(recheckAuthzs),
+	}
+	ra.log.InfoObject("FinalizationCaaCheck", caaEvent)
+
+	return nil
+}
+
+// staleCAAAuthorizations returns the authorizations whose CAA records must be
+// rechecked before issuance because they were validated too long before now.
+// If it returns an error, it will be of type BoulderError.
+func staleCAAAuthorizations(authzs map[identifier.ACMEIdentifier]*core.Authorization, now time.Time) ([]*core.Authorization, error) {
 	// recheckAuthzs is a list of authorizations that must have their CAA records rechecked
 	var recheckAuthzs []*core.Authorization
 
@@ -673,28 +1258,13 @@ func (ra *RegistrationAuthorityImpl) checkAuthorizationsCAA(
 
 	for _, authz := range authzs {
 		if staleCAA, err := validatedBefore(authz, caaRecheckAfter); err != nil {
-			return err
+			return nil, err
 		} else if staleCAA {
 			// Ensure that CAA is rechecked for this name
 			recheckAuthzs = append(recheckAuthzs, authz)
 		}
 	}
-
-	if len(recheckAuthzs) > 0 {
-		err := ra.recheckCAA(ctx, recheckAuthzs)
-		if err != nil {
-			return err
-		}
-	}
-
-	caaEvent := &finalizationCAACheckEvent{
-		Requester: acctID,
-		Reused:    len(authzs) - len(recheckAuthzs),
-		Rechecked: len(recheckAuthzs),
-	}
-	ra.log.InfoObject("FinalizationCaaCheck", caaEvent)
-
-	return nil
+	return recheckAuthzs, nil
 }
 
 // recheckCAA accepts a list of names that need to have their CAA records
@@ -739,12 +1309,16 @@ func (ra *RegistrationAuthorityImpl) recheckCAA(ctx context.Context, authzs []*c
 					Domain:           name,
 					ValidationMethod: method,
 					AccountURIID:     authz.RegistrationID,
+					PolicyHints:      ra.ValidationPolicyHints.hintsFor(authz.RegistrationID),
+					IsRecheck:        true,
 				})
 			} else {
 				resp, err = ra.VA.DoCAA(ctx, &vapb.IsCAAValidRequest{
 					Domain:           name,
 					ValidationMethod: method,
 					AccountURIID:     authz.RegistrationID,
+					PolicyHints:      ra.ValidationPolicyHints.hintsFor(authz.RegistrationID),
+					IsRecheck:        true,
 				})
 			}
 			if err != nil {
@@ -859,14 +1433,34 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 		RequestTime: ra.clk.Now(),
 		UserAgent:   web.UserAgent(ctx),
 	}
-	csr, err := ra.validateFinalizeRequest(ctx, req, &logEvent)
+	csr, authzs, err := ra.validateFinalizeRequest(ctx, req, &logEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	// CertificatesPerFQDNSet is only checked when the order is created, and is
+	// spent here, however long after that the order is finalized. It's
+	// refunded below if the certificate isn't issued. In either case, the
+	// order is left ready, so that it may be finalized again once the limits
+	// have capacity.
+	certNames := csrlib.NamesFromCSR(csr).SANs
+	err = ra.spendFQDNSetLimit(ctx, certNames)
+	if err != nil {
+		return nil, err
+	}
+
+	// The order is left ready, so that it may be finalized again once
+	// issuance across the deployment is no longer being throttled.
+	err = ra.spendGlobalIssuanceLimit(ctx)
 	if err != nil {
+		ra.refundFQDNSetLimit(ctx, certNames)
 		return nil, err
 	}
 
 	// Observe the age of this order, so we know how quickly most clients complete
 	// issuance flows.
 	ra.orderAges.WithLabelValues("FinalizeOrder").Observe(ra.clk.Since(req.Order.Created.AsTime()).Seconds())
+	ra.lifecycle.finalizing(req.Order, authzs)
 
 	// Step 2: Set the Order to Processing status
 	//
@@ -884,6 +1478,7 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 		// Fail the order with a server internal error - we weren't able to set the
 		// status to processing and that's unexpected & weird.
 		ra.failOrder(ctx, req.Order, probs.ServerInternal("Error setting order processing"))
+		ra.refundFQDNSetLimit(ctx, certNames)
 		return nil, err
 	}
 
@@ -923,12 +1518,332 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 	}
 }
 
+// PrecheckFinalize runs the checks FinalizeOrder would run before issuance:
+// parsing and verifying the CSR, matching its names to the order's valid
+// authorizations, evaluating whether their CAA checks are recent enough, and
+// checking the certificate rate limits. If any check fails it returns the same
+// error FinalizeOrder would. It never modifies the order, rechecks CAA, or
+// spends rate limits.
+func (ra *RegistrationAuthorityImpl) PrecheckFinalize(ctx context.Context, req *rapb.FinalizeOrderRequest) (*rapb.PrecheckFinalizeResponse, error) {
+	if req == nil || req.Order == nil || len(req.Csr) == 0 {
+		return nil, errIncompleteGRPCRequest
+	}
+
+	csr, err := parseFinalizeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if issuance.ContainsMustStaple(csr.Extensions) {
+		_, err = ra.checkMustStaple(req.Order)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	csrNames, err := ra.verifyFinalizeCSR(ctx, req, csr)
+	if err != nil {
+		return nil, err
+	}
+
+	now := ra.clk.Now()
+	authzs, err := ra.getOrderAuthorizations(
+		ctx, orderID(req.Order.Id), accountID(req.Order.RegistrationID), csrNames, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rather than rechecking CAA, report the names FinalizeOrder would recheck.
+	staleAuthzs, err := staleCAAAuthorizations(authzs, now)
+	if err != nil {
+		return nil, err
+	}
+	var caaRecheckNames []string
+	for _, authz := range staleAuthzs {
+		caaRecheckNames = append(caaRecheckNames, authz.Identifier.Value)
+	}
+	slices.Sort(caaRecheckNames)
+
+	err = ra.checkFinalizeLimits(ctx, req.Order.RegistrationID, csrNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rapb.PrecheckFinalizeResponse{
+		Status:          string(core.StatusReady),
+		CaaRecheckNames: caaRecheckNames,
+	}, nil
+}
+
+// CheckWillingness reports, for each requested identifier, whether the RA would
+// be willing to issue for it: whether it's a DNS identifier which policy
+// permits, including the rules for wildcards and the blocklists, and, if
+// requested, whether its CAA records permit issuance by the account for the
+// requested validation method. It creates nothing and spends no rate limits
+// other than the caller's own WillingnessChecksPerAccount limit, which is spent
+// once for each identifier before any are checked.
+func (ra *RegistrationAuthorityImpl) CheckWillingness(ctx context.Context, req *rapb.CheckWillingnessRequest) (*rapb.CheckWillingnessResponse, error) {
+	if req == nil || core.IsAnyNilOrZero(req.RegistrationID, req.Identifiers) {
+		return nil, errIncompleteGRPCRequest
+	}
+	if len(req.Identifiers) > ra.maxNames {
+		return nil, berrors.MalformedError(
+			"Willingness check cannot contain more than %d identifiers", ra.maxNames)
+	}
+	method := core.ChallengeTypeDNS01
+	if req.ValidationMethod != "" {
+		method = core.AcmeChallenge(req.ValidationMethod)
+		if !method.IsValid() {
+			return nil, berrors.MalformedError("unrecognized validation method %q", req.ValidationMethod)
+		}
+	}
+
+	err := ra.spendWillingnessLimit(ctx, req.RegistrationID, len(req.Identifiers))
+	if err != nil {
+		return nil, err
+	}
+
+	verdicts := make([]*rapb.IdentifierVerdict, len(req.Identifiers))
+	var wg sync.WaitGroup
+	for i, ident := range req.Identifiers {
+		verdicts[i] = &rapb.IdentifierVerdict{Identifier: ident}
+		prob := ra.checkPolicyWillingness(ident)
+		if prob != nil || !req.CheckCAA {
+			verdicts[i].Problem = prob
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			verdicts[i].Problem = ra.checkCAAWillingness(ctx, req.RegistrationID, ident.Value, method)
+		}()
+	}
+	wg.Wait()
+	return &rapb.CheckWillingnessResponse{Verdicts: verdicts}, nil
+}
+
+// checkPolicyWillingness returns the problem which would prevent issuance for
+// ident because of its type or the PA's policy, or nil if there is none.
+func (ra *RegistrationAuthorityImpl) checkPolicyWillingness(ident *corepb.Identifier) *corepb.ProblemDetails {
+	var prob *probs.ProblemDetails
+	if identifier.IdentifierType(ident.Type) != identifier.TypeDNS {
+		prob = probs.UnsupportedIdentifier("Identifier type %q is not supported", ident.Type)
+	} else {
+		names, err := policy.NormalizeDomainNames([]string{ident.Value})
+		if err == nil {
+			err = ra.PA.WillingToIssue(names)
+		}
+		if err != nil {
+			prob = web.ProblemDetailsForError(err, "Refusing to issue")
+		}
+	}
+	if prob == nil {
+		return nil
+	}
+	pbProb, err := bgrpc.ProblemDetailsToPB(prob)
+	if err != nil {
+		// This should never happen.
+		return &corepb.ProblemDetails{ProblemType: string(probs.ServerInternalProblem), Detail: "Error checking policy"}
+	}
+	return pbProb
+}
+
+// checkCAAWillingness returns the problem with the CAA records of name which
+// would prevent issuance to the account for the provided validation method, or
+// nil if there is none. The VA's CAA checks have no side effects.
+func (ra *RegistrationAuthorityImpl) checkCAAWillingness(ctx context.Context, regId int64, name string, method core.AcmeChallenge) *corepb.ProblemDetails {
+	caaReq := &vapb.IsCAAValidRequest{
+		Domain:           strings.ToLower(name),
+		ValidationMethod: string(method),
+		AccountURIID:     regId,
+		PolicyHints:      ra.ValidationPolicyHints.hintsFor(regId),
+	}
+	resp, err := callVA(ra, func(opts ...grpc.CallOption) (*vapb.IsCAAValidResponse, error) {
+		if !features.Get().EnforceMPIC {
+			return ra.VA.IsCAAValid(ctx, caaReq, opts...)
+		}
+		return ra.VA.DoCAA(ctx, caaReq, opts...)
+	})
+	if err != nil {
+		ra.log.Warningf("checking CAA for willingness to issue for %q: %s", name, err)
+		return &corepb.ProblemDetails{ProblemType: string(probs.ServerInternalProblem), Detail: "Error checking CAA"}
+	}
+	return resp.Problem
+}
+
+// spendWillingnessLimit spends against the WillingnessChecksPerAccount limit of
+// the account once for each identifier it's checking, and returns a rate limit
+// error if the account has checked too many. There is no reason to surface
+// other errors from this function to the caller, so they are logged and the
+// check is allowed to proceed.
+func (ra *RegistrationAuthorityImpl) spendWillingnessLimit(ctx context.Context, regId int64, identifiers int) error {
+	txn, err := ra.txnBuilder.WillingnessChecksPerAccountTransaction(regId, identifiers)
+	if err != nil {
+		ra.log.Warningf("building rate limit transaction for the %s rate limit: %s", ratelimits.WillingnessChecksPerAccount, err)
+		return nil
+	}
+	decision, err := ra.limiter.Spend(ctx, txn)
+	if err != nil {
+		ra.log.Warningf("spending against the %s rate limit: %s", ratelimits.WillingnessChecksPerAccount, err)
+		return nil
+	}
+	return decision.Result(ra.clk.Now())
+}
+
+// checkFinalizeLimits returns a rate limit error if issuing a certificate for
+// the provided names would exceed the limits spent by FinalizeOrder and
+// countCertificateIssued. Like them, it exempts a renewal of names which were
+// issued within the renewal window from the CertificatesPerDomain limit, and
+// returns an error if the SA can't tell whether this is a renewal. It doesn't
+// spend against any limit. There is no reason to surface errors from the
+// limiter to the Subscriber, so they are logged and the check is allowed to
+// pass.
+func (ra *RegistrationAuthorityImpl) checkFinalizeLimits(ctx context.Context, regId int64, names []string) error {
+	timestamps, err := ra.SA.FQDNSetTimestampsForWindow(ctx, &sapb.CountFQDNSetsRequest{
+		DnsNames: names,
+		Window:   durationpb.New(renewalWindow),
+		Limit:    1,
+	})
+	if err != nil {
+		return fmt.Errorf("checking if certificate is a renewal: %w", err)
+	}
+	isRenewal := len(timestamps.Timestamps) > 0
+
+	txns, err := ra.txnBuilder.FinalizeOrderLimitCheckOnlyTransactions(regId, names, isRenewal)
+	if err != nil {
+		ra.log.Warningf("building rate limit transactions at finalize precheck: %s", err)
+		return nil
+	}
+	decision, err := ra.limiter.BatchSpend(ctx, txns)
+	if err != nil {
+		ra.log.Warningf("checking rate limits at finalize precheck: %s", err)
+		return nil
+	}
+	return decision.Result(ra.clk.Now())
+}
+
+// spendFQDNSetLimit spends against the CertificatesPerFQDNSet limit of the
+// provided certificate names, and returns a rate limit error if too many
+// certificates have been issued for them. There is no reason to surface other
+// errors from this function to the Subscriber, so they are logged and the
+// finalization is allowed to proceed.
+func (ra *RegistrationAuthorityImpl) spendFQDNSetLimit(ctx context.Context, certNames []string) error {
+	txn, err := ra.txnBuilder.CertificatesPerFQDNSetTransaction(certNames)
+	if err != nil {
+		ra.log.Warningf("building rate limit transaction for the %s rate limit: %s", ratelimits.CertificatesPerFQDNSet, err)
+		return nil
+	}
+	decision, err := ra.limiter.Spend(ctx, txn)
+	if err != nil {
+		ra.log.Warningf("spending against the %s rate limit: %s", ratelimits.CertificatesPerFQDNSet, err)
+		return nil
+	}
+	return decision.Result(ra.clk.Now())
+}
+
+// refundFQDNSetLimit refunds the spend of spendFQDNSetLimit, when the
+// certificate wasn't issued. There is no reason to surface errors from this
+// function to the Subscriber, refunds are best effort.
+func (ra *RegistrationAuthorityImpl) refundFQDNSetLimit(ctx context.Context, certNames []string) {
+	txn, err := ra.txnBuilder.CertificatesPerFQDNSetTransaction(certNames)
+	if err != nil {
+		ra.log.Warningf("building rate limit transaction for the %s rate limit: %s", ratelimits.CertificatesPerFQDNSet, err)
+		return
+	}
+	_, err = ra.limiter.Refund(ctx, txn)
+	if err != nil {
+		ra.log.Warningf("refunding the %s rate limit: %s", ratelimits.CertificatesPerFQDNSet, err)
+	}
+}
+
+// spendGlobalIssuanceLimit spends against the GlobalIssuanceRate limit, and
+// returns a rate limit error if issuance across the whole deployment is being
+// throttled, that is, if every shard of the limit is exhausted. There is no reason to surface other errors from this function to
+// the Subscriber, so they are logged and the finalization is allowed to
+// proceed.
+func (ra *RegistrationAuthorityImpl) spendGlobalIssuanceLimit(ctx context.Context) error {
+	decision, err := ra.limiter.SpendGlobalIssuance(ctx, ra.txnBuilder)
+	if err != nil {
+		ra.log.Warningf("spending against the %s rate limit: %s", ratelimits.GlobalIssuanceRate, err)
+		return nil
+	}
+	return decision.Result(ra.clk.Now())
+}
+
 // validateFinalizeRequest checks that a FinalizeOrder request is fully correct
 // and ready for issuance.
 func (ra *RegistrationAuthorityImpl) validateFinalizeRequest(
 	ctx context.Context,
 	req *rapb.FinalizeOrderRequest,
-	logEvent *certificateRequestEvent) (*x509.CertificateRequest, error) {
+	logEvent *certificateRequestEvent) (*x509.CertificateRequest, map[identifier.ACMEIdentifier]*core.Authorization, error) {
+	csr, err := parseFinalizeRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if issuance.ContainsMustStaple(csr.Extensions) {
+		policy, err := ra.checkMustStaple(req.Order)
+		if policy == MustStapleAllow && ra.mustStapleAllowList != nil {
+			if err != nil {
+				ra.mustStapleRequestsCounter.WithLabelValues("denied").Inc()
+			} else {
+				ra.mustStapleRequestsCounter.WithLabelValues("allowed").Inc()
+			}
+		}
+		if err != nil {
+			ra.mustStapleOutcomes.WithLabelValues(string(policy), "rejected").Inc()
+			return nil, nil, err
+		}
+		if policy == MustStapleStrip {
+			ra.mustStapleOutcomes.WithLabelValues(string(policy), "stripped").Inc()
+			ra.log.Infof("Stripping OCSP must-staple extension requested by CSR for order %d under certificate profile %q",
+				req.Order.Id, req.Order.CertificateProfileName)
+			logEvent.MustStapleStripped = true
+		} else {
+			ra.mustStapleOutcomes.WithLabelValues(string(policy), "allowed").Inc()
+		}
+	}
+
+	csrNames, err := ra.verifyFinalizeCSR(ctx, req, csr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Double-check that all authorizations on this order are valid, are also
+	// associated with the same account as the order itself, and have recent CAA.
+	authzs, err := ra.checkOrderAuthorizations(
+		ctx, orderID(req.Order.Id), accountID(req.Order.RegistrationID), csrNames, ra.clk.Now())
+	if err != nil {
+		// Pass through the error without wrapping it because the called functions
+		// return BoulderError and we don't want to lose the type.
+		return nil, nil, err
+	}
+
+	// Collect up a certificateRequestAuthz that stores the ID and challenge type
+	// of each of the valid authorizations we used for this issuance.
+	logEventAuthzs := make(map[string]certificateRequestAuthz, len(csrNames))
+	for _, authz := range authzs {
+		// No need to check for error here because we know this same call just
+		// succeeded inside ra.checkOrderAuthorizations
+		solvedByChallengeType, _ := authz.SolvedBy()
+		logEventAuthzs[authz.Identifier.Value] = certificateRequestAuthz{
+			ID:            authz.ID,
+			ChallengeType: solvedByChallengeType,
+		}
+		authzAge := (ra.authorizationLifetime - authz.Expires.Sub(ra.clk.Now())).Seconds()
+		ra.authzAges.WithLabelValues("FinalizeOrder", string(authz.Status)).Observe(authzAge)
+	}
+	logEvent.Authorizations = logEventAuthzs
+
+	// Mark that we verified the CN and SANs
+	logEvent.VerifiedFields = []string{"subject.commonName", "subjectAltName"}
+
+	return csr, authzs, nil
+}
+
+// parseFinalizeRequest checks that the order in a FinalizeOrder request is
+// ready for finalization and parses the request's CSR.
+func parseFinalizeRequest(req *rapb.FinalizeOrderRequest) (*x509.CertificateRequest, error) {
 	if req.Order.Id <= 0 {
 		return nil, berrors.MalformedError("invalid order ID: %d", req.Order.Id)
 	}
@@ -956,19 +1871,100 @@ func (ra *RegistrationAuthorityImpl) validateFinalizeRequest(
 		return nil, berrors.BadCSRError("unable to parse CSR: %s", err.Error())
 	}
 
-	if ra.mustStapleAllowList != nil && issuance.ContainsMustStaple(csr.Extensions) {
-		if !ra.mustStapleAllowList.Contains(req.Order.RegistrationID) {
-			ra.mustStapleRequestsCounter.WithLabelValues("denied").Inc()
-			return nil, berrors.UnauthorizedError(
-				"OCSP must-staple extension is no longer available: see https://letsencrypt.org/2024/12/05/ending-ocsp",
-			)
-		} else {
-			ra.mustStapleRequestsCounter.WithLabelValues("allowed").Inc()
+	return csr, nil
+}
+
+// mustStapleUnavailableError is returned when a CSR requests the OCSP
+// must-staple extension on behalf of an account which isn't allowed to use it.
+func mustStapleUnavailableError() error {
+	return berrors.UnauthorizedError(
+		"OCSP must-staple extension is no longer available: see https://letsencrypt.org/2024/12/05/ending-ocsp",
+	)
+}
+
+// SetValidationProfiles replaces the RA's validation profiles, and the name of
+// the profile which the CA uses for orders which don't request one, such as
+// when its configuration is reloaded. Orders already created are unaffected.
+func (ra *RegistrationAuthorityImpl) SetValidationProfiles(validationProfiles map[string]*ValidationProfile, defaultProfileName string) {
+	ra.profilesMu.Lock()
+	defer ra.profilesMu.Unlock()
+	ra.validationProfiles = validationProfiles
+	ra.defaultProfileName = defaultProfileName
+}
+
+// getValidationProfiles returns the RA's current validation profiles. The map
+// returned must not be modified.
+func (ra *RegistrationAuthorityImpl) getValidationProfiles() map[string]*ValidationProfile {
+	ra.profilesMu.RLock()
+	defer ra.profilesMu.RUnlock()
+	return ra.validationProfiles
+}
+
+// GetIssuanceProfiles returns the name, validity period and identifier types
+// of each of the RA's validation profiles, in ascending order of name, so that
+// the WFE can advertise them without duplicating the RA's configuration. If
+// the RA has no validation profiles, and so permits any profile, none are
+// returned.
+func (ra *RegistrationAuthorityImpl) GetIssuanceProfiles(_ context.Context, _ *emptypb.Empty) (*rapb.GetIssuanceProfilesResponse, error) {
+	ra.profilesMu.RLock()
+	defer ra.profilesMu.RUnlock()
+
+	resp := &rapb.GetIssuanceProfilesResponse{}
+	for _, name := range slices.Sorted(maps.Keys(ra.validationProfiles)) {
+		vp := ra.validationProfiles[name]
+		profile := &rapb.IssuanceProfile{
+			Name:    name,
+			Default: name == ra.defaultProfileName,
+		}
+		if vp.validity != 0 {
+			profile.Validity = durationpb.New(vp.validity)
 		}
+		for _, identType := range vp.identifierTypes {
+			profile.IdentifierTypes = append(profile.IdentifierTypes, string(identType))
+		}
+		resp.Profiles = append(resp.Profiles, profile)
+	}
+	return resp, nil
+}
 
+// mustStaplePolicy returns the must-staple policy of the named validation
+// profile. Profiles without a configured policy allow must-staple.
+func (ra *RegistrationAuthorityImpl) mustStaplePolicy(profileName string) MustStaplePolicy {
+	vp, ok := ra.getValidationProfiles()[profileName]
+	if !ok {
+		return MustStapleAllow
 	}
+	return vp.mustStaplePolicy
+}
+
+// checkMustStaple applies the must-staple policy of the order's validation
+// profile to a CSR which requests the OCSP must-staple extension. It returns
+// the policy applied, and an error if the CSR must be rejected, either by that
+// policy or by the RA's must-staple allowlist.
+func (ra *RegistrationAuthorityImpl) checkMustStaple(order *corepb.Order) (MustStaplePolicy, error) {
+	policy := ra.mustStaplePolicy(order.CertificateProfileName)
+	switch policy {
+	case MustStapleReject:
+		return policy, berrors.BadCSRError(
+			"CSR requests the OCSP must-staple extension (TLS Feature, 1.3.6.1.5.5.7.1.24), which certificate profile %q does not permit",
+			order.CertificateProfileName)
+	case MustStapleStrip:
+		return policy, nil
+	}
+	if ra.mustStapleAllowList != nil && !ra.mustStapleAllowList.Contains(order.RegistrationID) {
+		return policy, mustStapleUnavailableError()
+	}
+	return policy, nil
+}
 
-	err = csrlib.VerifyCSR(ctx, csr, ra.maxNames, &ra.keyPolicy, ra.PA)
+// verifyFinalizeCSR checks the CSR from a FinalizeOrder request against policy,
+// the order's names, and the order's account. It returns the deduplicated,
+// lowercased and sorted names from the CSR.
+func (ra *RegistrationAuthorityImpl) verifyFinalizeCSR(
+	ctx context.Context,
+	req *rapb.FinalizeOrderRequest,
+	csr *x509.CertificateRequest) ([]string, error) {
+	err := csrlib.VerifyCSR(ctx, csr, ra.maxNames, &ra.keyPolicy, ra.PA)
 	if err != nil {
 		// VerifyCSR returns berror instances that can be passed through as-is
 		// without wrapping.
@@ -998,39 +1994,10 @@ func (ra *RegistrationAuthorityImpl) validateFinalizeRequest(
 
 	// Make sure they're not using their account key as the certificate key too.
 	if core.KeyDigestEquals(csr.PublicKey, account.Key) {
-		return nil, berrors.MalformedError("certificate public key must be different than account key")
-	}
-
-	// Double-check that all authorizations on this order are valid, are also
-	// associated with the same account as the order itself, and have recent CAA.
-	authzs, err := ra.checkOrderAuthorizations(
-		ctx, orderID(req.Order.Id), accountID(req.Order.RegistrationID), csrNames, ra.clk.Now())
-	if err != nil {
-		// Pass through the error without wrapping it because the called functions
-		// return BoulderError and we don't want to lose the type.
-		return nil, err
+		return nil, berrors.BadCSRError("certificate public key must be different than account key")
 	}
 
-	// Collect up a certificateRequestAuthz that stores the ID and challenge type
-	// of each of the valid authorizations we used for this issuance.
-	logEventAuthzs := make(map[string]certificateRequestAuthz, len(csrNames))
-	for _, authz := range authzs {
-		// No need to check for error here because we know this same call just
-		// succeeded inside ra.checkOrderAuthorizations
-		solvedByChallengeType, _ := authz.SolvedBy()
-		logEventAuthzs[authz.Identifier.Value] = certificateRequestAuthz{
-			ID:            authz.ID,
-			ChallengeType: solvedByChallengeType,
-		}
-		authzAge := (ra.authorizationLifetime - authz.Expires.Sub(ra.clk.Now())).Seconds()
-		ra.authzAges.WithLabelValues("FinalizeOrder", string(authz.Status)).Observe(authzAge)
-	}
-	logEvent.Authorizations = logEventAuthzs
-
-	// Mark that we verified the CN and SANs
-	logEvent.VerifiedFields = []string{"subject.commonName", "subjectAltName"}
-
-	return csr, nil
+	return csrNames, nil
 }
 
 // issueCertificateOuter exists solely to ensure that all calls to
@@ -1049,7 +2016,7 @@ func (ra *RegistrationAuthorityImpl) issueCertificateOuter(
 	isRenewal := false
 	timestamps, err := ra.SA.FQDNSetTimestampsForWindow(ctx, &sapb.CountFQDNSetsRequest{
 		DnsNames: order.DnsNames,
-		Window:   durationpb.New(120 * 24 * time.Hour),
+		Window:   durationpb.New(renewalWindow),
 		Limit:    1,
 	})
 	if err != nil {
@@ -1075,6 +2042,7 @@ func (ra *RegistrationAuthorityImpl) issueCertificateOuter(
 		// info.
 		ra.failOrder(ctx, order, web.ProblemDetailsForError(err, "Error finalizing order"))
 		order.Status = string(core.StatusInvalid)
+		ra.refundFQDNSetLimit(ctx, csrlib.NamesFromCSR(csr).SANs)
 
 		logEvent.Error = err.Error()
 		result = "error"
@@ -1099,6 +2067,7 @@ func (ra *RegistrationAuthorityImpl) issueCertificateOuter(
 		logEvent.NotAfter = cert.NotAfter
 		logEvent.CertProfileName = cpId.name
 		logEvent.CertProfileHash = hex.EncodeToString(cpId.hash)
+		ra.lifecycle.issued(order, logEvent.RequestTime)
 
 		result = "successful"
 	}
@@ -1110,24 +2079,18 @@ func (ra *RegistrationAuthorityImpl) issueCertificateOuter(
 }
 
 // countCertificateIssued increments the certificates (per domain and per
-// account) and duplicate certificate rate limits. There is no reason to surface
-// errors from this function to the Subscriber, spends against these limit are
-// best effort.
+// account) rate limits. The duplicate certificate rate limit was already spent
+// by FinalizeOrder. There is no reason to surface errors from this function to
+// the Subscriber, spends against these limit are best effort.
 func (ra *RegistrationAuthorityImpl) countCertificateIssued(ctx context.Context, regId int64, orderDomains []string, isRenewal bool) {
-	var transactions []ratelimits.Transaction
-	if !isRenewal {
-		txns, err := ra.txnBuilder.CertificatesPerDomainSpendOnlyTransactions(regId, orderDomains)
-		if err != nil {
-			ra.log.Warningf("building rate limit transactions at finalize: %s", err)
-		}
-		transactions = append(transactions, txns...)
+	if isRenewal {
+		return
 	}
-
-	txn, err := ra.txnBuilder.CertificatesPerFQDNSetSpendOnlyTransaction(orderDomains)
+	transactions, err := ra.txnBuilder.CertificatesPerDomainSpendOnlyTransactions(regId, orderDomains)
 	if err != nil {
-		ra.log.Warningf("building rate limit transaction at finalize: %s", err)
+		ra.log.Warningf("building rate limit transactions at finalize: %s", err)
+		return
 	}
-	transactions = append(transactions, txn)
 
 	_, err = ra.limiter.BatchSpend(ctx, transactions)
 	if err != nil {
@@ -1185,6 +2148,7 @@ func (ra *RegistrationAuthorityImpl) issueCertificateInner(
 		RegistrationID:  int64(acctID),
 		OrderID:         int64(oID),
 		CertProfileName: profileName,
+		OmitMustStaple:  issuance.ContainsMustStaple(csr.Extensions) && ra.mustStaplePolicy(profileName) == MustStapleStrip,
 	}
 	// Once we get a precert from IssuePrecertificate, we must attempt issuing
 	// a final certificate at most once. We achieve that by bailing on any error
@@ -1193,6 +2157,12 @@ func (ra *RegistrationAuthorityImpl) issueCertificateInner(
 	if err != nil {
 		return nil, nil, wrapError(err, "issuing precertificate")
 	}
+	if len(precert.LintFailures) > 0 {
+		err = ra.lintFailuresError(precert.LintFailures, csr, len(precert.DER) > 0)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
 	parsedPrecert, err := x509.ParseCertificate(precert.DER)
 	if err != nil {
@@ -1242,6 +2212,51 @@ func (ra *RegistrationAuthorityImpl) issueCertificateInner(
 	return parsedCertificate, &certProfileID{name: precert.CertProfileName, hash: precert.CertProfileHash}, nil
 }
 
+// lintFailuresError returns an error describing the provided lint failures,
+// reported by the CA when issuing a precertificate for csr, or nil if they
+// should not fail the order. Failures at least as severe as
+// ra.LintProblemSeverity result in a badCSR error with a suberror naming each
+// of them. ACME subproblems are specific to an identifier, but lints apply to
+// the certificate as a whole, so each suberror names the CSR's first SAN. If
+// every failure is less severe, but no precertificate was issued, an internal
+// error is returned.
+func (ra *RegistrationAuthorityImpl) lintFailuresError(failures []*capb.LintFailure, csr *x509.CertificateRequest, precertIssued bool) error {
+	ident := identifier.NewDNS(csrlib.NamesFromCSR(csr).SANs[0])
+	var subErrs []berrors.SubBoulderError
+	var failedLints []string
+	for _, f := range failures {
+		failedLints = append(failedLints, fmt.Sprintf("%s (%s: %s)", f.Name, f.Severity, f.Description))
+		status, ok := lint.StatusLabelToLintStatus[f.Severity]
+		if !ok {
+			// Treat severities we don't recognize as the most severe.
+			status = lint.Fatal
+		}
+		if status < ra.LintProblemSeverity {
+			continue
+		}
+		detail := fmt.Sprintf("Certificate failed lint %s (%s)", f.Name, f.Severity)
+		if f.Description != "" {
+			detail = fmt.Sprintf("%s: %s", detail, f.Description)
+		}
+		subErrs = append(subErrs, berrors.SubBoulderError{
+			Identifier:   ident,
+			BoulderError: &berrors.BoulderError{Type: berrors.BadCSR, Detail: detail},
+		})
+	}
+	ra.log.Warningf("CA reported lint failures: precertIssued=[%t] lints=[%s]", precertIssued, strings.Join(failedLints, ", "))
+
+	if len(subErrs) > 0 {
+		return (&berrors.BoulderError{
+			Type:   berrors.BadCSR,
+			Detail: fmt.Sprintf("Certificate request failed %d lint(s)", len(subErrs)),
+		}).WithSubErrors(subErrs)
+	}
+	if !precertIssued {
+		return berrors.InternalServerError("CA did not issue a precertificate: %d lint(s) failed", len(failures))
+	}
+	return nil
+}
+
 func (ra *RegistrationAuthorityImpl) getSCTs(ctx context.Context, cert []byte, expiration time.Time) (core.SCTDERs, error) {
 	started := ra.clk.Now()
 	scts, err := ra.ctpolicy.GetSCTs(ctx, cert, expiration)
@@ -1273,6 +2288,10 @@ func (ra *RegistrationAuthorityImpl) UpdateRegistrationContact(ctx context.Conte
 	if err != nil {
 		return nil, fmt.Errorf("invalid contact: %w", err)
 	}
+	err = ra.ContactDomains.Check(ctx, req.Contacts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contact: %w", err)
+	}
 
 	update, err := ra.SA.UpdateRegistrationContact(ctx, &sapb.UpdateRegistrationContactRequest{
 		RegistrationID: req.RegistrationID,
@@ -1292,19 +2311,139 @@ func (ra *RegistrationAuthorityImpl) UpdateRegistrationKey(ctx context.Context,
 	}
 
 	update, err := ra.SA.UpdateRegistrationKey(ctx, &sapb.UpdateRegistrationKeyRequest{
-		RegistrationID: req.RegistrationID,
-		Jwk:            req.Jwk,
+		RegistrationID: req.RegistrationID,
+		Jwk:            req.Jwk,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update registration key: %w", err)
+	}
+
+	return update, nil
+}
+
+// keyRolloverRequest is the payload of the inner JWS of an ACME key-change
+// request, per RFC 8555 Section 7.3.5.
+type keyRolloverRequest struct {
+	Account string          `json:"account"`
+	OldKey  jose.JSONWebKey `json:"oldKey"`
+}
+
+// KeyRollover changes an account's key to the key which signed the inner JWS
+// of an ACME key-change request. The inner JWS must verify with the key
+// embedded in its header, which must meet the key policy (and so must not be
+// blocked) and must not belong to any other account. Its payload must name the
+// account, and the account's current key as the old key. Each inner JWS may
+// only be used once, so that a rollover can't be replayed after the account's
+// key has been changed back. The SA enforces this, so that it holds across
+// every RA.
+//
+// The caller is responsible for verifying the outer JWS, which must be signed
+// by the account's current key, and that its URL matches the inner JWS's.
+func (ra *RegistrationAuthorityImpl) KeyRollover(ctx context.Context, req *rapb.KeyRolloverRequest) (*corepb.Registration, error) {
+	if core.IsAnyNilOrZero(req, req.RegistrationID, req.InnerJWS) {
+		return nil, errIncompleteGRPCRequest
+	}
+
+	innerJWS, err := jose.ParseSigned(string(req.InnerJWS), []jose.SignatureAlgorithm{jose.RS256, jose.ES256, jose.ES384, jose.ES512})
+	if err != nil {
+		return nil, berrors.MalformedError("failed to parse inner JWS: %s", err)
+	}
+	if len(innerJWS.Signatures) != 1 {
+		return nil, berrors.MalformedError("inner JWS must have exactly one signature")
+	}
+	newKey := innerJWS.Signatures[0].Header.JSONWebKey
+	if newKey == nil || !newKey.Valid() || !newKey.IsPublic() {
+		return nil, berrors.MalformedError("inner JWS must embed a valid public JWK")
+	}
+	err = ra.keyPolicy.GoodKey(ctx, newKey.Key)
+	if err != nil {
+		if errors.Is(err, goodkey.ErrBadKey) {
+			return nil, berrors.BadPublicKeyError("invalid new key: %s", err)
+		}
+		return nil, fmt.Errorf("checking new key: %w", err)
+	}
+	payload, err := innerJWS.Verify(newKey)
+	if err != nil {
+		return nil, berrors.MalformedError("inner JWS does not verify with embedded JWK")
+	}
+	var rollover keyRolloverRequest
+	err = json.Unmarshal(payload, &rollover)
+	if err != nil {
+		return nil, berrors.MalformedError("inner JWS payload did not parse as JSON key rollover object")
+	}
+	if !strings.HasSuffix(rollover.Account, "/"+strconv.FormatInt(req.RegistrationID, 10)) {
+		return nil, berrors.MalformedError("inner JWS specifies account %q, not account %d", rollover.Account, req.RegistrationID)
+	}
+	if rollover.OldKey.Key == nil {
+		return nil, berrors.MalformedError("inner JWS does not contain old key field matching current account key")
+	}
+
+	// The SA records the digest of the inner JWS's signature along with the
+	// new key, and refuses the change if it has already been recorded.
+	sigDigest := sha256.Sum256(innerJWS.Signatures[0].Signature)
+	return ra.rolloverKey(ctx, req.RegistrationID, rollover.OldKey, *newKey, sigDigest[:])
+}
+
+// rolloverKey changes the key of the account regID to newKey, provided that
+// its current key is oldKey, newKey doesn't belong to any other account, and
+// no key has been changed by a request with the same digest.
+func (ra *RegistrationAuthorityImpl) rolloverKey(ctx context.Context, regID int64, oldKey, newKey jose.JSONWebKey, digest []byte) (*corepb.Registration, error) {
+	reg, err := ra.SA.GetRegistration(ctx, &sapb.RegistrationID{Id: regID})
+	if err != nil {
+		return nil, fmt.Errorf("getting account: %w", err)
+	}
+	if core.AcmeStatus(reg.Status) != core.StatusValid {
+		return nil, berrors.UnauthorizedError("account %d is not valid, it is %s", regID, reg.Status)
+	}
+	var currentKey jose.JSONWebKey
+	err = currentKey.UnmarshalJSON(reg.Key)
+	if err != nil {
+		return nil, berrors.InternalServerError("failed to unmarshal account key: %s", err)
+	}
+
+	equal, err := core.PublicKeysEqual(oldKey.Key, currentKey.Key)
+	if err != nil || !equal {
+		return nil, berrors.MalformedError("inner JWS does not contain old key field matching current account key")
+	}
+	equal, err = core.PublicKeysEqual(newKey.Key, currentKey.Key)
+	if err != nil {
+		return nil, berrors.MalformedError("unable to compare new and old keys: %s", err)
+	}
+	if equal {
+		return nil, berrors.MalformedError("new key specified by rollover request is the same as the old key")
+	}
+
+	newKeyJSON, err := newKey.MarshalJSON()
+	if err != nil {
+		return nil, berrors.InternalServerError("failed to marshal new key: %s", err)
+	}
+	existing, err := ra.SA.GetRegistrationByKey(ctx, &sapb.JSONWebKey{Jwk: newKeyJSON})
+	if err == nil {
+		return nil, berrors.DuplicateError("new key is already in use for account %d", existing.Id)
+	}
+	if !errors.Is(err, berrors.NotFound) {
+		return nil, fmt.Errorf("looking up account by new key: %w", err)
+	}
+
+	update, err := ra.SA.UpdateRegistrationKey(ctx, &sapb.UpdateRegistrationKeyRequest{
+		RegistrationID:    regID,
+		Jwk:               newKeyJSON,
+		KeyRolloverDigest: digest,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update registration key: %w", err)
 	}
 
+	oldDigest, _ := core.KeyDigestB64(currentKey.Key)
+	newDigest, _ := core.KeyDigestB64(newKey.Key)
+	ra.log.AuditInfof("Account key rollover: regID=[%d] oldKeyDigest=[%s] newKeyDigest=[%s]", regID, oldDigest, newDigest)
 	return update, nil
 }
 
-// recordValidation records an authorization validation event,
-// it should only be used on v2 style authorizations.
-func (ra *RegistrationAuthorityImpl) recordValidation(ctx context.Context, authID string, authExpires *time.Time, challenge *core.Challenge) error {
+// recordValidation records an authorization validation event, along with the
+// outcome at each network perspective, if the VA reported them. It should only
+// be used on v2 style authorizations.
+func (ra *RegistrationAuthorityImpl) recordValidation(ctx context.Context, authID string, authExpires *time.Time, challenge *core.Challenge, perspectiveResults []*corepb.PerspectiveResult) error {
 	authzID, err := strconv.ParseInt(authID, 10, 64)
 	if err != nil {
 		return err
@@ -1324,17 +2463,92 @@ func (ra *RegistrationAuthorityImpl) recordValidation(ctx context.Context, authI
 		validated = timestamppb.New(*challenge.Validated)
 	}
 	_, err = ra.SA.FinalizeAuthorization2(ctx, &sapb.FinalizeAuthorizationRequest{
-		Id:                authzID,
-		Status:            string(challenge.Status),
-		Expires:           timestamppb.New(expires),
-		Attempted:         string(challenge.Type),
-		AttemptedAt:       validated,
-		ValidationRecords: vr.Records,
-		ValidationError:   vr.Problem,
+		Id:                 authzID,
+		Status:             string(challenge.Status),
+		Expires:            timestamppb.New(expires),
+		Attempted:          string(challenge.Type),
+		AttemptedAt:        validated,
+		ValidationRecords:  vr.Records,
+		ValidationError:    vr.Problem,
+		PerspectiveResults: perspectiveResults,
+	})
+	return err
+}
+
+// recordValidationAttempt adds attempt to the validation history of the
+// authorization. The history is kept whatever the outcome of finalizing the
+// authorization, so that the attempts of concurrent validations which lose
+// the race to finalize it aren't lost.
+func (ra *RegistrationAuthorityImpl) recordValidationAttempt(ctx context.Context, authID string, attempt core.ValidationAttempt) error {
+	authzID, err := strconv.ParseInt(authID, 10, 64)
+	if err != nil {
+		return err
+	}
+	_, err = ra.SA.AddValidationAttempt(ctx, &sapb.AddValidationAttemptRequest{
+		Id:      authzID,
+		Attempt: bgrpc.ValidationAttemptToPB(attempt),
 	})
 	return err
 }
 
+// countFailedAuthorization counts a failure of the given authorization, using
+// countFailedValidations, unless a failure of it has already been counted.
+// Each authorization fails at most once, but a client which retries a
+// challenge before its first failure is recorded can have several validations
+// of it fail concurrently, so the SA's failureCounted marker decides which of
+// them counts. If the marker can't be set, including because the SA predates
+// it, the failure is counted anyway.
+func (ra *RegistrationAuthorityImpl) countFailedAuthorization(ctx context.Context, authzID string, regId int64, ident identifier.ACMEIdentifier) error {
+	id, err := strconv.ParseInt(authzID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("parsing authorization ID %q: %w", authzID, err)
+	}
+	resp, err := ra.SA.SetAuthorizationFailureCounted(ctx, &sapb.SetAuthorizationFailureCountedRequest{Id: id, Counted: true})
+	if err != nil {
+		if status.Code(err) != codes.Unimplemented {
+			ra.log.Warningf("marking failure counted for authzID=[%d]: %s", id, err)
+		}
+	} else if !resp.Changed {
+		return nil
+	}
+	return ra.countFailedValidations(ctx, regId, ident)
+}
+
+// refundFailedAuthorization refunds the FailedAuthorizationsPerDomainPerAccount
+// spend for a failure of the given authorization which was counted before it
+// was administratively reset to pending, now that it has validated. As with
+// countFailedAuthorization, the SA's failureCounted marker ensures the spend is
+// refunded at most once. There is no reason to surface errors from this
+// function to the Subscriber.
+func (ra *RegistrationAuthorityImpl) refundFailedAuthorization(ctx context.Context, authzID string, regId int64, ident identifier.ACMEIdentifier) {
+	id, err := strconv.ParseInt(authzID, 10, 64)
+	if err != nil {
+		ra.log.Warningf("parsing authorization ID %q: %s", authzID, err)
+		return
+	}
+	resp, err := ra.SA.SetAuthorizationFailureCounted(ctx, &sapb.SetAuthorizationFailureCountedRequest{Id: id, Counted: false})
+	if err != nil {
+		// An SA which predates the marker never recorded that the failure
+		// was counted, so there's nothing known to refund.
+		if status.Code(err) != codes.Unimplemented {
+			ra.log.Warningf("clearing failure counted for authzID=[%d]: %s", id, err)
+		}
+		return
+	}
+	if !resp.Changed {
+		return
+	}
+	txn, err := ra.txnBuilder.FailedAuthorizationsPerDomainPerAccountSpendOnlyTransaction(regId, ident.Value)
+	if err != nil {
+		ra.log.Warningf("building rate limit transaction for the %s rate limit: %s", ratelimits.FailedAuthorizationsPerDomainPerAccount, err)
+		return
+	}
+	_, err = ra.limiter.Refund(ctx, txn)
+	if err != nil {
+		ra.log.Warningf("refunding the %s rate limit: %s", ratelimits.FailedAuthorizationsPerDomainPerAccount, err)
+	}
+}
+
 // countFailedValidations increments the FailedAuthorizationsPerDomainPerAccount limit.
 // and the FailedAuthorizationsForPausingPerDomainPerAccountTransaction limit.
 func (ra *RegistrationAuthorityImpl) countFailedValidations(ctx context.Context, regId int64, ident identifier.ACMEIdentifier) error {
@@ -1382,6 +2596,40 @@ func (ra *RegistrationAuthorityImpl) countFailedValidations(ctx context.Context,
 	return nil
 }
 
+// checkFailedValidationsLimit returns a rate limit error if the account has
+// failed to validate the identifier too many times recently. There is no reason
+// to surface other errors from this function to the Subscriber, so they are
+// logged and the validation is allowed to proceed.
+func (ra *RegistrationAuthorityImpl) checkFailedValidationsLimit(ctx context.Context, regId int64, ident identifier.ACMEIdentifier) error {
+	txn, err := ra.txnBuilder.FailedValidationsPerDomainPerAccountCheckOnlyTransaction(regId, ident.Value)
+	if err != nil {
+		ra.log.Warningf("building rate limit transaction for the %s rate limit: %s", ratelimits.FailedValidationsPerDomainPerAccount, err)
+		return nil
+	}
+	decision, err := ra.limiter.Check(ctx, txn)
+	if err != nil {
+		ra.log.Warningf("checking the %s rate limit: %s", ratelimits.FailedValidationsPerDomainPerAccount, err)
+		return nil
+	}
+	return decision.Result(ra.clk.Now())
+}
+
+// spendFailedValidationsLimit increments the FailedValidationsPerDomainPerAccount
+// limit. Unlike countFailedValidations it is called for every failed
+// validation, even repeated failures of the same authorization.
+func (ra *RegistrationAuthorityImpl) spendFailedValidationsLimit(ctx context.Context, regId int64, ident identifier.ACMEIdentifier) error {
+	txn, err := ra.txnBuilder.FailedValidationsPerDomainPerAccountSpendOnlyTransaction(regId, ident.Value)
+	if err != nil {
+		return fmt.Errorf("building rate limit transaction for the %s rate limit: %w", ratelimits.FailedValidationsPerDomainPerAccount, err)
+	}
+
+	_, err = ra.limiter.Spend(ctx, txn)
+	if err != nil {
+		return fmt.Errorf("spending against the %s rate limit: %w", ratelimits.FailedValidationsPerDomainPerAccount, err)
+	}
+	return nil
+}
+
 // resetAccountPausingLimit resets bucket to maximum capacity for given account.
 // There is no reason to surface errors from this function to the Subscriber.
 func (ra *RegistrationAuthorityImpl) resetAccountPausingLimit(ctx context.Context, regId int64, ident identifier.ACMEIdentifier) {
@@ -1397,30 +2645,36 @@ func (ra *RegistrationAuthorityImpl) resetAccountPausingLimit(ctx context.Contex
 
 // doDCVAndCAA performs DCV and CAA checks. When EnforceMPIC is enabled, the
 // checks are executed sequentially: DCV is performed first and CAA is only
-// checked if DCV is successful. Validation records from the DCV check are
-// returned even if the CAA check fails. When EnforceMPIC is disabled, DCV and
-// CAA checks are performed in the same request.
-func (ra *RegistrationAuthorityImpl) checkDCVAndCAA(ctx context.Context, dcvReq *vapb.PerformValidationRequest, caaReq *vapb.IsCAAValidRequest) (*corepb.ProblemDetails, []*corepb.ValidationRecord, error) {
+// checked if DCV is successful. The result of the DCV check is returned even if
+// the CAA check fails, with its problem replaced by that of the CAA check and
+// the per-perspective results of the CAA check appended to its own. When
+// EnforceMPIC is disabled, DCV and CAA checks are performed in the same
+// request.
+func (ra *RegistrationAuthorityImpl) checkDCVAndCAA(ctx context.Context, dcvReq *vapb.PerformValidationRequest, caaReq *vapb.IsCAAValidRequest) (*vapb.ValidationResult, error) {
 	if !features.Get().EnforceMPIC {
-		performValidationRes, err := ra.VA.PerformValidation(ctx, dcvReq)
-		if err != nil {
-			return nil, nil, err
-		}
-		return performValidationRes.Problem, performValidationRes.Records, nil
+		return callVA(ra, func(opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+			return ra.VA.PerformValidation(ctx, dcvReq, opts...)
+		})
 	} else {
-		doDCVRes, err := ra.VA.DoDCV(ctx, dcvReq)
+		doDCVRes, err := callVA(ra, func(opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+			return ra.VA.DoDCV(ctx, dcvReq, opts...)
+		})
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		if doDCVRes.Problem != nil {
-			return doDCVRes.Problem, doDCVRes.Records, nil
+			return doDCVRes, nil
 		}
 
-		doCAAResp, err := ra.VA.DoCAA(ctx, caaReq)
+		doCAAResp, err := callVA(ra, func(opts ...grpc.CallOption) (*vapb.IsCAAValidResponse, error) {
+			return ra.VA.DoCAA(ctx, caaReq, opts...)
+		})
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		return doCAAResp.Problem, doDCVRes.Records, nil
+		doDCVRes.Problem = doCAAResp.Problem
+		doDCVRes.PerspectiveResults = append(doDCVRes.PerspectiveResults, doCAAResp.PerspectiveResults...)
+		return doDCVRes, nil
 	}
 }
 
@@ -1494,50 +2748,87 @@ func (ra *RegistrationAuthorityImpl) PerformValidation(
 		return nil, berrors.MalformedError("cannot validate challenge: %s", cErr.Error())
 	}
 
-	// Dispatch to the VA for service
-	ra.drainWG.Add(1)
+	// Tell the VA which challenge types policy permits for this identifier, so
+	// that it refuses to validate any other, e.g. HTTP-01 for a wildcard name.
+	challTypes, err := ra.PA.ChallengeTypesFor(authz.Identifier)
+	if err != nil {
+		return nil, berrors.InternalServerError("determining allowed challenge types: %s", err)
+	}
+	allowedChallTypes := make([]string, len(challTypes))
+	for i, t := range challTypes {
+		allowedChallTypes[i] = string(t)
+	}
+
+	err = ra.checkFailedValidationsLimit(ctx, authz.RegistrationID, authz.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Schedule the validation, which a worker will dispatch to the VA for
+	// service. The validation will mutate the challenges to change status and
+	// add error, but we also return authz immediately. To avoid a data race,
+	// give the validation its own copy of the challenges slice for mutation.
+	vaAuthz := authz
+	vaAuthz.Challenges = slices.Clone(authz.Challenges)
+
+	// We track the validation's lifetime in a waitgroup global to this RA, so
+	// that it can wait for all validations to complete during shutdown.
 	vaCtx := context.Background()
-	go func(authz core.Authorization) {
+	ra.drainWG.Add(1)
+	queued := ra.validations.enqueue(func() {
 		defer ra.drainWG.Done()
-
-		// We will mutate challenges later in this goroutine to change status and
-		// add error, but we also return a copy of authz immediately. To avoid a
-		// data race, make a copy of the challenges slice here for mutation.
-		challenges := make([]core.Challenge, len(authz.Challenges))
-		copy(challenges, authz.Challenges)
-		authz.Challenges = challenges
+		authz := vaAuthz
 		chall, _ := bgrpc.ChallengeToPB(authz.Challenges[challIndex])
-		checkProb, checkRecords, err := ra.checkDCVAndCAA(
+		hints := ra.ValidationPolicyHints.hintsFor(authz.RegistrationID)
+		checkRes, err := ra.checkDCVAndCAA(
 			vaCtx,
 			&vapb.PerformValidationRequest{
 				DnsName:                  authz.Identifier.Value,
+				Identifier:               authz.Identifier.AsProto(),
 				Challenge:                chall,
 				Authz:                    &vapb.AuthzMeta{Id: authz.ID, RegID: authz.RegistrationID},
 				ExpectedKeyAuthorization: expectedKeyAuthorization,
+				PolicyHints:              hints,
+				AllowedChallengeTypes:    allowedChallTypes,
 			},
 			&vapb.IsCAAValidRequest{
 				Domain:           authz.Identifier.Value,
 				ValidationMethod: chall.Type,
 				AccountURIID:     authz.RegistrationID,
 				AuthzID:          authz.ID,
+				PolicyHints:      hints,
 			},
 		)
+		if errors.Is(err, errVAOverloaded) {
+			// The VA never attempted the validation, so leave the challenge
+			// pending for the client to retry, rather than failing it.
+			ra.log.Warningf("Leaving challenge pending after VA overload: regID=[%d] authzID=[%s] err=[%s]",
+				authz.RegistrationID, authz.ID, err)
+			return
+		}
 		challenge := &authz.Challenges[challIndex]
 		var prob *probs.ProblemDetails
 		if err != nil {
 			prob = probs.ServerInternal("Could not communicate with VA")
 			ra.log.AuditErrf("Could not communicate with VA: %s", err)
 		} else {
-			if checkProb != nil {
-				prob, err = bgrpc.PBToProblemDetails(checkProb)
+			if checkRes.Problem != nil {
+				prob, err = bgrpc.PBToProblemDetails(checkRes.Problem)
 				if err != nil {
 					prob = probs.ServerInternal("Could not communicate with VA")
 					ra.log.AuditErrf("Could not communicate with VA: %s", err)
+				} else if checkRes.StatusCode != 0 && prob.Type != probs.CAAProblem {
+					// Describe the HTTP-01 response which failed validation,
+					// unless the problem came from the CAA check instead.
+					prob.HTTPDetails = &probs.HTTPDetails{
+						FinalURL:   checkRes.FinalURL,
+						StatusCode: int(checkRes.StatusCode),
+					}
 				}
 			}
 			// Save the updated records
-			records := make([]core.ValidationRecord, len(checkRecords))
-			for i, r := range checkRecords {
+			records := make([]core.ValidationRecord, len(checkRes.Records))
+			for i, r := range checkRes.Records {
 				records[i], err = bgrpc.PBToValidationRecord(r)
 				if err != nil {
 					prob = probs.ServerInternal("Records for validation corrupt")
@@ -1552,20 +2843,48 @@ func (ra *RegistrationAuthorityImpl) PerformValidation(
 		if prob != nil {
 			challenge.Status = core.StatusInvalid
 			challenge.Error = prob
-			err := ra.countFailedValidations(vaCtx, authz.RegistrationID, authz.Identifier)
+			err := ra.countFailedAuthorization(vaCtx, authz.ID, authz.RegistrationID, authz.Identifier)
 			if err != nil {
 				ra.log.Warningf("incrementing failed validations: %s", err)
 			}
+			err = ra.spendFailedValidationsLimit(vaCtx, authz.RegistrationID, authz.Identifier)
+			if err != nil {
+				ra.log.Warningf("spending failed validation attempt: %s", err)
+			}
 		} else {
 			challenge.Status = core.StatusValid
+			if authz.FailureCounted {
+				ra.refundFailedAuthorization(vaCtx, authz.ID, authz.RegistrationID, authz.Identifier)
+			}
 			if features.Get().AutomaticallyPauseZombieClients {
 				ra.resetAccountPausingLimit(vaCtx, authz.RegistrationID, authz.Identifier)
 			}
 		}
 		challenge.Validated = &vStart
+
+		// Add this attempt to the authorization's history. The VA reports when
+		// the attempt was made and which perspectives took part, but the
+		// problem is ours, since it may have come from the CAA check or from
+		// processing the VA's response.
+		var perspectives string
+		attemptedAt := vStart
+		if checkRes.GetAttempt() != nil {
+			vaAttempt := bgrpc.PBToValidationAttempt(checkRes.Attempt)
+			perspectives = vaAttempt.Perspectives
+			if !vaAttempt.AttemptedAt.IsZero() {
+				attemptedAt = vaAttempt.AttemptedAt
+			}
+		}
+		err = ra.recordValidationAttempt(vaCtx, authz.ID, core.NewValidationAttempt(attemptedAt, prob, perspectives))
+		// An SA which doesn't store validation attempts has nowhere to record
+		// them.
+		if err != nil && status.Code(err) != codes.Unimplemented {
+			ra.log.Warningf("Failed to record validation attempt: regID=[%d] authzID=[%s] err=[%s]",
+				authz.RegistrationID, authz.ID, err)
+		}
 		authz.Challenges[challIndex] = *challenge
 
-		err = ra.recordValidation(vaCtx, authz.ID, authz.Expires, challenge)
+		err = ra.recordValidation(vaCtx, authz.ID, authz.Expires, challenge, checkRes.GetPerspectiveResults())
 		if err != nil {
 			if errors.Is(err, berrors.NotFound) {
 				// We log NotFound at a lower level because this is largely due to a
@@ -1579,7 +2898,17 @@ func (ra *RegistrationAuthorityImpl) PerformValidation(
 					authz.RegistrationID, authz.ID, err)
 			}
 		}
-	}(authz)
+	})
+	if !queued {
+		ra.drainWG.Done()
+		// The RA is overloaded or shutting down, neither of which is the
+		// Subscriber's doing.
+		return nil, errValidationNotScheduled
+	}
+
+	// The challenge is processing until the validation completes, after which
+	// the authorization reflects its outcome.
+	authz.Challenges[challIndex].Status = core.StatusProcessing
 	return bgrpc.AuthzToPB(authz)
 }
 
@@ -1912,12 +3241,95 @@ func (ra *RegistrationAuthorityImpl) RevokeCertByKey(ctx context.Context, req *r
 	}
 }
 
+// AdminPolicy holds the capabilities of an administrator permitted to call
+// AdministrativelyRevokeCertificate.
+type AdminPolicy struct {
+	// MaySkipBlockKey permits revoking for keyCompromise without blocking the
+	// certificate's key.
+	MaySkipBlockKey bool
+
+	// MayRevokeKeyCompromise permits revoking with reason keyCompromise.
+	MayRevokeKeyCompromise bool
+
+	// MaxRevocationsPerHour is the number of revocations the admin may request
+	// in any hour. Zero means there is no cap. The cap is enforced by each RA
+	// instance independently.
+	MaxRevocationsPerHour int
+}
+
+// authorizeAdminRevocation checks that the admin named in req is allowlisted
+// and holds the capabilities the request requires, then counts the request
+// against their hourly cap. Each check performed is recorded in logEvent. The
+// errors returned name neither the admin nor their capabilities. The returned
+// refund func gives back the cap consumed by this request, and must be called
+// if the revocation does not happen.
+func (ra *RegistrationAuthorityImpl) authorizeAdminRevocation(req *rapb.AdministrativelyRevokeCertificateRequest, logEvent *certificateRevocationEvent) (func(), error) {
+	check := func(format string, args ...any) {
+		logEvent.AdminChecks = append(logEvent.AdminChecks, fmt.Sprintf(format, args...))
+	}
+
+	if ra.admins == nil {
+		check("no admin allowlist configured")
+		return func() {}, nil
+	}
+	policy, ok := ra.admins[req.AdminName]
+	if !ok {
+		check("admin not allowlisted")
+		return nil, berrors.UnauthorizedError("admin is not permitted to revoke certificates")
+	}
+	check("admin allowlisted")
+
+	if req.Code == ocsp.KeyCompromise {
+		if !policy.MayRevokeKeyCompromise {
+			check("keyCompromise not permitted")
+			return nil, berrors.UnauthorizedError("admin is not permitted to revoke for keyCompromise")
+		}
+		check("keyCompromise permitted")
+	}
+	if req.SkipBlockKey {
+		if !policy.MaySkipBlockKey {
+			check("skipBlockKey not permitted")
+			return nil, berrors.UnauthorizedError("admin is not permitted to skip key blocking")
+		}
+		check("skipBlockKey permitted")
+	}
+
+	if policy.MaxRevocationsPerHour == 0 {
+		check("no hourly cap")
+		return func() {}, nil
+	}
+	ra.adminRevocationsMu.Lock()
+	defer ra.adminRevocationsMu.Unlock()
+	now := ra.clk.Now()
+	recent := slices.DeleteFunc(ra.adminRevocations[req.AdminName], func(t time.Time) bool {
+		return !t.After(now.Add(-time.Hour))
+	})
+	if len(recent) >= policy.MaxRevocationsPerHour {
+		ra.adminRevocations[req.AdminName] = recent
+		check("hourly cap of %d exceeded", policy.MaxRevocationsPerHour)
+		return nil, berrors.RateLimitError(recent[0].Add(time.Hour).Sub(now), "admin has exceeded their hourly revocation cap")
+	}
+	ra.adminRevocations[req.AdminName] = append(recent, now)
+	check("%d of hourly cap of %d", len(recent)+1, policy.MaxRevocationsPerHour)
+	return func() {
+		ra.adminRevocationsMu.Lock()
+		defer ra.adminRevocationsMu.Unlock()
+		times := ra.adminRevocations[req.AdminName]
+		i := slices.Index(times, now)
+		if i != -1 {
+			ra.adminRevocations[req.AdminName] = slices.Delete(times, i, i+1)
+		}
+		check("hourly cap refunded")
+	}, nil
+}
+
 // AdministrativelyRevokeCertificate terminates trust in the certificate
 // provided and does not require the registration ID of the requester since this
-// method is only called from the `admin` tool. It trusts that the admin
-// is doing the right thing, so if the requested reason is keyCompromise, it
-// blocks the key from future issuance even though compromise has not been
-// demonstrated here. It purges the certificate from the Akamai cache, and
+// method is only called from the `admin` tool. The admin must be permitted by
+// the configured allowlist, if any. It trusts that a permitted admin is doing
+// the right thing, so if the requested reason is keyCompromise, it blocks the
+// key from future issuance even though compromise has not been demonstrated
+// here. It purges the certificate from the Akamai cache, and
 // returns an error if that purge fails, since this method may be called late
 // in the BRs-mandated revocation timeframe.
 func (ra *RegistrationAuthorityImpl) AdministrativelyRevokeCertificate(ctx context.Context, req *rapb.AdministrativelyRevokeCertificateRequest) (*emptypb.Empty, error) {
@@ -1962,6 +3374,24 @@ func (ra *RegistrationAuthorityImpl) AdministrativelyRevokeCertificate(ctx conte
 		ra.log.AuditObject("Revocation request:", logEvent)
 	}()
 
+	var refund func()
+	refund, err = ra.authorizeAdminRevocation(req, &logEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ra.administrativelyRevokeSerial(ctx, req)
+	if err != nil {
+		refund()
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// administrativelyRevokeSerial performs the revocation requested by an
+// already validated and authorized AdministrativelyRevokeCertificateRequest.
+func (ra *RegistrationAuthorityImpl) administrativelyRevokeSerial(ctx context.Context, req *rapb.AdministrativelyRevokeCertificateRequest) error {
+	var err error
 	var cert *x509.Certificate
 	var issuerID issuance.NameID
 	var shard int64
@@ -1971,12 +3401,12 @@ func (ra *RegistrationAuthorityImpl) AdministrativelyRevokeCertificate(ctx conte
 		// be removed when req.Cert is removed.
 		cert, err = x509.ParseCertificate(req.Cert)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		issuerID = issuance.IssuerNameID(cert)
 		shard, err = crlShard(cert)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	} else if !req.Malformed {
 		// As long as we don't believe the cert will be malformed, we should
@@ -1985,26 +3415,26 @@ func (ra *RegistrationAuthorityImpl) AdministrativelyRevokeCertificate(ctx conte
 		var certPB *corepb.Certificate
 		certPB, err = ra.SA.GetLintPrecertificate(ctx, &sapb.Serial{Serial: req.Serial})
 		if err != nil {
-			return nil, err
+			return err
 		}
 		// Note that, although the thing we're parsing here is actually a linting
 		// precertificate, it has identical issuer info (and therefore an identical
 		// issuer NameID) to the real thing.
 		cert, err = x509.ParseCertificate(certPB.Der)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		issuerID = issuance.IssuerNameID(cert)
 		shard, err = crlShard(cert)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	} else {
 		// But if the cert is malformed, we at least still need its IssuerID.
 		var status *corepb.CertificateStatus
 		status, err = ra.SA.GetCertificateStatus(ctx, &sapb.Serial{Serial: req.Serial})
 		if err != nil {
-			return nil, fmt.Errorf("unable to confirm that serial %q was ever issued: %w", req.Serial, err)
+			return fmt.Errorf("unable to confirm that serial %q was ever issued: %w", req.Serial, err)
 		}
 		issuerID = issuance.NameID(status.IssuerID)
 		shard = req.CrlShard
@@ -2023,8 +3453,7 @@ func (ra *RegistrationAuthorityImpl) AdministrativelyRevokeCertificate(ctx conte
 		if cert != nil {
 			err = ra.purgeOCSPCache(ctx, cert, issuerID)
 			if err != nil {
-				err = fmt.Errorf("OCSP cache purge for already revoked serial %v failed: %w", req.Serial, err)
-				return nil, err
+				return fmt.Errorf("OCSP cache purge for already revoked serial %v failed: %w", req.Serial, err)
 			}
 		}
 	}
@@ -2032,31 +3461,161 @@ func (ra *RegistrationAuthorityImpl) AdministrativelyRevokeCertificate(ctx conte
 		if req.Code == ocsp.KeyCompromise && errors.Is(err, berrors.AlreadyRevoked) {
 			err = ra.updateRevocationForKeyCompromise(ctx, req.Serial, issuerID)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
-		return nil, err
+		return err
 	}
 
 	if req.Code == ocsp.KeyCompromise && !req.SkipBlockKey {
 		if cert == nil {
-			return nil, errors.New("revoking for key compromise requires providing the certificate's DER")
+			return errors.New("revoking for key compromise requires providing the certificate's DER")
 		}
 		err = ra.addToBlockedKeys(ctx, cert.PublicKey, "admin-revoker", fmt.Sprintf("revoked by %s", req.AdminName))
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	if cert != nil {
 		err = ra.purgeOCSPCache(ctx, cert, issuerID)
 		if err != nil {
-			err = fmt.Errorf("OCSP cache purge for serial %v failed: %w", req.Serial, err)
-			return nil, err
+			return fmt.Errorf("OCSP cache purge for serial %v failed: %w", req.Serial, err)
 		}
 	}
 
-	return &emptypb.Empty{}, nil
+	return nil
+}
+
+// revokeByKeyHashBatchSize is the number of certificates which
+// RevokeCertificatesByKeyHash revokes concurrently before reporting their
+// results and moving on to the next batch.
+const revokeByKeyHashBatchSize = 20
+
+// RevokeCertificatesByKeyHash revokes every unexpired certificate with the
+// provided SPKI hash, in batches of revokeByKeyHashBatchSize, streaming the
+// result for each serial back to the caller as each batch completes. A
+// failure to revoke one serial is reported in its result and does not stop
+// the others from being revoked. If the reason is keyCompromise the key is
+// blocked once all of the certificates have been attempted, provided at least
+// one of them was revoked. The whole request counts as a single revocation
+// against the admin's hourly cap, unless nothing was revoked. If the dryRun
+// flag is set the serials are returned without revoking anything.
+func (ra *RegistrationAuthorityImpl) RevokeCertificatesByKeyHash(req *rapb.RevokeCertificatesByKeyHashRequest, stream grpc.ServerStreamingServer[rapb.RevokeCertificatesByKeyHashResponse]) error {
+	if req == nil || len(req.KeyHash) == 0 || req.AdminName == "" {
+		return errIncompleteGRPCRequest
+	}
+	reasonCode := revocation.Reason(req.Code)
+	if _, present := revocation.AdminAllowedReasons[reasonCode]; !present {
+		return fmt.Errorf("cannot revoke for reason %d", reasonCode)
+	}
+	ctx := stream.Context()
+
+	logEvent := certificateRevocationEvent{
+		ID:        core.NewToken(),
+		Reason:    req.Code,
+		Method:    "admin-key-hash",
+		AdminName: req.AdminName,
+	}
+	refund, err := ra.authorizeAdminRevocation(&rapb.AdministrativelyRevokeCertificateRequest{
+		Code:      req.Code,
+		AdminName: req.AdminName,
+	}, &logEvent)
+	if err != nil {
+		logEvent.Error = err.Error()
+		ra.log.AuditObject("Revocation request:", logEvent)
+		return err
+	}
+
+	serialStream, err := ra.SA.GetSerialsByKey(ctx, &sapb.SPKIHash{KeyHash: req.KeyHash})
+	if err != nil {
+		refund()
+		return fmt.Errorf("getting serials for key hash: %w", err)
+	}
+	var serials []string
+	for {
+		serial, err := serialStream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			refund()
+			return fmt.Errorf("getting serials for key hash: %w", err)
+		}
+		serials = append(serials, serial.Serial)
+	}
+	ra.log.Infof("Admin %s requested revocation of %d certificates with key hash %x (dryRun=%t)",
+		req.AdminName, len(serials), req.KeyHash, req.DryRun)
+
+	if req.DryRun {
+		// Nothing is revoked, so the request doesn't count against the cap.
+		refund()
+		for _, serial := range serials {
+			err = stream.Send(&rapb.RevokeCertificatesByKeyHashResponse{Serial: serial})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// A failure to send a result doesn't stop the remaining certificates from
+	// being attempted, or the key from being blocked.
+	var sendErr error
+	var revoked int
+	for batch := range slices.Chunk(serials, revokeByKeyHashBatchSize) {
+		results := make([]*rapb.RevokeCertificatesByKeyHashResponse, len(batch))
+		var wg sync.WaitGroup
+		for i, serial := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serialEvent := logEvent
+				serialEvent.ID = core.NewToken()
+				serialEvent.SerialNumber = serial
+				err := ra.administrativelyRevokeSerial(ctx, &rapb.AdministrativelyRevokeCertificateRequest{
+					Serial:       serial,
+					Code:         req.Code,
+					AdminName:    req.AdminName,
+					SkipBlockKey: true,
+				})
+				results[i] = &rapb.RevokeCertificatesByKeyHashResponse{Serial: serial}
+				if err != nil {
+					serialEvent.Error = err.Error()
+					results[i].Error = err.Error()
+				}
+				ra.log.AuditObject("Revocation request:", serialEvent)
+			}()
+		}
+		wg.Wait()
+
+		for _, result := range results {
+			if result.Error == "" {
+				revoked++
+			}
+			if sendErr == nil {
+				sendErr = stream.Send(result)
+			}
+		}
+	}
+	if revoked == 0 {
+		// Without a revoked certificate there's no basis for blocking the key.
+		refund()
+		return sendErr
+	}
+
+	if reasonCode == ocsp.KeyCompromise {
+		_, err = ra.SA.AddBlockedKey(ctx, &sapb.AddBlockedKeyRequest{
+			KeyHash: req.KeyHash,
+			Added:   timestamppb.New(ra.clk.Now()),
+			Source:  "admin-revoker",
+			Comment: fmt.Sprintf("revoked by %s", req.AdminName),
+		})
+		if err != nil {
+			return fmt.Errorf("blocking key hash: %w", err)
+		}
+	}
+	return sendErr
 }
 
 // DeactivateRegistration deactivates a valid registration
@@ -2091,13 +3650,14 @@ func (ra *RegistrationAuthorityImpl) DeactivateAuthorization(ctx context.Context
 	if _, err := ra.SA.DeactivateAuthorization2(ctx, &sapb.AuthorizationID2{Id: authzID}); err != nil {
 		return nil, err
 	}
+	ra.invalidateOrdersForAuthorization(ctx, authzID, identifier.NewDNS(req.DnsName))
 	if req.Status == string(core.StatusPending) {
 		// Some clients deactivate pending authorizations without attempting them.
 		// We're not sure exactly when this happens but it's most likely due to
 		// internal errors in the client. From our perspective this uses storage
 		// resources similar to how failed authorizations do, so we increment the
 		// failed authorizations limit.
-		err = ra.countFailedValidations(ctx, req.RegistrationID, identifier.NewDNS(req.DnsName))
+		err = ra.countFailedAuthorization(ctx, req.Id, req.RegistrationID, identifier.NewDNS(req.DnsName))
 		if err != nil {
 			return nil, fmt.Errorf("failed to update rate limits: %w", err)
 		}
@@ -2105,6 +3665,41 @@ func (ra *RegistrationAuthorityImpl) DeactivateAuthorization(ctx context.Context
 	return &emptypb.Empty{}, nil
 }
 
+// maxOrdersInvalidatedPerDeactivation bounds the number of orders which
+// DeactivateAuthorization records an error on. The SA gives any remaining
+// orders the same error when they are next fetched.
+const maxOrdersInvalidatedPerDeactivation = 100
+
+// invalidateOrdersForAuthorization records an error explaining that the given
+// authorization was deactivated on the pending and ready orders which include
+// it, so that clients polling those orders learn why they became invalid.
+// Failures are logged rather than returned, since the authorization has
+// already been deactivated.
+func (ra *RegistrationAuthorityImpl) invalidateOrdersForAuthorization(ctx context.Context, authzID int64, ident identifier.ACMEIdentifier) {
+	orders, err := ra.SA.GetPendingOrdersForAuthorization(ctx, &sapb.GetPendingOrdersForAuthorizationRequest{
+		AuthzID: authzID,
+		Limit:   maxOrdersInvalidatedPerDeactivation,
+	})
+	if err != nil {
+		ra.log.Warningf("finding orders including deactivated authorization %d: %s", authzID, err)
+		return
+	}
+	pbProb, err := bgrpc.ProblemDetailsToPB(core.DeactivatedAuthorizationProblem(authzID, ident))
+	if err != nil {
+		ra.log.Warningf("converting problem for deactivated authorization %d: %s", authzID, err)
+		return
+	}
+	for _, orderID := range orders.Ids {
+		_, err := ra.SA.SetOrderError(ctx, &sapb.SetOrderErrorRequest{
+			Id:    orderID,
+			Error: pbProb,
+		})
+		if err != nil {
+			ra.log.Warningf("invalidating order %d including deactivated authorization %d: %s", orderID, authzID, err)
+		}
+	}
+}
+
 // GenerateOCSP looks up a certificate's status, then requests a signed OCSP
 // response for it from the CA. If the certificate status is not available
 // or the certificate is expired, it returns berrors.NotFoundError.
@@ -2150,9 +3745,22 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 		return nil, errIncompleteGRPCRequest
 	}
 
+	var readyBy time.Time
+	if !core.IsAnyNilOrZero(req.ReadyBy) {
+		readyBy = req.ReadyBy.AsTime()
+		if !readyBy.After(ra.clk.Now()) {
+			return nil, berrors.MalformedError("readyBy %s is not in the future", readyBy.Format(time.RFC3339))
+		}
+	}
+
+	dnsNames, err := policy.NormalizeDomainNames(req.DnsNames)
+	if err != nil {
+		return nil, err
+	}
+
 	newOrder := &sapb.NewOrderRequest{
 		RegistrationID:         req.RegistrationID,
-		DnsNames:               core.UniqueLowerNames(req.DnsNames),
+		DnsNames:               dnsNames,
 		CertificateProfileName: req.CertificateProfileName,
 		ReplacesSerial:         req.ReplacesSerial,
 	}
@@ -2162,8 +3770,9 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 			"Order cannot contain more than %d DNS names", ra.maxNames)
 	}
 
-	if req.CertificateProfileName != "" && ra.validationProfiles != nil {
-		vp, ok := ra.validationProfiles[req.CertificateProfileName]
+	validationProfiles := ra.getValidationProfiles()
+	if req.CertificateProfileName != "" && validationProfiles != nil {
+		vp, ok := validationProfiles[req.CertificateProfileName]
 		if !ok {
 			return nil, berrors.MalformedError("requested certificate profile %q not found",
 				req.CertificateProfileName,
@@ -2178,7 +3787,7 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 	}
 
 	// Validate that our policy allows issuing for each of the names in the order
-	err := ra.PA.WillingToIssue(newOrder.DnsNames)
+	err = ra.PA.WillingToIssue(newOrder.DnsNames)
 	if err != nil {
 		return nil, err
 	}
@@ -2209,10 +3818,14 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 		}
 
 		// Only re-use the order if the profile (even if it is just the empty
-		// string, leaving us to choose a default profile) matches.
-		if existingOrder.CertificateProfileName == newOrder.CertificateProfileName {
+		// string, leaving us to choose a default profile) matches, and it won't
+		// expire before the subscriber intends to finalize it.
+		if existingOrder.CertificateProfileName == newOrder.CertificateProfileName &&
+			!existingOrder.Expires.AsTime().Before(readyBy) {
 			// Track how often we reuse an existing order and how old that order is.
 			ra.orderAges.WithLabelValues("NewOrder").Observe(ra.clk.Since(existingOrder.Created.AsTime()).Seconds())
+			// No new order was created, so none is counted.
+			ra.releaseNewOrderLimits(ctx, req.ReservationToken)
 			return existingOrder, nil
 		}
 	}
@@ -2222,9 +3835,13 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 	// `sa.GetAuthorizations` returned an authorization that was very close to
 	// expiry. The resulting pending order that references it would itself end up
 	// expiring very soon.
-	// To prevent this we only return authorizations that are at least 1 day away
-	// from expiring.
-	authzExpiryCutoff := ra.clk.Now().AddDate(0, 0, 1)
+	// To prevent this we only return authorizations whose remaining lifetime
+	// covers the new order's lifetime, or that won't expire before the
+	// subscriber intends to finalize the order, whichever is later.
+	authzExpiryCutoff := ra.clk.Now().Add(ra.orderLifetime)
+	if readyBy.After(authzExpiryCutoff) {
+		authzExpiryCutoff = readyBy
+	}
 
 	var existingAuthz *sapb.Authorizations
 	if features.Get().NoPendingAuthzReuse {
@@ -2309,6 +3926,11 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 		ra.authzAges.WithLabelValues("NewOrder", string(core.StatusPending)).Observe(0)
 	}
 
+	evictions, err := ra.pendingAuthzsToEvict(ctx, newOrder.RegistrationID, newOrder.V2Authorizations, len(newAuthzs))
+	if err != nil {
+		return nil, err
+	}
+
 	// Start with the order's own expiry as the minExpiry. We only care
 	// about authz expiries that are sooner than the order's expiry
 	minExpiry := ra.clk.Now().Add(ra.orderLifetime)
@@ -2339,6 +3961,23 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 	// Set the order's expiry to the minimum expiry. The db doesn't store
 	// sub-second values, so truncate here.
 	newOrder.Expires = timestamppb.New(minExpiry.Truncate(time.Second))
+	if readyBy.After(newOrder.Expires.AsTime()) {
+		return nil, berrors.MalformedError("order would expire at %s, before readyBy %s",
+			newOrder.Expires.AsTime().Format(time.RFC3339), readyBy.Format(time.RFC3339))
+	}
+
+	// If every authorization was reused, the order is ready to be finalized
+	// and we know when doing so will start to require a CAA recheck. Like the
+	// expiry, it's truncated because the db doesn't store sub-second values.
+	if len(newAuthzs) == 0 {
+		recheckAfter := caaRecheckAfter(identToExistingAuthz)
+		if !recheckAfter.IsZero() {
+			if recheckAfter.After(newOrder.Expires.AsTime()) {
+				recheckAfter = newOrder.Expires.AsTime()
+			}
+			newOrder.FinalizeBy = timestamppb.New(recheckAfter.Truncate(time.Second))
+		}
+	}
 
 	newOrderAndAuthzsReq := &sapb.NewOrderAndAuthzsRequest{
 		NewOrder:  newOrder,
@@ -2346,6 +3985,8 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 	}
 	storedOrder, err := ra.SA.NewOrderAndAuthzs(ctx, newOrderAndAuthzsReq)
 	if err != nil {
+		// The order wasn't created, so it mustn't cost the Subscriber anything.
+		ra.releaseNewOrderLimits(ctx, req.ReservationToken)
 		return nil, err
 	}
 
@@ -2353,6 +3994,8 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 		return nil, errIncompleteGRPCResponse
 	}
 	ra.orderAges.WithLabelValues("NewOrder").Observe(0)
+	ra.commitNewOrderLimits(ctx, req.ReservationToken, storedOrder.Id)
+	ra.evictPendingAuthzs(ctx, newOrder.RegistrationID, evictions)
 
 	// Note how many names are being requested in this certificate order.
 	ra.namesPerCert.With(prometheus.Labels{"type": "requested"}).Observe(float64(len(storedOrder.DnsNames)))
@@ -2360,6 +4003,163 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 	return storedOrder, nil
 }
 
+// maxPendingAuthzPrunesPerOrder bounds how many of an account's pending
+// authorizations a single NewOrder request may deactivate to keep the account
+// under MaxPendingAuthorizationsPerAccount. It matches the most names an order
+// may contain.
+const maxPendingAuthzPrunesPerOrder = 100
+
+// pendingAuthzsToEvict returns the IDs of the pending authorizations to
+// deactivate, once an order with newAuthzs new pending authorizations has been
+// stored, to keep the account under ra.MaxPendingAuthorizationsPerAccount.
+// Only the soonest-expiring authorizations which no unexpired order references,
+// other than those in reusedIDs, are chosen. It returns a rate limit error if
+// there aren't enough of them.
+func (ra *RegistrationAuthorityImpl) pendingAuthzsToEvict(ctx context.Context, regID int64, reusedIDs []int64, newAuthzs int) ([]int64, error) {
+	if ra.MaxPendingAuthorizationsPerAccount <= 0 || newAuthzs == 0 {
+		return nil, nil
+	}
+
+	count, err := ra.SA.CountPendingAuthorizations2(ctx, &sapb.RegistrationID{Id: regID})
+	if err != nil {
+		return nil, fmt.Errorf("counting pending authorizations: %w", err)
+	}
+	excess := count.Count + int64(newAuthzs) - int64(ra.MaxPendingAuthorizationsPerAccount)
+	if excess <= 0 {
+		return nil, nil
+	}
+
+	// Unless an authorization expires sooner, the account is over the cap
+	// until every pending authorization it holds has expired.
+	retryAfter := ra.pendingAuthorizationLifetime
+	if excess <= maxPendingAuthzPrunesPerOrder {
+		// Ask for enough to skip over any pending authorizations this order
+		// is reusing.
+		pending, err := ra.SA.GetPendingAuthorizationsByExpiry(ctx, &sapb.GetPendingAuthorizationsByExpiryRequest{
+			RegistrationID: regID,
+			Limit:          excess + int64(len(reusedIDs)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("getting pending authorizations: %w", err)
+		}
+		if len(pending.Authzs) > 0 {
+			retryAfter = pending.Authzs[0].Expires.AsTime().Sub(ra.clk.Now())
+		}
+		var evictions []int64
+		for _, authz := range pending.Authzs {
+			if int64(len(evictions)) == excess {
+				break
+			}
+			authzID, err := strconv.ParseInt(authz.Id, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if slices.Contains(reusedIDs, authzID) {
+				continue
+			}
+			evictions = append(evictions, authzID)
+		}
+		if int64(len(evictions)) == excess {
+			return evictions, nil
+		}
+	}
+
+	ra.pendingAuthzCap.WithLabelValues("rejected").Inc()
+	return nil, berrors.RateLimitError(retryAfter,
+		"too many pending authorizations (%d) for this account, which may hold at most %d: "+
+			"complete or deactivate some before requesting more", count.Count, ra.MaxPendingAuthorizationsPerAccount)
+}
+
+// evictPendingAuthzs deactivates the pending authorizations chosen by
+// pendingAuthzsToEvict. The order has already been stored, so failures are
+// only logged: at worst the account holds a few more pending authorizations
+// than the cap until they expire.
+func (ra *RegistrationAuthorityImpl) evictPendingAuthzs(ctx context.Context, regID int64, authzIDs []int64) {
+	if len(authzIDs) == 0 {
+		return
+	}
+	for _, authzID := range authzIDs {
+		_, err := ra.SA.DeactivateAuthorization2(ctx, &sapb.AuthorizationID2{Id: authzID})
+		if err != nil {
+			ra.log.Warningf("deactivating pending authorization %d of account %d: %s", authzID, regID, err)
+			continue
+		}
+		ra.pendingAuthzCap.WithLabelValues("pruned").Inc()
+	}
+	ra.log.Infof("deactivated soonest-expiring pending authorizations of account %d to stay under cap of %d",
+		regID, ra.MaxPendingAuthorizationsPerAccount)
+}
+
+// commitNewOrderLimits commits the new order rate limit spends reserved by the
+// WFE. There is no reason to surface errors from this function to the
+// Subscriber, the order has already been created. If the reservation expired
+// its spends were refunded and the order goes uncounted. If the commit fails
+// for any other reason the reservation is left unresolved, so the discrepancy
+// is audit logged and handed to reconcileNewOrderLimits.
+func (ra *RegistrationAuthorityImpl) commitNewOrderLimits(ctx context.Context, token string, orderID int64) {
+	err := ra.limiter.Commit(ctx, token)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, ratelimits.ErrReservationNotFound) {
+		ra.newOrderReconciliations.WithLabelValues("expired").Inc()
+		ra.log.AuditErrf("New order rate limit discrepancy: orderID=[%d] reservation expired before it was committed, order is uncounted", orderID)
+		return
+	}
+	ra.log.AuditErrf("New order rate limit discrepancy: orderID=[%d] committing reservation failed, reconciling: %s", orderID, err)
+	ra.reconcileNewOrderLimits(ctx, token, orderID)
+}
+
+// releaseNewOrderLimits refunds the new order rate limit spends reserved by
+// the WFE for an order which wasn't created. The WFE releases the reservation
+// too if NewOrder fails, so a reservation which is already gone isn't an
+// error.
+func (ra *RegistrationAuthorityImpl) releaseNewOrderLimits(ctx context.Context, token string) {
+	err := ra.limiter.Release(ctx, token)
+	if err != nil && !errors.Is(err, ratelimits.ErrReservationNotFound) {
+		ra.log.Warningf("releasing new order rate limit reservation: %s", err)
+	}
+}
+
+const (
+	// newOrderReconcileAttempts bounds how many times reconcileNewOrderLimits
+	// tries to release a reservation. If every attempt fails, the reservation
+	// is still released once it expires.
+	newOrderReconcileAttempts = 5
+	newOrderReconcileBackoff  = time.Second
+)
+
+// reconcileNewOrderLimits resolves, in the background, a reservation whose
+// commit failed. The state of such a reservation is unknown: the commit may
+// have been applied with only its response lost. Releasing it is safe either
+// way, because a committed or expired reservation can't be released, so the
+// spends are refunded at most once and never held indefinitely.
+func (ra *RegistrationAuthorityImpl) reconcileNewOrderLimits(ctx context.Context, token string, orderID int64) {
+	ra.drainWG.Add(1)
+	go func() {
+		defer ra.drainWG.Done()
+		ctx := context.WithoutCancel(ctx)
+
+		var err error
+		for attempt := range newOrderReconcileAttempts {
+			ra.clk.Sleep(core.RetryBackoff(attempt, newOrderReconcileBackoff, 30*time.Second, 2))
+			err = ra.limiter.Release(ctx, token)
+			if err == nil {
+				ra.newOrderReconciliations.WithLabelValues("released").Inc()
+				ra.log.AuditInfof("New order rate limit reconciled: orderID=[%d] reservation released", orderID)
+				return
+			}
+			if errors.Is(err, ratelimits.ErrReservationNotFound) {
+				ra.newOrderReconciliations.WithLabelValues("already_resolved").Inc()
+				ra.log.AuditInfof("New order rate limit reconciled: orderID=[%d] reservation was already committed or expired", orderID)
+				return
+			}
+		}
+		ra.newOrderReconciliations.WithLabelValues("failed").Inc()
+		ra.log.AuditErrf("New order rate limit reconciliation failed: orderID=[%d] reservation will be released when it expires: %s", orderID, err)
+	}()
+}
+
 // createPendingAuthz checks that a name is allowed for issuance and creates the
 // necessary challenges for it and puts this and all of the relevant information
 // into a corepb.Authorization for transmission to the SA to be stored
@@ -2392,18 +4192,32 @@ func wildcardOverlap(dnsNames []string) error {
 	for _, v := range dnsNames {
 		nameMap[v] = true
 	}
+	var redundant []string
 	for name := range nameMap {
 		if name[0] == '*' {
 			continue
 		}
 		labels := strings.Split(name, ".")
 		labels[0] = "*"
-		if nameMap[strings.Join(labels, ".")] {
-			return berrors.MalformedError(
-				"Domain name %q is redundant with a wildcard domain in the same request. Remove one or the other from the certificate request.", name)
+		wildcard := strings.Join(labels, ".")
+		if nameMap[wildcard] {
+			redundant = append(redundant, fmt.Sprintf("%q is covered by %q", name, wildcard))
 		}
 	}
-	return nil
+	if len(redundant) == 0 {
+		return nil
+	}
+	// A wildcard covers exactly one label in its place, so it never covers its
+	// own base name (e.g. "*.example.com" doesn't cover "example.com"), which is
+	// why that combination isn't redundant. Clients requesting a wildcard along
+	// with names it covers have usually mistaken it for the reverse.
+	slices.Sort(redundant)
+	return berrors.MalformedError(
+		"Domain names are redundant with a wildcard domain in the same request: %s. "+
+			"Remove one or the other from the certificate request. Note that a wildcard "+
+			"covers any single label in its place, but not its base domain, which must be "+
+			"requested separately if needed.",
+		strings.Join(redundant, ", "))
 }
 
 // UnpauseAccount receives a validated account unpause request from the SFE and
@@ -2447,13 +4261,16 @@ func (ra *RegistrationAuthorityImpl) GetAuthorization(ctx context.Context, req *
 	return authz, nil
 }
 
-// Drain blocks until all detached goroutines are done.
+// Drain blocks until all detached goroutines and scheduled validations are
+// done. Validations scheduled after Drain is called are refused.
 //
-// The RA runs detached goroutines for challenge validation and finalization,
-// so that ACME responses can be returned to the user promptly while work continues.
+// The RA runs detached goroutines for finalization, and schedules challenge
+// validations to be performed by a pool of workers, so that ACME responses can
+// be returned to the user promptly while work continues.
 //
 // The main goroutine should call this before exiting to avoid canceling the work
 // being done in detached goroutines.
 func (ra *RegistrationAuthorityImpl) Drain() {
+	ra.validations.drain()
 	ra.drainWG.Wait()
 }
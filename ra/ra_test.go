@@ -3,6 +3,7 @@ package ra
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -17,7 +18,9 @@ import (
 	"math"
 	"math/big"
 	mrand "math/rand/v2"
+	"net/netip"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,15 +33,20 @@ import (
 	ctpkix "github.com/google/certificate-transparency-go/x509/pkix"
 	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zmap/zlint/v3/lint"
 	"golang.org/x/crypto/ocsp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	akamaipb "github.com/letsencrypt/boulder/akamai/proto"
 	"github.com/letsencrypt/boulder/allowlist"
+	"github.com/letsencrypt/boulder/bdns"
 	capb "github.com/letsencrypt/boulder/ca/proto"
 	"github.com/letsencrypt/boulder/config"
 	"github.com/letsencrypt/boulder/core"
@@ -55,6 +63,7 @@ import (
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/mocks"
 	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/probs"
 	pubpb "github.com/letsencrypt/boulder/publisher/proto"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
 	"github.com/letsencrypt/boulder/ratelimits"
@@ -65,6 +74,7 @@ import (
 	"github.com/letsencrypt/boulder/test/vars"
 	"github.com/letsencrypt/boulder/va"
 	vapb "github.com/letsencrypt/boulder/va/proto"
+	"github.com/letsencrypt/boulder/web"
 )
 
 // randomDomain creates a random domain name for testing.
@@ -185,6 +195,7 @@ func (dva *DummyValidationAuthority) PerformValidation(ctx context.Context, req
 	return &vapb.ValidationResult{
 		Records: dcvRes.Records,
 		Problem: caaResp.Problem,
+		Attempt: dcvRes.Attempt,
 	}, nil
 }
 
@@ -202,6 +213,10 @@ func (dva *DummyValidationAuthority) DoCAA(ctx context.Context, req *vapb.IsCAAV
 	return dva.doCAAResponse, dva.doCAAError
 }
 
+func (dva *DummyValidationAuthority) CheckCAAMulti(_ context.Context, _ *vapb.CheckCAAMultiRequest, _ ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "CheckCAAMulti not implemented")
+}
+
 var (
 	// These values we simulate from the client
 	AccountKeyJSONA = []byte(`{
@@ -331,8 +346,8 @@ func initAuthorities(t *testing.T) (*DummyValidationAuthority, sapb.StorageAutho
 		},
 	}, nil, nil, 0, log, metrics.NoopRegisterer)
 
-	rlSource := ratelimits.NewInmemSource()
-	limiter, err := ratelimits.NewLimiter(fc, rlSource, stats)
+	rlSource := ratelimits.NewInmemSource(fc)
+	limiter, err := ratelimits.NewLimiter(fc, rlSource, stats, log)
 	test.AssertNotError(t, err, "making limiter")
 	txnBuilder, err := ratelimits.NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "")
 	test.AssertNotError(t, err, "making transaction composer")
@@ -348,7 +363,7 @@ func initAuthorities(t *testing.T) (*DummyValidationAuthority, sapb.StorageAutho
 		nil,
 		nil,
 		7*24*time.Hour, 5*time.Minute,
-		ctp, nil, nil)
+		ctp, nil, nil, nil, 0, 0)
 	ra.SA = sa
 	ra.VA = va
 	ra.CA = ca
@@ -434,13 +449,107 @@ func TestValidateContacts(t *testing.T) {
 	test.AssertError(t, err, "Too long contacts")
 }
 
+// contactMockDNS is a bdns.Client which counts MX lookups, and whose MX
+// lookups for slow.com block until the context is done.
+type contactMockDNS struct {
+	*bdns.MockClient
+	sync.Mutex
+	mxLookups int
+}
+
+func (mock *contactMockDNS) LookupMX(ctx context.Context, hostname string) ([]string, bdns.ResolverAddrs, error) {
+	mock.Lock()
+	mock.mxLookups++
+	mock.Unlock()
+	if hostname == "slow.com" {
+		<-ctx.Done()
+		return nil, bdns.ResolverAddrs{{Addr: "contactMockDNS", Qtype: "MX"}}, ctx.Err()
+	}
+	return mock.MockClient.LookupMX(ctx, hostname)
+}
+
+func TestContactDomainChecker(t *testing.T) {
+	t.Parallel()
+	fc := clock.NewFake()
+	mockLog := blog.NewMock()
+	resolver := &contactMockDNS{MockClient: &bdns.MockClient{}}
+	checker := NewContactDomainChecker(resolver, false, fc, mockLog, metrics.NoopRegisterer)
+	checker.timeout = 50 * time.Millisecond
+
+	// A domain with MX records is accepted, and the result is cached.
+	err := checker.Check(ctx, []string{"mailto:admin@example.com", "mailto:other@EXAMPLE.com"})
+	test.AssertNotError(t, err, "domain with MX records should be accepted")
+	test.AssertEquals(t, resolver.mxLookups, 1)
+	err = checker.Check(ctx, []string{"mailto:admin@example.com"})
+	test.AssertNotError(t, err, "cached domain should be accepted")
+	test.AssertEquals(t, resolver.mxLookups, 1)
+	test.AssertMetricWithLabelsEquals(t, checker.checks, prometheus.Labels{"result": "verified", "cached": "true"}, 1)
+
+	// Once the cached result expires, the domain is looked up again.
+	fc.Add(25 * time.Hour)
+	err = checker.Check(ctx, []string{"mailto:admin@example.com"})
+	test.AssertNotError(t, err, "domain with MX records should be accepted")
+	test.AssertEquals(t, resolver.mxLookups, 2)
+
+	// A domain with no MX records but with an A record is accepted.
+	err = checker.Check(ctx, []string{"mailto:admin@no-mx.com"})
+	test.AssertNotError(t, err, "domain with A records should be accepted")
+
+	// A domain which does not exist, or which accepts no mail, is rejected.
+	err = checker.Check(ctx, []string{"mailto:admin@example.com", "mailto:admin@always.nxdomain"})
+	test.AssertErrorIs(t, err, berrors.InvalidEmail)
+	test.AssertContains(t, err.Error(), "always.nxdomain")
+	err = checker.Check(ctx, []string{"mailto:admin@null-mx.com"})
+	test.AssertErrorIs(t, err, berrors.InvalidEmail)
+	test.AssertMetricWithLabelsEquals(t, checker.checks, prometheus.Labels{"result": "nonexistent", "cached": "false"}, 2)
+
+	// A domain which cannot be checked within the time budget is accepted,
+	// but logged as unverified and not cached.
+	start := time.Now()
+	err = checker.Check(ctx, []string{"mailto:admin@slow.com"})
+	test.AssertNotError(t, err, "unverifiable domain should be accepted")
+	test.Assert(t, time.Since(start) < time.Second, "check should be bounded by its timeout")
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`Unable to verify contact email domain "slow.com"`)), 1)
+	err = checker.Check(ctx, []string{"mailto:admin@always.timeout"})
+	test.AssertNotError(t, err, "unverifiable domain should be accepted")
+	test.AssertMetricWithLabelsEquals(t, checker.checks, prometheus.Labels{"result": "unverified", "cached": "false"}, 2)
+	before := resolver.mxLookups
+	err = checker.Check(ctx, []string{"mailto:admin@slow.com"})
+	test.AssertNotError(t, err, "unverifiable domain should be accepted")
+	test.AssertEquals(t, resolver.mxLookups, before+1)
+
+	// A nil checker accepts everything.
+	var nilChecker *ContactDomainChecker
+	test.AssertNotError(t, nilChecker.Check(ctx, []string{"mailto:admin@always.nxdomain"}), "nil checker should accept")
+}
+
+func TestContactDomainCheckerHardReject(t *testing.T) {
+	t.Parallel()
+	resolver := &contactMockDNS{MockClient: &bdns.MockClient{}}
+	checker := NewContactDomainChecker(resolver, true, clock.NewFake(), blog.NewMock(), metrics.NoopRegisterer)
+	checker.timeout = 50 * time.Millisecond
+
+	err := checker.Check(ctx, []string{"mailto:admin@example.com"})
+	test.AssertNotError(t, err, "domain with MX records should be accepted")
+
+	err = checker.Check(ctx, []string{"mailto:admin@example.com", "mailto:admin@slow.com"})
+	test.AssertErrorIs(t, err, berrors.InvalidEmail)
+	test.AssertContains(t, err.Error(), "slow.com")
+
+	err = checker.Check(ctx, []string{"mailto:admin@always.timeout"})
+	test.AssertErrorIs(t, err, berrors.InvalidEmail)
+
+	err = checker.Check(ctx, []string{"mailto:admin@always.nxdomain"})
+	test.AssertErrorIs(t, err, berrors.InvalidEmail)
+}
+
 func TestNewRegistration(t *testing.T) {
 	_, sa, ra, _, _, cleanUp := initAuthorities(t)
 	defer cleanUp()
 	mailto := "mailto:foo@letsencrypt.org"
 	acctKeyB, err := AccountKeyB.MarshalJSON()
 	test.AssertNotError(t, err, "failed to marshal account key")
-	input := &corepb.Registration{
+	input := &rapb.NewRegistrationRequest{
 		Contact: []string{mailto},
 		Key:     acctKeyB,
 	}
@@ -473,7 +582,7 @@ func TestNewRegistrationSAFailure(t *testing.T) {
 	ra.SA = &mockSAFailsNewRegistration{}
 	acctKeyB, err := AccountKeyB.MarshalJSON()
 	test.AssertNotError(t, err, "failed to marshal account key")
-	input := corepb.Registration{
+	input := rapb.NewRegistrationRequest{
 		Contact: []string{"mailto:test@example.com"},
 		Key:     acctKeyB,
 	}
@@ -489,8 +598,7 @@ func TestNewRegistrationNoFieldOverwrite(t *testing.T) {
 	mailto := "mailto:foo@letsencrypt.org"
 	acctKeyC, err := AccountKeyC.MarshalJSON()
 	test.AssertNotError(t, err, "failed to marshal account key")
-	input := &corepb.Registration{
-		Id:        23,
+	input := &rapb.NewRegistrationRequest{
 		Key:       acctKeyC,
 		Contact:   []string{mailto},
 		Agreement: "I agreed",
@@ -498,7 +606,7 @@ func TestNewRegistrationNoFieldOverwrite(t *testing.T) {
 
 	result, err := ra.NewRegistration(ctx, input)
 	test.AssertNotError(t, err, "Could not create new registration")
-	test.Assert(t, result.Id != 23, "ID shouldn't be set by user")
+	test.Assert(t, result.Id != 0, "ID should be set by the SA")
 	// TODO: Enable this test case once we validate terms agreement.
 	//test.Assert(t, result.Agreement != "I agreed", "Agreement shouldn't be set with invalid URL")
 }
@@ -509,7 +617,7 @@ func TestNewRegistrationBadKey(t *testing.T) {
 	mailto := "mailto:foo@letsencrypt.org"
 	shortKey, err := ShortKey.MarshalJSON()
 	test.AssertNotError(t, err, "failed to marshal account key")
-	input := &corepb.Registration{
+	input := &rapb.NewRegistrationRequest{
 		Contact: []string{mailto},
 		Key:     shortKey,
 	}
@@ -517,6 +625,256 @@ func TestNewRegistrationBadKey(t *testing.T) {
 	test.AssertError(t, err, "Should have rejected authorization with short key")
 }
 
+// mockSARegistrationsByKey is a mock SA which stores registrations by the
+// digest of their key, as the real SA does.
+type mockSARegistrationsByKey struct {
+	sapb.StorageAuthorityClient
+	regs map[string]*corepb.Registration
+}
+
+func (sa *mockSARegistrationsByKey) GetRegistrationByKey(_ context.Context, req *sapb.JSONWebKey, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	sha, err := core.JWKDigestB64(req.Jwk)
+	if err != nil {
+		return nil, err
+	}
+	reg, ok := sa.regs[sha]
+	if !ok {
+		return nil, berrors.NotFoundError("no registrations with public key sha256 %q", sha)
+	}
+	return reg, nil
+}
+
+func TestGetRegistrationByKey(t *testing.T) {
+	t.Parallel()
+
+	digest := func(jwk []byte) string {
+		sha, err := core.JWKDigestB64(jwk)
+		test.AssertNotError(t, err, "computing key digest")
+		return sha
+	}
+	ra := &RegistrationAuthorityImpl{SA: &mockSARegistrationsByKey{
+		regs: map[string]*corepb.Registration{
+			digest(AccountKeyJSONA): {Id: 1, Key: AccountKeyJSONA, Status: string(core.StatusValid)},
+			digest(AccountKeyJSONB): {Id: 2, Key: AccountKeyJSONB, Status: string(core.StatusDeactivated)},
+		},
+	}}
+
+	// Found.
+	reg, err := ra.GetRegistrationByKey(ctx, &rapb.GetRegistrationByKeyRequest{Jwk: AccountKeyJSONA})
+	test.AssertNotError(t, err, "getting registration by key")
+	test.AssertEquals(t, reg.Id, int64(1))
+
+	// Not found.
+	_, err = ra.GetRegistrationByKey(ctx, &rapb.GetRegistrationByKeyRequest{Jwk: AccountKeyJSONC})
+	test.AssertErrorIs(t, err, berrors.NotFound)
+	test.AssertContains(t, err.Error(), digest(AccountKeyJSONC))
+
+	// Deactivated.
+	_, err = ra.GetRegistrationByKey(ctx, &rapb.GetRegistrationByKeyRequest{Jwk: AccountKeyJSONB})
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	test.AssertContains(t, err.Error(), "deactivated")
+
+	// Missing or unparseable keys are rejected without querying.
+	_, err = ra.GetRegistrationByKey(ctx, &rapb.GetRegistrationByKeyRequest{})
+	test.AssertErrorIs(t, err, errIncompleteGRPCRequest)
+	_, err = ra.GetRegistrationByKey(ctx, &rapb.GetRegistrationByKeyRequest{Jwk: []byte("{}")})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+}
+
+// signEABBinding returns an External Account Binding over accountKey, MACed
+// with macKey and addressed to url.
+func signEABBinding(t *testing.T, keyID string, macKey []byte, url string, accountKey []byte) []byte {
+	t.Helper()
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.HS256, Key: macKey},
+		&jose.SignerOptions{ExtraHeaders: map[jose.HeaderKey]interface{}{
+			"kid": keyID,
+			"url": url,
+		}},
+	)
+	test.AssertNotError(t, err, "failed to create EAB signer")
+	jws, err := signer.Sign(accountKey)
+	test.AssertNotError(t, err, "failed to sign EAB")
+	return []byte(jws.FullSerialize())
+}
+
+const eabNewAccountURL = "https://example.com/acme/new-acct"
+
+func TestEABVerifier(t *testing.T) {
+	t.Parallel()
+	var acctKeyB jose.JSONWebKey
+	err := json.Unmarshal(AccountKeyJSONB, &acctKeyB)
+	test.AssertNotError(t, err, "failed to unmarshal account key")
+	macKey := []byte("a very secret MAC key of 32 bytes")
+	verifier := NewEABVerifier(StaticEABKeys{"kid-1": macKey}, false)
+
+	// A nil verifier accepts registrations without a binding.
+	var nilVerifier *EABVerifier
+	keyID, err := nilVerifier.Verify(ctx, nil, "", &acctKeyB)
+	test.AssertNotError(t, err, "nil verifier should accept")
+	test.AssertEquals(t, keyID, "")
+
+	_, err = verifier.Verify(ctx, nil, eabNewAccountURL, &acctKeyB)
+	test.AssertErrorIs(t, err, berrors.ExternalAccountRequired)
+
+	binding := signEABBinding(t, "kid-1", macKey, eabNewAccountURL, AccountKeyJSONB)
+	keyID, err = verifier.Verify(ctx, binding, eabNewAccountURL, &acctKeyB)
+	test.AssertNotError(t, err, "valid binding should be accepted")
+	test.AssertEquals(t, keyID, "kid-1")
+
+	// The binding must be addressed to the newAccount request's URL.
+	_, err = verifier.Verify(ctx, binding, "https://example.com/acme/other", &acctKeyB)
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	wrongMAC := signEABBinding(t, "kid-1", []byte("not the right MAC key, not at all"), eabNewAccountURL, AccountKeyJSONB)
+	_, err = verifier.Verify(ctx, wrongMAC, eabNewAccountURL, &acctKeyB)
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	test.AssertContains(t, err.Error(), "invalid signature")
+
+	unknownKeyID := signEABBinding(t, "kid-2", macKey, eabNewAccountURL, AccountKeyJSONB)
+	_, err = verifier.Verify(ctx, unknownKeyID, eabNewAccountURL, &acctKeyB)
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	test.AssertContains(t, err.Error(), "kid-2")
+
+	// A binding over one account key can't be used to create an account with
+	// another.
+	var acctKeyC jose.JSONWebKey
+	err = json.Unmarshal(AccountKeyJSONC, &acctKeyC)
+	test.AssertNotError(t, err, "failed to unmarshal account key")
+	_, err = verifier.Verify(ctx, binding, eabNewAccountURL, &acctKeyC)
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	test.AssertContains(t, err.Error(), "different account key")
+
+	_, err = verifier.Verify(ctx, []byte("{}"), eabNewAccountURL, &acctKeyB)
+	test.AssertErrorIs(t, err, berrors.Malformed)
+}
+
+// mockSAWithEAB stores new registrations in memory, along with the EAB key
+// IDs claimed by them.
+type mockSAWithEAB struct {
+	sapb.StorageAuthorityClient
+	regs    []*corepb.Registration
+	claimed map[string]int64
+}
+
+func (msa *mockSAWithEAB) NewRegistration(_ context.Context, req *corepb.Registration, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	reg := &corepb.Registration{
+		Id:       int64(len(msa.regs) + 1),
+		Key:      req.Key,
+		Contact:  req.Contact,
+		Status:   req.Status,
+		EabKeyID: req.EabKeyID,
+	}
+	msa.regs = append(msa.regs, reg)
+	return reg, nil
+}
+
+func (msa *mockSAWithEAB) NewRegistrationClaimingEABKeyID(ctx context.Context, req *corepb.Registration, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	_, ok := msa.claimed[req.EabKeyID]
+	if ok {
+		return nil, berrors.UnauthorizedError("external account binding key %q has already been used to create an account", req.EabKeyID)
+	}
+	reg, err := msa.NewRegistration(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if msa.claimed == nil {
+		msa.claimed = make(map[string]int64)
+	}
+	msa.claimed[req.EabKeyID] = reg.Id
+	return reg, nil
+}
+
+func TestNewRegistrationEAB(t *testing.T) {
+	_, _, ra, _, _, cleanUp := initAuthorities(t)
+	defer cleanUp()
+	macKey := []byte("a very secret MAC key of 32 bytes")
+
+	for _, oneAccountPerKeyID := range []bool{true, false} {
+		t.Run(fmt.Sprintf("oneAccountPerKeyID=%t", oneAccountPerKeyID), func(t *testing.T) {
+			msa := &mockSAWithEAB{}
+			ra.SA = msa
+			ra.EAB = NewEABVerifier(StaticEABKeys{"kid-1": macKey}, oneAccountPerKeyID)
+
+			_, err := ra.NewRegistration(ctx, &rapb.NewRegistrationRequest{Key: AccountKeyJSONB})
+			test.AssertErrorIs(t, err, berrors.ExternalAccountRequired)
+
+			reg, err := ra.NewRegistration(ctx, &rapb.NewRegistrationRequest{
+				Key:                    AccountKeyJSONB,
+				ExternalAccountBinding: signEABBinding(t, "kid-1", macKey, eabNewAccountURL, AccountKeyJSONB),
+				NewAccountURL:          eabNewAccountURL,
+			})
+			test.AssertNotError(t, err, "valid binding should be accepted")
+			test.AssertEquals(t, reg.EabKeyID, "kid-1")
+
+			// A fresh binding with the same key ID, for a different account
+			// key, is only accepted if key IDs may be reused.
+			_, err = ra.NewRegistration(ctx, &rapb.NewRegistrationRequest{
+				Key:                    AccountKeyJSONC,
+				ExternalAccountBinding: signEABBinding(t, "kid-1", macKey, eabNewAccountURL, AccountKeyJSONC),
+				NewAccountURL:          eabNewAccountURL,
+			})
+			if oneAccountPerKeyID {
+				test.AssertErrorIs(t, err, berrors.Unauthorized)
+				test.AssertEquals(t, len(msa.regs), 1)
+				test.AssertEquals(t, msa.claimed["kid-1"], reg.Id)
+			} else {
+				test.AssertNotError(t, err, "reused key ID should be accepted")
+				test.AssertEquals(t, len(msa.regs), 2)
+				test.AssertEquals(t, len(msa.claimed), 0)
+			}
+		})
+	}
+}
+
+// mockSAWithEABKeys serves MAC keys from a fixed map.
+type mockSAWithEABKeys struct {
+	keys map[string][]byte
+	err  error
+}
+
+func (msa *mockSAWithEABKeys) GetEABKey(_ context.Context, req *sapb.EABKeyID, _ ...grpc.CallOption) (*sapb.EABKey, error) {
+	if msa.err != nil {
+		return nil, msa.err
+	}
+	key, ok := msa.keys[req.KeyID]
+	if !ok {
+		return nil, berrors.NotFoundError("no EAB key with ID %q", req.KeyID)
+	}
+	return &sapb.EABKey{MacKey: key}, nil
+}
+
+func TestSAEABKeys(t *testing.T) {
+	macKey := []byte("a very secret MAC key of 32 bytes")
+	msa := &mockSAWithEABKeys{keys: map[string][]byte{"kid-1": macKey}}
+	keys := NewSAEABKeys(msa)
+
+	key, err := keys.MACKey(ctx, "kid-1")
+	test.AssertNotError(t, err, "looking up provisioned key")
+	test.AssertByteEquals(t, key, macKey)
+
+	_, err = keys.MACKey(ctx, "kid-2")
+	test.AssertErrorIs(t, err, berrors.NotFound)
+
+	// A binding using a stored key verifies just as one using a static key.
+	var acctKeyB jose.JSONWebKey
+	err = json.Unmarshal(AccountKeyJSONB, &acctKeyB)
+	test.AssertNotError(t, err, "failed to unmarshal account key")
+	verifier := NewEABVerifier(keys, true)
+	kid, err := verifier.Verify(ctx, signEABBinding(t, "kid-1", macKey, eabNewAccountURL, AccountKeyJSONB), eabNewAccountURL, &acctKeyB)
+	test.AssertNotError(t, err, "binding with a stored key should verify")
+	test.AssertEquals(t, kid, "kid-1")
+
+	_, err = verifier.Verify(ctx, signEABBinding(t, "kid-2", macKey, eabNewAccountURL, AccountKeyJSONB), eabNewAccountURL, &acctKeyB)
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+
+	// Other SA errors aren't mistaken for an unknown key.
+	msa.err = errors.New("oops")
+	_, err = verifier.Verify(ctx, signEABBinding(t, "kid-1", macKey, eabNewAccountURL, AccountKeyJSONB), eabNewAccountURL, &acctKeyB)
+	test.AssertError(t, err, "SA failure should fail verification")
+	test.Assert(t, !errors.Is(err, berrors.Unauthorized), "SA failure shouldn't be Unauthorized")
+}
+
 func TestPerformValidationExpired(t *testing.T) {
 	_, sa, ra, _, fc, cleanUp := initAuthorities(t)
 	defer cleanUp()
@@ -604,6 +962,8 @@ func TestPerformValidationSuccess(t *testing.T) {
 		ChallengeIndex: challIdx,
 	})
 	test.AssertNotError(t, err, "PerformValidation failed")
+	// The challenge is processing until the validation completes.
+	test.AssertEquals(t, authzPB.Challenges[challIdx].Status, string(core.StatusProcessing))
 
 	var vaRequest *vapb.PerformValidationRequest
 	select {
@@ -616,6 +976,9 @@ func TestPerformValidationSuccess(t *testing.T) {
 	// Verify that the VA got the request, and it's the same as the others
 	test.AssertEquals(t, authzPB.Challenges[challIdx].Type, vaRequest.Challenge.Type)
 	test.AssertEquals(t, authzPB.Challenges[challIdx].Token, vaRequest.Challenge.Token)
+	test.AssertDeepEquals(t, vaRequest.AllowedChallengeTypes, []string{
+		string(core.ChallengeTypeHTTP01), string(core.ChallengeTypeDNS01), string(core.ChallengeTypeTLSALPN01),
+	})
 
 	// Sleep so the RA has a chance to write to the SA
 	time.Sleep(100 * time.Millisecond)
@@ -640,6 +1003,387 @@ func TestPerformValidationSuccess(t *testing.T) {
 	test.Assert(t, *challenge.Validated == expectedValidated, "Validated timestamp incorrect or missing")
 }
 
+// mockSAWithCapturedFinalize is a mock sapb.StorageAuthorityClient that
+// forwards all method calls to an inner SA, except for FinalizeAuthorization2,
+// which it writes to a channel instead. This leaves the authorization pending
+// so that tests can validate it again.
+type mockSAWithCapturedFinalize struct {
+	sapb.StorageAuthorityClient
+	out chan<- *sapb.FinalizeAuthorizationRequest
+}
+
+func (msa mockSAWithCapturedFinalize) FinalizeAuthorization2(_ context.Context, req *sapb.FinalizeAuthorizationRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	msa.out <- req
+	return &emptypb.Empty{}, nil
+}
+
+// pendingDNS01AuthzPB returns a pending authorization for a random domain,
+// with a single DNS-01 challenge.
+func pendingDNS01AuthzPB(t *testing.T, expires time.Time) *corepb.Authorization {
+	t.Helper()
+	authzPB, err := bgrpc.AuthzToPB(core.Authorization{
+		ID:             "1",
+		Identifier:     identifier.NewDNS(randomDomain()),
+		RegistrationID: Registration.Id,
+		Status:         core.StatusPending,
+		Expires:        &expires,
+		Challenges: []core.Challenge{
+			{Type: core.ChallengeTypeDNS01, Status: core.StatusPending, Token: core.NewToken()},
+		},
+	})
+	test.AssertNotError(t, err, "converting authz")
+	return authzPB
+}
+
+func TestPerformValidationQueueFull(t *testing.T) {
+	va, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+	ra.SA = &mockSAForPrecheck{}
+
+	// Occupy the only worker, and fill the queue behind it.
+	ra.validations.drain()
+	ra.validations = newValidationQueue(1, 1, metrics.NoopRegisterer)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	test.Assert(t, ra.validations.enqueue(func() {
+		close(started)
+		<-release
+	}), "enqueue should succeed")
+	<-started
+	test.Assert(t, ra.validations.enqueue(func() {}), "enqueue should succeed")
+
+	_, err := ra.PerformValidation(ctx, &rapb.PerformValidationRequest{
+		Authz:          pendingDNS01AuthzPB(t, fc.Now().Add(12*time.Hour)),
+		ChallengeIndex: 0,
+	})
+	test.AssertErrorIs(t, err, errValidationNotScheduled)
+	test.AssertEquals(t, len(va.doDCVRequest), 0)
+}
+
+func TestPerformValidationDrain(t *testing.T) {
+	va, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+	finalized := make(chan *sapb.FinalizeAuthorizationRequest, 1)
+	ra.SA = mockSAWithCapturedFinalize{StorageAuthorityClient: &mockSAForPrecheck{}, out: finalized}
+	va.doDCVResult = &vapb.ValidationResult{
+		Records: []*corepb.ValidationRecord{{Hostname: "example.com", ResolverAddrs: []string{"rebound"}}},
+	}
+	va.doCAAResponse = &vapb.IsCAAValidResponse{}
+
+	authzPB, err := ra.PerformValidation(ctx, &rapb.PerformValidationRequest{
+		Authz:          pendingDNS01AuthzPB(t, fc.Now().Add(12*time.Hour)),
+		ChallengeIndex: 0,
+	})
+	test.AssertNotError(t, err, "PerformValidation failed")
+	// The challenge is processing until the validation completes.
+	test.AssertEquals(t, authzPB.Challenges[0].Status, string(core.StatusProcessing))
+
+	// Draining waits for the scheduled validation to be recorded.
+	ra.Drain()
+	test.AssertEquals(t, len(va.doDCVRequest), 1)
+	test.AssertEquals(t, len(finalized), 1)
+	test.AssertEquals(t, (<-finalized).Status, string(core.StatusValid))
+
+	// Once draining, further validations are refused.
+	_, err = ra.PerformValidation(ctx, &rapb.PerformValidationRequest{
+		Authz:          pendingDNS01AuthzPB(t, fc.Now().Add(12*time.Hour)),
+		ChallengeIndex: 0,
+	})
+	test.AssertErrorIs(t, err, errValidationNotScheduled)
+	test.AssertEquals(t, len(va.doDCVRequest), 1)
+}
+
+// mockSAPersistingAttempts is a mock sapb.StorageAuthorityClient which keeps
+// the status and validation history of a single authorization the way the SA
+// does: FinalizeAuthorization2 only succeeds while the authorization is
+// pending, but AddValidationAttempt succeeds whatever its status. Each call to
+// FinalizeAuthorization2 is signalled on finalized.
+type mockSAPersistingAttempts struct {
+	sapb.StorageAuthorityClient
+	finalized chan struct{}
+
+	sync.Mutex
+	status   core.AcmeStatus
+	attempts []core.ValidationAttempt
+}
+
+func (msa *mockSAPersistingAttempts) FinalizeAuthorization2(_ context.Context, req *sapb.FinalizeAuthorizationRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	defer func() { msa.finalized <- struct{}{} }()
+	msa.Lock()
+	defer msa.Unlock()
+	if msa.status != core.StatusPending {
+		return nil, berrors.NotFoundError("no pending authorization with id %d", req.Id)
+	}
+	msa.status = core.AcmeStatus(req.Status)
+	return &emptypb.Empty{}, nil
+}
+
+func (msa *mockSAPersistingAttempts) SetAuthorizationFailureCounted(_ context.Context, _ *sapb.SetAuthorizationFailureCountedRequest, _ ...grpc.CallOption) (*sapb.SetAuthorizationFailureCountedResponse, error) {
+	return &sapb.SetAuthorizationFailureCountedResponse{Changed: true}, nil
+}
+
+func (msa *mockSAPersistingAttempts) AddValidationAttempt(_ context.Context, req *sapb.AddValidationAttemptRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	msa.Lock()
+	defer msa.Unlock()
+	msa.attempts = core.AppendValidationAttempt(msa.attempts, bgrpc.PBToValidationAttempt(req.Attempt))
+	return &emptypb.Empty{}, nil
+}
+
+func TestPerformValidationAttemptHistory(t *testing.T) {
+	va, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	msa := &mockSAPersistingAttempts{
+		StorageAuthorityClient: &mockSAForPrecheck{},
+		finalized:              make(chan struct{}, 1),
+		status:                 core.StatusPending,
+	}
+	ra.SA = msa
+
+	// Every validation is of the same pending authorization, as when a client
+	// retries a challenge before its first validation is recorded.
+	authzPB := pendingDNS01AuthzPB(t, fc.Now().Add(12*time.Hour))
+	va.doCAAResponse = &vapb.IsCAAValidResponse{Problem: nil}
+
+	// validate performs a validation which results in prob, and returns the
+	// attempts which have been persisted once it's recorded.
+	validate := func(prob *corepb.ProblemDetails) []core.ValidationAttempt {
+		t.Helper()
+		va.doDCVResult = &vapb.ValidationResult{
+			Records: []*corepb.ValidationRecord{
+				{Hostname: "example.com", ResolverAddrs: []string{"resolver:53"}},
+			},
+			Problem: prob,
+			Attempt: &corepb.ValidationAttempt{
+				AttemptedAt:  timestamppb.New(fc.Now()),
+				Perspectives: "Primary, 3/3 remote perspectives corroborated",
+			},
+		}
+		_, err := ra.PerformValidation(ctx, &rapb.PerformValidationRequest{
+			Authz:          authzPB,
+			ChallengeIndex: 0,
+		})
+		test.AssertNotError(t, err, "PerformValidation failed")
+		<-va.doDCVRequest
+
+		select {
+		case <-msa.finalized:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for FinalizeAuthorization2")
+		}
+		fc.Add(time.Minute)
+
+		msa.Lock()
+		defer msa.Unlock()
+		return slices.Clone(msa.attempts)
+	}
+
+	start := fc.Now()
+	validate(&corepb.ProblemDetails{ProblemType: string(probs.ConnectionProblem), Detail: "connection refused"})
+	validate(&corepb.ProblemDetails{ProblemType: string(probs.UnauthorizedProblem), Detail: "wrong key authorization"})
+	attempts := validate(nil)
+
+	// Only the first validation finalized the authorization, but all three
+	// attempts are persisted, oldest first.
+	test.AssertEquals(t, msa.status, core.StatusInvalid)
+	test.AssertEquals(t, len(attempts), 3)
+	test.AssertEquals(t, attempts[0].ProblemType, probs.ConnectionProblem)
+	test.AssertEquals(t, attempts[0].Detail, "connection refused")
+	test.AssertEquals(t, attempts[0].AttemptedAt, start)
+	test.AssertEquals(t, attempts[1].ProblemType, probs.UnauthorizedProblem)
+	test.AssertEquals(t, attempts[1].AttemptedAt, start.Add(time.Minute))
+	test.AssertEquals(t, attempts[2].ProblemType, probs.ProblemType(""))
+	test.AssertEquals(t, attempts[2].Detail, "")
+	test.AssertEquals(t, attempts[2].Perspectives, "Primary, 3/3 remote perspectives corroborated")
+
+	// Only the most recent attempts are kept.
+	for range core.MaxValidationAttempts {
+		attempts = validate(&corepb.ProblemDetails{ProblemType: string(probs.ConnectionProblem), Detail: "timeout"})
+	}
+	test.AssertEquals(t, len(attempts), core.MaxValidationAttempts)
+	for _, a := range attempts {
+		test.AssertEquals(t, a.Detail, "timeout")
+	}
+}
+
+// mockVAShedding is a mock VA which sheds the first sheds requests made of it,
+// as an overloaded VA would, and then validates successfully.
+type mockVAShedding struct {
+	sheds int
+	calls int
+}
+
+func (va *mockVAShedding) shed(opts []grpc.CallOption) error {
+	va.calls++
+	if va.calls > va.sheds {
+		return nil
+	}
+	for _, opt := range opts {
+		trailer, ok := opt.(grpc.TrailerCallOption)
+		if ok {
+			*trailer.TrailerAddr = metadata.Pairs("grpc-retry-pushback-ms", "2000")
+		}
+	}
+	return status.Error(codes.ResourceExhausted, "VA is at capacity")
+}
+
+func (va *mockVAShedding) PerformValidation(_ context.Context, _ *vapb.PerformValidationRequest, opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+	err := va.shed(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &vapb.ValidationResult{
+		Records: []*corepb.ValidationRecord{{Hostname: "example.com", ResolverAddrs: []string{"rebound"}}},
+	}, nil
+}
+
+func (va *mockVAShedding) DoDCV(ctx context.Context, req *vapb.PerformValidationRequest, opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+	return va.PerformValidation(ctx, req, opts...)
+}
+
+func (va *mockVAShedding) IsCAAValid(_ context.Context, _ *vapb.IsCAAValidRequest, _ ...grpc.CallOption) (*vapb.IsCAAValidResponse, error) {
+	return &vapb.IsCAAValidResponse{}, nil
+}
+
+func (va *mockVAShedding) DoCAA(_ context.Context, _ *vapb.IsCAAValidRequest, _ ...grpc.CallOption) (*vapb.IsCAAValidResponse, error) {
+	return &vapb.IsCAAValidResponse{}, nil
+}
+
+func (va *mockVAShedding) CheckCAAMulti(_ context.Context, _ *vapb.CheckCAAMultiRequest, _ ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "CheckCAAMulti not implemented")
+}
+
+func TestPerformValidationVAOverloaded(t *testing.T) {
+	_, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	testCases := []struct {
+		name          string
+		sheds         int
+		expectCalls   int
+		expectRecord  bool
+		expectRetried int
+		expectGaveUp  int
+	}{
+		{
+			name:          "Shed once, then validated",
+			sheds:         1,
+			expectCalls:   2,
+			expectRecord:  true,
+			expectRetried: 1,
+		},
+		{
+			name:          "Shed every time",
+			sheds:         100,
+			expectCalls:   vaOverloadRetries + 1,
+			expectRetried: vaOverloadRetries,
+			expectGaveUp:  1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ra.vaOverloads.Reset()
+			mockVA := &mockVAShedding{sheds: tc.sheds}
+			ra.VA = va.RemoteClients{VAClient: mockVA, CAAClient: mockVA}
+			finalized := make(chan *sapb.FinalizeAuthorizationRequest, 1)
+			ra.SA = mockSAWithCapturedFinalize{StorageAuthorityClient: &mockSAForPrecheck{}, out: finalized}
+
+			expires := fc.Now().Add(12 * time.Hour)
+			authzPB, err := bgrpc.AuthzToPB(core.Authorization{
+				ID:             "1",
+				Identifier:     identifier.NewDNS(randomDomain()),
+				RegistrationID: Registration.Id,
+				Status:         core.StatusPending,
+				Expires:        &expires,
+				Challenges: []core.Challenge{
+					{Type: core.ChallengeTypeDNS01, Status: core.StatusPending, Token: core.NewToken()},
+				},
+			})
+			test.AssertNotError(t, err, "converting authz")
+
+			start := fc.Now()
+			_, err = ra.PerformValidation(ctx, &rapb.PerformValidationRequest{Authz: authzPB, ChallengeIndex: 0})
+			test.AssertNotError(t, err, "PerformValidation failed")
+			ra.drainWG.Wait()
+
+			test.AssertEquals(t, mockVA.calls, tc.expectCalls)
+			// Each retry waits for the VA's pushback hint.
+			test.AssertEquals(t, fc.Since(start), time.Duration(tc.expectRetried)*2*time.Second)
+			test.AssertMetricWithLabelsEquals(t, ra.vaOverloads, prometheus.Labels{"result": "retried"}, float64(tc.expectRetried))
+			test.AssertMetricWithLabelsEquals(t, ra.vaOverloads, prometheus.Labels{"result": "abandoned"}, float64(tc.expectGaveUp))
+			if tc.expectRecord {
+				req := <-finalized
+				test.AssertEquals(t, req.Status, string(core.StatusValid))
+			} else {
+				// The challenge is left pending, rather than failed.
+				test.AssertEquals(t, len(finalized), 0)
+			}
+		})
+	}
+}
+
+func TestPerformValidationStoresPerspectiveResults(t *testing.T) {
+	va, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	features.Set(features.Config{EnforceMPIC: true})
+	defer features.Reset()
+
+	finalized := make(chan *sapb.FinalizeAuthorizationRequest, 1)
+	ra.SA = mockSAWithCapturedFinalize{StorageAuthorityClient: &mockSAForPrecheck{}, out: finalized}
+
+	dcvResults := []*corepb.PerspectiveResult{
+		{Perspective: "Primary", Rir: "ARIN", Passed: true, LatencyMs: 12, Operation: "dcv"},
+		{Perspective: "dadaist", Rir: "ARIN", Passed: true, LatencyMs: 40, Operation: "dcv"},
+		{Perspective: "surrealist", Rir: "RIPE", Passed: false, ProblemType: string(probs.ConnectionProblem), LatencyMs: 250, Operation: "dcv"},
+		{Perspective: "cubist", Rir: "APNIC", Passed: true, LatencyMs: 90, Operation: "dcv"},
+	}
+	caaResults := []*corepb.PerspectiveResult{
+		{Perspective: "Primary", Rir: "ARIN", Passed: true, LatencyMs: 5, Operation: "caa"},
+		{Perspective: "dadaist", Rir: "ARIN", Passed: true, LatencyMs: 20, Operation: "caa"},
+	}
+	va.doDCVResult = &vapb.ValidationResult{
+		Records: []*corepb.ValidationRecord{
+			{Hostname: "example.com", ResolverAddrs: []string{"resolver:53"}},
+		},
+		PerspectiveResults: dcvResults,
+	}
+	va.doCAAResponse = &vapb.IsCAAValidResponse{PerspectiveResults: caaResults}
+
+	expires := fc.Now().Add(12 * time.Hour)
+	authzPB, err := bgrpc.AuthzToPB(core.Authorization{
+		ID:             "1",
+		Identifier:     identifier.NewDNS(randomDomain()),
+		RegistrationID: Registration.Id,
+		Status:         core.StatusPending,
+		Expires:        &expires,
+		Challenges: []core.Challenge{
+			{Type: core.ChallengeTypeDNS01, Status: core.StatusPending, Token: core.NewToken()},
+		},
+	})
+	test.AssertNotError(t, err, "converting authz")
+
+	_, err = ra.PerformValidation(ctx, &rapb.PerformValidationRequest{Authz: authzPB, ChallengeIndex: 0})
+	test.AssertNotError(t, err, "PerformValidation failed")
+	<-va.doDCVRequest
+	<-va.doCAARequest
+
+	var req *sapb.FinalizeAuthorizationRequest
+	select {
+	case req = <-finalized:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for FinalizeAuthorization2")
+	}
+	test.AssertEquals(t, req.Status, string(core.StatusValid))
+	// The CAA results follow the DCV results.
+	results := append(dcvResults, caaResults...)
+	test.AssertEquals(t, len(req.PerspectiveResults), len(results))
+	for i := range results {
+		test.AssertDeepEquals(t, bgrpc.PBToPerspectiveResult(req.PerspectiveResults[i]), bgrpc.PBToPerspectiveResult(results[i]))
+	}
+}
+
 // mockSAWithSyncPause is a mock sapb.StorageAuthorityClient that forwards all
 // method calls to an inner SA, but also performs a blocking write to a channel
 // when PauseIdentifiers is called to allow the tests to synchronize.
@@ -725,6 +1469,192 @@ func TestPerformValidation_FailedValidationsTriggerPauseIdentifiersRatelimit(t *
 	test.AssertEquals(t, paused.Identifiers[0].Value, domain)
 }
 
+func TestPerformValidation_RepeatedFailuresSpendFailedValidationsRatelimit(t *testing.T) {
+	va, sa, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	// Allow only two failed validations per hour.
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.FailedValidationsPerDomainPerAccount.String(): &ratelimits.LimitConfig{
+			Burst:  2,
+			Count:  2,
+			Period: config.Duration{Duration: time.Hour}},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	ra.txnBuilder = txnBuilder
+
+	domain := randomDomain()
+	authzPB := createPendingAuthorization(t, sa, domain, fc.Now().Add(12*time.Hour))
+	va.doDCVResult = &vapb.ValidationResult{
+		Records: []*corepb.ValidationRecord{
+			{
+				AddressUsed:   []byte("192.168.0.1"),
+				Hostname:      domain,
+				Port:          "8080",
+				Url:           fmt.Sprintf("http://%s/", domain),
+				ResolverAddrs: []string{"rebound"},
+			},
+		},
+		Problem: &corepb.ProblemDetails{
+			ProblemType: string(probs.ConnectionProblem),
+			Detail:      "connection refused",
+		},
+	}
+
+	// Each failed validation spends from the limit, even when the same
+	// authorization is validated again.
+	for range 2 {
+		_, err = ra.PerformValidation(ctx, &rapb.PerformValidationRequest{
+			Authz:          authzPB,
+			ChallengeIndex: dnsChallIdx(t, authzPB.Challenges),
+		})
+		test.AssertNotError(t, err, "PerformValidation failed")
+		<-va.doDCVRequest
+		ra.drainWG.Wait()
+	}
+
+	// Once the limit is exhausted, the challenge isn't sent to the VA.
+	_, err = ra.PerformValidation(ctx, &rapb.PerformValidationRequest{
+		Authz:          authzPB,
+		ChallengeIndex: dnsChallIdx(t, authzPB.Challenges),
+	})
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+	test.AssertContains(t, err.Error(), "too many failed validations (2)")
+	test.AssertEquals(t, len(va.doDCVRequest), 0)
+}
+
+// mockSAWithFailureCounted holds the failureCounted markers of authorizations,
+// setting them atomically as the SA does.
+type mockSAWithFailureCounted struct {
+	sapb.StorageAuthorityClient
+	mu      sync.Mutex
+	counted map[int64]bool
+}
+
+func (sa *mockSAWithFailureCounted) SetAuthorizationFailureCounted(_ context.Context, req *sapb.SetAuthorizationFailureCountedRequest, _ ...grpc.CallOption) (*sapb.SetAuthorizationFailureCountedResponse, error) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	changed := sa.counted[req.Id] != req.Counted
+	sa.counted[req.Id] = req.Counted
+	return &sapb.SetAuthorizationFailureCountedResponse{Changed: changed}, nil
+}
+
+func TestCountFailedAuthorizationOncePerAuthorization(t *testing.T) {
+	fc := clock.NewFake()
+	rl := ratelimits.NewInmemSource(fc)
+	limiter, err := ratelimits.NewLimiter(fc, rl, metrics.NoopRegisterer, blog.NewMock())
+	test.AssertNotError(t, err, "making limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.FailedAuthorizationsPerDomainPerAccount.String(): &ratelimits.LimitConfig{
+			Burst:  10,
+			Count:  10,
+			Period: config.Duration{Duration: time.Hour}},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	msa := &mockSAWithFailureCounted{counted: make(map[int64]bool)}
+	ra := &RegistrationAuthorityImpl{
+		SA:         msa,
+		clk:        fc,
+		log:        blog.NewMock(),
+		limiter:    limiter,
+		txnBuilder: txnBuilder,
+	}
+
+	domain := randomDomain()
+	ident := identifier.NewDNS(domain)
+	bucketKey, err := ratelimits.NewRegIdDomainBucketKey(ratelimits.FailedAuthorizationsPerDomainPerAccount, 1, domain)
+	test.AssertNotError(t, err, "constructing test bucket key")
+	// One spend against a limit of 10 per hour moves the TAT 6 minutes ahead.
+	oneSpend := fc.Now().Add(6 * time.Minute)
+
+	// Simulate a client retrying a challenge before its first failure was
+	// recorded, so that several validations of the authorization fail at
+	// once.
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := ra.countFailedAuthorization(ctx, "1", 1, ident)
+			test.AssertNotError(t, err, "counting failed authorization")
+		}()
+	}
+	wg.Wait()
+	tat, err := rl.Get(ctx, bucketKey)
+	test.AssertNotError(t, err, "getting bucket")
+	test.AssertEquals(t, tat, oneSpend)
+	test.Assert(t, msa.counted[1], "authorization should be marked as counted")
+
+	// Refunding the failure of an authorization which was reset and then
+	// validated clears the marker, and happens once.
+	ra.refundFailedAuthorization(ctx, "1", 1, ident)
+	ra.refundFailedAuthorization(ctx, "1", 1, ident)
+	tat, err = rl.Get(ctx, bucketKey)
+	test.AssertNotError(t, err, "getting bucket")
+	test.AssertEquals(t, tat, fc.Now())
+	test.Assert(t, !msa.counted[1], "authorization should no longer be marked as counted")
+
+	// Failures of another authorization for the same identifier count too.
+	err = ra.countFailedAuthorization(ctx, "2", 1, ident)
+	test.AssertNotError(t, err, "counting failed authorization")
+	tat, err = rl.Get(ctx, bucketKey)
+	test.AssertNotError(t, err, "getting bucket")
+	test.AssertEquals(t, tat, oneSpend)
+}
+
+// mockSAWithoutFailureCounted is an SA which predates the failureCounted
+// marker.
+type mockSAWithoutFailureCounted struct {
+	sapb.StorageAuthorityClient
+}
+
+func (sa *mockSAWithoutFailureCounted) SetAuthorizationFailureCounted(_ context.Context, _ *sapb.SetAuthorizationFailureCountedRequest, _ ...grpc.CallOption) (*sapb.SetAuthorizationFailureCountedResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "unknown method SetAuthorizationFailureCounted")
+}
+
+func TestCountFailedAuthorizationWithOlderSA(t *testing.T) {
+	fc := clock.NewFake()
+	rl := ratelimits.NewInmemSource(fc)
+	limiter, err := ratelimits.NewLimiter(fc, rl, metrics.NoopRegisterer, blog.NewMock())
+	test.AssertNotError(t, err, "making limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.FailedAuthorizationsPerDomainPerAccount.String(): &ratelimits.LimitConfig{
+			Burst:  10,
+			Count:  10,
+			Period: config.Duration{Duration: time.Hour}},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	log := blog.NewMock()
+	ra := &RegistrationAuthorityImpl{
+		SA:         &mockSAWithoutFailureCounted{},
+		clk:        fc,
+		log:        log,
+		limiter:    limiter,
+		txnBuilder: txnBuilder,
+	}
+
+	domain := randomDomain()
+	ident := identifier.NewDNS(domain)
+	bucketKey, err := ratelimits.NewRegIdDomainBucketKey(ratelimits.FailedAuthorizationsPerDomainPerAccount, 1, domain)
+	test.AssertNotError(t, err, "constructing test bucket key")
+
+	// Without the marker, every failure is counted.
+	err = ra.countFailedAuthorization(ctx, "1", 1, ident)
+	test.AssertNotError(t, err, "counting failed authorization")
+	tat, err := rl.Get(ctx, bucketKey)
+	test.AssertNotError(t, err, "getting bucket")
+	test.AssertEquals(t, tat, fc.Now().Add(6*time.Minute))
+
+	// Nothing is known to have been counted, so nothing is refunded.
+	ra.refundFailedAuthorization(ctx, "1", 1, ident)
+	tat, err = rl.Get(ctx, bucketKey)
+	test.AssertNotError(t, err, "getting bucket")
+	test.AssertEquals(t, tat, fc.Now().Add(6*time.Minute))
+
+	// An older SA is expected during a deploy, and isn't worth a warning.
+	test.AssertEquals(t, len(log.GetAllMatching("failure counted")), 0)
+}
+
 // mockRLSourceWithSyncDelete is a mock ratelimits.Source that forwards all
 // method calls to an inner Source, but also performs a blocking write to a
 // channel when Delete is called to allow the tests to synchronize.
@@ -752,7 +1682,7 @@ func TestPerformValidation_FailedThenSuccessfulValidationResetsPauseIdentifiersR
 	limiter, err := ratelimits.NewLimiter(fc, mockRLSourceWithSyncDelete{
 		Source: rl,
 		out:    keyChan,
-	}, metrics.NoopRegisterer)
+	}, metrics.NoopRegisterer, blog.NewMock())
 	test.AssertNotError(t, err, "creating mock limiter")
 	ra.limiter = limiter
 
@@ -883,6 +1813,7 @@ func TestCertificateKeyNotEqualAccountKey(t *testing.T) {
 		Csr: csrBytes,
 	})
 	test.AssertError(t, err, "Should have rejected cert with key = account key")
+	test.AssertErrorIs(t, err, berrors.BadCSR)
 	test.AssertEquals(t, err.Error(), "certificate public key must be different than account key")
 }
 
@@ -900,6 +1831,67 @@ func TestDeactivateAuthorization(t *testing.T) {
 	test.AssertEquals(t, deact.Status, string(core.StatusDeactivated))
 }
 
+// mockSAWithPendingOrders holds a set of pending orders which all include the
+// deactivated authorization, and records the errors set on them.
+type mockSAWithPendingOrders struct {
+	sapb.StorageAuthorityClient
+	pending []int64
+	errors  map[int64]*corepb.ProblemDetails
+}
+
+func (sa *mockSAWithPendingOrders) DeactivateAuthorization2(_ context.Context, _ *sapb.AuthorizationID2, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func (sa *mockSAWithPendingOrders) GetPendingOrdersForAuthorization(_ context.Context, req *sapb.GetPendingOrdersForAuthorizationRequest, _ ...grpc.CallOption) (*sapb.OrderIDs, error) {
+	var ids []int64
+	for _, id := range sa.pending {
+		if _, ok := sa.errors[id]; ok {
+			continue
+		}
+		if int64(len(ids)) == req.Limit {
+			break
+		}
+		ids = append(ids, id)
+	}
+	return &sapb.OrderIDs{Ids: ids}, nil
+}
+
+func (sa *mockSAWithPendingOrders) SetOrderError(_ context.Context, req *sapb.SetOrderErrorRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	sa.errors[req.Id] = req.Error
+	return &emptypb.Empty{}, nil
+}
+
+func TestDeactivateAuthorizationInvalidatesOrders(t *testing.T) {
+	msa := &mockSAWithPendingOrders{errors: make(map[int64]*corepb.ProblemDetails)}
+	for i := range maxOrdersInvalidatedPerDeactivation + 5 {
+		msa.pending = append(msa.pending, int64(i+1))
+	}
+	ra := &RegistrationAuthorityImpl{SA: msa, log: blog.NewMock()}
+
+	_, err := ra.DeactivateAuthorization(ctx, &corepb.Authorization{
+		Id:             "7",
+		RegistrationID: 1,
+		DnsName:        "example.com",
+		Status:         string(core.StatusValid),
+	})
+	test.AssertNotError(t, err, "DeactivateAuthorization failed")
+
+	// Only a bounded batch of orders is invalidated by the RA; the SA explains
+	// the status of the remainder when they are next fetched.
+	test.AssertEquals(t, len(msa.errors), maxOrdersInvalidatedPerDeactivation)
+	for _, id := range msa.pending[:maxOrdersInvalidatedPerDeactivation] {
+		prob, ok := msa.errors[id]
+		test.Assert(t, ok, fmt.Sprintf("order %d was not invalidated", id))
+		test.AssertEquals(t, prob.ProblemType, string(probs.UnauthorizedProblem))
+		test.AssertEquals(t, prob.Detail, `Authorization 7 for dns identifier "example.com" was deactivated`)
+	}
+	for _, id := range msa.pending[maxOrdersInvalidatedPerDeactivation:] {
+		_, ok := msa.errors[id]
+		test.Assert(t, !ok, fmt.Sprintf("order %d was invalidated beyond the batch size", id))
+	}
+}
+
 type mockSARecordingPauses struct {
 	sapb.StorageAuthorityClient
 	recv *sapb.PauseRequest
@@ -910,10 +1902,18 @@ func (sa *mockSARecordingPauses) PauseIdentifiers(ctx context.Context, req *sapb
 	return &sapb.PauseIdentifiersResponse{Paused: int64(len(req.Identifiers))}, nil
 }
 
+func (sa *mockSARecordingPauses) SetAuthorizationFailureCounted(_ context.Context, _ *sapb.SetAuthorizationFailureCountedRequest, _ ...grpc.CallOption) (*sapb.SetAuthorizationFailureCountedResponse, error) {
+	return &sapb.SetAuthorizationFailureCountedResponse{Changed: true}, nil
+}
+
 func (sa *mockSARecordingPauses) DeactivateAuthorization2(_ context.Context, _ *sapb.AuthorizationID2, _ ...grpc.CallOption) (*emptypb.Empty, error) {
 	return nil, nil
 }
 
+func (sa *mockSARecordingPauses) GetPendingOrdersForAuthorization(_ context.Context, _ *sapb.GetPendingOrdersForAuthorizationRequest, _ ...grpc.CallOption) (*sapb.OrderIDs, error) {
+	return &sapb.OrderIDs{}, nil
+}
+
 func TestDeactivateAuthorization_Pausing(t *testing.T) {
 	_, _, ra, _, _, cleanUp := initAuthorities(t)
 	defer cleanUp()
@@ -1017,11 +2017,17 @@ func (cr noopCAA) DoCAA(
 	return &vapb.IsCAAValidResponse{}, nil
 }
 
+func (cr noopCAA) CheckCAAMulti(_ context.Context, _ *vapb.CheckCAAMultiRequest, _ ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "CheckCAAMulti not implemented")
+}
+
 // caaRecorder implements vapb.CAAClient, always returning nil, but recording
 // the names it was called for.
 type caaRecorder struct {
 	sync.Mutex
-	names map[string]bool
+	names    map[string]bool
+	calls    int
+	rechecks int
 }
 
 func (cr *caaRecorder) IsCAAValid(
@@ -1032,6 +2038,10 @@ func (cr *caaRecorder) IsCAAValid(
 	cr.Lock()
 	defer cr.Unlock()
 	cr.names[in.Domain] = true
+	cr.calls++
+	if in.IsRecheck {
+		cr.rechecks++
+	}
 	return &vapb.IsCAAValidResponse{}, nil
 }
 
@@ -1043,9 +2053,17 @@ func (cr *caaRecorder) DoCAA(
 	cr.Lock()
 	defer cr.Unlock()
 	cr.names[in.Domain] = true
+	cr.calls++
+	if in.IsRecheck {
+		cr.rechecks++
+	}
 	return &vapb.IsCAAValidResponse{}, nil
 }
 
+func (cr *caaRecorder) CheckCAAMulti(_ context.Context, _ *vapb.CheckCAAMultiRequest, _ ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "CheckCAAMulti not implemented")
+}
+
 // Test that the right set of domain names have their CAA rechecked, based on
 // their `Validated` (attemptedAt in the database) timestamp.
 func TestRecheckCAADates(t *testing.T) {
@@ -1213,6 +2231,9 @@ func TestRecheckCAADates(t *testing.T) {
 	if _, present := recorder.names["*.wildcard.com"]; !present {
 		t.Errorf("Failed to recheck CAA for *.wildcard.com")
 	}
+
+	// Every check is marked as a recheck, for the VA.
+	test.AssertEquals(t, recorder.rechecks, recorder.calls)
 }
 
 type caaFailer struct{}
@@ -1259,6 +2280,10 @@ func (cf *caaFailer) DoCAA(
 	return cvrpb, nil
 }
 
+func (cf *caaFailer) CheckCAAMulti(_ context.Context, _ *vapb.CheckCAAMultiRequest, _ ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "CheckCAAMulti not implemented")
+}
+
 func TestRecheckCAAEmpty(t *testing.T) {
 	_, _, ra, _, _, cleanUp := initAuthorities(t)
 	defer cleanUp()
@@ -1359,18 +2384,26 @@ func TestNewOrder(t *testing.T) {
 	orderA, err := ra.NewOrder(context.Background(), &rapb.NewOrderRequest{
 		RegistrationID:         Registration.Id,
 		CertificateProfileName: "test",
-		DnsNames:               []string{"b.com", "a.com", "a.com", "C.COM"},
+		DnsNames:               []string{"b.com", "a.com", "C.COM"},
 	})
 	test.AssertNotError(t, err, "ra.NewOrder failed")
 	test.AssertEquals(t, orderA.RegistrationID, int64(1))
 	test.AssertEquals(t, orderA.Expires.AsTime(), now.Add(ra.orderLifetime))
 	test.AssertEquals(t, len(orderA.DnsNames), 3)
 	test.AssertEquals(t, orderA.CertificateProfileName, "test")
-	// We expect the order names to have been sorted, deduped, and lowercased
+	// We expect the order names to have been sorted and lowercased
 	test.AssertDeepEquals(t, orderA.DnsNames, []string{"a.com", "b.com", "c.com"})
 	test.AssertEquals(t, orderA.Id, int64(1))
 	test.AssertEquals(t, numAuthorizations(orderA), 3)
 
+	_, err = ra.NewOrder(context.Background(), &rapb.NewOrderRequest{
+		RegistrationID: Registration.Id,
+		DnsNames:       []string{"b.com", "a.com", "A.com"},
+	})
+	test.AssertError(t, err, "NewOrder with duplicate names did not error")
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	test.AssertContains(t, err.Error(), `"a.com" (requested as "a.com", "A.com")`)
+
 	_, err = ra.NewOrder(context.Background(), &rapb.NewOrderRequest{
 		RegistrationID: Registration.Id,
 		DnsNames:       []string{"a"},
@@ -1399,7 +2432,7 @@ func TestNewOrder_OrderReusex(t *testing.T) {
 	// Create a second registration to reference
 	acctKeyB, err := AccountKeyB.MarshalJSON()
 	test.AssertNotError(t, err, "failed to marshal account key")
-	input := &corepb.Registration{Key: acctKeyB}
+	input := &rapb.NewRegistrationRequest{Key: acctKeyB}
 	secondReg, err := ra.NewRegistration(context.Background(), input)
 	test.AssertNotError(t, err, "Error creating a second test registration")
 
@@ -1582,7 +2615,7 @@ func TestNewOrder_AuthzReuse(t *testing.T) {
 	// Create a second registration to reference later.
 	acctKeyB, err := AccountKeyB.MarshalJSON()
 	test.AssertNotError(t, err, "failed to marshal account key")
-	input := &corepb.Registration{Key: acctKeyB}
+	input := &rapb.NewRegistrationRequest{Key: acctKeyB}
 	secondReg, err := ra.NewRegistration(context.Background(), input)
 	test.AssertNotError(t, err, "Error creating a second test registration")
 
@@ -1667,6 +2700,126 @@ func TestNewOrder_AuthzReuse_NoPending(t *testing.T) {
 	test.AssertNotEquals(t, new.V2Authorizations[0], extant.V2Authorizations[0])
 }
 
+// mockSAWithPendingAuthzs holds an account's pending authorizations, soonest
+// expiring first, and records which are deactivated.
+type mockSAWithPendingAuthzs struct {
+	sapb.StorageAuthorityClient
+	pending     []int64
+	deactivated []int64
+	// failDeactivate, if non-zero, is an authorization which can't be
+	// deactivated.
+	failDeactivate int64
+}
+
+func (sa *mockSAWithPendingAuthzs) CountPendingAuthorizations2(_ context.Context, _ *sapb.RegistrationID, _ ...grpc.CallOption) (*sapb.Count, error) {
+	return &sapb.Count{Count: int64(len(sa.pending))}, nil
+}
+
+func (sa *mockSAWithPendingAuthzs) GetPendingAuthorizationsByExpiry(_ context.Context, req *sapb.GetPendingAuthorizationsByExpiryRequest, _ ...grpc.CallOption) (*sapb.Authorizations, error) {
+	var authzs []*corepb.Authorization
+	for i, id := range sa.pending {
+		if int64(len(authzs)) == req.Limit {
+			break
+		}
+		authzs = append(authzs, &corepb.Authorization{
+			Id:      fmt.Sprint(id),
+			Status:  string(core.StatusPending),
+			Expires: timestamppb.New(time.Unix(0, 0).Add(time.Duration(i+1) * time.Hour)),
+		})
+	}
+	return &sapb.Authorizations{Authzs: authzs}, nil
+}
+
+func (sa *mockSAWithPendingAuthzs) DeactivateAuthorization2(_ context.Context, req *sapb.AuthorizationID2, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	if req.Id == sa.failDeactivate {
+		return nil, errors.New("oops")
+	}
+	sa.pending = slices.DeleteFunc(sa.pending, func(id int64) bool { return id == req.Id })
+	sa.deactivated = append(sa.deactivated, req.Id)
+	return &emptypb.Empty{}, nil
+}
+
+func TestPendingAuthzsToEvict(t *testing.T) {
+	testCases := []struct {
+		name          string
+		max           int
+		pending       int
+		reused        []int64
+		newAuthzs     int
+		expectEvicted []int64
+		expectErr     bool
+	}{
+		{"no cap", 0, 10, nil, 5, nil, false},
+		{"no new authzs", 10, 20, nil, 0, nil, false},
+		{"under cap", 10, 5, nil, 5, nil, false},
+		{"prune to fit", 10, 10, nil, 3, []int64{1, 2, 3}, false},
+		{"prune around reused", 10, 10, []int64{1, 3}, 2, []int64{2, 4}, false},
+		{"only reused to prune", 3, 3, []int64{1, 2, 3}, 1, nil, true},
+		{"too many to prune", 10, maxPendingAuthzPrunesPerOrder + 10, nil, 1, nil, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msa := &mockSAWithPendingAuthzs{}
+			for i := range tc.pending {
+				msa.pending = append(msa.pending, int64(i+1))
+			}
+			fc := clock.NewFake()
+			fc.Set(time.Unix(0, 0))
+			ra := &RegistrationAuthorityImpl{
+				SA:                                 msa,
+				MaxPendingAuthorizationsPerAccount: tc.max,
+				clk:                                fc,
+				log:                                blog.NewMock(),
+				pendingAuthorizationLifetime:       7 * 24 * time.Hour,
+				pendingAuthzCap: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "pending_authorization_cap",
+				}, []string{"action"}),
+			}
+
+			evictions, err := ra.pendingAuthzsToEvict(ctx, 1, tc.reused, tc.newAuthzs)
+			// Nothing is deactivated until the order has been stored.
+			test.AssertEquals(t, len(msa.deactivated), 0)
+			if !tc.expectErr {
+				test.AssertNotError(t, err, "choosing pending authzs to evict")
+				test.AssertDeepEquals(t, evictions, tc.expectEvicted)
+				ra.evictPendingAuthzs(ctx, 1, evictions)
+				test.AssertDeepEquals(t, msa.deactivated, tc.expectEvicted)
+				test.AssertMetricWithLabelsEquals(t, ra.pendingAuthzCap, prometheus.Labels{"action": "pruned"}, float64(len(tc.expectEvicted)))
+				return
+			}
+			test.AssertErrorIs(t, err, berrors.RateLimit)
+			test.AssertMetricWithLabelsEquals(t, ra.pendingAuthzCap, prometheus.Labels{"action": "rejected"}, 1)
+			var berr *berrors.BoulderError
+			test.AssertErrorWraps(t, err, &berr)
+			if len(tc.reused) > 0 {
+				// The soonest pending authorization expires in an hour.
+				test.AssertEquals(t, berr.RetryAfter, time.Hour)
+			} else {
+				test.AssertEquals(t, berr.RetryAfter, ra.pendingAuthorizationLifetime)
+			}
+		})
+	}
+}
+
+func TestEvictPendingAuthzsContinuesAfterFailure(t *testing.T) {
+	msa := &mockSAWithPendingAuthzs{pending: []int64{1, 2, 3}, failDeactivate: 2}
+	log := blog.NewMock()
+	ra := &RegistrationAuthorityImpl{
+		SA:  msa,
+		log: log,
+		pendingAuthzCap: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pending_authorization_cap",
+		}, []string{"action"}),
+	}
+
+	// The order has already been stored, so a failure to deactivate one
+	// authorization is logged rather than returned.
+	ra.evictPendingAuthzs(ctx, 1, []int64{1, 2, 3})
+	test.AssertDeepEquals(t, msa.deactivated, []int64{1, 3})
+	test.AssertMetricWithLabelsEquals(t, ra.pendingAuthzCap, prometheus.Labels{"action": "pruned"}, 2)
+	test.AssertEquals(t, len(log.GetAllMatching("deactivating pending authorization 2 of account 1")), 1)
+}
+
 func TestNewOrder_ProfileSelectionAllowList(t *testing.T) {
 	_, _, ra, _, _, cleanUp := initAuthorities(t)
 	defer cleanUp()
@@ -1685,14 +2838,14 @@ func TestNewOrder_ProfileSelectionAllowList(t *testing.T) {
 		{
 			name: "Allow all account IDs for this specific profile",
 			validationProfiles: map[string]*ValidationProfile{
-				"test": NewValidationProfile(nil),
+				"test": NewValidationProfile(nil, ValidationProfileConfig{}),
 			},
 			expectErr: false,
 		},
 		{
 			name: "Deny all but account Id 1337",
 			validationProfiles: map[string]*ValidationProfile{
-				"test": NewValidationProfile(allowlist.NewList([]int64{1337})),
+				"test": NewValidationProfile(allowlist.NewList([]int64{1337}), ValidationProfileConfig{}),
 			},
 			expectErr:         true,
 			expectErrContains: "not permitted to use certificate profile",
@@ -1700,7 +2853,7 @@ func TestNewOrder_ProfileSelectionAllowList(t *testing.T) {
 		{
 			name: "Deny all",
 			validationProfiles: map[string]*ValidationProfile{
-				"test": NewValidationProfile(allowlist.NewList([]int64{})),
+				"test": NewValidationProfile(allowlist.NewList([]int64{}), ValidationProfileConfig{}),
 			},
 			expectErr:         true,
 			expectErrContains: "not permitted to use certificate profile",
@@ -1708,7 +2861,7 @@ func TestNewOrder_ProfileSelectionAllowList(t *testing.T) {
 		{
 			name: "Allow Registration.Id",
 			validationProfiles: map[string]*ValidationProfile{
-				"test": NewValidationProfile(allowlist.NewList([]int64{Registration.Id})),
+				"test": NewValidationProfile(allowlist.NewList([]int64{Registration.Id}), ValidationProfileConfig{}),
 			},
 			expectErr: false,
 		},
@@ -1735,6 +2888,53 @@ func TestNewOrder_ProfileSelectionAllowList(t *testing.T) {
 	}
 }
 
+func TestGetIssuanceProfiles(t *testing.T) {
+	t.Parallel()
+
+	ra := &RegistrationAuthorityImpl{}
+	resp, err := ra.GetIssuanceProfiles(context.Background(), &emptypb.Empty{})
+	test.AssertNotError(t, err, "GetIssuanceProfiles failed")
+	test.AssertEquals(t, len(resp.Profiles), 0)
+
+	ra.SetValidationProfiles(map[string]*ValidationProfile{
+		"shortlived": NewValidationProfile(nil, ValidationProfileConfig{
+			Validity:        160 * time.Hour,
+			IdentifierTypes: []identifier.IdentifierType{identifier.TypeDNS, identifier.TypeIP},
+		}),
+		"classic": NewValidationProfile(allowlist.NewList([]int64{1337}), ValidationProfileConfig{
+			MustStaplePolicy: MustStapleStrip,
+			Validity:         90 * 24 * time.Hour,
+			IdentifierTypes:  []identifier.IdentifierType{identifier.TypeDNS},
+		}),
+		"bare": NewValidationProfile(nil, ValidationProfileConfig{}),
+	}, "classic")
+	resp, err = ra.GetIssuanceProfiles(context.Background(), &emptypb.Empty{})
+	test.AssertNotError(t, err, "GetIssuanceProfiles failed")
+	test.AssertDeepEquals(t, resp, &rapb.GetIssuanceProfilesResponse{
+		Profiles: []*rapb.IssuanceProfile{
+			{Name: "bare"},
+			{Name: "classic", Validity: durationpb.New(90 * 24 * time.Hour), IdentifierTypes: []string{"dns"}, Default: true},
+			{Name: "shortlived", Validity: durationpb.New(160 * time.Hour), IdentifierTypes: []string{"dns", "ip"}},
+		},
+	})
+
+	// Replacing the profiles, as a config reload does, removes those which are
+	// no longer configured.
+	ra.SetValidationProfiles(map[string]*ValidationProfile{
+		"shortlived": NewValidationProfile(nil, ValidationProfileConfig{
+			Validity:        160 * time.Hour,
+			IdentifierTypes: []identifier.IdentifierType{identifier.TypeDNS},
+		}),
+	}, "shortlived")
+	resp, err = ra.GetIssuanceProfiles(context.Background(), &emptypb.Empty{})
+	test.AssertNotError(t, err, "GetIssuanceProfiles failed")
+	test.AssertDeepEquals(t, resp, &rapb.GetIssuanceProfilesResponse{
+		Profiles: []*rapb.IssuanceProfile{
+			{Name: "shortlived", Validity: durationpb.New(160 * time.Hour), IdentifierTypes: []string{"dns"}, Default: true},
+		},
+	})
+}
+
 // mockSAWithAuthzs has a GetAuthorizations2 method that returns the protobuf
 // version of its authzs struct member. It also has a fake GetOrderForNames
 // which always fails, and a fake NewOrderAndAuthzs which always succeeds, to
@@ -1774,36 +2974,269 @@ func (msa *mockSAWithAuthzs) GetAuthorizations2(ctx context.Context, req *sapb.G
 	})
 }
 
+func (msa *mockSAWithAuthzs) GetValidOrderAuthorizations2(ctx context.Context, req *sapb.GetValidOrderAuthorizationsRequest, _ ...grpc.CallOption) (*sapb.Authorizations, error) {
+	return msa.GetValidAuthorizations2(ctx, &sapb.GetValidAuthorizationsRequest{RegistrationID: req.AcctID})
+}
+
 func (msa *mockSAWithAuthzs) GetAuthorization2(ctx context.Context, req *sapb.AuthorizationID2, _ ...grpc.CallOption) (*corepb.Authorization, error) {
 	for _, authz := range msa.authzs {
 		if authz.ID == fmt.Sprintf("%d", req.Id) {
 			return bgrpc.AuthzToPB(*authz)
 		}
 	}
-	return nil, berrors.NotFoundError("no such authz")
-}
+	return nil, berrors.NotFoundError("no such authz")
+}
+
+// NewOrderAndAuthzs is a mock which just reflects the incoming request back,
+// pretending to have created new db rows for the requested newAuthzs.
+func (msa *mockSAWithAuthzs) NewOrderAndAuthzs(ctx context.Context, req *sapb.NewOrderAndAuthzsRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
+	authzIDs := req.NewOrder.V2Authorizations
+	for range req.NewAuthzs {
+		authzIDs = append(authzIDs, mrand.Int64())
+	}
+	return &corepb.Order{
+		// Fields from the input new order request.
+		RegistrationID:         req.NewOrder.RegistrationID,
+		Expires:                req.NewOrder.Expires,
+		DnsNames:               req.NewOrder.DnsNames,
+		V2Authorizations:       authzIDs,
+		CertificateProfileName: req.NewOrder.CertificateProfileName,
+		FinalizeBy:             req.NewOrder.FinalizeBy,
+		// Mock new fields generated by the database transaction.
+		Id:      mrand.Int64(),
+		Created: timestamppb.Now(),
+		// A new order is never processing because it can't have been finalized yet.
+		BeganProcessing: false,
+		Status:          string(core.StatusPending),
+	}, nil
+}
+
+// mockSAFailsNewOrder is a mockSAWithAuthzs whose NewOrderAndAuthzs always
+// fails, as though the order couldn't be written.
+type mockSAFailsNewOrder struct {
+	mockSAWithAuthzs
+}
+
+func (msa *mockSAFailsNewOrder) NewOrderAndAuthzs(ctx context.Context, req *sapb.NewOrderAndAuthzsRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
+	return nil, errors.New("database unavailable")
+}
+
+// mockSAReusesOrder is a mockSAWithAuthzs whose GetOrderForNames always
+// returns an existing order, so that NewOrder reuses it.
+type mockSAReusesOrder struct {
+	mockSAWithAuthzs
+	expires time.Time
+}
+
+func (msa *mockSAReusesOrder) GetOrderForNames(ctx context.Context, req *sapb.GetOrderForNamesRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
+	return &corepb.Order{
+		Id:               1,
+		RegistrationID:   req.AcctID,
+		Status:           string(core.StatusPending),
+		DnsNames:         req.DnsNames,
+		V2Authorizations: []int64{1},
+		Created:          timestamppb.New(msa.expires.Add(-7 * 24 * time.Hour)),
+		Expires:          timestamppb.New(msa.expires),
+	}, nil
+}
+
+// mockRLSourceFailingReservations is a mock ratelimits.Source that forwards
+// all method calls to an inner Source, but fails Commit and Release as
+// configured. If commitLost is set, Commit is applied by the inner Source
+// before failing, as though only its response was lost.
+type mockRLSourceFailingReservations struct {
+	ratelimits.Source
+	failCommit   bool
+	commitLost   bool
+	failReleases int
+}
+
+func (rl *mockRLSourceFailingReservations) Commit(ctx context.Context, token string, bucketKeys []string, now time.Time) error {
+	if rl.commitLost {
+		err := rl.Source.Commit(ctx, token, bucketKeys, now)
+		if err != nil {
+			return err
+		}
+		return errors.New("connection reset")
+	}
+	if rl.failCommit {
+		return errors.New("connection refused")
+	}
+	return rl.Source.Commit(ctx, token, bucketKeys, now)
+}
+
+func (rl *mockRLSourceFailingReservations) Release(ctx context.Context, token string, bucketKeys []string, now time.Time) error {
+	if rl.failReleases > 0 {
+		rl.failReleases--
+		return errors.New("connection refused")
+	}
+	return rl.Source.Release(ctx, token, bucketKeys, now)
+}
+
+func TestNewOrderLimitReservation(t *testing.T) {
+	ctx := context.Background()
+	names := []string{"reserved.example.com"}
+
+	testCases := []struct {
+		name   string
+		source *mockRLSourceFailingReservations
+		// failWrite makes the SA fail to create the order.
+		failWrite bool
+		// reuseOrder makes the SA return an existing order to reuse.
+		reuseOrder bool
+		// expireFirst lets the reservation expire before NewOrder runs.
+		expireFirst bool
+		// expectSpent is whether the order is still counted once every
+		// reconciliation is done.
+		expectSpent     bool
+		expectReconcile string
+		expectAuditLog  string
+	}{
+		{
+			name:        "order created and committed",
+			source:      &mockRLSourceFailingReservations{},
+			expectSpent: true,
+		},
+		{
+			name:      "SA write fails",
+			source:    &mockRLSourceFailingReservations{},
+			failWrite: true,
+		},
+		{
+			name:       "existing order reused",
+			source:     &mockRLSourceFailingReservations{},
+			reuseOrder: true,
+		},
+		{
+			name:            "reservation expires before commit",
+			source:          &mockRLSourceFailingReservations{},
+			expireFirst:     true,
+			expectReconcile: "expired",
+			expectAuditLog:  "reservation expired before it was committed",
+		},
+		{
+			name:            "commit fails",
+			source:          &mockRLSourceFailingReservations{failCommit: true, failReleases: 2},
+			expectReconcile: "released",
+			expectAuditLog:  "committing reservation failed, reconciling: connection refused",
+		},
+		{
+			name:            "commit applied but its response lost",
+			source:          &mockRLSourceFailingReservations{commitLost: true},
+			expectSpent:     true,
+			expectReconcile: "already_resolved",
+			expectAuditLog:  "committing reservation failed, reconciling: connection reset",
+		},
+		{
+			name:            "commit and reconciliation fail",
+			source:          &mockRLSourceFailingReservations{failCommit: true, failReleases: newOrderReconcileAttempts},
+			expectReconcile: "failed",
+			expectAuditLog:  "reconciliation failed",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, ra, _, fc, cleanUp := initAuthorities(t)
+			defer cleanUp()
+			mockLog := ra.log.(*blog.Mock)
+			mockLog.Clear()
+			ra.newOrderReconciliations.Reset()
+
+			// Allow three new orders per account per hour.
+			txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+				ratelimits.NewOrdersPerAccount.String(): &ratelimits.LimitConfig{
+					Burst:  3,
+					Count:  3,
+					Period: config.Duration{Duration: time.Hour}},
+			})
+			test.AssertNotError(t, err, "making transaction builder")
+			tc.source.Source = ratelimits.NewInmemSource(fc)
+			limiter, err := ratelimits.NewLimiter(fc, tc.source, metrics.NoopRegisterer, blog.NewMock())
+			test.AssertNotError(t, err, "making limiter")
+			ra.limiter = limiter
+			// probe shares the source, but not its failures.
+			probe, err := ratelimits.NewLimiter(fc, tc.source.Source, metrics.NoopRegisterer, blog.NewMock())
+			test.AssertNotError(t, err, "making probe limiter")
+
+			txns, err := txnBuilder.NewOrderLimitTransactions(Registration.Id, ratelimits.AccountAgeUnknown, names, false)
+			test.AssertNotError(t, err, "building new order transactions")
+
+			// available reports how many more new orders the account may
+			// create, by reserving one more and releasing it again.
+			available := func() int64 {
+				t.Helper()
+				token, d, err := probe.Reserve(ctx, txns)
+				test.AssertNotError(t, err, "probing new order limits")
+				if token == "" {
+					return 0
+				}
+				test.AssertNotError(t, probe.Release(ctx, token), "releasing probe")
+				return d.Results()[0].Remaining + 1
+			}
 
-// NewOrderAndAuthzs is a mock which just reflects the incoming request back,
-// pretending to have created new db rows for the requested newAuthzs.
-func (msa *mockSAWithAuthzs) NewOrderAndAuthzs(ctx context.Context, req *sapb.NewOrderAndAuthzsRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
-	authzIDs := req.NewOrder.V2Authorizations
-	for range req.NewAuthzs {
-		authzIDs = append(authzIDs, mrand.Int64())
+			// Reserve the limits as the WFE does before calling NewOrder.
+			token, d, err := ra.limiter.Reserve(ctx, txns)
+			test.AssertNotError(t, err, "reserving new order limits")
+			test.AssertNotError(t, d.Result(fc.Now()), "new order limits should allow the order")
+			test.AssertEquals(t, available(), int64(2))
+			if tc.expireFirst {
+				fc.Add(2 * time.Minute)
+			}
+
+			ra.SA = &mockSAWithAuthzs{}
+			if tc.failWrite {
+				ra.SA = &mockSAFailsNewOrder{}
+			}
+			if tc.reuseOrder {
+				ra.SA = &mockSAReusesOrder{expires: fc.Now().Add(7 * 24 * time.Hour)}
+			}
+			_, err = ra.NewOrder(ctx, &rapb.NewOrderRequest{
+				RegistrationID:   Registration.Id,
+				DnsNames:         names,
+				ReservationToken: token,
+			})
+			if tc.failWrite {
+				test.AssertError(t, err, "NewOrder should have failed")
+			} else {
+				test.AssertNotError(t, err, "NewOrder failed")
+			}
+			ra.Drain()
+
+			// Like the WFE, release the reservation if NewOrder failed. By
+			// now it must have been resolved one way or another, so the
+			// order can't be refunded twice.
+			if tc.failWrite {
+				err = ra.limiter.Release(ctx, token)
+				test.AssertErrorIs(t, err, ratelimits.ErrReservationNotFound)
+			}
+
+			if tc.expectReconcile == "failed" {
+				// The reservation is still held, but is released once it
+				// expires.
+				test.AssertEquals(t, available(), int64(2))
+				fc.Add(2 * time.Minute)
+			}
+			expectAvailable := int64(3)
+			if tc.expectSpent {
+				expectAvailable = 2
+			}
+			test.AssertEquals(t, available(), expectAvailable)
+
+			for _, result := range []string{"expired", "released", "already_resolved", "failed"} {
+				expect := 0
+				if result == tc.expectReconcile {
+					expect = 1
+				}
+				test.AssertMetricWithLabelsEquals(t, ra.newOrderReconciliations, prometheus.Labels{"result": result}, float64(expect))
+			}
+			discrepancies := mockLog.GetAllMatching("New order rate limit")
+			if tc.expectAuditLog == "" {
+				test.AssertEquals(t, len(discrepancies), 0)
+			} else {
+				test.AssertEquals(t, len(mockLog.GetAllMatching(tc.expectAuditLog)), 1)
+			}
+		})
 	}
-	return &corepb.Order{
-		// Fields from the input new order request.
-		RegistrationID:         req.NewOrder.RegistrationID,
-		Expires:                req.NewOrder.Expires,
-		DnsNames:               req.NewOrder.DnsNames,
-		V2Authorizations:       authzIDs,
-		CertificateProfileName: req.NewOrder.CertificateProfileName,
-		// Mock new fields generated by the database transaction.
-		Id:      mrand.Int64(),
-		Created: timestamppb.Now(),
-		// A new order is never processing because it can't have been finalized yet.
-		BeganProcessing: false,
-		Status:          string(core.StatusPending),
-	}, nil
 }
 
 // TestNewOrderAuthzReuseSafety checks that the RA's safety check for reusing an
@@ -2120,6 +3553,270 @@ func TestNewOrderExpiry(t *testing.T) {
 	test.AssertEquals(t, order.Expires.AsTime(), expectedOrderExpiry)
 }
 
+// mockSAWithAuthzsValidUntil is a mockSAWithAuthzs which, like the real SA,
+// only returns authorizations which expire after the requested ValidUntil.
+type mockSAWithAuthzsValidUntil struct {
+	mockSAWithAuthzs
+}
+
+func (msa *mockSAWithAuthzsValidUntil) GetValidAuthorizations2(ctx context.Context, req *sapb.GetValidAuthorizationsRequest, _ ...grpc.CallOption) (*sapb.Authorizations, error) {
+	resp := &sapb.Authorizations{}
+	for _, v := range msa.authzs {
+		if req.ValidUntil != nil && !v.Expires.After(req.ValidUntil.AsTime()) {
+			continue
+		}
+		authzPB, err := bgrpc.AuthzToPB(*v)
+		if err != nil {
+			return nil, err
+		}
+		resp.Authzs = append(resp.Authzs, authzPB)
+	}
+	return resp, nil
+}
+
+func (msa *mockSAWithAuthzsValidUntil) GetAuthorizations2(ctx context.Context, req *sapb.GetAuthorizationsRequest, _ ...grpc.CallOption) (*sapb.Authorizations, error) {
+	return msa.GetValidAuthorizations2(ctx, &sapb.GetValidAuthorizationsRequest{
+		RegistrationID: req.RegistrationID,
+		DnsNames:       req.DnsNames,
+		ValidUntil:     req.ValidUntil,
+	})
+}
+
+// TestNewOrderAuthzReuseLifetime checks that NewOrder only reuses an existing
+// authorization if its remaining lifetime covers the new order's lifetime.
+func TestNewOrderAuthzReuseLifetime(t *testing.T) {
+	_, _, ra, _, clk, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	ra.orderLifetime = 48 * time.Hour
+	orderReq := &rapb.NewOrderRequest{
+		RegistrationID: Registration.Id,
+		DnsNames:       []string{"zombo.com"},
+	}
+
+	testCases := []struct {
+		name          string
+		authzExpires  time.Time
+		expectReuse   bool
+		expectExpires time.Time
+	}{
+		{
+			name:          "authz expires before the order would",
+			authzExpires:  clk.Now().Add(35 * time.Hour),
+			expectReuse:   false,
+			expectExpires: clk.Now().Add(ra.orderLifetime),
+		},
+		{
+			name:          "authz outlives the order",
+			authzExpires:  clk.Now().Add(72 * time.Hour),
+			expectReuse:   true,
+			expectExpires: clk.Now().Add(ra.orderLifetime),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ra.SA = &mockSAWithAuthzsValidUntil{mockSAWithAuthzs{
+				authzs: []*core.Authorization{
+					{
+						ID:             "1",
+						Identifier:     identifier.NewDNS("zombo.com"),
+						RegistrationID: Registration.Id,
+						Expires:        &tc.authzExpires,
+						Status:         core.StatusValid,
+						Challenges: []core.Challenge{
+							{
+								Type:   core.ChallengeTypeHTTP01,
+								Status: core.StatusValid,
+								Token:  core.NewToken(),
+							},
+						},
+					},
+				},
+			}}
+
+			order, err := ra.NewOrder(context.Background(), orderReq)
+			test.AssertNotError(t, err, "NewOrder failed")
+			test.AssertEquals(t, numAuthorizations(order), 1)
+			test.AssertEquals(t, order.V2Authorizations[0] == 1, tc.expectReuse)
+			test.AssertEquals(t, order.Expires.AsTime(), tc.expectExpires)
+		})
+	}
+}
+
+func TestNewOrderReadyBy(t *testing.T) {
+	_, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	ctx := context.Background()
+	validated := fc.Now().Add(-1 * time.Hour)
+	authzExpires := fc.Now().Add(48 * time.Hour)
+	ra.SA = &mockSAWithAuthzs{
+		authzs: []*core.Authorization{
+			{
+				ID:             "1",
+				Identifier:     identifier.NewDNS("zombo.com"),
+				RegistrationID: Registration.Id,
+				Expires:        &authzExpires,
+				Status:         core.StatusValid,
+				Challenges: []core.Challenge{
+					{
+						Type:      core.ChallengeTypeHTTP01,
+						Status:    core.StatusValid,
+						Token:     core.NewToken(),
+						Validated: &validated,
+					},
+				},
+			},
+		},
+	}
+
+	// A readyBy in the past is rejected.
+	_, err := ra.NewOrder(ctx, &rapb.NewOrderRequest{
+		RegistrationID: Registration.Id,
+		DnsNames:       []string{"zombo.com"},
+		ReadyBy:        timestamppb.New(fc.Now().Add(-time.Minute)),
+	})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	test.AssertContains(t, err.Error(), "is not in the future")
+
+	// A readyBy after the order would expire is rejected.
+	_, err = ra.NewOrder(ctx, &rapb.NewOrderRequest{
+		RegistrationID: Registration.Id,
+		DnsNames:       []string{"zombo.com"},
+		ReadyBy:        timestamppb.New(authzExpires.Add(time.Hour)),
+	})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	test.AssertContains(t, err.Error(), "before readyBy")
+
+	// An order which reuses only valid authorizations can be finalized without
+	// a CAA recheck until 7 hours after the oldest of them was validated.
+	order, err := ra.NewOrder(ctx, &rapb.NewOrderRequest{
+		RegistrationID: Registration.Id,
+		DnsNames:       []string{"zombo.com"},
+		ReadyBy:        timestamppb.New(fc.Now().Add(24 * time.Hour)),
+	})
+	test.AssertNotError(t, err, "NewOrder with readyBy failed")
+	test.AssertEquals(t, order.FinalizeBy.AsTime(), validated.Add(7*time.Hour))
+
+	// An order with a new pending authorization has no finalizeBy yet.
+	order, err = ra.NewOrder(ctx, &rapb.NewOrderRequest{
+		RegistrationID: Registration.Id,
+		DnsNames:       []string{"zombo.com", "www.zombo.com"},
+	})
+	test.AssertNotError(t, err, "NewOrder with a pending authz failed")
+	test.Assert(t, order.FinalizeBy == nil, "order with a pending authz should have no finalizeBy")
+}
+
+// mockSAStoresOrders is a mockSAForPrecheck which stores the orders it
+// creates, as ready, and returns them from GetOrder.
+type mockSAStoresOrders struct {
+	mockSAForPrecheck
+	orders map[int64]*corepb.Order
+}
+
+func (msa *mockSAStoresOrders) NewOrderAndAuthzs(ctx context.Context, req *sapb.NewOrderAndAuthzsRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
+	order, err := msa.mockSAForPrecheck.NewOrderAndAuthzs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	order.Status = string(core.StatusReady)
+	msa.orders[order.Id] = order
+	return order, nil
+}
+
+func (msa *mockSAStoresOrders) GetOrder(_ context.Context, req *sapb.OrderRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
+	order, ok := msa.orders[req.Id]
+	if !ok {
+		return nil, berrors.NotFoundError("no order with ID %d", req.Id)
+	}
+	return proto.Clone(order).(*corepb.Order), nil
+}
+
+// Test that the finalizeBy of an order is stored, that finalizing the order
+// before it doesn't recheck CAA, and that finalizing it afterwards, but before
+// its authorizations expire, rechecks CAA rather than failing.
+func TestFinalizeOrderCAAThreshold(t *testing.T) {
+	_, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	recorder := &caaRecorder{names: make(map[string]bool)}
+	ra.VA = va.RemoteClients{CAAClient: recorder}
+
+	ctx := context.Background()
+	validated := fc.Now()
+	authzExpires := fc.Now().Add(48 * time.Hour)
+	msa := &mockSAStoresOrders{
+		mockSAForPrecheck: mockSAForPrecheck{
+			mockSAWithAuthzs: mockSAWithAuthzs{
+				authzs: []*core.Authorization{
+					{
+						ID:             "1",
+						Identifier:     identifier.NewDNS("zombo.com"),
+						RegistrationID: Registration.Id,
+						Expires:        &authzExpires,
+						Status:         core.StatusValid,
+						Challenges: []core.Challenge{
+							{
+								Type:      core.ChallengeTypeHTTP01,
+								Status:    core.StatusValid,
+								Token:     core.NewToken(),
+								Validated: &validated,
+							},
+						},
+					},
+				},
+			},
+		},
+		orders: make(map[int64]*corepb.Order),
+	}
+	ra.SA = msa
+
+	testKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating test key")
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		PublicKey: testKey.Public(),
+		DNSNames:  []string{"zombo.com"},
+	}, testKey)
+	test.AssertNotError(t, err, "creating CSR")
+	cert, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		DNSNames:              []string{"zombo.com"},
+		NotBefore:             fc.Now(),
+		NotAfter:              fc.Now().Add(90 * 24 * time.Hour),
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{}, testKey.Public(), testKey)
+	test.AssertNotError(t, err, "creating certificate")
+	ra.CA = &mocks.MockCA{PEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})}
+
+	created, err := ra.NewOrder(ctx, &rapb.NewOrderRequest{
+		RegistrationID: Registration.Id,
+		DnsNames:       []string{"zombo.com"},
+		ReadyBy:        timestamppb.New(fc.Now().Add(24 * time.Hour)),
+	})
+	test.AssertNotError(t, err, "NewOrder with readyBy failed")
+
+	// The finalizeBy is stored with the order, so that it's returned
+	// whenever the order is retrieved.
+	order, err := msa.GetOrder(ctx, &sapb.OrderRequest{Id: created.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	test.Assert(t, order.FinalizeBy != nil, "stored order should have a finalizeBy")
+	test.AssertEquals(t, order.FinalizeBy.AsTime(), created.FinalizeBy.AsTime())
+	finalizeBy := order.FinalizeBy.AsTime()
+
+	fc.Set(finalizeBy.Add(-time.Minute))
+	_, err = ra.FinalizeOrder(ctx, &rapb.FinalizeOrderRequest{Order: order, Csr: csr})
+	test.AssertNotError(t, err, "finalizing before finalizeBy failed")
+	test.AssertEquals(t, recorder.calls, 0)
+
+	fc.Set(finalizeBy.Add(time.Minute))
+	order, err = msa.GetOrder(ctx, &sapb.OrderRequest{Id: created.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	_, err = ra.FinalizeOrder(ctx, &rapb.FinalizeOrderRequest{Order: order, Csr: csr})
+	test.AssertNotError(t, err, "finalizing after finalizeBy failed")
+	test.AssertEquals(t, recorder.calls, 1)
+}
+
 func TestFinalizeOrder(t *testing.T) {
 	_, sa, ra, _, _, cleanUp := initAuthorities(t)
 	defer cleanUp()
@@ -2350,57 +4047,220 @@ func TestFinalizeOrder(t *testing.T) {
 					BeganProcessing:   false,
 					Created:           timestamppb.New(now),
 				},
-				Csr: oneDomainCSR,
+				Csr: oneDomainCSR,
+			},
+			ExpectedErrMsg: fmt.Sprintf("registration with ID '%d' not found", fakeRegID),
+		},
+		{
+			Name: "Order with missing authorizations",
+			OrderReq: &rapb.FinalizeOrderRequest{
+				Order: &corepb.Order{
+					Status:            string(core.StatusReady),
+					DnsNames:          []string{"a.com", "b.com"},
+					Id:                missingAuthzOrder.Id,
+					RegistrationID:    Registration.Id,
+					Expires:           timestamppb.New(exp),
+					CertificateSerial: "",
+					BeganProcessing:   false,
+					Created:           timestamppb.New(now),
+				},
+				Csr: twoDomainCSR,
+			},
+			ExpectedErrMsg: "authorizations for these identifiers not found: a.com, b.com",
+		},
+		{
+			Name: "Order with correct authorizations, ready status",
+			OrderReq: &rapb.FinalizeOrderRequest{
+				Order: validatedOrder,
+				Csr:   validCSR,
+			},
+			ExpectIssuance: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, result := ra.FinalizeOrder(context.Background(), tc.OrderReq)
+			// If we don't expect issuance we expect an error
+			if !tc.ExpectIssuance {
+				// Check that the error happened and the message matches expected
+				test.AssertError(t, result, "FinalizeOrder did not fail when expected to")
+				test.AssertEquals(t, result.Error(), tc.ExpectedErrMsg)
+			} else {
+				// Otherwise we expect an issuance and no error
+				test.AssertNotError(t, result, fmt.Sprintf("FinalizeOrder result was %#v, expected nil", result))
+				// Check that the order now has a serial for the issued certificate
+				updatedOrder, err := sa.GetOrder(
+					context.Background(),
+					&sapb.OrderRequest{Id: tc.OrderReq.Order.Id})
+				test.AssertNotError(t, err, "Error getting order to check serial")
+				test.AssertNotEquals(t, updatedOrder.CertificateSerial, "")
+				test.AssertEquals(t, updatedOrder.Status, "valid")
+				test.AssertEquals(t, updatedOrder.Expires.AsTime(), exp)
+			}
+		})
+	}
+}
+
+// mockSAForPrecheck serves the reads made by PrecheckFinalize from a fixed set
+// of authorizations and counts any attempt to modify the order. Calls to any
+// other method panic on the embedded nil client.
+type mockSAForPrecheck struct {
+	mockSAWithAuthzs
+	writes int
+}
+
+func (msa *mockSAForPrecheck) GetRegistration(_ context.Context, req *sapb.RegistrationID, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	return &corepb.Registration{
+		Id:     req.Id,
+		Key:    AccountKeyJSONA,
+		Status: string(core.StatusValid),
+	}, nil
+}
+
+func (msa *mockSAForPrecheck) FQDNSetTimestampsForWindow(_ context.Context, _ *sapb.CountFQDNSetsRequest, _ ...grpc.CallOption) (*sapb.Timestamps, error) {
+	return &sapb.Timestamps{}, nil
+}
+
+func (msa *mockSAForPrecheck) SetOrderProcessing(_ context.Context, _ *sapb.OrderRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	msa.writes++
+	return &emptypb.Empty{}, nil
+}
+
+func (msa *mockSAForPrecheck) SetOrderError(_ context.Context, _ *sapb.SetOrderErrorRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	msa.writes++
+	return &emptypb.Empty{}, nil
+}
+
+func (msa *mockSAForPrecheck) FinalizeOrder(_ context.Context, _ *sapb.FinalizeOrderRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	msa.writes++
+	return &emptypb.Empty{}, nil
+}
+
+func (msa *mockSAForPrecheck) AddValidationAttempt(_ context.Context, _ *sapb.AddValidationAttemptRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func TestPrecheckFinalize(t *testing.T) {
+	_, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	recorder := &caaRecorder{names: make(map[string]bool)}
+	ra.VA = va.RemoteClients{CAAClient: recorder}
+
+	ctx := context.Background()
+	domain := randomDomain()
+
+	makeCSR := func(names ...string) []byte {
+		testKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		test.AssertNotError(t, err, "generating test key")
+		csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			PublicKey: testKey.Public(),
+			DNSNames:  names,
+		}, testKey)
+		test.AssertNotError(t, err, "creating CSR")
+		return csr
+	}
+
+	makeSA := func(validated time.Time) *mockSAForPrecheck {
+		expires := fc.Now().Add(24 * time.Hour)
+		return &mockSAForPrecheck{
+			mockSAWithAuthzs: mockSAWithAuthzs{
+				authzs: []*core.Authorization{
+					{
+						ID:             "1",
+						Identifier:     identifier.NewDNS(domain),
+						RegistrationID: Registration.Id,
+						Expires:        &expires,
+						Status:         core.StatusValid,
+						Challenges: []core.Challenge{
+							{
+								Type:      core.ChallengeTypeHTTP01,
+								Status:    core.StatusValid,
+								Token:     core.NewToken(),
+								Validated: &validated,
+							},
+						},
+					},
+				},
 			},
-			ExpectedErrMsg: fmt.Sprintf("registration with ID '%d' not found", fakeRegID),
+		}
+	}
+
+	order := &corepb.Order{
+		Id:             1,
+		RegistrationID: Registration.Id,
+		Status:         string(core.StatusReady),
+		DnsNames:       []string{domain},
+		Created:        timestamppb.New(fc.Now()),
+	}
+
+	testCases := []struct {
+		name                string
+		validated           time.Time
+		csr                 []byte
+		exhaustLimits       bool
+		expectErrType       berrors.ErrorType
+		expectErrContains   string
+		expectCAARecheckFor []string
+	}{
+		{
+			name:      "Clean pass",
+			validated: fc.Now().Add(-time.Hour),
+			csr:       makeCSR(domain),
 		},
 		{
-			Name: "Order with missing authorizations",
-			OrderReq: &rapb.FinalizeOrderRequest{
-				Order: &corepb.Order{
-					Status:            string(core.StatusReady),
-					DnsNames:          []string{"a.com", "b.com"},
-					Id:                missingAuthzOrder.Id,
-					RegistrationID:    Registration.Id,
-					Expires:           timestamppb.New(exp),
-					CertificateSerial: "",
-					BeganProcessing:   false,
-					Created:           timestamppb.New(now),
-				},
-				Csr: twoDomainCSR,
-			},
-			ExpectedErrMsg: "authorizations for these identifiers not found: a.com, b.com",
+			name:              "CSR doesn't match order names",
+			validated:         fc.Now().Add(-time.Hour),
+			csr:               makeCSR(domain, "www."+domain),
+			expectErrType:     berrors.Unauthorized,
+			expectErrContains: "CSR does not specify same identifiers as Order",
 		},
 		{
-			Name: "Order with correct authorizations, ready status",
-			OrderReq: &rapb.FinalizeOrderRequest{
-				Order: validatedOrder,
-				Csr:   validCSR,
-			},
-			ExpectIssuance: true,
+			name:                "Stale CAA is reported rather than rechecked",
+			validated:           fc.Now().Add(-8 * time.Hour),
+			csr:                 makeCSR(domain),
+			expectCAARecheckFor: []string{domain},
+		},
+		{
+			name:              "Certificate rate limit exhausted",
+			validated:         fc.Now().Add(-time.Hour),
+			csr:               makeCSR(domain),
+			exhaustLimits:     true,
+			expectErrType:     berrors.RateLimit,
+			expectErrContains: "too many certificates",
 		},
 	}
 
 	for _, tc := range testCases {
-		t.Run(tc.Name, func(t *testing.T) {
-			_, result := ra.FinalizeOrder(context.Background(), tc.OrderReq)
-			// If we don't expect issuance we expect an error
-			if !tc.ExpectIssuance {
-				// Check that the error happened and the message matches expected
-				test.AssertError(t, result, "FinalizeOrder did not fail when expected to")
-				test.AssertEquals(t, result.Error(), tc.ExpectedErrMsg)
+		t.Run(tc.name, func(t *testing.T) {
+			msa := makeSA(tc.validated)
+			ra.SA = msa
+
+			if tc.exhaustLimits {
+				txn, err := ra.txnBuilder.CertificatesPerFQDNSetTransaction([]string{domain})
+				test.AssertNotError(t, err, "building transaction")
+				for range 2 {
+					_, err := ra.limiter.Spend(ctx, txn)
+					test.AssertNotError(t, err, "spending rate limit")
+				}
+			}
+
+			resp, err := ra.PrecheckFinalize(ctx, &rapb.FinalizeOrderRequest{
+				Order: order,
+				Csr:   tc.csr,
+			})
+			if tc.expectErrContains != "" {
+				test.AssertError(t, err, "PrecheckFinalize should have failed")
+				test.AssertErrorIs(t, err, tc.expectErrType)
+				test.AssertContains(t, err.Error(), tc.expectErrContains)
 			} else {
-				// Otherwise we expect an issuance and no error
-				test.AssertNotError(t, result, fmt.Sprintf("FinalizeOrder result was %#v, expected nil", result))
-				// Check that the order now has a serial for the issued certificate
-				updatedOrder, err := sa.GetOrder(
-					context.Background(),
-					&sapb.OrderRequest{Id: tc.OrderReq.Order.Id})
-				test.AssertNotError(t, err, "Error getting order to check serial")
-				test.AssertNotEquals(t, updatedOrder.CertificateSerial, "")
-				test.AssertEquals(t, updatedOrder.Status, "valid")
-				test.AssertEquals(t, updatedOrder.Expires.AsTime(), exp)
+				test.AssertNotError(t, err, "PrecheckFinalize failed")
+				test.AssertEquals(t, resp.Status, string(core.StatusReady))
+				test.AssertDeepEquals(t, resp.CaaRecheckNames, tc.expectCAARecheckFor)
 			}
+			test.AssertEquals(t, msa.writes, 0)
+			test.AssertEquals(t, recorder.calls, 0)
 		})
 	}
 }
@@ -2736,6 +4596,570 @@ func TestFinalizeWithMustStaple(t *testing.T) {
 	}
 }
 
+// mockCARecordingOmitMustStaple records whether the RA's request asked the CA
+// to omit the must-staple extension.
+type mockCARecordingOmitMustStaple struct {
+	mocks.MockCA
+	omitMustStaple bool
+}
+
+func (ca *mockCARecordingOmitMustStaple) IssuePrecertificate(ctx context.Context, req *capb.IssueCertificateRequest, _ ...grpc.CallOption) (*capb.IssuePrecertificateResponse, error) {
+	ca.omitMustStaple = req.OmitMustStaple
+	return ca.MockCA.IssuePrecertificate(ctx, req)
+}
+
+func TestFinalizeMustStaplePolicy(t *testing.T) {
+	_, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	ocspMustStapleExt := pkix.Extension{
+		Id:    asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24},
+		Value: []byte{0x30, 0x03, 0x02, 0x01, 0x05},
+	}
+	ra.validationProfiles = map[string]*ValidationProfile{
+		"allow":  NewValidationProfile(nil, ValidationProfileConfig{}),
+		"strip":  NewValidationProfile(nil, ValidationProfileConfig{MustStaplePolicy: MustStapleStrip}),
+		"reject": NewValidationProfile(nil, ValidationProfileConfig{MustStaplePolicy: MustStapleReject}),
+	}
+
+	testCases := []struct {
+		profile        string
+		mustStaple     bool
+		expectErr      bool
+		expectOmit     bool
+		expectOutcome  string
+		expectStripLog bool
+	}{
+		{profile: "allow"},
+		{profile: "allow", mustStaple: true, expectOutcome: "allowed"},
+		{profile: "strip"},
+		{profile: "strip", mustStaple: true, expectOmit: true, expectOutcome: "stripped", expectStripLog: true},
+		{profile: "reject"},
+		{profile: "reject", mustStaple: true, expectErr: true, expectOutcome: "rejected"},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s, must-staple %t", tc.profile, tc.mustStaple), func(t *testing.T) {
+			mockLog := ra.log.(*blog.Mock)
+			mockLog.Clear()
+			ra.mustStapleOutcomes.Reset()
+
+			domain := randomDomain()
+			validated := fc.Now().Add(-time.Hour)
+			expires := fc.Now().Add(24 * time.Hour)
+			ra.SA = &mockSAForPrecheck{
+				mockSAWithAuthzs: mockSAWithAuthzs{
+					authzs: []*core.Authorization{
+						{
+							ID:             "1",
+							Identifier:     identifier.NewDNS(domain),
+							RegistrationID: Registration.Id,
+							Expires:        &expires,
+							Status:         core.StatusValid,
+							Challenges: []core.Challenge{
+								{
+									Type:      core.ChallengeTypeHTTP01,
+									Status:    core.StatusValid,
+									Token:     core.NewToken(),
+									Validated: &validated,
+								},
+							},
+						},
+					},
+				},
+			}
+
+			testKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			test.AssertNotError(t, err, "generating test key")
+			var extensions []pkix.Extension
+			if tc.mustStaple {
+				extensions = []pkix.Extension{ocspMustStapleExt}
+			}
+			csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+				PublicKey:       testKey.Public(),
+				DNSNames:        []string{domain},
+				ExtraExtensions: extensions,
+			}, testKey)
+			test.AssertNotError(t, err, "creating CSR")
+			cert, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+				SerialNumber:          big.NewInt(1),
+				DNSNames:              []string{domain},
+				NotBefore:             fc.Now(),
+				NotAfter:              fc.Now().Add(90 * 24 * time.Hour),
+				BasicConstraintsValid: true,
+				ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			}, &x509.Certificate{}, testKey.Public(), testKey)
+			test.AssertNotError(t, err, "creating certificate")
+			mockCA := &mockCARecordingOmitMustStaple{
+				MockCA: mocks.MockCA{PEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})},
+			}
+			ra.CA = mockCA
+
+			_, err = ra.FinalizeOrder(context.Background(), &rapb.FinalizeOrderRequest{
+				Order: &corepb.Order{
+					Id:                     1,
+					RegistrationID:         Registration.Id,
+					Status:                 string(core.StatusReady),
+					DnsNames:               []string{domain},
+					Created:                timestamppb.New(fc.Now()),
+					CertificateProfileName: tc.profile,
+				},
+				Csr: csr,
+			})
+			if tc.expectErr {
+				test.AssertErrorIs(t, err, berrors.BadCSR)
+				test.AssertContains(t, err.Error(), "1.3.6.1.5.5.7.1.24")
+			} else {
+				test.AssertNotError(t, err, "FinalizeOrder failed")
+				test.AssertEquals(t, mockCA.omitMustStaple, tc.expectOmit)
+			}
+			if tc.expectOutcome != "" {
+				test.AssertMetricWithLabelsEquals(t, ra.mustStapleOutcomes,
+					prometheus.Labels{"policy": tc.profile, "outcome": tc.expectOutcome}, 1)
+			} else {
+				test.AssertMetricWithLabelsEquals(t, ra.mustStapleOutcomes, prometheus.Labels{}, 0)
+			}
+			stripLogs := mockLog.GetAllMatching("Stripping OCSP must-staple extension")
+			test.AssertEquals(t, len(stripLogs) == 1, tc.expectStripLog)
+		})
+	}
+}
+
+func TestFinalizeOrderGlobalIssuanceRate(t *testing.T) {
+	_, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	// Throttle issuance across the deployment to a single certificate per
+	// hour, as an override would during an incident.
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.GlobalIssuanceRate.String(): &ratelimits.LimitConfig{
+			Burst:  1,
+			Count:  1,
+			Period: config.Duration{Duration: time.Hour}},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	ra.txnBuilder = txnBuilder
+
+	testKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating test key")
+	finalize := func() (*mockSAForPrecheck, error) {
+		t.Helper()
+		domain := randomDomain()
+		validated := fc.Now().Add(-time.Hour)
+		expires := fc.Now().Add(24 * time.Hour)
+		msa := &mockSAForPrecheck{
+			mockSAWithAuthzs: mockSAWithAuthzs{
+				authzs: []*core.Authorization{
+					{
+						ID:             "1",
+						Identifier:     identifier.NewDNS(domain),
+						RegistrationID: Registration.Id,
+						Expires:        &expires,
+						Status:         core.StatusValid,
+						Challenges: []core.Challenge{
+							{
+								Type:      core.ChallengeTypeHTTP01,
+								Status:    core.StatusValid,
+								Token:     core.NewToken(),
+								Validated: &validated,
+							},
+						},
+					},
+				},
+			},
+		}
+		ra.SA = msa
+
+		csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			PublicKey: testKey.Public(),
+			DNSNames:  []string{domain},
+		}, testKey)
+		test.AssertNotError(t, err, "creating CSR")
+		cert, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			DNSNames:              []string{domain},
+			NotBefore:             fc.Now(),
+			NotAfter:              fc.Now().Add(90 * 24 * time.Hour),
+			BasicConstraintsValid: true,
+			ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}, &x509.Certificate{}, testKey.Public(), testKey)
+		test.AssertNotError(t, err, "creating certificate")
+		ra.CA = &mocks.MockCA{PEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})}
+
+		_, err = ra.FinalizeOrder(context.Background(), &rapb.FinalizeOrderRequest{
+			Order: &corepb.Order{
+				Id:             1,
+				RegistrationID: Registration.Id,
+				Status:         string(core.StatusReady),
+				DnsNames:       []string{domain},
+				Created:        timestamppb.New(fc.Now()),
+			},
+			Csr: csr,
+		})
+		return msa, err
+	}
+
+	_, err = finalize()
+	test.AssertNotError(t, err, "FinalizeOrder failed")
+
+	// Any further issuance, by any account, is refused until the limit
+	// refills, and the order is left ready to be finalized again.
+	msa, err := finalize()
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+	test.AssertContains(t, err.Error(), "temporary, service-wide limit")
+	test.AssertEquals(t, msa.writes, 0)
+
+	fc.Add(time.Hour)
+	_, err = finalize()
+	test.AssertNotError(t, err, "FinalizeOrder failed after the limit refilled")
+}
+
+func TestFinalizeOrderCertificatesPerFQDNSet(t *testing.T) {
+	_, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+	ctx := context.Background()
+
+	// Allow a single certificate for each set of names per hour.
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.CertificatesPerFQDNSet.String(): &ratelimits.LimitConfig{
+			Burst:  1,
+			Count:  1,
+			Period: config.Duration{Duration: time.Hour}},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	ra.txnBuilder = txnBuilder
+
+	hasCapacity := func(names []string) bool {
+		t.Helper()
+		txn, err := txnBuilder.CertificatesPerFQDNSetTransaction(names)
+		test.AssertNotError(t, err, "building transaction")
+		d, err := ra.limiter.Check(ctx, txn)
+		test.AssertNotError(t, err, "checking rate limit")
+		return d.Result(fc.Now()) == nil
+	}
+
+	// createOrder runs the new-order rate limit checks which the WFE runs
+	// before an order is created.
+	createOrder := func(names []string) {
+		t.Helper()
+		txns, err := txnBuilder.NewOrderLimitTransactions(Registration.Id, ratelimits.AccountAgeUnknown, names, false)
+		test.AssertNotError(t, err, "building new order transactions")
+		d, err := ra.limiter.BatchSpend(ctx, txns)
+		test.AssertNotError(t, err, "spending new order rate limits")
+		test.AssertNotError(t, d.Result(fc.Now()), "new order rate limits should allow the order")
+	}
+
+	testKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating test key")
+	finalize := func(orderNames, csrNames []string, failIssuance bool) (*mockSAForPrecheck, error) {
+		t.Helper()
+		validated := fc.Now().Add(-time.Hour)
+		expires := fc.Now().Add(24 * time.Hour)
+		msa := &mockSAForPrecheck{}
+		for i, name := range orderNames {
+			msa.authzs = append(msa.authzs, &core.Authorization{
+				ID:             strconv.Itoa(i + 1),
+				Identifier:     identifier.NewDNS(name),
+				RegistrationID: Registration.Id,
+				Expires:        &expires,
+				Status:         core.StatusValid,
+				Challenges: []core.Challenge{
+					{
+						Type:      core.ChallengeTypeHTTP01,
+						Status:    core.StatusValid,
+						Token:     core.NewToken(),
+						Validated: &validated,
+					},
+				},
+			})
+		}
+		ra.SA = msa
+
+		csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			PublicKey: testKey.Public(),
+			DNSNames:  csrNames,
+		}, testKey)
+		test.AssertNotError(t, err, "creating CSR")
+		cert, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			DNSNames:              csrNames,
+			NotBefore:             fc.Now(),
+			NotAfter:              fc.Now().Add(90 * 24 * time.Hour),
+			BasicConstraintsValid: true,
+			ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}, &x509.Certificate{}, testKey.Public(), testKey)
+		test.AssertNotError(t, err, "creating certificate")
+		ra.CA = &mocks.MockCA{PEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})}
+		if failIssuance {
+			ra.CA = &mockCAFailPrecert{err: berrors.InternalServerError("HSM unavailable")}
+		}
+
+		_, err = ra.FinalizeOrder(ctx, &rapb.FinalizeOrderRequest{
+			Order: &corepb.Order{
+				Id:             1,
+				RegistrationID: Registration.Id,
+				Status:         string(core.StatusReady),
+				DnsNames:       orderNames,
+				Created:        timestamppb.New(fc.Now()),
+			},
+			Csr: csr,
+		})
+		return msa, err
+	}
+
+	t.Run("create then finalize", func(t *testing.T) {
+		names := []string{randomDomain()}
+
+		// Creating orders only checks the limit, so that any number of orders
+		// may be created while it has capacity.
+		createOrder(names)
+		createOrder(names)
+		test.Assert(t, hasCapacity(names), "limit should have capacity")
+
+		// Finalizing an order spends the limit, however long after it was
+		// created.
+		fc.Add(3 * 24 * time.Hour)
+		_, err := finalize(names, names, false)
+		test.AssertNotError(t, err, "FinalizeOrder failed")
+		test.Assert(t, !hasCapacity(names), "limit should have been spent")
+
+		// The other order can't be finalized until the limit refills, and is
+		// left ready to be finalized again.
+		msa, err := finalize(names, names, false)
+		test.AssertErrorIs(t, err, berrors.RateLimit)
+		test.AssertContains(t, err.Error(), "too many certificates")
+		test.AssertEquals(t, msa.writes, 0)
+
+		fc.Add(time.Hour)
+		_, err = finalize(names, names, false)
+		test.AssertNotError(t, err, "FinalizeOrder failed after the limit refilled")
+	})
+
+	t.Run("finalize failure refunds", func(t *testing.T) {
+		names := []string{randomDomain()}
+		createOrder(names)
+
+		_, err := finalize(names, names, true)
+		test.AssertError(t, err, "FinalizeOrder should have failed to issue")
+		test.AssertContains(t, err.Error(), "HSM unavailable")
+		test.Assert(t, hasCapacity(names), "limit should have capacity")
+
+		_, err = finalize(names, names, false)
+		test.AssertNotError(t, err, "FinalizeOrder failed after a refunded failure")
+		test.Assert(t, !hasCapacity(names), "limit should have been spent")
+	})
+
+	t.Run("CSR with a subset of order names", func(t *testing.T) {
+		subset := []string{randomDomain()}
+		orderNames := append([]string{randomDomain()}, subset...)
+		createOrder(orderNames)
+
+		// The certificate's names must be exactly those of the order, so
+		// neither set of names is spent.
+		msa, err := finalize(orderNames, subset, false)
+		test.AssertErrorIs(t, err, berrors.Unauthorized)
+		test.AssertContains(t, err.Error(), "CSR does not specify same identifiers as Order")
+		test.AssertEquals(t, msa.writes, 0)
+		test.Assert(t, hasCapacity(subset), "limit should have capacity")
+		test.Assert(t, hasCapacity(orderNames), "limit should have capacity")
+
+		// The limit is spent for the names of the certificate which is issued.
+		_, err = finalize(orderNames, orderNames, false)
+		test.AssertNotError(t, err, "FinalizeOrder failed")
+		test.Assert(t, !hasCapacity(orderNames), "limit should have been spent")
+		test.Assert(t, hasCapacity(subset), "limit should have capacity")
+	})
+}
+
+func TestCheckWillingness(t *testing.T) {
+	_, _, ra, _, _, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	// The check has no side effects, so any call to the SA would panic.
+	ra.SA = struct{ sapb.StorageAuthorityClient }{}
+	ra.VA = va.RemoteClients{CAAClient: &caaFailer{}}
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.WillingnessChecksPerAccount.String(): &ratelimits.LimitConfig{
+			Burst:  4,
+			Count:  4,
+			Period: config.Duration{Duration: time.Hour}},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	ra.txnBuilder = txnBuilder
+
+	resp, err := ra.CheckWillingness(context.Background(), &rapb.CheckWillingnessRequest{
+		RegistrationID: Registration.Id,
+		Identifiers: []*corepb.Identifier{
+			identifier.NewDNS("highrisk.le-test.hoffman-andrews.com").AsProto(),
+			identifier.NewDNS("a.com").AsProto(),
+			identifier.NewDNS("b.com").AsProto(),
+			identifier.NewIP(netip.MustParseAddr("10.0.0.1")).AsProto(),
+		},
+		CheckCAA: true,
+	})
+	test.AssertNotError(t, err, "CheckWillingness failed")
+	test.AssertEquals(t, len(resp.Verdicts), 4)
+
+	// The banned name is refused by policy.
+	test.AssertEquals(t, resp.Verdicts[0].Identifier.Value, "highrisk.le-test.hoffman-andrews.com")
+	test.AssertNotNil(t, resp.Verdicts[0].Problem, "banned name should be refused")
+	test.AssertEquals(t, resp.Verdicts[0].Problem.ProblemType, string(probs.RejectedIdentifierProblem))
+
+	// The name whose CAA records forbid issuance is refused by the VA.
+	test.AssertEquals(t, resp.Verdicts[1].Identifier.Value, "a.com")
+	test.AssertNotNil(t, resp.Verdicts[1].Problem, "CAA-blocked name should be refused")
+	test.AssertEquals(t, resp.Verdicts[1].Problem.Detail, "CAA invalid for a.com")
+
+	// The clean name has no problem.
+	test.AssertEquals(t, resp.Verdicts[2].Identifier.Value, "b.com")
+	test.Assert(t, resp.Verdicts[2].Problem == nil, "clean name should be allowed")
+
+	// Identifiers of types other than DNS aren't supported.
+	test.AssertNotNil(t, resp.Verdicts[3].Problem, "IP identifier should be refused")
+	test.AssertEquals(t, resp.Verdicts[3].Problem.ProblemType, string(probs.UnsupportedIdentifierProblem))
+
+	// Each identifier checked spends against the account's limit.
+	_, err = ra.CheckWillingness(context.Background(), &rapb.CheckWillingnessRequest{
+		RegistrationID: Registration.Id,
+		Identifiers:    []*corepb.Identifier{identifier.NewDNS("b.com").AsProto()},
+	})
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+
+	_, err = ra.CheckWillingness(context.Background(), &rapb.CheckWillingnessRequest{})
+	test.AssertErrorIs(t, err, errIncompleteGRPCRequest)
+}
+
+func TestFinalizeOrderCSRKey(t *testing.T) {
+	_, _, ra, _, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	// Allow a single certificate, so that any spend before the key checks
+	// would leave the clean pass throttled.
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.GlobalIssuanceRate.String(): &ratelimits.LimitConfig{
+			Burst:  1,
+			Count:  1,
+			Period: config.Duration{Duration: time.Hour}},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	ra.txnBuilder = txnBuilder
+
+	blockedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating test key")
+	blockedDigest, err := core.KeyDigest(blockedKey.Public())
+	test.AssertNotError(t, err, "computing key digest")
+	ra.keyPolicy, err = goodkey.NewPolicy(nil, func(_ context.Context, keyHash []byte) (bool, error) {
+		return bytes.Equal(keyHash, blockedDigest[:]), nil
+	})
+	test.AssertNotError(t, err, "making keypolicy")
+	smallRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	test.AssertNotError(t, err, "generating test key")
+	p224Key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	test.AssertNotError(t, err, "generating test key")
+	goodKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating test key")
+
+	finalize := func(key crypto.Signer) (*mockSAForPrecheck, error) {
+		t.Helper()
+		domain := randomDomain()
+		validated := fc.Now().Add(-time.Hour)
+		expires := fc.Now().Add(24 * time.Hour)
+		msa := &mockSAForPrecheck{
+			mockSAWithAuthzs: mockSAWithAuthzs{
+				authzs: []*core.Authorization{
+					{
+						ID:             "1",
+						Identifier:     identifier.NewDNS(domain),
+						RegistrationID: Registration.Id,
+						Expires:        &expires,
+						Status:         core.StatusValid,
+						Challenges: []core.Challenge{
+							{
+								Type:      core.ChallengeTypeHTTP01,
+								Status:    core.StatusValid,
+								Token:     core.NewToken(),
+								Validated: &validated,
+							},
+						},
+					},
+				},
+			},
+		}
+		ra.SA = msa
+
+		csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			DNSNames: []string{domain},
+		}, key)
+		test.AssertNotError(t, err, "creating CSR")
+		cert, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			DNSNames:              []string{domain},
+			NotBefore:             fc.Now(),
+			NotAfter:              fc.Now().Add(90 * 24 * time.Hour),
+			BasicConstraintsValid: true,
+			ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}, &x509.Certificate{}, key.Public(), goodKey)
+		test.AssertNotError(t, err, "creating certificate")
+		ra.CA = &mocks.MockCA{PEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})}
+
+		_, err = ra.FinalizeOrder(context.Background(), &rapb.FinalizeOrderRequest{
+			Order: &corepb.Order{
+				Id:             1,
+				RegistrationID: Registration.Id,
+				Status:         string(core.StatusReady),
+				DnsNames:       []string{domain},
+				Created:        timestamppb.New(fc.Now()),
+			},
+			Csr: csr,
+		})
+		return msa, err
+	}
+
+	testCases := []struct {
+		name              string
+		key               crypto.Signer
+		expectErrType     berrors.ErrorType
+		expectErrContains string
+	}{
+		{
+			name:              "Account key",
+			key:               AccountPrivateKey.Key.(crypto.Signer),
+			expectErrType:     berrors.BadCSR,
+			expectErrContains: "certificate public key must be different than account key",
+		},
+		{
+			name:              "Blocked key",
+			key:               blockedKey,
+			expectErrType:     berrors.BadPublicKey,
+			expectErrContains: "public key is forbidden",
+		},
+		{
+			name:              "Small RSA key",
+			key:               smallRSAKey,
+			expectErrType:     berrors.BadCSR,
+			expectErrContains: "key size not supported: 1024",
+		},
+		{
+			name:              "Disallowed curve",
+			key:               p224Key,
+			expectErrType:     berrors.BadCSR,
+			expectErrContains: "ECDSA curve P-224 not allowed",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The order is rejected before it's modified, or the CA is called.
+			msa, err := finalize(tc.key)
+			test.AssertErrorIs(t, err, tc.expectErrType)
+			test.AssertContains(t, err.Error(), tc.expectErrContains)
+			test.AssertEquals(t, msa.writes, 0)
+		})
+	}
+
+	// None of the rejected requests spent the global issuance limit.
+	_, err = finalize(goodKey)
+	test.AssertNotError(t, err, "FinalizeOrder failed")
+}
+
 func TestIssueCertificateAuditLog(t *testing.T) {
 	_, sa, ra, _, _, cleanUp := initAuthorities(t)
 	defer cleanUp()
@@ -2975,11 +5399,11 @@ func TestUpdateMissingAuthorization(t *testing.T) {
 
 	// Twiddle the authz to pretend its been validated by the VA
 	authz.Challenges[0].Status = "valid"
-	err = ra.recordValidation(ctx, authz.ID, authz.Expires, &authz.Challenges[0])
+	err = ra.recordValidation(ctx, authz.ID, authz.Expires, &authz.Challenges[0], nil)
 	test.AssertNotError(t, err, "ra.recordValidation failed")
 
 	// Try to record the same validation a second time.
-	err = ra.recordValidation(ctx, authz.ID, authz.Expires, &authz.Challenges[0])
+	err = ra.recordValidation(ctx, authz.ID, authz.Expires, &authz.Challenges[0], nil)
 	test.AssertError(t, err, "ra.recordValidation didn't fail")
 	test.AssertErrorIs(t, err, berrors.NotFound)
 }
@@ -3088,6 +5512,7 @@ func TestWildcardOverlap(t *testing.T) {
 		t.Errorf("Got no error, expected one")
 	}
 	test.AssertErrorIs(t, err, berrors.Malformed)
+	test.AssertContains(t, err.Error(), `"www.example.com" is covered by "*.example.com"`)
 
 	err = wildcardOverlap([]string{
 		"*.foo.example.com",
@@ -3097,6 +5522,30 @@ func TestWildcardOverlap(t *testing.T) {
 	if err != nil {
 		t.Errorf("Got error %q, expected none", err)
 	}
+
+	// A wildcard doesn't cover its own base name, so requesting both isn't
+	// redundant.
+	err = wildcardOverlap([]string{
+		"*.example.com",
+		"example.com",
+	})
+	if err != nil {
+		t.Errorf("Got error %q, expected none", err)
+	}
+
+	// Every redundant name is listed, along with what a wildcard covers.
+	err = wildcardOverlap([]string{
+		"*.example.com",
+		"example.com",
+		"www.example.com",
+		"*.example.net",
+		"mail.example.net",
+	})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	test.AssertEquals(t, err.Error(), `Domain names are redundant with a wildcard domain in the same request: `+
+		`"mail.example.net" is covered by "*.example.net", "www.example.com" is covered by "*.example.com". `+
+		`Remove one or the other from the certificate request. Note that a wildcard covers any single label `+
+		`in its place, but not its base domain, which must be requested separately if needed.`)
 }
 
 // mockCAFailPrecert is a mock CA that always returns an error from `IssuePrecertificate`
@@ -3385,6 +5834,174 @@ func TestIssueCertificateOuter(t *testing.T) {
 	test.AssertMetricWithLabelsEquals(t, ra.newCertCounter, prometheus.Labels{"profileName": mockCA.profileName, "profileHash": fmt.Sprintf("%x", mockCA.profileHash)}, 1)
 }
 
+type mockSARecordingOrderError struct {
+	sapb.StorageAuthorityClient
+	req *sapb.SetOrderErrorRequest
+}
+
+func (sa *mockSARecordingOrderError) SetOrderError(_ context.Context, req *sapb.SetOrderErrorRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	sa.req = req
+	return &emptypb.Empty{}, nil
+}
+
+func TestFailOrderPersistsSubProblems(t *testing.T) {
+	mockSA := &mockSARecordingOrderError{}
+	ra := &RegistrationAuthorityImpl{SA: mockSA, log: blog.NewMock()}
+
+	prob := web.ProblemDetailsForError((&berrors.BoulderError{
+		Type:   berrors.CAA,
+		Detail: "Rechecking CAA for \"example.com\" and 1 more identifiers failed",
+	}).WithSubErrors([]berrors.SubBoulderError{
+		{
+			Identifier:   identifier.NewDNS("example.com"),
+			BoulderError: &berrors.BoulderError{Type: berrors.CAA, Detail: "CAA record for example.com prevents issuance"},
+		},
+		{
+			Identifier:   identifier.NewDNS("www.example.com"),
+			BoulderError: &berrors.BoulderError{Type: berrors.CAA, Detail: "CAA record for www.example.com prevents issuance"},
+		},
+	}), "Error finalizing order")
+
+	order := &corepb.Order{Id: 1}
+	ra.failOrder(context.Background(), order, prob)
+	test.AssertNotNil(t, mockSA.req, "SetOrderError was not called")
+	test.AssertEquals(t, mockSA.req.Id, order.Id)
+
+	persisted, err := bgrpc.PBToProblemDetails(mockSA.req.Error)
+	test.AssertNotError(t, err, "converting persisted problem")
+	test.AssertDeepEquals(t, persisted, prob)
+	test.AssertEquals(t, len(persisted.SubProblems), 2)
+}
+
+// mockCALintFailures is a mock CA which reports lint failures from
+// IssuePrecertificate. If issue is true it also returns the inner mock CA's
+// precertificate, otherwise it returns none.
+type mockCALintFailures struct {
+	mocks.MockCA
+	failures []*capb.LintFailure
+	issue    bool
+}
+
+func (ca *mockCALintFailures) IssuePrecertificate(ctx context.Context, req *capb.IssueCertificateRequest, _ ...grpc.CallOption) (*capb.IssuePrecertificateResponse, error) {
+	if !ca.issue {
+		return &capb.IssuePrecertificateResponse{CertProfileName: req.CertProfileName, LintFailures: ca.failures}, nil
+	}
+	resp, err := ca.MockCA.IssuePrecertificate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.LintFailures = ca.failures
+	return resp, nil
+}
+
+type mockSAFinalizeRecordingOrderError struct {
+	mockSAWithFinalize
+	req *sapb.SetOrderErrorRequest
+}
+
+func (sa *mockSAFinalizeRecordingOrderError) SetOrderError(_ context.Context, req *sapb.SetOrderErrorRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	sa.req = req
+	return &emptypb.Empty{}, nil
+}
+
+func TestIssueCertificateLintFailures(t *testing.T) {
+	_, _, ra, _, fc, cleanup := initAuthorities(t)
+	defer cleanup()
+
+	testKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating test key")
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{"example.com"}}, testKey)
+	test.AssertNotError(t, err, "creating test csr")
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	test.AssertNotError(t, err, "parsing test csr")
+	certDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		DNSNames:              []string{"example.com"},
+		NotBefore:             fc.Now(),
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{}, testKey.Public(), testKey)
+	test.AssertNotError(t, err, "creating test cert")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	warning := &capb.LintFailure{Name: "w_some_warning", Severity: "warn", Description: "be careful"}
+	lintErr := &capb.LintFailure{Name: "e_some_error", Severity: "error", Description: "broken"}
+
+	testCases := []struct {
+		name      string
+		threshold lint.LintStatus
+		failures  []*capb.LintFailure
+		issue     bool
+		// expectSubProbs are the details of the subproblems expected in a
+		// badCSR problem. If nil, and expectInternal is false, the order is
+		// expected to be valid.
+		expectSubProbs []string
+		expectInternal bool
+	}{
+		{
+			name:      "every failure at or above the threshold",
+			threshold: lint.Warn,
+			failures:  []*capb.LintFailure{lintErr, warning},
+			expectSubProbs: []string{
+				"Certificate failed lint e_some_error (error): broken",
+				"Certificate failed lint w_some_warning (warn): be careful",
+			},
+		},
+		{
+			name:           "warning below the threshold",
+			threshold:      lint.Error,
+			failures:       []*capb.LintFailure{lintErr, warning},
+			expectSubProbs: []string{"Certificate failed lint e_some_error (error): broken"},
+		},
+		{
+			name:           "only warnings below the threshold, no precertificate",
+			threshold:      lint.Error,
+			failures:       []*capb.LintFailure{warning},
+			expectInternal: true,
+		},
+		{
+			name:      "only warnings below the threshold, precertificate issued",
+			threshold: lint.Error,
+			failures:  []*capb.LintFailure{warning},
+			issue:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ra.CA = &mockCALintFailures{MockCA: mocks.MockCA{PEM: certPEM}, failures: tc.failures, issue: tc.issue}
+			mockSA := &mockSAFinalizeRecordingOrderError{}
+			ra.SA = mockSA
+			ra.LintProblemSeverity = tc.threshold
+
+			order := &corepb.Order{Id: 1, RegistrationID: 1, DnsNames: []string{"example.com"}}
+			order, err := ra.issueCertificateOuter(context.Background(), order, csr, certificateRequestEvent{})
+			if tc.expectSubProbs == nil && !tc.expectInternal {
+				test.AssertNotError(t, err, "lint failures below the threshold should not fail the order")
+				test.AssertEquals(t, order.Status, string(core.StatusValid))
+				test.AssertBoxedNil(t, mockSA.req, "SetOrderError should not have been called")
+				return
+			}
+			test.AssertError(t, err, "expected issuance to fail")
+			test.AssertEquals(t, order.Status, string(core.StatusInvalid))
+			test.AssertNotNil(t, mockSA.req, "SetOrderError was not called")
+			prob, err := bgrpc.PBToProblemDetails(mockSA.req.Error)
+			test.AssertNotError(t, err, "converting persisted problem")
+			if tc.expectInternal {
+				test.AssertEquals(t, prob.Type, probs.ServerInternalProblem)
+				test.AssertEquals(t, len(prob.SubProblems), 0)
+				return
+			}
+			test.AssertEquals(t, prob.Type, probs.BadCSRProblem)
+			test.AssertEquals(t, len(prob.SubProblems), len(tc.expectSubProbs))
+			for i, subProb := range prob.SubProblems {
+				test.AssertEquals(t, subProb.Type, probs.BadCSRProblem)
+				test.AssertContains(t, subProb.Detail, tc.expectSubProbs[i])
+				test.AssertEquals(t, subProb.Identifier, identifier.NewDNS("example.com"))
+			}
+		})
+	}
+}
+
 func TestNewOrderMaxNames(t *testing.T) {
 	_, _, ra, _, _, cleanUp := initAuthorities(t)
 	defer cleanUp()
@@ -3403,6 +6020,66 @@ func TestNewOrderMaxNames(t *testing.T) {
 	test.AssertErrorIs(t, err, berrors.Malformed)
 }
 
+func TestNewOrderNameRejections(t *testing.T) {
+	_, _, ra, _, _, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	testCases := []struct {
+		name       string
+		dnsNames   []string
+		expectType berrors.ErrorType
+		expectSubs map[string]berrors.ErrorType
+	}{
+		{
+			name:       "one name forbidden by policy",
+			dnsNames:   []string{"example.org"},
+			expectType: berrors.RejectedIdentifier,
+		},
+		{
+			name:       "one name forbidden by policy among several",
+			dnsNames:   []string{"example.org", "not-example.com"},
+			expectType: berrors.RejectedIdentifier,
+			expectSubs: map[string]berrors.ErrorType{"example.org": berrors.RejectedIdentifier},
+		},
+		{
+			name:       "one malformed name among several",
+			dnsNames:   []string{"example.comm", "not-example.com"},
+			expectType: berrors.Malformed,
+			expectSubs: map[string]berrors.ErrorType{"example.comm": berrors.Malformed},
+		},
+		{
+			name:       "duplicate names once normalized",
+			dnsNames:   []string{"not-example.com", "NOT-example.com."},
+			expectType: berrors.Malformed,
+		},
+		{
+			name:       "name covered by a wildcard once normalized",
+			dnsNames:   []string{"*.not-example.com", "WWW.not-example.com."},
+			expectType: berrors.Malformed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ra.NewOrder(context.Background(), &rapb.NewOrderRequest{
+				RegistrationID: Registration.Id,
+				DnsNames:       tc.dnsNames,
+			})
+			test.AssertError(t, err, "NewOrder didn't fail")
+			test.AssertErrorIs(t, err, tc.expectType)
+			test.Assert(t, !errors.Is(err, berrors.RateLimit), "policy rejection reported as a rate limit")
+
+			var bErr *berrors.BoulderError
+			test.AssertErrorWraps(t, err, &bErr)
+			test.AssertEquals(t, len(bErr.SubErrors), len(tc.expectSubs))
+			for _, subErr := range bErr.SubErrors {
+				expectType, ok := tc.expectSubs[subErr.Identifier.Value]
+				test.Assert(t, ok, fmt.Sprintf("unexpected suberror for %q", subErr.Identifier.Value))
+				test.AssertEquals(t, subErr.BoulderError.Type, expectType)
+			}
+		})
+	}
+}
+
 // CSR generated by Go:
 // * Random public key
 // * CN = not-example.com
@@ -3967,6 +6644,286 @@ func TestAdministrativelyRevokeCertificate(t *testing.T) {
 	test.AssertError(t, err, "AdministrativelyRevokeCertificate should have failed with just serial for keyCompromise")
 }
 
+func TestAdministrativelyRevokeCertificateAdminPolicy(t *testing.T) {
+	_, _, ra, _, clk, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	ra.OCSP = &mockOCSPA{}
+	ra.purger = &mockPurger{}
+	mockLog := blog.NewMock()
+	ra.log = mockLog
+
+	serial, cert := test.ThrowAwayCert(t, clk)
+	cert.IsCA = true
+	ic, err := issuance.NewCertificate(cert)
+	test.AssertNotError(t, err, "failed to create issuer cert")
+	ra.issuersByNameID = map[issuance.NameID]*issuance.Certificate{
+		ic.NameID(): ic,
+	}
+	mockSA := newMockSARevocation(cert)
+	ra.SA = mockSA
+
+	ra.admins = map[string]*AdminPolicy{
+		"oncall": {MaxRevocationsPerHour: 2},
+		"lead":   {MayRevokeKeyCompromise: true},
+	}
+
+	// An admin who isn't allowlisted is rejected without revoking anything,
+	// and without their name in the error.
+	_, err = ra.AdministrativelyRevokeCertificate(context.Background(), &rapb.AdministrativelyRevokeCertificateRequest{
+		Serial:    serial,
+		Code:      ocsp.Unspecified,
+		AdminName: "mallory",
+	})
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	test.AssertNotContains(t, err.Error(), "mallory")
+	test.AssertEquals(t, len(mockSA.revoked), 0)
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`"AdminName":"mallory".*"AdminChecks":\["admin not allowlisted"\]`)), 1)
+
+	// An admin without the keyCompromise capability can't use it.
+	_, err = ra.AdministrativelyRevokeCertificate(context.Background(), &rapb.AdministrativelyRevokeCertificateRequest{
+		Serial:    serial,
+		Code:      ocsp.KeyCompromise,
+		AdminName: "oncall",
+	})
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	test.AssertEquals(t, len(mockSA.revoked), 0)
+
+	// An admin with the keyCompromise capability may still not skip key
+	// blocking.
+	_, err = ra.AdministrativelyRevokeCertificate(context.Background(), &rapb.AdministrativelyRevokeCertificateRequest{
+		Serial:       serial,
+		Code:         ocsp.KeyCompromise,
+		AdminName:    "lead",
+		SkipBlockKey: true,
+	})
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	test.AssertEquals(t, len(mockSA.revoked), 0)
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`"AdminChecks":\["admin allowlisted","keyCompromise permitted","skipBlockKey not permitted"\]`)), 1)
+
+	// A revocation which fails doesn't count against the hourly cap.
+	_, err = ra.AdministrativelyRevokeCertificate(context.Background(), &rapb.AdministrativelyRevokeCertificateRequest{
+		Serial:    "deadbeef",
+		Code:      ocsp.Unspecified,
+		AdminName: "oncall",
+	})
+	test.AssertError(t, err, "revoking an unknown serial should fail")
+	test.AssertEquals(t, len(mockSA.revoked), 0)
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`"SerialNumber":"deadbeef".*"AdminChecks":\["admin allowlisted","1 of hourly cap of 2","hourly cap refunded"\]`)), 1)
+
+	// The hourly cap permits two revocations and then rejects a third.
+	for range 2 {
+		mockSA.reset()
+		_, err = ra.AdministrativelyRevokeCertificate(context.Background(), &rapb.AdministrativelyRevokeCertificateRequest{
+			Serial:    serial,
+			Code:      ocsp.Unspecified,
+			AdminName: "oncall",
+		})
+		test.AssertNotError(t, err, "AdministrativelyRevokeCertificate failed")
+		test.AssertEquals(t, len(mockSA.revoked), 1)
+	}
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`"SerialNumber":"`+serial+`".*"AdminName":"oncall","AdminChecks":\["admin allowlisted","2 of hourly cap of 2"\]`)), 1)
+
+	mockSA.reset()
+	_, err = ra.AdministrativelyRevokeCertificate(context.Background(), &rapb.AdministrativelyRevokeCertificateRequest{
+		Serial:    serial,
+		Code:      ocsp.Unspecified,
+		AdminName: "oncall",
+	})
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+	test.AssertEquals(t, len(mockSA.revoked), 0)
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`"AdminChecks":\["admin allowlisted","hourly cap of 2 exceeded"\]`)), 1)
+
+	// Once an hour has passed the admin may revoke again.
+	clk.Add(time.Hour)
+	_, err = ra.AdministrativelyRevokeCertificate(context.Background(), &rapb.AdministrativelyRevokeCertificateRequest{
+		Serial:    serial,
+		Code:      ocsp.Unspecified,
+		AdminName: "oncall",
+	})
+	test.AssertNotError(t, err, "AdministrativelyRevokeCertificate failed")
+	test.AssertEquals(t, len(mockSA.revoked), 1)
+}
+
+// mockSARevocationByKey extends mockSARevocation with GetSerialsByKey, which
+// returns all of the known serials for keyHash, and makes it safe for the
+// concurrent revocations performed by RevokeCertificatesByKeyHash. Revoking
+// any of the serials in fail returns an error.
+type mockSARevocationByKey struct {
+	*mockSARevocation
+	sync.Mutex
+
+	keyHash []byte
+	fail    map[string]bool
+}
+
+func (msar *mockSARevocationByKey) GetSerialsByKey(_ context.Context, req *sapb.SPKIHash, _ ...grpc.CallOption) (grpc.ServerStreamingClient[sapb.Serial], error) {
+	if !bytes.Equal(req.KeyHash, msar.keyHash) {
+		return &mocks.ServerStreamClient[sapb.Serial]{}, nil
+	}
+	var results []*sapb.Serial
+	for serial := range msar.known {
+		results = append(results, &sapb.Serial{Serial: serial})
+	}
+	return &mocks.ServerStreamClient[sapb.Serial]{Results: results}, nil
+}
+
+func (msar *mockSARevocationByKey) GetLintPrecertificate(ctx context.Context, req *sapb.Serial, _ ...grpc.CallOption) (*corepb.Certificate, error) {
+	msar.Lock()
+	defer msar.Unlock()
+	return msar.mockSARevocation.GetLintPrecertificate(ctx, req)
+}
+
+func (msar *mockSARevocationByKey) RevokeCertificate(ctx context.Context, req *sapb.RevokeCertificateRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	msar.Lock()
+	defer msar.Unlock()
+	if msar.fail[req.Serial] {
+		return nil, errors.New("oops")
+	}
+	return msar.mockSARevocation.RevokeCertificate(ctx, req)
+}
+
+func (msar *mockSARevocationByKey) AddBlockedKey(ctx context.Context, req *sapb.AddBlockedKeyRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	msar.Lock()
+	defer msar.Unlock()
+	return msar.mockSARevocation.AddBlockedKey(ctx, req)
+}
+
+// revokeByKeyHashStream is a fake server stream which collects the results
+// sent by RevokeCertificatesByKeyHash.
+type revokeByKeyHashStream struct {
+	grpc.ServerStream
+	results []*rapb.RevokeCertificatesByKeyHashResponse
+}
+
+func (s *revokeByKeyHashStream) Send(resp *rapb.RevokeCertificatesByKeyHashResponse) error {
+	s.results = append(s.results, resp)
+	return nil
+}
+
+func (s *revokeByKeyHashStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestRevokeCertificatesByKeyHash(t *testing.T) {
+	_, _, ra, _, clk, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	ra.OCSP = &mockOCSPA{}
+	ra.purger = &mockPurger{}
+
+	// Every throwaway cert is self-signed with an empty subject, so they all
+	// share a single issuer NameID.
+	_, issuer := test.ThrowAwayCert(t, clk)
+	issuer.IsCA = true
+	ic, err := issuance.NewCertificate(issuer)
+	test.AssertNotError(t, err, "failed to create issuer cert")
+	ra.issuersByNameID = map[issuance.NameID]*issuance.Certificate{
+		ic.NameID(): ic,
+	}
+
+	keyHash := []byte("compromised key hash")
+	newMockSA := func(count int) *mockSARevocationByKey {
+		msa := &mockSARevocationByKey{
+			mockSARevocation: &mockSARevocation{
+				known:   make(map[string]*x509.Certificate),
+				revoked: make(map[string]*corepb.CertificateStatus),
+			},
+			keyHash: keyHash,
+		}
+		for range count {
+			serial, cert := test.ThrowAwayCert(t, clk)
+			msa.known[serial] = cert
+		}
+		return msa
+	}
+
+	// An incomplete request fails immediately.
+	err = ra.RevokeCertificatesByKeyHash(&rapb.RevokeCertificatesByKeyHashRequest{Code: ocsp.KeyCompromise, AdminName: "root"}, &revokeByKeyHashStream{})
+	test.AssertError(t, err, "RevokeCertificatesByKeyHash should have failed without a key hash")
+
+	testCases := []struct {
+		name  string
+		count int
+		fail  int
+	}{
+		{name: "no matches", count: 0},
+		{name: "one match", count: 1},
+		{name: "many matches", count: 2*revokeByKeyHashBatchSize + 5},
+		{name: "many matches with a mid-batch failure", count: 2*revokeByKeyHashBatchSize + 5, fail: 1},
+		{name: "every revocation fails", count: 2, fail: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSA := newMockSA(tc.count)
+			ra.SA = mockSA
+			mockSA.fail = make(map[string]bool)
+			for serial := range mockSA.known {
+				if len(mockSA.fail) == tc.fail {
+					break
+				}
+				mockSA.fail[serial] = true
+			}
+
+			// A dry run reports every serial without revoking or blocking.
+			stream := &revokeByKeyHashStream{}
+			err := ra.RevokeCertificatesByKeyHash(&rapb.RevokeCertificatesByKeyHashRequest{
+				KeyHash:   keyHash,
+				Code:      ocsp.KeyCompromise,
+				AdminName: "root",
+				DryRun:    true,
+			}, stream)
+			test.AssertNotError(t, err, "dry run failed")
+			test.AssertEquals(t, len(stream.results), tc.count)
+			test.AssertEquals(t, len(mockSA.revoked), 0)
+			test.AssertEquals(t, len(mockSA.blocked), 0)
+
+			stream = &revokeByKeyHashStream{}
+			err = ra.RevokeCertificatesByKeyHash(&rapb.RevokeCertificatesByKeyHashRequest{
+				KeyHash:   keyHash,
+				Code:      ocsp.KeyCompromise,
+				AdminName: "root",
+			}, stream)
+			test.AssertNotError(t, err, "RevokeCertificatesByKeyHash failed")
+			test.AssertEquals(t, len(stream.results), tc.count)
+			test.AssertEquals(t, len(mockSA.revoked), tc.count-tc.fail)
+			for _, result := range stream.results {
+				_, known := mockSA.known[result.Serial]
+				test.Assert(t, known, "unexpected serial in results")
+				if mockSA.fail[result.Serial] {
+					test.AssertContains(t, result.Error, "oops")
+				} else {
+					test.AssertEquals(t, result.Error, "")
+					test.AssertEquals(t, mockSA.revoked[result.Serial].RevokedReason, int64(ocsp.KeyCompromise))
+				}
+			}
+
+			// The key is blocked exactly once, regardless of the number of
+			// certificates, but only if at least one of them was revoked.
+			if tc.count == tc.fail {
+				test.AssertEquals(t, len(mockSA.blocked), 0)
+				return
+			}
+			test.AssertEquals(t, len(mockSA.blocked), 1)
+			test.AssertByteEquals(t, mockSA.blocked[0].KeyHash, keyHash)
+			test.AssertEquals(t, mockSA.blocked[0].Comment, "revoked by root")
+		})
+	}
+
+	// A reason other than keyCompromise revokes without blocking the key.
+	mockSA := newMockSA(1)
+	ra.SA = mockSA
+	stream := &revokeByKeyHashStream{}
+	err = ra.RevokeCertificatesByKeyHash(&rapb.RevokeCertificatesByKeyHashRequest{
+		KeyHash:   keyHash,
+		Code:      ocsp.Superseded,
+		AdminName: "root",
+	}, stream)
+	test.AssertNotError(t, err, "RevokeCertificatesByKeyHash failed")
+	test.AssertEquals(t, len(mockSA.revoked), 1)
+	test.AssertEquals(t, len(mockSA.blocked), 0)
+}
+
 // An authority that returns an error from NewOrderAndAuthzs if the
 // "ReplacesSerial" field of the request is empty.
 type mockNewOrderMustBeReplacementAuthority struct {
@@ -4233,6 +7190,213 @@ func TestUpdateRegistrationKey(t *testing.T) {
 	test.AssertContains(t, err.Error(), "mocked to always error")
 }
 
+// mockSAKeyRollover is a mock SA which stores registrations by ID, and allows
+// their keys to be updated. Like the real SA, it rejects a key rollover digest
+// it has already seen.
+type mockSAKeyRollover struct {
+	sapb.StorageAuthorityClient
+	regs      map[int64]*corepb.Registration
+	rollovers map[string]bool
+}
+
+func (sa *mockSAKeyRollover) GetRegistration(_ context.Context, req *sapb.RegistrationID, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	reg, ok := sa.regs[req.Id]
+	if !ok {
+		return nil, berrors.NotFoundError("no registration with ID %d", req.Id)
+	}
+	return proto.Clone(reg).(*corepb.Registration), nil
+}
+
+func (sa *mockSAKeyRollover) GetRegistrationByKey(_ context.Context, req *sapb.JSONWebKey, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	sha, err := core.JWKDigestB64(req.Jwk)
+	if err != nil {
+		return nil, err
+	}
+	for _, reg := range sa.regs {
+		regSHA, err := core.JWKDigestB64(reg.Key)
+		if err == nil && regSHA == sha {
+			return proto.Clone(reg).(*corepb.Registration), nil
+		}
+	}
+	return nil, berrors.NotFoundError("no registrations with public key sha256 %q", sha)
+}
+
+func (sa *mockSAKeyRollover) UpdateRegistrationKey(_ context.Context, req *sapb.UpdateRegistrationKeyRequest, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	reg, ok := sa.regs[req.RegistrationID]
+	if !ok {
+		return nil, berrors.NotFoundError("no registration with ID %d", req.RegistrationID)
+	}
+	if len(req.KeyRolloverDigest) > 0 {
+		if sa.rollovers[string(req.KeyRolloverDigest)] {
+			return nil, berrors.MalformedError("key rollover request has already been used")
+		}
+		sa.rollovers[string(req.KeyRolloverDigest)] = true
+	}
+	reg.Key = req.Jwk
+	return proto.Clone(reg).(*corepb.Registration), nil
+}
+
+// signKeyRollover returns the inner JWS of a key-change request, signed by
+// newKey with it embedded, requesting that the account with the provided URL
+// have its key changed from oldKey.
+func signKeyRollover(t *testing.T, newKey crypto.Signer, account string, oldKey crypto.PublicKey) []byte {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: newKey}, &jose.SignerOptions{
+		EmbedJWK:     true,
+		ExtraHeaders: map[jose.HeaderKey]interface{}{"url": "http://localhost/acme/key-change"},
+	})
+	test.AssertNotError(t, err, "creating signer")
+	payload, err := json.Marshal(keyRolloverRequest{Account: account, OldKey: jose.JSONWebKey{Key: oldKey}})
+	test.AssertNotError(t, err, "marshaling key rollover request")
+	jws, err := signer.Sign(payload)
+	test.AssertNotError(t, err, "signing key rollover request")
+	return []byte(jws.FullSerialize())
+}
+
+func TestKeyRollover(t *testing.T) {
+	t.Parallel()
+
+	newECKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		test.AssertNotError(t, err, "generating key")
+		return key
+	}
+	jwkJSON := func(key *ecdsa.PrivateKey) []byte {
+		jwk, err := jose.JSONWebKey{Key: key.Public()}.MarshalJSON()
+		test.AssertNotError(t, err, "marshaling JWK")
+		return jwk
+	}
+	oldKey, newKey, otherKey, blockedKey := newECKey(), newECKey(), newECKey(), newECKey()
+	blockedDigest, err := core.KeyDigest(blockedKey.Public())
+	test.AssertNotError(t, err, "computing key digest")
+	keyPolicy, err := goodkey.NewPolicy(nil, func(_ context.Context, keyHash []byte) (bool, error) {
+		return bytes.Equal(keyHash, blockedDigest[:]), nil
+	})
+	test.AssertNotError(t, err, "creating key policy")
+
+	mockSA := &mockSAKeyRollover{regs: map[int64]*corepb.Registration{
+		1: {Id: 1, Key: jwkJSON(oldKey), Status: string(core.StatusValid)},
+		2: {Id: 2, Key: jwkJSON(otherKey), Status: string(core.StatusValid)},
+	}, rollovers: make(map[string]bool)}
+	mockLog := blog.NewMock()
+	ra := &RegistrationAuthorityImpl{
+		SA:        mockSA,
+		keyPolicy: keyPolicy,
+		log:       mockLog,
+	}
+	account := "http://localhost/acme/acct/1"
+
+	_, err = ra.KeyRollover(ctx, &rapb.KeyRolloverRequest{RegistrationID: 1})
+	test.AssertErrorIs(t, err, errIncompleteGRPCRequest)
+
+	_, err = ra.KeyRollover(ctx, &rapb.KeyRolloverRequest{RegistrationID: 1, InnerJWS: []byte("{}")})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	test.AssertContains(t, err.Error(), "failed to parse inner JWS")
+
+	// An inner JWS whose payload was swapped after signing doesn't verify.
+	var tampered map[string]string
+	err = json.Unmarshal(signKeyRollover(t, newKey, account, oldKey.Public()), &tampered)
+	test.AssertNotError(t, err, "unmarshaling JWS")
+	var other map[string]string
+	err = json.Unmarshal(signKeyRollover(t, newKey, "http://localhost/acme/acct/2", otherKey.Public()), &other)
+	test.AssertNotError(t, err, "unmarshaling JWS")
+	tampered["payload"] = other["payload"]
+	tamperedJWS, err := json.Marshal(tampered)
+	test.AssertNotError(t, err, "marshaling JWS")
+
+	testCases := []struct {
+		name        string
+		regID       int64
+		innerJWS    []byte
+		expectErr   berrors.ErrorType
+		expectError string
+	}{
+		{
+			name:        "signature doesn't verify",
+			regID:       1,
+			innerJWS:    tamperedJWS,
+			expectErr:   berrors.Malformed,
+			expectError: "inner JWS does not verify with embedded JWK",
+		},
+		{
+			name:        "wrong old key",
+			regID:       1,
+			innerJWS:    signKeyRollover(t, newKey, account, otherKey.Public()),
+			expectErr:   berrors.Malformed,
+			expectError: "inner JWS does not contain old key field matching current account key",
+		},
+		{
+			name:        "wrong account",
+			regID:       1,
+			innerJWS:    signKeyRollover(t, newKey, "http://localhost/acme/acct/11", oldKey.Public()),
+			expectErr:   berrors.Malformed,
+			expectError: `inner JWS specifies account "http://localhost/acme/acct/11", not account 1`,
+		},
+		{
+			name:        "unchanged key",
+			regID:       1,
+			innerJWS:    signKeyRollover(t, oldKey, account, oldKey.Public()),
+			expectErr:   berrors.Malformed,
+			expectError: "same as the old key",
+		},
+		{
+			name:        "key of another account",
+			regID:       1,
+			innerJWS:    signKeyRollover(t, otherKey, account, oldKey.Public()),
+			expectErr:   berrors.Duplicate,
+			expectError: "new key is already in use for account 2",
+		},
+		{
+			name:        "blocked key",
+			regID:       1,
+			innerJWS:    signKeyRollover(t, blockedKey, account, oldKey.Public()),
+			expectErr:   berrors.BadPublicKey,
+			expectError: "public key is forbidden",
+		},
+		{
+			name:        "unknown account",
+			regID:       3,
+			innerJWS:    signKeyRollover(t, newKey, "http://localhost/acme/acct/3", oldKey.Public()),
+			expectErr:   berrors.NotFound,
+			expectError: "no registration with ID 3",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := ra.KeyRollover(ctx, &rapb.KeyRolloverRequest{RegistrationID: tc.regID, InnerJWS: tc.innerJWS})
+		test.AssertErrorIs(t, err, tc.expectErr)
+		test.AssertContains(t, err.Error(), tc.expectError)
+		test.AssertByteEquals(t, mockSA.regs[1].Key, jwkJSON(oldKey))
+	}
+
+	// A request which fails because the account isn't valid can be retried
+	// once it is.
+	innerJWS := signKeyRollover(t, newKey, account, oldKey.Public())
+	mockSA.regs[1].Status = string(core.StatusDeactivated)
+	_, err = ra.KeyRollover(ctx, &rapb.KeyRolloverRequest{RegistrationID: 1, InnerJWS: innerJWS})
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	mockSA.regs[1].Status = string(core.StatusValid)
+
+	reg, err := ra.KeyRollover(ctx, &rapb.KeyRolloverRequest{RegistrationID: 1, InnerJWS: innerJWS})
+	test.AssertNotError(t, err, "rolling over key")
+	test.AssertByteEquals(t, reg.Key, jwkJSON(newKey))
+	test.AssertByteEquals(t, mockSA.regs[1].Key, jwkJSON(newKey))
+	oldDigest, _ := core.KeyDigestB64(oldKey.Public())
+	newDigest, _ := core.KeyDigestB64(newKey.Public())
+	test.AssertEquals(t, len(mockLog.GetAllMatching(fmt.Sprintf(
+		`Account key rollover: regID=\[1\] oldKeyDigest=\[%s\] newKeyDigest=\[%s\]`,
+		regexp.QuoteMeta(oldDigest), regexp.QuoteMeta(newDigest)))), 1)
+
+	// Replaying the request fails, even once the account's key has been
+	// changed back.
+	_, err = ra.KeyRollover(ctx, &rapb.KeyRolloverRequest{RegistrationID: 1, InnerJWS: innerJWS})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	mockSA.regs[1].Key = jwkJSON(oldKey)
+	_, err = ra.KeyRollover(ctx, &rapb.KeyRolloverRequest{RegistrationID: 1, InnerJWS: innerJWS})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	test.AssertContains(t, err.Error(), "key rollover request has already been used")
+	test.AssertByteEquals(t, mockSA.regs[1].Key, jwkJSON(oldKey))
+}
+
 func TestCRLShard(t *testing.T) {
 	var cdp []string
 	n, err := crlShard(&x509.Certificate{CRLDistributionPoints: cdp})
@@ -0,0 +1,119 @@
+package ra
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// defaultValidationWorkers is the number of validations an RA performs at
+	// once, if RegistrationAuthorityImpl.ValidationWorkers is unset.
+	defaultValidationWorkers = 500
+
+	// defaultValidationQueueSize is the number of validations an RA holds
+	// waiting for a worker, if RegistrationAuthorityImpl.ValidationQueueSize
+	// is unset.
+	defaultValidationQueueSize = 5000
+)
+
+// validationQueue performs validations, which PerformValidation schedules so
+// that it can respond without waiting for the VA, with a bounded pool of
+// workers. Validations which can't be queued are refused, rather than
+// spawning unbounded goroutines.
+type validationQueue struct {
+	depth    prometheus.Gauge
+	busy     prometheus.Gauge
+	enqueues *prometheus.CounterVec
+
+	work    chan func()
+	workers sync.WaitGroup
+
+	// mu guards draining, and the sending on and closing of work.
+	mu       sync.RWMutex
+	draining bool
+}
+
+// newValidationQueue returns a *validationQueue, whose metrics are registered
+// with stats, with workers workers already taking validations from a queue of
+// up to size validations. If either is zero, its default is used.
+func newValidationQueue(workers, size int, stats prometheus.Registerer) *validationQueue {
+	depth := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "validation_queue_depth",
+		Help: "Number of validations waiting for a worker",
+	})
+	stats.MustRegister(depth)
+
+	busy := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "validation_workers_busy",
+		Help: "Number of validation workers performing a validation",
+	})
+	stats.MustRegister(busy)
+
+	enqueues := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "validation_enqueues",
+		Help: "Number of validations scheduled by PerformValidation, labeled by result=[queued|full|draining]",
+	}, []string{"result"})
+	stats.MustRegister(enqueues)
+
+	if workers <= 0 {
+		workers = defaultValidationWorkers
+	}
+	if size <= 0 {
+		size = defaultValidationQueueSize
+	}
+	q := &validationQueue{
+		depth:    depth,
+		busy:     busy,
+		enqueues: enqueues,
+		work:     make(chan func(), size),
+	}
+	for range workers {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			for validate := range q.work {
+				q.depth.Dec()
+				q.busy.Inc()
+				validate()
+				q.busy.Dec()
+			}
+		}()
+	}
+	return q
+}
+
+// enqueue schedules validate to be performed by a worker. It returns false,
+// without scheduling validate, if the queue is full or being drained.
+func (q *validationQueue) enqueue(validate func()) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.draining {
+		q.enqueues.WithLabelValues("draining").Inc()
+		return false
+	}
+	// The depth is incremented first so that a worker can't decrement it
+	// before it's incremented.
+	q.depth.Inc()
+	select {
+	case q.work <- validate:
+		q.enqueues.WithLabelValues("queued").Inc()
+		return true
+	default:
+		q.depth.Dec()
+		q.enqueues.WithLabelValues("full").Inc()
+		return false
+	}
+}
+
+// drain refuses further validations, and blocks until those already queued
+// have been performed.
+func (q *validationQueue) drain() {
+	q.mu.Lock()
+	if !q.draining {
+		q.draining = true
+		close(q.work)
+	}
+	q.mu.Unlock()
+	q.workers.Wait()
+}
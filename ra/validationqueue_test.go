@@ -0,0 +1,61 @@
+package ra
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestValidationQueue(t *testing.T) {
+	t.Parallel()
+
+	// A single worker, blocked on its first validation, with room for one
+	// more in the queue.
+	q := newValidationQueue(1, 1, metrics.NoopRegisterer)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var completed atomic.Int64
+	test.Assert(t, q.enqueue(func() {
+		close(started)
+		<-release
+		completed.Add(1)
+	}), "enqueue should succeed")
+	<-started
+	test.AssertMetricWithLabelsEquals(t, q.busy, nil, 1)
+
+	test.Assert(t, q.enqueue(func() { completed.Add(1) }), "enqueue should succeed while the queue has room")
+	test.AssertMetricWithLabelsEquals(t, q.depth, nil, 1)
+
+	// The queue is full.
+	test.Assert(t, !q.enqueue(func() { completed.Add(1) }), "enqueue should fail when the queue is full")
+	test.AssertMetricWithLabelsEquals(t, q.enqueues, prometheus.Labels{"result": "queued"}, 2)
+	test.AssertMetricWithLabelsEquals(t, q.enqueues, prometheus.Labels{"result": "full"}, 1)
+
+	// Draining waits for the queued validations, and refuses more.
+	drained := make(chan struct{})
+	go func() {
+		q.drain()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		t.Fatal("drain returned while a validation was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-drained
+	test.AssertEquals(t, completed.Load(), int64(2))
+	test.AssertMetricWithLabelsEquals(t, q.depth, nil, 0)
+	test.AssertMetricWithLabelsEquals(t, q.busy, nil, 0)
+
+	test.Assert(t, !q.enqueue(func() {}), "enqueue should fail once draining")
+	test.AssertMetricWithLabelsEquals(t, q.enqueues, prometheus.Labels{"result": "draining"}, 1)
+
+	// Draining again does nothing.
+	q.drain()
+}
@@ -0,0 +1,65 @@
+package ra
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// vaOverloadRetries is how many times a request which an overloaded VA
+	// shed is retried before the validation is abandoned.
+	vaOverloadRetries = 2
+
+	// maxVAPushback caps how long the RA waits before retrying a request which
+	// an overloaded VA shed, whatever the VA's pushback hint.
+	maxVAPushback = 5 * time.Second
+
+	// defaultVAPushback is how long the RA waits before retrying a request
+	// which an overloaded VA shed without a pushback hint.
+	defaultVAPushback = time.Second
+)
+
+// errVAOverloaded is returned when the VA sheds a request, and every retry,
+// because it is at capacity. The validation wasn't attempted, so it mustn't
+// count against the authorization.
+var errVAOverloaded = errors.New("VA is overloaded")
+
+// vaPushback returns how long to wait before retrying a request which the VA
+// shed, from the grpc-retry-pushback-ms hint in the trailer md.
+func vaPushback(md metadata.MD) time.Duration {
+	values := md.Get("grpc-retry-pushback-ms")
+	if len(values) != 1 {
+		return defaultVAPushback
+	}
+	ms, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || ms < 0 {
+		return defaultVAPushback
+	}
+	return min(time.Duration(ms)*time.Millisecond, maxVAPushback)
+}
+
+// callVA makes a VA request with call, retrying up to vaOverloadRetries times,
+// after the VA's pushback hint, if the VA sheds it with
+// codes.ResourceExhausted. If the VA sheds every attempt, it returns an error
+// wrapping errVAOverloaded.
+func callVA[T any](ra *RegistrationAuthorityImpl, call func(...grpc.CallOption) (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		md := metadata.MD{}
+		resp, err := call(grpc.Trailer(&md))
+		if status.Code(err) != codes.ResourceExhausted {
+			return resp, err
+		}
+		if attempt == vaOverloadRetries {
+			ra.vaOverloads.WithLabelValues("abandoned").Inc()
+			return resp, errors.Join(errVAOverloaded, err)
+		}
+		ra.vaOverloads.WithLabelValues("retried").Inc()
+		ra.clk.Sleep(vaPushback(md))
+	}
+}
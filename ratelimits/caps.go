@@ -0,0 +1,124 @@
+package ratelimits
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// Results of capping an override, used as labels of the
+// ratelimits_override_capped metric.
+const (
+	capClamped  = "clamped"
+	capRejected = "rejected"
+)
+
+// OverrideCap is the largest burst and count which an override of a limit may
+// have. A zero field is uncapped.
+type OverrideCap struct {
+	MaxBurst int64 `validate:"gte=0"`
+	MaxCount int64 `validate:"gte=0"`
+}
+
+// defaultOverrideCaps are the caps applied to overrides of each Name unless
+// replaced by EnforceOverrideCaps. They're far above any override granted in
+// practice, and exist only to catch typos. Default limits are never capped.
+var defaultOverrideCaps = map[Name]OverrideCap{
+	NewRegistrationsPerIPAddress:                      {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	NewRegistrationsPerIPv6Range:                      {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	NewOrdersPerAccount:                               {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	FailedAuthorizationsPerDomainPerAccount:           {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	CertificatesPerDomain:                             {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	CertificatesPerDomainPerAccount:                   {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	CertificatesPerFQDNSet:                            {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	FailedAuthorizationsForPausingPerDomainPerAccount: {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	FailedValidationsPerDomainPerAccount:              {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+	GlobalIssuanceRate:                                {MaxBurst: 1_000_000_000, MaxCount: 1_000_000_000},
+	NewOrdersPerDomain:                                {MaxBurst: 1_000_000_000, MaxCount: 1_000_000_000},
+	WillingnessChecksPerAccount:                       {MaxBurst: 1_000_000, MaxCount: 1_000_000},
+}
+
+// overrideCapper caps the burst and count of override limits. A nil
+// *overrideCapper clamps overrides to defaultOverrideCaps, without logging or
+// metrics.
+type overrideCapper struct {
+	caps map[Name]OverrideCap
+
+	// strict, if set, rejects overrides above their cap rather than clamping
+	// them.
+	strict bool
+	log    blog.Logger
+	capped *prometheus.CounterVec
+}
+
+// newOverrideCapper returns an *overrideCapper which applies caps, keyed by
+// the string representation of each Name, in place of the defaults of the
+// same Names.
+func newOverrideCapper(caps map[string]OverrideCap, strict bool, stats prometheus.Registerer, logger blog.Logger) (*overrideCapper, error) {
+	merged := maps.Clone(defaultOverrideCaps)
+	for k, v := range caps {
+		name, ok := stringToName[k]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized name %q in override caps, must be one of %v", k, limitNames)
+		}
+		if v.MaxBurst < 0 || v.MaxCount < 0 {
+			return nil, fmt.Errorf("invalid override cap for %q, MaxBurst and MaxCount must be >= 0", k)
+		}
+		merged[name] = v
+	}
+
+	capped := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_override_capped",
+		Help: fmt.Sprintf("Number of overrides of limit=[name] loaded with a burst or count above their cap, labeled by result=[%s|%s]", capClamped, capRejected),
+	}, []string{"limit", "result"})
+	stats.MustRegister(capped)
+
+	return &overrideCapper{
+		caps:   merged,
+		strict: strict,
+		log:    logger,
+		capped: capped,
+	}, nil
+}
+
+// applyOverride prepares an override limit, parsed from any source, for the
+// registry. It clamps the burst and count of lim to the cap of its Name, or
+// in strict mode returns an error if either is above it, then precomputes and
+// validates it. ids are the ids of the overrides which share lim, and are
+// only used for logging. Every override must pass through applyOverride, so
+// that no source of overrides can bypass the caps.
+func (c *overrideCapper) applyOverride(lim *limit, ids []string) error {
+	caps := defaultOverrideCaps
+	if c != nil {
+		caps = c.caps
+	}
+	limCap := caps[lim.name]
+
+	overBurst := limCap.MaxBurst > 0 && lim.burst > limCap.MaxBurst
+	overCount := limCap.MaxCount > 0 && lim.count > limCap.MaxCount
+	if overBurst || overCount {
+		if c != nil && c.strict {
+			c.capped.WithLabelValues(lim.name.String(), capRejected).Inc()
+			return fmt.Errorf("override burst %d and count %d exceed the cap of burst %d and count %d",
+				lim.burst, lim.count, limCap.MaxBurst, limCap.MaxCount)
+		}
+		if c != nil {
+			c.capped.WithLabelValues(lim.name.String(), capClamped).Inc()
+			c.log.Warningf("Clamping override of %s for ids [%s] from burst %d and count %d to the cap of burst %d and count %d",
+				lim.name, strings.Join(ids, ", "), lim.burst, lim.count, limCap.MaxBurst, limCap.MaxCount)
+		}
+		if overBurst {
+			lim.burst = limCap.MaxBurst
+		}
+		if overCount {
+			lim.count = limCap.MaxCount
+		}
+	}
+
+	lim.precompute()
+	return validateLimit(lim)
+}
@@ -0,0 +1,152 @@
+package ratelimits
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/config"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func newCapsTestOverrides(burst, count int64) overridesYAML {
+	return overridesYAML{{
+		CertificatesPerDomain.String(): overrideYAML{
+			LimitConfig: LimitConfig{Burst: burst, Count: count, Period: config.Duration{Duration: 7 * 24 * time.Hour}},
+			Ids:         []overrideIdYAML{{Id: "example.com"}, {Id: "example.net"}},
+		},
+	}}
+}
+
+func TestApplyOverrideClamps(t *testing.T) {
+	t.Parallel()
+	key := joinWithColon(CertificatesPerDomain.EnumString(), "example.com")
+
+	// Without configured caps, overrides are clamped to the defaults.
+	parsed, err := parseOverrideLimits(newCapsTestOverrides(5_000_000, 5_000_000), nil)
+	test.AssertNotError(t, err, "parsing overrides")
+	test.AssertEquals(t, parsed[key].burst, defaultOverrideCaps[CertificatesPerDomain].MaxBurst)
+	test.AssertEquals(t, parsed[key].count, defaultOverrideCaps[CertificatesPerDomain].MaxCount)
+
+	mockLog := blog.NewMock()
+	caps, err := newOverrideCapper(map[string]OverrideCap{
+		CertificatesPerDomain.String(): {MaxBurst: 1000, MaxCount: 500},
+	}, false, prometheus.NewRegistry(), mockLog)
+	test.AssertNotError(t, err, "creating capper")
+
+	// Only the field above its cap is clamped, and the precomputed fields
+	// reflect the clamped values.
+	parsed, err = parseOverrideLimits(newCapsTestOverrides(800, 5_000_000), caps)
+	test.AssertNotError(t, err, "parsing overrides")
+	test.AssertEquals(t, parsed[key].burst, int64(800))
+	test.AssertEquals(t, parsed[key].count, int64(500))
+	test.AssertEquals(t, parsed[key].emissionInterval, (7*24*time.Hour).Nanoseconds()/500)
+	test.AssertMetricWithLabelsEquals(t, caps.capped, prometheus.Labels{"limit": CertificatesPerDomain.String(), "result": capClamped}, 1)
+	test.AssertEquals(t, len(mockLog.GetAllMatching(
+		`Clamping override of CertificatesPerDomain for ids \[example.com, example.net\] from burst 800 and count 5000000 to the cap of burst 1000 and count 500`)), 1)
+
+	// Overrides within their cap are untouched.
+	parsed, err = parseOverrideLimits(newCapsTestOverrides(1000, 500), caps)
+	test.AssertNotError(t, err, "parsing overrides")
+	test.AssertEquals(t, parsed[key].burst, int64(1000))
+	test.AssertEquals(t, parsed[key].count, int64(500))
+	test.AssertMetricWithLabelsEquals(t, caps.capped, prometheus.Labels{"limit": CertificatesPerDomain.String(), "result": capClamped}, 1)
+
+	// A zero cap is uncapped.
+	caps, err = newOverrideCapper(map[string]OverrideCap{
+		CertificatesPerDomain.String(): {},
+	}, false, prometheus.NewRegistry(), mockLog)
+	test.AssertNotError(t, err, "creating capper")
+	parsed, err = parseOverrideLimits(newCapsTestOverrides(5_000_000, 5_000_000), caps)
+	test.AssertNotError(t, err, "parsing overrides")
+	test.AssertEquals(t, parsed[key].burst, int64(5_000_000))
+}
+
+func TestApplyOverrideStrict(t *testing.T) {
+	t.Parallel()
+
+	caps, err := newOverrideCapper(map[string]OverrideCap{
+		CertificatesPerDomain.String(): {MaxBurst: 1000, MaxCount: 1000},
+	}, true, prometheus.NewRegistry(), blog.NewMock())
+	test.AssertNotError(t, err, "creating capper")
+
+	_, err = parseOverrideLimits(newCapsTestOverrides(5_000_000, 1000), caps)
+	test.AssertError(t, err, "override above its cap should be rejected")
+	test.AssertContains(t, err.Error(), "exceed the cap of burst 1000 and count 1000")
+	test.AssertMetricWithLabelsEquals(t, caps.capped, prometheus.Labels{"limit": CertificatesPerDomain.String(), "result": capRejected}, 1)
+
+	_, err = parseOverrideLimits(newCapsTestOverrides(1000, 1000), caps)
+	test.AssertNotError(t, err, "override at its cap should be accepted")
+}
+
+func TestNewOverrideCapper(t *testing.T) {
+	t.Parallel()
+
+	_, err := newOverrideCapper(map[string]OverrideCap{"CertificatesPerDomainz": {MaxBurst: 1}}, false, prometheus.NewRegistry(), blog.NewMock())
+	test.AssertError(t, err, "unknown name should be rejected")
+	_, err = newOverrideCapper(map[string]OverrideCap{CertificatesPerDomain.String(): {MaxBurst: -1}}, false, prometheus.NewRegistry(), blog.NewMock())
+	test.AssertError(t, err, "negative cap should be rejected")
+
+	// Names without a configured cap keep their default.
+	caps, err := newOverrideCapper(map[string]OverrideCap{CertificatesPerDomain.String(): {MaxBurst: 1}}, false, prometheus.NewRegistry(), blog.NewMock())
+	test.AssertNotError(t, err, "creating capper")
+	test.AssertEquals(t, caps.caps[CertificatesPerDomain], OverrideCap{MaxBurst: 1})
+	test.AssertEquals(t, caps.caps[NewOrdersPerAccount], defaultOverrideCaps[NewOrdersPerAccount])
+}
+
+func TestEnforceOverrideCaps(t *testing.T) {
+	t.Parallel()
+
+	// Defaults are exempt from the caps, and the overrides already loaded are
+	// capped.
+	registry, err := newLimitRegistry(LimitConfigs{
+		CertificatesPerDomain.String(): &LimitConfig{Burst: 5_000_000, Count: 5_000_000, Period: config.Duration{Duration: time.Hour}},
+	}, newCapsTestOverrides(5000, 5000), nil)
+	test.AssertNotError(t, err, "creating registry")
+	tb := &TransactionBuilder{registry}
+
+	err = tb.EnforceOverrideCaps(map[string]OverrideCap{
+		CertificatesPerDomain.String(): {MaxBurst: 1000, MaxCount: 1000},
+	}, false, prometheus.NewRegistry(), blog.NewMock())
+	test.AssertNotError(t, err, "enforcing caps")
+
+	dl, err := tb.getLimit(CertificatesPerDomain, "")
+	test.AssertNotError(t, err, "getting default")
+	test.AssertEquals(t, dl.burst, int64(5_000_000))
+	ol, err := tb.getLimit(CertificatesPerDomain, joinWithColon(CertificatesPerDomain.EnumString(), "example.com"))
+	test.AssertNotError(t, err, "getting override")
+	test.Assert(t, ol.isOverride, "expected the override")
+	test.AssertEquals(t, ol.burst, int64(1000))
+
+	// Strict caps which reject the loaded overrides leave them unchanged.
+	err = tb.EnforceOverrideCaps(map[string]OverrideCap{
+		CertificatesPerDomain.String(): {MaxBurst: 10, MaxCount: 10},
+	}, true, prometheus.NewRegistry(), blog.NewMock())
+	test.AssertError(t, err, "strict caps should reject the loaded overrides")
+	ol, err = tb.getLimit(CertificatesPerDomain, joinWithColon(CertificatesPerDomain.EnumString(), "example.com"))
+	test.AssertNotError(t, err, "getting override")
+	test.AssertEquals(t, ol.burst, int64(1000))
+}
+
+func TestReloadAppliesOverrideCaps(t *testing.T) {
+	t.Parallel()
+
+	tb, err := NewTransactionBuilderFromFiles("testdata/working_default.yml", "testdata/working_override.yml")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+	err = tb.EnforceOverrideCaps(map[string]OverrideCap{
+		NewRegistrationsPerIPAddress.String(): {MaxBurst: 30, MaxCount: 30},
+	}, true, prometheus.NewRegistry(), blog.NewMock())
+	test.AssertError(t, err, "strict caps should reject the loaded overrides")
+
+	err = tb.EnforceOverrideCaps(map[string]OverrideCap{
+		NewRegistrationsPerIPAddress.String(): {MaxBurst: 30, MaxCount: 30},
+	}, false, prometheus.NewRegistry(), blog.NewMock())
+	test.AssertNotError(t, err, "enforcing caps")
+	err = tb.ReloadFromFiles("testdata/working_default.yml", "testdata/working_override.yml")
+	test.AssertNotError(t, err, "reloading")
+	ol, err := tb.getLimit(NewRegistrationsPerIPAddress, joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.2"))
+	test.AssertNotError(t, err, "getting override")
+	test.AssertEquals(t, ol.burst, int64(30))
+}
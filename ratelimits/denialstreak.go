@@ -0,0 +1,152 @@
+package ratelimits
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// denialStreakGap is the longest an account may go without a request being
+// denied by FailedAuthorizationsPerDomainPerAccount before its denial streak
+// ends.
+const denialStreakGap = 24 * time.Hour
+
+// DenialStreak is an uninterrupted series of requests by a single account
+// which were denied by FailedAuthorizationsPerDomainPerAccount.
+type DenialStreak struct {
+	// First is the time of the first denial of the streak.
+	First time.Time
+
+	// Last is the time of the most recent denial of the streak.
+	Last time.Time
+
+	// Count is the number of denials in the streak.
+	Count int64
+}
+
+// DenialStreakRecorder is implemented by sources which can store the denial
+// streak of each account, so that accounts which are denied for days on end
+// can be proposed for pausing.
+type DenialStreakRecorder interface {
+	// RecordDenial adds a denial at now to the streak of regId. If regId has
+	// no streak, or its last denial was more than gap before now, a new
+	// streak is started.
+	RecordDenial(ctx context.Context, regId int64, now time.Time, gap time.Duration) error
+
+	// ResetDenialStreak ends the streak of regId, if it has one.
+	ResetDenialStreak(ctx context.Context, regId int64) error
+
+	// DenialStreaks returns every stored streak, keyed by regId.
+	DenialStreaks(ctx context.Context) (map[int64]DenialStreak, error)
+}
+
+// EnableDenialStreaks enables tracking of the denial streak of each account:
+// every request denied by FailedAuthorizationsPerDomainPerAccount extends the
+// streak of its account, and every refund of that limit, which is made when
+// one of the account's authorizations validates, ends it. An error is returned
+// if the source doesn't implement DenialStreakRecorder.
+func (l *Limiter) EnableDenialStreaks() error {
+	streaks, ok := l.source.(DenialStreakRecorder)
+	if !ok {
+		return fmt.Errorf("source %T can't record denial streaks", l.source)
+	}
+	l.streaks = streaks
+	return nil
+}
+
+// streakRegId returns the regId of the account whose denial streak txn
+// affects, if any.
+func streakRegId(txn Transaction) (int64, bool) {
+	if txn.limit.name != FailedAuthorizationsPerDomainPerAccount {
+		return 0, false
+	}
+	// FailedAuthorizationsPerDomainPerAccount limit uses the
+	// 'enum:regId:domain' bucket key format for transactions.
+	parts := strings.SplitN(txn.bucketKey, ":", 3)
+	if len(parts) != 3 {
+		return 0, false
+	}
+	regId, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return regId, true
+}
+
+// recordDenialStreaks extends the denial streak of the account of each of the
+// denied Transactions. Streaks are advisory, so failures to record them are
+// logged rather than failing the request.
+func (l *Limiter) recordDenialStreaks(ctx context.Context, denied []Transaction) {
+	if l.streaks == nil {
+		return
+	}
+	regIds := make(map[int64]bool)
+	for _, txn := range denied {
+		regId, ok := streakRegId(txn)
+		if ok {
+			regIds[regId] = true
+		}
+	}
+	now := l.clk.Now()
+	for regId := range regIds {
+		err := l.streaks.RecordDenial(ctx, regId, now, denialStreakGap)
+		if err != nil {
+			l.log.Warningf("recording denial streak for regId=[%d]: %s", regId, err)
+		}
+	}
+}
+
+// resetDenialStreaks ends the denial streak of the account of each of the
+// refunded Transactions. As with recordDenialStreaks, failures are logged.
+func (l *Limiter) resetDenialStreaks(ctx context.Context, refunded []Transaction) {
+	if l.streaks == nil {
+		return
+	}
+	regIds := make(map[int64]bool)
+	for _, txn := range refunded {
+		regId, ok := streakRegId(txn)
+		if ok && !txn.checkOnly() {
+			regIds[regId] = true
+		}
+	}
+	for regId := range regIds {
+		err := l.streaks.ResetDenialStreak(ctx, regId)
+		if err != nil {
+			l.log.Warningf("resetting denial streak for regId=[%d]: %s", regId, err)
+		}
+	}
+}
+
+// DenialStreaks returns, in ascending order, the regIds of the accounts whose
+// requests have been denied by FailedAuthorizationsPerDomainPerAccount
+// continuously for at least threshold, with no gap between denials longer
+// than a day. Streaks which have ended since their last denial are removed.
+// It returns an error if EnableDenialStreaks hasn't been called.
+func (l *Limiter) DenialStreaks(ctx context.Context, threshold time.Duration) ([]int64, error) {
+	if l.streaks == nil {
+		return nil, fmt.Errorf("denial streaks are not enabled")
+	}
+	streaks, err := l.streaks.DenialStreaks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting denial streaks: %w", err)
+	}
+	now := l.clk.Now()
+	var regIds []int64
+	for regId, streak := range streaks {
+		if now.Sub(streak.Last) > denialStreakGap {
+			err := l.streaks.ResetDenialStreak(ctx, regId)
+			if err != nil {
+				return nil, fmt.Errorf("resetting ended denial streak for regId=[%d]: %w", regId, err)
+			}
+			continue
+		}
+		if streak.Last.Sub(streak.First) >= threshold {
+			regIds = append(regIds, regId)
+		}
+	}
+	slices.Sort(regIds)
+	return regIds, nil
+}
@@ -0,0 +1,114 @@
+package ratelimits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestDenialStreaks(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	source := NewInmemSource(clk)
+	limiter := newTestLimiter(t, source, clk)
+	_, err := limiter.DenialStreaks(context.Background(), time.Hour)
+	test.AssertError(t, err, "DenialStreaks should fail before EnableDenialStreaks")
+	err = limiter.EnableDenialStreaks()
+	test.AssertNotError(t, err, "EnableDenialStreaks failed")
+
+	// Two failed authorizations a week, so that a drained bucket stays
+	// drained for the whole test.
+	txnBuilder, err := NewTransactionBuilder(LimitConfigs{
+		FailedAuthorizationsPerDomainPerAccount.String(): {
+			Burst:  2,
+			Count:  2,
+			Period: config.Duration{Duration: 7 * 24 * time.Hour},
+		},
+	})
+	test.AssertNotError(t, err, "building transaction builder")
+
+	fail := func(regId int64) {
+		t.Helper()
+		txn, err := txnBuilder.FailedAuthorizationsPerDomainPerAccountSpendOnlyTransaction(regId, "example.com")
+		test.AssertNotError(t, err, "building transaction")
+		_, err = limiter.Spend(context.Background(), txn)
+		test.AssertNotError(t, err, "spending")
+	}
+	newOrder := func(regId int64) bool {
+		t.Helper()
+		txns, err := txnBuilder.FailedAuthorizationsPerDomainPerAccountCheckOnlyTransactions(regId, []string{"example.com"})
+		test.AssertNotError(t, err, "building transactions")
+		d, err := limiter.BatchSpend(context.Background(), txns)
+		test.AssertNotError(t, err, "spending")
+		return d.allowed
+	}
+
+	// Both accounts exhaust their failed authorizations.
+	for range 2 {
+		fail(1)
+		fail(2)
+	}
+	test.AssertEquals(t, len(source.streaks), 0)
+
+	// Account 1 is denied every six hours for three days. Account 2 is denied
+	// on the first day, and then not again for two days.
+	for i := range 13 {
+		test.Assert(t, !newOrder(1), "account 1 should be denied")
+		if i == 0 || i == 12 {
+			test.Assert(t, !newOrder(2), "account 2 should be denied")
+		}
+		if i < 12 {
+			clk.Add(6 * time.Hour)
+		}
+	}
+	test.AssertEquals(t, source.streaks[1].Count, int64(13))
+	test.AssertEquals(t, source.streaks[1].Last.Sub(source.streaks[1].First), 72*time.Hour)
+	test.AssertEquals(t, source.streaks[2].Count, int64(1))
+	test.AssertEquals(t, source.streaks[2].First, clk.Now())
+
+	regIds, err := limiter.DenialStreaks(context.Background(), 48*time.Hour)
+	test.AssertNotError(t, err, "DenialStreaks failed")
+	test.AssertDeepEquals(t, regIds, []int64{1})
+	regIds, err = limiter.DenialStreaks(context.Background(), 0)
+	test.AssertNotError(t, err, "DenialStreaks failed")
+	test.AssertDeepEquals(t, regIds, []int64{1, 2})
+
+	// Refunding a failed authorization, because it went on to validate, ends
+	// the streak.
+	txn, err := txnBuilder.FailedAuthorizationsPerDomainPerAccountSpendOnlyTransaction(1, "example.com")
+	test.AssertNotError(t, err, "building transaction")
+	_, err = limiter.Refund(context.Background(), txn)
+	test.AssertNotError(t, err, "refunding")
+	regIds, err = limiter.DenialStreaks(context.Background(), 0)
+	test.AssertNotError(t, err, "DenialStreaks failed")
+	test.AssertDeepEquals(t, regIds, []int64{2})
+
+	// Once account 2 hasn't been denied for more than a day, its streak has
+	// ended, and is removed.
+	clk.Add(denialStreakGap + time.Second)
+	regIds, err = limiter.DenialStreaks(context.Background(), 0)
+	test.AssertNotError(t, err, "DenialStreaks failed")
+	test.AssertEquals(t, len(regIds), 0)
+	test.AssertEquals(t, len(source.streaks), 0)
+}
+
+func TestParseDenialStreak(t *testing.T) {
+	t.Parallel()
+
+	streak, ok := parseDenialStreak("1000:61000:7")
+	test.Assert(t, ok, "parsing valid streak")
+	test.AssertEquals(t, streak.First, time.UnixMilli(1000).UTC())
+	test.AssertEquals(t, streak.Last, time.UnixMilli(61000).UTC())
+	test.AssertEquals(t, streak.Count, int64(7))
+
+	for _, value := range []string{"", "1000:61000", "1000:61000:7:8", "1000:x:7"} {
+		_, ok := parseDenialStreak(value)
+		test.Assert(t, !ok, "parsing malformed streak "+value)
+	}
+}
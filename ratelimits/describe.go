@@ -0,0 +1,87 @@
+package ratelimits
+
+import (
+	"maps"
+	"slices"
+)
+
+// LimitDescription documents a rate limit Name and its currently loaded
+// default, for rendering in Subscriber facing documentation.
+type LimitDescription struct {
+	// Name is the string representation of the Name, as used in the defaults
+	// and overrides files.
+	Name string `json:"name"`
+
+	// BucketKey is the format of the limit's bucket keys, e.g. "regId:domain".
+	BucketKey string `json:"bucketKey"`
+
+	// OverridesSupported is true if the limit may be overridden.
+	OverridesSupported bool `json:"overridesSupported"`
+
+	// OverrideKey is the format of the ids of the limit's overrides, which
+	// may differ from BucketKey. It is empty if OverridesSupported is false.
+	OverrideKey string `json:"overrideKey,omitempty"`
+
+	// Description is a short description of what the limit counts.
+	Description string `json:"description"`
+
+	// Default is the limit's loaded default, or nil if it has none.
+	Default *LimitDefault `json:"default,omitempty"`
+}
+
+// LimitDefault is the loaded default of a rate limit, or one of its tiers.
+type LimitDefault struct {
+	Burst int64 `json:"burst"`
+	Count int64 `json:"count"`
+	// Period is formatted as by time.Duration.String.
+	Period string `json:"period"`
+	// Mode is omitted for tiers, which always use the mode of their default.
+	Mode LimitMode `json:"mode,omitempty"`
+	// Tiers are the defaults which replace this one for accounts in the given
+	// age buckets.
+	Tiers map[AccountAgeBucket]*LimitDefault `json:"tiers,omitempty"`
+}
+
+func newLimitDefault(l *limit) *LimitDefault {
+	return &LimitDefault{
+		Burst:  l.burst,
+		Count:  l.count,
+		Period: l.period.Duration.String(),
+	}
+}
+
+// Describe returns a description of every rate limit Name, ordered by Name,
+// along with its currently loaded default, if any.
+func (l *limitRegistry) Describe() []LimitDescription {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var descriptions []LimitDescription
+	for _, name := range slices.Sorted(maps.Keys(nameToMetadata)) {
+		md := nameToMetadata[name]
+		desc := LimitDescription{
+			Name:               name.String(),
+			BucketKey:          md.bucketKey,
+			OverridesSupported: md.overrideKey != "",
+			OverrideKey:        md.overrideKey,
+			Description:        md.description,
+		}
+		dl, ok := l.defaults[name.EnumString()]
+		if ok {
+			desc.Default = newLimitDefault(dl)
+			desc.Default.Mode = dl.mode
+			for bucket, tierLimits := range l.tiers {
+				tl, ok := tierLimits[name.EnumString()]
+				if !ok {
+					continue
+				}
+				if desc.Default.Tiers == nil {
+					desc.Default.Tiers = make(map[AccountAgeBucket]*LimitDefault)
+				}
+				desc.Default.Tiers[bucket] = newLimitDefault(tl)
+			}
+		}
+		descriptions = append(descriptions, desc)
+	}
+	return descriptions
+}
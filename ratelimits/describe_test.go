@@ -0,0 +1,75 @@
+package ratelimits
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/test"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite the golden output of TestDescribe")
+
+func TestNameMetadataComplete(t *testing.T) {
+	t.Parallel()
+
+	for name := range nameToString {
+		if name == Unknown {
+			continue
+		}
+		md, ok := nameToMetadata[name]
+		test.Assert(t, ok, "no entry in nameToMetadata for "+name.String())
+		test.Assert(t, md.description != "", "no description in nameToMetadata for "+name.String())
+		test.Assert(t, md.bucketKey != "", "no bucket key in nameToMetadata for "+name.String())
+	}
+	_, ok := nameToMetadata[Unknown]
+	test.Assert(t, !ok, "Unknown should not be described")
+	test.AssertEquals(t, len(nameToMetadata), len(nameToString)-1)
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	builder, err := NewTransactionBuilder(LimitConfigs{
+		NewRegistrationsPerIPAddress.String(): {
+			Burst:  20,
+			Count:  20,
+			Period: config.Duration{Duration: time.Second},
+		},
+		NewOrdersPerAccount.String(): {
+			Burst:  300,
+			Count:  300,
+			Period: config.Duration{Duration: 3 * time.Hour},
+			Tiers: map[AccountAgeBucket]*LimitConfig{
+				AccountAgeNew: {
+					Burst:  30,
+					Count:  30,
+					Period: config.Duration{Duration: 3 * time.Hour},
+				},
+			},
+		},
+		FailedValidationsPerDomainPerAccount.String(): {
+			Burst:  10,
+			Count:  10,
+			Period: config.Duration{Duration: time.Hour},
+			Mode:   ModeLogOnly,
+		},
+	})
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	got, err := json.MarshalIndent(builder.Describe(), "", "  ")
+	test.AssertNotError(t, err, "marshaling descriptions")
+	got = append(got, '\n')
+
+	golden := "testdata/describe.golden.json"
+	if *updateGolden {
+		err = os.WriteFile(golden, got, 0644)
+		test.AssertNotError(t, err, "writing golden output")
+	}
+	want, err := os.ReadFile(golden)
+	test.AssertNotError(t, err, "reading golden output")
+	test.AssertEquals(t, string(got), string(want))
+}
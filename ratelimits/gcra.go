@@ -16,15 +16,21 @@ func maybeSpend(clk clock.Clock, txn Transaction, tat time.Time) *Decision {
 		// the caller has introduced a bug.
 		panic("invalid cost for maybeSpend")
 	}
-	nowUnix := clk.Now().UnixNano()
-	tatUnix := tat.UnixNano()
+	now := clk.Now()
+	nowUnix := now.UnixNano()
 
-	// If the TAT is in the future, use it as the starting point for the
-	// calculation. Otherwise, use the current time. This is to prevent the
-	// bucket from being filled with capacity from the past.
-	if nowUnix > tatUnix {
-		tatUnix = nowUnix
+	// If the TAT is later than the current time less the bucket's jitter, use
+	// it as the starting point for the calculation. Otherwise, use the current
+	// time less the jitter. This is to prevent the bucket from being filled
+	// with capacity from the past. The jitter is always less than one
+	// emissionInterval, so it staggers when the bucket refills without adding
+	// capacity.
+	jitter := txn.limit.jitter(txn.bucketKey)
+	start := now.Add(-jitter)
+	if tat.After(start) {
+		start = tat
 	}
+	tatUnix := start.UnixNano()
 
 	// Compute the cost increment.
 	costIncrement := txn.limit.emissionInterval * txn.cost
@@ -42,6 +48,7 @@ func maybeSpend(clk clock.Clock, txn Transaction, tat time.Time) *Decision {
 			retryIn:     -time.Duration(difference),
 			resetIn:     time.Duration(tatUnix - nowUnix),
 			newTAT:      time.Unix(0, tatUnix).UTC(),
+			jitter:      jitter,
 			transaction: txn,
 		}
 	}
@@ -58,6 +65,7 @@ func maybeSpend(clk clock.Clock, txn Transaction, tat time.Time) *Decision {
 		retryIn:     retryIn,
 		resetIn:     time.Duration(newTAT - nowUnix),
 		newTAT:      time.Unix(0, newTAT).UTC(),
+		jitter:      jitter,
 		transaction: txn,
 	}
 }
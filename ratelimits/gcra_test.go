@@ -16,16 +16,16 @@ func TestDecide(t *testing.T) {
 	limit.precompute()
 
 	// Begin by using 1 of our 10 requests.
-	d := maybeSpend(clk, Transaction{"test", limit, 1, true, true}, clk.Now())
+	d := maybeSpend(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, clk.Now())
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(9))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Second)
 	// Transaction is set when we're allowed.
-	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, true, true})
+	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, txnCheckAndSpend})
 
 	// Immediately use another 9 of our remaining requests.
-	d = maybeSpend(clk, Transaction{"test", limit, 9, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 9, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(0))
 	// We should have to wait 1 second before we can use another request but we
@@ -37,21 +37,21 @@ func TestDecide(t *testing.T) {
 	test.AssertEquals(t, d.newTAT, clk.Now().Add(time.Second*10))
 
 	// Let's try using just 1 more request without waiting.
-	d = maybeSpend(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, !d.allowed, "should not be allowed")
 	test.AssertEquals(t, d.remaining, int64(0))
 	test.AssertEquals(t, d.retryIn, time.Second)
 	test.AssertEquals(t, d.resetIn, time.Second*10)
 	// Transaction is set when we're denied.
-	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, true, true})
+	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, txnCheckAndSpend})
 
 	// Let's try being exactly as patient as we're told to be.
 	clk.Add(d.retryIn)
-	d = maybeSpend(clk, Transaction{"test", limit, 0, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 0, txnCheckAndSpend}, d.newTAT)
 	test.AssertEquals(t, d.remaining, int64(1))
 
 	// We are 1 second in the future, we should have 1 new request.
-	d = maybeSpend(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(0))
 	test.AssertEquals(t, d.retryIn, time.Second)
@@ -61,7 +61,7 @@ func TestDecide(t *testing.T) {
 	clk.Add(d.resetIn)
 
 	// We should have 10 new requests. If we use 1 we should have 9 remaining.
-	d = maybeSpend(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(9))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
@@ -72,7 +72,7 @@ func TestDecide(t *testing.T) {
 
 	// We should still have 9 remaining because we're still 1ms shy of the
 	// refill time.
-	d = maybeSpend(clk, Transaction{"test", limit, 0, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 0, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(9))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
@@ -83,7 +83,7 @@ func TestDecide(t *testing.T) {
 	clk.Add(20 * time.Hour)
 
 	// C'mon, big money, no whammies, no whammies, STOP!
-	d = maybeSpend(clk, Transaction{"test", limit, 0, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 0, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(10))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
@@ -91,7 +91,7 @@ func TestDecide(t *testing.T) {
 
 	// Turns out that the most we can accrue is 10 (limit.Burst). Let's empty
 	// this bucket out so we can try something else.
-	d = maybeSpend(clk, Transaction{"test", limit, 10, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 10, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(0))
 	// We should have to wait 1 second before we can use another request but we
@@ -101,14 +101,14 @@ func TestDecide(t *testing.T) {
 	test.AssertEquals(t, d.resetIn, time.Second*10)
 
 	// If you spend 0 while you have 0 you should get 0.
-	d = maybeSpend(clk, Transaction{"test", limit, 0, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 0, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(0))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Second*10)
 
 	// We don't play by the rules, we spend 1 when we have 0.
-	d = maybeSpend(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, !d.allowed, "should not be allowed")
 	test.AssertEquals(t, d.remaining, int64(0))
 	test.AssertEquals(t, d.retryIn, time.Second)
@@ -118,7 +118,7 @@ func TestDecide(t *testing.T) {
 	clk.Add(d.retryIn)
 
 	// Our patience pays off, we should have 1 new request. Let's use it.
-	d = maybeSpend(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(0))
 	test.AssertEquals(t, d.retryIn, time.Second)
@@ -130,7 +130,7 @@ func TestDecide(t *testing.T) {
 	// Attempt to spend 7 when we only have 5. We should be denied but the
 	// decision should reflect a retry of 2 seconds, the time it would take to
 	// refill from 5 to 7.
-	d = maybeSpend(clk, Transaction{"test", limit, 7, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 7, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, !d.allowed, "should not be allowed")
 	test.AssertEquals(t, d.remaining, int64(5))
 	test.AssertEquals(t, d.retryIn, time.Second*2)
@@ -143,28 +143,28 @@ func TestMaybeRefund(t *testing.T) {
 	limit.precompute()
 
 	// Begin by using 1 of our 10 requests.
-	d := maybeSpend(clk, Transaction{"test", limit, 1, true, true}, clk.Now())
+	d := maybeSpend(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, clk.Now())
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(9))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Second)
 	// Transaction is set when we're refunding.
-	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, true, true})
+	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, txnCheckAndSpend})
 
 	// Refund back to 10.
-	d = maybeRefund(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeRefund(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.AssertEquals(t, d.remaining, int64(10))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Duration(0))
 
 	// Refund 0, we should still have 10.
-	d = maybeRefund(clk, Transaction{"test", limit, 0, true, true}, d.newTAT)
+	d = maybeRefund(clk, Transaction{"test", limit, 0, txnCheckAndSpend}, d.newTAT)
 	test.AssertEquals(t, d.remaining, int64(10))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Duration(0))
 
 	// Spend 1 more of our 10 requests.
-	d = maybeSpend(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(9))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
@@ -174,16 +174,16 @@ func TestMaybeRefund(t *testing.T) {
 	clk.Add(d.resetIn)
 
 	// Attempt to refund from 10 to 11.
-	d = maybeRefund(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeRefund(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, !d.allowed, "should not be allowed")
 	test.AssertEquals(t, d.remaining, int64(10))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Duration(0))
 	// Transaction is set when our bucket is full.
-	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, true, true})
+	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, txnCheckAndSpend})
 
 	// Spend 10 all 10 of our requests.
-	d = maybeSpend(clk, Transaction{"test", limit, 10, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 10, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(0))
 	// We should have to wait 1 second before we can use another request but we
@@ -193,7 +193,7 @@ func TestMaybeRefund(t *testing.T) {
 	test.AssertEquals(t, d.resetIn, time.Second*10)
 
 	// Attempt a refund of 10.
-	d = maybeRefund(clk, Transaction{"test", limit, 10, true, true}, d.newTAT)
+	d = maybeRefund(clk, Transaction{"test", limit, 10, txnCheckAndSpend}, d.newTAT)
 	test.AssertEquals(t, d.remaining, int64(10))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Duration(0))
@@ -202,17 +202,17 @@ func TestMaybeRefund(t *testing.T) {
 	clk.Add(11 * time.Second)
 
 	// Attempt to refund to 11, then ensure it's still 10.
-	d = maybeRefund(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeRefund(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, !d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(10))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Duration(0))
 	// Transaction is set when our TAT is in the past.
-	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, true, true})
+	test.AssertEquals(t, d.transaction, Transaction{"test", limit, 1, txnCheckAndSpend})
 
 	// Spend 5 of our 10 requests, then refund 1.
-	d = maybeSpend(clk, Transaction{"test", limit, 5, true, true}, d.newTAT)
-	d = maybeRefund(clk, Transaction{"test", limit, 1, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 5, txnCheckAndSpend}, d.newTAT)
+	d = maybeRefund(clk, Transaction{"test", limit, 1, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(6))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
@@ -221,7 +221,7 @@ func TestMaybeRefund(t *testing.T) {
 	clk.Add(time.Millisecond * 2500)
 
 	// Ensure we have 8.5 requests.
-	d = maybeSpend(clk, Transaction{"test", limit, 0, true, true}, d.newTAT)
+	d = maybeSpend(clk, Transaction{"test", limit, 0, txnCheckAndSpend}, d.newTAT)
 	test.Assert(t, d.allowed, "should be allowed")
 	test.AssertEquals(t, d.remaining, int64(8))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
@@ -229,7 +229,7 @@ func TestMaybeRefund(t *testing.T) {
 	test.AssertEquals(t, d.resetIn, time.Millisecond*1500)
 
 	// Refund 2 requests, we should only have 10, not 10.5.
-	d = maybeRefund(clk, Transaction{"test", limit, 2, true, true}, d.newTAT)
+	d = maybeRefund(clk, Transaction{"test", limit, 2, txnCheckAndSpend}, d.newTAT)
 	test.AssertEquals(t, d.remaining, int64(10))
 	test.AssertEquals(t, d.retryIn, time.Duration(0))
 	test.AssertEquals(t, d.resetIn, time.Duration(0))
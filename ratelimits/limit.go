@@ -3,8 +3,13 @@ package ratelimits
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"maps"
+	"net/netip"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/letsencrypt/boulder/config"
 	"github.com/letsencrypt/boulder/core"
@@ -32,10 +37,85 @@ type LimitConfig struct {
 	// Period is the duration of time in which the count (of requests) is
 	// allowed. It must be greater than zero.
 	Period config.Duration
+
+	// JitterFraction is the largest fraction of Period by which the refill
+	// schedule of a new bucket may be staggered. Each bucket's jitter is
+	// derived from a hash of its key, so it is deterministic for the same key
+	// while differing between keys. The jitter is further capped at the
+	// interval between refills (Period / Count) so that it never changes the
+	// capacity of a bucket. It must be between 0 and 1, the default of zero
+	// disables jitter.
+	JitterFraction float64 `yaml:"jitterFraction,omitempty"`
+
+	// Tiers optionally replaces this default limit for accounts in the given
+	// age buckets. Accounts in a bucket without an entry, or whose bucket is
+	// unknown, are subject to this limit. Tiers are only supported for default
+	// limits of the names in tieredNames, and may not themselves have tiers.
+	Tiers map[AccountAgeBucket]*LimitConfig `yaml:"tiers,omitempty"`
+
+	// Mode determines how the Limiter acts on the decisions of this limit,
+	// and defaults to ModeEnforce. It is only supported for default limits,
+	// and applies to every tier and override of the same name.
+	Mode LimitMode `yaml:"mode,omitempty"`
 }
 
 type LimitConfigs map[string]*LimitConfig
 
+// LimitMode determines how the Limiter acts on the decisions of a limit.
+type LimitMode string
+
+const (
+	// ModeEnforce denies requests which exceed the limit.
+	ModeEnforce LimitMode = "enforce"
+
+	// ModeLogOnly spends normally, but reports requests which exceed the limit
+	// as allowed. Each such request is counted, and a sample of them are
+	// logged, so that the impact of a new limit can be measured before it is
+	// enforced.
+	ModeLogOnly LimitMode = "log-only"
+
+	// ModeOff disables the limit, exactly as though it were not configured.
+	ModeOff LimitMode = "off"
+)
+
+// AccountAgeBucket classifies accounts by age, so that newer accounts can be
+// subject to different default limits than established ones.
+type AccountAgeBucket string
+
+const (
+	// AccountAgeUnknown selects the base default limits.
+	AccountAgeUnknown AccountAgeBucket = ""
+
+	// AccountAgeNew is the bucket of accounts younger than NewAccountAge.
+	AccountAgeNew AccountAgeBucket = "new"
+
+	// AccountAgeEstablished is the bucket of accounts at least NewAccountAge
+	// old.
+	AccountAgeEstablished AccountAgeBucket = "established"
+)
+
+// NewAccountAge is the age below which an account is in the AccountAgeNew
+// bucket.
+const NewAccountAge = 7 * 24 * time.Hour
+
+// AccountAgeBucketFor returns the age bucket, at now, of an account created at
+// createdAt. If createdAt is the zero value, AccountAgeUnknown is returned.
+func AccountAgeBucketFor(createdAt, now time.Time) AccountAgeBucket {
+	if createdAt.IsZero() {
+		return AccountAgeUnknown
+	}
+	if now.Sub(createdAt) < NewAccountAge {
+		return AccountAgeNew
+	}
+	return AccountAgeEstablished
+}
+
+// tieredNames are the names of limits whose transactions are built with an
+// AccountAgeBucket, and so for which tiers may be configured.
+var tieredNames = map[Name]bool{
+	NewOrdersPerAccount: true,
+}
+
 // limit defines the configuration for a rate limit or a rate limit override.
 //
 // The zero value of this struct is invalid, because some of the fields must
@@ -71,12 +151,48 @@ type limit struct {
 
 	// isOverride is true if the limit is an override.
 	isOverride bool
+
+	// overrideCIDR is the range of an override keyed by a CIDR rather than a
+	// single id, which applies to the bucket of every address in the range.
+	// It is empty for all other limits.
+	overrideCIDR string
+
+	// jitterFraction is the largest fraction of the period by which the
+	// refill schedule of a new bucket may be staggered.
+	jitterFraction float64
+
+	// maxJitter is the upper bound, in nanoseconds, of the jitter applied to
+	// buckets of this limit (min(period * jitterFraction, emissionInterval)).
+	// This is precomputed to avoid doing the same calculation on every
+	// request.
+	maxJitter int64
+
+	// mode is the LimitMode of the default limit of the same name. It is
+	// empty, and treated as ModeEnforce, for overrides of names which have no
+	// default limit.
+	mode LimitMode
 }
 
-// precompute calculates the emissionInterval and burstOffset for the limit.
+// precompute calculates the emissionInterval, burstOffset and maxJitter for
+// the limit.
 func (l *limit) precompute() {
 	l.emissionInterval = l.period.Nanoseconds() / l.count
 	l.burstOffset = l.emissionInterval * l.burst
+	l.maxJitter = min(int64(float64(l.period.Nanoseconds())*l.jitterFraction), l.emissionInterval)
+}
+
+// jitter returns the deterministic offset by which the refill schedule of the
+// bucket at bucketKey is staggered. A new bucket is treated as though it
+// started refilling this long ago. Because the jitter is always less than one
+// emissionInterval this never grants a whole additional request, it only
+// shifts the times at which requests become available again.
+func (l *limit) jitter(bucketKey string) time.Duration {
+	if l.maxJitter <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(bucketKey))
+	return time.Duration(h.Sum64() % uint64(l.maxJitter))
 }
 
 func validateLimit(l *limit) error {
@@ -89,6 +205,9 @@ func validateLimit(l *limit) error {
 	if l.period.Duration <= 0 {
 		return fmt.Errorf("invalid period '%s', must be > 0", l.period)
 	}
+	if l.jitterFraction < 0 || l.jitterFraction > 1 {
+		return fmt.Errorf("invalid jitter fraction '%g', must be >= 0 and <= 1", l.jitterFraction)
+	}
 	return nil
 }
 
@@ -108,15 +227,17 @@ func loadDefaults(path string) (LimitConfigs, error) {
 	return lm, nil
 }
 
+type overrideIdYAML struct {
+	Id string `yaml:"id"`
+	// Comment is an optional field that can be used to provide additional
+	// context for the override.
+	Comment string `yaml:"comment,omitempty"`
+}
+
 type overrideYAML struct {
 	LimitConfig `yaml:",inline"`
 	// Ids is a list of ids that this override applies to.
-	Ids []struct {
-		Id string `yaml:"id"`
-		// Comment is an optional field that can be used to provide additional
-		// context for the override.
-		Comment string `yaml:"comment,omitempty"`
-	} `yaml:"ids"`
+	Ids []overrideIdYAML `yaml:"ids"`
 }
 
 type overridesYAML []map[string]overrideYAML
@@ -162,7 +283,8 @@ func parseOverrideNameId(key string) (Name, string, error) {
 // formatted as a list of maps, where each map has a single key representing the
 // limit name and a value that is a map containing the limit fields and an
 // additional 'ids' field that is a list of ids that this override applies to.
-func parseOverrideLimits(newOverridesYAML overridesYAML) (limits, error) {
+// Each override is capped by caps, which may be nil.
+func parseOverrideLimits(newOverridesYAML overridesYAML, caps *overrideCapper) (limits, error) {
 	parsed := make(limits)
 
 	for _, ov := range newOverridesYAML {
@@ -172,22 +294,39 @@ func parseOverrideLimits(newOverridesYAML overridesYAML) (limits, error) {
 				return nil, fmt.Errorf("unrecognized name %q in override limit, must be one of %v", k, limitNames)
 			}
 
+			if len(v.Tiers) > 0 {
+				return nil, fmt.Errorf("override limit %q has tiers, tiers are only supported for default limits", k)
+			}
+			if v.Mode != "" {
+				return nil, fmt.Errorf("override limit %q has a mode, modes are only supported for default limits", k)
+			}
+
 			lim := &limit{
-				burst:      v.Burst,
-				count:      v.Count,
-				period:     v.Period,
-				name:       name,
-				isOverride: true,
+				burst:          v.Burst,
+				count:          v.Count,
+				period:         v.Period,
+				name:           name,
+				isOverride:     true,
+				jitterFraction: v.JitterFraction,
 			}
-			lim.precompute()
 
-			err := validateLimit(lim)
+			var ids []string
+			for _, entry := range v.Ids {
+				ids = append(ids, entry.Id)
+			}
+			err := caps.applyOverride(lim, ids)
 			if err != nil {
 				return nil, fmt.Errorf("validating override limit %q: %w", k, err)
 			}
 
 			for _, entry := range v.Ids {
-				id := entry.Id
+				// Overrides are keyed on the same A-label form of their
+				// domains as the buckets they apply to.
+				id, err := normalizeIdForName(name, entry.Id)
+				if err != nil {
+					return nil, fmt.Errorf(
+						"validating name %s and id %q for override limit %q: %w", name, entry.Id, k, err)
+				}
 				err = validateIdForName(name, id)
 				if err != nil {
 					return nil, fmt.Errorf(
@@ -199,6 +338,15 @@ func parseOverrideLimits(newOverridesYAML overridesYAML) (limits, error) {
 					// comma-separated list of FQDNs and compute the hash here.
 					id = fmt.Sprintf("%x", core.HashNames(strings.Split(id, ",")))
 				}
+				if name == NewRegistrationsPerIPAddress && strings.Contains(id, "/") {
+					// Each CIDR override gets its own copy of the limit, so
+					// that spends against it can be attributed to its range.
+					id = netip.MustParsePrefix(id).String()
+					cidrLim := *lim
+					cidrLim.overrideCIDR = id
+					parsed[joinWithColon(name.EnumString(), id)] = &cidrLim
+					continue
+				}
 				parsed[joinWithColon(name.EnumString(), id)] = lim
 			}
 		}
@@ -207,49 +355,119 @@ func parseOverrideLimits(newOverridesYAML overridesYAML) (limits, error) {
 }
 
 // parseDefaultLimits validates a map of default limits and rekeys it by 'Name'.
-func parseDefaultLimits(newDefaultLimits LimitConfigs) (limits, error) {
+// The tiers of each default limit are returned separately, keyed by bucket and
+// then by 'Name'.
+func parseDefaultLimits(newDefaultLimits LimitConfigs) (limits, map[AccountAgeBucket]limits, error) {
 	parsed := make(limits)
+	tiers := make(map[AccountAgeBucket]limits)
 
 	for k, v := range newDefaultLimits {
 		name, ok := stringToName[k]
 		if !ok {
-			return nil, fmt.Errorf("unrecognized name %q in default limit, must be one of %v", k, limitNames)
+			return nil, nil, fmt.Errorf("unrecognized name %q in default limit, must be one of %v", k, limitNames)
 		}
 
-		lim := &limit{
-			burst:  v.Burst,
-			count:  v.Count,
-			period: v.Period,
-			name:   name,
+		lim, err := parseDefaultLimit(name, v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing default limit %q: %w", k, err)
 		}
+		parsed[name.EnumString()] = lim
 
-		err := validateLimit(lim)
-		if err != nil {
-			return nil, fmt.Errorf("parsing default limit %q: %w", k, err)
+		if len(v.Tiers) > 0 && !tieredNames[name] {
+			return nil, nil, fmt.Errorf("default limit %q has tiers, tiers are not supported for this limit", k)
 		}
+		for bucket, tv := range v.Tiers {
+			if bucket != AccountAgeNew && bucket != AccountAgeEstablished {
+				return nil, nil, fmt.Errorf("unrecognized tier %q in default limit %q, must be one of %q or %q",
+					bucket, k, AccountAgeNew, AccountAgeEstablished)
+			}
+			if tv == nil {
+				return nil, nil, fmt.Errorf("empty tier %q in default limit %q", bucket, k)
+			}
+			if len(tv.Tiers) > 0 {
+				return nil, nil, fmt.Errorf("tier %q of default limit %q has tiers of its own", bucket, k)
+			}
+			if tv.Mode != "" {
+				return nil, nil, fmt.Errorf("tier %q of default limit %q has a mode, tiers use the mode of their default limit", bucket, k)
+			}
+			tierLim, err := parseDefaultLimit(name, tv)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing tier %q of default limit %q: %w", bucket, k, err)
+			}
+			tierLim.mode = lim.mode
+			if tiers[bucket] == nil {
+				tiers[bucket] = make(limits)
+			}
+			tiers[bucket][name.EnumString()] = tierLim
+		}
+	}
+	return parsed, tiers, nil
+}
 
-		lim.precompute()
-		parsed[name.EnumString()] = lim
+// parseDefaultLimit validates a single default limit, or a tier of one.
+func parseDefaultLimit(name Name, v *LimitConfig) (*limit, error) {
+	lim := &limit{
+		burst:          v.Burst,
+		count:          v.Count,
+		period:         v.Period,
+		name:           name,
+		jitterFraction: v.JitterFraction,
+		mode:           v.Mode,
 	}
-	return parsed, nil
+
+	err := validateLimit(lim)
+	if err != nil {
+		return nil, err
+	}
+
+	switch lim.mode {
+	case "":
+		lim.mode = ModeEnforce
+	case ModeEnforce, ModeLogOnly, ModeOff:
+	default:
+		return nil, fmt.Errorf("invalid mode %q, must be one of %q, %q or %q", lim.mode, ModeEnforce, ModeLogOnly, ModeOff)
+	}
+
+	lim.precompute()
+	return lim, nil
 }
 
 type limitRegistry struct {
+	// mu guards the limits below, which are replaced wholesale by reload.
+	mu sync.RWMutex
+
 	// defaults stores default limits by 'name'.
 	defaults limits
 
+	// tiers stores the default limits of each account age bucket by 'name'.
+	// A bucket's entry takes precedence over the default of the same name.
+	tiers map[AccountAgeBucket]limits
+
 	// overrides stores override limits by 'name:id'.
 	overrides limits
+
+	// cidrOverrides stores the overrides keyed by a CIDR by 'name', for
+	// longest-prefix matching against the addresses of buckets which have no
+	// override of their own.
+	cidrOverrides map[Name]*prefixTrie
+
+	// rawOverrides are the overrides as loaded, before parsing, so that they
+	// can be capped again by setOverrideCaps.
+	rawOverrides overridesYAML
+
+	// caps caps the overrides. It is nil, for the default caps, unless
+	// setOverrideCaps has been called.
+	caps *overrideCapper
 }
 
-func newLimitRegistryFromFiles(defaults, overrides string) (*limitRegistry, error) {
+func newLimitRegistryFromFiles(defaults, overrides string, caps *overrideCapper) (*limitRegistry, error) {
 	defaultsData, err := loadDefaults(defaults)
 	if err != nil {
 		return nil, err
 	}
 
 	if overrides == "" {
-		return newLimitRegistry(defaultsData, nil)
+		return newLimitRegistry(defaultsData, nil, caps)
 	}
 
 	overridesData, err := loadOverrides(overrides)
@@ -257,36 +475,141 @@ func newLimitRegistryFromFiles(defaults, overrides string) (*limitRegistry, erro
 		return nil, err
 	}
 
-	return newLimitRegistry(defaultsData, overridesData)
+	return newLimitRegistry(defaultsData, overridesData, caps)
 }
 
-func newLimitRegistry(defaults LimitConfigs, overrides overridesYAML) (*limitRegistry, error) {
-	regDefaults, err := parseDefaultLimits(defaults)
+func newLimitRegistry(defaults LimitConfigs, overrides overridesYAML, caps *overrideCapper) (*limitRegistry, error) {
+	regDefaults, regTiers, err := parseDefaultLimits(defaults)
 	if err != nil {
 		return nil, err
 	}
 
-	regOverrides, err := parseOverrideLimits(overrides)
+	regOverrides, cidrOverrides, err := buildOverrides(regDefaults, overrides, caps)
 	if err != nil {
 		return nil, err
 	}
 
 	return &limitRegistry{
-		defaults:  regDefaults,
-		overrides: regOverrides,
+		defaults:      regDefaults,
+		tiers:         regTiers,
+		overrides:     regOverrides,
+		cidrOverrides: cidrOverrides,
+		rawOverrides:  overrides,
+		caps:          caps,
 	}, nil
 }
 
+// buildOverrides parses and caps overrides, and returns them along with the
+// CIDR overrides among them, indexed by Name. Each override takes the mode of
+// the default limit of the same name in regDefaults.
+func buildOverrides(regDefaults limits, overrides overridesYAML, caps *overrideCapper) (limits, map[Name]*prefixTrie, error) {
+	regOverrides, err := parseOverrideLimits(overrides, caps)
+	if err != nil {
+		return nil, nil, err
+	}
+	cidrOverrides := make(map[Name]*prefixTrie)
+	for _, ol := range regOverrides {
+		dl, ok := regDefaults[ol.name.EnumString()]
+		if ok {
+			ol.mode = dl.mode
+		}
+		if ol.overrideCIDR != "" {
+			if cidrOverrides[ol.name] == nil {
+				cidrOverrides[ol.name] = &prefixTrie{}
+			}
+			cidrOverrides[ol.name].insert(netip.MustParsePrefix(ol.overrideCIDR), ol)
+		}
+	}
+	return regOverrides, cidrOverrides, nil
+}
+
+// reload replaces all of the limits in the registry with those in the provided
+// defaults and overrides YAML files, which are loaded exactly as by
+// newLimitRegistryFromFiles. If they cannot be loaded, an error is returned
+// and the registry is left unchanged.
+func (l *limitRegistry) reload(defaults, overrides string) error {
+	l.mu.RLock()
+	caps := l.caps
+	l.mu.RUnlock()
+
+	reloaded, err := newLimitRegistryFromFiles(defaults, overrides, caps)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaults = reloaded.defaults
+	l.tiers = reloaded.tiers
+	l.overrides = reloaded.overrides
+	l.cidrOverrides = reloaded.cidrOverrides
+	l.rawOverrides = reloaded.rawOverrides
+	return nil
+}
+
+// setOverrideCaps replaces the caps applied to overrides, and re-applies them
+// to the overrides currently in the registry. If any of those overrides are
+// rejected, an error is returned and the registry is left unchanged.
+func (l *limitRegistry) setOverrideCaps(caps *overrideCapper) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	regOverrides, cidrOverrides, err := buildOverrides(l.defaults, l.rawOverrides, caps)
+	if err != nil {
+		return err
+	}
+	l.overrides = regOverrides
+	l.cidrOverrides = cidrOverrides
+	l.caps = caps
+	return nil
+}
+
+// overrideLimits returns a copy of the override limits currently in the
+// registry, keyed by 'name:id'.
+func (l *limitRegistry) overrideLimits() limits {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return maps.Clone(l.overrides)
+}
+
 // getLimit returns the limit for the specified by name and bucketKey, name is
 // required, bucketKey is optional. If bucketkey is empty, the default for the
 // limit specified by name is returned. If no default limit exists for the
-// specified name, errLimitDisabled is returned.
+// specified name, or its mode is ModeOff, errLimitDisabled is returned.
 func (l *limitRegistry) getLimit(name Name, bucketKey string) (*limit, error) {
+	return l.getTieredLimit(name, bucketKey, AccountAgeUnknown)
+}
+
+// getTieredLimit is like getLimit, except that when no override exists the
+// default for the provided account age bucket, if one is configured, is
+// returned in place of the base default.
+func (l *limitRegistry) getTieredLimit(name Name, bucketKey string, ageBucket AccountAgeBucket) (*limit, error) {
+	return l.resolveLimit(name, bucketKey, ageBucket, netip.Addr{})
+}
+
+// getIPAddressLimit is like getLimit, except that when no override exists for
+// bucketKey, the override of the most specific CIDR range containing addr, if
+// any, is returned in place of the default.
+func (l *limitRegistry) getIPAddressLimit(name Name, bucketKey string, addr netip.Addr) (*limit, error) {
+	return l.resolveLimit(name, bucketKey, AccountAgeUnknown, addr)
+}
+
+// resolveLimit implements getTieredLimit and getIPAddressLimit. Overrides are
+// checked in order of specificity: first an override of bucketKey itself, then
+// of a CIDR range containing addr, if addr is valid.
+func (l *limitRegistry) resolveLimit(name Name, bucketKey string, ageBucket AccountAgeBucket, addr netip.Addr) (*limit, error) {
 	if !name.isValid() {
 		// This should never happen. Callers should only be specifying the limit
 		// Name enums defined in this package.
 		return nil, fmt.Errorf("specified name enum %q, is invalid", name)
 	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	dl, ok := l.defaults[name.EnumString()]
+	if ok && dl.mode == ModeOff {
+		return nil, errLimitDisabled
+	}
 	if bucketKey != "" {
 		// Check for override.
 		ol, ok := l.overrides[bucketKey]
@@ -294,7 +617,18 @@ func (l *limitRegistry) getLimit(name Name, bucketKey string) (*limit, error) {
 			return ol, nil
 		}
 	}
-	dl, ok := l.defaults[name.EnumString()]
+	if addr.IsValid() {
+		cl := l.cidrOverrides[name].lookup(addr)
+		if cl != nil {
+			return cl, nil
+		}
+	}
+	if ageBucket != AccountAgeUnknown {
+		tl, ok := l.tiers[ageBucket][name.EnumString()]
+		if ok {
+			return tl, nil
+		}
+	}
 	if ok {
 		return dl, nil
 	}
@@ -1,6 +1,7 @@
 package ratelimits
 
 import (
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -19,7 +20,8 @@ func loadAndParseDefaultLimits(path string) (limits, error) {
 		return nil, err
 	}
 
-	return parseDefaultLimits(fromFile)
+	l, _, err := parseDefaultLimits(fromFile)
+	return l, err
 }
 
 // loadAndParseOverrideLimits is a helper that calls both loadOverrides and
@@ -32,7 +34,7 @@ func loadAndParseOverrideLimits(path string) (limits, error) {
 		return nil, err
 	}
 
-	return parseOverrideLimits(fromFile)
+	return parseOverrideLimits(fromFile, nil)
 }
 
 func TestParseOverrideNameId(t *testing.T) {
@@ -76,12 +78,51 @@ func TestValidateLimit(t *testing.T) {
 		{burst: 0, count: 1, period: config.Duration{Duration: time.Second}},
 		{burst: 1, count: 0, period: config.Duration{Duration: time.Second}},
 		{burst: 1, count: 1, period: config.Duration{Duration: 0}},
+		{burst: 1, count: 1, period: config.Duration{Duration: time.Second}, jitterFraction: -0.1},
+		{burst: 1, count: 1, period: config.Duration{Duration: time.Second}, jitterFraction: 1.1},
 	} {
 		err = validateLimit(l)
 		test.AssertError(t, err, "limit should be invalid")
 	}
 }
 
+func TestLimitJitter(t *testing.T) {
+	newLimit := func(count int64, fraction float64) *limit {
+		l := &limit{burst: count, count: count, period: config.Duration{Duration: time.Hour}, jitterFraction: fraction}
+		l.precompute()
+		return l
+	}
+
+	// Jitter is disabled by default.
+	test.AssertEquals(t, newLimit(10, 0).jitter("4:10.0.0.1"), time.Duration(0))
+
+	// Jitter is capped at the fraction of the period...
+	l := newLimit(2, 0.1)
+	test.AssertEquals(t, l.maxJitter, (6 * time.Minute).Nanoseconds())
+	// ...and at the emissionInterval, so that it never grants capacity.
+	l = newLimit(10, 0.5)
+	test.AssertEquals(t, l.maxJitter, l.emissionInterval)
+
+	// Jitter is deterministic for the same key, even across limits with the
+	// same configuration.
+	test.AssertEquals(t, l.jitter("4:10.0.0.1"), l.jitter("4:10.0.0.1"))
+	test.AssertEquals(t, l.jitter("4:10.0.0.1"), newLimit(10, 0.5).jitter("4:10.0.0.1"))
+
+	// Jitter is spread across the whole range for different keys.
+	const keys = 10000
+	const bins = 10
+	var counts [bins]int
+	for i := range keys {
+		j := l.jitter(fmt.Sprintf("4:%d", i))
+		test.Assert(t, j >= 0 && j.Nanoseconds() < l.maxJitter, fmt.Sprintf("jitter %s out of range", j))
+		counts[j.Nanoseconds()*bins/l.maxJitter]++
+	}
+	for bin, count := range counts {
+		// Each bin should hold roughly keys/bins; allow a generous margin.
+		test.Assert(t, count > keys/bins/2 && count < keys/bins*2, fmt.Sprintf("bin %d holds %d of %d keys", bin, count, keys))
+	}
+}
+
 func TestLoadAndParseOverrideLimits(t *testing.T) {
 	// Load a single valid override limit with Id formatted as 'enum:RegId'.
 	l, err := loadAndParseOverrideLimits("testdata/working_override.yml")
@@ -222,3 +263,132 @@ func TestLoadAndParseDefaultLimits(t *testing.T) {
 	test.AssertError(t, err, "multiple default limits, one is bad")
 	test.Assert(t, !os.IsNotExist(err), "test file should exist")
 }
+
+func TestParseDefaultLimitTiers(t *testing.T) {
+	fromFile, err := loadDefaults("testdata/working_default_tiers.yml")
+	test.AssertNotError(t, err, "loading defaults")
+	l, tiers, err := parseDefaultLimits(fromFile)
+	test.AssertNotError(t, err, "valid default limit with tiers")
+	test.AssertEquals(t, l[NewOrdersPerAccount.EnumString()].burst, int64(300))
+	test.AssertEquals(t, tiers[AccountAgeNew][NewOrdersPerAccount.EnumString()].burst, int64(30))
+	test.AssertEquals(t, tiers[AccountAgeNew][NewOrdersPerAccount.EnumString()].name, NewOrdersPerAccount)
+	_, ok := tiers[AccountAgeEstablished]
+	test.Assert(t, !ok, "no established tier was configured")
+
+	// Tiers must be one of the known age buckets.
+	_, err = loadAndParseDefaultLimits("testdata/busted_default_tier_unknown.yml")
+	test.AssertError(t, err, "default limit with an unknown tier")
+	test.AssertContains(t, err.Error(), `unrecognized tier "ancient"`)
+
+	// Tiers are only supported for limits built with an account age bucket.
+	_, err = loadAndParseDefaultLimits("testdata/busted_default_tier_unsupported.yml")
+	test.AssertError(t, err, "tiers for a limit which doesn't support them")
+	test.AssertContains(t, err.Error(), "tiers are not supported")
+
+	// Tiers must themselves be valid limits.
+	_, _, err = parseDefaultLimits(LimitConfigs{
+		NewOrdersPerAccount.String(): &LimitConfig{
+			Burst:  300,
+			Count:  300,
+			Period: config.Duration{Duration: 3 * time.Hour},
+			Tiers: map[AccountAgeBucket]*LimitConfig{
+				AccountAgeNew: {Burst: 0, Count: 30, Period: config.Duration{Duration: 3 * time.Hour}},
+			},
+		},
+	})
+	test.AssertError(t, err, "tier with burst=0")
+	test.AssertContains(t, err.Error(), "invalid burst")
+
+	// Overrides may not have tiers.
+	_, err = parseOverrideLimits(overridesYAML{{
+		NewOrdersPerAccount.String(): overrideYAML{
+			LimitConfig: LimitConfig{
+				Burst:  300,
+				Count:  300,
+				Period: config.Duration{Duration: 3 * time.Hour},
+				Tiers: map[AccountAgeBucket]*LimitConfig{
+					AccountAgeNew: {Burst: 30, Count: 30, Period: config.Duration{Duration: 3 * time.Hour}},
+				},
+			},
+			Ids: []overrideIdYAML{{Id: "1337"}},
+		},
+	}}, nil)
+	test.AssertError(t, err, "override with tiers")
+	test.AssertContains(t, err.Error(), "only supported for default limits")
+}
+
+func TestParseLimitModes(t *testing.T) {
+	period := config.Duration{Duration: 3 * time.Hour}
+
+	// The mode defaults to enforce, and tiers and overrides share the mode of
+	// their default limit.
+	registry, err := newLimitRegistry(LimitConfigs{
+		NewOrdersPerAccount.String(): &LimitConfig{
+			Burst: 300, Count: 300, Period: period, Mode: ModeLogOnly,
+			Tiers: map[AccountAgeBucket]*LimitConfig{
+				AccountAgeNew: {Burst: 30, Count: 30, Period: period},
+			},
+		},
+		NewRegistrationsPerIPAddress.String(): &LimitConfig{Burst: 20, Count: 20, Period: period},
+	}, overridesYAML{{
+		NewOrdersPerAccount.String(): overrideYAML{
+			LimitConfig: LimitConfig{Burst: 600, Count: 600, Period: period},
+			Ids:         []overrideIdYAML{{Id: "1337"}},
+		},
+	}}, nil)
+	test.AssertNotError(t, err, "valid limits with modes")
+	test.AssertEquals(t, registry.defaults[NewOrdersPerAccount.EnumString()].mode, ModeLogOnly)
+	test.AssertEquals(t, registry.tiers[AccountAgeNew][NewOrdersPerAccount.EnumString()].mode, ModeLogOnly)
+	test.AssertEquals(t, registry.overrides[joinWithColon(NewOrdersPerAccount.EnumString(), "1337")].mode, ModeLogOnly)
+	test.AssertEquals(t, registry.defaults[NewRegistrationsPerIPAddress.EnumString()].mode, ModeEnforce)
+
+	// A limit which is off is disabled, even for ids with an override.
+	registry, err = newLimitRegistry(LimitConfigs{
+		NewOrdersPerAccount.String(): &LimitConfig{Burst: 300, Count: 300, Period: period, Mode: ModeOff},
+	}, overridesYAML{{
+		NewOrdersPerAccount.String(): overrideYAML{
+			LimitConfig: LimitConfig{Burst: 600, Count: 600, Period: period},
+			Ids:         []overrideIdYAML{{Id: "1337"}},
+		},
+	}}, nil)
+	test.AssertNotError(t, err, "valid limit which is off")
+	_, err = registry.getLimit(NewOrdersPerAccount, joinWithColon(NewOrdersPerAccount.EnumString(), "1337"))
+	test.AssertErrorIs(t, err, errLimitDisabled)
+
+	// Modes must be one of those defined.
+	_, _, err = parseDefaultLimits(LimitConfigs{
+		NewOrdersPerAccount.String(): &LimitConfig{Burst: 300, Count: 300, Period: period, Mode: "dry-run"},
+	})
+	test.AssertError(t, err, "default limit with an unknown mode")
+	test.AssertContains(t, err.Error(), `invalid mode "dry-run"`)
+
+	// Tiers may not have a mode of their own.
+	_, _, err = parseDefaultLimits(LimitConfigs{
+		NewOrdersPerAccount.String(): &LimitConfig{
+			Burst: 300, Count: 300, Period: period,
+			Tiers: map[AccountAgeBucket]*LimitConfig{
+				AccountAgeNew: {Burst: 30, Count: 30, Period: period, Mode: ModeLogOnly},
+			},
+		},
+	})
+	test.AssertError(t, err, "tier with a mode")
+	test.AssertContains(t, err.Error(), "tiers use the mode of their default limit")
+
+	// Overrides may not have a mode.
+	_, err = parseOverrideLimits(overridesYAML{{
+		NewOrdersPerAccount.String(): overrideYAML{
+			LimitConfig: LimitConfig{Burst: 600, Count: 600, Period: period, Mode: ModeOff},
+			Ids:         []overrideIdYAML{{Id: "1337"}},
+		},
+	}}, nil)
+	test.AssertError(t, err, "override with a mode")
+	test.AssertContains(t, err.Error(), "modes are only supported for default limits")
+}
+
+func TestAccountAgeBucketFor(t *testing.T) {
+	now := time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)
+	test.AssertEquals(t, AccountAgeBucketFor(time.Time{}, now), AccountAgeUnknown)
+	test.AssertEquals(t, AccountAgeBucketFor(now.Add(-time.Hour), now), AccountAgeNew)
+	test.AssertEquals(t, AccountAgeBucketFor(now.Add(-NewAccountAge+time.Second), now), AccountAgeNew)
+	test.AssertEquals(t, AccountAgeBucketFor(now.Add(-NewAccountAge), now), AccountAgeEstablished)
+}
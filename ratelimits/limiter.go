@@ -2,18 +2,25 @@ package ratelimits
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"math/rand/v2"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
 )
 
 const (
@@ -30,19 +37,42 @@ const (
 // checked limit is found to be disabled.
 var allowedDecision = &Decision{allowed: true, remaining: math.MaxInt64}
 
+// logOnlySampleInterval is the interval at which requests denied by limits in
+// ModeLogOnly are audit logged. The first, and every logOnlySampleInterval'th
+// thereafter, is logged.
+const logOnlySampleInterval = 100
+
 // Limiter provides a high-level interface for rate limiting requests by
 // utilizing a token bucket-style approach.
 type Limiter struct {
 	// source is used to store buckets. It must be safe for concurrent use.
 	source Source
 	clk    clock.Clock
+	log    blog.Logger
+
+	// logOnlyDenials is the number of requests denied by limits in
+	// ModeLogOnly, and is used to sample them for the audit log.
+	logOnlyDenials atomic.Int64
+
+	spendLatency    *prometheus.HistogramVec
+	wouldHaveDenied *prometheus.CounterVec
+
+	// overrides tracks the utilization of overrides. It is nil unless
+	// EnableOverrideUtilization has been called.
+	overrides *overrideTracker
 
-	spendLatency *prometheus.HistogramVec
+	// receipts signs and verifies limit check receipts. It is nil unless
+	// EnableReceipts has been called.
+	receipts *receiptSigner
+
+	// streaks stores the denial streak of each account. It is nil unless
+	// EnableDenialStreaks has been called.
+	streaks DenialStreakRecorder
 }
 
 // NewLimiter returns a new *Limiter. The provided source must be safe for
 // concurrent use.
-func NewLimiter(clk clock.Clock, source Source, stats prometheus.Registerer) (*Limiter, error) {
+func NewLimiter(clk clock.Clock, source Source, stats prometheus.Registerer, logger blog.Logger) (*Limiter, error) {
 	spendLatency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name: "ratelimits_spend_latency",
 		Help: fmt.Sprintf("Latency of ratelimit checks labeled by limit=[name] and decision=[%s|%s], in seconds", Allowed, Denied),
@@ -51,13 +81,38 @@ func NewLimiter(clk clock.Clock, source Source, stats prometheus.Registerer) (*L
 	}, []string{"limit", "decision"})
 	stats.MustRegister(spendLatency)
 
+	wouldHaveDenied := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_would_have_denied",
+		Help: "Number of requests which were allowed only because the limit=[name] which would have denied them is in log-only mode",
+	}, []string{"limit"})
+	stats.MustRegister(wouldHaveDenied)
+
 	return &Limiter{
-		source:       source,
-		clk:          clk,
-		spendLatency: spendLatency,
+		source:          source,
+		clk:             clk,
+		log:             logger,
+		spendLatency:    spendLatency,
+		wouldHaveDenied: wouldHaveDenied,
 	}, nil
 }
 
+// applyMode returns the *Decision which should be reported to the caller for
+// the provided Transaction and the *Decision reached for it. Denials by limits
+// in ModeLogOnly are counted, sampled to the audit log, and reported as
+// allowed. The bucket key is hashed before logging because it may contain an
+// IP address or domain name.
+func (l *Limiter) applyMode(txn Transaction, d *Decision) *Decision {
+	if d.allowed || txn.limit.mode != ModeLogOnly {
+		return d
+	}
+	l.wouldHaveDenied.WithLabelValues(txn.limit.name.String()).Inc()
+	if (l.logOnlyDenials.Add(1)-1)%logOnlySampleInterval == 0 {
+		l.log.AuditInfof("Rate limit %s would have denied request in log-only mode [bucket key hash: %x, retry in: %s]",
+			txn.limit.name, sha256.Sum256([]byte(txn.bucketKey)), d.retryIn)
+	}
+	return allowedDecision
+}
+
 // Decision represents the result of a rate limit check or spend operation. To
 // check the result of a *Decision, call the Result() method.
 type Decision struct {
@@ -82,16 +137,78 @@ type Decision struct {
 	// (burst * (period / count)) in the future at any single point in time.
 	newTAT time.Time
 
+	// jitter is the offset by which the refill schedule of the bucket is
+	// staggered. It is reported so that reset times can be explained.
+	jitter time.Duration
+
 	// transaction is the Transaction that resulted in this Decision. It is
 	// included for the production of verbose Subscriber-facing errors. It is
 	// set by the Limiter before returning the Decision.
 	transaction Transaction
+
+	// results holds the outcome of each Transaction evaluated by the batch
+	// which produced this Decision, in the order they were provided. It is
+	// only set on Decisions returned by BatchSpend and Reserve.
+	results []TransactionResult
+}
+
+// TransactionResult is the outcome of a single Transaction evaluated as part
+// of a batch.
+type TransactionResult struct {
+	// Name is the limit which the Transaction was evaluated against.
+	Name Name
+
+	// Allowed is true if the Transaction was allowed. Denials by limits in
+	// ModeLogOnly are reported as allowed.
+	Allowed bool
+
+	// Remaining is the number of requests the client is allowed to make
+	// before they're rate limited by this limit.
+	Remaining int64
+
+	// RetryAfter is the duration the client MUST wait before this limit will
+	// allow a request.
+	RetryAfter time.Duration
+}
+
+// Results returns the outcome of each Transaction evaluated by the batch which
+// produced this *Decision, in the order they were provided. Allow-only and
+// spend-only Transactions are omitted.
+func (d *Decision) Results() []TransactionResult {
+	return d.results
+}
+
+// Binding returns the result for the limit which denied the request for the
+// longest, which is the one reported to the Subscriber by Result. If several
+// limits deny for equally long, the one with the least remaining capacity is
+// binding, and after that the latest in the batch. It returns false if no
+// limit denied the request.
+func (d *Decision) Binding() (TransactionResult, bool) {
+	var binding TransactionResult
+	var found bool
+	for _, r := range d.results {
+		if r.Allowed {
+			continue
+		}
+		if !found || r.RetryAfter > binding.RetryAfter ||
+			(r.RetryAfter == binding.RetryAfter && r.Remaining <= binding.Remaining) {
+			binding, found = r, true
+		}
+	}
+	return binding, found
+}
+
+// withResults returns a copy of d carrying the provided results.
+func (d *Decision) withResults(results []TransactionResult) *Decision {
+	withResults := *d
+	withResults.results = results
+	return &withResults
 }
 
 // Result translates a denied *Decision into a berrors.RateLimitError for the
 // Subscriber, or returns nil if the *Decision allows the request. The error
-// message includes a human-readable description of the exceeded rate limit and
-// a retry-after timestamp.
+// message includes a human-readable description of the exceeded rate limit, a
+// retry-after timestamp, and the name of the limit.
 func (d *Decision) Result(now time.Time) error {
 	if d.allowed {
 		return nil
@@ -108,27 +225,30 @@ func (d *Decision) Result(now time.Time) error {
 	case NewRegistrationsPerIPAddress:
 		return berrors.RegistrationsPerIPAddressError(
 			retryAfter,
-			"too many new registrations (%d) from this IP address in the last %s, retry after %s",
+			"too many new registrations (%d) from this IP address in the last %s, retry after %s (limit %s)",
 			d.transaction.limit.burst,
 			d.transaction.limit.period.Duration,
 			retryAfterTs,
+			d.transaction.limit.name,
 		)
 
 	case NewRegistrationsPerIPv6Range:
 		return berrors.RegistrationsPerIPv6RangeError(
 			retryAfter,
-			"too many new registrations (%d) from this /48 subnet of IPv6 addresses in the last %s, retry after %s",
+			"too many new registrations (%d) from this /48 subnet of IPv6 addresses in the last %s, retry after %s (limit %s)",
 			d.transaction.limit.burst,
 			d.transaction.limit.period.Duration,
 			retryAfterTs,
+			d.transaction.limit.name,
 		)
 	case NewOrdersPerAccount:
 		return berrors.NewOrdersPerAccountError(
 			retryAfter,
-			"too many new orders (%d) from this account in the last %s, retry after %s",
+			"too many new orders (%d) from this account in the last %s, retry after %s (limit %s)",
 			d.transaction.limit.burst,
 			d.transaction.limit.period.Duration,
 			retryAfterTs,
+			d.transaction.limit.name,
 		)
 
 	case FailedAuthorizationsPerDomainPerAccount:
@@ -140,11 +260,29 @@ func (d *Decision) Result(now time.Time) error {
 		domain := d.transaction.bucketKey[idx+1:]
 		return berrors.FailedAuthorizationsPerDomainPerAccountError(
 			retryAfter,
-			"too many failed authorizations (%d) for %q in the last %s, retry after %s",
+			"too many failed authorizations (%d) for %q in the last %s, retry after %s (limit %s)",
+			d.transaction.limit.burst,
+			domain,
+			d.transaction.limit.period.Duration,
+			retryAfterTs,
+			d.transaction.limit.name,
+		)
+
+	case FailedValidationsPerDomainPerAccount:
+		// Uses bucket key 'enum:regId:domain'.
+		idx := strings.LastIndex(d.transaction.bucketKey, ":")
+		if idx == -1 {
+			return berrors.InternalServerError("unrecognized bucket key while generating error")
+		}
+		domain := d.transaction.bucketKey[idx+1:]
+		return berrors.FailedValidationsPerDomainPerAccountError(
+			retryAfter,
+			"too many failed validations (%d) for %q in the last %s, retry after %s (limit %s)",
 			d.transaction.limit.burst,
 			domain,
 			d.transaction.limit.period.Duration,
 			retryAfterTs,
+			d.transaction.limit.name,
 		)
 
 	case CertificatesPerDomain, CertificatesPerDomainPerAccount:
@@ -156,20 +294,57 @@ func (d *Decision) Result(now time.Time) error {
 		domain := d.transaction.bucketKey[idx+1:]
 		return berrors.CertificatesPerDomainError(
 			retryAfter,
-			"too many certificates (%d) already issued for %q in the last %s, retry after %s",
+			"too many certificates (%d) already issued for %q in the last %s, retry after %s (limit %s)",
 			d.transaction.limit.burst,
 			domain,
 			d.transaction.limit.period.Duration,
 			retryAfterTs,
+			d.transaction.limit.name,
 		)
 
 	case CertificatesPerFQDNSet:
 		return berrors.CertificatesPerFQDNSetError(
 			retryAfter,
-			"too many certificates (%d) already issued for this exact set of domains in the last %s, retry after %s",
+			"too many certificates (%d) already issued for this exact set of domains in the last %s, retry after %s (limit %s)",
 			d.transaction.limit.burst,
 			d.transaction.limit.period.Duration,
 			retryAfterTs,
+			d.transaction.limit.name,
+		)
+
+	case GlobalIssuanceRate:
+		return berrors.RateLimitError(
+			retryAfter,
+			"too many certificates are being issued across the whole service; this is a temporary, service-wide limit which is not specific to this account, retry after %s (limit %s)",
+			retryAfterTs,
+			d.transaction.limit.name,
+		)
+
+	case NewOrdersPerDomain:
+		// Uses bucket key 'enum:domain'.
+		idx := strings.LastIndex(d.transaction.bucketKey, ":")
+		if idx == -1 {
+			return berrors.InternalServerError("unrecognized bucket key while generating error")
+		}
+		domain := d.transaction.bucketKey[idx+1:]
+		return berrors.RateLimitError(
+			retryAfter,
+			"too many new orders (%d) created for %q by all accounts in the last %s, retry after %s (limit %s)",
+			d.transaction.limit.burst,
+			domain,
+			d.transaction.limit.period.Duration,
+			retryAfterTs,
+			d.transaction.limit.name,
+		)
+
+	case WillingnessChecksPerAccount:
+		return berrors.RateLimitError(
+			retryAfter,
+			"too many identifiers (%d) checked for willingness to issue by this account in the last %s, retry after %s (limit %s)",
+			d.transaction.limit.burst,
+			d.transaction.limit.period.Duration,
+			retryAfterTs,
+			d.transaction.limit.name,
 		)
 
 	default:
@@ -183,6 +358,10 @@ func (d *Decision) Result(now time.Time) error {
 // cost WERE to be deducted. If no bucket exists it will NOT be created. No
 // state is persisted to the underlying datastore.
 func (l *Limiter) Check(ctx context.Context, txn Transaction) (*Decision, error) {
+	err := txn.wellFormed()
+	if err != nil {
+		return nil, err
+	}
 	if txn.allowOnly() {
 		return allowedDecision, nil
 	}
@@ -195,11 +374,182 @@ func (l *Limiter) Check(ctx context.Context, txn Transaction) (*Decision, error)
 			return nil, err
 		}
 		// First request from this client. No need to initialize the bucket
-		// because this is a check, not a spend. A TAT of "now" is equivalent to
-		// a full bucket.
-		return maybeSpend(l.clk, txn, l.clk.Now()), nil
+		// because this is a check, not a spend. A zero TAT is equivalent to a
+		// full bucket.
+		return l.applyMode(txn, maybeSpend(l.clk, txn, time.Time{})), nil
+	}
+	d := maybeSpend(l.clk, txn, tat)
+	if !d.allowed {
+		l.recordDenialStreaks(ctx, []Transaction{txn})
 	}
-	return maybeSpend(l.clk, txn, tat), nil
+	return l.applyMode(txn, d), nil
+}
+
+// Status is the current state of a single bucket, as reported by
+// AccountOverview, or of every shard of GlobalIssuanceRate, as reported by
+// GlobalIssuanceStatus.
+type Status struct {
+	// Name is the limit which the bucket belongs to.
+	Name Name
+
+	// BucketKey is the key of the bucket, formatted as 'name:id'.
+	BucketKey string
+
+	// Burst is the maximum capacity of the bucket.
+	Burst int64
+
+	// Remaining is the capacity currently left in the bucket.
+	Remaining int64
+
+	// ResetIn is the duration the bucket will take to refill to its maximum
+	// capacity, assuming no further requests are made.
+	ResetIn time.Duration
+}
+
+// AccountOverview returns the Status of every bucket which limits the provided
+// account: its NewOrdersPerAccount bucket, limited by the default for the
+// account's age bucket when no override exists, followed by the
+// FailedAuthorizationsPerDomainPerAccount buckets for each of the
+// recentDomains, and then the CertificatesPerDomainPerAccount (if an override
+// is configured) and CertificatesPerDomain buckets for the eTLD+1 of each of
+// the recentDomains. Buckets for disabled limits are omitted. All buckets are
+// read from the source in a single batch, and buckets which don't exist are
+// reported at full capacity. No state is persisted to the underlying
+// datastore.
+func (l *Limiter) AccountOverview(ctx context.Context, txnBuilder *TransactionBuilder, regId int64, ageBucket AccountAgeBucket, recentDomains []string) ([]Status, error) {
+	txns, err := txnBuilder.accountOverviewTransactions(regId, ageBucket, recentDomains)
+	if err != nil {
+		return nil, fmt.Errorf("building account overview transactions: %w", err)
+	}
+	if len(txns) == 0 {
+		return nil, nil
+	}
+
+	bucketKeys := make([]string, 0, len(txns))
+	for _, txn := range txns {
+		bucketKeys = append(bucketKeys, txn.bucketKey)
+	}
+
+	tats, err := l.source.BatchGet(ctx, bucketKeys)
+	if err != nil {
+		return nil, fmt.Errorf("batch get for %d keys: %w", len(bucketKeys), err)
+	}
+
+	statuses := make([]Status, 0, len(txns))
+	for _, txn := range txns {
+		// A zero TAT, for a bucket which doesn't exist, is equivalent to a
+		// full bucket.
+		d := maybeSpend(l.clk, txn, tats[txn.bucketKey])
+		statuses = append(statuses, Status{
+			Name:      txn.limit.name,
+			BucketKey: txn.bucketKey,
+			Burst:     txn.limit.burst,
+			Remaining: d.remaining,
+			// The TAT of a full bucket may be up to one jitter in the past.
+			ResetIn: max(d.resetIn, 0),
+		})
+	}
+	return statuses, nil
+}
+
+// globalIssuanceShards returns a zero-cost check-only Transaction for every
+// shard of the GlobalIssuanceRate limit, along with the TAT of each shard's
+// bucket. All shards are read from the source in a single batch. It returns no
+// Transactions if the limit is disabled.
+func (l *Limiter) globalIssuanceShards(ctx context.Context, txnBuilder *TransactionBuilder) ([]Transaction, map[string]time.Time, error) {
+	txns, err := txnBuilder.globalIssuanceRateShardTransactions()
+	if err != nil {
+		return nil, nil, fmt.Errorf("building global issuance transactions: %w", err)
+	}
+	if len(txns) == 0 {
+		return nil, nil, nil
+	}
+
+	bucketKeys := make([]string, 0, len(txns))
+	for _, txn := range txns {
+		bucketKeys = append(bucketKeys, txn.bucketKey)
+	}
+
+	tats, err := l.source.BatchGet(ctx, bucketKeys)
+	if err != nil {
+		return nil, nil, fmt.Errorf("batch get for %d keys: %w", len(bucketKeys), err)
+	}
+	return txns, tats, nil
+}
+
+// GlobalIssuanceStatus returns the Status of the GlobalIssuanceRate limit,
+// aggregated across all of its shards: the Burst and Remaining are the sums of
+// those of each shard, and the ResetIn is the longest of any shard. It returns
+// nil if the limit is disabled. No state is persisted to the underlying
+// datastore.
+func (l *Limiter) GlobalIssuanceStatus(ctx context.Context, txnBuilder *TransactionBuilder) (*Status, error) {
+	txns, tats, err := l.globalIssuanceShards(ctx, txnBuilder)
+	if err != nil {
+		return nil, err
+	}
+	if len(txns) == 0 {
+		return nil, nil
+	}
+
+	status := &Status{
+		Name:      GlobalIssuanceRate,
+		BucketKey: joinWithColon(GlobalIssuanceRate.EnumString(), globalId),
+	}
+	for _, txn := range txns {
+		// A zero TAT, for a bucket which doesn't exist, is equivalent to a
+		// full bucket.
+		d := maybeSpend(l.clk, txn, tats[txn.bucketKey])
+		status.Burst += txn.limit.burst
+		status.Remaining += d.remaining
+		// The TAT of a full bucket may be up to one jitter in the past.
+		status.ResetIn = max(status.ResetIn, d.resetIn)
+	}
+	return status, nil
+}
+
+// SpendGlobalIssuance spends a single certificate against the
+// GlobalIssuanceRate limit. The spend is first made against a randomly chosen
+// shard. If that shard is exhausted, every shard is read in a single batch and
+// the spend falls back to the shard with the most capacity remaining, so that
+// issuance is only denied once the limit as a whole is exhausted. A denied
+// *Decision reports the soonest that any shard will have capacity again.
+func (l *Limiter) SpendGlobalIssuance(ctx context.Context, txnBuilder *TransactionBuilder) (*Decision, error) {
+	txn, err := txnBuilder.GlobalIssuanceRateTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("building global issuance transaction: %w", err)
+	}
+	d, err := l.Spend(ctx, txn)
+	if err != nil || d.allowed {
+		return d, err
+	}
+
+	txns, tats, err := l.globalIssuanceShards(ctx, txnBuilder)
+	if err != nil {
+		return nil, err
+	}
+	var fallback *Transaction
+	var fallbackRemaining int64
+	for _, shard := range txns {
+		shardTxn, err := NewSpendTransaction(shard.limit, shard.bucketKey, 1)
+		if err != nil {
+			return nil, err
+		}
+		shardDecision := maybeSpend(l.clk, shardTxn, tats[shard.bucketKey])
+		if !shardDecision.allowed {
+			if shardDecision.retryIn < d.retryIn {
+				d = shardDecision
+			}
+			continue
+		}
+		if fallback == nil || shardDecision.remaining > fallbackRemaining {
+			fallback = &shardTxn
+			fallbackRemaining = shardDecision.remaining
+		}
+	}
+	if fallback == nil {
+		return d, nil
+	}
+	return l.Spend(ctx, *fallback)
 }
 
 // Spend attempts to deduct the cost from the provided bucket's capacity. The
@@ -216,6 +566,10 @@ func prepareBatch(txns []Transaction) ([]Transaction, []string, error) {
 	var bucketKeys []string
 	var transactions []Transaction
 	for _, txn := range txns {
+		err := txn.wellFormed()
+		if err != nil {
+			return nil, nil, err
+		}
 		if txn.allowOnly() {
 			// Ignore allow-only transactions.
 			continue
@@ -229,6 +583,17 @@ func prepareBatch(txns []Transaction) ([]Transaction, []string, error) {
 	return transactions, bucketKeys, nil
 }
 
+// newTransactionResult returns the TransactionResult for a Transaction and
+// the *Decision reported for it.
+func newTransactionResult(txn Transaction, d *Decision) TransactionResult {
+	return TransactionResult{
+		Name:       txn.limit.name,
+		Allowed:    d.allowed,
+		Remaining:  d.remaining,
+		RetryAfter: d.retryIn,
+	}
+}
+
 func stricter(existing *Decision, incoming *Decision) *Decision {
 	if existing.retryIn == incoming.retryIn {
 		if existing.remaining < incoming.remaining {
@@ -271,18 +636,20 @@ func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision
 	incrBuckets := make(map[string]increment)
 	staleBuckets := make(map[string]time.Time)
 	txnOutcomes := make(map[Transaction]string)
+	var results []TransactionResult
+	var denied []Transaction
 
 	for _, txn := range batch {
 		storedTAT, bucketExists := tats[txn.bucketKey]
 		d := maybeSpend(l.clk, txn, storedTAT)
+		l.overrides.observe(txn, d, l.clk.Now())
 
-		if d.allowed && (storedTAT != d.newTAT) && txn.spend {
+		if d.allowed && (storedTAT != d.newTAT) && txn.spends() {
 			if !bucketExists {
 				newBuckets[txn.bucketKey] = d.newTAT
 			} else if storedTAT.After(l.clk.Now()) {
 				incrBuckets[txn.bucketKey] = increment{
 					cost: time.Duration(txn.cost * txn.limit.emissionInterval),
-					ttl:  time.Duration(txn.limit.burstOffset),
 				}
 			} else {
 				staleBuckets[txn.bucketKey] = d.newTAT
@@ -292,7 +659,12 @@ func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision
 		if !txn.spendOnly() {
 			// Spend-only Transactions are best-effort and do not contribute to
 			// the batchDecision.
-			batchDecision = stricter(batchDecision, d)
+			reported := l.applyMode(txn, d)
+			batchDecision = stricter(batchDecision, reported)
+			results = append(results, newTransactionResult(txn, reported))
+			if !d.allowed {
+				denied = append(denied, txn)
+			}
 		}
 
 		txnOutcomes[txn] = Denied
@@ -300,6 +672,7 @@ func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision
 			txnOutcomes[txn] = Allowed
 		}
 	}
+	l.recordDenialStreaks(ctx, denied)
 
 	if batchDecision.allowed {
 		if len(newBuckets) > 0 {
@@ -317,7 +690,6 @@ func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision
 				if alreadyExists[txn.bucketKey] {
 					incrBuckets[txn.bucketKey] = increment{
 						cost: time.Duration(txn.cost * txn.limit.emissionInterval),
-						ttl:  time.Duration(txn.limit.burstOffset),
 					}
 				}
 			}
@@ -348,7 +720,201 @@ func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision
 	for txn, outcome := range txnOutcomes {
 		l.spendLatency.WithLabelValues(txn.limit.name.String(), outcome).Observe(perTxnLatency.Seconds())
 	}
-	return batchDecision, nil
+	return batchDecision.withResults(results), nil
+}
+
+// reservationTTL is how long the provisional spends made by Reserve are held
+// before they're automatically released.
+const reservationTTL = time.Minute
+
+// reservationToken identifies a reservation made by Reserve and the buckets it
+// holds spends against. The Source records each spend alongside its bucket,
+// atomically with the spend itself, so the token rather than the Source is
+// what ties a reservation's buckets together. Buckets may be on different
+// shards, so no single write could record them all.
+type reservationToken struct {
+	ID         string   `json:"id"`
+	BucketKeys []string `json:"buckets"`
+}
+
+// encode returns the token in the form handed to callers of Reserve.
+func (t reservationToken) encode() (string, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeReservationToken parses a token returned from Reserve.
+func decodeReservationToken(token string) (reservationToken, error) {
+	var t reservationToken
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return t, fmt.Errorf("malformed reservation token: %w", err)
+	}
+	err = json.Unmarshal(b, &t)
+	if err != nil {
+		return t, fmt.Errorf("malformed reservation token: %w", err)
+	}
+	if t.ID == "" || len(t.BucketKeys) == 0 {
+		return t, errors.New("malformed reservation token: missing id or buckets")
+	}
+	return t, nil
+}
+
+// Reserve attempts to provisionally deduct the costs from the provided
+// buckets' capacities. Unlike BatchSpend, the check and deduction are atomic
+// for each bucket, so concurrent reservations cannot overshoot a limit. If the
+// returned *Decision allows the request, the returned token must be passed to
+// Commit to make the spends permanent or to Release to roll them back. Spends
+// which are neither committed nor released within reservationTTL are released
+// automatically. If the request is denied, any provisional spends are released
+// before returning. The token is empty if there was nothing to reserve.
+// Check-only Transactions are checked but never reserved. The returned
+// *Decision represents the strictest of all *Decisions reached in the batch.
+func (l *Limiter) Reserve(ctx context.Context, txns []Transaction) (string, *Decision, error) {
+	start := l.clk.Now()
+
+	batch, bucketKeys, err := prepareBatch(txns)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(batch) == 0 {
+		// All Transactions were allow-only.
+		return "", allowedDecision, nil
+	}
+
+	// Remove cancellation from the request context so that transactions are not
+	// interrupted by a client disconnect.
+	ctx = context.WithoutCancel(ctx)
+
+	var checkKeys []string
+	reservations := make(map[string]reservation)
+	for _, txn := range batch {
+		if txn.checkOnly() {
+			checkKeys = append(checkKeys, txn.bucketKey)
+			continue
+		}
+		reservations[txn.bucketKey] = reservation{
+			cost:        time.Duration(txn.cost * txn.limit.emissionInterval),
+			burstOffset: time.Duration(txn.limit.burstOffset),
+			ttl:         time.Duration(txn.limit.burstOffset),
+			jitter:      txn.limit.jitter(txn.bucketKey),
+		}
+	}
+
+	tats := make(map[string]time.Time, len(bucketKeys))
+	if len(checkKeys) > 0 {
+		tats, err = l.source.BatchGet(ctx, checkKeys)
+		if err != nil {
+			return "", nil, fmt.Errorf("batch get for %d keys: %w", len(checkKeys), err)
+		}
+	}
+
+	var rt reservationToken
+	var reserved map[string]bool
+	if len(reservations) > 0 {
+		rt.ID = core.RandomString(16)
+		now := l.clk.Now()
+		var reservedTATs map[string]time.Time
+		reservedTATs, reserved, err = l.source.BatchReserve(ctx, rt.ID, now, now.Add(reservationTTL), reservations)
+		if err != nil {
+			return "", nil, fmt.Errorf("batch reserve for %d keys: %w", len(reservations), err)
+		}
+		maps.Copy(tats, reservedTATs)
+	}
+
+	batchDecision := allowedDecision
+	txnOutcomes := make(map[Transaction]string)
+	var results []TransactionResult
+	var denied []Transaction
+	for _, txn := range batch {
+		d := maybeSpend(l.clk, txn, tats[txn.bucketKey])
+		if !txn.checkOnly() {
+			// The source reached its decision atomically, so it's
+			// authoritative.
+			d.allowed = reserved[txn.bucketKey]
+		}
+		l.overrides.observe(txn, d, l.clk.Now())
+
+		if !txn.spendOnly() {
+			// Spend-only Transactions are best-effort and do not contribute to
+			// the batchDecision.
+			reported := l.applyMode(txn, d)
+			batchDecision = stricter(batchDecision, reported)
+			results = append(results, newTransactionResult(txn, reported))
+			if !d.allowed {
+				denied = append(denied, txn)
+			}
+		}
+
+		txnOutcomes[txn] = Denied
+		if d.allowed {
+			txnOutcomes[txn] = Allowed
+		}
+	}
+	l.recordDenialStreaks(ctx, denied)
+
+	var token string
+	if len(reserved) > 0 {
+		rt.BucketKeys = slices.Sorted(maps.Keys(reserved))
+		if !batchDecision.allowed {
+			err = l.source.Release(ctx, rt.ID, rt.BucketKeys, l.clk.Now())
+			if err != nil {
+				return "", nil, fmt.Errorf("releasing reservation for denied batch: %w", err)
+			}
+		} else {
+			token, err = rt.encode()
+			if err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	// Observe latency equally across all transactions in the batch.
+	totalLatency := l.clk.Since(start)
+	perTxnLatency := totalLatency / time.Duration(len(txnOutcomes))
+	for txn, outcome := range txnOutcomes {
+		l.spendLatency.WithLabelValues(txn.limit.name.String(), outcome).Observe(perTxnLatency.Seconds())
+	}
+	return token, batchDecision.withResults(results), nil
+}
+
+// Commit makes the provisional spends held by a token returned from Reserve
+// permanent. If the reservation expired before it could be committed, its
+// spends have been refunded and ErrReservationNotFound is returned. Committing
+// an empty token is a no-op.
+func (l *Limiter) Commit(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	rt, err := decodeReservationToken(token)
+	if err != nil {
+		return err
+	}
+	// Remove cancellation from the request context so that transactions are not
+	// interrupted by a client disconnect.
+	ctx = context.WithoutCancel(ctx)
+	return l.source.Commit(ctx, rt.ID, rt.BucketKeys, l.clk.Now())
+}
+
+// Release refunds the provisional spends held by a token returned from
+// Reserve. Releasing a reservation which was already committed, released, or
+// has expired returns ErrReservationNotFound. Releasing an empty token is a
+// no-op.
+func (l *Limiter) Release(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	rt, err := decodeReservationToken(token)
+	if err != nil {
+		return err
+	}
+	// Remove cancellation from the request context so that transactions are not
+	// interrupted by a client disconnect.
+	ctx = context.WithoutCancel(ctx)
+	return l.source.Release(ctx, rt.ID, rt.BucketKeys, l.clk.Now())
 }
 
 // Refund attempts to refund all of the cost to the capacity of the specified
@@ -393,6 +959,7 @@ func (l *Limiter) BatchRefund(ctx context.Context, txns []Transaction) (*Decisio
 
 	batchDecision := allowedDecision
 	incrBuckets := make(map[string]increment)
+	l.resetDenialStreaks(ctx, batch)
 
 	for _, txn := range batch {
 		tat, bucketExists := tats[txn.bucketKey]
@@ -411,7 +978,6 @@ func (l *Limiter) BatchRefund(ctx context.Context, txns []Transaction) (*Decisio
 			// New bucket state should be persisted.
 			incrBuckets[txn.bucketKey] = increment{
 				cost: time.Duration(-txn.cost * txn.limit.emissionInterval),
-				ttl:  time.Duration(txn.limit.burstOffset),
 			}
 		}
 	}
@@ -2,15 +2,22 @@ package ratelimits
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand/v2"
 	"net"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/letsencrypt/boulder/config"
 	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/test"
 )
@@ -21,7 +28,7 @@ const tenZeroZeroTwo = "10.0.0.2"
 
 // newTestLimiter constructs a new limiter.
 func newTestLimiter(t *testing.T, s Source, clk clock.FakeClock) *Limiter {
-	l, err := NewLimiter(clk, s, metrics.NoopRegisterer)
+	l, err := NewLimiter(clk, s, metrics.NoopRegisterer, blog.NewMock())
 	test.AssertNotError(t, err, "should not error")
 	return l
 }
@@ -54,6 +61,83 @@ func setup(t *testing.T) (context.Context, map[string]*Limiter, *TransactionBuil
 	}, newTestTransactionBuilder(t), clk, randIP.String()
 }
 
+func TestLimiter_TransactionKinds(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, txnBuilder, _, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			test.AssertNotError(t, err, "should not error")
+			testLimit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+			test.AssertNotError(t, err, "should not error")
+			newTxn := func(construct func(*limit, string, int64) (Transaction, error), cost int64) Transaction {
+				t.Helper()
+				txn, err := construct(testLimit, bucketKey, cost)
+				test.AssertNotError(t, err, "txn should be valid")
+				return txn
+			}
+
+			// A check-only transaction is never spent, even in a batch.
+			checkOnly20 := newTxn(NewCheckOnlyTransaction, 20)
+			d, err := l.Check(testCtx, checkOnly20)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			d, err = l.BatchSpend(testCtx, []Transaction{checkOnly20})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			d, err = l.Check(testCtx, checkOnly20)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "check-only transaction should not have been spent")
+
+			// A check-and-spend transaction is spent, and denied once the
+			// bucket is empty.
+			d, err = l.Spend(testCtx, newTxn(NewSpendTransaction, 20))
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			d, err = l.Check(testCtx, newTxn(NewCheckOnlyTransaction, 1))
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, !d.allowed, "should not be allowed")
+			d, err = l.Spend(testCtx, newTxn(NewSpendTransaction, 1))
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, !d.allowed, "should not be allowed")
+
+			// A spend-only transaction is allowed even though the bucket is
+			// empty.
+			d, err = l.Spend(testCtx, newTxn(NewSpendOnlyTransaction, 1))
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "spend-only transaction should be allowed")
+
+			// Transactions which weren't made by a constructor are rejected
+			// by every method, rather than being processed as some kind.
+			for _, tc := range []struct {
+				txn   Transaction
+				field string
+			}{
+				{Transaction{bucketKey: bucketKey, limit: testLimit, cost: 1, kind: transactionKind(42)}, "kind"},
+				{Transaction{bucketKey: bucketKey, cost: 1, kind: txnCheckOnly}, "limit"},
+			} {
+				assertMalformed := func(err error) {
+					t.Helper()
+					var malformedErr *MalformedTransactionError
+					test.Assert(t, errors.As(err, &malformedErr), fmt.Sprintf("expected *MalformedTransactionError, got %v", err))
+					test.AssertEquals(t, malformedErr.Field, tc.field)
+					test.AssertEquals(t, malformedErr.BucketKey, bucketKey)
+				}
+				_, err = l.Check(testCtx, tc.txn)
+				assertMalformed(err)
+				_, err = l.BatchSpend(testCtx, []Transaction{checkOnly20, tc.txn})
+				assertMalformed(err)
+				_, _, err = l.Reserve(testCtx, []Transaction{tc.txn})
+				assertMalformed(err)
+				_, err = l.BatchRefund(testCtx, []Transaction{tc.txn})
+				assertMalformed(err)
+				_, _, err = l.CheckWithReceipt(testCtx, []Transaction{tc.txn})
+				assertMalformed(err)
+			}
+		})
+	}
+}
+
 func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 	t.Parallel()
 	testCtx, limiters, txnBuilder, clk, testIP := setup(t)
@@ -65,14 +149,14 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 			test.AssertNotError(t, err, "should not error")
 
 			// Attempt to spend all 40 requests, this should succeed.
-			overriddenTxn40, err := newTransaction(overriddenLimit, overriddenBucketKey, 40)
+			overriddenTxn40, err := NewSpendTransaction(overriddenLimit, overriddenBucketKey, 40)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err := l.Spend(testCtx, overriddenTxn40)
 			test.AssertNotError(t, err, "should not error")
 			test.Assert(t, d.allowed, "should be allowed")
 
 			// Attempting to spend 1 more, this should fail.
-			overriddenTxn1, err := newTransaction(overriddenLimit, overriddenBucketKey, 1)
+			overriddenTxn1, err := NewSpendTransaction(overriddenLimit, overriddenBucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.Spend(testCtx, overriddenTxn1)
 			test.AssertNotError(t, err, "should not error")
@@ -124,7 +208,7 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 			// Spend the same bucket but in a batch with bucket subject to
 			// default limits. This should succeed, but the decision should
 			// reflect that of the default bucket.
-			defaultTxn1, err := newTransaction(normalLimit, normalBucketKey, 1)
+			defaultTxn1, err := NewSpendTransaction(normalLimit, normalBucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.BatchSpend(testCtx, []Transaction{overriddenTxn1, defaultTxn1})
 			test.AssertNotError(t, err, "should not error")
@@ -159,7 +243,7 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 			// Spend the same bucket but in a batch with a Transaction that is
 			// check-only. This should succeed, but the decision should reflect
 			// that of the default bucket.
-			defaultCheckOnlyTxn1, err := newCheckOnlyTransaction(normalLimit, normalBucketKey, 1)
+			defaultCheckOnlyTxn1, err := NewCheckOnlyTransaction(normalLimit, normalBucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.BatchSpend(testCtx, []Transaction{overriddenTxn1, defaultCheckOnlyTxn1})
 			test.AssertNotError(t, err, "should not error")
@@ -168,7 +252,7 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 			test.AssertEquals(t, d.resetIn, time.Millisecond*50)
 
 			// Check the remaining quota of the overridden bucket.
-			overriddenCheckOnlyTxn0, err := newCheckOnlyTransaction(overriddenLimit, overriddenBucketKey, 0)
+			overriddenCheckOnlyTxn0, err := NewCheckOnlyTransaction(overriddenLimit, overriddenBucketKey, 0)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.Check(testCtx, overriddenCheckOnlyTxn0)
 			test.AssertNotError(t, err, "should not error")
@@ -177,7 +261,7 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 			test.AssertEquals(t, d.resetIn, time.Millisecond*25)
 
 			// Check the remaining quota of the default bucket.
-			defaultTxn0, err := newTransaction(normalLimit, normalBucketKey, 0)
+			defaultTxn0, err := NewSpendTransaction(normalLimit, normalBucketKey, 0)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.Check(testCtx, defaultTxn0)
 			test.AssertNotError(t, err, "should not error")
@@ -188,7 +272,7 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 			// Spend the same bucket but in a batch with a Transaction that is
 			// spend-only. This should succeed, but the decision should reflect
 			// that of the overridden bucket.
-			defaultSpendOnlyTxn1, err := newSpendOnlyTransaction(normalLimit, normalBucketKey, 1)
+			defaultSpendOnlyTxn1, err := NewSpendOnlyTransaction(normalLimit, normalBucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.BatchSpend(testCtx, []Transaction{overriddenTxn1, defaultSpendOnlyTxn1})
 			test.AssertNotError(t, err, "should not error")
@@ -213,7 +297,7 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 			// Once more, but in now the spend-only Transaction will attempt to
 			// spend 20 requests. The spend-only Transaction should fail, but
 			// the decision should reflect that of the overridden bucket.
-			defaultSpendOnlyTxn20, err := newSpendOnlyTransaction(normalLimit, normalBucketKey, 20)
+			defaultSpendOnlyTxn20, err := NewSpendOnlyTransaction(normalLimit, normalBucketKey, 20)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.BatchSpend(testCtx, []Transaction{overriddenTxn1, defaultSpendOnlyTxn20})
 			test.AssertNotError(t, err, "should not error")
@@ -254,7 +338,7 @@ func TestLimiter_InitializationViaCheckAndSpend(t *testing.T) {
 
 			// Check on an empty bucket should return the theoretical next state
 			// of that bucket if the cost were spent.
-			txn1, err := newTransaction(limit, bucketKey, 1)
+			txn1, err := NewSpendTransaction(limit, bucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err := l.Check(testCtx, txn1)
 			test.AssertNotError(t, err, "should not error")
@@ -267,7 +351,7 @@ func TestLimiter_InitializationViaCheckAndSpend(t *testing.T) {
 
 			// However, that cost should not be spent yet, a 0 cost check should
 			// tell us that we actually have 20 remaining.
-			txn0, err := newTransaction(limit, bucketKey, 0)
+			txn0, err := NewSpendTransaction(limit, bucketKey, 0)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.Check(testCtx, txn0)
 			test.AssertNotError(t, err, "should not error")
@@ -316,7 +400,7 @@ func TestLimiter_DefaultLimits(t *testing.T) {
 			test.AssertNotError(t, err, "should not error")
 
 			// Attempt to spend all 20 requests, this should succeed.
-			txn20, err := newTransaction(limit, bucketKey, 20)
+			txn20, err := NewSpendTransaction(limit, bucketKey, 20)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err := l.Spend(testCtx, txn20)
 			test.AssertNotError(t, err, "should not error")
@@ -325,7 +409,7 @@ func TestLimiter_DefaultLimits(t *testing.T) {
 			test.AssertEquals(t, d.resetIn, time.Second)
 
 			// Attempting to spend 1 more, this should fail.
-			txn1, err := newTransaction(limit, bucketKey, 1)
+			txn1, err := NewSpendTransaction(limit, bucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.Spend(testCtx, txn1)
 			test.AssertNotError(t, err, "should not error")
@@ -379,7 +463,7 @@ func TestLimiter_RefundAndReset(t *testing.T) {
 			test.AssertNotError(t, err, "should not error")
 
 			// Attempt to spend all 20 requests, this should succeed.
-			txn20, err := newTransaction(limit, bucketKey, 20)
+			txn20, err := NewSpendTransaction(limit, bucketKey, 20)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err := l.Spend(testCtx, txn20)
 			test.AssertNotError(t, err, "should not error")
@@ -388,7 +472,7 @@ func TestLimiter_RefundAndReset(t *testing.T) {
 			test.AssertEquals(t, d.resetIn, time.Second)
 
 			// Refund 10 requests.
-			txn10, err := newTransaction(limit, bucketKey, 10)
+			txn10, err := NewSpendTransaction(limit, bucketKey, 10)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.Refund(testCtx, txn10)
 			test.AssertNotError(t, err, "should not error")
@@ -415,7 +499,7 @@ func TestLimiter_RefundAndReset(t *testing.T) {
 			clk.Add(d.resetIn)
 
 			// Refund 1 requests above our limit, this should fail.
-			txn1, err := newTransaction(limit, bucketKey, 1)
+			txn1, err := NewSpendTransaction(limit, bucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			d, err = l.Refund(testCtx, txn1)
 			test.AssertNotError(t, err, "should not error")
@@ -427,7 +511,7 @@ func TestLimiter_RefundAndReset(t *testing.T) {
 			test.AssertNotError(t, err, "should not error")
 
 			// Refund a spendOnly Transaction, which should succeed.
-			spendOnlyTxn1, err := newSpendOnlyTransaction(limit, bucketKey, 1)
+			spendOnlyTxn1, err := NewSpendOnlyTransaction(limit, bucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			_, err = l.Refund(testCtx, spendOnlyTxn1)
 			test.AssertNotError(t, err, "should not error")
@@ -438,7 +522,7 @@ func TestLimiter_RefundAndReset(t *testing.T) {
 
 			// Refund a checkOnly Transaction, which shouldn't error but should
 			// return the same TAT as the previous spend.
-			checkOnlyTxn1, err := newCheckOnlyTransaction(limit, bucketKey, 1)
+			checkOnlyTxn1, err := NewCheckOnlyTransaction(limit, bucketKey, 1)
 			test.AssertNotError(t, err, "txn should be valid")
 			newDecision, err := l.Refund(testCtx, checkOnlyTxn1)
 			test.AssertNotError(t, err, "should not error")
@@ -447,6 +531,328 @@ func TestLimiter_RefundAndReset(t *testing.T) {
 	}
 }
 
+func TestLimiter_ReserveConcurrent(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, txnBuilder, _, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			test.AssertNotError(t, err, "should not error")
+			limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+			test.AssertNotError(t, err, "should not error")
+			txn1, err := NewSpendTransaction(limit, bucketKey, 1)
+			test.AssertNotError(t, err, "txn should be valid")
+
+			// Make many more concurrent reservations than the limit allows.
+			var wg sync.WaitGroup
+			tokens := make(chan string, 50)
+			for range 50 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					token, d, err := l.Reserve(testCtx, []Transaction{txn1})
+					test.AssertNotError(t, err, "should not error")
+					if d.allowed {
+						tokens <- token
+					} else {
+						test.AssertEquals(t, token, "")
+					}
+				}()
+			}
+			wg.Wait()
+			close(tokens)
+
+			// Exactly the burst of 20 should have been reserved.
+			seen := make(map[string]bool)
+			for token := range tokens {
+				test.Assert(t, token != "", "token should be set")
+				test.Assert(t, !seen[token], "tokens should be unique")
+				seen[token] = true
+				err = l.Commit(testCtx, token)
+				test.AssertNotError(t, err, "should not error")
+			}
+			test.AssertEquals(t, len(seen), 20)
+
+			// The committed spends have exhausted the bucket.
+			d, err := l.Check(testCtx, txn1)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, !d.allowed, "should not be allowed")
+		})
+	}
+}
+
+func TestLimiter_ReserveCommitAndRelease(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, clk, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			// Use a limit which refills far slower than reservations expire.
+			limit := &limit{name: NewRegistrationsPerIPAddress, burst: 10, count: 10, period: config.Duration{Duration: time.Hour}}
+			limit.precompute()
+			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			test.AssertNotError(t, err, "should not error")
+			txn5, err := NewSpendTransaction(limit, bucketKey, 5)
+			test.AssertNotError(t, err, "txn should be valid")
+			checkOnlyTxn5, err := NewCheckOnlyTransaction(limit, bucketKey, 5)
+			test.AssertNotError(t, err, "txn should be valid")
+
+			// Reserve and commit 5, leaving 5.
+			token, d, err := l.Reserve(testCtx, []Transaction{txn5})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			test.AssertEquals(t, d.remaining, int64(5))
+			err = l.Commit(testCtx, token)
+			test.AssertNotError(t, err, "should not error")
+
+			// A committed reservation can't be committed or released again.
+			err = l.Commit(testCtx, token)
+			test.AssertErrorIs(t, err, ErrReservationNotFound)
+			err = l.Release(testCtx, token)
+			test.AssertErrorIs(t, err, ErrReservationNotFound)
+
+			// Reserve the remaining 5, further reservations are denied and hold
+			// nothing.
+			token, d, err = l.Reserve(testCtx, []Transaction{txn5})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			test.AssertEquals(t, d.remaining, int64(0))
+			deniedToken, d, err := l.Reserve(testCtx, []Transaction{txn5})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, !d.allowed, "should not be allowed")
+			test.AssertEquals(t, deniedToken, "")
+
+			// Releasing the reservation returns its capacity.
+			err = l.Release(testCtx, token)
+			test.AssertNotError(t, err, "should not error")
+			d, err = l.Check(testCtx, checkOnlyTxn5)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+
+			// A reservation which isn't committed in time is released
+			// automatically and can no longer be committed.
+			token, d, err = l.Reserve(testCtx, []Transaction{txn5})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			clk.Add(reservationTTL)
+			_, d, err = l.Reserve(testCtx, []Transaction{txn5})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "expired reservation should have been released")
+			err = l.Commit(testCtx, token)
+			test.AssertErrorIs(t, err, ErrReservationNotFound)
+
+			// A token which wasn't returned from Reserve is rejected.
+			err = l.Commit(testCtx, "not-a-token")
+			test.AssertError(t, err, "malformed token should be rejected")
+		})
+	}
+}
+
+func TestLimiter_ReserveExpiresWithoutLaterReserve(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, clk, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			// Use a limit which refills far slower than reservations expire.
+			limit := &limit{name: NewRegistrationsPerIPAddress, burst: 10, count: 10, period: config.Duration{Duration: time.Hour}}
+			limit.precompute()
+			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			test.AssertNotError(t, err, "should not error")
+			txn10, err := NewSpendTransaction(limit, bucketKey, 10)
+			test.AssertNotError(t, err, "txn should be valid")
+			checkOnlyTxn10, err := NewCheckOnlyTransaction(limit, bucketKey, 10)
+			test.AssertNotError(t, err, "txn should be valid")
+
+			// Reserve the whole bucket, and then neither commit nor release it.
+			token, d, err := l.Reserve(testCtx, []Transaction{txn10})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+
+			rs, ok := l.source.(*RedisSource)
+			if ok {
+				// The record of the reservation must outlive the time the
+				// bucket could take to refill once the reservation expires.
+				ttl, err := rs.client.PTTL(testCtx, reservationsKey(bucketKey)).Result()
+				test.AssertNotError(t, err, "should not error")
+				test.Assert(t, ttl >= reservationTTL+time.Hour, fmt.Sprintf("reservations TTL %s expires before the bucket could refill", ttl))
+			}
+
+			clk.Add(reservationTTL / 2)
+			d, err = l.Check(testCtx, checkOnlyTxn10)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, !d.allowed, "reservation shouldn't have expired yet")
+
+			// Once the reservation has expired, reading the bucket, with no
+			// later Reserve against it, refunds it.
+			clk.Add(reservationTTL / 2)
+			d, err = l.Check(testCtx, checkOnlyTxn10)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "expired reservation should have been refunded")
+			d, err = l.BatchSpend(testCtx, []Transaction{txn10})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "expired reservation should have been refunded")
+			err = l.Commit(testCtx, token)
+			test.AssertErrorIs(t, err, ErrReservationNotFound)
+		})
+	}
+}
+
+func TestLimiter_JitterPreservesCapacity(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, clk, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			plainLimit := &limit{name: NewRegistrationsPerIPAddress, burst: 10, count: 10, period: config.Duration{Duration: time.Hour}}
+			plainLimit.precompute()
+			plainKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			test.AssertNotError(t, err, "should not error")
+
+			jitteredLimit := &limit{name: NewOrdersPerAccount, burst: 10, count: 10, period: config.Duration{Duration: time.Hour}, jitterFraction: 0.5}
+			jitteredLimit.precompute()
+			jitteredKey, err := newRegIdBucketKey(NewOrdersPerAccount, rand.Int64N(1<<40)+1)
+			test.AssertNotError(t, err, "should not error")
+			jitter := jitteredLimit.jitter(jitteredKey)
+			test.Assert(t, jitter > 0, "jitter should be non-zero")
+
+			// The jitter is reported by Check, even before the bucket exists.
+			checkTxn, err := NewCheckOnlyTransaction(jitteredLimit, jitteredKey, 1)
+			test.AssertNotError(t, err, "txn should be valid")
+			d, err := l.Check(testCtx, checkTxn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.jitter, jitter)
+
+			plainTxn, err := NewSpendTransaction(plainLimit, plainKey, 1)
+			test.AssertNotError(t, err, "txn should be valid")
+			jitteredTxn, err := NewSpendTransaction(jitteredLimit, jitteredKey, 1)
+			test.AssertNotError(t, err, "txn should be valid")
+
+			// The first spend against the jittered bucket leaves the same
+			// capacity, but the bucket refills sooner by the jitter.
+			d, err = l.Spend(testCtx, plainTxn)
+			test.AssertNotError(t, err, "should not error")
+			plainReset := d.resetIn
+			test.AssertEquals(t, d.remaining, int64(9))
+			d, err = l.Spend(testCtx, jitteredTxn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.remaining, int64(9))
+			test.AssertEquals(t, d.resetIn, plainReset-jitter)
+
+			// Spending as often as allowed, once a minute for a whole period,
+			// yields the same number of requests with and without jitter.
+			var plainAllowed, jitteredAllowed int
+			for range 61 {
+				for _, txn := range []Transaction{plainTxn, jitteredTxn} {
+					for {
+						d, err := l.Spend(testCtx, txn)
+						test.AssertNotError(t, err, "should not error")
+						if !d.allowed {
+							break
+						}
+						if txn == plainTxn {
+							plainAllowed++
+						} else {
+							jitteredAllowed++
+						}
+					}
+				}
+				clk.Add(time.Minute)
+			}
+			test.AssertEquals(t, plainAllowed, 19)
+			test.AssertEquals(t, jitteredAllowed, plainAllowed)
+		})
+	}
+}
+
+func TestLimiter_BucketExpiryBoundary(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, clk, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			lim := &limit{name: NewRegistrationsPerIPAddress, burst: 10, count: 10, period: config.Duration{Duration: time.Hour}}
+			lim.precompute()
+			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			test.AssertNotError(t, err, "should not error")
+			txn, err := NewSpendTransaction(lim, bucketKey, 1)
+			test.AssertNotError(t, err, "txn should be valid")
+
+			// Drain the bucket, creating it and then incrementing it.
+			for range 10 {
+				d, err := l.Spend(testCtx, txn)
+				test.AssertNotError(t, err, "should not error")
+				test.Assert(t, d.allowed, "should be allowed")
+			}
+			tat, err := l.source.Get(testCtx, bucketKey)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, tat, clk.Now().Add(time.Hour))
+
+			rs, ok := l.source.(*RedisSource)
+			if ok {
+				// The key must outlive the time the bucket takes to refill.
+				ttl, err := rs.client.PTTL(testCtx, bucketKey).Result()
+				test.AssertNotError(t, err, "should not error")
+				test.Assert(t, ttl > tat.Sub(clk.Now()), fmt.Sprintf("TTL %s expires before the bucket refills", ttl))
+				test.Assert(t, ttl <= tat.Sub(clk.Now())+bucketTTLMargin, fmt.Sprintf("TTL %s exceeds the margin", ttl))
+			}
+
+			// Just before the TAT the bucket is still partially drained, so
+			// losing the key would grant capacity early. (The remaining
+			// capacity reported by Check is net of the checked cost.)
+			clk.Add(tat.Sub(clk.Now()) - time.Nanosecond)
+			d, err := l.Check(testCtx, txn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.remaining, int64(8))
+
+			// At the TAT the bucket has fully refilled, and behaves exactly as
+			// it would if its key had expired.
+			clk.Add(time.Nanosecond)
+			withKey, err := l.Check(testCtx, txn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, withKey.remaining, int64(9))
+			err = l.Reset(testCtx, bucketKey)
+			test.AssertNotError(t, err, "should not error")
+			expired, err := l.Check(testCtx, txn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, expired.remaining, withKey.remaining)
+			test.AssertEquals(t, expired.newTAT, withKey.newTAT)
+			test.AssertEquals(t, expired.resetIn, withKey.resetIn)
+		})
+	}
+}
+
+func TestLimiter_ReserveBatchDenied(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, txnBuilder, _, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			test.AssertNotError(t, err, "should not error")
+			limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+			test.AssertNotError(t, err, "should not error")
+			otherBucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(tenZeroZeroTwo))
+			test.AssertNotError(t, err, "should not error")
+			txn20, err := NewSpendTransaction(limit, bucketKey, 20)
+			test.AssertNotError(t, err, "txn should be valid")
+			otherTxn1, err := NewSpendTransaction(limit, otherBucketKey, 1)
+			test.AssertNotError(t, err, "txn should be valid")
+			checkOnlyOtherTxn20, err := NewCheckOnlyTransaction(limit, otherBucketKey, 20)
+			test.AssertNotError(t, err, "txn should be valid")
+
+			// Exhaust the first bucket.
+			d, err := l.Spend(testCtx, txn20)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+
+			// A batch which is denied by one bucket holds nothing in the other.
+			token, d, err := l.Reserve(testCtx, []Transaction{txn20, otherTxn1})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, !d.allowed, "should not be allowed")
+			test.AssertEquals(t, token, "")
+			d, err = l.Check(testCtx, checkOnlyOtherTxn20)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+		})
+	}
+}
+
 func TestRateLimitError(t *testing.T) {
 	t.Parallel()
 	now := clock.NewFake().Now()
@@ -476,7 +882,7 @@ func TestRateLimitError(t *testing.T) {
 					},
 				},
 			},
-			expectedErr:     "too many new registrations (10) from this IP address in the last 1h0m0s, retry after 1970-01-01 00:00:05 UTC: see https://letsencrypt.org/docs/rate-limits/#new-registrations-per-ip-address",
+			expectedErr:     "too many new registrations (10) from this IP address in the last 1h0m0s, retry after 1970-01-01 00:00:05 UTC (limit NewRegistrationsPerIPAddress): see https://letsencrypt.org/docs/rate-limits/#new-registrations-per-ip-address",
 			expectedErrType: berrors.RateLimit,
 		},
 		{
@@ -492,7 +898,7 @@ func TestRateLimitError(t *testing.T) {
 					},
 				},
 			},
-			expectedErr:     "too many new registrations (5) from this /48 subnet of IPv6 addresses in the last 1h0m0s, retry after 1970-01-01 00:00:10 UTC: see https://letsencrypt.org/docs/rate-limits/#new-registrations-per-ipv6-range",
+			expectedErr:     "too many new registrations (5) from this /48 subnet of IPv6 addresses in the last 1h0m0s, retry after 1970-01-01 00:00:10 UTC (limit NewRegistrationsPerIPv6Range): see https://letsencrypt.org/docs/rate-limits/#new-registrations-per-ipv6-range",
 			expectedErrType: berrors.RateLimit,
 		},
 		{
@@ -508,7 +914,7 @@ func TestRateLimitError(t *testing.T) {
 					},
 				},
 			},
-			expectedErr:     "too many new orders (2) from this account in the last 1h0m0s, retry after 1970-01-01 00:00:10 UTC: see https://letsencrypt.org/docs/rate-limits/#new-orders-per-account",
+			expectedErr:     "too many new orders (2) from this account in the last 1h0m0s, retry after 1970-01-01 00:00:10 UTC (limit NewOrdersPerAccount): see https://letsencrypt.org/docs/rate-limits/#new-orders-per-account",
 			expectedErrType: berrors.RateLimit,
 		},
 		{
@@ -525,7 +931,24 @@ func TestRateLimitError(t *testing.T) {
 					bucketKey: "4:12345:example.com",
 				},
 			},
-			expectedErr:     "too many failed authorizations (7) for \"example.com\" in the last 1h0m0s, retry after 1970-01-01 00:00:15 UTC: see https://letsencrypt.org/docs/rate-limits/#authorization-failures-per-hostname-per-account",
+			expectedErr:     "too many failed authorizations (7) for \"example.com\" in the last 1h0m0s, retry after 1970-01-01 00:00:15 UTC (limit FailedAuthorizationsPerDomainPerAccount): see https://letsencrypt.org/docs/rate-limits/#authorization-failures-per-hostname-per-account",
+			expectedErrType: berrors.RateLimit,
+		},
+		{
+			name: "FailedValidationsPerDomainPerAccount limit reached",
+			decision: &Decision{
+				allowed: false,
+				retryIn: 15 * time.Second,
+				transaction: Transaction{
+					limit: &limit{
+						name:   FailedValidationsPerDomainPerAccount,
+						burst:  7,
+						period: config.Duration{Duration: time.Hour},
+					},
+					bucketKey: "9:12345:example.com",
+				},
+			},
+			expectedErr:     "too many failed validations (7) for \"example.com\" in the last 1h0m0s, retry after 1970-01-01 00:00:15 UTC (limit FailedValidationsPerDomainPerAccount): see https://letsencrypt.org/docs/rate-limits/",
 			expectedErrType: berrors.RateLimit,
 		},
 		{
@@ -542,7 +965,7 @@ func TestRateLimitError(t *testing.T) {
 					bucketKey: "5:example.org",
 				},
 			},
-			expectedErr:     "too many certificates (3) already issued for \"example.org\" in the last 1h0m0s, retry after 1970-01-01 00:00:20 UTC: see https://letsencrypt.org/docs/rate-limits/#new-certificates-per-registered-domain",
+			expectedErr:     "too many certificates (3) already issued for \"example.org\" in the last 1h0m0s, retry after 1970-01-01 00:00:20 UTC (limit CertificatesPerDomain): see https://letsencrypt.org/docs/rate-limits/#new-certificates-per-registered-domain",
 			expectedErrType: berrors.RateLimit,
 		},
 		{
@@ -559,7 +982,58 @@ func TestRateLimitError(t *testing.T) {
 					bucketKey: "6:12345678:example.net",
 				},
 			},
-			expectedErr:     "too many certificates (3) already issued for \"example.net\" in the last 1h0m0s, retry after 1970-01-01 00:00:20 UTC: see https://letsencrypt.org/docs/rate-limits/#new-certificates-per-registered-domain",
+			expectedErr:     "too many certificates (3) already issued for \"example.net\" in the last 1h0m0s, retry after 1970-01-01 00:00:20 UTC (limit CertificatesPerDomainPerAccount): see https://letsencrypt.org/docs/rate-limits/#new-certificates-per-registered-domain",
+			expectedErrType: berrors.RateLimit,
+		},
+		{
+			name: "GlobalIssuanceRate limit reached",
+			decision: &Decision{
+				allowed: false,
+				retryIn: 15 * time.Second,
+				transaction: Transaction{
+					limit: &limit{
+						name:   GlobalIssuanceRate,
+						burst:  1,
+						period: config.Duration{Duration: time.Hour},
+					},
+					bucketKey: "10:global:3",
+				},
+			},
+			expectedErr:     "too many certificates are being issued across the whole service; this is a temporary, service-wide limit which is not specific to this account, retry after 1970-01-01 00:00:15 UTC (limit GlobalIssuanceRate): see https://letsencrypt.org/docs/rate-limits/",
+			expectedErrType: berrors.RateLimit,
+		},
+		{
+			name: "NewOrdersPerDomain limit reached",
+			decision: &Decision{
+				allowed: false,
+				retryIn: 20 * time.Second,
+				transaction: Transaction{
+					limit: &limit{
+						name:   NewOrdersPerDomain,
+						burst:  100,
+						period: config.Duration{Duration: time.Hour},
+					},
+					bucketKey: "11:example.org",
+				},
+			},
+			expectedErr:     "too many new orders (100) created for \"example.org\" by all accounts in the last 1h0m0s, retry after 1970-01-01 00:00:20 UTC (limit NewOrdersPerDomain): see https://letsencrypt.org/docs/rate-limits/",
+			expectedErrType: berrors.RateLimit,
+		},
+		{
+			name: "WillingnessChecksPerAccount limit reached",
+			decision: &Decision{
+				allowed: false,
+				retryIn: 20 * time.Second,
+				transaction: Transaction{
+					limit: &limit{
+						name:   WillingnessChecksPerAccount,
+						burst:  100,
+						period: config.Duration{Duration: time.Hour},
+					},
+					bucketKey: "12:12345",
+				},
+			},
+			expectedErr:     "too many identifiers (100) checked for willingness to issue by this account in the last 1h0m0s, retry after 1970-01-01 00:00:20 UTC (limit WillingnessChecksPerAccount): see https://letsencrypt.org/docs/rate-limits/",
 			expectedErrType: berrors.RateLimit,
 		},
 		{
@@ -592,3 +1066,391 @@ func TestRateLimitError(t *testing.T) {
 		})
 	}
 }
+
+func TestLimiter_BatchSpendBinding(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, clk, _ := setup(t)
+	tb, err := NewTransactionBuilder(LimitConfigs{
+		NewRegistrationsPerIPAddress.String(): &LimitConfig{
+			Burst:  1,
+			Count:  1,
+			Period: config.Duration{Duration: time.Second},
+		},
+		NewRegistrationsPerIPv6Range.String(): &LimitConfig{
+			Burst:  1,
+			Count:  1,
+			Period: config.Duration{Duration: time.Hour},
+		},
+	})
+	test.AssertNotError(t, err, "should not error")
+
+	var subtests int
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			// Each subtest spends from its own buckets.
+			subtests++
+			ip := net.ParseIP(fmt.Sprintf("2001:db8:%x::1", subtests))
+			txns, err := tb.NewAccountLimitTransactions(ip)
+			test.AssertNotError(t, err, "should not error")
+
+			d, err := l.BatchSpend(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			_, found := d.Binding()
+			test.Assert(t, !found, "allowed decision should have no binding limit")
+			test.AssertDeepEquals(t, d.Results(), []TransactionResult{
+				{Name: NewRegistrationsPerIPAddress, Allowed: true, Remaining: 0, RetryAfter: time.Second},
+				{Name: NewRegistrationsPerIPv6Range, Allowed: true, Remaining: 0, RetryAfter: time.Hour},
+			})
+
+			// Both limits deny the next request, and the one which denies for
+			// longer is binding.
+			d, err = l.BatchSpend(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, !d.allowed, "should not be allowed")
+			test.AssertDeepEquals(t, d.Results(), []TransactionResult{
+				{Name: NewRegistrationsPerIPAddress, Allowed: false, Remaining: 0, RetryAfter: time.Second},
+				{Name: NewRegistrationsPerIPv6Range, Allowed: false, Remaining: 0, RetryAfter: time.Hour},
+			})
+			binding, found := d.Binding()
+			test.Assert(t, found, "denied decision should have a binding limit")
+			test.AssertEquals(t, binding.Name, NewRegistrationsPerIPv6Range)
+			test.AssertEquals(t, binding.RetryAfter, time.Hour)
+
+			err = d.Result(clk.Now())
+			test.AssertErrorIs(t, err, berrors.RateLimit)
+			test.AssertContains(t, err.Error(), "from this /48 subnet of IPv6 addresses")
+			test.AssertContains(t, err.Error(), "(limit NewRegistrationsPerIPv6Range)")
+		})
+	}
+}
+
+func TestLimiter_SwitchingTiersKeepsBucket(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, _, _ := setup(t)
+	tb, err := NewTransactionBuilderFromFiles("testdata/working_default_tiers.yml", "")
+	test.AssertNotError(t, err, "should not error")
+
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			regId := rand.Int64N(1000000) + 1
+
+			// Spend 10 of the 30 allowed to new accounts every 3h, leaving 20.
+			newTxn, err := tb.ordersPerAccountTransaction(regId, AccountAgeNew)
+			test.AssertNotError(t, err, "txn should be valid")
+			for range 10 {
+				d, err := l.Spend(testCtx, newTxn)
+				test.AssertNotError(t, err, "should not error")
+				test.Assert(t, d.allowed, "should be allowed")
+			}
+			d, err := l.Check(testCtx, newTxn)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			test.AssertEquals(t, d.remaining, int64(19))
+
+			// Once established, the same bucket is evaluated against the larger
+			// limit: the hour of refill spent as a new account still counts.
+			establishedTxn, err := tb.ordersPerAccountTransaction(regId, AccountAgeEstablished)
+			test.AssertNotError(t, err, "txn should be valid")
+			d, err = l.Spend(testCtx, establishedTxn)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			test.AssertEquals(t, d.remaining, int64(199))
+
+			// And switching back doesn't reset it either.
+			d, err = l.Check(testCtx, newTxn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.remaining, int64(18))
+		})
+	}
+}
+
+func TestLimiter_Modes(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, _, testIP := setup(t)
+
+	var subtests int
+	for _, mode := range []LimitMode{ModeEnforce, ModeLogOnly, ModeOff} {
+		tb, err := NewTransactionBuilder(LimitConfigs{
+			NewRegistrationsPerIPAddress.String(): &LimitConfig{
+				Burst:  1,
+				Count:  1,
+				Period: config.Duration{Duration: time.Second},
+				Mode:   mode,
+			},
+		})
+		test.AssertNotError(t, err, "should not error")
+
+		for name, l := range limiters {
+			t.Run(fmt.Sprintf("%s/%s", mode, name), func(t *testing.T) {
+				// Each subtest spends from its own bucket.
+				subtests++
+				ip := net.ParseIP(testIP).To4()
+				ip[3] = byte(subtests)
+				txn, err := tb.registrationsPerIPAddressTransaction(ip)
+				test.AssertNotError(t, err, "txn should be valid")
+				mockLog := l.log.(*blog.Mock)
+				mockLog.Clear()
+				l.wouldHaveDenied.Reset()
+
+				// The first request fits within the burst in every mode.
+				d, err := l.Spend(testCtx, txn)
+				test.AssertNotError(t, err, "should not error")
+				test.Assert(t, d.allowed, "should be allowed")
+
+				// The second and third exceed it, but are denied only when the
+				// limit is enforced.
+				for range 2 {
+					d, err = l.Spend(testCtx, txn)
+					test.AssertNotError(t, err, "should not error")
+					test.AssertEquals(t, d.allowed, mode != ModeEnforce)
+					d, err = l.Check(testCtx, txn)
+					test.AssertNotError(t, err, "should not error")
+					test.AssertEquals(t, d.allowed, mode != ModeEnforce)
+				}
+
+				var expected float64
+				if mode == ModeLogOnly {
+					expected = 4
+				}
+				test.AssertMetricWithLabelsEquals(t, l.wouldHaveDenied, prometheus.Labels{
+					"limit": NewRegistrationsPerIPAddress.String(),
+				}, expected)
+
+				// Only the first of the log-only denials is sampled.
+				logged := mockLog.GetAllMatching(`Rate limit NewRegistrationsPerIPAddress would have denied request in log-only mode \[bucket key hash: [0-9a-f]{64}, `)
+				if mode == ModeLogOnly {
+					test.AssertEquals(t, len(logged), 1)
+					test.AssertNotContains(t, logged[0], ip.String())
+				} else {
+					test.AssertEquals(t, len(logged), 0)
+				}
+
+				// Denied requests are never spent, even in log-only mode, and
+				// no bucket is created when the limit is off.
+				tat, err := l.source.Get(testCtx, txn.bucketKey)
+				if mode == ModeOff {
+					test.AssertErrorIs(t, err, ErrBucketNotFound)
+				} else {
+					test.AssertNotError(t, err, "should not error")
+					test.AssertEquals(t, tat, l.clk.Now().Add(time.Second))
+				}
+			})
+		}
+	}
+}
+
+// countingSource wraps a Source and counts the reads made against it.
+type countingSource struct {
+	Source
+	gets      int
+	batchGets int
+}
+
+func (c *countingSource) Get(ctx context.Context, bucketKey string) (time.Time, error) {
+	c.gets++
+	return c.Source.Get(ctx, bucketKey)
+}
+
+func (c *countingSource) BatchGet(ctx context.Context, bucketKeys []string) (map[string]time.Time, error) {
+	c.batchGets++
+	return c.Source.BatchGet(ctx, bucketKeys)
+}
+
+func TestLimiter_AccountOverview(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	source := &countingSource{Source: NewInmemSource(clk)}
+	l := newTestLimiter(t, source, clk)
+	txnBuilder, err := NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "testdata/working_override_13371338.yml")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	// Seed a mix of buckets: one partially spent per account bucket, one
+	// partially spent per domain per account bucket, and one exhausted per
+	// domain bucket. All buckets for example.net are left missing.
+	spend := func(name Name, limitBucketKey, bucketKey string, cost int64) {
+		t.Helper()
+		limit, err := txnBuilder.getLimit(name, limitBucketKey)
+		test.AssertNotError(t, err, "getting limit")
+		txn, err := NewSpendTransaction(limit, bucketKey, cost)
+		test.AssertNotError(t, err, "creating transaction")
+		d, err := l.Spend(context.Background(), txn)
+		test.AssertNotError(t, err, "spending")
+		test.Assert(t, d.allowed, "seeding spend should be allowed")
+	}
+	spend(NewOrdersPerAccount, "3:13371338", "3:13371338", 1)
+	spend(FailedAuthorizationsPerDomainPerAccount, "4:13371338", "4:13371338:www.example.com", 2)
+	spend(CertificatesPerDomain, "5:example.com", "5:example.com", 2)
+	source.gets, source.batchGets = 0, 0
+
+	statuses, err := l.AccountOverview(context.Background(), txnBuilder, 13371338, AccountAgeUnknown, []string{"www.example.com", "example.net"})
+	test.AssertNotError(t, err, "getting account overview")
+	test.AssertEquals(t, source.batchGets, 1)
+	test.AssertEquals(t, source.gets, 0)
+
+	type want struct {
+		name      Name
+		bucketKey string
+		burst     int64
+		remaining int64
+		resetting bool
+	}
+	expected := []want{
+		{NewOrdersPerAccount, "3:13371338", 1500, 1499, true},
+		{FailedAuthorizationsPerDomainPerAccount, "4:13371338:www.example.com", 1337, 1335, true},
+		{FailedAuthorizationsPerDomainPerAccount, "4:13371338:example.net", 1337, 1337, false},
+		{CertificatesPerDomainPerAccount, "6:13371338:example.com", 1337, 1337, false},
+		{CertificatesPerDomain, "5:example.com", 2, 0, true},
+		{CertificatesPerDomainPerAccount, "6:13371338:example.net", 1337, 1337, false},
+		{CertificatesPerDomain, "5:example.net", 2, 2, false},
+	}
+	test.AssertEquals(t, len(statuses), len(expected))
+	for i, s := range statuses {
+		test.AssertEquals(t, s.Name, expected[i].name)
+		test.AssertEquals(t, s.BucketKey, expected[i].bucketKey)
+		test.AssertEquals(t, s.Burst, expected[i].burst)
+		test.AssertEquals(t, s.Remaining, expected[i].remaining)
+		test.AssertEquals(t, s.ResetIn > 0, expected[i].resetting)
+	}
+
+	// Without an override, CertificatesPerDomainPerAccount is not reported.
+	statuses, err = l.AccountOverview(context.Background(), txnBuilder, 1234, AccountAgeUnknown, []string{"example.org"})
+	test.AssertNotError(t, err, "getting account overview")
+	test.AssertEquals(t, len(statuses), 3)
+	test.AssertEquals(t, statuses[0].Name, NewOrdersPerAccount)
+	test.AssertEquals(t, statuses[1].Name, FailedAuthorizationsPerDomainPerAccount)
+	test.AssertEquals(t, statuses[2].Name, CertificatesPerDomain)
+	test.AssertEquals(t, statuses[2].Remaining, int64(2))
+
+	// NewOrdersPerAccount is reported with the default for the account's age
+	// bucket.
+	tieredBuilder, err := NewTransactionBuilderFromFiles("testdata/working_default_tiers.yml", "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+	for ageBucket, burst := range map[AccountAgeBucket]int64{
+		AccountAgeUnknown:     300,
+		AccountAgeNew:         30,
+		AccountAgeEstablished: 300,
+	} {
+		statuses, err = l.AccountOverview(context.Background(), tieredBuilder, 1234, ageBucket, nil)
+		test.AssertNotError(t, err, "getting account overview")
+		test.AssertEquals(t, len(statuses), 1)
+		test.AssertEquals(t, statuses[0].Name, NewOrdersPerAccount)
+		test.AssertEquals(t, statuses[0].Burst, burst)
+	}
+}
+
+func TestLimiter_GlobalIssuanceRate(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	defaults := filepath.Join(t.TempDir(), "defaults.yml")
+	err := os.WriteFile(defaults, []byte("GlobalIssuanceRate:\n  burst: 1000\n  count: 1000\n  period: 1h\n"), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+	txnBuilder, err := NewTransactionBuilderFromFiles(defaults, "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	// spend spends against the GlobalIssuanceRate limit n times, and returns
+	// the number of spends which were allowed.
+	spend := func(n int) int64 {
+		t.Helper()
+		var allowed int64
+		for range n {
+			d, err := l.SpendGlobalIssuance(context.Background(), txnBuilder)
+			test.AssertNotError(t, err, "spending")
+			if d.allowed {
+				allowed++
+				continue
+			}
+			test.AssertErrorIs(t, d.Result(clk.Now()), berrors.RateLimit)
+		}
+		return allowed
+	}
+
+	// Before anything is spent, every shard is full.
+	status, err := l.GlobalIssuanceStatus(context.Background(), txnBuilder)
+	test.AssertNotError(t, err, "getting global issuance status")
+	test.AssertEquals(t, status.BucketKey, "10:global")
+	test.AssertEquals(t, status.Burst, int64(1000))
+	test.AssertEquals(t, status.Remaining, int64(1000))
+
+	// Spends are scattered across the shards, but sum to the limit.
+	test.AssertEquals(t, spend(100), int64(100))
+	status, err = l.GlobalIssuanceStatus(context.Background(), txnBuilder)
+	test.AssertNotError(t, err, "getting global issuance status")
+	test.AssertEquals(t, status.Burst, int64(1000))
+	test.AssertEquals(t, status.Remaining, int64(900))
+	test.Assert(t, status.ResetIn > 0, "spent shards should be resetting")
+
+	// Lowering the limit with an override throttles issuance to exactly the
+	// override, however the spends happen to be scattered.
+	err = txnBuilder.ReloadFromFiles(defaults, "testdata/working_override_global.yml")
+	test.AssertNotError(t, err, "reloading with override")
+	clk.Add(time.Hour)
+	allowed := spend(100)
+	test.AssertEquals(t, allowed, int64(10))
+	status, err = l.GlobalIssuanceStatus(context.Background(), txnBuilder)
+	test.AssertNotError(t, err, "getting global issuance status")
+	test.AssertEquals(t, status.Burst, int64(10))
+	test.AssertEquals(t, status.Remaining, 10-allowed)
+
+	// Without a default or override the limit is disabled.
+	err = txnBuilder.ReloadFromFiles("testdata/working_default.yml", "")
+	test.AssertNotError(t, err, "reloading without the limit")
+	status, err = l.GlobalIssuanceStatus(context.Background(), txnBuilder)
+	test.AssertNotError(t, err, "getting global issuance status")
+	test.Assert(t, status == nil, "disabled limit should have no status")
+	test.AssertEquals(t, spend(2000), int64(2000))
+}
+
+func TestLimiter_GlobalIssuanceRateShardFallback(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	defaults := filepath.Join(t.TempDir(), "defaults.yml")
+	err := os.WriteFile(defaults, []byte("GlobalIssuanceRate:\n  burst: 16\n  count: 16\n  period: 1h\n"), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+	txnBuilder, err := NewTransactionBuilderFromFiles(defaults, "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	// Each of the 16 shards holds a single certificate per hour.
+	shards, err := txnBuilder.globalIssuanceRateShardTransactions()
+	test.AssertNotError(t, err, "creating shard transactions")
+	test.AssertEquals(t, len(shards), 16)
+
+	// Exhaust a single shard, as unevenly scattered spends would.
+	txn, err := NewSpendTransaction(shards[0].limit, shards[0].bucketKey, 1)
+	test.AssertNotError(t, err, "creating transaction")
+	d, err := l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "spending")
+	test.Assert(t, d.allowed, "first spend against the shard should be allowed")
+	d, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "spending")
+	test.Assert(t, !d.allowed, "shard should be exhausted")
+
+	// Spends which land on the exhausted shard fall back to the others, so
+	// every remaining certificate is allowed.
+	for i := range 15 {
+		d, err := l.SpendGlobalIssuance(context.Background(), txnBuilder)
+		test.AssertNotError(t, err, "spending")
+		test.Assert(t, d.allowed, fmt.Sprintf("spend %d should be allowed while other shards have capacity", i))
+	}
+	status, err := l.GlobalIssuanceStatus(context.Background(), txnBuilder)
+	test.AssertNotError(t, err, "getting global issuance status")
+	test.AssertEquals(t, status.Remaining, int64(0))
+
+	// Once every shard is exhausted the spend is denied, until the first
+	// shard refills.
+	d, err = l.SpendGlobalIssuance(context.Background(), txnBuilder)
+	test.AssertNotError(t, err, "spending")
+	test.Assert(t, !d.allowed, "spend should be denied once every shard is exhausted")
+	test.AssertErrorIs(t, d.Result(clk.Now()), berrors.RateLimit)
+	test.Assert(t, d.retryIn <= time.Hour, fmt.Sprintf("retry in %s, want at most an hour", d.retryIn))
+
+	clk.Add(d.retryIn)
+	d, err = l.SpendGlobalIssuance(context.Background(), txnBuilder)
+	test.AssertNotError(t, err, "spending")
+	test.Assert(t, d.allowed, "spend should be allowed once a shard refills")
+}
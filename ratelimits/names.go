@@ -3,6 +3,7 @@ package ratelimits
 import (
 	"fmt"
 	"net"
+	"net/netip"
 	"strconv"
 	"strings"
 
@@ -15,6 +16,7 @@ import (
 //
 // IMPORTANT: If you add or remove a limit Name, you MUST update:
 //   - the string representation of the Name in nameToString,
+//   - the documentation of the Name in nameToMetadata,
 //   - the validators for that name in validateIdForName(),
 //   - the transaction constructors for that name in bucket.go, and
 //   - the Subscriber facing error message in ErrForDecision().
@@ -25,7 +27,10 @@ const (
 	// limit name.
 	Unknown Name = iota
 
-	// NewRegistrationsPerIPAddress uses bucket key 'enum:ipAddress'.
+	// NewRegistrationsPerIPAddress uses bucket key 'enum:ipAddress'. Overrides
+	// may also be keyed 'enum:ipAddressCIDR', in which case they apply to the
+	// bucket of each address within the range, unless a more specific
+	// override exists.
 	NewRegistrationsPerIPAddress
 
 	// NewRegistrationsPerIPv6Range uses bucket key 'enum:ipv6rangeCIDR'. The
@@ -86,6 +91,39 @@ const (
 	//    where regId is the ACME registration Id of the account and domain is a
 	//    domain name in the certificate.
 	FailedAuthorizationsForPausingPerDomainPerAccount
+
+	// FailedValidationsPerDomainPerAccount counts every failed validation,
+	// even repeated failures of the same authorization, unlike
+	// FailedAuthorizationsPerDomainPerAccount. It uses two different bucket
+	// keys depending on the context:
+	//  - When referenced in an overrides file: uses bucket key 'enum:regId',
+	//    where regId is the ACME registration Id of the account.
+	//  - When referenced in a transaction: uses bucket key 'enum:regId:domain',
+	//    where regId is the ACME registration Id of the account and domain is
+	//    the domain name being validated.
+	FailedValidationsPerDomainPerAccount
+
+	// GlobalIssuanceRate limits the issuance of the whole deployment. Its
+	// default should be far above normal issuance, so that it can be lowered
+	// by an override during an incident. It uses two different bucket keys
+	// depending on the context:
+	//  - When referenced in an overrides file: uses bucket key 'enum:global',
+	//    where global is the literal string "global".
+	//  - When referenced in a transaction: uses bucket key 'enum:global:shard',
+	//    where shard is the index of one of the buckets across which the limit
+	//    is divided, so that no single bucket is spent by every issuance.
+	GlobalIssuanceRate
+
+	// NewOrdersPerDomain uses bucket key 'enum:domain', where domain is a
+	// registered domain (eTLD+1) of a name in the order. It's spent once per
+	// registered domain by every new order, no matter which account creates
+	// it, so that a domain targeted by many accounts is noticed.
+	NewOrdersPerDomain
+
+	// WillingnessChecksPerAccount uses bucket key 'enum:regId', where regId is
+	// the ACME registration Id of the account asking whether the CA is willing
+	// to issue. It's spent once for each identifier checked.
+	WillingnessChecksPerAccount
 )
 
 // nameToString is a map of Name values to string names.
@@ -99,6 +137,89 @@ var nameToString = map[Name]string{
 	CertificatesPerDomainPerAccount:                   "CertificatesPerDomainPerAccount",
 	CertificatesPerFQDNSet:                            "CertificatesPerFQDNSet",
 	FailedAuthorizationsForPausingPerDomainPerAccount: "FailedAuthorizationsForPausingPerDomainPerAccount",
+	FailedValidationsPerDomainPerAccount:              "FailedValidationsPerDomainPerAccount",
+	GlobalIssuanceRate:                                "GlobalIssuanceRate",
+	NewOrdersPerDomain:                                "NewOrdersPerDomain",
+	WillingnessChecksPerAccount:                       "WillingnessChecksPerAccount",
+}
+
+// nameMetadata documents a Name for Describe.
+type nameMetadata struct {
+	// bucketKey is the format of the Name's bucket keys, without the 'enum:'
+	// prefix.
+	bucketKey string
+
+	// overrideKey is the format of the ids of the Name's overrides, or empty
+	// if overrides aren't supported.
+	overrideKey string
+
+	// description is a short, Subscriber facing description of the Name.
+	description string
+}
+
+// nameToMetadata is a map of Name values to their documentation. Every Name
+// other than Unknown must have an entry.
+var nameToMetadata = map[Name]nameMetadata{
+	NewRegistrationsPerIPAddress: {
+		bucketKey:   "ipAddress",
+		overrideKey: "ipAddress or ipAddressCIDR",
+		description: "New accounts which may be registered from a single IP address.",
+	},
+	NewRegistrationsPerIPv6Range: {
+		bucketKey:   "ipv6rangeCIDR",
+		overrideKey: "ipv6rangeCIDR",
+		description: "New accounts which may be registered from a single /48 IPv6 range.",
+	},
+	NewOrdersPerAccount: {
+		bucketKey:   "regId",
+		overrideKey: "regId",
+		description: "New orders which may be created by a single account.",
+	},
+	FailedAuthorizationsPerDomainPerAccount: {
+		bucketKey:   "regId:domain",
+		overrideKey: "regId",
+		description: "Authorizations for a single identifier which may fail for a single account.",
+	},
+	CertificatesPerDomain: {
+		bucketKey:   "domain",
+		overrideKey: "domain",
+		description: "Certificates which may be issued for a single registered domain.",
+	},
+	CertificatesPerDomainPerAccount: {
+		bucketKey:   "regId:domain",
+		overrideKey: "regId",
+		description: "Certificates which may be issued for a single registered domain to an account with an override of CertificatesPerDomain.",
+	},
+	CertificatesPerFQDNSet: {
+		bucketKey:   "fqdnSet",
+		overrideKey: "fqdnSet",
+		description: "Certificates which may be issued for exactly the same set of identifiers. It's only checked when an order is created, and is spent when the order is finalized, for the identifiers of the certificate's CSR, and refunded if issuance fails.",
+	},
+	FailedAuthorizationsForPausingPerDomainPerAccount: {
+		bucketKey:   "regId:domain",
+		overrideKey: "regId",
+		description: "Authorizations for a single identifier which may fail for a single account before it is paused from requesting that identifier.",
+	},
+	FailedValidationsPerDomainPerAccount: {
+		bucketKey:   "regId:domain",
+		overrideKey: "regId",
+		description: "Validations, including retries of the same authorization, which may fail for a single identifier and account.",
+	},
+	GlobalIssuanceRate: {
+		bucketKey:   "global:shard",
+		overrideKey: "global",
+		description: "Certificates which may be issued by the whole service. This limit is only lowered temporarily, during an incident.",
+	},
+	NewOrdersPerDomain: {
+		bucketKey:   "domain",
+		overrideKey: "domain",
+		description: "New orders which may be created for a single registered domain, by all accounts.",
+	},
+	WillingnessChecksPerAccount: {
+		bucketKey:   "regId",
+		overrideKey: "regId",
+		description: "Identifiers which a single account may ask whether the CA is willing to issue for.",
+	},
 }
 
 // isValid returns true if the Name is a valid rate limit name.
@@ -132,6 +253,22 @@ func validIPAddress(id string) error {
 	return nil
 }
 
+// validIPAddressCIDR validates that the provided string is an IPv4 or IPv6
+// CIDR range with no bits set beyond its mask.
+func validIPAddressCIDR(id string) error {
+	prefix, err := netip.ParsePrefix(id)
+	if err != nil {
+		return fmt.Errorf("invalid CIDR, %q must be an IPv4 or IPv6 CIDR range", id)
+	}
+	if prefix.Addr().Is4In6() {
+		return fmt.Errorf("invalid CIDR, %q must not be an IPv4-mapped IPv6 range", id)
+	}
+	if prefix.Masked() != prefix {
+		return fmt.Errorf("invalid CIDR, %q has bits set beyond its mask, did you mean %q?", id, prefix.Masked())
+	}
+	return nil
+}
+
 // validIPv6RangeCIDR validates that the provided string is formatted is an IPv6
 // CIDR range with a /48 mask.
 func validIPv6RangeCIDR(id string) error {
@@ -169,6 +306,39 @@ func validateDomain(id string) error {
 	return nil
 }
 
+// validateRegisteredDomain validates that the provided string is formatted
+// 'domain', where domain is a domain name already normalized by
+// NormalizeDomainForLimit.
+func validateRegisteredDomain(id string) error {
+	err := validateDomain(id)
+	if err != nil {
+		return err
+	}
+	normalized := NormalizeDomainForLimit(id)
+	if normalized != id {
+		return fmt.Errorf(
+			"invalid domain, %q must be a registered domain (eTLD+1), e.g. %q", id, normalized)
+	}
+	return nil
+}
+
+// validateRegIdRegisteredDomain validates that the provided string is formatted
+// 'regId:domain', where regId is an ACME registration Id and domain is a domain
+// name already normalized by NormalizeDomainForLimit.
+func validateRegIdRegisteredDomain(id string) error {
+	err := validateRegIdDomain(id)
+	if err != nil {
+		return err
+	}
+	domain := strings.Split(id, ":")[1]
+	normalized := NormalizeDomainForLimit(domain)
+	if normalized != domain {
+		return fmt.Errorf(
+			"invalid domain, %q must be a registered domain (eTLD+1), e.g. %q", domain, normalized)
+	}
+	return nil
+}
+
 // validateRegIdDomain validates that the provided string is formatted
 // 'regId:domain', where regId is an ACME registration Id and domain is a domain
 // name.
@@ -202,17 +372,58 @@ func validateFQDNSet(id string) error {
 	return policy.WellFormedDomainNames(domains)
 }
 
+// validateGlobal validates that the provided string is formatted 'global' or
+// 'global:shard', where shard is the index of a GlobalIssuanceRate bucket.
+func validateGlobal(id string) error {
+	global, shard, ok := strings.Cut(id, ":")
+	if global != globalId {
+		return fmt.Errorf("invalid id, %q must be formatted %q or '%s:shard'", id, globalId, globalId)
+	}
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(shard)
+	if err != nil || n < 0 || n >= globalIssuanceShards {
+		return fmt.Errorf("invalid shard, %q must be formatted '%s:shard', where shard is less than %d", id, globalId, globalIssuanceShards)
+	}
+	return nil
+}
+
+// validateIdForName validates that the provided id is formatted as required
+// by the named limit. Any domains it contains are first normalized by
+// normalizeIdForName, so ids which differ only in the form of their domains
+// are equally valid. Ids whose domains fail IDNA conversion are rejected.
 func validateIdForName(name Name, id string) error {
+	normalized, normErr := normalizeIdForName(name, id)
+	if normErr == nil {
+		id = normalized
+	}
+	err := validateIdFormatForName(name, id)
+	if err != nil {
+		// Prefer the more specific error describing the format.
+		return err
+	}
+	return normErr
+}
+
+// validateIdFormatForName validates that the provided id, with any domains
+// already normalized, is formatted as required by the named limit.
+func validateIdFormatForName(name Name, id string) error {
 	switch name {
 	case NewRegistrationsPerIPAddress:
-		// 'enum:ipaddress'
-		return validIPAddress(id)
+		if strings.Contains(id, "/") {
+			// 'enum:ipAddressCIDR' for overrides
+			return validIPAddressCIDR(id)
+		} else {
+			// 'enum:ipaddress'
+			return validIPAddress(id)
+		}
 
 	case NewRegistrationsPerIPv6Range:
 		// 'enum:ipv6rangeCIDR'
 		return validIPv6RangeCIDR(id)
 
-	case NewOrdersPerAccount:
+	case NewOrdersPerAccount, WillingnessChecksPerAccount:
 		// 'enum:regId'
 		return validateRegId(id)
 
@@ -228,15 +439,15 @@ func validateIdForName(name Name, id string) error {
 	case CertificatesPerDomainPerAccount:
 		if strings.Contains(id, ":") {
 			// 'enum:regId:domain' for transaction
-			return validateRegIdDomain(id)
+			return validateRegIdRegisteredDomain(id)
 		} else {
 			// 'enum:regId' for overrides
 			return validateRegId(id)
 		}
 
-	case CertificatesPerDomain:
+	case CertificatesPerDomain, NewOrdersPerDomain:
 		// 'enum:domain'
-		return validateDomain(id)
+		return validateRegisteredDomain(id)
 
 	case CertificatesPerFQDNSet:
 		// 'enum:fqdnSet'
@@ -251,6 +462,19 @@ func validateIdForName(name Name, id string) error {
 			return validateRegId(id)
 		}
 
+	case FailedValidationsPerDomainPerAccount:
+		if strings.Contains(id, ":") {
+			// 'enum:regId:domain' for transaction
+			return validateRegIdDomain(id)
+		} else {
+			// 'enum:regId' for overrides
+			return validateRegId(id)
+		}
+
+	case GlobalIssuanceRate:
+		// 'enum:global' for overrides, 'enum:global:shard' for transactions
+		return validateGlobal(id)
+
 	case Unknown:
 		fallthrough
 
@@ -72,6 +72,34 @@ func TestValidateIdForName(t *testing.T) {
 			id:    "2001:0db8:85a3:0000:0000:8a2e:0370:7334:9000",
 			err:   "must be an IP address",
 		},
+		{
+			limit: NewRegistrationsPerIPAddress,
+			desc:  "valid IPv4 CIDR range",
+			id:    "10.0.0.0/8",
+		},
+		{
+			limit: NewRegistrationsPerIPAddress,
+			desc:  "valid IPv6 CIDR range",
+			id:    "2001:db8::/32",
+		},
+		{
+			limit: NewRegistrationsPerIPAddress,
+			desc:  "CIDR range with host bits set",
+			id:    "10.1.2.3/8",
+			err:   "has bits set beyond its mask",
+		},
+		{
+			limit: NewRegistrationsPerIPAddress,
+			desc:  "IPv4-mapped IPv6 CIDR range",
+			id:    "::ffff:10.0.0.0/104",
+			err:   "must not be an IPv4-mapped IPv6 range",
+		},
+		{
+			limit: NewRegistrationsPerIPAddress,
+			desc:  "invalid CIDR range",
+			id:    "10.0.0.0/33",
+			err:   "must be an IPv4 or IPv6 CIDR range",
+		},
 		{
 			limit: NewRegistrationsPerIPv6Range,
 			desc:  "valid IPv6 address range",
@@ -162,6 +190,96 @@ func TestValidateIdForName(t *testing.T) {
 			id:    "12ea5",
 			err:   "invalid regId",
 		},
+		{
+			limit: FailedValidationsPerDomainPerAccount,
+			desc:  "transaction: valid regId and domain",
+			id:    "12345:example.com",
+		},
+		{
+			limit: FailedValidationsPerDomainPerAccount,
+			desc:  "transaction: invalid regId",
+			id:    "12ea5:example.com",
+			err:   "invalid regId",
+		},
+		{
+			limit: FailedValidationsPerDomainPerAccount,
+			desc:  "transaction: invalid domain",
+			id:    "12345:examplecom",
+			err:   "name needs at least one dot",
+		},
+		{
+			limit: FailedValidationsPerDomainPerAccount,
+			desc:  "transaction: missing domain",
+			id:    "12345:",
+			err:   "Domain name is empty",
+		},
+		{
+			limit: FailedValidationsPerDomainPerAccount,
+			desc:  "transaction: too many components",
+			id:    "12345:example.com:extra",
+			err:   "invalid regId:domain",
+		},
+		{
+			limit: FailedValidationsPerDomainPerAccount,
+			desc:  "override: valid regId",
+			id:    "12345",
+		},
+		{
+			limit: FailedValidationsPerDomainPerAccount,
+			desc:  "override: invalid regId",
+			id:    "12ea5",
+			err:   "invalid regId",
+		},
+		{
+			limit: GlobalIssuanceRate,
+			desc:  "override: valid global",
+			id:    "global",
+		},
+		{
+			limit: GlobalIssuanceRate,
+			desc:  "override: invalid global",
+			id:    "12345",
+			err:   "invalid id",
+		},
+		{
+			limit: GlobalIssuanceRate,
+			desc:  "transaction: valid shard",
+			id:    "global:15",
+		},
+		{
+			limit: GlobalIssuanceRate,
+			desc:  "transaction: shard out of range",
+			id:    "global:16",
+			err:   "invalid shard",
+		},
+		{
+			limit: GlobalIssuanceRate,
+			desc:  "transaction: invalid shard",
+			id:    "global:-1",
+			err:   "invalid shard",
+		},
+		{
+			limit: NewOrdersPerDomain,
+			desc:  "valid registered domain",
+			id:    "example.com",
+		},
+		{
+			limit: NewOrdersPerDomain,
+			desc:  "subdomain is not a registered domain",
+			id:    "www.example.com",
+			err:   "must be a registered domain",
+		},
+		{
+			limit: WillingnessChecksPerAccount,
+			desc:  "valid regId",
+			id:    "1234567890",
+		},
+		{
+			limit: WillingnessChecksPerAccount,
+			desc:  "invalid regId",
+			id:    "lol",
+			err:   "invalid regId",
+		},
 		{
 			limit: CertificatesPerDomainPerAccount,
 			desc:  "transaction: valid regId and domain",
@@ -179,6 +297,17 @@ func TestValidateIdForName(t *testing.T) {
 			id:    "12345:examplecom",
 			err:   "name needs at least one dot",
 		},
+		{
+			limit: CertificatesPerDomainPerAccount,
+			desc:  "transaction: subdomain is not a registered domain",
+			id:    "12345:www.example.com",
+			err:   "must be a registered domain",
+		},
+		{
+			limit: CertificatesPerDomainPerAccount,
+			desc:  "transaction: valid regId and private suffix registered domain",
+			id:    "12345:foo.github.io",
+		},
 		{
 			limit: CertificatesPerDomainPerAccount,
 			desc:  "override: valid regId",
@@ -195,6 +324,40 @@ func TestValidateIdForName(t *testing.T) {
 			desc:  "valid domain",
 			id:    "example.com",
 		},
+		{
+			limit: CertificatesPerDomain,
+			desc:  "subdomain is not a registered domain",
+			id:    "www.example.com",
+			err:   "must be a registered domain",
+		},
+		{
+			limit: CertificatesPerDomain,
+			desc:  "subdomain of a multi-label suffix is not a registered domain",
+			id:    "www.example.co.uk",
+			err:   "must be a registered domain",
+		},
+		{
+			limit: CertificatesPerDomain,
+			desc:  "valid registered domain under a multi-label suffix",
+			id:    "example.co.uk",
+		},
+		{
+			limit: CertificatesPerDomain,
+			desc:  "exact ICANN public suffix",
+			id:    "co.uk",
+			err:   "Domain name is an ICANN TLD",
+		},
+		{
+			limit: CertificatesPerDomain,
+			desc:  "valid registered domain under a private suffix",
+			id:    "foo.github.io",
+		},
+		{
+			limit: CertificatesPerDomain,
+			desc:  "subdomain of a registered domain under a private suffix",
+			id:    "www.foo.github.io",
+			err:   "must be a registered domain",
+		},
 		{
 			limit: CertificatesPerDomain,
 			desc:  "malformed domain",
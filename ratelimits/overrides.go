@@ -0,0 +1,230 @@
+package ratelimits
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/letsencrypt/boulder/config"
+)
+
+// OverrideTier is a single tier of an OverrideTierTable. A request qualifies
+// for a tier when both its expected weekly volume and its domain count meet
+// the tier's minimums.
+type OverrideTier struct {
+	MinWeeklyVolume int64 `yaml:"minWeeklyVolume"`
+	MinDomainCount  int64 `yaml:"minDomainCount"`
+
+	// Burst and Count are the values of an override granted at this tier. If
+	// Burst is zero it defaults to Count.
+	Burst int64 `yaml:"burst,omitempty"`
+	Count int64 `yaml:"count"`
+}
+
+// OverrideTierTable is the table of tiers from which overrides of a single
+// limit are granted.
+type OverrideTierTable struct {
+	// Period is the period of every override granted from this table.
+	Period config.Duration `yaml:"period"`
+
+	// Tiers must be sorted in ascending order of both MinWeeklyVolume and
+	// MinDomainCount. The highest tier a request qualifies for is granted.
+	Tiers []OverrideTier `yaml:"tiers"`
+
+	// MaxBurst and MaxCount are hard caps on the burst and count of any
+	// override granted from this table. A request which qualifies for a tier
+	// above these caps requires manual review.
+	MaxBurst int64 `yaml:"maxBurst"`
+	MaxCount int64 `yaml:"maxCount"`
+}
+
+// OverrideTierTables maps limit names to the table from which overrides of
+// that limit are granted.
+type OverrideTierTables map[string]*OverrideTierTable
+
+// OverrideJustification describes the expected usage of a requester asking
+// for an override.
+type OverrideJustification struct {
+	ExpectedWeeklyVolume int64
+	DomainCount          int64
+
+	// Comment identifies the request, e.g. by requester name or ticket, and
+	// is included in the comment of the emitted override.
+	Comment string
+}
+
+// OverrideEntry is a single override computed by ComputeOverride.
+type OverrideEntry struct {
+	LimitConfig
+
+	Name    Name
+	Id      string
+	Comment string
+	Expires time.Time
+}
+
+func (t *OverrideTierTable) validate() error {
+	if t.Period.Duration <= 0 {
+		return fmt.Errorf("invalid period '%s', must be > 0", t.Period)
+	}
+	if len(t.Tiers) == 0 {
+		return errors.New("no tiers configured")
+	}
+	if t.MaxBurst <= 0 || t.MaxCount <= 0 {
+		return fmt.Errorf("invalid hard caps burst '%d' and count '%d', must be > 0", t.MaxBurst, t.MaxCount)
+	}
+	for i := 1; i < len(t.Tiers); i++ {
+		prev, cur := t.Tiers[i-1], t.Tiers[i]
+		if cur.MinWeeklyVolume < prev.MinWeeklyVolume || cur.MinDomainCount < prev.MinDomainCount {
+			return fmt.Errorf("tier %d has lower minimums than tier %d, tiers must be sorted in ascending order", i+1, i)
+		}
+	}
+	return nil
+}
+
+// ComputeOverride returns an override of the named limit for the provided id,
+// granted from the highest tier of the limit's table that the justification
+// qualifies for. It returns an error if the limit has no table, the
+// justification does not qualify for any tier, or the qualifying tier exceeds
+// the table's hard caps.
+func ComputeOverride(tables OverrideTierTables, name Name, id string, j OverrideJustification, expires time.Time) (*OverrideEntry, error) {
+	table, ok := tables[name.String()]
+	if !ok || table == nil {
+		return nil, fmt.Errorf("no override tier table configured for limit %s", name)
+	}
+	err := table.validate()
+	if err != nil {
+		return nil, fmt.Errorf("validating override tier table for limit %s: %w", name, err)
+	}
+	err = validateIdForName(name, id)
+	if err != nil {
+		return nil, fmt.Errorf("validating name %s and id %q: %w", name, id, err)
+	}
+
+	tierIdx := -1
+	for i, tier := range table.Tiers {
+		if j.ExpectedWeeklyVolume >= tier.MinWeeklyVolume && j.DomainCount >= tier.MinDomainCount {
+			tierIdx = i
+		}
+	}
+	if tierIdx < 0 {
+		return nil, fmt.Errorf("expected weekly volume %d across %d domains does not qualify for an override of limit %s",
+			j.ExpectedWeeklyVolume, j.DomainCount, name)
+	}
+	tier := table.Tiers[tierIdx]
+	burst := tier.Burst
+	if burst == 0 {
+		burst = tier.Count
+	}
+	if burst > table.MaxBurst || tier.Count > table.MaxCount {
+		return nil, fmt.Errorf("tier %d of limit %s (burst %d, count %d) exceeds the hard cap (burst %d, count %d) and requires manual review",
+			tierIdx+1, name, burst, tier.Count, table.MaxBurst, table.MaxCount)
+	}
+
+	err = validateLimit(&limit{burst: burst, count: tier.Count, period: table.Period})
+	if err != nil {
+		return nil, fmt.Errorf("validating tier %d of limit %s: %w", tierIdx+1, name, err)
+	}
+
+	// The overrides file has no expiry field, so the expiry is recorded in the
+	// comment for whoever reviews the file next.
+	comment := fmt.Sprintf("tier %d: %d/week across %d domains, expires %s",
+		tierIdx+1, j.ExpectedWeeklyVolume, j.DomainCount, expires.UTC().Format(time.DateOnly))
+	if j.Comment != "" {
+		comment = j.Comment + "; " + comment
+	}
+
+	return &OverrideEntry{
+		LimitConfig: LimitConfig{
+			Burst:  burst,
+			Count:  tier.Count,
+			Period: table.Period,
+		},
+		Name:    name,
+		Id:      id,
+		Comment: comment,
+		Expires: expires,
+	}, nil
+}
+
+// YAML returns the override as a single entry in the format of an
+// overrides file, suitable for appending to one.
+func (e *OverrideEntry) YAML() ([]byte, error) {
+	entry := overridesYAML{{
+		e.Name.String(): overrideYAML{
+			LimitConfig: e.LimitConfig,
+			Ids:         []overrideIdYAML{{Id: e.Id, Comment: e.Comment}},
+		},
+	}}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err := enc.Encode(entry)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DiffOverride loads the overrides file at path and returns a human-readable
+// description of each difference between the proposed override and any
+// existing override for the same limit and id. It returns an empty slice if
+// they are identical.
+func DiffOverride(path string, proposed *OverrideEntry) ([]string, error) {
+	existing, err := loadOverrides(path)
+	if err != nil {
+		return nil, err
+	}
+	return diffOverride(existing, proposed)
+}
+
+func diffOverride(existing overridesYAML, proposed *OverrideEntry) ([]string, error) {
+	_, proposedKey, err := buildBucketKey(proposed.Name.String(), proposed.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *overrideYAML
+	var foundComment string
+	for _, ov := range existing {
+		for k, v := range ov {
+			for _, entry := range v.Ids {
+				_, key, err := buildBucketKey(k, entry.Id)
+				if err != nil || key != proposedKey {
+					continue
+				}
+				// Later entries take precedence, as in parseOverrideLimits.
+				found = &v
+				foundComment = entry.Comment
+			}
+		}
+	}
+	if found == nil {
+		return []string{fmt.Sprintf("no existing override for %s:%s", proposed.Name, proposed.Id)}, nil
+	}
+
+	var diffs []string
+	if found.Burst != proposed.Burst {
+		diffs = append(diffs, fmt.Sprintf("burst: %d -> %d", found.Burst, proposed.Burst))
+	}
+	if found.Count != proposed.Count {
+		diffs = append(diffs, fmt.Sprintf("count: %d -> %d", found.Count, proposed.Count))
+	}
+	if found.Period != proposed.Period {
+		diffs = append(diffs, fmt.Sprintf("period: %s -> %s", found.Period, proposed.Period))
+	}
+	if found.JitterFraction != proposed.JitterFraction {
+		diffs = append(diffs, fmt.Sprintf("jitterFraction: %g -> %g", found.JitterFraction, proposed.JitterFraction))
+	}
+	if foundComment != proposed.Comment {
+		diffs = append(diffs, fmt.Sprintf("comment: %q -> %q", foundComment, proposed.Comment))
+	}
+	return diffs, nil
+}
@@ -0,0 +1,181 @@
+package ratelimits
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/strictyaml"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func testOverrideTierTables() OverrideTierTables {
+	return OverrideTierTables{
+		NewOrdersPerAccount.String(): {
+			Period: config.Duration{Duration: 3 * time.Hour},
+			Tiers: []OverrideTier{
+				{MinWeeklyVolume: 1000, MinDomainCount: 0, Count: 1500},
+				{MinWeeklyVolume: 10000, MinDomainCount: 100, Burst: 20000, Count: 15000},
+				{MinWeeklyVolume: 100000, MinDomainCount: 1000, Count: 150000},
+			},
+			MaxBurst: 50000,
+			MaxCount: 50000,
+		},
+	}
+}
+
+func TestComputeOverrideTiers(t *testing.T) {
+	t.Parallel()
+	tables := testOverrideTierTables()
+	expires := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		j           OverrideJustification
+		expectBurst int64
+		expectCount int64
+		expectErr   string
+	}{
+		{
+			name:      "below the lowest tier",
+			j:         OverrideJustification{ExpectedWeeklyVolume: 999, DomainCount: 5000},
+			expectErr: "does not qualify",
+		},
+		{
+			name:        "exactly the lowest tier",
+			j:           OverrideJustification{ExpectedWeeklyVolume: 1000},
+			expectBurst: 1500,
+			expectCount: 1500,
+		},
+		{
+			name:        "second tier volume without the domain count",
+			j:           OverrideJustification{ExpectedWeeklyVolume: 10000, DomainCount: 99},
+			expectBurst: 1500,
+			expectCount: 1500,
+		},
+		{
+			name:        "exactly the second tier",
+			j:           OverrideJustification{ExpectedWeeklyVolume: 10000, DomainCount: 100},
+			expectBurst: 20000,
+			expectCount: 15000,
+		},
+		{
+			name:      "third tier exceeds the hard cap",
+			j:         OverrideJustification{ExpectedWeeklyVolume: 100000, DomainCount: 1000},
+			expectErr: "exceeds the hard cap",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			entry, err := ComputeOverride(tables, NewOrdersPerAccount, "1337", tc.j, expires)
+			if tc.expectErr != "" {
+				test.AssertError(t, err, "expected an error")
+				test.AssertContains(t, err.Error(), tc.expectErr)
+				return
+			}
+			test.AssertNotError(t, err, "computing override")
+			test.AssertEquals(t, entry.Burst, tc.expectBurst)
+			test.AssertEquals(t, entry.Count, tc.expectCount)
+			test.AssertEquals(t, entry.Period.Duration, 3*time.Hour)
+			test.AssertContains(t, entry.Comment, "expires 2026-01-02")
+		})
+	}
+
+	// A limit without a table can't be granted automatically.
+	_, err := ComputeOverride(tables, CertificatesPerDomain, "example.com", OverrideJustification{ExpectedWeeklyVolume: 1000}, expires)
+	test.AssertError(t, err, "expected an error for a limit without a table")
+
+	// An id which is invalid for the limit is rejected.
+	_, err = ComputeOverride(tables, NewOrdersPerAccount, "not-a-regid", OverrideJustification{ExpectedWeeklyVolume: 1000}, expires)
+	test.AssertError(t, err, "expected an error for an invalid id")
+
+	// A table with unsorted tiers is rejected.
+	unsorted := testOverrideTierTables()
+	unsorted[NewOrdersPerAccount.String()].Tiers[0].MinWeeklyVolume = 20000
+	_, err = ComputeOverride(unsorted, NewOrdersPerAccount, "1337", OverrideJustification{ExpectedWeeklyVolume: 1000}, expires)
+	test.AssertError(t, err, "expected an error for unsorted tiers")
+	test.AssertContains(t, err.Error(), "ascending order")
+}
+
+func TestOverrideEntryYAML(t *testing.T) {
+	t.Parallel()
+	tables := testOverrideTierTables()
+	expires := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	j := OverrideJustification{ExpectedWeeklyVolume: 12000, DomainCount: 150, Comment: "Big Hosting Co"}
+
+	entry, err := ComputeOverride(tables, NewOrdersPerAccount, "1337", j, expires)
+	test.AssertNotError(t, err, "computing override")
+	out, err := entry.YAML()
+	test.AssertNotError(t, err, "marshalling override")
+	test.AssertEquals(t, string(out), `- NewOrdersPerAccount:
+    burst: 20000
+    count: 15000
+    period: 3h0m0s
+    ids:
+      - id: "1337"
+        comment: 'Big Hosting Co; tier 2: 12000/week across 150 domains, expires 2026-01-02'
+`)
+
+	// Regenerating from the same inputs produces identical output.
+	again, err := ComputeOverride(tables, NewOrdersPerAccount, "1337", j, expires)
+	test.AssertNotError(t, err, "computing override again")
+	againOut, err := again.YAML()
+	test.AssertNotError(t, err, "marshalling override again")
+	test.AssertByteEquals(t, againOut, out)
+
+	// The output is a valid overrides file.
+	var ov overridesYAML
+	err = strictyaml.Unmarshal(out, &ov)
+	test.AssertNotError(t, err, "unmarshalling emitted override")
+	parsed, err := parseOverrideLimits(ov, nil)
+	test.AssertNotError(t, err, "parsing emitted override")
+	lim, ok := parsed[joinWithColon(NewOrdersPerAccount.EnumString(), "1337")]
+	test.Assert(t, ok, "emitted override should be keyed by name and id")
+	test.AssertEquals(t, lim.burst, int64(20000))
+	test.AssertEquals(t, lim.count, int64(15000))
+
+	// And regenerating against it reports no differences.
+	diffs, err := diffOverride(ov, again)
+	test.AssertNotError(t, err, "diffing override")
+	test.AssertEquals(t, len(diffs), 0)
+}
+
+func TestDiffOverride(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+	err := os.WriteFile(path, []byte(`- NewOrdersPerAccount:
+    burst: 1500
+    count: 1500
+    period: 3h
+    ids:
+      - id: "1337"
+        comment: Old
+      - id: "1338"
+`), 0600)
+	test.AssertNotError(t, err, "writing overrides file")
+
+	proposed := &OverrideEntry{
+		LimitConfig: LimitConfig{Burst: 20000, Count: 15000, Period: config.Duration{Duration: 3 * time.Hour}},
+		Name:        NewOrdersPerAccount,
+		Id:          "1337",
+		Comment:     "New",
+	}
+	diffs, err := DiffOverride(path, proposed)
+	test.AssertNotError(t, err, "diffing override")
+	test.AssertDeepEquals(t, diffs, []string{
+		"burst: 1500 -> 20000",
+		"count: 1500 -> 15000",
+		`comment: "Old" -> "New"`,
+	})
+
+	proposed.Id = "1339"
+	diffs, err = DiffOverride(path, proposed)
+	test.AssertNotError(t, err, "diffing override")
+	test.AssertDeepEquals(t, diffs, []string{"no existing override for NewOrdersPerAccount:1339"})
+
+	_, err = DiffOverride(filepath.Join(t.TempDir(), "missing.yaml"), proposed)
+	test.AssertError(t, err, "expected an error for a missing overrides file")
+}
@@ -0,0 +1,80 @@
+package ratelimits
+
+import (
+	"net/netip"
+)
+
+// prefixTrie is a binary trie of IP prefixes, each of which carries an
+// override limit. IPv4 and IPv6 prefixes are held in separate trees, so that
+// an IPv4 address never matches an IPv6 prefix or vice versa.
+type prefixTrie struct {
+	v4 *prefixTrieNode
+	v6 *prefixTrieNode
+}
+
+// prefixTrieNode is a single node of a prefixTrie. Its depth in the tree is the
+// length of the prefix it represents, and limit is non-nil only if that prefix
+// was inserted.
+type prefixTrieNode struct {
+	children [2]*prefixTrieNode
+	limit    *limit
+}
+
+// root returns the root node of the tree for addresses like addr, allocating
+// it if create is true.
+func (t *prefixTrie) root(addr netip.Addr, create bool) *prefixTrieNode {
+	tree := &t.v6
+	if addr.Is4() {
+		tree = &t.v4
+	}
+	if *tree == nil && create {
+		*tree = &prefixTrieNode{}
+	}
+	return *tree
+}
+
+// insert adds prefix, which must be masked, to the trie with the provided
+// limit, replacing the limit of any identical prefix.
+func (t *prefixTrie) insert(prefix netip.Prefix, lim *limit) {
+	addr := prefix.Addr()
+	node := t.root(addr, true)
+	bytes := addr.AsSlice()
+	for i := range prefix.Bits() {
+		bit := bitAt(bytes, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &prefixTrieNode{}
+		}
+		node = node.children[bit]
+	}
+	node.limit = lim
+}
+
+// lookup returns the limit of the longest prefix in the trie which contains
+// addr, or nil if there is none. It is safe to call on a nil *prefixTrie.
+func (t *prefixTrie) lookup(addr netip.Addr) *limit {
+	if t == nil {
+		return nil
+	}
+	addr = addr.Unmap()
+	node := t.root(addr, false)
+	if node == nil {
+		return nil
+	}
+	bytes := addr.AsSlice()
+	match := node.limit
+	for i := range addr.BitLen() {
+		node = node.children[bitAt(bytes, i)]
+		if node == nil {
+			break
+		}
+		if node.limit != nil {
+			match = node.limit
+		}
+	}
+	return match
+}
+
+// bitAt returns the i-th most significant bit of b.
+func bitAt(b []byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
@@ -0,0 +1,87 @@
+package ratelimits
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestPrefixTrieLongestPrefixMatch(t *testing.T) {
+	t.Parallel()
+
+	var trie prefixTrie
+	limits := make(map[string]*limit)
+	for _, cidr := range []string{
+		"0.0.0.0/0",
+		"10.0.0.0/8",
+		"10.20.0.0/16",
+		"10.20.30.0/24",
+		"10.20.30.40/32",
+		"2001:db8::/32",
+		"2001:db8:1::/48",
+	} {
+		limits[cidr] = &limit{overrideCIDR: cidr}
+		trie.insert(netip.MustParsePrefix(cidr), limits[cidr])
+	}
+
+	testCases := []struct {
+		addr string
+		want string
+	}{
+		{addr: "192.0.2.1", want: "0.0.0.0/0"},
+		{addr: "10.1.1.1", want: "10.0.0.0/8"},
+		{addr: "10.20.1.1", want: "10.20.0.0/16"},
+		{addr: "10.20.30.1", want: "10.20.30.0/24"},
+		{addr: "10.20.30.40", want: "10.20.30.40/32"},
+		{addr: "10.20.30.41", want: "10.20.30.0/24"},
+		{addr: "::ffff:10.20.1.1", want: "10.20.0.0/16"},
+		{addr: "2001:db8:2::1", want: "2001:db8::/32"},
+		{addr: "2001:db8:1:ffff::1", want: "2001:db8:1::/48"},
+		// IPv6 addresses never match IPv4 ranges, even the IPv4 default route.
+		{addr: "2001:db9::1", want: ""},
+	}
+	for _, tc := range testCases {
+		got := trie.lookup(netip.MustParseAddr(tc.addr))
+		if tc.want == "" {
+			test.Assert(t, got == nil, fmt.Sprintf("%s: expected no match, got %v", tc.addr, got))
+			continue
+		}
+		test.Assert(t, got == limits[tc.want], fmt.Sprintf("%s: expected %s, got %v", tc.addr, tc.want, got))
+	}
+
+	// A nil trie matches nothing.
+	var empty *prefixTrie
+	test.Assert(t, empty.lookup(netip.MustParseAddr("10.0.0.1")) == nil, "expected no match in nil trie")
+}
+
+// newBenchmarkPrefixTrie returns a trie of n nested and disjoint IPv4 and IPv6
+// ranges, as might be configured for large NAT egress points.
+func newBenchmarkPrefixTrie(n int) *prefixTrie {
+	trie := &prefixTrie{}
+	for i := range n {
+		lim := &limit{}
+		trie.insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 0}), 24), lim)
+		trie.insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i >> 8), 0, 0}), 16), lim)
+		trie.insert(netip.PrefixFrom(netip.AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, byte(i >> 8), byte(i)}), 48), lim)
+	}
+	return trie
+}
+
+func BenchmarkPrefixTrieLookup(b *testing.B) {
+	for _, n := range []int{10, 1000, 5000} {
+		trie := newBenchmarkPrefixTrie(n)
+		addrs := []netip.Addr{
+			netip.MustParseAddr("10.0.7.1"),
+			netip.MustParseAddr("192.0.2.1"),
+			netip.MustParseAddr("2001:db8:3:1::1"),
+			netip.MustParseAddr("2001:db9::1"),
+		}
+		b.Run(fmt.Sprintf("prefixes=%d", n), func(b *testing.B) {
+			for i := range b.N {
+				trie.lookup(addrs[i%len(addrs)])
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.20.1
+// source: ratelimits.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RateLimits_CheckStatus_FullMethodName = "/ratelimits.RateLimits/CheckStatus"
+	RateLimits_Spend_FullMethodName       = "/ratelimits.RateLimits/Spend"
+	RateLimits_Refund_FullMethodName      = "/ratelimits.RateLimits/Refund"
+	RateLimits_BuildKey_FullMethodName    = "/ratelimits.RateLimits/BuildKey"
+)
+
+// RateLimitsClient is the client API for RateLimits service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RateLimitsClient interface {
+	// CheckStatus reports the state of a bucket without deducting from it.
+	CheckStatus(ctx context.Context, in *LimitRequest, opts ...grpc.CallOption) (*Decision, error)
+	// Spend deducts the cost from a bucket, creating it if necessary.
+	Spend(ctx context.Context, in *LimitRequest, opts ...grpc.CallOption) (*Decision, error)
+	// Refund returns the cost to an existing bucket.
+	Refund(ctx context.Context, in *LimitRequest, opts ...grpc.CallOption) (*Decision, error)
+	// BuildKey validates a limit name and id and returns the bucket key.
+	BuildKey(ctx context.Context, in *BuildKeyRequest, opts ...grpc.CallOption) (*BuildKeyResponse, error)
+}
+
+type rateLimitsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRateLimitsClient(cc grpc.ClientConnInterface) RateLimitsClient {
+	return &rateLimitsClient{cc}
+}
+
+func (c *rateLimitsClient) CheckStatus(ctx context.Context, in *LimitRequest, opts ...grpc.CallOption) (*Decision, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Decision)
+	err := c.cc.Invoke(ctx, RateLimits_CheckStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimitsClient) Spend(ctx context.Context, in *LimitRequest, opts ...grpc.CallOption) (*Decision, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Decision)
+	err := c.cc.Invoke(ctx, RateLimits_Spend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimitsClient) Refund(ctx context.Context, in *LimitRequest, opts ...grpc.CallOption) (*Decision, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Decision)
+	err := c.cc.Invoke(ctx, RateLimits_Refund_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimitsClient) BuildKey(ctx context.Context, in *BuildKeyRequest, opts ...grpc.CallOption) (*BuildKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BuildKeyResponse)
+	err := c.cc.Invoke(ctx, RateLimits_BuildKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RateLimitsServer is the server API for RateLimits service.
+// All implementations must embed UnimplementedRateLimitsServer
+// for forward compatibility
+type RateLimitsServer interface {
+	// CheckStatus reports the state of a bucket without deducting from it.
+	CheckStatus(context.Context, *LimitRequest) (*Decision, error)
+	// Spend deducts the cost from a bucket, creating it if necessary.
+	Spend(context.Context, *LimitRequest) (*Decision, error)
+	// Refund returns the cost to an existing bucket.
+	Refund(context.Context, *LimitRequest) (*Decision, error)
+	// BuildKey validates a limit name and id and returns the bucket key.
+	BuildKey(context.Context, *BuildKeyRequest) (*BuildKeyResponse, error)
+	mustEmbedUnimplementedRateLimitsServer()
+}
+
+// UnimplementedRateLimitsServer must be embedded to have forward compatible implementations.
+type UnimplementedRateLimitsServer struct {
+}
+
+func (UnimplementedRateLimitsServer) CheckStatus(context.Context, *LimitRequest) (*Decision, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckStatus not implemented")
+}
+func (UnimplementedRateLimitsServer) Spend(context.Context, *LimitRequest) (*Decision, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Spend not implemented")
+}
+func (UnimplementedRateLimitsServer) Refund(context.Context, *LimitRequest) (*Decision, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refund not implemented")
+}
+func (UnimplementedRateLimitsServer) BuildKey(context.Context, *BuildKeyRequest) (*BuildKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BuildKey not implemented")
+}
+func (UnimplementedRateLimitsServer) mustEmbedUnimplementedRateLimitsServer() {}
+
+// UnsafeRateLimitsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RateLimitsServer will
+// result in compilation errors.
+type UnsafeRateLimitsServer interface {
+	mustEmbedUnimplementedRateLimitsServer()
+}
+
+func RegisterRateLimitsServer(s grpc.ServiceRegistrar, srv RateLimitsServer) {
+	s.RegisterService(&RateLimits_ServiceDesc, srv)
+}
+
+func _RateLimits_CheckStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimitsServer).CheckStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimits_CheckStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimitsServer).CheckStatus(ctx, req.(*LimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimits_Spend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimitsServer).Spend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimits_Spend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimitsServer).Spend(ctx, req.(*LimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimits_Refund_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimitsServer).Refund(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimits_Refund_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimitsServer).Refund(ctx, req.(*LimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimits_BuildKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BuildKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimitsServer).BuildKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimits_BuildKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimitsServer).BuildKey(ctx, req.(*BuildKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RateLimits_ServiceDesc is the grpc.ServiceDesc for RateLimits service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RateLimits_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ratelimits.RateLimits",
+	HandlerType: (*RateLimitsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CheckStatus",
+			Handler:    _RateLimits_CheckStatus_Handler,
+		},
+		{
+			MethodName: "Spend",
+			Handler:    _RateLimits_Spend_Handler,
+		},
+		{
+			MethodName: "Refund",
+			Handler:    _RateLimits_Refund_Handler,
+		},
+		{
+			MethodName: "BuildKey",
+			Handler:    _RateLimits_BuildKey_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ratelimits.proto",
+}
@@ -0,0 +1,365 @@
+package ratelimits
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// receiptTTL is how long a receipt returned by CheckWithReceipt may be passed
+// to BatchSpendWithReceipt. It covers the gap between building an order and
+// the client's next request, and is kept short because the buckets it
+// describes refill in the meantime.
+const receiptTTL = 2 * time.Second
+
+// Results of presenting a receipt to BatchSpendWithReceipt, used as labels of
+// the ratelimits_receipts metric.
+const (
+	receiptUsed       = "used"
+	receiptExpired    = "expired"
+	receiptMismatched = "mismatched"
+	receiptInvalid    = "invalid"
+	receiptChanged    = "changed"
+	receiptDenied     = "denied"
+)
+
+// BucketVersioner is implemented by sources which can count the writes to
+// each bucket, so that BatchSpendWithReceipt can tell whether the buckets
+// described by a receipt have changed since it was issued.
+type BucketVersioner interface {
+	// TrackVersions starts counting the writes to each bucket. Until it's
+	// called writes don't change versions, so sources whose limiter doesn't
+	// issue receipts don't pay for them. It must be called before the source
+	// is used.
+	TrackVersions()
+
+	// BatchGetVersioned is like BatchGet, but also returns the version of each
+	// of the specified bucketKeys. A bucket's version is changed by every
+	// write to it, including writes which delete it. Buckets which haven't
+	// been written to recently have version 0, and are omitted from the
+	// returned versions.
+	BatchGetVersioned(ctx context.Context, bucketKeys []string) (map[string]time.Time, map[string]int64, error)
+
+	// BatchSetIfUnchanged stores the TATs of the specified buckets, for each
+	// bucket only if its version is still the one specified, in which case its
+	// version is changed. Buckets whose version has changed are left as they
+	// are, and returned in the set of changed bucketKeys.
+	BatchSetIfUnchanged(ctx context.Context, buckets map[string]versionedSet) (map[string]bool, error)
+}
+
+// versionedSet is a write to a single bucket which is only made if the bucket
+// hasn't been written to since it was read at version.
+type versionedSet struct {
+	version int64
+
+	// tat is the TAT to store. If it's the zero time, the bucket's version is
+	// checked but the bucket isn't written to.
+	tat time.Time
+}
+
+// receiptSigner signs and verifies the receipts returned by CheckWithReceipt.
+type receiptSigner struct {
+	key      []byte
+	source   BucketVersioner
+	receipts *prometheus.CounterVec
+}
+
+// receiptContents is the signed portion of a receipt.
+type receiptContents struct {
+	Buckets []receiptBucket `json:"buckets"`
+	Allowed bool            `json:"allowed"`
+	Expires int64           `json:"expires"`
+}
+
+// receiptBucket is the state of a single bucket when a receipt was issued.
+type receiptBucket struct {
+	Key string `json:"key"`
+	// TAT is the bucket's TAT in Unix nanoseconds, or 0 if it didn't exist.
+	TAT     int64 `json:"tat"`
+	Version int64 `json:"version"`
+}
+
+// EnableReceipts allows CheckWithReceipt to issue receipts, which are signed
+// with key, and BatchSpendWithReceipt to accept them. Every instance which
+// may be presented a receipt must share the same key, which must be at least
+// 32 bytes long. It must be called at most once, before the Limiter is used.
+// An error is returned if the source doesn't implement BucketVersioner.
+func (l *Limiter) EnableReceipts(key []byte, stats prometheus.Registerer) error {
+	source, ok := l.source.(BucketVersioner)
+	if !ok {
+		return fmt.Errorf("source %T can't track bucket versions", l.source)
+	}
+	if len(key) < 32 {
+		return fmt.Errorf("receipt key must be at least 32 bytes, got %d", len(key))
+	}
+	source.TrackVersions()
+
+	receipts := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_receipts",
+		Help: fmt.Sprintf("Number of limit check receipts presented to BatchSpendWithReceipt, labeled by result=[%s|%s|%s|%s|%s|%s]",
+			receiptUsed, receiptExpired, receiptMismatched, receiptInvalid, receiptChanged, receiptDenied),
+	}, []string{"result"})
+	stats.MustRegister(receipts)
+
+	l.receipts = &receiptSigner{
+		key:      slices.Clone(key),
+		source:   source,
+		receipts: receipts,
+	}
+	return nil
+}
+
+func (s *receiptSigner) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// sign returns the receipt for contents.
+func (s *receiptSigner) sign(contents receiptContents) (string, error) {
+	serialized, err := json.Marshal(contents)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(serialized)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload)), nil
+}
+
+// errInvalidReceipt is returned by verify for receipts which are malformed or
+// weren't signed with the signer's key.
+var errInvalidReceipt = errors.New("invalid receipt")
+
+// verify returns the contents of receipt if it was signed with the signer's
+// key.
+func (s *receiptSigner) verify(receipt string) (receiptContents, error) {
+	payload, sig, ok := strings.Cut(receipt, ".")
+	if !ok {
+		return receiptContents{}, errInvalidReceipt
+	}
+	decodedSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(decodedSig, s.mac(payload)) {
+		return receiptContents{}, errInvalidReceipt
+	}
+	serialized, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return receiptContents{}, errInvalidReceipt
+	}
+	var contents receiptContents
+	err = json.Unmarshal(serialized, &contents)
+	if err != nil {
+		return receiptContents{}, errInvalidReceipt
+	}
+	return contents, nil
+}
+
+// CheckWithReceipt is like Check for a batch of Transactions: it DOES NOT
+// deduct their costs, and no state is persisted to the underlying datastore.
+// The returned *Decision represents the strictest of all *Decisions reached
+// in the batch, as if the costs WERE to be deducted. If the batch is allowed
+// and receipts are enabled, a receipt is also returned which may be passed,
+// within receiptTTL, to BatchSpendWithReceipt for the same Transactions.
+// Otherwise the receipt is empty.
+func (l *Limiter) CheckWithReceipt(ctx context.Context, txns []Transaction) (*Decision, string, error) {
+	batch, bucketKeys, err := prepareBatch(txns)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(batch) == 0 {
+		// All Transactions were allow-only.
+		return allowedDecision, "", nil
+	}
+
+	var tats map[string]time.Time
+	var versions map[string]int64
+	if l.receipts == nil {
+		tats, err = l.source.BatchGet(ctx, bucketKeys)
+	} else {
+		tats, versions, err = l.receipts.source.BatchGetVersioned(ctx, bucketKeys)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("batch get for %d keys: %w", len(bucketKeys), err)
+	}
+
+	batchDecision := allowedDecision
+	var results []TransactionResult
+	for _, txn := range batch {
+		if txn.spendOnly() {
+			// Spend-only Transactions are best-effort and do not contribute to
+			// the batchDecision.
+			continue
+		}
+		reported := l.applyMode(txn, maybeSpend(l.clk, txn, tats[txn.bucketKey]))
+		batchDecision = stricter(batchDecision, reported)
+		results = append(results, newTransactionResult(txn, reported))
+	}
+	batchDecision = batchDecision.withResults(results)
+
+	if !batchDecision.allowed || l.receipts == nil {
+		return batchDecision, "", nil
+	}
+
+	contents := receiptContents{
+		Allowed: true,
+		Expires: l.clk.Now().Add(receiptTTL).UnixNano(),
+	}
+	for _, bucketKey := range bucketKeys {
+		bucket := receiptBucket{
+			Key:     bucketKey,
+			Version: versions[bucketKey],
+		}
+		tat, ok := tats[bucketKey]
+		if ok {
+			bucket.TAT = tat.UnixNano()
+		}
+		contents.Buckets = append(contents.Buckets, bucket)
+	}
+	receipt, err := l.receipts.sign(contents)
+	if err != nil {
+		return nil, "", fmt.Errorf("signing receipt: %w", err)
+	}
+	return batchDecision, receipt, nil
+}
+
+// BatchSpendWithReceipt is like BatchSpend, but accepts a receipt returned by
+// CheckWithReceipt for the same Transactions. If none of the buckets have
+// been written to since the receipt was issued, their states are taken from
+// it rather than read again. If the receipt is empty, expired, invalid, or
+// for other Transactions, or any of the buckets have since been written to,
+// this falls back to BatchSpend.
+func (l *Limiter) BatchSpendWithReceipt(ctx context.Context, txns []Transaction, receipt string) (*Decision, error) {
+	if receipt == "" || l.receipts == nil {
+		return l.BatchSpend(ctx, txns)
+	}
+
+	d, result, err := l.spendWithReceipt(ctx, txns, receipt)
+	if err != nil {
+		return nil, err
+	}
+	l.receipts.receipts.WithLabelValues(result).Inc()
+	if result != receiptUsed {
+		return l.BatchSpend(ctx, txns)
+	}
+	return d, nil
+}
+
+// spendWithReceipt attempts to spend the costs of txns using the bucket
+// states recorded in receipt. It returns the result of presenting the
+// receipt, and a *Decision only if the result is receiptUsed. Otherwise no
+// state has been persisted to the underlying datastore.
+func (l *Limiter) spendWithReceipt(ctx context.Context, txns []Transaction, receipt string) (*Decision, string, error) {
+	start := l.clk.Now()
+
+	contents, err := l.receipts.verify(receipt)
+	if err != nil {
+		return nil, receiptInvalid, nil
+	}
+	if !contents.Allowed {
+		return nil, receiptDenied, nil
+	}
+	if !start.Before(time.Unix(0, contents.Expires)) {
+		return nil, receiptExpired, nil
+	}
+
+	batch, bucketKeys, err := prepareBatch(txns)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(batch) == 0 || len(bucketKeys) != len(contents.Buckets) {
+		return nil, receiptMismatched, nil
+	}
+	tats := make(map[string]time.Time, len(contents.Buckets))
+	sets := make(map[string]versionedSet, len(contents.Buckets))
+	for i, bucket := range contents.Buckets {
+		if bucket.Key != bucketKeys[i] {
+			return nil, receiptMismatched, nil
+		}
+		if bucket.TAT != 0 {
+			tats[bucket.Key] = time.Unix(0, bucket.TAT).UTC()
+		}
+		sets[bucket.Key] = versionedSet{version: bucket.Version}
+	}
+
+	batchDecision := allowedDecision
+	txnOutcomes := make(map[Transaction]string)
+	var spent []Transaction
+	var results []TransactionResult
+	decisions := make([]*Decision, 0, len(batch))
+	for _, txn := range batch {
+		storedTAT := tats[txn.bucketKey]
+		d := maybeSpend(l.clk, txn, storedTAT)
+		decisions = append(decisions, d)
+
+		if d.allowed && (storedTAT != d.newTAT) && txn.spends() {
+			// The stored TAT was current as of the receipt, so unlike
+			// BatchSpend there's no need to distinguish stale buckets from
+			// those which should be incremented.
+			sets[txn.bucketKey] = versionedSet{
+				version: sets[txn.bucketKey].version,
+				tat:     d.newTAT,
+			}
+			spent = append(spent, txn)
+		}
+
+		if !txn.spendOnly() {
+			// Spend-only Transactions are best-effort and do not contribute to
+			// the batchDecision.
+			reported := l.applyMode(txn, d)
+			batchDecision = stricter(batchDecision, reported)
+			results = append(results, newTransactionResult(txn, reported))
+		}
+
+		txnOutcomes[txn] = Denied
+		if d.allowed {
+			txnOutcomes[txn] = Allowed
+		}
+	}
+	if !batchDecision.allowed {
+		// The buckets have refilled since the receipt was issued, so this
+		// should never happen. Leave the denial to a full evaluation.
+		return nil, receiptDenied, nil
+	}
+
+	// Remove cancellation from the request context so that transactions are not
+	// interrupted by a client disconnect.
+	ctx = context.WithoutCancel(ctx)
+	changed, err := l.receipts.source.BatchSetIfUnchanged(ctx, sets)
+	if err != nil {
+		return nil, "", fmt.Errorf("batch set if unchanged for %d keys: %w", len(sets), err)
+	}
+	if len(changed) > 0 {
+		// Undo the spends which were written before falling back to a full
+		// evaluation.
+		var written []Transaction
+		for _, txn := range spent {
+			if !changed[txn.bucketKey] {
+				written = append(written, txn)
+			}
+		}
+		_, err = l.BatchRefund(ctx, written)
+		if err != nil {
+			return nil, "", fmt.Errorf("refunding spends for changed receipt: %w", err)
+		}
+		return nil, receiptChanged, nil
+	}
+
+	for i, txn := range batch {
+		l.overrides.observe(txn, decisions[i], l.clk.Now())
+	}
+
+	// Observe latency equally across all transactions in the batch.
+	totalLatency := l.clk.Since(start)
+	perTxnLatency := totalLatency / time.Duration(len(txnOutcomes))
+	for txn, outcome := range txnOutcomes {
+		l.spendLatency.WithLabelValues(txn.limit.name.String(), outcome).Observe(perTxnLatency.Seconds())
+	}
+	return batchDecision.withResults(results), receiptUsed, nil
+}
@@ -0,0 +1,275 @@
+package ratelimits
+
+import (
+	"bytes"
+	"context"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// newReceiptTestTransactions returns transactions of 1 against two buckets,
+// one for testIP and one for a random account, each with a burst of 10 which
+// refills far slower than receipts expire.
+func newReceiptTestTransactions(t *testing.T, testIP string) (Transaction, Transaction) {
+	t.Helper()
+	ipLimit := &limit{name: NewRegistrationsPerIPAddress, burst: 10, count: 10, period: config.Duration{Duration: time.Hour}}
+	ipLimit.precompute()
+	ipBucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+	test.AssertNotError(t, err, "should not error")
+	ipTxn, err := NewSpendTransaction(ipLimit, ipBucketKey, 1)
+	test.AssertNotError(t, err, "txn should be valid")
+
+	acctLimit := &limit{name: NewOrdersPerAccount, burst: 10, count: 10, period: config.Duration{Duration: time.Hour}}
+	acctLimit.precompute()
+	acctBucketKey, err := newRegIdBucketKey(NewOrdersPerAccount, rand.Int64N(1<<40)+1)
+	test.AssertNotError(t, err, "should not error")
+	acctTxn, err := NewSpendTransaction(acctLimit, acctBucketKey, 1)
+	test.AssertNotError(t, err, "txn should be valid")
+	return ipTxn, acctTxn
+}
+
+func enableTestReceipts(t *testing.T, l *Limiter) {
+	t.Helper()
+	err := l.EnableReceipts(bytes.Repeat([]byte{0x42}, 32), prometheus.NewRegistry())
+	test.AssertNotError(t, err, "enabling receipts")
+}
+
+func TestEnableReceipts(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	err := l.EnableReceipts([]byte("too short"), prometheus.NewRegistry())
+	test.AssertError(t, err, "short key should be rejected")
+
+	// Until receipts are enabled none are issued, and the source doesn't
+	// track versions.
+	ipTxn, acctTxn := newReceiptTestTransactions(t, "10.0.0.1")
+	d, receipt, err := l.CheckWithReceipt(context.Background(), []Transaction{ipTxn, acctTxn})
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.allowed, "should be allowed")
+	test.AssertEquals(t, receipt, "")
+	_, err = l.BatchSpendWithReceipt(context.Background(), []Transaction{ipTxn, acctTxn}, receipt)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, l.source.(*InmemSource).versions == nil, "versions should not be tracked")
+
+	// A source which can't track versions is rejected.
+	l = newTestLimiter(t, struct{ Source }{NewInmemSource(clk)}, clk)
+	err = l.EnableReceipts(bytes.Repeat([]byte{0x42}, 32), prometheus.NewRegistry())
+	test.AssertError(t, err, "source without versions should be rejected")
+}
+
+func TestLimiter_BucketVersions(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, clk, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			source := l.source.(BucketVersioner)
+			_, acctTxn := newReceiptTestTransactions(t, testIP)
+			bucketKey := acctTxn.bucketKey
+			version := func() int64 {
+				t.Helper()
+				_, versions, err := source.BatchGetVersioned(testCtx, []string{bucketKey})
+				test.AssertNotError(t, err, "BatchGetVersioned failed")
+				return versions[bucketKey]
+			}
+
+			// Until receipts are enabled, writes don't change versions.
+			err := l.source.BatchSet(testCtx, map[string]time.Time{bucketKey: clk.Now().Add(time.Minute)})
+			test.AssertNotError(t, err, "BatchSet failed")
+			test.AssertEquals(t, version(), int64(0))
+
+			enableTestReceipts(t, l)
+			err = l.source.BatchSet(testCtx, map[string]time.Time{bucketKey: clk.Now().Add(time.Minute)})
+			test.AssertNotError(t, err, "BatchSet failed")
+			written := version()
+			test.Assert(t, written != 0, "write should change the version")
+
+			// A bucket which already exists isn't written by
+			// BatchSetNotExisting, so its version doesn't change.
+			alreadyExists, err := l.source.BatchSetNotExisting(testCtx, map[string]time.Time{bucketKey: clk.Now().Add(time.Hour)})
+			test.AssertNotError(t, err, "BatchSetNotExisting failed")
+			test.Assert(t, alreadyExists[bucketKey], "bucket should already exist")
+			test.AssertEquals(t, version(), written)
+
+			err = l.source.BatchIncrement(testCtx, map[string]increment{bucketKey: {cost: time.Minute}})
+			test.AssertNotError(t, err, "BatchIncrement failed")
+			test.Assert(t, version() != written, "increment should change the version")
+		})
+	}
+}
+
+func TestLimiter_ReceiptFastPath(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, _, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			enableTestReceipts(t, l)
+			ipTxn, acctTxn := newReceiptTestTransactions(t, testIP)
+			txns := []Transaction{ipTxn, acctTxn}
+
+			// The first receipt is for buckets which don't exist yet, and the
+			// second for buckets which do.
+			for i := range 2 {
+				d, receipt, err := l.CheckWithReceipt(testCtx, txns)
+				test.AssertNotError(t, err, "should not error")
+				test.Assert(t, d.allowed, "should be allowed")
+				test.AssertEquals(t, d.remaining, int64(9-i))
+				test.Assert(t, receipt != "", "expected a receipt")
+
+				// The check persisted nothing.
+				d, _, err = l.CheckWithReceipt(testCtx, txns)
+				test.AssertNotError(t, err, "should not error")
+				test.AssertEquals(t, d.remaining, int64(9-i))
+
+				d, err = l.BatchSpendWithReceipt(testCtx, txns, receipt)
+				test.AssertNotError(t, err, "should not error")
+				test.Assert(t, d.allowed, "should be allowed")
+				test.AssertEquals(t, d.remaining, int64(9-i))
+				test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": receiptUsed}, float64(i+1))
+			}
+
+			// Both spends were persisted.
+			d, err := l.BatchSpend(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.remaining, int64(7))
+
+			// A denied check yields no receipt.
+			ipTxn10, err := NewSpendTransaction(ipTxn.limit, ipTxn.bucketKey, 10)
+			test.AssertNotError(t, err, "txn should be valid")
+			d, receipt, err := l.CheckWithReceipt(testCtx, []Transaction{ipTxn10})
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, !d.allowed, "should not be allowed")
+			test.AssertEquals(t, receipt, "")
+		})
+	}
+}
+
+func TestLimiter_ReceiptInvalidatedByWrite(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, _, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			enableTestReceipts(t, l)
+			ipTxn, acctTxn := newReceiptTestTransactions(t, testIP)
+			txns := []Transaction{ipTxn, acctTxn}
+
+			_, err := l.BatchSpend(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+			_, receipt, err := l.CheckWithReceipt(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+
+			// A spend against one of the buckets between the check and the
+			// spend with the receipt bumps its version, so the receipt is
+			// ignored and its stale TAT isn't written over the newer one.
+			_, err = l.Spend(testCtx, acctTxn)
+			test.AssertNotError(t, err, "should not error")
+			d, err := l.BatchSpendWithReceipt(testCtx, txns, receipt)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			test.AssertEquals(t, d.remaining, int64(7))
+			test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": receiptChanged}, 1)
+			test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": receiptUsed}, 0)
+
+			// The unchanged bucket, which was written before the change was
+			// detected, was spent against exactly once.
+			d, err = l.Check(testCtx, ipTxn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.remaining, int64(7))
+			d, err = l.Check(testCtx, acctTxn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.remaining, int64(6))
+
+			// A receipt can't be used twice, because using it bumps the
+			// versions of its buckets.
+			_, receipt, err = l.CheckWithReceipt(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+			_, err = l.BatchSpendWithReceipt(testCtx, txns, receipt)
+			test.AssertNotError(t, err, "should not error")
+			_, err = l.BatchSpendWithReceipt(testCtx, txns, receipt)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": receiptUsed}, 1)
+			test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": receiptChanged}, 2)
+
+			// Resetting a bucket also invalidates receipts.
+			_, receipt, err = l.CheckWithReceipt(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+			err = l.Reset(testCtx, ipTxn.bucketKey)
+			test.AssertNotError(t, err, "should not error")
+			d, err = l.BatchSpendWithReceipt(testCtx, txns, receipt)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.remaining, int64(4))
+			test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": receiptChanged}, 3)
+		})
+	}
+}
+
+func TestLimiter_ReceiptRejected(t *testing.T) {
+	t.Parallel()
+	testCtx, limiters, _, clk, testIP := setup(t)
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			enableTestReceipts(t, l)
+			ipTxn, acctTxn := newReceiptTestTransactions(t, testIP)
+			txns := []Transaction{ipTxn, acctTxn}
+
+			_, receipt, err := l.CheckWithReceipt(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+			payload, sig, _ := strings.Cut(receipt, ".")
+			_, otherAcctTxn := newReceiptTestTransactions(t, testIP)
+
+			// A receipt signed with another key.
+			other := newInmemTestLimiter(t, clk)
+			err = other.EnableReceipts(bytes.Repeat([]byte{0x43}, 32), prometheus.NewRegistry())
+			test.AssertNotError(t, err, "enabling receipts")
+			_, otherReceipt, err := other.CheckWithReceipt(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+
+			testCases := []struct {
+				name    string
+				receipt string
+				txns    []Transaction
+				result  string
+			}{
+				{"tampered payload", "f" + payload[1:] + "." + sig, txns, receiptInvalid},
+				{"tampered signature", payload + "." + strings.ToUpper(sig), txns, receiptInvalid},
+				{"missing signature", payload, txns, receiptInvalid},
+				{"other key", otherReceipt, txns, receiptInvalid},
+				{"other buckets", receipt, []Transaction{ipTxn, otherAcctTxn}, receiptMismatched},
+				{"fewer buckets", receipt, []Transaction{ipTxn}, receiptMismatched},
+			}
+			for _, tc := range testCases {
+				// Every rejected receipt falls back to a full evaluation.
+				d, err := l.BatchSpendWithReceipt(testCtx, tc.txns, tc.receipt)
+				test.AssertNotError(t, err, tc.name)
+				test.Assert(t, d.allowed, tc.name)
+				test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": tc.result}, 1)
+				l.receipts.receipts.Reset()
+			}
+
+			// An expired receipt.
+			_, receipt, err = l.CheckWithReceipt(testCtx, txns)
+			test.AssertNotError(t, err, "should not error")
+			clk.Add(receiptTTL)
+			d, err := l.BatchSpendWithReceipt(testCtx, txns, receipt)
+			test.AssertNotError(t, err, "should not error")
+			test.Assert(t, d.allowed, "should be allowed")
+			test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": receiptExpired}, 1)
+
+			// None of the rejected receipts were used, and every fallback
+			// spent against the first bucket exactly once.
+			test.AssertMetricWithLabelsEquals(t, l.receipts.receipts, prometheus.Labels{"result": receiptUsed}, 0)
+			d, err = l.Check(testCtx, ipTxn)
+			test.AssertNotError(t, err, "should not error")
+			test.AssertEquals(t, d.remaining, int64(9-len(testCases)-1))
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
+)
+
+// unknownCaller is the value of the 'caller' label when the client's identity
+// could not be determined from its TLS certificate.
+const unknownCaller = "unknown"
+
+// mutatingMethods are the RPCs which modify bucket state. Only clients whose
+// certificates contain an allow-listed name may call them.
+var mutatingMethods = map[string]bool{
+	rlpb.RateLimits_Spend_FullMethodName:  true,
+	rlpb.RateLimits_Refund_FullMethodName: true,
+}
+
+// Server exposes a *Limiter over gRPC for components which are not written in
+// Go. Call NewServer to create a new *Server and install its Unary method as a
+// unary interceptor so that Spend and Refund are restricted to allow-listed
+// clients.
+type Server struct {
+	rlpb.UnimplementedRateLimitsServer
+
+	limiter    *Limiter
+	txnBuilder *TransactionBuilder
+
+	// mutationClients is the set of client certificate names which may call
+	// Spend and Refund.
+	mutationClients map[string]struct{}
+
+	requests *prometheus.CounterVec
+}
+
+var _ rlpb.RateLimitsServer = (*Server)(nil)
+
+// NewServer returns a new *Server. The provided mutationClients are the client
+// certificate names which are allowed to call Spend and Refund, all other
+// authenticated clients may only call CheckStatus and BuildKey.
+func NewServer(limiter *Limiter, txnBuilder *TransactionBuilder, mutationClients []string, stats prometheus.Registerer) (*Server, error) {
+	if limiter == nil || txnBuilder == nil {
+		return nil, errors.New("limiter and transaction builder are required")
+	}
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_grpc_requests",
+		Help: "Number of ratelimits gRPC requests labeled by method=[name], caller=[client name] and result=[success|denied|error]",
+	}, []string{"method", "caller", "result"})
+	stats.MustRegister(requests)
+
+	allowed := make(map[string]struct{}, len(mutationClients))
+	for _, name := range mutationClients {
+		allowed[name] = struct{}{}
+	}
+
+	return &Server{
+		limiter:         limiter,
+		txnBuilder:      txnBuilder,
+		mutationClients: allowed,
+		requests:        requests,
+	}, nil
+}
+
+// callerNames returns the DNS names from the verified client certificate in
+// the provided context, or nil if there is none.
+func callerNames(ctx context.Context) []string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return nil
+	}
+	tlsAuth, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	if len(tlsAuth.State.VerifiedChains) == 0 || len(tlsAuth.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return tlsAuth.State.VerifiedChains[0][0].DNSNames
+}
+
+// Unary is a gRPC unary interceptor which rejects calls to Spend and Refund
+// from clients that are not allow-listed and records a metric for every
+// request. Errors are wrapped for transport as Boulder's other gRPC servers
+// wrap them, so that clients receive their type and gRPC status code.
+func (s *Server) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	_, method, _ := strings.Cut(strings.TrimPrefix(info.FullMethod, "/"), "/")
+	names := callerNames(ctx)
+	caller := unknownCaller
+	if len(names) > 0 {
+		caller = names[0]
+	}
+
+	if mutatingMethods[info.FullMethod] {
+		authorized := false
+		for _, name := range names {
+			_, ok := s.mutationClients[name]
+			if ok {
+				authorized = true
+				caller = name
+				break
+			}
+		}
+		if !authorized {
+			s.requests.WithLabelValues(method, caller, Denied).Inc()
+			return nil, bgrpc.WrapError(ctx, berrors.UnauthorizedError("client names %v are not authorized to call %s", names, method))
+		}
+	}
+
+	resp, err := handler(ctx, req)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	s.requests.WithLabelValues(method, caller, result).Inc()
+	return resp, bgrpc.WrapError(ctx, err)
+}
+
+// buildBucketKey validates the provided limit name and id and returns the
+// corresponding Name and bucket key.
+func buildBucketKey(nameStr, id string) (Name, string, error) {
+	if nameStr == "" || id == "" {
+		return Unknown, "", berrors.MalformedError("name and id are required")
+	}
+	name, ok := stringToName[nameStr]
+	if !ok || !name.isValid() {
+		return Unknown, "", berrors.MalformedError("unrecognized limit name %q, must be one of %v", nameStr, limitNames)
+	}
+	switch name {
+	case CertificatesPerDomain:
+		// Callers may provide any FQDN, but buckets are keyed on its
+		// registered domain.
+		id = NormalizeDomainForLimit(id)
+	case CertificatesPerDomainPerAccount:
+		regId, domain, ok := strings.Cut(id, ":")
+		if ok {
+			id = joinWithColon(regId, NormalizeDomainForLimit(domain))
+		}
+	}
+	normalized, err := normalizeIdForName(name, id)
+	if err != nil {
+		return Unknown, "", berrors.MalformedError("invalid id %q for limit %s: %s", id, name, err)
+	}
+	id = normalized
+	err = validateIdForName(name, id)
+	if err != nil {
+		return Unknown, "", berrors.MalformedError("invalid id %q for limit %s: %s", id, name, err)
+	}
+	if name == CertificatesPerFQDNSet {
+		// Match the bucket keys produced by newFQDNSetBucketKey and by
+		// parseOverrideLimits.
+		id = fmt.Sprintf("%x", core.HashNames(strings.Split(id, ",")))
+	}
+	return name, joinWithColon(name.EnumString(), id), nil
+}
+
+// transactionFor returns a Transaction for the provided request. If the limit
+// is disabled an allow-only Transaction is returned.
+func (s *Server) transactionFor(req *rlpb.LimitRequest, checkOnly bool) (Transaction, error) {
+	name, bucketKey, err := buildBucketKey(req.GetName(), req.GetId())
+	if err != nil {
+		return Transaction{}, err
+	}
+	limit, err := s.txnBuilder.getLimit(name, bucketKey)
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return newAllowOnlyTransaction(), nil
+		}
+		return Transaction{}, err
+	}
+	var txn Transaction
+	if checkOnly {
+		txn, err = NewCheckOnlyTransaction(limit, bucketKey, req.GetCost())
+	} else {
+		txn, err = NewSpendTransaction(limit, bucketKey, req.GetCost())
+	}
+	if err != nil {
+		return Transaction{}, berrors.MalformedError("%s", err)
+	}
+	return txn, nil
+}
+
+// decisionToPB converts a *Decision to its protobuf representation.
+func decisionToPB(d *Decision) *rlpb.Decision {
+	return &rlpb.Decision{
+		Allowed:   d.allowed,
+		Remaining: d.remaining,
+		RetryIn:   durationpb.New(d.retryIn),
+		ResetIn:   durationpb.New(d.resetIn),
+		Jitter:    durationpb.New(d.jitter),
+	}
+}
+
+// CheckStatus implements rlpb.RateLimitsServer. It reports whether the cost
+// could be spent from the requested bucket without modifying it.
+func (s *Server) CheckStatus(ctx context.Context, req *rlpb.LimitRequest) (*rlpb.Decision, error) {
+	txn, err := s.transactionFor(req, true)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.limiter.Check(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	return decisionToPB(d), nil
+}
+
+// Spend implements rlpb.RateLimitsServer. It deducts the cost from the
+// requested bucket.
+func (s *Server) Spend(ctx context.Context, req *rlpb.LimitRequest) (*rlpb.Decision, error) {
+	txn, err := s.transactionFor(req, false)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.limiter.Spend(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	return decisionToPB(d), nil
+}
+
+// Refund implements rlpb.RateLimitsServer. It returns the cost to the
+// requested bucket, if it exists.
+func (s *Server) Refund(ctx context.Context, req *rlpb.LimitRequest) (*rlpb.Decision, error) {
+	txn, err := s.transactionFor(req, false)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.limiter.Refund(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	return decisionToPB(d), nil
+}
+
+// BuildKey implements rlpb.RateLimitsServer. It validates the requested name
+// and id and returns the bucket key they refer to.
+func (s *Server) BuildKey(_ context.Context, req *rlpb.BuildKeyRequest) (*rlpb.BuildKeyResponse, error) {
+	_, bucketKey, err := buildBucketKey(req.GetName(), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &rlpb.BuildKeyResponse{BucketKey: bucketKey}, nil
+}
@@ -0,0 +1,243 @@
+package ratelimits
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/metrics"
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	clk := clock.NewFake()
+	txnBuilder, err := NewTransactionBuilder(LimitConfigs{
+		NewOrdersPerAccount.String(): &LimitConfig{
+			Burst:          2,
+			Count:          2,
+			Period:         config.Duration{Duration: time.Hour},
+			JitterFraction: 0.5,
+		},
+	})
+	test.AssertNotError(t, err, "creating transaction builder")
+	s, err := NewServer(newInmemTestLimiter(t, clk), txnBuilder, []string{"portal.boulder"}, metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "creating server")
+	return s
+}
+
+// callerContext returns a context carrying a verified client certificate with
+// the provided DNS names, as the gRPC TLS credentials would.
+func callerContext(names ...string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{
+				VerifiedChains: [][]*x509.Certificate{{{DNSNames: names}}},
+			},
+		},
+	})
+}
+
+// trailerStream is a grpc.ServerTransportStream which accepts the trailer
+// metadata set when errors are wrapped for transport.
+type trailerStream struct {
+	method  string
+	trailer metadata.MD
+}
+
+func (ts *trailerStream) Method() string                  { return ts.method }
+func (ts *trailerStream) SetHeader(metadata.MD) error     { return nil }
+func (ts *trailerStream) SendHeader(metadata.MD) error    { return nil }
+func (ts *trailerStream) SetTrailer(md metadata.MD) error { ts.trailer = md; return nil }
+
+// invoke calls the named method of s through its interceptor.
+func invoke(ctx context.Context, s *Server, fullMethod string, req *rlpb.LimitRequest) (*rlpb.Decision, error) {
+	ctx = grpc.NewContextWithServerTransportStream(ctx, &trailerStream{method: fullMethod})
+	handlers := map[string]func(context.Context, *rlpb.LimitRequest) (*rlpb.Decision, error){
+		rlpb.RateLimits_CheckStatus_FullMethodName: s.CheckStatus,
+		rlpb.RateLimits_Spend_FullMethodName:       s.Spend,
+		rlpb.RateLimits_Refund_FullMethodName:      s.Refund,
+	}
+	resp, err := s.Unary(ctx, req, &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return handlers[fullMethod](ctx, req.(*rlpb.LimitRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*rlpb.Decision), nil
+}
+
+func TestServerBuildKey(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+
+	resp, err := s.BuildKey(context.Background(), &rlpb.BuildKeyRequest{Name: "NewOrdersPerAccount", Id: "1337"})
+	test.AssertNotError(t, err, "building key")
+	test.AssertEquals(t, resp.BucketKey, fmt.Sprintf("%d:1337", NewOrdersPerAccount))
+
+	resp, err = s.BuildKey(context.Background(), &rlpb.BuildKeyRequest{Name: "CertificatesPerFQDNSet", Id: "example.com,example.org"})
+	test.AssertNotError(t, err, "building key")
+	test.AssertEquals(t, resp.BucketKey, fmt.Sprintf("%d:%x", CertificatesPerFQDNSet, core.HashNames([]string{"example.com", "example.org"})))
+
+	// CertificatesPerDomain ids are normalized to their registered domain.
+	resp, err = s.BuildKey(context.Background(), &rlpb.BuildKeyRequest{Name: "CertificatesPerDomain", Id: "www.Example.co.uk"})
+	test.AssertNotError(t, err, "building key")
+	test.AssertEquals(t, resp.BucketKey, fmt.Sprintf("%d:example.co.uk", CertificatesPerDomain))
+
+	resp, err = s.BuildKey(context.Background(), &rlpb.BuildKeyRequest{Name: "CertificatesPerDomainPerAccount", Id: "1337:foo.bar.github.io"})
+	test.AssertNotError(t, err, "building key")
+	test.AssertEquals(t, resp.BucketKey, fmt.Sprintf("%d:1337:bar.github.io", CertificatesPerDomainPerAccount))
+
+	testCases := []struct {
+		name string
+		req  *rlpb.BuildKeyRequest
+	}{
+		{"empty request", &rlpb.BuildKeyRequest{}},
+		{"unknown name", &rlpb.BuildKeyRequest{Name: "Unknown", Id: "1337"}},
+		{"invalid name", &rlpb.BuildKeyRequest{Name: "NotALimit", Id: "1337"}},
+		{"invalid id", &rlpb.BuildKeyRequest{Name: "NewOrdersPerAccount", Id: "not-a-regid"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.BuildKey(context.Background(), tc.req)
+			test.AssertErrorIs(t, err, berrors.Malformed)
+		})
+	}
+}
+
+func TestServerCheckSpendRefund(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	ctx := callerContext("portal.boulder")
+	req := &rlpb.LimitRequest{Name: "NewOrdersPerAccount", Id: "1337", Cost: 1}
+
+	// Checking a bucket which doesn't exist reports full capacity.
+	d, err := invoke(ctx, s, rlpb.RateLimits_CheckStatus_FullMethodName, req)
+	test.AssertNotError(t, err, "checking status")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(1))
+	test.Assert(t, d.Jitter.AsDuration() > 0, "jitter should be reported")
+
+	// Spend the full burst.
+	d, err = invoke(ctx, s, rlpb.RateLimits_Spend_FullMethodName, &rlpb.LimitRequest{Name: "NewOrdersPerAccount", Id: "1337", Cost: 2})
+	test.AssertNotError(t, err, "spending")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(0))
+
+	// Checking now reports the bucket as exhausted.
+	d, err = invoke(ctx, s, rlpb.RateLimits_CheckStatus_FullMethodName, req)
+	test.AssertNotError(t, err, "checking status")
+	test.Assert(t, !d.Allowed, "should not be allowed")
+	test.Assert(t, d.RetryIn.AsDuration() > 0, "retryIn should be set")
+
+	// Refund one request and check again.
+	d, err = invoke(ctx, s, rlpb.RateLimits_Refund_FullMethodName, req)
+	test.AssertNotError(t, err, "refunding")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(1))
+
+	d, err = invoke(ctx, s, rlpb.RateLimits_CheckStatus_FullMethodName, req)
+	test.AssertNotError(t, err, "checking status")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(0))
+
+	// A limit without a configured default is always allowed.
+	d, err = invoke(ctx, s, rlpb.RateLimits_Spend_FullMethodName, &rlpb.LimitRequest{Name: "CertificatesPerDomain", Id: "example.com", Cost: 1})
+	test.AssertNotError(t, err, "spending disabled limit")
+	test.Assert(t, d.Allowed, "should be allowed")
+
+	// A cost over the burst is rejected.
+	_, err = invoke(ctx, s, rlpb.RateLimits_Spend_FullMethodName, &rlpb.LimitRequest{Name: "NewOrdersPerAccount", Id: "1337", Cost: 3})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	test.AssertMetricWithLabelsEquals(t, s.requests, prometheus.Labels{"method": "Spend", "caller": "portal.boulder", "result": "success"}, 2)
+	test.AssertMetricWithLabelsEquals(t, s.requests, prometheus.Labels{"method": "Spend", "caller": "portal.boulder", "result": "error"}, 1)
+	test.AssertMetricWithLabelsEquals(t, s.requests, prometheus.Labels{"method": "CheckStatus", "caller": "portal.boulder", "result": "success"}, 3)
+}
+
+func TestServerMutationAuthz(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	req := &rlpb.LimitRequest{Name: "NewOrdersPerAccount", Id: "1337", Cost: 1}
+
+	// Any authenticated client may check status.
+	ctx := callerContext("viewer.boulder")
+	_, err := invoke(ctx, s, rlpb.RateLimits_CheckStatus_FullMethodName, req)
+	test.AssertNotError(t, err, "checking status")
+
+	// But only allow-listed clients may spend or refund.
+	for _, method := range []string{rlpb.RateLimits_Spend_FullMethodName, rlpb.RateLimits_Refund_FullMethodName} {
+		_, err = invoke(ctx, s, method, req)
+		test.AssertErrorIs(t, err, berrors.Unauthorized)
+		_, err = invoke(context.Background(), s, method, req)
+		test.AssertErrorIs(t, err, berrors.Unauthorized)
+	}
+
+	// An allow-listed name anywhere in the certificate is sufficient.
+	_, err = invoke(callerContext("viewer.boulder", "portal.boulder"), s, rlpb.RateLimits_Spend_FullMethodName, req)
+	test.AssertNotError(t, err, "spending")
+
+	test.AssertMetricWithLabelsEquals(t, s.requests, prometheus.Labels{"method": "Spend", "caller": "viewer.boulder", "result": Denied}, 1)
+	test.AssertMetricWithLabelsEquals(t, s.requests, prometheus.Labels{"method": "Refund", "caller": unknownCaller, "result": Denied}, 1)
+	test.AssertMetricWithLabelsEquals(t, s.requests, prometheus.Labels{"method": "Spend", "caller": "portal.boulder", "result": "success"}, 1)
+}
+
+func TestServerOverGRPC(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	test.AssertNotError(t, err, "listening")
+	srv := grpc.NewServer(grpc.UnaryInterceptor(s.Unary))
+	rlpb.RegisterRateLimitsServer(srv, s)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	test.AssertNotError(t, err, "dialing")
+	defer conn.Close()
+	client := rlpb.NewRateLimitsClient(conn)
+
+	key, err := client.BuildKey(context.Background(), &rlpb.BuildKeyRequest{Name: "NewOrdersPerAccount", Id: "1337"})
+	test.AssertNotError(t, err, "building key")
+	test.AssertEquals(t, key.BucketKey, fmt.Sprintf("%d:1337", NewOrdersPerAccount))
+
+	req := &rlpb.LimitRequest{Name: "NewOrdersPerAccount", Id: "1337", Cost: 1}
+	d, err := client.CheckStatus(context.Background(), req)
+	test.AssertNotError(t, err, "checking status")
+	test.Assert(t, d.Allowed, "should be allowed")
+
+	// A plaintext client has no certificate and so may not spend. The error
+	// reaches the client with its gRPC status code and Boulder error type.
+	var trailer metadata.MD
+	_, err = client.Spend(context.Background(), req, grpc.Trailer(&trailer))
+	test.AssertError(t, err, "spending without a client certificate should fail")
+	test.AssertEquals(t, status.Code(err), codes.PermissionDenied)
+	test.AssertDeepEquals(t, trailer.Get("errortype"), []string{strconv.Itoa(int(berrors.Unauthorized))})
+
+	// As do the errors of the handlers.
+	trailer = nil
+	_, err = client.CheckStatus(context.Background(), &rlpb.LimitRequest{Name: "NewOrdersPerAccount", Id: "1337", Cost: 3}, grpc.Trailer(&trailer))
+	test.AssertError(t, err, "checking a cost over the burst should fail")
+	test.AssertEquals(t, status.Code(err), codes.InvalidArgument)
+	test.AssertDeepEquals(t, trailer.Get("errortype"), []string{strconv.Itoa(int(berrors.Malformed))})
+}
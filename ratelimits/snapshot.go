@@ -0,0 +1,94 @@
+package ratelimits
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrChecksumMismatch indicates that a TATBatch's entries don't match its
+// checksum, so it was corrupted or truncated after it was exported.
+var ErrChecksumMismatch = errors.New("TAT batch checksum mismatch")
+
+// TATEntry is the TAT of a single bucket.
+type TATEntry struct {
+	// BucketKey is the key of the bucket, formatted as 'name:id'.
+	BucketKey string `json:"bucketKey"`
+
+	// TAT is the bucket's theoretical arrival time.
+	TAT time.Time `json:"tat"`
+}
+
+// TATBatch is a batch of bucket TATs exported from a source.
+type TATBatch struct {
+	Entries []TATEntry `json:"entries"`
+
+	// Checksum covers the batch's entries, in order. See checksumTATs.
+	Checksum string `json:"checksum"`
+
+	// Cursor resumes the export after this batch. It's empty once the export
+	// is complete.
+	Cursor string `json:"cursor"`
+}
+
+// TATSnapshotter is implemented by sources whose buckets can be exported, so
+// that they can be restored into a fresh source after the loss of the
+// original, rather than every bucket restarting full.
+type TATSnapshotter interface {
+	// ExportTATs returns a batch of up to roughly count bucket TATs, starting
+	// at cursor, which is empty for the first batch. A batch may be empty
+	// even though the export isn't complete, so callers must continue until
+	// the returned cursor is empty. Buckets written during an export may or
+	// may not be included.
+	ExportTATs(ctx context.Context, cursor string, count int) (TATBatch, error)
+
+	// ImportTATs verifies the checksum of an exported batch and restores its
+	// TATs, returning the number of buckets written. Buckets whose TATs are no
+	// later than now have fully refilled since they were exported, so they're
+	// skipped. A bucket which already exists keeps whichever of the two TATs
+	// is later, so importing never makes a bucket less restrictive.
+	ImportTATs(ctx context.Context, batch TATBatch, now time.Time) (int, error)
+}
+
+// checksumTATs returns the hex-encoded SHA-256 digest of entries, each
+// encoded as its bucket key, a zero byte, and its TAT in Unix nanoseconds as
+// a big-endian uint64.
+func checksumTATs(entries []TATEntry) string {
+	h := sha256.New()
+	var tat [8]byte
+	for _, e := range entries {
+		h.Write([]byte(e.BucketKey))
+		h.Write([]byte{0})
+		binary.BigEndian.PutUint64(tat[:], uint64(e.TAT.UnixNano()))
+		h.Write(tat[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newTATBatch returns a batch of entries, with their checksum, which resumes
+// at cursor.
+func newTATBatch(entries []TATEntry, cursor string) TATBatch {
+	return TATBatch{
+		Entries:  entries,
+		Checksum: checksumTATs(entries),
+		Cursor:   cursor,
+	}
+}
+
+// unrefilledTATs verifies the checksum of batch and returns those of its
+// entries whose buckets haven't fully refilled by now.
+func unrefilledTATs(batch TATBatch, now time.Time) ([]TATEntry, error) {
+	if checksumTATs(batch.Entries) != batch.Checksum {
+		return nil, ErrChecksumMismatch
+	}
+	var entries []TATEntry
+	for _, e := range batch.Entries {
+		if e.TAT.After(now) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
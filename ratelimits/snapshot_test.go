@@ -0,0 +1,143 @@
+package ratelimits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// copyTATs exports every bucket of from, in batches of count, and imports each
+// batch into to after a round trip through JSON, as a snapshot would take
+// through object storage. It returns the number of buckets imported.
+func copyTATs(t *testing.T, from, to TATSnapshotter, count int, now time.Time) int {
+	t.Helper()
+	var imported int
+	cursor := ""
+	for {
+		batch, err := from.ExportTATs(context.Background(), cursor, count)
+		test.AssertNotError(t, err, "ExportTATs failed")
+		test.Assert(t, len(batch.Entries) <= count, "batch larger than requested")
+
+		stored, err := json.Marshal(batch)
+		test.AssertNotError(t, err, "marshaling batch")
+		var restored TATBatch
+		err = json.Unmarshal(stored, &restored)
+		test.AssertNotError(t, err, "unmarshaling batch")
+
+		n, err := to.ImportTATs(context.Background(), restored, now)
+		test.AssertNotError(t, err, "ImportTATs failed")
+		imported += n
+
+		cursor = batch.Cursor
+		if cursor == "" {
+			return imported
+		}
+	}
+}
+
+func TestExportImportTATs(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	txnBuilder := newTestTransactionBuilder(t)
+	original := NewInmemSource(clk)
+	limiter := newTestLimiter(t, original, clk)
+
+	// Drain the bucket of each IP address by a different amount, leaving the
+	// last one empty.
+	var txns []Transaction
+	for i := range 7 {
+		bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(fmt.Sprintf("10.1.0.%d", i)))
+		test.AssertNotError(t, err, "building bucket key")
+		limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+		test.AssertNotError(t, err, "getting limit")
+		txn, err := NewSpendTransaction(limit, bucketKey, 1)
+		test.AssertNotError(t, err, "building transaction")
+		spend, err := NewSpendTransaction(limit, bucketKey, int64(i+1)*limit.burst/7)
+		test.AssertNotError(t, err, "building transaction")
+		_, err = limiter.Spend(context.Background(), spend)
+		test.AssertNotError(t, err, "spending")
+		txns = append(txns, txn)
+	}
+
+	// A bucket which has already fully refilled isn't imported.
+	err := original.BatchSet(context.Background(), map[string]time.Time{"refilled": clk.Now().Add(-time.Second)})
+	test.AssertNotError(t, err, "BatchSet failed")
+
+	restored := NewInmemSource(clk)
+	imported := copyTATs(t, original, restored, 3, clk.Now())
+	test.AssertEquals(t, imported, len(txns))
+	_, err = restored.Get(context.Background(), "refilled")
+	test.AssertErrorIs(t, err, ErrBucketNotFound)
+
+	// The restored source makes the same decisions as the original.
+	restoredLimiter := newTestLimiter(t, restored, clk)
+	for _, txn := range txns {
+		want, err := limiter.Check(context.Background(), txn)
+		test.AssertNotError(t, err, "checking original")
+		got, err := restoredLimiter.Check(context.Background(), txn)
+		test.AssertNotError(t, err, "checking restored")
+		test.AssertEquals(t, got.allowed, want.allowed)
+		test.AssertEquals(t, got.remaining, want.remaining)
+		test.AssertEquals(t, got.retryIn, want.retryIn)
+	}
+	d, err := restoredLimiter.Check(context.Background(), txns[len(txns)-1])
+	test.AssertNotError(t, err, "checking restored")
+	test.Assert(t, !d.allowed, "drained bucket should remain drained")
+
+	// Importing again writes nothing, since no TAT is later than the one
+	// already stored, and never makes a bucket less restrictive.
+	later := clk.Now().Add(time.Hour)
+	err = restored.BatchSet(context.Background(), map[string]time.Time{txns[0].bucketKey: later})
+	test.AssertNotError(t, err, "BatchSet failed")
+	imported = copyTATs(t, original, restored, 100, clk.Now())
+	test.AssertEquals(t, imported, 0)
+	tat, err := restored.Get(context.Background(), txns[0].bucketKey)
+	test.AssertNotError(t, err, "Get failed")
+	test.AssertEquals(t, tat, later)
+
+	// Once the snapshot is older than the buckets' refill period, nothing is
+	// imported.
+	imported = copyTATs(t, original, NewInmemSource(clk), 100, clk.Now().Add(time.Hour))
+	test.AssertEquals(t, imported, 0)
+}
+
+func TestImportTATsChecksum(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	original := NewInmemSource(clk)
+	err := original.BatchSet(context.Background(), map[string]time.Time{
+		"a": clk.Now().Add(time.Minute),
+		"b": clk.Now().Add(time.Minute),
+	})
+	test.AssertNotError(t, err, "BatchSet failed")
+	batch, err := original.ExportTATs(context.Background(), "", 10)
+	test.AssertNotError(t, err, "ExportTATs failed")
+	test.AssertEquals(t, len(batch.Entries), 2)
+	test.AssertEquals(t, batch.Cursor, "")
+
+	restored := NewInmemSource(clk)
+	tampered := batch
+	tampered.Entries = []TATEntry{batch.Entries[0], {BucketKey: "b", TAT: clk.Now().Add(time.Hour)}}
+	_, err = restored.ImportTATs(context.Background(), tampered, clk.Now())
+	test.AssertErrorIs(t, err, ErrChecksumMismatch)
+
+	truncated := batch
+	truncated.Entries = batch.Entries[:1]
+	_, err = restored.ImportTATs(context.Background(), truncated, clk.Now())
+	test.AssertErrorIs(t, err, ErrChecksumMismatch)
+
+	// Nothing was written.
+	test.AssertEquals(t, len(restored.m), 0)
+
+	_, err = original.ExportTATs(context.Background(), "", 0)
+	test.AssertError(t, err, "ExportTATs should refuse a count of 0")
+}
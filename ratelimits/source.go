@@ -3,13 +3,16 @@ package ratelimits
 import (
 	"context"
 	"fmt"
-	"sync"
 	"time"
 )
 
 // ErrBucketNotFound indicates that the bucket was not found.
 var ErrBucketNotFound = fmt.Errorf("bucket not found")
 
+// ErrReservationNotFound indicates that the reservation was not found, either
+// because it was never made, was already committed or released, or expired.
+var ErrReservationNotFound = fmt.Errorf("reservation not found")
+
 // Source is an interface for creating and modifying TATs.
 type Source interface {
 	// BatchSet stores the TATs at the specified bucketKeys (formatted as
@@ -55,86 +58,44 @@ type Source interface {
 	//   b) guaranteeing the operation will not block indefinitely (e.g. via
 	//    the underlying storage client implementation).
 	Delete(ctx context.Context, bucketKey string) error
+
+	// BatchReserve provisionally spends against the specified buckets on
+	// behalf of token. For each bucket, atomically: reservations which expired
+	// before now are refunded, then, if the bucket has the capacity to satisfy
+	// the cost, the cost is spent and recorded under token until expiresAt.
+	// Returns the TATs of existing buckets prior to any spend and the set of
+	// bucketKeys which were reserved.
+	BatchReserve(ctx context.Context, token string, now, expiresAt time.Time, buckets map[string]reservation) (map[string]time.Time, map[string]bool, error)
+
+	// Commit makes the provisional spends held by token against the specified
+	// buckets permanent. Spends whose reservations expired before now are
+	// refunded instead and ErrReservationNotFound is returned.
+	Commit(ctx context.Context, token string, bucketKeys []string, now time.Time) error
+
+	// Release refunds the provisional spends held by token against the
+	// specified buckets. If token holds no spends ErrReservationNotFound is
+	// returned.
+	Release(ctx context.Context, token string, bucketKeys []string, now time.Time) error
 }
 
 type increment struct {
 	cost time.Duration
-	ttl  time.Duration
-}
-
-// inmem is an in-memory implementation of the source interface used for
-// testing.
-type inmem struct {
-	sync.RWMutex
-	m map[string]time.Time
 }
 
-var _ Source = (*inmem)(nil)
-
-func NewInmemSource() *inmem {
-	return &inmem{m: make(map[string]time.Time)}
-}
-
-func (in *inmem) BatchSet(_ context.Context, bucketKeys map[string]time.Time) error {
-	in.Lock()
-	defer in.Unlock()
-	for k, v := range bucketKeys {
-		in.m[k] = v
-	}
-	return nil
-}
-
-func (in *inmem) BatchSetNotExisting(_ context.Context, bucketKeys map[string]time.Time) (map[string]bool, error) {
-	in.Lock()
-	defer in.Unlock()
-	alreadyExists := make(map[string]bool, len(bucketKeys))
-	for k, v := range bucketKeys {
-		_, ok := in.m[k]
-		if ok {
-			alreadyExists[k] = true
-		} else {
-			in.m[k] = v
-		}
-	}
-	return alreadyExists, nil
-}
-
-func (in *inmem) BatchIncrement(_ context.Context, bucketKeys map[string]increment) error {
-	in.Lock()
-	defer in.Unlock()
-	for k, v := range bucketKeys {
-		in.m[k] = in.m[k].Add(v.cost)
-	}
-	return nil
-}
+// reservation is a provisional spend against a single bucket.
+type reservation struct {
+	// cost is the amount the bucket's TAT is advanced by.
+	cost time.Duration
 
-func (in *inmem) Get(_ context.Context, bucketKey string) (time.Time, error) {
-	in.RLock()
-	defer in.RUnlock()
-	tat, ok := in.m[bucketKey]
-	if !ok {
-		return time.Time{}, ErrBucketNotFound
-	}
-	return tat, nil
-}
+	// burstOffset is the burstOffset of the bucket's limit.
+	burstOffset time.Duration
 
-func (in *inmem) BatchGet(_ context.Context, bucketKeys []string) (map[string]time.Time, error) {
-	in.RLock()
-	defer in.RUnlock()
-	tats := make(map[string]time.Time, len(bucketKeys))
-	for _, k := range bucketKeys {
-		tat, ok := in.m[k]
-		if !ok {
-			continue
-		}
-		tats[k] = tat
-	}
-	return tats, nil
-}
+	// ttl is the longest the bucket can take to fully refill once the
+	// reservation has been spent against it, which bounds the TTL applied to
+	// the bucket.
+	ttl time.Duration
 
-func (in *inmem) Delete(_ context.Context, bucketKey string) error {
-	in.Lock()
-	defer in.Unlock()
-	delete(in.m, bucketKey)
-	return nil
+	// jitter is the offset by which the refill schedule of the bucket is
+	// staggered.
+	jitter time.Duration
 }
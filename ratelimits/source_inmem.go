@@ -0,0 +1,643 @@
+package ratelimits
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/config"
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// Compile-time check that InmemSource implements the source interface.
+var _ Source = (*InmemSource)(nil)
+var _ TATSnapshotter = (*InmemSource)(nil)
+var _ DenialStreakRecorder = (*InmemSource)(nil)
+var _ BucketVersioner = (*InmemSource)(nil)
+
+// inmemExpiryInterval is how often an InmemSource removes the buckets and
+// reservations whose TTLs have passed.
+const inmemExpiryInterval = time.Minute
+
+// defaultSnapshotInterval is how often an InmemSource's snapshot is written
+// if InmemConfig.SnapshotInterval isn't set.
+const defaultSnapshotInterval = time.Minute
+
+// InmemConfig configures an InmemSource.
+type InmemConfig struct {
+	// MaxBuckets, if non-zero, caps the number of buckets stored. Beyond it,
+	// the least recently used full buckets are evicted, which loses nothing,
+	// as a full bucket is indistinguishable from one which doesn't exist.
+	// Buckets which are still refilling are never evicted, so the cap is
+	// exceeded while every bucket is refilling.
+	MaxBuckets int `validate:"min=0"`
+
+	// SnapshotFile, if set, is the path to which the TATs of buckets which
+	// are still refilling are periodically written, and from which they're
+	// restored at startup, so that a restart doesn't refill every bucket.
+	SnapshotFile string
+
+	// SnapshotInterval is how often SnapshotFile is written. Defaults to one
+	// minute.
+	SnapshotInterval config.Duration `validate:"-"`
+}
+
+// heldSpend is the cost held against a single bucket by a reservation.
+type heldSpend struct {
+	cost      time.Duration
+	expiresAt time.Time
+}
+
+// InmemSource is a ratelimits source which stores buckets in memory, for tests
+// and for deployments which don't want to run Redis. Its buckets aren't shared
+// with other processes, so each component using it must run as a single
+// instance. Like RedisSource, a bucket expires bucketTTLMargin after it has
+// fully refilled.
+type InmemSource struct {
+	sync.Mutex
+	clk clock.Clock
+	m   map[string]time.Time
+
+	// lru orders the bucketKeys in m from most to least recently used, and
+	// elems holds the element of each.
+	lru   *list.List
+	elems map[string]*list.Element
+
+	// maxBuckets, if non-zero, is the number of buckets beyond which full
+	// buckets are evicted.
+	maxBuckets int
+
+	// evictableAfter is the earliest time at which any bucket could become
+	// evictable, as of the last eviction which couldn't get back under
+	// maxBuckets. Until then, evictions aren't attempted.
+	evictableAfter time.Time
+
+	// nextExpiry is when expired buckets and reservations are next removed.
+	nextExpiry time.Time
+
+	// held maps bucketKeys to reservation tokens to the spends they hold.
+	held map[string]map[string]heldSpend
+
+	// versions maps the bucketKeys in m to the value of writes when they were
+	// last written. It is nil unless TrackVersions has been called. A bucket's
+	// version is removed along with the bucket, and since writes only ever
+	// increases a bucket which is removed and written again never gets a
+	// version it had before.
+	versions map[string]int64
+	writes   int64
+
+	// streaks maps regIds to their denial streaks.
+	streaks map[int64]DenialStreak
+
+	evictions prometheus.Counter
+}
+
+// NewInmemSource returns a new, uncapped, in-memory source.
+func NewInmemSource(clk clock.Clock) *InmemSource {
+	return &InmemSource{
+		clk:     clk,
+		m:       make(map[string]time.Time),
+		lru:     list.New(),
+		elems:   make(map[string]*list.Element),
+		held:    make(map[string]map[string]heldSpend),
+		streaks: make(map[int64]DenialStreak),
+		evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ratelimits_inmem_evictions",
+			Help: "Number of full buckets evicted from the in-memory ratelimits source to stay within its maximum number of buckets",
+		}),
+	}
+}
+
+// NewInmemSourceFromConfig returns a new in-memory source configured by c. If
+// c.SnapshotFile exists, the source is restored from it. Callers are
+// responsible for calling SnapshotEvery to keep it up to date.
+func NewInmemSourceFromConfig(c InmemConfig, clk clock.Clock, stats prometheus.Registerer) (*InmemSource, error) {
+	in := NewInmemSource(clk)
+	in.maxBuckets = c.MaxBuckets
+	stats.MustRegister(in.evictions)
+	stats.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "ratelimits_inmem_buckets",
+		Help: "Number of buckets stored in the in-memory ratelimits source",
+	}, func() float64 {
+		in.Lock()
+		defer in.Unlock()
+		return float64(len(in.m))
+	}))
+
+	if c.SnapshotFile != "" {
+		_, err := in.LoadSnapshot(c.SnapshotFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("restoring snapshot: %w", err)
+		}
+	}
+	return in, nil
+}
+
+// touch marks bucketKey as the most recently used bucket. The caller must hold
+// the lock.
+func (in *InmemSource) touch(bucketKey string) {
+	e, ok := in.elems[bucketKey]
+	if ok {
+		in.lru.MoveToFront(e)
+		return
+	}
+	in.elems[bucketKey] = in.lru.PushFront(bucketKey)
+}
+
+// get returns the TAT of bucketKey, if it exists, marking it as used. The
+// caller must hold the lock.
+func (in *InmemSource) get(bucketKey string) (time.Time, bool) {
+	tat, ok := in.m[bucketKey]
+	if ok {
+		in.touch(bucketKey)
+	}
+	return tat, ok
+}
+
+// set stores tat at bucketKey, marking it as used. The caller must hold the
+// lock.
+func (in *InmemSource) set(bucketKey string, tat time.Time) {
+	in.m[bucketKey] = tat
+	in.touch(bucketKey)
+	if in.versions != nil {
+		in.writes++
+		in.versions[bucketKey] = in.writes
+	}
+	if tat.Before(in.evictableAfter) {
+		in.evictableAfter = tat
+	}
+}
+
+// remove deletes bucketKey. The caller must hold the lock.
+func (in *InmemSource) remove(bucketKey string) {
+	delete(in.m, bucketKey)
+	delete(in.versions, bucketKey)
+	e, ok := in.elems[bucketKey]
+	if ok {
+		in.lru.Remove(e)
+		delete(in.elems, bucketKey)
+	}
+}
+
+// evictableAt returns the time from which bucketKey is full and has no spends
+// held against it by reservations which haven't expired. The caller must hold
+// the lock.
+func (in *InmemSource) evictableAt(bucketKey string) time.Time {
+	at := in.m[bucketKey]
+	for _, spend := range in.held[bucketKey] {
+		if spend.expiresAt.After(at) {
+			at = spend.expiresAt
+		}
+	}
+	return at
+}
+
+// evict removes the least recently used evictable buckets until no more than
+// maxBuckets remain, or none are evictable. Spends held against an evicted
+// bucket have expired, and refunding them to a full bucket would be a no-op,
+// so they're dropped. The caller must hold the lock.
+func (in *InmemSource) evict(now time.Time) {
+	if in.maxBuckets == 0 || len(in.m) <= in.maxBuckets || now.Before(in.evictableAfter) {
+		return
+	}
+	var soonest time.Time
+	for e := in.lru.Back(); e != nil && len(in.m) > in.maxBuckets; {
+		prev := e.Prev()
+		bucketKey := e.Value.(string)
+		at := in.evictableAt(bucketKey)
+		if at.After(now) {
+			if soonest.IsZero() || at.Before(soonest) {
+				soonest = at
+			}
+		} else {
+			in.remove(bucketKey)
+			delete(in.held, bucketKey)
+			in.evictions.Inc()
+		}
+		e = prev
+	}
+	if len(in.m) > in.maxBuckets {
+		// Every bucket was considered, so there's no point trying again until
+		// the first of them becomes evictable.
+		in.evictableAfter = soonest
+	}
+}
+
+// expire refunds and removes reservations which have expired, and removes the
+// buckets whose TTLs have passed, at most once every inmemExpiryInterval. The
+// caller must hold the lock.
+func (in *InmemSource) expire(now time.Time) {
+	if now.Before(in.nextExpiry) {
+		return
+	}
+	in.nextExpiry = now.Add(inmemExpiryInterval)
+
+	for bucketKey := range in.held {
+		in.refundExpired(bucketKey, now)
+	}
+	for bucketKey, tat := range in.m {
+		if !tat.Add(bucketTTLMargin).After(now) {
+			in.remove(bucketKey)
+		}
+	}
+}
+
+// maintain expires and evicts buckets after a write. The caller must hold the
+// lock.
+func (in *InmemSource) maintain() {
+	now := in.clk.Now()
+	in.expire(now)
+	in.evict(now)
+}
+
+func (in *InmemSource) BatchSet(_ context.Context, bucketKeys map[string]time.Time) error {
+	in.Lock()
+	defer in.Unlock()
+	for k, v := range bucketKeys {
+		in.set(k, v)
+	}
+	in.maintain()
+	return nil
+}
+
+func (in *InmemSource) BatchSetNotExisting(_ context.Context, bucketKeys map[string]time.Time) (map[string]bool, error) {
+	in.Lock()
+	defer in.Unlock()
+	alreadyExists := make(map[string]bool, len(bucketKeys))
+	for k, v := range bucketKeys {
+		_, ok := in.get(k)
+		if ok {
+			alreadyExists[k] = true
+		} else {
+			in.set(k, v)
+		}
+	}
+	in.maintain()
+	return alreadyExists, nil
+}
+
+func (in *InmemSource) BatchIncrement(_ context.Context, bucketKeys map[string]increment) error {
+	in.Lock()
+	defer in.Unlock()
+	for k, v := range bucketKeys {
+		in.set(k, in.m[k].Add(v.cost))
+	}
+	in.maintain()
+	return nil
+}
+
+func (in *InmemSource) Get(_ context.Context, bucketKey string) (time.Time, error) {
+	in.Lock()
+	defer in.Unlock()
+	in.refundExpired(bucketKey, in.clk.Now())
+	tat, ok := in.get(bucketKey)
+	if !ok {
+		return time.Time{}, ErrBucketNotFound
+	}
+	return tat, nil
+}
+
+func (in *InmemSource) BatchGet(_ context.Context, bucketKeys []string) (map[string]time.Time, error) {
+	in.Lock()
+	defer in.Unlock()
+	now := in.clk.Now()
+	tats := make(map[string]time.Time, len(bucketKeys))
+	for _, k := range bucketKeys {
+		in.refundExpired(k, now)
+		tat, ok := in.get(k)
+		if !ok {
+			continue
+		}
+		tats[k] = tat
+	}
+	return tats, nil
+}
+
+func (in *InmemSource) Delete(_ context.Context, bucketKey string) error {
+	in.Lock()
+	defer in.Unlock()
+	in.remove(bucketKey)
+	return nil
+}
+
+// refund returns cost to the bucket at bucketKey. The caller must hold the
+// lock.
+func (in *InmemSource) refund(bucketKey string, cost time.Duration, now time.Time) {
+	tat, ok := in.m[bucketKey]
+	if !ok || !tat.After(now) {
+		// The bucket is already full.
+		return
+	}
+	newTAT := tat.Add(-cost)
+	if !newTAT.After(now) {
+		in.remove(bucketKey)
+		return
+	}
+	in.set(bucketKey, newTAT)
+}
+
+// refundExpired refunds and removes the reservations held against bucketKey
+// which expired by now. It's called whenever a bucket is read, so that a
+// reservation which is never committed or released doesn't hold its spend
+// until the next reservation against the same bucket. The caller must hold the
+// lock.
+func (in *InmemSource) refundExpired(bucketKey string, now time.Time) {
+	spends := in.held[bucketKey]
+	for token, spend := range spends {
+		if !spend.expiresAt.After(now) {
+			delete(spends, token)
+			in.refund(bucketKey, spend.cost, now)
+		}
+	}
+	if len(spends) == 0 {
+		delete(in.held, bucketKey)
+	}
+}
+
+func (in *InmemSource) BatchReserve(_ context.Context, token string, now, expiresAt time.Time, buckets map[string]reservation) (map[string]time.Time, map[string]bool, error) {
+	in.Lock()
+	defer in.Unlock()
+	tats := make(map[string]time.Time, len(buckets))
+	reserved := make(map[string]bool, len(buckets))
+	for bucketKey, r := range buckets {
+		in.refundExpired(bucketKey, now)
+
+		tat, ok := in.get(bucketKey)
+		if ok {
+			tats[bucketKey] = tat
+		}
+		start := now.Add(-r.jitter)
+		if tat.After(start) {
+			start = tat
+		}
+		newTAT := start.Add(r.cost)
+		if newTAT.Add(-r.burstOffset).After(now) {
+			// Too little capacity to satisfy the cost.
+			continue
+		}
+		in.set(bucketKey, newTAT)
+		if in.held[bucketKey] == nil {
+			in.held[bucketKey] = make(map[string]heldSpend)
+		}
+		in.held[bucketKey][token] = heldSpend{cost: r.cost, expiresAt: expiresAt}
+		reserved[bucketKey] = true
+	}
+	in.maintain()
+	return tats, reserved, nil
+}
+
+// settle removes the spends held by token against the specified buckets,
+// refunding them if release is true or if they expired before now.
+func (in *InmemSource) settle(token string, bucketKeys []string, now time.Time, release bool) error {
+	in.Lock()
+	defer in.Unlock()
+	var settledAny, expired bool
+	for _, bucketKey := range bucketKeys {
+		spend, ok := in.held[bucketKey][token]
+		if !ok {
+			expired = true
+			continue
+		}
+		delete(in.held[bucketKey], token)
+		if !spend.expiresAt.After(now) {
+			expired = true
+			in.refund(bucketKey, spend.cost, now)
+			continue
+		}
+		settledAny = true
+		if release {
+			in.refund(bucketKey, spend.cost, now)
+		}
+	}
+	if !settledAny || (expired && !release) {
+		return ErrReservationNotFound
+	}
+	return nil
+}
+
+func (in *InmemSource) Commit(_ context.Context, token string, bucketKeys []string, now time.Time) error {
+	return in.settle(token, bucketKeys, now, false)
+}
+
+func (in *InmemSource) Release(_ context.Context, token string, bucketKeys []string, now time.Time) error {
+	return in.settle(token, bucketKeys, now, true)
+}
+
+// TrackVersions also versions the buckets which already exist, such as those
+// restored from a snapshot, so that only buckets which don't exist have
+// version 0.
+func (in *InmemSource) TrackVersions() {
+	in.Lock()
+	defer in.Unlock()
+	if in.versions != nil {
+		return
+	}
+	in.versions = make(map[string]int64, len(in.m))
+	for k := range in.m {
+		in.writes++
+		in.versions[k] = in.writes
+	}
+}
+
+func (in *InmemSource) BatchGetVersioned(_ context.Context, bucketKeys []string) (map[string]time.Time, map[string]int64, error) {
+	in.Lock()
+	defer in.Unlock()
+	now := in.clk.Now()
+	tats := make(map[string]time.Time, len(bucketKeys))
+	versions := make(map[string]int64, len(bucketKeys))
+	for _, k := range bucketKeys {
+		in.refundExpired(k, now)
+		tat, ok := in.get(k)
+		if !ok {
+			continue
+		}
+		tats[k] = tat
+		version, ok := in.versions[k]
+		if ok {
+			versions[k] = version
+		}
+	}
+	return tats, versions, nil
+}
+
+func (in *InmemSource) BatchSetIfUnchanged(_ context.Context, buckets map[string]versionedSet) (map[string]bool, error) {
+	in.Lock()
+	defer in.Unlock()
+	changed := make(map[string]bool)
+	for k, set := range buckets {
+		if in.versions[k] != set.version {
+			changed[k] = true
+			continue
+		}
+		if !set.tat.IsZero() {
+			in.set(k, set.tat)
+		}
+	}
+	in.maintain()
+	return changed, nil
+}
+
+// ExportTATs exports buckets in order of their keys. The cursor is the last
+// bucketKey exported.
+func (in *InmemSource) ExportTATs(_ context.Context, cursor string, count int) (TATBatch, error) {
+	if count < 1 {
+		return TATBatch{}, fmt.Errorf("invalid export count %d", count)
+	}
+	in.Lock()
+	defer in.Unlock()
+	var bucketKeys []string
+	for k := range in.m {
+		if k > cursor {
+			bucketKeys = append(bucketKeys, k)
+		}
+	}
+	slices.Sort(bucketKeys)
+
+	var entries []TATEntry
+	for _, k := range bucketKeys[:min(count, len(bucketKeys))] {
+		entries = append(entries, TATEntry{BucketKey: k, TAT: in.m[k]})
+	}
+	next := ""
+	if len(entries) < len(bucketKeys) {
+		next = entries[len(entries)-1].BucketKey
+	}
+	return newTATBatch(entries, next), nil
+}
+
+func (in *InmemSource) ImportTATs(_ context.Context, batch TATBatch, now time.Time) (int, error) {
+	entries, err := unrefilledTATs(batch, now)
+	if err != nil {
+		return 0, err
+	}
+	in.Lock()
+	defer in.Unlock()
+	var imported int
+	for _, e := range entries {
+		tat, ok := in.m[e.BucketKey]
+		if ok && !e.TAT.After(tat) {
+			continue
+		}
+		in.set(e.BucketKey, e.TAT)
+		imported++
+	}
+	in.maintain()
+	return imported, nil
+}
+
+// WriteSnapshot writes the TATs of the buckets which haven't fully refilled to
+// path, as a JSON encoded TATBatch. The snapshot is written to a temporary file
+// which is then renamed, so path always holds a complete snapshot.
+func (in *InmemSource) WriteSnapshot(path string) error {
+	now := in.clk.Now()
+	in.Lock()
+	var entries []TATEntry
+	for k, tat := range in.m {
+		if tat.After(now) {
+			entries = append(entries, TATEntry{BucketKey: k, TAT: tat})
+		}
+	}
+	in.Unlock()
+	slices.SortFunc(entries, func(a, b TATEntry) int {
+		return cmp.Compare(a.BucketKey, b.BucketKey)
+	})
+
+	data, err := json.Marshal(newTATBatch(entries, ""))
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot restores the TATs written to path by WriteSnapshot, returning
+// the number of buckets restored. Buckets which have fully refilled since the
+// snapshot was written are skipped, and existing buckets keep whichever of the
+// two TATs is later.
+func (in *InmemSource) LoadSnapshot(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var batch TATBatch
+	err = json.Unmarshal(data, &batch)
+	if err != nil {
+		return 0, fmt.Errorf("parsing snapshot %q: %w", path, err)
+	}
+	return in.ImportTATs(context.Background(), batch, in.clk.Now())
+}
+
+// SnapshotEvery calls WriteSnapshot with path every interval, or every
+// defaultSnapshotInterval if interval is zero, until the context is done.
+// Errors are logged, and the previous snapshot is kept.
+func (in *InmemSource) SnapshotEvery(ctx context.Context, path string, interval time.Duration, logger blog.Logger) {
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := in.WriteSnapshot(path)
+			if err != nil {
+				logger.Errf("Failed to write rate limit snapshot to %q: %s", path, err)
+			}
+		}
+	}
+}
+
+func (in *InmemSource) RecordDenial(_ context.Context, regId int64, now time.Time, gap time.Duration) error {
+	in.Lock()
+	defer in.Unlock()
+	streak, ok := in.streaks[regId]
+	if !ok || now.Sub(streak.Last) > gap {
+		streak = DenialStreak{First: now}
+	}
+	streak.Last = now
+	streak.Count++
+	in.streaks[regId] = streak
+	return nil
+}
+
+func (in *InmemSource) ResetDenialStreak(_ context.Context, regId int64) error {
+	in.Lock()
+	defer in.Unlock()
+	delete(in.streaks, regId)
+	return nil
+}
+
+func (in *InmemSource) DenialStreaks(_ context.Context) (map[int64]DenialStreak, error) {
+	in.Lock()
+	defer in.Unlock()
+	return maps.Clone(in.streaks), nil
+}
@@ -0,0 +1,187 @@
+package ratelimits
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func newInmemTestLimiter(t *testing.T, clk clock.FakeClock) *Limiter {
+	return newTestLimiter(t, NewInmemSource(clk), clk)
+}
+
+func TestInmemSourceConcurrentReserve(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	source := NewInmemSource(clk)
+	limiter := newTestLimiter(t, source, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	txn, err := txnBuilder.registrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "building transaction")
+	snapshot := filepath.Join(t.TempDir(), "snapshot.json")
+
+	// Exactly the burst of 20 is allowed, however the reservations race, and
+	// reading or snapshotting the source meanwhile is safe.
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, d, err := limiter.Reserve(context.Background(), []Transaction{txn})
+			test.AssertNotError(t, err, "Reserve failed")
+			if d.allowed {
+				allowed.Add(1)
+				test.AssertNotError(t, limiter.Commit(context.Background(), token), "Commit failed")
+			}
+			_, err = source.BatchGet(context.Background(), []string{txn.bucketKey})
+			test.AssertNotError(t, err, "BatchGet failed")
+			if i%10 == 0 {
+				test.AssertNotError(t, source.WriteSnapshot(snapshot), "WriteSnapshot failed")
+			}
+		}()
+	}
+	wg.Wait()
+	test.AssertEquals(t, allowed.Load(), int64(20))
+}
+
+func TestInmemSourceEviction(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	source, err := NewInmemSourceFromConfig(InmemConfig{MaxBuckets: 3}, clk, metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "NewInmemSourceFromConfig failed")
+	set := func(bucketKey string, refill time.Duration) {
+		t.Helper()
+		err := source.BatchSet(context.Background(), map[string]time.Time{bucketKey: clk.Now().Add(refill)})
+		test.AssertNotError(t, err, "BatchSet failed")
+	}
+	assertBuckets := func(want ...string) {
+		t.Helper()
+		test.AssertEquals(t, len(source.m), len(want))
+		for _, bucketKey := range want {
+			_, ok := source.m[bucketKey]
+			test.Assert(t, ok, fmt.Sprintf("bucket %q was evicted", bucketKey))
+		}
+	}
+
+	// Buckets which are still refilling are never evicted, even beyond the
+	// cap.
+	set("a", time.Minute)
+	set("b", time.Minute)
+	set("c", time.Minute)
+	set("d", 5*time.Minute)
+	assertBuckets("a", "b", "c", "d")
+
+	// Once a, b, and c are full, the least recently used of them are evicted
+	// to get back to the cap.
+	clk.Add(2 * time.Minute)
+	_, err = source.Get(context.Background(), "a")
+	test.AssertNotError(t, err, "Get failed")
+	set("e", 5*time.Minute)
+	assertBuckets("a", "d", "e")
+	test.AssertMetricWithLabelsEquals(t, source.evictions, prometheus.Labels{}, 2)
+
+	set("f", 5*time.Minute)
+	assertBuckets("d", "e", "f")
+	set("g", 5*time.Minute)
+	assertBuckets("d", "e", "f", "g")
+
+	// A full bucket with a spend held against it by a reservation which hasn't
+	// expired isn't evicted either.
+	clk.Add(5 * time.Minute)
+	_, reserved, err := source.BatchReserve(context.Background(), "token", clk.Now(), clk.Now().Add(time.Minute),
+		map[string]reservation{"d": {cost: time.Second, burstOffset: time.Minute}})
+	test.AssertNotError(t, err, "BatchReserve failed")
+	test.Assert(t, reserved["d"], "bucket should have been reserved")
+	assertBuckets("d", "f", "g")
+	_, err = source.BatchGet(context.Background(), []string{"g", "f"})
+	test.AssertNotError(t, err, "BatchGet failed")
+	clk.Add(time.Second)
+	set("h", time.Minute)
+	assertBuckets("d", "f", "h")
+	test.AssertNotError(t, source.Commit(context.Background(), "token", []string{"d"}, clk.Now()), "Commit failed")
+}
+
+func TestInmemSourceExpiry(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	source := NewInmemSource(clk)
+	source.TrackVersions()
+	err := source.BatchSet(context.Background(), map[string]time.Time{"a": clk.Now().Add(time.Minute)})
+	test.AssertNotError(t, err, "BatchSet failed")
+	_, reserved, err := source.BatchReserve(context.Background(), "token", clk.Now(), clk.Now().Add(time.Minute),
+		map[string]reservation{"b": {cost: time.Minute, burstOffset: time.Hour}})
+	test.AssertNotError(t, err, "BatchReserve failed")
+	test.Assert(t, reserved["b"], "bucket should have been reserved")
+
+	// Once a has been full for bucketTTLMargin, it's removed along with its
+	// version, and the expired reservation is refunded and forgotten.
+	clk.Add(time.Minute + bucketTTLMargin)
+	err = source.BatchSet(context.Background(), map[string]time.Time{"c": clk.Now().Add(time.Minute)})
+	test.AssertNotError(t, err, "BatchSet failed")
+	test.AssertEquals(t, len(source.m), 1)
+	test.AssertEquals(t, source.lru.Len(), 1)
+	test.AssertEquals(t, len(source.versions), 1)
+	test.AssertEquals(t, len(source.held), 0)
+	test.AssertErrorIs(t, source.Release(context.Background(), "token", []string{"b"}, clk.Now()), ErrReservationNotFound)
+
+	// Were a written again, it would get a version it's never had.
+	_, versions, err := source.BatchGetVersioned(context.Background(), []string{"a", "c"})
+	test.AssertNotError(t, err, "BatchGetVersioned failed")
+	test.AssertDeepEquals(t, versions, map[string]int64{"c": source.writes})
+}
+
+func TestInmemSourceSnapshot(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	// Without a snapshot, the source starts empty.
+	original, err := NewInmemSourceFromConfig(InmemConfig{SnapshotFile: path}, clk, metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "NewInmemSourceFromConfig failed")
+	test.AssertEquals(t, len(original.m), 0)
+
+	err = original.BatchSet(context.Background(), map[string]time.Time{
+		"refilled": clk.Now().Add(-time.Second),
+		"minute":   clk.Now().Add(time.Minute),
+		"hour":     clk.Now().Add(time.Hour),
+	})
+	test.AssertNotError(t, err, "BatchSet failed")
+	test.AssertNotError(t, original.WriteSnapshot(path), "WriteSnapshot failed")
+	files, err := os.ReadDir(filepath.Dir(path))
+	test.AssertNotError(t, err, "reading snapshot directory")
+	test.AssertEquals(t, len(files), 1)
+
+	// Only the buckets which haven't fully refilled by the time the snapshot
+	// is restored are.
+	clk.Add(2 * time.Minute)
+	restored, err := NewInmemSourceFromConfig(InmemConfig{SnapshotFile: path}, clk, metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "NewInmemSourceFromConfig failed")
+	tats, err := restored.BatchGet(context.Background(), []string{"refilled", "minute", "hour"})
+	test.AssertNotError(t, err, "BatchGet failed")
+	test.AssertDeepEquals(t, tats, map[string]time.Time{"hour": clk.Now().Add(58 * time.Minute)})
+
+	// A corrupted snapshot isn't restored.
+	test.AssertNotError(t, os.WriteFile(path, []byte(`{"entries":[{"bucketKey":"hour","tat":"2099-01-01T00:00:00Z"}],"checksum":""}`), 0600), "writing snapshot")
+	_, err = NewInmemSourceFromConfig(InmemConfig{SnapshotFile: path}, clk, metrics.NoopRegisterer)
+	test.AssertErrorIs(t, err, ErrChecksumMismatch)
+	test.AssertNotError(t, os.WriteFile(path, []byte("{"), 0600), "writing snapshot")
+	_, err = NewInmemSourceFromConfig(InmemConfig{SnapshotFile: path}, clk, metrics.NoopRegisterer)
+	test.AssertError(t, err, "truncated snapshot should not be restored")
+}
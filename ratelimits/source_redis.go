@@ -3,7 +3,12 @@ package ratelimits
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmhodges/clock"
@@ -13,12 +18,19 @@ import (
 
 // Compile-time check that RedisSource implements the source interface.
 var _ Source = (*RedisSource)(nil)
+var _ TATSnapshotter = (*RedisSource)(nil)
+var _ DenialStreakRecorder = (*RedisSource)(nil)
+var _ BucketVersioner = (*RedisSource)(nil)
 
 // RedisSource is a ratelimits source backed by sharded Redis.
 type RedisSource struct {
 	client  *redis.Ring
 	clk     clock.Clock
 	latency *prometheus.HistogramVec
+
+	// versionTTL is the TTL of the key holding each bucket's version. It is
+	// zero, and versions aren't written, unless TrackVersions has been called.
+	versionTTL time.Duration
 }
 
 // NewRedisSource returns a new Redis backed source using the provided
@@ -27,7 +39,7 @@ func NewRedisSource(client *redis.Ring, clk clock.Clock, stats prometheus.Regist
 	latency := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "ratelimits_latency",
-			Help: "Histogram of Redis call latencies labeled by call=[set|get|delete|ping] and result=[success|error]",
+			Help: "Histogram of Redis call latencies labeled by call=[set|get|delete|ping|batchreserve|commit|release|setmissingttls|exporttats|importtats|recorddenial|resetdenialstreak|denialstreaks] and result=[success|error]",
 			// Exponential buckets ranging from 0.0005s to 3s.
 			Buckets: prometheus.ExponentialBucketsRange(0.0005, 3, 8),
 		},
@@ -81,6 +93,70 @@ func (r *RedisSource) observeLatency(call string, latency time.Duration, err err
 	r.latency.With(prometheus.Labels{"call": call, "result": result}).Observe(latency.Seconds())
 }
 
+// bucketTTLMargin is added to the TTL of every bucket key, beyond the time its
+// bucket needs to fully refill, to allow for clock skew between the limiter and
+// Redis.
+const bucketTTLMargin = 10 * time.Minute
+
+// bucketTTL returns the TTL of a bucket key holding tat. A bucket is full once
+// its TAT has passed, at which point it's indistinguishable from a bucket which
+// doesn't exist, so its key can safely expire. Expiring it any earlier would
+// make a partially drained bucket look full.
+func bucketTTL(tat, now time.Time) time.Duration {
+	return max(tat.Sub(now), 0) + bucketTTLMargin
+}
+
+// bucketVersionTTL is the TTL of the key holding a bucket's version, which is
+// refreshed by every write to the bucket. It need only outlive the receipts
+// which record versions, which are far shorter lived.
+const bucketVersionTTL = 10 * time.Minute
+
+// versionKey returns the key of the version of bucketKey. The hash tag places
+// it on the same shard as the bucket, which the scripts below require.
+func versionKey(bucketKey string) string {
+	return "{" + bucketKey + "}:version"
+}
+
+// TrackVersions makes every subsequent write to a bucket increment its
+// version. Every limiter writing to the same buckets must track versions, or
+// its writes won't invalidate receipts issued by the others.
+func (r *RedisSource) TrackVersions() {
+	r.versionTTL = bucketVersionTTL
+}
+
+// bumpLua is shared by the scripts which write to buckets. Its bump increments
+// the version at key, unless versionTTL is '0' because versions aren't being
+// tracked.
+const bumpLua = `
+local function bump(key, versionTTL)
+  if versionTTL ~= '0' then
+    redis.call('INCR', key)
+    redis.call('PEXPIRE', key, versionTTL)
+  end
+end
+`
+
+// setScript stores the TAT at KEYS[1], if ARGV[3] is "nx" only if the bucket
+// doesn't already exist, and increments the bucket's version at KEYS[2] if the
+// TAT was stored. It's used in place of SET while versions are tracked, so
+// that no other write can be made between the two. It returns 1 if the TAT was
+// stored, otherwise 0.
+//
+// ARGV: tat, bucket TTL (ms), "nx" or "", bucketVersionTTL (ms).
+var setScript = redis.NewScript(bumpLua + `
+local stored
+if ARGV[3] == 'nx' then
+  stored = redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2], 'NX')
+else
+  stored = redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+end
+if not stored then
+  return 0
+end
+bump(KEYS[2], ARGV[4])
+return 1
+`)
+
 // BatchSet stores TATs at the specified bucketKeys using a pipelined Redis
 // Transaction in order to reduce the number of round-trips to each Redis shard.
 func (r *RedisSource) BatchSet(ctx context.Context, buckets map[string]time.Time) error {
@@ -88,9 +164,16 @@ func (r *RedisSource) BatchSet(ctx context.Context, buckets map[string]time.Time
 
 	pipeline := r.client.Pipeline()
 	for bucketKey, tat := range buckets {
-		// Set a TTL of TAT + 10 minutes to account for clock skew.
-		ttl := tat.UTC().Sub(r.clk.Now()) + 10*time.Minute
-		pipeline.Set(ctx, bucketKey, tat.UTC().UnixNano(), ttl)
+		if r.versionTTL == 0 {
+			pipeline.Set(ctx, bucketKey, tat.UTC().UnixNano(), bucketTTL(tat, r.clk.Now()))
+			continue
+		}
+		setScript.Eval(ctx, pipeline, []string{bucketKey, versionKey(bucketKey)},
+			tat.UTC().UnixNano(),
+			bucketTTL(tat, r.clk.Now()).Milliseconds(),
+			"",
+			r.versionTTL.Milliseconds(),
+		)
 	}
 	_, err := pipeline.Exec(ctx)
 	if err != nil {
@@ -110,11 +193,22 @@ func (r *RedisSource) BatchSetNotExisting(ctx context.Context, buckets map[strin
 	start := r.clk.Now()
 
 	pipeline := r.client.Pipeline()
-	cmds := make(map[string]*redis.BoolCmd, len(buckets))
+	results := make(map[string]func() (bool, error), len(buckets))
 	for bucketKey, tat := range buckets {
-		// Set a TTL of TAT + 10 minutes to account for clock skew.
-		ttl := tat.UTC().Sub(r.clk.Now()) + 10*time.Minute
-		cmds[bucketKey] = pipeline.SetNX(ctx, bucketKey, tat.UTC().UnixNano(), ttl)
+		if r.versionTTL == 0 {
+			results[bucketKey] = pipeline.SetNX(ctx, bucketKey, tat.UTC().UnixNano(), bucketTTL(tat, r.clk.Now())).Result
+			continue
+		}
+		cmd := setScript.Eval(ctx, pipeline, []string{bucketKey, versionKey(bucketKey)},
+			tat.UTC().UnixNano(),
+			bucketTTL(tat, r.clk.Now()).Milliseconds(),
+			"nx",
+			r.versionTTL.Milliseconds(),
+		)
+		results[bucketKey] = func() (bool, error) {
+			stored, err := cmd.Int64()
+			return stored == 1, err
+		}
 	}
 	_, err := pipeline.Exec(ctx)
 	if err != nil {
@@ -124,8 +218,8 @@ func (r *RedisSource) BatchSetNotExisting(ctx context.Context, buckets map[strin
 
 	alreadyExists := make(map[string]bool, len(buckets))
 	totalLatency := r.clk.Since(start)
-	for bucketKey, cmd := range cmds {
-		success, err := cmd.Result()
+	for bucketKey, result := range results {
+		success, err := result()
 		if err != nil {
 			return nil, err
 		}
@@ -138,16 +232,37 @@ func (r *RedisSource) BatchSetNotExisting(ctx context.Context, buckets map[strin
 	return alreadyExists, nil
 }
 
-// BatchIncrement updates TATs for the specified bucketKeys using a pipelined
-// Redis Transaction in order to reduce the number of round-trips to each Redis
+// incrementScript increments the TAT at KEYS[1] and then refreshes its TTL
+// according to the resulting TAT, which concurrent increments may have pushed
+// further into the future than the caller expected. See bucketTTL. The
+// bucket's version at KEYS[2] is incremented.
+//
+// ARGV: cost, now, bucketTTLMargin (ms), bucketVersionTTL (ms).
+var incrementScript = redis.NewScript(bumpLua + `
+local tat = redis.call('INCRBY', KEYS[1], ARGV[1])
+local ttl = math.ceil((tat - tonumber(ARGV[2])) / 1000000)
+if ttl < 0 then
+  ttl = 0
+end
+redis.call('PEXPIRE', KEYS[1], ttl + tonumber(ARGV[3]))
+bump(KEYS[2], ARGV[4])
+return tat
+`)
+
+// BatchIncrement updates TATs for the specified bucketKeys using a Lua script
+// per bucket, pipelined to reduce the number of round-trips to each Redis
 // shard.
 func (r *RedisSource) BatchIncrement(ctx context.Context, buckets map[string]increment) error {
 	start := r.clk.Now()
 
 	pipeline := r.client.Pipeline()
 	for bucketKey, incr := range buckets {
-		pipeline.IncrBy(ctx, bucketKey, incr.cost.Nanoseconds())
-		pipeline.Expire(ctx, bucketKey, incr.ttl)
+		incrementScript.Eval(ctx, pipeline, []string{bucketKey, versionKey(bucketKey)},
+			incr.cost.Nanoseconds(),
+			r.clk.Now().UnixNano(),
+			bucketTTLMargin.Milliseconds(),
+			r.versionTTL.Milliseconds(),
+		)
 	}
 	_, err := pipeline.Exec(ctx)
 	if err != nil {
@@ -160,12 +275,30 @@ func (r *RedisSource) BatchIncrement(ctx context.Context, buckets map[string]inc
 	return nil
 }
 
-// Get retrieves the TAT at the specified bucketKey. If the bucketKey does not
-// exist, ErrBucketNotFound is returned.
+// getScript refunds the expired reservations held against the bucket at
+// KEYS[1], which are recorded in the hash at KEYS[2], and then returns the
+// bucket's TAT, or nil if it doesn't exist. Reading a bucket this way means a
+// reservation which is never committed or released is refunded by the next
+// read once it expires, rather than only by the next reservation. A refund
+// increments the bucket's version at KEYS[3].
+//
+// ARGV: now, bucketVersionTTL (ms).
+var getScript = redis.NewScript(refundLua + `
+refundExpired(tonumber(ARGV[1]), ARGV[2])
+return redis.call('GET', KEYS[1])
+`)
+
+// Get retrieves the TAT at the specified bucketKey, refunding any expired
+// reservations held against it first. If the bucketKey does not exist,
+// ErrBucketNotFound is returned.
 func (r *RedisSource) Get(ctx context.Context, bucketKey string) (time.Time, error) {
 	start := r.clk.Now()
 
-	tatNano, err := r.client.Get(ctx, bucketKey).Int64()
+	tatNano, err := getScript.Run(ctx, r.client,
+		[]string{bucketKey, reservationsKey(bucketKey), versionKey(bucketKey)},
+		r.clk.Now().UnixNano(),
+		r.versionTTL.Milliseconds(),
+	).Int64()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			// Bucket key does not exist.
@@ -181,16 +314,20 @@ func (r *RedisSource) Get(ctx context.Context, bucketKey string) (time.Time, err
 	return time.Unix(0, tatNano).UTC(), nil
 }
 
-// BatchGet retrieves the TATs at the specified bucketKeys using a pipelined
-// Redis Transaction in order to reduce the number of round-trips to each Redis
-// shard. If a bucketKey does not exist, it WILL NOT be included in the returned
-// map.
+// BatchGet retrieves the TATs at the specified bucketKeys, refunding any
+// expired reservations held against them first, using a Lua script per bucket,
+// pipelined to reduce the number of round-trips to each Redis shard. If a
+// bucketKey does not exist, it WILL NOT be included in the returned map.
 func (r *RedisSource) BatchGet(ctx context.Context, bucketKeys []string) (map[string]time.Time, error) {
 	start := r.clk.Now()
 
 	pipeline := r.client.Pipeline()
 	for _, bucketKey := range bucketKeys {
-		pipeline.Get(ctx, bucketKey)
+		getScript.Eval(ctx, pipeline,
+			[]string{bucketKey, reservationsKey(bucketKey), versionKey(bucketKey)},
+			r.clk.Now().UnixNano(),
+			r.versionTTL.Milliseconds(),
+		)
 	}
 	results, err := pipeline.Exec(ctx)
 	if err != nil && !errors.Is(err, redis.Nil) {
@@ -203,7 +340,7 @@ func (r *RedisSource) BatchGet(ctx context.Context, bucketKeys []string) (map[st
 	tats := make(map[string]time.Time, len(bucketKeys))
 	notFoundCount := 0
 	for i, result := range results {
-		tatNano, err := result.(*redis.StringCmd).Int64()
+		tatNano, err := result.(*redis.Cmd).Int64()
 		if err != nil {
 			if !errors.Is(err, redis.Nil) {
 				// This should never happen as any errors should have been
@@ -230,12 +367,160 @@ func (r *RedisSource) BatchGet(ctx context.Context, bucketKeys []string) (map[st
 	return tats, nil
 }
 
+// getVersionedScript is like getScript, but returns the bucket's TAT and its
+// version at KEYS[3], each of which is "" if it doesn't exist.
+//
+// ARGV: now, bucketVersionTTL (ms).
+var getVersionedScript = redis.NewScript(refundLua + `
+refundExpired(tonumber(ARGV[1]), ARGV[2])
+return {redis.call('GET', KEYS[1]) or '', redis.call('GET', KEYS[3]) or ''}
+`)
+
+// BatchGetVersioned retrieves the TATs and versions of the specified
+// bucketKeys, refunding any expired reservations held against them first,
+// using a Lua script per bucket, pipelined to reduce the number of round-trips
+// to each Redis shard. If a bucketKey does not exist, it WILL NOT be included
+// in the returned TATs, and if it hasn't been written to within
+// bucketVersionTTL it WILL NOT be included in the returned versions.
+func (r *RedisSource) BatchGetVersioned(ctx context.Context, bucketKeys []string) (map[string]time.Time, map[string]int64, error) {
+	start := r.clk.Now()
+
+	pipeline := r.client.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(bucketKeys))
+	for _, bucketKey := range bucketKeys {
+		cmds = append(cmds, getVersionedScript.Eval(ctx, pipeline,
+			[]string{bucketKey, reservationsKey(bucketKey), versionKey(bucketKey)},
+			r.clk.Now().UnixNano(),
+			r.versionTTL.Milliseconds(),
+		))
+	}
+	_, err := pipeline.Exec(ctx)
+	if err != nil {
+		r.observeLatency("batchgetversioned", r.clk.Since(start), err)
+		return nil, nil, err
+	}
+
+	tats := make(map[string]time.Time, len(bucketKeys))
+	versions := make(map[string]int64, len(bucketKeys))
+	for i, bucketKey := range bucketKeys {
+		result, err := cmds[i].StringSlice()
+		if err != nil || len(result) != 2 {
+			err = fmt.Errorf("unexpected result %v from get versioned script: %w", result, err)
+			r.observeLatency("batchgetversioned", r.clk.Since(start), err)
+			return nil, nil, err
+		}
+		if result[0] != "" {
+			tatNano, err := strconv.ParseInt(result[0], 10, 64)
+			if err != nil {
+				r.observeLatency("batchgetversioned", r.clk.Since(start), err)
+				return nil, nil, err
+			}
+			tats[bucketKey] = time.Unix(0, tatNano).UTC()
+		}
+		if result[1] != "" {
+			version, err := strconv.ParseInt(result[1], 10, 64)
+			if err != nil {
+				r.observeLatency("batchgetversioned", r.clk.Since(start), err)
+				return nil, nil, err
+			}
+			versions[bucketKey] = version
+		}
+	}
+
+	r.observeLatency("batchgetversioned", r.clk.Since(start), nil)
+	return tats, versions, nil
+}
+
+// setIfUnchangedScript stores the TAT at KEYS[1], and increments the version
+// at KEYS[2], only if the version is still ARGV[1]. A missing version is
+// version 0. If ARGV[2] is 0 the version is checked but nothing is written.
+// It returns 1 if the version had changed, otherwise 0.
+//
+// ARGV: version, tat, bucket TTL (ms), bucketVersionTTL (ms).
+var setIfUnchangedScript = redis.NewScript(bumpLua + `
+local version = tonumber(redis.call('GET', KEYS[2]) or '0')
+if version ~= tonumber(ARGV[1]) then
+  return 1
+end
+if ARGV[2] ~= '0' then
+  redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+  bump(KEYS[2], ARGV[4])
+end
+return 0
+`)
+
+// BatchSetIfUnchanged stores the TATs of the specified buckets whose versions
+// are unchanged using a Lua script per bucket, pipelined to reduce the number
+// of round-trips to each Redis shard. It returns the bucketKeys whose versions
+// had changed, which were not written.
+//
+// Versions expire after bucketVersionTTL without a write, after which a
+// bucket's version reads as 0 again. A receipt old enough to observe this is
+// long expired.
+func (r *RedisSource) BatchSetIfUnchanged(ctx context.Context, buckets map[string]versionedSet) (map[string]bool, error) {
+	start := r.clk.Now()
+
+	pipeline := r.client.Pipeline()
+	cmds := make(map[string]*redis.Cmd, len(buckets))
+	for bucketKey, set := range buckets {
+		var tatNano int64
+		var ttl time.Duration
+		if !set.tat.IsZero() {
+			tatNano = set.tat.UTC().UnixNano()
+			ttl = bucketTTL(set.tat, r.clk.Now())
+		}
+		cmds[bucketKey] = setIfUnchangedScript.Eval(ctx, pipeline,
+			[]string{bucketKey, versionKey(bucketKey)},
+			set.version,
+			tatNano,
+			ttl.Milliseconds(),
+			r.versionTTL.Milliseconds(),
+		)
+	}
+	_, err := pipeline.Exec(ctx)
+	if err != nil {
+		r.observeLatency("batchsetifunchanged", r.clk.Since(start), err)
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+	for bucketKey, cmd := range cmds {
+		result, err := cmd.Int64()
+		if err != nil {
+			r.observeLatency("batchsetifunchanged", r.clk.Since(start), err)
+			return nil, err
+		}
+		if result == 1 {
+			changed[bucketKey] = true
+		}
+	}
+
+	r.observeLatency("batchsetifunchanged", r.clk.Since(start), nil)
+	return changed, nil
+}
+
+// deleteScript deletes the bucket at KEYS[1] and increments its version at
+// KEYS[2]. It's used in place of DEL while versions are tracked.
+//
+// ARGV: bucketVersionTTL (ms).
+var deleteScript = redis.NewScript(bumpLua + `
+redis.call('DEL', KEYS[1])
+bump(KEYS[2], ARGV[1])
+return 1
+`)
+
 // Delete deletes the TAT at the specified bucketKey ('name:id'). A nil return
 // value does not indicate that the bucketKey existed.
 func (r *RedisSource) Delete(ctx context.Context, bucketKey string) error {
 	start := r.clk.Now()
 
-	err := r.client.Del(ctx, bucketKey).Err()
+	var err error
+	if r.versionTTL == 0 {
+		err = r.client.Del(ctx, bucketKey).Err()
+	} else {
+		err = deleteScript.Run(ctx, r.client, []string{bucketKey, versionKey(bucketKey)},
+			r.versionTTL.Milliseconds()).Err()
+	}
 	if err != nil {
 		r.observeLatency("delete", r.clk.Since(start), err)
 		return err
@@ -261,3 +546,684 @@ func (r *RedisSource) Ping(ctx context.Context) error {
 	r.observeLatency("ping", r.clk.Since(start), nil)
 	return nil
 }
+
+// refundLua is shared by the reservation scripts and the get scripts. Its
+// refund returns cost to the bucket at KEYS[1], deleting the bucket if it
+// would otherwise be full, and increments the bucket's version at KEYS[3]. Its
+// refundExpired refunds and removes each of the reservations recorded in the
+// hash at KEYS[2] which expired by now.
+//
+// Note: Lua numbers are doubles so TATs, which are Unix nanoseconds, lose
+// precision when compared. The resulting error is well under a microsecond.
+// Stored TATs are only ever modified with exact integer commands.
+const refundLua = bumpLua + `
+local function refund(cost, now, versionTTL)
+  local tat = redis.call('GET', KEYS[1])
+  if not tat or tonumber(tat) <= now then
+    return
+  end
+  bump(KEYS[3], versionTTL)
+  if tonumber(tat) - cost <= now then
+    redis.call('DEL', KEYS[1])
+  else
+    redis.call('DECRBY', KEYS[1], cost)
+  end
+end
+
+local function refundExpired(now, versionTTL)
+  local held = redis.call('HGETALL', KEYS[2])
+  for i = 1, #held, 2 do
+    local cost, expiresAt = string.match(held[i + 1], '^(%d+):(%d+)$')
+    if tonumber(expiresAt) <= now then
+      redis.call('HDEL', KEYS[2], held[i])
+      refund(tonumber(cost), now, versionTTL)
+    end
+  end
+end
+`
+
+// reserveScript refunds expired reservations held against the bucket at
+// KEYS[1] and then, if the bucket has sufficient capacity, spends the cost and
+// records it in the hash of reservations at KEYS[2]. The hash is kept at
+// least until the reservation could be refunded into a bucket which hasn't
+// yet refilled, so an expired reservation is always found by the next read of
+// the bucket. Every write increments the bucket's version at KEYS[3]. It
+// returns whether the cost was reserved and the TAT prior to the spend, or ""
+// if the bucket did not exist.
+//
+// ARGV: now, cost, burstOffset, ttl (ms), token, expiresAt, now - jitter +
+// cost, now - jitter, reservations ttl (ms), bucketVersionTTL (ms).
+var reserveScript = redis.NewScript(refundLua + `
+local now = tonumber(ARGV[1])
+refundExpired(now, ARGV[10])
+
+local tat = redis.call('GET', KEYS[1])
+local earliest = tonumber(ARGV[8])
+local start = earliest
+if tat and tonumber(tat) > earliest then
+  start = tonumber(tat)
+end
+if start + tonumber(ARGV[2]) - tonumber(ARGV[3]) > now then
+  return {0, tat or ''}
+end
+
+if start == earliest then
+  redis.call('SET', KEYS[1], ARGV[7], 'PX', ARGV[4])
+else
+  redis.call('INCRBY', KEYS[1], ARGV[2])
+  redis.call('PEXPIRE', KEYS[1], ARGV[4])
+end
+bump(KEYS[3], ARGV[10])
+redis.call('HSET', KEYS[2], ARGV[5], ARGV[2] .. ':' .. ARGV[6])
+if redis.call('PTTL', KEYS[2]) < tonumber(ARGV[9]) then
+  redis.call('PEXPIRE', KEYS[2], ARGV[9])
+end
+return {1, tat or ''}
+`)
+
+// settleScript removes the reservation held by a token against the bucket at
+// KEYS[1] from the hash of reservations at KEYS[2]. The cost is refunded if
+// the reservation is being released or has expired, incrementing the bucket's
+// version at KEYS[3]. It returns 1 if a reservation was committed or released,
+// otherwise 0.
+//
+// ARGV: token, now, "commit" or "release", bucketVersionTTL (ms).
+var settleScript = redis.NewScript(refundLua + `
+local now = tonumber(ARGV[2])
+local entry = redis.call('HGET', KEYS[2], ARGV[1])
+if not entry then
+  return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+local cost, expiresAt = string.match(entry, '^(%d+):(%d+)$')
+if tonumber(expiresAt) <= now then
+  refund(tonumber(cost), now, ARGV[4])
+  return 0
+end
+if ARGV[3] == 'release' then
+  refund(tonumber(cost), now, ARGV[4])
+end
+return 1
+`)
+
+// reservationsKey returns the key of the hash of reservations held against
+// bucketKey. The hash tag places it on the same shard as the bucket, which
+// the scripts above require.
+func reservationsKey(bucketKey string) string {
+	return "{" + bucketKey + "}:reservations"
+}
+
+// BatchReserve provisionally spends against the specified buckets using a
+// Lua script per bucket, pipelined to reduce the number of round-trips to
+// each Redis shard. Each script records its reservation alongside the bucket
+// it was spent against, so a spend is never made without a record of it.
+func (r *RedisSource) BatchReserve(ctx context.Context, token string, now, expiresAt time.Time, buckets map[string]reservation) (map[string]time.Time, map[string]bool, error) {
+	start := r.clk.Now()
+
+	pipeline := r.client.Pipeline()
+	cmds := make(map[string]*redis.Cmd, len(buckets))
+	for bucketKey, res := range buckets {
+		ttl := max(res.ttl, expiresAt.Sub(now)) + bucketTTLMargin
+		// Another write may leave the bucket draining for up to res.ttl after
+		// the reservation expires.
+		reservationsTTL := expiresAt.Sub(now) + res.ttl + bucketTTLMargin
+		cmds[bucketKey] = reserveScript.Eval(ctx, pipeline,
+			[]string{bucketKey, reservationsKey(bucketKey), versionKey(bucketKey)},
+			now.UnixNano(),
+			res.cost.Nanoseconds(),
+			res.burstOffset.Nanoseconds(),
+			ttl.Milliseconds(),
+			token,
+			expiresAt.UnixNano(),
+			now.Add(res.cost-res.jitter).UnixNano(),
+			now.Add(-res.jitter).UnixNano(),
+			reservationsTTL.Milliseconds(),
+			r.versionTTL.Milliseconds(),
+		)
+	}
+	_, err := pipeline.Exec(ctx)
+	if err != nil {
+		r.observeLatency("batchreserve", r.clk.Since(start), err)
+		return nil, nil, err
+	}
+
+	tats := make(map[string]time.Time, len(buckets))
+	reserved := make(map[string]bool, len(buckets))
+	for bucketKey, cmd := range cmds {
+		result, err := cmd.Slice()
+		if err != nil || len(result) != 2 {
+			err = fmt.Errorf("unexpected result %v from reserve script: %w", result, err)
+			r.observeLatency("batchreserve", r.clk.Since(start), err)
+			return nil, nil, err
+		}
+		if result[0] == int64(1) {
+			reserved[bucketKey] = true
+		}
+		tat, _ := result[1].(string)
+		if tat != "" {
+			tatNano, err := strconv.ParseInt(tat, 10, 64)
+			if err != nil {
+				r.observeLatency("batchreserve", r.clk.Since(start), err)
+				return nil, nil, err
+			}
+			tats[bucketKey] = time.Unix(0, tatNano).UTC()
+		}
+	}
+
+	r.observeLatency("batchreserve", r.clk.Since(start), nil)
+	return tats, reserved, nil
+}
+
+// settle commits or releases the reservations held by token against the
+// specified buckets.
+func (r *RedisSource) settle(ctx context.Context, call, token string, bucketKeys []string, now time.Time) error {
+	start := r.clk.Now()
+
+	pipeline := r.client.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(bucketKeys))
+	for _, bucketKey := range bucketKeys {
+		cmds = append(cmds, settleScript.Eval(ctx, pipeline,
+			[]string{bucketKey, reservationsKey(bucketKey), versionKey(bucketKey)},
+			token,
+			now.UnixNano(),
+			call,
+			r.versionTTL.Milliseconds(),
+		))
+	}
+	_, err := pipeline.Exec(ctx)
+	if err != nil {
+		r.observeLatency(call, r.clk.Since(start), err)
+		return err
+	}
+
+	var settledAny, expired bool
+	for _, cmd := range cmds {
+		settled, err := cmd.Int64()
+		if err != nil {
+			r.observeLatency(call, r.clk.Since(start), err)
+			return err
+		}
+		if settled == 0 {
+			expired = true
+		} else {
+			settledAny = true
+		}
+	}
+	if !settledAny || (expired && call == "commit") {
+		r.observeLatency(call, r.clk.Since(start), redis.Nil)
+		return ErrReservationNotFound
+	}
+
+	r.observeLatency(call, r.clk.Since(start), nil)
+	return nil
+}
+
+// Commit makes the provisional spends held by token permanent.
+func (r *RedisSource) Commit(ctx context.Context, token string, bucketKeys []string, now time.Time) error {
+	return r.settle(ctx, "commit", token, bucketKeys, now)
+}
+
+// Release refunds the provisional spends held by token.
+func (r *RedisSource) Release(ctx context.Context, token string, bucketKeys []string, now time.Time) error {
+	return r.settle(ctx, "release", token, bucketKeys, now)
+}
+
+// TTLReport summarizes a call to SetMissingTTLs.
+type TTLReport struct {
+	// Sampled is the number of distinct keys examined.
+	Sampled int
+
+	// MissingTTL is the number of sampled bucket keys which had no TTL.
+	MissingTTL int
+
+	// Expired is the number of bucket keys without a TTL whose buckets had
+	// already fully refilled, and which were deleted rather than given one.
+	Expired int
+
+	// ReclaimedBytes is the memory freed by deleting expired bucket keys, as
+	// reported by MEMORY USAGE.
+	ReclaimedBytes int64
+}
+
+// setMissingTTLScript gives the bucket key at KEYS[1] a TTL, if it's lacking
+// one, or deletes it if its bucket has already fully refilled. Keys which
+// aren't bucket keys are left alone. It returns 0 if the key was left alone, 1
+// if it was given a TTL, or 2 if it was deleted, along with the number of bytes
+// freed by deleting it.
+//
+// ARGV: now, bucketTTLMargin (ms).
+var setMissingTTLScript = redis.NewScript(`
+if redis.call('TYPE', KEYS[1]).ok ~= 'string' or redis.call('PTTL', KEYS[1]) ~= -1 then
+  return {0, 0}
+end
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat then
+  return {0, 0}
+end
+local now = tonumber(ARGV[1])
+if tat <= now then
+  local usage = redis.call('MEMORY', 'USAGE', KEYS[1]) or 0
+  redis.call('DEL', KEYS[1])
+  return {2, usage}
+end
+redis.call('PEXPIRE', KEYS[1], math.ceil((tat - now) / 1000000) + tonumber(ARGV[2]))
+return {1, 0}
+`)
+
+// SetMissingTTLs samples up to sampleSize random keys from each shard and
+// gives any bucket keys among them which lack a TTL the TTL they would have
+// been given when last written. Bucket keys written before TTLs were applied
+// to every write never expire, so this should be run repeatedly until the
+// reported MissingTTL falls to zero.
+func (r *RedisSource) SetMissingTTLs(ctx context.Context, sampleSize int) (TTLReport, error) {
+	start := r.clk.Now()
+
+	var mu sync.Mutex
+	var report TTLReport
+	err := r.client.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+		pipeline := shard.Pipeline()
+		for range sampleSize {
+			pipeline.RandomKey(ctx)
+		}
+		results, err := pipeline.Exec(ctx)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		keys := make([]string, 0, len(results))
+		for _, result := range results {
+			key, err := result.(*redis.StringCmd).Result()
+			if err != nil {
+				// The shard is empty.
+				continue
+			}
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+
+		shardReport, err := r.setMissingTTLs(ctx, shard, keys)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		report.Sampled += shardReport.Sampled
+		report.MissingTTL += shardReport.MissingTTL
+		report.Expired += shardReport.Expired
+		report.ReclaimedBytes += shardReport.ReclaimedBytes
+		return nil
+	})
+	if err != nil {
+		r.observeLatency("setmissingttls", r.clk.Since(start), err)
+		return TTLReport{}, err
+	}
+
+	r.observeLatency("setmissingttls", r.clk.Since(start), nil)
+	return report, nil
+}
+
+// setMissingTTLs applies setMissingTTLScript to each of the provided keys.
+func (r *RedisSource) setMissingTTLs(ctx context.Context, client redis.Cmdable, keys []string) (TTLReport, error) {
+	pipeline := client.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, setMissingTTLScript.Eval(ctx, pipeline, []string{key},
+			r.clk.Now().UnixNano(),
+			bucketTTLMargin.Milliseconds(),
+		))
+	}
+	_, err := pipeline.Exec(ctx)
+	if err != nil {
+		return TTLReport{}, err
+	}
+
+	report := TTLReport{Sampled: len(keys)}
+	for _, cmd := range cmds {
+		result, err := cmd.Int64Slice()
+		if err != nil || len(result) != 2 {
+			return TTLReport{}, fmt.Errorf("unexpected result %v from set missing TTL script: %w", result, err)
+		}
+		switch result[0] {
+		case 1:
+			report.MissingTTL++
+		case 2:
+			report.MissingTTL++
+			report.Expired++
+			report.ReclaimedBytes += result[1]
+		}
+	}
+	return report, nil
+}
+
+// shardsByAddr returns the shards of the *redis.Ring which are currently
+// reachable, ordered by address.
+func (r *RedisSource) shardsByAddr(ctx context.Context) ([]*redis.Client, error) {
+	var mu sync.Mutex
+	var shards []*redis.Client
+	err := r.client.ForEachShard(ctx, func(_ context.Context, shard *redis.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+		shards = append(shards, shard)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(shards, func(a, b *redis.Client) int {
+		return strings.Compare(a.Options().Addr, b.Options().Addr)
+	})
+	return shards, nil
+}
+
+// isBucketKey returns false for the keys of reservations and bucket versions,
+// which are stored alongside bucket keys.
+func isBucketKey(key string) bool {
+	return !strings.HasPrefix(key, "{")
+}
+
+// ExportTATs scans the shards one at a time, in order of their addresses,
+// making a single SCAN of up to count keys per call. The cursor is the address
+// of the shard being scanned and its SCAN cursor, separated by a space. Every
+// shard must be reachable for the export to complete.
+func (r *RedisSource) ExportTATs(ctx context.Context, cursor string, count int) (TATBatch, error) {
+	start := r.clk.Now()
+
+	batch, err := r.exportTATs(ctx, cursor, count)
+	if err != nil {
+		r.observeLatency("exporttats", r.clk.Since(start), err)
+		return TATBatch{}, err
+	}
+
+	r.observeLatency("exporttats", r.clk.Since(start), nil)
+	return batch, nil
+}
+
+func (r *RedisSource) exportTATs(ctx context.Context, cursor string, count int) (TATBatch, error) {
+	if count < 1 {
+		return TATBatch{}, fmt.Errorf("invalid export count %d", count)
+	}
+	shards, err := r.shardsByAddr(ctx)
+	if err != nil {
+		return TATBatch{}, err
+	}
+	if len(shards) == 0 {
+		return TATBatch{}, errors.New("no shards available")
+	}
+
+	shardIdx := 0
+	var scanCursor uint64
+	if cursor != "" {
+		addr, scan, ok := strings.Cut(cursor, " ")
+		if ok {
+			scanCursor, err = strconv.ParseUint(scan, 10, 64)
+		}
+		if !ok || err != nil {
+			return TATBatch{}, fmt.Errorf("malformed export cursor %q", cursor)
+		}
+		shardIdx = slices.IndexFunc(shards, func(shard *redis.Client) bool {
+			return shard.Options().Addr == addr
+		})
+		if shardIdx == -1 {
+			return TATBatch{}, fmt.Errorf("shard %q of export cursor is not available", addr)
+		}
+	}
+	shard := shards[shardIdx]
+
+	keys, scanCursor, err := shard.Scan(ctx, scanCursor, "", int64(count)).Result()
+	if err != nil {
+		return TATBatch{}, err
+	}
+
+	pipeline := shard.Pipeline()
+	cmds := make(map[string]*redis.StringCmd, len(keys))
+	for _, key := range keys {
+		if isBucketKey(key) {
+			cmds[key] = pipeline.Get(ctx, key)
+		}
+	}
+	if len(cmds) > 0 {
+		_, err = pipeline.Exec(ctx)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return TATBatch{}, err
+		}
+	}
+
+	var entries []TATEntry
+	for _, key := range keys {
+		cmd, ok := cmds[key]
+		if !ok {
+			continue
+		}
+		tatNano, err := cmd.Int64()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				// The bucket expired since it was scanned.
+				continue
+			}
+			return TATBatch{}, fmt.Errorf("reading TAT of %q: %w", key, err)
+		}
+		entries = append(entries, TATEntry{BucketKey: key, TAT: time.Unix(0, tatNano).UTC()})
+	}
+
+	next := ""
+	if scanCursor != 0 {
+		next = fmt.Sprintf("%s %d", shard.Options().Addr, scanCursor)
+	} else if shardIdx+1 < len(shards) {
+		next = shards[shardIdx+1].Options().Addr + " 0"
+	}
+	return newTATBatch(entries, next), nil
+}
+
+// importTATScript stores the TAT at KEYS[1], unless the bucket already exists
+// with a later TAT, and increments the bucket's version at KEYS[2]. It returns
+// 1 if the TAT was stored, otherwise 0.
+//
+// ARGV: tat, bucket TTL (ms), bucketVersionTTL (ms).
+var importTATScript = redis.NewScript(bumpLua + `
+local tat = redis.call('GET', KEYS[1])
+if tat and tonumber(tat) >= tonumber(ARGV[1]) then
+  return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+bump(KEYS[2], ARGV[3])
+return 1
+`)
+
+// ImportTATs restores the TATs of batch using a Lua script per bucket,
+// pipelined to reduce the number of round-trips to each Redis shard.
+func (r *RedisSource) ImportTATs(ctx context.Context, batch TATBatch, now time.Time) (int, error) {
+	start := r.clk.Now()
+
+	entries, err := unrefilledTATs(batch, now)
+	if err != nil {
+		r.observeLatency("importtats", r.clk.Since(start), err)
+		return 0, err
+	}
+	if len(entries) == 0 {
+		r.observeLatency("importtats", r.clk.Since(start), nil)
+		return 0, nil
+	}
+
+	pipeline := r.client.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(entries))
+	for _, e := range entries {
+		cmds = append(cmds, importTATScript.Eval(ctx, pipeline,
+			[]string{e.BucketKey, versionKey(e.BucketKey)},
+			e.TAT.UTC().UnixNano(),
+			bucketTTL(e.TAT, now).Milliseconds(),
+			r.versionTTL.Milliseconds(),
+		))
+	}
+	_, err = pipeline.Exec(ctx)
+	if err != nil {
+		r.observeLatency("importtats", r.clk.Since(start), err)
+		return 0, err
+	}
+
+	var imported int
+	for _, cmd := range cmds {
+		stored, err := cmd.Int64()
+		if err != nil {
+			r.observeLatency("importtats", r.clk.Since(start), err)
+			return 0, err
+		}
+		imported += int(stored)
+	}
+
+	r.observeLatency("importtats", r.clk.Since(start), nil)
+	return imported, nil
+}
+
+// denialStreakKey returns the key of the denial streak of regId. Its value is
+// the Unix milliseconds of the first and last denials of the streak and the
+// number of denials, separated by colons, and it expires when the streak
+// ends. The hash tag spreads streaks across shards by regId, and keeps them
+// out of TAT exports.
+func denialStreakKey(regId int64) string {
+	return denialStreakKeyPrefix + strconv.FormatInt(regId, 10) + "}"
+}
+
+// denialStreakKeyPrefix is the prefix of every key returned by
+// denialStreakKey.
+const denialStreakKeyPrefix = "{denialstreak:"
+
+// recordDenialScript adds a denial to the streak at KEYS[1], starting a new
+// streak if there is none or its last denial was more than gap before now,
+// and expires it gap after now. Times are in milliseconds, which Lua numbers
+// represent exactly.
+//
+// ARGV: now (ms), gap (ms).
+var recordDenialScript = redis.NewScript(`
+local first = ARGV[1]
+local count = 0
+local entry = redis.call('GET', KEYS[1])
+if entry then
+  local f, l, c = string.match(entry, '^(%d+):(%d+):(%d+)$')
+  if f and tonumber(ARGV[1]) - tonumber(l) <= tonumber(ARGV[2]) then
+    first = f
+    count = tonumber(c)
+  end
+end
+redis.call('SET', KEYS[1], first .. ':' .. ARGV[1] .. ':' .. string.format('%d', count + 1), 'PX', ARGV[2])
+return 1
+`)
+
+// RecordDenial adds a denial to the streak of regId using a Lua script, so
+// that concurrent denials of the same account are all counted.
+func (r *RedisSource) RecordDenial(ctx context.Context, regId int64, now time.Time, gap time.Duration) error {
+	start := r.clk.Now()
+
+	if gap < time.Millisecond {
+		err := fmt.Errorf("invalid denial streak gap %s", gap)
+		r.observeLatency("recorddenial", r.clk.Since(start), err)
+		return err
+	}
+	err := recordDenialScript.Run(ctx, r.client, []string{denialStreakKey(regId)},
+		now.UnixMilli(), gap.Milliseconds()).Err()
+	if err != nil {
+		r.observeLatency("recorddenial", r.clk.Since(start), err)
+		return err
+	}
+
+	r.observeLatency("recorddenial", r.clk.Since(start), nil)
+	return nil
+}
+
+// ResetDenialStreak deletes the streak of regId.
+func (r *RedisSource) ResetDenialStreak(ctx context.Context, regId int64) error {
+	start := r.clk.Now()
+
+	err := r.client.Del(ctx, denialStreakKey(regId)).Err()
+	if err != nil {
+		r.observeLatency("resetdenialstreak", r.clk.Since(start), err)
+		return err
+	}
+
+	r.observeLatency("resetdenialstreak", r.clk.Since(start), nil)
+	return nil
+}
+
+// denialStreaksScanCount is the number of keys examined by each SCAN made by
+// DenialStreaks.
+const denialStreaksScanCount = 1000
+
+// DenialStreaks returns every stored streak, scanning each shard in turn.
+// Malformed entries are skipped. Every shard must be reachable.
+func (r *RedisSource) DenialStreaks(ctx context.Context) (map[int64]DenialStreak, error) {
+	start := r.clk.Now()
+
+	streaks, err := r.denialStreaks(ctx)
+	if err != nil {
+		r.observeLatency("denialstreaks", r.clk.Since(start), err)
+		return nil, err
+	}
+
+	r.observeLatency("denialstreaks", r.clk.Since(start), nil)
+	return streaks, nil
+}
+
+func (r *RedisSource) denialStreaks(ctx context.Context) (map[int64]DenialStreak, error) {
+	shards, err := r.shardsByAddr(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	streaks := make(map[int64]DenialStreak)
+	for _, shard := range shards {
+		var cursor uint64
+		for {
+			var keys []string
+			keys, cursor, err = shard.Scan(ctx, cursor, denialStreakKeyPrefix+"*", denialStreaksScanCount).Result()
+			if err != nil {
+				return nil, err
+			}
+			if len(keys) > 0 {
+				values, err := shard.MGet(ctx, keys...).Result()
+				if err != nil {
+					return nil, err
+				}
+				for i, key := range keys {
+					regId, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(key, denialStreakKeyPrefix), "}"), 10, 64)
+					if err != nil {
+						continue
+					}
+					// Keys which expired since the SCAN are nil.
+					value, ok := values[i].(string)
+					if !ok {
+						continue
+					}
+					streak, ok := parseDenialStreak(value)
+					if !ok {
+						continue
+					}
+					streaks[regId] = streak
+				}
+			}
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+	return streaks, nil
+}
+
+// parseDenialStreak parses the value of a key returned by denialStreakKey.
+func parseDenialStreak(value string) (DenialStreak, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return DenialStreak{}, false
+	}
+	var nums [3]int64
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return DenialStreak{}, false
+		}
+		nums[i] = n
+	}
+	return DenialStreak{
+		First: time.UnixMilli(nums[0]).UTC(),
+		Last:  time.UnixMilli(nums[1]).UTC(),
+		Count: nums[2],
+	}, true
+}
@@ -2,6 +2,10 @@ package ratelimits
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
 	"testing"
 	"time"
 
@@ -84,9 +88,9 @@ func TestRedisSource_BatchSetAndGet(t *testing.T) {
 	}
 
 	incr := map[string]increment{
-		"test1": {time.Second, time.Minute},
-		"test2": {time.Second * 2, time.Minute},
-		"test3": {time.Second * 3, time.Minute},
+		"test1": {time.Second},
+		"test2": {time.Second * 2},
+		"test3": {time.Second * 3},
 	}
 
 	err := s.BatchSet(context.Background(), set)
@@ -114,3 +118,202 @@ func TestRedisSource_BatchSetAndGet(t *testing.T) {
 	test.AssertNotError(t, err, "BatchGet() should not error when a key isn't found")
 	test.Assert(t, got["test4"].IsZero(), "BatchGet() should return a zero time for a key that does not exist")
 }
+
+func TestRedisSource_BucketTTLs(t *testing.T) {
+	clk := clock.NewFake()
+	s := newTestRedisSource(clk, map[string]string{
+		"shard1": "10.33.33.4:4218",
+		"shard2": "10.33.33.5:4218",
+	})
+	ctx := context.Background()
+	key := fmt.Sprintf("ttl:%x", rand.Uint64())
+
+	// assertTTL asserts that the bucket key's TTL is within a second of the
+	// expected TTL. Redis counts down the TTL in real time.
+	assertTTL := func(expected time.Duration) {
+		t.Helper()
+		ttl, err := s.client.PTTL(ctx, key).Result()
+		test.AssertNotError(t, err, "PTTL() should not error")
+		test.Assert(t, ttl <= expected && ttl > expected-time.Second,
+			fmt.Sprintf("TTL %s, expected %s", ttl, expected))
+	}
+
+	// A new bucket expires once it has fully refilled, plus the margin.
+	_, err := s.BatchSetNotExisting(ctx, map[string]time.Time{key: clk.Now().Add(time.Hour)})
+	test.AssertNotError(t, err, "BatchSetNotExisting() should not error")
+	assertTTL(time.Hour + bucketTTLMargin)
+
+	// Increments refresh the TTL according to the resulting TAT.
+	clk.Add(10 * time.Minute)
+	err = s.BatchIncrement(ctx, map[string]increment{key: {30 * time.Minute}})
+	test.AssertNotError(t, err, "BatchIncrement() should not error")
+	assertTTL(80*time.Minute + bucketTTLMargin)
+
+	// Refunds shorten it.
+	err = s.BatchIncrement(ctx, map[string]increment{key: {-time.Hour}})
+	test.AssertNotError(t, err, "BatchIncrement() should not error")
+	assertTTL(20*time.Minute + bucketTTLMargin)
+
+	// A TAT in the past leaves only the margin.
+	err = s.BatchSet(ctx, map[string]time.Time{key: clk.Now().Add(-time.Hour)})
+	test.AssertNotError(t, err, "BatchSet() should not error")
+	assertTTL(bucketTTLMargin)
+
+	err = s.BatchSet(ctx, map[string]time.Time{key: clk.Now().Add(2 * time.Hour)})
+	test.AssertNotError(t, err, "BatchSet() should not error")
+	assertTTL(2*time.Hour + bucketTTLMargin)
+}
+
+func TestRedisSource_SetMissingTTLs(t *testing.T) {
+	clk := clock.NewFake()
+	s := newTestRedisSource(clk, map[string]string{
+		"shard1": "10.33.33.4:4218",
+		"shard2": "10.33.33.5:4218",
+	})
+	ctx := context.Background()
+	prefix := fmt.Sprintf("missingttl:%x:", rand.Uint64())
+	draining := prefix + "draining"
+	full := prefix + "full"
+	withTTL := prefix + "withTTL"
+	notBucket := prefix + "notBucket"
+	hash := prefix + "hash"
+
+	// Write keys as they would have been written before TTLs were applied to
+	// every write.
+	test.AssertNotError(t, s.client.Set(ctx, draining, clk.Now().Add(time.Hour).UnixNano(), 0).Err(), "Set() should not error")
+	test.AssertNotError(t, s.client.Set(ctx, full, clk.Now().Add(-time.Second).UnixNano(), 0).Err(), "Set() should not error")
+	test.AssertNotError(t, s.client.Set(ctx, withTTL, clk.Now().Add(time.Hour).UnixNano(), time.Minute).Err(), "Set() should not error")
+	test.AssertNotError(t, s.client.Set(ctx, notBucket, "not a TAT", 0).Err(), "Set() should not error")
+	test.AssertNotError(t, s.client.HSet(ctx, hash, "field", "value").Err(), "HSet() should not error")
+
+	report, err := s.setMissingTTLs(ctx, s.client, []string{draining, full, withTTL, notBucket, hash})
+	test.AssertNotError(t, err, "setMissingTTLs() should not error")
+	test.AssertEquals(t, report.Sampled, 5)
+	test.AssertEquals(t, report.MissingTTL, 2)
+	test.AssertEquals(t, report.Expired, 1)
+	test.Assert(t, report.ReclaimedBytes > 0, "deleting a key should reclaim space")
+
+	// The draining bucket keeps its TAT and expires once it has refilled.
+	ttl, err := s.client.PTTL(ctx, draining).Result()
+	test.AssertNotError(t, err, "PTTL() should not error")
+	test.Assert(t, ttl > time.Hour && ttl <= time.Hour+bucketTTLMargin, fmt.Sprintf("unexpected TTL %s", ttl))
+	tat, err := s.Get(ctx, draining)
+	test.AssertNotError(t, err, "Get() should not error")
+	test.AssertEquals(t, tat, clk.Now().Add(time.Hour))
+
+	// The full bucket is gone, which is indistinguishable from it being full.
+	_, err = s.Get(ctx, full)
+	test.AssertErrorIs(t, err, ErrBucketNotFound)
+
+	// Keys with a TTL and keys which aren't buckets are untouched.
+	ttl, err = s.client.PTTL(ctx, withTTL).Result()
+	test.AssertNotError(t, err, "PTTL() should not error")
+	test.Assert(t, ttl <= time.Minute, fmt.Sprintf("unexpected TTL %s", ttl))
+	test.AssertEquals(t, s.client.PTTL(ctx, notBucket).Val(), time.Duration(-1))
+	test.AssertEquals(t, s.client.PTTL(ctx, hash).Val(), time.Duration(-1))
+
+	// A second pass finds nothing to do.
+	report, err = s.setMissingTTLs(ctx, s.client, []string{draining, full, withTTL, notBucket, hash})
+	test.AssertNotError(t, err, "setMissingTTLs() should not error")
+	test.AssertEquals(t, report, TTLReport{Sampled: 5})
+
+	// Sampling the shards themselves works too.
+	_, err = s.SetMissingTTLs(ctx, 10)
+	test.AssertNotError(t, err, "SetMissingTTLs() should not error")
+}
+
+func TestRedisSource_ExportImportTATs(t *testing.T) {
+	clk := clock.NewFake()
+	s := newTestRedisSource(clk, map[string]string{
+		"shard1": "10.33.33.4:4218",
+		"shard2": "10.33.33.5:4218",
+	})
+	ctx := context.Background()
+
+	prefix := fmt.Sprintf("export:%x:", rand.Uint64())
+	set := make(map[string]time.Time)
+	for i := range 50 {
+		set[fmt.Sprintf("%s%d", prefix, i)] = clk.Now().Add(time.Duration(i+1) * time.Minute)
+	}
+	err := s.BatchSet(ctx, set)
+	test.AssertNotError(t, err, "BatchSet() should not error")
+
+	// Export everything, including buckets written by other tests, a few keys
+	// at a time.
+	snapshot := NewInmemSource(clk)
+	copyTATs(t, s, snapshot, 7, clk.Now())
+	for k, v := range set {
+		tat, err := snapshot.Get(ctx, k)
+		test.AssertNotError(t, err, fmt.Sprintf("bucket %q was not exported", k))
+		test.AssertEquals(t, tat, v)
+	}
+	for k := range snapshot.m {
+		test.Assert(t, isBucketKey(k), fmt.Sprintf("exported non-bucket key %q", k))
+	}
+
+	// Restore our buckets after losing them.
+	for k := range set {
+		err = s.Delete(ctx, k)
+		test.AssertNotError(t, err, "Delete() should not error")
+	}
+	entries := make([]TATEntry, 0, len(set))
+	for k, v := range set {
+		entries = append(entries, TATEntry{BucketKey: k, TAT: v})
+	}
+	imported, err := s.ImportTATs(ctx, newTATBatch(entries, ""), clk.Now())
+	test.AssertNotError(t, err, "ImportTATs() should not error")
+	test.AssertEquals(t, imported, len(set))
+	got, err := s.BatchGet(ctx, slices.Collect(maps.Keys(set)))
+	test.AssertNotError(t, err, "BatchGet() should not error")
+	for k, v := range set {
+		test.AssertEquals(t, got[k], v)
+	}
+
+	// An import never moves a TAT earlier.
+	imported, err = s.ImportTATs(ctx, newTATBatch(entries, ""), clk.Now())
+	test.AssertNotError(t, err, "ImportTATs() should not error")
+	test.AssertEquals(t, imported, 0)
+
+	_, err = s.ExportTATs(ctx, "10.33.33.4:1337 0", 10)
+	test.AssertError(t, err, "ExportTATs() should refuse a cursor of an unknown shard")
+}
+
+func TestRedisSource_DenialStreaks(t *testing.T) {
+	clk := clock.NewFake()
+	s := newTestRedisSource(clk, map[string]string{
+		"shard1": "10.33.33.4:4218",
+		"shard2": "10.33.33.5:4218",
+	})
+	ctx := context.Background()
+
+	regId := rand.Int64()
+	first := clk.Now()
+	for range 3 {
+		err := s.RecordDenial(ctx, regId, clk.Now(), time.Hour)
+		test.AssertNotError(t, err, "RecordDenial() should not error")
+		clk.Add(30 * time.Minute)
+	}
+	streaks, err := s.DenialStreaks(ctx)
+	test.AssertNotError(t, err, "DenialStreaks() should not error")
+	test.AssertEquals(t, streaks[regId], DenialStreak{First: first.UTC(), Last: first.Add(time.Hour).UTC(), Count: 3})
+
+	// The streak expires when it ends.
+	ttl, err := s.client.PTTL(ctx, denialStreakKey(regId)).Result()
+	test.AssertNotError(t, err, "PTTL() should not error")
+	test.Assert(t, ttl > 0 && ttl <= time.Hour, fmt.Sprintf("streak TTL %s, want (0, 1h]", ttl))
+
+	// A denial after more than the gap starts a new streak.
+	clk.Add(2 * time.Hour)
+	err = s.RecordDenial(ctx, regId, clk.Now(), time.Hour)
+	test.AssertNotError(t, err, "RecordDenial() should not error")
+	streaks, err = s.DenialStreaks(ctx)
+	test.AssertNotError(t, err, "DenialStreaks() should not error")
+	test.AssertEquals(t, streaks[regId], DenialStreak{First: clk.Now().UTC(), Last: clk.Now().UTC(), Count: 1})
+
+	err = s.ResetDenialStreak(ctx, regId)
+	test.AssertNotError(t, err, "ResetDenialStreak() should not error")
+	streaks, err = s.DenialStreaks(ctx)
+	test.AssertNotError(t, err, "DenialStreaks() should not error")
+	_, ok := streaks[regId]
+	test.Assert(t, !ok, "streak should have been reset")
+}
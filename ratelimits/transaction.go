@@ -1,13 +1,20 @@
 package ratelimits
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net"
+	"net/netip"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/letsencrypt/boulder/core"
+	blog "github.com/letsencrypt/boulder/log"
 )
 
 // ErrInvalidCost indicates that the cost specified was < 0.
@@ -16,6 +23,24 @@ var ErrInvalidCost = fmt.Errorf("invalid cost, must be >= 0")
 // ErrInvalidCostOverLimit indicates that the cost specified was > limit.Burst.
 var ErrInvalidCostOverLimit = fmt.Errorf("invalid cost, must be <= limit.Burst")
 
+// MalformedTransactionError is returned by the Limiter for a Transaction which
+// wasn't made by one of the Transaction constructors, for instance because it
+// has an unknown kind or no limit.
+type MalformedTransactionError struct {
+	// Field is the Transaction field which is invalid: "kind", "limit" or
+	// "bucketKey".
+	Field string
+
+	// BucketKey is the bucket key of the Transaction, which may be empty.
+	BucketKey string
+
+	kind transactionKind
+}
+
+func (e *MalformedTransactionError) Error() string {
+	return fmt.Sprintf("malformed %s transaction for bucket %q: invalid %s", e.kind, e.BucketKey, e.Field)
+}
+
 // newIPAddressBucketKey validates and returns a bucketKey for limits that use
 // the 'enum:ipAddress' bucket key format.
 func newIPAddressBucketKey(name Name, ip net.IP) (string, error) { //nolint: unparam
@@ -55,53 +80,143 @@ func newRegIdBucketKey(name Name, regId int64) (string, error) {
 }
 
 // newDomainBucketKey validates and returns a bucketKey for limits that use the
-// 'enum:domain' bucket key format.
+// 'enum:domain' bucket key format. The domain is keyed in A-label form.
 func newDomainBucketKey(name Name, orderName string) (string, error) {
-	err := validateIdForName(name, orderName)
+	id, err := normalizeIdForName(name, orderName)
 	if err != nil {
 		return "", err
 	}
-	return joinWithColon(name.EnumString(), orderName), nil
+	err = validateIdForName(name, id)
+	if err != nil {
+		return "", err
+	}
+	return joinWithColon(name.EnumString(), id), nil
 }
 
 // NewRegIdDomainBucketKey validates and returns a bucketKey for limits that use
 // the 'enum:regId:domain' bucket key format. This function is exported for use
-// in ra.resetAccountPausingLimit.
+// in ra.resetAccountPausingLimit. The domain is keyed in A-label form.
 func NewRegIdDomainBucketKey(name Name, regId int64, orderName string) (string, error) {
-	regIdStr := strconv.FormatInt(regId, 10)
-	err := validateIdForName(name, joinWithColon(regIdStr, orderName))
+	id, err := normalizeIdForName(name, joinWithColon(strconv.FormatInt(regId, 10), orderName))
+	if err != nil {
+		return "", err
+	}
+	err = validateIdForName(name, id)
 	if err != nil {
 		return "", err
 	}
-	return joinWithColon(name.EnumString(), regIdStr, orderName), nil
+	return joinWithColon(name.EnumString(), id), nil
+}
+
+const (
+	// globalId is the id of GlobalIssuanceRate overrides.
+	globalId = "global"
+
+	// globalIssuanceShards is the greatest number of buckets across which the
+	// GlobalIssuanceRate limit is divided.
+	globalIssuanceShards = 16
+)
+
+// newGlobalShardBucketKey validates and returns a bucketKey for the
+// GlobalIssuanceRate limit, which uses the 'enum:global:shard' bucket key
+// format for transactions.
+func newGlobalShardBucketKey(shard int) (string, error) {
+	id := joinWithColon(globalId, strconv.Itoa(shard))
+	err := validateIdForName(GlobalIssuanceRate, id)
+	if err != nil {
+		return "", err
+	}
+	return joinWithColon(GlobalIssuanceRate.EnumString(), id), nil
+}
+
+// globalIssuanceShardCount returns the number of buckets across which l, a
+// GlobalIssuanceRate limit, is divided. Each bucket must be left with a burst
+// and count of at least one, so small limits use fewer buckets.
+func globalIssuanceShardCount(l *limit) int {
+	return int(min(globalIssuanceShards, l.burst, l.count))
+}
+
+// globalIssuanceShardLimit returns the share of l, a GlobalIssuanceRate limit,
+// which applies to the bucket of the provided shard. The burst and count of l
+// are divided between the shards, with any remainder going to the lowest
+// shards, so that the shares of every shard sum to l.
+func globalIssuanceShardLimit(l *limit, shard int) *limit {
+	shards := int64(globalIssuanceShardCount(l))
+	share := *l
+	share.burst = l.burst / shards
+	if int64(shard) < l.burst%shards {
+		share.burst++
+	}
+	share.count = l.count / shards
+	if int64(shard) < l.count%shards {
+		share.count++
+	}
+	share.precompute()
+	return &share
 }
 
 // newFQDNSetBucketKey validates and returns a bucketKey for limits that use the
-// 'enum:fqdnSet' bucket key format.
+// 'enum:fqdnSet' bucket key format. The set is hashed with each domain in
+// A-label form.
 func newFQDNSetBucketKey(name Name, orderNames []string) (string, error) { //nolint: unparam
-	err := validateIdForName(name, strings.Join(orderNames, ","))
+	fqdnSet, err := normalizeIdForName(name, strings.Join(orderNames, ","))
+	if err != nil {
+		return "", err
+	}
+	err = validateIdForName(name, fqdnSet)
 	if err != nil {
 		return "", err
 	}
-	id := fmt.Sprintf("%x", core.HashNames(orderNames))
+	id := fmt.Sprintf("%x", core.HashNames(strings.Split(fqdnSet, ",")))
 	return joinWithColon(name.EnumString(), id), nil
 }
 
+// transactionKind determines how a Transaction is processed by the Limiter.
+type transactionKind int
+
+const (
+	// txnAllowOnly Transactions are considered "allowed" regardless of the
+	// bucket's capacity. This is useful for limits that are disabled. It's the
+	// zero value, so that the zero value of Transaction is allow-only.
+	txnAllowOnly transactionKind = iota
+
+	// txnCheckAndSpend Transactions have their cost checked against the
+	// bucket's capacity and spent/refunded, when possible.
+	txnCheckAndSpend
+
+	// txnCheckOnly Transactions have their cost checked against the bucket's
+	// capacity, but never spent/refunded.
+	txnCheckOnly
+
+	// txnSpendOnly Transactions are spent on a best-effort basis. Regardless
+	// of the bucket's capacity, the Transaction is considered "allowed".
+	txnSpendOnly
+)
+
+// String returns the name of the transactionKind, for use in errors.
+func (k transactionKind) String() string {
+	switch k {
+	case txnAllowOnly:
+		return "allow-only"
+	case txnCheckAndSpend:
+		return "check-and-spend"
+	case txnCheckOnly:
+		return "check-only"
+	case txnSpendOnly:
+		return "spend-only"
+	}
+	return fmt.Sprintf("unknown(%d)", int(k))
+}
+
 // Transaction represents a single rate limit operation. It includes a
 // bucketKey, which combines the specific rate limit enum with a unique
 // identifier to form the key where the state of the "bucket" can be referenced
 // or stored by the Limiter, the rate limit being enforced, a cost which MUST be
-// >= 0, and check/spend fields, which indicate how the Transaction should be
-// processed. The following are acceptable combinations of check/spend:
-//   - check-and-spend: when check and spend are both true, the cost will be
-//     checked against the bucket's capacity and spent/refunded, when possible.
-//   - check-only: when only check is true, the cost will be checked against the
-//     bucket's capacity, but will never be spent/refunded.
-//   - spend-only: when only spend is true, spending is best-effort. Regardless
-//     of the bucket's capacity, the transaction will be considered "allowed".
-//   - allow-only: when neither check nor spend are true, the transaction will
-//     be considered "allowed" regardless of the bucket's capacity. This is
-//     useful for limits that are disabled.
+// >= 0, and a kind, which indicates how the Transaction should be processed.
+// Transactions MUST be made by NewSpendTransaction, NewCheckOnlyTransaction,
+// NewSpendOnlyTransaction or newAllowOnlyTransaction, each of which produces
+// exactly one kind, and the Limiter rejects any others with a
+// *MalformedTransactionError.
 //
 // The zero value of Transaction is an allow-only transaction and is valid even if
 // it would fail validateTransaction (for instance because cost and burst are zero).
@@ -109,20 +224,48 @@ type Transaction struct {
 	bucketKey string
 	limit     *limit
 	cost      int64
-	check     bool
-	spend     bool
+	kind      transactionKind
 }
 
 func (txn Transaction) checkOnly() bool {
-	return txn.check && !txn.spend
+	return txn.kind == txnCheckOnly
 }
 
 func (txn Transaction) spendOnly() bool {
-	return txn.spend && !txn.check
+	return txn.kind == txnSpendOnly
 }
 
 func (txn Transaction) allowOnly() bool {
-	return !txn.check && !txn.spend
+	return txn.kind == txnAllowOnly
+}
+
+// spends returns true if the cost of the Transaction is deducted from its
+// bucket's capacity when allowed.
+func (txn Transaction) spends() bool {
+	return txn.kind == txnCheckAndSpend || txn.kind == txnSpendOnly
+}
+
+// wellFormed returns a *MalformedTransactionError unless the Transaction is
+// allow-only, or has a known kind, a limit and a bucketKey as the Transaction
+// constructors guarantee.
+func (txn Transaction) wellFormed() error {
+	malformed := func(field string) error {
+		return &MalformedTransactionError{Field: field, BucketKey: txn.bucketKey, kind: txn.kind}
+	}
+	switch txn.kind {
+	case txnAllowOnly:
+		return nil
+	case txnCheckAndSpend, txnCheckOnly, txnSpendOnly:
+	default:
+		return malformed("kind")
+	}
+	if txn.limit == nil {
+		return malformed("limit")
+	}
+	if txn.bucketKey == "" {
+		return malformed("bucketKey")
+	}
+	return nil
 }
 
 func validateTransaction(txn Transaction) (Transaction, error) {
@@ -143,31 +286,35 @@ func validateTransaction(txn Transaction) (Transaction, error) {
 	return txn, nil
 }
 
-func newTransaction(limit *limit, bucketKey string, cost int64) (Transaction, error) {
+// NewSpendTransaction returns a check-and-spend Transaction.
+func NewSpendTransaction(limit *limit, bucketKey string, cost int64) (Transaction, error) {
 	return validateTransaction(Transaction{
 		bucketKey: bucketKey,
 		limit:     limit,
 		cost:      cost,
-		check:     true,
-		spend:     true,
+		kind:      txnCheckAndSpend,
 	})
 }
 
-func newCheckOnlyTransaction(limit *limit, bucketKey string, cost int64) (Transaction, error) {
+// NewCheckOnlyTransaction returns a Transaction which is checked but never
+// spent.
+func NewCheckOnlyTransaction(limit *limit, bucketKey string, cost int64) (Transaction, error) {
 	return validateTransaction(Transaction{
 		bucketKey: bucketKey,
 		limit:     limit,
 		cost:      cost,
-		check:     true,
+		kind:      txnCheckOnly,
 	})
 }
 
-func newSpendOnlyTransaction(limit *limit, bucketKey string, cost int64) (Transaction, error) {
+// NewSpendOnlyTransaction returns a Transaction which is spent on a
+// best-effort basis, and always allowed.
+func NewSpendOnlyTransaction(limit *limit, bucketKey string, cost int64) (Transaction, error) {
 	return validateTransaction(Transaction{
 		bucketKey: bucketKey,
 		limit:     limit,
 		cost:      cost,
-		spend:     true,
+		kind:      txnSpendOnly,
 	})
 }
 
@@ -188,7 +335,7 @@ type TransactionBuilder struct {
 // that contain the default and override limits, respectively. Overrides is
 // optional, defaults is required.
 func NewTransactionBuilderFromFiles(defaults, overrides string) (*TransactionBuilder, error) {
-	registry, err := newLimitRegistryFromFiles(defaults, overrides)
+	registry, err := newLimitRegistryFromFiles(defaults, overrides, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -199,28 +346,74 @@ func NewTransactionBuilderFromFiles(defaults, overrides string) (*TransactionBui
 // defaults map is expected to contain default limit data. Overrides are not
 // supported. Defaults is required.
 func NewTransactionBuilder(defaults LimitConfigs) (*TransactionBuilder, error) {
-	registry, err := newLimitRegistry(defaults, nil)
+	registry, err := newLimitRegistry(defaults, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 	return &TransactionBuilder{registry}, nil
 }
 
+// ReloadFromFiles replaces the default and override limits, including their
+// modes, with those in the YAML files at the provided paths, as accepted by
+// NewTransactionBuilderFromFiles. Transactions built before the reload are
+// unaffected. If the files cannot be loaded an error is returned and the
+// existing limits are kept.
+func (builder *TransactionBuilder) ReloadFromFiles(defaults, overrides string) error {
+	return builder.reload(defaults, overrides)
+}
+
+// EnforceOverrideCaps replaces the caps on the burst and count of overrides,
+// for the Names in caps, which is keyed by the string representation of each
+// Name. Overrides above their cap are clamped to it, with a warning logged
+// and a metric incremented, or if strict is set, they cause loading the
+// overrides to fail. The caps are applied to the overrides already loaded,
+// and to all those loaded later. Default limits are never capped. It must be
+// called at most once, before the TransactionBuilder is used.
+func (builder *TransactionBuilder) EnforceOverrideCaps(caps map[string]OverrideCap, strict bool, stats prometheus.Registerer, logger blog.Logger) error {
+	capper, err := newOverrideCapper(caps, strict, stats, logger)
+	if err != nil {
+		return err
+	}
+	return builder.setOverrideCaps(capper)
+}
+
+// ReloadFromFilesEvery calls ReloadFromFiles with the provided paths every
+// interval until the context is done. Errors are logged, and the existing
+// limits are kept until the next successful reload.
+func (builder *TransactionBuilder) ReloadFromFilesEvery(ctx context.Context, defaults, overrides string, interval time.Duration, logger blog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := builder.ReloadFromFiles(defaults, overrides)
+			if err != nil {
+				logger.Errf("Failed to reload rate limits from %q and %q: %s", defaults, overrides, err)
+			}
+		}
+	}
+}
+
 // registrationsPerIPAddressTransaction returns a Transaction for the
-// NewRegistrationsPerIPAddress limit for the provided IP address.
+// NewRegistrationsPerIPAddress limit for the provided IP address. The bucket is
+// always per-address, even when its limit is the override of a CIDR range
+// containing the address.
 func (builder *TransactionBuilder) registrationsPerIPAddressTransaction(ip net.IP) (Transaction, error) {
 	bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, ip)
 	if err != nil {
 		return Transaction{}, err
 	}
-	limit, err := builder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	addr, _ := netip.AddrFromSlice(ip)
+	limit, err := builder.getIPAddressLimit(NewRegistrationsPerIPAddress, bucketKey, addr)
 	if err != nil {
 		if errors.Is(err, errLimitDisabled) {
 			return newAllowOnlyTransaction(), nil
 		}
 		return Transaction{}, err
 	}
-	return newTransaction(limit, bucketKey, 1)
+	return NewSpendTransaction(limit, bucketKey, 1)
 }
 
 // registrationsPerIPv6RangeTransaction returns a Transaction for the
@@ -238,24 +431,76 @@ func (builder *TransactionBuilder) registrationsPerIPv6RangeTransaction(ip net.I
 		}
 		return Transaction{}, err
 	}
-	return newTransaction(limit, bucketKey, 1)
+	return NewSpendTransaction(limit, bucketKey, 1)
 }
 
 // ordersPerAccountTransaction returns a Transaction for the NewOrdersPerAccount
-// limit for the provided ACME registration Id.
-func (builder *TransactionBuilder) ordersPerAccountTransaction(regId int64) (Transaction, error) {
+// limit for the provided ACME registration Id, using the default limit for
+// the account's age bucket when no override exists. The bucket key doesn't
+// depend on the age bucket, so an account which moves between buckets keeps
+// its existing bucket.
+func (builder *TransactionBuilder) ordersPerAccountTransaction(regId int64, ageBucket AccountAgeBucket) (Transaction, error) {
 	bucketKey, err := newRegIdBucketKey(NewOrdersPerAccount, regId)
 	if err != nil {
 		return Transaction{}, err
 	}
-	limit, err := builder.getLimit(NewOrdersPerAccount, bucketKey)
+	limit, err := builder.getTieredLimit(NewOrdersPerAccount, bucketKey, ageBucket)
 	if err != nil {
 		if errors.Is(err, errLimitDisabled) {
 			return newAllowOnlyTransaction(), nil
 		}
 		return Transaction{}, err
 	}
-	return newTransaction(limit, bucketKey, 1)
+	return NewSpendTransaction(limit, bucketKey, 1)
+}
+
+// newOrdersPerDomainTransactions returns a Transaction for the
+// NewOrdersPerDomain limit for each unique registered domain of the provided
+// order domain names, so that an order for many subdomains of a registered
+// domain spends its bucket once.
+//
+// Precondition: All orderDomains must comply with policy.WellFormedDomainNames.
+func (builder *TransactionBuilder) newOrdersPerDomainTransactions(orderDomains []string) ([]Transaction, error) {
+	var txns []Transaction
+	for _, name := range FQDNsToETLDsPlusOne(orderDomains) {
+		bucketKey, err := newDomainBucketKey(NewOrdersPerDomain, name)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := builder.getLimit(NewOrdersPerDomain, bucketKey)
+		if err != nil {
+			if errors.Is(err, errLimitDisabled) {
+				continue
+			}
+			return nil, err
+		}
+		txn, err := NewSpendTransaction(limit, bucketKey, 1)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, txn)
+	}
+	return txns, nil
+}
+
+// WillingnessChecksPerAccountTransaction returns a Transaction for the
+// WillingnessChecksPerAccount limit for the provided ACME registration Id,
+// costing one for each of the identifiers checked. This method should be used
+// for checking capacity and spending it, before checking whether the CA is
+// willing to issue for the identifiers.
+func (builder *TransactionBuilder) WillingnessChecksPerAccountTransaction(regId int64, identifiers int) (Transaction, error) {
+	bucketKey, err := newRegIdBucketKey(WillingnessChecksPerAccount, regId)
+	if err != nil {
+		return Transaction{}, err
+	}
+	limit, err := builder.getLimit(WillingnessChecksPerAccount, bucketKey)
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return newAllowOnlyTransaction(), nil
+		}
+		return Transaction{}, err
+	}
+	return NewSpendTransaction(limit, bucketKey, int64(identifiers))
 }
 
 // FailedAuthorizationsPerDomainPerAccountCheckOnlyTransactions returns a slice
@@ -291,7 +536,7 @@ func (builder *TransactionBuilder) FailedAuthorizationsPerDomainPerAccountCheckO
 		// Add a check-only transaction for each per domain per account bucket.
 		// The cost is 0, as we are only checking that the account and domain
 		// pair aren't already over the limit.
-		txn, err := newCheckOnlyTransaction(limit, perDomainPerAccountBucketKey, 1)
+		txn, err := NewCheckOnlyTransaction(limit, perDomainPerAccountBucketKey, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -325,7 +570,7 @@ func (builder *TransactionBuilder) FailedAuthorizationsPerDomainPerAccountSpendO
 	if err != nil {
 		return Transaction{}, err
 	}
-	txn, err := newSpendOnlyTransaction(limit, perDomainPerAccountBucketKey, 1)
+	txn, err := NewSpendOnlyTransaction(limit, perDomainPerAccountBucketKey, 1)
 	if err != nil {
 		return Transaction{}, err
 	}
@@ -359,7 +604,7 @@ func (builder *TransactionBuilder) FailedAuthorizationsForPausingPerDomainPerAcc
 		return Transaction{}, err
 	}
 
-	txn, err := newTransaction(limit, perDomainPerAccountBucketKey, 1)
+	txn, err := NewSpendTransaction(limit, perDomainPerAccountBucketKey, 1)
 	if err != nil {
 		return Transaction{}, err
 	}
@@ -367,6 +612,94 @@ func (builder *TransactionBuilder) FailedAuthorizationsForPausingPerDomainPerAcc
 	return txn, nil
 }
 
+// FailedValidationsPerDomainPerAccountCheckOnlyTransaction returns a
+// check-only Transaction for the provided domain name. An error is returned if
+// the domain name is invalid. This method should be used for checking
+// capacity, before dispatching a challenge for validation.
+func (builder *TransactionBuilder) FailedValidationsPerDomainPerAccountCheckOnlyTransaction(regId int64, domain string) (Transaction, error) {
+	return builder.failedValidationsPerDomainPerAccountTransaction(regId, domain, NewCheckOnlyTransaction)
+}
+
+// FailedValidationsPerDomainPerAccountSpendOnlyTransaction returns a
+// spend-only Transaction for the provided domain name. An error is returned if
+// the domain name is invalid. This method should be used for spending
+// capacity, as a result of each failed validation.
+func (builder *TransactionBuilder) FailedValidationsPerDomainPerAccountSpendOnlyTransaction(regId int64, domain string) (Transaction, error) {
+	return builder.failedValidationsPerDomainPerAccountTransaction(regId, domain, NewSpendOnlyTransaction)
+}
+
+func (builder *TransactionBuilder) failedValidationsPerDomainPerAccountTransaction(regId int64, domain string, newTxn func(*limit, string, int64) (Transaction, error)) (Transaction, error) {
+	// FailedValidationsPerDomainPerAccount limit uses the 'enum:regId' bucket
+	// key format for overrides.
+	perAccountBucketKey, err := newRegIdBucketKey(FailedValidationsPerDomainPerAccount, regId)
+	if err != nil {
+		return Transaction{}, err
+	}
+	limit, err := builder.getLimit(FailedValidationsPerDomainPerAccount, perAccountBucketKey)
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return newAllowOnlyTransaction(), nil
+		}
+		return Transaction{}, err
+	}
+
+	// FailedValidationsPerDomainPerAccount limit uses the 'enum:regId:domain'
+	// bucket key format for transactions.
+	perDomainPerAccountBucketKey, err := NewRegIdDomainBucketKey(FailedValidationsPerDomainPerAccount, regId, domain)
+	if err != nil {
+		return Transaction{}, err
+	}
+	return newTxn(limit, perDomainPerAccountBucketKey, 1)
+}
+
+// GlobalIssuanceRateTransaction returns a Transaction against a randomly
+// chosen shard of the GlobalIssuanceRate limit. It is used by
+// Limiter.SpendGlobalIssuance, which should be used for checking and spending
+// capacity, once for each order finalized.
+func (builder *TransactionBuilder) GlobalIssuanceRateTransaction() (Transaction, error) {
+	limit, err := builder.getLimit(GlobalIssuanceRate, joinWithColon(GlobalIssuanceRate.EnumString(), globalId))
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return newAllowOnlyTransaction(), nil
+		}
+		return Transaction{}, err
+	}
+
+	shard := rand.IntN(globalIssuanceShardCount(limit))
+	bucketKey, err := newGlobalShardBucketKey(shard)
+	if err != nil {
+		return Transaction{}, err
+	}
+	return NewSpendTransaction(globalIssuanceShardLimit(limit, shard), bucketKey, 1)
+}
+
+// globalIssuanceRateShardTransactions returns a zero-cost check-only
+// Transaction for every shard of the GlobalIssuanceRate limit, or nil if the
+// limit is disabled.
+func (builder *TransactionBuilder) globalIssuanceRateShardTransactions() ([]Transaction, error) {
+	limit, err := builder.getLimit(GlobalIssuanceRate, joinWithColon(GlobalIssuanceRate.EnumString(), globalId))
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var txns []Transaction
+	for shard := range globalIssuanceShardCount(limit) {
+		bucketKey, err := newGlobalShardBucketKey(shard)
+		if err != nil {
+			return nil, err
+		}
+		txn, err := NewCheckOnlyTransaction(globalIssuanceShardLimit(limit, shard), bucketKey, 0)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, txn)
+	}
+	return txns, nil
+}
+
 // certificatesPerDomainCheckOnlyTransactions returns a slice of Transactions
 // for the provided order domain names. An error is returned if any of the order
 // domain names are invalid. This method should be used for checking capacity,
@@ -412,7 +745,7 @@ func (builder *TransactionBuilder) certificatesPerDomainCheckOnlyTransactions(re
 			}
 			// Add a check-only transaction for each per account per domain
 			// bucket.
-			txn, err := newCheckOnlyTransaction(perAccountLimit, perAccountPerDomainKey, 1)
+			txn, err := NewCheckOnlyTransaction(perAccountLimit, perAccountPerDomainKey, 1)
 			if err != nil {
 				if errors.Is(err, errLimitDisabled) {
 					continue
@@ -431,7 +764,7 @@ func (builder *TransactionBuilder) certificatesPerDomainCheckOnlyTransactions(re
 				return nil, err
 			}
 			// Add a check-only transaction for each per domain bucket.
-			txn, err := newCheckOnlyTransaction(perDomainLimit, perDomainBucketKey, 1)
+			txn, err := NewCheckOnlyTransaction(perDomainLimit, perDomainBucketKey, 1)
 			if err != nil {
 				return nil, err
 			}
@@ -490,7 +823,7 @@ func (builder *TransactionBuilder) CertificatesPerDomainSpendOnlyTransactions(re
 			}
 			// Add a spend-only transaction for each per account per domain
 			// bucket.
-			txn, err := newSpendOnlyTransaction(perAccountLimit, perAccountPerDomainKey, 1)
+			txn, err := NewSpendOnlyTransaction(perAccountLimit, perAccountPerDomainKey, 1)
 			if err != nil {
 				return nil, err
 			}
@@ -505,7 +838,7 @@ func (builder *TransactionBuilder) CertificatesPerDomainSpendOnlyTransactions(re
 			}
 
 			// Add a spend-only transaction for each per domain bucket.
-			txn, err = newSpendOnlyTransaction(perDomainLimit, perDomainBucketKey, 1)
+			txn, err = NewSpendOnlyTransaction(perDomainLimit, perDomainBucketKey, 1)
 			if err != nil {
 				return nil, err
 			}
@@ -521,7 +854,7 @@ func (builder *TransactionBuilder) CertificatesPerDomainSpendOnlyTransactions(re
 				return nil, err
 			}
 			// Add a spend-only transaction for each per domain bucket.
-			txn, err := newSpendOnlyTransaction(perDomainLimit, perDomainBucketKey, 1)
+			txn, err := NewSpendOnlyTransaction(perDomainLimit, perDomainBucketKey, 1)
 			if err != nil {
 				return nil, err
 			}
@@ -546,14 +879,16 @@ func (builder *TransactionBuilder) certificatesPerFQDNSetCheckOnlyTransaction(or
 		}
 		return Transaction{}, err
 	}
-	return newCheckOnlyTransaction(limit, bucketKey, 1)
+	return NewCheckOnlyTransaction(limit, bucketKey, 1)
 }
 
-// CertificatesPerFQDNSetSpendOnlyTransaction returns a spend-only Transaction
-// for the provided order domain names. This method should only be used for
-// spending capacity, when a certificate is issued.
-func (builder *TransactionBuilder) CertificatesPerFQDNSetSpendOnlyTransaction(orderNames []string) (Transaction, error) {
-	bucketKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, orderNames)
+// CertificatesPerFQDNSetTransaction returns a Transaction for the names of a
+// certificate about to be issued. It's spent when an order is finalized, before
+// issuance, and refunded if issuance fails, so that orders created while the
+// limit had capacity can't all be finalized after it has none. The names must
+// be those of the certificate, from its CSR, rather than those of the order.
+func (builder *TransactionBuilder) CertificatesPerFQDNSetTransaction(certNames []string) (Transaction, error) {
+	bucketKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, certNames)
 	if err != nil {
 		return Transaction{}, err
 	}
@@ -564,30 +899,43 @@ func (builder *TransactionBuilder) CertificatesPerFQDNSetSpendOnlyTransaction(or
 		}
 		return Transaction{}, err
 	}
-	return newSpendOnlyTransaction(limit, bucketKey, 1)
+	return NewSpendTransaction(limit, bucketKey, 1)
 }
 
 // NewOrderLimitTransactions takes in values from a new-order request and
 // returns the set of rate limit transactions that should be evaluated before
 // allowing the request to proceed.
 //
+// The ageBucket of the account selects between any tiers configured for the
+// default limits, use AccountAgeBucketFor to determine it.
+//
+// Unlike NewOrdersPerAccount, NewOrdersPerDomain is spent by renewals. Only
+// ARI renewals are exempt from it, because their new order limits aren't
+// checked at all.
+//
 // Precondition: names must be a list of DNS names that all pass
 // policy.WellFormedDomainNames.
-func (builder *TransactionBuilder) NewOrderLimitTransactions(regId int64, names []string, isRenewal bool) ([]Transaction, error) {
+func (builder *TransactionBuilder) NewOrderLimitTransactions(regId int64, ageBucket AccountAgeBucket, names []string, isRenewal bool) ([]Transaction, error) {
 	makeTxnError := func(err error, limit Name) error {
 		return fmt.Errorf("error constructing rate limit transaction for %s rate limit: %w", limit, err)
 	}
 
 	var transactions []Transaction
 	if !isRenewal {
-		txn, err := builder.ordersPerAccountTransaction(regId)
+		txn, err := builder.ordersPerAccountTransaction(regId, ageBucket)
 		if err != nil {
 			return nil, makeTxnError(err, NewOrdersPerAccount)
 		}
 		transactions = append(transactions, txn)
 	}
 
-	txns, err := builder.FailedAuthorizationsPerDomainPerAccountCheckOnlyTransactions(regId, names)
+	txns, err := builder.newOrdersPerDomainTransactions(names)
+	if err != nil {
+		return nil, makeTxnError(err, NewOrdersPerDomain)
+	}
+	transactions = append(transactions, txns...)
+
+	txns, err = builder.FailedAuthorizationsPerDomainPerAccountCheckOnlyTransactions(regId, names)
 	if err != nil {
 		return nil, makeTxnError(err, FailedAuthorizationsPerDomainPerAccount)
 	}
@@ -608,6 +956,114 @@ func (builder *TransactionBuilder) NewOrderLimitTransactions(regId int64, names
 	return append(transactions, txn), nil
 }
 
+// FinalizeOrderLimitCheckOnlyTransactions takes in values from a finalize
+// request and returns check-only transactions for the certificate limits which
+// FinalizeOrder spends when a certificate is issued.
+//
+// Precondition: names must be a list of DNS names that all pass
+// policy.WellFormedDomainNames.
+func (builder *TransactionBuilder) FinalizeOrderLimitCheckOnlyTransactions(regId int64, names []string, isRenewal bool) ([]Transaction, error) {
+	makeTxnError := func(err error, limit Name) error {
+		return fmt.Errorf("error constructing rate limit transaction for %s rate limit: %w", limit, err)
+	}
+
+	var transactions []Transaction
+	if !isRenewal {
+		txns, err := builder.certificatesPerDomainCheckOnlyTransactions(regId, names)
+		if err != nil {
+			return nil, makeTxnError(err, CertificatesPerDomain)
+		}
+		transactions = append(transactions, txns...)
+	}
+
+	txn, err := builder.certificatesPerFQDNSetCheckOnlyTransaction(names)
+	if err != nil {
+		return nil, makeTxnError(err, CertificatesPerFQDNSet)
+	}
+	return append(transactions, txn), nil
+}
+
+// accountOverviewTransactions returns zero-cost check-only Transactions for
+// every bucket which limits the provided account: its NewOrdersPerAccount
+// bucket, and, for each of the provided domains, its
+// FailedAuthorizationsPerDomainPerAccount bucket, its
+// CertificatesPerDomainPerAccount bucket when an override is configured, and
+// the global CertificatesPerDomain bucket. Buckets for disabled limits are
+// omitted. NewOrdersPerAccount uses the default for the provided age bucket,
+// as ordersPerAccountTransaction does.
+func (builder *TransactionBuilder) accountOverviewTransactions(regId int64, ageBucket AccountAgeBucket, recentDomains []string) ([]Transaction, error) {
+	var txns []Transaction
+	addTxn := func(name Name, limitBucketKey, bucketKey string) error {
+		limit, err := builder.getTieredLimit(name, limitBucketKey, ageBucket)
+		if err != nil {
+			if errors.Is(err, errLimitDisabled) {
+				return nil
+			}
+			return err
+		}
+		txn, err := NewCheckOnlyTransaction(limit, bucketKey, 0)
+		if err != nil {
+			return err
+		}
+		txns = append(txns, txn)
+		return nil
+	}
+
+	ordersBucketKey, err := newRegIdBucketKey(NewOrdersPerAccount, regId)
+	if err != nil {
+		return nil, err
+	}
+	err = addTxn(NewOrdersPerAccount, ordersBucketKey, ordersBucketKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both per domain per account limits use the 'enum:regId' bucket key
+	// format for overrides.
+	failedAuthzsOverrideKey, err := newRegIdBucketKey(FailedAuthorizationsPerDomainPerAccount, regId)
+	if err != nil {
+		return nil, err
+	}
+	certsOverrideKey, err := newRegIdBucketKey(CertificatesPerDomainPerAccount, regId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, domain := range recentDomains {
+		bucketKey, err := NewRegIdDomainBucketKey(FailedAuthorizationsPerDomainPerAccount, regId, domain)
+		if err != nil {
+			return nil, err
+		}
+		err = addTxn(FailedAuthorizationsPerDomainPerAccount, failedAuthzsOverrideKey, bucketKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, domain := range FQDNsToETLDsPlusOne(recentDomains) {
+		// CertificatesPerDomainPerAccount never has a default, so it is only
+		// reported when an override is configured for the account.
+		bucketKey, err := NewRegIdDomainBucketKey(CertificatesPerDomainPerAccount, regId, domain)
+		if err != nil {
+			return nil, err
+		}
+		err = addTxn(CertificatesPerDomainPerAccount, certsOverrideKey, bucketKey)
+		if err != nil {
+			return nil, err
+		}
+
+		bucketKey, err = newDomainBucketKey(CertificatesPerDomain, domain)
+		if err != nil {
+			return nil, err
+		}
+		err = addTxn(CertificatesPerDomain, bucketKey, bucketKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return txns, nil
+}
+
 // NewAccountLimitTransactions takes in an IP address from a new-account request
 // and returns the set of rate limit transactions that should be evaluated
 // before allowing the request to proceed.
@@ -1,9 +1,13 @@
 package ratelimits
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -38,7 +42,46 @@ func TestNewRegistrationsPerIPAddressTransactions(t *testing.T) {
 	txn, err := tb.registrationsPerIPAddressTransaction(net.ParseIP("1.2.3.4"))
 	test.AssertNotError(t, err, "creating transaction")
 	test.AssertEquals(t, txn.bucketKey, "1:1.2.3.4")
-	test.Assert(t, txn.check && txn.spend, "should be check-and-spend")
+	test.Assert(t, txn.kind == txnCheckAndSpend, "should be check-and-spend")
+}
+
+func TestNewRegistrationsPerIPAddressCIDROverrides(t *testing.T) {
+	t.Parallel()
+
+	tb, err := NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "testdata/working_overrides_cidr.yml")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	testCases := []struct {
+		ip          string
+		burst       int64
+		overrideKey string
+	}{
+		// Outside every range, the default applies.
+		{ip: "192.0.2.1", burst: 10000},
+		{ip: "2001:db9::1", burst: 10000},
+		// The most specific override containing the address applies.
+		{ip: "10.1.2.3", burst: 100, overrideKey: "1:10.0.0.0/8"},
+		{ip: "10.20.1.2", burst: 200, overrideKey: "1:10.20.0.0/16"},
+		{ip: "10.20.30.41", burst: 200, overrideKey: "1:10.20.0.0/16"},
+		{ip: "10.20.30.40", burst: 300, overrideKey: "1:10.20.30.40"},
+		{ip: "2001:db8:1234::1", burst: 100, overrideKey: "1:2001:db8::/32"},
+		// IPv4-mapped IPv6 addresses match IPv4 ranges.
+		{ip: "::ffff:10.1.2.3", burst: 100, overrideKey: "1:10.0.0.0/8"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.ip, func(t *testing.T) {
+			t.Parallel()
+			txn, err := tb.registrationsPerIPAddressTransaction(net.ParseIP(tc.ip))
+			test.AssertNotError(t, err, "creating transaction")
+			// The bucket is always per-address.
+			test.AssertEquals(t, txn.bucketKey, "1:"+net.ParseIP(tc.ip).String())
+			test.AssertEquals(t, txn.limit.burst, tc.burst)
+			test.AssertEquals(t, txn.limit.isOverride, tc.overrideKey != "")
+			if tc.overrideKey != "" {
+				test.AssertEquals(t, overrideKey(txn), tc.overrideKey)
+			}
+		})
+	}
 }
 
 func TestNewRegistrationsPerIPv6AddressTransactions(t *testing.T) {
@@ -51,7 +94,7 @@ func TestNewRegistrationsPerIPv6AddressTransactions(t *testing.T) {
 	txn, err := tb.registrationsPerIPv6RangeTransaction(net.ParseIP("2001:db8::1"))
 	test.AssertNotError(t, err, "creating transaction")
 	test.AssertEquals(t, txn.bucketKey, "2:2001:db8::/48")
-	test.Assert(t, txn.check && txn.spend, "should be check-and-spend")
+	test.Assert(t, txn.kind == txnCheckAndSpend, "should be check-and-spend")
 }
 
 func TestNewOrdersPerAccountTransactions(t *testing.T) {
@@ -61,10 +104,117 @@ func TestNewOrdersPerAccountTransactions(t *testing.T) {
 	test.AssertNotError(t, err, "creating TransactionBuilder")
 
 	// A check-and-spend transaction for the global limit.
-	txn, err := tb.ordersPerAccountTransaction(123456789)
+	txn, err := tb.ordersPerAccountTransaction(123456789, AccountAgeUnknown)
 	test.AssertNotError(t, err, "creating transaction")
 	test.AssertEquals(t, txn.bucketKey, "3:123456789")
-	test.Assert(t, txn.check && txn.spend, "should be check-and-spend")
+	test.Assert(t, txn.kind == txnCheckAndSpend, "should be check-and-spend")
+}
+
+func TestNewOrdersPerAccountTiers(t *testing.T) {
+	t.Parallel()
+
+	defaults, err := loadDefaults("testdata/working_default_tiers.yml")
+	test.AssertNotError(t, err, "loading defaults")
+	registry, err := newLimitRegistry(defaults, overridesYAML{{
+		NewOrdersPerAccount.String(): overrideYAML{
+			LimitConfig: LimitConfig{Burst: 3000, Count: 3000, Period: config.Duration{Duration: 3 * time.Hour}},
+			Ids:         []overrideIdYAML{{Id: "1337"}},
+		},
+	}}, nil)
+	test.AssertNotError(t, err, "creating registry")
+	tb := &TransactionBuilder{registry}
+
+	testCases := []struct {
+		name          string
+		regId         int64
+		ageBucket     AccountAgeBucket
+		expectedBurst int64
+	}{
+		{"new account uses its tier", 1, AccountAgeNew, 30},
+		{"established account falls back to the default", 1, AccountAgeEstablished, 300},
+		{"unknown age uses the default", 1, AccountAgeUnknown, 300},
+		{"override takes precedence over the tier", 1337, AccountAgeNew, 3000},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			txns, err := tb.NewOrderLimitTransactions(tc.regId, tc.ageBucket, []string{"example.com"}, false)
+			test.AssertNotError(t, err, "creating transactions")
+			var found bool
+			for _, txn := range txns {
+				if txn.limit == nil || txn.limit.name != NewOrdersPerAccount {
+					continue
+				}
+				found = true
+				test.AssertEquals(t, txn.bucketKey, fmt.Sprintf("3:%d", tc.regId))
+				test.AssertEquals(t, txn.limit.burst, tc.expectedBurst)
+			}
+			test.Assert(t, found, "no NewOrdersPerAccount transaction")
+		})
+	}
+}
+
+func TestNewOrdersPerDomainTransactions(t *testing.T) {
+	t.Parallel()
+
+	defaults, err := loadDefaults("../test/config-next/wfe2-ratelimit-defaults.yml")
+	test.AssertNotError(t, err, "loading defaults")
+	registry, err := newLimitRegistry(defaults, overridesYAML{{
+		NewOrdersPerDomain.String(): overrideYAML{
+			LimitConfig: LimitConfig{Burst: 5, Count: 5, Period: config.Duration{Duration: time.Hour}},
+			Ids:         []overrideIdYAML{{Id: "example.org"}},
+		},
+	}}, nil)
+	test.AssertNotError(t, err, "creating registry")
+	tb := &TransactionBuilder{registry}
+
+	perDomain := func(txns []Transaction) map[string]int64 {
+		bursts := make(map[string]int64)
+		for _, txn := range txns {
+			if txn.limit == nil || txn.limit.name != NewOrdersPerDomain {
+				continue
+			}
+			test.Assert(t, txn.kind == txnCheckAndSpend, "should be check-and-spend")
+			test.AssertEquals(t, txn.cost, int64(1))
+			_, seen := bursts[txn.bucketKey]
+			test.Assert(t, !seen, fmt.Sprintf("%q spent more than once", txn.bucketKey))
+			bursts[txn.bucketKey] = txn.limit.burst
+		}
+		return bursts
+	}
+
+	// Many subdomains of a registered domain spend its bucket once, and the
+	// override of example.org applies to its subdomains.
+	names := []string{"example.com", "www.example.com", "a.b.example.com", "www.example.org", "example.org"}
+	txns, err := tb.NewOrderLimitTransactions(123456789, AccountAgeUnknown, names, false)
+	test.AssertNotError(t, err, "creating transactions")
+	test.AssertDeepEquals(t, perDomain(txns), map[string]int64{"11:example.com": 100000, "11:example.org": 5})
+
+	// Renewals which aren't ARI renewals still spend it.
+	txns, err = tb.NewOrderLimitTransactions(123456789, AccountAgeUnknown, names, true)
+	test.AssertNotError(t, err, "creating transactions")
+	test.AssertDeepEquals(t, perDomain(txns), map[string]int64{"11:example.com": 100000, "11:example.org": 5})
+}
+
+func TestWillingnessChecksPerAccountTransaction(t *testing.T) {
+	t.Parallel()
+
+	tb, err := NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	// A check-and-spend transaction costing one for each identifier.
+	txn, err := tb.WillingnessChecksPerAccountTransaction(123456789, 3)
+	test.AssertNotError(t, err, "creating transaction")
+	test.AssertEquals(t, txn.bucketKey, "12:123456789")
+	test.AssertEquals(t, txn.cost, int64(3))
+	test.Assert(t, txn.kind == txnCheckAndSpend, "should be check-and-spend")
+
+	// Without a default, the limit is disabled.
+	tb, err = NewTransactionBuilderFromFiles("testdata/working_default.yml", "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+	txn, err = tb.WillingnessChecksPerAccountTransaction(123456789, 3)
+	test.AssertNotError(t, err, "creating transaction")
+	test.Assert(t, txn.allowOnly(), "should be allow-only")
 }
 
 func TestFailedAuthorizationsPerDomainPerAccountTransactions(t *testing.T) {
@@ -114,8 +264,116 @@ func TestFailedAuthorizationsForPausingPerDomainPerAccountTransactions(t *testin
 	txn, err := tb.FailedAuthorizationsForPausingPerDomainPerAccountTransaction(13371338, "so.many.labels.here.example.com")
 	test.AssertNotError(t, err, "creating transaction")
 	test.AssertEquals(t, txn.bucketKey, "8:13371338:so.many.labels.here.example.com")
-	test.Assert(t, txn.check && txn.spend, "should be check and spend")
+	test.Assert(t, txn.kind == txnCheckAndSpend, "should be check and spend")
+	test.Assert(t, txn.limit.isOverride, "should be an override")
+}
+
+func TestFailedValidationsPerDomainPerAccountTransactions(t *testing.T) {
+	t.Parallel()
+
+	tb, err := NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "testdata/working_override_13371338.yml")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	// A check-only transaction for the default per-account limit.
+	txn, err := tb.FailedValidationsPerDomainPerAccountCheckOnlyTransaction(123456789, "so.many.labels.here.example.com")
+	test.AssertNotError(t, err, "creating transaction")
+	test.AssertEquals(t, txn.bucketKey, "9:123456789:so.many.labels.here.example.com")
+	test.Assert(t, txn.checkOnly(), "should be check-only")
+	test.Assert(t, !txn.limit.isOverride, "should not be an override")
+
+	// A spend-only transaction for the default per-account limit.
+	txn, err = tb.FailedValidationsPerDomainPerAccountSpendOnlyTransaction(123456789, "so.many.labels.here.example.com")
+	test.AssertNotError(t, err, "creating transaction")
+	test.AssertEquals(t, txn.bucketKey, "9:123456789:so.many.labels.here.example.com")
+	test.Assert(t, txn.spendOnly(), "should be spend-only")
+	test.Assert(t, !txn.limit.isOverride, "should not be an override")
+
+	// A spend-only transaction for the per-account limit override.
+	txn, err = tb.FailedValidationsPerDomainPerAccountSpendOnlyTransaction(13371338, "so.many.labels.here.example.com")
+	test.AssertNotError(t, err, "creating transaction")
+	test.AssertEquals(t, txn.bucketKey, "9:13371338:so.many.labels.here.example.com")
+	test.Assert(t, txn.spendOnly(), "should be spend-only")
 	test.Assert(t, txn.limit.isOverride, "should be an override")
+	test.AssertEquals(t, txn.limit.burst, int64(1337))
+
+	// Without a default or override the limit is disabled.
+	tb, err = NewTransactionBuilderFromFiles("testdata/working_default.yml", "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+	txn, err = tb.FailedValidationsPerDomainPerAccountSpendOnlyTransaction(123456789, "example.com")
+	test.AssertNotError(t, err, "creating transaction")
+	test.Assert(t, txn.allowOnly(), "should be allow-only")
+}
+
+func TestGlobalIssuanceRateTransactions(t *testing.T) {
+	t.Parallel()
+
+	tb, err := NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	// The default is divided across every shard.
+	txn, err := tb.GlobalIssuanceRateTransaction()
+	test.AssertNotError(t, err, "creating transaction")
+	test.Assert(t, strings.HasPrefix(txn.bucketKey, "10:global:"), "should be a shard bucket key")
+	test.Assert(t, txn.kind == txnCheckAndSpend, "should be check and spend")
+	test.Assert(t, !txn.limit.isOverride, "should not be an override")
+	test.AssertEquals(t, txn.limit.burst, int64(62500))
+
+	// The override is divided across fewer shards, so that each has a burst
+	// and count of at least one.
+	tb, err = NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "testdata/working_override_global.yml")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+	txns, err := tb.globalIssuanceRateShardTransactions()
+	test.AssertNotError(t, err, "creating transactions")
+	test.AssertEquals(t, len(txns), 10)
+	for i, txn := range txns {
+		test.AssertEquals(t, txn.bucketKey, fmt.Sprintf("10:global:%d", i))
+		test.Assert(t, txn.limit.isOverride, "should be an override")
+		test.AssertEquals(t, txn.limit.burst, int64(1))
+		test.AssertEquals(t, txn.limit.emissionInterval, time.Hour.Nanoseconds())
+	}
+
+	// Without a default or override the limit is disabled.
+	tb, err = NewTransactionBuilderFromFiles("testdata/working_default.yml", "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+	txn, err = tb.GlobalIssuanceRateTransaction()
+	test.AssertNotError(t, err, "creating transaction")
+	test.Assert(t, txn.allowOnly(), "should be allow-only")
+}
+
+func TestGlobalIssuanceShardLimit(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		burst, count int64
+		shards       int
+	}{
+		{burst: 1000000, count: 1000000, shards: 16},
+		{burst: 100, count: 50, shards: 16},
+		{burst: 20, count: 18, shards: 16},
+		{burst: 10, count: 5, shards: 5},
+		{burst: 1, count: 100, shards: 1},
+	} {
+		l := &limit{
+			name:   GlobalIssuanceRate,
+			burst:  tc.burst,
+			count:  tc.count,
+			period: config.Duration{Duration: time.Hour},
+		}
+		l.precompute()
+		test.AssertEquals(t, globalIssuanceShardCount(l), tc.shards)
+
+		// The shares of every shard sum to the whole limit.
+		var burst, count int64
+		for shard := range tc.shards {
+			share := globalIssuanceShardLimit(l, shard)
+			test.Assert(t, share.burst > 0 && share.count > 0, "every shard should have capacity")
+			test.AssertEquals(t, share.emissionInterval, share.period.Nanoseconds()/share.count)
+			burst += share.burst
+			count += share.count
+		}
+		test.AssertEquals(t, burst, tc.burst)
+		test.AssertEquals(t, count, tc.count)
+	}
 }
 
 func TestCertificatesPerDomainTransactions(t *testing.T) {
@@ -205,6 +463,30 @@ func TestCertificatesPerFQDNSetTransactions(t *testing.T) {
 	test.Assert(t, !txn.limit.isOverride, "should not be an override")
 }
 
+func TestFinalizeOrderLimitCheckOnlyTransactions(t *testing.T) {
+	t.Parallel()
+
+	tb, err := NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	// One check-only transaction for each limit spent at finalize.
+	txns, err := tb.FinalizeOrderLimitCheckOnlyTransactions(123456789, []string{"example.com"}, false)
+	test.AssertNotError(t, err, "creating transactions")
+	test.AssertEquals(t, len(txns), 2)
+	for _, txn := range txns {
+		test.Assert(t, txn.checkOnly(), "should be check-only")
+	}
+	test.AssertEquals(t, txns[0].limit.name, CertificatesPerDomain)
+	test.AssertEquals(t, txns[1].limit.name, CertificatesPerFQDNSet)
+
+	// Renewals are exempt from the CertificatesPerDomain limit.
+	txns, err = tb.FinalizeOrderLimitCheckOnlyTransactions(123456789, []string{"example.com"}, true)
+	test.AssertNotError(t, err, "creating transactions")
+	test.AssertEquals(t, len(txns), 1)
+	test.AssertEquals(t, txns[0].limit.name, CertificatesPerFQDNSet)
+	test.Assert(t, txns[0].checkOnly(), "should be check-only")
+}
+
 func TestNewTransactionBuilder(t *testing.T) {
 	t.Parallel()
 
@@ -226,3 +508,110 @@ func TestNewTransactionBuilder(t *testing.T) {
 	test.AssertEquals(t, newRegDefault.count, expectedCount)
 	test.AssertEquals(t, newRegDefault.period, expectedPeriod)
 }
+
+func TestTransactionBuilderReloadFromFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	defaults := filepath.Join(dir, "defaults.yml")
+	writeDefaults := func(mode LimitMode) {
+		t.Helper()
+		err := os.WriteFile(defaults, []byte(fmt.Sprintf(
+			"NewRegistrationsPerIPAddress:\n  burst: 20\n  count: 20\n  period: 1s\n  mode: %s\n", mode)), 0600)
+		test.AssertNotError(t, err, "writing defaults")
+	}
+
+	writeDefaults(ModeLogOnly)
+	tb, err := NewTransactionBuilderFromFiles(defaults, "testdata/working_override.yml")
+	test.AssertNotError(t, err, "creating TransactionBuilder")
+
+	txn, err := tb.registrationsPerIPAddressTransaction(net.ParseIP("10.0.0.2"))
+	test.AssertNotError(t, err, "creating transaction")
+	test.AssertEquals(t, txn.limit.mode, ModeLogOnly)
+	test.AssertEquals(t, txn.limit.burst, int64(40))
+
+	// Switching the mode takes effect for both defaults and overrides.
+	writeDefaults(ModeOff)
+	err = tb.ReloadFromFiles(defaults, "testdata/working_override.yml")
+	test.AssertNotError(t, err, "reloading")
+	txn, err = tb.registrationsPerIPAddressTransaction(net.ParseIP("10.0.0.2"))
+	test.AssertNotError(t, err, "creating transaction")
+	test.Assert(t, txn.allowOnly(), "limit which is off should be allow-only")
+
+	// A failed reload keeps the existing limits.
+	err = tb.ReloadFromFiles("testdata/busted_default_burst_0.yml", "")
+	test.AssertError(t, err, "reloading busted defaults")
+	txn, err = tb.registrationsPerIPAddressTransaction(net.ParseIP("10.0.0.3"))
+	test.AssertNotError(t, err, "creating transaction")
+	test.Assert(t, txn.allowOnly(), "limit which is off should be allow-only")
+
+	writeDefaults(ModeEnforce)
+	err = tb.ReloadFromFiles(defaults, "")
+	test.AssertNotError(t, err, "reloading")
+	txn, err = tb.registrationsPerIPAddressTransaction(net.ParseIP("10.0.0.2"))
+	test.AssertNotError(t, err, "creating transaction")
+	test.AssertEquals(t, txn.limit.mode, ModeEnforce)
+	test.AssertEquals(t, txn.limit.burst, int64(20))
+}
+
+func TestTransactionConstructors(t *testing.T) {
+	t.Parallel()
+
+	testLimit := &limit{burst: 10, count: 10, period: config.Duration{Duration: time.Second}}
+	testLimit.precompute()
+
+	testCases := []struct {
+		name        string
+		newTxn      func(*limit, string, int64) (Transaction, error)
+		kind        transactionKind
+		checkOnly   bool
+		spendOnly   bool
+		spends      bool
+		description string
+	}{
+		{"spend", NewSpendTransaction, txnCheckAndSpend, false, false, true, "check-and-spend"},
+		{"check-only", NewCheckOnlyTransaction, txnCheckOnly, true, false, false, "check-only"},
+		{"spend-only", NewSpendOnlyTransaction, txnSpendOnly, false, true, true, "spend-only"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			txn, err := tc.newTxn(testLimit, "test", 1)
+			test.AssertNotError(t, err, "creating transaction")
+			test.AssertEquals(t, txn.kind, tc.kind)
+			test.AssertEquals(t, txn.kind.String(), tc.description)
+			test.AssertEquals(t, txn.checkOnly(), tc.checkOnly)
+			test.AssertEquals(t, txn.spendOnly(), tc.spendOnly)
+			test.AssertEquals(t, txn.spends(), tc.spends)
+			test.Assert(t, !txn.allowOnly(), "should not be allow-only")
+			test.AssertNotError(t, txn.wellFormed(), "constructed transaction should be well formed")
+
+			_, err = tc.newTxn(testLimit, "test", -1)
+			test.AssertErrorIs(t, err, ErrInvalidCost)
+			_, err = tc.newTxn(testLimit, "test", 11)
+			test.AssertErrorIs(t, err, ErrInvalidCostOverLimit)
+		})
+	}
+
+	// The zero value, and so newAllowOnlyTransaction, is allow-only and well
+	// formed despite having no limit.
+	txn := newAllowOnlyTransaction()
+	test.Assert(t, txn.allowOnly(), "should be allow-only")
+	test.Assert(t, !txn.spends(), "allow-only transaction should not spend")
+	test.AssertNotError(t, txn.wellFormed(), "allow-only transaction should be well formed")
+
+	// Transactions which weren't constructed aren't well formed.
+	for _, tc := range []struct {
+		txn   Transaction
+		field string
+	}{
+		{Transaction{bucketKey: "test", limit: testLimit, cost: 1, kind: transactionKind(42)}, "kind"},
+		{Transaction{bucketKey: "test", cost: 1, kind: txnCheckOnly}, "limit"},
+		{Transaction{limit: testLimit, cost: 1, kind: txnSpendOnly}, "bucketKey"},
+	} {
+		var malformedErr *MalformedTransactionError
+		test.Assert(t, errors.As(tc.txn.wellFormed(), &malformedErr), "should be a *MalformedTransactionError")
+		test.AssertEquals(t, malformedErr.Field, tc.field)
+		test.AssertEquals(t, malformedErr.BucketKey, tc.txn.bucketKey)
+	}
+}
@@ -1,33 +1,121 @@
 package ratelimits
 
 import (
+	"fmt"
 	"strings"
 
-	"github.com/letsencrypt/boulder/core"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weppos/publicsuffix-go/publicsuffix"
+	"golang.org/x/net/idna"
+
+	"github.com/letsencrypt/boulder/core"
 )
 
+// domainIdsNormalized counts the domain ids which normalizeDomainId changed.
+// It is registered by RegisterNormalizationMetrics.
+var domainIdsNormalized = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "ratelimits_domain_ids_normalized",
+	Help: "A counter of domain ids which were converted to lowercase A-label form before use in a bucket key or override, labelled by limit. Nonzero counts indicate callers or override files still supplying other forms",
+}, []string{"limit"})
+
+// RegisterNormalizationMetrics registers the metric counting the domain ids
+// which were normalized to A-label form. It must be called at most once.
+func RegisterNormalizationMetrics(stats prometheus.Registerer) {
+	stats.MustRegister(domainIdsNormalized)
+}
+
 // joinWithColon joins the provided args with a colon.
 func joinWithColon(args ...string) string {
 	return strings.Join(args, ":")
 }
 
+// normalizeDomainId returns the provided domain of an id of the named limit in
+// lowercase A-label form, so that the Unicode, mixed-case, and punycode forms
+// of a name all share one bucket. A leading wildcard label is preserved.
+// Names which fail IDNA conversion are rejected.
+func normalizeDomainId(name Name, domain string) (string, error) {
+	base, wildcard := strings.CutPrefix(domain, "*.")
+	normalized, err := idna.Lookup.ToASCII(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain, %q is not a valid IDNA name: %w", domain, err)
+	}
+	if wildcard {
+		normalized = "*." + normalized
+	}
+	if normalized != domain {
+		domainIdsNormalized.WithLabelValues(name.String()).Inc()
+	}
+	return normalized, nil
+}
+
+// normalizeIdForName returns the provided id of the named limit with each of
+// its domains normalized by normalizeDomainId. Ids of limits without domains,
+// and the regId-only override ids of per-domain-per-account limits, are
+// returned unchanged.
+func normalizeIdForName(name Name, id string) (string, error) {
+	switch name {
+	case CertificatesPerDomain, NewOrdersPerDomain:
+		// 'enum:domain'
+		return normalizeDomainId(name, id)
+
+	case CertificatesPerFQDNSet:
+		// 'enum:fqdnSet'
+		domains := strings.Split(id, ",")
+		for i, domain := range domains {
+			normalized, err := normalizeDomainId(name, domain)
+			if err != nil {
+				return "", err
+			}
+			domains[i] = normalized
+		}
+		return strings.Join(domains, ","), nil
+
+	case CertificatesPerDomainPerAccount,
+		FailedAuthorizationsPerDomainPerAccount,
+		FailedAuthorizationsForPausingPerDomainPerAccount,
+		FailedValidationsPerDomainPerAccount:
+		regId, domain, ok := strings.Cut(id, ":")
+		if !ok {
+			// 'enum:regId' for overrides
+			return id, nil
+		}
+		// 'enum:regId:domain' for transaction
+		normalized, err := normalizeDomainId(name, domain)
+		if err != nil {
+			return "", err
+		}
+		return joinWithColon(regId, normalized), nil
+
+	default:
+		return id, nil
+	}
+}
+
+// NormalizeDomainForLimit returns the registered domain (eTLD+1) of the
+// provided name, lowercased, as used in CertificatesPerDomain and
+// CertificatesPerDomainPerAccount bucket keys. Names which are exactly a public
+// suffix, from either the ICANN or private section of the Public Suffix List,
+// are keyed on the name itself.
+func NormalizeDomainForLimit(name string) string {
+	name = strings.ToLower(name)
+	domain, err := publicsuffix.Domain(name)
+	if err != nil {
+		// The only possible errors are:
+		// (1) publicsuffix.Domain is giving garbage values
+		// (2) the public suffix is the domain itself
+		// We assume 2 and use the original name.
+		return name
+	}
+	return domain
+}
+
 // FQDNsToETLDsPlusOne transforms a list of FQDNs into a list of eTLD+1's for
-// the CertificatesPerDomain limit. It also de-duplicates the output domains.
-// Exact public suffix matches are included.
+// the CertificatesPerDomain limit, using NormalizeDomainForLimit. It also
+// de-duplicates the output domains.
 func FQDNsToETLDsPlusOne(names []string) []string {
 	var domains []string
 	for _, name := range names {
-		domain, err := publicsuffix.Domain(name)
-		if err != nil {
-			// The only possible errors are:
-			// (1) publicsuffix.Domain is giving garbage values
-			// (2) the public suffix is the domain itself
-			// We assume 2 and include the original name in the result.
-			domains = append(domains, name)
-		} else {
-			domains = append(domains, domain)
-		}
+		domains = append(domains, NormalizeDomainForLimit(name))
 	}
 	return core.UniqueLowerNames(domains)
 }
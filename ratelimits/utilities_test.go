@@ -2,10 +2,37 @@ package ratelimits
 
 import (
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/config"
 	"github.com/letsencrypt/boulder/test"
 )
 
+func TestNormalizeDomainForLimit(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"www.example.com", "example.com"},
+		{"foo.bar.baz.www.EXAMPLE.com", "example.com"},
+		{"www.example.co.uk", "example.co.uk"},
+		// Exact public suffixes are keyed on the name itself.
+		{"co.uk", "co.uk"},
+		{"github.io", "github.io"},
+		// github.io is in the private section of the Public Suffix List.
+		{"foo.github.io", "foo.github.io"},
+		{"www.foo.github.io", "foo.github.io"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test.AssertEquals(t, NormalizeDomainForLimit(tc.name), tc.want)
+		})
+	}
+}
+
 func TestFQDNsToETLDsPlusOne(t *testing.T) {
 	domains := FQDNsToETLDsPlusOne([]string{})
 	test.AssertEquals(t, len(domains), 0)
@@ -25,3 +52,87 @@ func TestFQDNsToETLDsPlusOne(t *testing.T) {
 	domains = FQDNsToETLDsPlusOne([]string{"github.io", "foo.github.io", "bar.github.io"})
 	test.AssertDeepEquals(t, domains, []string{"bar.github.io", "foo.github.io", "github.io"})
 }
+
+func TestNormalizeIdForName(t *testing.T) {
+	// Not parallel, so that no other test changes the metric.
+	domainIdsNormalized.Reset()
+
+	for _, id := range []string{"1234:münchen.de", "1234:MÜNCHEN.de", "1234:xn--mnchen-3ya.de", "1234:XN--Mnchen-3ya.DE"} {
+		normalized, err := normalizeIdForName(FailedValidationsPerDomainPerAccount, id)
+		test.AssertNotError(t, err, id)
+		test.AssertEquals(t, normalized, "1234:xn--mnchen-3ya.de")
+	}
+	// Only the ids which weren't already in A-label form are counted.
+	test.AssertMetricWithLabelsEquals(t, domainIdsNormalized, prometheus.Labels{"limit": FailedValidationsPerDomainPerAccount.String()}, 3)
+
+	normalized, err := normalizeIdForName(FailedValidationsPerDomainPerAccount, "1234")
+	test.AssertNotError(t, err, "regId override id")
+	test.AssertEquals(t, normalized, "1234")
+
+	normalized, err = normalizeIdForName(CertificatesPerFQDNSet, "*.Example.com,münchen.de")
+	test.AssertNotError(t, err, "fqdnSet")
+	test.AssertEquals(t, normalized, "*.example.com,xn--mnchen-3ya.de")
+
+	normalized, err = normalizeIdForName(NewRegistrationsPerIPAddress, "10.0.0.1")
+	test.AssertNotError(t, err, "ids without domains")
+	test.AssertEquals(t, normalized, "10.0.0.1")
+
+	_, err = normalizeIdForName(CertificatesPerDomain, "xn--zz.com")
+	test.AssertError(t, err, "malformed punycode should be rejected")
+	err = validateIdForName(CertificatesPerDomain, "xn--zz.com")
+	test.AssertError(t, err, "malformed punycode should be rejected")
+}
+
+func TestNormalizedBucketKeys(t *testing.T) {
+	t.Parallel()
+
+	want, err := newDomainBucketKey(CertificatesPerDomain, "xn--mnchen-3ya.de")
+	test.AssertNotError(t, err, "punycode domain")
+	test.AssertEquals(t, want, joinWithColon(CertificatesPerDomain.EnumString(), "xn--mnchen-3ya.de"))
+	for _, domain := range []string{"münchen.de", "MÜNCHEN.DE", "XN--MNCHEN-3YA.DE"} {
+		got, err := newDomainBucketKey(CertificatesPerDomain, domain)
+		test.AssertNotError(t, err, domain)
+		test.AssertEquals(t, got, want)
+	}
+
+	want, err = NewRegIdDomainBucketKey(FailedAuthorizationsPerDomainPerAccount, 1234, "www.xn--mnchen-3ya.de")
+	test.AssertNotError(t, err, "punycode domain")
+	for _, domain := range []string{"www.münchen.de", "WWW.München.de"} {
+		got, err := NewRegIdDomainBucketKey(FailedAuthorizationsPerDomainPerAccount, 1234, domain)
+		test.AssertNotError(t, err, domain)
+		test.AssertEquals(t, got, want)
+	}
+
+	want, err = newFQDNSetBucketKey(CertificatesPerFQDNSet, []string{"example.com", "xn--mnchen-3ya.de"})
+	test.AssertNotError(t, err, "punycode domain")
+	got, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, []string{"Example.com", "münchen.de"})
+	test.AssertNotError(t, err, "unicode domain")
+	test.AssertEquals(t, got, want)
+
+	_, err = newDomainBucketKey(CertificatesPerDomain, "xn--zz.com")
+	test.AssertError(t, err, "malformed punycode should be rejected")
+}
+
+func TestNormalizedOverrides(t *testing.T) {
+	t.Parallel()
+
+	parsed, err := parseOverrideLimits(overridesYAML{{
+		CertificatesPerDomain.String(): overrideYAML{
+			LimitConfig: LimitConfig{Burst: 40, Count: 40, Period: config.Duration{Duration: time.Second}},
+			Ids:         []overrideIdYAML{{Id: "München.de"}},
+		},
+	}}, nil)
+	test.AssertNotError(t, err, "parsing override with a unicode id")
+	key, err := newDomainBucketKey(CertificatesPerDomain, "münchen.de")
+	test.AssertNotError(t, err, "unicode domain")
+	test.AssertEquals(t, key, joinWithColon(CertificatesPerDomain.EnumString(), "xn--mnchen-3ya.de"))
+	test.AssertNotNil(t, parsed[key], "override should apply to the A-label bucket")
+
+	_, err = parseOverrideLimits(overridesYAML{{
+		CertificatesPerDomain.String(): overrideYAML{
+			LimitConfig: LimitConfig{Burst: 40, Count: 40, Period: config.Duration{Duration: time.Second}},
+			Ids:         []overrideIdYAML{{Id: "xn--zz.com"}},
+		},
+	}}, nil)
+	test.AssertError(t, err, "override with malformed punycode should be rejected")
+}
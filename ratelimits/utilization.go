@@ -0,0 +1,216 @@
+package ratelimits
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OverrideState summarizes how an override is being used, as reported by
+// OverrideUtilization.
+type OverrideState string
+
+const (
+	// OverrideIdle is the state of an override which hasn't been spent
+	// against since tracking was enabled.
+	OverrideIdle OverrideState = "idle"
+
+	// OverrideUsed is the state of an override which has been spent against,
+	// and has never denied a request.
+	OverrideUsed OverrideState = "used"
+
+	// OverrideMaxed is the state of an override which has denied at least one
+	// request, meaning it may be undersized.
+	OverrideMaxed OverrideState = "maxed"
+)
+
+// OverrideUsage is the utilization of a single override, as reported by
+// OverrideUtilization.
+type OverrideUsage struct {
+	// Name is the limit which the override applies to.
+	Name Name
+
+	// Key is the key of the override, formatted as 'name:id'. An override of
+	// a per-account limit applies to every bucket of that account.
+	Key string
+
+	// Burst is the maximum capacity of the override.
+	Burst int64
+
+	// Spends is the number of requests spent against the override, whether
+	// or not they were allowed.
+	Spends int64
+
+	// Denials is the number of those requests which the override denied.
+	Denials int64
+
+	// RemainingFraction is the fraction of the override's burst which
+	// remained after the most recent spend. It is 1 for idle overrides.
+	RemainingFraction float64
+
+	// LastSpend is when the override was most recently spent against. It is
+	// zero for idle overrides.
+	LastSpend time.Time
+
+	// State summarizes the above.
+	State OverrideState
+}
+
+// perAccountOverrideNames are the names of limits whose Transactions use the
+// 'enum:regId:domain' bucket key format, but whose overrides use 'enum:regId'.
+var perAccountOverrideNames = map[Name]bool{
+	FailedAuthorizationsPerDomainPerAccount:           true,
+	FailedAuthorizationsForPausingPerDomainPerAccount: true,
+	FailedValidationsPerDomainPerAccount:              true,
+	CertificatesPerDomainPerAccount:                   true,
+}
+
+// overrideKey returns the 'name:id' key of the override which applies to
+// txn's bucket. Every shard of GlobalIssuanceRate shares a single override.
+func overrideKey(txn Transaction) string {
+	if txn.limit.overrideCIDR != "" {
+		return joinWithColon(txn.limit.name.EnumString(), txn.limit.overrideCIDR)
+	}
+	if perAccountOverrideNames[txn.limit.name] || txn.limit.name == GlobalIssuanceRate {
+		idx := strings.LastIndex(txn.bucketKey, ":")
+		if idx != -1 {
+			return txn.bucketKey[:idx]
+		}
+	}
+	return txn.bucketKey
+}
+
+// overrideUsage is the mutable utilization of a single override.
+type overrideUsage struct {
+	spends            int64
+	denials           int64
+	remainingFraction float64
+	lastSpend         time.Time
+}
+
+// overrideTracker accumulates the utilization of overrides. Overrides are
+// configured by hand, so their keys are low-cardinality by definition
+// and are safe to use as metric labels.
+type overrideTracker struct {
+	sync.Mutex
+	usage map[string]*overrideUsage
+
+	// txnBuilder holds the configured overrides, which are reported even if
+	// they haven't been spent against.
+	txnBuilder *TransactionBuilder
+
+	spends            *prometheus.CounterVec
+	denials           *prometheus.CounterVec
+	remainingFraction *prometheus.GaugeVec
+}
+
+// EnableOverrideUtilization starts tracking how much each override configured
+// in txnBuilder is used, both in metrics under the ratelimits_override
+// namespace and for OverrideUtilization. It must be called at most once,
+// before the Limiter is used.
+func (l *Limiter) EnableOverrideUtilization(txnBuilder *TransactionBuilder, stats prometheus.Registerer) {
+	spends := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ratelimits_override",
+		Name:      "spends",
+		Help:      "Number of requests spent against the override of limit=[name] with key=[name:id]",
+	}, []string{"limit", "key"})
+	stats.MustRegister(spends)
+
+	denials := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ratelimits_override",
+		Name:      "denials",
+		Help:      "Number of requests denied by the override of limit=[name] with key=[name:id]",
+	}, []string{"limit", "key"})
+	stats.MustRegister(denials)
+
+	remainingFraction := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ratelimits_override",
+		Name:      "remaining_fraction",
+		Help:      "Fraction of the burst of the override of limit=[name] with key=[name:id] which remained after the most recent spend",
+	}, []string{"limit", "key"})
+	stats.MustRegister(remainingFraction)
+
+	l.overrides = &overrideTracker{
+		usage:             make(map[string]*overrideUsage),
+		txnBuilder:        txnBuilder,
+		spends:            spends,
+		denials:           denials,
+		remainingFraction: remainingFraction,
+	}
+}
+
+// observe records a spend against txn's bucket, and the *Decision reached for
+// it, against the override which applies to that bucket, if any. Check-only
+// Transactions aren't spends, so they are ignored. It is safe to call on a nil
+// *overrideTracker, which records nothing.
+func (t *overrideTracker) observe(txn Transaction, d *Decision, now time.Time) {
+	if t == nil || !txn.limit.isOverride || !txn.spends() {
+		return
+	}
+	fraction := float64(max(d.remaining, 0)) / float64(txn.limit.burst)
+
+	name := txn.limit.name.String()
+	key := overrideKey(txn)
+	t.spends.WithLabelValues(name, key).Inc()
+	if !d.allowed {
+		t.denials.WithLabelValues(name, key).Inc()
+	}
+	t.remainingFraction.WithLabelValues(name, key).Set(fraction)
+
+	t.Lock()
+	defer t.Unlock()
+	u, ok := t.usage[key]
+	if !ok {
+		u = &overrideUsage{}
+		t.usage[key] = u
+	}
+	u.spends++
+	if !d.allowed {
+		u.denials++
+	}
+	u.remainingFraction = fraction
+	u.lastSpend = now
+}
+
+// OverrideUtilization returns a snapshot of the utilization of every override
+// currently configured, sorted by key. Overrides which haven't been spent
+// against are reported as OverrideIdle. It returns nil if
+// EnableOverrideUtilization hasn't been called.
+func (l *Limiter) OverrideUtilization() []OverrideUsage {
+	t := l.overrides
+	if t == nil {
+		return nil
+	}
+	overrides := t.txnBuilder.overrideLimits()
+
+	t.Lock()
+	defer t.Unlock()
+	report := make([]OverrideUsage, 0, len(overrides))
+	for _, key := range slices.Sorted(maps.Keys(overrides)) {
+		lim := overrides[key]
+		entry := OverrideUsage{
+			Name:              lim.name,
+			Key:               key,
+			Burst:             lim.burst,
+			RemainingFraction: 1,
+			State:             OverrideIdle,
+		}
+		u, ok := t.usage[key]
+		if ok {
+			entry.Spends = u.spends
+			entry.Denials = u.denials
+			entry.RemainingFraction = u.remainingFraction
+			entry.LastSpend = u.lastSpend
+			entry.State = OverrideUsed
+			if u.denials > 0 {
+				entry.State = OverrideMaxed
+			}
+		}
+		report = append(report, entry)
+	}
+	return report
+}
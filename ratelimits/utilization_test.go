@@ -0,0 +1,97 @@
+package ratelimits
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestLimiter_OverrideUtilization(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder, err := NewTransactionBuilderFromFiles("testdata/working_defaults.yml", "testdata/working_overrides.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	// Utilization isn't tracked until enabled.
+	test.AssertEquals(t, len(l.OverrideUtilization()), 0)
+	stats := prometheus.NewRegistry()
+	l.EnableOverrideUtilization(txnBuilder, stats)
+
+	// Spend a little of the NewRegistrationsPerIPAddress override, which has a
+	// burst of 40.
+	usedTxn, err := txnBuilder.registrationsPerIPAddressTransaction(net.ParseIP(tenZeroZeroTwo))
+	test.AssertNotError(t, err, "should not error")
+	for range 10 {
+		d, err := l.Spend(ctx, usedTxn)
+		test.AssertNotError(t, err, "should not error")
+		test.Assert(t, d.allowed, "should be allowed")
+	}
+
+	// Exhaust one of the buckets of the per-account
+	// FailedAuthorizationsForPausingPerDomainPerAccount override, which has a
+	// burst of 60, and then exceed it.
+	maxedTxn, err := txnBuilder.FailedAuthorizationsForPausingPerDomainPerAccountTransaction(1234, "example.com")
+	test.AssertNotError(t, err, "should not error")
+	maxedKey := joinWithColon(FailedAuthorizationsForPausingPerDomainPerAccount.EnumString(), "1234")
+	for range 60 {
+		_, err := l.Spend(ctx, maxedTxn)
+		test.AssertNotError(t, err, "should not error")
+	}
+	d, err := l.Spend(ctx, maxedTxn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.allowed, "should be denied")
+
+	// Checks aren't spends.
+	_, err = l.Check(ctx, usedTxn)
+	test.AssertNotError(t, err, "should not error")
+
+	report := l.OverrideUtilization()
+	byKey := make(map[string]OverrideUsage)
+	for _, u := range report {
+		byKey[u.Key] = u
+	}
+	test.AssertEquals(t, len(report), 6)
+
+	used := byKey[usedTxn.bucketKey]
+	test.AssertEquals(t, used.State, OverrideUsed)
+	test.AssertEquals(t, used.Name, NewRegistrationsPerIPAddress)
+	test.AssertEquals(t, used.Burst, int64(40))
+	test.AssertEquals(t, used.Spends, int64(10))
+	test.AssertEquals(t, used.Denials, int64(0))
+	test.AssertEquals(t, used.RemainingFraction, 0.75)
+	test.AssertEquals(t, used.LastSpend, clk.Now())
+
+	maxed := byKey[maxedKey]
+	test.AssertEquals(t, maxed.State, OverrideMaxed)
+	test.AssertEquals(t, maxed.Spends, int64(61))
+	test.AssertEquals(t, maxed.Denials, int64(1))
+	test.AssertEquals(t, maxed.RemainingFraction, 0.0)
+
+	var idle int
+	for _, u := range report {
+		if u.Key == usedTxn.bucketKey || u.Key == maxedKey {
+			continue
+		}
+		test.AssertEquals(t, u.State, OverrideIdle)
+		test.AssertEquals(t, u.Spends, int64(0))
+		test.AssertEquals(t, u.RemainingFraction, 1.0)
+		test.Assert(t, u.LastSpend.IsZero(), "idle override should have no last spend")
+		idle++
+	}
+	test.AssertEquals(t, idle, 4)
+
+	// The same utilization is exported as metrics.
+	labels := prometheus.Labels{"limit": FailedAuthorizationsForPausingPerDomainPerAccount.String(), "key": maxedKey}
+	test.AssertMetricWithLabelsEquals(t, l.overrides.spends, labels, 61)
+	test.AssertMetricWithLabelsEquals(t, l.overrides.denials, labels, 1)
+	test.AssertMetricWithLabelsEquals(t, l.overrides.remainingFraction, labels, 0)
+	labels = prometheus.Labels{"limit": NewRegistrationsPerIPAddress.String(), "key": usedTxn.bucketKey}
+	test.AssertMetricWithLabelsEquals(t, l.overrides.remainingFraction, labels, 0.75)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/core"
 	boulderDB "github.com/letsencrypt/boulder/db"
+	"github.com/letsencrypt/boulder/features"
 	blog "github.com/letsencrypt/boulder/log"
 )
 
@@ -267,14 +268,32 @@ func initTables(dbMap *borp.DbMap) {
 
 	regTable.ColMap("Key").SetNotNull(true)
 	regTable.ColMap("KeySHA256").SetNotNull(true).SetUnique(true)
+	if !features.Get().StoreEABKeyID {
+		// The eabKeyID column only exists once the RegistrationsEABKeyID
+		// migration has been applied.
+		regTable.ColMap("EABKeyID").SetTransient(true)
+	}
 	dbMap.AddTableWithName(issuedNameModel{}, "issuedNames").SetKeys(true, "ID")
 	dbMap.AddTableWithName(core.Certificate{}, "certificates").SetKeys(true, "ID")
 	dbMap.AddTableWithName(core.CertificateStatus{}, "certificateStatus").SetKeys(true, "ID")
 	dbMap.AddTableWithName(core.FQDNSet{}, "fqdnSets").SetKeys(true, "ID")
-	dbMap.AddTableWithName(orderModel{}, "orders").SetKeys(true, "ID")
+	orderTable := dbMap.AddTableWithName(orderModel{}, "orders").SetKeys(true, "ID")
+	if !features.Get().StoreOrderFinalizeBy {
+		// The finalizeBy column only exists once the OrdersFinalizeBy
+		// migration has been applied.
+		orderTable.ColMap("FinalizeBy").SetTransient(true)
+	}
 	dbMap.AddTableWithName(orderToAuthzModel{}, "orderToAuthz").SetKeys(false, "OrderID", "AuthzID")
 	dbMap.AddTableWithName(orderFQDNSet{}, "orderFqdnSets").SetKeys(true, "ID")
-	dbMap.AddTableWithName(authzModel{}, "authz2").SetKeys(true, "ID")
+	authzTable := dbMap.AddTableWithName(authzModel{}, "authz2").SetKeys(true, "ID")
+	if !features.Get().StoreAuthzValidationDetails {
+		// These columns only exist once the AuthzValidationAttempts,
+		// AuthzPerspectiveResults, and AuthzFailureCounted migrations have
+		// been applied.
+		authzTable.ColMap("ValidationAttempts").SetTransient(true)
+		authzTable.ColMap("PerspectiveResults").SetTransient(true)
+		authzTable.ColMap("FailureCounted").SetTransient(true)
+	}
 	dbMap.AddTableWithName(orderToAuthzModel{}, "orderToAuthz2").SetKeys(false, "OrderID", "AuthzID")
 	dbMap.AddTableWithName(recordedSerialModel{}, "serials").SetKeys(true, "ID")
 	dbMap.AddTableWithName(lintingCertModel{}, "precertificates").SetKeys(true, "ID")
@@ -285,6 +304,8 @@ func initTables(dbMap *borp.DbMap) {
 	dbMap.AddTableWithName(revokedCertModel{}, "revokedCertificates").SetKeys(true, "ID")
 	dbMap.AddTableWithName(replacementOrderModel{}, "replacementOrders").SetKeys(true, "ID")
 	dbMap.AddTableWithName(pausedModel{}, "paused")
+	dbMap.AddTableWithName(eabKeyIDAccountModel{}, "eabKeyIDAccounts")
+	dbMap.AddTableWithName(keyRolloverModel{}, "keyRollovers")
 
 	// Read-only maps used for selecting subsets of columns.
 	dbMap.AddTableWithName(CertStatusMetadata{}, "certificateStatus")
@@ -0,0 +1,24 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+ALTER TABLE `registrations` ADD COLUMN `eabKeyID` varchar(255) DEFAULT NULL,
+  ADD KEY `eabKeyID` (`eabKeyID`);
+
+CREATE TABLE `eabKeyIDAccounts` (
+  `eabKeyID` varchar(255) NOT NULL,
+  `registrationID` bigint(20) NOT NULL,
+  PRIMARY KEY (`eabKeyID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `externalAccountKeys` (
+  `keyID` varchar(255) NOT NULL,
+  `macKey` varbinary(255) NOT NULL,
+  PRIMARY KEY (`keyID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `externalAccountKeys`;
+DROP TABLE `eabKeyIDAccounts`;
+ALTER TABLE `registrations` DROP KEY `eabKeyID`, DROP COLUMN `eabKeyID`;
@@ -34,6 +34,9 @@ GRANT SELECT,INSERT,UPDATE ON revokedCertificates TO 'sa'@'localhost';
 GRANT SELECT,INSERT,UPDATE ON replacementOrders TO 'sa'@'localhost';
 -- Tests need to be able to TRUNCATE this table, so DROP is necessary.
 GRANT SELECT,INSERT,UPDATE,DROP ON paused TO 'sa'@'localhost';
+GRANT SELECT,INSERT ON eabKeyIDAccounts TO 'sa'@'localhost';
+GRANT SELECT ON externalAccountKeys TO 'sa'@'localhost';
+GRANT SELECT,INSERT ON keyRollovers TO 'sa'@'localhost';
 
 GRANT SELECT ON certificates TO 'sa_ro'@'localhost';
 GRANT SELECT ON certificateStatus TO 'sa_ro'@'localhost';
@@ -53,6 +56,7 @@ GRANT SELECT ON crlShards TO 'sa_ro'@'localhost';
 GRANT SELECT ON revokedCertificates TO 'sa_ro'@'localhost';
 GRANT SELECT ON replacementOrders TO 'sa_ro'@'localhost';
 GRANT SELECT ON paused TO 'sa_ro'@'localhost';
+GRANT SELECT ON externalAccountKeys TO 'sa_ro'@'localhost';
 
 -- OCSP Responder
 GRANT SELECT ON certificateStatus TO 'ocsp_resp'@'localhost';
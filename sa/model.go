@@ -15,13 +15,13 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/go-jose/go-jose/v4"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
 	"github.com/letsencrypt/boulder/db"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/identifier"
 	"github.com/letsencrypt/boulder/probs"
@@ -60,6 +60,16 @@ func badJSONError(msg string, jsonData []byte, err error) error {
 
 const regFields = "id, jwk, jwk_sha256, contact, agreement, createdAt, LockCol, status"
 
+// selectRegFields returns the columns selected into a regModel. The eabKeyID
+// column is only selected when the StoreEABKeyID feature is enabled, as it
+// may not exist otherwise.
+func selectRegFields() string {
+	if features.Get().StoreEABKeyID {
+		return regFields + ", eabKeyID"
+	}
+	return regFields
+}
+
 // ClearEmail removes the provided email address from one specified registration. If
 // there are multiple email addresses present, it does not modify other ones. If the email
 // address is not present, it does not modify the registration and will return a nil error.
@@ -132,7 +142,7 @@ func selectRegistration(ctx context.Context, s db.OneSelector, whereCol string,
 	err := s.SelectOne(
 		ctx,
 		&model,
-		"SELECT "+regFields+" FROM registrations WHERE "+whereCol+" = ? LIMIT 1",
+		"SELECT "+selectRegFields()+" FROM registrations WHERE "+whereCol+" = ? LIMIT 1",
 		args...,
 	)
 	return &model, err
@@ -281,18 +291,36 @@ type regModel struct {
 	CreatedAt time.Time `db:"createdAt"`
 	LockCol   int64
 	Status    string `db:"status"`
+	// EABKeyID is a pointer because the column is NULL-able. It's only
+	// stored when the StoreEABKeyID feature is enabled.
+	EABKeyID *string `db:"eabKeyID"`
+}
+
+// eabKeyIDAccountModel records the registration created with an External
+// Account Binding key identifier which may only be used for one account. The
+// eabKeyID is the table's primary key, so only one registration can claim it.
+type eabKeyIDAccountModel struct {
+	EABKeyID       string `db:"eabKeyID"`
+	RegistrationID int64  `db:"registrationID"`
+}
+
+// keyRolloverModel records a key rollover request, by the digest of its
+// signature, so that it can't be replayed.
+type keyRolloverModel struct {
+	Digest         []byte    `db:"digest"`
+	RegistrationID int64     `db:"registrationID"`
+	Created        time.Time `db:"created"`
+}
+
+// externalAccountKeyModel is the MAC key of an External Account Binding key
+// identifier, as provisioned by an operator.
+type externalAccountKeyModel struct {
+	KeyID  string `db:"keyID"`
+	MACKey []byte `db:"macKey"`
 }
 
 func registrationPbToModel(reg *corepb.Registration) (*regModel, error) {
-	// Even though we don't need to convert from JSON to an in-memory JSONWebKey
-	// for the sake of the `Key` field, we do need to do the conversion in order
-	// to compute the SHA256 key digest.
-	var jwk jose.JSONWebKey
-	err := jwk.UnmarshalJSON(reg.Key)
-	if err != nil {
-		return nil, err
-	}
-	sha, err := core.KeyDigestB64(jwk.Key)
+	sha, err := core.JWKDigestB64(reg.Key)
 	if err != nil {
 		return nil, err
 	}
@@ -314,6 +342,11 @@ func registrationPbToModel(reg *corepb.Registration) (*regModel, error) {
 		createdAt = reg.CreatedAt.AsTime()
 	}
 
+	var eabKeyID *string
+	if reg.EabKeyID != "" {
+		eabKeyID = &reg.EabKeyID
+	}
+
 	return &regModel{
 		ID:        reg.Id,
 		Key:       reg.Key,
@@ -322,6 +355,7 @@ func registrationPbToModel(reg *corepb.Registration) (*regModel, error) {
 		Agreement: reg.Agreement,
 		CreatedAt: createdAt,
 		Status:    reg.Status,
+		EABKeyID:  eabKeyID,
 	}, nil
 }
 
@@ -338,6 +372,11 @@ func registrationModelToPb(reg *regModel) (*corepb.Registration, error) {
 		}
 	}
 
+	var eabKeyID string
+	if reg.EABKeyID != nil {
+		eabKeyID = *reg.EABKeyID
+	}
+
 	return &corepb.Registration{
 		Id:        reg.ID,
 		Key:       reg.Key,
@@ -345,6 +384,7 @@ func registrationModelToPb(reg *regModel) (*corepb.Registration, error) {
 		Agreement: reg.Agreement,
 		CreatedAt: timestamppb.New(reg.CreatedAt.UTC()),
 		Status:    reg.Status,
+		EabKeyID:  eabKeyID,
 	}, nil
 }
 
@@ -376,6 +416,9 @@ type orderModel struct {
 	CertificateSerial      string
 	BeganProcessing        bool
 	CertificateProfileName *string
+	// FinalizeBy is a pointer because the column is NULL-able. It's only
+	// stored when the StoreOrderFinalizeBy feature is enabled.
+	FinalizeBy *time.Time
 }
 
 type orderToAuthzModel struct {
@@ -396,9 +439,21 @@ func orderToModel(order *corepb.Order) (*orderModel, error) {
 		CertificateSerial:      order.CertificateSerial,
 		CertificateProfileName: &profile,
 	}
+	if !core.IsAnyNilOrZero(order.FinalizeBy) {
+		finalizeBy := order.FinalizeBy.AsTime()
+		om.FinalizeBy = &finalizeBy
+	}
 
 	if order.Error != nil {
-		errJSON, err := json.Marshal(order.Error)
+		prob, err := grpc.PBToProblemDetails(order.Error)
+		if err != nil {
+			return nil, err
+		}
+		errJSON, err := json.Marshal(storedOrderError{
+			ProblemDetails:   *prob,
+			LegacyType:       prob.Type,
+			LegacyHTTPStatus: prob.HTTPStatus,
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -410,6 +465,36 @@ func orderToModel(order *corepb.Order) (*orderModel, error) {
 	return om, nil
 }
 
+// storedOrderError is the form in which an order's error is stored in the
+// orders table. Errors are stored as a JSON-encoded probs.ProblemDetails, which
+// preserves subproblems. Older rows hold a JSON-encoded corepb.ProblemDetails,
+// which uses different field names for the type and status; those are decoded
+// into the legacy fields. The legacy fields are also written, so that an SA
+// which predates this format can still read the top-level problem.
+type storedOrderError struct {
+	probs.ProblemDetails
+	LegacyType       probs.ProblemType `json:"problemType,omitempty"`
+	LegacyHTTPStatus int               `json:"httpStatus,omitempty"`
+}
+
+// unmarshalOrderError decodes an order's error column, in either the current
+// or the legacy format, to a *corepb.ProblemDetails.
+func unmarshalOrderError(errJSON []byte) (*corepb.ProblemDetails, error) {
+	var stored storedOrderError
+	err := json.Unmarshal(errJSON, &stored)
+	if err != nil {
+		return nil, badJSONError("failed to unmarshal order model's error", errJSON, err)
+	}
+	prob := stored.ProblemDetails
+	if prob.Type == "" {
+		prob.Type = stored.LegacyType
+	}
+	if prob.HTTPStatus == 0 {
+		prob.HTTPStatus = stored.LegacyHTTPStatus
+	}
+	return grpc.ProblemDetailsToPB(&prob)
+}
+
 func modelToOrder(om *orderModel) (*corepb.Order, error) {
 	profile := ""
 	if om.CertificateProfileName != nil {
@@ -424,30 +509,34 @@ func modelToOrder(om *orderModel) (*corepb.Order, error) {
 		BeganProcessing:        om.BeganProcessing,
 		CertificateProfileName: profile,
 	}
+	if om.FinalizeBy != nil {
+		order.FinalizeBy = timestamppb.New(*om.FinalizeBy)
+	}
 	if len(om.Error) > 0 {
-		var problem corepb.ProblemDetails
-		err := json.Unmarshal(om.Error, &problem)
+		problem, err := unmarshalOrderError(om.Error)
 		if err != nil {
-			return &corepb.Order{}, badJSONError(
-				"failed to unmarshal order model's error",
-				om.Error,
-				err)
+			return &corepb.Order{}, err
 		}
-		order.Error = &problem
+		// Subproblems can be large, so only the top-level problem is included
+		// in the order. Use GetOrderError to retrieve the full problem.
+		problem.SubProblems = nil
+		order.Error = problem
 	}
 	return order, nil
 }
 
 var challTypeToUint = map[string]uint8{
-	"http-01":     0,
-	"dns-01":      1,
-	"tls-alpn-01": 2,
+	"http-01":        0,
+	"dns-01":         1,
+	"tls-alpn-01":    2,
+	"dns-account-01": 3,
 }
 
 var uintToChallType = map[uint8]string{
 	0: "http-01",
 	1: "dns-01",
 	2: "tls-alpn-01",
+	3: "dns-account-01",
 }
 
 var identifierTypeToUint = map[string]uint8{
@@ -482,6 +571,22 @@ func statusUint(status core.AcmeStatus) uint8 {
 // it must be carried through to every use in sa.go
 const authzFields = "id, identifierType, identifierValue, registrationID, certificateProfileName, status, expires, challenges, attempted, attemptedAt, token, validationError, validationRecord"
 
+// authzValidationDetailsFields are the authz2 columns added by the
+// AuthzValidationAttempts, AuthzPerspectiveResults, and AuthzFailureCounted
+// migrations.
+const authzValidationDetailsFields = "validationAttempts, perspectiveResults, failureCounted"
+
+// selectAuthzFields returns the columns selected into an authzModel. The
+// authzValidationDetailsFields are only selected when the
+// StoreAuthzValidationDetails feature is enabled, as they may not exist
+// otherwise.
+func selectAuthzFields() string {
+	if features.Get().StoreAuthzValidationDetails {
+		return authzFields + ", " + authzValidationDetailsFields
+	}
+	return authzFields
+}
+
 // authzModel represents one row in the authz2 table. The CertificateProfileName
 // column is a pointer because the column is NULL-able.
 type authzModel struct {
@@ -498,6 +603,9 @@ type authzModel struct {
 	Token                  []byte     `db:"token"`
 	ValidationError        []byte     `db:"validationError"`
 	ValidationRecord       []byte     `db:"validationRecord"`
+	ValidationAttempts     []byte     `db:"validationAttempts"`
+	PerspectiveResults     []byte     `db:"perspectiveResults"`
+	FailureCounted         bool       `db:"failureCounted"`
 }
 
 // rehydrateHostPort mutates a validation record. If the URL in the validation
@@ -578,7 +686,7 @@ func SelectAuthzsMatchingIssuance(
 			attemptedAt <= ? AND
 			identifierType = ? AND
 			identifierValue IN (%s)`,
-		authzFields,
+		selectAuthzFields(),
 		db.QuestionMarks(len(dnsNames)))
 	var args []any
 	args = append(args,
@@ -736,6 +844,20 @@ func authzPBToModel(authz *corepb.Authorization) (*authzModel, error) {
 			if err != nil {
 				return nil, err
 			}
+			if len(chall.Attempts) > 0 {
+				attempts := make([]core.ValidationAttempt, len(chall.Attempts))
+				for i, attemptPB := range chall.Attempts {
+					attempts[i] = grpc.PBToValidationAttempt(attemptPB)
+				}
+				am.ValidationAttempts, err = json.Marshal(attempts)
+				if err != nil {
+					return nil, err
+				}
+			}
+			am.PerspectiveResults, err = perspectiveResultsToJSON(chall.PerspectiveResults)
+			if err != nil {
+				return nil, err
+			}
 			// If there is a error associated with the challenge marshal it to JSON
 			// so that we can store it in the database.
 			if chall.Error != nil {
@@ -759,6 +881,25 @@ func authzPBToModel(authz *corepb.Authorization) (*authzModel, error) {
 	return am, nil
 }
 
+// perspectiveResultsToJSON marshals the provided per-perspective results for
+// storage in the authz2 table. It returns nil if there are none, so that the
+// column is left NULL, and an error if there are more than
+// core.MaxPerspectiveResults.
+func perspectiveResultsToJSON(resultPBs []*corepb.PerspectiveResult) ([]byte, error) {
+	if len(resultPBs) == 0 {
+		return nil, nil
+	}
+	if len(resultPBs) > core.MaxPerspectiveResults {
+		return nil, berrors.InternalServerError(
+			"too many perspective results: got %d, at most %d are allowed", len(resultPBs), core.MaxPerspectiveResults)
+	}
+	results := make([]core.PerspectiveResult, len(resultPBs))
+	for i, resultPB := range resultPBs {
+		results[i] = grpc.PBToPerspectiveResult(resultPB)
+	}
+	return json.Marshal(results)
+}
+
 // populateAttemptedFields takes a challenge and populates it with the validation fields status,
 // validation records, and error (the latter only if the validation failed) from an authzModel.
 func populateAttemptedFields(am authzModel, challenge *corepb.Challenge) error {
@@ -781,6 +922,34 @@ func populateAttemptedFields(am authzModel, challenge *corepb.Challenge) error {
 		// If the error is empty the challenge must be valid.
 		challenge.Status = string(core.StatusValid)
 	}
+	if len(am.ValidationAttempts) != 0 {
+		var attempts []core.ValidationAttempt
+		err := json.Unmarshal(am.ValidationAttempts, &attempts)
+		if err != nil {
+			return badJSONError(
+				"failed to unmarshal authz2 model's validation attempts",
+				am.ValidationAttempts,
+				err)
+		}
+		challenge.Attempts = make([]*corepb.ValidationAttempt, len(attempts))
+		for i, a := range attempts {
+			challenge.Attempts[i] = grpc.ValidationAttemptToPB(a)
+		}
+	}
+	if len(am.PerspectiveResults) != 0 {
+		var results []core.PerspectiveResult
+		err := json.Unmarshal(am.PerspectiveResults, &results)
+		if err != nil {
+			return badJSONError(
+				"failed to unmarshal authz2 model's perspective results",
+				am.PerspectiveResults,
+				err)
+		}
+		challenge.PerspectiveResults = make([]*corepb.PerspectiveResult, len(results))
+		for i, r := range results {
+			challenge.PerspectiveResults[i] = grpc.PerspectiveResultToPB(r)
+		}
+	}
 	var records []core.ValidationRecord
 	err := json.Unmarshal(am.ValidationRecord, &records)
 	if err != nil {
@@ -826,6 +995,7 @@ func modelToAuthzPB(am authzModel) (*corepb.Authorization, error) {
 		RegistrationID:         am.RegistrationID,
 		Expires:                timestamppb.New(am.Expires),
 		CertificateProfileName: profile,
+		FailureCounted:         am.FailureCounted,
 	}
 	// Populate authorization challenge array. We do this by iterating through
 	// the challenge type bitmap and creating a challenge of each type if its
@@ -1142,8 +1312,26 @@ func statusForOrder(order *corepb.Order, authzValidityInfo []authzValidity, now
 			"authorizations", order.Id)
 }
 
+// deactivatedAuthzProblem returns the problem for an order which is invalid
+// because one of the authorizations described by authzValidityInfo was
+// deactivated, or nil if none of them were.
+func deactivatedAuthzProblem(authzValidityInfo []authzValidity) (*corepb.ProblemDetails, error) {
+	for _, info := range authzValidityInfo {
+		if uintToStatus[info.Status] != core.StatusDeactivated {
+			continue
+		}
+		ident := identifier.ACMEIdentifier{
+			Type:  identifier.IdentifierType(uintToIdentifierType[info.IdentifierType]),
+			Value: info.IdentifierValue,
+		}
+		return grpc.ProblemDetailsToPB(core.DeactivatedAuthorizationProblem(info.ID, ident))
+	}
+	return nil, nil
+}
+
 // authzValidity is a subset of authzModel
 type authzValidity struct {
+	ID              int64     `db:"id"`
 	IdentifierType  uint8     `db:"identifierType"`
 	IdentifierValue string    `db:"identifierValue"`
 	Status          uint8     `db:"status"`
@@ -1161,7 +1349,7 @@ func getAuthorizationStatuses(ctx context.Context, s db.Selector, ids []int64) (
 	_, err := s.Select(
 		ctx,
 		&validities,
-		fmt.Sprintf("SELECT identifierType, identifierValue, status, expires FROM authz2 WHERE id IN (%s)",
+		fmt.Sprintf("SELECT id, identifierType, identifierValue, status, expires FROM authz2 WHERE id IN (%s)",
 			db.QuestionMarks(len(ids))),
 		params...,
 	)
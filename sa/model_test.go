@@ -8,6 +8,7 @@ import (
 	"crypto/x509/pkix"
 	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"os"
@@ -81,6 +82,10 @@ func TestAuthzModel(t *testing.T) {
 						AddressesTried:    [][]byte{{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}},
 					},
 				},
+				PerspectiveResults: []*corepb.PerspectiveResult{
+					{Perspective: "Primary", Rir: "ARIN", Passed: true, LatencyMs: 12},
+					{Perspective: "surreal", Rir: "RIPE", ProblemType: string(probs.ConnectionProblem), LatencyMs: 3000},
+				},
 			},
 		},
 	}
@@ -268,6 +273,108 @@ func TestOrderModelThereAndBackAgain(t *testing.T) {
 	test.AssertDeepEquals(t, order, returnOrder)
 }
 
+func TestOrderModelErrorRoundTrip(t *testing.T) {
+	prob := &corepb.ProblemDetails{
+		ProblemType: string(probs.RejectedIdentifierProblem),
+		Detail:      "Error finalizing order",
+		HttpStatus:  400,
+		SubProblems: []*corepb.SubProblemDetails{
+			{
+				Problem: &corepb.ProblemDetails{
+					ProblemType: string(probs.RejectedIdentifierProblem),
+					Detail:      "nested problem",
+					HttpStatus:  400,
+					SubProblems: []*corepb.SubProblemDetails{
+						{
+							Problem:    &corepb.ProblemDetails{ProblemType: string(probs.CAAProblem), Detail: "deeply nested problem"},
+							Identifier: &corepb.Identifier{Type: "dns", Value: "a.example.com"},
+						},
+					},
+				},
+				Identifier: &corepb.Identifier{Type: "dns", Value: "example.com"},
+			},
+		},
+	}
+	model, err := orderToModel(&corepb.Order{Id: 1, Error: prob})
+	test.AssertNotError(t, err, "orderToModel should not have errored")
+
+	// The stored error retains the full structure.
+	stored, err := unmarshalOrderError(model.Error)
+	test.AssertNotError(t, err, "unmarshalOrderError should not have errored")
+	test.AssertDeepEquals(t, stored, prob)
+
+	// An SA which predates the format, and unmarshals the stored error as a
+	// corepb.ProblemDetails, can still read the top-level problem.
+	var legacy corepb.ProblemDetails
+	err = json.Unmarshal(model.Error, &legacy)
+	test.AssertNotError(t, err, "unmarshaling stored error as legacy format")
+	test.AssertEquals(t, legacy.ProblemType, prob.ProblemType)
+	test.AssertEquals(t, legacy.Detail, prob.Detail)
+	test.AssertEquals(t, legacy.HttpStatus, prob.HttpStatus)
+
+	// The order itself only carries the top-level problem.
+	order, err := modelToOrder(model)
+	test.AssertNotError(t, err, "modelToOrder should not have errored")
+	test.AssertEquals(t, order.Error.ProblemType, prob.ProblemType)
+	test.AssertEquals(t, order.Error.Detail, prob.Detail)
+	test.AssertEquals(t, order.Error.HttpStatus, prob.HttpStatus)
+	test.AssertEquals(t, len(order.Error.SubProblems), 0)
+}
+
+// TestOrderModelLegacyError tests that order errors stored as a JSON-encoded
+// corepb.ProblemDetails, before subproblems were preserved, still deserialize.
+func TestOrderModelLegacyError(t *testing.T) {
+	legacyJSON := []byte(`{"problemType":"caa","detail":"CAA record for example.com prevents issuance","httpStatus":403}`)
+	expected := &corepb.ProblemDetails{
+		ProblemType: "caa",
+		Detail:      "CAA record for example.com prevents issuance",
+		HttpStatus:  403,
+	}
+
+	stored, err := unmarshalOrderError(legacyJSON)
+	test.AssertNotError(t, err, "unmarshalOrderError should not have errored")
+	test.AssertDeepEquals(t, stored, expected)
+
+	order, err := modelToOrder(&orderModel{Error: legacyJSON})
+	test.AssertNotError(t, err, "modelToOrder should not have errored")
+	test.AssertDeepEquals(t, order.Error, expected)
+}
+
+func TestDeactivatedAuthzProblem(t *testing.T) {
+	validities := []authzValidity{
+		{ID: 1, IdentifierType: identifierTypeToUint["dns"], IdentifierValue: "example.com", Status: statusUint(core.StatusValid)},
+		{ID: 2, IdentifierType: identifierTypeToUint["dns"], IdentifierValue: "example.net", Status: statusUint(core.StatusInvalid)},
+	}
+	prob, err := deactivatedAuthzProblem(validities)
+	test.AssertNotError(t, err, "deactivatedAuthzProblem failed")
+	test.AssertBoxedNil(t, prob, "expected no problem without a deactivated authorization")
+
+	validities = append(validities, authzValidity{
+		ID: 3, IdentifierType: identifierTypeToUint["dns"], IdentifierValue: "example.org", Status: statusUint(core.StatusDeactivated),
+	})
+	prob, err = deactivatedAuthzProblem(validities)
+	test.AssertNotError(t, err, "deactivatedAuthzProblem failed")
+	test.AssertEquals(t, prob.ProblemType, string(probs.UnauthorizedProblem))
+	test.AssertEquals(t, prob.Detail, `Authorization 3 for dns identifier "example.org" was deactivated`)
+}
+
+func TestPerspectiveResultsToJSON(t *testing.T) {
+	// No results are stored as NULL.
+	j, err := perspectiveResultsToJSON(nil)
+	test.AssertNotError(t, err, "perspectiveResultsToJSON failed")
+	test.Assert(t, j == nil, "expected nil JSON for no results")
+
+	results := make([]*corepb.PerspectiveResult, core.MaxPerspectiveResults)
+	for i := range results {
+		results[i] = &corepb.PerspectiveResult{Perspective: fmt.Sprintf("remote%d", i), Passed: true}
+	}
+	_, err = perspectiveResultsToJSON(results)
+	test.AssertNotError(t, err, "perspectiveResultsToJSON failed at the limit")
+
+	_, err = perspectiveResultsToJSON(append(results, &corepb.PerspectiveResult{Perspective: "one too many"}))
+	test.AssertError(t, err, "perspectiveResultsToJSON should reject too many results")
+}
+
 // TestPopulateAttemptedFieldsBadJSON tests that populating a challenge from an
 // authz2 model with an invalid validation error or an invalid validation record
 // produces the expected bad JSON error.
@@ -290,6 +397,12 @@ func TestPopulateAttemptedFieldsBadJSON(t *testing.T) {
 				ValidationRecord: badJSON,
 			},
 		},
+		{
+			Name: "Bad perspective results field",
+			Model: &authzModel{
+				PerspectiveResults: badJSON,
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
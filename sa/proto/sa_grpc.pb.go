@@ -22,34 +22,39 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	StorageAuthorityReadOnly_CountInvalidAuthorizations2_FullMethodName  = "/sa.StorageAuthorityReadOnly/CountInvalidAuthorizations2"
-	StorageAuthorityReadOnly_CountPendingAuthorizations2_FullMethodName  = "/sa.StorageAuthorityReadOnly/CountPendingAuthorizations2"
-	StorageAuthorityReadOnly_FQDNSetExists_FullMethodName                = "/sa.StorageAuthorityReadOnly/FQDNSetExists"
-	StorageAuthorityReadOnly_FQDNSetTimestampsForWindow_FullMethodName   = "/sa.StorageAuthorityReadOnly/FQDNSetTimestampsForWindow"
-	StorageAuthorityReadOnly_GetAuthorization2_FullMethodName            = "/sa.StorageAuthorityReadOnly/GetAuthorization2"
-	StorageAuthorityReadOnly_GetAuthorizations2_FullMethodName           = "/sa.StorageAuthorityReadOnly/GetAuthorizations2"
-	StorageAuthorityReadOnly_GetCertificate_FullMethodName               = "/sa.StorageAuthorityReadOnly/GetCertificate"
-	StorageAuthorityReadOnly_GetLintPrecertificate_FullMethodName        = "/sa.StorageAuthorityReadOnly/GetLintPrecertificate"
-	StorageAuthorityReadOnly_GetCertificateStatus_FullMethodName         = "/sa.StorageAuthorityReadOnly/GetCertificateStatus"
-	StorageAuthorityReadOnly_GetMaxExpiration_FullMethodName             = "/sa.StorageAuthorityReadOnly/GetMaxExpiration"
-	StorageAuthorityReadOnly_GetOrder_FullMethodName                     = "/sa.StorageAuthorityReadOnly/GetOrder"
-	StorageAuthorityReadOnly_GetOrderForNames_FullMethodName             = "/sa.StorageAuthorityReadOnly/GetOrderForNames"
-	StorageAuthorityReadOnly_GetRegistration_FullMethodName              = "/sa.StorageAuthorityReadOnly/GetRegistration"
-	StorageAuthorityReadOnly_GetRegistrationByKey_FullMethodName         = "/sa.StorageAuthorityReadOnly/GetRegistrationByKey"
-	StorageAuthorityReadOnly_GetRevocationStatus_FullMethodName          = "/sa.StorageAuthorityReadOnly/GetRevocationStatus"
-	StorageAuthorityReadOnly_GetRevokedCerts_FullMethodName              = "/sa.StorageAuthorityReadOnly/GetRevokedCerts"
-	StorageAuthorityReadOnly_GetRevokedCertsByShard_FullMethodName       = "/sa.StorageAuthorityReadOnly/GetRevokedCertsByShard"
-	StorageAuthorityReadOnly_GetSerialMetadata_FullMethodName            = "/sa.StorageAuthorityReadOnly/GetSerialMetadata"
-	StorageAuthorityReadOnly_GetSerialsByAccount_FullMethodName          = "/sa.StorageAuthorityReadOnly/GetSerialsByAccount"
-	StorageAuthorityReadOnly_GetSerialsByKey_FullMethodName              = "/sa.StorageAuthorityReadOnly/GetSerialsByKey"
-	StorageAuthorityReadOnly_GetValidAuthorizations2_FullMethodName      = "/sa.StorageAuthorityReadOnly/GetValidAuthorizations2"
-	StorageAuthorityReadOnly_GetValidOrderAuthorizations2_FullMethodName = "/sa.StorageAuthorityReadOnly/GetValidOrderAuthorizations2"
-	StorageAuthorityReadOnly_IncidentsForSerial_FullMethodName           = "/sa.StorageAuthorityReadOnly/IncidentsForSerial"
-	StorageAuthorityReadOnly_KeyBlocked_FullMethodName                   = "/sa.StorageAuthorityReadOnly/KeyBlocked"
-	StorageAuthorityReadOnly_ReplacementOrderExists_FullMethodName       = "/sa.StorageAuthorityReadOnly/ReplacementOrderExists"
-	StorageAuthorityReadOnly_SerialsForIncident_FullMethodName           = "/sa.StorageAuthorityReadOnly/SerialsForIncident"
-	StorageAuthorityReadOnly_CheckIdentifiersPaused_FullMethodName       = "/sa.StorageAuthorityReadOnly/CheckIdentifiersPaused"
-	StorageAuthorityReadOnly_GetPausedIdentifiers_FullMethodName         = "/sa.StorageAuthorityReadOnly/GetPausedIdentifiers"
+	StorageAuthorityReadOnly_CountInvalidAuthorizations2_FullMethodName      = "/sa.StorageAuthorityReadOnly/CountInvalidAuthorizations2"
+	StorageAuthorityReadOnly_CountPendingAuthorizations2_FullMethodName      = "/sa.StorageAuthorityReadOnly/CountPendingAuthorizations2"
+	StorageAuthorityReadOnly_GetPendingAuthorizationsByExpiry_FullMethodName = "/sa.StorageAuthorityReadOnly/GetPendingAuthorizationsByExpiry"
+	StorageAuthorityReadOnly_FQDNSetExists_FullMethodName                    = "/sa.StorageAuthorityReadOnly/FQDNSetExists"
+	StorageAuthorityReadOnly_FQDNSetTimestampsForWindow_FullMethodName       = "/sa.StorageAuthorityReadOnly/FQDNSetTimestampsForWindow"
+	StorageAuthorityReadOnly_GetAuthorization2_FullMethodName                = "/sa.StorageAuthorityReadOnly/GetAuthorization2"
+	StorageAuthorityReadOnly_GetAuthorizations2_FullMethodName               = "/sa.StorageAuthorityReadOnly/GetAuthorizations2"
+	StorageAuthorityReadOnly_GetCertificate_FullMethodName                   = "/sa.StorageAuthorityReadOnly/GetCertificate"
+	StorageAuthorityReadOnly_GetLintPrecertificate_FullMethodName            = "/sa.StorageAuthorityReadOnly/GetLintPrecertificate"
+	StorageAuthorityReadOnly_GetCertificateStatus_FullMethodName             = "/sa.StorageAuthorityReadOnly/GetCertificateStatus"
+	StorageAuthorityReadOnly_GetEABKey_FullMethodName                        = "/sa.StorageAuthorityReadOnly/GetEABKey"
+	StorageAuthorityReadOnly_GetMaxExpiration_FullMethodName                 = "/sa.StorageAuthorityReadOnly/GetMaxExpiration"
+	StorageAuthorityReadOnly_GetOrder_FullMethodName                         = "/sa.StorageAuthorityReadOnly/GetOrder"
+	StorageAuthorityReadOnly_GetOrderError_FullMethodName                    = "/sa.StorageAuthorityReadOnly/GetOrderError"
+	StorageAuthorityReadOnly_GetOrderForNames_FullMethodName                 = "/sa.StorageAuthorityReadOnly/GetOrderForNames"
+	StorageAuthorityReadOnly_GetPendingOrdersForAuthorization_FullMethodName = "/sa.StorageAuthorityReadOnly/GetPendingOrdersForAuthorization"
+	StorageAuthorityReadOnly_GetAbandonedOrders_FullMethodName               = "/sa.StorageAuthorityReadOnly/GetAbandonedOrders"
+	StorageAuthorityReadOnly_GetRegistration_FullMethodName                  = "/sa.StorageAuthorityReadOnly/GetRegistration"
+	StorageAuthorityReadOnly_GetRegistrationByKey_FullMethodName             = "/sa.StorageAuthorityReadOnly/GetRegistrationByKey"
+	StorageAuthorityReadOnly_GetRevocationStatus_FullMethodName              = "/sa.StorageAuthorityReadOnly/GetRevocationStatus"
+	StorageAuthorityReadOnly_GetRevokedCerts_FullMethodName                  = "/sa.StorageAuthorityReadOnly/GetRevokedCerts"
+	StorageAuthorityReadOnly_GetRevokedCertsByShard_FullMethodName           = "/sa.StorageAuthorityReadOnly/GetRevokedCertsByShard"
+	StorageAuthorityReadOnly_GetSerialMetadata_FullMethodName                = "/sa.StorageAuthorityReadOnly/GetSerialMetadata"
+	StorageAuthorityReadOnly_GetSerialsByAccount_FullMethodName              = "/sa.StorageAuthorityReadOnly/GetSerialsByAccount"
+	StorageAuthorityReadOnly_GetSerialsByKey_FullMethodName                  = "/sa.StorageAuthorityReadOnly/GetSerialsByKey"
+	StorageAuthorityReadOnly_GetValidAuthorizations2_FullMethodName          = "/sa.StorageAuthorityReadOnly/GetValidAuthorizations2"
+	StorageAuthorityReadOnly_GetValidOrderAuthorizations2_FullMethodName     = "/sa.StorageAuthorityReadOnly/GetValidOrderAuthorizations2"
+	StorageAuthorityReadOnly_IncidentsForSerial_FullMethodName               = "/sa.StorageAuthorityReadOnly/IncidentsForSerial"
+	StorageAuthorityReadOnly_KeyBlocked_FullMethodName                       = "/sa.StorageAuthorityReadOnly/KeyBlocked"
+	StorageAuthorityReadOnly_ReplacementOrderExists_FullMethodName           = "/sa.StorageAuthorityReadOnly/ReplacementOrderExists"
+	StorageAuthorityReadOnly_SerialsForIncident_FullMethodName               = "/sa.StorageAuthorityReadOnly/SerialsForIncident"
+	StorageAuthorityReadOnly_CheckIdentifiersPaused_FullMethodName           = "/sa.StorageAuthorityReadOnly/CheckIdentifiersPaused"
+	StorageAuthorityReadOnly_GetPausedIdentifiers_FullMethodName             = "/sa.StorageAuthorityReadOnly/GetPausedIdentifiers"
 )
 
 // StorageAuthorityReadOnlyClient is the client API for StorageAuthorityReadOnly service.
@@ -58,6 +63,7 @@ const (
 type StorageAuthorityReadOnlyClient interface {
 	CountInvalidAuthorizations2(ctx context.Context, in *CountInvalidAuthorizationsRequest, opts ...grpc.CallOption) (*Count, error)
 	CountPendingAuthorizations2(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*Count, error)
+	GetPendingAuthorizationsByExpiry(ctx context.Context, in *GetPendingAuthorizationsByExpiryRequest, opts ...grpc.CallOption) (*Authorizations, error)
 	FQDNSetExists(ctx context.Context, in *FQDNSetExistsRequest, opts ...grpc.CallOption) (*Exists, error)
 	FQDNSetTimestampsForWindow(ctx context.Context, in *CountFQDNSetsRequest, opts ...grpc.CallOption) (*Timestamps, error)
 	GetAuthorization2(ctx context.Context, in *AuthorizationID2, opts ...grpc.CallOption) (*proto.Authorization, error)
@@ -65,9 +71,13 @@ type StorageAuthorityReadOnlyClient interface {
 	GetCertificate(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*proto.Certificate, error)
 	GetLintPrecertificate(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*proto.Certificate, error)
 	GetCertificateStatus(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*proto.CertificateStatus, error)
+	GetEABKey(ctx context.Context, in *EABKeyID, opts ...grpc.CallOption) (*EABKey, error)
 	GetMaxExpiration(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*timestamppb.Timestamp, error)
 	GetOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*proto.Order, error)
+	GetOrderError(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*proto.ProblemDetails, error)
 	GetOrderForNames(ctx context.Context, in *GetOrderForNamesRequest, opts ...grpc.CallOption) (*proto.Order, error)
+	GetPendingOrdersForAuthorization(ctx context.Context, in *GetPendingOrdersForAuthorizationRequest, opts ...grpc.CallOption) (*OrderIDs, error)
+	GetAbandonedOrders(ctx context.Context, in *GetAbandonedOrdersRequest, opts ...grpc.CallOption) (*Orders, error)
 	GetRegistration(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*proto.Registration, error)
 	GetRegistrationByKey(ctx context.Context, in *JSONWebKey, opts ...grpc.CallOption) (*proto.Registration, error)
 	GetRevocationStatus(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*RevocationStatus, error)
@@ -114,6 +124,16 @@ func (c *storageAuthorityReadOnlyClient) CountPendingAuthorizations2(ctx context
 	return out, nil
 }
 
+func (c *storageAuthorityReadOnlyClient) GetPendingAuthorizationsByExpiry(ctx context.Context, in *GetPendingAuthorizationsByExpiryRequest, opts ...grpc.CallOption) (*Authorizations, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Authorizations)
+	err := c.cc.Invoke(ctx, StorageAuthorityReadOnly_GetPendingAuthorizationsByExpiry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityReadOnlyClient) FQDNSetExists(ctx context.Context, in *FQDNSetExistsRequest, opts ...grpc.CallOption) (*Exists, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Exists)
@@ -184,6 +204,16 @@ func (c *storageAuthorityReadOnlyClient) GetCertificateStatus(ctx context.Contex
 	return out, nil
 }
 
+func (c *storageAuthorityReadOnlyClient) GetEABKey(ctx context.Context, in *EABKeyID, opts ...grpc.CallOption) (*EABKey, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EABKey)
+	err := c.cc.Invoke(ctx, StorageAuthorityReadOnly_GetEABKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityReadOnlyClient) GetMaxExpiration(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*timestamppb.Timestamp, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(timestamppb.Timestamp)
@@ -204,6 +234,16 @@ func (c *storageAuthorityReadOnlyClient) GetOrder(ctx context.Context, in *Order
 	return out, nil
 }
 
+func (c *storageAuthorityReadOnlyClient) GetOrderError(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*proto.ProblemDetails, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(proto.ProblemDetails)
+	err := c.cc.Invoke(ctx, StorageAuthorityReadOnly_GetOrderError_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityReadOnlyClient) GetOrderForNames(ctx context.Context, in *GetOrderForNamesRequest, opts ...grpc.CallOption) (*proto.Order, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(proto.Order)
@@ -214,6 +254,26 @@ func (c *storageAuthorityReadOnlyClient) GetOrderForNames(ctx context.Context, i
 	return out, nil
 }
 
+func (c *storageAuthorityReadOnlyClient) GetPendingOrdersForAuthorization(ctx context.Context, in *GetPendingOrdersForAuthorizationRequest, opts ...grpc.CallOption) (*OrderIDs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderIDs)
+	err := c.cc.Invoke(ctx, StorageAuthorityReadOnly_GetPendingOrdersForAuthorization_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityReadOnlyClient) GetAbandonedOrders(ctx context.Context, in *GetAbandonedOrdersRequest, opts ...grpc.CallOption) (*Orders, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Orders)
+	err := c.cc.Invoke(ctx, StorageAuthorityReadOnly_GetAbandonedOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityReadOnlyClient) GetRegistration(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*proto.Registration, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(proto.Registration)
@@ -425,6 +485,7 @@ func (c *storageAuthorityReadOnlyClient) GetPausedIdentifiers(ctx context.Contex
 type StorageAuthorityReadOnlyServer interface {
 	CountInvalidAuthorizations2(context.Context, *CountInvalidAuthorizationsRequest) (*Count, error)
 	CountPendingAuthorizations2(context.Context, *RegistrationID) (*Count, error)
+	GetPendingAuthorizationsByExpiry(context.Context, *GetPendingAuthorizationsByExpiryRequest) (*Authorizations, error)
 	FQDNSetExists(context.Context, *FQDNSetExistsRequest) (*Exists, error)
 	FQDNSetTimestampsForWindow(context.Context, *CountFQDNSetsRequest) (*Timestamps, error)
 	GetAuthorization2(context.Context, *AuthorizationID2) (*proto.Authorization, error)
@@ -432,9 +493,13 @@ type StorageAuthorityReadOnlyServer interface {
 	GetCertificate(context.Context, *Serial) (*proto.Certificate, error)
 	GetLintPrecertificate(context.Context, *Serial) (*proto.Certificate, error)
 	GetCertificateStatus(context.Context, *Serial) (*proto.CertificateStatus, error)
+	GetEABKey(context.Context, *EABKeyID) (*EABKey, error)
 	GetMaxExpiration(context.Context, *emptypb.Empty) (*timestamppb.Timestamp, error)
 	GetOrder(context.Context, *OrderRequest) (*proto.Order, error)
+	GetOrderError(context.Context, *OrderRequest) (*proto.ProblemDetails, error)
 	GetOrderForNames(context.Context, *GetOrderForNamesRequest) (*proto.Order, error)
+	GetPendingOrdersForAuthorization(context.Context, *GetPendingOrdersForAuthorizationRequest) (*OrderIDs, error)
+	GetAbandonedOrders(context.Context, *GetAbandonedOrdersRequest) (*Orders, error)
 	GetRegistration(context.Context, *RegistrationID) (*proto.Registration, error)
 	GetRegistrationByKey(context.Context, *JSONWebKey) (*proto.Registration, error)
 	GetRevocationStatus(context.Context, *Serial) (*RevocationStatus, error)
@@ -464,6 +529,9 @@ func (UnimplementedStorageAuthorityReadOnlyServer) CountInvalidAuthorizations2(c
 func (UnimplementedStorageAuthorityReadOnlyServer) CountPendingAuthorizations2(context.Context, *RegistrationID) (*Count, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CountPendingAuthorizations2 not implemented")
 }
+func (UnimplementedStorageAuthorityReadOnlyServer) GetPendingAuthorizationsByExpiry(context.Context, *GetPendingAuthorizationsByExpiryRequest) (*Authorizations, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPendingAuthorizationsByExpiry not implemented")
+}
 func (UnimplementedStorageAuthorityReadOnlyServer) FQDNSetExists(context.Context, *FQDNSetExistsRequest) (*Exists, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FQDNSetExists not implemented")
 }
@@ -485,15 +553,27 @@ func (UnimplementedStorageAuthorityReadOnlyServer) GetLintPrecertificate(context
 func (UnimplementedStorageAuthorityReadOnlyServer) GetCertificateStatus(context.Context, *Serial) (*proto.CertificateStatus, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetCertificateStatus not implemented")
 }
+func (UnimplementedStorageAuthorityReadOnlyServer) GetEABKey(context.Context, *EABKeyID) (*EABKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEABKey not implemented")
+}
 func (UnimplementedStorageAuthorityReadOnlyServer) GetMaxExpiration(context.Context, *emptypb.Empty) (*timestamppb.Timestamp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMaxExpiration not implemented")
 }
 func (UnimplementedStorageAuthorityReadOnlyServer) GetOrder(context.Context, *OrderRequest) (*proto.Order, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
 }
+func (UnimplementedStorageAuthorityReadOnlyServer) GetOrderError(context.Context, *OrderRequest) (*proto.ProblemDetails, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderError not implemented")
+}
 func (UnimplementedStorageAuthorityReadOnlyServer) GetOrderForNames(context.Context, *GetOrderForNamesRequest) (*proto.Order, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOrderForNames not implemented")
 }
+func (UnimplementedStorageAuthorityReadOnlyServer) GetPendingOrdersForAuthorization(context.Context, *GetPendingOrdersForAuthorizationRequest) (*OrderIDs, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPendingOrdersForAuthorization not implemented")
+}
+func (UnimplementedStorageAuthorityReadOnlyServer) GetAbandonedOrders(context.Context, *GetAbandonedOrdersRequest) (*Orders, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAbandonedOrders not implemented")
+}
 func (UnimplementedStorageAuthorityReadOnlyServer) GetRegistration(context.Context, *RegistrationID) (*proto.Registration, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRegistration not implemented")
 }
@@ -592,6 +672,24 @@ func _StorageAuthorityReadOnly_CountPendingAuthorizations2_Handler(srv interface
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthorityReadOnly_GetPendingAuthorizationsByExpiry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPendingAuthorizationsByExpiryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityReadOnlyServer).GetPendingAuthorizationsByExpiry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthorityReadOnly_GetPendingAuthorizationsByExpiry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityReadOnlyServer).GetPendingAuthorizationsByExpiry(ctx, req.(*GetPendingAuthorizationsByExpiryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthorityReadOnly_FQDNSetExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(FQDNSetExistsRequest)
 	if err := dec(in); err != nil {
@@ -718,6 +816,24 @@ func _StorageAuthorityReadOnly_GetCertificateStatus_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthorityReadOnly_GetEABKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EABKeyID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityReadOnlyServer).GetEABKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthorityReadOnly_GetEABKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityReadOnlyServer).GetEABKey(ctx, req.(*EABKeyID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthorityReadOnly_GetMaxExpiration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -754,6 +870,24 @@ func _StorageAuthorityReadOnly_GetOrder_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthorityReadOnly_GetOrderError_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityReadOnlyServer).GetOrderError(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthorityReadOnly_GetOrderError_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityReadOnlyServer).GetOrderError(ctx, req.(*OrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthorityReadOnly_GetOrderForNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetOrderForNamesRequest)
 	if err := dec(in); err != nil {
@@ -772,6 +906,42 @@ func _StorageAuthorityReadOnly_GetOrderForNames_Handler(srv interface{}, ctx con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthorityReadOnly_GetPendingOrdersForAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPendingOrdersForAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityReadOnlyServer).GetPendingOrdersForAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthorityReadOnly_GetPendingOrdersForAuthorization_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityReadOnlyServer).GetPendingOrdersForAuthorization(ctx, req.(*GetPendingOrdersForAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthorityReadOnly_GetAbandonedOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAbandonedOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityReadOnlyServer).GetAbandonedOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthorityReadOnly_GetAbandonedOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityReadOnlyServer).GetAbandonedOrders(ctx, req.(*GetAbandonedOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthorityReadOnly_GetRegistration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RegistrationID)
 	if err := dec(in); err != nil {
@@ -1040,6 +1210,10 @@ var StorageAuthorityReadOnly_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CountPendingAuthorizations2",
 			Handler:    _StorageAuthorityReadOnly_CountPendingAuthorizations2_Handler,
 		},
+		{
+			MethodName: "GetPendingAuthorizationsByExpiry",
+			Handler:    _StorageAuthorityReadOnly_GetPendingAuthorizationsByExpiry_Handler,
+		},
 		{
 			MethodName: "FQDNSetExists",
 			Handler:    _StorageAuthorityReadOnly_FQDNSetExists_Handler,
@@ -1068,6 +1242,10 @@ var StorageAuthorityReadOnly_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetCertificateStatus",
 			Handler:    _StorageAuthorityReadOnly_GetCertificateStatus_Handler,
 		},
+		{
+			MethodName: "GetEABKey",
+			Handler:    _StorageAuthorityReadOnly_GetEABKey_Handler,
+		},
 		{
 			MethodName: "GetMaxExpiration",
 			Handler:    _StorageAuthorityReadOnly_GetMaxExpiration_Handler,
@@ -1076,10 +1254,22 @@ var StorageAuthorityReadOnly_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetOrder",
 			Handler:    _StorageAuthorityReadOnly_GetOrder_Handler,
 		},
+		{
+			MethodName: "GetOrderError",
+			Handler:    _StorageAuthorityReadOnly_GetOrderError_Handler,
+		},
 		{
 			MethodName: "GetOrderForNames",
 			Handler:    _StorageAuthorityReadOnly_GetOrderForNames_Handler,
 		},
+		{
+			MethodName: "GetPendingOrdersForAuthorization",
+			Handler:    _StorageAuthorityReadOnly_GetPendingOrdersForAuthorization_Handler,
+		},
+		{
+			MethodName: "GetAbandonedOrders",
+			Handler:    _StorageAuthorityReadOnly_GetAbandonedOrders_Handler,
+		},
 		{
 			MethodName: "GetRegistration",
 			Handler:    _StorageAuthorityReadOnly_GetRegistration_Handler,
@@ -1156,55 +1346,63 @@ var StorageAuthorityReadOnly_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	StorageAuthority_CountInvalidAuthorizations2_FullMethodName  = "/sa.StorageAuthority/CountInvalidAuthorizations2"
-	StorageAuthority_CountPendingAuthorizations2_FullMethodName  = "/sa.StorageAuthority/CountPendingAuthorizations2"
-	StorageAuthority_FQDNSetExists_FullMethodName                = "/sa.StorageAuthority/FQDNSetExists"
-	StorageAuthority_FQDNSetTimestampsForWindow_FullMethodName   = "/sa.StorageAuthority/FQDNSetTimestampsForWindow"
-	StorageAuthority_GetAuthorization2_FullMethodName            = "/sa.StorageAuthority/GetAuthorization2"
-	StorageAuthority_GetAuthorizations2_FullMethodName           = "/sa.StorageAuthority/GetAuthorizations2"
-	StorageAuthority_GetCertificate_FullMethodName               = "/sa.StorageAuthority/GetCertificate"
-	StorageAuthority_GetLintPrecertificate_FullMethodName        = "/sa.StorageAuthority/GetLintPrecertificate"
-	StorageAuthority_GetCertificateStatus_FullMethodName         = "/sa.StorageAuthority/GetCertificateStatus"
-	StorageAuthority_GetMaxExpiration_FullMethodName             = "/sa.StorageAuthority/GetMaxExpiration"
-	StorageAuthority_GetOrder_FullMethodName                     = "/sa.StorageAuthority/GetOrder"
-	StorageAuthority_GetOrderForNames_FullMethodName             = "/sa.StorageAuthority/GetOrderForNames"
-	StorageAuthority_GetRegistration_FullMethodName              = "/sa.StorageAuthority/GetRegistration"
-	StorageAuthority_GetRegistrationByKey_FullMethodName         = "/sa.StorageAuthority/GetRegistrationByKey"
-	StorageAuthority_GetRevocationStatus_FullMethodName          = "/sa.StorageAuthority/GetRevocationStatus"
-	StorageAuthority_GetRevokedCerts_FullMethodName              = "/sa.StorageAuthority/GetRevokedCerts"
-	StorageAuthority_GetRevokedCertsByShard_FullMethodName       = "/sa.StorageAuthority/GetRevokedCertsByShard"
-	StorageAuthority_GetSerialMetadata_FullMethodName            = "/sa.StorageAuthority/GetSerialMetadata"
-	StorageAuthority_GetSerialsByAccount_FullMethodName          = "/sa.StorageAuthority/GetSerialsByAccount"
-	StorageAuthority_GetSerialsByKey_FullMethodName              = "/sa.StorageAuthority/GetSerialsByKey"
-	StorageAuthority_GetValidAuthorizations2_FullMethodName      = "/sa.StorageAuthority/GetValidAuthorizations2"
-	StorageAuthority_GetValidOrderAuthorizations2_FullMethodName = "/sa.StorageAuthority/GetValidOrderAuthorizations2"
-	StorageAuthority_IncidentsForSerial_FullMethodName           = "/sa.StorageAuthority/IncidentsForSerial"
-	StorageAuthority_KeyBlocked_FullMethodName                   = "/sa.StorageAuthority/KeyBlocked"
-	StorageAuthority_ReplacementOrderExists_FullMethodName       = "/sa.StorageAuthority/ReplacementOrderExists"
-	StorageAuthority_SerialsForIncident_FullMethodName           = "/sa.StorageAuthority/SerialsForIncident"
-	StorageAuthority_CheckIdentifiersPaused_FullMethodName       = "/sa.StorageAuthority/CheckIdentifiersPaused"
-	StorageAuthority_GetPausedIdentifiers_FullMethodName         = "/sa.StorageAuthority/GetPausedIdentifiers"
-	StorageAuthority_AddBlockedKey_FullMethodName                = "/sa.StorageAuthority/AddBlockedKey"
-	StorageAuthority_AddCertificate_FullMethodName               = "/sa.StorageAuthority/AddCertificate"
-	StorageAuthority_AddPrecertificate_FullMethodName            = "/sa.StorageAuthority/AddPrecertificate"
-	StorageAuthority_SetCertificateStatusReady_FullMethodName    = "/sa.StorageAuthority/SetCertificateStatusReady"
-	StorageAuthority_AddSerial_FullMethodName                    = "/sa.StorageAuthority/AddSerial"
-	StorageAuthority_DeactivateAuthorization2_FullMethodName     = "/sa.StorageAuthority/DeactivateAuthorization2"
-	StorageAuthority_DeactivateRegistration_FullMethodName       = "/sa.StorageAuthority/DeactivateRegistration"
-	StorageAuthority_FinalizeAuthorization2_FullMethodName       = "/sa.StorageAuthority/FinalizeAuthorization2"
-	StorageAuthority_FinalizeOrder_FullMethodName                = "/sa.StorageAuthority/FinalizeOrder"
-	StorageAuthority_NewOrderAndAuthzs_FullMethodName            = "/sa.StorageAuthority/NewOrderAndAuthzs"
-	StorageAuthority_NewRegistration_FullMethodName              = "/sa.StorageAuthority/NewRegistration"
-	StorageAuthority_RevokeCertificate_FullMethodName            = "/sa.StorageAuthority/RevokeCertificate"
-	StorageAuthority_SetOrderError_FullMethodName                = "/sa.StorageAuthority/SetOrderError"
-	StorageAuthority_SetOrderProcessing_FullMethodName           = "/sa.StorageAuthority/SetOrderProcessing"
-	StorageAuthority_UpdateRegistrationContact_FullMethodName    = "/sa.StorageAuthority/UpdateRegistrationContact"
-	StorageAuthority_UpdateRegistrationKey_FullMethodName        = "/sa.StorageAuthority/UpdateRegistrationKey"
-	StorageAuthority_UpdateRevokedCertificate_FullMethodName     = "/sa.StorageAuthority/UpdateRevokedCertificate"
-	StorageAuthority_LeaseCRLShard_FullMethodName                = "/sa.StorageAuthority/LeaseCRLShard"
-	StorageAuthority_UpdateCRLShard_FullMethodName               = "/sa.StorageAuthority/UpdateCRLShard"
-	StorageAuthority_PauseIdentifiers_FullMethodName             = "/sa.StorageAuthority/PauseIdentifiers"
-	StorageAuthority_UnpauseAccount_FullMethodName               = "/sa.StorageAuthority/UnpauseAccount"
+	StorageAuthority_CountInvalidAuthorizations2_FullMethodName      = "/sa.StorageAuthority/CountInvalidAuthorizations2"
+	StorageAuthority_CountPendingAuthorizations2_FullMethodName      = "/sa.StorageAuthority/CountPendingAuthorizations2"
+	StorageAuthority_GetPendingAuthorizationsByExpiry_FullMethodName = "/sa.StorageAuthority/GetPendingAuthorizationsByExpiry"
+	StorageAuthority_FQDNSetExists_FullMethodName                    = "/sa.StorageAuthority/FQDNSetExists"
+	StorageAuthority_FQDNSetTimestampsForWindow_FullMethodName       = "/sa.StorageAuthority/FQDNSetTimestampsForWindow"
+	StorageAuthority_GetAuthorization2_FullMethodName                = "/sa.StorageAuthority/GetAuthorization2"
+	StorageAuthority_GetAuthorizations2_FullMethodName               = "/sa.StorageAuthority/GetAuthorizations2"
+	StorageAuthority_GetCertificate_FullMethodName                   = "/sa.StorageAuthority/GetCertificate"
+	StorageAuthority_GetLintPrecertificate_FullMethodName            = "/sa.StorageAuthority/GetLintPrecertificate"
+	StorageAuthority_GetCertificateStatus_FullMethodName             = "/sa.StorageAuthority/GetCertificateStatus"
+	StorageAuthority_GetEABKey_FullMethodName                        = "/sa.StorageAuthority/GetEABKey"
+	StorageAuthority_GetMaxExpiration_FullMethodName                 = "/sa.StorageAuthority/GetMaxExpiration"
+	StorageAuthority_GetOrder_FullMethodName                         = "/sa.StorageAuthority/GetOrder"
+	StorageAuthority_GetOrderError_FullMethodName                    = "/sa.StorageAuthority/GetOrderError"
+	StorageAuthority_GetOrderForNames_FullMethodName                 = "/sa.StorageAuthority/GetOrderForNames"
+	StorageAuthority_GetPendingOrdersForAuthorization_FullMethodName = "/sa.StorageAuthority/GetPendingOrdersForAuthorization"
+	StorageAuthority_GetAbandonedOrders_FullMethodName               = "/sa.StorageAuthority/GetAbandonedOrders"
+	StorageAuthority_GetRegistration_FullMethodName                  = "/sa.StorageAuthority/GetRegistration"
+	StorageAuthority_GetRegistrationByKey_FullMethodName             = "/sa.StorageAuthority/GetRegistrationByKey"
+	StorageAuthority_GetRevocationStatus_FullMethodName              = "/sa.StorageAuthority/GetRevocationStatus"
+	StorageAuthority_GetRevokedCerts_FullMethodName                  = "/sa.StorageAuthority/GetRevokedCerts"
+	StorageAuthority_GetRevokedCertsByShard_FullMethodName           = "/sa.StorageAuthority/GetRevokedCertsByShard"
+	StorageAuthority_GetSerialMetadata_FullMethodName                = "/sa.StorageAuthority/GetSerialMetadata"
+	StorageAuthority_GetSerialsByAccount_FullMethodName              = "/sa.StorageAuthority/GetSerialsByAccount"
+	StorageAuthority_GetSerialsByKey_FullMethodName                  = "/sa.StorageAuthority/GetSerialsByKey"
+	StorageAuthority_GetValidAuthorizations2_FullMethodName          = "/sa.StorageAuthority/GetValidAuthorizations2"
+	StorageAuthority_GetValidOrderAuthorizations2_FullMethodName     = "/sa.StorageAuthority/GetValidOrderAuthorizations2"
+	StorageAuthority_IncidentsForSerial_FullMethodName               = "/sa.StorageAuthority/IncidentsForSerial"
+	StorageAuthority_KeyBlocked_FullMethodName                       = "/sa.StorageAuthority/KeyBlocked"
+	StorageAuthority_ReplacementOrderExists_FullMethodName           = "/sa.StorageAuthority/ReplacementOrderExists"
+	StorageAuthority_SerialsForIncident_FullMethodName               = "/sa.StorageAuthority/SerialsForIncident"
+	StorageAuthority_CheckIdentifiersPaused_FullMethodName           = "/sa.StorageAuthority/CheckIdentifiersPaused"
+	StorageAuthority_GetPausedIdentifiers_FullMethodName             = "/sa.StorageAuthority/GetPausedIdentifiers"
+	StorageAuthority_AddBlockedKey_FullMethodName                    = "/sa.StorageAuthority/AddBlockedKey"
+	StorageAuthority_AddCertificate_FullMethodName                   = "/sa.StorageAuthority/AddCertificate"
+	StorageAuthority_AddPrecertificate_FullMethodName                = "/sa.StorageAuthority/AddPrecertificate"
+	StorageAuthority_SetCertificateStatusReady_FullMethodName        = "/sa.StorageAuthority/SetCertificateStatusReady"
+	StorageAuthority_AddSerial_FullMethodName                        = "/sa.StorageAuthority/AddSerial"
+	StorageAuthority_DeactivateAuthorization2_FullMethodName         = "/sa.StorageAuthority/DeactivateAuthorization2"
+	StorageAuthority_DeactivateRegistration_FullMethodName           = "/sa.StorageAuthority/DeactivateRegistration"
+	StorageAuthority_FinalizeAuthorization2_FullMethodName           = "/sa.StorageAuthority/FinalizeAuthorization2"
+	StorageAuthority_FinalizeOrder_FullMethodName                    = "/sa.StorageAuthority/FinalizeOrder"
+	StorageAuthority_SetAuthorizationFailureCounted_FullMethodName   = "/sa.StorageAuthority/SetAuthorizationFailureCounted"
+	StorageAuthority_AddValidationAttempt_FullMethodName             = "/sa.StorageAuthority/AddValidationAttempt"
+	StorageAuthority_NewOrderAndAuthzs_FullMethodName                = "/sa.StorageAuthority/NewOrderAndAuthzs"
+	StorageAuthority_NewRegistration_FullMethodName                  = "/sa.StorageAuthority/NewRegistration"
+	StorageAuthority_NewRegistrationClaimingEABKeyID_FullMethodName  = "/sa.StorageAuthority/NewRegistrationClaimingEABKeyID"
+	StorageAuthority_RevokeCertificate_FullMethodName                = "/sa.StorageAuthority/RevokeCertificate"
+	StorageAuthority_SetOrderError_FullMethodName                    = "/sa.StorageAuthority/SetOrderError"
+	StorageAuthority_SetOrderProcessing_FullMethodName               = "/sa.StorageAuthority/SetOrderProcessing"
+	StorageAuthority_UpdateRegistrationContact_FullMethodName        = "/sa.StorageAuthority/UpdateRegistrationContact"
+	StorageAuthority_UpdateRegistrationKey_FullMethodName            = "/sa.StorageAuthority/UpdateRegistrationKey"
+	StorageAuthority_UpdateRevokedCertificate_FullMethodName         = "/sa.StorageAuthority/UpdateRevokedCertificate"
+	StorageAuthority_LeaseCRLShard_FullMethodName                    = "/sa.StorageAuthority/LeaseCRLShard"
+	StorageAuthority_UpdateCRLShard_FullMethodName                   = "/sa.StorageAuthority/UpdateCRLShard"
+	StorageAuthority_PauseIdentifiers_FullMethodName                 = "/sa.StorageAuthority/PauseIdentifiers"
+	StorageAuthority_UnpauseAccount_FullMethodName                   = "/sa.StorageAuthority/UnpauseAccount"
 )
 
 // StorageAuthorityClient is the client API for StorageAuthority service.
@@ -1214,6 +1412,7 @@ type StorageAuthorityClient interface {
 	// Getters: this list must be identical to the StorageAuthorityReadOnly rpcs.
 	CountInvalidAuthorizations2(ctx context.Context, in *CountInvalidAuthorizationsRequest, opts ...grpc.CallOption) (*Count, error)
 	CountPendingAuthorizations2(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*Count, error)
+	GetPendingAuthorizationsByExpiry(ctx context.Context, in *GetPendingAuthorizationsByExpiryRequest, opts ...grpc.CallOption) (*Authorizations, error)
 	FQDNSetExists(ctx context.Context, in *FQDNSetExistsRequest, opts ...grpc.CallOption) (*Exists, error)
 	FQDNSetTimestampsForWindow(ctx context.Context, in *CountFQDNSetsRequest, opts ...grpc.CallOption) (*Timestamps, error)
 	GetAuthorization2(ctx context.Context, in *AuthorizationID2, opts ...grpc.CallOption) (*proto.Authorization, error)
@@ -1221,9 +1420,13 @@ type StorageAuthorityClient interface {
 	GetCertificate(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*proto.Certificate, error)
 	GetLintPrecertificate(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*proto.Certificate, error)
 	GetCertificateStatus(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*proto.CertificateStatus, error)
+	GetEABKey(ctx context.Context, in *EABKeyID, opts ...grpc.CallOption) (*EABKey, error)
 	GetMaxExpiration(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*timestamppb.Timestamp, error)
 	GetOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*proto.Order, error)
+	GetOrderError(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*proto.ProblemDetails, error)
 	GetOrderForNames(ctx context.Context, in *GetOrderForNamesRequest, opts ...grpc.CallOption) (*proto.Order, error)
+	GetPendingOrdersForAuthorization(ctx context.Context, in *GetPendingOrdersForAuthorizationRequest, opts ...grpc.CallOption) (*OrderIDs, error)
+	GetAbandonedOrders(ctx context.Context, in *GetAbandonedOrdersRequest, opts ...grpc.CallOption) (*Orders, error)
 	GetRegistration(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*proto.Registration, error)
 	GetRegistrationByKey(ctx context.Context, in *JSONWebKey, opts ...grpc.CallOption) (*proto.Registration, error)
 	GetRevocationStatus(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*RevocationStatus, error)
@@ -1250,8 +1453,16 @@ type StorageAuthorityClient interface {
 	DeactivateRegistration(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	FinalizeAuthorization2(ctx context.Context, in *FinalizeAuthorizationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	FinalizeOrder(ctx context.Context, in *FinalizeOrderRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	SetAuthorizationFailureCounted(ctx context.Context, in *SetAuthorizationFailureCountedRequest, opts ...grpc.CallOption) (*SetAuthorizationFailureCountedResponse, error)
+	// AddValidationAttempt appends an attempt to the validation history of an
+	// authorization, whatever its status, keeping only the most recent ones.
+	AddValidationAttempt(ctx context.Context, in *AddValidationAttemptRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	NewOrderAndAuthzs(ctx context.Context, in *NewOrderAndAuthzsRequest, opts ...grpc.CallOption) (*proto.Order, error)
 	NewRegistration(ctx context.Context, in *proto.Registration, opts ...grpc.CallOption) (*proto.Registration, error)
+	// NewRegistrationClaimingEABKeyID stores a new Registration, as
+	// NewRegistration does, unless another was already stored by this method
+	// with the same eabKeyID.
+	NewRegistrationClaimingEABKeyID(ctx context.Context, in *proto.Registration, opts ...grpc.CallOption) (*proto.Registration, error)
 	RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	SetOrderError(ctx context.Context, in *SetOrderErrorRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	SetOrderProcessing(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
@@ -1292,6 +1503,16 @@ func (c *storageAuthorityClient) CountPendingAuthorizations2(ctx context.Context
 	return out, nil
 }
 
+func (c *storageAuthorityClient) GetPendingAuthorizationsByExpiry(ctx context.Context, in *GetPendingAuthorizationsByExpiryRequest, opts ...grpc.CallOption) (*Authorizations, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Authorizations)
+	err := c.cc.Invoke(ctx, StorageAuthority_GetPendingAuthorizationsByExpiry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityClient) FQDNSetExists(ctx context.Context, in *FQDNSetExistsRequest, opts ...grpc.CallOption) (*Exists, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Exists)
@@ -1362,6 +1583,16 @@ func (c *storageAuthorityClient) GetCertificateStatus(ctx context.Context, in *S
 	return out, nil
 }
 
+func (c *storageAuthorityClient) GetEABKey(ctx context.Context, in *EABKeyID, opts ...grpc.CallOption) (*EABKey, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EABKey)
+	err := c.cc.Invoke(ctx, StorageAuthority_GetEABKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityClient) GetMaxExpiration(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*timestamppb.Timestamp, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(timestamppb.Timestamp)
@@ -1382,6 +1613,16 @@ func (c *storageAuthorityClient) GetOrder(ctx context.Context, in *OrderRequest,
 	return out, nil
 }
 
+func (c *storageAuthorityClient) GetOrderError(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*proto.ProblemDetails, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(proto.ProblemDetails)
+	err := c.cc.Invoke(ctx, StorageAuthority_GetOrderError_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityClient) GetOrderForNames(ctx context.Context, in *GetOrderForNamesRequest, opts ...grpc.CallOption) (*proto.Order, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(proto.Order)
@@ -1392,6 +1633,26 @@ func (c *storageAuthorityClient) GetOrderForNames(ctx context.Context, in *GetOr
 	return out, nil
 }
 
+func (c *storageAuthorityClient) GetPendingOrdersForAuthorization(ctx context.Context, in *GetPendingOrdersForAuthorizationRequest, opts ...grpc.CallOption) (*OrderIDs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderIDs)
+	err := c.cc.Invoke(ctx, StorageAuthority_GetPendingOrdersForAuthorization_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) GetAbandonedOrders(ctx context.Context, in *GetAbandonedOrdersRequest, opts ...grpc.CallOption) (*Orders, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Orders)
+	err := c.cc.Invoke(ctx, StorageAuthority_GetAbandonedOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityClient) GetRegistration(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*proto.Registration, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(proto.Registration)
@@ -1687,6 +1948,26 @@ func (c *storageAuthorityClient) FinalizeOrder(ctx context.Context, in *Finalize
 	return out, nil
 }
 
+func (c *storageAuthorityClient) SetAuthorizationFailureCounted(ctx context.Context, in *SetAuthorizationFailureCountedRequest, opts ...grpc.CallOption) (*SetAuthorizationFailureCountedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetAuthorizationFailureCountedResponse)
+	err := c.cc.Invoke(ctx, StorageAuthority_SetAuthorizationFailureCounted_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) AddValidationAttempt(ctx context.Context, in *AddValidationAttemptRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, StorageAuthority_AddValidationAttempt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityClient) NewOrderAndAuthzs(ctx context.Context, in *NewOrderAndAuthzsRequest, opts ...grpc.CallOption) (*proto.Order, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(proto.Order)
@@ -1707,6 +1988,16 @@ func (c *storageAuthorityClient) NewRegistration(ctx context.Context, in *proto.
 	return out, nil
 }
 
+func (c *storageAuthorityClient) NewRegistrationClaimingEABKeyID(ctx context.Context, in *proto.Registration, opts ...grpc.CallOption) (*proto.Registration, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(proto.Registration)
+	err := c.cc.Invoke(ctx, StorageAuthority_NewRegistrationClaimingEABKeyID_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityClient) RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(emptypb.Empty)
@@ -1814,6 +2105,7 @@ type StorageAuthorityServer interface {
 	// Getters: this list must be identical to the StorageAuthorityReadOnly rpcs.
 	CountInvalidAuthorizations2(context.Context, *CountInvalidAuthorizationsRequest) (*Count, error)
 	CountPendingAuthorizations2(context.Context, *RegistrationID) (*Count, error)
+	GetPendingAuthorizationsByExpiry(context.Context, *GetPendingAuthorizationsByExpiryRequest) (*Authorizations, error)
 	FQDNSetExists(context.Context, *FQDNSetExistsRequest) (*Exists, error)
 	FQDNSetTimestampsForWindow(context.Context, *CountFQDNSetsRequest) (*Timestamps, error)
 	GetAuthorization2(context.Context, *AuthorizationID2) (*proto.Authorization, error)
@@ -1821,9 +2113,13 @@ type StorageAuthorityServer interface {
 	GetCertificate(context.Context, *Serial) (*proto.Certificate, error)
 	GetLintPrecertificate(context.Context, *Serial) (*proto.Certificate, error)
 	GetCertificateStatus(context.Context, *Serial) (*proto.CertificateStatus, error)
+	GetEABKey(context.Context, *EABKeyID) (*EABKey, error)
 	GetMaxExpiration(context.Context, *emptypb.Empty) (*timestamppb.Timestamp, error)
 	GetOrder(context.Context, *OrderRequest) (*proto.Order, error)
+	GetOrderError(context.Context, *OrderRequest) (*proto.ProblemDetails, error)
 	GetOrderForNames(context.Context, *GetOrderForNamesRequest) (*proto.Order, error)
+	GetPendingOrdersForAuthorization(context.Context, *GetPendingOrdersForAuthorizationRequest) (*OrderIDs, error)
+	GetAbandonedOrders(context.Context, *GetAbandonedOrdersRequest) (*Orders, error)
 	GetRegistration(context.Context, *RegistrationID) (*proto.Registration, error)
 	GetRegistrationByKey(context.Context, *JSONWebKey) (*proto.Registration, error)
 	GetRevocationStatus(context.Context, *Serial) (*RevocationStatus, error)
@@ -1850,8 +2146,16 @@ type StorageAuthorityServer interface {
 	DeactivateRegistration(context.Context, *RegistrationID) (*emptypb.Empty, error)
 	FinalizeAuthorization2(context.Context, *FinalizeAuthorizationRequest) (*emptypb.Empty, error)
 	FinalizeOrder(context.Context, *FinalizeOrderRequest) (*emptypb.Empty, error)
+	SetAuthorizationFailureCounted(context.Context, *SetAuthorizationFailureCountedRequest) (*SetAuthorizationFailureCountedResponse, error)
+	// AddValidationAttempt appends an attempt to the validation history of an
+	// authorization, whatever its status, keeping only the most recent ones.
+	AddValidationAttempt(context.Context, *AddValidationAttemptRequest) (*emptypb.Empty, error)
 	NewOrderAndAuthzs(context.Context, *NewOrderAndAuthzsRequest) (*proto.Order, error)
 	NewRegistration(context.Context, *proto.Registration) (*proto.Registration, error)
+	// NewRegistrationClaimingEABKeyID stores a new Registration, as
+	// NewRegistration does, unless another was already stored by this method
+	// with the same eabKeyID.
+	NewRegistrationClaimingEABKeyID(context.Context, *proto.Registration) (*proto.Registration, error)
 	RevokeCertificate(context.Context, *RevokeCertificateRequest) (*emptypb.Empty, error)
 	SetOrderError(context.Context, *SetOrderErrorRequest) (*emptypb.Empty, error)
 	SetOrderProcessing(context.Context, *OrderRequest) (*emptypb.Empty, error)
@@ -1875,6 +2179,9 @@ func (UnimplementedStorageAuthorityServer) CountInvalidAuthorizations2(context.C
 func (UnimplementedStorageAuthorityServer) CountPendingAuthorizations2(context.Context, *RegistrationID) (*Count, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CountPendingAuthorizations2 not implemented")
 }
+func (UnimplementedStorageAuthorityServer) GetPendingAuthorizationsByExpiry(context.Context, *GetPendingAuthorizationsByExpiryRequest) (*Authorizations, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPendingAuthorizationsByExpiry not implemented")
+}
 func (UnimplementedStorageAuthorityServer) FQDNSetExists(context.Context, *FQDNSetExistsRequest) (*Exists, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FQDNSetExists not implemented")
 }
@@ -1896,15 +2203,27 @@ func (UnimplementedStorageAuthorityServer) GetLintPrecertificate(context.Context
 func (UnimplementedStorageAuthorityServer) GetCertificateStatus(context.Context, *Serial) (*proto.CertificateStatus, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetCertificateStatus not implemented")
 }
+func (UnimplementedStorageAuthorityServer) GetEABKey(context.Context, *EABKeyID) (*EABKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEABKey not implemented")
+}
 func (UnimplementedStorageAuthorityServer) GetMaxExpiration(context.Context, *emptypb.Empty) (*timestamppb.Timestamp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMaxExpiration not implemented")
 }
 func (UnimplementedStorageAuthorityServer) GetOrder(context.Context, *OrderRequest) (*proto.Order, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
 }
+func (UnimplementedStorageAuthorityServer) GetOrderError(context.Context, *OrderRequest) (*proto.ProblemDetails, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderError not implemented")
+}
 func (UnimplementedStorageAuthorityServer) GetOrderForNames(context.Context, *GetOrderForNamesRequest) (*proto.Order, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOrderForNames not implemented")
 }
+func (UnimplementedStorageAuthorityServer) GetPendingOrdersForAuthorization(context.Context, *GetPendingOrdersForAuthorizationRequest) (*OrderIDs, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPendingOrdersForAuthorization not implemented")
+}
+func (UnimplementedStorageAuthorityServer) GetAbandonedOrders(context.Context, *GetAbandonedOrdersRequest) (*Orders, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAbandonedOrders not implemented")
+}
 func (UnimplementedStorageAuthorityServer) GetRegistration(context.Context, *RegistrationID) (*proto.Registration, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRegistration not implemented")
 }
@@ -1980,12 +2299,21 @@ func (UnimplementedStorageAuthorityServer) FinalizeAuthorization2(context.Contex
 func (UnimplementedStorageAuthorityServer) FinalizeOrder(context.Context, *FinalizeOrderRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FinalizeOrder not implemented")
 }
+func (UnimplementedStorageAuthorityServer) SetAuthorizationFailureCounted(context.Context, *SetAuthorizationFailureCountedRequest) (*SetAuthorizationFailureCountedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAuthorizationFailureCounted not implemented")
+}
+func (UnimplementedStorageAuthorityServer) AddValidationAttempt(context.Context, *AddValidationAttemptRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddValidationAttempt not implemented")
+}
 func (UnimplementedStorageAuthorityServer) NewOrderAndAuthzs(context.Context, *NewOrderAndAuthzsRequest) (*proto.Order, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NewOrderAndAuthzs not implemented")
 }
 func (UnimplementedStorageAuthorityServer) NewRegistration(context.Context, *proto.Registration) (*proto.Registration, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NewRegistration not implemented")
 }
+func (UnimplementedStorageAuthorityServer) NewRegistrationClaimingEABKeyID(context.Context, *proto.Registration) (*proto.Registration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NewRegistrationClaimingEABKeyID not implemented")
+}
 func (UnimplementedStorageAuthorityServer) RevokeCertificate(context.Context, *RevokeCertificateRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RevokeCertificate not implemented")
 }
@@ -2065,6 +2393,24 @@ func _StorageAuthority_CountPendingAuthorizations2_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_GetPendingAuthorizationsByExpiry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPendingAuthorizationsByExpiryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).GetPendingAuthorizationsByExpiry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthority_GetPendingAuthorizationsByExpiry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).GetPendingAuthorizationsByExpiry(ctx, req.(*GetPendingAuthorizationsByExpiryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthority_FQDNSetExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(FQDNSetExistsRequest)
 	if err := dec(in); err != nil {
@@ -2191,6 +2537,24 @@ func _StorageAuthority_GetCertificateStatus_Handler(srv interface{}, ctx context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_GetEABKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EABKeyID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).GetEABKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthority_GetEABKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).GetEABKey(ctx, req.(*EABKeyID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthority_GetMaxExpiration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -2227,6 +2591,24 @@ func _StorageAuthority_GetOrder_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_GetOrderError_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).GetOrderError(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthority_GetOrderError_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).GetOrderError(ctx, req.(*OrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthority_GetOrderForNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetOrderForNamesRequest)
 	if err := dec(in); err != nil {
@@ -2245,6 +2627,42 @@ func _StorageAuthority_GetOrderForNames_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_GetPendingOrdersForAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPendingOrdersForAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).GetPendingOrdersForAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthority_GetPendingOrdersForAuthorization_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).GetPendingOrdersForAuthorization(ctx, req.(*GetPendingOrdersForAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_GetAbandonedOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAbandonedOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).GetAbandonedOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthority_GetAbandonedOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).GetAbandonedOrders(ctx, req.(*GetAbandonedOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthority_GetRegistration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RegistrationID)
 	if err := dec(in); err != nil {
@@ -2660,6 +3078,42 @@ func _StorageAuthority_FinalizeOrder_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_SetAuthorizationFailureCounted_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAuthorizationFailureCountedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).SetAuthorizationFailureCounted(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthority_SetAuthorizationFailureCounted_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).SetAuthorizationFailureCounted(ctx, req.(*SetAuthorizationFailureCountedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_AddValidationAttempt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddValidationAttemptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).AddValidationAttempt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthority_AddValidationAttempt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).AddValidationAttempt(ctx, req.(*AddValidationAttemptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthority_NewOrderAndAuthzs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(NewOrderAndAuthzsRequest)
 	if err := dec(in); err != nil {
@@ -2696,6 +3150,24 @@ func _StorageAuthority_NewRegistration_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_NewRegistrationClaimingEABKeyID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(proto.Registration)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).NewRegistrationClaimingEABKeyID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAuthority_NewRegistrationClaimingEABKeyID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).NewRegistrationClaimingEABKeyID(ctx, req.(*proto.Registration))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthority_RevokeCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RevokeCertificateRequest)
 	if err := dec(in); err != nil {
@@ -2891,6 +3363,10 @@ var StorageAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CountPendingAuthorizations2",
 			Handler:    _StorageAuthority_CountPendingAuthorizations2_Handler,
 		},
+		{
+			MethodName: "GetPendingAuthorizationsByExpiry",
+			Handler:    _StorageAuthority_GetPendingAuthorizationsByExpiry_Handler,
+		},
 		{
 			MethodName: "FQDNSetExists",
 			Handler:    _StorageAuthority_FQDNSetExists_Handler,
@@ -2919,6 +3395,10 @@ var StorageAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetCertificateStatus",
 			Handler:    _StorageAuthority_GetCertificateStatus_Handler,
 		},
+		{
+			MethodName: "GetEABKey",
+			Handler:    _StorageAuthority_GetEABKey_Handler,
+		},
 		{
 			MethodName: "GetMaxExpiration",
 			Handler:    _StorageAuthority_GetMaxExpiration_Handler,
@@ -2927,10 +3407,22 @@ var StorageAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetOrder",
 			Handler:    _StorageAuthority_GetOrder_Handler,
 		},
+		{
+			MethodName: "GetOrderError",
+			Handler:    _StorageAuthority_GetOrderError_Handler,
+		},
 		{
 			MethodName: "GetOrderForNames",
 			Handler:    _StorageAuthority_GetOrderForNames_Handler,
 		},
+		{
+			MethodName: "GetPendingOrdersForAuthorization",
+			Handler:    _StorageAuthority_GetPendingOrdersForAuthorization_Handler,
+		},
+		{
+			MethodName: "GetAbandonedOrders",
+			Handler:    _StorageAuthority_GetAbandonedOrders_Handler,
+		},
 		{
 			MethodName: "GetRegistration",
 			Handler:    _StorageAuthority_GetRegistration_Handler,
@@ -3011,6 +3503,14 @@ var StorageAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "FinalizeOrder",
 			Handler:    _StorageAuthority_FinalizeOrder_Handler,
 		},
+		{
+			MethodName: "SetAuthorizationFailureCounted",
+			Handler:    _StorageAuthority_SetAuthorizationFailureCounted_Handler,
+		},
+		{
+			MethodName: "AddValidationAttempt",
+			Handler:    _StorageAuthority_AddValidationAttempt_Handler,
+		},
 		{
 			MethodName: "NewOrderAndAuthzs",
 			Handler:    _StorageAuthority_NewOrderAndAuthzs_Handler,
@@ -3019,6 +3519,10 @@ var StorageAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "NewRegistration",
 			Handler:    _StorageAuthority_NewRegistration_Handler,
 		},
+		{
+			MethodName: "NewRegistrationClaimingEABKeyID",
+			Handler:    _StorageAuthority_NewRegistrationClaimingEABKeyID_Handler,
+		},
 		{
 			MethodName: "RevokeCertificate",
 			Handler:    _StorageAuthority_RevokeCertificate_Handler,
@@ -10,10 +10,11 @@ import (
 	"strings"
 	"time"
 
-	"github.com/go-jose/go-jose/v4"
 	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/crypto/ocsp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -125,6 +126,56 @@ func (ssa *SQLStorageAuthority) NewRegistration(ctx context.Context, req *corepb
 	return registrationModelToPb(reg)
 }
 
+// NewRegistrationClaimingEABKeyID stores a new Registration, as
+// NewRegistration does, and records it as the only Registration which may be
+// created with its External Account Binding key identifier. If another
+// Registration has already claimed that key identifier, nothing is stored and
+// an Unauthorized error is returned.
+func (ssa *SQLStorageAuthority) NewRegistrationClaimingEABKeyID(ctx context.Context, req *corepb.Registration) (*corepb.Registration, error) {
+	if len(req.Key) == 0 || req.EabKeyID == "" {
+		return nil, errIncompleteRequest
+	}
+	if !features.Get().StoreEABKeyID {
+		return nil, errors.New("storing EAB key IDs is not enabled")
+	}
+
+	reg, err := registrationPbToModel(req)
+	if err != nil {
+		return nil, err
+	}
+
+	reg.CreatedAt = ssa.clk.Now()
+
+	_, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+		err := tx.Insert(ctx, reg)
+		if err != nil {
+			if db.IsDuplicate(err) {
+				return nil, berrors.DuplicateError("key is already in use for a different account")
+			}
+			return nil, err
+		}
+
+		// The eabKeyID is the primary key of eabKeyIDAccounts, so this insert
+		// fails, and the registration is rolled back, if another registration
+		// has already claimed it.
+		err = tx.Insert(ctx, &eabKeyIDAccountModel{
+			EABKeyID:       req.EabKeyID,
+			RegistrationID: reg.ID,
+		})
+		if err != nil {
+			if db.IsDuplicate(err) {
+				return nil, berrors.UnauthorizedError("external account binding key %q has already been used to create an account", req.EabKeyID)
+			}
+			return nil, err
+		}
+		return nil, nil
+	})
+	if overallError != nil {
+		return nil, overallError
+	}
+	return registrationModelToPb(reg)
+}
+
 // UpdateRegistrationContact stores an updated contact in a Registration.
 // The updated contacts field may be empty.
 func (ssa *SQLStorageAuthority) UpdateRegistrationContact(ctx context.Context, req *sapb.UpdateRegistrationContactRequest) (*corepb.Registration, error) {
@@ -179,26 +230,36 @@ func (ssa *SQLStorageAuthority) UpdateRegistrationContact(ctx context.Context, r
 	return result.(*corepb.Registration), nil
 }
 
-// UpdateRegistrationKey stores an updated key in a Registration.
+// UpdateRegistrationKey stores an updated key in a Registration. If the update
+// is made by a key rollover request, the request's digest is recorded, and the
+// update refused if it has been recorded before.
 func (ssa *SQLStorageAuthority) UpdateRegistrationKey(ctx context.Context, req *sapb.UpdateRegistrationKeyRequest) (*corepb.Registration, error) {
 	if core.IsAnyNilOrZero(req.RegistrationID, req.Jwk) {
 		return nil, errIncompleteRequest
 	}
 
-	// Even though we don't need to convert from JSON to an in-memory JSONWebKey
-	// for the sake of the `Key` field, we do need to do the conversion in order
-	// to compute the SHA256 key digest.
-	var jwk jose.JSONWebKey
-	err := jwk.UnmarshalJSON(req.Jwk)
-	if err != nil {
-		return nil, fmt.Errorf("parsing JWK: %w", err)
-	}
-	sha, err := core.KeyDigestB64(jwk.Key)
+	sha, err := core.JWKDigestB64(req.Jwk)
 	if err != nil {
 		return nil, fmt.Errorf("computing key digest: %w", err)
 	}
 
 	result, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+		if len(req.KeyRolloverDigest) != 0 {
+			// The digest is the table's primary key, so this fails, and the
+			// key isn't updated, if the same request has been made before.
+			err := tx.Insert(ctx, &keyRolloverModel{
+				Digest:         req.KeyRolloverDigest,
+				RegistrationID: req.RegistrationID,
+				Created:        ssa.clk.Now(),
+			})
+			if err != nil {
+				if db.IsDuplicate(err) {
+					return nil, berrors.MalformedError("key rollover request has already been used")
+				}
+				return nil, err
+			}
+		}
+
 		result, err := tx.ExecContext(ctx,
 			"UPDATE registrations SET jwk = ?, jwk_sha256 = ? WHERE id = ? LIMIT 1",
 			req.Jwk,
@@ -533,7 +594,7 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 			}
 		} else {
 			if len(req.NewAuthzs) != 0 {
-				inserter, err := db.NewMultiInserter("authz2", strings.Split(authzFields, ", "), "id")
+				inserter, err := db.NewMultiInserter("authz2", strings.Split(selectAuthzFields(), ", "), "id")
 				if err != nil {
 					return nil, err
 				}
@@ -542,7 +603,7 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 					if err != nil {
 						return nil, err
 					}
-					err = inserter.Add([]interface{}{
+					values := []interface{}{
 						am.ID,
 						am.IdentifierType,
 						am.IdentifierValue,
@@ -556,7 +617,11 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 						am.Token,
 						nil,
 						nil,
-					})
+					}
+					if features.Get().StoreAuthzValidationDetails {
+						values = append(values, nil, nil, false)
+					}
+					err = inserter.Add(values)
 					if err != nil {
 						return nil, err
 					}
@@ -576,6 +641,10 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 			Created:                created,
 			CertificateProfileName: &req.NewOrder.CertificateProfileName,
 		}
+		if !core.IsAnyNilOrZero(req.NewOrder.FinalizeBy) {
+			finalizeBy := req.NewOrder.FinalizeBy.AsTime()
+			om.FinalizeBy = &finalizeBy
+		}
 		err := tx.Insert(ctx, &om)
 		if err != nil {
 			return nil, err
@@ -639,6 +708,7 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 			// transmits it to the CA, the empty string will take the value of
 			// DefaultCertProfileName from the //issuance package.
 			CertificateProfileName: req.NewOrder.CertificateProfileName,
+			FinalizeBy:             req.NewOrder.FinalizeBy,
 		}
 
 		// Calculate the order status before returning it. Since it may have reused
@@ -797,6 +867,17 @@ func (ssa *SQLStorageAuthority) FinalizeAuthorization2(ctx context.Context, req
 		validationError = :validationError,
 		expires = :expires
 		WHERE id = :id AND status = :pending`
+	if features.Get().StoreAuthzValidationDetails {
+		query = `UPDATE authz2 SET
+		status = :status,
+		attempted = :attempted,
+		attemptedAt = :attemptedAt,
+		validationRecord = :validationRecord,
+		validationError = :validationError,
+		perspectiveResults = :perspectiveResults,
+		expires = :expires
+		WHERE id = :id AND status = :pending`
+	}
 	var validationRecords []core.ValidationRecord
 	for _, recordPB := range req.ValidationRecords {
 		record, err := bgrpc.PBToValidationRecord(recordPB)
@@ -827,6 +908,10 @@ func (ssa *SQLStorageAuthority) FinalizeAuthorization2(ctx context.Context, req
 		}
 		veJSON = j
 	}
+	prJSON, err := perspectiveResultsToJSON(req.PerspectiveResults)
+	if err != nil {
+		return nil, err
+	}
 	// Check to see if the AttemptedAt time is non zero and convert to
 	// *time.Time if so. If it is zero, leave nil and don't convert. Keep the
 	// database attemptedAt field Null instead of 1970-01-01 00:00:00.
@@ -846,6 +931,8 @@ func (ssa *SQLStorageAuthority) FinalizeAuthorization2(ctx context.Context, req
 		// if req.ValidationError is nil veJSON should also be nil
 		// which should result in a NULL field
 		"validationError": veJSON,
+		// likewise for req.PerspectiveResults and prJSON
+		"perspectiveResults": prJSON,
 	}
 
 	res, err := ssa.dbMap.ExecContext(ctx, query, params)
@@ -864,6 +951,89 @@ func (ssa *SQLStorageAuthority) FinalizeAuthorization2(ctx context.Context, req
 	return &emptypb.Empty{}, nil
 }
 
+// AddValidationAttempt appends an attempt to the validation history of an
+// authorization, discarding the oldest attempts so that at most
+// core.MaxValidationAttempts remain. Unlike FinalizeAuthorization2, it doesn't
+// require the authorization to be pending, so that the attempts of concurrent
+// validations, all but one of which fail to finalize it, are kept.
+func (ssa *SQLStorageAuthority) AddValidationAttempt(ctx context.Context, req *sapb.AddValidationAttemptRequest) (*emptypb.Empty, error) {
+	if core.IsAnyNilOrZero(req.Id, req.Attempt) {
+		return nil, errIncompleteRequest
+	}
+	if !features.Get().StoreAuthzValidationDetails {
+		return nil, status.Error(codes.Unimplemented, "storing validation attempts is not enabled")
+	}
+
+	_, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+		var row struct {
+			ValidationAttempts []byte
+		}
+		err := tx.SelectOne(ctx, &row,
+			"SELECT validationAttempts FROM authz2 WHERE id = ? FOR UPDATE", req.Id)
+		if err != nil {
+			if db.IsNoRows(err) {
+				return nil, berrors.NotFoundError("no authorization with id %d", req.Id)
+			}
+			return nil, err
+		}
+		var attempts []core.ValidationAttempt
+		if len(row.ValidationAttempts) != 0 {
+			err = json.Unmarshal(row.ValidationAttempts, &attempts)
+			if err != nil {
+				return nil, badJSONError(
+					"failed to unmarshal authz2 model's validation attempts",
+					row.ValidationAttempts,
+					err)
+			}
+		}
+		attempts = core.AppendValidationAttempt(attempts, bgrpc.PBToValidationAttempt(req.Attempt))
+		attemptsJSON, err := json.Marshal(attempts)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE authz2 SET validationAttempts = ? WHERE id = ?", attemptsJSON, req.Id)
+		return nil, err
+	})
+	if overallError != nil {
+		return nil, overallError
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// SetAuthorizationFailureCounted sets the marker recording whether a failure
+// of the given authorization has been counted against the
+// FailedAuthorizationsPerDomainPerAccount limit. The response reports whether
+// the marker changed, so that of several concurrent callers setting the same
+// value exactly one observes the change.
+func (ssa *SQLStorageAuthority) SetAuthorizationFailureCounted(ctx context.Context, req *sapb.SetAuthorizationFailureCountedRequest) (*sapb.SetAuthorizationFailureCountedResponse, error) {
+	if req.Id == 0 {
+		return nil, errIncompleteRequest
+	}
+	if !features.Get().StoreAuthzValidationDetails {
+		return nil, status.Error(codes.Unimplemented, "storing authorization failure markers is not enabled")
+	}
+
+	res, err := ssa.dbMap.ExecContext(ctx,
+		`UPDATE authz2 SET failureCounted = :counted WHERE id = :id AND failureCounted != :counted`,
+		map[string]interface{}{
+			"counted": req.Counted,
+			"id":      req.Id,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows > 1 {
+		return nil, berrors.InternalServerError("multiple rows updated for authorization id %d", req.Id)
+	}
+	return &sapb.SetAuthorizationFailureCountedResponse{Changed: rows == 1}, nil
+}
+
 // addRevokedCertificate is a helper used by both RevokeCertificate and
 // UpdateRevokedCertificate. It inserts a new row into the revokedCertificates
 // table based on the contents of the input request. The second argument must be
@@ -18,6 +18,7 @@ import (
 	"math/bits"
 	mrand "math/rand/v2"
 	"net"
+	"os"
 	"reflect"
 	"slices"
 	"strings"
@@ -91,7 +92,15 @@ func (s *fakeServerStream[T]) Context() context.Context {
 // be defer'ed to the end of the test.
 func initSA(t testing.TB) (*SQLStorageAuthority, clock.FakeClock, func()) {
 	t.Helper()
-	features.Reset()
+	return initSAWithFeatures(t, features.Config{})
+}
+
+// initSAWithFeatures is like initSA, but enables the given features before
+// the SA's tables are mapped, as boulder-sa does. The caller must defer
+// features.Reset().
+func initSAWithFeatures(t testing.TB, fs features.Config) (*SQLStorageAuthority, clock.FakeClock, func()) {
+	t.Helper()
+	features.Set(fs)
 
 	dbMap, err := DBMapForTest(vars.DBConnSA)
 	if err != nil {
@@ -236,6 +245,92 @@ func TestAddRegistration(t *testing.T) {
 	test.AssertError(t, err, "Registration object for invalid key was returned")
 }
 
+func TestGetEABKey(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires externalAccountKeys database table")
+	}
+	sa, _, cleanUp := initSA(t)
+	defer cleanUp()
+
+	_, err := sa.dbMap.ExecContext(ctx, "INSERT INTO externalAccountKeys (keyID, macKey) VALUES (?, ?)", "kid-1", []byte("mac key"))
+	test.AssertNotError(t, err, "inserting EAB key")
+
+	key, err := sa.GetEABKey(ctx, &sapb.EABKeyID{KeyID: "kid-1"})
+	test.AssertNotError(t, err, "Couldn't get EAB key")
+	test.AssertByteEquals(t, key.MacKey, []byte("mac key"))
+
+	_, err = sa.GetEABKey(ctx, &sapb.EABKeyID{KeyID: "kid-2"})
+	test.AssertErrorIs(t, err, berrors.NotFound)
+
+	_, err = sa.GetEABKey(ctx, &sapb.EABKeyID{})
+	test.AssertError(t, err, "EAB key was returned for an empty key ID")
+}
+
+func TestNewRegistrationClaimingEABKeyID(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires eabKeyIDAccounts database table")
+	}
+	sa, _, cleanUp := initSAWithFeatures(t, features.Config{StoreEABKeyID: true})
+	defer features.Reset()
+	defer cleanUp()
+
+	jwkJSON, _ := goodTestJWK().MarshalJSON()
+	reg, err := sa.NewRegistrationClaimingEABKeyID(ctx, &corepb.Registration{
+		Key:      jwkJSON,
+		EabKeyID: "kid-1",
+	})
+	test.AssertNotError(t, err, "Couldn't create new registration")
+	test.AssertEquals(t, reg.EabKeyID, "kid-1")
+
+	dbReg, err := sa.GetRegistration(ctx, &sapb.RegistrationID{Id: reg.Id})
+	test.AssertNotError(t, err, "Couldn't get registration")
+	test.AssertEquals(t, dbReg.EabKeyID, "kid-1")
+
+	// A second registration claiming the same key ID is refused, and not
+	// stored.
+	otherJWK := `{"kty":"RSA","n":"vd7rZIoTLEe-z1_8G1FcXSw9CQFEJgV4g9V277sER7yx5Qjz_Pkf2YVth6wwwFJEmzc0hoKY-MMYFNwBE4hQHw","e":"AQAB"}`
+	_, err = sa.NewRegistrationClaimingEABKeyID(ctx, &corepb.Registration{
+		Key:      []byte(otherJWK),
+		EabKeyID: "kid-1",
+	})
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	_, err = sa.GetRegistrationByKey(ctx, &sapb.JSONWebKey{Jwk: []byte(otherJWK)})
+	test.AssertErrorIs(t, err, berrors.NotFound)
+
+	// A plain NewRegistration records the key ID without claiming it.
+	_, err = sa.NewRegistration(ctx, &corepb.Registration{
+		Key:      []byte(otherJWK),
+		EabKeyID: "kid-1",
+	})
+	test.AssertNotError(t, err, "Couldn't create unclaimed registration")
+
+	_, err = sa.NewRegistrationClaimingEABKeyID(ctx, &corepb.Registration{Key: jwkJSON})
+	test.AssertError(t, err, "Registration was claimed without an EAB key ID")
+}
+
+func TestNewRegistrationClaimingEABKeyIDDisabled(t *testing.T) {
+	sa, _, cleanUp := initSA(t)
+	defer cleanUp()
+
+	jwkJSON, _ := goodTestJWK().MarshalJSON()
+	_, err := sa.NewRegistrationClaimingEABKeyID(ctx, &corepb.Registration{
+		Key:      jwkJSON,
+		EabKeyID: "kid-1",
+	})
+	test.AssertError(t, err, "Registration was claimed with StoreEABKeyID disabled")
+
+	// Without the feature, the key ID isn't stored, so that the SA works
+	// before the migration adding its column.
+	reg, err := sa.NewRegistration(ctx, &corepb.Registration{
+		Key:      jwkJSON,
+		EabKeyID: "kid-1",
+	})
+	test.AssertNotError(t, err, "Couldn't create new registration")
+	dbReg, err := sa.GetRegistration(ctx, &sapb.RegistrationID{Id: reg.Id})
+	test.AssertNotError(t, err, "Couldn't get registration")
+	test.AssertEquals(t, dbReg.EabKeyID, "")
+}
+
 func TestNoSuchRegistrationErrors(t *testing.T) {
 	sa, _, cleanUp := initSA(t)
 	defer cleanUp()
@@ -853,6 +948,42 @@ func TestDeactivateAuthorization2(t *testing.T) {
 	test.AssertNotError(t, err, "sa.DeactivateAuthorization2 failed")
 }
 
+func TestSetAuthorizationFailureCounted(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires failureCounted database column")
+	}
+	sa, fc, cleanUp := initSAWithFeatures(t, features.Config{StoreAuthzValidationDetails: true})
+	defer features.Reset()
+	defer cleanUp()
+
+	authzID := createPendingAuthorization(t, sa, "example.com", fc.Now().Add(time.Hour).UTC())
+
+	// Only the first of several callers setting the marker changes it.
+	for i, expectChanged := range []bool{true, false} {
+		resp, err := sa.SetAuthorizationFailureCounted(context.Background(), &sapb.SetAuthorizationFailureCountedRequest{Id: authzID, Counted: true})
+		test.AssertNotError(t, err, "sa.SetAuthorizationFailureCounted failed")
+		test.AssertEquals(t, resp.Changed, expectChanged)
+		authz, err := sa.GetAuthorization2(context.Background(), &sapb.AuthorizationID2{Id: authzID})
+		test.AssertNotError(t, err, "sa.GetAuthorization2 failed")
+		test.Assert(t, authz.FailureCounted, fmt.Sprintf("authorization not marked as counted after call %d", i))
+	}
+
+	resp, err := sa.SetAuthorizationFailureCounted(context.Background(), &sapb.SetAuthorizationFailureCountedRequest{Id: authzID, Counted: false})
+	test.AssertNotError(t, err, "sa.SetAuthorizationFailureCounted failed")
+	test.Assert(t, resp.Changed, "clearing the marker should change it")
+	authz, err := sa.GetAuthorization2(context.Background(), &sapb.AuthorizationID2{Id: authzID})
+	test.AssertNotError(t, err, "sa.GetAuthorization2 failed")
+	test.Assert(t, !authz.FailureCounted, "authorization still marked as counted")
+
+	// Nonexistent authorizations never change.
+	resp, err = sa.SetAuthorizationFailureCounted(context.Background(), &sapb.SetAuthorizationFailureCountedRequest{Id: authzID + 1000, Counted: true})
+	test.AssertNotError(t, err, "sa.SetAuthorizationFailureCounted failed")
+	test.Assert(t, !resp.Changed, "nonexistent authorization should not change")
+
+	_, err = sa.SetAuthorizationFailureCounted(context.Background(), &sapb.SetAuthorizationFailureCountedRequest{})
+	test.AssertError(t, err, "missing authorization ID should fail")
+}
+
 func TestDeactivateAccount(t *testing.T) {
 	sa, _, cleanUp := initSA(t)
 	defer cleanUp()
@@ -1092,6 +1223,59 @@ func TestNewOrderAndAuthzs_Profile(t *testing.T) {
 	}
 }
 
+func TestNewOrderAndAuthzs_FinalizeBy(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires finalizeBy database column")
+	}
+	sa, fc, cleanup := initSAWithFeatures(t, features.Config{StoreOrderFinalizeBy: true})
+	defer features.Reset()
+	defer cleanup()
+
+	reg := createWorkingRegistration(t, sa)
+	expires := fc.Now().Add(time.Hour).Truncate(time.Second)
+	finalizeBy := fc.Now().Add(30 * time.Minute).Truncate(time.Second)
+
+	order, err := sa.NewOrderAndAuthzs(context.Background(), &sapb.NewOrderAndAuthzsRequest{
+		NewOrder: &sapb.NewOrderRequest{
+			RegistrationID: reg.Id,
+			Expires:        timestamppb.New(expires),
+			DnsNames:       []string{"example.com"},
+			FinalizeBy:     timestamppb.New(finalizeBy),
+		},
+		NewAuthzs: []*sapb.NewAuthzRequest{
+			{
+				Identifier:     &corepb.Identifier{Type: "dns", Value: "example.com"},
+				RegistrationID: reg.Id,
+				Expires:        timestamppb.New(expires),
+				ChallengeTypes: []string{string(core.ChallengeTypeHTTP01)},
+				Token:          core.NewToken(),
+			},
+		},
+	})
+	test.AssertNotError(t, err, "sa.NewOrderAndAuthzs failed")
+	test.AssertEquals(t, order.FinalizeBy.AsTime(), finalizeBy)
+
+	// The finalizeBy is stored, so it's returned when the order is retrieved.
+	gotOrder, err := sa.GetOrder(context.Background(), &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "sa.GetOrder failed")
+	test.Assert(t, gotOrder.FinalizeBy != nil, "retrieved order should have a finalizeBy")
+	test.AssertEquals(t, gotOrder.FinalizeBy.AsTime(), finalizeBy)
+
+	// An order created without one has none.
+	order, err = sa.NewOrderAndAuthzs(context.Background(), &sapb.NewOrderAndAuthzsRequest{
+		NewOrder: &sapb.NewOrderRequest{
+			RegistrationID:   reg.Id,
+			Expires:          timestamppb.New(expires),
+			DnsNames:         []string{"example.com"},
+			V2Authorizations: order.V2Authorizations,
+		},
+	})
+	test.AssertNotError(t, err, "sa.NewOrderAndAuthzs failed")
+	gotOrder, err = sa.GetOrder(context.Background(), &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "sa.GetOrder failed")
+	test.Assert(t, gotOrder.FinalizeBy == nil, "retrieved order should have no finalizeBy")
+}
+
 func BenchmarkNewOrderAndAuthzs(b *testing.B) {
 	for _, flag := range []bool{false, true} {
 		for _, numIdents := range []int{1, 2, 5, 10, 20, 50, 100} {
@@ -1183,6 +1367,212 @@ func TestSetOrderProcessing(t *testing.T) {
 	test.AssertErrorIs(t, err, berrors.OrderNotReady)
 }
 
+func TestGetOrderError(t *testing.T) {
+	sa, fc, cleanup := initSA(t)
+	defer cleanup()
+
+	reg := createWorkingRegistration(t, sa)
+	expires := fc.Now().Add(time.Hour)
+	attemptedAt := fc.Now()
+	authzID := createFinalizedAuthorization(t, sa, "example.com", expires, "valid", attemptedAt)
+
+	expires1Year := sa.clk.Now().Add(365 * 24 * time.Hour)
+	order, err := sa.NewOrderAndAuthzs(context.Background(), &sapb.NewOrderAndAuthzsRequest{
+		NewOrder: &sapb.NewOrderRequest{
+			RegistrationID:   reg.Id,
+			Expires:          timestamppb.New(expires1Year),
+			DnsNames:         []string{"example.com"},
+			V2Authorizations: []int64{authzID},
+		},
+	})
+	test.AssertNotError(t, err, "NewOrderAndAuthzs failed")
+
+	// An order without an error has nothing to return.
+	_, err = sa.GetOrderError(context.Background(), &sapb.OrderRequest{Id: order.Id})
+	test.AssertErrorIs(t, err, berrors.NotFound)
+
+	prob := &corepb.ProblemDetails{
+		ProblemType: string(probs.CAAProblem),
+		Detail:      "Rechecking CAA failed",
+		HttpStatus:  403,
+		SubProblems: []*corepb.SubProblemDetails{
+			{
+				Problem: &corepb.ProblemDetails{
+					ProblemType: string(probs.CAAProblem),
+					Detail:      "CAA record for example.com prevents issuance",
+					HttpStatus:  403,
+				},
+				Identifier: &corepb.Identifier{Type: "dns", Value: "example.com"},
+			},
+		},
+	}
+	_, err = sa.SetOrderError(context.Background(), &sapb.SetOrderErrorRequest{Id: order.Id, Error: prob})
+	test.AssertNotError(t, err, "SetOrderError failed")
+
+	// GetOrder only includes the top-level problem.
+	updatedOrder, err := sa.GetOrder(context.Background(), &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	test.AssertEquals(t, updatedOrder.Status, string(core.StatusInvalid))
+	test.AssertEquals(t, updatedOrder.Error.Detail, prob.Detail)
+	test.AssertEquals(t, len(updatedOrder.Error.SubProblems), 0)
+
+	// GetOrderError includes the subproblems.
+	orderErr, err := sa.GetOrderError(context.Background(), &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "GetOrderError failed")
+	test.AssertDeepEquals(t, orderErr, prob)
+
+	_, err = sa.GetOrderError(context.Background(), &sapb.OrderRequest{Id: order.Id + 1000})
+	test.AssertErrorIs(t, err, berrors.NotFound)
+}
+
+func TestGetPendingOrdersForAuthorization(t *testing.T) {
+	sa, fc, cleanup := initSA(t)
+	defer cleanup()
+
+	reg := createWorkingRegistration(t, sa)
+	authzExpires := fc.Now().Add(time.Hour)
+	authzID := createPendingAuthorization(t, sa, "example.com", authzExpires)
+	otherAuthzID := createPendingAuthorization(t, sa, "example.net", authzExpires)
+
+	var orderIDs []int64
+	for _, authzs := range [][]int64{{authzID}, {authzID, otherAuthzID}, {authzID}, {otherAuthzID}} {
+		order, err := sa.NewOrderAndAuthzs(context.Background(), &sapb.NewOrderAndAuthzsRequest{
+			NewOrder: &sapb.NewOrderRequest{
+				RegistrationID:   reg.Id,
+				Expires:          timestamppb.New(authzExpires),
+				DnsNames:         []string{"example.com"},
+				V2Authorizations: authzs,
+			},
+		})
+		test.AssertNotError(t, err, "NewOrderAndAuthzs failed")
+		orderIDs = append(orderIDs, order.Id)
+	}
+
+	// An order which already has an error is no longer pending.
+	_, err := sa.SetOrderError(context.Background(), &sapb.SetOrderErrorRequest{
+		Id:    orderIDs[2],
+		Error: &corepb.ProblemDetails{ProblemType: string(probs.ServerInternalProblem), Detail: "oops", HttpStatus: 500},
+	})
+	test.AssertNotError(t, err, "SetOrderError failed")
+
+	pending, err := sa.GetPendingOrdersForAuthorization(context.Background(), &sapb.GetPendingOrdersForAuthorizationRequest{AuthzID: authzID, Limit: 10})
+	test.AssertNotError(t, err, "GetPendingOrdersForAuthorization failed")
+	test.AssertDeepEquals(t, pending.Ids, []int64{orderIDs[0], orderIDs[1]})
+
+	pending, err = sa.GetPendingOrdersForAuthorization(context.Background(), &sapb.GetPendingOrdersForAuthorizationRequest{AuthzID: authzID, Limit: 1})
+	test.AssertNotError(t, err, "GetPendingOrdersForAuthorization failed")
+	test.AssertDeepEquals(t, pending.Ids, []int64{orderIDs[0]})
+
+	// Expired orders are not returned.
+	fc.Add(2 * time.Hour)
+	pending, err = sa.GetPendingOrdersForAuthorization(context.Background(), &sapb.GetPendingOrdersForAuthorizationRequest{AuthzID: authzID, Limit: 10})
+	test.AssertNotError(t, err, "GetPendingOrdersForAuthorization failed")
+	test.AssertEquals(t, len(pending.Ids), 0)
+
+	_, err = sa.GetPendingOrdersForAuthorization(context.Background(), &sapb.GetPendingOrdersForAuthorizationRequest{AuthzID: authzID})
+	test.AssertErrorIs(t, err, errIncompleteRequest)
+}
+
+func TestGetAbandonedOrders(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires orders expires_idx database index")
+	}
+	sa, fc, cleanUp := initSAWithFeatures(t, features.Config{ExpiredOrdersIndex: true})
+	defer features.Reset()
+	defer cleanUp()
+
+	reg := createWorkingRegistration(t, sa)
+	start := fc.Now().Truncate(time.Second)
+	authzID := createPendingAuthorization(t, sa, "example.com", start.Add(3*time.Hour))
+
+	// Orders expire after one hour, except the last, which expires after two.
+	var orderIDs []int64
+	for _, expires := range []time.Duration{time.Hour, time.Hour, time.Hour, time.Hour, 2 * time.Hour} {
+		order, err := sa.NewOrderAndAuthzs(context.Background(), &sapb.NewOrderAndAuthzsRequest{
+			NewOrder: &sapb.NewOrderRequest{
+				RegistrationID:   reg.Id,
+				Expires:          timestamppb.New(start.Add(expires)),
+				DnsNames:         []string{"example.com"},
+				V2Authorizations: []int64{authzID},
+			},
+		})
+		test.AssertNotError(t, err, "NewOrderAndAuthzs failed")
+		orderIDs = append(orderIDs, order.Id)
+	}
+
+	// Orders which failed or began processing weren't abandoned.
+	_, err := sa.SetOrderError(context.Background(), &sapb.SetOrderErrorRequest{
+		Id:    orderIDs[2],
+		Error: &corepb.ProblemDetails{ProblemType: string(probs.ServerInternalProblem), Detail: "oops", HttpStatus: 500},
+	})
+	test.AssertNotError(t, err, "SetOrderError failed")
+	_, err = sa.SetOrderProcessing(context.Background(), &sapb.OrderRequest{Id: orderIDs[3]})
+	test.AssertNotError(t, err, "SetOrderProcessing failed")
+
+	ids := func(orders *sapb.Orders) []int64 {
+		var ids []int64
+		for _, order := range orders.Orders {
+			ids = append(ids, order.Id)
+		}
+		return ids
+	}
+	req := &sapb.GetAbandonedOrdersRequest{
+		ExpiredAfter:  timestamppb.New(start),
+		ExpiredBefore: timestamppb.New(start.Add(time.Hour)),
+		Limit:         10,
+	}
+	orders, err := sa.GetAbandonedOrders(context.Background(), req)
+	test.AssertNotError(t, err, "GetAbandonedOrders failed")
+	test.AssertDeepEquals(t, ids(orders), []int64{orderIDs[0], orderIDs[1]})
+	test.AssertEquals(t, orders.Orders[0].RegistrationID, reg.Id)
+
+	// Orders are paged through by expiry then ID.
+	req.Limit = 1
+	orders, err = sa.GetAbandonedOrders(context.Background(), req)
+	test.AssertNotError(t, err, "GetAbandonedOrders failed")
+	test.AssertDeepEquals(t, ids(orders), []int64{orderIDs[0]})
+	req.ExpiredAfter = orders.Orders[0].Expires
+	req.AfterID = orderIDs[0]
+	req.ExpiredBefore = timestamppb.New(start.Add(2 * time.Hour))
+	req.Limit = 10
+	orders, err = sa.GetAbandonedOrders(context.Background(), req)
+	test.AssertNotError(t, err, "GetAbandonedOrders failed")
+	test.AssertDeepEquals(t, ids(orders), []int64{orderIDs[1], orderIDs[4]})
+
+	_, err = sa.GetAbandonedOrders(context.Background(), &sapb.GetAbandonedOrdersRequest{ExpiredAfter: req.ExpiredAfter})
+	test.AssertErrorIs(t, err, errIncompleteRequest)
+}
+
+func TestGetOrderDeactivatedAuthorizationError(t *testing.T) {
+	sa, fc, cleanup := initSA(t)
+	defer cleanup()
+
+	reg := createWorkingRegistration(t, sa)
+	authzExpires := fc.Now().Add(time.Hour)
+	authzID := createPendingAuthorization(t, sa, "example.com", authzExpires)
+
+	order, err := sa.NewOrderAndAuthzs(context.Background(), &sapb.NewOrderAndAuthzsRequest{
+		NewOrder: &sapb.NewOrderRequest{
+			RegistrationID:   reg.Id,
+			Expires:          timestamppb.New(authzExpires),
+			DnsNames:         []string{"example.com"},
+			V2Authorizations: []int64{authzID},
+		},
+	})
+	test.AssertNotError(t, err, "NewOrderAndAuthzs failed")
+
+	_, err = sa.DeactivateAuthorization2(context.Background(), &sapb.AuthorizationID2{Id: authzID})
+	test.AssertNotError(t, err, "DeactivateAuthorization2 failed")
+
+	// No error was recorded on the order, so GetOrder explains its status.
+	storedOrder, err := sa.GetOrder(context.Background(), &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	test.AssertEquals(t, storedOrder.Status, string(core.StatusInvalid))
+	test.AssertNotNil(t, storedOrder.Error, "GetOrder returned no error for the order")
+	test.AssertEquals(t, storedOrder.Error.ProblemType, string(probs.UnauthorizedProblem))
+	test.AssertEquals(t, storedOrder.Error.Detail, fmt.Sprintf("Authorization %d for dns identifier \"example.com\" was deactivated", authzID))
+}
+
 func TestFinalizeOrder(t *testing.T) {
 	sa, fc, cleanup := initSA(t)
 	defer cleanup()
@@ -2245,6 +2635,96 @@ func TestFinalizeAuthorization2(t *testing.T) {
 	test.AssertDeepEquals(t, dbVer.Challenges[0].Error, prob)
 }
 
+func TestFinalizeAuthorization2PerspectiveResults(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires perspectiveResults database column")
+	}
+	sa, fc, cleanUp := initSAWithFeatures(t, features.Config{StoreAuthzValidationDetails: true})
+	defer features.Reset()
+	defer cleanUp()
+
+	authzID := createPendingAuthorization(t, sa, "aaa", fc.Now().Add(time.Hour))
+	_, err := sa.FinalizeAuthorization2(context.Background(), &sapb.FinalizeAuthorizationRequest{
+		Id:          authzID,
+		Status:      string(core.StatusValid),
+		Expires:     timestamppb.New(fc.Now().Add(2 * time.Hour)),
+		Attempted:   string(core.ChallengeTypeDNS01),
+		AttemptedAt: timestamppb.New(fc.Now()),
+		PerspectiveResults: []*corepb.PerspectiveResult{
+			{Perspective: "Primary", Rir: "ARIN", Passed: true, LatencyMs: 12},
+			{Perspective: "surreal", Rir: "RIPE", ProblemType: string(probs.ConnectionProblem), LatencyMs: 3000},
+		},
+	})
+	test.AssertNotError(t, err, "sa.FinalizeAuthorization2 failed")
+
+	dbVer, err := sa.GetAuthorization2(context.Background(), &sapb.AuthorizationID2{Id: authzID})
+	test.AssertNotError(t, err, "sa.GetAuthorization2 failed")
+	test.AssertEquals(t, len(dbVer.Challenges[0].PerspectiveResults), 2)
+	test.AssertEquals(t, dbVer.Challenges[0].PerspectiveResults[0].Perspective, "Primary")
+	test.Assert(t, dbVer.Challenges[0].PerspectiveResults[0].Passed, "primary perspective should have passed")
+	test.AssertEquals(t, dbVer.Challenges[0].PerspectiveResults[1].ProblemType, string(probs.ConnectionProblem))
+	test.AssertEquals(t, dbVer.Challenges[0].PerspectiveResults[1].LatencyMs, int64(3000))
+}
+
+func TestAddValidationAttempt(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires validationAttempts database column")
+	}
+	sa, fc, cleanUp := initSAWithFeatures(t, features.Config{StoreAuthzValidationDetails: true})
+	defer features.Reset()
+	defer cleanUp()
+
+	_, err := sa.AddValidationAttempt(ctx, &sapb.AddValidationAttemptRequest{Id: 1})
+	test.AssertErrorIs(t, err, errIncompleteRequest)
+
+	attempt := func(detail string) *corepb.ValidationAttempt {
+		return &corepb.ValidationAttempt{
+			AttemptedAt:  timestamppb.New(fc.Now()),
+			ProblemType:  string(probs.ConnectionProblem),
+			Detail:       detail,
+			Perspectives: "Primary",
+		}
+	}
+	_, err = sa.AddValidationAttempt(ctx, &sapb.AddValidationAttemptRequest{Id: 999999, Attempt: attempt("no such authz")})
+	test.AssertErrorIs(t, err, berrors.NotFound)
+
+	authzID := createPendingAuthorization(t, sa, "aaa", fc.Now().Add(time.Hour))
+	_, err = sa.AddValidationAttempt(ctx, &sapb.AddValidationAttemptRequest{Id: authzID, Attempt: attempt("first")})
+	test.AssertNotError(t, err, "adding first attempt")
+
+	// Attempts are still added once the authorization is no longer pending,
+	// as they are by validations which lose the race to finalize it.
+	prob, _ := bgrpc.ProblemDetailsToPB(probs.Connection("first"))
+	_, err = sa.FinalizeAuthorization2(ctx, &sapb.FinalizeAuthorizationRequest{
+		Id:              authzID,
+		ValidationError: prob,
+		Status:          string(core.StatusInvalid),
+		Attempted:       string(core.ChallengeTypeHTTP01),
+		Expires:         timestamppb.New(fc.Now().Add(time.Hour)),
+	})
+	test.AssertNotError(t, err, "sa.FinalizeAuthorization2 failed")
+	fc.Add(time.Minute)
+	_, err = sa.AddValidationAttempt(ctx, &sapb.AddValidationAttemptRequest{Id: authzID, Attempt: attempt("second")})
+	test.AssertNotError(t, err, "adding second attempt")
+
+	dbVer, err := sa.GetAuthorization2(ctx, &sapb.AuthorizationID2{Id: authzID})
+	test.AssertNotError(t, err, "sa.GetAuthorization2 failed")
+	test.AssertEquals(t, len(dbVer.Challenges[0].Attempts), 2)
+	test.AssertEquals(t, dbVer.Challenges[0].Attempts[0].Detail, "first")
+	test.AssertEquals(t, dbVer.Challenges[0].Attempts[1].Detail, "second")
+	test.AssertEquals(t, dbVer.Challenges[0].Attempts[1].AttemptedAt.AsTime(), fc.Now())
+
+	// Only the most recent attempts are kept.
+	for i := range core.MaxValidationAttempts {
+		_, err = sa.AddValidationAttempt(ctx, &sapb.AddValidationAttemptRequest{Id: authzID, Attempt: attempt(fmt.Sprint(i))})
+		test.AssertNotError(t, err, "adding attempt")
+	}
+	dbVer, err = sa.GetAuthorization2(ctx, &sapb.AuthorizationID2{Id: authzID})
+	test.AssertNotError(t, err, "sa.GetAuthorization2 failed")
+	test.AssertEquals(t, len(dbVer.Challenges[0].Attempts), core.MaxValidationAttempts)
+	test.AssertEquals(t, dbVer.Challenges[0].Attempts[0].Detail, "0")
+}
+
 func TestRehydrateHostPort(t *testing.T) {
 	sa, fc, cleanUp := initSA(t)
 	defer cleanUp()
@@ -2394,6 +2874,69 @@ func TestCountPendingAuthorizations2(t *testing.T) {
 	test.AssertEquals(t, count.Count, int64(0))
 }
 
+func TestGetPendingAuthorizationsByExpiry(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+
+	idA := createPendingAuthorization(t, sa, "a.example.com", fc.Now().Add(time.Hour*3).UTC())
+	idB := createPendingAuthorization(t, sa, "b.example.com", fc.Now().Add(time.Hour).UTC())
+	idC := createPendingAuthorization(t, sa, "c.example.com", fc.Now().Add(time.Hour*2).UTC())
+
+	// The soonest to expire are returned first, up to the limit.
+	regID := int64(1)
+	authzs, err := sa.GetPendingAuthorizationsByExpiry(context.Background(), &sapb.GetPendingAuthorizationsByExpiryRequest{
+		RegistrationID: regID,
+		Limit:          2,
+	})
+	test.AssertNotError(t, err, "sa.GetPendingAuthorizationsByExpiry failed")
+	test.AssertEquals(t, len(authzs.Authzs), 2)
+	test.AssertEquals(t, authzs.Authzs[0].Id, fmt.Sprint(idB))
+	test.AssertEquals(t, authzs.Authzs[1].Id, fmt.Sprint(idC))
+
+	// Expired authorizations aren't returned.
+	fc.Add(time.Hour * 2)
+	authzs, err = sa.GetPendingAuthorizationsByExpiry(context.Background(), &sapb.GetPendingAuthorizationsByExpiryRequest{
+		RegistrationID: regID,
+		Limit:          10,
+	})
+	test.AssertNotError(t, err, "sa.GetPendingAuthorizationsByExpiry failed")
+	test.AssertEquals(t, len(authzs.Authzs), 1)
+	test.AssertEquals(t, authzs.Authzs[0].Id, fmt.Sprint(idA))
+
+	// Authorizations referenced by an unexpired order aren't returned, until
+	// the order expires.
+	_, err = sa.NewOrderAndAuthzs(ctx, &sapb.NewOrderAndAuthzsRequest{
+		NewOrder: &sapb.NewOrderRequest{
+			RegistrationID:   regID,
+			Expires:          timestamppb.New(fc.Now().Add(30 * time.Minute)),
+			V2Authorizations: []int64{idA},
+			DnsNames:         []string{"a.example.com"},
+		},
+	})
+	test.AssertNotError(t, err, "sa.NewOrderAndAuthzs failed")
+	authzs, err = sa.GetPendingAuthorizationsByExpiry(context.Background(), &sapb.GetPendingAuthorizationsByExpiryRequest{
+		RegistrationID: regID,
+		Limit:          10,
+	})
+	test.AssertNotError(t, err, "sa.GetPendingAuthorizationsByExpiry failed")
+	test.AssertEquals(t, len(authzs.Authzs), 0)
+
+	fc.Add(45 * time.Minute)
+	authzs, err = sa.GetPendingAuthorizationsByExpiry(context.Background(), &sapb.GetPendingAuthorizationsByExpiryRequest{
+		RegistrationID: regID,
+		Limit:          10,
+	})
+	test.AssertNotError(t, err, "sa.GetPendingAuthorizationsByExpiry failed")
+	test.AssertEquals(t, len(authzs.Authzs), 1)
+	test.AssertEquals(t, authzs.Authzs[0].Id, fmt.Sprint(idA))
+
+	// A limit is required.
+	_, err = sa.GetPendingAuthorizationsByExpiry(context.Background(), &sapb.GetPendingAuthorizationsByExpiryRequest{
+		RegistrationID: regID,
+	})
+	test.AssertError(t, err, "sa.GetPendingAuthorizationsByExpiry should require a limit")
+}
+
 func TestAuthzModelMapToPB(t *testing.T) {
 	baseExpires := time.Now()
 	input := map[string]authzModel{
@@ -4472,3 +5015,39 @@ func TestUpdateRegistrationKey(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateRegistrationKeyRolloverDigest(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires keyRollovers database table")
+	}
+	sa, _, cleanUp := initSA(t)
+	defer cleanUp()
+
+	reg, err := sa.NewRegistration(ctx, &corepb.Registration{
+		Key: newAcctKey(t),
+	})
+	test.AssertNotError(t, err, "creating new registration")
+
+	digest := sha256.Sum256([]byte("key rollover request"))
+	newKey := newAcctKey(t)
+	_, err = sa.UpdateRegistrationKey(ctx, &sapb.UpdateRegistrationKeyRequest{
+		RegistrationID:    reg.Id,
+		Jwk:               newKey,
+		KeyRolloverDigest: digest[:],
+	})
+	test.AssertNotError(t, err, "updating registration key")
+
+	// A second update with the same digest is rejected and leaves the key
+	// unchanged.
+	_, err = sa.UpdateRegistrationKey(ctx, &sapb.UpdateRegistrationKeyRequest{
+		RegistrationID:    reg.Id,
+		Jwk:               newAcctKey(t),
+		KeyRolloverDigest: digest[:],
+	})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	test.AssertContains(t, err.Error(), "key rollover request has already been used")
+
+	refetchedReg, err := sa.GetRegistration(ctx, &sapb.RegistrationID{Id: reg.Id})
+	test.AssertNotError(t, err, "retrieving registration")
+	test.AssertDeepEquals(t, refetchedReg.Key, newKey)
+}
@@ -11,10 +11,11 @@ import (
 	"strings"
 	"time"
 
-	"github.com/go-jose/go-jose/v4"
 	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -22,6 +23,7 @@ import (
 	corepb "github.com/letsencrypt/boulder/core/proto"
 	"github.com/letsencrypt/boulder/db"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/identifier"
 	blog "github.com/letsencrypt/boulder/log"
@@ -135,13 +137,7 @@ func (ssa *SQLStorageAuthorityRO) GetRegistrationByKey(ctx context.Context, req
 		return nil, errIncompleteRequest
 	}
 
-	var jwk jose.JSONWebKey
-	err := jwk.UnmarshalJSON(req.Jwk)
-	if err != nil {
-		return nil, err
-	}
-
-	sha, err := core.KeyDigestB64(jwk.Key)
+	sha, err := core.JWKDigestB64(req.Jwk)
 	if err != nil {
 		return nil, err
 	}
@@ -156,6 +152,30 @@ func (ssa *SQLStorageAuthorityRO) GetRegistrationByKey(ctx context.Context, req
 	return registrationModelToPb(model)
 }
 
+// GetEABKey returns the MAC key provisioned for the given External Account
+// Binding key identifier.
+func (ssa *SQLStorageAuthorityRO) GetEABKey(ctx context.Context, req *sapb.EABKeyID) (*sapb.EABKey, error) {
+	if req == nil || req.KeyID == "" {
+		return nil, errIncompleteRequest
+	}
+
+	var model externalAccountKeyModel
+	err := ssa.dbReadOnlyMap.SelectOne(
+		ctx,
+		&model,
+		"SELECT keyID, macKey FROM externalAccountKeys WHERE keyID = ?",
+		req.KeyID,
+	)
+	if err != nil {
+		if db.IsNoRows(err) {
+			return nil, berrors.NotFoundError("no EAB key with ID %q", req.KeyID)
+		}
+		return nil, err
+	}
+
+	return &sapb.EABKey{MacKey: model.MACKey}, nil
+}
+
 // incrementIP returns a copy of `ip` incremented at a bit index `index`,
 // or in other words the first IP of the next highest subnet given a mask of
 // length `index`.
@@ -456,6 +476,16 @@ func (ssa *SQLStorageAuthorityRO) GetOrder(ctx context.Context, req *sapb.OrderR
 		}
 		order.Status = status
 
+		// The RA records an error on orders invalidated by the deactivation of
+		// one of their authorizations, but only for a bounded number of them.
+		// Explain the status of any it didn't reach in the same way.
+		if order.Status == string(core.StatusInvalid) && order.Error == nil {
+			order.Error, err = deactivatedAuthzProblem(authzValidityInfo)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		return order, nil
 	}
 
@@ -488,6 +518,36 @@ func (ssa *SQLStorageAuthorityRO) GetOrder(ctx context.Context, req *sapb.OrderR
 	return order, nil
 }
 
+// GetOrderError returns the full problem, including any subproblems, which
+// caused the specified order to become invalid. If the order does not exist,
+// has expired, or has no error, a NotFound error is returned.
+func (ssa *SQLStorageAuthorityRO) GetOrderError(ctx context.Context, req *sapb.OrderRequest) (*corepb.ProblemDetails, error) {
+	if req == nil || req.Id == 0 {
+		return nil, errIncompleteRequest
+	}
+
+	var om orderModel
+	err := ssa.dbReadOnlyMap.SelectOne(
+		ctx,
+		&om,
+		"SELECT id, expires, error FROM orders WHERE id = ?",
+		req.Id,
+	)
+	if err != nil {
+		if db.IsNoRows(err) {
+			return nil, berrors.NotFoundError("no order found for ID %d", req.Id)
+		}
+		return nil, err
+	}
+	if om.Expires.Before(ssa.clk.Now()) {
+		return nil, berrors.NotFoundError("no order found for ID %d", req.Id)
+	}
+	if len(om.Error) == 0 {
+		return nil, berrors.NotFoundError("no error found for order ID %d", req.Id)
+	}
+	return unmarshalOrderError(om.Error)
+}
+
 // GetOrderForNames tries to find a **pending** or **ready** order with the
 // exact set of names requested, associated with the given accountID. Only
 // unexpired orders are considered. If no order meeting these requirements is
@@ -549,6 +609,87 @@ func (ssa *SQLStorageAuthorityRO) GetOrderForNames(ctx context.Context, req *sap
 	return order, nil
 }
 
+// GetPendingOrdersForAuthorization returns the IDs of up to req.Limit
+// unexpired orders which include the given authorization and which have
+// neither an error nor begun processing, i.e. those which are pending or
+// ready. IDs are returned in ascending order.
+func (ssa *SQLStorageAuthorityRO) GetPendingOrdersForAuthorization(ctx context.Context, req *sapb.GetPendingOrdersForAuthorizationRequest) (*sapb.OrderIDs, error) {
+	if core.IsAnyNilOrZero(req, req.AuthzID, req.Limit) {
+		return nil, errIncompleteRequest
+	}
+
+	var ids []int64
+	_, err := ssa.dbReadOnlyMap.Select(
+		ctx,
+		&ids,
+		`SELECT orders.id
+		FROM orderToAuthz2
+		JOIN orders ON orders.id = orderToAuthz2.orderID
+		WHERE orderToAuthz2.authzID = ?
+		AND orders.expires > ?
+		AND orders.error IS NULL
+		AND orders.beganProcessing = false
+		ORDER BY orders.id
+		LIMIT ?`,
+		req.AuthzID,
+		ssa.clk.Now(),
+		req.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &sapb.OrderIDs{Ids: ids}, nil
+}
+
+// GetAbandonedOrders returns up to req.Limit orders which expired after the
+// position given by req.ExpiredAfter and req.AfterID, and no later than
+// req.ExpiredBefore, without either an error or having begun processing. Only
+// the fields stored in the orders table are populated. Orders are returned in
+// order of expiry then ID, so that the last order returned is the position
+// from which to request the next page.
+func (ssa *SQLStorageAuthorityRO) GetAbandonedOrders(ctx context.Context, req *sapb.GetAbandonedOrdersRequest) (*sapb.Orders, error) {
+	if core.IsAnyNilOrZero(req, req.ExpiredAfter, req.ExpiredBefore, req.Limit) {
+		return nil, errIncompleteRequest
+	}
+	if !features.Get().ExpiredOrdersIndex {
+		return nil, status.Error(codes.Unimplemented, "looking up orders by expiry is not enabled")
+	}
+
+	var oms []orderModel
+	_, err := ssa.dbReadOnlyMap.Select(
+		ctx,
+		&oms,
+		`SELECT id, registrationID, expires, created, error, certificateSerial, beganProcessing, certificateProfileName
+		FROM orders
+		USE INDEX (expires_idx)
+		WHERE (expires > :expiredAfter OR (expires = :expiredAfter AND id > :afterID))
+		AND expires <= :expiredBefore
+		AND error IS NULL
+		AND beganProcessing = false
+		ORDER BY expires, id
+		LIMIT :limit`,
+		map[string]interface{}{
+			"expiredAfter":  req.ExpiredAfter.AsTime(),
+			"afterID":       req.AfterID,
+			"expiredBefore": req.ExpiredBefore.AsTime(),
+			"limit":         req.Limit,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*corepb.Order, 0, len(oms))
+	for _, om := range oms {
+		order, err := modelToOrder(&om)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return &sapb.Orders{Orders: orders}, nil
+}
+
 // GetAuthorization2 returns the authz2 style authorization identified by the provided ID or an error.
 // If no authorization is found matching the ID a berrors.NotFound type error is returned.
 func (ssa *SQLStorageAuthorityRO) GetAuthorization2(ctx context.Context, req *sapb.AuthorizationID2) (*corepb.Authorization, error) {
@@ -614,7 +755,7 @@ func (ssa *SQLStorageAuthorityRO) GetAuthorizations2(ctx context.Context, req *s
 			expires > ? AND
 			identifierType = ? AND
 			identifierValue IN (%s)`,
-		authzFields,
+		selectAuthzFields(),
 		db.QuestionMarks(len(req.DnsNames)),
 	)
 
@@ -681,6 +822,56 @@ func (ssa *SQLStorageAuthorityRO) CountPendingAuthorizations2(ctx context.Contex
 	return &sapb.Count{Count: count}, nil
 }
 
+// GetPendingAuthorizationsByExpiry returns up to req.Limit pending, unexpired
+// authorizations for the given registration, soonest to expire first. Only
+// authorizations which no unexpired order references are returned, so that
+// deactivating them can't invalidate an order the Subscriber is working on.
+func (ssa *SQLStorageAuthorityRO) GetPendingAuthorizationsByExpiry(ctx context.Context, req *sapb.GetPendingAuthorizationsByExpiryRequest) (*sapb.Authorizations, error) {
+	if core.IsAnyNilOrZero(req, req.RegistrationID, req.Limit) {
+		return nil, errIncompleteRequest
+	}
+
+	var authzModels []authzModel
+	_, err := ssa.dbReadOnlyMap.Select(
+		ctx,
+		&authzModels,
+		fmt.Sprintf(`SELECT %s FROM authz2
+			USE INDEX (regID_expires_idx)
+			WHERE registrationID = :regID AND
+			status = :status AND
+			expires > :expires AND
+			NOT EXISTS (
+				SELECT 1 FROM orderToAuthz2
+				JOIN orders ON orders.id = orderToAuthz2.orderID
+				WHERE orderToAuthz2.authzID = authz2.id AND
+				orders.expires > :expires
+			)
+			ORDER BY expires ASC
+			LIMIT :limit`,
+			selectAuthzFields(),
+		),
+		map[string]interface{}{
+			"regID":   req.RegistrationID,
+			"status":  statusUint(core.StatusPending),
+			"expires": ssa.clk.Now(),
+			"limit":   req.Limit,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	authzs := make([]*corepb.Authorization, 0, len(authzModels))
+	for _, am := range authzModels {
+		authz, err := modelToAuthzPB(am)
+		if err != nil {
+			return nil, err
+		}
+		authzs = append(authzs, authz)
+	}
+	return &sapb.Authorizations{Authzs: authzs}, nil
+}
+
 // GetValidOrderAuthorizations2 is used to get all authorizations
 // associated with the given Order ID.
 // NOTE: The name is outdated. It does *not* filter out invalid or expired
@@ -697,7 +888,7 @@ func (ssa *SQLStorageAuthorityRO) GetValidOrderAuthorizations2(ctx context.Conte
 
 	// The authz2 and orderToAuthz2 tables both have a column named "id", so we
 	// need to be explicit about which table's "id" column we want to select.
-	qualifiedAuthzFields := strings.Split(authzFields, " ")
+	qualifiedAuthzFields := strings.Split(selectAuthzFields(), " ")
 	for i, field := range qualifiedAuthzFields {
 		if field == "id," {
 			qualifiedAuthzFields[i] = "authz2.id,"
@@ -789,7 +980,7 @@ func (ssa *SQLStorageAuthorityRO) GetValidAuthorizations2(ctx context.Context, r
 			expires > ? AND
 			identifierType = ? AND
 			identifierValue IN (%s)`,
-		authzFields,
+		selectAuthzFields(),
 		db.QuestionMarks(len(req.DnsNames)),
 	)
 
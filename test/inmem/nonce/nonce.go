@@ -20,7 +20,7 @@ var _ noncepb.NonceServiceClient = &Service{}
 
 // Nonce implements proto.NonceServiceClient
 func (imns *Service) Nonce(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*noncepb.NonceMessage, error) {
-	n, err := imns.NonceService.Nonce()
+	n, err := imns.NonceService.Nonce(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +29,7 @@ func (imns *Service) Nonce(ctx context.Context, in *emptypb.Empty, opts ...grpc.
 
 // Redeem implements proto.NonceServiceClient
 func (imns *Service) Redeem(ctx context.Context, in *noncepb.NonceMessage, opts ...grpc.CallOption) (*noncepb.ValidMessage, error) {
-	valid := imns.NonceService.Valid(in.Nonce)
+	valid := imns.NonceService.Valid(ctx, in.Nonce)
 	return &noncepb.ValidMessage{Valid: valid}, nil
 }
 
@@ -53,6 +53,10 @@ func (sa SA) CountPendingAuthorizations2(ctx context.Context, req *sapb.Registra
 	return sa.Impl.CountPendingAuthorizations2(ctx, req)
 }
 
+func (sa SA) GetPendingAuthorizationsByExpiry(ctx context.Context, req *sapb.GetPendingAuthorizationsByExpiryRequest, _ ...grpc.CallOption) (*sapb.Authorizations, error) {
+	return sa.Impl.GetPendingAuthorizationsByExpiry(ctx, req)
+}
+
 func (sa SA) DeactivateAuthorization2(ctx context.Context, req *sapb.AuthorizationID2, _ ...grpc.CallOption) (*emptypb.Empty, error) {
 	return sa.Impl.DeactivateAuthorization2(ctx, req)
 }
@@ -61,6 +65,10 @@ func (sa SA) FinalizeAuthorization2(ctx context.Context, req *sapb.FinalizeAutho
 	return sa.Impl.FinalizeAuthorization2(ctx, req)
 }
 
+func (sa SA) AddValidationAttempt(ctx context.Context, req *sapb.AddValidationAttemptRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	return sa.Impl.AddValidationAttempt(ctx, req)
+}
+
 func (sa SA) NewOrderAndAuthzs(ctx context.Context, req *sapb.NewOrderAndAuthzsRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
 	return sa.Impl.NewOrderAndAuthzs(ctx, req)
 }
@@ -69,10 +77,22 @@ func (sa SA) GetOrder(ctx context.Context, req *sapb.OrderRequest, _ ...grpc.Cal
 	return sa.Impl.GetOrder(ctx, req)
 }
 
+func (sa SA) GetOrderError(ctx context.Context, req *sapb.OrderRequest, _ ...grpc.CallOption) (*corepb.ProblemDetails, error) {
+	return sa.Impl.GetOrderError(ctx, req)
+}
+
 func (sa SA) GetOrderForNames(ctx context.Context, req *sapb.GetOrderForNamesRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
 	return sa.Impl.GetOrderForNames(ctx, req)
 }
 
+func (sa SA) GetPendingOrdersForAuthorization(ctx context.Context, req *sapb.GetPendingOrdersForAuthorizationRequest, _ ...grpc.CallOption) (*sapb.OrderIDs, error) {
+	return sa.Impl.GetPendingOrdersForAuthorization(ctx, req)
+}
+
+func (sa SA) GetAbandonedOrders(ctx context.Context, req *sapb.GetAbandonedOrdersRequest, _ ...grpc.CallOption) (*sapb.Orders, error) {
+	return sa.Impl.GetAbandonedOrders(ctx, req)
+}
+
 func (sa SA) SetOrderError(ctx context.Context, req *sapb.SetOrderErrorRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
 	return sa.Impl.SetOrderError(ctx, req)
 }
@@ -0,0 +1,116 @@
+package va
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// retryPushbackKey is the gRPC trailer which tells the client how long to wait,
+// in milliseconds, before retrying a request which was shed.
+const retryPushbackKey = "grpc-retry-pushback-ms"
+
+// admissionController bounds the number of validations and CAA checks the VA
+// performs concurrently, so that under a load spike it sheds requests early
+// rather than slowing every request down together. Requests which arrive while
+// the VA is at capacity wait up to maxWait for a slot, and are then refused
+// with codes.ResourceExhausted.
+type admissionController struct {
+	slots   chan struct{}
+	maxWait time.Duration
+	clk     clock.Clock
+
+	inFlight prometheus.Gauge
+	queued   prometheus.Gauge
+	shed     *prometheus.CounterVec
+}
+
+// newAdmissionController returns an admissionController which admits up to
+// maxConcurrent requests at once, or nil if maxConcurrent is zero, in which
+// case every request is admitted.
+func newAdmissionController(maxConcurrent int, maxWait time.Duration, clk clock.Clock, stats prometheus.Registerer) *admissionController {
+	if maxConcurrent == 0 {
+		return nil
+	}
+	inFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "validations_in_flight",
+		Help: "Number of validations and CAA checks admitted and in progress",
+	})
+	stats.MustRegister(inFlight)
+	queued := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "validations_queued",
+		Help: "Number of validations and CAA checks waiting to be admitted",
+	})
+	stats.MustRegister(queued)
+	shed := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "validations_shed",
+		Help: "A counter of validations and CAA checks refused because the VA was at capacity, labelled by operation",
+	}, []string{"operation"})
+	stats.MustRegister(shed)
+
+	return &admissionController{
+		slots:    make(chan struct{}, maxConcurrent),
+		maxWait:  maxWait,
+		clk:      clk,
+		inFlight: inFlight,
+		queued:   queued,
+		shed:     shed,
+	}
+}
+
+// admit waits for a slot for a request of the given operation, for up to the
+// controller's maxWait. On success, the caller must call the returned function
+// once the request is complete. If no slot becomes available in time, it
+// returns a codes.ResourceExhausted error, and sets a retry pushback hint in
+// the request's gRPC trailer. It is safe to call on a nil *admissionController,
+// which admits every request.
+func (a *admissionController) admit(ctx context.Context, op string) (func(), error) {
+	if a == nil {
+		return func() {}, nil
+	}
+	release := func() {
+		<-a.slots
+		a.inFlight.Dec()
+	}
+
+	select {
+	case a.slots <- struct{}{}:
+		a.inFlight.Inc()
+		return release, nil
+	default:
+	}
+
+	a.queued.Inc()
+	defer a.queued.Dec()
+	timer := a.clk.NewTimer(a.maxWait)
+	defer timer.Stop()
+	select {
+	case a.slots <- struct{}{}:
+		a.inFlight.Inc()
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+
+	a.shed.WithLabelValues(op).Inc()
+	pushback := max(a.maxWait, time.Second)
+	// The trailer can't be set outside of a gRPC server, such as in tests, so
+	// the error is ignored.
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(retryPushbackKey, strconv.FormatInt(pushback.Milliseconds(), 10)))
+	return nil, status.Errorf(codes.ResourceExhausted,
+		"VA is at capacity: no %s slot became available within %s", op, a.maxWait)
+}
+
+// isShed returns true if err is a VA's refusal of a request because it was at
+// capacity, in which case the request wasn't attempted.
+func isShed(err error) bool {
+	return status.Code(err) == codes.ResourceExhausted
+}
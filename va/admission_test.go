@@ -0,0 +1,165 @@
+package va
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+// awaitQueued yields until n requests are waiting for an admission slot.
+func awaitQueued(t *testing.T, a *admissionController, n int) {
+	t.Helper()
+	for range 100000 {
+		var m io_prometheus_client.Metric
+		err := a.queued.Write(&m)
+		test.AssertNotError(t, err, "reading queued gauge")
+		if int(m.GetGauge().GetValue()) == n {
+			return
+		}
+		runtime.Gosched()
+	}
+	t.Fatalf("timed out waiting for %d queued requests", n)
+}
+
+func TestAdmissionControllerSheds(t *testing.T) {
+	const maxConcurrent = 3
+	const maxWait = 50 * time.Millisecond
+
+	// The server holds every request until unblocked, so that validations
+	// pile up in the VA.
+	unblock := make(chan struct{})
+	arrived := make(chan struct{}, maxConcurrent)
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-unblock
+		fmt.Fprint(w, expectedKeyAuthorization)
+	}))
+	defer hs.Close()
+
+	va, _ := setup(hs, "", nil, nil)
+	fc := va.clk.(clock.FakeClock)
+	va.admission = newAdmissionController(maxConcurrent, maxWait, fc, metrics.NoopRegisterer)
+
+	validate := func(i int) (*vapb.ValidationResult, error) {
+		req := createValidationRequest("localhost", core.ChallengeTypeHTTP01)
+		req.Authz.Id = fmt.Sprint(i)
+		return va.PerformValidation(ctx, req)
+	}
+
+	// Fill every slot with a validation which won't complete until the
+	// server is unblocked.
+	type result struct {
+		res *vapb.ValidationResult
+		err error
+	}
+	admitted := make(chan result, maxConcurrent)
+	for i := range maxConcurrent {
+		go func() {
+			res, err := validate(i)
+			admitted <- result{res, err}
+		}()
+	}
+	for range maxConcurrent {
+		<-arrived
+	}
+	test.AssertMetricWithLabelsEquals(t, va.admission.inFlight, prometheus.Labels{}, maxConcurrent)
+
+	// Further requests queue until the max queue wait passes, and are then
+	// shed.
+	shed := make(chan error)
+	for i := range 5 {
+		go func() {
+			_, err := validate(maxConcurrent + i)
+			shed <- err
+		}()
+		awaitQueued(t, va.admission, 1)
+		fc.Add(maxWait)
+		err := <-shed
+		test.AssertError(t, err, "validation beyond capacity should be shed")
+		test.AssertEquals(t, status.Code(err), codes.ResourceExhausted)
+	}
+	go func() {
+		_, err := va.IsCAAValid(ctx, &vapb.IsCAAValidRequest{
+			Domain:           "localhost",
+			ValidationMethod: string(core.ChallengeTypeHTTP01),
+			AccountURIID:     1,
+		})
+		shed <- err
+	}()
+	awaitQueued(t, va.admission, 1)
+	fc.Add(maxWait)
+	test.AssertEquals(t, status.Code(<-shed), codes.ResourceExhausted)
+	test.AssertMetricWithLabelsEquals(t, va.admission.shed, prometheus.Labels{"operation": opDCVAndCAA}, 5)
+	test.AssertMetricWithLabelsEquals(t, va.admission.shed, prometheus.Labels{"operation": opCAA}, 1)
+	test.AssertMetricWithLabelsEquals(t, va.admission.queued, prometheus.Labels{}, 0)
+
+	// The admitted validations are unaffected.
+	close(unblock)
+	for range maxConcurrent {
+		r := <-admitted
+		test.AssertNotError(t, r.err, "admitted validation failed")
+		test.Assert(t, r.res.Problem == nil, fmt.Sprintf("admitted validation failed: %#v", r.res.Problem))
+	}
+	test.AssertMetricWithLabelsEquals(t, va.admission.inFlight, prometheus.Labels{}, 0)
+
+	// Once capacity frees up, requests are admitted again.
+	res, err := validate(100)
+	test.AssertNotError(t, err, "validation after load subsided failed")
+	test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed: %#v", res.Problem))
+}
+
+func TestAdmissionControllerQueues(t *testing.T) {
+	t.Parallel()
+	fc := clock.NewFake()
+	a := newAdmissionController(1, time.Second, fc, metrics.NoopRegisterer)
+
+	release, err := a.admit(ctx, opDCV)
+	test.AssertNotError(t, err, "first request should be admitted")
+
+	// A request which arrives at capacity waits for a slot rather than being
+	// shed, if one frees up within the max queue wait.
+	admitted := make(chan error)
+	go func() {
+		release, err := a.admit(ctx, opDCV)
+		if err == nil {
+			release()
+		}
+		admitted <- err
+	}()
+	awaitQueued(t, a, 1)
+	fc.Add(time.Second - time.Millisecond)
+	release()
+	test.AssertNotError(t, <-admitted, "queued request should be admitted")
+
+	// A nil controller admits everything.
+	var unbounded *admissionController
+	release, err = unbounded.admit(ctx, opDCV)
+	test.AssertNotError(t, err, "nil controller should admit")
+	release()
+}
+
+func TestRemoteShedIsNotCorroborating(t *testing.T) {
+	t.Parallel()
+
+	va, mockLog := setup(nil, "", nil, nil)
+	prob := va.remoteProblem("remote", nil, status.Error(codes.ResourceExhausted, "VA is at capacity"))
+	test.AssertEquals(t, prob.Type, probs.ServerInternalProblem)
+	test.AssertContains(t, prob.Detail, "remote VA at capacity")
+	test.AssertEquals(t, len(mockLog.GetAllMatching("ERR:")), 0)
+	test.AssertEquals(t, len(mockLog.GetAllMatching("WARNING: Remote VA \\(remote\\) is at capacity")), 1)
+}
@@ -0,0 +1,48 @@
+package va
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/letsencrypt/boulder/core"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+)
+
+// attemptStartEvent is logged when a validation or CAA check begins. Its
+// AttemptID matches that of the result logged when it finishes, and those
+// logged by the remote perspectives consulted for it.
+type attemptStartEvent struct {
+	AttemptID     string
+	Operation     string
+	Identifier    string
+	ChallengeType core.AcmeChallenge
+	Requester     int64
+	Perspective   string
+}
+
+// startAttempt logs the start of a validation or CAA check of ident, and
+// marks it in flight until the returned function is called. The attempt takes
+// its ID from ctx if the primary VA set one, so that remote perspectives log
+// the primary's ID, and otherwise is assigned a new one. The returned context
+// carries the ID to any remote perspectives consulted.
+func (va *ValidationAuthorityImpl) startAttempt(ctx context.Context, op, ident string, challType core.AcmeChallenge, regID int64) (context.Context, string, func()) {
+	attemptID := bgrpc.ValidationAttemptID(ctx)
+	if attemptID == "" {
+		attemptID = uuid.NewString()
+		ctx = bgrpc.WithValidationAttemptID(ctx, attemptID)
+	}
+
+	va.log.AuditObject("Validation started", attemptStartEvent{
+		AttemptID:     attemptID,
+		Operation:     op,
+		Identifier:    ident,
+		ChallengeType: challType,
+		Requester:     regID,
+		Perspective:   va.perspective,
+	})
+
+	inFlight := va.metrics.validationsInFlight.WithLabelValues(op, string(challType))
+	inFlight.Inc()
+	return ctx, attemptID, inFlight.Dec
+}
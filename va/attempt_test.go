@@ -0,0 +1,147 @@
+package va
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/core"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+// parseAttemptLines returns the start and finish events, keyed by AttemptID,
+// which were logged to mockLog with the given finish message.
+func parseAttemptLines(t *testing.T, mockLog *blog.Mock, finishMsg string) (map[string]attemptStartEvent, map[string]validationLogEvent) {
+	t.Helper()
+	re := regexp.MustCompile(`JSON=(\{.*\})`)
+	starts := make(map[string]attemptStartEvent)
+	for _, line := range mockLog.GetAllMatching(`Validation started JSON=`) {
+		var event attemptStartEvent
+		err := json.Unmarshal([]byte(re.FindStringSubmatch(line)[1]), &event)
+		test.AssertNotError(t, err, "parsing start event")
+		starts[event.AttemptID] = event
+	}
+	finishes := make(map[string]validationLogEvent)
+	for _, line := range mockLog.GetAllMatching(finishMsg + ` JSON=`) {
+		var event validationLogEvent
+		err := json.Unmarshal([]byte(re.FindStringSubmatch(line)[1]), &event)
+		test.AssertNotError(t, err, "parsing finish event")
+		finishes[event.AttemptID] = event
+	}
+	return starts, finishes
+}
+
+func TestValidationAttemptLogging(t *testing.T) {
+	t.Parallel()
+
+	ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true})
+	defer ms.Close()
+
+	remotes := []remoteConf{
+		{rir: arin},
+		{rir: ripe},
+		{rir: apnic},
+	}
+	va, mockLog := setupWithRemotes(ms.Server, pass, remotes, nil)
+
+	testCases := []struct {
+		name      string
+		op        string
+		finishMsg string
+		do        func() error
+	}{
+		{
+			name:      "DCV",
+			op:        opDCV,
+			finishMsg: "Validation result",
+			do: func() error {
+				_, err := va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+				return err
+			},
+		},
+		{
+			name:      "CAA",
+			op:        opCAA,
+			finishMsg: "CAA check result",
+			do: func() error {
+				_, err := va.DoCAA(ctx, &vapb.IsCAAValidRequest{
+					Domain:           "letsencrypt.org",
+					ValidationMethod: string(core.ChallengeTypeHTTP01),
+					AccountURIID:     1,
+				})
+				return err
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockLog.Clear()
+			for _, rva := range va.remoteVAs {
+				rva.VAClient.(identifiedVAClient).VAClient.(*inMemVA).rva.log.(*blog.Mock).Clear()
+			}
+
+			err := tc.do()
+			test.AssertNotError(t, err, "performing operation")
+
+			starts, finishes := parseAttemptLines(t, mockLog, tc.finishMsg)
+			test.AssertEquals(t, len(starts), 1)
+			test.AssertEquals(t, len(finishes), 1)
+			var attemptID string
+			for id, start := range starts {
+				attemptID = id
+				test.AssertEquals(t, start.Operation, tc.op)
+				test.AssertEquals(t, start.Identifier, "letsencrypt.org")
+				test.AssertEquals(t, start.ChallengeType, core.ChallengeTypeHTTP01)
+				test.AssertEquals(t, start.Requester, int64(1))
+				test.AssertEquals(t, start.Perspective, PrimaryPerspective)
+			}
+			test.Assert(t, attemptID != "", "start event has no attempt ID")
+			_, ok := finishes[attemptID]
+			test.Assert(t, ok, "finish event doesn't match start event")
+
+			// Every remote perspective logs the primary's attempt ID.
+			for _, rva := range va.remoteVAs {
+				remote := rva.VAClient.(identifiedVAClient).VAClient.(*inMemVA).rva
+				starts, finishes := parseAttemptLines(t, remote.log.(*blog.Mock), tc.finishMsg)
+				test.AssertEquals(t, len(starts), 1)
+				test.AssertEquals(t, starts[attemptID].Perspective, rva.Perspective)
+				_, ok := finishes[attemptID]
+				test.Assert(t, ok, "remote finish event doesn't match primary start event")
+				test.AssertMetricWithLabelsEquals(t, remote.metrics.validationsInFlight, prometheus.Labels{
+					"operation": tc.op, "challenge_type": string(core.ChallengeTypeHTTP01),
+				}, 0)
+			}
+
+			test.AssertMetricWithLabelsEquals(t, va.metrics.validationsInFlight, prometheus.Labels{
+				"operation": tc.op, "challenge_type": string(core.ChallengeTypeHTTP01),
+			}, 0)
+		})
+	}
+}
+
+func TestStartAttempt(t *testing.T) {
+	t.Parallel()
+
+	va, mockLog := setup(nil, "", nil, nil)
+	labels := prometheus.Labels{"operation": opDCV, "challenge_type": string(core.ChallengeTypeDNS01)}
+
+	attemptCtx, attemptID, done := va.startAttempt(ctx, opDCV, "example.com", core.ChallengeTypeDNS01, 1)
+	test.Assert(t, attemptID != "", "expected a new attempt ID")
+	test.AssertEquals(t, bgrpc.ValidationAttemptID(attemptCtx), attemptID)
+	test.AssertMetricWithLabelsEquals(t, va.metrics.validationsInFlight, labels, 1)
+
+	// An attempt started with the ID of another reuses it.
+	_, nestedID, nestedDone := va.startAttempt(attemptCtx, opDCV, "example.com", core.ChallengeTypeDNS01, 1)
+	test.AssertEquals(t, nestedID, attemptID)
+	test.AssertMetricWithLabelsEquals(t, va.metrics.validationsInFlight, labels, 2)
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`Validation started JSON=\{"AttemptID":"`+attemptID+`"`)), 2)
+
+	nestedDone()
+	done()
+	test.AssertMetricWithLabelsEquals(t, va.metrics.validationsInFlight, labels, 0)
+}
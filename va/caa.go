@@ -2,6 +2,7 @@ package va
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -10,6 +11,8 @@ import (
 	"time"
 
 	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/letsencrypt/boulder/bdns"
@@ -27,12 +30,33 @@ type caaParams struct {
 	validationMethod core.AcmeChallenge
 }
 
+// CAAValidationMethodsMode determines what the VA does when a CAA record would
+// authorize issuance but for its RFC 8657 validationmethods parameter.
+type CAAValidationMethodsMode string
+
+const (
+	// CAAValidationMethodsEnforce refuses issuance when no record permits the
+	// validation method in use.
+	CAAValidationMethodsEnforce CAAValidationMethodsMode = "enforce"
+
+	// CAAValidationMethodsLogOnly permits issuance when the only records which
+	// would otherwise authorize us exclude the validation method in use, but
+	// audit logs and counts each such mismatch. It exists to measure the impact
+	// of enforcement before enabling it.
+	CAAValidationMethodsLogOnly CAAValidationMethodsMode = "log-only"
+)
+
 // IsCAAValid checks requested CAA records from a VA, and recursively any RVAs
 // configured in the VA. It returns a response or an error.
 func (va *ValidationAuthorityImpl) IsCAAValid(ctx context.Context, req *vapb.IsCAAValidRequest) (*vapb.IsCAAValidResponse, error) {
 	if core.IsAnyNilOrZero(req.Domain, req.ValidationMethod, req.AccountURIID) {
 		return nil, berrors.InternalServerError("incomplete IsCAAValid request")
 	}
+	release, err := va.admission.admit(ctx, opCAA)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	logEvent := verificationRequestEvent{
 		// TODO(#7061) Plumb req.Authz.Id as "AuthzID:" through from the RA to
 		// correlate which authz triggered this request.
@@ -50,10 +74,15 @@ func (va *ValidationAuthorityImpl) IsCAAValid(ctx context.Context, req *vapb.IsC
 		accountURIID:     req.AccountURIID,
 		validationMethod: challType,
 	}
+	ctx, logEvent.PolicyHints = va.applyPolicyHints(ctx, req.PolicyHints)
+	var done func()
+	ctx, logEvent.AttemptID, done = va.startAttempt(ctx, opCAA, req.Domain, challType, req.AccountURIID)
+	defer done()
 
 	var prob *probs.ProblemDetails
 	var internalErr error
 	var localLatency time.Duration
+	var recheckLocalOnly bool
 	start := va.clk.Now()
 
 	defer func() {
@@ -63,6 +92,7 @@ func (va *ValidationAuthorityImpl) IsCAAValid(ctx context.Context, req *vapb.IsC
 			// CAA check failed.
 			probType = string(prob.Type)
 			logEvent.Error = prob.Error()
+			logEvent.DNSDetails = prob.DNSDetails
 		} else {
 			// CAA check passed.
 			outcome = pass
@@ -70,8 +100,12 @@ func (va *ValidationAuthorityImpl) IsCAAValid(ctx context.Context, req *vapb.IsC
 		// Observe local check latency (primary|remote).
 		va.observeLatency(opCAA, va.perspective, string(challType), probType, outcome, localLatency)
 		if va.isPrimaryVA() {
-			// Observe total check latency (primary+remote).
-			va.observeLatency(opCAA, allPerspectives, string(challType), probType, outcome, va.clk.Since(start))
+			// Observe total check latency (primary+remote|recheck_local).
+			total := allPerspectives
+			if recheckLocalOnly {
+				total = recheckLocalPerspective
+			}
+			va.observeLatency(opCAA, total, string(challType), probType, outcome, va.clk.Since(start))
 		}
 		// Log the total check latency.
 		logEvent.Latency = va.clk.Since(start).Round(time.Millisecond).Seconds()
@@ -86,11 +120,16 @@ func (va *ValidationAuthorityImpl) IsCAAValid(ctx context.Context, req *vapb.IsC
 
 	if internalErr != nil {
 		logEvent.InternalError = internalErr.Error()
+		logEvent.DNSResolver = dnsResolver(internalErr)
+		va.noteResourceExhaustion(opCAA, req.Domain, internalErr)
 		prob = detailedError(internalErr)
 		prob.Detail = fmt.Sprintf("While processing CAA for %s: %s", req.Domain, prob.Detail)
 	}
 
-	if features.Get().EnforceMultiCAA {
+	if features.Get().EnforceMultiCAA && va.skipRecheckCorroboration(req) {
+		recheckLocalOnly = true
+		va.metrics.mpicSkipped.WithLabelValues(mpicSkippedRecheckLocalOnly).Inc()
+	} else if features.Get().EnforceMultiCAA {
 		op := func(ctx context.Context, remoteva RemoteVA, req proto.Message) (remoteResult, error) {
 			checkRequest, ok := req.(*vapb.IsCAAValidRequest)
 			if !ok {
@@ -134,45 +173,109 @@ func (va *ValidationAuthorityImpl) checkCAA(
 	ctx context.Context,
 	identifier identifier.ACMEIdentifier,
 	params *caaParams) error {
+	_, err := va.checkCAAWithQueries(ctx, identifier, params)
+	return err
+}
+
+// checkCAAWithQueries is like checkCAA, but also returns the DNS queries which
+// were made, whether or not the check succeeded.
+func (va *ValidationAuthorityImpl) checkCAAWithQueries(
+	ctx context.Context,
+	identifier identifier.ACMEIdentifier,
+	params *caaParams) (bdns.ResolverAddrs, error) {
 	if core.IsAnyNilOrZero(params, params.validationMethod, params.accountURIID) {
-		return probs.ServerInternal("expected validationMethod or accountURIID not provided to checkCAA")
+		return nil, probs.ServerInternal("expected validationMethod or accountURIID not provided to checkCAA")
 	}
 
-	foundAt, valid, response, err := va.checkCAARecords(ctx, identifier, params)
+	var err error
+	identifier.Value, err = va.normalizeDNSName(identifier.Value)
 	if err != nil {
-		return berrors.DNSError("%s", err)
+		return nil, err
+	}
+	if len(identifier.Value) > maxCAANameLength {
+		return nil, berrors.MalformedError("Identifier is longer than %d octets", maxCAANameLength)
 	}
 
-	va.log.AuditInfof("Checked CAA records for %s, [Present: %t, Account ID: %d, Challenge: %s, Valid for issuance: %t, Found at: %q] Response=%q",
-		identifier.Value, foundAt != "", params.accountURIID, params.validationMethod, valid, foundAt, response)
+	foundAt, valid, reason, response, lookups, err := va.checkCAARecords(ctx, identifier, params)
+	var queries bdns.ResolverAddrs
+	for _, lookup := range lookups {
+		queries = append(queries, lookup.Resolvers...)
+	}
+	lookupsJSON, jsonErr := json.Marshal(lookups)
+	if jsonErr != nil {
+		return queries, fmt.Errorf("marshalling CAA lookups: %w", jsonErr)
+	}
+	if err != nil {
+		va.log.AuditInfof("Failed to check CAA records for %s, [Account ID: %d, Challenge: %s] Error=%q Lookups=%s",
+			identifier.Value, params.accountURIID, params.validationMethod, err, lookupsJSON)
+		return queries, newDNSError(err)
+	}
+
+	va.log.AuditInfof("Checked CAA records for %s, [Present: %t, Account ID: %d, Challenge: %s, Valid for issuance: %t, Found at: %q] Response=%q Lookups=%s",
+		identifier.Value, foundAt != "", params.accountURIID, params.validationMethod, valid, foundAt, response, lookupsJSON)
 	if !valid {
-		return berrors.CAAError("CAA record for %s prevents issuance", foundAt)
+		if reason != "" {
+			return queries, berrors.CAAError("CAA record for %s prevents issuance: %s", foundAt, reason)
+		}
+		return queries, berrors.CAAError("CAA record for %s prevents issuance", foundAt)
 	}
-	return nil
+	return queries, nil
 }
 
 // caaResult represents the result of querying CAA for a single name. It breaks
 // the CAA resource records down by category, keeping only the issue and
-// issuewild records. It also records whether any unrecognized RRs were marked
-// critical, and stores the raw response text for logging and debugging.
+// issuewild records, and the URLs of the iodef records. It also records the
+// first unrecognized tag which was marked critical, any unrecognized tags which
+// were not, and stores the raw response text for logging and debugging.
 type caaResult struct {
 	name            string
 	present         bool
 	issue           []*dns.CAA
 	issuewild       []*dns.CAA
-	criticalUnknown bool
+	iodef           []string
+	criticalUnknown string
+	ignoredTags     []string
 	dig             string
 	resolvers       bdns.ResolverAddrs
+	queriedAt       time.Time
+	digest          string
 	err             error
 }
 
+// caaLookup describes a single CAA lookup made while checking CAA, including
+// the bdns.AnswerDigest of the records which were returned. Its fields are
+// exported for logging purposes.
+type caaLookup struct {
+	Name      string
+	Resolvers bdns.ResolverAddrs `json:",omitempty"`
+	QueriedAt time.Time
+	Digest    string `json:",omitempty"`
+}
+
+// caaLookups summarizes the given results, in the order they were provided,
+// for audit logging. Lookups which failed have no digest.
+func caaLookups(results []caaResult) []caaLookup {
+	lookups := make([]caaLookup, 0, len(results))
+	for _, r := range results {
+		lookups = append(lookups, caaLookup{
+			Name:      r.name,
+			Resolvers: r.resolvers,
+			QueriedAt: r.queriedAt,
+			Digest:    r.digest,
+		})
+	}
+	return lookups
+}
+
 // filterCAA processes a set of CAA resource records and picks out the only bits
 // we care about. It returns two slices of CAA records, representing the issue
-// records and the issuewild records respectively, and a boolean indicating
-// whether any unrecognized records had the critical bit set.
-func filterCAA(rrs []*dns.CAA) ([]*dns.CAA, []*dns.CAA, bool) {
+// records and the issuewild records respectively, the tag of the first
+// unrecognized record which had the critical bit set (or the empty string if
+// there was none), and the tags of any unrecognized non-critical records.
+func filterCAA(rrs []*dns.CAA) ([]*dns.CAA, []*dns.CAA, string, []string) {
 	var issue, issuewild []*dns.CAA
-	var criticalUnknown bool
+	var criticalUnknown string
+	var ignoredTags []string
 
 	for _, caaRecord := range rrs {
 		switch strings.ToLower(caaRecord.Tag) {
@@ -181,10 +284,10 @@ func filterCAA(rrs []*dns.CAA) ([]*dns.CAA, []*dns.CAA, bool) {
 		case "issuewild":
 			issuewild = append(issuewild, caaRecord)
 		case "iodef":
-			// We support the iodef property tag insofar as we recognize it, but we
-			// never choose to send notifications to the specified addresses. So we
-			// do not store the contents of the property tag, but also avoid setting
-			// the criticalUnknown bit if there are critical iodef tags.
+			// We support the iodef property tag insofar as we recognize it, and
+			// so avoid setting the criticalUnknown bit if there are critical
+			// iodef tags. Its URLs are picked out by iodefURLs, for reporting
+			// violations if that's enabled.
 			continue
 		case "issuemail":
 			// We support the issuemail property tag insofar as we recognize it and
@@ -199,37 +302,65 @@ func filterCAA(rrs []*dns.CAA) ([]*dns.CAA, []*dns.CAA, bool) {
 			// widespread that that bit must reasonably be considered an alias for
 			// the critical bit. The remaining bits are 0/ignore as proscribed by the
 			// RFC.
-			if (caaRecord.Flag & (128 | 1)) != 0 {
-				criticalUnknown = true
+			if (caaRecord.Flag & (128 | 1)) == 0 {
+				ignoredTags = append(ignoredTags, caaRecord.Tag)
+				continue
+			}
+			if criticalUnknown == "" {
+				criticalUnknown = caaRecord.Tag
 			}
 		}
 	}
 
-	return issue, issuewild, criticalUnknown
+	return issue, issuewild, criticalUnknown, ignoredTags
+}
+
+// maxCAANameLength is the maximum length, in octets, of a name for which CAA
+// will be checked. It bounds the number of names climbed by caaTreeNames.
+const maxCAANameLength = 253
+
+// caaTreeNames returns the names which must be queried to find the CAA relevant
+// RRset for name, in the order RFC 8659 Section 3 climbs them: name itself,
+// then each of its parents up to but not including the root. Only parents of
+// the original name are climbed. If a query is answered via a CNAME or DNAME,
+// the resolver follows the alias for that query alone, and the parents of the
+// alias target are never climbed.
+func caaTreeNames(name string) []string {
+	labels := strings.Split(name, ".")
+	names := make([]string, len(labels))
+	for i := range labels {
+		names[i] = strings.Join(labels[i:], ".")
+	}
+	return names
 }
 
 // parallelCAALookup makes parallel requests for the target name and all parent
-// names. It returns a slice of CAA results, with the results from querying the
-// FQDN in the zeroth index, and the results from querying the TLD in the last
-// index.
+// names, as returned by caaTreeNames. It returns a slice of CAA results, with
+// the results from querying the FQDN in the zeroth index, and the results from
+// querying the TLD in the last index.
 func (va *ValidationAuthorityImpl) parallelCAALookup(ctx context.Context, name string) []caaResult {
-	labels := strings.Split(name, ".")
-	results := make([]caaResult, len(labels))
+	names := caaTreeNames(name)
+	results := make([]caaResult, len(names))
 	var wg sync.WaitGroup
 
-	for i := range len(labels) {
+	for i := range names {
 		// Start the concurrent DNS lookup.
 		wg.Add(1)
 		go func(name string, r *caaResult) {
 			r.name = name
+			r.queriedAt = va.clk.Now()
 			var records []*dns.CAA
 			records, r.dig, r.resolvers, r.err = va.dnsClient.LookupCAA(ctx, name)
+			if r.err == nil {
+				r.digest = bdns.CAAAnswerDigest(records)
+			}
 			if len(records) > 0 {
 				r.present = true
 			}
-			r.issue, r.issuewild, r.criticalUnknown = filterCAA(records)
+			r.issue, r.issuewild, r.criticalUnknown, r.ignoredTags = filterCAA(records)
+			r.iodef = iodefURLs(records)
 			wg.Done()
-		}(strings.Join(labels[i:], "."), &results[i])
+		}(names[i], &results[i])
 	}
 
 	wg.Wait()
@@ -257,39 +388,48 @@ func selectCAA(rrs []caaResult) (*caaResult, error) {
 // first CAA RRSet found by traversing upwards from the FQDN by removing the
 // leftmost label. It returns nil if no RRSet is found on any parent of the
 // given FQDN. The returned result also contains the raw CAA response, and an
-// error if one is encountered while querying or parsing the records.
+// error if one is encountered while querying or parsing the records. A summary
+// of every lookup which was made is returned alongside it.
 //
 // [1]: https://datatracker.ietf.org/doc/html/rfc8659#name-relevant-resource-record-se
-func (va *ValidationAuthorityImpl) getCAA(ctx context.Context, hostname string) (*caaResult, error) {
+func (va *ValidationAuthorityImpl) getCAA(ctx context.Context, hostname string) (*caaResult, []caaLookup, error) {
+	ctx, span := va.tracer.Start(ctx, "dns resolution", trace.WithAttributes(
+		attribute.String("hostname", hostname),
+		attribute.String("type", "CAA"),
+	))
+	defer span.End()
 	hostname = strings.TrimRight(hostname, ".")
 
-	// See RFC 6844 "Certification Authority Processing" for pseudocode, as
-	// amended by https://www.rfc-editor.org/errata/eid5065.
-	// Essentially: check CAA records for the FDQN to be issued, and all
-	// parent domains.
+	// See RFC 8659 Section 3: check CAA records for the FQDN to be issued,
+	// and all of its parent domains, stopping at the first non-empty RRset.
+	// Unlike RFC 6844, the parents of CNAME targets are not climbed.
 	//
 	// The lookups are performed in parallel in order to avoid timing out
 	// the RPC call.
 	//
-	// We depend on our resolver to snap CNAME and DNAME records.
+	// We depend on our resolver to follow CNAME and DNAME records for each
+	// individual query, and on bdns to reject alias chains which loop.
 	results := va.parallelCAALookup(ctx, hostname)
-	return selectCAA(results)
+	caaSet, err := selectCAA(results)
+	spanError(span, err)
+	return caaSet, caaLookups(results), err
 }
 
 // checkCAARecords fetches the CAA records for the given identifier and then
 // validates them. If the identifier argument's value has a wildcard prefix then
 // the prefix is stripped and validation will be performed against the base
 // domain, honouring any issueWild CAA records encountered as appropriate.
-// checkCAARecords returns four values: the first is a string indicating at
+// checkCAARecords returns five values: the first is a string indicating at
 // which name (i.e. FQDN or parent thereof) CAA records were found, if any. The
 // second is a bool indicating whether issuance for the identifier is valid. The
+// third is a more specific reason for refusing issuance, if there is one. The
 // unmodified *dns.CAA records that were processed/filtered are returned as the
-// third argument. Any  errors encountered are returned as the fourth return
-// value (or nil).
+// fourth argument, and a summary of each CAA lookup made as the fifth. Any
+// errors encountered are returned as the sixth return value (or nil).
 func (va *ValidationAuthorityImpl) checkCAARecords(
 	ctx context.Context,
 	identifier identifier.ACMEIdentifier,
-	params *caaParams) (string, bool, string, error) {
+	params *caaParams) (string, bool, string, string, []caaLookup, error) {
 	hostname := strings.ToLower(identifier.Value)
 	// If this is a wildcard name, remove the prefix
 	var wildcard bool
@@ -297,35 +437,60 @@ func (va *ValidationAuthorityImpl) checkCAARecords(
 		hostname = strings.TrimPrefix(identifier.Value, `*.`)
 		wildcard = true
 	}
-	caaSet, err := va.getCAA(ctx, hostname)
+	caaSet, lookups, err := va.getCAA(ctx, hostname)
 	if err != nil {
-		return "", false, "", err
+		return "", false, "", "", lookups, err
 	}
 	raw := ""
 	if caaSet != nil {
 		raw = caaSet.dig
 	}
-	valid, foundAt := va.validateCAA(caaSet, wildcard, params)
-	return foundAt, valid, raw, nil
+	mode := validationPolicyFrom(ctx).caaMode(va.caaValidationMethodsMode)
+	valid, foundAt, reason := va.validateCAA(caaSet, wildcard, params, mode)
+	if !valid {
+		va.reportCAAViolation(caaSet, hostname, params.accountURIID, violatedProperty(caaSet, wildcard))
+	}
+	return foundAt, valid, reason, raw, lookups, nil
+}
+
+// violatedProperty returns the tag of the property of caaSet which refused
+// issuance, given that it did: an unrecognized critical property, or else the
+// issuewild or issue properties which validateCAA checked.
+func violatedProperty(caaSet *caaResult, wildcard bool) string {
+	if caaSet.criticalUnknown != "" {
+		return caaSet.criticalUnknown
+	}
+	if wildcard && len(caaSet.issuewild) > 0 {
+		return "issuewild"
+	}
+	return "issue"
 }
 
 // validateCAA checks a provided *caaResult. When the wildcard argument is true
 // this means the issueWild records must be validated as well. This function
 // returns a boolean indicating whether issuance is allowed by this set of CAA
-// records, and a string indicating the name at which the CAA records allowing
+// records, a string indicating the name at which the CAA records allowing
 // issuance were found (if any -- since finding no records at all allows
-// issuance).
-func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool, params *caaParams) (bool, string) {
+// issuance), and a string describing why issuance was refused when that is
+// more specific than the records simply not authorizing us. The mode
+// determines whether validationmethods mismatches are enforced.
+func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool, params *caaParams, mode CAAValidationMethodsMode) (bool, string, string) {
 	if caaSet == nil {
 		// No CAA records found, can issue
 		va.metrics.caaCounter.WithLabelValues("no records").Inc()
-		return true, ""
+		return true, "", ""
 	}
 
-	if caaSet.criticalUnknown {
-		// Contains unknown critical directives
+	if len(caaSet.ignoredTags) > 0 {
+		va.log.Infof("Ignoring unrecognized non-critical CAA property tags %q for %s", caaSet.ignoredTags, caaSet.name)
+	}
+
+	if caaSet.criticalUnknown != "" {
+		// Per RFC 8659 Section 4.1, a critical property tag which we do not
+		// recognize anywhere in the Relevant RRset forbids issuance, even if
+		// another record would otherwise authorize us.
 		va.metrics.caaCounter.WithLabelValues("record with unknown critical directive").Inc()
-		return false, caaSet.name
+		return false, caaSet.name, fmt.Sprintf("unrecognized critical property tag %q", caaSet.criticalUnknown)
 	}
 
 	if len(caaSet.issue) == 0 && !wildcard {
@@ -334,7 +499,7 @@ func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool,
 		// non-wildcard identifier, or there is only an iodef or non-critical unknown
 		// directive.)
 		va.metrics.caaCounter.WithLabelValues("no relevant records").Inc()
-		return true, caaSet.name
+		return true, caaSet.name, ""
 	}
 
 	// Per RFC 8659 Section 5.3:
@@ -355,7 +520,9 @@ func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool,
 	// includes the case of the unsatisfiable CAA record value ";", used to
 	// prevent issuance by any CA under any circumstance.
 	//
-	// Our CAA identity must be found in the chosen checkSet.
+	// Our CAA identity must be found in the chosen checkSet. Every record is
+	// evaluated, so any one of several issue properties may authorize us.
+	var methodMismatch []caaParameter
 	for _, caa := range records {
 		parsedDomain, parsedParams, err := parseCAARecord(caa)
 		if err != nil {
@@ -371,16 +538,30 @@ func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool,
 		}
 
 		if !caaValidationMethodMatches(parsedParams, params.validationMethod) {
+			if methodMismatch == nil {
+				methodMismatch = parsedParams
+			}
 			continue
 		}
 
 		va.metrics.caaCounter.WithLabelValues("authorized").Inc()
-		return true, caaSet.name
+		return true, caaSet.name, ""
+	}
+
+	if methodMismatch != nil && mode == CAAValidationMethodsLogOnly {
+		// A record would have authorized us but for its validationmethods
+		// parameter. Record what enforcement would have refused, then proceed.
+		permitted := caaPermittedValidationMethods(methodMismatch)
+		va.metrics.caaValidationMethodMismatches.WithLabelValues(string(params.validationMethod), permitted).Inc()
+		va.log.AuditInfof("Ignoring CAA validationmethods mismatch for %s in log-only mode, [Account ID: %d, Challenge: %s, Permitted: %q]",
+			caaSet.name, params.accountURIID, params.validationMethod, permitted)
+		va.metrics.caaCounter.WithLabelValues("authorized").Inc()
+		return true, caaSet.name, ""
 	}
 
 	// The list of authorized issuers is non-empty, but we are not in it. Fail.
 	va.metrics.caaCounter.WithLabelValues("unauthorized").Inc()
-	return false, caaSet.name
+	return false, caaSet.name, ""
 }
 
 // caaParameter is a key-value pair parsed from a single CAA RR.
@@ -503,6 +684,19 @@ func caaAccountURIMatches(caaParams []caaParameter, accountURIPrefixes []string,
 
 var validationMethodRegexp = regexp.MustCompile(`^[[:alnum:]-]+$`)
 
+// caaPermittedValidationMethods returns the value of the validationmethods CAA
+// parameter, or the values of each such parameter joined by semicolons if
+// there are several.
+func caaPermittedValidationMethods(caaParams []caaParameter) string {
+	var methods []string
+	for _, param := range caaParams {
+		if param.tag == "validationmethods" {
+			methods = append(methods, param.val)
+		}
+	}
+	return strings.Join(methods, ";")
+}
+
 // caaValidationMethodMatches checks that the validationmethods CAA parameter,
 // if present, contains the exact name of the ACME validation method used to
 // validate this domain. We accept only a single "validationmethods" parameter
@@ -9,7 +9,9 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/miekg/dns"
 	"github.com/prometheus/client_golang/prometheus"
@@ -30,13 +32,22 @@ import (
 // answers for CAA queries.
 type caaMockDNS struct{}
 
-func (mock caaMockDNS) LookupTXT(_ context.Context, hostname string) ([]string, bdns.ResolverAddrs, error) {
-	return nil, bdns.ResolverAddrs{"caaMockDNS"}, nil
+func (mock caaMockDNS) LookupTXT(_ context.Context, hostname string) ([]string, time.Duration, bdns.ResolverAddrs, error) {
+	return nil, 0, bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "TXT"}}, nil
 }
 
 func (mock caaMockDNS) LookupHost(_ context.Context, hostname string) ([]net.IP, bdns.ResolverAddrs, error) {
 	ip := net.ParseIP("127.0.0.1")
-	return []net.IP{ip}, bdns.ResolverAddrs{"caaMockDNS"}, nil
+	return []net.IP{ip}, bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "A"}}, nil
+}
+
+func (mock caaMockDNS) LookupHostFamilies(_ context.Context, hostname string) (*bdns.HostLookup, error) {
+	ip := net.ParseIP("127.0.0.1")
+	return &bdns.HostLookup{IPv4: []net.IP{ip}, Resolvers: bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "A"}}}, nil
+}
+
+func (mock caaMockDNS) LookupMX(_ context.Context, hostname string) ([]string, bdns.ResolverAddrs, error) {
+	return nil, bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "MX"}}, nil
 }
 
 func (mock caaMockDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA, string, bdns.ResolverAddrs, error) {
@@ -44,7 +55,7 @@ func (mock caaMockDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA,
 	var record dns.CAA
 	switch strings.TrimRight(domain, ".") {
 	case "caa-timeout.com":
-		return nil, "", bdns.ResolverAddrs{"caaMockDNS"}, fmt.Errorf("error")
+		return nil, "", bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "CAA"}}, fmt.Errorf("error")
 	case "reserved.com":
 		record.Tag = "issue"
 		record.Value = "ca.com"
@@ -58,17 +69,17 @@ func (mock caaMockDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA,
 		record.Tag = "issue"
 		record.Value = "ca.com"
 		results = append(results, &record)
-	case "present.com", "present.servfail.com":
+	case "present.com", "present.servfail.com", "unknown-critical-remote.com":
 		record.Tag = "issue"
 		record.Value = "letsencrypt.org"
 		results = append(results, &record)
 	case "com":
 		// com has no CAA records.
-		return nil, "", bdns.ResolverAddrs{"caaMockDNS"}, nil
+		return nil, "", bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "CAA"}}, nil
 	case "gonetld":
-		return nil, "", bdns.ResolverAddrs{"caaMockDNS"}, fmt.Errorf("NXDOMAIN")
+		return nil, "", bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "CAA"}}, fmt.Errorf("NXDOMAIN")
 	case "servfail.com", "servfail.present.com":
-		return results, "", bdns.ResolverAddrs{"caaMockDNS"}, fmt.Errorf("SERVFAIL")
+		return results, "", bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "CAA"}}, fmt.Errorf("SERVFAIL")
 	case "multi-crit-present.com":
 		record.Flag = 1
 		record.Tag = "issue"
@@ -92,6 +103,44 @@ func (mock caaMockDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA,
 		record.Tag = "foo"
 		record.Value = "bar"
 		results = append(results, &record)
+	case "unknown-critical-with-issue.com":
+		// The unknown critical record in this set comes after an issue record
+		// which would otherwise authorize us.
+		record.Tag = "issue"
+		record.Value = "letsencrypt.org"
+		results = append(results, &record)
+		secondRecord := record
+		secondRecord.Flag = 128
+		secondRecord.Tag = "tbs"
+		secondRecord.Value = "bar"
+		results = append(results, &secondRecord)
+	case "unknown-noncritical-with-issue.com":
+		record.Tag = "issue"
+		record.Value = "letsencrypt.org"
+		results = append(results, &record)
+		secondRecord := record
+		secondRecord.Flag = 0
+		secondRecord.Tag = "tbs"
+		secondRecord.Value = "bar"
+		results = append(results, &secondRecord)
+	case "multi-issue-present.com":
+		// Only the last of several issue records authorizes us.
+		record.Tag = "issue"
+		record.Value = "ca.com"
+		results = append(results, &record)
+		secondRecord := record
+		secondRecord.Value = "other-ca.com"
+		results = append(results, &secondRecord)
+		thirdRecord := record
+		thirdRecord.Value = "letsencrypt.org"
+		results = append(results, &thirdRecord)
+	case "multi-issue-absent.com":
+		record.Tag = "issue"
+		record.Value = "ca.com"
+		results = append(results, &record)
+		secondRecord := record
+		secondRecord.Value = "other-ca.com"
+		results = append(results, &secondRecord)
 	case "present-with-parameter.com":
 		record.Tag = "issue"
 		record.Value = "  letsencrypt.org  ;foo=bar;baz=bar"
@@ -185,12 +234,145 @@ func (mock caaMockDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA,
 		record.Tag = "issuewild"
 		record.Value = "letsencrypt.org"
 		results = append(results, &record)
+	case "cname-exact.com":
+		// A CNAME to cname-target.net, whose records the resolver returns.
+		record.Tag = "issue"
+		record.Value = "letsencrypt.org"
+		results = append(results, &record)
+	case "cname-target.net":
+		// The parent of the target of www.cname-crosszone.com's CNAME, which
+		// must never be queried on its behalf.
+		record.Tag = "issue"
+		record.Value = "ca.com"
+		results = append(results, &record)
+	case "host.dname-subtree.com":
+		// dname-subtree.com has a DNAME to dname-target.net, so the resolver
+		// returns the records of host.dname-target.net.
+		record.Tag = "issue"
+		record.Value = "ca.com"
+		results = append(results, &record)
+	case "cname-loop.com":
+		return nil, "", bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "CAA"}}, fmt.Errorf("CNAME or DNAME loop")
 	}
 	var response string
 	if len(results) > 0 {
 		response = "foo"
 	}
-	return results, response, bdns.ResolverAddrs{"caaMockDNS"}, nil
+	return results, response, bdns.ResolverAddrs{{Addr: "caaMockDNS", Qtype: "CAA"}}, nil
+}
+
+// caaRecordingDNS is a caaMockDNS which records the names it was asked to look
+// up CAA records for.
+type caaRecordingDNS struct {
+	caaMockDNS
+	sync.Mutex
+	queried []string
+}
+
+func (mock *caaRecordingDNS) LookupCAA(ctx context.Context, domain string) ([]*dns.CAA, string, bdns.ResolverAddrs, error) {
+	mock.Lock()
+	mock.queried = append(mock.queried, domain)
+	mock.Unlock()
+	return mock.caaMockDNS.LookupCAA(ctx, domain)
+}
+
+// TestCAATreeClimbing checks that the CAA relevant RRset is found by climbing
+// only the parents of the original name, as in RFC 8659, for each alias
+// topology that caaMockDNS simulates.
+func TestCAATreeClimbing(t *testing.T) {
+	testCases := []struct {
+		name          string
+		domain        string
+		expectValid   bool
+		expectErr     string
+		expectFoundAt string
+		expectQueried []string
+	}{
+		{
+			name:          "CNAME at the exact name",
+			domain:        "cname-exact.com",
+			expectValid:   true,
+			expectFoundAt: "cname-exact.com",
+			expectQueried: []string{"cname-exact.com", "com"},
+		},
+		{
+			name:          "CNAME crossing zones doesn't climb the target's parents",
+			domain:        "www.cname-crosszone.com",
+			expectValid:   true,
+			expectQueried: []string{"www.cname-crosszone.com", "cname-crosszone.com", "com"},
+		},
+		{
+			name:          "DNAME-rewritten name",
+			domain:        "host.dname-subtree.com",
+			expectValid:   false,
+			expectErr:     "CAA record for host.dname-subtree.com prevents issuance",
+			expectFoundAt: "host.dname-subtree.com",
+			expectQueried: []string{"host.dname-subtree.com", "dname-subtree.com", "com"},
+		},
+		{
+			name:          "DNAME-rewritten name without records",
+			domain:        "other.dname-subtree.com",
+			expectValid:   true,
+			expectQueried: []string{"other.dname-subtree.com", "dname-subtree.com", "com"},
+		},
+		{
+			name:          "alias loop",
+			domain:        "www.cname-loop.com",
+			expectValid:   false,
+			expectErr:     "CNAME or DNAME loop",
+			expectQueried: []string{"www.cname-loop.com", "cname-loop.com", "com"},
+		},
+		{
+			name:          "name too long",
+			domain:        strings.Repeat("a.", 127) + "com",
+			expectValid:   false,
+			expectErr:     "Identifier is longer than 253 octets",
+			expectQueried: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDNS := &caaRecordingDNS{}
+			va, mockLog := setup(nil, "", nil, mockDNS)
+
+			params := &caaParams{accountURIID: 12345, validationMethod: core.ChallengeTypeHTTP01}
+			err := va.checkCAA(ctx, identifier.NewDNS(tc.domain), params)
+			if tc.expectValid {
+				test.AssertNotError(t, err, "checking CAA")
+			} else {
+				test.AssertError(t, err, "checking CAA should have failed")
+				test.AssertContains(t, err.Error(), tc.expectErr)
+			}
+
+			// The lookups are made concurrently, so compare them unordered.
+			slices.Sort(mockDNS.queried)
+			expected := slices.Clone(tc.expectQueried)
+			slices.Sort(expected)
+			test.AssertDeepEquals(t, mockDNS.queried, expected)
+			if tc.expectQueried == nil {
+				return
+			}
+
+			// The audit log records the names queried in the order they were
+			// climbed.
+			caaLogLines := mockLog.GetAllMatching(`CAA records for ` + regexp.QuoteMeta(tc.domain))
+			test.AssertEquals(t, len(caaLogLines), 1)
+			if tc.expectFoundAt != "" {
+				test.AssertContains(t, caaLogLines[0], fmt.Sprintf("Found at: %q", tc.expectFoundAt))
+			}
+			_, lookupsJSON, found := strings.Cut(caaLogLines[0], " Lookups=")
+			test.Assert(t, found, "CAA log line is missing lookups")
+			var lookups []caaLookup
+			err = json.Unmarshal([]byte(lookupsJSON), &lookups)
+			test.AssertNotError(t, err, "unmarshalling logged lookups")
+			var names []string
+			for _, lookup := range lookups {
+				names = append(names, lookup.Name)
+			}
+			test.AssertDeepEquals(t, names, tc.expectQueried)
+		})
+	}
 }
 
 func TestCAATimeout(t *testing.T) {
@@ -291,6 +473,30 @@ func TestCAAChecking(t *testing.T) {
 			FoundAt: "unknown-noncritical.com",
 			Valid:   true,
 		},
+		{
+			Name:    "Bad (unknown critical alongside matching issue)",
+			Domain:  "unknown-critical-with-issue.com",
+			FoundAt: "unknown-critical-with-issue.com",
+			Valid:   false,
+		},
+		{
+			Name:    "Good (unknown non-critical alongside matching issue)",
+			Domain:  "unknown-noncritical-with-issue.com",
+			FoundAt: "unknown-noncritical-with-issue.com",
+			Valid:   true,
+		},
+		{
+			Name:    "Good (multiple issue, last matching)",
+			Domain:  "multi-issue-present.com",
+			FoundAt: "multi-issue-present.com",
+			Valid:   true,
+		},
+		{
+			Name:    "Bad (multiple issue, none matching)",
+			Domain:  "multi-issue-absent.com",
+			FoundAt: "multi-issue-absent.com",
+			Valid:   false,
+		},
 		{
 			Name:    "Good (issue rec with unknown params)",
 			Domain:  "present-with-parameter.com",
@@ -419,7 +625,7 @@ func TestCAAChecking(t *testing.T) {
 		defer mockLog.Clear()
 		t.Run(caaTest.Name, func(t *testing.T) {
 			ident := identifier.NewDNS(caaTest.Domain)
-			foundAt, valid, _, err := va.checkCAARecords(ctx, ident, params)
+			foundAt, valid, _, _, _, err := va.checkCAARecords(ctx, ident, params)
 			if err != nil {
 				t.Errorf("checkCAARecords error for %s: %s", caaTest.Domain, err)
 			}
@@ -515,12 +721,51 @@ func TestCAALogging(t *testing.T) {
 				t.Errorf("checkCAARecords didn't audit log CAA record info. Instead got:\n%s\n",
 					strings.Join(mockLog.GetAllMatching(`.*`), "\n"))
 			} else {
-				test.AssertEquals(t, caaLogLines[0], tc.ExpectedLogline)
+				logline, _, found := strings.Cut(caaLogLines[0], " Lookups=")
+				test.Assert(t, found, "CAA log line is missing lookups")
+				test.AssertEquals(t, logline, tc.ExpectedLogline)
 			}
 		})
 	}
 }
 
+func TestCAALoggingIncludesAnswerDigests(t *testing.T) {
+	va, mockLog := setup(nil, "", nil, caaMockDNS{})
+
+	params := &caaParams{accountURIID: 12345, validationMethod: core.ChallengeTypeHTTP01}
+	err := va.checkCAA(ctx, identifier.NewDNS("not.here.but.still.present.com"), params)
+	test.AssertNotError(t, err, "checking CAA")
+
+	caaLogLines := mockLog.GetAllMatching(`Checked CAA records for`)
+	test.AssertEquals(t, len(caaLogLines), 1)
+	_, lookupsJSON, found := strings.Cut(caaLogLines[0], " Lookups=")
+	test.Assert(t, found, "CAA log line is missing lookups")
+
+	var lookups []caaLookup
+	err = json.Unmarshal([]byte(lookupsJSON), &lookups)
+	test.AssertNotError(t, err, "unmarshalling logged lookups")
+
+	// Every name up to the TLD is looked up, and each lookup's digest can be
+	// recomputed from the records returned for it.
+	var names []string
+	for _, lookup := range lookups {
+		names = append(names, lookup.Name)
+		records, _, resolvers, err := caaMockDNS{}.LookupCAA(ctx, lookup.Name)
+		test.AssertNotError(t, err, "looking up CAA")
+		test.AssertEquals(t, lookup.Digest, bdns.CAAAnswerDigest(records))
+		test.AssertDeepEquals(t, lookup.Resolvers, resolvers)
+		test.AssertEquals(t, lookup.QueriedAt, va.clk.Now())
+	}
+	test.AssertDeepEquals(t, names, []string{
+		"not.here.but.still.present.com",
+		"here.but.still.present.com",
+		"but.still.present.com",
+		"still.present.com",
+		"present.com",
+		"com",
+	})
+}
+
 type caaCheckFuncRunner func(context.Context, *ValidationAuthorityImpl, *vapb.IsCAAValidRequest) (*vapb.IsCAAValidResponse, error)
 
 var runIsCAAValid = func(ctx context.Context, va *ValidationAuthorityImpl, req *vapb.IsCAAValidRequest) (*vapb.IsCAAValidResponse, error) {
@@ -531,6 +776,37 @@ var runDoCAA = func(ctx context.Context, va *ValidationAuthorityImpl, req *vapb.
 	return va.DoCAA(ctx, req)
 }
 
+func TestDoCAAPerspectiveResults(t *testing.T) {
+	t.Parallel()
+
+	va, _ := setupWithRemotes(nil, "local", []remoteConf{
+		{ua: "remote", rir: arin},
+		{ua: "remote", rir: ripe, impl: RemoteClients{VAClient: brokenRemoteVA{}, CAAClient: brokenRemoteVA{}}},
+		{ua: "remote", rir: apnic},
+	}, caaMockDNS{})
+
+	res, err := va.DoCAA(context.Background(), &vapb.IsCAAValidRequest{
+		Domain:           "present-dns-only.com",
+		ValidationMethod: string(core.ChallengeTypeDNS01),
+		AccountURIID:     1,
+	})
+	test.AssertNotError(t, err, "DoCAA failed")
+	test.AssertBoxedNil(t, res.Problem, "DoCAA should have passed with one broken remote")
+
+	// The primary's result comes first, followed by every remote's, all of
+	// them attributed to CAA.
+	test.AssertEquals(t, len(res.PerspectiveResults), 4)
+	test.AssertEquals(t, res.PerspectiveResults[0].Perspective, PrimaryPerspective)
+	var failed int
+	for _, r := range res.PerspectiveResults {
+		test.AssertEquals(t, r.Operation, opCAA)
+		if !r.Passed {
+			failed++
+		}
+	}
+	test.AssertEquals(t, failed, 1)
+}
+
 // TestIsCAAValidErrMessage tests that an error result from `va.IsCAAValid`
 // includes the domain name that was being checked in the failure detail.
 func TestIsCAAValidErrMessage(t *testing.T) {
@@ -630,16 +906,28 @@ var errCAABrokenDNSClient = errors.New("dnsClient is broken")
 // errors.
 type caaBrokenDNS struct{}
 
-func (b caaBrokenDNS) LookupTXT(_ context.Context, hostname string) ([]string, bdns.ResolverAddrs, error) {
-	return nil, bdns.ResolverAddrs{"caaBrokenDNS"}, errCAABrokenDNSClient
+func (b caaBrokenDNS) LookupTXT(_ context.Context, hostname string) ([]string, time.Duration, bdns.ResolverAddrs, error) {
+	return nil, 0, bdns.ResolverAddrs{{Addr: "caaBrokenDNS", Qtype: "TXT"}}, errCAABrokenDNSClient
 }
 
 func (b caaBrokenDNS) LookupHost(_ context.Context, hostname string) ([]net.IP, bdns.ResolverAddrs, error) {
-	return nil, bdns.ResolverAddrs{"caaBrokenDNS"}, errCAABrokenDNSClient
+	return nil, bdns.ResolverAddrs{{Addr: "caaBrokenDNS", Qtype: "A"}}, errCAABrokenDNSClient
+}
+
+func (b caaBrokenDNS) LookupHostFamilies(_ context.Context, hostname string) (*bdns.HostLookup, error) {
+	return &bdns.HostLookup{
+		ErrA:      errCAABrokenDNSClient,
+		ErrAAAA:   errCAABrokenDNSClient,
+		Resolvers: bdns.ResolverAddrs{{Addr: "caaBrokenDNS", Qtype: "A"}},
+	}, errCAABrokenDNSClient
+}
+
+func (b caaBrokenDNS) LookupMX(_ context.Context, hostname string) ([]string, bdns.ResolverAddrs, error) {
+	return nil, bdns.ResolverAddrs{{Addr: "caaBrokenDNS", Qtype: "MX"}}, errCAABrokenDNSClient
 }
 
 func (b caaBrokenDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA, string, bdns.ResolverAddrs, error) {
-	return nil, "", bdns.ResolverAddrs{"caaBrokenDNS"}, errCAABrokenDNSClient
+	return nil, "", bdns.ResolverAddrs{{Addr: "caaBrokenDNS", Qtype: "CAA"}}, errCAABrokenDNSClient
 }
 
 func TestDisabledMultiCAARechecking(t *testing.T) {
@@ -670,14 +958,24 @@ func TestDisabledMultiCAARechecking(t *testing.T) {
 // changed while queries were inflight.
 type caaHijackedDNS struct{}
 
-func (h caaHijackedDNS) LookupTXT(_ context.Context, hostname string) ([]string, bdns.ResolverAddrs, error) {
-	return nil, bdns.ResolverAddrs{"caaHijackedDNS"}, nil
+func (h caaHijackedDNS) LookupTXT(_ context.Context, hostname string) ([]string, time.Duration, bdns.ResolverAddrs, error) {
+	return nil, 0, bdns.ResolverAddrs{{Addr: "caaHijackedDNS", Qtype: "TXT"}}, nil
 }
 
 func (h caaHijackedDNS) LookupHost(_ context.Context, hostname string) ([]net.IP, bdns.ResolverAddrs, error) {
 	ip := net.ParseIP("127.0.0.1")
-	return []net.IP{ip}, bdns.ResolverAddrs{"caaHijackedDNS"}, nil
+	return []net.IP{ip}, bdns.ResolverAddrs{{Addr: "caaHijackedDNS", Qtype: "A"}}, nil
+}
+
+func (h caaHijackedDNS) LookupHostFamilies(_ context.Context, hostname string) (*bdns.HostLookup, error) {
+	ip := net.ParseIP("127.0.0.1")
+	return &bdns.HostLookup{IPv4: []net.IP{ip}, Resolvers: bdns.ResolverAddrs{{Addr: "caaHijackedDNS", Qtype: "A"}}}, nil
+}
+
+func (h caaHijackedDNS) LookupMX(_ context.Context, hostname string) ([]string, bdns.ResolverAddrs, error) {
+	return nil, bdns.ResolverAddrs{{Addr: "caaHijackedDNS", Qtype: "MX"}}, nil
 }
+
 func (h caaHijackedDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA, string, bdns.ResolverAddrs, error) {
 	// These records are altered from their caaMockDNS counterparts. Use this to
 	// tickle remoteValidationFailures.
@@ -689,7 +987,16 @@ func (h caaHijackedDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA,
 		record.Value = "other-ca.com"
 		results = append(results, &record)
 	case "present-dns-only.com":
-		return results, "", bdns.ResolverAddrs{"caaHijackedDNS"}, fmt.Errorf("SERVFAIL")
+		return results, "", bdns.ResolverAddrs{{Addr: "caaHijackedDNS", Qtype: "CAA"}}, fmt.Errorf("SERVFAIL")
+	case "unknown-critical-remote.com":
+		record.Tag = "issue"
+		record.Value = "letsencrypt.org"
+		results = append(results, &record)
+		secondRecord := record
+		secondRecord.Flag = 128
+		secondRecord.Tag = "tbs"
+		secondRecord.Value = "bar"
+		results = append(results, &secondRecord)
 	case "satisfiable-wildcard.com":
 		record.Tag = "issuewild"
 		record.Value = ";"
@@ -703,7 +1010,7 @@ func (h caaHijackedDNS) LookupCAA(_ context.Context, domain string) ([]*dns.CAA,
 	if len(results) > 0 {
 		response = "foo"
 	}
-	return results, response, bdns.ResolverAddrs{"caaHijackedDNS"}, nil
+	return results, response, bdns.ResolverAddrs{{Addr: "caaHijackedDNS", Qtype: "CAA"}}, nil
 }
 
 // parseValidationLogEvent extracts ... from JSON={ ... } in a ValidateChallenge
@@ -1000,6 +1307,31 @@ func TestMultiCAARechecking(t *testing.T) {
 				{ua: remoteUA, rir: apnic},
 			},
 		},
+		{
+			name:                  "all VAs functional, unknown critical CAA tag forbids issuance",
+			domains:               "unknown-critical-with-issue.com",
+			expectedProbSubstring: `CAA record for unknown-critical-with-issue.com prevents issuance: unrecognized critical property tag "tbs"`,
+			expectedProbType:      probs.CAAProblem,
+			localDNSClient:        caaMockDNS{},
+			remoteVAs: []remoteConf{
+				{ua: remoteUA, rir: arin},
+				{ua: remoteUA, rir: ripe},
+				{ua: remoteUA, rir: apnic},
+			},
+		},
+		{
+			name:                     "3 RVAs see unknown critical CAA tag",
+			domains:                  "unknown-critical-remote.com",
+			expectedProbSubstring:    `CAA record for unknown-critical-remote.com prevents issuance: unrecognized critical property tag "tbs"`,
+			expectedProbType:         probs.CAAProblem,
+			expectedDiffLogSubstring: `"RemoteSuccesses":0,"RemoteFailures":3`,
+			localDNSClient:           caaMockDNS{},
+			remoteVAs: []remoteConf{
+				{ua: hijackedUA, rir: arin, dns: caaHijackedDNS{}},
+				{ua: hijackedUA, rir: ripe, dns: caaHijackedDNS{}},
+				{ua: hijackedUA, rir: apnic, dns: caaHijackedDNS{}},
+			},
+		},
 		{
 			name:                     "2 hijacked RVAs, CAA issue type present",
 			domains:                  "present.com",
@@ -1201,7 +1533,7 @@ func TestMultiCAARechecking(t *testing.T) {
 				}
 
 				if testFunc.name == "DoCAA" && tc.expectedSummary != nil {
-					gotAuditLog := parseValidationLogEvent(t, mockLog.GetAllMatching("JSON=.*"))
+					gotAuditLog := parseValidationLogEvent(t, mockLog.GetAllMatching(`CAA check result JSON=.*`))
 					slices.Sort(tc.expectedSummary.Passed)
 					slices.Sort(tc.expectedSummary.Failed)
 					slices.Sort(tc.expectedSummary.PassedRIRs)
@@ -1225,6 +1557,100 @@ func TestMultiCAARechecking(t *testing.T) {
 	}
 }
 
+func TestRecheckCAALocalOnly(t *testing.T) {
+	// Every remote perspective sees hijacked CAA records which forbid
+	// issuance, so the check fails if and only if they're consulted.
+	remoteVAs := []remoteConf{
+		{ua: "hijacked", rir: arin, dns: caaHijackedDNS{}},
+		{ua: "hijacked", rir: ripe, dns: caaHijackedDNS{}},
+		{ua: "hijacked", rir: apnic, dns: caaHijackedDNS{}},
+	}
+
+	testCases := []struct {
+		name              string
+		localOnly         bool
+		isRecheck         bool
+		expectLocalOnly   bool
+		expectPerspective string
+	}{
+		{
+			name:              "flag off, recheck",
+			isRecheck:         true,
+			expectPerspective: allPerspectives,
+		},
+		{
+			name:              "flag on, initial check",
+			localOnly:         true,
+			expectPerspective: allPerspectives,
+		},
+		{
+			name:              "flag on, recheck",
+			localOnly:         true,
+			isRecheck:         true,
+			expectLocalOnly:   true,
+			expectPerspective: recheckLocalPerspective,
+		},
+	}
+	for _, tc := range testCases {
+		for _, testFunc := range []struct {
+			name string
+			impl caaCheckFuncRunner
+		}{
+			{"IsCAAValid", runIsCAAValid},
+			{"DoCAA", runDoCAA},
+		} {
+			t.Run(tc.name+"_"+testFunc.name, func(t *testing.T) {
+				va, mockLog := setupWithRemotes(nil, "local", remoteVAs, caaMockDNS{})
+				va.recheckCAALocalOnly = tc.localOnly
+				features.Set(features.Config{EnforceMultiCAA: true})
+				defer features.Reset()
+
+				res, err := testFunc.impl(context.Background(), va, &vapb.IsCAAValidRequest{
+					Domain:           "present.com",
+					ValidationMethod: string(core.ChallengeTypeDNS01),
+					AccountURIID:     1,
+					IsRecheck:        tc.isRecheck,
+				})
+				test.AssertNotError(t, err, "CAA check failed")
+
+				skipped := mockLog.GetAllMatching("recheck: remote corroboration skipped by policy")
+				result := pass
+				probType := ""
+				if tc.expectLocalOnly {
+					test.Assert(t, res.Problem == nil, "local-only recheck should have passed")
+					test.AssertEquals(t, len(skipped), 1)
+					test.AssertMetricWithLabelsEquals(t, va.metrics.mpicSkipped, prometheus.Labels{"reason": mpicSkippedRecheckLocalOnly}, 1)
+				} else {
+					test.AssertNotNil(t, res.Problem, "remote corroboration should have failed the check")
+					test.AssertEquals(t, res.Problem.ProblemType, string(probs.CAAProblem))
+					test.AssertContains(t, res.Problem.Detail, "During secondary validation")
+					test.AssertEquals(t, len(skipped), 0)
+					test.AssertMetricWithLabelsEquals(t, va.metrics.mpicSkipped, prometheus.Labels{"reason": mpicSkippedRecheckLocalOnly}, 0)
+					result = fail
+					probType = string(probs.CAAProblem)
+				}
+				test.AssertMetricWithLabelsEquals(t, va.metrics.validationLatency, prometheus.Labels{
+					"operation":      opCAA,
+					"perspective":    tc.expectPerspective,
+					"challenge_type": string(core.ChallengeTypeDNS01),
+					"problem_type":   probType,
+					"result":         result,
+				}, 1)
+
+				if testFunc.name == "DoCAA" {
+					gotAuditLog := parseValidationLogEvent(t, mockLog.GetAllMatching(`CAA check result JSON=.*`))
+					if tc.expectLocalOnly {
+						test.AssertEquals(t, gotAuditLog.Summary.SkippedReason, mpicSkippedRecheckLocalOnly)
+					} else {
+						test.AssertEquals(t, gotAuditLog.Summary.SkippedReason, "")
+						test.AssertEquals(t, gotAuditLog.Summary.QuorumResult, "0/3")
+					}
+				}
+			})
+		}
+	}
+}
+
 func TestCAAFailure(t *testing.T) {
 	hs := httpSrv(t, expectedToken)
 	defer hs.Close()
@@ -1246,13 +1672,78 @@ func TestCAAFailure(t *testing.T) {
 	test.AssertContains(t, prob.Error(), "NXDOMAIN")
 }
 
+func TestCAAUnknownTags(t *testing.T) {
+	va, mockLog := setup(nil, "", nil, caaMockDNS{})
+
+	// An unknown critical tag forbids issuance and is named in the problem.
+	err := va.checkCAA(ctx, dnsi("unknown-critical-with-issue.com"), &caaParams{1, core.ChallengeTypeHTTP01})
+	test.AssertErrorIs(t, err, berrors.CAA)
+	prob := detailedError(err)
+	test.AssertEquals(t, prob.Type, probs.CAAProblem)
+	test.AssertEquals(t, prob.Detail, `CAA record for unknown-critical-with-issue.com prevents issuance: unrecognized critical property tag "tbs"`)
+
+	// An unknown non-critical tag is ignored, but logged.
+	mockLog.Clear()
+	err = va.checkCAA(ctx, dnsi("unknown-noncritical-with-issue.com"), &caaParams{1, core.ChallengeTypeHTTP01})
+	test.AssertNotError(t, err, "unknown non-critical tag should not forbid issuance")
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`Ignoring unrecognized non-critical CAA property tags \["tbs"\] for unknown-noncritical-with-issue.com`)), 1)
+}
+
+func TestCAAValidationMethodsMode(t *testing.T) {
+	testCases := []struct {
+		domain string
+		// mismatch is the permitted_methods label expected when a
+		// validationmethods mismatch alone refuses issuance, or empty if
+		// validationmethods plays no part in the outcome.
+		mismatch string
+		// valid is whether issuance is permitted in enforce mode.
+		valid bool
+	}{
+		{domain: "present-dns-only.com", mismatch: "dns-01"},
+		{domain: "present-dns-only-correct-accounturi.com", mismatch: "dns-01"},
+		{domain: "present-http-only.com", valid: true},
+		{domain: "present-http-or-dns.com", valid: true},
+		{domain: "present-http-only-correct-accounturi.com", valid: true},
+		{domain: "present-http-only-incorrect-accounturi.com"},
+		{domain: "reserved.com"},
+	}
+
+	for _, mode := range []CAAValidationMethodsMode{CAAValidationMethodsEnforce, CAAValidationMethodsLogOnly} {
+		for _, tc := range testCases {
+			t.Run(fmt.Sprintf("%s/%s", mode, tc.domain), func(t *testing.T) {
+				va, mockLog := setup(nil, "", nil, caaMockDNS{})
+				va.accountURIPrefixes = []string{"https://letsencrypt.org/acct/reg/"}
+				va.caaValidationMethodsMode = mode
+
+				err := va.checkCAA(ctx, dnsi(tc.domain), &caaParams{123, core.ChallengeTypeHTTP01})
+				if tc.valid || (tc.mismatch != "" && mode == CAAValidationMethodsLogOnly) {
+					test.AssertNotError(t, err, "expected CAA to permit issuance")
+				} else {
+					test.AssertErrorIs(t, err, berrors.CAA)
+				}
+
+				var expected float64
+				if tc.mismatch != "" && mode == CAAValidationMethodsLogOnly {
+					expected = 1
+				}
+				test.AssertMetricWithLabelsEquals(t, va.metrics.caaValidationMethodMismatches, prometheus.Labels{
+					"requested_method":  string(core.ChallengeTypeHTTP01),
+					"permitted_methods": tc.mismatch,
+				}, expected)
+				test.AssertEquals(t, len(mockLog.GetAllMatching(`Ignoring CAA validationmethods mismatch`)), int(expected))
+			})
+		}
+	}
+}
+
 func TestFilterCAA(t *testing.T) {
 	testCases := []struct {
 		name              string
 		input             []*dns.CAA
 		expectedIssueVals []string
 		expectedWildVals  []string
-		expectedCU        bool
+		expectedCU        string
+		expectedIgnored   []string
 	}{
 		{
 			name: "recognized non-critical",
@@ -1281,33 +1772,46 @@ func TestFilterCAA(t *testing.T) {
 			input: []*dns.CAA{
 				{Tag: "unknown", Flag: 2},
 			},
+			expectedIgnored: []string{"unknown"},
 		},
 		{
 			name: "unrecognized critical",
 			input: []*dns.CAA{
 				{Tag: "unknown", Flag: 128},
 			},
-			expectedCU: true,
+			expectedCU: "unknown",
 		},
 		{
 			name: "unrecognized improper critical",
 			input: []*dns.CAA{
 				{Tag: "unknown", Flag: 1},
 			},
-			expectedCU: true,
+			expectedCU: "unknown",
 		},
 		{
 			name: "unrecognized very improper critical",
 			input: []*dns.CAA{
 				{Tag: "unknown", Flag: 9},
 			},
-			expectedCU: true,
+			expectedCU: "unknown",
+		},
+		{
+			name: "unrecognized critical after issue",
+			input: []*dns.CAA{
+				{Tag: "issue", Value: "a"},
+				{Tag: "other", Flag: 2},
+				{Tag: "first", Flag: 128},
+				{Tag: "second", Flag: 128},
+			},
+			expectedIssueVals: []string{"a"},
+			expectedCU:        "first",
+			expectedIgnored:   []string{"other"},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			issue, wild, cu := filterCAA(tc.input)
+			issue, wild, cu, ignored := filterCAA(tc.input)
 			for _, tag := range issue {
 				test.AssertSliceContains(t, tc.expectedIssueVals, tag.Value)
 			}
@@ -1315,6 +1819,7 @@ func TestFilterCAA(t *testing.T) {
 				test.AssertSliceContains(t, tc.expectedWildVals, tag.Value)
 			}
 			test.AssertEquals(t, tc.expectedCU, cu)
+			test.AssertDeepEquals(t, ignored, tc.expectedIgnored)
 		})
 	}
 }
@@ -1330,9 +1835,9 @@ func TestSelectCAA(t *testing.T) {
 
 	// A slice of empty caaResults should return nil, "", nil
 	r = []caaResult{
-		{"", false, nil, nil, false, "", nil, nil},
-		{"", false, nil, nil, false, "", nil, nil},
-		{"", false, nil, nil, false, "", nil, nil},
+		{"", false, nil, nil, nil, "", nil, "", nil, time.Time{}, "", nil},
+		{"", false, nil, nil, nil, "", nil, "", nil, time.Time{}, "", nil},
+		{"", false, nil, nil, nil, "", nil, "", nil, time.Time{}, "", nil},
 	}
 	s, err = selectCAA(r)
 	test.Assert(t, s == nil, "set is not nil")
@@ -1341,8 +1846,8 @@ func TestSelectCAA(t *testing.T) {
 	// A slice of caaResults containing an error followed by a CAA
 	// record should return the error
 	r = []caaResult{
-		{"foo.com", false, nil, nil, false, "", nil, errors.New("oops")},
-		{"com", true, []*dns.CAA{&expected}, nil, false, "foo", nil, nil},
+		{"foo.com", false, nil, nil, nil, "", nil, "", nil, time.Time{}, "", errors.New("oops")},
+		{"com", true, []*dns.CAA{&expected}, nil, nil, "", nil, "foo", nil, time.Time{}, "", nil},
 	}
 	s, err = selectCAA(r)
 	test.Assert(t, s == nil, "set is not nil")
@@ -1352,8 +1857,8 @@ func TestSelectCAA(t *testing.T) {
 	//  A slice of caaResults containing a good record that precedes an
 	//  error, should return that good record, not the error
 	r = []caaResult{
-		{"foo.com", true, []*dns.CAA{&expected}, nil, false, "foo", nil, nil},
-		{"com", false, nil, nil, false, "", nil, errors.New("")},
+		{"foo.com", true, []*dns.CAA{&expected}, nil, nil, "", nil, "foo", nil, time.Time{}, "", nil},
+		{"com", false, nil, nil, nil, "", nil, "", nil, time.Time{}, "", errors.New("")},
 	}
 	s, err = selectCAA(r)
 	test.AssertEquals(t, len(s.issue), 1)
@@ -1364,9 +1869,9 @@ func TestSelectCAA(t *testing.T) {
 	// A slice of caaResults containing multiple CAA records should
 	// return the first non-empty CAA record
 	r = []caaResult{
-		{"bar.foo.com", false, []*dns.CAA{}, []*dns.CAA{}, false, "", nil, nil},
-		{"foo.com", true, []*dns.CAA{&expected}, nil, false, "foo", nil, nil},
-		{"com", true, []*dns.CAA{&expected}, nil, false, "bar", nil, nil},
+		{"bar.foo.com", false, []*dns.CAA{}, []*dns.CAA{}, nil, "", nil, "", nil, time.Time{}, "", nil},
+		{"foo.com", true, []*dns.CAA{&expected}, nil, nil, "", nil, "foo", nil, time.Time{}, "", nil},
+		{"com", true, []*dns.CAA{&expected}, nil, nil, "", nil, "bar", nil, time.Time{}, "", nil},
 	}
 	s, err = selectCAA(r)
 	test.AssertEquals(t, len(s.issue), 1)
@@ -0,0 +1,315 @@
+package va
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/letsencrypt/boulder/core"
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	berrors "github.com/letsencrypt/boulder/errors"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/identifier"
+	"github.com/letsencrypt/boulder/probs"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+// caaBatchCheck is the CAA check of a single identifier of a CheckCAAMulti
+// request.
+type caaBatchCheck struct {
+	req       *vapb.IsCAAValidRequest
+	challType core.AcmeChallenge
+
+	// ctx carries the policy selected by the check's policy hints.
+	ctx          context.Context
+	done         func()
+	logEvent     validationLogEvent
+	prob         *probs.ProblemDetails
+	summary      *mpicSummary
+	localLatency time.Duration
+}
+
+// CheckCAAMulti conducts a CAA check for each identifier of the batch, as
+// DoCAA would for each of them. Up to va.caaBatchParallelism identifiers are
+// checked at once. When invoked on the primary VA, it also sends the whole
+// batch to each remote VA in a single request, and decides whether the remote
+// results corroborate its own separately for each identifier. The results are
+// in the same order as the checks of the request. Every check of a batch
+// shares its attempt ID, so the checks of an identifier are matched across
+// perspectives by the attempt ID together with the identifier.
+func (va *ValidationAuthorityImpl) CheckCAAMulti(ctx context.Context, req *vapb.CheckCAAMultiRequest) (*vapb.CheckCAAMultiResponse, error) {
+	if len(req.GetChecks()) == 0 {
+		return nil, berrors.InternalServerError("incomplete CheckCAAMulti request")
+	}
+	if len(req.Checks) > va.maxCAABatchSize {
+		return nil, berrors.InternalServerError("CheckCAAMulti request of %d identifiers exceeds the maximum of %d",
+			len(req.Checks), va.maxCAABatchSize)
+	}
+	checks := make([]*caaBatchCheck, len(req.Checks))
+	for i, check := range req.Checks {
+		if core.IsAnyNilOrZero(check, check.Domain, check.ValidationMethod, check.AccountURIID) {
+			return nil, berrors.InternalServerError("incomplete IsCAAValid request")
+		}
+		challType := core.AcmeChallenge(check.ValidationMethod)
+		if !challType.IsValid() {
+			return nil, berrors.InternalServerError("unrecognized validation method %q", check.ValidationMethod)
+		}
+		checks[i] = &caaBatchCheck{req: check, challType: challType}
+	}
+
+	release, err := va.admission.admit(ctx, opCAA)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, span := va.tracer.Start(ctx, "VA.CheckCAAMulti", trace.WithAttributes(
+		attribute.Int("identifiers", len(checks)),
+		attribute.String("perspective", va.perspective),
+	))
+	defer span.End()
+	if bgrpc.ValidationAttemptID(ctx) == "" {
+		ctx = bgrpc.WithValidationAttemptID(ctx, uuid.NewString())
+	}
+
+	start := va.clk.Now()
+	for _, check := range checks {
+		check.logEvent = validationLogEvent{
+			AuthzID:    check.req.AuthzID,
+			Requester:  check.req.AccountURIID,
+			Identifier: check.req.Domain,
+		}
+		var checkCtx context.Context
+		checkCtx, check.logEvent.AttemptID, check.done = va.startAttempt(ctx, opCAA, check.req.Domain, check.challType, check.req.AccountURIID)
+		check.ctx, check.logEvent.PolicyHints = va.applyPolicyHints(checkCtx, check.req.PolicyHints)
+	}
+
+	va.checkCAABatch(checks)
+	if va.isPrimaryVA() {
+		va.corroborateCAABatch(ctx, checks)
+	}
+
+	resp := &vapb.CheckCAAMultiResponse{
+		Perspective: va.perspective,
+		Rir:         va.rir,
+	}
+	for _, check := range checks {
+		resp.Results = append(resp.Results, va.finishCAABatchCheck(check, start))
+	}
+	return resp, nil
+}
+
+// checkCAABatch conducts the local CAA check of each of checks, with up to
+// va.caaBatchParallelism workers.
+func (va *ValidationAuthorityImpl) checkCAABatch(checks []*caaBatchCheck) {
+	work := make(chan *caaBatchCheck)
+	var wg sync.WaitGroup
+	for range min(va.caaBatchParallelism, len(checks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for check := range work {
+				va.checkCAABatchItem(check)
+			}
+		}()
+	}
+	for _, check := range checks {
+		work <- check
+	}
+	close(work)
+	wg.Wait()
+}
+
+// checkCAABatchItem conducts the local CAA check of a single identifier of a
+// batch.
+func (va *ValidationAuthorityImpl) checkCAABatchItem(check *caaBatchCheck) {
+	start := va.clk.Now()
+	caaCtx, caaSpan := va.tracer.Start(check.ctx, "caa check", trace.WithAttributes(
+		attribute.String("identifier", check.req.Domain),
+		attribute.String("challenge_type", string(check.challType)),
+	))
+	err := va.checkCAA(caaCtx, identifier.NewDNS(check.req.Domain), &caaParams{
+		accountURIID:     check.req.AccountURIID,
+		validationMethod: check.challType,
+	})
+	spanError(caaSpan, err)
+	caaSpan.End()
+
+	// Stop the clock for local check latency.
+	check.localLatency = va.clk.Since(start)
+
+	if err != nil {
+		check.logEvent.InternalError = err.Error()
+		check.logEvent.DNSResolver = dnsResolver(err)
+		va.noteResourceExhaustion(opCAA, check.req.Domain, err)
+		check.prob = detailedError(err)
+		check.prob.Detail = fmt.Sprintf("While processing CAA for %s: %s", check.req.Domain, check.prob.Detail)
+	}
+}
+
+// corroborateCAABatch sends the checks which aren't of internal identifiers to
+// each remote VA in a single CheckCAAMulti request, and fails each check whose
+// remote results don't corroborate the primary's. As in doRemoteOperation,
+// remaining requests are canceled once the outcome of every check is settled.
+func (va *ValidationAuthorityImpl) corroborateCAABatch(ctx context.Context, checks []*caaBatchCheck) {
+	var remoteChecks []*caaBatchCheck
+	batch := &vapb.CheckCAAMultiRequest{}
+	for _, check := range checks {
+		check.logEvent.InternalIdentifierRule = va.skipRemoteCorroboration(check.ctx, opCAA, check.req.Domain, nil)
+		if check.logEvent.InternalIdentifierRule != "" {
+			check.summary = va.skippedMPIC(mpicSkippedInternalIdentifier)
+			continue
+		}
+		remoteChecks = append(remoteChecks, check)
+		batch.Checks = append(batch.Checks, check.req)
+	}
+	if len(remoteChecks) == 0 {
+		return
+	}
+
+	remoteVAs, maxRemoteFailures, prob := va.selectRemoteVAs()
+	if prob != nil {
+		for _, check := range remoteChecks {
+			check.prob = probs.ServerInternal(prob.Detail)
+			check.summary = va.skippedMPIC(mpicSkippedNoRemotes)
+		}
+		return
+	}
+	remoteVACount := len(remoteVAs)
+
+	type response struct {
+		rva     RemoteVA
+		result  *vapb.CheckCAAMultiResponse
+		err     error
+		latency time.Duration
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make(chan *response, remoteVACount)
+	for _, i := range rand.Perm(remoteVACount) {
+		go func(rva RemoteVA) {
+			opCtx, span := va.tracer.Start(subCtx, "remote perspective", trace.WithAttributes(
+				attribute.String("perspective", rva.Perspective),
+				attribute.String("rir", rva.RIR),
+			))
+			start := va.clk.Now()
+			res, err := rva.CheckCAAMulti(opCtx, batch)
+			if err == nil {
+				err = checkRemotePerspective(rva, res.GetPerspective(), res.GetRir())
+			}
+			if err == nil && len(res.GetResults()) != len(batch.Checks) {
+				err = fmt.Errorf("expected %d results but got %d", len(batch.Checks), len(res.GetResults()))
+			}
+			if va.selector != nil && !core.IsCanceled(err) {
+				va.selector.record(rva.Perspective, err == nil, va.clk.Since(start))
+			}
+			if err != nil {
+				spanError(span, err)
+			}
+			// End the span before responding so that it's complete by the time
+			// corroborateCAABatch returns.
+			span.End()
+			responses <- &response{rva, res, err, va.clk.Since(start)}
+		}(remoteVAs[i])
+	}
+
+	tallies := make([]*corroboration, len(remoteChecks))
+	for i := range tallies {
+		tallies[i] = newCorroboration(remoteVACount, maxRemoteFailures, va.minDistinctASNs)
+	}
+	for resp := range responses {
+		// A failed request fails every check of the batch at that
+		// perspective, so it's only logged once.
+		var rpcProb *probs.ProblemDetails
+		if resp.err != nil {
+			rpcProb = va.remoteProblem(resp.rva.describe(), nil, resp.err)
+		}
+
+		settled := true
+		for i, tally := range tallies {
+			var prob *probs.ProblemDetails
+			if rpcProb != nil {
+				// Each tally may modify its problem, so they can't share one.
+				prob = probs.ServerInternal(rpcProb.Detail)
+			} else {
+				prob = va.remoteProblem(resp.rva.describe(), resp.result.Results[i].GetProblem(), nil)
+			}
+			tally.add(resp.rva, prob, resp.latency, "")
+			settled = settled && tally.settled()
+		}
+		if settled {
+			cancel()
+		}
+
+		// Once all the VAs have returned a result, break the loop.
+		if tallies[0].complete() {
+			break
+		}
+	}
+
+	for i, check := range remoteChecks {
+		var remoteProb *probs.ProblemDetails
+		check.summary, remoteProb = tallies[i].outcome()
+		// If the remote result was a non-nil problem then fail the CAA check
+		if remoteProb != nil {
+			check.prob = remoteProb
+			va.log.Infof("CAA check failed due to remote failures: identifier=%v err=%s",
+				check.req.Domain, remoteProb)
+		}
+	}
+}
+
+// finishCAABatchCheck records the latency of a check of a batch which began at
+// start, logs its result, and returns its response.
+func (va *ValidationAuthorityImpl) finishCAABatchCheck(check *caaBatchCheck, start time.Time) *vapb.IsCAAValidResponse {
+	defer check.done()
+	probType := ""
+	outcome := fail
+	if check.prob != nil {
+		// CAA check failed.
+		check.prob = filterProblemDetails(check.prob)
+		probType = string(check.prob.Type)
+		check.logEvent.Error = check.prob.Error()
+		check.logEvent.DNSDetails = check.prob.DNSDetails
+	} else {
+		// CAA check passed.
+		outcome = pass
+	}
+	// Observe local check latency (primary|remote).
+	va.observeLatency(opCAA, va.perspective, string(check.challType), probType, outcome, check.localLatency)
+	if va.isPrimaryVA() {
+		// Observe total check latency (primary+remote|internal).
+		va.observeLatency(opCAA, totalPerspective(check.logEvent.InternalIdentifierRule), string(check.challType), probType, outcome, va.clk.Since(start))
+		check.logEvent.Summary = check.summary
+	}
+	// Log the total check latency.
+	check.logEvent.Latency = va.clk.Since(start).Round(time.Millisecond).Seconds()
+	va.log.AuditObject("CAA check result", check.logEvent)
+
+	return va.caaResponse(check.prob)
+}
+
+// caaResponse returns the response to a CAA check which failed with prob, or
+// passed if prob is nil. The problem must already have been filtered with
+// filterProblemDetails.
+func (va *ValidationAuthorityImpl) caaResponse(prob *probs.ProblemDetails) *vapb.IsCAAValidResponse {
+	resp := &vapb.IsCAAValidResponse{
+		Perspective: va.perspective,
+		Rir:         va.rir,
+	}
+	if prob != nil {
+		resp.Problem = &corepb.ProblemDetails{
+			ProblemType: string(prob.Type),
+			Detail:      replaceInvalidUTF8([]byte(prob.Detail)),
+		}
+	}
+	return resp
+}
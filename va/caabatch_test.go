@@ -0,0 +1,115 @@
+package va
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/test"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+// countingCAA wraps a CAAClient, counting the CheckCAAMulti requests it
+// forwards.
+type countingCAA struct {
+	vapb.CAAClient
+	calls *atomic.Int64
+}
+
+func (c countingCAA) CheckCAAMulti(ctx context.Context, req *vapb.CheckCAAMultiRequest, opts ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	c.calls.Add(1)
+	return c.CAAClient.CheckCAAMulti(ctx, req, opts...)
+}
+
+func TestCheckCAAMulti(t *testing.T) {
+	t.Parallel()
+
+	// Two of the four remotes are hijacked, which is more failures than are
+	// allowed, so names whose CAA records are altered by the hijack fail and
+	// every other name passes.
+	va, mockLog := setupWithRemotes(nil, "", []remoteConf{
+		{rir: arin, dns: caaHijackedDNS{}},
+		{rir: ripe, dns: caaHijackedDNS{}},
+		{rir: apnic},
+		{rir: lacnic},
+	}, caaMockDNS{})
+	var calls atomic.Int64
+	for i, rva := range va.remoteVAs {
+		va.remoteVAs[i].CAAClient = countingCAA{rva.CAAClient, &calls}
+	}
+
+	hijacked := []string{"present.com", "present.servfail.com", "unknown-critical-remote.com"}
+	req := &vapb.CheckCAAMultiRequest{}
+	expectFail := map[int]bool{}
+	for i := range 20 {
+		domain := fmt.Sprintf("absent-%d.com", i)
+		if i%4 == 0 {
+			domain = hijacked[(i/4)%len(hijacked)]
+			expectFail[i] = true
+		}
+		req.Checks = append(req.Checks, &vapb.IsCAAValidRequest{
+			Domain:           domain,
+			ValidationMethod: string(core.ChallengeTypeDNS01),
+			AccountURIID:     1,
+		})
+	}
+
+	resp, err := va.CheckCAAMulti(context.Background(), req)
+	test.AssertNotError(t, err, "CheckCAAMulti failed")
+	test.AssertEquals(t, resp.Perspective, PrimaryPerspective)
+	test.AssertEquals(t, len(resp.Results), len(req.Checks))
+	for i, result := range resp.Results {
+		if expectFail[i] {
+			test.AssertNotNil(t, result.Problem, fmt.Sprintf("expected %s to fail", req.Checks[i].Domain))
+			test.AssertContains(t, result.Problem.Detail, "During secondary validation")
+		} else {
+			test.AssertBoxedNil(t, result.Problem, fmt.Sprintf("expected %s to pass", req.Checks[i].Domain))
+		}
+	}
+
+	// Each perspective was consulted once for the whole batch.
+	test.AssertEquals(t, calls.Load(), int64(len(va.remoteVAs)))
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`CAA check result JSON=.*`)), len(req.Checks))
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`CAA check failed due to remote failures:`)), len(expectFail))
+}
+
+func TestCheckCAAMultiLocalFailure(t *testing.T) {
+	t.Parallel()
+
+	va, _ := setupWithRemotes(nil, "", []remoteConf{
+		{rir: arin},
+		{rir: ripe},
+		{rir: apnic},
+	}, caaMockDNS{})
+
+	resp, err := va.CheckCAAMulti(context.Background(), &vapb.CheckCAAMultiRequest{
+		Checks: []*vapb.IsCAAValidRequest{
+			{Domain: "reserved.com", ValidationMethod: string(core.ChallengeTypeDNS01), AccountURIID: 1},
+			{Domain: "absent.com", ValidationMethod: string(core.ChallengeTypeDNS01), AccountURIID: 1},
+		},
+	})
+	test.AssertNotError(t, err, "CheckCAAMulti failed")
+	test.AssertNotNil(t, resp.Results[0].Problem, "expected reserved.com to fail")
+	test.AssertContains(t, resp.Results[0].Problem.Detail, "While processing CAA for reserved.com")
+	test.AssertBoxedNil(t, resp.Results[1].Problem, "expected absent.com to pass")
+}
+
+func TestCheckCAAMultiInvalid(t *testing.T) {
+	t.Parallel()
+
+	va, _ := setup(nil, "", nil, caaMockDNS{})
+	va.maxCAABatchSize = 2
+
+	check := &vapb.IsCAAValidRequest{Domain: "absent.com", ValidationMethod: string(core.ChallengeTypeDNS01), AccountURIID: 1}
+	_, err := va.CheckCAAMulti(context.Background(), &vapb.CheckCAAMultiRequest{})
+	test.AssertError(t, err, "empty batch should be refused")
+	_, err = va.CheckCAAMulti(context.Background(), &vapb.CheckCAAMultiRequest{Checks: []*vapb.IsCAAValidRequest{check, check, check}})
+	test.AssertError(t, err, "oversized batch should be refused")
+	test.AssertContains(t, err.Error(), "exceeds the maximum of 2")
+	_, err = va.CheckCAAMulti(context.Background(), &vapb.CheckCAAMultiRequest{Checks: []*vapb.IsCAAValidRequest{check, {Domain: "absent.com"}}})
+	test.AssertError(t, err, "incomplete check should be refused")
+}
@@ -2,6 +2,8 @@ package vacfg
 
 import (
 	"fmt"
+	"net/netip"
+	"time"
 
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/config"
@@ -26,7 +28,124 @@ type Common struct {
 	DNSTimeout                config.Duration `validate:"required"`
 	DNSAllowLoopbackAddresses bool
 
+	// DNSHostLookupTimeout bounds each of the A and AAAA lookups made when
+	// resolving a hostname, including any retries. The two lookups are made in
+	// parallel, so a slow authoritative server for one address family fails
+	// only that lookup. Defaults to DNSTimeout multiplied by DNSTries.
+	DNSHostLookupTimeout config.Duration `validate:"-"`
+
 	AccountURIPrefixes []string `validate:"min=1,dive,required,url"`
+
+	// DevMode permits settings which are only appropriate in development and
+	// testing environments, such as http AccountURIPrefixes. It must never be
+	// set in production.
+	DevMode bool
+
+	// MaxHTTPRetryAfter is the longest Retry-After which will be honored when
+	// an HTTP-01 challenge request receives a 429 or 503 response. Such a
+	// request is retried exactly once. Longer or absent Retry-After values fail
+	// the validation immediately. Defaults to 2s.
+	MaxHTTPRetryAfter config.Duration `validate:"-"`
+
+	// CAAValidationMethodsMode determines how the RFC 8657 validationmethods
+	// CAA parameter is applied. In "enforce" mode, the default, issuance is
+	// refused unless a record permits the validation method in use. In
+	// "log-only" mode such refusals are audit logged and counted, but issuance
+	// proceeds.
+	CAAValidationMethodsMode string `validate:"omitempty,oneof=enforce log-only"`
+
+	// HTTPHeaders are additional request headers sent with every request made
+	// for an HTTP-01 validation, including those following redirects. For
+	// example, a gateway in front of subscriber origins may require a header
+	// to skip bot mitigation. Headers which carry credentials, control the
+	// connection, or are already set by the VA, such as Host, Authorization
+	// and Cookie, are rejected at startup.
+	HTTPHeaders map[string]string `validate:"max=8"`
+
+	// SLOThreshold is the latency above which a successful validation is
+	// logged as a warning, attributed to its slowest phase, and counted in the
+	// validation_slo_breaches metric. Defaults to 10s.
+	SLOThreshold config.Duration `validate:"-"`
+
+	// HTTP01ConfirmOverTLSDomains are domains for which, when the
+	// HTTP01ConfirmOverTLS feature is enabled, a successful HTTP-01 validation
+	// must be confirmed by fetching the same response over HTTPS. Each entry
+	// covers the domain itself and all of its subdomains.
+	HTTP01ConfirmOverTLSDomains []string `validate:"omitempty,dive,fqdn"`
+
+	// ValidationDedupWindow is how long the result of a validation is shared
+	// with identical requests for the same authorization, account, identifier,
+	// challenge type and token, so that clients which retry rapidly don't each
+	// trigger a new validation. Identical requests made while a validation is
+	// in flight always wait for its result. Defaults to 2s.
+	ValidationDedupWindow config.Duration `validate:"-"`
+
+	// MaxConcurrentValidations is the number of validations and CAA checks
+	// the VA performs at once. Requests which arrive while the VA is at
+	// capacity wait up to MaxValidationQueueWait for a slot, and are then
+	// refused with a gRPC ResourceExhausted error and a retry pushback hint.
+	// If zero, the default, the number is unbounded.
+	MaxConcurrentValidations int `validate:"min=0"`
+
+	// MaxValidationQueueWait is how long a request waits for a slot when the
+	// VA is at capacity. If zero, such requests are refused immediately.
+	MaxValidationQueueWait config.Duration `validate:"-"`
+
+	// ProxyProtocolSourceAddress, if set, is the public address from which
+	// this VA's HTTP-01 and TLS-ALPN-01 connections reach challenge targets,
+	// through an egress proxy which requires the PROXY protocol. Each such
+	// connection begins with a PROXY protocol v2 header naming this address as
+	// its source, so that the target sees it rather than the proxy's address.
+	// It must not be set for a VA which connects to challenge targets
+	// directly, as the header would corrupt the request.
+	ProxyProtocolSourceAddress string `validate:"omitempty,ip"`
+
+	// MinTXTTTL is the TTL below which the TXT records of a DNS-01 or
+	// DNS-ACCOUNT-01 validation are audit logged and counted, and noted in
+	// the problem if they don't match, because each perspective may see a
+	// different value while they propagate. Such records are not refused.
+	// Defaults to 5s.
+	MinTXTTTL config.Duration `validate:"-"`
+
+	// MaxCAABatchSize is the most identifiers a single CheckCAAMulti request
+	// may carry. Larger requests are refused. Defaults to 100.
+	MaxCAABatchSize int `validate:"min=0"`
+
+	// CAABatchParallelism is the number of identifiers of a CheckCAAMulti
+	// request whose CAA is checked at once. Defaults to 10.
+	CAABatchParallelism int `validate:"min=0"`
+
+	// MaxValidationBytes is the most bytes a single HTTP-01 or TLS-ALPN-01
+	// validation may receive, counting the headers and bodies of every
+	// request and redirect, and TLS handshakes. Validations which receive
+	// more fail with a connection problem. Defaults to 1 MiB.
+	MaxValidationBytes int64 `validate:"min=0"`
+
+	// MaxConnsPerTargetIP is the number of connections the VA holds open to
+	// any one target IP address at once, across every HTTP-01 and TLS-ALPN-01
+	// validation in flight. Connections which would exceed it wait up to
+	// MaxTargetIPConnWait for another to close, and then fail the validation
+	// with a serverInternal problem, so the Subscriber isn't blamed. Defaults
+	// to 10.
+	MaxConnsPerTargetIP int `validate:"min=0"`
+
+	// MaxTargetIPConnWait is how long a connection waits for a slot when the
+	// VA is at its limit of connections to the target IP address. Defaults to
+	// 5s.
+	MaxTargetIPConnWait config.Duration `validate:"-"`
+}
+
+// ProxyProtocolSource returns ProxyProtocolSourceAddress, or the zero
+// netip.Addr if it isn't set.
+func (c *Common) ProxyProtocolSource() (netip.Addr, error) {
+	if c.ProxyProtocolSourceAddress == "" {
+		return netip.Addr{}, nil
+	}
+	addr, err := netip.ParseAddr(c.ProxyProtocolSourceAddress)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid 'proxyProtocolSourceAddress': %w", err)
+	}
+	return addr, nil
 }
 
 // SetDefaultsAndValidate performs some basic sanity checks on fields stored in
@@ -48,5 +167,41 @@ func (c *Common) SetDefaultsAndValidate(grpcAddr, debugAddr *string) error {
 		c.DNSTries = 1
 	}
 
+	if c.MaxHTTPRetryAfter.Duration <= 0 {
+		c.MaxHTTPRetryAfter.Duration = 2 * time.Second
+	}
+
+	if c.SLOThreshold.Duration <= 0 {
+		c.SLOThreshold.Duration = 10 * time.Second
+	}
+
+	if c.ValidationDedupWindow.Duration <= 0 {
+		c.ValidationDedupWindow.Duration = 2 * time.Second
+	}
+
+	if c.MinTXTTTL.Duration <= 0 {
+		c.MinTXTTTL.Duration = 5 * time.Second
+	}
+
+	if c.MaxCAABatchSize <= 0 {
+		c.MaxCAABatchSize = 100
+	}
+
+	if c.CAABatchParallelism <= 0 {
+		c.CAABatchParallelism = 10
+	}
+
+	if c.MaxValidationBytes <= 0 {
+		c.MaxValidationBytes = 1 << 20
+	}
+
+	if c.MaxConnsPerTargetIP <= 0 {
+		c.MaxConnsPerTargetIP = 10
+	}
+
+	if c.MaxTargetIPConnWait.Duration <= 0 {
+		c.MaxTargetIPConnWait.Duration = 5 * time.Second
+	}
+
 	return nil
 }
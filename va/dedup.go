@@ -0,0 +1,157 @@
+package va
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/probs"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+// dedupKey identifies validation requests which are identical, and whose
+// results can therefore be shared. The authorization ID is included so that a
+// result is never shared beyond the authorization it was reached for.
+type dedupKey struct {
+	op        string
+	authzID   string
+	regID     int64
+	ident     string
+	challType string
+	token     string
+	hints     string
+	// disallowed is true if the request's allowed challenge types exclude
+	// its challenge type, so that such a request never shares a result.
+	disallowed bool
+}
+
+// dedupCall is a validation which is in flight, or which completed within the
+// dedup window.
+type dedupCall struct {
+	// done is closed once result and err are set.
+	done   chan struct{}
+	result *vapb.ValidationResult
+	err    error
+	// expires is when a completed result stops being shared. It is zero while
+	// the validation is in flight.
+	expires time.Time
+}
+
+// validationDeduper coalesces identical validation requests, so that clients
+// which re-POST the same challenge many times per second trigger only one
+// validation, and one remote fan-out.
+type validationDeduper struct {
+	sync.Mutex
+	// window is how long the result of a completed validation is shared. If
+	// zero, only requests made while a validation is in flight share its
+	// result.
+	window time.Duration
+	calls  map[dedupKey]*dedupCall
+}
+
+func newValidationDeduper(window time.Duration) *validationDeduper {
+	return &validationDeduper{window: window, calls: make(map[dedupKey]*dedupCall)}
+}
+
+// shareable returns true if a validation result may be shared with identical
+// requests. Internal errors, including cancellation of the request which
+// performed the validation, are never shared.
+func shareable(result *vapb.ValidationResult, err error) bool {
+	if err != nil || result == nil {
+		return false
+	}
+	return result.Problem == nil || result.Problem.ProblemType != string(probs.ServerInternalProblem)
+}
+
+// dedupValidation calls validate with req, unless an identical validation is
+// already in flight, in which case it waits for and returns that validation's
+// result, or completed within the dedup window, in which case it returns that
+// validation's result immediately. If the result of the identical validation
+// can't be shared, validate is called afresh.
+func (va *ValidationAuthorityImpl) dedupValidation(
+	ctx context.Context,
+	op string,
+	req *vapb.PerformValidationRequest,
+	validate func(context.Context, *vapb.PerformValidationRequest) (*vapb.ValidationResult, error),
+) (*vapb.ValidationResult, error) {
+	key := dedupKey{
+		op:         op,
+		authzID:    req.Authz.Id,
+		regID:      req.Authz.RegID,
+		ident:      req.DnsName,
+		challType:  req.Challenge.Type,
+		token:      req.Challenge.Token,
+		hints:      policyHintsDedupKey(req.PolicyHints),
+		disallowed: disallowedChallengeType(req.AllowedChallengeTypes, core.AcmeChallenge(req.Challenge.Type)) != nil,
+	}
+	d := va.deduper
+	for {
+		d.Lock()
+		call, ok := d.calls[key]
+		if ok && !call.expires.IsZero() && !va.clk.Now().Before(call.expires) {
+			delete(d.calls, key)
+			ok = false
+		}
+		if !ok {
+			call = &dedupCall{done: make(chan struct{})}
+			d.calls[key] = call
+			d.Unlock()
+			return va.performDeduped(ctx, key, call, req, validate)
+		}
+		completed := !call.expires.IsZero()
+		d.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if shareable(call.result, call.err) {
+			reason := "in_flight"
+			if completed {
+				reason = "completed"
+			}
+			va.metrics.validationsDeduplicated.WithLabelValues(op, reason).Inc()
+			va.log.Infof("Sharing result of identical %s validation of %s for authz %s", op, req.DnsName, req.Authz.Id)
+			return proto.Clone(call.result).(*vapb.ValidationResult), nil
+		}
+		// The identical validation failed internally, try again.
+	}
+}
+
+// performDeduped calls validate with req, records the result in call, and
+// wakes any requests waiting on it. The result remains shareable for the
+// deduper's window.
+func (va *ValidationAuthorityImpl) performDeduped(
+	ctx context.Context,
+	key dedupKey,
+	call *dedupCall,
+	req *vapb.PerformValidationRequest,
+	validate func(context.Context, *vapb.PerformValidationRequest) (*vapb.ValidationResult, error),
+) (*vapb.ValidationResult, error) {
+	d := va.deduper
+	result, err := validate(ctx, req)
+
+	d.Lock()
+	defer d.Unlock()
+	call.result, call.err = result, err
+	if d.window > 0 && shareable(result, err) {
+		call.expires = va.clk.Now().Add(d.window)
+		expired := va.clk.After(d.window)
+		go func() {
+			<-expired
+			d.Lock()
+			defer d.Unlock()
+			if d.calls[key] == call {
+				delete(d.calls, key)
+			}
+		}()
+	} else {
+		delete(d.calls, key)
+	}
+	close(call.done)
+	return result, err
+}
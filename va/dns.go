@@ -4,35 +4,165 @@ import (
 	"context"
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base32"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/letsencrypt/boulder/bdns"
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/identifier"
+	"github.com/letsencrypt/boulder/probs"
 )
 
+// dnsError is a berrors.DNS error which also wraps the error returned by the
+// DNS client, so that detailedError can describe the query which failed.
+type dnsError struct {
+	boulderErr error
+	err        error
+}
+
+// newDNSError returns a berrors.DNS error with the same message as err.
+func newDNSError(err error) error {
+	return dnsError{boulderErr: berrors.DNSError("%s", err), err: err}
+}
+
+func (e dnsError) Error() string {
+	return e.boulderErr.Error()
+}
+
+func (e dnsError) Unwrap() []error {
+	return []error{e.boulderErr, e.err}
+}
+
+// dnsDetails returns the details of the DNS query which caused err, or nil if
+// err was not caused by a bdns.Error.
+func dnsDetails(err error) *probs.DNSDetails {
+	var queryErr interface{ QueryDetails() bdns.QueryDetails }
+	if !errors.As(err, &queryErr) {
+		return nil
+	}
+	details := queryErr.QueryDetails()
+	return &probs.DNSDetails{
+		QueryName: details.QueryName,
+		QueryType: details.QueryType,
+		RCode:     details.RCode,
+		EDE:       details.EDE,
+	}
+}
+
+// dnsResolver returns the address of the resolver queried by the DNS query
+// which caused err, or "" if err was not caused by a bdns.Error. It is logged,
+// but not included in problems, so that resolver addresses aren't exposed to
+// clients.
+func dnsResolver(err error) string {
+	var queryErr interface{ QueryDetails() bdns.QueryDetails }
+	if !errors.As(err, &queryErr) {
+		return ""
+	}
+	return queryErr.QueryDetails().Resolver
+}
+
+// dnsQueries returns the DNS queries described by resolvers, for inclusion in
+// a core.ValidationRecord.
+func dnsQueries(resolvers bdns.ResolverAddrs) []core.DNSQuery {
+	var queries []core.DNSQuery
+	for _, r := range resolvers {
+		queries = append(queries, core.DNSQuery{
+			Resolver:    r.Addr,
+			Qtype:       r.Qtype,
+			RTT:         r.RTT,
+			TCPFallback: r.TCPFallback,
+		})
+	}
+	return queries
+}
+
+// Reasons for a failure to resolve a hostname's addresses, used as labels of
+// the address_resolution_failures metric.
+const (
+	resolutionNXDOMAIN = "nxdomain"
+	resolutionNoData   = "nodata"
+	resolutionOther    = "other"
+)
+
+// addressLookupError returns the reason that the lookup of hostname's
+// addresses failed with err, and the error to report for it. A name which
+// doesn't exist and a name which exists without any A or AAAA records are
+// described distinctly, because they call for different fixes.
+func addressLookupError(hostname string, lookup *bdns.HostLookup, err error) (string, error) {
+	if bdns.IsNXDOMAIN(lookup.ErrA) && bdns.IsNXDOMAIN(lookup.ErrAAAA) {
+		return resolutionNXDOMAIN, dnsError{
+			boulderErr: newCatalogError(berrors.DNSError, msgDNSNXDOMAIN, hostname),
+			err:        err,
+		}
+	}
+	if bdns.IsNoData(lookup.ErrA) && bdns.IsNoData(lookup.ErrAAAA) {
+		return resolutionNoData, dnsError{
+			boulderErr: newCatalogError(berrors.DNSError, msgDNSNoAddressRecords, hostname),
+			err:        err,
+		}
+	}
+	return resolutionOther, newDNSError(err)
+}
+
+// addrLookup describes the A and AAAA queries made by getAddrs, so that they
+// can be included in validation records.
+type addrLookup struct {
+	resolvers bdns.ResolverAddrs
+	// digest and queriedAt are only set if the lookup succeeded.
+	digest    string
+	queriedAt *time.Time
+}
+
 // getAddr will query for all A/AAAA records associated with hostname and return
 // the preferred address, the first net.IP in the addrs slice, and all addresses
 // resolved. This is the same choice made by the Go internal resolution library
 // used by net/http. If there is an error resolving the hostname, or if no
 // usable IP addresses are available then a berrors.DNSError instance is
 // returned with a nil net.IP slice.
-func (va ValidationAuthorityImpl) getAddrs(ctx context.Context, hostname string) ([]net.IP, bdns.ResolverAddrs, error) {
-	addrs, resolvers, err := va.dnsClient.LookupHost(ctx, hostname)
+func (va ValidationAuthorityImpl) getAddrs(ctx context.Context, hostname string) ([]net.IP, addrLookup, error) {
+	ctx, span := va.tracer.Start(ctx, "dns resolution", trace.WithAttributes(
+		attribute.String("hostname", hostname),
+		attribute.String("type", "A/AAAA"),
+	))
+	// The A and AAAA queries are made in parallel with independent timeouts, so
+	// that a slow or broken authoritative server for one address family does
+	// not hold up validation over the other.
+	start := va.clk.Now()
+	lookup, err := va.dnsClient.LookupHostFamilies(ctx, hostname)
+	phaseTimingsFrom(ctx).add(phaseDNS, va.clk.Since(start))
+	if lookup.ErrA != nil && lookup.ErrAAAA == nil {
+		span.SetAttributes(attribute.String("errA", lookup.ErrA.Error()))
+		va.log.Debugf("A lookup for %s failed, continuing with AAAA: %s", hostname, lookup.ErrA)
+	} else if lookup.ErrAAAA != nil && lookup.ErrA == nil {
+		span.SetAttributes(attribute.String("errAAAA", lookup.ErrAAAA.Error()))
+		va.log.Debugf("AAAA lookup for %s failed, continuing with A: %s", hostname, lookup.ErrAAAA)
+	}
+	spanError(span, err)
+	span.End()
 	if err != nil {
-		return nil, resolvers, berrors.DNSError("%v", err)
+		reason, err := addressLookupError(hostname, lookup, err)
+		va.metrics.addressResolutionFailures.WithLabelValues(reason).Inc()
+		return nil, addrLookup{resolvers: lookup.Resolvers}, err
 	}
 
+	addrs := lookup.Addrs()
 	if len(addrs) == 0 {
 		// This should be unreachable, as no valid IP addresses being found results
-		// in an error being returned from LookupHost.
-		return nil, resolvers, berrors.DNSError("No valid IP addresses found for %s", hostname)
+		// in an error being returned from LookupHostFamilies.
+		return nil, addrLookup{resolvers: lookup.Resolvers}, newCatalogError(berrors.DNSError, msgDNSNoValidAddresses, hostname)
 	}
 	va.log.Debugf("Resolved addresses for %s: %s", hostname, addrs)
-	return addrs, resolvers, nil
+	return addrs, addrLookup{resolvers: lookup.Resolvers, digest: lookup.Digest, queriedAt: &start}, nil
 }
 
 // availableAddresses takes a ValidationRecord and splits the AddressesResolved
@@ -51,32 +181,106 @@ func availableAddresses(allAddrs []net.IP) (v4 []net.IP, v6 []net.IP) {
 func (va *ValidationAuthorityImpl) validateDNS01(ctx context.Context, ident identifier.ACMEIdentifier, keyAuthorization string) ([]core.ValidationRecord, error) {
 	if ident.Type != identifier.TypeDNS {
 		va.log.Infof("Identifier type for DNS challenge was not DNS: %s", ident)
-		return nil, berrors.MalformedError("Identifier type for DNS was not itself DNS")
+		return nil, newCatalogError(berrors.MalformedError, msgDNSIdentifierNotDNS)
+	}
+
+	// Look for the required record in the DNS
+	challengeSubdomain := fmt.Sprintf("%s.%s", core.DNSPrefix, ident.Value)
+	return va.validateTXT(ctx, ident, core.ChallengeTypeDNS01, challengeSubdomain, keyAuthorization)
+}
+
+// dnsAccountLabel returns the label, unique to the account with the given URL,
+// beneath which a dns-account-01 challenge's TXT record is placed: an
+// underscore followed by the lowercase base32 encoding of the first 10 bytes
+// of the SHA-256 digest of the URL, per draft-ietf-acme-dns-account-label.
+func dnsAccountLabel(accountURL string) string {
+	digest := sha256.Sum256([]byte(accountURL))
+	return "_" + strings.ToLower(base32.StdEncoding.EncodeToString(digest[:10]))
+}
+
+// validateDNSAccount01 validates a dns-account-01 challenge, which is like
+// dns-01 but with the TXT record at a label derived from the account URL, so
+// that several ACME clients can validate the same name without sharing a
+// record. The account may be known by a URL with any of the accountURIPrefixes,
+// so each of their labels is tried in turn. Each derived label is audit logged.
+func (va *ValidationAuthorityImpl) validateDNSAccount01(ctx context.Context, ident identifier.ACMEIdentifier, regID int64, keyAuthorization string) ([]core.ValidationRecord, error) {
+	if !features.Get().DNSAccount01Enabled {
+		return nil, newCatalogError(berrors.MalformedError, msgDNSAccount01Disabled, string(core.ChallengeTypeDNSAccount01))
+	}
+	if ident.Type != identifier.TypeDNS {
+		va.log.Infof("Identifier type for DNS challenge was not DNS: %s", ident)
+		return nil, newCatalogError(berrors.MalformedError, msgDNSIdentifierNotDNS)
 	}
 
+	var firstRecords []core.ValidationRecord
+	var firstErr error
+	for i, prefix := range va.accountURIPrefixes {
+		accountURL := fmt.Sprintf("%s%d", prefix, regID)
+		label := dnsAccountLabel(accountURL)
+		va.log.AuditInfof("dns-account-01 label derived: identifier=%q accountURL=%q label=%q", ident.Value, accountURL, label)
+
+		challengeSubdomain := fmt.Sprintf("%s.%s.%s", label, core.DNSPrefix, ident.Value)
+		records, err := va.validateTXT(ctx, ident, core.ChallengeTypeDNSAccount01, challengeSubdomain, keyAuthorization)
+		if err == nil {
+			return records, nil
+		}
+		if i == 0 {
+			firstRecords, firstErr = records, err
+		}
+	}
+	// Report the failure at the label of the first prefix, which is the one
+	// most clients will have used.
+	return firstRecords, firstErr
+}
+
+// validateTXT validates a DNS-based challenge by looking for the digest of
+// keyAuthorization among the TXT records at challengeSubdomain.
+func (va *ValidationAuthorityImpl) validateTXT(ctx context.Context, ident identifier.ACMEIdentifier, challType core.AcmeChallenge, challengeSubdomain string, keyAuthorization string) ([]core.ValidationRecord, error) {
 	// Compute the digest of the key authorization file
 	h := sha256.New()
 	h.Write([]byte(keyAuthorization))
 	authorizedKeysDigest := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
 
-	// Look for the required record in the DNS
-	challengeSubdomain := fmt.Sprintf("%s.%s", core.DNSPrefix, ident.Value)
-	txts, resolvers, err := va.dnsClient.LookupTXT(ctx, challengeSubdomain)
+	ctx, span := va.tracer.Start(ctx, "dns resolution", trace.WithAttributes(
+		attribute.String("hostname", challengeSubdomain),
+		attribute.String("type", "TXT"),
+	))
+	queriedAt := va.clk.Now()
+	txts, ttl, resolvers, err := va.dnsClient.LookupTXT(ctx, challengeSubdomain)
+	phaseTimingsFrom(ctx).add(phaseDNS, va.clk.Since(queriedAt))
+	spanError(span, err)
+	span.End()
 	if err != nil {
-		return nil, berrors.DNSError("%s", err)
+		return nil, newDNSError(err)
 	}
 
+	// Record a digest of the answers we acted upon, whether or not they
+	// contain the expected value, so that they can be verified later.
+	records := []core.ValidationRecord{{
+		DnsName:         ident.Value,
+		ResolverAddrs:   resolvers.Strings(),
+		DNSQueries:      dnsQueries(resolvers),
+		DNSAnswerDigest: bdns.TXTAnswerDigest(challengeSubdomain, txts),
+		DNSQueriedAt:    &queriedAt,
+	}}
+
 	// If there weren't any TXT records return a distinct error message to allow
 	// troubleshooters to differentiate between no TXT records and
 	// invalid/incorrect TXT records.
 	if len(txts) == 0 {
-		return nil, berrors.UnauthorizedError("No TXT record found at %s", challengeSubdomain)
+		return records, newCatalogError(berrors.UnauthorizedError, msgTXTNotFound, challengeSubdomain)
 	}
 
+	// Records with very low TTLs may be seen with different values by each
+	// perspective while they propagate, so they're noted but not refused.
+	lowTTL := ttl < va.minTXTTTL
 	for _, element := range txts {
 		if subtle.ConstantTimeCompare([]byte(element), []byte(authorizedKeysDigest)) == 1 {
 			// Successful challenge validation
-			return []core.ValidationRecord{{DnsName: ident.Value, ResolverAddrs: resolvers}}, nil
+			if lowTTL {
+				va.noteLowTTLTXT(ident, challType, challengeSubdomain, ttl, pass)
+			}
+			return records, nil
 		}
 	}
 
@@ -86,8 +290,21 @@ func (va *ValidationAuthorityImpl) validateDNS01(ctx context.Context, ident iden
 	}
 	var andMore string
 	if len(txts) > 1 {
-		andMore = fmt.Sprintf(" (and %d more)", len(txts)-1)
+		andMore = renderMessage(msgTXTIncorrectAndMore, len(txts)-1)
 	}
-	return nil, berrors.UnauthorizedError("Incorrect TXT record %q%s found at %s",
-		invalidRecord, andMore, challengeSubdomain)
+	var ttlHint string
+	if lowTTL {
+		va.noteLowTTLTXT(ident, challType, challengeSubdomain, ttl, fail)
+		ttlHint = renderMessage(msgTXTIncorrectLowTTLHint, ttl.String())
+	}
+	return records, newCatalogError(berrors.UnauthorizedError, msgTXTIncorrect,
+		invalidRecord, andMore, challengeSubdomain, ttlHint)
+}
+
+// noteLowTTLTXT audit logs and counts a TXT record lookup, for a validation
+// with the given result, whose answers had a TTL below va.minTXTTTL.
+func (va *ValidationAuthorityImpl) noteLowTTLTXT(ident identifier.ACMEIdentifier, challType core.AcmeChallenge, challengeSubdomain string, ttl time.Duration, result string) {
+	va.log.AuditInfof("Low TTL TXT record: identifier=%q challengeType=%s name=%q ttl=%s minimum=%s result=%s",
+		ident.Value, challType, challengeSubdomain, ttl, va.minTXTTTL, result)
+	va.metrics.lowTTLTXTRecords.WithLabelValues(string(challType), result).Inc()
 }
@@ -2,8 +2,10 @@ package va
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 
 	"github.com/letsencrypt/boulder/bdns"
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/identifier"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/probs"
@@ -112,6 +115,7 @@ func TestDNSValidationNoServer(t *testing.T) {
 		metrics.NoopRegisterer,
 		clock.New(),
 		1,
+		0,
 		log,
 		nil)
 
@@ -136,6 +140,158 @@ func TestDNSValidationNoAuthorityOK(t *testing.T) {
 	test.Assert(t, prob == nil, "Should be valid.")
 }
 
+func TestDNSValidationAuditLogsAnswerDigest(t *testing.T) {
+	va, mockLog := setup(nil, "", nil, nil)
+
+	txtQuery := core.DNSQuery{Resolver: "MockClient", Qtype: "TXT", RTT: time.Millisecond}
+	caaQuery := core.DNSQuery{Resolver: "MockClient", Qtype: "CAA", RTT: time.Millisecond}
+	testCases := []struct {
+		domain  string
+		txts    []string
+		success bool
+		// caaQueries are made only after a successful validation, for
+		// good-dns01.com and com.
+		caaQueries []core.DNSQuery
+	}{
+		{"good-dns01.com", []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, true, []core.DNSQuery{caaQuery, caaQuery}},
+		{"wrong-many-dns01.com", []string{"e", "d", "c", "b", "a"}, false, nil},
+		{"empty-txts.com", nil, false, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.domain, func(t *testing.T) {
+			mockLog.Clear()
+			req := createValidationRequest(tc.domain, core.ChallengeTypeDNS01)
+			res, err := va.PerformValidation(ctx, req)
+			test.AssertNotError(t, err, "performing validation")
+			test.AssertEquals(t, res.Problem == nil, tc.success)
+
+			// The digest in the audit log can be recomputed from the TXT
+			// records, regardless of the order they're provided in.
+			audit := parseValidationLogEvent(t, mockLog.GetAllMatching(`Validation result JSON=.*`))
+			test.AssertEquals(t, len(audit.Challenge.ValidationRecord), 1)
+			record := audit.Challenge.ValidationRecord[0]
+			test.AssertEquals(t, record.DNSAnswerDigest, bdns.TXTAnswerDigest("_acme-challenge."+tc.domain, tc.txts))
+			test.AssertDeepEquals(t, record.ResolverAddrs, []string{"MockClient"})
+			test.AssertDeepEquals(t, record.DNSQueries, []core.DNSQuery{txtQuery})
+			test.Assert(t, record.DNSQueriedAt != nil && record.DNSQueriedAt.Equal(va.clk.Now()), "DNSQueriedAt is missing")
+
+			// And it is returned to the RA in the validation records.
+			test.AssertEquals(t, len(res.Records), 1)
+			test.AssertEquals(t, res.Records[0].DnsAnswerDigest, record.DNSAnswerDigest)
+
+			// The CAA queries are logged separately from the record.
+			var caa struct{ CAAQueries []core.DNSQuery }
+			line := mockLog.GetAllMatching(`Validation result JSON=.*`)[0]
+			err = json.Unmarshal([]byte(line[strings.Index(line, "JSON=")+len("JSON="):]), &caa)
+			test.AssertNotError(t, err, "parsing audit log")
+			test.AssertDeepEquals(t, caa.CAAQueries, tc.caaQueries)
+		})
+	}
+}
+
+func TestDNSValidationLowTTL(t *testing.T) {
+	va, mockLog := setup(nil, "", nil, nil)
+
+	testCases := []struct {
+		ttl     int
+		lowTTL  bool
+		wantErr string
+	}{
+		{0, true, "; your record's TTL is 0s; very low TTLs can cause perspectives to see stale data"},
+		{4, true, "; your record's TTL is 4s; very low TTLs can cause perspectives to see stale data"},
+		{300, false, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("ttl %d", tc.ttl), func(t *testing.T) {
+			mockLog.Clear()
+			va.metrics.lowTTLTXTRecords.Reset()
+
+			// A matching record is never refused for its TTL.
+			domain := fmt.Sprintf("ttl-%d.com", tc.ttl)
+			_, err := va.validateDNS01(ctx, dnsi(domain), expectedKeyAuthorization)
+			test.AssertNotError(t, err, "validation with a matching record failed")
+			logged := mockLog.GetAllMatching(fmt.Sprintf(
+				`Low TTL TXT record: identifier="%s" challengeType=dns-01 name="_acme-challenge.%s" ttl=%ds minimum=5s result=pass`, domain, domain, tc.ttl))
+			test.AssertEquals(t, len(logged) == 1, tc.lowTTL)
+
+			// A mismatched record's problem hints at its TTL.
+			domain = fmt.Sprintf("wrong-ttl-%d.com", tc.ttl)
+			_, err = va.validateDNS01(ctx, dnsi(domain), expectedKeyAuthorization)
+			test.AssertError(t, err, "validation with a wrong record succeeded")
+			test.AssertEquals(t, detailedError(err).Error(), fmt.Sprintf(
+				"unauthorized :: Incorrect TXT record \"a\" found at _acme-challenge.%s%s", domain, tc.wantErr))
+			logged = mockLog.GetAllMatching(`Low TTL TXT record: .* result=fail`)
+			test.AssertEquals(t, len(logged) == 1, tc.lowTTL)
+
+			var expected float64
+			if tc.lowTTL {
+				expected = 1
+			}
+			for _, result := range []string{pass, fail} {
+				test.AssertMetricWithLabelsEquals(t, va.metrics.lowTTLTXTRecords, prometheus.Labels{
+					"challenge_type": string(core.ChallengeTypeDNS01),
+					"result":         result,
+				}, expected)
+			}
+		})
+	}
+}
+
+func TestDNSAccountLabel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		accountURL string
+		expected   string
+	}{
+		{"http://boulder.service.consul:4000/acme/reg/1", "_6g727n6x5dk6qex5"},
+		{"http://boulder.service.consul:4001/acme/acct/1", "_nc25al42opo24oqd"},
+		{"https://acme-v02.api.letsencrypt.org/acme/acct/123456", "_bnxkrtp5hx67nw4s"},
+	}
+	for _, tc := range testCases {
+		test.AssertEquals(t, dnsAccountLabel(tc.accountURL), tc.expected)
+	}
+}
+
+func TestDNSAccount01Validation(t *testing.T) {
+	va, mockLog := setup(nil, "", nil, nil)
+	features.Set(features.Config{DNSAccount01Enabled: true})
+	defer features.Reset()
+
+	req := createValidationRequest("good-dns-account01.com", core.ChallengeTypeDNSAccount01)
+	res, err := va.DoDCV(ctx, req)
+	test.AssertNotError(t, err, "performing validation")
+	test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed with: %#v", res.Problem))
+	test.AssertEquals(t, len(res.Records), 1)
+	test.AssertEquals(t, res.Records[0].DnsAnswerDigest, bdns.TXTAnswerDigest(
+		"_6g727n6x5dk6qex5._acme-challenge.good-dns-account01.com", []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}))
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`dns-account-01 label derived: identifier="good-dns-account01.com" `+
+		`accountURL="http://boulder.service.consul:4000/acme/reg/1" label="_6g727n6x5dk6qex5"`)), 1)
+
+	// The record for the dns-01 challenge doesn't satisfy dns-account-01.
+	_, err = va.validateDNSAccount01(ctx, dnsi("good-dns01.com"), 1, expectedKeyAuthorization)
+	prob := detailedError(err)
+	test.AssertEquals(t, prob.Error(), "unauthorized :: Incorrect TXT record \"hostname\" found at _6g727n6x5dk6qex5._acme-challenge.good-dns01.com")
+
+	// Another account's label doesn't satisfy it either.
+	_, err = va.validateDNSAccount01(ctx, dnsi("good-dns-account01.com"), 2, expectedKeyAuthorization)
+	test.AssertError(t, err, "validation succeeded with another account's label")
+
+	// The label of any of the account's URLs is accepted, but failures are
+	// reported at the label of the first.
+	va.accountURIPrefixes = []string{"http://boulder.service.consul:4000/acme/reg/", "http://boulder.service.consul:4001/acme/acct/"}
+	_, err = va.validateDNSAccount01(ctx, dnsi("other-prefix-dns-account01.com"), 1, expectedKeyAuthorization)
+	test.AssertNotError(t, err, "validating with the label of the second account URL")
+	_, err = va.validateDNSAccount01(ctx, dnsi("wrong-dns01.com"), 1, expectedKeyAuthorization)
+	test.AssertContains(t, err.Error(), "found at _6g727n6x5dk6qex5._acme-challenge.wrong-dns01.com")
+
+	// Without the feature, the challenge isn't performed.
+	features.Reset()
+	res, err = va.DoDCV(ctx, req)
+	test.AssertNotError(t, err, "performing validation")
+	test.AssertEquals(t, res.Problem.ProblemType, string(probs.MalformedProblem))
+}
+
 func TestAvailableAddresses(t *testing.T) {
 	v6a := net.ParseIP("::1")
 	v6b := net.ParseIP("2001:db8::2:1") // 2001:DB8 is reserved for docs (RFC 3849)
@@ -210,3 +366,101 @@ func TestAvailableAddresses(t *testing.T) {
 		}
 	}
 }
+
+// dnsMockPartialFailure is a bdns.Client whose A lookups always fail, while
+// its AAAA lookups succeed.
+type dnsMockPartialFailure struct {
+	*bdns.MockClient
+}
+
+func (mock dnsMockPartialFailure) LookupHostFamilies(_ context.Context, hostname string) (*bdns.HostLookup, error) {
+	return &bdns.HostLookup{
+		IPv6:      []net.IP{net.ParseIP("::1")},
+		ErrA:      fmt.Errorf("SERVFAIL looking up A for %s", hostname),
+		Resolvers: bdns.ResolverAddrs{{Addr: "dnsMockPartialFailure", Qtype: "A"}, {Addr: "dnsMockPartialFailure", Qtype: "AAAA"}},
+	}, nil
+}
+
+func TestGetAddrsPartialFailure(t *testing.T) {
+	va, mockLog := setup(nil, "", nil, dnsMockPartialFailure{&bdns.MockClient{}})
+
+	addrs, lookup, err := va.getAddrs(context.Background(), "example.com")
+	test.AssertNotError(t, err, "getAddrs should succeed when only the A lookup fails")
+	test.AssertDeepEquals(t, addrs, []net.IP{net.ParseIP("::1")})
+	test.AssertDeepEquals(t, lookup.resolvers, bdns.ResolverAddrs{{Addr: "dnsMockPartialFailure", Qtype: "A"}, {Addr: "dnsMockPartialFailure", Qtype: "AAAA"}})
+	test.AssertEquals(t, len(mockLog.GetAllMatching("A lookup for example.com failed, continuing with AAAA")), 1)
+}
+
+// dnsMockMixedFailure is a bdns.Client whose A lookups fail with the error the
+// bdns.MockClient returns for hostA, and whose AAAA lookups fail with the
+// error it returns for hostAAAA.
+type dnsMockMixedFailure struct {
+	*bdns.MockClient
+	hostA, hostAAAA string
+}
+
+func (mock dnsMockMixedFailure) LookupHostFamilies(ctx context.Context, hostname string) (*bdns.HostLookup, error) {
+	_, _, errA := mock.MockClient.LookupHost(ctx, mock.hostA)
+	_, _, errAAAA := mock.MockClient.LookupHost(ctx, mock.hostAAAA)
+	return &bdns.HostLookup{ErrA: errA, ErrAAAA: errAAAA}, fmt.Errorf("%w; %s", errA, errAAAA)
+}
+
+func TestGetAddrsNoDataAndNXDOMAIN(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		client         bdns.Client
+		hostname       string
+		expectedDetail string
+		expectedReason string
+	}{
+		{
+			name:           "NXDOMAIN",
+			hostname:       "always.nxdomain",
+			expectedDetail: "DNS problem: always.nxdomain does not exist (NXDOMAIN)",
+			expectedReason: resolutionNXDOMAIN,
+		},
+		{
+			name:           "NODATA",
+			hostname:       "always.nodata",
+			expectedDetail: "DNS problem: no A or AAAA records exist for always.nodata",
+			expectedReason: resolutionNoData,
+		},
+		{
+			name:           "timeout",
+			hostname:       "always.timeout",
+			expectedDetail: "DNS problem: query timed out looking up A for always.timeout",
+			expectedReason: resolutionOther,
+		},
+		{
+			name:           "NXDOMAIN for A, timeout for AAAA",
+			client:         dnsMockMixedFailure{&bdns.MockClient{}, "always.nxdomain", "always.timeout"},
+			hostname:       "example.com",
+			expectedDetail: "DNS problem: NXDOMAIN looking up A for always.nxdomain - check that a DNS record exists for this domain; DNS problem: query timed out looking up A for always.timeout",
+			expectedReason: resolutionOther,
+		},
+		{
+			name:           "NODATA for A, NXDOMAIN for AAAA",
+			client:         dnsMockMixedFailure{&bdns.MockClient{}, "always.nodata", "always.nxdomain"},
+			hostname:       "example.com",
+			expectedDetail: "DNS problem: no A records exist for always.nodata; DNS problem: NXDOMAIN looking up A for always.nxdomain - check that a DNS record exists for this domain",
+			expectedReason: resolutionOther,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			va, _ := setup(nil, "", nil, tc.client)
+
+			_, err := va.validateHTTP01(ctx, dnsi(tc.hostname), expectedToken, expectedKeyAuthorization)
+			test.AssertError(t, err, "validation should have failed")
+			prob := detailedError(err)
+			test.AssertEquals(t, prob.Type, probs.DNSProblem)
+			test.AssertEquals(t, prob.Detail, tc.expectedDetail)
+			// The details of the A query are reported, whatever the reason.
+			test.AssertEquals(t, prob.DNSDetails.QueryType, "A")
+			test.AssertMetricWithLabelsEquals(t, va.metrics.addressResolutionFailures, prometheus.Labels{"reason": tc.expectedReason}, 1)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package va
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -8,13 +9,22 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 	"unicode"
 
-	"github.com/letsencrypt/boulder/bdns"
+	"github.com/jmhodges/clock"
+	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/http/httpguts"
+
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/iana"
@@ -31,11 +41,159 @@ const (
 	// bytes before rejecting a response (32 byte b64 encoded token + . + 32 byte
 	// b64 encoded key fingerprint).
 	maxResponseSize = 128
+	// maxMultipleKeyAuthorizationsSize is the maximum number of bytes that will
+	// be read from an HTTP-01 challenge response to determine whether a
+	// response exceeding maxResponseSize contains several concatenated key
+	// authorizations.
+	maxMultipleKeyAuthorizationsSize = 2048
 	// maxPathSize is the maximum number of bytes we will accept in the path of a
 	// redirect URL.
 	maxPathSize = 2000
+	// cacheBustParam is the name of the query parameter added to an HTTP-01
+	// request which is retried because the first response appeared to be
+	// served from a stale cache.
+	cacheBustParam = "acme-cache-bust"
+	// maxHTTPHeaders is the maximum number of additional request headers which
+	// may be configured for HTTP-01 requests.
+	maxHTTPHeaders = 8
 )
 
+// forbiddenHTTPHeaders are the request headers which may not be configured as
+// additional HTTP-01 request headers, because they carry credentials, control
+// the connection or are already set by the VA. Any header beginning with
+// "Proxy-" or "Sec-" is also forbidden.
+var forbiddenHTTPHeaders = map[string]bool{
+	"Accept":            true,
+	"Accept-Encoding":   true,
+	"Authorization":     true,
+	"Cache-Control":     true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Cookie":            true,
+	"Forwarded":         true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Pragma":            true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+	"User-Agent":        true,
+	"X-Forwarded-For":   true,
+	"X-Forwarded-Host":  true,
+	"X-Forwarded-Proto": true,
+	"X-Real-Ip":         true,
+}
+
+// validateHTTPHeaders checks the additional request headers configured for
+// HTTP-01 requests and returns them keyed by their canonical names. It returns
+// an error if there are too many, if any name or value is malformed, or if any
+// name is forbidden.
+func validateHTTPHeaders(headers map[string]string) (map[string]string, error) {
+	if len(headers) > maxHTTPHeaders {
+		return nil, fmt.Errorf("at most %d additional HTTP headers may be configured, got %d", maxHTTPHeaders, len(headers))
+	}
+	canonical := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid additional HTTP header name %q", name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid value for additional HTTP header %q", name)
+		}
+		key := http.CanonicalHeaderKey(name)
+		if forbiddenHTTPHeaders[key] || strings.HasPrefix(key, "Proxy-") || strings.HasPrefix(key, "Sec-") {
+			return nil, fmt.Errorf("additional HTTP header %q may not be configured", key)
+		}
+		if _, ok := canonical[key]; ok {
+			return nil, fmt.Errorf("additional HTTP header %q configured more than once", key)
+		}
+		canonical[key] = value
+	}
+	return canonical, nil
+}
+
+// validateConfirmOverTLSDomains checks the domains configured to require
+// HTTP-01 confirmation over HTTPS and returns them lowercased, without any
+// trailing dot. It returns an error if any entry is not a domain name.
+func validateConfirmOverTLSDomains(domains []string) ([]string, error) {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+		_, ok := dns.IsDomainName(domain)
+		if !ok || domain == "" || strings.HasPrefix(domain, ".") || net.ParseIP(domain) != nil {
+			return nil, fmt.Errorf("invalid HTTP-01 confirm over TLS domain %q", domain)
+		}
+		normalized = append(normalized, domain)
+	}
+	return normalized, nil
+}
+
+// requiresConfirmOverTLS returns true if the HTTP01ConfirmOverTLS feature is
+// enabled, or the request's policy hints enable it, and name is, or is a
+// subdomain of, one of the VA's configured HTTP-01 confirm over TLS domains.
+func (va *ValidationAuthorityImpl) requiresConfirmOverTLS(ctx context.Context, name string) bool {
+	if !validationPolicyFrom(ctx).confirmOverTLSEnabled() {
+		return false
+	}
+	name = strings.ToLower(name)
+	for _, domain := range va.confirmOverTLSDomains {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpResponseRecord records the final URL and status code of the last HTTP-01
+// response received during a single validation. Like phaseTimings, it is
+// carried in the validation's context. All methods are safe for concurrent
+// use, and on a nil *httpResponseRecord, which records nothing.
+type httpResponseRecord struct {
+	sync.Mutex
+	finalURL   string
+	statusCode int
+}
+
+type httpResponseRecordKey struct{}
+
+// withHTTPResponseRecord returns a child context carrying a new, empty
+// *httpResponseRecord, which is also returned.
+func withHTTPResponseRecord(ctx context.Context) (context.Context, *httpResponseRecord) {
+	record := &httpResponseRecord{}
+	return context.WithValue(ctx, httpResponseRecordKey{}, record), record
+}
+
+// httpResponseRecordFrom returns the *httpResponseRecord carried by ctx, or
+// nil.
+func httpResponseRecordFrom(ctx context.Context) *httpResponseRecord {
+	record, _ := ctx.Value(httpResponseRecordKey{}).(*httpResponseRecord)
+	return record
+}
+
+// set replaces any previously recorded response, so that a retried fetch is
+// described by its last response.
+func (r *httpResponseRecord) set(finalURL string, statusCode int) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.finalURL = finalURL
+	r.statusCode = statusCode
+}
+
+// get returns the recorded final URL and status code, or zero values if no
+// response was recorded.
+func (r *httpResponseRecord) get() (string, int) {
+	if r == nil {
+		return "", 0
+	}
+	r.Lock()
+	defer r.Unlock()
+	return r.finalURL, r.statusCode
+}
+
 // preresolvedDialer is a struct type that provides a DialContext function which
 // will connect to the provided IP and port instead of letting DNS resolve
 // The hostname of the preresolvedDialer is used to ensure the dial only completes
@@ -45,6 +203,18 @@ type preresolvedDialer struct {
 	port     int
 	hostname string
 	timeout  time.Duration
+	clk      clock.Clock
+
+	// proxyProtocolSource, if valid, is the VA's public address, which is sent
+	// in a PROXY protocol header at the start of every connection.
+	proxyProtocolSource netip.Addr
+
+	// control, if set, is called before each connection is made. See
+	// ValidationAuthorityImpl.dialControl.
+	control func(network, address string, c syscall.RawConn) error
+
+	// conns, if set, bounds the connections open to the pre-resolved IP.
+	conns *targetConnLimiter
 }
 
 // a dialerMismatchError is produced when a preresolvedDialer is used to dial
@@ -129,8 +299,61 @@ func (d *preresolvedDialer) DialContext(
 		Timeout: d.timeout,
 		// Default KeepAlive - see Golang src/net/http/transport.go DefaultTransport
 		KeepAlive: 30 * time.Second,
+		Control:   d.control,
+	}
+	start := d.clk.Now()
+	ip, _ := netip.AddrFromSlice(d.ip)
+	release, err := d.conns.acquire(ctx, ip)
+	if err != nil {
+		phaseTimingsFrom(ctx).add(phaseConnect, d.clk.Since(start))
+		return nil, err
+	}
+	conn, err := throwAwayDialer.DialContext(ctx, network, targetAddr)
+	phaseTimingsFrom(ctx).add(phaseConnect, d.clk.Since(start))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	conn = &limitedConn{Conn: conn, release: release}
+	if d.proxyProtocolSource.IsValid() {
+		err = writeProxyProtocolHeader(conn, d.proxyProtocolSource)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return &h2cDetectingConn{Conn: transferBudgetFrom(ctx).wrap(conn)}, nil
+}
+
+// errCleartextHTTP2 is returned when reading from a connection on which the
+// server sent an HTTP/2 frame before any TLS handshake, as a server which only
+// speaks HTTP/2 with prior knowledge over plaintext (h2c) does.
+var errCleartextHTTP2 = errors.New("server sent a cleartext HTTP/2 frame")
+
+// h2cDetectingConn is a net.Conn which fails its first read with
+// errCleartextHTTP2 if the server began by sending an HTTP/2 SETTINGS frame.
+// Our HTTP/1.1 client would otherwise wait for a status line that never
+// arrives until the validation times out.
+type h2cDetectingConn struct {
+	net.Conn
+	checked bool
+}
+
+func (c *h2cDetectingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if c.checked || n == 0 {
+		return n, err
+	}
+	c.checked = true
+	// An HTTP/2 server's first frame is always SETTINGS: a 24-bit length,
+	// which is never large enough to set the first byte, then type 0x4, a
+	// flags byte and a stream ID of zero. HTTP/1.x responses begin with
+	// "HTTP" and TLS records with a content type byte, neither of which is
+	// ever zero.
+	if n >= 9 && b[0] == 0 && b[3] == 0x4 && bytes.Equal(b[5:9], []byte{0, 0, 0, 0}) {
+		return 0, errCleartextHTTP2
 	}
-	return throwAwayDialer.DialContext(ctx, network, targetAddr)
+	return n, err
 }
 
 // a dialerFunc meets the function signature requirements of
@@ -140,12 +363,21 @@ type dialerFunc func(ctx context.Context, network, addr string) (net.Conn, error
 // httpTransport constructs a HTTP Transport with settings appropriate for
 // HTTP-01 validation. The provided dialerFunc is used as the Transport's
 // DialContext handler.
+//
+// Over plaintext the Transport only speaks HTTP/1.1. Over TLS, which is only
+// used after a redirect to HTTPS, it offers both HTTP/2 and HTTP/1.1 via ALPN
+// and accepts whichever the server chooses. HTTP/3 is not supported, and
+// Alt-Svc headers advertising it are ignored.
 func httpTransport(df dialerFunc) *http.Transport {
 	return &http.Transport{
 		DialContext: df,
 		// We are talking to a client that does not yet have a certificate,
 		// so we accept a temporary, invalid one.
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		// Setting a custom DialContext and TLSClientConfig would otherwise
+		// disable HTTP/2, leaving servers which only speak HTTP/2 over TLS
+		// unable to complete the handshake.
+		ForceAttemptHTTP2: true,
 		// We don't expect to make multiple requests to a client, so close
 		// connection immediately.
 		DisableKeepAlives: true,
@@ -177,8 +409,8 @@ type httpValidationTarget struct {
 	next []net.IP
 	// the current IP address being used for validation (if any)
 	cur net.IP
-	// the DNS resolver(s) that will attempt to fulfill the validation request
-	resolvers bdns.ResolverAddrs
+	// the DNS lookups which resolved the host's IP addresses
+	lookup addrLookup
 }
 
 // nextIP changes the cur IP by removing the first entry from the next slice and
@@ -208,7 +440,7 @@ func (va *ValidationAuthorityImpl) newHTTPValidationTarget(
 	path string,
 	query string) (*httpValidationTarget, error) {
 	// Resolve IP addresses for the hostname
-	addrs, resolvers, err := va.getAddrs(ctx, host)
+	addrs, lookup, err := va.getAddrs(ctx, host)
 	if err != nil {
 		return nil, err
 	}
@@ -219,7 +451,7 @@ func (va *ValidationAuthorityImpl) newHTTPValidationTarget(
 		path:      path,
 		query:     query,
 		available: addrs,
-		resolvers: resolvers,
+		lookup:    lookup,
 	}
 
 	// Separate the addresses into the available v4 and v6 addresses
@@ -251,62 +483,83 @@ func (va *ValidationAuthorityImpl) newHTTPValidationTarget(
 }
 
 // extractRequestTarget extracts the hostname and port specified in the provided
-// HTTP redirect request. If the request's URL's protocol schema is not HTTP or
-// HTTPS an error is returned. If an explicit port is specified in the request's
-// URL and it isn't the VA's HTTP or HTTPS port, an error is returned. If the
-// request's URL's Host is a bare IPv4 or IPv6 address and not a domain name an
-// error is returned.
-func (va *ValidationAuthorityImpl) extractRequestTarget(req *http.Request) (string, int, error) {
+// HTTP redirect request, which the http.Client has already resolved against
+// the previous hop, so relative and protocol-relative redirects are checked as
+// the absolute URLs they refer to. An error describing the problem is returned
+// if the request's URL:
+//   - uses a protocol scheme other than HTTP or HTTPS,
+//   - includes userinfo,
+//   - specifies an explicit port which isn't the VA's HTTP or HTTPS port,
+//   - has a host which is an IP address, unless it is exactly origIP, the IP
+//     address identifier being validated, or
+//   - has a host which isn't a syntactically valid DNS name ending in an IANA
+//     registered TLD.
+//
+// origIP is the zero netip.Addr when validating a DNS identifier.
+func (va *ValidationAuthorityImpl) extractRequestTarget(req *http.Request, ports validationPorts, origIP netip.Addr) (string, int, error) {
 	// A nil request is certainly not a valid redirect and has no port to extract.
-	if req == nil {
-		return "", 0, fmt.Errorf("redirect HTTP request was nil")
+	if req == nil || req.URL == nil {
+		return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectNoTarget)
 	}
 
 	reqScheme := req.URL.Scheme
 
 	// The redirect request must use HTTP or HTTPs protocol schemes regardless of the port..
 	if reqScheme != "http" && reqScheme != "https" {
-		return "", 0, berrors.ConnectionFailureError(
-			"Invalid protocol scheme in redirect target. "+
-				`Only "http" and "https" protocol schemes are supported, not %q`, reqScheme)
+		return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectScheme, reqScheme)
+	}
+
+	// Credentials in the redirect target would be sent to the next hop as
+	// basic authentication, which we never want to do.
+	if req.URL.User != nil {
+		return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectUserinfo)
 	}
 
-	// Try and split an explicit port number from the request URL host. If there is
-	// one we need to make sure its a valid port. If there isn't one we need to
-	// pick the port based on the reqScheme default port.
-	reqHost := req.URL.Host
+	// If there is an explicit port number we need to make sure its a valid
+	// port. If there isn't one we need to pick the port based on the reqScheme
+	// default port.
+	reqHost := req.URL.Hostname()
 	var reqPort int
-	if h, p, err := net.SplitHostPort(reqHost); err == nil {
-		reqHost = h
+	if p := req.URL.Port(); p != "" {
+		var err error
 		reqPort, err = strconv.Atoi(p)
 		if err != nil {
-			return "", 0, err
+			return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectInvalidPort, p)
 		}
 
 		// The explicit port must match the VA's configured HTTP or HTTPS port.
-		if reqPort != va.httpPort && reqPort != va.httpsPort {
-			return "", 0, berrors.ConnectionFailureError(
-				"Invalid port in redirect target. Only ports %d and %d are supported, not %d",
-				va.httpPort, va.httpsPort, reqPort)
+		if reqPort != ports.http && reqPort != ports.https {
+			return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectUnsupportedPort, ports.http, ports.https, reqPort)
 		}
 	} else if reqScheme == "http" {
-		reqPort = va.httpPort
-	} else if reqScheme == "https" {
-		reqPort = va.httpsPort
+		reqPort = ports.http
 	} else {
-		// This shouldn't happen but defensively return an internal server error in
-		// case it does.
-		return "", 0, fmt.Errorf("unable to determine redirect HTTP request port")
+		reqPort = ports.https
 	}
 
 	if reqHost == "" {
-		return "", 0, berrors.ConnectionFailureError("Invalid empty hostname in redirect target")
+		return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectEmptyHost)
 	}
 
 	// Check that the request host isn't a bare IP address. We only follow
-	// redirects to hostnames.
-	if net.ParseIP(reqHost) != nil {
-		return "", 0, berrors.ConnectionFailureError("Invalid host in redirect target %q. Only domain names are supported, not IP addresses", reqHost)
+	// redirects to hostnames, or back to the IP address being validated.
+	if addr, err := netip.ParseAddr(reqHost); err == nil {
+		if origIP.IsValid() && addr == origIP {
+			return addr.String(), reqPort, nil
+		}
+		if origIP.IsValid() {
+			return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectOtherIP, reqHost, origIP.String())
+		}
+		return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectIP, reqHost)
+	}
+
+	if strings.HasSuffix(reqHost, ".") {
+		va.log.Infof("Stripping trailing dot from redirect target %q", reqHost)
+		reqHost = strings.TrimSuffix(reqHost, ".")
+	}
+	err := validRedirectHostname(reqHost)
+	if err != nil {
+		return "", 0, err
 	}
 
 	// Often folks will misconfigure their webserver to send an HTTP redirect
@@ -319,19 +572,43 @@ func (va *ValidationAuthorityImpl) extractRequestTarget(req *http.Request) (stri
 	// This happens frequently enough we want to return a distinct error message
 	// for this case by detecting the reqHost ending in ".well-known".
 	if strings.HasSuffix(reqHost, ".well-known") {
-		return "", 0, berrors.ConnectionFailureError(
-			"Invalid host in redirect target %q. Check webserver config for missing '/' in redirect target.",
-			reqHost,
-		)
+		return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectWellKnownHost, reqHost)
 	}
 
 	if _, err := iana.ExtractSuffix(reqHost); err != nil {
-		return "", 0, berrors.ConnectionFailureError("Invalid hostname in redirect target, must end in IANA registered TLD")
+		return "", 0, newCatalogError(berrors.ConnectionFailureError, msgRedirectNotIANATLD)
 	}
 
 	return reqHost, reqPort, nil
 }
 
+// validRedirectHostname returns an error describing why host, without any
+// trailing dot, isn't a syntactically valid DNS hostname: at most 253 bytes of
+// labels of 1 to 63 letters, digits and hyphens, which don't begin or end with
+// a hyphen. Internationalized names must be in their A-label (punycode) form.
+func validRedirectHostname(host string) error {
+	if len(host) > 253 {
+		return newCatalogError(berrors.ConnectionFailureError, msgRedirectHostnameTooLong)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" {
+			return newCatalogError(berrors.ConnectionFailureError, msgRedirectEmptyLabel, host)
+		}
+		if len(label) > 63 {
+			return newCatalogError(berrors.ConnectionFailureError, msgRedirectLabelTooLong, host)
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return newCatalogError(berrors.ConnectionFailureError, msgRedirectInvalidCharacters, host)
+			}
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return newCatalogError(berrors.ConnectionFailureError, msgRedirectLabelHyphen, host)
+		}
+	}
+	return nil
+}
+
 // setupHTTPValidation sets up a preresolvedDialer and a validation record for
 // the given request URL and httpValidationTarget. If the req URL is empty, or
 // the validation target is nil or has no available IP addresses, an error will
@@ -359,7 +636,11 @@ func (va *ValidationAuthorityImpl) setupHTTPValidation(
 		Port:              strconv.Itoa(target.port),
 		AddressesResolved: target.available,
 		URL:               reqURL,
-		ResolverAddrs:     target.resolvers,
+		ResolverAddrs:     target.lookup.resolvers.Strings(),
+		DNSQueries:        dnsQueries(target.lookup.resolvers),
+		DNSAnswerDigest:   target.lookup.digest,
+		DNSQueriedAt:      target.lookup.queriedAt,
+		ProxyProtocol:     va.proxyProtocolSource.IsValid(),
 	}
 
 	// Get the target IP to build a preresolved dialer with
@@ -372,12 +653,18 @@ func (va *ValidationAuthorityImpl) setupHTTPValidation(
 				target.host)
 	}
 	record.AddressUsed = targetIP
+	record.AddressFamily = ipFamily(targetIP)
 
 	dialer := &preresolvedDialer{
 		ip:       targetIP,
 		port:     target.port,
 		hostname: target.host,
 		timeout:  va.singleDialTimeout,
+		clk:      va.clk,
+
+		proxyProtocolSource: va.proxyProtocolSource,
+		control:             va.dialControl,
+		conns:               va.targetConns,
 	}
 	return dialer, record, nil
 }
@@ -388,12 +675,151 @@ func (va *ValidationAuthorityImpl) setupHTTPValidation(
 func (va *ValidationAuthorityImpl) fetchHTTP(
 	ctx context.Context,
 	host string,
-	path string) ([]byte, []core.ValidationRecord, error) {
-	body, records, err := va.processHTTPValidation(ctx, host, path)
+	path string,
+	query string) ([]byte, []core.ValidationRecord, bool, error) {
+	ctx, span := va.tracer.Start(ctx, "http fetch", trace.WithAttributes(
+		attribute.String("host", host),
+		attribute.String("path", path),
+	))
+	defer span.End()
+	body, records, cached, err := va.processHTTPValidation(ctx, host, path, query)
+	spanError(span, err)
+	if err != nil {
+		return body, records, cached, err
+	}
+	return body, records, cached, nil
+}
+
+// responseFromCache returns true if the HTTP response carries headers
+// indicating that it was served from an intermediary cache (e.g. a CDN) rather
+// than by the origin server: an Age header greater than zero, or an X-Cache
+// header reporting a hit.
+func responseFromCache(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	age, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Age")))
+	if err == nil && age > 0 {
+		return true
+	}
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(resp.Header.Get("X-Cache"))), "HIT")
+}
+
+// retryAfterError wraps the error for an HTTP-01 response with a 429 or 503
+// status code which carried a usable Retry-After header.
+type retryAfterError struct {
+	retryAfter time.Duration
+	err        error
+}
+
+func (e retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// retryAfterDelay returns the delay requested by the Retry-After header of a
+// 429 or 503 response, which may be either a number of seconds or an HTTP
+// date. It returns false for any other status code or if the header is absent
+// or cannot be parsed.
+func retryAfterDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
 	if err != nil {
-		return body, records, err
+		return 0, false
 	}
-	return body, records, nil
+	delay := date.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+// addressFamilyError wraps a connection failure for a host whose resolved
+// addresses were all of a single address family, so that the problem returned
+// to the client can point out that the address family may be the issue.
+type addressFamilyError struct {
+	family string
+	err    error
+}
+
+func (e addressFamilyError) Error() string {
+	return e.err.Error()
+}
+
+func (e addressFamilyError) Unwrap() error {
+	return e.err
+}
+
+// hint returns the text appended to the problem detail for e.
+func (e addressFamilyError) hint() string {
+	if e.family == "ipv6" {
+		return "the domain only has AAAA (IPv6) records; ensure your webserver listens on IPv6 or add an A record"
+	}
+	return "the domain only has A (IPv4) records; ensure your webserver listens on IPv4 or add an AAAA record"
+}
+
+// addressFamily returns "ipv4" or "ipv6" if all of the provided addresses are
+// of that family and "dual" otherwise.
+func addressFamily(addrs []net.IP) string {
+	v4, v6 := availableAddresses(addrs)
+	if len(v6) == 0 {
+		return "ipv4"
+	}
+	if len(v4) == 0 {
+		return "ipv6"
+	}
+	return "dual"
+}
+
+// ipFamily returns "ipv4" or "ipv6", the address family of ip, or "none" if
+// ip is nil.
+func ipFamily(ip net.IP) string {
+	if ip == nil {
+		return "none"
+	}
+	if ip.To4() != nil {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+// decisiveAddressFamily returns the address family of the connection made for
+// the last of records, which is the one that decided the validation, or "none"
+// if no connection was made, as for DNS-01.
+func decisiveAddressFamily(records []core.ValidationRecord) string {
+	if len(records) == 0 {
+		return "none"
+	}
+	return ipFamily(records[len(records)-1].AddressUsed)
+}
+
+// connectionFailure records a failure to connect to the address used by the
+// provided record and returns err wrapped in an ipError. If the host resolved
+// to addresses of only one family err is also wrapped in an
+// addressFamilyError.
+func (va *ValidationAuthorityImpl) connectionFailure(record core.ValidationRecord, err error) error {
+	family := addressFamily(record.AddressesResolved)
+	va.metrics.http01ConnectionFailures.WithLabelValues(family).Inc()
+	if family != "dual" {
+		err = addressFamilyError{family: family, err: err}
+	}
+	return newIPError(record.AddressUsed, err)
 }
 
 // fallbackErr returns true only for net.OpError instances where the op is equal
@@ -411,29 +837,40 @@ func fallbackErr(err error) bool {
 	return errors.As(err, &netOpError) && netOpError.Op == "dial"
 }
 
-// processHTTPValidation performs an HTTP validation for the given host, port
-// and path. If successful the body of the HTTP response is returned along with
-// the validation records created during the validation. If not successful
-// a non-nil error and potentially some ValidationRecords are returned.
+// processHTTPValidation performs an HTTP validation for the given host, port,
+// path and (optional) query. If successful the body of the HTTP response is
+// returned along with the validation records created during the validation,
+// and whether the final response appears to have been served from an
+// intermediary cache. If not successful a non-nil error and potentially some
+// ValidationRecords are returned.
 func (va *ValidationAuthorityImpl) processHTTPValidation(
 	ctx context.Context,
 	host string,
-	path string) ([]byte, []core.ValidationRecord, error) {
-	// Create a target for the host, port and path with no query parameters
-	target, err := va.newHTTPValidationTarget(ctx, host, va.httpPort, path, "")
+	path string,
+	query string) ([]byte, []core.ValidationRecord, bool, error) {
+	// Some servers reject requests whose Host header isn't in canonical form,
+	// so make sure we always send (and dial) a lowercased hostname.
+	host = strings.ToLower(host)
+	// Redirects may only lead back to an IP address if it's the one being
+	// validated.
+	origIP, _ := netip.ParseAddr(host)
+
+	// Create a target for the host, port, path and query
+	target, err := va.newHTTPValidationTarget(ctx, host, va.ports.http, path, query)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, false, err
 	}
 
 	// Create an initial GET Request
 	initialURL := url.URL{
-		Scheme: "http",
-		Host:   host,
-		Path:   path,
+		Scheme:   "http",
+		Host:     host,
+		Path:     path,
+		RawQuery: query,
 	}
 	initialReq, err := http.NewRequest("GET", initialURL.String(), nil)
 	if err != nil {
-		return nil, nil, newIPError(target.cur, err)
+		return nil, nil, false, newIPError(target.cur, err)
 	}
 
 	// Add a context to the request. Shave some time from the
@@ -446,7 +883,7 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 	// are so common (and because it might fix a flaky build).
 	deadline, ok := ctx.Deadline()
 	if !ok {
-		return nil, nil, fmt.Errorf("processHTTPValidation had no deadline")
+		return nil, nil, false, fmt.Errorf("processHTTPValidation had no deadline")
 	} else {
 		deadline = deadline.Add(-200 * time.Millisecond)
 	}
@@ -466,11 +903,20 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 	// because it's a one-line fix with no downside. We're not likely to want to
 	// do many more things to satisfy misunderstandings around HTTP.
 	initialReq.Header.Set("Accept", "*/*")
+	// Ask any caches between us and the origin server not to answer from a
+	// stored response. Some CDNs keep serving a previously fetched (and
+	// incorrect) challenge response for minutes after the subscriber has fixed
+	// the file on the origin.
+	initialReq.Header.Set("Cache-Control", "no-cache")
+	initialReq.Header.Set("Pragma", "no-cache")
+	for name, value := range va.httpHeaders {
+		initialReq.Header.Set(name, value)
+	}
 
 	// Set up the initial validation request and a base validation record
 	dialer, baseRecord, err := va.setupHTTPValidation(initialReq.URL.String(), target)
 	if err != nil {
-		return nil, []core.ValidationRecord{}, newIPError(target.cur, err)
+		return nil, []core.ValidationRecord{}, false, newIPError(target.cur, err)
 	}
 
 	// Build a transport for this validation that will use the preresolvedDialer's
@@ -487,18 +933,16 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 	numRedirects := 0
 	processRedirect := func(req *http.Request, via []*http.Request) error {
 		va.log.Debugf("processing a HTTP redirect from the server to %q", req.URL.String())
+		records[len(records)-1].Protocol = req.Response.Proto
 		// Only process up to maxRedirect redirects
 		if numRedirects > maxRedirect {
-			return berrors.ConnectionFailureError("Too many redirects")
+			return newCatalogError(berrors.ConnectionFailureError, msgRedirectTooMany)
 		}
 		numRedirects++
 		va.metrics.http01Redirects.Inc()
 
 		if req.Response.TLS != nil && req.Response.TLS.Version < tls.VersionTLS12 {
-			return berrors.ConnectionFailureError(
-				"validation attempt was redirected to an HTTPS server that doesn't " +
-					"support TLSv1.2 or better. See " +
-					"https://community.letsencrypt.org/t/rejecting-sha-1-csrs-and-validation-using-tls-1-0-1-1-urls/175144")
+			return newCatalogError(berrors.ConnectionFailureError, msgRedirectOldTLS)
 		}
 
 		// If the response contains an HTTP 303 or any other forbidden redirect,
@@ -511,23 +955,30 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 			301: {}, 302: {}, 307: {}, 308: {},
 		}
 		if _, present := acceptableRedirects[req.Response.StatusCode]; !present {
-			return berrors.ConnectionFailureError("received disallowed redirect status code")
+			return newCatalogError(berrors.ConnectionFailureError, msgRedirectDisallowedStatus)
 		}
 
 		// Lowercase the redirect host immediately, as the dialer and redirect
 		// validation expect it to have been lowercased already.
 		req.URL.Host = strings.ToLower(req.URL.Host)
 
+		// The http.Client copies headers to the redirected request, but drops
+		// some of them for redirects to other domains. Set the additional
+		// headers again so that they are sent on every hop regardless.
+		for name, value := range va.httpHeaders {
+			req.Header.Set(name, value)
+		}
+
 		// Extract the redirect target's host and port. This will return an error if
 		// the redirect request scheme, host or port is not acceptable.
-		redirHost, redirPort, err := va.extractRequestTarget(req)
+		redirHost, redirPort, err := va.extractRequestTarget(req, va.ports, origIP)
 		if err != nil {
 			return err
 		}
 
 		redirPath := req.URL.Path
 		if len(redirPath) > maxPathSize {
-			return berrors.ConnectionFailureError("Redirect target too long")
+			return newCatalogError(berrors.ConnectionFailureError, msgRedirectPathTooLong)
 		}
 
 		// If the redirect URL has query parameters we need to preserve
@@ -541,7 +992,7 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 		// redirect limit, return error.
 		for _, record := range records {
 			if req.URL.String() == record.URL {
-				return berrors.ConnectionFailureError("Redirect loop detected")
+				return newCatalogError(berrors.ConnectionFailureError, msgRedirectLoop)
 			}
 		}
 
@@ -575,6 +1026,16 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 		CheckRedirect: processRedirect,
 	}
 
+	// Time spent fetching excludes the time spent connecting, which the dialer
+	// records separately.
+	timings := phaseTimingsFrom(ctx)
+	fetchStart := va.clk.Now()
+	connectBefore := timings.get(phaseConnect)
+	defer func() {
+		connect := timings.get(phaseConnect) - connectBefore
+		timings.add(phaseFetch, max(va.clk.Since(fetchStart)-connect, 0))
+	}()
+
 	// Make the initial validation request. This may result in redirects being
 	// followed.
 	httpResponse, err := client.Do(initialReq)
@@ -585,14 +1046,14 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 		// have a fallback address to use and must return the original error.
 		advanceTargetIPErr := target.nextIP()
 		if advanceTargetIPErr != nil {
-			return nil, records, newIPError(records[len(records)-1].AddressUsed, err)
+			return nil, records, false, va.connectionFailure(records[len(records)-1], err)
 		}
 
 		// setup another validation to retry the target with the new IP and append
 		// the retry record.
 		retryDialer, retryRecord, err := va.setupHTTPValidation(initialReq.URL.String(), target)
 		if err != nil {
-			return nil, records, newIPError(records[len(records)-1].AddressUsed, err)
+			return nil, records, false, newIPError(records[len(records)-1].AddressUsed, err)
 		}
 
 		records = append(records, retryRecord)
@@ -605,60 +1066,258 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 		httpResponse, err = client.Do(initialReq)
 		// If the retry still failed there isn't anything more to do, return the
 		// error immediately.
-		if err != nil {
-			return nil, records, newIPError(retryRecord.AddressUsed, err)
+		if err != nil && fallbackErr(err) {
+			return nil, records, false, va.connectionFailure(retryRecord, err)
+		} else if err != nil {
+			return nil, records, false, newIPError(retryRecord.AddressUsed, err)
 		}
 	} else if err != nil {
 		// if the error was not a fallbackErr then return immediately.
-		return nil, records, newIPError(records[len(records)-1].AddressUsed, err)
+		return nil, records, false, newIPError(records[len(records)-1].AddressUsed, err)
+	}
+
+	records[len(records)-1].Protocol = httpResponse.Proto
+	httpResponseRecordFrom(ctx).set(records[len(records)-1].URL, httpResponse.StatusCode)
+
+	if httpResponse.StatusCode == http.StatusSwitchingProtocols {
+		// Closing the body, which is the upgraded connection, ensures that we
+		// don't hold the connection open waiting for a protocol we don't
+		// speak.
+		_ = httpResponse.Body.Close()
+		return nil, records, false, newIPError(records[len(records)-1].AddressUsed, newCatalogError(berrors.ConnectionFailureError, msgSwitchingProtocols,
+			records[len(records)-1].URL, httpResponse.Header.Get("Upgrade")))
 	}
 
 	if httpResponse.StatusCode != 200 {
-		return nil, records, newIPError(records[len(records)-1].AddressUsed, berrors.UnauthorizedError("Invalid response from %s: %d",
-			records[len(records)-1].URL, httpResponse.StatusCode))
+		err = newCatalogError(berrors.UnauthorizedError, msgResponseStatus,
+			records[len(records)-1].URL, httpResponse.StatusCode)
+		retryAfter, ok := retryAfterDelay(httpResponse, va.clk.Now())
+		if ok {
+			err = retryAfterError{retryAfter: retryAfter, err: err}
+		}
+		return nil, records, false, newIPError(records[len(records)-1].AddressUsed, err)
 	}
 
 	// At this point we've made a successful request (be it from a retry or
 	// otherwise) and can read and process the response body.
-	body, err := io.ReadAll(&io.LimitedReader{R: httpResponse.Body, N: maxResponseSize})
+	body, err := io.ReadAll(&io.LimitedReader{R: httpResponse.Body, N: maxMultipleKeyAuthorizationsSize})
 	closeErr := httpResponse.Body.Close()
 	if err == nil {
 		err = closeErr
 	}
 	if err != nil {
-		return nil, records, newIPError(records[len(records)-1].AddressUsed, berrors.UnauthorizedError("Error reading HTTP response body: %v", err))
+		return nil, records, false, newIPError(records[len(records)-1].AddressUsed, newCatalogError(berrors.UnauthorizedError, msgResponseReadFailed, err.Error()))
 	}
 
-	// io.LimitedReader will silently truncate a Reader so if the
-	// resulting payload is the same size as maxResponseSize fail
+	// Fail if the payload is larger than maxResponseSize. The body is
+	// returned alongside the error so that the caller can recognize several
+	// concatenated key authorizations.
 	if len(body) >= maxResponseSize {
-		return nil, records, newIPError(records[len(records)-1].AddressUsed, berrors.UnauthorizedError("Invalid response from %s: %q",
-			records[len(records)-1].URL, body))
+		return body, records, false, newIPError(records[len(records)-1].AddressUsed, newCatalogError(berrors.UnauthorizedError, msgResponseTooLarge,
+			records[len(records)-1].URL, string(body[:maxResponseSize])))
 	}
 
-	return body, records, nil
+	return body, records, responseFromCache(httpResponse), nil
 }
 
 func (va *ValidationAuthorityImpl) validateHTTP01(ctx context.Context, ident identifier.ACMEIdentifier, token string, keyAuthorization string) ([]core.ValidationRecord, error) {
 	if ident.Type != identifier.TypeDNS {
 		va.log.Infof("Got non-DNS identifier for HTTP validation: %s", ident)
-		return nil, berrors.MalformedError("Identifier type for HTTP validation was not DNS")
+		return nil, newCatalogError(berrors.MalformedError, msgHTTPIdentifierNotDNS)
 	}
 
 	// Perform the fetch
 	path := fmt.Sprintf(".well-known/acme-challenge/%s", token)
-	body, validationRecords, err := va.fetchHTTP(ctx, ident.Value, "/"+path)
+	body, validationRecords, cached, err := va.fetchHTTP(ctx, ident.Value, "/"+path, "")
+	var retryErr retryAfterError
+	if errors.As(err, &retryErr) && retryErr.retryAfter <= va.maxHTTPRetryAfter {
+		// Some hosting providers briefly return a 429 or 503 while the
+		// challenge file is being deployed. Honor a short Retry-After and try
+		// exactly once more, provided doing so fits within our deadline.
+		deadline, ok := ctx.Deadline()
+		if ok && time.Until(deadline) <= retryErr.retryAfter {
+			return validationRecords, err
+		}
+		va.log.Infof("Retrying HTTP-01 for %s after %s as requested by Retry-After", ident, retryErr.retryAfter)
+		timer := va.clk.NewTimer(retryErr.retryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return validationRecords, err
+		case <-timer.C:
+		}
+		va.metrics.http01RetryAfterRetries.Inc()
+		var retryRecords []core.ValidationRecord
+		body, retryRecords, cached, err = va.fetchHTTP(ctx, ident.Value, "/"+path, "")
+		if len(retryRecords) > 0 {
+			retryRecords[0].RetriedAfter = true
+		}
+		validationRecords = append(validationRecords, retryRecords...)
+	}
 	if err != nil {
+		if containsMultipleKeyAuthorizations(string(body), keyAuthorization) {
+			return validationRecords, va.multipleKeyAuthorizationsError(ident, keyAuthorization)
+		}
 		return validationRecords, err
 	}
-	payload := strings.TrimRightFunc(string(body), unicode.IsSpace)
+	strict := validationPolicyFrom(ctx).strictBody()
+	payload := http01Payload(body, strict)
+
+	if payload != keyAuthorization && cached {
+		// The mismatched response appears to have come from an intermediary
+		// cache which ignored our no-cache request headers. Retry exactly once
+		// with a query parameter the cache has never seen, which forces it to
+		// go back to the origin server.
+		va.metrics.http01CacheBustRetries.Inc()
+		va.log.Infof("Retrying HTTP-01 for %s with a cache-busting query parameter after a cached mismatch", ident)
+		query := cacheBustParam + "=" + core.RandomString(8)
+		retryBody, retryRecords, _, err := va.fetchHTTP(ctx, ident.Value, "/"+path, query)
+		if len(retryRecords) > 0 {
+			retryRecords[0].CacheBusted = true
+		}
+		validationRecords = append(validationRecords, retryRecords...)
+		if err != nil {
+			if containsMultipleKeyAuthorizations(string(retryBody), keyAuthorization) {
+				return validationRecords, va.multipleKeyAuthorizationsError(ident, keyAuthorization)
+			}
+			return validationRecords, err
+		}
+		payload = http01Payload(retryBody, strict)
+	}
 
+	if payload != keyAuthorization && containsMultipleKeyAuthorizations(payload, keyAuthorization) {
+		return validationRecords, va.multipleKeyAuthorizationsError(ident, keyAuthorization)
+	}
 	if payload != keyAuthorization {
-		problem := berrors.UnauthorizedError("The key authorization file from the server did not match this challenge. Expected %q (got %q)",
+		problem := newCatalogError(berrors.UnauthorizedError, msgKeyAuthorizationMismatch,
 			keyAuthorization, payload)
 		va.log.Infof("%s for %s", problem, ident)
 		return validationRecords, problem
 	}
 
+	// A response which was itself served over HTTPS, after a redirect, needs
+	// no further confirmation.
+	if va.requiresConfirmOverTLS(ctx, ident.Value) && !strings.HasPrefix(validationRecords[len(validationRecords)-1].URL, "https://") {
+		record, err := va.confirmHTTP01OverTLS(ctx, ident.Value, "/"+path, keyAuthorization)
+		if record != nil {
+			validationRecords = append(validationRecords, *record)
+		}
+		if err != nil {
+			va.log.Infof("%s for %s", err, ident)
+			return validationRecords, err
+		}
+	}
+
 	return validationRecords, nil
 }
+
+// confirmHTTP01OverTLS fetches path from host directly over HTTPS, without
+// following redirects or verifying the server's certificate, and returns an
+// error unless the response is the expected key authorization. It returns the
+// validation record for the request, if one was made.
+func (va *ValidationAuthorityImpl) confirmHTTP01OverTLS(ctx context.Context, host string, path string, keyAuthorization string) (*core.ValidationRecord, error) {
+	host = strings.ToLower(host)
+	confirmURL := url.URL{
+		Scheme: "https",
+		Host:   host,
+		Path:   path,
+	}
+	fetchFailed := func(err error) error {
+		// The VA being at its connection limit isn't the Subscriber's fault.
+		var busyErr targetBusyError
+		if errors.As(err, &busyErr) {
+			return err
+		}
+		return newCatalogError(berrors.UnauthorizedError, msgConfirmOverTLSFailed,
+			confirmURL.String(), detailedError(err).Detail)
+	}
+
+	target, err := va.newHTTPValidationTarget(ctx, host, va.ports.https, path, "")
+	if err != nil {
+		return nil, fetchFailed(err)
+	}
+	dialer, record, err := va.setupHTTPValidation(confirmURL.String(), target)
+	if err != nil {
+		return nil, fetchFailed(newIPError(target.cur, err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", confirmURL.String(), nil)
+	if err != nil {
+		return &record, fetchFailed(newIPError(record.AddressUsed, err))
+	}
+	if va.userAgent != "" {
+		req.Header.Set("User-Agent", va.userAgent)
+	}
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Pragma", "no-cache")
+	for name, value := range va.httpHeaders {
+		req.Header.Set(name, value)
+	}
+
+	va.log.AuditInfof("Confirming HTTP-01 for %q over HTTPS with GET to %q", host, confirmURL.String())
+	client := http.Client{
+		Transport: httpTransport(dialer.DialContext),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &record, fetchFailed(newIPError(record.AddressUsed, err))
+	}
+	record.Protocol = resp.Proto
+	body, err := io.ReadAll(&io.LimitedReader{R: resp.Body, N: maxResponseSize})
+	closeErr := resp.Body.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &record, fetchFailed(newIPError(record.AddressUsed, err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &record, newCatalogError(berrors.UnauthorizedError, msgConfirmOverTLSStatus,
+			confirmURL.String(), resp.StatusCode)
+	}
+
+	payload := http01Payload(body, validationPolicyFrom(ctx).strictBody())
+	if payload != keyAuthorization {
+		return &record, newCatalogError(berrors.UnauthorizedError, msgConfirmOverTLSMismatch,
+			confirmURL.String(), keyAuthorization, payload)
+	}
+	return &record, nil
+}
+
+// http01Payload returns the HTTP-01 response body to compare against the key
+// authorization. Trailing whitespace is trimmed, unless strict is true.
+func http01Payload(body []byte, strict bool) string {
+	if strict {
+		return string(body)
+	}
+	return strings.TrimRightFunc(string(body), unicode.IsSpace)
+}
+
+// keyAuthorizationPattern matches strings shaped like a key authorization: a
+// token and a base64url encoded SHA-256 JWK thumbprint separated by a ".".
+var keyAuthorizationPattern = regexp.MustCompile(`[A-Za-z0-9_-]{22,}\.[A-Za-z0-9_-]{43}`)
+
+// containsMultipleKeyAuthorizations returns true if body contains the expected
+// key authorization alongside at least one other string shaped like a key
+// authorization, as served by web server configurations which concatenate
+// every pending token into one response.
+func containsMultipleKeyAuthorizations(body string, keyAuthorization string) bool {
+	before, after, found := strings.Cut(body, keyAuthorization)
+	if !found {
+		return false
+	}
+	return keyAuthorizationPattern.MatchString(before + " " + after)
+}
+
+// multipleKeyAuthorizationsError returns the error for an HTTP-01 response
+// which contained the expected key authorization among others.
+func (va *ValidationAuthorityImpl) multipleKeyAuthorizationsError(ident identifier.ACMEIdentifier, keyAuthorization string) error {
+	problem := newCatalogError(berrors.UnauthorizedError, msgKeyAuthorizationMultiple,
+		keyAuthorization)
+	va.log.Infof("%s for %s", problem, ident)
+	return problem
+}
@@ -0,0 +1,150 @@
+package va
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/letsencrypt/boulder/core"
+)
+
+// internalPerspective labels the validation latency of validations and CAA
+// checks for internal identifiers, which are performed by the primary VA
+// alone, in place of allPerspectives.
+const internalPerspective = "internal"
+
+// InternalIdentifiers are the identifiers for which the primary VA skips
+// remote corroboration, because they're only reachable from the primary's
+// network. Skipping corroboration does not satisfy the Multi-Perspective
+// Issuance Corroboration requirements of BRs Sections 3.2.2.9 and 5.4.1, so
+// it must never be enabled in a publicly trusted deployment.
+type InternalIdentifiers struct {
+	// InsecureInternalIssuance must be set for Prefixes or Domains to be
+	// accepted.
+	InsecureInternalIssuance bool
+	// Prefixes are the address ranges which identifiers must resolve to. An
+	// identifier is internal if every address it resolves to is within one
+	// of them.
+	Prefixes []netip.Prefix
+	// Domains are internal, along with all of their subdomains.
+	Domains []string
+}
+
+// validate returns a normalized copy of internal, or an error if it's
+// configured without InsecureInternalIssuance or for a remote VA.
+func (internal InternalIdentifiers) validate(perspective string) (InternalIdentifiers, error) {
+	if len(internal.Prefixes) == 0 && len(internal.Domains) == 0 {
+		return InternalIdentifiers{}, nil
+	}
+	if !internal.InsecureInternalIssuance {
+		return InternalIdentifiers{}, errors.New("internal identifiers may not be configured without insecureInternalIssuance")
+	}
+	if perspective != PrimaryPerspective {
+		return InternalIdentifiers{}, fmt.Errorf("only the primary VA may have internal identifiers, perspective %q has %d",
+			perspective, len(internal.Prefixes)+len(internal.Domains))
+	}
+
+	normalized := InternalIdentifiers{InsecureInternalIssuance: true}
+	for _, prefix := range internal.Prefixes {
+		if !prefix.IsValid() {
+			return InternalIdentifiers{}, fmt.Errorf("invalid internal identifier prefix %q", prefix)
+		}
+		normalized.Prefixes = append(normalized.Prefixes, prefix.Masked())
+	}
+	for _, domain := range internal.Domains {
+		domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+		_, ok := dns.IsDomainName(domain)
+		if !ok || domain == "" || strings.HasPrefix(domain, ".") || net.ParseIP(domain) != nil {
+			return InternalIdentifiers{}, fmt.Errorf("invalid internal identifier domain %q", domain)
+		}
+		normalized.Domains = append(normalized.Domains, domain)
+	}
+	return normalized, nil
+}
+
+// internalIdentifierRule returns the rule which makes name an internal
+// identifier, or "" if it isn't one. name matches a "domain:" rule if it is,
+// or is a subdomain of, one of the internal domains, and a "prefix:" rule if
+// every address it resolves to is within one of the internal prefixes. The
+// addresses are taken from records if they include any, and are otherwise
+// looked up.
+func (va *ValidationAuthorityImpl) internalIdentifierRule(ctx context.Context, name string, records []core.ValidationRecord) string {
+	if len(va.internal.Prefixes) == 0 && len(va.internal.Domains) == 0 {
+		return ""
+	}
+
+	name = strings.ToLower(name)
+	for _, domain := range va.internal.Domains {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return "domain:" + domain
+		}
+	}
+	if len(va.internal.Prefixes) == 0 {
+		return ""
+	}
+
+	var addrs []net.IP
+	for _, record := range records {
+		addrs = append(addrs, record.AddressesResolved...)
+	}
+	if len(addrs) == 0 {
+		var err error
+		addrs, _, err = va.getAddrs(ctx, name)
+		if err != nil {
+			return ""
+		}
+	}
+
+	var matched []string
+	for _, ip := range addrs {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			return ""
+		}
+		addr = addr.Unmap()
+		i := -1
+		for j, prefix := range va.internal.Prefixes {
+			if prefix.Contains(addr) {
+				i = j
+				break
+			}
+		}
+		if i == -1 {
+			// Addresses outside the internal prefixes are reachable by the
+			// remote perspectives, so they must corroborate.
+			return ""
+		}
+		rule := "prefix:" + va.internal.Prefixes[i].String()
+		if !slices.Contains(matched, rule) {
+			matched = append(matched, rule)
+		}
+	}
+	return strings.Join(matched, ",")
+}
+
+// skipRemoteCorroboration returns the rule which makes name an internal
+// identifier, for which remote corroboration is skipped, or "" if it isn't
+// one. Each skip is audit logged.
+func (va *ValidationAuthorityImpl) skipRemoteCorroboration(ctx context.Context, op, name string, records []core.ValidationRecord) string {
+	rule := va.internalIdentifierRule(ctx, name, records)
+	if rule != "" {
+		va.log.AuditInfof("MPIC skipped: internal identifier: operation=%s identifier=%q rule=%q", op, name, rule)
+	}
+	return rule
+}
+
+// totalPerspective returns the perspective label of the total latency of a
+// validation or CAA check by the primary VA, given the internal identifier
+// rule which skipped its remote corroboration, if any.
+func totalPerspective(internalRule string) string {
+	if internalRule != "" {
+		return internalPerspective
+	}
+	return allPerspectives
+}
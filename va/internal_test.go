@@ -0,0 +1,168 @@
+package va
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/test"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+func TestInternalIdentifiersValidate(t *testing.T) {
+	t.Parallel()
+
+	internal, err := InternalIdentifiers{}.validate("dadaist")
+	test.AssertNotError(t, err, "empty internal identifiers should be valid for any perspective")
+	test.AssertDeepEquals(t, internal, InternalIdentifiers{})
+
+	internal, err = InternalIdentifiers{
+		InsecureInternalIssuance: true,
+		Prefixes:                 []netip.Prefix{netip.MustParsePrefix("10.1.2.3/8")},
+		Domains:                  []string{"Corp.Example."},
+	}.validate(PrimaryPerspective)
+	test.AssertNotError(t, err, "validating internal identifiers")
+	test.AssertDeepEquals(t, internal.Prefixes, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	test.AssertDeepEquals(t, internal.Domains, []string{"corp.example"})
+
+	// Without the flag, no rules are accepted, and the flag alone enables
+	// nothing.
+	_, err = InternalIdentifiers{Domains: []string{"corp.example"}}.validate(PrimaryPerspective)
+	test.AssertError(t, err, "internal domains were accepted without insecureInternalIssuance")
+	_, err = InternalIdentifiers{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}.validate(PrimaryPerspective)
+	test.AssertError(t, err, "internal prefixes were accepted without insecureInternalIssuance")
+	internal, err = InternalIdentifiers{InsecureInternalIssuance: true}.validate(PrimaryPerspective)
+	test.AssertNotError(t, err, "validating internal identifiers")
+	test.Assert(t, !internal.InsecureInternalIssuance, "insecureInternalIssuance should be dropped without any rules")
+
+	_, err = InternalIdentifiers{InsecureInternalIssuance: true, Domains: []string{"corp.example"}}.validate("dadaist")
+	test.AssertError(t, err, "internal identifiers were accepted for a remote VA")
+	_, err = InternalIdentifiers{InsecureInternalIssuance: true, Domains: []string{"10.0.0.1"}}.validate(PrimaryPerspective)
+	test.AssertError(t, err, "an IP address was accepted as an internal domain")
+	_, err = InternalIdentifiers{InsecureInternalIssuance: true, Prefixes: []netip.Prefix{{}}}.validate(PrimaryPerspective)
+	test.AssertError(t, err, "an invalid prefix was accepted")
+}
+
+func TestInternalIdentifierRule(t *testing.T) {
+	t.Parallel()
+
+	va, _ := setup(nil, "", nil, nil)
+	internal, err := InternalIdentifiers{
+		InsecureInternalIssuance: true,
+		Prefixes: []netip.Prefix{
+			netip.MustParsePrefix("127.0.0.0/8"),
+			netip.MustParsePrefix("10.0.0.0/8"),
+		},
+		Domains: []string{"corp.example"},
+	}.validate(PrimaryPerspective)
+	test.AssertNotError(t, err, "validating internal identifiers")
+	va.internal = internal
+
+	testCases := []struct {
+		name     string
+		ident    string
+		records  []core.ValidationRecord
+		expected string
+	}{
+		{"internal domain", "corp.example", nil, "domain:corp.example"},
+		{"internal subdomain", "WWW.Corp.Example", nil, "domain:corp.example"},
+		{"other domain resolving to an internal prefix", "notcorp.example", nil, "prefix:127.0.0.0/8"},
+		{"resolves to an internal prefix", "letsencrypt.org", nil, "prefix:127.0.0.0/8"},
+		{"resolves partly to an internal prefix", "ipv4.and.ipv6.localhost", nil, ""},
+		{"doesn't resolve", "always.nxdomain", nil, ""},
+		{
+			name:     "records within internal prefixes",
+			ident:    "always.error",
+			records:  []core.ValidationRecord{{AddressesResolved: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("127.0.0.1")}}},
+			expected: "prefix:10.0.0.0/8,prefix:127.0.0.0/8",
+		},
+		{
+			name:     "records partly outside internal prefixes",
+			ident:    "letsencrypt.org",
+			records:  []core.ValidationRecord{{AddressesResolved: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("192.0.2.1")}}},
+			expected: "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			test.AssertEquals(t, va.internalIdentifierRule(ctx, tc.ident, tc.records), tc.expected)
+		})
+	}
+
+	// Without any rules, nothing is internal.
+	empty, _ := setup(nil, "", nil, nil)
+	test.AssertEquals(t, empty.internalIdentifierRule(ctx, "letsencrypt.org", nil), "")
+}
+
+func TestInternalIdentifierSkipsRemotes(t *testing.T) {
+	t.Parallel()
+
+	ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true})
+	defer ms.Close()
+
+	broken := RemoteClients{VAClient: brokenRemoteVA{}, CAAClient: brokenRemoteVA{}}
+	remotes := []remoteConf{
+		{rir: arin, impl: broken},
+		{rir: ripe, impl: broken},
+		{rir: apnic, impl: broken},
+	}
+
+	testCases := []struct {
+		name          string
+		internal      InternalIdentifiers
+		expectSkipped string
+	}{
+		{"unmatched", InternalIdentifiers{InsecureInternalIssuance: true, Domains: []string{"corp.example"}}, ""},
+		{"matched domain", InternalIdentifiers{InsecureInternalIssuance: true, Domains: []string{"letsencrypt.org"}}, "domain:letsencrypt.org"},
+		{"matched prefix", InternalIdentifiers{InsecureInternalIssuance: true, Prefixes: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}, "prefix:127.0.0.0/8"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			va, mockLog := setupWithRemotes(ms.Server, pass, remotes, nil)
+			va.internal = tc.internal
+
+			expectedPerspective := allPerspectives
+			if tc.expectSkipped != "" {
+				expectedPerspective = internalPerspective
+			}
+
+			res, err := va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+			test.AssertNotError(t, err, "performing validation")
+			caaRes, err := va.DoCAA(ctx, &vapb.IsCAAValidRequest{
+				Domain:           "letsencrypt.org",
+				ValidationMethod: string(core.ChallengeTypeHTTP01),
+				AccountURIID:     1,
+			})
+			test.AssertNotError(t, err, "performing CAA check")
+
+			if tc.expectSkipped == "" {
+				// The broken remotes fail both operations.
+				test.AssertNotNil(t, res.Problem, "validation should have failed")
+				test.AssertNotNil(t, caaRes.Problem, "CAA check should have failed")
+				test.AssertEquals(t, len(mockLog.GetAllMatching("MPIC skipped")), 0)
+			} else {
+				test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed with: %#v", res.Problem))
+				test.Assert(t, caaRes.Problem == nil, "CAA check should have succeeded")
+				skipped := mockLog.GetAllMatching(`MPIC skipped: internal identifier: operation=(dcv|caa) identifier="letsencrypt.org" rule="` + tc.expectSkipped + `"`)
+				test.AssertEquals(t, len(skipped), 2)
+				event := parseValidationLogEvent(t, mockLog.GetAllMatching(`Validation result JSON=`))
+				test.AssertEquals(t, event.InternalIdentifierRule, tc.expectSkipped)
+				test.AssertEquals(t, event.Summary.SkippedReason, mpicSkippedInternalIdentifier)
+				test.AssertEquals(t, event.Summary.QuorumResult, "0/0")
+				test.AssertMetricWithLabelsEquals(t, va.metrics.mpicSkipped, prometheus.Labels{"reason": mpicSkippedInternalIdentifier}, 2)
+			}
+
+			for _, op := range []string{opDCV, opCAA} {
+				test.AssertMetricWithLabelsEquals(t, va.metrics.validationLatency, prometheus.Labels{
+					"operation":   op,
+					"perspective": expectedPerspective,
+				}, 1)
+			}
+		})
+	}
+}
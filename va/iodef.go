@@ -0,0 +1,322 @@
+package va
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/bdns"
+	blog "github.com/letsencrypt/boulder/log"
+	bmail "github.com/letsencrypt/boulder/mail"
+)
+
+const (
+	// defaultIODEFMinInterval is the least time between reports to the same
+	// destination, if IODEFConfig.MinInterval is unset.
+	defaultIODEFMinInterval = time.Hour
+
+	// defaultIODEFTimeout bounds the sending of each report, if
+	// IODEFConfig.Timeout is unset.
+	defaultIODEFTimeout = 10 * time.Second
+)
+
+// IODEFConfig configures the reporting of CAA policy violations to the iodef
+// URLs (RFC 8659 Section 4.4) of the Relevant RRset. Reports are only sent to
+// mailto: and https: URLs. Reporting is disabled if QueueSize is zero.
+type IODEFConfig struct {
+	// QueueSize is the number of reports which may be waiting to be sent.
+	// Reports which don't fit are dropped, so that reporting never delays a
+	// CAA check.
+	QueueSize int
+	// MinInterval is the least time between reports to the same destination,
+	// the address of a mailto: URL or the host of an https: URL. Reports which
+	// arrive sooner are dropped. Defaults to 1h.
+	MinInterval time.Duration
+	// Timeout bounds the sending of each report. Defaults to 10s.
+	Timeout time.Duration
+	// Mailer, if set, sends the reports to mailto: URLs. Otherwise those
+	// reports are dropped.
+	Mailer bmail.Mailer
+	// AccountIDHashKey is the HMAC-SHA256 key with which account IDs are
+	// hashed, so that reports can't be linked to accounts by anyone without
+	// it. It's required if reporting is enabled.
+	AccountIDHashKey []byte
+}
+
+// iodefReport is the JSON body of a report of a CAA policy violation. It's a
+// much simplified form of an RFC 7970 incident report.
+type iodefReport struct {
+	// Domain is the identifier, without any wildcard prefix, for which
+	// issuance was requested.
+	Domain string `json:"domain"`
+	// Time is when the CAA check was made.
+	Time time.Time `json:"time"`
+	// Issuer is the CAA issuer domain of the reporting CA.
+	Issuer string `json:"issuer"`
+	// AccountIDHash is the hex-encoded HMAC-SHA256, keyed with
+	// IODEFConfig.AccountIDHashKey, of the decimal ID of the account which
+	// requested issuance.
+	AccountIDHash string `json:"accountIDHash"`
+	// ViolatedProperty is the tag of the CAA property which refused issuance.
+	ViolatedProperty string `json:"violatedProperty"`
+}
+
+// iodefDelivery is a report waiting to be sent to dest.
+type iodefDelivery struct {
+	dest   *url.URL
+	report iodefReport
+}
+
+// iodefReporter sends reports of CAA policy violations asynchronously, from a
+// bounded queue, with per-destination rate limiting.
+type iodefReporter struct {
+	queue       chan iodefDelivery
+	minInterval time.Duration
+	timeout     time.Duration
+	client      *http.Client
+	mailer      bmail.Mailer
+	hashKey     []byte
+	resolver    bdns.Client
+	clk         clock.Clock
+	log         blog.Logger
+	reports     *prometheus.CounterVec
+	// connect dials an address which dial has checked. It's replaced in
+	// tests, which can't listen on a public address and port 443.
+	connect func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// mu guards lastSent, the time at which a report was last queued for each
+	// destination.
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// newIODEFReporter returns an *iodefReporter, whose sender has been started,
+// or nil if conf disables reporting. HTTPS reports are only sent to port 443
+// of hosts which resolver resolves to a public address.
+func newIODEFReporter(conf IODEFConfig, resolver bdns.Client, clk clock.Clock, logger blog.Logger, reports *prometheus.CounterVec) *iodefReporter {
+	if conf.QueueSize == 0 {
+		return nil
+	}
+	if conf.MinInterval == 0 {
+		conf.MinInterval = defaultIODEFMinInterval
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = defaultIODEFTimeout
+	}
+
+	r := &iodefReporter{
+		queue:       make(chan iodefDelivery, conf.QueueSize),
+		minInterval: conf.MinInterval,
+		timeout:     conf.Timeout,
+		mailer:      conf.Mailer,
+		hashKey:     conf.AccountIDHashKey,
+		resolver:    resolver,
+		clk:         clk,
+		log:         logger,
+		reports:     reports,
+		connect:     (&net.Dialer{}).DialContext,
+		lastSent:    make(map[string]time.Time),
+	}
+	r.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext:       r.dial,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	go r.run()
+	return r
+}
+
+// dial connects to addr, which must be port 443 of a host name. The host is
+// resolved by the VA's resolver, as for HTTP-01, and reserved addresses are
+// refused even if the resolver permits them, so that reports can't be
+// directed at internal services.
+func (r *iodefReporter) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if port != "443" {
+		return nil, fmt.Errorf("refusing to dial port %s", port)
+	}
+	addrs, _, err := r.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range addrs {
+		if !bdns.IsReservedIP(ip) {
+			return r.connect(ctx, network, net.JoinHostPort(ip.String(), port))
+		}
+	}
+	return nil, fmt.Errorf("no public addresses found for %q", host)
+}
+
+// iodefURLs returns the values of the iodef properties among rrs.
+func iodefURLs(rrs []*dns.CAA) []string {
+	var urls []string
+	for _, rr := range rrs {
+		if strings.EqualFold(rr.Tag, "iodef") {
+			urls = append(urls, rr.Value)
+		}
+	}
+	return urls
+}
+
+// iodefDestination returns the destination of u, to which reports are rate
+// limited, or an error if reports can't be sent to u.
+func iodefDestination(u *url.URL) (string, error) {
+	switch u.Scheme {
+	case "mailto":
+		addr, err := mail.ParseAddress(u.Opaque)
+		if err != nil {
+			return "", fmt.Errorf("invalid mailto address: %w", err)
+		}
+		return "mailto:" + strings.ToLower(addr.Address), nil
+	case "https":
+		host := u.Hostname()
+		if host == "" || net.ParseIP(host) != nil {
+			return "", errors.New("https URL must name a host")
+		}
+		if u.Port() != "" && u.Port() != "443" {
+			return "", errors.New("https URL must use port 443")
+		}
+		return "https://" + strings.ToLower(host), nil
+	}
+	return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+}
+
+// enqueue queues report to be sent to each of the iodef URLs rawURLs, without
+// ever blocking. Reports to URLs which are unsupported, or whose destination
+// was sent a report within the last minInterval, or which don't fit in the
+// queue, are dropped.
+func (r *iodefReporter) enqueue(rawURLs []string, report iodefReport) {
+	for _, rawURL := range rawURLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			r.reports.WithLabelValues("", "unsupported").Inc()
+			continue
+		}
+		dest, err := iodefDestination(u)
+		if err != nil || (u.Scheme == "mailto" && r.mailer == nil) {
+			r.reports.WithLabelValues(u.Scheme, "unsupported").Inc()
+			continue
+		}
+
+		r.mu.Lock()
+		now := r.clk.Now()
+		last, ok := r.lastSent[dest]
+		limited := ok && now.Sub(last) < r.minInterval
+		if !limited {
+			r.lastSent[dest] = now
+			// Forget destinations which are no longer limited, so that
+			// lastSent doesn't grow without bound.
+			if len(r.lastSent) > 2*cap(r.queue) {
+				for d, t := range r.lastSent {
+					if now.Sub(t) >= r.minInterval {
+						delete(r.lastSent, d)
+					}
+				}
+			}
+		}
+		r.mu.Unlock()
+		if limited {
+			r.reports.WithLabelValues(u.Scheme, "rate_limited").Inc()
+			continue
+		}
+
+		select {
+		case r.queue <- iodefDelivery{dest: u, report: report}:
+		default:
+			r.reports.WithLabelValues(u.Scheme, "dropped").Inc()
+		}
+	}
+}
+
+// run sends the queued reports, one at a time, forever. Failures are logged.
+func (r *iodefReporter) run() {
+	for d := range r.queue {
+		err := r.send(d)
+		if err != nil {
+			r.log.Warningf("Failed to send CAA iodef report for %s to %q: %s", d.report.Domain, d.dest, err)
+			r.reports.WithLabelValues(d.dest.Scheme, "failed").Inc()
+			continue
+		}
+		r.reports.WithLabelValues(d.dest.Scheme, "sent").Inc()
+	}
+}
+
+// send sends a single report, by email or HTTPS POST as d.dest requires.
+func (r *iodefReporter) send(d iodefDelivery) error {
+	body, err := json.Marshal(d.report)
+	if err != nil {
+		return err
+	}
+
+	if d.dest.Scheme == "mailto" {
+		conn, err := r.mailer.Connect()
+		if err != nil {
+			return fmt.Errorf("connecting to mail server: %w", err)
+		}
+		defer conn.Close()
+		addr, err := mail.ParseAddress(d.dest.Opaque)
+		if err != nil {
+			return err
+		}
+		return conn.SendMail([]string{addr.Address}, "CAA policy violation report for "+d.report.Domain, string(body))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.dest.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// reportCAAViolation queues a report that a CAA check of domain, for the
+// account accountID, was refused by the violated property of caaSet to each of
+// caaSet's iodef URLs. It does nothing if reporting is disabled.
+func (va *ValidationAuthorityImpl) reportCAAViolation(caaSet *caaResult, domain string, accountID int64, violated string) {
+	if va.iodef == nil || caaSet == nil || len(caaSet.iodef) == 0 {
+		return
+	}
+	mac := hmac.New(sha256.New, va.iodef.hashKey)
+	mac.Write([]byte(strconv.FormatInt(accountID, 10)))
+	va.iodef.enqueue(caaSet.iodef, iodefReport{
+		Domain:           domain,
+		Time:             va.clk.Now().UTC(),
+		Issuer:           va.issuerDomain,
+		AccountIDHash:    hex.EncodeToString(mac.Sum(nil)),
+		ViolatedProperty: violated,
+	})
+}
@@ -0,0 +1,264 @@
+package va
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/bdns"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/identifier"
+	"github.com/letsencrypt/boulder/mail"
+	"github.com/letsencrypt/boulder/mocks"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// iodefMockDNS serves the CAA records in records, resolves every host to a
+// public address except those under internal.example, and otherwise behaves
+// like caaMockDNS.
+type iodefMockDNS struct {
+	caaMockDNS
+	records map[string][]*dns.CAA
+}
+
+func (mock iodefMockDNS) LookupHost(_ context.Context, hostname string) ([]net.IP, bdns.ResolverAddrs, error) {
+	ip := net.ParseIP("93.184.215.14")
+	if strings.HasSuffix(hostname, "internal.example") {
+		ip = net.ParseIP("10.0.0.1")
+	}
+	return []net.IP{ip}, bdns.ResolverAddrs{{Addr: "iodefMockDNS", Qtype: "A"}}, nil
+}
+
+func (mock iodefMockDNS) LookupCAA(ctx context.Context, domain string) ([]*dns.CAA, string, bdns.ResolverAddrs, error) {
+	records, ok := mock.records[strings.TrimRight(domain, ".")]
+	if !ok {
+		return mock.caaMockDNS.LookupCAA(ctx, domain)
+	}
+	return records, "", bdns.ResolverAddrs{{Addr: "iodefMockDNS", Qtype: "CAA"}}, nil
+}
+
+// setupIODEF returns a VA which reports CAA violations to the iodef URLs of
+// records, emailing reports with conf's mailer. HTTPS reports are delivered to
+// hs, whatever address they're dialed at, and hs's certificate is trusted.
+func setupIODEF(t *testing.T, conf IODEFConfig, records map[string][]*dns.CAA, hs *httptest.Server) *ValidationAuthorityImpl {
+	t.Helper()
+	va, _ := setup(nil, "", nil, nil)
+	va.dnsClient = iodefMockDNS{records: records}
+	conf.AccountIDHashKey = []byte("iodef test key")
+	va.iodef = newIODEFReporter(conf, va.dnsClient, va.clk, va.log, va.metrics.iodefReports)
+	if hs != nil {
+		roots := x509.NewCertPool()
+		roots.AddCert(hs.Certificate())
+		va.iodef.client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: roots}
+		va.iodef.connect = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, hs.Listener.Addr().String())
+		}
+	}
+	return va
+}
+
+func caaRecords(tagValues ...string) []*dns.CAA {
+	var records []*dns.CAA
+	for i := 0; i < len(tagValues); i += 2 {
+		records = append(records, &dns.CAA{Tag: tagValues[i], Value: tagValues[i+1]})
+	}
+	return records
+}
+
+// waitForMessages waits for mailer to have sent n messages, and returns them.
+func waitForMessages(t *testing.T, mailer *mocks.Mailer, n int) []mocks.MailerMessage {
+	t.Helper()
+	for range 100 {
+		mailer.Lock()
+		messages := slices.Clone(mailer.Messages)
+		mailer.Unlock()
+		if len(messages) >= n {
+			return messages
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d messages", n)
+	return nil
+}
+
+func TestIODEFReports(t *testing.T) {
+	t.Parallel()
+
+	reports := make(chan iodefReport, 10)
+	hs := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report iodefReport
+		err := json.NewDecoder(r.Body).Decode(&report)
+		if err != nil || r.URL.Path != "/report" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reports <- report
+	}))
+	defer hs.Close()
+	reportURL := "https://example.com/report"
+
+	mailer := &mocks.Mailer{}
+	va := setupIODEF(t, IODEFConfig{QueueSize: 10, MinInterval: time.Hour, Mailer: mailer}, map[string][]*dns.CAA{
+		"refused.com":  caaRecords("issue", "other-ca.com", "iodef", reportURL, "iodef", "mailto:security@refused.com", "iodef", "ftp://refused.com"),
+		"wildcard.com": caaRecords("issue", "letsencrypt.org", "issuewild", ";", "iodef", reportURL),
+		"allowed.com":  caaRecords("issue", "letsencrypt.org", "iodef", reportURL),
+	}, hs)
+	params := &caaParams{accountURIID: 12345, validationMethod: core.ChallengeTypeHTTP01}
+
+	err := va.checkCAA(ctx, identifier.NewDNS("refused.com"), params)
+	test.AssertError(t, err, "CAA should refuse issuance")
+	report := <-reports
+	test.AssertEquals(t, report.Domain, "refused.com")
+	test.AssertEquals(t, report.Time, va.clk.Now().UTC())
+	test.AssertEquals(t, report.Issuer, "letsencrypt.org")
+	test.AssertEquals(t, report.AccountIDHash, "9dab75acfc17e966fe284bb15967259f56013966819e4b45a3ba2ecb2c1924b5")
+	test.AssertEquals(t, report.ViolatedProperty, "issue")
+
+	// The report is also emailed, once the sender gets to it, and the
+	// unsupported URL is skipped.
+	messages := waitForMessages(t, mailer, 1)
+	test.AssertEquals(t, messages[0].To, "security@refused.com")
+	test.AssertEquals(t, messages[0].Subject, "CAA policy violation report for refused.com")
+	test.AssertContains(t, messages[0].Body, `"violatedProperty":"issue"`)
+	test.AssertMetricWithLabelsEquals(t, va.metrics.iodefReports, prometheus.Labels{"scheme": "ftp", "result": "unsupported"}, 1)
+
+	// Further violations reported to the same destinations within the
+	// minimum interval are dropped, whatever the domain.
+	err = va.checkCAA(ctx, identifier.NewDNS("*.wildcard.com"), params)
+	test.AssertError(t, err, "CAA should refuse issuance")
+	test.AssertMetricWithLabelsEquals(t, va.metrics.iodefReports, prometheus.Labels{"scheme": "https", "result": "rate_limited"}, 1)
+
+	// Issuance which CAA permits is never reported.
+	err = va.checkCAA(ctx, identifier.NewDNS("allowed.com"), params)
+	test.AssertNotError(t, err, "CAA should permit issuance")
+
+	va.clk.(clock.FakeClock).Add(time.Hour)
+	err = va.checkCAA(ctx, identifier.NewDNS("*.wildcard.com"), params)
+	test.AssertError(t, err, "CAA should refuse issuance")
+	report = <-reports
+	test.AssertEquals(t, report.Domain, "wildcard.com")
+	test.AssertEquals(t, report.ViolatedProperty, "issuewild")
+
+	select {
+	case report := <-reports:
+		t.Errorf("unexpected report for %s", report.Domain)
+	default:
+	}
+}
+
+// blockingMailer is a mail.Mailer whose Connect blocks until release is
+// closed, after signalling on connecting.
+type blockingMailer struct {
+	mocks.Mailer
+	connecting chan struct{}
+	release    chan struct{}
+}
+
+func (m *blockingMailer) Connect() (mail.Conn, error) {
+	m.connecting <- struct{}{}
+	<-m.release
+	return m.Mailer.Connect()
+}
+
+func TestIODEFReportsDoNotBlock(t *testing.T) {
+	t.Parallel()
+
+	mailer := &blockingMailer{connecting: make(chan struct{}, 10), release: make(chan struct{})}
+	records := make(map[string][]*dns.CAA)
+	for i := range 4 {
+		records[fmt.Sprintf("refused%d.com", i)] = caaRecords("issue", "other-ca.com", "iodef", fmt.Sprintf("mailto:security@refused%d.com", i))
+	}
+	va := setupIODEF(t, IODEFConfig{QueueSize: 1, Mailer: mailer}, records, nil)
+	params := &caaParams{accountURIID: 12345, validationMethod: core.ChallengeTypeHTTP01}
+
+	// The sender is stuck on the first report, so the second waits in the
+	// queue, and the rest are dropped, without delaying the checks.
+	err := va.checkCAA(ctx, identifier.NewDNS("refused0.com"), params)
+	test.AssertError(t, err, "CAA should refuse issuance")
+	<-mailer.connecting
+	for i := 1; i < 4; i++ {
+		checked := make(chan error)
+		go func() {
+			checked <- va.checkCAA(ctx, identifier.NewDNS(fmt.Sprintf("refused%d.com", i)), params)
+		}()
+		select {
+		case err := <-checked:
+			test.AssertError(t, err, "CAA should refuse issuance")
+		case <-time.After(5 * time.Second):
+			t.Fatal("CAA check was blocked by iodef reporting")
+		}
+	}
+	test.AssertMetricWithLabelsEquals(t, va.metrics.iodefReports, prometheus.Labels{"scheme": "mailto", "result": "dropped"}, 2)
+
+	close(mailer.release)
+	<-mailer.connecting
+	messages := waitForMessages(t, &mailer.Mailer, 2)
+	test.AssertEquals(t, messages[0].To, "security@refused0.com")
+	test.AssertEquals(t, messages[1].To, "security@refused1.com")
+}
+
+func TestIODEFDestination(t *testing.T) {
+	t.Parallel()
+
+	for rawURL, want := range map[string]string{
+		"mailto:Security@Example.com":             "mailto:security@example.com",
+		"https://Example.com/report":              "https://example.com",
+		"https://example.com:443/other?q=1":       "https://example.com",
+		"https://example.com:8443/report":         "",
+		"http://example.com/report":               "",
+		"https://127.0.0.1/report":                "",
+		"mailto:not-an-address":                   "",
+		"tel:+15555555555":                        "",
+		"https:///report":                         "",
+		"https://[2001:db8::1]:443/report":        "",
+		"mailto:security@example.com?subject=CAA": "mailto:security@example.com",
+	} {
+		u, err := url.Parse(rawURL)
+		test.AssertNotError(t, err, "parsing URL")
+		got, err := iodefDestination(u)
+		if want == "" {
+			test.AssertError(t, err, fmt.Sprintf("expected %q to be refused", rawURL))
+			continue
+		}
+		test.AssertNotError(t, err, fmt.Sprintf("expected %q to be accepted", rawURL))
+		test.AssertEquals(t, got, want)
+	}
+}
+
+func TestIODEFDial(t *testing.T) {
+	t.Parallel()
+
+	va := setupIODEF(t, IODEFConfig{QueueSize: 1}, nil, nil)
+	var dialed []string
+	va.iodef.connect = func(_ context.Context, _, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, errors.New("not connecting")
+	}
+
+	_, err := va.iodef.dial(ctx, "tcp", "example.com:8443")
+	test.AssertError(t, err, "dialing a port other than 443 should fail")
+	test.AssertContains(t, err.Error(), "refusing to dial port 8443")
+
+	_, err = va.iodef.dial(ctx, "tcp", "reports.internal.example:443")
+	test.AssertError(t, err, "dialing a host with only reserved addresses should fail")
+	test.AssertContains(t, err.Error(), "no public addresses")
+
+	_, err = va.iodef.dial(ctx, "tcp", "example.com:443")
+	test.AssertError(t, err, "connect should have failed")
+	test.AssertDeepEquals(t, dialed, []string{"93.184.215.14:443"})
+}
@@ -0,0 +1,284 @@
+package va
+
+import (
+	"errors"
+	"fmt"
+)
+
+// messageID identifies a Subscriber facing problem detail message in
+// messageCatalog. IDs are audit logged, so that failures can be grouped by
+// message rather than by their rendered text, and documentation and
+// translations are keyed by them. An ID is never reused for a message with a
+// different meaning.
+type messageID string
+
+// Messages of the HTTP-01 challenge.
+const (
+	msgRedirectNoTarget          messageID = "http01.redirect.no-target"
+	msgRedirectScheme            messageID = "http01.redirect.scheme"
+	msgRedirectUserinfo          messageID = "http01.redirect.userinfo"
+	msgRedirectInvalidPort       messageID = "http01.redirect.invalid-port"
+	msgRedirectUnsupportedPort   messageID = "http01.redirect.unsupported-port"
+	msgRedirectEmptyHost         messageID = "http01.redirect.empty-host"
+	msgRedirectOtherIP           messageID = "http01.redirect.other-ip"
+	msgRedirectIP                messageID = "http01.redirect.ip"
+	msgRedirectWellKnownHost     messageID = "http01.redirect.well-known-host"
+	msgRedirectNotIANATLD        messageID = "http01.redirect.not-iana-tld"
+	msgRedirectHostnameTooLong   messageID = "http01.redirect.hostname-too-long"
+	msgRedirectEmptyLabel        messageID = "http01.redirect.empty-label"
+	msgRedirectLabelTooLong      messageID = "http01.redirect.label-too-long"
+	msgRedirectInvalidCharacters messageID = "http01.redirect.invalid-characters"
+	msgRedirectLabelHyphen       messageID = "http01.redirect.label-hyphen"
+	msgRedirectTooMany           messageID = "http01.redirect.too-many"
+	msgRedirectOldTLS            messageID = "http01.redirect.old-tls"
+	msgRedirectDisallowedStatus  messageID = "http01.redirect.disallowed-status"
+	msgRedirectPathTooLong       messageID = "http01.redirect.path-too-long"
+	msgRedirectLoop              messageID = "http01.redirect.loop"
+	msgSwitchingProtocols        messageID = "http01.response.switching-protocols"
+	msgResponseStatus            messageID = "http01.response.status"
+	msgResponseReadFailed        messageID = "http01.response.read-failed"
+	msgResponseTooLarge          messageID = "http01.response.too-large"
+	msgHTTPIdentifierNotDNS      messageID = "http01.identifier-not-dns"
+	msgKeyAuthorizationMismatch  messageID = "http01.key-authorization.mismatch"
+	msgKeyAuthorizationMultiple  messageID = "http01.key-authorization.multiple"
+	msgConfirmOverTLSFailed      messageID = "http01.confirm-over-tls.failed"
+	msgConfirmOverTLSStatus      messageID = "http01.confirm-over-tls.status"
+	msgConfirmOverTLSMismatch    messageID = "http01.confirm-over-tls.mismatch"
+)
+
+// Messages of address resolution and the DNS-01 challenge.
+const (
+	msgDNSNXDOMAIN            messageID = "dns.nxdomain"
+	msgDNSNoAddressRecords    messageID = "dns.no-address-records"
+	msgDNSNoValidAddresses    messageID = "dns.no-valid-addresses"
+	msgDNSIdentifierNotDNS    messageID = "dns01.identifier-not-dns"
+	msgDNSAccount01Disabled   messageID = "dns01.account-disabled"
+	msgTXTNotFound            messageID = "dns01.txt.not-found"
+	msgTXTIncorrect           messageID = "dns01.txt.incorrect"
+	msgTXTIncorrectAndMore    messageID = "dns01.txt.incorrect.and-more"
+	msgTXTIncorrectLowTTLHint messageID = "dns01.txt.incorrect.low-ttl-hint"
+)
+
+// message is the default English rendering of a messageID.
+type message struct {
+	// format is a fmt format string with one verb for each parameter.
+	format string
+
+	// params are the names of the message's parameters, in order, as they're
+	// audit logged.
+	params []string
+}
+
+// messageCatalog is the default English rendering of every messageID. Some
+// messages are fragments which are rendered into the parameters of others.
+var messageCatalog = map[messageID]message{
+	msgRedirectNoTarget: {
+		format: "Invalid redirect: no redirect target",
+	},
+	msgRedirectScheme: {
+		format: `Invalid protocol scheme in redirect target. Only "http" and "https" protocol schemes are supported, not %q`,
+		params: []string{"scheme"},
+	},
+	msgRedirectUserinfo: {
+		format: "Invalid redirect target. Userinfo is not permitted in redirect targets",
+	},
+	msgRedirectInvalidPort: {
+		format: "Invalid port in redirect target %q",
+		params: []string{"port"},
+	},
+	msgRedirectUnsupportedPort: {
+		format: "Invalid port in redirect target. Only ports %d and %d are supported, not %d",
+		params: []string{"httpPort", "httpsPort", "port"},
+	},
+	msgRedirectEmptyHost: {
+		format: "Invalid empty hostname in redirect target",
+	},
+	msgRedirectOtherIP: {
+		format: "Invalid host in redirect target %q. Only domain names and the IP address being validated, %s, are supported",
+		params: []string{"host", "validatedIP"},
+	},
+	msgRedirectIP: {
+		format: "Invalid host in redirect target %q. Only domain names are supported, not IP addresses",
+		params: []string{"host"},
+	},
+	msgRedirectWellKnownHost: {
+		format: "Invalid host in redirect target %q. Check webserver config for missing '/' in redirect target.",
+		params: []string{"host"},
+	},
+	msgRedirectNotIANATLD: {
+		format: "Invalid hostname in redirect target, must end in IANA registered TLD",
+	},
+	msgRedirectHostnameTooLong: {
+		format: "Invalid hostname in redirect target. Hostnames must be at most 253 bytes long",
+	},
+	msgRedirectEmptyLabel: {
+		format: "Invalid hostname in redirect target %q. Hostnames must not contain empty labels",
+		params: []string{"host"},
+	},
+	msgRedirectLabelTooLong: {
+		format: "Invalid hostname in redirect target %q. Labels must be at most 63 bytes long",
+		params: []string{"host"},
+	},
+	msgRedirectInvalidCharacters: {
+		format: "Invalid hostname in redirect target %q. Hostnames may only contain letters, digits, hyphens and dots",
+		params: []string{"host"},
+	},
+	msgRedirectLabelHyphen: {
+		format: "Invalid hostname in redirect target %q. Labels must not begin or end with a hyphen",
+		params: []string{"host"},
+	},
+	msgRedirectTooMany: {
+		format: "Too many redirects",
+	},
+	msgRedirectOldTLS: {
+		format: "validation attempt was redirected to an HTTPS server that doesn't support TLSv1.2 or better. See https://community.letsencrypt.org/t/rejecting-sha-1-csrs-and-validation-using-tls-1-0-1-1-urls/175144",
+	},
+	msgRedirectDisallowedStatus: {
+		format: "received disallowed redirect status code",
+	},
+	msgRedirectPathTooLong: {
+		format: "Redirect target too long",
+	},
+	msgRedirectLoop: {
+		format: "Redirect loop detected",
+	},
+	msgSwitchingProtocols: {
+		format: "Fetching %s: Server attempted to switch protocols to %q, but HTTP-01 challenges are only fetched using HTTP/1.1, or HTTP/2 over TLS",
+		params: []string{"url", "upgrade"},
+	},
+	msgResponseStatus: {
+		format: "Invalid response from %s: %d",
+		params: []string{"url", "status"},
+	},
+	msgResponseReadFailed: {
+		format: "Error reading HTTP response body: %v",
+		params: []string{"error"},
+	},
+	msgResponseTooLarge: {
+		format: "Invalid response from %s: %q",
+		params: []string{"url", "body"},
+	},
+	msgHTTPIdentifierNotDNS: {
+		format: "Identifier type for HTTP validation was not DNS",
+	},
+	msgKeyAuthorizationMismatch: {
+		format: "The key authorization file from the server did not match this challenge. Expected %q (got %q)",
+		params: []string{"expected", "got"},
+	},
+	msgKeyAuthorizationMultiple: {
+		format: "The response contained multiple key authorizations; serve only the one for this token. Expected %q",
+		params: []string{"expected"},
+	},
+	msgConfirmOverTLSFailed: {
+		format: "HTTP-01 validation of this name must be confirmed over HTTPS, but fetching %s failed: %s",
+		params: []string{"url", "detail"},
+	},
+	msgConfirmOverTLSStatus: {
+		format: "HTTP-01 validation of this name must be confirmed over HTTPS, but %s returned status %d",
+		params: []string{"url", "status"},
+	},
+	msgConfirmOverTLSMismatch: {
+		format: "HTTP-01 validation of this name must be confirmed over HTTPS, but the response from %s did not match the response over HTTP. Expected %q (got %q)",
+		params: []string{"url", "expected", "got"},
+	},
+	msgDNSNXDOMAIN: {
+		format: "DNS problem: %s does not exist (NXDOMAIN)",
+		params: []string{"hostname"},
+	},
+	msgDNSNoAddressRecords: {
+		format: "DNS problem: no A or AAAA records exist for %s",
+		params: []string{"hostname"},
+	},
+	msgDNSNoValidAddresses: {
+		format: "No valid IP addresses found for %s",
+		params: []string{"hostname"},
+	},
+	msgDNSIdentifierNotDNS: {
+		format: "Identifier type for DNS was not itself DNS",
+	},
+	msgDNSAccount01Disabled: {
+		format: "invalid challenge type %s",
+		params: []string{"challengeType"},
+	},
+	msgTXTNotFound: {
+		format: "No TXT record found at %s",
+		params: []string{"name"},
+	},
+	msgTXTIncorrect: {
+		format: "Incorrect TXT record %q%s found at %s%s",
+		params: []string{"record", "andMore", "name", "lowTTLHint"},
+	},
+	msgTXTIncorrectAndMore: {
+		format: " (and %d more)",
+		params: []string{"count"},
+	},
+	msgTXTIncorrectLowTTLHint: {
+		format: "; your record's TTL is %s; very low TTLs can cause perspectives to see stale data",
+		params: []string{"ttl"},
+	},
+}
+
+// renderMessage returns the default English rendering of id with params.
+func renderMessage(id messageID, params ...any) string {
+	m, ok := messageCatalog[id]
+	if !ok {
+		// This should never happen, TestMessageCatalog requires every
+		// messageID to have an entry.
+		return fmt.Sprintf("%s %v", id, params)
+	}
+	return fmt.Sprintf(m.format, params...)
+}
+
+// catalogError is a berrors error whose detail was rendered from
+// messageCatalog, which also carries the message's ID and parameters so that
+// they can be audit logged.
+type catalogError struct {
+	boulderErr error
+	id         messageID
+	params     []any
+}
+
+// newCatalogError returns an error made by newErr, e.g.
+// berrors.UnauthorizedError, whose detail is id rendered with params.
+func newCatalogError(newErr func(string, ...any) error, id messageID, params ...any) error {
+	return catalogError{
+		boulderErr: newErr("%s", renderMessage(id, params...)),
+		id:         id,
+		params:     params,
+	}
+}
+
+func (e catalogError) Error() string {
+	return e.boulderErr.Error()
+}
+
+func (e catalogError) Unwrap() error {
+	return e.boulderErr
+}
+
+// messageRecord is the audit logged form of a catalogError.
+type messageRecord struct {
+	ID     messageID
+	Params map[string]any `json:",omitempty"`
+}
+
+// messageRecordFor returns the messageRecord of the first catalogError in
+// err's tree, or nil if there is none.
+func messageRecordFor(err error) *messageRecord {
+	var catErr catalogError
+	if !errors.As(err, &catErr) {
+		return nil
+	}
+	record := &messageRecord{ID: catErr.id}
+	names := messageCatalog[catErr.id].params
+	for i, param := range catErr.params {
+		if i >= len(names) {
+			break
+		}
+		if record.Params == nil {
+			record.Params = make(map[string]any, len(names))
+		}
+		record.Params[names[i]] = param
+	}
+	return record
+}
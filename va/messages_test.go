@@ -0,0 +1,226 @@
+package va
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/test"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite the golden output of TestMessageCatalogGolden")
+
+// messageExamples are the parameters each message is rendered with in
+// testdata/messages.golden. Their types are the types of the parameters.
+var messageExamples = map[messageID][]any{
+	msgRedirectNoTarget:          nil,
+	msgRedirectScheme:            {"ftp"},
+	msgRedirectUserinfo:          nil,
+	msgRedirectInvalidPort:       {"eighty"},
+	msgRedirectUnsupportedPort:   {80, 443, 8080},
+	msgRedirectEmptyHost:         nil,
+	msgRedirectOtherIP:           {"10.0.0.2", "10.0.0.1"},
+	msgRedirectIP:                {"10.0.0.2"},
+	msgRedirectWellKnownHost:     {"example.com.well-known"},
+	msgRedirectNotIANATLD:        nil,
+	msgRedirectHostnameTooLong:   nil,
+	msgRedirectEmptyLabel:        {"example..com"},
+	msgRedirectLabelTooLong:      {strings.Repeat("a", 64) + ".com"},
+	msgRedirectInvalidCharacters: {"ex_ample.com"},
+	msgRedirectLabelHyphen:       {"-example.com"},
+	msgRedirectTooMany:           nil,
+	msgRedirectOldTLS:            nil,
+	msgRedirectDisallowedStatus:  nil,
+	msgRedirectPathTooLong:       nil,
+	msgRedirectLoop:              nil,
+	msgSwitchingProtocols:        {"http://example.com/.well-known/acme-challenge/token", "websocket"},
+	msgResponseStatus:            {"http://example.com/.well-known/acme-challenge/token", 404},
+	msgResponseReadFailed:        {"unexpected EOF"},
+	msgResponseTooLarge:          {"http://example.com/.well-known/acme-challenge/token", "<html>\n"},
+	msgHTTPIdentifierNotDNS:      nil,
+	msgKeyAuthorizationMismatch:  {"token.thumbprint", "<html>"},
+	msgKeyAuthorizationMultiple:  {"token.thumbprint"},
+	msgConfirmOverTLSFailed:      {"https://example.com/.well-known/acme-challenge/token", "Connection refused"},
+	msgConfirmOverTLSStatus:      {"https://example.com/.well-known/acme-challenge/token", 404},
+	msgConfirmOverTLSMismatch:    {"https://example.com/.well-known/acme-challenge/token", "token.thumbprint", "<html>"},
+	msgDNSNXDOMAIN:               {"example.com"},
+	msgDNSNoAddressRecords:       {"example.com"},
+	msgDNSNoValidAddresses:       {"example.com"},
+	msgDNSIdentifierNotDNS:       nil,
+	msgDNSAccount01Disabled:      {"dns-account-01"},
+	msgTXTNotFound:               {"_acme-challenge.example.com"},
+	msgTXTIncorrect:              {"a", " (and 4 more)", "_acme-challenge.example.com", ""},
+	msgTXTIncorrectAndMore:       {4},
+	msgTXTIncorrectLowTTLHint:    {"5s"},
+}
+
+// TestMessageCatalogGolden checks that every message renders, with its
+// examples, exactly as it's recorded in testdata/messages.golden, so that
+// changes to the text of messages are deliberate.
+func TestMessageCatalogGolden(t *testing.T) {
+	t.Parallel()
+
+	var ids []messageID
+	for id, m := range messageCatalog {
+		ids = append(ids, id)
+		examples, ok := messageExamples[id]
+		if !ok {
+			t.Errorf("message %q has no examples", id)
+			continue
+		}
+		test.AssertEquals(t, len(examples), len(m.params))
+	}
+	slices.Sort(ids)
+
+	var rendered strings.Builder
+	for _, id := range ids {
+		msg := renderMessage(id, messageExamples[id]...)
+		if strings.Contains(msg, "%!") {
+			t.Errorf("message %q has a format error: %s", id, msg)
+		}
+		fmt.Fprintf(&rendered, "%s\t%s\n", id, strings.ReplaceAll(msg, "\n", `\n`))
+	}
+
+	golden := filepath.Join("testdata", "messages.golden")
+	if *updateGolden {
+		test.AssertNotError(t, os.WriteFile(golden, []byte(rendered.String()), 0644), "writing golden file")
+	}
+	expected, err := os.ReadFile(golden)
+	test.AssertNotError(t, err, "reading golden file")
+	test.AssertEquals(t, rendered.String(), string(expected))
+}
+
+// TestMessageCatalogComplete checks that every messageID declared in this
+// package has a catalog entry, and that every message is rendered with a
+// messageID constant and as many parameters as its entry names.
+func TestMessageCatalogComplete(t *testing.T) {
+	t.Parallel()
+
+	fset := token.NewFileSet()
+	files, err := filepath.Glob("*.go")
+	test.AssertNotError(t, err, "listing source files")
+	var parsed []*ast.File
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		test.AssertNotError(t, err, "parsing "+file)
+		parsed = append(parsed, f)
+	}
+
+	// Collect the value of each messageID constant.
+	consts := make(map[string]messageID)
+	for _, f := range parsed {
+		ast.Inspect(f, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if !ok || spec.Type == nil {
+				return true
+			}
+			typ, ok := spec.Type.(*ast.Ident)
+			if !ok || typ.Name != "messageID" {
+				return true
+			}
+			for i, name := range spec.Names {
+				lit, ok := spec.Values[i].(*ast.BasicLit)
+				test.Assert(t, ok, fmt.Sprintf("messageID %s should be a string literal", name.Name))
+				consts[name.Name] = messageID(strings.Trim(lit.Value, "\"`"))
+			}
+			return true
+		})
+	}
+	declared := make(map[messageID]bool)
+	for name, id := range consts {
+		test.Assert(t, !declared[id], fmt.Sprintf("messageID %q is declared more than once", id))
+		declared[id] = true
+		_, ok := messageCatalog[id]
+		test.Assert(t, ok, fmt.Sprintf("messageID %s (%q) has no catalog entry", name, id))
+	}
+	for id := range messageCatalog {
+		test.Assert(t, declared[id], fmt.Sprintf("catalog entry %q has no messageID constant", id))
+	}
+
+	// Check the messageID and parameters of each message rendered.
+	for _, f := range parsed {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fun, ok := call.Fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			var idArg int
+			switch fun.Name {
+			case "renderMessage":
+				idArg = 0
+			case "newCatalogError":
+				idArg = 1
+			default:
+				return true
+			}
+			pos := fset.Position(call.Pos())
+			if call.Ellipsis.IsValid() {
+				// Only the helpers themselves pass their parameters through.
+				test.AssertEquals(t, pos.Filename, "messages.go")
+				return true
+			}
+			ident, ok := call.Args[idArg].(*ast.Ident)
+			test.Assert(t, ok, fmt.Sprintf("%s: %s should be called with a messageID constant", pos, fun.Name))
+			if !ok {
+				return true
+			}
+			id, ok := consts[ident.Name]
+			test.Assert(t, ok, fmt.Sprintf("%s: %s is not a messageID constant", pos, ident.Name))
+			if !ok {
+				return true
+			}
+			params := len(call.Args) - idArg - 1
+			if params != len(messageCatalog[id].params) {
+				t.Errorf("%s: message %q rendered with %d parameters, but its catalog entry names %d", pos, id, params, len(messageCatalog[id].params))
+			}
+			return true
+		})
+	}
+}
+
+func TestCatalogError(t *testing.T) {
+	t.Parallel()
+
+	err := newCatalogError(berrors.UnauthorizedError, msgResponseStatus, "http://example.com/", 404)
+	test.AssertEquals(t, err.Error(), "Invalid response from http://example.com/: 404")
+	test.AssertErrorIs(t, err, berrors.Unauthorized)
+	test.AssertEquals(t, detailedError(newIPError([]byte{10, 0, 0, 1}, err)).Detail, "10.0.0.1: Invalid response from http://example.com/: 404")
+
+	// The message is found beneath the errors wrapping it.
+	test.AssertDeepEquals(t, messageRecordFor(newIPError([]byte{10, 0, 0, 1}, err)), &messageRecord{
+		ID:     msgResponseStatus,
+		Params: map[string]any{"url": "http://example.com/", "status": 404},
+	})
+	test.AssertDeepEquals(t, messageRecordFor(newCatalogError(berrors.ConnectionFailureError, msgRedirectLoop)), &messageRecord{ID: msgRedirectLoop})
+	test.Assert(t, messageRecordFor(berrors.UnauthorizedError("uncataloged")) == nil, "uncataloged error should have no message")
+}
+
+func TestValidationResultLogsMessage(t *testing.T) {
+	t.Parallel()
+
+	va, mockLog := setup(nil, "", nil, nil)
+	res, err := va.DoDCV(context.Background(), createValidationRequest("empty-txts.com", core.ChallengeTypeDNS01))
+	test.AssertNotError(t, err, "DoDCV failed")
+	test.AssertEquals(t, res.Problem.Detail, "No TXT record found at _acme-challenge.empty-txts.com")
+
+	results := mockLog.GetAllMatching(`Validation result JSON=`)
+	test.AssertEquals(t, len(results), 1)
+	test.AssertContains(t, results[0], `"Message":{"ID":"dns01.txt.not-found","Params":{"name":"_acme-challenge.empty-txts.com"}}`)
+}
@@ -0,0 +1,143 @@
+package va
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/letsencrypt/boulder/features"
+)
+
+const (
+	// hintStrictBodyMatch, if "true", requires the HTTP-01 response body to
+	// match the key authorization exactly, without trimming trailing
+	// whitespace.
+	hintStrictBodyMatch = "strict-body-match"
+
+	// hintConfirmOverTLS, if "true" or "false", overrides the
+	// HTTP01ConfirmOverTLS feature flag for this request.
+	hintConfirmOverTLS = "confirm-over-tls"
+
+	// hintCAAValidationMethods, if "enforce" or "log-only", overrides the VA's
+	// CAAValidationMethodsMode for this request.
+	hintCAAValidationMethods = "caa-validation-methods"
+)
+
+// validationPolicy holds the per-request variants of VA behavior which may be
+// selected by policy hints. The zero value selects the VA's defaults. All
+// methods are safe to call on a nil *validationPolicy.
+type validationPolicy struct {
+	strictBodyMatch bool
+
+	// confirmOverTLS, if non-nil, overrides the HTTP01ConfirmOverTLS feature.
+	confirmOverTLS *bool
+
+	// caaValidationMethodsMode, if non-empty, overrides the VA's
+	// CAAValidationMethodsMode.
+	caaValidationMethodsMode CAAValidationMethodsMode
+}
+
+// policyHintRegistry maps each known policy hint key to a function which
+// applies a value of that hint to a *validationPolicy, or returns an error if
+// the value is invalid.
+var policyHintRegistry = map[string]func(*validationPolicy, string) error{
+	hintStrictBodyMatch: func(p *validationPolicy, v string) error {
+		strict, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		p.strictBodyMatch = strict
+		return nil
+	},
+	hintConfirmOverTLS: func(p *validationPolicy, v string) error {
+		confirm, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		p.confirmOverTLS = &confirm
+		return nil
+	},
+	hintCAAValidationMethods: func(p *validationPolicy, v string) error {
+		mode := CAAValidationMethodsMode(v)
+		if mode != CAAValidationMethodsEnforce && mode != CAAValidationMethodsLogOnly {
+			return fmt.Errorf("unknown CAA validationmethods mode %q", v)
+		}
+		p.caaValidationMethodsMode = mode
+		return nil
+	},
+}
+
+// applyPolicyHints returns a child context carrying the *validationPolicy
+// selected by hints, and the subset of hints which were applied, for audit
+// logging. Hints with unknown keys or invalid values are ignored and counted.
+// If no hints are applied, ctx is returned unchanged.
+func (va *ValidationAuthorityImpl) applyPolicyHints(ctx context.Context, hints map[string]string) (context.Context, map[string]string) {
+	if len(hints) == 0 {
+		return ctx, nil
+	}
+	policy := &validationPolicy{}
+	applied := make(map[string]string)
+	for _, key := range slices.Sorted(maps.Keys(hints)) {
+		apply, ok := policyHintRegistry[key]
+		if !ok {
+			va.metrics.policyHintsIgnored.WithLabelValues("unknown_key").Inc()
+			continue
+		}
+		err := apply(policy, hints[key])
+		if err != nil {
+			va.metrics.policyHintsIgnored.WithLabelValues("invalid_value").Inc()
+			va.log.Warningf("Ignoring invalid value %q of policy hint %q: %s", hints[key], key, err)
+			continue
+		}
+		applied[key] = hints[key]
+	}
+	if len(applied) == 0 {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, validationPolicyKey{}, policy), applied
+}
+
+type validationPolicyKey struct{}
+
+// validationPolicyFrom returns the *validationPolicy carried by ctx, or nil.
+func validationPolicyFrom(ctx context.Context) *validationPolicy {
+	policy, _ := ctx.Value(validationPolicyKey{}).(*validationPolicy)
+	return policy
+}
+
+// strictBody returns true if HTTP-01 response bodies must match the key
+// authorization exactly.
+func (p *validationPolicy) strictBody() bool {
+	return p != nil && p.strictBodyMatch
+}
+
+// confirmOverTLSEnabled returns true if HTTP-01 validations of configured
+// names must be confirmed over HTTPS.
+func (p *validationPolicy) confirmOverTLSEnabled() bool {
+	if p != nil && p.confirmOverTLS != nil {
+		return *p.confirmOverTLS
+	}
+	return features.Get().HTTP01ConfirmOverTLS
+}
+
+// caaMode returns the CAAValidationMethodsMode to use, which is def unless
+// overridden.
+func (p *validationPolicy) caaMode(def CAAValidationMethodsMode) CAAValidationMethodsMode {
+	if p != nil && p.caaValidationMethodsMode != "" {
+		return p.caaValidationMethodsMode
+	}
+	return def
+}
+
+// policyHintsDedupKey returns a canonical encoding of hints, so that requests
+// with different hints are never deduplicated.
+func policyHintsDedupKey(hints map[string]string) string {
+	var b strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(hints)) {
+		fmt.Fprintf(&b, "%q=%q;", key, hints[key])
+	}
+	return b.String()
+}
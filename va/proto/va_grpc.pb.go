@@ -148,8 +148,9 @@ var VA_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	CAA_IsCAAValid_FullMethodName = "/va.CAA/IsCAAValid"
-	CAA_DoCAA_FullMethodName      = "/va.CAA/DoCAA"
+	CAA_IsCAAValid_FullMethodName    = "/va.CAA/IsCAAValid"
+	CAA_DoCAA_FullMethodName         = "/va.CAA/DoCAA"
+	CAA_CheckCAAMulti_FullMethodName = "/va.CAA/CheckCAAMulti"
 )
 
 // CAAClient is the client API for CAA service.
@@ -158,6 +159,9 @@ const (
 type CAAClient interface {
 	IsCAAValid(ctx context.Context, in *IsCAAValidRequest, opts ...grpc.CallOption) (*IsCAAValidResponse, error)
 	DoCAA(ctx context.Context, in *IsCAAValidRequest, opts ...grpc.CallOption) (*IsCAAValidResponse, error)
+	// CheckCAAMulti checks CAA for every identifier in the batch, consulting
+	// each remote perspective once for the whole batch.
+	CheckCAAMulti(ctx context.Context, in *CheckCAAMultiRequest, opts ...grpc.CallOption) (*CheckCAAMultiResponse, error)
 }
 
 type cAAClient struct {
@@ -188,12 +192,25 @@ func (c *cAAClient) DoCAA(ctx context.Context, in *IsCAAValidRequest, opts ...gr
 	return out, nil
 }
 
+func (c *cAAClient) CheckCAAMulti(ctx context.Context, in *CheckCAAMultiRequest, opts ...grpc.CallOption) (*CheckCAAMultiResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckCAAMultiResponse)
+	err := c.cc.Invoke(ctx, CAA_CheckCAAMulti_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CAAServer is the server API for CAA service.
 // All implementations must embed UnimplementedCAAServer
 // for forward compatibility
 type CAAServer interface {
 	IsCAAValid(context.Context, *IsCAAValidRequest) (*IsCAAValidResponse, error)
 	DoCAA(context.Context, *IsCAAValidRequest) (*IsCAAValidResponse, error)
+	// CheckCAAMulti checks CAA for every identifier in the batch, consulting
+	// each remote perspective once for the whole batch.
+	CheckCAAMulti(context.Context, *CheckCAAMultiRequest) (*CheckCAAMultiResponse, error)
 	mustEmbedUnimplementedCAAServer()
 }
 
@@ -207,6 +224,9 @@ func (UnimplementedCAAServer) IsCAAValid(context.Context, *IsCAAValidRequest) (*
 func (UnimplementedCAAServer) DoCAA(context.Context, *IsCAAValidRequest) (*IsCAAValidResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DoCAA not implemented")
 }
+func (UnimplementedCAAServer) CheckCAAMulti(context.Context, *CheckCAAMultiRequest) (*CheckCAAMultiResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckCAAMulti not implemented")
+}
 func (UnimplementedCAAServer) mustEmbedUnimplementedCAAServer() {}
 
 // UnsafeCAAServer may be embedded to opt out of forward compatibility for this service.
@@ -256,6 +276,24 @@ func _CAA_DoCAA_Handler(srv interface{}, ctx context.Context, dec func(interface
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CAA_CheckCAAMulti_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckCAAMultiRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAAServer).CheckCAAMulti(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CAA_CheckCAAMulti_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAAServer).CheckCAAMulti(ctx, req.(*CheckCAAMultiRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CAA_ServiceDesc is the grpc.ServiceDesc for CAA service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -271,6 +309,10 @@ var CAA_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DoCAA",
 			Handler:    _CAA_DoCAA_Handler,
 		},
+		{
+			MethodName: "CheckCAAMulti",
+			Handler:    _CAA_CheckCAAMulti_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "va.proto",
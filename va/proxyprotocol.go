@@ -0,0 +1,74 @@
+package va
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// proxyProtocolSignature begins every PROXY protocol v2 header.
+var proxyProtocolSignature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	// proxyProtocolV2Proxy is the version (2) and command (PROXY) byte of a
+	// PROXY protocol v2 header.
+	proxyProtocolV2Proxy = 0x21
+
+	// proxyProtocolTCP4 and proxyProtocolTCP6 are the address family and
+	// transport protocol bytes of a PROXY protocol v2 header for TCP over IPv4
+	// and IPv6 respectively.
+	proxyProtocolTCP4 = 0x11
+	proxyProtocolTCP6 = 0x21
+)
+
+// proxyProtocolHeader returns a PROXY protocol v2 header, as described in
+// https://www.haproxy.org/download/3.0/doc/proxy-protocol.txt, for a TCP
+// connection from src to dst. If src and dst are of different address
+// families, both are encoded as IPv6 addresses.
+func proxyProtocolHeader(src, dst netip.AddrPort) []byte {
+	srcAddr, dstAddr := src.Addr().Unmap(), dst.Addr().Unmap()
+	family := byte(proxyProtocolTCP4)
+	if !srcAddr.Is4() || !dstAddr.Is4() {
+		family = proxyProtocolTCP6
+		srcAddr = netip.AddrFrom16(srcAddr.As16())
+		dstAddr = netip.AddrFrom16(dstAddr.As16())
+	}
+
+	var addrs []byte
+	addrs = append(addrs, srcAddr.AsSlice()...)
+	addrs = append(addrs, dstAddr.AsSlice()...)
+	addrs = binary.BigEndian.AppendUint16(addrs, src.Port())
+	addrs = binary.BigEndian.AppendUint16(addrs, dst.Port())
+
+	header := append([]byte{}, proxyProtocolSignature...)
+	header = append(header, proxyProtocolV2Proxy, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addrs)))
+	return append(header, addrs...)
+}
+
+// writeProxyProtocolHeader writes a PROXY protocol v2 header to conn, a TCP
+// connection made through the VA's egress proxy, so that the origin sees
+// source, the VA's public address, as the address the connection came from.
+// The header's destination is conn's remote address, and its source port is
+// conn's local port.
+func writeProxyProtocolHeader(conn net.Conn, source netip.Addr) error {
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("PROXY protocol requires a TCP connection, got local address %q", conn.LocalAddr())
+	}
+	remote, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("PROXY protocol requires a TCP connection, got remote address %q", conn.RemoteAddr())
+	}
+
+	header := proxyProtocolHeader(
+		netip.AddrPortFrom(source, uint16(local.Port)),
+		remote.AddrPort(),
+	)
+	_, err := conn.Write(header)
+	if err != nil {
+		return fmt.Errorf("writing PROXY protocol header: %w", err)
+	}
+	return nil
+}
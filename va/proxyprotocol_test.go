@@ -0,0 +1,227 @@
+package va
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// parsedProxyHeader is the result of parsing the start of a connection which
+// may begin with a PROXY protocol v2 header.
+type parsedProxyHeader struct {
+	present  bool
+	src, dst netip.AddrPort
+	// next is the first byte which followed the header, if any.
+	next byte
+}
+
+// readProxyProtocolHeader parses a PROXY protocol v2 header from the start of
+// r, if there is one, leaving r positioned just after it.
+func readProxyProtocolHeader(r *bufio.Reader) (parsedProxyHeader, error) {
+	var parsed parsedProxyHeader
+	sig, err := r.Peek(len(proxyProtocolSignature))
+	if err != nil {
+		return parsed, err
+	}
+	if bytes.Equal(sig, proxyProtocolSignature) {
+		parsed.present = true
+		fixed := make([]byte, len(proxyProtocolSignature)+4)
+		_, err = io.ReadFull(r, fixed)
+		if err != nil {
+			return parsed, err
+		}
+		verCmd, family := fixed[12], fixed[13]
+		if verCmd != proxyProtocolV2Proxy {
+			return parsed, fmt.Errorf("unexpected version and command 0x%x", verCmd)
+		}
+		addrs := make([]byte, binary.BigEndian.Uint16(fixed[14:]))
+		_, err = io.ReadFull(r, addrs)
+		if err != nil {
+			return parsed, err
+		}
+		var addrLen int
+		switch family {
+		case proxyProtocolTCP4:
+			addrLen = 4
+		case proxyProtocolTCP6:
+			addrLen = 16
+		default:
+			return parsed, fmt.Errorf("unexpected address family 0x%x", family)
+		}
+		if len(addrs) != 2*addrLen+4 {
+			return parsed, fmt.Errorf("unexpected address length %d for family 0x%x", len(addrs), family)
+		}
+		srcAddr, _ := netip.AddrFromSlice(addrs[:addrLen])
+		dstAddr, _ := netip.AddrFromSlice(addrs[addrLen : 2*addrLen])
+		ports := addrs[2*addrLen:]
+		parsed.src = netip.AddrPortFrom(srcAddr, binary.BigEndian.Uint16(ports))
+		parsed.dst = netip.AddrPortFrom(dstAddr, binary.BigEndian.Uint16(ports[2:]))
+	}
+	next, err := r.Peek(1)
+	if err != nil {
+		return parsed, err
+	}
+	parsed.next = next[0]
+	return parsed, nil
+}
+
+// proxyProtocolListener is a net.Listener which parses any PROXY protocol
+// header at the start of each connection it accepts, and sends the result to
+// headers.
+type proxyProtocolListener struct {
+	net.Listener
+	headers chan parsedProxyHeader
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	parsed, err := readProxyProtocolHeader(r)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	l.headers <- parsed
+	return &bufferedConn{Conn: conn, r: r}, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func TestProxyProtocolHeader(t *testing.T) {
+	t.Parallel()
+
+	// The example from the PROXY protocol specification, section 2.2.
+	header := proxyProtocolHeader(
+		netip.MustParseAddrPort("192.0.2.1:56324"),
+		netip.MustParseAddrPort("198.51.100.7:443"),
+	)
+	expected := append(append([]byte{}, proxyProtocolSignature...),
+		0x21, 0x11, 0x00, 0x0c,
+		192, 0, 2, 1,
+		198, 51, 100, 7,
+		0xdc, 0x04,
+		0x01, 0xbb,
+	)
+	test.AssertByteEquals(t, header, expected)
+
+	testCases := []struct {
+		name         string
+		src, dst     string
+		expectSrc    string
+		expectDst    string
+		expectFamily byte
+	}{
+		{"IPv4", "192.0.2.1:1234", "198.51.100.7:80", "192.0.2.1:1234", "198.51.100.7:80", proxyProtocolTCP4},
+		{"IPv6", "[2001:db8::1]:1234", "[2001:db8::7]:443", "[2001:db8::1]:1234", "[2001:db8::7]:443", proxyProtocolTCP6},
+		{"IPv4-mapped IPv6 destination", "192.0.2.1:1234", "[::ffff:198.51.100.7]:80", "192.0.2.1:1234", "198.51.100.7:80", proxyProtocolTCP4},
+		{"IPv4 source, IPv6 destination", "192.0.2.1:1234", "[2001:db8::7]:443", "[::ffff:192.0.2.1]:1234", "[2001:db8::7]:443", proxyProtocolTCP6},
+		{"IPv6 source, IPv4 destination", "[2001:db8::1]:1234", "198.51.100.7:80", "[2001:db8::1]:1234", "[::ffff:198.51.100.7]:80", proxyProtocolTCP6},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			header := proxyProtocolHeader(netip.MustParseAddrPort(tc.src), netip.MustParseAddrPort(tc.dst))
+			test.AssertEquals(t, header[13], tc.expectFamily)
+			// Follow the header with a byte, as a connection would.
+			parsed, err := readProxyProtocolHeader(bufio.NewReader(bytes.NewReader(append(header, 'G'))))
+			test.AssertNotError(t, err, "parsing header")
+			test.Assert(t, parsed.present, "header not found")
+			test.AssertEquals(t, parsed.src, netip.MustParseAddrPort(tc.expectSrc))
+			test.AssertEquals(t, parsed.dst, netip.MustParseAddrPort(tc.expectDst))
+			test.AssertEquals(t, parsed.next, byte('G'))
+		})
+	}
+}
+
+func TestHTTP01ProxyProtocol(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			token := core.NewToken()
+			hs := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, ka(token))
+			}))
+			headers := make(chan parsedProxyHeader, 10)
+			hs.Listener = &proxyProtocolListener{Listener: hs.Listener, headers: headers}
+			hs.Start()
+			defer hs.Close()
+
+			va, _ := setup(hs, "", nil, nil)
+			if enabled {
+				va.proxyProtocolSource = netip.MustParseAddr("192.0.2.1")
+			}
+
+			records, err := va.validateHTTP01(ctx, dnsi("localhost"), token, ka(token))
+			test.AssertNotError(t, err, "validation failed")
+			test.AssertEquals(t, len(records), 1)
+			test.AssertEquals(t, records[0].ProxyProtocol, enabled)
+
+			parsed := <-headers
+			test.AssertEquals(t, parsed.present, enabled)
+			// Whether or not there was a header, the request itself follows.
+			test.AssertEquals(t, parsed.next, byte('G'))
+			if enabled {
+				test.AssertEquals(t, parsed.src.Addr(), netip.MustParseAddr("192.0.2.1"))
+				test.Assert(t, parsed.src.Port() != 0, "expected the source port of the connection")
+				test.AssertEquals(t, parsed.dst, netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), uint16(getPort(hs))))
+			}
+		})
+	}
+}
+
+func TestTLSALPN01ProxyProtocol(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			headers := make(chan parsedProxyHeader, 10)
+			ports := defaultValidationPorts()
+			ports.tls = rawCloseSrv(t, func(conn net.Conn) {
+				parsed, err := readProxyProtocolHeader(bufio.NewReader(conn))
+				if err != nil {
+					t.Errorf("reading PROXY protocol header: %s", err)
+				}
+				headers <- parsed
+			})
+
+			va, _ := setupWithPorts(ports, "", nil, nil)
+			if enabled {
+				va.proxyProtocolSource = netip.MustParseAddr("2001:db8::1")
+			}
+
+			// The server closes the connection without completing the
+			// handshake, so the validation fails, but the record still notes
+			// how the connection was made.
+			records, err := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+			test.AssertError(t, err, "validation should have failed")
+			test.AssertEquals(t, len(records), 1)
+			test.AssertEquals(t, records[0].ProxyProtocol, enabled)
+
+			parsed := <-headers
+			test.AssertEquals(t, parsed.present, enabled)
+			// Whether or not there was a header, the TLS handshake follows.
+			test.AssertEquals(t, parsed.next, byte(0x16))
+			if enabled {
+				test.AssertEquals(t, parsed.src.Addr(), netip.MustParseAddr("2001:db8::1"))
+				test.AssertEquals(t, parsed.dst, netip.AddrPortFrom(netip.MustParseAddr("::ffff:127.0.0.1"), uint16(ports.tls)))
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package va
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	blog "github.com/letsencrypt/boulder/log"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+// remotePeer records the identity authenticated by the TLS certificate of a
+// remote VA's gRPC server, which is the certificate's dNSNames. The first
+// identity authenticated, and any which differs from the one before it, is
+// checked against the identity the remote VA is expected to have, so that a
+// remote VA whose address was copied from another perspective's config, and
+// whose results would be attributed to the wrong perspective, is noticed. All
+// methods are safe for concurrent use, and on a nil *remotePeer, which has no
+// identity.
+type remotePeer struct {
+	address       string
+	perspective   string
+	expected      string
+	log           blog.Logger
+	mismatches    *prometheus.CounterVec
+	authenticated atomic.Pointer[string]
+}
+
+// identity returns the identity most recently authenticated, or "" if none has
+// been.
+func (p *remotePeer) identity() string {
+	if p == nil {
+		return ""
+	}
+	identity := p.authenticated.Load()
+	if identity == nil {
+		return ""
+	}
+	return *identity
+}
+
+// observe records the identity authenticated by info, if it's from a TLS
+// connection, and checks it against the expected identity if it's new.
+func (p *remotePeer) observe(info credentials.AuthInfo) {
+	if p == nil {
+		return
+	}
+	tlsInfo, ok := info.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return
+	}
+	names := tlsInfo.State.PeerCertificates[0].DNSNames
+	identity := strings.Join(names, ",")
+	prev := p.authenticated.Swap(&identity)
+	if prev != nil && *prev == identity {
+		return
+	}
+	if p.expected == "" || slices.ContainsFunc(names, func(name string) bool {
+		return strings.EqualFold(name, p.expected)
+	}) {
+		return
+	}
+	p.mismatches.WithLabelValues(p.perspective).Inc()
+	p.log.AuditErrf("Remote VA at %s for perspective %q authenticated as %q, but %q was expected - misconfiguration likely",
+		p.address, p.perspective, identity, p.expected)
+}
+
+// identifiedCall makes an RPC with call, recording the identity of the remote
+// VA which answered it with p.
+func identifiedCall[Req, Res any](ctx context.Context, p *remotePeer, call func(context.Context, Req, ...grpc.CallOption) (Res, error), in Req, opts []grpc.CallOption) (Res, error) {
+	var answered peer.Peer
+	res, err := call(ctx, in, append(opts, grpc.Peer(&answered))...)
+	p.observe(answered.AuthInfo)
+	return res, err
+}
+
+// identifiedVAClient is a vapb.VAClient which records the identity of the
+// remote VA answering each RPC.
+type identifiedVAClient struct {
+	vapb.VAClient
+	peer *remotePeer
+}
+
+func (c identifiedVAClient) PerformValidation(ctx context.Context, in *vapb.PerformValidationRequest, opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+	return identifiedCall(ctx, c.peer, c.VAClient.PerformValidation, in, opts)
+}
+
+func (c identifiedVAClient) DoDCV(ctx context.Context, in *vapb.PerformValidationRequest, opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+	return identifiedCall(ctx, c.peer, c.VAClient.DoDCV, in, opts)
+}
+
+// identifiedCAAClient is a vapb.CAAClient which records the identity of the
+// remote VA answering each RPC.
+type identifiedCAAClient struct {
+	vapb.CAAClient
+	peer *remotePeer
+}
+
+func (c identifiedCAAClient) IsCAAValid(ctx context.Context, in *vapb.IsCAAValidRequest, opts ...grpc.CallOption) (*vapb.IsCAAValidResponse, error) {
+	return identifiedCall(ctx, c.peer, c.CAAClient.IsCAAValid, in, opts)
+}
+
+func (c identifiedCAAClient) DoCAA(ctx context.Context, in *vapb.IsCAAValidRequest, opts ...grpc.CallOption) (*vapb.IsCAAValidResponse, error) {
+	return identifiedCall(ctx, c.peer, c.CAAClient.DoCAA, in, opts)
+}
+
+func (c identifiedCAAClient) CheckCAAMulti(ctx context.Context, in *vapb.CheckCAAMultiRequest, opts ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return identifiedCall(ctx, c.peer, c.CAAClient.CheckCAAMulti, in, opts)
+}
+
+// identify returns a copy of rva whose clients record the identity of the
+// remote VA answering each RPC, and check it against rva.ExpectedIdentity.
+func (rva RemoteVA) identify(logger blog.Logger, mismatches *prometheus.CounterVec) RemoteVA {
+	p := &remotePeer{
+		address:     rva.Address,
+		perspective: rva.Perspective,
+		expected:    rva.ExpectedIdentity,
+		log:         logger,
+		mismatches:  mismatches,
+	}
+	rva.peer = p
+	rva.RemoteClients = RemoteClients{
+		VAClient:  identifiedVAClient{VAClient: rva.VAClient, peer: p},
+		CAAClient: identifiedCAAClient{CAAClient: rva.CAAClient, peer: p},
+	}
+	return rva
+}
+
+// describe returns the address of rva, and the identity it last authenticated
+// as, if any, for logging.
+func (rva RemoteVA) describe() string {
+	identity := rva.peer.identity()
+	if identity == "" {
+		return rva.Address
+	}
+	return rva.Address + " authenticated as " + identity
+}
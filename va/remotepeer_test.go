@@ -0,0 +1,129 @@
+package va
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/test"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+// authenticatedVA is an in-memory remote VA which reports, as a gRPC
+// connection over TLS would, that its server certificate is for names.
+type authenticatedVA struct {
+	inMemVA
+	names []string
+}
+
+func (a *authenticatedVA) authenticate(opts []grpc.CallOption) {
+	for _, opt := range opts {
+		p, ok := opt.(grpc.PeerCallOption)
+		if !ok {
+			continue
+		}
+		*p.PeerAddr = peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{DNSNames: a.names}},
+		}}}
+	}
+}
+
+func (a *authenticatedVA) DoDCV(ctx context.Context, req *vapb.PerformValidationRequest, opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+	a.authenticate(opts)
+	return a.inMemVA.DoDCV(ctx, req, opts...)
+}
+
+func (a *authenticatedVA) DoCAA(ctx context.Context, req *vapb.IsCAAValidRequest, opts ...grpc.CallOption) (*vapb.IsCAAValidResponse, error) {
+	a.authenticate(opts)
+	return a.inMemVA.DoCAA(ctx, req, opts...)
+}
+
+func TestRemoteVAIdentity(t *testing.T) {
+	t.Parallel()
+
+	ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true, fail: false})
+	defer ms.Close()
+
+	// The second remote VA's address was copied from the first's, so it
+	// answers as the first, and the third fails validation.
+	remoteVAs := setupRemotes([]remoteConf{
+		{ua: pass, rir: arin},
+		{ua: pass, rir: ripe},
+		{ua: fail, rir: apnic},
+	}, ms.Server)
+	for i, names := range [][]string{{"rva.dc-0"}, {"rva.dc-0"}, {"rva.dc-2", "rva.internal"}} {
+		remoteVAs[i].RemoteClients = RemoteClients{
+			VAClient:  &authenticatedVA{*remoteVAs[i].VAClient.(*inMemVA), names},
+			CAAClient: remoteVAs[i].CAAClient,
+		}
+		remoteVAs[i].Address = fmt.Sprintf("rva.dc-%d:9097", i)
+		remoteVAs[i].ExpectedIdentity = fmt.Sprintf("rva.dc-%d", i)
+	}
+	va, mockLog := setup(ms.Server, pass, remoteVAs, nil)
+
+	for range 2 {
+		mockLog.Clear()
+		res, err := va.DoDCV(ctx, createValidationRequest("localhost", core.ChallengeTypeHTTP01))
+		test.AssertNotError(t, err, "DoDCV failed")
+		test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed: %#v", res.Problem))
+
+		// The failed perspective is logged with the identity it
+		// authenticated as.
+		results := mockLog.GetAllMatching(`Validation result JSON=`)
+		test.AssertEquals(t, len(results), 1)
+		test.AssertContains(t, results[0], `"failedPeers":{"dc-2-APNIC":"rva.dc-2,rva.internal"}`)
+	}
+
+	// The mismatch was audit logged and counted once, when the identity was
+	// first authenticated, and matching identities weren't.
+	test.AssertMetricWithLabelsEquals(t, va.metrics.remoteVAIdentityMismatches, prometheus.Labels{"perspective": "dc-1-RIPE"}, 1)
+	test.AssertMetricWithLabelsEquals(t, va.metrics.remoteVAIdentityMismatches, prometheus.Labels{"perspective": "dc-0-ARIN"}, 0)
+	test.AssertMetricWithLabelsEquals(t, va.metrics.remoteVAIdentityMismatches, prometheus.Labels{"perspective": "dc-2-APNIC"}, 0)
+	test.AssertEquals(t, va.remoteVAs[1].describe(), "rva.dc-1:9097 authenticated as rva.dc-0")
+}
+
+func TestRemotePeerObserve(t *testing.T) {
+	t.Parallel()
+
+	va, mockLog := setup(nil, "", nil, nil)
+	rva := RemoteVA{Address: "rva:9097", Perspective: "dc-0", ExpectedIdentity: "rva.dc-0"}.identify(va.log, va.metrics.remoteVAIdentityMismatches)
+	authenticate := func(names ...string) {
+		rva.peer.observe(credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{DNSNames: names}},
+		}})
+	}
+
+	// Connections which aren't over TLS authenticate nothing.
+	rva.peer.observe(nil)
+	test.AssertEquals(t, rva.describe(), "rva:9097")
+
+	authenticate("RVA.dc-0")
+	test.AssertEquals(t, rva.describe(), "rva:9097 authenticated as RVA.dc-0")
+	test.AssertEquals(t, len(mockLog.GetAllMatching("ERR:")), 0)
+
+	// A change of identity is checked again.
+	authenticate("rva.dc-1")
+	authenticate("rva.dc-1")
+	test.AssertEquals(t, len(mockLog.GetAllMatching(
+		`Remote VA at rva:9097 for perspective "dc-0" authenticated as "rva.dc-1", but "rva.dc-0" was expected`)), 1)
+	authenticate("rva.dc-0")
+	authenticate("rva.dc-1")
+	test.AssertMetricWithLabelsEquals(t, va.metrics.remoteVAIdentityMismatches, prometheus.Labels{"perspective": "dc-0"}, 2)
+
+	// Without an expected identity, any is accepted.
+	var nilPeer *remotePeer
+	test.AssertEquals(t, nilPeer.identity(), "")
+	unchecked := RemoteVA{Address: "rva:9097", Perspective: "dc-1"}.identify(va.log, va.metrics.remoteVAIdentityMismatches)
+	unchecked.peer.observe(credentials.TLSInfo{State: tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{DNSNames: []string{"anything"}}},
+	}})
+	test.AssertMetricWithLabelsEquals(t, va.metrics.remoteVAIdentityMismatches, prometheus.Labels{"perspective": "dc-1"}, 0)
+}
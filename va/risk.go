@@ -0,0 +1,191 @@
+package va
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/letsencrypt/boulder/identifier"
+	"github.com/letsencrypt/boulder/probs"
+)
+
+// RiskDecision is a RiskChecker's verdict on the validation of an identifier.
+type RiskDecision string
+
+const (
+	// RiskAllow permits the validation to proceed.
+	RiskAllow RiskDecision = "allow"
+	// RiskFlag permits the validation to proceed, but tags its audit log and
+	// metrics so that it can be reviewed.
+	RiskFlag RiskDecision = "flag"
+	// RiskDeny refuses the validation with a rejectedIdentifier problem.
+	RiskDeny RiskDecision = "deny"
+)
+
+const (
+	// defaultRiskDenyDetail is the detail of the problem of validations which
+	// the RiskChecker denies, if RiskConfig.DenyDetail is unset. It's
+	// deliberately generic, so as not to reveal how identifiers are screened.
+	defaultRiskDenyDetail = "The ACME server refuses to issue a certificate for this domain name, because of its policy"
+
+	// defaultRiskCheckTimeout bounds each request an HTTPRiskChecker makes, if
+	// no timeout is configured.
+	defaultRiskCheckTimeout = 2 * time.Second
+
+	// maxRiskResponseBytes is the largest response an HTTPRiskChecker reads.
+	maxRiskResponseBytes = 64 * 1024
+)
+
+// RiskChecker screens the identifiers being validated, for instance by
+// consulting an external service which scores domains for phishing risk.
+type RiskChecker interface {
+	// Check returns the decision on validating ident on behalf of the account
+	// regID, and the reason for it, which is only audit logged. If it returns
+	// an error, the validation proceeds as though it were allowed.
+	Check(ctx context.Context, ident identifier.ACMEIdentifier, regID int64) (RiskDecision, string, error)
+}
+
+// RiskConfig configures the screening of identifiers by the primary VA before
+// they're validated.
+type RiskConfig struct {
+	// Checker is consulted at the start of each validation. If unset, every
+	// validation is allowed.
+	Checker RiskChecker
+	// DenyDetail is the detail of the problem of validations which the Checker
+	// denies. The reason the Checker gives is never shown to the Subscriber.
+	DenyDetail string
+}
+
+// noopRiskChecker is the RiskChecker of a VA which isn't configured with one.
+// It allows every validation.
+type noopRiskChecker struct{}
+
+func (noopRiskChecker) Check(context.Context, identifier.ACMEIdentifier, int64) (RiskDecision, string, error) {
+	return RiskAllow, "", nil
+}
+
+// riskRecord is the audit logged form of a RiskChecker's decision to flag or
+// deny a validation.
+type riskRecord struct {
+	Decision RiskDecision
+	Reason   string `json:",omitempty"`
+}
+
+// checkRisk consults the VA's RiskChecker about validating ident on behalf of
+// regID. It returns a problem if the validation is denied, and a riskRecord to
+// be audit logged if the validation is flagged or denied. Errors from the
+// RiskChecker, and decisions it doesn't recognize, are counted and logged, and
+// the validation is allowed to proceed.
+func (va *ValidationAuthorityImpl) checkRisk(ctx context.Context, ident identifier.ACMEIdentifier, regID int64) (*probs.ProblemDetails, *riskRecord) {
+	decision, reason, err := va.riskChecker.Check(ctx, ident, regID)
+	if err == nil {
+		switch decision {
+		case RiskAllow, RiskFlag, RiskDeny:
+		default:
+			err = fmt.Errorf("unrecognized decision %q", decision)
+		}
+	}
+	if err != nil {
+		va.metrics.riskChecks.WithLabelValues("error").Inc()
+		va.log.Warningf("checking risk of validating %q for account %d, allowing it: %s", ident.Value, regID, err)
+		return nil, nil
+	}
+
+	va.metrics.riskChecks.WithLabelValues(string(decision)).Inc()
+	switch decision {
+	case RiskFlag:
+		return nil, &riskRecord{Decision: decision, Reason: reason}
+	case RiskDeny:
+		return probs.RejectedIdentifier(va.riskDenyDetail), &riskRecord{Decision: decision, Reason: reason}
+	}
+	return nil, nil
+}
+
+// riskCheckRequest is the JSON body an HTTPRiskChecker posts.
+type riskCheckRequest struct {
+	Identifier     identifier.ACMEIdentifier `json:"identifier"`
+	RegistrationID int64                     `json:"registrationID"`
+}
+
+// riskCheckResponse is the JSON body an HTTPRiskChecker expects in response.
+type riskCheckResponse struct {
+	Decision RiskDecision `json:"decision"`
+	Reason   string       `json:"reason"`
+}
+
+// HTTPRiskChecker is a reference RiskChecker which posts each identifier, and
+// the account validating it, as JSON to a risk scoring service, which responds
+// with a JSON object containing a "decision" of "allow", "flag" or "deny" and
+// an optional "reason".
+type HTTPRiskChecker struct {
+	url    string
+	client *http.Client
+}
+
+var _ RiskChecker = (*HTTPRiskChecker)(nil)
+
+// NewHTTPRiskChecker returns an HTTPRiskChecker which posts to endpoint, an
+// absolute http or https URL, and gives up on each request after timeout. If
+// timeout is zero, it defaults to 2s.
+func NewHTTPRiskChecker(endpoint string, timeout time.Duration) (*HTTPRiskChecker, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing risk check URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("risk check URL must be an absolute http or https URL, got %q", endpoint)
+	}
+	if timeout < 0 {
+		return nil, fmt.Errorf("risk check timeout must not be negative, got %s", timeout)
+	}
+	if timeout == 0 {
+		timeout = defaultRiskCheckTimeout
+	}
+	return &HTTPRiskChecker{
+		url: endpoint,
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// Check implements RiskChecker.
+func (c *HTTPRiskChecker) Check(ctx context.Context, ident identifier.ACMEIdentifier, regID int64) (RiskDecision, string, error) {
+	body, err := json.Marshal(riskCheckRequest{Identifier: ident, RegistrationID: regID})
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("risk check returned status %d", resp.StatusCode)
+	}
+
+	var result riskCheckResponse
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxRiskResponseBytes)).Decode(&result)
+	if err != nil {
+		return "", "", fmt.Errorf("decoding risk check response: %w", err)
+	}
+	if result.Decision == "" {
+		return "", "", errors.New("risk check response has no decision")
+	}
+	return result.Decision, result.Reason, nil
+}
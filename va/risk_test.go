@@ -0,0 +1,220 @@
+package va
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/identifier"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// fakeRiskChecker is an in-memory RiskChecker which returns the same result
+// for every identifier, and records the identifier and account it was last
+// asked about.
+type fakeRiskChecker struct {
+	decision RiskDecision
+	reason   string
+	err      error
+
+	ident identifier.ACMEIdentifier
+	regID int64
+}
+
+func (c *fakeRiskChecker) Check(_ context.Context, ident identifier.ACMEIdentifier, regID int64) (RiskDecision, string, error) {
+	c.ident = ident
+	c.regID = regID
+	return c.decision, c.reason, c.err
+}
+
+func TestRiskCheck(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		checker       *fakeRiskChecker
+		expectProblem bool
+		expectResult  string
+		expectLogged  string
+	}{
+		{
+			name:         "allow",
+			checker:      &fakeRiskChecker{decision: RiskAllow, reason: "low score"},
+			expectResult: "allow",
+		},
+		{
+			name:         "flag",
+			checker:      &fakeRiskChecker{decision: RiskFlag, reason: "lookalike of example.net"},
+			expectResult: "flag",
+			expectLogged: `"Risk":{"Decision":"flag","Reason":"lookalike of example.net"}`,
+		},
+		{
+			name:          "deny",
+			checker:       &fakeRiskChecker{decision: RiskDeny, reason: "known phishing domain"},
+			expectProblem: true,
+			expectResult:  "deny",
+			expectLogged:  `"Risk":{"Decision":"deny","Reason":"known phishing domain"}`,
+		},
+		{
+			name:         "error fails open",
+			checker:      &fakeRiskChecker{err: errors.New("connection refused")},
+			expectResult: "error",
+		},
+		{
+			name:         "unrecognized decision fails open",
+			checker:      &fakeRiskChecker{decision: "quarantine"},
+			expectResult: "error",
+		},
+	}
+	for _, tc := range testCases {
+		for _, runner := range []struct {
+			name string
+			run  validationFuncRunner
+		}{
+			{"PerformValidation", runPerformValidation},
+			{"DoDCV", runDoDCV},
+		} {
+			t.Run(tc.name+"/"+runner.name, func(t *testing.T) {
+				t.Parallel()
+
+				va, mockLog := setupWithRemotes(nil, "", []remoteConf{{rir: arin}, {rir: ripe}, {rir: apnic}}, nil)
+				checker := *tc.checker
+				va.riskChecker = &checker
+				va.riskDenyDetail = "Issuance for this name is not permitted"
+
+				req := createValidationRequest("good-dns01.com", core.ChallengeTypeDNS01)
+				res, err := runner.run(context.Background(), va, req)
+				test.AssertNotError(t, err, "validation failed")
+				test.AssertEquals(t, checker.ident, identifier.NewDNS("good-dns01.com"))
+				test.AssertEquals(t, checker.regID, req.Authz.RegID)
+
+				if tc.expectProblem {
+					test.AssertNotNil(t, res.Problem, "denied validation should have a problem")
+					test.AssertEquals(t, res.Problem.ProblemType, string(probs.RejectedIdentifierProblem))
+					test.AssertEquals(t, res.Problem.Detail, "Issuance for this name is not permitted")
+					test.AssertEquals(t, len(res.Records), 0)
+				} else {
+					test.Assert(t, res.Problem == nil, "validation should have succeeded")
+				}
+				test.AssertMetricWithLabelsEquals(t, va.metrics.riskChecks, prometheus.Labels{"result": tc.expectResult}, 1)
+
+				results := mockLog.GetAllMatching(`Validation result JSON=`)
+				test.AssertEquals(t, len(results), 1)
+				if tc.expectLogged != "" {
+					test.AssertContains(t, results[0], tc.expectLogged)
+				} else {
+					test.AssertNotContains(t, results[0], `"Risk"`)
+				}
+				if tc.expectResult == "error" {
+					test.AssertEquals(t, len(mockLog.GetAllMatching(`allowing it`)), 1)
+				}
+			})
+		}
+	}
+}
+
+func TestRiskCheckIdentifierFromRequest(t *testing.T) {
+	t.Parallel()
+
+	for _, runner := range []struct {
+		name string
+		run  validationFuncRunner
+	}{
+		{"PerformValidation", runPerformValidation},
+		{"DoDCV", runDoDCV},
+	} {
+		t.Run(runner.name, func(t *testing.T) {
+			t.Parallel()
+
+			va, _ := setupWithRemotes(nil, "", []remoteConf{{rir: arin}, {rir: ripe}, {rir: apnic}}, nil)
+			checker := &fakeRiskChecker{decision: RiskDeny}
+			va.riskChecker = checker
+
+			// An IP address identifier is screened as one, rather than as a
+			// DNS name.
+			ident := identifier.NewIP(netip.MustParseAddr("192.0.2.1"))
+			req := createValidationRequest(ident.Value, core.ChallengeTypeHTTP01)
+			req.Identifier = ident.AsProto()
+			res, err := runner.run(context.Background(), va, req)
+			test.AssertNotError(t, err, "validation failed")
+			test.AssertNotNil(t, res.Problem, "denied validation should have a problem")
+			test.AssertEquals(t, checker.ident, ident)
+		})
+	}
+}
+
+func TestHTTPRiskChecker(t *testing.T) {
+	t.Parallel()
+
+	// Each request the server receives is passed back to the test, since
+	// handlers run on their own goroutines. The slow.com handler is held until
+	// the test has seen its request time out.
+	received := make(chan riskCheckRequest, 5)
+	unblockSlow := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req riskCheckRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received <- req
+		switch req.Identifier.Value {
+		case "phish.com":
+			_, _ = w.Write([]byte(`{"decision":"deny","reason":"known phishing domain"}`))
+		case "slow.com":
+			select {
+			case <-unblockSlow:
+			case <-r.Context().Done():
+			}
+			_, _ = w.Write([]byte(`{"decision":"allow"}`))
+		case "broken.com":
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		case "empty.com":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			_, _ = w.Write([]byte(`{"decision":"allow"}`))
+		}
+	}))
+	defer srv.Close()
+
+	checker, err := NewHTTPRiskChecker(srv.URL, 100*time.Millisecond)
+	test.AssertNotError(t, err, "creating HTTPRiskChecker")
+
+	decision, reason, err := checker.Check(context.Background(), identifier.NewDNS("phish.com"), 1234)
+	test.AssertNotError(t, err, "checking phish.com")
+	test.AssertEquals(t, decision, RiskDeny)
+	test.AssertEquals(t, reason, "known phishing domain")
+	test.AssertEquals(t, <-received, riskCheckRequest{Identifier: identifier.NewDNS("phish.com"), RegistrationID: 1234})
+
+	decision, _, err = checker.Check(context.Background(), identifier.NewDNS("example.com"), 1234)
+	test.AssertNotError(t, err, "checking example.com")
+	test.AssertEquals(t, decision, RiskAllow)
+
+	_, _, err = checker.Check(context.Background(), identifier.NewDNS("slow.com"), 1234)
+	test.AssertError(t, err, "risk check should have timed out")
+	close(unblockSlow)
+
+	_, _, err = checker.Check(context.Background(), identifier.NewDNS("broken.com"), 1234)
+	test.AssertError(t, err, "risk check should have failed")
+	test.AssertContains(t, err.Error(), "status 500")
+
+	_, _, err = checker.Check(context.Background(), identifier.NewDNS("empty.com"), 1234)
+	test.AssertError(t, err, "risk check without a decision should have failed")
+
+	for _, endpoint := range []string{"", "/relative", "ftp://risk.example.com/", "https://"} {
+		_, err := NewHTTPRiskChecker(endpoint, time.Second)
+		test.AssertError(t, err, "NewHTTPRiskChecker accepted "+endpoint)
+	}
+	_, err = NewHTTPRiskChecker(srv.URL, -time.Second)
+	test.AssertError(t, err, "NewHTTPRiskChecker accepted a negative timeout")
+}
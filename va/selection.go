@@ -0,0 +1,259 @@
+package va
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// selectionEWMAWeight is the weight given to each new observation of a
+	// remote perspective's health, relative to all of those before it.
+	selectionEWMAWeight = 0.2
+
+	// minSelectionWeight is the lowest weight a remote perspective can have
+	// when selecting perspectives, so that even a perspective which has
+	// recently failed every operation retains some chance of being chosen.
+	minSelectionWeight = 0.01
+
+	// defaultStaleSelections is the number of selections after which a
+	// remote perspective which has gone unchosen is chosen regardless of its
+	// health, so that its stats don't go stale.
+	defaultStaleSelections = 50
+)
+
+// PerspectiveSelection configures the primary VA to query only a subset of its
+// remote VAs for each operation, rather than all of them. The subset is chosen
+// at random, weighted towards the perspectives which have recently been the
+// most reliable and responsive.
+type PerspectiveSelection struct {
+	// Quorum is the number of remote perspectives which must corroborate each
+	// operation. If zero, every remote VA is queried for every operation.
+	Quorum int
+
+	// Headroom is the number of remote perspectives which are queried in
+	// addition to the Quorum, any of which may fail without failing the
+	// operation.
+	Headroom int
+}
+
+// validate returns an error if the selection can't be satisfied by the
+// configured remote VAs, or would violate the quorum requirements of BRs
+// Section 3.2.2.9.
+func (s PerspectiveSelection) validate(remoteVAs []RemoteVA, minDistinctASNs int) error {
+	if s.Quorum < 0 || s.Headroom < 0 {
+		return fmt.Errorf("perspective selection quorum and headroom must not be negative, got %d and %d", s.Quorum, s.Headroom)
+	}
+	if s.Quorum == 0 {
+		if s.Headroom != 0 {
+			return fmt.Errorf("perspective selection headroom %d is configured without a quorum", s.Headroom)
+		}
+		return nil
+	}
+
+	size := s.Quorum + s.Headroom
+	if size < 3 {
+		return fmt.Errorf("perspective selection must choose at least 3 remote perspectives, got %d", size)
+	}
+	if size > len(remoteVAs) {
+		return fmt.Errorf("perspective selection chooses %d remote perspectives but only %d are configured", size, len(remoteVAs))
+	}
+	if s.Headroom > maxAllowedFailures(size) {
+		return fmt.Errorf("perspective selection headroom %d exceeds the %d failures allowed among %d remote perspectives",
+			s.Headroom, maxAllowedFailures(size), size)
+	}
+	if minDistinctASNs > size {
+		return fmt.Errorf("%d distinct ASNs are required but perspective selection chooses only %d remote perspectives", minDistinctASNs, size)
+	}
+
+	rirSet := make(map[string]struct{})
+	for _, rva := range remoteVAs {
+		rirSet[rva.RIR] = struct{}{}
+	}
+	if len(rirSet) < requiredRIRs {
+		return fmt.Errorf("perspective selection requires remote VAs in at least %d RIRs, but they are configured in only %d", requiredRIRs, len(rirSet))
+	}
+	return nil
+}
+
+// perspectiveHealth tracks the recent health of a single remote perspective.
+type perspectiveHealth struct {
+	// successRate is an exponentially weighted moving average of whether
+	// operations on the perspective succeeded (1) or failed (0).
+	successRate float64
+
+	// latency is an exponentially weighted moving average of the number of
+	// seconds operations on the perspective took to complete.
+	latency float64
+
+	// lastSelected is the number of the most recent selection which chose
+	// the perspective, or zero if it has never been chosen.
+	lastSelected uint64
+}
+
+// weight returns the relative likelihood of the perspective being chosen.
+func (h *perspectiveHealth) weight() float64 {
+	return max(h.successRate/(1+h.latency), minSelectionWeight)
+}
+
+// perspectiveSelector chooses the subset of remote perspectives to query for
+// each operation. It is safe for concurrent use.
+type perspectiveSelector struct {
+	quorum          int
+	headroom        int
+	minDistinctASNs int
+
+	// staleSelections is the number of selections after which a perspective
+	// which has gone unchosen is chosen regardless of its health.
+	staleSelections uint64
+
+	mu         sync.Mutex
+	rng        *rand.Rand
+	selections uint64
+	health     map[string]*perspectiveHealth
+}
+
+// newPerspectiveSelector returns a perspectiveSelector for the provided remote
+// VAs, which must already have been validated against the selection. Every
+// perspective starts out presumed healthy, so that each is tried early on.
+func newPerspectiveSelector(selection PerspectiveSelection, remoteVAs []RemoteVA, minDistinctASNs int, rng *rand.Rand) *perspectiveSelector {
+	health := make(map[string]*perspectiveHealth, len(remoteVAs))
+	for _, rva := range remoteVAs {
+		health[rva.Perspective] = &perspectiveHealth{successRate: 1}
+	}
+	return &perspectiveSelector{
+		quorum:          selection.Quorum,
+		headroom:        selection.Headroom,
+		minDistinctASNs: minDistinctASNs,
+		staleSelections: defaultStaleSelections,
+		rng:             rng,
+		health:          health,
+	}
+}
+
+// choose returns the quorum plus headroom remote VAs to query for an
+// operation. The chosen remote VAs always span at least requiredRIRs RIRs, and
+// at least minDistinctASNs ASNs, so that it remains possible for the operation
+// to satisfy those requirements. Otherwise they're chosen at random, weighted
+// by their health, except that the perspective which has gone unchosen the
+// longest is always chosen once it has gone unchosen for staleSelections
+// selections.
+func (s *perspectiveSelector) choose(remoteVAs []RemoteVA) []RemoteVA {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.selections++
+	size := s.quorum + s.headroom
+	candidates := slices.Clone(remoteVAs)
+	chosen := make([]RemoteVA, 0, size)
+	take := func(i int) {
+		s.health[candidates[i].Perspective].lastSelected = s.selections
+		chosen = append(chosen, candidates[i])
+		candidates = slices.Delete(candidates, i, i+1)
+	}
+
+	stalest := -1
+	for i, rva := range candidates {
+		lastSelected := s.health[rva.Perspective].lastSelected
+		if s.selections-lastSelected <= s.staleSelections {
+			continue
+		}
+		if stalest == -1 || lastSelected < s.health[candidates[stalest].Perspective].lastSelected {
+			stalest = i
+		}
+	}
+	if stalest != -1 {
+		take(stalest)
+	}
+
+	for len(chosen) < size {
+		take(s.pick(candidates, s.eligible(chosen, candidates, size-len(chosen))))
+	}
+	return chosen
+}
+
+// eligible returns the indices of the candidates which may fill the next of
+// the remaining slots. Once the remaining slots are only just enough to reach
+// the required number of distinct RIRs or ASNs, only candidates which add a
+// new one are eligible.
+func (s *perspectiveSelector) eligible(chosen, candidates []RemoteVA, remaining int) []int {
+	rirs := make(map[string]struct{})
+	asns := make(map[uint32]struct{})
+	for _, rva := range chosen {
+		rirs[rva.RIR] = struct{}{}
+		if rva.ASN != 0 {
+			asns[rva.ASN] = struct{}{}
+		}
+	}
+
+	all := make([]int, len(candidates))
+	for i := range candidates {
+		all[i] = i
+	}
+	filter := func(indices []int, keep func(RemoteVA) bool) []int {
+		var kept []int
+		for _, i := range indices {
+			if keep(candidates[i]) {
+				kept = append(kept, i)
+			}
+		}
+		if len(kept) == 0 {
+			// The constraint can't be satisfied, so don't apply it.
+			return indices
+		}
+		return kept
+	}
+
+	indices := all
+	if requiredRIRs-len(rirs) >= remaining {
+		indices = filter(indices, func(rva RemoteVA) bool {
+			_, ok := rirs[rva.RIR]
+			return !ok
+		})
+	}
+	if s.minDistinctASNs-len(asns) >= remaining {
+		indices = filter(indices, func(rva RemoteVA) bool {
+			_, ok := asns[rva.ASN]
+			return rva.ASN != 0 && !ok
+		})
+	}
+	return indices
+}
+
+// pick returns one of the provided indices of candidates, chosen at random and
+// weighted by the health of each candidate.
+func (s *perspectiveSelector) pick(candidates []RemoteVA, indices []int) int {
+	var total float64
+	for _, i := range indices {
+		total += s.health[candidates[i].Perspective].weight()
+	}
+	r := s.rng.Float64() * total
+	for _, i := range indices {
+		r -= s.health[candidates[i].Perspective].weight()
+		if r < 0 {
+			return i
+		}
+	}
+	// Floating point rounding may leave a sliver of r behind.
+	return indices[len(indices)-1]
+}
+
+// record updates the health of the perspective with the outcome of an
+// operation on it.
+func (s *perspectiveSelector) record(perspective string, success bool, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.health[perspective]
+	if !ok {
+		return
+	}
+	var observed float64
+	if success {
+		observed = 1
+	}
+	h.successRate += selectionEWMAWeight * (observed - h.successRate)
+	h.latency += selectionEWMAWeight * (latency.Seconds() - h.latency)
+}
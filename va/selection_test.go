@@ -0,0 +1,275 @@
+package va
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// selectionRemotes returns remote VAs, without clients, in the provided RIRs.
+func selectionRemotes(rirs ...string) []RemoteVA {
+	var remoteVAs []RemoteVA
+	for i, rir := range rirs {
+		remoteVAs = append(remoteVAs, RemoteVA{
+			Perspective: fmt.Sprintf("dc-%d-%s", i, rir),
+			RIR:         rir,
+		})
+	}
+	return remoteVAs
+}
+
+func TestPerspectiveSelectionValidate(t *testing.T) {
+	t.Parallel()
+
+	remoteVAs := selectionRemotes(arin, arin, arin, arin, ripe, apnic, arin, arin)
+
+	testCases := []struct {
+		name            string
+		selection       PerspectiveSelection
+		remoteVAs       []RemoteVA
+		minDistinctASNs int
+		expectErr       bool
+	}{
+		{
+			name:      "disabled",
+			selection: PerspectiveSelection{},
+			remoteVAs: remoteVAs,
+		},
+		{
+			name:      "quorum and headroom",
+			selection: PerspectiveSelection{Quorum: 4, Headroom: 2},
+			remoteVAs: remoteVAs,
+		},
+		{
+			name:      "every remote",
+			selection: PerspectiveSelection{Quorum: 6, Headroom: 2},
+			remoteVAs: remoteVAs,
+		},
+		{
+			name:      "negative quorum",
+			selection: PerspectiveSelection{Quorum: -1},
+			remoteVAs: remoteVAs,
+			expectErr: true,
+		},
+		{
+			name:      "negative headroom",
+			selection: PerspectiveSelection{Quorum: 3, Headroom: -1},
+			remoteVAs: remoteVAs,
+			expectErr: true,
+		},
+		{
+			name:      "headroom without quorum",
+			selection: PerspectiveSelection{Headroom: 2},
+			remoteVAs: remoteVAs,
+			expectErr: true,
+		},
+		{
+			name:      "too few chosen",
+			selection: PerspectiveSelection{Quorum: 2},
+			remoteVAs: remoteVAs,
+			expectErr: true,
+		},
+		{
+			name:      "more chosen than configured",
+			selection: PerspectiveSelection{Quorum: 7, Headroom: 2},
+			remoteVAs: remoteVAs,
+			expectErr: true,
+		},
+		{
+			name:      "too much headroom",
+			selection: PerspectiveSelection{Quorum: 2, Headroom: 2},
+			remoteVAs: remoteVAs,
+			expectErr: true,
+		},
+		{
+			name:            "more ASNs required than chosen",
+			selection:       PerspectiveSelection{Quorum: 3},
+			remoteVAs:       remoteVAs,
+			minDistinctASNs: 4,
+			expectErr:       true,
+		},
+		{
+			name:      "single RIR",
+			selection: PerspectiveSelection{Quorum: 3},
+			remoteVAs: selectionRemotes(arin, arin, arin, arin),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.selection.validate(tc.remoteVAs, tc.minDistinctASNs)
+			if tc.expectErr {
+				test.AssertError(t, err, "validate allowed an invalid selection")
+			} else {
+				test.AssertNotError(t, err, "validate rejected a valid selection")
+			}
+		})
+	}
+}
+
+func TestPerspectiveSelectorSatisfiesRIRs(t *testing.T) {
+	t.Parallel()
+
+	// Only two of the eight remotes are outside ARIN, and both are unhealthy,
+	// so weighting alone would almost never choose them.
+	remoteVAs := selectionRemotes(arin, arin, arin, arin, arin, arin, ripe, apnic)
+	s := newPerspectiveSelector(PerspectiveSelection{Quorum: 2, Headroom: 1}, remoteVAs, 0, rand.New(rand.NewPCG(1, 2)))
+	for range 20 {
+		s.record(remoteVAs[6].Perspective, false, time.Second)
+		s.record(remoteVAs[7].Perspective, false, time.Second)
+	}
+
+	for i := range 1000 {
+		chosen := s.choose(remoteVAs)
+		test.AssertEquals(t, len(chosen), 3)
+
+		perspectives := make(map[string]struct{})
+		rirs := make(map[string]struct{})
+		for _, rva := range chosen {
+			perspectives[rva.Perspective] = struct{}{}
+			rirs[rva.RIR] = struct{}{}
+		}
+		test.AssertEquals(t, len(perspectives), 3)
+		test.Assert(t, len(rirs) >= requiredRIRs, fmt.Sprintf("selection %d spans only %d RIRs: %v", i, len(rirs), chosen))
+	}
+}
+
+func TestPerspectiveSelectorSatisfiesASNs(t *testing.T) {
+	t.Parallel()
+
+	remoteVAs := selectionRemotes(arin, arin, arin, arin, ripe, apnic)
+	for i := range remoteVAs {
+		remoteVAs[i].ASN = 100
+	}
+	remoteVAs[3].ASN = 200
+	remoteVAs[5].ASN = 300
+
+	s := newPerspectiveSelector(PerspectiveSelection{Quorum: 2, Headroom: 1}, remoteVAs, 3, rand.New(rand.NewPCG(1, 2)))
+	for range 1000 {
+		asns := make(map[uint32]struct{})
+		for _, rva := range s.choose(remoteVAs) {
+			asns[rva.ASN] = struct{}{}
+		}
+		test.AssertEquals(t, len(asns), 3)
+	}
+}
+
+func TestPerspectiveSelectorRotation(t *testing.T) {
+	t.Parallel()
+
+	remoteVAs := selectionRemotes(arin, ripe, apnic, arin, ripe, apnic, arin, ripe)
+	s := newPerspectiveSelector(PerspectiveSelection{Quorum: 2, Headroom: 1}, remoteVAs, 0, rand.New(rand.NewPCG(3, 4)))
+	s.staleSelections = 10
+
+	// Make one perspective so unhealthy that it would rarely, if ever, be
+	// chosen on its merits.
+	unhealthy := remoteVAs[0].Perspective
+	for range 50 {
+		s.record(unhealthy, false, 10*time.Second)
+	}
+
+	lastChosen := make(map[string]int)
+	maxGap := make(map[string]int)
+	for i := 1; i <= 500; i++ {
+		for _, rva := range s.choose(remoteVAs) {
+			maxGap[rva.Perspective] = max(maxGap[rva.Perspective], i-lastChosen[rva.Perspective])
+			lastChosen[rva.Perspective] = i
+		}
+		// Keep the unhealthy perspective unhealthy, even when it's rotated in.
+		s.record(unhealthy, false, 10*time.Second)
+	}
+
+	for _, rva := range remoteVAs {
+		_, ok := lastChosen[rva.Perspective]
+		test.Assert(t, ok, fmt.Sprintf("%s was never chosen", rva.Perspective))
+		// The stalest perspective is only forced in once per selection, so
+		// allow for another perspective going stale at the same time.
+		test.Assert(t, maxGap[rva.Perspective] <= 2*int(s.staleSelections),
+			fmt.Sprintf("%s went unchosen for %d selections", rva.Perspective, maxGap[rva.Perspective]))
+	}
+	test.Assert(t, maxGap[unhealthy] > int(s.staleSelections)/2,
+		fmt.Sprintf("unhealthy perspective %s was chosen every %d selections", unhealthy, maxGap[unhealthy]))
+}
+
+func TestPerspectiveSelectorPrefersHealthy(t *testing.T) {
+	t.Parallel()
+
+	remoteVAs := selectionRemotes(arin, ripe, apnic, arin, ripe, apnic)
+	s := newPerspectiveSelector(PerspectiveSelection{Quorum: 2, Headroom: 1}, remoteVAs, 0, rand.New(rand.NewPCG(5, 6)))
+	s.staleSelections = 1000
+
+	slow := remoteVAs[3].Perspective
+	failing := remoteVAs[4].Perspective
+	for range 20 {
+		for _, rva := range remoteVAs {
+			switch rva.Perspective {
+			case slow:
+				s.record(rva.Perspective, true, 5*time.Second)
+			case failing:
+				s.record(rva.Perspective, false, 100*time.Millisecond)
+			default:
+				s.record(rva.Perspective, true, 100*time.Millisecond)
+			}
+		}
+	}
+
+	counts := make(map[string]int)
+	for range 600 {
+		for _, rva := range s.choose(remoteVAs) {
+			counts[rva.Perspective]++
+		}
+	}
+	healthy := counts[remoteVAs[0].Perspective]
+	test.Assert(t, counts[slow] < healthy/2, fmt.Sprintf("slow perspective chosen %d times, healthy %d", counts[slow], healthy))
+	test.Assert(t, counts[failing] < healthy/10, fmt.Sprintf("failing perspective chosen %d times, healthy %d", counts[failing], healthy))
+}
+
+func TestPerspectiveSelectorIsDeterministic(t *testing.T) {
+	t.Parallel()
+
+	remoteVAs := selectionRemotes(arin, ripe, apnic, arin, ripe, apnic, arin, ripe)
+	newSelector := func() *perspectiveSelector {
+		return newPerspectiveSelector(PerspectiveSelection{Quorum: 4, Headroom: 2}, remoteVAs, 0, rand.New(rand.NewPCG(7, 8)))
+	}
+	a, b := newSelector(), newSelector()
+	for range 100 {
+		test.AssertDeepEquals(t, a.choose(remoteVAs), b.choose(remoteVAs))
+	}
+}
+
+func TestMultiVAPerspectiveSelection(t *testing.T) {
+	t.Parallel()
+
+	ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true})
+	defer ms.Close()
+
+	va, mockLog := setupWithRemotes(ms.Server, pass, []remoteConf{
+		{ua: pass, rir: arin},
+		{ua: pass, rir: ripe},
+		{ua: pass, rir: apnic},
+		{ua: pass, rir: arin},
+		{ua: pass, rir: ripe},
+		{ua: pass, rir: apnic},
+	}, nil)
+	va.selector = newPerspectiveSelector(PerspectiveSelection{Quorum: 2, Headroom: 1}, va.remoteVAs, 0, rand.New(rand.NewPCG(1, 2)))
+
+	req := createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01)
+	res, err := runDoDCV(ctx, va, req)
+	test.AssertNotError(t, err, "performing validation")
+	test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed with: %#v", res.Problem))
+
+	gotAuditLog := parseValidationLogEvent(t, mockLog.GetAllMatching(`Validation result JSON=.*`))
+	test.AssertEquals(t, len(gotAuditLog.Summary.Passed)+len(gotAuditLog.Summary.Failed), 3)
+	test.AssertMetricWithLabelsEquals(t, va.metrics.remoteVASelections, prometheus.Labels{}, 3)
+	for _, perspective := range gotAuditLog.Summary.Passed {
+		test.AssertMetricWithLabelsEquals(t, va.metrics.remoteVASelections, prometheus.Labels{"perspective": perspective}, 1)
+	}
+}
@@ -0,0 +1,144 @@
+package va
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/letsencrypt/boulder/core"
+)
+
+// validationPhase names a part of a validation, so that the latency of slow
+// validations can be attributed to it.
+type validationPhase string
+
+const (
+	// phaseDNS is time spent resolving the identifier, or looking up its
+	// DNS-01 TXT records.
+	phaseDNS = validationPhase("dns")
+	// phaseConnect is time spent establishing TCP connections and, for
+	// TLS-ALPN-01, completing the TLS handshake.
+	phaseConnect = validationPhase("connect")
+	// phaseFetch is time spent making HTTP-01 requests and reading their
+	// responses, excluding the time spent connecting.
+	phaseFetch = validationPhase("fetch")
+	// phaseRemoteQuorum is time spent waiting for the remote VAs to reach a
+	// quorum.
+	phaseRemoteQuorum = validationPhase("remote_quorum")
+)
+
+// phaseTimings accumulates the time spent in each phase of a single
+// validation. It is carried in the validation's context so that the functions
+// doing the work can record their latency without threading it through every
+// signature. All methods are safe for concurrent use, and on a nil
+// *phaseTimings, which records nothing.
+type phaseTimings struct {
+	sync.Mutex
+	durations map[validationPhase]time.Duration
+}
+
+type phaseTimingsKey struct{}
+
+// withPhaseTimings returns a child context carrying a new, empty
+// *phaseTimings, which is also returned.
+func withPhaseTimings(ctx context.Context) (context.Context, *phaseTimings) {
+	timings := &phaseTimings{durations: make(map[validationPhase]time.Duration)}
+	return context.WithValue(ctx, phaseTimingsKey{}, timings), timings
+}
+
+// phaseTimingsFrom returns the *phaseTimings carried by ctx, or nil.
+func phaseTimingsFrom(ctx context.Context) *phaseTimings {
+	timings, _ := ctx.Value(phaseTimingsKey{}).(*phaseTimings)
+	return timings
+}
+
+// add adds d to the time spent in phase.
+func (p *phaseTimings) add(phase validationPhase, d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.durations[phase] += d
+}
+
+// get returns the time spent in phase so far.
+func (p *phaseTimings) get(phase validationPhase) time.Duration {
+	if p == nil {
+		return 0
+	}
+	p.Lock()
+	defer p.Unlock()
+	return p.durations[phase]
+}
+
+// seconds returns the time spent in each phase, in seconds, for logging.
+func (p *phaseTimings) seconds() map[validationPhase]float64 {
+	if p == nil {
+		return nil
+	}
+	p.Lock()
+	defer p.Unlock()
+	if len(p.durations) == 0 {
+		return nil
+	}
+	seconds := make(map[validationPhase]float64, len(p.durations))
+	for phase, d := range p.durations {
+		seconds[phase] = d.Round(time.Millisecond).Seconds()
+	}
+	return seconds
+}
+
+// slowest returns the phase in which the most time was spent, and that time.
+// Ties are broken in favour of the phase which comes first in a validation.
+func (p *phaseTimings) slowest() (validationPhase, time.Duration) {
+	if p == nil {
+		return "", 0
+	}
+	p.Lock()
+	defer p.Unlock()
+	var slowest validationPhase
+	var longest time.Duration
+	for _, phase := range []validationPhase{phaseDNS, phaseConnect, phaseFetch, phaseRemoteQuorum} {
+		if p.durations[phase] > longest {
+			slowest, longest = phase, p.durations[phase]
+		}
+	}
+	return slowest, longest
+}
+
+// sloBreachEvent is logged when a validation succeeds, but takes longer than
+// the VA's SLO threshold.
+type sloBreachEvent struct {
+	AuthzID       string
+	Identifier    string
+	ChallengeType core.AcmeChallenge
+	Perspective   string
+	Latency       float64
+	Threshold     float64
+	SlowestPhase  validationPhase
+	Phases        map[validationPhase]float64
+}
+
+// checkValidationSLO logs a warning and increments the validation_slo_breaches
+// metric if a successful validation took longer than the VA's SLO threshold.
+func (va *ValidationAuthorityImpl) checkValidationSLO(authzID, ident string, challType core.AcmeChallenge, latency time.Duration, timings *phaseTimings) {
+	if latency <= va.sloThreshold {
+		return
+	}
+	slowest, _ := timings.slowest()
+	if slowest == "" {
+		slowest = "unknown"
+	}
+	va.metrics.validationSLOBreaches.WithLabelValues(string(slowest), string(challType)).Inc()
+	va.log.WarningObject("Validation exceeded SLO", sloBreachEvent{
+		AuthzID:       authzID,
+		Identifier:    ident,
+		ChallengeType: challType,
+		Perspective:   va.perspective,
+		Latency:       latency.Round(time.Millisecond).Seconds(),
+		Threshold:     va.sloThreshold.Seconds(),
+		SlowestPhase:  slowest,
+		Phases:        timings.seconds(),
+	})
+}
@@ -0,0 +1,182 @@
+package va
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxReportedTargets is the number of target IP addresses, those with the
+// most open connections, whose connections are reported by the
+// validation_target_connections gauge. Reporting every target would give the
+// gauge unbounded cardinality.
+const maxReportedTargets = 10
+
+// targetConnLimiter bounds the number of connections the VA holds open to each
+// target IP address at once, across every HTTP-01 and TLS-ALPN-01 validation
+// in flight. A single origin serving many Subscribers' domains would otherwise
+// receive a storm of connections from the VA during a mass renewal.
+// Connections which would exceed the limit wait up to maxWait for another to
+// close, and then fail with a targetBusyError.
+type targetConnLimiter struct {
+	maxConns int
+	maxWait  time.Duration
+	clk      clock.Clock
+
+	mu      sync.Mutex
+	targets map[netip.Addr]*targetConns
+
+	connections *prometheus.Desc
+	waits       *prometheus.CounterVec
+}
+
+// targetConns holds the connection slots of a single target IP address. It's
+// forgotten once no connection holds or awaits one of its slots.
+type targetConns struct {
+	slots chan struct{}
+	users int
+}
+
+// newTargetConnLimiter returns a targetConnLimiter which allows up to
+// maxConns connections to each target IP address at once.
+func newTargetConnLimiter(maxConns int, maxWait time.Duration, clk clock.Clock, stats prometheus.Registerer) *targetConnLimiter {
+	waits := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "validation_target_connection_waits",
+		Help: "A counter of connections which waited because the VA was at its limit of connections to the target IP address, labelled by result=[acquired|timed_out]",
+	}, []string{"result"})
+	stats.MustRegister(waits)
+
+	l := &targetConnLimiter{
+		maxConns: maxConns,
+		maxWait:  maxWait,
+		clk:      clk,
+		targets:  make(map[netip.Addr]*targetConns),
+		connections: prometheus.NewDesc(
+			"validation_target_connections",
+			fmt.Sprintf("Number of connections open to each of the %d target IP addresses with the most", maxReportedTargets),
+			[]string{"ip"}, nil),
+		waits: waits,
+	}
+	stats.MustRegister(l)
+	return l
+}
+
+// Describe implements prometheus.Collector.
+func (l *targetConnLimiter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- l.connections
+}
+
+// Collect implements prometheus.Collector. It reports the number of
+// connections open to the maxReportedTargets target IP addresses with the
+// most.
+func (l *targetConnLimiter) Collect(ch chan<- prometheus.Metric) {
+	type target struct {
+		ip    netip.Addr
+		conns int
+	}
+	l.mu.Lock()
+	targets := make([]target, 0, len(l.targets))
+	for ip, t := range l.targets {
+		if len(t.slots) > 0 {
+			targets = append(targets, target{ip, len(t.slots)})
+		}
+	}
+	l.mu.Unlock()
+
+	slices.SortFunc(targets, func(a, b target) int {
+		return cmp.Or(cmp.Compare(b.conns, a.conns), a.ip.Compare(b.ip))
+	})
+	for _, t := range targets[:min(len(targets), maxReportedTargets)] {
+		ch <- prometheus.MustNewConstMetric(l.connections, prometheus.GaugeValue, float64(t.conns), t.ip.String())
+	}
+}
+
+// acquire waits for a slot for a connection to ip, for up to the limiter's
+// maxWait. On success, the caller must call the returned function once the
+// connection is closed. If no slot becomes available in time, or ctx is done
+// first, it returns a targetBusyError. It is safe to call on a nil
+// *targetConnLimiter, which allows every connection.
+func (l *targetConnLimiter) acquire(ctx context.Context, ip netip.Addr) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	ip = ip.Unmap()
+
+	l.mu.Lock()
+	t, ok := l.targets[ip]
+	if !ok {
+		t = &targetConns{slots: make(chan struct{}, l.maxConns)}
+		l.targets[ip] = t
+	}
+	t.users++
+	l.mu.Unlock()
+
+	release := func() {
+		<-t.slots
+		l.forget(ip, t)
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+		return sync.OnceFunc(release), nil
+	default:
+	}
+
+	timer := l.clk.NewTimer(l.maxWait)
+	defer timer.Stop()
+	select {
+	case t.slots <- struct{}{}:
+		l.waits.WithLabelValues("acquired").Inc()
+		return sync.OnceFunc(release), nil
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+
+	l.forget(ip, t)
+	l.waits.WithLabelValues("timed_out").Inc()
+	return nil, targetBusyError{ip: ip, maxConns: l.maxConns}
+}
+
+// forget notes that a connection to ip no longer holds or awaits a slot of t,
+// and forgets t if no other connection does.
+func (l *targetConnLimiter) forget(ip netip.Addr, t *targetConns) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t.users--
+	if t.users == 0 {
+		delete(l.targets, ip)
+	}
+}
+
+// targetBusyError is returned when a connection to a target IP address isn't
+// made because the VA already held as many connections to it as it may, and
+// none of them closed in time. It's the VA's problem, not the Subscriber's.
+type targetBusyError struct {
+	ip       netip.Addr
+	maxConns int
+}
+
+func (e targetBusyError) Error() string {
+	return fmt.Sprintf("the VA is at its limit of %d connections to %s", e.maxConns, e.ip)
+}
+
+// limitedConn is a connection which holds a targetConnLimiter slot, and
+// releases it when closed.
+type limitedConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.release()
+	return err
+}
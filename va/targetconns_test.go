@@ -0,0 +1,214 @@
+package va
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+	vapb "github.com/letsencrypt/boulder/va/proto"
+)
+
+// advanceUntil advances fc by d until a value is received from done, for a
+// call which gives up waiting when a timer of fc, which it may not have
+// created yet, fires.
+func advanceUntil[T any](fc clock.FakeClock, d time.Duration, done <-chan T) T {
+	for {
+		select {
+		case v := <-done:
+			return v
+		default:
+			fc.Add(d)
+			runtime.Gosched()
+		}
+	}
+}
+
+func TestTargetConnLimiter(t *testing.T) {
+	t.Parallel()
+
+	fc := clock.NewFake()
+	l := newTargetConnLimiter(maxReportedTargets+2, 10*time.Millisecond, fc, metrics.NoopRegisterer)
+
+	// Open i+1 connections to each of a dozen addresses, so that the gauge
+	// only reports the ten with the most.
+	var releases []func()
+	for i := range maxReportedTargets + 2 {
+		ip := netip.AddrFrom4([4]byte{10, 0, 0, byte(i)})
+		for range i + 1 {
+			release, err := l.acquire(context.Background(), ip)
+			test.AssertNotError(t, err, "acquiring connection slot")
+			releases = append(releases, release)
+		}
+	}
+	test.AssertMetricWithLabelsEquals(t, l, prometheus.Labels{"ip": "10.0.0.11"}, 12)
+	test.AssertMetricWithLabelsEquals(t, l, prometheus.Labels{"ip": "10.0.0.2"}, 3)
+	test.AssertMetricWithLabelsEquals(t, l, prometheus.Labels{"ip": "10.0.0.1"}, 0)
+	test.AssertMetricWithLabelsEquals(t, l, prometheus.Labels{"ip": "10.0.0.0"}, 0)
+
+	// The busiest address is at its limit, so another connection times out
+	// waiting. An IPv4-mapped IPv6 address is the same target.
+	acquired := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(context.Background(), netip.MustParseAddr("::ffff:10.0.0.11"))
+		acquired <- err
+	}()
+	err := advanceUntil(fc, l.maxWait, acquired)
+	test.AssertError(t, err, "acquired a connection slot beyond the limit")
+	test.AssertEquals(t, err.Error(), "the VA is at its limit of 12 connections to 10.0.0.11")
+	test.AssertMetricWithLabelsEquals(t, l.waits, prometheus.Labels{"result": "timed_out"}, 1)
+
+	// Releasing a slot more than once frees it only once.
+	releases[len(releases)-1]()
+	releases[len(releases)-1]()
+	test.AssertMetricWithLabelsEquals(t, l, prometheus.Labels{"ip": "10.0.0.11"}, 11)
+
+	for _, release := range releases {
+		release()
+	}
+	test.AssertMetricWithLabelsEquals(t, l, prometheus.Labels{}, 0)
+	test.AssertEquals(t, len(l.targets), 0)
+
+	var nilLimiter *targetConnLimiter
+	release, err := nilLimiter.acquire(context.Background(), netip.MustParseAddr("10.0.0.1"))
+	test.AssertNotError(t, err, "nil limiter refused a connection")
+	release()
+}
+
+// slowConcurrencySrv returns a server which holds each request for delay
+// before responding with the expected key authorization, and a function which
+// reports the most requests it held at once.
+func slowConcurrencySrv(delay time.Duration) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	var inFlight, peak int
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(delay)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		fmt.Fprint(w, expectedKeyAuthorization)
+	}))
+	return hs, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return peak
+	}
+}
+
+func TestTargetConnLimitHoldsUnderLoad(t *testing.T) {
+	const maxConns = 10
+	const validations = 50
+
+	hs, peak := slowConcurrencySrv(100 * time.Millisecond)
+	defer hs.Close()
+
+	va, _ := setup(hs, "", nil, nil)
+	va.targetConns = newTargetConnLimiter(maxConns, 10*time.Second, va.clk, metrics.NoopRegisterer)
+
+	// Every validation is of a different identifier, all of which resolve to
+	// the same address.
+	var wg sync.WaitGroup
+	results := make([]*vapb.ValidationResult, validations)
+	errs := make([]error, validations)
+	for i := range validations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := createValidationRequest("localhost", core.ChallengeTypeHTTP01)
+			req.Authz.Id = fmt.Sprint(i)
+			results[i], errs[i] = va.PerformValidation(ctx, req)
+		}()
+	}
+	wg.Wait()
+
+	for i := range validations {
+		test.AssertNotError(t, errs[i], "validation failed")
+		test.Assert(t, results[i].Problem == nil, fmt.Sprintf("validation %d had a problem: %v", i, results[i].Problem))
+	}
+	test.AssertEquals(t, peak(), maxConns)
+	awaitNoTargets(t, va.targetConns)
+}
+
+// awaitNoTargets waits for l to forget every target, once the HTTP client
+// has closed each connection, which it does in the background.
+func awaitNoTargets(t *testing.T, l *targetConnLimiter) {
+	t.Helper()
+	for range 100000 {
+		l.mu.Lock()
+		n := len(l.targets)
+		l.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		runtime.Gosched()
+	}
+	t.Fatalf("timed out waiting for every target to be forgotten")
+}
+
+func TestTargetConnLimitTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	arrived := make(chan struct{}, 1)
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-unblock
+		fmt.Fprint(w, expectedKeyAuthorization)
+	}))
+	defer hs.Close()
+
+	va, _ := setup(hs, "", nil, nil)
+	va.targetConns = newTargetConnLimiter(1, 50*time.Millisecond, va.clk, metrics.NoopRegisterer)
+
+	// Hold the only connection slot with a validation which won't complete
+	// until the server is unblocked.
+	var wg sync.WaitGroup
+	var first *vapb.ValidationResult
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		req := createValidationRequest("localhost", core.ChallengeTypeHTTP01)
+		req.Authz.Id = "1"
+		first, _ = va.PerformValidation(ctx, req)
+	}()
+	<-arrived
+
+	// Another validation against the same address gives up waiting, and the
+	// VA, not the Subscriber, is blamed.
+	type result struct {
+		res *vapb.ValidationResult
+		err error
+	}
+	second := make(chan result, 1)
+	go func() {
+		req := createValidationRequest("localhost", core.ChallengeTypeHTTP01)
+		req.Authz.Id = "2"
+		res, err := va.PerformValidation(ctx, req)
+		second <- result{res, err}
+	}()
+	r := advanceUntil(va.clk.(clock.FakeClock), va.targetConns.maxWait, second)
+	res, err := r.res, r.err
+	test.AssertNotError(t, err, "validation failed")
+	test.AssertNotNil(t, res.Problem, "validation beyond the connection limit should have a problem")
+	test.AssertEquals(t, res.Problem.ProblemType, string(probs.ServerInternalProblem))
+	test.AssertContains(t, res.Problem.Detail, "at its connection limit for 127.0.0.1; please retry")
+	test.AssertMetricWithLabelsEquals(t, va.targetConns.waits, prometheus.Labels{"result": "timed_out"}, 1)
+
+	close(unblock)
+	wg.Wait()
+	test.Assert(t, first.Problem == nil, "validation holding the connection slot should have succeeded")
+}
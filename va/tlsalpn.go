@@ -12,9 +12,16 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/netip"
 	"strconv"
 	"strings"
+	"syscall"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/idna"
 
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
@@ -59,15 +66,20 @@ func certAltNames(cert *x509.Certificate) []string {
 func (va *ValidationAuthorityImpl) tryGetChallengeCert(
 	ctx context.Context,
 	identifier identifier.ACMEIdentifier,
+	port int,
 	tlsConfig *tls.Config,
 ) (*x509.Certificate, *tls.ConnectionState, core.ValidationRecord, error) {
 
-	allAddrs, resolvers, err := va.getAddrs(ctx, identifier.Value)
+	allAddrs, lookup, err := va.getAddrs(ctx, identifier.Value)
 	validationRecord := core.ValidationRecord{
 		DnsName:           identifier.Value,
 		AddressesResolved: allAddrs,
-		Port:              strconv.Itoa(va.tlsPort),
-		ResolverAddrs:     resolvers,
+		Port:              strconv.Itoa(port),
+		ResolverAddrs:     lookup.resolvers.Strings(),
+		DNSQueries:        dnsQueries(lookup.resolvers),
+		DNSAnswerDigest:   lookup.digest,
+		DNSQueriedAt:      lookup.queriedAt,
+		ProxyProtocol:     va.proxyProtocolSource.IsValid(),
 	}
 	if err != nil {
 		return nil, nil, validationRecord, err
@@ -86,6 +98,7 @@ func (va *ValidationAuthorityImpl) tryGetChallengeCert(
 	if len(v6) > 0 {
 		address := net.JoinHostPort(v6[0].String(), validationRecord.Port)
 		validationRecord.AddressUsed = v6[0]
+		validationRecord.AddressFamily = ipFamily(v6[0])
 
 		cert, cs, err := va.getChallengeCert(ctx, address, identifier, tlsConfig)
 
@@ -113,8 +126,23 @@ func (va *ValidationAuthorityImpl) tryGetChallengeCert(
 	// Otherwise if there are no IPv6 addresses, or there was an error
 	// talking to the first IPv6 address, try the first IPv4 address
 	validationRecord.AddressUsed = v4[0]
+	validationRecord.AddressFamily = ipFamily(v4[0])
 	address := net.JoinHostPort(v4[0].String(), validationRecord.Port)
 	cert, cs, err := va.getChallengeCert(ctx, address, identifier, tlsConfig)
+	var closedErr handshakeClosedError
+	if errors.As(err, &closedErr) {
+		validationRecord.HandshakeBytesSent = closedErr.bytesSent
+		validationRecord.HandshakeBytesReceived = closedErr.bytesReceived
+	}
+	var alpnErr alpnMismatchError
+	if errors.As(err, &alpnErr) {
+		validationRecord.TLSALPNFailure = alpnErr.failure
+		validationRecord.WebServerProtocol = alpnErr.webProtocol
+	}
+	var nameErr unrecognizedNameError
+	if errors.As(err, &nameErr) {
+		validationRecord.TLSALPNFailure = sniUnrecognized
+	}
 	return cert, cs, validationRecord, err
 }
 
@@ -131,23 +159,42 @@ func (va *ValidationAuthorityImpl) getChallengeCert(
 	dialCtx, cancel := context.WithTimeout(ctx, va.singleDialTimeout)
 	defer cancel()
 
-	dialer := &tls.Dialer{Config: config}
-	conn, err := dialer.DialContext(dialCtx, "tcp", hostPort)
-	if err != nil {
+	wrapErr := func(err error) error {
 		va.log.Infof("%s connection failure for %s. err=[%#v] errStr=[%s]", core.ChallengeTypeTLSALPN01, identifier, err, err)
 		host, _, splitErr := net.SplitHostPort(hostPort)
 		if splitErr == nil && net.ParseIP(host) != nil {
 			// Wrap the validation error and the IP of the remote host in an
 			// IPError so we can display the IP in the problem details returned
 			// to the client.
-			return nil, nil, ipError{net.ParseIP(host), err}
+			return ipError{net.ParseIP(host), err}
 		}
-		return nil, nil, err
+		return err
+	}
+
+	connectStart := va.clk.Now()
+	conn, counter, err := va.dialTLS(dialCtx, hostPort, config)
+	phaseTimingsFrom(ctx).add(phaseConnect, va.clk.Since(connectStart))
+	if err != nil {
+		if counter != nil && closedDuringHandshake(err) {
+			err = handshakeClosedError{
+				bytesSent:     counter.written,
+				bytesReceived: counter.read,
+				err:           err,
+			}
+		} else if failure := alpnFailure(err); failure != "" {
+			err = alpnMismatchError{
+				failure:     failure,
+				webProtocol: va.probeWebServer(ctx, hostPort, config.ServerName),
+				err:         err,
+			}
+		} else if isTLSAlert(err, "unrecognized name") {
+			err = unrecognizedNameError{name: config.ServerName, err: err}
+		}
+		return nil, nil, wrapErr(err)
 	}
 	defer conn.Close()
 
-	// tls.Dialer.DialContext guarantees that the *net.Conn it returns is a *tls.Conn.
-	cs := conn.(*tls.Conn).ConnectionState()
+	cs := conn.ConnectionState()
 	certs := cs.PeerCertificates
 	if len(certs) == 0 {
 		va.log.Infof("%s challenge for %s resulted in no certificates", core.ChallengeTypeTLSALPN01, identifier.Value)
@@ -160,9 +207,241 @@ func (va *ValidationAuthorityImpl) getChallengeCert(
 	return certs[0], &cs, nil
 }
 
+// dialTLS connects to hostPort and completes a TLS handshake using config. It
+// dials and handshakes separately, rather than with a tls.Dialer, so that a
+// server which accepts the TCP connection and then closes it during the
+// handshake can be told apart from one which refuses the connection. If the
+// connection was made, the returned *countingConn holds the bytes exchanged,
+// even when the handshake fails. The connection holds one of the VA's slots
+// for connections to the target IP until it's closed.
+func (va *ValidationAuthorityImpl) dialTLS(ctx context.Context, hostPort string, config *tls.Config) (*tls.Conn, *countingConn, error) {
+	release := func() {}
+	addrPort, err := netip.ParseAddrPort(hostPort)
+	if err == nil {
+		release, err = va.targetConns.acquire(ctx, addrPort.Addr())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	dialer := &net.Dialer{Control: va.dialControl}
+	rawConn, err := dialer.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	rawConn = &limitedConn{Conn: rawConn, release: release}
+	if va.proxyProtocolSource.IsValid() {
+		err = writeProxyProtocolHeader(rawConn, va.proxyProtocolSource)
+		if err != nil {
+			rawConn.Close()
+			return nil, nil, err
+		}
+	}
+	counter := &countingConn{Conn: transferBudgetFrom(ctx).wrap(rawConn)}
+	conn := tls.Client(counter, config)
+	err = conn.HandshakeContext(ctx)
+	if err != nil {
+		conn.Close()
+		return nil, counter, err
+	}
+	return conn, counter, nil
+}
+
+// probeWebServer returns the protocol, "h2" or "http/1.1", which the server at
+// hostPort negotiates when offered them as a web browser would, or "" if it
+// negotiates neither. It's used to explain why a server didn't negotiate
+// acme-tls/1: one which negotiates either is a standard web server.
+func (va *ValidationAuthorityImpl) probeWebServer(ctx context.Context, hostPort string, serverName string) string {
+	probeCtx, cancel := context.WithTimeout(ctx, va.singleDialTimeout)
+	defer cancel()
+	conn, _, err := va.dialTLS(probeCtx, hostPort, &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		NextProtos:         []string{"h2", "http/1.1"},
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	return conn.ConnectionState().NegotiatedProtocol
+}
+
+// Reasons, recorded in core.ValidationRecord.TLSALPNFailure, why a TLS-ALPN-01
+// handshake didn't negotiate acme-tls/1 for the requested name.
+const (
+	// alpnRefused means the server refused the handshake with a
+	// no_application_protocol alert, because it doesn't support acme-tls/1.
+	alpnRefused = "alpn_refused"
+
+	// alpnUnadvertised means the server selected a protocol other than
+	// acme-tls/1, which was the only one offered.
+	alpnUnadvertised = "alpn_unadvertised"
+
+	// alpnNotNegotiated means the server completed the handshake without
+	// selecting any protocol, because it ignores ALPN.
+	alpnNotNegotiated = "alpn_not_negotiated"
+
+	// sniUnrecognized means the server refused the handshake with an
+	// unrecognized_name alert, because it isn't configured for the name.
+	sniUnrecognized = "sni_unrecognized"
+)
+
+// isTLSAlert returns true if err is the TLS alert with the provided
+// description, as sent by the server.
+func isTLSAlert(err error, description string) bool {
+	var netOpErr *net.OpError
+	return errors.As(err, &netOpErr) && netOpErr.Op == "remote error" &&
+		fmt.Sprintf("%T", netOpErr.Err) == "tls.alert" && netOpErr.Err.Error() == "tls: "+description
+}
+
+// alpnFailure returns the reason, alpnRefused or alpnUnadvertised, that the
+// provided TLS handshake error shows acme-tls/1 wasn't negotiated, or "" if it
+// doesn't.
+func alpnFailure(err error) string {
+	if isTLSAlert(err, "no application protocol") {
+		return alpnRefused
+	}
+	if err.Error() == "tls: server selected unadvertised ALPN protocol" {
+		return alpnUnadvertised
+	}
+	return ""
+}
+
+// alpnMismatchError is returned when a server doesn't negotiate acme-tls/1
+// during a TLS-ALPN-01 handshake.
+type alpnMismatchError struct {
+	// failure is alpnRefused, alpnUnadvertised or alpnNotNegotiated.
+	failure string
+	// webProtocol is the protocol which the server negotiated when probed
+	// by probeWebServer, if any.
+	webProtocol string
+	// err is the handshake error, if the handshake failed.
+	err error
+}
+
+func (e alpnMismatchError) Error() string {
+	var reason string
+	switch e.failure {
+	case alpnRefused:
+		reason = "the server does not support it (no_application_protocol alert)"
+	case alpnUnadvertised:
+		reason = "the server selected a different protocol, which was not offered"
+	default:
+		reason = "the server completed the handshake without selecting any protocol"
+	}
+	msg := fmt.Sprintf("Cannot negotiate ALPN protocol %q for %s challenge: %s",
+		ACMETLS1Protocol, core.ChallengeTypeTLSALPN01, reason)
+	if e.webProtocol != "" {
+		msg += fmt.Sprintf("; a standard web server offering %q answered instead, "+
+			"which usually means the %s responder isn't receiving connections for this name",
+			e.webProtocol, core.ChallengeTypeTLSALPN01)
+	}
+	return msg
+}
+
+func (e alpnMismatchError) Unwrap() error {
+	return e.err
+}
+
+// unrecognizedNameError is returned when a server refuses a TLS-ALPN-01
+// handshake with an unrecognized_name alert.
+type unrecognizedNameError struct {
+	name string
+	err  error
+}
+
+func (e unrecognizedNameError) Error() string {
+	return fmt.Sprintf("The server does not recognize the requested name %q (unrecognized_name alert); "+
+		"it must be configured to answer %s challenges for this name",
+		e.name, core.ChallengeTypeTLSALPN01)
+}
+
+func (e unrecognizedNameError) Unwrap() error {
+	return e.err
+}
+
+// countingConn is a net.Conn which counts the bytes read from and written to
+// the underlying connection.
+type countingConn struct {
+	net.Conn
+	read    int64
+	written int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read += int64(n)
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written += int64(n)
+	return n, err
+}
+
+// handshakeClosedError is returned when a server accepts the TCP connection
+// but closes it before the TLS handshake completes, without sending an alert.
+type handshakeClosedError struct {
+	bytesSent     int64
+	bytesReceived int64
+	err           error
+}
+
+func (e handshakeClosedError) Error() string {
+	return fmt.Sprintf("Server closed the connection during the TLS handshake (%d bytes sent, %d bytes received); "+
+		"this is often caused by a proxy or load balancer with SNI-based routing that does not recognize the requested name or the %q protocol",
+		e.bytesSent, e.bytesReceived, ACMETLS1Protocol)
+}
+
+func (e handshakeClosedError) Unwrap() error {
+	return e.err
+}
+
+// closedDuringHandshake returns true if the provided TLS handshake error
+// indicates that the peer closed or reset the connection.
+func closedDuringHandshake(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// maxDetailNameLength is the most of a certificate's names echoed back in a
+// problem detail. It is the maximum length of a DNS name.
+const maxDetailNameLength = 253
+
+// quoteForDetail truncates and quotes names taken from a certificate so that
+// they are safe to include in a problem detail. A multibyte character cut by
+// the truncation is dropped.
+func quoteForDetail(names string) string {
+	if len(names) > maxDetailNameLength {
+		names = strings.ToValidUTF8(names[:maxDetailNameLength], "") + "..."
+	}
+	return fmt.Sprintf("%q", names)
+}
+
+// normalizeSAN lowercases a DNS name, strips a single trailing dot and converts
+// any U-labels to A-labels, so that names which differ only in those respects
+// compare as equal.
+func normalizeSAN(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	aLabels, err := idna.ToASCII(name)
+	if err != nil {
+		return name
+	}
+	return aLabels
+}
+
+// checkExpectedSAN checks that the certificate contains exactly one dNSName,
+// that its subjectAltName extension contains nothing else, and that the dNSName
+// matches the identifier being validated after normalization.
 func checkExpectedSAN(cert *x509.Certificate, name identifier.ACMEIdentifier) error {
-	if len(cert.DNSNames) != 1 {
-		return errors.New("wrong number of dNSNames")
+	switch len(cert.DNSNames) {
+	case 0:
+		return errors.New("no dNSNames, expected exactly one")
+	case 1:
+	default:
+		return fmt.Errorf("%d dNSNames (%s), expected exactly one",
+			len(cert.DNSNames), quoteForDetail(strings.Join(cert.DNSNames, ", ")))
 	}
 
 	for _, ext := range cert.Extensions {
@@ -171,13 +450,14 @@ func checkExpectedSAN(cert *x509.Certificate, name identifier.ACMEIdentifier) er
 				{Tag: 2, Class: 2, Bytes: []byte(cert.DNSNames[0])},
 			})
 			if err != nil || !bytes.Equal(expectedSANs, ext.Value) {
-				return errors.New("SAN extension does not match expected bytes")
+				return fmt.Errorf("SAN extension does not match expected bytes (names: %s)",
+					quoteForDetail(strings.Join(certAltNames(cert), ", ")))
 			}
 		}
 	}
 
-	if !strings.EqualFold(cert.DNSNames[0], name.Value) {
-		return errors.New("dNSName does not match expected identifier")
+	if normalizeSAN(cert.DNSNames[0]) != normalizeSAN(name.Value) {
+		return fmt.Errorf("dNSName %s does not match expected identifier", quoteForDetail(cert.DNSNames[0]))
 	}
 
 	return nil
@@ -211,11 +491,16 @@ func (va *ValidationAuthorityImpl) validateTLSALPN01(ctx context.Context, identi
 		return nil, berrors.MalformedError("Identifier type for TLS-ALPN-01 was not DNS")
 	}
 
-	cert, cs, tvr, problem := va.tryGetChallengeCert(ctx, identifier, &tls.Config{
+	fetchCtx, span := va.tracer.Start(ctx, "tls fetch", trace.WithAttributes(
+		attribute.String("host", identifier.Value),
+	))
+	cert, cs, tvr, problem := va.tryGetChallengeCert(fetchCtx, identifier, va.ports.tls, &tls.Config{
 		MinVersion: tls.VersionTLS12,
 		NextProtos: []string{ACMETLS1Protocol},
 		ServerName: identifier.Value,
 	})
+	spanError(span, problem)
+	span.End()
 	// Copy the single validationRecord into the slice that we have to return, and
 	// get a reference to it so we can modify it if we have to.
 	validationRecords := []core.ValidationRecord{tvr}
@@ -225,10 +510,16 @@ func (va *ValidationAuthorityImpl) validateTLSALPN01(ctx context.Context, identi
 	}
 
 	if cs.NegotiatedProtocol != ACMETLS1Protocol {
-		return validationRecords, berrors.UnauthorizedError(
-			"Cannot negotiate ALPN protocol %q for %s challenge",
-			ACMETLS1Protocol,
-			core.ChallengeTypeTLSALPN01)
+		hostPort := net.JoinHostPort(validationRecord.AddressUsed.String(), validationRecord.Port)
+		alpnErr := alpnMismatchError{
+			failure:     alpnNotNegotiated,
+			webProtocol: va.probeWebServer(ctx, hostPort, identifier.Value),
+		}
+		va.log.Infof("%s [%s] server at %s did not negotiate ALPN: %s",
+			core.ChallengeTypeTLSALPN01, identifier, hostPort, alpnErr)
+		validationRecord.TLSALPNFailure = alpnErr.failure
+		validationRecord.WebServerProtocol = alpnErr.webProtocol
+		return validationRecords, alpnErr
 	}
 
 	badCertErr := func(msg string) error {
@@ -262,9 +553,8 @@ func (va *ValidationAuthorityImpl) validateTLSALPN01(ctx context.Context, identi
 	// only the dNSName being validated and no other entries.
 	err = checkExpectedSAN(cert, identifier)
 	if err != nil {
-		names := strings.Join(certAltNames(cert), ", ")
 		return validationRecords, badCertErr(
-			fmt.Sprintf("Received certificate with unexpected identifiers (%q): %q", names, err))
+			fmt.Sprintf("Received certificate with unexpected identifiers: %s.", err))
 	}
 
 	// Verify key authorization in acmeValidation extension
@@ -2,6 +2,8 @@ package va
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/tls"
@@ -10,6 +12,7 @@ import (
 	"encoding/asn1"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math/big"
 	"net"
 	"net/http"
@@ -265,14 +268,12 @@ func TestTLSALPN01Refused(t *testing.T) {
 }
 
 func TestTLSALPN01TalkingToHTTP(t *testing.T) {
-	hs, err := tlsalpn01Srv(t, expectedKeyAuthorization, IdPeAcmeIdentifier, 0, "expected")
-	test.AssertNotError(t, err, "Error creating test server")
-
-	va, _ := setup(hs, "", nil, nil)
 	httpOnly := httpSrv(t, "")
-	va.tlsPort = getPort(httpOnly)
+	ports := defaultValidationPorts()
+	ports.tls = getPort(httpOnly)
+	va, _ := setupWithPorts(ports, "", nil, nil)
 
-	_, err = va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+	_, err := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
 	test.AssertError(t, err, "TLS-SNI-01 validation passed when talking to a HTTP-only server")
 	prob := detailedError(err)
 	expected := "Server only speaks HTTP, not TLS"
@@ -281,6 +282,236 @@ func TestTLSALPN01TalkingToHTTP(t *testing.T) {
 	}
 }
 
+// rawCloseSrv listens on a random local port and passes each connection it
+// accepts to handler, closing the connection once handler returns. It returns
+// the port the listener is bound to.
+func rawCloseSrv(t *testing.T, handler func(net.Conn)) int {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	test.AssertNotError(t, err, "listening")
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			handler(conn)
+			conn.Close()
+		}
+	}()
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+// readClientHello reads a single TLS record from conn.
+func readClientHello(conn net.Conn) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return
+	}
+	_, _ = io.CopyN(io.Discard, conn, int64(header[3])<<8|int64(header[4]))
+}
+
+func TestTLSALPN01ClosedDuringHandshake(t *testing.T) {
+	testCases := []struct {
+		name             string
+		handler          func(net.Conn)
+		expectReceived   int64
+		expectClientSent bool
+	}{
+		{
+			// The server resets only once the ClientHello arrives: a reset
+			// sent straight after accept can reach the client before connect
+			// returns, which is a connection problem rather than a TLS one.
+			name: "reset after ClientHello",
+			handler: func(conn net.Conn) {
+				readClientHello(conn)
+				_ = conn.(*net.TCPConn).SetLinger(0)
+			},
+			expectClientSent: true,
+		},
+		{
+			name:             "closed after ClientHello",
+			handler:          readClientHello,
+			expectClientSent: true,
+		},
+		{
+			name: "closed after partial ServerHello",
+			handler: func(conn net.Conn) {
+				readClientHello(conn)
+				// A handshake record header promising more bytes than are sent.
+				_, _ = conn.Write([]byte{0x16, 0x03, 0x03, 0x00, 0x40, 0x02, 0x00})
+			},
+			expectReceived:   7,
+			expectClientSent: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ports := defaultValidationPorts()
+			ports.tls = rawCloseSrv(t, tc.handler)
+			va, _ := setupWithPorts(ports, "", nil, nil)
+
+			records, err := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+			test.AssertError(t, err, "validation should have failed")
+			prob := detailedError(err)
+			test.AssertEquals(t, prob.Type, probs.TLSProblem)
+			test.AssertContains(t, prob.Detail, "127.0.0.1: Server closed the connection during the TLS handshake")
+			test.AssertContains(t, prob.Detail, "SNI")
+
+			test.AssertEquals(t, len(records), 1)
+			test.AssertEquals(t, records[0].HandshakeBytesReceived, tc.expectReceived)
+			if tc.expectClientSent {
+				test.Assert(t, records[0].HandshakeBytesSent > 0, "expected ClientHello bytes to be recorded")
+			}
+		})
+	}
+
+	// A server which refuses the connection outright is a connection problem,
+	// not a TLS problem, and no handshake bytes are recorded.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	test.AssertNotError(t, err, "listening")
+	ports := defaultValidationPorts()
+	ports.tls = lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+
+	va, _ := setupWithPorts(ports, "", nil, nil)
+	records, err := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+	test.AssertError(t, err, "validation should have failed")
+	prob := detailedError(err)
+	test.AssertEquals(t, prob.Type, probs.ConnectionProblem)
+	test.AssertEquals(t, prob.Detail, "127.0.0.1: Connection refused")
+	test.AssertEquals(t, len(records), 1)
+	test.AssertEquals(t, records[0].HandshakeBytesSent, int64(0))
+	test.AssertEquals(t, records[0].HandshakeBytesReceived, int64(0))
+}
+
+// alpnTestSrv listens on a random local port and completes a TLS handshake,
+// with a self-signed certificate for "expected" and the provided ALPN
+// protocols, on each connection it accepts. It returns the port the listener
+// is bound to.
+func alpnTestSrv(t *testing.T, nextProtos []string) int {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating key")
+	template := tlsCertTemplate([]string{"expected"})
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	test.AssertNotError(t, err, "creating certificate")
+	config := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}},
+		NextProtos:   nextProtos,
+	}
+	return rawCloseSrv(t, func(conn net.Conn) {
+		_ = tls.Server(conn, config).Handshake()
+	})
+}
+
+func TestTLSALPN01WebServerAnswered(t *testing.T) {
+	testCases := []struct {
+		name          string
+		nextProtos    []string
+		expectType    probs.ProblemType
+		expectFailure string
+		expectWeb     string
+		expectDetail  string
+	}{
+		{
+			name:          "web server offering only h2",
+			nextProtos:    []string{"h2"},
+			expectType:    probs.TLSProblem,
+			expectFailure: alpnRefused,
+			expectWeb:     "h2",
+			expectDetail:  "no_application_protocol alert",
+		},
+		{
+			name:          "web server offering only http/1.1",
+			nextProtos:    []string{"http/1.1"},
+			expectType:    probs.TLSProblem,
+			expectFailure: alpnRefused,
+			expectWeb:     "http/1.1",
+			expectDetail:  "no_application_protocol alert",
+		},
+		{
+			name:          "server offering some other protocol",
+			nextProtos:    []string{"imap"},
+			expectType:    probs.TLSProblem,
+			expectFailure: alpnRefused,
+			expectDetail:  "no_application_protocol alert",
+		},
+		{
+			name:          "server ignoring ALPN",
+			expectType:    probs.UnauthorizedProblem,
+			expectFailure: alpnNotNegotiated,
+			expectDetail:  "without selecting any protocol",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ports := defaultValidationPorts()
+			ports.tls = alpnTestSrv(t, tc.nextProtos)
+			va, _ := setupWithPorts(ports, "", nil, nil)
+
+			records, err := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+			test.AssertError(t, err, "validation should have failed")
+			prob := detailedError(err)
+			test.AssertEquals(t, prob.Type, tc.expectType)
+			test.AssertContains(t, prob.Detail, `Cannot negotiate ALPN protocol "acme-tls/1" for tls-alpn-01 challenge`)
+			test.AssertContains(t, prob.Detail, tc.expectDetail)
+			if tc.expectWeb != "" {
+				test.AssertContains(t, prob.Detail, fmt.Sprintf("a standard web server offering %q answered instead", tc.expectWeb))
+			} else {
+				test.AssertNotContains(t, prob.Detail, "standard web server")
+			}
+
+			test.AssertEquals(t, len(records), 1)
+			test.AssertEquals(t, records[0].TLSALPNFailure, tc.expectFailure)
+			test.AssertEquals(t, records[0].WebServerProtocol, tc.expectWeb)
+		})
+	}
+}
+
+func TestTLSALPN01BytesBudget(t *testing.T) {
+	ports := defaultValidationPorts()
+	ports.tls = alpnTestSrv(t, []string{"h2"})
+	va, _ := setupWithPorts(ports, "", nil, nil)
+	// Less than the server's certificate, so that the handshake alone
+	// exceeds the budget.
+	va.maxValidationBytes = 256
+
+	records, err := va.validateChallenge(ctx, dnsi("expected"), 1, core.ChallengeTypeTLSALPN01, "", expectedKeyAuthorization)
+	test.AssertError(t, err, "validation should have exceeded the budget")
+	prob := detailedError(err)
+	test.AssertEquals(t, prob.Type, probs.ConnectionProblem)
+	test.AssertContains(t, prob.Detail, "exceeded the limit of 256 bytes received")
+	test.AssertEquals(t, len(records), 1)
+	test.Assert(t, records[0].BytesReceived > 256, "expected the handshake bytes to be counted")
+	test.AssertMetricWithLabelsEquals(t, va.metrics.validationBytes, prometheus.Labels{"challenge_type": "tls-alpn-01"}, 1)
+}
+
+func TestTLSALPN01UnrecognizedName(t *testing.T) {
+	ports := defaultValidationPorts()
+	ports.tls = rawCloseSrv(t, func(conn net.Conn) {
+		readClientHello(conn)
+		// A fatal unrecognized_name alert record.
+		_, _ = conn.Write([]byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, 0x70})
+	})
+	va, _ := setupWithPorts(ports, "", nil, nil)
+
+	records, err := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+	test.AssertError(t, err, "validation should have failed")
+	prob := detailedError(err)
+	test.AssertEquals(t, prob.Type, probs.TLSProblem)
+	test.AssertContains(t, prob.Detail, `127.0.0.1: The server does not recognize the requested name "expected"`)
+	test.AssertNotContains(t, prob.Detail, "Cannot negotiate ALPN protocol")
+
+	test.AssertEquals(t, len(records), 1)
+	test.AssertEquals(t, records[0].TLSALPNFailure, sniUnrecognized)
+	test.AssertEquals(t, records[0].WebServerProtocol, "")
+}
+
 func brokenTLSSrv() *httptest.Server {
 	server := httptest.NewUnstartedServer(http.DefaultServeMux)
 	server.TLS = &tls.Config{
@@ -388,12 +619,14 @@ func TestTLSALPN01Success(t *testing.T) {
 
 	va, _ := setup(hs, "", nil, nil)
 
-	_, prob := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+	records, prob := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
 	if prob != nil {
 		t.Errorf("Validation failed: %v", prob)
 	}
 	test.AssertMetricWithLabelsEquals(
 		t, va.metrics.tlsALPNOIDCounter, prometheus.Labels{"oid": IdPeAcmeIdentifier.String()}, 1)
+	test.AssertEquals(t, len(records), 1)
+	test.AssertEquals(t, records[0].DNSAnswerDigest, bdns.HostAnswerDigest("expected", records[0].AddressesResolved))
 
 	hs.Close()
 }
@@ -576,6 +809,21 @@ func TestTLSALPN01WrongName(t *testing.T) {
 
 	_, prob := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
 	test.AssertError(t, prob, "validation should have failed")
+	test.AssertContains(t, prob.Error(), `dNSName "incorrect" does not match expected identifier`)
+}
+
+func TestTLSALPN01WrongLongName(t *testing.T) {
+	// Create a cert with a name too long to echo back in full.
+	longName := strings.Repeat("a", 300)
+	hs, err := tlsalpn01Srv(t, expectedKeyAuthorization, IdPeAcmeIdentifier, tls.VersionTLS12, longName)
+	test.AssertNotError(t, err, "failed to set up tls-alpn-01 server")
+
+	va, _ := setup(hs, "", nil, nil)
+
+	_, prob := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+	test.AssertError(t, prob, "validation should have failed")
+	test.AssertContains(t, prob.Error(), fmt.Sprintf(`dNSName "%s..." does not match`, longName[:maxDetailNameLength]))
+	test.AssertNotContains(t, prob.Error(), longName)
 }
 
 func TestTLSALPN01ExtraNames(t *testing.T) {
@@ -587,6 +835,88 @@ func TestTLSALPN01ExtraNames(t *testing.T) {
 
 	_, prob := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
 	test.AssertError(t, prob, "validation should have failed")
+	test.AssertContains(t, prob.Error(), `2 dNSNames ("expected, extra"), expected exactly one`)
+}
+
+func TestCheckExpectedSANTruncatesNames(t *testing.T) {
+	t.Parallel()
+
+	// However many names a certificate has, only maxDetailNameLength bytes of
+	// them are echoed back in the problem detail.
+	var names []string
+	for i := range 100 {
+		names = append(names, fmt.Sprintf("%d.%s.example.com", i, strings.Repeat("a", 50)))
+	}
+	err := checkExpectedSAN(&x509.Certificate{DNSNames: names}, identifier.NewDNS("expected"))
+	test.AssertError(t, err, "certificate with 100 names should be rejected")
+	test.AssertContains(t, err.Error(), fmt.Sprintf(`100 dNSNames ("%s..."), expected exactly one`,
+		strings.Join(names, ", ")[:maxDetailNameLength]))
+
+	// A multibyte character cut by the truncation is dropped rather than
+	// escaped.
+	err = checkExpectedSAN(&x509.Certificate{DNSNames: []string{strings.Repeat("é", maxDetailNameLength)}}, identifier.NewDNS("expected"))
+	test.AssertError(t, err, "certificate with the wrong name should be rejected")
+	test.AssertContains(t, err.Error(), fmt.Sprintf(`dNSName "%s..." does not match`, strings.Repeat("é", maxDetailNameLength/2)))
+}
+
+func TestTLSALPN01NoNames(t *testing.T) {
+	// Create a cert whose subjectAltName extension has no dNSNames at all.
+	template := tlsCertTemplate(nil)
+	template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+
+	shasum := sha256.Sum256([]byte(expectedKeyAuthorization))
+	encHash, err := asn1.Marshal(shasum[:])
+	test.AssertNotError(t, err, "failed to create key authorization")
+	template.ExtraExtensions = []pkix.Extension{{
+		Id:       IdPeAcmeIdentifier,
+		Critical: true,
+		Value:    encHash,
+	}}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &TheKey.PublicKey, &TheKey)
+	test.AssertNotError(t, err, "failed to create acme-tls/1 cert")
+
+	hs := tlsalpn01SrvWithCert(t, &tls.Certificate{
+		Certificate: [][]byte{certBytes},
+		PrivateKey:  &TheKey,
+	}, tls.VersionTLS12)
+
+	va, _ := setup(hs, "", nil, nil)
+
+	_, prob := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+	test.AssertError(t, prob, "validation should have failed")
+	test.AssertContains(t, prob.Error(), "no dNSNames, expected exactly one")
+}
+
+func TestTLSALPN01NormalizedNames(t *testing.T) {
+	testCases := []struct {
+		name  string
+		san   string
+		ident string
+	}{
+		{
+			name:  "uppercase with trailing dot",
+			san:   "EXAMPLE.COM.",
+			ident: "example.com",
+		},
+		{
+			name:  "punycoded SAN for a unicode identifier",
+			san:   "xn--bcher-kva.example",
+			ident: "bücher.example",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hs, err := tlsalpn01Srv(t, expectedKeyAuthorization, IdPeAcmeIdentifier, tls.VersionTLS12, tc.san)
+			test.AssertNotError(t, err, "failed to set up tls-alpn-01 server")
+			defer hs.Close()
+
+			va, _ := setup(hs, "", nil, nil)
+
+			_, prob := va.validateTLSALPN01(ctx, dnsi(tc.ident), expectedKeyAuthorization)
+			test.AssertNotError(t, prob, "validation should have succeeded")
+		})
+	}
 }
 
 func TestTLSALPN01NotSelfSigned(t *testing.T) {
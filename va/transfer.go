@@ -0,0 +1,86 @@
+package va
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+)
+
+// transferBudget counts the bytes read from the network by a single
+// validation, across every connection it makes, and fails reads once more
+// than its limit have been read. Capping each response body isn't enough on
+// its own, as a chain of redirects can otherwise make the VA read megabytes of
+// headers and bodies. It is carried in the validation's context, like
+// phaseTimings. All methods are safe for concurrent use, and on a nil
+// *transferBudget, which has no limit and counts nothing.
+type transferBudget struct {
+	limit int64
+	read  atomic.Int64
+}
+
+type transferBudgetKey struct{}
+
+// withTransferBudget returns a child context carrying a new *transferBudget
+// of limit bytes, which is also returned.
+func withTransferBudget(ctx context.Context, limit int64) (context.Context, *transferBudget) {
+	budget := &transferBudget{limit: limit}
+	return context.WithValue(ctx, transferBudgetKey{}, budget), budget
+}
+
+// transferBudgetFrom returns the *transferBudget carried by ctx, or nil.
+func transferBudgetFrom(ctx context.Context) *transferBudget {
+	budget, _ := ctx.Value(transferBudgetKey{}).(*transferBudget)
+	return budget
+}
+
+// total returns the number of bytes read so far.
+func (b *transferBudget) total() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.read.Load()
+}
+
+// exceeded returns true if more than the limit has been read.
+func (b *transferBudget) exceeded() bool {
+	return b != nil && b.read.Load() > b.limit
+}
+
+// wrap returns a net.Conn whose reads are counted against b, and fail with a
+// transferBudgetError once b is exceeded. If b is nil, conn is returned.
+func (b *transferBudget) wrap(conn net.Conn) net.Conn {
+	if b == nil {
+		return conn
+	}
+	return &budgetedConn{Conn: conn, budget: b}
+}
+
+// budgetedConn is a net.Conn whose reads are counted against a
+// transferBudget.
+type budgetedConn struct {
+	net.Conn
+	budget *transferBudget
+}
+
+func (c *budgetedConn) Read(b []byte) (int, error) {
+	if c.budget.exceeded() {
+		return 0, transferBudgetError{limit: c.budget.limit}
+	}
+	n, err := c.Conn.Read(b)
+	if c.budget.read.Add(int64(n)) > c.budget.limit {
+		return 0, transferBudgetError{limit: c.budget.limit}
+	}
+	return n, err
+}
+
+// transferBudgetError is returned when a validation reads more than its
+// transferBudget allows.
+type transferBudgetError struct {
+	limit int64
+}
+
+func (e transferBudgetError) Error() string {
+	return fmt.Sprintf("Validation exceeded the limit of %d bytes received across all of its requests, "+
+		"including redirects and TLS handshakes", e.limit)
+}
@@ -31,8 +31,10 @@ func filterProblemDetails(prob *probs.ProblemDetails) *probs.ProblemDetails {
 		return nil
 	}
 	return &probs.ProblemDetails{
-		Type:       probs.ProblemType(replaceInvalidUTF8([]byte(prob.Type))),
-		Detail:     replaceInvalidUTF8([]byte(prob.Detail)),
-		HTTPStatus: prob.HTTPStatus,
+		Type:        probs.ProblemType(replaceInvalidUTF8([]byte(prob.Type))),
+		Detail:      replaceInvalidUTF8([]byte(prob.Detail)),
+		HTTPStatus:  prob.HTTPStatus,
+		DNSDetails:  prob.DNSDetails,
+		HTTPDetails: prob.HTTPDetails,
 	}
 }
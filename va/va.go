@@ -7,17 +7,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand/v2"
 	"net"
+	"net/netip"
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/letsencrypt/boulder/bdns"
@@ -76,6 +84,10 @@ var (
 	// the broken transport prefix. Most of the time the error is returned with
 	// a transport connection error prefix.
 	h2SettingsFrameErrRegex = regexp.MustCompile(`(?:net\/http\: HTTP\/1\.x transport connection broken: )?malformed HTTP response \"\\x00\\x00\\x[a-f0-9]{2}\\x04\\x00\\x00\\x00\\x00\\x00.*"`)
+	// locationParseErrRegex matches the error net/http returns, before
+	// processRedirect is consulted, when a redirect's Location header isn't a
+	// valid URL reference.
+	locationParseErrRegex = regexp.MustCompile(`failed to parse Location header`)
 )
 
 // RemoteClients wraps the vapb.VAClient and vapb.CAAClient interfaces to aid in
@@ -93,6 +105,24 @@ type RemoteVA struct {
 	Address     string
 	Perspective string
 	RIR         string
+
+	// ExpectedIdentity is a dNSName which the certificate of the remote VA's
+	// gRPC server must contain. Unlike the name the gRPC client verifies,
+	// which may be shared by the remote VAs of every perspective, it should
+	// identify this perspective's remote VAs alone. A mismatch is audit
+	// logged and counted, but doesn't fail the remote VA's operations. It is
+	// optional, and empty means any identity is accepted.
+	ExpectedIdentity string
+
+	// ASN is the Autonomous System Number from which this remote VA's
+	// requests originate. It is optional, and zero means unknown. Remote VAs
+	// with an unknown ASN never count towards the minimum number of distinct
+	// ASNs required by the primary VA.
+	ASN uint32
+
+	// peer records the identity the remote VA authenticated as. It is set by
+	// the VA's constructor.
+	peer *remotePeer
 }
 
 type vaMetrics struct {
@@ -108,8 +138,26 @@ type vaMetrics struct {
 	tlsALPNOIDCounter                 *prometheus.CounterVec
 	http01Fallbacks                   prometheus.Counter
 	http01Redirects                   prometheus.Counter
+	http01CacheBustRetries            prometheus.Counter
+	http01RetryAfterRetries           prometheus.Counter
+	http01ConnectionFailures          *prometheus.CounterVec
 	caaCounter                        *prometheus.CounterVec
+	caaValidationMethodMismatches     *prometheus.CounterVec
 	ipv4FallbackCounter               prometheus.Counter
+	remoteVASelections                *prometheus.CounterVec
+	validationSLOBreaches             *prometheus.CounterVec
+	validationsDeduplicated           *prometheus.CounterVec
+	policyHintsIgnored                *prometheus.CounterVec
+	addressResolutionFailures         *prometheus.CounterVec
+	validationsInFlight               *prometheus.GaugeVec
+	lowTTLTXTRecords                  *prometheus.CounterVec
+	mpicSkipped                       *prometheus.CounterVec
+	localResourceExhaustion           *prometheus.CounterVec
+	iodefReports                      *prometheus.CounterVec
+	validationBytes                   *prometheus.HistogramVec
+	remoteVAIdentityMismatches        *prometheus.CounterVec
+	validationAddressFamilies         *prometheus.CounterVec
+	riskChecks                        *prometheus.CounterVec
 }
 
 func initMetrics(stats prometheus.Registerer) *vaMetrics {
@@ -148,16 +196,123 @@ func initMetrics(stats prometheus.Registerer) *vaMetrics {
 			Help: "Number of HTTP-01 redirects followed",
 		})
 	stats.MustRegister(http01Redirects)
+	http01CacheBustRetries := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "http01_cache_bust_retries",
+			Help: "Number of HTTP-01 requests retried with a cache-busting query parameter after a cached mismatch",
+		})
+	stats.MustRegister(http01CacheBustRetries)
+	http01RetryAfterRetries := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "http01_retry_after_retries",
+			Help: "Number of HTTP-01 requests retried after a 429 or 503 response with a short Retry-After",
+		})
+	stats.MustRegister(http01RetryAfterRetries)
+	http01ConnectionFailures := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http01_connection_failures",
+			Help: "Number of HTTP-01 requests which failed to connect, labeled by the address_family=[ipv4|ipv6|dual] of the host's resolved addresses",
+		},
+		[]string{"address_family"},
+	)
+	stats.MustRegister(http01ConnectionFailures)
 	caaCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "caa_sets_processed",
 		Help: "A counter of CAA sets processed labelled by result",
 	}, []string{"result"})
 	stats.MustRegister(caaCounter)
+	caaValidationMethodMismatches := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "caa_validationmethods_mismatches",
+		Help: "A counter of CAA checks which passed only because validationmethods is not enforced, labelled by requested_method and permitted_methods",
+	}, []string{"requested_method", "permitted_methods"})
+	stats.MustRegister(caaValidationMethodMismatches)
 	ipv4FallbackCounter := prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "tls_alpn_ipv4_fallback",
 		Help: "A counter of IPv4 fallbacks during TLS ALPN validation",
 	})
 	stats.MustRegister(ipv4FallbackCounter)
+	remoteVASelections := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "remote_va_selections",
+		Help: "A counter of the number of times each remote perspective was chosen for an operation when perspective selection is enabled",
+	}, []string{"perspective"})
+	stats.MustRegister(remoteVASelections)
+	validationSLOBreaches := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "validation_slo_breaches",
+		Help: "A counter of successful validations which took longer than the SLO threshold, labelled by the slowest phase=[dns|connect|fetch|remote_quorum|unknown] and challenge_type",
+	}, []string{"phase", "challenge_type"})
+	stats.MustRegister(validationSLOBreaches)
+	validationsDeduplicated := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "validations_deduplicated",
+		Help: "A counter of validation requests which shared the result of an identical validation, labelled by operation and whether that validation was reason=[in_flight|completed]",
+	}, []string{"operation", "reason"})
+	stats.MustRegister(validationsDeduplicated)
+	policyHintsIgnored := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "policy_hints_ignored",
+		Help: "A counter of per-request policy hints which were ignored, labelled by reason=[unknown_key|invalid_value]",
+	}, []string{"reason"})
+	stats.MustRegister(policyHintsIgnored)
+	addressResolutionFailures := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "address_resolution_failures",
+		Help: "A counter of failures to resolve the addresses to connect to for HTTP-01 and TLS-ALPN-01 validation, labelled by reason=[nxdomain|nodata|other]",
+	}, []string{"reason"})
+	stats.MustRegister(addressResolutionFailures)
+	validationsInFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "validations_in_flight",
+		Help: "A gauge of the validations and CAA checks in progress, labelled by operation=[dcv|caa|dcv+caa] and challenge_type",
+	}, []string{"operation", "challenge_type"})
+	stats.MustRegister(validationsInFlight)
+
+	lowTTLTXTRecords := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "low_ttl_txt_records",
+		Help: "A counter of TXT record lookups for DNS-01 and DNS-ACCOUNT-01 validation whose answers had a TTL below the configured minimum, labelled by challenge_type and result=[pass|fail]",
+	}, []string{"challenge_type", "result"})
+	stats.MustRegister(lowTTLTXTRecords)
+
+	mpicSkipped := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mpic_skipped",
+		Help: "A counter of validations and CAA checks whose remote corroboration the primary VA skipped, labelled by reason=[primary_failed|no_remotes_configured|internal_identifier|dry_run|recheck_local_only]",
+	}, []string{"reason"})
+	stats.MustRegister(mpicSkipped)
+	for _, reason := range mpicSkippedReasons {
+		mpicSkipped.WithLabelValues(reason)
+	}
+
+	localResourceExhaustion := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "local_resource_exhaustion",
+		Help: "A counter of validations and CAA checks which failed because the VA ran out of a local resource, labelled by operation and resource=[file_descriptors|local_ports]",
+	}, []string{"operation", "resource"})
+	stats.MustRegister(localResourceExhaustion)
+
+	iodefReports := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "iodef_reports",
+		Help: "A counter of CAA iodef reports of policy violations, labelled by URL scheme and result=[sent|failed|dropped|rate_limited|unsupported]",
+	}, []string{"scheme", "result"})
+	stats.MustRegister(iodefReports)
+
+	validationBytes := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "validation_bytes_received",
+		Help:    "Histogram of the total bytes received from the network by each validation, across all of its requests, redirects and TLS handshakes, labelled by challenge_type",
+		Buckets: prometheus.ExponentialBuckets(512, 2, 13),
+	}, []string{"challenge_type"})
+	stats.MustRegister(validationBytes)
+
+	remoteVAIdentityMismatches := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "remote_va_identity_mismatches",
+		Help: "A counter of remote VAs which authenticated as an identity other than the one expected of them, labelled by perspective",
+	}, []string{"perspective"})
+	stats.MustRegister(remoteVAIdentityMismatches)
+
+	validationAddressFamilies := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "validation_address_families",
+		Help: "A counter of successful local validations, labelled by perspective, challenge_type, and the address_family=[ipv4|ipv6|none] of the connection which decided them",
+	}, []string{"perspective", "challenge_type", "address_family"})
+	stats.MustRegister(validationAddressFamilies)
+
+	riskChecks := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "risk_checks",
+		Help: "A counter of identifiers screened by the risk checker before validation, labelled by result=[allow|flag|deny|error]",
+	}, []string{"result"})
+	stats.MustRegister(riskChecks)
 
 	return &vaMetrics{
 		validationLatency:                 validationLatency,
@@ -165,21 +320,39 @@ func initMetrics(stats prometheus.Registerer) *vaMetrics {
 		tlsALPNOIDCounter:                 tlsALPNOIDCounter,
 		http01Fallbacks:                   http01Fallbacks,
 		http01Redirects:                   http01Redirects,
+		http01CacheBustRetries:            http01CacheBustRetries,
+		http01RetryAfterRetries:           http01RetryAfterRetries,
+		http01ConnectionFailures:          http01ConnectionFailures,
 		caaCounter:                        caaCounter,
+		caaValidationMethodMismatches:     caaValidationMethodMismatches,
 		ipv4FallbackCounter:               ipv4FallbackCounter,
+		remoteVASelections:                remoteVASelections,
+		validationSLOBreaches:             validationSLOBreaches,
+		validationsDeduplicated:           validationsDeduplicated,
+		policyHintsIgnored:                policyHintsIgnored,
+		addressResolutionFailures:         addressResolutionFailures,
+		validationsInFlight:               validationsInFlight,
+		lowTTLTXTRecords:                  lowTTLTXTRecords,
+		mpicSkipped:                       mpicSkipped,
+		localResourceExhaustion:           localResourceExhaustion,
+		iodefReports:                      iodefReports,
+		validationBytes:                   validationBytes,
+		remoteVAIdentityMismatches:        remoteVAIdentityMismatches,
+		validationAddressFamilies:         validationAddressFamilies,
+		riskChecks:                        riskChecks,
 	}
 }
 
-// PortConfig specifies what ports the VA should call to on the remote
-// host when performing its checks.
-type portConfig struct {
-	HTTPPort  int
-	HTTPSPort int
-	TLSPort   int
+// validationPorts are the ports the VA connects to on the remote host when
+// performing its checks. They are set once, when the VA is constructed, and
+// never modified afterwards.
+type validationPorts struct {
+	http  int
+	https int
+	tls   int
 }
 
-// newDefaultPortConfig is a constructor which returns a portConfig with default
-// settings.
+// defaultValidationPorts returns the ports which must be used in production.
 //
 // CABF BRs section 1.6.1: Authorized Ports: One of the following ports: 80
 // (http), 443 (https), 25 (smtp), 22 (ssh).
@@ -189,39 +362,155 @@ type portConfig struct {
 //
 // RFC 8737 section 3: The ACME server initiates a TLS connection to the chosen
 // IP address. This connection MUST use TCP port 443.
-func newDefaultPortConfig() *portConfig {
-	return &portConfig{
-		HTTPPort:  80,
-		HTTPSPort: 443,
-		TLSPort:   443,
+func defaultValidationPorts() validationPorts {
+	return validationPorts{
+		http:  80,
+		https: 443,
+		tls:   443,
+	}
+}
+
+// validate returns an error if any of the ports is not between 1 and 65535,
+// and logs a warning for each port which differs from its default.
+func (p validationPorts) validate(logger blog.Logger) error {
+	defaults := defaultValidationPorts()
+	for _, port := range []struct {
+		name       string
+		value, def int
+	}{
+		{"HTTP", p.http, defaults.http},
+		{"HTTPS", p.https, defaults.https},
+		{"TLS", p.tls, defaults.tls},
+	} {
+		if port.value < 1 || port.value > 65535 {
+			return fmt.Errorf("invalid %s port %d, must be between 1 and 65535", port.name, port.value)
+		}
+		if port.value != port.def {
+			logger.Warningf("VA is using %s port %d instead of %d, this must never happen outside of tests", port.name, port.value, port.def)
+		}
 	}
+	return nil
 }
 
 // ValidationAuthorityImpl represents a VA
 type ValidationAuthorityImpl struct {
 	vapb.UnsafeVAServer
 	vapb.UnsafeCAAServer
-	log                blog.Logger
-	dnsClient          bdns.Client
-	issuerDomain       string
-	httpPort           int
-	httpsPort          int
-	tlsPort            int
-	userAgent          string
-	clk                clock.Clock
-	remoteVAs          []RemoteVA
-	maxRemoteFailures  int
-	accountURIPrefixes []string
-	singleDialTimeout  time.Duration
-	perspective        string
-	rir                string
+	log                      blog.Logger
+	dnsClient                bdns.Client
+	issuerDomain             string
+	ports                    validationPorts
+	userAgent                string
+	clk                      clock.Clock
+	remoteVAs                []RemoteVA
+	maxRemoteFailures        int
+	minDistinctASNs          int
+	selector                 *perspectiveSelector
+	accountURIPrefixes       []string
+	singleDialTimeout        time.Duration
+	maxHTTPRetryAfter        time.Duration
+	caaValidationMethodsMode CAAValidationMethodsMode
+	httpHeaders              map[string]string
+	sloThreshold             time.Duration
+	confirmOverTLSDomains    []string
+	deduper                  *validationDeduper
+	admission                *admissionController
+	perspective              string
+	rir                      string
+	proxyProtocolSource      netip.Addr
+	internal                 InternalIdentifiers
+	minTXTTTL                time.Duration
+	maxCAABatchSize          int
+	caaBatchParallelism      int
+	iodef                    *iodefReporter
+	maxValidationBytes       int64
+	riskChecker              RiskChecker
+	riskDenyDetail           string
+	recheckCAALocalOnly      bool
+	targetConns              *targetConnLimiter
+
+	// dialControl, if set, is the net.Dialer Control function of every
+	// connection made to validate a challenge. It's only set by tests, to
+	// inject dial failures.
+	dialControl func(network, address string, c syscall.RawConn) error
 
 	metrics *vaMetrics
+	tracer  trace.Tracer
 }
 
 var _ vapb.VAServer = (*ValidationAuthorityImpl)(nil)
 var _ vapb.CAAServer = (*ValidationAuthorityImpl)(nil)
 
+// Options are the settings of a VA beyond its dependencies, its perspective
+// and the account URI prefixes of its challenges. The settings which a
+// deployment doesn't use can be left zero, except where noted.
+type Options struct {
+	// MinDistinctASNs is the number of distinct ASNs which the remote VAs
+	// must span.
+	MinDistinctASNs int
+	// PerspectiveSelection configures the querying of a subset of the remote
+	// VAs for each operation.
+	PerspectiveSelection PerspectiveSelection
+	// DevMode permits settings which are only appropriate in development and
+	// testing environments, such as http account URI prefixes.
+	DevMode bool
+	// MaxHTTPRetryAfter is the longest Retry-After which is honored when an
+	// HTTP-01 challenge request receives a 429 or 503 response.
+	MaxHTTPRetryAfter time.Duration
+	// CAAValidationMethodsMode determines how the RFC 8657 validationmethods
+	// CAA parameter is applied. Defaults to CAAValidationMethodsEnforce.
+	CAAValidationMethodsMode CAAValidationMethodsMode
+	// HTTPHeaders are additional request headers sent with every HTTP-01
+	// request.
+	HTTPHeaders map[string]string
+	// SLOThreshold is the latency above which a successful validation is
+	// logged and counted as slow. It must be positive.
+	SLOThreshold time.Duration
+	// ConfirmOverTLSDomains are the domains whose HTTP-01 validations must be
+	// confirmed over HTTPS when the HTTP01ConfirmOverTLS feature is enabled.
+	ConfirmOverTLSDomains []string
+	// DedupWindow is how long the result of a validation is shared with
+	// identical requests.
+	DedupWindow time.Duration
+	// MaxConcurrentValidations is the number of validations and CAA checks
+	// performed at once. If zero, the number is unbounded.
+	MaxConcurrentValidations int
+	// MaxQueueWait is how long a request waits for a slot when the VA is at
+	// MaxConcurrentValidations.
+	MaxQueueWait time.Duration
+	// ProxyProtocolSource, if valid, is the source address sent in the PROXY
+	// protocol header of each HTTP-01 and TLS-ALPN-01 connection.
+	ProxyProtocolSource netip.Addr
+	// Internal are the identifiers for which the primary VA skips remote
+	// corroboration.
+	Internal InternalIdentifiers
+	// MinTXTTTL is the TTL below which DNS-01 and DNS-ACCOUNT-01 TXT records
+	// are logged and counted.
+	MinTXTTTL time.Duration
+	// MaxCAABatchSize is the most identifiers a CheckCAAMulti request may
+	// carry. It must be positive.
+	MaxCAABatchSize int
+	// CAABatchParallelism is the number of identifiers of a CheckCAAMulti
+	// request checked at once. It must be positive.
+	CAABatchParallelism int
+	// IODEF configures the reporting of CAA policy violations.
+	IODEF IODEFConfig
+	// MaxValidationBytes is the most bytes a single HTTP-01 or TLS-ALPN-01
+	// validation may receive. It must be positive.
+	MaxValidationBytes int64
+	// Risk configures the screening of identifiers before they're validated.
+	Risk RiskConfig
+	// RecheckCAALocalOnly makes CAA rechecks use only the primary
+	// perspective.
+	RecheckCAALocalOnly bool
+	// MaxConnsPerTargetIP is the number of connections held open to any one
+	// target IP address at once. It must be positive.
+	MaxConnsPerTargetIP int
+	// MaxTargetIPConnWait is how long a connection waits for a slot when the
+	// VA is at MaxConnsPerTargetIP connections to the target.
+	MaxTargetIPConnWait time.Duration
+}
+
 // NewValidationAuthorityImpl constructs a new VA
 func NewValidationAuthorityImpl(
 	resolver bdns.Client,
@@ -234,10 +523,137 @@ func NewValidationAuthorityImpl(
 	accountURIPrefixes []string,
 	perspective string,
 	rir string,
+	opts Options,
 ) (*ValidationAuthorityImpl, error) {
+	return newValidationAuthorityImpl(defaultValidationPorts(), resolver, remoteVAs, userAgent, issuerDomain, stats, clk,
+		logger, accountURIPrefixes, perspective, rir, opts)
+}
 
-	if len(accountURIPrefixes) == 0 {
-		return nil, errors.New("no account URI prefixes configured")
+// newValidationAuthorityImpl constructs a new VA which connects to the
+// provided ports. It exists so that tests can point the VA at test servers
+// listening on other ports, everything else must use
+// NewValidationAuthorityImpl.
+func newValidationAuthorityImpl(
+	ports validationPorts,
+	resolver bdns.Client,
+	remoteVAs []RemoteVA,
+	userAgent string,
+	issuerDomain string,
+	stats prometheus.Registerer,
+	clk clock.Clock,
+	logger blog.Logger,
+	accountURIPrefixes []string,
+	perspective string,
+	rir string,
+	opts Options,
+) (*ValidationAuthorityImpl, error) {
+	err := ports.validate(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateAccountURIPrefixes(accountURIPrefixes, opts.DevMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if userAgent == "" {
+		return nil, errors.New("no user agent configured")
+	}
+
+	switch opts.CAAValidationMethodsMode {
+	case "":
+		opts.CAAValidationMethodsMode = CAAValidationMethodsEnforce
+	case CAAValidationMethodsEnforce, CAAValidationMethodsLogOnly:
+	default:
+		return nil, fmt.Errorf("unknown CAA validationmethods mode %q", opts.CAAValidationMethodsMode)
+	}
+
+	opts.HTTPHeaders, err = validateHTTPHeaders(opts.HTTPHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.SLOThreshold <= 0 {
+		return nil, fmt.Errorf("validation SLO threshold must be positive, got %s", opts.SLOThreshold)
+	}
+
+	opts.ConfirmOverTLSDomains, err = validateConfirmOverTLSDomains(opts.ConfirmOverTLSDomains)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DedupWindow < 0 {
+		return nil, fmt.Errorf("validation dedup window must not be negative, got %s", opts.DedupWindow)
+	}
+
+	if opts.MaxConcurrentValidations < 0 {
+		return nil, fmt.Errorf("max concurrent validations must not be negative, got %d", opts.MaxConcurrentValidations)
+	}
+	if opts.MaxQueueWait < 0 {
+		return nil, fmt.Errorf("max validation queue wait must not be negative, got %s", opts.MaxQueueWait)
+	}
+
+	err = validatePerspective(perspective, rir, remoteVAs)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.ProxyProtocolSource = opts.ProxyProtocolSource.Unmap()
+	if opts.ProxyProtocolSource.IsUnspecified() {
+		return nil, fmt.Errorf("PROXY protocol source address must be a specific address, got %s", opts.ProxyProtocolSource)
+	}
+
+	opts.Internal, err = opts.Internal.validate(perspective)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MinTXTTTL < 0 {
+		return nil, fmt.Errorf("minimum TXT record TTL must not be negative, got %s", opts.MinTXTTTL)
+	}
+
+	if opts.MaxCAABatchSize < 1 {
+		return nil, fmt.Errorf("max CAA batch size must be positive, got %d", opts.MaxCAABatchSize)
+	}
+	if opts.CAABatchParallelism < 1 {
+		return nil, fmt.Errorf("CAA batch parallelism must be positive, got %d", opts.CAABatchParallelism)
+	}
+
+	if opts.IODEF.QueueSize < 0 || opts.IODEF.MinInterval < 0 || opts.IODEF.Timeout < 0 {
+		return nil, errors.New("iodef report queue size, minimum interval and timeout must not be negative")
+	}
+	if opts.IODEF.QueueSize > 0 && perspective != PrimaryPerspective {
+		return nil, errors.New("iodef reports may only be sent by the primary VA")
+	}
+	if opts.IODEF.QueueSize > 0 && len(opts.IODEF.AccountIDHashKey) == 0 {
+		return nil, errors.New("iodef reports require an account ID hash key")
+	}
+
+	if opts.MaxValidationBytes < 1 {
+		return nil, fmt.Errorf("max validation bytes must be positive, got %d", opts.MaxValidationBytes)
+	}
+
+	if opts.Risk.Checker != nil && perspective != PrimaryPerspective {
+		return nil, errors.New("risk checks may only be made by the primary VA")
+	}
+	riskChecks := opts.Risk.Checker != nil
+	if !riskChecks {
+		opts.Risk.Checker = noopRiskChecker{}
+	}
+	if opts.Risk.DenyDetail == "" {
+		opts.Risk.DenyDetail = defaultRiskDenyDetail
+	}
+
+	if opts.RecheckCAALocalOnly && perspective != PrimaryPerspective {
+		return nil, errors.New("recheckCAALocalOnly may only be set for the primary VA")
+	}
+
+	if opts.MaxConnsPerTargetIP < 1 {
+		return nil, fmt.Errorf("max connections per target IP must be positive, got %d", opts.MaxConnsPerTargetIP)
+	}
+	if opts.MaxTargetIPConnWait < 0 {
+		return nil, fmt.Errorf("max target IP connection wait must not be negative, got %s", opts.MaxTargetIPConnWait)
 	}
 
 	for i, va1 := range remoteVAs {
@@ -249,33 +665,152 @@ func NewValidationAuthorityImpl(
 		}
 	}
 
-	pc := newDefaultPortConfig()
+	if opts.MinDistinctASNs < 0 {
+		return nil, fmt.Errorf("minimum distinct ASNs must not be negative, got %d", opts.MinDistinctASNs)
+	}
+	asns := make(map[uint32]struct{})
+	for _, rva := range remoteVAs {
+		if rva.ASN != 0 {
+			asns[rva.ASN] = struct{}{}
+		}
+	}
+	if len(asns) < opts.MinDistinctASNs {
+		return nil, fmt.Errorf("%d distinct ASNs are required but remote VAs are configured with only %d", opts.MinDistinctASNs, len(asns))
+	}
+
+	err = opts.PerspectiveSelection.validate(remoteVAs, opts.MinDistinctASNs)
+	if err != nil {
+		return nil, err
+	}
+	var selector *perspectiveSelector
+	if opts.PerspectiveSelection.Quorum > 0 {
+		selector = newPerspectiveSelector(opts.PerspectiveSelection, remoteVAs, opts.MinDistinctASNs, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	}
 
 	va := &ValidationAuthorityImpl{
 		log:                logger,
 		dnsClient:          resolver,
 		issuerDomain:       issuerDomain,
-		httpPort:           pc.HTTPPort,
-		httpsPort:          pc.HTTPSPort,
-		tlsPort:            pc.TLSPort,
+		ports:              ports,
 		userAgent:          userAgent,
 		clk:                clk,
 		metrics:            initMetrics(stats),
+		tracer:             otel.GetTracerProvider().Tracer("github.com/letsencrypt/boulder/va"),
 		remoteVAs:          remoteVAs,
 		maxRemoteFailures:  maxAllowedFailures(len(remoteVAs)),
+		minDistinctASNs:    opts.MinDistinctASNs,
+		selector:           selector,
 		accountURIPrefixes: accountURIPrefixes,
 		// singleDialTimeout specifies how long an individual `DialContext` operation may take
 		// before timing out. This timeout ignores the base RPC timeout and is strictly
 		// used for the DialContext operations that take place during an
 		// HTTP-01 challenge validation.
-		singleDialTimeout: 10 * time.Second,
-		perspective:       perspective,
-		rir:               rir,
+		singleDialTimeout:        10 * time.Second,
+		maxHTTPRetryAfter:        opts.MaxHTTPRetryAfter,
+		caaValidationMethodsMode: opts.CAAValidationMethodsMode,
+		httpHeaders:              opts.HTTPHeaders,
+		sloThreshold:             opts.SLOThreshold,
+		confirmOverTLSDomains:    opts.ConfirmOverTLSDomains,
+		deduper:                  newValidationDeduper(opts.DedupWindow),
+		admission:                newAdmissionController(opts.MaxConcurrentValidations, opts.MaxQueueWait, clk, stats),
+		perspective:              perspective,
+		rir:                      rir,
+		proxyProtocolSource:      opts.ProxyProtocolSource,
+		internal:                 opts.Internal,
+		minTXTTTL:                opts.MinTXTTTL,
+		maxCAABatchSize:          opts.MaxCAABatchSize,
+		caaBatchParallelism:      opts.CAABatchParallelism,
+		maxValidationBytes:       opts.MaxValidationBytes,
+		riskChecker:              opts.Risk.Checker,
+		riskDenyDetail:           opts.Risk.DenyDetail,
+		recheckCAALocalOnly:      opts.RecheckCAALocalOnly,
+		targetConns:              newTargetConnLimiter(opts.MaxConnsPerTargetIP, opts.MaxTargetIPConnWait, clk, stats),
+	}
+	va.iodef = newIODEFReporter(opts.IODEF, resolver, clk, logger, va.metrics.iodefReports)
+	va.remoteVAs = make([]RemoteVA, len(remoteVAs))
+	for i, rva := range remoteVAs {
+		va.remoteVAs[i] = rva.identify(logger, va.metrics.remoteVAIdentityMismatches)
+	}
+
+	var proxyProtocolSourceLog string
+	if opts.ProxyProtocolSource.IsValid() {
+		proxyProtocolSourceLog = opts.ProxyProtocolSource.String()
+	}
+
+	logger.Infof("VA configured with perspective=%q rir=%q remoteVAs=%d maxRemoteFailures=%d minDistinctASNs=%d "+
+		"perspectiveSelection=%d+%d accountURIPrefixes=%q ports=%d/%d/%d devMode=%t caaValidationMethodsMode=%q "+
+		"httpHeaders=%q sloThreshold=%s confirmOverTLSDomains=%q dedupWindow=%s maxConcurrentValidations=%d maxQueueWait=%s "+
+		"proxyProtocolSource=%q insecureInternalIssuance=%t internalPrefixes=%q internalDomains=%q minTXTTTL=%s "+
+		"maxCAABatchSize=%d caaBatchParallelism=%d iodefQueueSize=%d maxValidationBytes=%d riskChecks=%t recheckCAALocalOnly=%t "+
+		"maxConnsPerTargetIP=%d maxTargetIPConnWait=%s",
+		perspective, rir, len(remoteVAs), va.maxRemoteFailures, opts.MinDistinctASNs, opts.PerspectiveSelection.Quorum, opts.PerspectiveSelection.Headroom,
+		accountURIPrefixes, ports.http, ports.https, ports.tls, opts.DevMode, opts.CAAValidationMethodsMode,
+		slices.Sorted(maps.Keys(opts.HTTPHeaders)), opts.SLOThreshold, opts.ConfirmOverTLSDomains, opts.DedupWindow, opts.MaxConcurrentValidations, opts.MaxQueueWait,
+		proxyProtocolSourceLog, opts.Internal.InsecureInternalIssuance, opts.Internal.Prefixes, opts.Internal.Domains, opts.MinTXTTTL,
+		opts.MaxCAABatchSize, opts.CAABatchParallelism, opts.IODEF.QueueSize, opts.MaxValidationBytes, riskChecks, opts.RecheckCAALocalOnly,
+		opts.MaxConnsPerTargetIP, opts.MaxTargetIPConnWait)
+	for _, rva := range remoteVAs {
+		logger.Infof("VA configured with remote VA address=%q perspective=%q rir=%q asn=%d expectedIdentity=%q",
+			rva.Address, rva.Perspective, rva.RIR, rva.ASN, rva.ExpectedIdentity)
+	}
+	if opts.Internal.InsecureInternalIssuance {
+		logger.Warningf("VA configured with insecureInternalIssuance: remote corroboration is skipped for internal identifiers")
 	}
 
 	return va, nil
 }
 
+// validateAccountURIPrefixes returns an error unless at least one account URI
+// prefix is configured and each is an absolute https URL. If devMode is true,
+// http URLs are also accepted.
+func validateAccountURIPrefixes(accountURIPrefixes []string, devMode bool) error {
+	if len(accountURIPrefixes) == 0 {
+		return errors.New("no account URI prefixes configured")
+	}
+	for _, prefix := range accountURIPrefixes {
+		u, err := url.Parse(prefix)
+		if err != nil {
+			return fmt.Errorf("parsing account URI prefix %q: %w", prefix, err)
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return fmt.Errorf("account URI prefix %q is not an absolute URL", prefix)
+		}
+		if u.Scheme != "https" && !(devMode && u.Scheme == "http") {
+			return fmt.Errorf("account URI prefix %q must use https", prefix)
+		}
+	}
+	return nil
+}
+
+// rirs are the Regional Internet Registries a remote VA may be located in.
+var rirs = []string{"ARIN", "RIPE", "APNIC", "LACNIC", "AFRINIC"}
+
+// validatePerspective returns an error if the perspective, rir and remoteVAs
+// of a VA are inconsistent with each other. Only the primary VA may have
+// remote VAs, and only a remote VA may be located in an RIR.
+func validatePerspective(perspective, rir string, remoteVAs []RemoteVA) error {
+	if perspective == PrimaryPerspective {
+		if rir != "" {
+			return fmt.Errorf("the primary VA must not have an RIR, got %q", rir)
+		}
+		return nil
+	}
+	if len(remoteVAs) > 0 {
+		return fmt.Errorf("only the primary VA may have remote VAs, perspective %q has %d", perspective, len(remoteVAs))
+	}
+	if rir == "" {
+		// TODO(#7615): Require rir once perspective and rir are required.
+		return nil
+	}
+	if perspective == "" {
+		return fmt.Errorf("RIR %q is configured without a perspective", rir)
+	}
+	if !slices.Contains(rirs, rir) {
+		return fmt.Errorf("unrecognized RIR %q, must be one of %v", rir, rirs)
+	}
+	return nil
+}
+
 // maxAllowedFailures returns the maximum number of allowed failures
 // for a given number of remote perspectives, according to the "Quorum
 // Requirements" table in BRs Section 3.2.2.9, as follows:
@@ -297,13 +832,37 @@ func maxAllowedFailures(perspectiveCount int) int {
 // verificationRequestEvent is logged once for each validation attempt. Its
 // fields are exported for logging purposes.
 type verificationRequestEvent struct {
-	AuthzID       string
-	Requester     int64
-	Identifier    string
-	Challenge     core.Challenge
-	Error         string `json:",omitempty"`
+	AttemptID  string
+	AuthzID    string
+	Requester  int64
+	Identifier string
+	Challenge  core.Challenge
+	Error      string            `json:",omitempty"`
+	DNSDetails *probs.DNSDetails `json:",omitempty"`
+	// DNSResolver is the address of the resolver queried by the DNS query
+	// described by DNSDetails.
+	DNSResolver   string `json:",omitempty"`
 	InternalError string `json:",omitempty"`
-	Latency       float64
+	// Message identifies the problem detail message of a failed local
+	// validation, and its parameters, if it was rendered from messageCatalog.
+	Message *messageRecord `json:",omitempty"`
+	// FinalURL and StatusCode describe the last HTTP-01 response, if any.
+	FinalURL   string `json:",omitempty"`
+	StatusCode int    `json:",omitempty"`
+	// CAAQueries describes the DNS queries made by the CAA check which
+	// followed a successful local validation.
+	CAAQueries []core.DNSQuery `json:",omitempty"`
+	Latency    float64
+	// Phases is the time, in seconds, spent in each phase of the validation.
+	Phases map[validationPhase]float64 `json:",omitempty"`
+	// PolicyHints are the policy hints which were applied to the request.
+	PolicyHints map[string]string `json:",omitempty"`
+	// AllowedChallengeTypes are the challenge types the RA permitted for the
+	// identifier, if it restricted them.
+	AllowedChallengeTypes []string `json:",omitempty"`
+	// Risk is the RiskChecker's decision, if it flagged or denied the
+	// validation.
+	Risk *riskRecord `json:",omitempty"`
 }
 
 // ipError is an error type used to pass though the IP address of the remote
@@ -329,12 +888,57 @@ func (i ipError) Error() string {
 	return fmt.Sprintf("%s: %s", i.ip, i.err)
 }
 
+// The local resources whose exhaustion localResourceExhausted detects.
+const (
+	resourceFileDescriptors = "file_descriptors"
+	resourceLocalPorts      = "local_ports"
+)
+
+// localResourceExhausted returns the local resource which the VA ran out of,
+// causing err, or "" if err wasn't caused by local resource exhaustion. Dials
+// fail with EADDRNOTAVAIL, "cannot assign requested address", when there are
+// no ephemeral ports left to connect from.
+func localResourceExhausted(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, syscall.EMFILE), errors.Is(err, syscall.ENFILE),
+		strings.Contains(err.Error(), "too many open files"):
+		return resourceFileDescriptors
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return resourceLocalPorts
+	}
+	return ""
+}
+
+// noteResourceExhaustion audit logs, and counts, a validation or CAA check of
+// ident which failed with err because the VA ran out of a local resource. It
+// does nothing if err wasn't caused by local resource exhaustion.
+func (va *ValidationAuthorityImpl) noteResourceExhaustion(op, ident string, err error) {
+	resource := localResourceExhausted(err)
+	if resource == "" {
+		return
+	}
+	va.log.AuditErrf("VA local resource exhaustion: operation=%s resource=%s identifier=%q err=%q", op, resource, ident, err)
+	va.metrics.localResourceExhaustion.WithLabelValues(op, resource).Inc()
+}
+
 // detailedError returns a ProblemDetails corresponding to an error
 // that occurred during HTTP-01 or TLS-ALPN domain validation. Specifically it
 // tries to unwrap known Go error types and present something a little more
 // meaningful. It additionally handles `berrors.ConnectionFailure` errors by
 // passing through the detailed message.
 func detailedError(err error) *probs.ProblemDetails {
+	// Running out of file descriptors or local ports is our problem, not the
+	// subscriber's, so it mustn't be reported as one of theirs.
+	if localResourceExhausted(err) != "" {
+		return probs.ServerInternal("The VA is temporarily out of local resources; please retry")
+	}
+	var busyErr targetBusyError
+	if errors.As(err, &busyErr) {
+		return probs.ServerInternal(fmt.Sprintf("The VA is temporarily at its connection limit for %s; please retry", busyErr.ip))
+	}
+
 	var ipErr ipError
 	if errors.As(err, &ipErr) {
 		detailedErr := detailedError(ipErr.err)
@@ -346,6 +950,16 @@ func detailedError(err error) *probs.ProblemDetails {
 		detailedErr.Detail = fmt.Sprintf("%s: %s", ipErr.ip, detailedErr.Detail)
 		return detailedErr
 	}
+
+	// Append a hint about the address family before unwrapping the url.Error
+	// or net.OpError it wraps.
+	var familyErr addressFamilyError
+	if errors.As(err, &familyErr) {
+		prob := detailedError(familyErr.err)
+		prob.Detail = fmt.Sprintf("%s; %s", prob.Detail, familyErr.hint())
+		return prob
+	}
+
 	// net/http wraps net.OpError in a url.Error. Unwrap them.
 	var urlErr *url.Error
 	if errors.As(err, &urlErr) {
@@ -354,11 +968,42 @@ func detailedError(err error) *probs.ProblemDetails {
 		return prob
 	}
 
+	// Check for a server which closed the connection mid-handshake before the
+	// generic connection reset handling below.
+	var closedErr handshakeClosedError
+	if errors.As(err, &closedErr) {
+		return probs.TLS(closedErr.Error())
+	}
+
+	// Check for TLS-ALPN-01 handshakes which failed to negotiate acme-tls/1
+	// or the requested name before the generic TLS alert handling below. A
+	// handshake which completed without acme-tls/1 isn't a TLS problem.
+	var alpnErr alpnMismatchError
+	if errors.As(err, &alpnErr) {
+		if alpnErr.failure == alpnNotNegotiated {
+			return probs.Unauthorized(alpnErr.Error())
+		}
+		return probs.TLS(alpnErr.Error())
+	}
+	var nameErr unrecognizedNameError
+	if errors.As(err, &nameErr) {
+		return probs.TLS(nameErr.Error())
+	}
+
+	var budgetErr transferBudgetError
+	if errors.As(err, &budgetErr) {
+		return probs.Connection(budgetErr.Error())
+	}
+
 	var tlsErr tls.RecordHeaderError
 	if errors.As(err, &tlsErr) && bytes.Equal(tlsErr.RecordHeader[:], badTLSHeader) {
 		return probs.Malformed("Server only speaks HTTP, not TLS")
 	}
 
+	if errors.Is(err, errCleartextHTTP2) {
+		return probs.Connection("Server only speaks HTTP/2 over plaintext (h2c), but HTTP-01 challenges are fetched using HTTP/1.1")
+	}
+
 	var netOpErr *net.OpError
 	if errors.As(err, &netOpErr) {
 		if fmt.Sprintf("%T", netOpErr.Err) == "tls.alert" {
@@ -393,7 +1038,9 @@ func detailedError(err error) *probs.ProblemDetails {
 		return probs.Unauthorized(err.Error())
 	}
 	if errors.Is(err, berrors.DNS) {
-		return probs.DNS(err.Error())
+		prob := probs.DNS(err.Error())
+		prob.DNSDetails = dnsDetails(err)
+		return prob
 	}
 	if errors.Is(err, berrors.Malformed) {
 		return probs.Malformed(err.Error())
@@ -405,42 +1052,167 @@ func detailedError(err error) *probs.ProblemDetails {
 	if h2SettingsFrameErrRegex.MatchString(err.Error()) {
 		return probs.Connection("Server is speaking HTTP/2 over HTTP")
 	}
+	if locationParseErrRegex.MatchString(err.Error()) {
+		return probs.Connection("Invalid redirect target. The Location header could not be parsed as a URL")
+	}
 	return probs.Connection("Error getting validation data")
 }
 
+// validationResult returns a *vapb.ValidationResult for the provided records
+// and problem, which may be nil. It includes a summary of the attempt, begun at
+// attemptedAt, for the RA to add to the challenge's history. If the remote
+// perspectives were consulted, summary describes their results, and if the
+// primary skipped them, it gives the reason. If local is
+// non-nil, the result includes the outcome at each perspective, local first,
+// capped at core.MaxPerspectiveResultsPerOperation. If httpRes recorded an HTTP-01
+// response, the result includes its final URL and status code.
+func (va *ValidationAuthorityImpl) validationResult(records []core.ValidationRecord, prob *probs.ProblemDetails, attemptedAt time.Time, local *core.PerspectiveResult, summary *mpicSummary, httpRes *httpResponseRecord) (*vapb.ValidationResult, error) {
+	prob = filterProblemDetails(prob)
+	res, err := bgrpc.ValidationResultToPB(records, prob, va.perspective, va.rir)
+	if err != nil {
+		return nil, err
+	}
+	finalURL, statusCode := httpRes.get()
+	res.FinalURL = finalURL
+	res.StatusCode = int32(statusCode)
+	perspectives := va.perspective
+	if summary != nil {
+		res.MpicSkippedReason = summary.SkippedReason
+	}
+	if summary != nil && summary.SkippedReason == "" {
+		perspectives = fmt.Sprintf("%s, %s remote perspectives corroborated", va.perspective, summary.QuorumResult)
+	}
+	res.Attempt = bgrpc.ValidationAttemptToPB(core.NewValidationAttempt(attemptedAt, prob, perspectives))
+	res.PerspectiveResults = perspectiveResultsToPB(local, summary)
+	return res, nil
+}
+
+// perspectiveResultsToPB returns the outcome at each perspective of a check,
+// local first, capped at core.MaxPerspectiveResultsPerOperation. The remote
+// results, if any, are taken from summary and attributed to the same
+// operation as local. It returns nil if local is nil.
+func perspectiveResultsToPB(local *core.PerspectiveResult, summary *mpicSummary) []*corepb.PerspectiveResult {
+	if local == nil {
+		return nil
+	}
+	results := []*corepb.PerspectiveResult{bgrpc.PerspectiveResultToPB(*local)}
+	if summary != nil {
+		for _, r := range summary.perspectiveResults {
+			if len(results) >= core.MaxPerspectiveResultsPerOperation {
+				break
+			}
+			r.Operation = local.Operation
+			results = append(results, bgrpc.PerspectiveResultToPB(r))
+		}
+	}
+	return results
+}
+
+// localPerspectiveResult returns the outcome at this VA's own perspective of a
+// local check, op, which took latency and resulted in prob, or succeeded if
+// prob is nil. It returns nil unless this is the primary VA, since only the
+// primary reports per-perspective results to the RA.
+func (va *ValidationAuthorityImpl) localPerspectiveResult(op string, prob *probs.ProblemDetails, latency time.Duration) *core.PerspectiveResult {
+	if !va.isPrimaryVA() {
+		return nil
+	}
+	result := &core.PerspectiveResult{
+		Perspective: va.perspective,
+		RIR:         va.rir,
+		Passed:      prob == nil,
+		LatencyMS:   latency.Milliseconds(),
+		Operation:   op,
+	}
+	if prob != nil {
+		result.ProblemType = prob.Type
+	}
+	return result
+}
+
 // isPrimaryVA returns true if the VA is the primary validation perspective.
 func (va *ValidationAuthorityImpl) isPrimaryVA() bool {
 	return va.perspective == PrimaryPerspective
 }
 
+// normalizeDNSName strips exactly one trailing dot from the provided name and
+// rejects names which are empty or contain empty labels, whitespace, or
+// control characters. It is called before any lookups are performed so that
+// such names fail fast with a malformed error rather than deep inside DNS.
+func (va *ValidationAuthorityImpl) normalizeDNSName(name string) (string, error) {
+	if strings.HasSuffix(name, ".") {
+		va.log.Infof("Stripping trailing dot from identifier %q", name)
+		name = strings.TrimSuffix(name, ".")
+	}
+	if name == "" {
+		return "", berrors.MalformedError("Identifier is empty")
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return "", berrors.MalformedError("Identifier %q contains whitespace or control characters", name)
+		}
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return "", berrors.MalformedError("Identifier %q contains an empty label", name)
+		}
+	}
+	return name, nil
+}
+
 // validateChallenge simply passes through to the appropriate validation method
 // depending on the challenge type.
 func (va *ValidationAuthorityImpl) validateChallenge(
 	ctx context.Context,
 	ident identifier.ACMEIdentifier,
+	regID int64,
 	kind core.AcmeChallenge,
 	token string,
 	keyAuthorization string,
 ) ([]core.ValidationRecord, error) {
+	var err error
+	ident.Value, err = va.normalizeDNSName(ident.Value)
+	if err != nil {
+		return nil, err
+	}
+
 	// Strip a (potential) leading wildcard token from the identifier.
 	ident.Value = strings.TrimPrefix(ident.Value, "*.")
 
+	// Every connection made to validate the challenge shares a single budget
+	// of bytes which may be read, so that a chain of redirects can't make us
+	// read more than a single response could.
+	ctx, budget := withTransferBudget(ctx, va.maxValidationBytes)
+	var records []core.ValidationRecord
 	switch kind {
 	case core.ChallengeTypeHTTP01:
-		return va.validateHTTP01(ctx, ident, token, keyAuthorization)
+		records, err = va.validateHTTP01(ctx, ident, token, keyAuthorization)
 	case core.ChallengeTypeDNS01:
 		return va.validateDNS01(ctx, ident, keyAuthorization)
 	case core.ChallengeTypeTLSALPN01:
-		return va.validateTLSALPN01(ctx, ident, keyAuthorization)
+		records, err = va.validateTLSALPN01(ctx, ident, keyAuthorization)
+	case core.ChallengeTypeDNSAccount01:
+		return va.validateDNSAccount01(ctx, ident, regID, keyAuthorization)
+	default:
+		return nil, berrors.MalformedError("invalid challenge type %s", kind)
+	}
+
+	va.metrics.validationBytes.WithLabelValues(string(kind)).Observe(float64(budget.total()))
+	if len(records) > 0 {
+		records[len(records)-1].BytesReceived = budget.total()
+	}
+	if err != nil && budget.exceeded() {
+		// The error which the exhausted budget caused may have been wrapped
+		// beyond recognition, for instance by the HTTP client.
+		err = transferBudgetError{limit: budget.limit}
 	}
-	return nil, berrors.MalformedError("invalid challenge type %s", kind)
+	return records, err
 }
 
 // observeLatency records entries in the validationLatency histogram of the
 // latency to perform validations from the primary and remote VA perspectives.
 // The labels are:
 //   - operation: VA.DoDCV or VA.DoCAA as [dcv|caa]
-//   - perspective: [ValidationAuthorityImpl.perspective|all]
+//   - perspective: [ValidationAuthorityImpl.perspective|all|internal|recheck_local]
 //   - challenge_type: core.Challenge.Type
 //   - problem_type: probs.ProblemType
 //   - result: the result of the validation as [pass|fail]
@@ -455,6 +1227,19 @@ func (va *ValidationAuthorityImpl) observeLatency(op, perspective, challType, pr
 	va.metrics.validationLatency.With(labels).Observe(latency.Seconds())
 }
 
+// spanError marks the span as failed if err is non-nil.
+func spanError(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// spanProblem marks the span as failed and records the problem type.
+func spanProblem(span trace.Span, probType, detail string) {
+	span.SetAttributes(attribute.String("problem_type", probType))
+	span.SetStatus(codes.Error, detail)
+}
+
 // remoteOperation is a func type that encapsulates the operation and request
 // passed to va.performRemoteOperation. The operation must be a method on
 // vapb.VAClient or vapb.CAAClient, and the request must be the corresponding
@@ -494,6 +1279,7 @@ func (va *ValidationAuthorityImpl) performRemoteOperation(ctx context.Context, o
 		addr        string
 		perspective string
 		rir         string
+		peer        string
 		result      remoteResult
 		err         error
 	}
@@ -506,7 +1292,7 @@ func (va *ValidationAuthorityImpl) performRemoteOperation(ctx context.Context, o
 		go func(rva RemoteVA) {
 			res, err := op(subCtx, rva, req)
 			if err != nil {
-				responses <- &response{rva.Address, rva.Perspective, rva.RIR, res, err}
+				responses <- &response{rva.describe(), rva.Perspective, rva.RIR, rva.peer.identity(), res, err}
 				return
 			}
 			// TODO(#7615): Remove the != "" checks once perspective and rir are required.
@@ -515,20 +1301,24 @@ func (va *ValidationAuthorityImpl) performRemoteOperation(ctx context.Context, o
 				err = fmt.Errorf(
 					"Expected perspective %q (%q) but got reply from %q (%q) - misconfiguration likely", rva.Perspective, rva.RIR, res.GetPerspective(), res.GetRir(),
 				)
-				responses <- &response{rva.Address, rva.Perspective, rva.RIR, res, err}
+				responses <- &response{rva.describe(), rva.Perspective, rva.RIR, rva.peer.identity(), res, err}
 				return
 			}
-			responses <- &response{rva.Address, rva.Perspective, rva.RIR, res, err}
+			responses <- &response{rva.describe(), rva.Perspective, rva.RIR, rva.peer.identity(), res, err}
 		}(va.remoteVAs[i])
 	}
 
 	required := remoteVACount - va.maxRemoteFailures
 	var passed []string
 	var failed []string
+	var failedPeers []string
 	var firstProb *probs.ProblemDetails
 
 	for resp := range responses {
 		var currProb *probs.ProblemDetails
+		if resp.peer != "" && (resp.err != nil || resp.result.GetProblem() != nil) {
+			failedPeers = append(failedPeers, resp.peer)
+		}
 
 		if resp.err != nil {
 			// Failed to communicate with the remote VA.
@@ -582,7 +1372,7 @@ func (va *ValidationAuthorityImpl) performRemoteOperation(ctx context.Context, o
 
 	if isCAACheck {
 		// We're checking CAA, log the results.
-		va.logRemoteResults(isCAAValidReq, len(passed), len(failed))
+		va.logRemoteResults(isCAAValidReq, len(passed), len(failed), failedPeers)
 	}
 
 	if len(passed) >= required {
@@ -598,25 +1388,28 @@ func (va *ValidationAuthorityImpl) performRemoteOperation(ctx context.Context, o
 }
 
 // logRemoteResults is called by performRemoteOperation when the request passed
-// is *vapb.IsCAAValidRequest.
-func (va *ValidationAuthorityImpl) logRemoteResults(req *vapb.IsCAAValidRequest, passed int, failed int) {
+// is *vapb.IsCAAValidRequest. failedPeers are the identities authenticated by
+// the failed remote VAs, where known.
+func (va *ValidationAuthorityImpl) logRemoteResults(req *vapb.IsCAAValidRequest, passed int, failed int, failedPeers []string) {
 	if failed == 0 {
 		// There's no point logging a differential line if everything succeeded.
 		return
 	}
 
 	logOb := struct {
-		Domain          string
-		AccountID       int64
-		ChallengeType   string
-		RemoteSuccesses int
-		RemoteFailures  int
+		Domain             string
+		AccountID          int64
+		ChallengeType      string
+		RemoteSuccesses    int
+		RemoteFailures     int
+		RemoteFailurePeers []string `json:",omitempty"`
 	}{
-		Domain:          req.Domain,
-		AccountID:       req.AccountURIID,
-		ChallengeType:   req.ValidationMethod,
-		RemoteSuccesses: passed,
-		RemoteFailures:  failed,
+		Domain:             req.Domain,
+		AccountID:          req.AccountURIID,
+		ChallengeType:      req.ValidationMethod,
+		RemoteSuccesses:    passed,
+		RemoteFailures:     failed,
+		RemoteFailurePeers: slices.Sorted(slices.Values(failedPeers)),
 	}
 
 	logJSON, err := json.Marshal(logOb)
@@ -634,7 +1427,9 @@ func (va *ValidationAuthorityImpl) logRemoteResults(req *vapb.IsCAAValidRequest,
 
 // performLocalValidation performs primary domain control validation and then
 // checks CAA. If either step fails, it immediately returns a bare error so
-// that our audit logging can include the underlying error.
+// that our audit logging can include the underlying error. The DNS queries
+// made by the CAA check, if any, are returned separately from the records of
+// the validation.
 func (va *ValidationAuthorityImpl) performLocalValidation(
 	ctx context.Context,
 	ident identifier.ACMEIdentifier,
@@ -642,27 +1437,23 @@ func (va *ValidationAuthorityImpl) performLocalValidation(
 	kind core.AcmeChallenge,
 	token string,
 	keyAuthorization string,
-) ([]core.ValidationRecord, error) {
+) ([]core.ValidationRecord, []core.DNSQuery, error) {
 	// Do primary domain control validation. Any kind of error returned by this
 	// counts as a validation error, and will be converted into an appropriate
 	// probs.ProblemDetails by the calling function.
-	records, err := va.validateChallenge(ctx, ident, kind, token, keyAuthorization)
+	records, err := va.validateChallenge(ctx, ident, regid, kind, token, keyAuthorization)
 	if err != nil {
-		return records, err
+		return records, nil, err
 	}
 
 	// Do primary CAA checks. Any kind of error returned by this counts as not
 	// receiving permission to issue, and will be converted into an appropriate
 	// probs.ProblemDetails by the calling function.
-	err = va.checkCAA(ctx, ident, &caaParams{
+	queries, err := va.checkCAAWithQueries(ctx, ident, &caaParams{
 		accountURIID:     regid,
 		validationMethod: kind,
 	})
-	if err != nil {
-		return records, err
-	}
-
-	return records, nil
+	return records, dnsQueries(queries), err
 }
 
 // PerformValidation conducts a local Domain Control Validation (DCV) and CAA
@@ -675,11 +1466,43 @@ func (va *ValidationAuthorityImpl) performLocalValidation(
 // ValidationResult always includes a list of ValidationRecords, even when it
 // also contains Problems. This method does NOT implement Multi-Perspective
 // Issuance Corroboration as defined in BRs Sections 3.2.2.9 and 5.4.1.
+// Identical requests which arrive while a validation is in flight, or shortly
+// after it completes, share its result.
 func (va *ValidationAuthorityImpl) PerformValidation(ctx context.Context, req *vapb.PerformValidationRequest) (*vapb.ValidationResult, error) {
 	if core.IsAnyNilOrZero(req, req.DnsName, req.Challenge, req.Authz, req.ExpectedKeyAuthorization) {
 		return nil, berrors.InternalServerError("Incomplete validation request")
 	}
+	release, err := va.admission.admit(ctx, opDCVAndCAA)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return va.dedupValidation(ctx, opDCVAndCAA, req, va.performValidation)
+}
 
+// requestIdentifier returns the identifier which req asks to be validated. An
+// RA which predates the identifier field only sets dnsName.
+func requestIdentifier(req *vapb.PerformValidationRequest) identifier.ACMEIdentifier {
+	if req.Identifier == nil {
+		return identifier.NewDNS(req.DnsName)
+	}
+	return identifier.FromProto(req.Identifier)
+}
+
+// disallowedChallengeType returns a problem if allowed, the challenge types the
+// RA's policy permits for the identifier being validated, is non-empty and
+// doesn't include challType. The RA should never request such a validation,
+// for instance of HTTP-01 for a wildcard name, so this is a defense in depth.
+func disallowedChallengeType(allowed []string, challType core.AcmeChallenge) *probs.ProblemDetails {
+	if len(allowed) == 0 || slices.Contains(allowed, string(challType)) {
+		return nil
+	}
+	return probs.Malformed("Challenge type %q may not be used to validate this identifier; allowed challenge types are %s",
+		challType, strings.Join(allowed, ", "))
+}
+
+// performValidation performs the validation requested of PerformValidation.
+func (va *ValidationAuthorityImpl) performValidation(ctx context.Context, req *vapb.PerformValidationRequest) (*vapb.ValidationResult, error) {
 	chall, err := bgrpc.PBToChallenge(req.Challenge)
 	if err != nil {
 		return nil, errors.New("challenge failed to deserialize")
@@ -693,21 +1516,29 @@ func (va *ValidationAuthorityImpl) PerformValidation(ctx context.Context, req *v
 	// Set up variables and a deferred closure to report validation latency
 	// metrics and log validation errors. Below here, do not use := to redeclare
 	// `prob`, or this will fail.
+	ctx, attemptID, done := va.startAttempt(ctx, opDCVAndCAA, req.DnsName, chall.Type, req.Authz.RegID)
+	defer done()
 	var prob *probs.ProblemDetails
 	var localLatency time.Duration
+	ctx, timings := withPhaseTimings(ctx)
+	ctx, httpRes := withHTTPResponseRecord(ctx)
 	start := va.clk.Now()
 	logEvent := verificationRequestEvent{
+		AttemptID:  attemptID,
 		AuthzID:    req.Authz.Id,
 		Requester:  req.Authz.RegID,
 		Identifier: req.DnsName,
 		Challenge:  chall,
 	}
+	ctx, logEvent.PolicyHints = va.applyPolicyHints(ctx, req.PolicyHints)
+	logEvent.AllowedChallengeTypes = req.AllowedChallengeTypes
 	defer func() {
 		probType := ""
 		outcome := fail
 		if prob != nil {
 			probType = string(prob.Type)
 			logEvent.Error = prob.Error()
+			logEvent.DNSDetails = prob.DNSDetails
 			logEvent.Challenge.Error = prob
 			logEvent.Challenge.Status = core.StatusInvalid
 		} else {
@@ -720,23 +1551,39 @@ func (va *ValidationAuthorityImpl) PerformValidation(ctx context.Context, req *v
 			// Observe total validation latency (primary+remote).
 			va.observeLatency(opDCVAndCAA, allPerspectives, string(chall.Type), probType, outcome, va.clk.Since(start))
 		}
+		if prob == nil {
+			va.checkValidationSLO(req.Authz.Id, req.DnsName, chall.Type, va.clk.Since(start), timings)
+		}
 
 		// Log the total validation latency.
 		logEvent.Latency = va.clk.Since(start).Round(time.Millisecond).Seconds()
+		logEvent.Phases = timings.seconds()
+		logEvent.FinalURL, logEvent.StatusCode = httpRes.get()
 		va.log.AuditObject("Validation result", logEvent)
 	}()
 
+	prob = disallowedChallengeType(req.AllowedChallengeTypes, chall.Type)
+	if prob != nil {
+		return va.validationResult(nil, prob, start, nil, nil, httpRes)
+	}
+
+	prob, logEvent.Risk = va.checkRisk(ctx, requestIdentifier(req), req.Authz.RegID)
+	if prob != nil {
+		return va.validationResult(nil, prob, start, nil, nil, httpRes)
+	}
+
 	// Do local validation. Note that we process the result in a couple ways
 	// *before* checking whether it returned an error. These few checks are
 	// carefully written to ensure that they work whether the local validation
 	// was successful or not, and cannot themselves fail.
-	records, err := va.performLocalValidation(
+	records, caaQueries, err := va.performLocalValidation(
 		ctx,
-		identifier.NewDNS(req.DnsName),
+		requestIdentifier(req),
 		req.Authz.RegID,
 		chall.Type,
 		chall.Token,
 		req.ExpectedKeyAuthorization)
+	logEvent.CAAQueries = caaQueries
 
 	// Stop the clock for local validation latency.
 	localLatency = va.clk.Since(start)
@@ -749,9 +1596,13 @@ func (va *ValidationAuthorityImpl) PerformValidation(ctx context.Context, req *v
 
 	if err != nil {
 		logEvent.InternalError = err.Error()
+		logEvent.DNSResolver = dnsResolver(err)
+		va.noteResourceExhaustion(opDCVAndCAA, req.DnsName, err)
 		prob = detailedError(err)
-		return bgrpc.ValidationResultToPB(records, filterProblemDetails(prob), va.perspective, va.rir)
+		logEvent.Message = messageRecordFor(err)
+		return va.validationResult(records, prob, start, nil, nil, httpRes)
 	}
+	va.metrics.validationAddressFamilies.WithLabelValues(va.perspective, string(chall.Type), decisiveAddressFamily(records)).Inc()
 
 	// Do remote validation. We do this after local validation is complete to
 	// avoid wasting work when validation will fail anyway. This only returns a
@@ -765,6 +1616,8 @@ func (va *ValidationAuthorityImpl) PerformValidation(ctx context.Context, req *v
 		}
 		return remoteva.PerformValidation(ctx, validationRequest)
 	}
+	remoteStart := va.clk.Now()
 	prob = va.performRemoteOperation(ctx, op, req)
-	return bgrpc.ValidationResultToPB(records, filterProblemDetails(prob), va.perspective, va.rir)
+	timings.add(phaseRemoteQuorum, va.clk.Since(remoteStart))
+	return va.validationResult(records, prob, start, nil, nil, httpRes)
 }
@@ -10,23 +10,31 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
 	"github.com/jmhodges/clock"
+	"github.com/miekg/dns"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/letsencrypt/boulder/bdns"
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
+	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/features"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/identifier"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
@@ -108,6 +116,20 @@ func createValidationRequest(domain string, challengeType core.AcmeChallenge) *v
 // If remoteVAs is nil, this builds a VA that acts like a remote (and does not
 // perform multi-perspective validation). Otherwise it acts like a primary.
 func setup(srv *httptest.Server, userAgent string, remoteVAs []RemoteVA, mockDNSClientOverride bdns.Client) (*ValidationAuthorityImpl, *blog.Mock) {
+	// Adjusting industry regulated ACME challenge port settings is fine during
+	// testing
+	ports := defaultValidationPorts()
+	if srv != nil {
+		port := getPort(srv)
+		ports.http = port
+		ports.tls = port
+	}
+	return setupWithPorts(ports, userAgent, remoteVAs, mockDNSClientOverride)
+}
+
+// setupWithPorts is like setup, but connects to the provided ports rather than
+// those of a test server.
+func setupWithPorts(ports validationPorts, userAgent string, remoteVAs []RemoteVA, mockDNSClientOverride bdns.Client) (*ValidationAuthorityImpl, *blog.Mock) {
 	features.Reset()
 	fc := clock.NewFake()
 
@@ -124,7 +146,8 @@ func setup(srv *httptest.Server, userAgent string, remoteVAs []RemoteVA, mockDNS
 		perspective = "example perspective " + core.RandomString(4)
 	}
 
-	va, err := NewValidationAuthorityImpl(
+	va, err := newValidationAuthorityImpl(
+		ports,
 		&bdns.MockClient{Log: logger},
 		remoteVAs,
 		userAgent,
@@ -135,6 +158,7 @@ func setup(srv *httptest.Server, userAgent string, remoteVAs []RemoteVA, mockDNS
 		accountURIPrefixes,
 		perspective,
 		"",
+		testOptions(),
 	)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create validation authority: %v", err))
@@ -144,17 +168,24 @@ func setup(srv *httptest.Server, userAgent string, remoteVAs []RemoteVA, mockDNS
 		va.dnsClient = mockDNSClientOverride
 	}
 
-	// Adjusting industry regulated ACME challenge port settings is fine during
-	// testing
-	if srv != nil {
-		port := getPort(srv)
-		va.httpPort = port
-		va.tlsPort = port
-	}
-
 	return va, logger
 }
 
+// testOptions returns the Options of a VA set up for tests.
+func testOptions() Options {
+	return Options{
+		DevMode:             true,
+		MaxHTTPRetryAfter:   2 * time.Second,
+		SLOThreshold:        10 * time.Second,
+		MinTXTTTL:           5 * time.Second,
+		MaxCAABatchSize:     100,
+		CAABatchParallelism: 10,
+		MaxValidationBytes:  1 << 20,
+		MaxConnsPerTargetIP: 10,
+		MaxTargetIPConnWait: 5 * time.Second,
+	}
+}
+
 func setupRemote(srv *httptest.Server, userAgent string, mockDNSClientOverride bdns.Client, perspective, rir string) RemoteClients {
 	rva, _ := setup(srv, userAgent, nil, mockDNSClientOverride)
 	rva.perspective = perspective
@@ -182,6 +213,8 @@ type remoteConf struct {
 	ua string
 	// rir is required.
 	rir string
+	// asn is optional.
+	asn uint32
 	// dns is optional.
 	dns bdns.Client
 	// impl is optional.
@@ -213,6 +246,7 @@ func setupRemotes(confs []remoteConf, srv *httptest.Server) []RemoteVA {
 			RemoteClients: clients,
 			Perspective:   perspective,
 			RIR:           c.rir,
+			ASN:           c.asn,
 		})
 	}
 
@@ -282,6 +316,10 @@ func (v cancelledVA) DoCAA(_ context.Context, _ *vapb.IsCAAValidRequest, _ ...gr
 	return nil, context.Canceled
 }
 
+func (v cancelledVA) CheckCAAMulti(_ context.Context, _ *vapb.CheckCAAMultiRequest, _ ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return nil, context.Canceled
+}
+
 // brokenRemoteVA is a mock for the VAClient and CAAClient interfaces that always return
 // errors.
 type brokenRemoteVA struct{}
@@ -308,6 +346,10 @@ func (b brokenRemoteVA) DoCAA(_ context.Context, _ *vapb.IsCAAValidRequest, _ ..
 	return nil, errBrokenRemoteVA
 }
 
+func (b brokenRemoteVA) CheckCAAMulti(_ context.Context, _ *vapb.CheckCAAMultiRequest, _ ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return nil, errBrokenRemoteVA
+}
+
 // inMemVA is a wrapper which fulfills the VAClient and CAAClient
 // interfaces, but then forwards requests directly to its inner
 // ValidationAuthorityImpl rather than over the network. This lets a local
@@ -332,6 +374,10 @@ func (inmem *inMemVA) DoCAA(ctx context.Context, req *vapb.IsCAAValidRequest, _
 	return inmem.rva.DoCAA(ctx, req)
 }
 
+func (inmem *inMemVA) CheckCAAMulti(ctx context.Context, req *vapb.CheckCAAMultiRequest, _ ...grpc.CallOption) (*vapb.CheckCAAMultiResponse, error) {
+	return inmem.rva.CheckCAAMulti(ctx, req)
+}
+
 func TestNewValidationAuthorityImplWithDuplicateRemotes(t *testing.T) {
 	var remoteVAs []RemoteVA
 	for i := 0; i < 3; i++ {
@@ -351,13 +397,427 @@ func TestNewValidationAuthorityImplWithDuplicateRemotes(t *testing.T) {
 		clock.NewFake(),
 		blog.NewMock(),
 		accountURIPrefixes,
-		"example perspective",
+		PrimaryPerspective,
 		"",
+		testOptions(),
 	)
 	test.AssertError(t, err, "NewValidationAuthorityImpl allowed duplicate remote perspectives")
 	test.AssertContains(t, err.Error(), "duplicate remote VA perspective \"dadaist\"")
 }
 
+func TestNewValidationAuthorityImplMinDistinctASNs(t *testing.T) {
+	remoteVAs := setupRemotes([]remoteConf{
+		{rir: arin, asn: 100},
+		{rir: ripe, asn: 100},
+		{rir: apnic},
+	}, nil)
+
+	newVA := func(minDistinctASNs int) error {
+		opts := testOptions()
+		opts.MinDistinctASNs = minDistinctASNs
+		_, err := NewValidationAuthorityImpl(
+			&bdns.MockClient{Log: blog.NewMock()},
+			remoteVAs,
+			"user agent 1.0",
+			"letsencrypt.org",
+			metrics.NoopRegisterer,
+			clock.NewFake(),
+			blog.NewMock(),
+			accountURIPrefixes,
+			PrimaryPerspective,
+			"",
+			opts,
+		)
+		return err
+	}
+
+	test.AssertNotError(t, newVA(0), "NewValidationAuthorityImpl rejected no ASN requirement")
+	test.AssertNotError(t, newVA(1), "NewValidationAuthorityImpl rejected a satisfiable ASN requirement")
+	err := newVA(2)
+	test.AssertError(t, err, "NewValidationAuthorityImpl allowed an unsatisfiable ASN requirement")
+	test.AssertContains(t, err.Error(), "2 distinct ASNs are required but remote VAs are configured with only 1")
+	test.AssertError(t, newVA(-1), "NewValidationAuthorityImpl allowed a negative ASN requirement")
+}
+
+func TestNewValidationAuthorityImplInvalidConfig(t *testing.T) {
+	remoteVAs := setupRemotes([]remoteConf{{rir: arin}}, nil)
+
+	type config struct {
+		remoteVAs          []RemoteVA
+		userAgent          string
+		accountURIPrefixes []string
+		perspective        string
+		rir                string
+		opts               Options
+	}
+	valid := func() config {
+		opts := testOptions()
+		opts.DevMode = false
+		return config{
+			remoteVAs:          remoteVAs,
+			userAgent:          "user agent 1.0",
+			accountURIPrefixes: []string{"https://acme-v02.api.letsencrypt.org/acme/acct/"},
+			perspective:        PrimaryPerspective,
+			opts:               opts,
+		}
+	}
+	newVA := func(c config) error {
+		_, err := NewValidationAuthorityImpl(
+			&bdns.MockClient{Log: blog.NewMock()},
+			c.remoteVAs,
+			c.userAgent,
+			"letsencrypt.org",
+			metrics.NoopRegisterer,
+			clock.NewFake(),
+			blog.NewMock(),
+			c.accountURIPrefixes,
+			c.perspective,
+			c.rir,
+			c.opts,
+		)
+		return err
+	}
+
+	test.AssertNotError(t, newVA(valid()), "NewValidationAuthorityImpl rejected a valid primary config")
+
+	remote := valid()
+	remote.remoteVAs = nil
+	remote.perspective = "dadaist"
+	remote.rir = arin
+	test.AssertNotError(t, newVA(remote), "NewValidationAuthorityImpl rejected a valid remote config")
+
+	dev := valid()
+	dev.accountURIPrefixes = []string{"http://boulder.service.consul:4000/acme/reg/"}
+	dev.opts.DevMode = true
+	test.AssertNotError(t, newVA(dev), "NewValidationAuthorityImpl rejected an http prefix in dev mode")
+
+	headers := valid()
+	headers.opts.HTTPHeaders = map[string]string{"X-Acme-Validation": "1", "x-tenant": "example"}
+	test.AssertNotError(t, newVA(headers), "NewValidationAuthorityImpl rejected additional HTTP headers")
+
+	proxied := valid()
+	proxied.opts.ProxyProtocolSource = netip.MustParseAddr("192.0.2.1")
+	test.AssertNotError(t, newVA(proxied), "NewValidationAuthorityImpl rejected a PROXY protocol source address")
+
+	internal := valid()
+	internal.opts.Internal = InternalIdentifiers{
+		InsecureInternalIssuance: true,
+		Prefixes:                 []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}
+	test.AssertNotError(t, newVA(internal), "NewValidationAuthorityImpl rejected internal identifiers")
+
+	testCases := []struct {
+		name        string
+		modify      func(*config)
+		expectedErr string
+	}{
+		{
+			name:        "no account URI prefixes",
+			modify:      func(c *config) { c.accountURIPrefixes = nil },
+			expectedErr: "no account URI prefixes configured",
+		},
+		{
+			name:        "unparseable account URI prefix",
+			modify:      func(c *config) { c.accountURIPrefixes = []string{"https://exa mple.com/acct/"} },
+			expectedErr: "parsing account URI prefix",
+		},
+		{
+			name:        "relative account URI prefix",
+			modify:      func(c *config) { c.accountURIPrefixes = []string{"/acme/acct/"} },
+			expectedErr: "is not an absolute URL",
+		},
+		{
+			name:        "account URI prefix without a host",
+			modify:      func(c *config) { c.accountURIPrefixes = []string{"https:///acme/acct/"} },
+			expectedErr: "is not an absolute URL",
+		},
+		{
+			name: "http account URI prefix",
+			modify: func(c *config) {
+				c.accountURIPrefixes = append(c.accountURIPrefixes, "http://acme-v02.api.letsencrypt.org/acme/acct/")
+			},
+			expectedErr: "must use https",
+		},
+		{
+			name: "ftp account URI prefix in dev mode",
+			modify: func(c *config) {
+				c.accountURIPrefixes = []string{"ftp://acme-v02.api.letsencrypt.org/acme/acct/"}
+				c.opts.DevMode = true
+			},
+			expectedErr: "must use https",
+		},
+		{
+			name:        "no user agent",
+			modify:      func(c *config) { c.userAgent = "" },
+			expectedErr: "no user agent configured",
+		},
+		{
+			name:        "zero SLO threshold",
+			modify:      func(c *config) { c.opts.SLOThreshold = 0 },
+			expectedErr: "validation SLO threshold must be positive, got 0s",
+		},
+		{
+			name:        "negative dedup window",
+			modify:      func(c *config) { c.opts.DedupWindow = -time.Second },
+			expectedErr: "validation dedup window must not be negative, got -1s",
+		},
+		{
+			name:        "negative max concurrent validations",
+			modify:      func(c *config) { c.opts.MaxConcurrentValidations = -1 },
+			expectedErr: "max concurrent validations must not be negative, got -1",
+		},
+		{
+			name:        "negative max validation queue wait",
+			modify:      func(c *config) { c.opts.MaxQueueWait = -time.Second },
+			expectedErr: "max validation queue wait must not be negative, got -1s",
+		},
+		{
+			name:        "IP address confirm over TLS domain",
+			modify:      func(c *config) { c.opts.ConfirmOverTLSDomains = []string{"10.0.0.1"} },
+			expectedErr: `invalid HTTP-01 confirm over TLS domain "10.0.0.1"`,
+		},
+		{
+			name:        "leading dot confirm over TLS domain",
+			modify:      func(c *config) { c.opts.ConfirmOverTLSDomains = []string{".example.com"} },
+			expectedErr: `invalid HTTP-01 confirm over TLS domain ".example.com"`,
+		},
+		{
+			name:        "Host HTTP header",
+			modify:      func(c *config) { c.opts.HTTPHeaders = map[string]string{"Host": "example.com"} },
+			expectedErr: `additional HTTP header "Host" may not be configured`,
+		},
+		{
+			name:        "lowercase Authorization HTTP header",
+			modify:      func(c *config) { c.opts.HTTPHeaders = map[string]string{"authorization": "Bearer x"} },
+			expectedErr: `additional HTTP header "Authorization" may not be configured`,
+		},
+		{
+			name:        "Cookie HTTP header",
+			modify:      func(c *config) { c.opts.HTTPHeaders = map[string]string{"Cookie": "a=b"} },
+			expectedErr: `additional HTTP header "Cookie" may not be configured`,
+		},
+		{
+			name:        "Proxy- HTTP header",
+			modify:      func(c *config) { c.opts.HTTPHeaders = map[string]string{"Proxy-Foo": "bar"} },
+			expectedErr: `additional HTTP header "Proxy-Foo" may not be configured`,
+		},
+		{
+			name:        "malformed HTTP header name",
+			modify:      func(c *config) { c.opts.HTTPHeaders = map[string]string{"X Acme": "1"} },
+			expectedErr: `invalid additional HTTP header name "X Acme"`,
+		},
+		{
+			name:        "malformed HTTP header value",
+			modify:      func(c *config) { c.opts.HTTPHeaders = map[string]string{"X-Acme": "1\r\nHost: evil"} },
+			expectedErr: `invalid value for additional HTTP header "X-Acme"`,
+		},
+		{
+			name:        "duplicate HTTP header",
+			modify:      func(c *config) { c.opts.HTTPHeaders = map[string]string{"X-Acme": "1", "x-acme": "2"} },
+			expectedErr: `additional HTTP header "X-Acme" configured more than once`,
+		},
+		{
+			name: "too many HTTP headers",
+			modify: func(c *config) {
+				c.opts.HTTPHeaders = make(map[string]string)
+				for i := range maxHTTPHeaders + 1 {
+					c.opts.HTTPHeaders[fmt.Sprintf("X-Header-%d", i)] = "1"
+				}
+			},
+			expectedErr: "at most 8 additional HTTP headers may be configured, got 9",
+		},
+		{
+			name:        "primary with an RIR",
+			modify:      func(c *config) { c.rir = arin },
+			expectedErr: "the primary VA must not have an RIR",
+		},
+		{
+			name:        "remote with remote VAs",
+			modify:      func(c *config) { c.perspective = "dadaist" },
+			expectedErr: "only the primary VA may have remote VAs",
+		},
+		{
+			name: "RIR without a perspective",
+			modify: func(c *config) {
+				c.remoteVAs = nil
+				c.perspective = ""
+				c.rir = arin
+			},
+			expectedErr: "configured without a perspective",
+		},
+		{
+			name: "unrecognized RIR",
+			modify: func(c *config) {
+				c.remoteVAs = nil
+				c.perspective = "dadaist"
+				c.rir = "NORAD"
+			},
+			expectedErr: "unrecognized RIR \"NORAD\"",
+		},
+		{
+			name:        "unspecified PROXY protocol source address",
+			modify:      func(c *config) { c.opts.ProxyProtocolSource = netip.IPv6Unspecified() },
+			expectedErr: "PROXY protocol source address must be a specific address",
+		},
+		{
+			name: "internal identifiers without insecureInternalIssuance",
+			modify: func(c *config) {
+				c.opts.Internal = InternalIdentifiers{Domains: []string{"corp.example"}}
+			},
+			expectedErr: "internal identifiers may not be configured without insecureInternalIssuance",
+		},
+		{
+			name: "remote with internal identifiers",
+			modify: func(c *config) {
+				c.remoteVAs = nil
+				c.perspective = "dadaist"
+				c.rir = arin
+				c.opts.Internal = InternalIdentifiers{InsecureInternalIssuance: true, Domains: []string{"corp.example"}}
+			},
+			expectedErr: "only the primary VA may have internal identifiers",
+		},
+		{
+			name: "remote with a risk checker",
+			modify: func(c *config) {
+				c.remoteVAs = nil
+				c.perspective = "dadaist"
+				c.rir = arin
+				c.opts.Risk = RiskConfig{Checker: &fakeRiskChecker{decision: RiskAllow}}
+			},
+			expectedErr: "risk checks may only be made by the primary VA",
+		},
+		{
+			name: "remote with recheckCAALocalOnly",
+			modify: func(c *config) {
+				c.remoteVAs = nil
+				c.perspective = "dadaist"
+				c.rir = arin
+				c.opts.RecheckCAALocalOnly = true
+			},
+			expectedErr: "recheckCAALocalOnly may only be set for the primary VA",
+		},
+		{
+			name:        "zero max connections per target IP",
+			modify:      func(c *config) { c.opts.MaxConnsPerTargetIP = 0 },
+			expectedErr: "max connections per target IP must be positive",
+		},
+		{
+			name:        "negative target IP connection wait",
+			modify:      func(c *config) { c.opts.MaxTargetIPConnWait = -time.Second },
+			expectedErr: "max target IP connection wait must not be negative",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := valid()
+			tc.modify(&c)
+			err := newVA(c)
+			test.AssertError(t, err, "NewValidationAuthorityImpl allowed an invalid config")
+			test.AssertContains(t, err.Error(), tc.expectedErr)
+		})
+	}
+}
+
+func TestNewValidationAuthorityImplLogsSettings(t *testing.T) {
+	va, mockLog := setup(nil, "", nil, nil)
+	matches := mockLog.GetAllMatching(`VA configured with perspective="` + va.perspective + `" rir="" remoteVAs=0 .* devMode=true`)
+	test.AssertEquals(t, len(matches), 1)
+}
+
+func TestNewValidationAuthorityImplPorts(t *testing.T) {
+	newVA := func(ports validationPorts, logger blog.Logger) error {
+		_, err := newValidationAuthorityImpl(
+			ports,
+			&bdns.MockClient{Log: logger},
+			nil,
+			"user agent 1.0",
+			"letsencrypt.org",
+			metrics.NoopRegisterer,
+			clock.NewFake(),
+			logger,
+			accountURIPrefixes,
+			"example perspective",
+			"",
+			testOptions(),
+		)
+		return err
+	}
+
+	testCases := []struct {
+		name        string
+		ports       validationPorts
+		expectedErr string
+	}{
+		{"zero HTTP port", validationPorts{http: 0, https: 443, tls: 443}, "invalid HTTP port 0"},
+		{"negative HTTPS port", validationPorts{http: 80, https: -443, tls: 443}, "invalid HTTPS port -443"},
+		{"TLS port too large", validationPorts{http: 80, https: 443, tls: 65536}, "invalid TLS port 65536"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newVA(tc.ports, blog.NewMock())
+			test.AssertError(t, err, "newValidationAuthorityImpl allowed an invalid port")
+			test.AssertContains(t, err.Error(), tc.expectedErr)
+		})
+	}
+
+	// The default ports are accepted without complaint.
+	logger := blog.NewMock()
+	test.AssertNotError(t, newVA(defaultValidationPorts(), logger), "newValidationAuthorityImpl rejected the default ports")
+	test.AssertEquals(t, len(logger.GetAllMatching("WARNING")), 0)
+
+	// Valid but non-default ports are accepted with a warning for each.
+	logger = blog.NewMock()
+	test.AssertNotError(t, newVA(validationPorts{http: 5002, https: 443, tls: 65535}, logger), "newValidationAuthorityImpl rejected valid ports")
+	test.AssertEquals(t, len(logger.GetAllMatching("WARNING: VA is using HTTP port 5002 instead of 80")), 1)
+	test.AssertEquals(t, len(logger.GetAllMatching("WARNING: VA is using TLS port 65535 instead of 443")), 1)
+	test.AssertEquals(t, len(logger.GetAllMatching("HTTPS port")), 0)
+}
+
+// TestConcurrentValidationPorts runs HTTP-01 and TLS-ALPN-01 validations
+// concurrently, and is most useful under the race detector, which would flag
+// any write to the VA's ports after construction.
+func TestConcurrentValidationPorts(t *testing.T) {
+	hs := httpSrv(t, expectedToken)
+	defer hs.Close()
+
+	// A TLS server which closes every connection right away is enough to learn
+	// which port the VA dialed.
+	ports := defaultValidationPorts()
+	ports.http = getPort(hs)
+	ports.tls = rawCloseSrv(t, func(net.Conn) {})
+	va, _ := setupWithPorts(ports, "", nil, nil)
+
+	type result struct {
+		challType core.AcmeChallenge
+		records   []core.ValidationRecord
+		err       error
+	}
+	results := make(chan result, 20)
+	for range 10 {
+		go func() {
+			records, err := va.validateHTTP01(ctx, dnsi("localhost"), expectedToken, expectedKeyAuthorization)
+			results <- result{core.ChallengeTypeHTTP01, records, err}
+		}()
+		go func() {
+			// The TLS server closes every connection, so this validation is
+			// expected to fail after recording the port it dialed.
+			records, _ := va.validateTLSALPN01(ctx, dnsi("expected"), expectedKeyAuthorization)
+			results <- result{core.ChallengeTypeTLSALPN01, records, nil}
+		}()
+	}
+	for range 20 {
+		res := <-results
+		test.AssertNotError(t, res.err, fmt.Sprintf("%s validation failed", res.challType))
+		test.AssertEquals(t, len(res.records), 1)
+		switch res.challType {
+		case core.ChallengeTypeHTTP01:
+			test.AssertEquals(t, res.records[0].Port, fmt.Sprint(ports.http))
+		case core.ChallengeTypeTLSALPN01:
+			test.AssertEquals(t, res.records[0].Port, fmt.Sprint(ports.tls))
+		}
+	}
+}
+
 type validationFuncRunner func(context.Context, *ValidationAuthorityImpl, *vapb.PerformValidationRequest) (*vapb.ValidationResult, error)
 
 var runPerformValidation = func(ctx context.Context, va *ValidationAuthorityImpl, req *vapb.PerformValidationRequest) (*vapb.ValidationResult, error) {
@@ -368,6 +828,193 @@ var runDoDCV = func(ctx context.Context, va *ValidationAuthorityImpl, req *vapb.
 	return va.DoDCV(ctx, req)
 }
 
+// slowRemoteVA is a remote VA which advances the primary VA's clock before
+// performing a real validation.
+type slowRemoteVA struct {
+	vapb.VAClient
+	clk   clock.FakeClock
+	delay time.Duration
+}
+
+func (s *slowRemoteVA) DoDCV(ctx context.Context, req *vapb.PerformValidationRequest, opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+	s.clk.Add(s.delay)
+	return s.VAClient.DoDCV(ctx, req, opts...)
+}
+
+func TestValidationSLO(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fast", func(t *testing.T) {
+		t.Parallel()
+		ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true})
+		defer ms.Close()
+
+		va, mockLog := setup(ms.Server, pass, nil, nil)
+		res, err := va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+		test.AssertNotError(t, err, "performing validation")
+		test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed with: %#v", res.Problem))
+		test.AssertEquals(t, len(mockLog.GetAllMatching("exceeded SLO")), 0)
+	})
+
+	t.Run("slow local server", func(t *testing.T) {
+		t.Parallel()
+		fc := clock.NewFake()
+		hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fc.Add(11 * time.Second)
+			fmt.Fprint(w, expectedKeyAuthorization)
+		}))
+		defer hs.Close()
+
+		va, mockLog := setup(hs, "", nil, nil)
+		va.clk = fc
+		res, err := va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+		test.AssertNotError(t, err, "performing validation")
+		test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed with: %#v", res.Problem))
+
+		test.AssertMetricWithLabelsEquals(t, va.metrics.validationSLOBreaches, prometheus.Labels{
+			"phase":          "fetch",
+			"challenge_type": string(core.ChallengeTypeHTTP01),
+		}, 1)
+		warnings := mockLog.GetAllMatching(`^WARNING: Validation exceeded SLO JSON=`)
+		test.AssertEquals(t, len(warnings), 1)
+		test.AssertContains(t, warnings[0], `"SlowestPhase":"fetch"`)
+		test.AssertContains(t, warnings[0], `"Latency":11`)
+		test.AssertContains(t, warnings[0], `"Threshold":10`)
+	})
+
+	t.Run("slow remote", func(t *testing.T) {
+		t.Parallel()
+		ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true})
+		defer ms.Close()
+
+		va, mockLog := setupWithRemotes(ms.Server, pass, []remoteConf{
+			{ua: pass, rir: arin},
+			{ua: pass, rir: ripe},
+			{ua: pass, rir: apnic},
+		}, nil)
+		fc := clock.NewFake()
+		va.clk = fc
+		for i := range va.remoteVAs {
+			va.remoteVAs[i].VAClient = &slowRemoteVA{VAClient: va.remoteVAs[i].VAClient, clk: fc, delay: 6 * time.Second}
+		}
+		res, err := va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+		test.AssertNotError(t, err, "performing validation")
+		test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed with: %#v", res.Problem))
+
+		test.AssertMetricWithLabelsEquals(t, va.metrics.validationSLOBreaches, prometheus.Labels{
+			"phase":          "remote_quorum",
+			"challenge_type": string(core.ChallengeTypeHTTP01),
+		}, 1)
+		warnings := mockLog.GetAllMatching(`^WARNING: Validation exceeded SLO JSON=`)
+		test.AssertEquals(t, len(warnings), 1)
+		test.AssertContains(t, warnings[0], `"SlowestPhase":"remote_quorum"`)
+	})
+}
+
+// countingRemoteVA wraps a remote VA and counts the DoDCV calls made of it.
+type countingRemoteVA struct {
+	RemoteClients
+	calls *atomic.Int64
+}
+
+func (c *countingRemoteVA) DoDCV(ctx context.Context, req *vapb.PerformValidationRequest, opts ...grpc.CallOption) (*vapb.ValidationResult, error) {
+	c.calls.Add(1)
+	return c.VAClient.DoDCV(ctx, req, opts...)
+}
+
+func TestDoDCVDedup(t *testing.T) {
+	t.Parallel()
+
+	var fetches atomic.Int64
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.UserAgent() == pass {
+			fetches.Add(1)
+		}
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, expectedKeyAuthorization)
+	}))
+	defer hs.Close()
+
+	va, _ := setupWithRemotes(hs, pass, []remoteConf{
+		{ua: "remote", rir: arin},
+		{ua: "remote", rir: ripe},
+		{ua: "remote", rir: apnic},
+	}, nil)
+	var remoteCalls atomic.Int64
+	for i := range va.remoteVAs {
+		va.remoteVAs[i].VAClient = &countingRemoteVA{RemoteClients: va.remoteVAs[i].RemoteClients, calls: &remoteCalls}
+	}
+
+	// Identical requests made while a validation is in flight share its
+	// result.
+	type result struct {
+		res *vapb.ValidationResult
+		err error
+	}
+	results := make(chan result, 10)
+	for range 10 {
+		go func() {
+			res, err := va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+			results <- result{res, err}
+		}()
+	}
+	for range 10 {
+		r := <-results
+		test.AssertNotError(t, r.err, "performing validation")
+		test.Assert(t, r.res.Problem == nil, fmt.Sprintf("validation failed with: %#v", r.res.Problem))
+	}
+	test.AssertEquals(t, fetches.Load(), int64(1))
+	test.AssertEquals(t, remoteCalls.Load(), int64(3))
+	test.AssertMetricWithLabelsEquals(t, va.metrics.validationsDeduplicated, prometheus.Labels{
+		"operation": opDCV,
+		"reason":    "in_flight",
+	}, 9)
+
+	// Without a window, a later identical request is validated afresh.
+	_, err := va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+	test.AssertNotError(t, err, "performing validation")
+	test.AssertEquals(t, fetches.Load(), int64(2))
+
+	// With one, it shares the result until the window has passed.
+	fc := clock.NewFake()
+	va.clk = fc
+	va.deduper.window = 2 * time.Second
+	for range 2 {
+		_, err = va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+		test.AssertNotError(t, err, "performing validation")
+	}
+	test.AssertEquals(t, fetches.Load(), int64(3))
+	test.AssertMetricWithLabelsEquals(t, va.metrics.validationsDeduplicated, prometheus.Labels{
+		"operation": opDCV,
+		"reason":    "completed",
+	}, 1)
+
+	// Requests for another token aren't identical.
+	req := createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01)
+	req.Challenge.Token = core.NewToken()
+	_, err = va.DoDCV(ctx, req)
+	test.AssertNotError(t, err, "performing validation")
+	test.AssertEquals(t, fetches.Load(), int64(4))
+
+	fc.Add(2 * time.Second)
+	_, err = va.DoDCV(ctx, createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01))
+	test.AssertNotError(t, err, "performing validation")
+	test.AssertEquals(t, fetches.Load(), int64(5))
+}
+
+func TestDedupShareable(t *testing.T) {
+	t.Parallel()
+
+	test.Assert(t, shareable(&vapb.ValidationResult{}, nil), "successful result should be shareable")
+	test.Assert(t, shareable(&vapb.ValidationResult{
+		Problem: &corepb.ProblemDetails{ProblemType: string(probs.UnauthorizedProblem)},
+	}, nil), "failed validation should be shareable")
+	test.Assert(t, !shareable(&vapb.ValidationResult{
+		Problem: &corepb.ProblemDetails{ProblemType: string(probs.ServerInternalProblem)},
+	}, nil), "internal problem should not be shareable")
+	test.Assert(t, !shareable(nil, errors.New("oops")), "error should not be shareable")
+}
+
 func TestPerformValidationWithMismatchedRemoteVAPerspectives(t *testing.T) {
 	t.Parallel()
 
@@ -456,12 +1103,100 @@ func TestPerformValidationWithMismatchedRemoteVARIRs(t *testing.T) {
 func TestValidateMalformedChallenge(t *testing.T) {
 	va, _ := setup(nil, "", nil, nil)
 
-	_, err := va.validateChallenge(ctx, dnsi("example.com"), "fake-type-01", expectedToken, expectedKeyAuthorization)
+	_, err := va.validateChallenge(ctx, dnsi("example.com"), 1, "fake-type-01", expectedToken, expectedKeyAuthorization)
 
 	prob := detailedError(err)
 	test.AssertEquals(t, prob.Type, probs.MalformedProblem)
 }
 
+// panicDNS is a bdns.Client which panics if any lookup is performed.
+type panicDNS struct{}
+
+func (panicDNS) LookupTXT(_ context.Context, hostname string) ([]string, time.Duration, bdns.ResolverAddrs, error) {
+	panic(fmt.Sprintf("unexpected TXT lookup for %q", hostname))
+}
+
+func (panicDNS) LookupHost(_ context.Context, hostname string) ([]net.IP, bdns.ResolverAddrs, error) {
+	panic(fmt.Sprintf("unexpected host lookup for %q", hostname))
+}
+
+func (panicDNS) LookupHostFamilies(_ context.Context, hostname string) (*bdns.HostLookup, error) {
+	panic(fmt.Sprintf("unexpected host lookup for %q", hostname))
+}
+
+func (panicDNS) LookupMX(_ context.Context, hostname string) ([]string, bdns.ResolverAddrs, error) {
+	panic(fmt.Sprintf("unexpected MX lookup for %q", hostname))
+}
+
+func (panicDNS) LookupCAA(_ context.Context, hostname string) ([]*dns.CAA, string, bdns.ResolverAddrs, error) {
+	panic(fmt.Sprintf("unexpected CAA lookup for %q", hostname))
+}
+
+func TestNormalizeDNSName(t *testing.T) {
+	va, mockLog := setup(nil, "", nil, nil)
+
+	accepted := []struct {
+		in   string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"example.com.", "example.com"},
+		{"*.example.com.", "*.example.com"},
+		{"com", "com"},
+	}
+	for _, tc := range accepted {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := va.normalizeDNSName(tc.in)
+			test.AssertNotError(t, err, "normalizing name")
+			test.AssertEquals(t, got, tc.want)
+		})
+	}
+	test.AssertEquals(t, len(mockLog.GetAllMatching(`Stripping trailing dot from identifier "example.com."`)), 1)
+
+	rejected := []string{
+		"",
+		".",
+		"example.com..",
+		".example.com",
+		"example..com",
+		"example .com",
+		" example.com",
+		"example.com\t",
+		"example.com\n",
+		"exam\x00ple.com",
+		"exam\u00a0ple.com",
+	}
+	for _, name := range rejected {
+		t.Run(fmt.Sprintf("%q", name), func(t *testing.T) {
+			_, err := va.normalizeDNSName(name)
+			test.AssertErrorIs(t, err, berrors.Malformed)
+		})
+	}
+}
+
+func TestValidationRejectsMalformedIdentifiers(t *testing.T) {
+	va, _ := setup(nil, "", nil, panicDNS{})
+
+	for _, name := range []string{"example .com", "example..com", "example.com..", "exam\tple.com"} {
+		for _, kind := range []core.AcmeChallenge{core.ChallengeTypeHTTP01, core.ChallengeTypeDNS01, core.ChallengeTypeTLSALPN01} {
+			t.Run(fmt.Sprintf("%q %s", name, kind), func(t *testing.T) {
+				_, err := va.validateChallenge(ctx, dnsi(name), 1, kind, expectedToken, expectedKeyAuthorization)
+				test.AssertEquals(t, detailedError(err).Type, probs.MalformedProblem)
+
+				err = va.checkCAA(ctx, dnsi(name), &caaParams{accountURIID: 1, validationMethod: kind})
+				test.AssertEquals(t, detailedError(err).Type, probs.MalformedProblem)
+			})
+		}
+	}
+
+	// PerformValidation reports the rejection as a malformed problem rather
+	// than an internal error.
+	req := createValidationRequest("example .com", core.ChallengeTypeHTTP01)
+	res, err := va.PerformValidation(ctx, req)
+	test.AssertNotError(t, err, "PerformValidation should not return an error")
+	test.AssertEquals(t, res.Problem.ProblemType, string(probs.MalformedProblem))
+}
+
 func TestPerformValidationInvalid(t *testing.T) {
 	t.Parallel()
 	va, _ := setup(nil, "", nil, nil)
@@ -487,6 +1222,9 @@ func TestPerformValidationInvalid(t *testing.T) {
 			req := createValidationRequest("foo.com", core.ChallengeTypeDNS01)
 			res, _ := tc.validationFunc(context.Background(), va, req)
 			test.Assert(t, res.Problem != nil, "validation succeeded")
+			test.AssertEquals(t, res.Attempt.ProblemType, string(probs.UnauthorizedProblem))
+			test.AssertEquals(t, res.Attempt.Detail, res.Problem.Detail)
+			test.Assert(t, strings.HasPrefix(res.Attempt.Perspectives, va.perspective), "attempt should name the primary perspective")
 			if tc.validationFuncName == "PerformValidation" {
 				test.AssertMetricWithLabelsEquals(t, va.metrics.validationLatency, prometheus.Labels{
 					"operation":      opDCVAndCAA,
@@ -508,6 +1246,54 @@ func TestPerformValidationInvalid(t *testing.T) {
 	}
 }
 
+func TestDNSProblemDetails(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		validationFuncName string
+		validationFunc     validationFuncRunner
+	}{
+		{
+			validationFuncName: "PerformValidation",
+			validationFunc:     runPerformValidation,
+		},
+		{
+			validationFuncName: "DoDCV",
+			validationFunc:     runDoDCV,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.validationFuncName, func(t *testing.T) {
+			t.Parallel()
+			va, mockLog := setup(nil, "", nil, nil)
+
+			req := createValidationRequest("always.timeout", core.ChallengeTypeHTTP01)
+			res, err := tc.validationFunc(context.Background(), va, req)
+			test.AssertNotError(t, err, "failed validation should not be an error")
+			test.Assert(t, res.Problem != nil, "validation succeeded")
+			test.AssertEquals(t, res.Problem.ProblemType, string(probs.DNSProblem))
+			test.AssertEquals(t, res.Problem.Detail, "DNS problem: query timed out looking up A for always.timeout")
+			test.AssertDeepEquals(t, res.Problem.DnsDetails, &corepb.DNSDetails{
+				QueryName: "always.timeout",
+				QueryType: "A",
+			})
+
+			// The resolver is logged, but not exposed in the problem.
+			matchingLogs := mockLog.GetAllMatching(
+				`Validation result JSON=.*"DNSDetails":\{"queryName":"always.timeout","queryType":"A"\},"DNSResolver":"MockClient"`)
+			test.AssertEquals(t, len(matchingLogs), 1)
+		})
+	}
+
+	// DNS errors which didn't come from a DNS query have no details.
+	va, _ := setup(nil, "", nil, caaBrokenDNS{})
+	_, err := va.validateDNS01(ctx, dnsi("good-dns01.com"), expectedKeyAuthorization)
+	prob := detailedError(err)
+	test.AssertEquals(t, prob.Type, probs.DNSProblem)
+	test.Assert(t, prob.DNSDetails == nil, "unexpected DNS details")
+}
+
 func TestInternalErrorLogged(t *testing.T) {
 	t.Parallel()
 
@@ -570,6 +1356,8 @@ func TestPerformValidationValid(t *testing.T) {
 			req := createValidationRequest("good-dns01.com", core.ChallengeTypeDNS01)
 			res, _ := tc.validationFunc(context.Background(), va, req)
 			test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed: %#v", res.Problem))
+			test.Assert(t, res.Attempt.AttemptedAt.IsValid(), "attempt should have a timestamp")
+			test.AssertEquals(t, res.Attempt.ProblemType, "")
 			if tc.validationFuncName == "PerformValidation" {
 				test.AssertMetricWithLabelsEquals(t, va.metrics.validationLatency, prometheus.Labels{
 					"operation":      opDCVAndCAA,
@@ -663,6 +1451,57 @@ func TestPerformValidationWildcard(t *testing.T) {
 	}
 }
 
+func TestPerformValidationAllowedChallengeTypes(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		validationFuncName string
+		validationFunc     validationFuncRunner
+	}{
+		{"PerformValidation", runPerformValidation},
+		{"DoDCV", runDoDCV},
+	} {
+		t.Run(tc.validationFuncName, func(t *testing.T) {
+			t.Parallel()
+
+			va, mockLog := setup(nil, "", nil, nil)
+
+			// HTTP-01 isn't allowed for a wildcard name, so it's refused before
+			// any validation is attempted.
+			req := createValidationRequest("*.good-dns01.com", core.ChallengeTypeHTTP01)
+			req.AllowedChallengeTypes = []string{string(core.ChallengeTypeDNS01)}
+			res, err := tc.validationFunc(context.Background(), va, req)
+			test.AssertNotError(t, err, "validation errored")
+			test.AssertNotNil(t, res.Problem, "validation of disallowed challenge type succeeded")
+			test.AssertEquals(t, res.Problem.ProblemType, string(probs.MalformedProblem))
+			test.AssertContains(t, res.Problem.Detail, `Challenge type "http-01" may not be used to validate this identifier; allowed challenge types are dns-01`)
+			test.AssertEquals(t, len(res.Records), 0)
+			resultLog := mockLog.GetAllMatching(`Validation result`)
+			test.AssertEquals(t, len(resultLog), 1)
+			test.AssertContains(t, resultLog[0], `"AllowedChallengeTypes":["dns-01"]`)
+
+			// An allowed challenge type is validated as usual.
+			mockLog.Clear()
+			req = createValidationRequest("*.good-dns01.com", core.ChallengeTypeDNS01)
+			req.AllowedChallengeTypes = []string{string(core.ChallengeTypeDNS01)}
+			res, err = tc.validationFunc(context.Background(), va, req)
+			test.AssertNotError(t, err, "validation errored")
+			test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed: %#v", res.Problem))
+
+			// Without allowed challenge types, the challenge type isn't
+			// restricted.
+			mockLog.Clear()
+			req = createValidationRequest("good-dns01.com", core.ChallengeTypeDNS01)
+			res, err = tc.validationFunc(context.Background(), va, req)
+			test.AssertNotError(t, err, "validation errored")
+			test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed: %#v", res.Problem))
+			resultLog = mockLog.GetAllMatching(`Validation result`)
+			test.AssertEquals(t, len(resultLog), 1)
+			test.AssertNotContains(t, resultLog[0], "AllowedChallengeTypes")
+		})
+	}
+}
+
 func TestDCVAndCAASequencing(t *testing.T) {
 	va, mockLog := setup(nil, "", nil, nil)
 
@@ -749,6 +1588,47 @@ func TestPerformRemoteOperation(t *testing.T) {
 	}
 }
 
+func TestValidationResultPerspectiveResults(t *testing.T) {
+	t.Parallel()
+
+	// Without remote VAs, setup returns a remote VA.
+	va, _ := setup(nil, "", nil, nil)
+	va.perspective = PrimaryPerspective
+	var remote []core.PerspectiveResult
+	for i := range core.MaxPerspectiveResultsPerOperation + 4 {
+		remote = append(remote, core.PerspectiveResult{
+			Perspective: fmt.Sprintf("remote%02d", i),
+			RIR:         arin,
+			ProblemType: probs.ConnectionProblem,
+			LatencyMS:   int64(i),
+		})
+	}
+	prob := probs.Connection("During secondary validation: connection refused")
+	res, err := va.validationResult(nil, prob, va.clk.Now(), va.localPerspectiveResult(opDCV, nil, 25*time.Millisecond), &mpicSummary{perspectiveResults: remote}, nil)
+	test.AssertNotError(t, err, "validationResult failed")
+
+	// The primary's result comes first, and the remote results are capped.
+	test.AssertEquals(t, len(res.PerspectiveResults), core.MaxPerspectiveResultsPerOperation)
+	test.AssertDeepEquals(t, bgrpc.PBToPerspectiveResult(res.PerspectiveResults[0]), core.PerspectiveResult{
+		Perspective: PrimaryPerspective,
+		RIR:         va.rir,
+		Passed:      true,
+		LatencyMS:   25,
+		Operation:   opDCV,
+	})
+	for i, r := range res.PerspectiveResults[1:] {
+		expected := remote[i]
+		expected.Operation = opDCV
+		test.AssertDeepEquals(t, bgrpc.PBToPerspectiveResult(r), expected)
+	}
+
+	// Remote VAs don't report per-perspective results.
+	remoteVA, _ := setup(nil, "", nil, nil)
+	res, err = remoteVA.validationResult(nil, nil, va.clk.Now(), remoteVA.localPerspectiveResult(opDCV, nil, time.Millisecond), nil, nil)
+	test.AssertNotError(t, err, "validationResult failed")
+	test.AssertEquals(t, len(res.PerspectiveResults), 0)
+}
+
 func TestMultiVA(t *testing.T) {
 	t.Parallel()
 
@@ -781,11 +1661,12 @@ func TestMultiVA(t *testing.T) {
 	}
 
 	testCases := []struct {
-		Name                string
-		Remotes             []remoteConf
-		PrimaryUA           string
-		ExpectedProbType    string
-		ExpectedLogContains string
+		Name                  string
+		Remotes               []remoteConf
+		PrimaryUA             string
+		ExpectedProbType      string
+		ExpectedLogContains   string
+		ExpectedSkippedReason string
 	}{
 		{
 			// With local and all remote VAs working there should be no problem.
@@ -805,8 +1686,9 @@ func TestMultiVA(t *testing.T) {
 				{ua: pass, rir: ripe},
 				{ua: pass, rir: apnic},
 			},
-			PrimaryUA:        fail,
-			ExpectedProbType: string(probs.UnauthorizedProblem),
+			PrimaryUA:             fail,
+			ExpectedProbType:      string(probs.UnauthorizedProblem),
+			ExpectedSkippedReason: mpicSkippedPrimaryFailed,
 		},
 		{
 			// If one out of three remote VAs fails with an internal err it should succeed
@@ -957,6 +1839,30 @@ func TestMultiVA(t *testing.T) {
 					// That result should match expected.
 					test.AssertEquals(t, res.Problem.ProblemType, tc.ExpectedProbType)
 				}
+				test.AssertEquals(t, res.Attempt.ProblemType, tc.ExpectedProbType)
+				if testFunc.name == "DoDCV" && tc.ExpectedProbType == "" {
+					test.Assert(t, strings.HasSuffix(res.Attempt.Perspectives, "remote perspectives corroborated"), "attempt should summarize remote perspectives")
+					// Every perspective's outcome is reported, the primary's first.
+					test.AssertEquals(t, len(res.PerspectiveResults), 1+len(tc.Remotes))
+					test.AssertEquals(t, res.PerspectiveResults[0].Perspective, PrimaryPerspective)
+					test.Assert(t, res.PerspectiveResults[0].Passed, "primary perspective should have passed")
+				}
+				if testFunc.name == "PerformValidation" {
+					test.AssertEquals(t, len(res.PerspectiveResults), 0)
+				}
+				if testFunc.name == "DoDCV" {
+					test.AssertEquals(t, res.MpicSkippedReason, tc.ExpectedSkippedReason)
+					event := parseValidationLogEvent(t, mockLog.GetAllMatching(`Validation result JSON=`))
+					test.AssertNotNil(t, event.Summary, "primary should always log an MPIC summary")
+					test.AssertEquals(t, event.Summary.SkippedReason, tc.ExpectedSkippedReason)
+					if tc.ExpectedSkippedReason != "" {
+						// The remote perspectives were never consulted.
+						test.AssertEquals(t, event.Summary.QuorumResult, "0/0")
+						test.AssertEquals(t, len(event.Summary.Passed)+len(event.Summary.Failed), 0)
+						test.AssertEquals(t, len(res.PerspectiveResults), 1)
+						test.AssertMetricWithLabelsEquals(t, localVA.metrics.mpicSkipped, prometheus.Labels{"reason": tc.ExpectedSkippedReason}, 1)
+					}
+				}
 
 				if tc.ExpectedLogContains != "" {
 					lines := mockLog.GetAllMatching(tc.ExpectedLogContains)
@@ -969,6 +1875,28 @@ func TestMultiVA(t *testing.T) {
 	}
 }
 
+func TestDoDCVTooFewRemotes(t *testing.T) {
+	t.Parallel()
+
+	ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true})
+	defer ms.Close()
+	va, mockLog := setupWithRemotes(ms.Server, pass, []remoteConf{
+		{ua: pass, rir: arin},
+		{ua: pass, rir: ripe},
+	}, nil)
+
+	res, err := va.DoDCV(ctx, createValidationRequest("localhost", core.ChallengeTypeHTTP01))
+	test.AssertNotError(t, err, "performing validation")
+	test.AssertNotNil(t, res.Problem, "validation should have failed")
+	test.AssertEquals(t, res.Problem.ProblemType, string(probs.ServerInternalProblem))
+	test.AssertEquals(t, res.MpicSkippedReason, mpicSkippedNoRemotes)
+
+	event := parseValidationLogEvent(t, mockLog.GetAllMatching(`Validation result JSON=`))
+	test.AssertEquals(t, event.Summary.SkippedReason, mpicSkippedNoRemotes)
+	test.AssertEquals(t, event.Summary.QuorumResult, "0/0")
+	test.AssertMetricWithLabelsEquals(t, va.metrics.mpicSkipped, prometheus.Labels{"reason": mpicSkippedNoRemotes}, 1)
+}
+
 func TestMultiVAEarlyReturn(t *testing.T) {
 	t.Parallel()
 
@@ -1139,6 +2067,268 @@ func TestMultiVALogging(t *testing.T) {
 	}
 }
 
+func TestValidationAddressFamily(t *testing.T) {
+	t.Parallel()
+
+	// The server only listens on IPv4, so the dual-stack host's IPv6 address
+	// is tried first, and the IPv4 one decides each perspective's validation.
+	ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true, fail: false})
+	defer ms.Close()
+	va, mockLog := setupWithRemotes(ms.Server, pass, []remoteConf{
+		{ua: pass, rir: arin},
+		{ua: pass, rir: ripe},
+		{ua: fail, rir: apnic},
+	}, nil)
+
+	res, err := va.DoDCV(ctx, createValidationRequest("ipv4.and.ipv6.localhost", core.ChallengeTypeHTTP01))
+	test.AssertNotError(t, err, "DoDCV failed")
+	test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed: %#v", res.Problem))
+	test.AssertEquals(t, len(res.Records), 2)
+	test.AssertEquals(t, res.Records[0].AddressFamily, "ipv6")
+	test.AssertEquals(t, res.Records[1].AddressFamily, "ipv4")
+	test.AssertMetricWithLabelsEquals(t, va.metrics.validationAddressFamilies, prometheus.Labels{
+		"perspective": PrimaryPerspective, "challenge_type": string(core.ChallengeTypeHTTP01), "address_family": "ipv4"}, 1)
+	test.AssertMetricWithLabelsEquals(t, va.metrics.validationAddressFamilies, prometheus.Labels{"address_family": "ipv6"}, 0)
+
+	// Since one perspective disagreed, each perspective's family is logged.
+	results := mockLog.GetAllMatching(`Validation result JSON=`)
+	test.AssertEquals(t, len(results), 1)
+	test.AssertContains(t, results[0],
+		`"addressFamilies":{"Primary":"ipv4","dc-0-ARIN":"ipv4","dc-1-RIPE":"ipv4","dc-2-APNIC":"ipv4"}`)
+
+	// Validations which make no connection have no address family.
+	test.AssertEquals(t, decisiveAddressFamily(nil), "none")
+	test.AssertEquals(t, remoteAddressFamily((*vapb.ValidationResult)(nil)), "")
+	test.AssertEquals(t, remoteAddressFamily(&vapb.IsCAAValidResponse{}), "")
+	test.AssertEquals(t, remoteAddressFamily(&vapb.ValidationResult{}), "none")
+}
+
+func TestMultiVAMinDistinctASNs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		remotes            []remoteConf
+		minDistinctASNs    int
+		expectedProbDetail string
+		expectedASNs       []uint32
+	}{
+		{
+			name: "3 distinct ASNs, 3 required",
+			remotes: []remoteConf{
+				{ua: pass, rir: arin, asn: 100},
+				{ua: pass, rir: ripe, asn: 200},
+				{ua: pass, rir: apnic, asn: 300},
+			},
+			minDistinctASNs: 3,
+			expectedASNs:    []uint32{100, 200, 300},
+		},
+		{
+			name: "2 distinct ASNs among 3 passing, 2 required",
+			remotes: []remoteConf{
+				{ua: pass, rir: arin, asn: 100},
+				{ua: pass, rir: ripe, asn: 100},
+				{ua: pass, rir: apnic, asn: 200},
+			},
+			minDistinctASNs: 2,
+			expectedASNs:    []uint32{100, 200},
+		},
+		{
+			name: "1 distinct ASN among 2 passing, 2 required",
+			remotes: []remoteConf{
+				{ua: pass, rir: arin, asn: 100},
+				{ua: pass, rir: ripe, asn: 100},
+				{ua: fail, rir: apnic, asn: 200},
+			},
+			minDistinctASNs:    2,
+			expectedProbDetail: "During secondary validation: corroborating perspectives span 1 distinct ASNs [100], but at least 2 are required",
+			expectedASNs:       []uint32{100},
+		},
+		{
+			name: "1 distinct ASN among 2 passing, none required",
+			remotes: []remoteConf{
+				{ua: pass, rir: arin, asn: 100},
+				{ua: pass, rir: ripe, asn: 100},
+				{ua: fail, rir: apnic, asn: 200},
+			},
+			expectedASNs: []uint32{100},
+		},
+		{
+			name: "no ASNs configured, none required",
+			remotes: []remoteConf{
+				{ua: pass, rir: arin},
+				{ua: pass, rir: ripe},
+				{ua: pass, rir: apnic},
+			},
+		},
+		{
+			name: "no ASNs configured, 2 required",
+			remotes: []remoteConf{
+				{ua: pass, rir: arin},
+				{ua: pass, rir: ripe},
+				{ua: pass, rir: apnic},
+			},
+			minDistinctASNs:    2,
+			expectedProbDetail: "During secondary validation: corroborating perspectives span 0 distinct ASNs [], but at least 2 are required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true, fail: false})
+			defer ms.Close()
+
+			va, mockLog := setupWithRemotes(ms.Server, pass, tc.remotes, nil)
+			va.minDistinctASNs = tc.minDistinctASNs
+
+			req := createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01)
+			res, err := runDoDCV(ctx, va, req)
+			test.AssertNotError(t, err, "performing validation")
+			if tc.expectedProbDetail != "" {
+				test.AssertNotNil(t, res.Problem, "expected a problem, got nil")
+				test.AssertEquals(t, res.Problem.ProblemType, string(probs.ServerInternalProblem))
+				test.AssertEquals(t, res.Problem.Detail, tc.expectedProbDetail)
+			} else {
+				test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed with: %#v", res.Problem))
+			}
+
+			gotAuditLog := parseValidationLogEvent(t, mockLog.GetAllMatching(`Validation result JSON=.*`))
+			test.AssertDeepEquals(t, gotAuditLog.Summary.PassedASNs, tc.expectedASNs)
+		})
+	}
+}
+
+// spanRecorder is an in-memory sdktrace.SpanExporter.
+type spanRecorder struct {
+	sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (r *spanRecorder) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	r.Lock()
+	defer r.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error {
+	return nil
+}
+
+// byName returns the recorded spans grouped by name.
+func (r *spanRecorder) byName() map[string][]sdktrace.ReadOnlySpan {
+	r.Lock()
+	defer r.Unlock()
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range r.spans {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	return spans
+}
+
+// spanAttr returns the value of the named attribute of span, or "".
+func spanAttr(span sdktrace.ReadOnlySpan, key string) string {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestMultiVATracing(t *testing.T) {
+	t.Parallel()
+
+	// The incoming trace context, as propagated through gRPC metadata.
+	incoming := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	traceCtx := trace.ContextWithRemoteSpanContext(ctx, incoming)
+
+	t.Run("passing", func(t *testing.T) {
+		t.Parallel()
+		ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true, fail: false})
+		defer ms.Close()
+
+		va, _ := setupWithRemotes(ms.Server, pass, []remoteConf{
+			{ua: pass, rir: arin},
+			{ua: pass, rir: ripe},
+			{ua: pass, rir: apnic},
+		}, nil)
+		recorder := &spanRecorder{}
+		va.tracer = sdktrace.NewTracerProvider(sdktrace.WithSyncer(recorder)).Tracer("test")
+
+		req := createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01)
+		res, err := va.DoDCV(traceCtx, req)
+		test.AssertNotError(t, err, "performing validation")
+		test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed with: %#v", res.Problem))
+
+		spans := recorder.byName()
+		test.AssertEquals(t, len(spans["VA.DoDCV"]), 1)
+		root := spans["VA.DoDCV"][0]
+		test.AssertEquals(t, root.SpanContext().TraceID(), incoming.TraceID())
+		test.AssertEquals(t, root.Parent().SpanID(), incoming.SpanID())
+		test.AssertEquals(t, root.Status().Code, codes.Unset)
+
+		test.AssertEquals(t, len(spans["http fetch"]), 1)
+		fetch := spans["http fetch"][0]
+		test.AssertEquals(t, fetch.Parent().SpanID(), root.SpanContext().SpanID())
+
+		test.AssertEquals(t, len(spans["dns resolution"]), 1)
+		test.AssertEquals(t, spans["dns resolution"][0].Parent().SpanID(), fetch.SpanContext().SpanID())
+
+		test.AssertEquals(t, len(spans["remote perspective"]), 3)
+		rirs := make(map[string]bool)
+		for _, span := range spans["remote perspective"] {
+			test.AssertEquals(t, span.Parent().SpanID(), root.SpanContext().SpanID())
+			test.Assert(t, spanAttr(span, "perspective") != "", "perspective should be set")
+			rirs[spanAttr(span, "rir")] = true
+		}
+		test.AssertDeepEquals(t, rirs, map[string]bool{arin: true, ripe: true, apnic: true})
+	})
+
+	t.Run("failing", func(t *testing.T) {
+		t.Parallel()
+		ms := httpMultiSrv(t, expectedToken, map[string]bool{pass: true, fail: false})
+		defer ms.Close()
+
+		va, _ := setupWithRemotes(ms.Server, pass, []remoteConf{
+			{ua: fail, rir: arin},
+			{ua: fail, rir: ripe},
+			{ua: pass, rir: apnic},
+		}, nil)
+		recorder := &spanRecorder{}
+		va.tracer = sdktrace.NewTracerProvider(sdktrace.WithSyncer(recorder)).Tracer("test")
+
+		req := createValidationRequest("letsencrypt.org", core.ChallengeTypeHTTP01)
+		res, err := va.DoDCV(traceCtx, req)
+		test.AssertNotError(t, err, "performing validation")
+		test.AssertNotNil(t, res.Problem, "validation should have failed")
+
+		spans := recorder.byName()
+		test.AssertEquals(t, len(spans["VA.DoDCV"]), 1)
+		root := spans["VA.DoDCV"][0]
+		test.AssertEquals(t, root.Status().Code, codes.Error)
+		test.AssertEquals(t, root.Status().Description, res.Problem.Detail)
+		test.AssertEquals(t, spanAttr(root, "problem_type"), string(probs.UnauthorizedProblem))
+
+		test.AssertEquals(t, len(spans["remote perspective"]), 3)
+		var failed int
+		for _, span := range spans["remote perspective"] {
+			if spanAttr(span, "problem_type") == string(probs.UnauthorizedProblem) {
+				test.AssertEquals(t, span.Status().Code, codes.Error)
+				failed++
+			}
+		}
+		test.AssertEquals(t, failed, 2)
+	})
+}
+
 func TestDetailedError(t *testing.T) {
 	cases := []struct {
 		err      error
@@ -1182,6 +2372,46 @@ func TestDetailedError(t *testing.T) {
 			ip:       nil,
 			expected: "Connection reset by peer",
 		},
+		{
+			err: &net.OpError{
+				Op:  "dial",
+				Net: "tcp",
+				Err: &os.SyscallError{
+					Syscall: "socket",
+					Err:     syscall.EMFILE,
+				},
+			},
+			expected: "The VA is temporarily out of local resources; please retry",
+		},
+		{
+			err: ipError{
+				ip: net.ParseIP("192.168.1.1"),
+				err: &net.OpError{
+					Op:  "dial",
+					Net: "tcp",
+					Err: &os.SyscallError{
+						Syscall: "socket",
+						Err:     syscall.ENFILE,
+					},
+				},
+			},
+			expected: "The VA is temporarily out of local resources; please retry",
+		},
+		{
+			err: &net.OpError{
+				Op:  "dial",
+				Net: "tcp",
+				Err: &os.SyscallError{
+					Syscall: "connect",
+					Err:     syscall.EADDRNOTAVAIL,
+				},
+			},
+			expected: "The VA is temporarily out of local resources; please retry",
+		},
+		{
+			err:      errors.New("open /etc/resolv.conf: too many open files"),
+			expected: "The VA is temporarily out of local resources; please retry",
+		},
 	}
 	for _, tc := range cases {
 		actual := detailedError(tc.err).Detail
@@ -1191,6 +2421,44 @@ func TestDetailedError(t *testing.T) {
 	}
 }
 
+func TestValidationLocalResourceExhaustion(t *testing.T) {
+	t.Parallel()
+
+	hs := httpSrv(t, expectedToken)
+	defer hs.Close()
+
+	testCases := []struct {
+		name     string
+		err      error
+		resource string
+	}{
+		{name: "EMFILE", err: syscall.EMFILE, resource: resourceFileDescriptors},
+		{name: "ENFILE", err: syscall.ENFILE, resource: resourceFileDescriptors},
+		{name: "EADDRNOTAVAIL", err: syscall.EADDRNOTAVAIL, resource: resourceLocalPorts},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			va, mockLog := setup(hs, "", nil, nil)
+			va.dialControl = func(network, address string, c syscall.RawConn) error {
+				return tc.err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			res, err := va.DoDCV(ctx, createValidationRequest("localhost", core.ChallengeTypeHTTP01))
+			test.AssertNotError(t, err, "DoDCV failed")
+			test.AssertNotNil(t, res.Problem, "expected validation to fail")
+			test.AssertEquals(t, res.Problem.ProblemType, string(probs.ServerInternalProblem))
+			test.AssertEquals(t, res.Problem.Detail, "The VA is temporarily out of local resources; please retry")
+
+			test.AssertEquals(t, len(mockLog.GetAllMatching(fmt.Sprintf(`VA local resource exhaustion: operation=%s resource=%s identifier="localhost"`, opDCV, tc.resource))), 1)
+			test.AssertMetricWithLabelsEquals(t, va.metrics.localResourceExhaustion, prometheus.Labels{"operation": opDCV, "resource": tc.resource}, 1)
+		})
+	}
+}
+
 func TestLogRemoteDifferentials(t *testing.T) {
 	t.Parallel()
 
@@ -1241,7 +2509,7 @@ func TestLogRemoteDifferentials(t *testing.T) {
 				Domain:           "example.com",
 				AccountURIID:     1999,
 				ValidationMethod: "blorpus-01",
-			}, tc.passed, tc.failed)
+			}, tc.passed, tc.failed, nil)
 
 			lines := mockLog.GetAllMatching("remoteVADifferentials JSON=.*")
 			if tc.expectedLog != "" {
@@ -1253,3 +2521,42 @@ func TestLogRemoteDifferentials(t *testing.T) {
 		})
 	}
 }
+
+func TestPerformValidationPolicyHints(t *testing.T) {
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, expectedKeyAuthorization+"\n")
+	}))
+	defer hs.Close()
+	va, mockLog := setup(hs, "", nil, nil)
+
+	// By default, trailing whitespace is trimmed from the response body.
+	req := createValidationRequest("localhost", core.ChallengeTypeHTTP01)
+	res, err := va.PerformValidation(ctx, req)
+	test.AssertNotError(t, err, "PerformValidation failed")
+	test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed: %#v", res.Problem))
+
+	// The strict-body-match hint requires an exact match for this request
+	// alone. Unknown keys and invalid values are ignored.
+	req = createValidationRequest("localhost", core.ChallengeTypeHTTP01)
+	req.PolicyHints = map[string]string{
+		hintStrictBodyMatch:      "true",
+		hintCAAValidationMethods: "sometimes",
+		"not-a-hint":             "true",
+	}
+	res, err = va.PerformValidation(ctx, req)
+	test.AssertNotError(t, err, "PerformValidation failed")
+	test.AssertNotNil(t, res.Problem, "validation should have failed")
+	test.AssertEquals(t, res.Problem.ProblemType, string(probs.UnauthorizedProblem))
+	test.AssertMetricWithLabelsEquals(t, va.metrics.policyHintsIgnored, prometheus.Labels{"reason": "unknown_key"}, 1)
+	test.AssertMetricWithLabelsEquals(t, va.metrics.policyHintsIgnored, prometheus.Labels{"reason": "invalid_value"}, 1)
+
+	// Only the applied hints are audit logged.
+	lines := mockLog.GetAllMatching(`Validation result JSON=.*"PolicyHints":\{"strict-body-match":"true"\}`)
+	test.AssertEquals(t, len(lines), 1)
+
+	// A later request without the hint uses the default again.
+	req = createValidationRequest("localhost", core.ChallengeTypeHTTP01)
+	res, err = va.PerformValidation(ctx, req)
+	test.AssertNotError(t, err, "PerformValidation failed")
+	test.Assert(t, res.Problem == nil, fmt.Sprintf("validation failed: %#v", res.Problem))
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"maps"
 	"math/rand/v2"
+	"net"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/letsencrypt/boulder/core"
@@ -16,6 +18,8 @@ import (
 	"github.com/letsencrypt/boulder/identifier"
 	"github.com/letsencrypt/boulder/probs"
 	vapb "github.com/letsencrypt/boulder/va/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -41,17 +45,43 @@ type mpicSummary struct {
 	// perspectives reside in.
 	PassedRIRs []string `json:"passedRIRs"`
 
+	// PassedASNs are the Autonomous System Numbers that the passing
+	// perspectives reside in. It is omitted if none of the passing
+	// perspectives have a configured ASN.
+	PassedASNs []uint32 `json:"passedASNs,omitempty"`
+
 	// QuorumResult is the Multi-Perspective Issuance Corroboration quorum
 	// result, per BRs Section 5.4.1, Requirement 2.7 (i.e., "3/4" which should
 	// be interpreted as "Three (3) out of four (4) attempted Network
 	// Perspectives corroborated the determinations made by the Primary Network
 	// Perspective".
 	QuorumResult string `json:"quorumResult"`
+
+	// FailedPeers maps the failed perspectives to the identities their
+	// remote VAs authenticated as, where known, so that results attributed
+	// to the wrong perspective by a misconfigured address can be spotted.
+	FailedPeers map[string]string `json:"failedPeers,omitempty"`
+
+	// AddressFamilies maps each perspective, the primary's included, to the
+	// address family, "ipv4", "ipv6" or "none", of the connection which
+	// decided its DCV result. It's only set when the perspectives disagreed,
+	// since differences in routing between families are a common cause.
+	AddressFamilies map[string]string `json:"addressFamilies,omitempty"`
+
+	// SkippedReason is why the primary didn't consult the remote
+	// perspectives, one of mpicSkippedReasons, or empty if it did. When it's
+	// set, the other fields are empty and QuorumResult is "0/0".
+	SkippedReason string `json:"skippedReason,omitempty"`
+
+	// perspectiveResults is the outcome at each remote perspective, sorted by
+	// perspective. It isn't logged, since Passed and Failed already are, but
+	// is returned to the RA for storage with the authorization.
+	perspectiveResults []core.PerspectiveResult
 }
 
 // summarizeMPIC prepares an *mpicSummary for logging, ensuring there are no nil
-// slices and output is deterministic.
-func summarizeMPIC(passed, failed []string, passedRIRSet map[string]struct{}) *mpicSummary {
+// slices (other than the optional PassedASNs) and output is deterministic.
+func summarizeMPIC(passed, failed []string, passedRIRSet map[string]struct{}, passedASNSet map[uint32]struct{}) *mpicSummary {
 	if passed == nil {
 		passed = []string{}
 	}
@@ -69,39 +99,300 @@ func summarizeMPIC(passed, failed []string, passedRIRSet map[string]struct{}) *m
 	}
 	slices.Sort(passedRIRs)
 
+	var passedASNs []uint32
+	if len(passedASNSet) > 0 {
+		passedASNs = slices.Sorted(maps.Keys(passedASNSet))
+	}
+
 	return &mpicSummary{
 		Passed:       passed,
 		Failed:       failed,
 		PassedRIRs:   passedRIRs,
+		PassedASNs:   passedASNs,
 		QuorumResult: fmt.Sprintf("%d/%d", len(passed), len(passed)+len(failed)),
 	}
 }
 
-// doRemoteOperation concurrently calls the provided operation with `req` and a
-// RemoteVA once for each configured RemoteVA. It cancels remaining operations
-// and returns early if either the required number of successful results is
-// obtained or the number of failures exceeds va.maxRemoteFailures.
-//
-// Internal logic errors are logged. If the number of operation failures exceeds
-// va.maxRemoteFailures, the first encountered problem is returned as a
-// *probs.ProblemDetails.
-func (va *ValidationAuthorityImpl) doRemoteOperation(ctx context.Context, op remoteOperation, req proto.Message) (*mpicSummary, *probs.ProblemDetails) {
-	remoteVACount := len(va.remoteVAs)
+// Reasons for which the primary VA skips remote corroboration, recorded as
+// mpicSummary.SkippedReason.
+const (
+	// mpicSkippedPrimaryFailed means the primary's own validation failed, so
+	// there was nothing for the remote perspectives to corroborate.
+	mpicSkippedPrimaryFailed = "primary_failed"
+
+	// mpicSkippedNoRemotes means too few remote perspectives are configured,
+	// or available for selection, to corroborate the primary.
+	mpicSkippedNoRemotes = "no_remotes_configured"
+
+	// mpicSkippedInternalIdentifier means the identifier matched an internal
+	// identifier rule, which exempts it from corroboration.
+	mpicSkippedInternalIdentifier = "internal_identifier"
+
+	// mpicSkippedDryRun means the operation was a dry run, whose result isn't
+	// relied upon for issuance. The VA doesn't yet perform any.
+	mpicSkippedDryRun = "dry_run"
+
+	// mpicSkippedRecheckLocalOnly means the operation was a CAA recheck, and
+	// the primary is configured to recheck CAA from its own perspective alone.
+	mpicSkippedRecheckLocalOnly = "recheck_local_only"
+)
+
+// mpicSkippedReasons are all the reasons for which remote corroboration may
+// be skipped.
+var mpicSkippedReasons = []string{
+	mpicSkippedPrimaryFailed,
+	mpicSkippedNoRemotes,
+	mpicSkippedInternalIdentifier,
+	mpicSkippedDryRun,
+	mpicSkippedRecheckLocalOnly,
+}
+
+// recheckLocalPerspective labels the total latency of CAA rechecks which the
+// primary VA performs alone because of recheckCAALocalOnly, in place of
+// allPerspectives.
+const recheckLocalPerspective = "recheck_local"
+
+// skipRecheckCorroboration returns true if req is a CAA recheck, and the
+// primary VA is configured to skip remote corroboration of rechecks. Each skip
+// is audit logged.
+func (va *ValidationAuthorityImpl) skipRecheckCorroboration(req *vapb.IsCAAValidRequest) bool {
+	if !va.recheckCAALocalOnly || !req.IsRecheck {
+		return false
+	}
+	va.log.AuditInfof("MPIC skipped: recheck: remote corroboration skipped by policy: operation=%s identifier=%q", opCAA, req.Domain)
+	return true
+}
+
+// skippedMPIC counts an operation whose remote corroboration the primary
+// skipped for reason, and returns its *mpicSummary for logging.
+func (va *ValidationAuthorityImpl) skippedMPIC(reason string) *mpicSummary {
+	va.metrics.mpicSkipped.WithLabelValues(reason).Inc()
+	summary := summarizeMPIC(nil, nil, nil, nil)
+	summary.SkippedReason = reason
+	return summary
+}
+
+// selectRemoteVAs returns the remote VAs to consult for an operation, and the
+// number of them which may fail without failing the operation. These are every
+// configured RemoteVA, or those chosen by va.selector if perspective selection
+// is enabled. If too few remote VAs are available, a problem is returned.
+func (va *ValidationAuthorityImpl) selectRemoteVAs() ([]RemoteVA, int, *probs.ProblemDetails) {
+	remoteVAs := va.remoteVAs
+	maxRemoteFailures := va.maxRemoteFailures
+	if va.selector != nil {
+		remoteVAs = va.selector.choose(va.remoteVAs)
+		maxRemoteFailures = va.selector.headroom
+		for _, rva := range remoteVAs {
+			va.metrics.remoteVASelections.WithLabelValues(rva.Perspective).Inc()
+		}
+	}
 	//  - Mar 15, 2026: MUST implement using at least 3 perspectives
 	//  - Jun 15, 2026: MUST implement using at least 4 perspectives
 	//  - Dec 15, 2026: MUST implement using at least 5 perspectives
 	// See "Phased Implementation Timeline" in
 	// https://github.com/cabforum/servercert/blob/main/docs/BR.md#3229-multi-perspective-issuance-corroboration
-	if remoteVACount < 3 {
-		return nil, probs.ServerInternal("Insufficient remote perspectives: need at least 3")
+	if len(remoteVAs) < 3 {
+		return nil, 0, probs.ServerInternal("Insufficient remote perspectives: need at least 3")
+	}
+	return remoteVAs, maxRemoteFailures, nil
+}
+
+// checkRemotePerspective returns an error if a reply which claims to be from
+// the given perspective and RIR didn't come from rva.
+func checkRemotePerspective(rva RemoteVA, perspective, rir string) error {
+	if perspective != rva.Perspective || rir != rva.RIR {
+		return fmt.Errorf(
+			"Expected perspective %q (%q) but got reply from %q (%q) - misconfiguration likely", rva.Perspective, rva.RIR, perspective, rir,
+		)
+	}
+	return nil
+}
+
+// remoteAddressFamily returns the address family of the connection which
+// decided a remote perspective's DCV result, or "" if res isn't one. It's
+// derived from the address used so that results from remote VAs which don't
+// yet set AddressFamily are covered.
+func remoteAddressFamily(res remoteResult) string {
+	dcv, ok := res.(*vapb.ValidationResult)
+	if !ok || dcv == nil {
+		return ""
+	}
+	records := dcv.GetRecords()
+	if len(records) == 0 {
+		return "none"
+	}
+	return ipFamily(net.ParseIP(string(records[len(records)-1].AddressUsed)))
+}
+
+// remoteProblem converts the outcome of an operation on the remote VA at addr
+// to a problem, or nil if the operation succeeded. Failures to communicate
+// with the remote VA and malformed problems are logged.
+func (va *ValidationAuthorityImpl) remoteProblem(addr string, problem *corepb.ProblemDetails, err error) *probs.ProblemDetails {
+	if err != nil {
+		// Failed to communicate with the remote VA.
+		if core.IsCanceled(err) {
+			return probs.ServerInternal("Secondary validation RPC canceled")
+		}
+		if isShed(err) {
+			// The remote VA didn't attempt the operation, so it neither
+			// corroborates nor contradicts the primary.
+			va.log.Warningf("Remote VA (%s) is at capacity: %s", addr, err)
+			return probs.ServerInternal("Secondary validation RPC refused: remote VA at capacity")
+		}
+		va.log.Errf("Operation on remote VA (%s) failed: %s", addr, err)
+		return probs.ServerInternal("Secondary validation RPC failed")
+	}
+	if problem != nil {
+		// The remote VA returned a problem.
+		prob, err := bgrpc.PBToProblemDetails(problem)
+		if err != nil {
+			va.log.Errf("Operation on Remote VA (%s) returned malformed problem: %s", addr, err)
+			return probs.ServerInternal("Secondary validation RPC returned malformed result")
+		}
+		return prob
+	}
+	return nil
+}
+
+// corroboration tallies the results of the remote perspectives consulted for
+// a single operation, and decides whether they corroborate the primary's.
+type corroboration struct {
+	remoteVACount     int
+	maxRemoteFailures int
+	minDistinctASNs   int
+
+	passed      []string
+	failed      []string
+	failedPeers map[string]string
+	families    map[string]string
+	passedRIRs  map[string]struct{}
+	passedASNs  map[uint32]struct{}
+	firstProb   *probs.ProblemDetails
+	results     []core.PerspectiveResult
+}
+
+func newCorroboration(remoteVACount, maxRemoteFailures, minDistinctASNs int) *corroboration {
+	return &corroboration{
+		remoteVACount:     remoteVACount,
+		maxRemoteFailures: maxRemoteFailures,
+		minDistinctASNs:   minDistinctASNs,
+		passedRIRs:        map[string]struct{}{},
+		passedASNs:        map[uint32]struct{}{},
+		families:          map[string]string{},
+	}
+}
+
+// add records the result at rva, which passed if prob is nil. family is the
+// address family of the connection which decided it, or "" if unknown.
+func (c *corroboration) add(rva RemoteVA, prob *probs.ProblemDetails, latency time.Duration, family string) {
+	if family != "" {
+		c.families[rva.Perspective] = family
+	}
+	if prob != nil {
+		c.failed = append(c.failed, rva.Perspective)
+		identity := rva.peer.identity()
+		if identity != "" {
+			if c.failedPeers == nil {
+				c.failedPeers = make(map[string]string)
+			}
+			c.failedPeers[rva.Perspective] = identity
+		}
+		if c.firstProb == nil {
+			// A problem was encountered for the first time.
+			c.firstProb = prob
+		}
+	} else {
+		c.passed = append(c.passed, rva.Perspective)
+		c.passedRIRs[rva.RIR] = struct{}{}
+		if rva.ASN != 0 {
+			c.passedASNs[rva.ASN] = struct{}{}
+		}
 	}
 
+	result := core.PerspectiveResult{
+		Perspective: rva.Perspective,
+		RIR:         rva.RIR,
+		Passed:      prob == nil,
+		LatencyMS:   latency.Milliseconds(),
+	}
+	if prob != nil {
+		result.ProblemType = prob.Type
+	}
+	c.results = append(c.results, result)
+}
+
+// quorum returns true if enough perspectives, in enough RIRs, have passed.
+func (c *corroboration) quorum() bool {
+	return len(c.passed) >= c.remoteVACount-c.maxRemoteFailures && len(c.passedRIRs) >= requiredRIRs
+}
+
+// settled returns true once the remaining perspectives can't change the
+// outcome, because either enough have passed or too many have failed.
+func (c *corroboration) settled() bool {
+	return (c.quorum() && len(c.passedASNs) >= c.minDistinctASNs) || len(c.failed) > c.maxRemoteFailures
+}
+
+// complete returns true once every perspective has returned a result.
+func (c *corroboration) complete() bool {
+	return len(c.passed)+len(c.failed) >= c.remoteVACount
+}
+
+// outcome returns a summary of the results, and a problem if they don't
+// corroborate the primary's.
+func (c *corroboration) outcome() (*mpicSummary, *probs.ProblemDetails) {
+	summary := summarizeMPIC(c.passed, c.failed, c.passedRIRs, c.passedASNs)
+	slices.SortFunc(c.results, func(a, b core.PerspectiveResult) int {
+		return strings.Compare(a.Perspective, b.Perspective)
+	})
+	summary.perspectiveResults = c.results
+	summary.FailedPeers = c.failedPeers
+	if len(c.failed) > 0 {
+		summary.AddressFamilies = c.families
+	}
+	if c.quorum() {
+		if len(c.passedASNs) < c.minDistinctASNs {
+			// Enough perspectives corroborated, but too many of them share a
+			// network to be resistant to a single BGP hijack.
+			return summary, probs.ServerInternal(fmt.Sprintf(
+				"During secondary validation: corroborating perspectives span %d distinct ASNs %v, but at least %d are required",
+				len(c.passedASNs), summary.PassedASNs, c.minDistinctASNs))
+		}
+		return summary, nil
+	}
+	if c.firstProb == nil {
+		// This should never happen. If we didn't meet the thresholds above we
+		// should have seen at least one error.
+		return summary, probs.ServerInternal(
+			"During secondary validation: validation failed but the problem is unavailable")
+	}
+	c.firstProb.Detail = fmt.Sprintf("During secondary validation: %s", c.firstProb.Detail)
+	return summary, c.firstProb
+}
+
+// doRemoteOperation concurrently calls the provided operation with `req` and a
+// RemoteVA once for each configured RemoteVA, or once for each RemoteVA chosen
+// by va.selector if perspective selection is enabled. It cancels remaining
+// operations and returns early if either the required number of successful
+// results is obtained or the number of failures exceeds va.maxRemoteFailures
+// (or the selection's headroom).
+//
+// Internal logic errors are logged. If the number of operation failures exceeds
+// the allowed maximum, the first encountered problem is returned as a
+// *probs.ProblemDetails. If enough operations succeed but they span fewer than
+// va.minDistinctASNs distinct ASNs, a problem describing the shortfall is
+// returned.
+func (va *ValidationAuthorityImpl) doRemoteOperation(ctx context.Context, op remoteOperation, req proto.Message) (*mpicSummary, *probs.ProblemDetails) {
+	remoteVAs, maxRemoteFailures, prob := va.selectRemoteVAs()
+	if prob != nil {
+		return va.skippedMPIC(mpicSkippedNoRemotes), prob
+	}
+	remoteVACount := len(remoteVAs)
+
 	type response struct {
-		addr        string
-		perspective string
-		rir         string
-		result      remoteResult
-		err         error
+		rva     RemoteVA
+		result  remoteResult
+		err     error
+		latency time.Duration
 	}
 
 	subCtx, cancel := context.WithCancel(ctx)
@@ -110,101 +401,92 @@ func (va *ValidationAuthorityImpl) doRemoteOperation(ctx context.Context, op rem
 	responses := make(chan *response, remoteVACount)
 	for _, i := range rand.Perm(remoteVACount) {
 		go func(rva RemoteVA) {
-			res, err := op(subCtx, rva, req)
-			if err != nil {
-				responses <- &response{rva.Address, rva.Perspective, rva.RIR, res, err}
-				return
+			opCtx, span := va.tracer.Start(subCtx, "remote perspective", trace.WithAttributes(
+				attribute.String("perspective", rva.Perspective),
+				attribute.String("rir", rva.RIR),
+			))
+			start := va.clk.Now()
+			res, err := op(opCtx, rva, req)
+			if err == nil {
+				err = checkRemotePerspective(rva, res.GetPerspective(), res.GetRir())
 			}
-			if res.GetPerspective() != rva.Perspective || res.GetRir() != rva.RIR {
-				err = fmt.Errorf(
-					"Expected perspective %q (%q) but got reply from %q (%q) - misconfiguration likely", rva.Perspective, rva.RIR, res.GetPerspective(), res.GetRir(),
-				)
-				responses <- &response{rva.Address, rva.Perspective, rva.RIR, res, err}
-				return
-			}
-			responses <- &response{rva.Address, rva.Perspective, rva.RIR, res, err}
-		}(va.remoteVAs[i])
-	}
-
-	required := remoteVACount - va.maxRemoteFailures
-	var passed []string
-	var failed []string
-	var passedRIRs = map[string]struct{}{}
-	var firstProb *probs.ProblemDetails
-
-	for resp := range responses {
-		var currProb *probs.ProblemDetails
-
-		if resp.err != nil {
-			// Failed to communicate with the remote VA.
-			failed = append(failed, resp.perspective)
-
-			if core.IsCanceled(resp.err) {
-				currProb = probs.ServerInternal("Secondary validation RPC canceled")
-			} else {
-				va.log.Errf("Operation on remote VA (%s) failed: %s", resp.addr, resp.err)
-				currProb = probs.ServerInternal("Secondary validation RPC failed")
+			if va.selector != nil && !core.IsCanceled(err) && !isShed(err) {
+				// Operations we canceled, or which the perspective shed
+				// because it was at capacity, say nothing about the health of
+				// the perspective, but anything else does. A problem returned
+				// by the perspective is a healthy response.
+				va.selector.record(rva.Perspective, err == nil, va.clk.Since(start))
 			}
-		} else if resp.result.GetProblem() != nil {
-			// The remote VA returned a problem.
-			failed = append(failed, resp.perspective)
-
-			var err error
-			currProb, err = bgrpc.PBToProblemDetails(resp.result.GetProblem())
 			if err != nil {
-				va.log.Errf("Operation on Remote VA (%s) returned malformed problem: %s", resp.addr, err)
-				currProb = probs.ServerInternal("Secondary validation RPC returned malformed result")
+				spanError(span, err)
+			} else if res.GetProblem() != nil {
+				spanProblem(span, res.GetProblem().ProblemType, res.GetProblem().Detail)
 			}
-		} else {
-			// The remote VA returned a successful result.
-			passed = append(passed, resp.perspective)
-			passedRIRs[resp.rir] = struct{}{}
-		}
+			// End the span before responding so that it's complete by the time
+			// doRemoteOperation returns.
+			span.End()
+			responses <- &response{rva, res, err, va.clk.Since(start)}
+		}(remoteVAs[i])
+	}
 
-		if firstProb == nil && currProb != nil {
-			// A problem was encountered for the first time.
-			firstProb = currProb
+	tally := newCorroboration(remoteVACount, maxRemoteFailures, va.minDistinctASNs)
+	for resp := range responses {
+		var problem *corepb.ProblemDetails
+		if resp.err == nil {
+			problem = resp.result.GetProblem()
 		}
+		tally.add(resp.rva, va.remoteProblem(resp.rva.describe(), problem, resp.err), resp.latency, remoteAddressFamily(resp.result))
 
 		// To respond faster, if we get enough successes or too many failures, we cancel remaining RPCs.
 		// Finish the loop to collect remaining responses into `failed` so we can rely on having a response
 		// for every request we made.
-		if len(passed) >= required && len(passedRIRs) >= requiredRIRs {
-			cancel()
-		}
-		if len(failed) > va.maxRemoteFailures {
+		if tally.settled() {
 			cancel()
 		}
 
 		// Once all the VAs have returned a result, break the loop.
-		if len(passed)+len(failed) >= remoteVACount {
+		if tally.complete() {
 			break
 		}
 	}
-	if len(passed) >= required && len(passedRIRs) >= requiredRIRs {
-		return summarizeMPIC(passed, failed, passedRIRs), nil
-	}
-	if firstProb == nil {
-		// This should never happen. If we didn't meet the thresholds above we
-		// should have seen at least one error.
-		return summarizeMPIC(passed, failed, passedRIRs), probs.ServerInternal(
-			"During secondary validation: validation failed but the problem is unavailable")
-	}
-	firstProb.Detail = fmt.Sprintf("During secondary validation: %s", firstProb.Detail)
-	return summarizeMPIC(passed, failed, passedRIRs), firstProb
+	return tally.outcome()
 }
 
 // validationLogEvent is a struct that contains the information needed to log
 // the results of DoCAA and DoDCV.
 type validationLogEvent struct {
-	AuthzID       string
-	Requester     int64
-	Identifier    string
-	Challenge     core.Challenge
-	Error         string `json:",omitempty"`
+	AttemptID  string
+	AuthzID    string
+	Requester  int64
+	Identifier string
+	Challenge  core.Challenge
+	Error      string            `json:",omitempty"`
+	DNSDetails *probs.DNSDetails `json:",omitempty"`
+	// DNSResolver is the address of the resolver queried by the DNS query
+	// described by DNSDetails.
+	DNSResolver   string `json:",omitempty"`
 	InternalError string `json:",omitempty"`
-	Latency       float64
-	Summary       *mpicSummary `json:",omitempty"`
+	// Message identifies the problem detail message of a failed local
+	// validation, and its parameters, if it was rendered from messageCatalog.
+	Message *messageRecord `json:",omitempty"`
+	// FinalURL and StatusCode describe the last HTTP-01 response, if any.
+	FinalURL   string `json:",omitempty"`
+	StatusCode int    `json:",omitempty"`
+	Latency    float64
+	Summary    *mpicSummary `json:",omitempty"`
+	// Phases is the time, in seconds, spent in each phase of the validation.
+	Phases map[validationPhase]float64 `json:",omitempty"`
+	// PolicyHints are the policy hints which were applied to the request.
+	PolicyHints map[string]string `json:",omitempty"`
+	// AllowedChallengeTypes are the challenge types the RA permitted for the
+	// identifier, if it restricted them.
+	AllowedChallengeTypes []string `json:",omitempty"`
+	// InternalIdentifierRule is the rule which made the identifier internal,
+	// if remote corroboration was skipped because of it.
+	InternalIdentifierRule string `json:",omitempty"`
+	// Risk is the RiskChecker's decision, if it flagged or denied the
+	// validation.
+	Risk *riskRecord `json:",omitempty"`
 }
 
 // DoDCV conducts a local Domain Control Validation (DCV) for the specified
@@ -216,12 +498,23 @@ type validationLogEvent struct {
 // communication problem). ValidationResult always includes a list of
 // ValidationRecords, even when it also contains Problems. This method
 // implements the DCV portion of Multi-Perspective Issuance Corroboration as
-// defined in BRs Sections 3.2.2.9 and 5.4.1.
+// defined in BRs Sections 3.2.2.9 and 5.4.1. Identical requests which arrive
+// while a validation is in flight, or shortly after it completes, share its
+// result.
 func (va *ValidationAuthorityImpl) DoDCV(ctx context.Context, req *vapb.PerformValidationRequest) (*vapb.ValidationResult, error) {
 	if core.IsAnyNilOrZero(req, req.DnsName, req.Challenge, req.Authz, req.ExpectedKeyAuthorization) {
 		return nil, berrors.InternalServerError("Incomplete validation request")
 	}
+	release, err := va.admission.admit(ctx, opDCV)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return va.dedupValidation(ctx, opDCV, req, va.doDCV)
+}
 
+// doDCV performs the validation requested of DoDCV.
+func (va *ValidationAuthorityImpl) doDCV(ctx context.Context, req *vapb.PerformValidationRequest) (*vapb.ValidationResult, error) {
 	chall, err := bgrpc.PBToChallenge(req.Challenge)
 	if err != nil {
 		return nil, errors.New("challenge failed to deserialize")
@@ -235,22 +528,35 @@ func (va *ValidationAuthorityImpl) DoDCV(ctx context.Context, req *vapb.PerformV
 	// Initialize variables and a deferred function to handle validation latency
 	// metrics, log validation errors, and log an MPIC summary. Avoid using :=
 	// to redeclare `prob`, `localLatency`, or `summary` below this point.
+	ctx, span := va.tracer.Start(ctx, "VA.DoDCV", trace.WithAttributes(
+		attribute.String("identifier", req.DnsName),
+		attribute.String("challenge_type", string(chall.Type)),
+		attribute.String("perspective", va.perspective),
+	))
+	ctx, attemptID, done := va.startAttempt(ctx, opDCV, req.DnsName, chall.Type, req.Authz.RegID)
+	defer done()
 	var prob *probs.ProblemDetails
 	var summary *mpicSummary
 	var localLatency time.Duration
+	ctx, timings := withPhaseTimings(ctx)
+	ctx, httpRes := withHTTPResponseRecord(ctx)
 	start := va.clk.Now()
 	logEvent := validationLogEvent{
+		AttemptID:  attemptID,
 		AuthzID:    req.Authz.Id,
 		Requester:  req.Authz.RegID,
 		Identifier: req.DnsName,
 		Challenge:  chall,
 	}
+	ctx, logEvent.PolicyHints = va.applyPolicyHints(ctx, req.PolicyHints)
+	logEvent.AllowedChallengeTypes = req.AllowedChallengeTypes
 	defer func() {
 		probType := ""
 		outcome := fail
 		if prob != nil {
 			probType = string(prob.Type)
 			logEvent.Error = prob.Error()
+			logEvent.DNSDetails = prob.DNSDetails
 			logEvent.Challenge.Error = prob
 			logEvent.Challenge.Status = core.StatusInvalid
 		} else {
@@ -260,23 +566,52 @@ func (va *ValidationAuthorityImpl) DoDCV(ctx context.Context, req *vapb.PerformV
 		// Observe local validation latency (primary|remote).
 		va.observeLatency(opDCV, va.perspective, string(chall.Type), probType, outcome, localLatency)
 		if va.isPrimaryVA() {
-			// Observe total validation latency (primary+remote).
-			va.observeLatency(opDCV, allPerspectives, string(chall.Type), probType, outcome, va.clk.Since(start))
+			// Observe total validation latency (primary+remote|internal).
+			va.observeLatency(opDCV, totalPerspective(logEvent.InternalIdentifierRule), string(chall.Type), probType, outcome, va.clk.Since(start))
 			logEvent.Summary = summary
 		}
+		if prob == nil {
+			va.checkValidationSLO(req.Authz.Id, req.DnsName, chall.Type, va.clk.Since(start), timings)
+		}
 
 		// Log the total validation latency.
 		logEvent.Latency = va.clk.Since(start).Round(time.Millisecond).Seconds()
+		logEvent.Phases = timings.seconds()
+		logEvent.FinalURL, logEvent.StatusCode = httpRes.get()
 		va.log.AuditObject("Validation result", logEvent)
+
+		if prob != nil {
+			spanProblem(span, string(prob.Type), prob.Detail)
+		}
+		span.End()
 	}()
 
+	prob = disallowedChallengeType(req.AllowedChallengeTypes, chall.Type)
+	if prob != nil {
+		if va.isPrimaryVA() {
+			summary = va.skippedMPIC(mpicSkippedPrimaryFailed)
+		}
+		return va.validationResult(nil, prob, start, va.localPerspectiveResult(opDCV, prob, 0), summary, httpRes)
+	}
+
+	// Screen the identifier before making any connection to validate it. Only
+	// the primary VA is configured with a RiskChecker.
+	prob, logEvent.Risk = va.checkRisk(ctx, requestIdentifier(req), req.Authz.RegID)
+	if prob != nil {
+		if va.isPrimaryVA() {
+			summary = va.skippedMPIC(mpicSkippedPrimaryFailed)
+		}
+		return va.validationResult(nil, prob, start, va.localPerspectiveResult(opDCV, prob, 0), summary, httpRes)
+	}
+
 	// Do local validation. Note that we process the result in a couple ways
 	// *before* checking whether it returned an error. These few checks are
 	// carefully written to ensure that they work whether the local validation
 	// was successful or not, and cannot themselves fail.
 	records, err := va.validateChallenge(
 		ctx,
-		identifier.NewDNS(req.DnsName),
+		requestIdentifier(req),
+		req.Authz.RegID,
 		chall.Type,
 		chall.Token,
 		req.ExpectedKeyAuthorization,
@@ -293,11 +628,24 @@ func (va *ValidationAuthorityImpl) DoDCV(ctx context.Context, req *vapb.PerformV
 
 	if err != nil {
 		logEvent.InternalError = err.Error()
+		logEvent.DNSResolver = dnsResolver(err)
+		va.noteResourceExhaustion(opDCV, req.DnsName, err)
 		prob = detailedError(err)
-		return bgrpc.ValidationResultToPB(records, filterProblemDetails(prob), va.perspective, va.rir)
+		logEvent.Message = messageRecordFor(err)
+		if va.isPrimaryVA() {
+			summary = va.skippedMPIC(mpicSkippedPrimaryFailed)
+		}
+		return va.validationResult(records, prob, start, va.localPerspectiveResult(opDCV, prob, localLatency), summary, httpRes)
 	}
+	va.metrics.validationAddressFamilies.WithLabelValues(va.perspective, string(chall.Type), decisiveAddressFamily(records)).Inc()
 
 	if va.isPrimaryVA() {
+		logEvent.InternalIdentifierRule = va.skipRemoteCorroboration(ctx, opDCV, req.DnsName, records)
+		if logEvent.InternalIdentifierRule != "" {
+			summary = va.skippedMPIC(mpicSkippedInternalIdentifier)
+		}
+	}
+	if va.isPrimaryVA() && logEvent.InternalIdentifierRule == "" {
 		// Do remote validation. We do this after local validation is complete
 		// to avoid wasting work when validation will fail anyway. This only
 		// returns a singular problem, because the remote VAs have already
@@ -310,9 +658,14 @@ func (va *ValidationAuthorityImpl) DoDCV(ctx context.Context, req *vapb.PerformV
 			}
 			return remoteva.DoDCV(ctx, validationRequest)
 		}
+		remoteStart := va.clk.Now()
 		summary, prob = va.doRemoteOperation(ctx, op, req)
+		timings.add(phaseRemoteQuorum, va.clk.Since(remoteStart))
+		if summary.AddressFamilies != nil {
+			summary.AddressFamilies[va.perspective] = decisiveAddressFamily(records)
+		}
 	}
-	return bgrpc.ValidationResultToPB(records, filterProblemDetails(prob), va.perspective, va.rir)
+	return va.validationResult(records, prob, start, va.localPerspectiveResult(opDCV, nil, localLatency), summary, httpRes)
 }
 
 // DoCAA conducts a CAA check for the specified dnsName. When invoked on the
@@ -327,6 +680,11 @@ func (va *ValidationAuthorityImpl) DoCAA(ctx context.Context, req *vapb.IsCAAVal
 	if core.IsAnyNilOrZero(req.Domain, req.ValidationMethod, req.AccountURIID) {
 		return nil, berrors.InternalServerError("incomplete IsCAAValid request")
 	}
+	release, err := va.admission.admit(ctx, opCAA)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	logEvent := validationLogEvent{
 		AuthzID:    req.AuthzID,
 		Requester:  req.AccountURIID,
@@ -347,10 +705,20 @@ func (va *ValidationAuthorityImpl) DoCAA(ctx context.Context, req *vapb.IsCAAVal
 	// Initialize variables and a deferred function to handle check latency
 	// metrics, log check errors, and log an MPIC summary. Avoid using := to
 	// redeclare `prob`, `localLatency`, or `summary` below this point.
+	ctx, span := va.tracer.Start(ctx, "VA.DoCAA", trace.WithAttributes(
+		attribute.String("identifier", req.Domain),
+		attribute.String("challenge_type", string(challType)),
+		attribute.String("perspective", va.perspective),
+	))
+	var done func()
+	ctx, logEvent.AttemptID, done = va.startAttempt(ctx, opCAA, req.Domain, challType, req.AccountURIID)
+	defer done()
 	var prob *probs.ProblemDetails
 	var summary *mpicSummary
 	var internalErr error
 	var localLatency time.Duration
+	var recheckLocalOnly bool
+	ctx, logEvent.PolicyHints = va.applyPolicyHints(ctx, req.PolicyHints)
 	start := va.clk.Now()
 
 	defer func() {
@@ -360,6 +728,7 @@ func (va *ValidationAuthorityImpl) DoCAA(ctx context.Context, req *vapb.IsCAAVal
 			// CAA check failed.
 			probType = string(prob.Type)
 			logEvent.Error = prob.Error()
+			logEvent.DNSDetails = prob.DNSDetails
 		} else {
 			// CAA check passed.
 			outcome = pass
@@ -367,28 +736,52 @@ func (va *ValidationAuthorityImpl) DoCAA(ctx context.Context, req *vapb.IsCAAVal
 		// Observe local check latency (primary|remote).
 		va.observeLatency(opCAA, va.perspective, string(challType), probType, outcome, localLatency)
 		if va.isPrimaryVA() {
-			// Observe total check latency (primary+remote).
-			va.observeLatency(opCAA, allPerspectives, string(challType), probType, outcome, va.clk.Since(start))
+			// Observe total check latency (primary+remote|internal|recheck_local).
+			total := totalPerspective(logEvent.InternalIdentifierRule)
+			if recheckLocalOnly {
+				total = recheckLocalPerspective
+			}
+			va.observeLatency(opCAA, total, string(challType), probType, outcome, va.clk.Since(start))
 			logEvent.Summary = summary
 		}
 		// Log the total check latency.
 		logEvent.Latency = va.clk.Since(start).Round(time.Millisecond).Seconds()
 
 		va.log.AuditObject("CAA check result", logEvent)
+
+		if prob != nil {
+			spanProblem(span, string(prob.Type), prob.Detail)
+		}
+		span.End()
 	}()
 
-	internalErr = va.checkCAA(ctx, acmeID, params)
+	caaCtx, caaSpan := va.tracer.Start(ctx, "caa check")
+	internalErr = va.checkCAA(caaCtx, acmeID, params)
+	spanError(caaSpan, internalErr)
+	caaSpan.End()
 
 	// Stop the clock for local check latency.
 	localLatency = va.clk.Since(start)
 
 	if internalErr != nil {
 		logEvent.InternalError = internalErr.Error()
+		logEvent.DNSResolver = dnsResolver(internalErr)
+		va.noteResourceExhaustion(opCAA, req.Domain, internalErr)
 		prob = detailedError(internalErr)
 		prob.Detail = fmt.Sprintf("While processing CAA for %s: %s", req.Domain, prob.Detail)
 	}
+	localResult := va.localPerspectiveResult(opCAA, prob, localLatency)
 
 	if va.isPrimaryVA() {
+		logEvent.InternalIdentifierRule = va.skipRemoteCorroboration(ctx, opCAA, req.Domain, nil)
+		if logEvent.InternalIdentifierRule != "" {
+			summary = va.skippedMPIC(mpicSkippedInternalIdentifier)
+		} else if va.skipRecheckCorroboration(req) {
+			recheckLocalOnly = true
+			summary = va.skippedMPIC(mpicSkippedRecheckLocalOnly)
+		}
+	}
+	if va.isPrimaryVA() && logEvent.InternalIdentifierRule == "" && !recheckLocalOnly {
 		op := func(ctx context.Context, remoteva RemoteVA, req proto.Message) (remoteResult, error) {
 			checkRequest, ok := req.(*vapb.IsCAAValidRequest)
 			if !ok {
@@ -411,18 +804,8 @@ func (va *ValidationAuthorityImpl) DoCAA(ctx context.Context, req *vapb.IsCAAVal
 		// It will also later be serialized in JSON, which defaults to UTF-8. Make
 		// sure it is UTF-8 clean now.
 		prob = filterProblemDetails(prob)
-		return &vapb.IsCAAValidResponse{
-			Problem: &corepb.ProblemDetails{
-				ProblemType: string(prob.Type),
-				Detail:      replaceInvalidUTF8([]byte(prob.Detail)),
-			},
-			Perspective: va.perspective,
-			Rir:         va.rir,
-		}, nil
-	} else {
-		return &vapb.IsCAAValidResponse{
-			Perspective: va.perspective,
-			Rir:         va.rir,
-		}, nil
 	}
+	resp := va.caaResponse(prob)
+	resp.PerspectiveResults = perspectiveResultsToPB(localResult, summary)
+	return resp, nil
 }
@@ -208,7 +208,7 @@ func nonceWellFormed(nonceHeader string, prefixLen int) *probs.ProblemDetails {
 		// Nonce was not valid base64url.
 		return errBadNonce
 	}
-	if len(body) != nonce.NonceLen {
+	if len(body) != nonce.NonceLen && len(body) != nonce.LegacyNonceLen {
 		// Nonce was an unexpected length.
 		return errBadNonce
 	}
@@ -13,6 +13,8 @@ import (
 	"math/rand/v2"
 	"net"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +25,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
@@ -683,6 +686,9 @@ func (wfe *WebFrontEndImpl) NewAccount(
 		Contact              *[]string `json:"contact"`
 		TermsOfServiceAgreed bool      `json:"termsOfServiceAgreed"`
 		OnlyReturnExisting   bool      `json:"onlyReturnExisting"`
+		// ExternalAccountBinding is passed through to the RA, which verifies
+		// it.
+		ExternalAccountBinding json.RawMessage `json:"externalAccountBinding"`
 	}
 
 	err := json.Unmarshal(body, &accountCreateRequest)
@@ -766,12 +772,23 @@ func (wfe *WebFrontEndImpl) NewAccount(
 		contacts = *accountCreateRequest.Contact
 	}
 
-	// Create corepb.Registration from provided account information
-	reg := corepb.Registration{
+	// Create the registration request from provided account information
+	reg := rapb.NewRegistrationRequest{
 		Contact:   contacts,
 		Agreement: wfe.SubscriberAgreementURL,
 		Key:       keyBytes,
 	}
+	if len(accountCreateRequest.ExternalAccountBinding) != 0 {
+		// validSelfAuthenticatedPOST has already checked that the outer JWS
+		// was addressed to this URL.
+		newAccountURL := url.URL{
+			Scheme: requestProto(request),
+			Host:   request.Host,
+			Path:   request.RequestURI,
+		}
+		reg.ExternalAccountBinding = accountCreateRequest.ExternalAccountBinding
+		reg.NewAccountURL = newAccountURL.String()
+	}
 
 	refundLimits, err := wfe.checkNewAccountLimits(ctx, ip)
 	if err != nil {
@@ -1177,6 +1194,14 @@ func (wfe *WebFrontEndImpl) prepChallengeForDisplay(
 	if challenge.Error != nil {
 		challenge.Error.Type = probs.ErrorNS + challenge.Error.Type
 	}
+	// The attempts may be shared with other copies of the challenge, so
+	// prefix a copy of them.
+	challenge.Attempts = slices.Clone(challenge.Attempts)
+	for idx := range challenge.Attempts {
+		if challenge.Attempts[idx].ProblemType != "" {
+			challenge.Attempts[idx].ProblemType = probs.ErrorNS + challenge.Attempts[idx].ProblemType
+		}
+	}
 
 	// If the authz has been marked invalid, consider all challenges on that authz
 	// to be invalid as well.
@@ -1187,6 +1212,7 @@ func (wfe *WebFrontEndImpl) prepChallengeForDisplay(
 	// This field is not useful for the client, only internal debugging,
 	for idx := range challenge.ValidationRecord {
 		challenge.ValidationRecord[idx].ResolverAddrs = nil
+		challenge.ValidationRecord[idx].DNSQueries = nil
 	}
 }
 
@@ -1907,8 +1933,15 @@ func (wfe *WebFrontEndImpl) KeyRollover(
 		return
 	}
 
-	// Update the account key to the new key
-	updatedAcctPb, err := wfe.ra.UpdateRegistrationKey(ctx, &rapb.UpdateRegistrationKeyRequest{RegistrationID: acct.ID, Jwk: newKeyBytes})
+	var updatedAcctPb *corepb.Registration
+	if features.Get().RAKeyRollover {
+		// Have the RA verify the inner JWS for itself, and update the account
+		// key to the new key
+		updatedAcctPb, err = wfe.ra.KeyRollover(ctx, &rapb.KeyRolloverRequest{RegistrationID: acct.ID, InnerJWS: outerBody})
+	} else {
+		// Update the account key to the new key
+		updatedAcctPb, err = wfe.ra.UpdateRegistrationKey(ctx, &rapb.UpdateRegistrationKeyRequest{RegistrationID: acct.ID, Jwk: newKeyBytes})
+	}
 	if err != nil {
 		if errors.Is(err, berrors.Duplicate) {
 			// It is possible that between checking for the existing key, and performing the update
@@ -1953,6 +1986,9 @@ type orderJSON struct {
 	Profile        string                      `json:"profile,omitempty"`
 	Certificate    string                      `json:"certificate,omitempty"`
 	Error          *probs.ProblemDetails       `json:"error,omitempty"`
+	// FinalizeBy is a hint of the time after which finalizing the order will
+	// require CAA to be rechecked.
+	FinalizeBy *time.Time `json:"finalizeBy,omitempty"`
 }
 
 // orderToOrderJSON converts a *corepb.Order instance into an orderJSON struct
@@ -1982,6 +2018,13 @@ func (wfe *WebFrontEndImpl) orderToOrderJSON(request *http.Request, order *corep
 		}
 		respObj.Error = prob
 		respObj.Error.Type = probs.ErrorNS + respObj.Error.Type
+		for i := range respObj.Error.SubProblems {
+			respObj.Error.SubProblems[i].Type = probs.ErrorNS + respObj.Error.SubProblems[i].Type
+		}
+	}
+	if !core.IsAnyNilOrZero(order.FinalizeBy) {
+		finalizeBy := order.FinalizeBy.AsTime()
+		respObj.FinalizeBy = &finalizeBy
 	}
 	for _, v2ID := range order.V2Authorizations {
 		respObj.Authorizations = append(respObj.Authorizations, web.RelativeEndpoint(request, fmt.Sprintf("%s%d/%d", authzPath, order.RegistrationID, v2ID)))
@@ -1995,30 +2038,37 @@ func (wfe *WebFrontEndImpl) orderToOrderJSON(request *http.Request, order *corep
 }
 
 // checkNewOrderLimits checks whether sufficient limit quota exists for the
-// creation of a new order. If so, that quota is spent. If an error is
-// encountered during the check, it is logged but not returned. A refund
-// function is returned that can be used to refund the quota if the order is not
-// created, the func will be nil if any error was encountered during the check.
-func (wfe *WebFrontEndImpl) checkNewOrderLimits(ctx context.Context, regId int64, names []string, isRenewal bool) (func(), error) {
-	txns, err := wfe.txnBuilder.NewOrderLimitTransactions(regId, names, isRenewal)
+// creation of a new order. If so, that quota is reserved and a token for the
+// reservation is returned, which the RA commits once the order is created. A
+// release function is also returned that can be used to roll back the
+// reservation if the order is not created, the func will be nil if any error
+// was encountered during the check.
+func (wfe *WebFrontEndImpl) checkNewOrderLimits(ctx context.Context, acct *core.Registration, names []string, isRenewal bool) (string, func(), error) {
+	var createdAt time.Time
+	if acct.CreatedAt != nil {
+		createdAt = *acct.CreatedAt
+	}
+	ageBucket := ratelimits.AccountAgeBucketFor(createdAt, wfe.clk.Now())
+
+	txns, err := wfe.txnBuilder.NewOrderLimitTransactions(acct.ID, ageBucket, names, isRenewal)
 	if err != nil {
-		return nil, fmt.Errorf("building new order limit transactions: %w", err)
+		return "", nil, fmt.Errorf("building new order limit transactions: %w", err)
 	}
 
-	d, err := wfe.limiter.BatchSpend(ctx, txns)
+	token, d, err := wfe.limiter.Reserve(ctx, txns)
 	if err != nil {
-		return nil, fmt.Errorf("spending new order limits: %w", err)
+		return "", nil, fmt.Errorf("reserving new order limits: %w", err)
 	}
 
 	err = d.Result(wfe.clk.Now())
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
-	return func() {
-		_, err := wfe.limiter.BatchRefund(ctx, txns)
-		if err != nil {
-			wfe.log.Warningf("refunding new order limits: %s", err)
+	return token, func() {
+		err := wfe.limiter.Release(ctx, token)
+		if err != nil && !errors.Is(err, ratelimits.ErrReservationNotFound) {
+			wfe.log.Warningf("releasing new order limits: %s", err)
 		}
 	}, nil
 }
@@ -2227,14 +2277,16 @@ func (wfe *WebFrontEndImpl) NewOrder(
 	}
 
 	// newOrderRequest is the JSON structure of the request body. We only
-	// support the identifiers and replaces fields. If notBefore or notAfter are
-	// sent we return a probs.Malformed as we do not support them.
+	// support the identifiers, replaces, profile and readyBy fields. If
+	// notBefore or notAfter are sent we return a probs.Malformed as we do not
+	// support them.
 	var newOrderRequest struct {
 		Identifiers []identifier.ACMEIdentifier `json:"identifiers"`
 		NotBefore   string
 		NotAfter    string
 		Replaces    string
 		Profile     string
+		ReadyBy     time.Time
 	}
 	err := json.Unmarshal(body, &newOrderRequest)
 	if err != nil {
@@ -2273,7 +2325,11 @@ func (wfe *WebFrontEndImpl) NewOrder(
 		names[i] = ident.Value
 	}
 
-	names = core.UniqueLowerNames(names)
+	names, err = policy.NormalizeDomainNames(names)
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Invalid identifiers requested"), nil)
+		return
+	}
 	err = policy.WellFormedDomainNames(names)
 	if err != nil {
 		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Invalid identifiers requested"), nil)
@@ -2287,7 +2343,13 @@ func (wfe *WebFrontEndImpl) NewOrder(
 	logEvent.DNSNames = names
 
 	if features.Get().CheckIdentifiersPaused {
-		pausedValues, err := wfe.checkIdentifiersPaused(ctx, newOrderRequest.Identifiers, acct.ID)
+		// Check the normalized names, so that requesting a paused name in
+		// another form, such as with a trailing dot, doesn't bypass the pause.
+		idents := make([]identifier.ACMEIdentifier, len(names))
+		for i, name := range names {
+			idents[i] = identifier.NewDNS(name)
+		}
+		pausedValues, err := wfe.checkIdentifiersPaused(ctx, idents, acct.ID)
 		if err != nil {
 			wfe.sendError(response, logEvent, probs.ServerInternal("Failure while checking pause status of identifiers"), err)
 			return
@@ -2339,9 +2401,10 @@ func (wfe *WebFrontEndImpl) NewOrder(
 		return
 	}
 
+	var reservationToken string
 	refundLimits := func() {}
 	if !isARIRenewal {
-		refundLimits, err = wfe.checkNewOrderLimits(ctx, acct.ID, names, isRenewal || isARIRenewal)
+		reservationToken, refundLimits, err = wfe.checkNewOrderLimits(ctx, acct, names, isRenewal || isARIRenewal)
 		if err != nil {
 			if errors.Is(err, berrors.RateLimit) {
 				wfe.sendError(response, logEvent, probs.RateLimited(err.Error()), err)
@@ -2365,11 +2428,18 @@ func (wfe *WebFrontEndImpl) NewOrder(
 		}
 	}()
 
+	var readyBy *timestamppb.Timestamp
+	if !newOrderRequest.ReadyBy.IsZero() {
+		readyBy = timestamppb.New(newOrderRequest.ReadyBy)
+	}
+
 	order, err := wfe.ra.NewOrder(ctx, &rapb.NewOrderRequest{
 		RegistrationID:         acct.ID,
 		DnsNames:               names,
 		ReplacesSerial:         replaces,
 		CertificateProfileName: newOrderRequest.Profile,
+		ReservationToken:       reservationToken,
+		ReadyBy:                readyBy,
 	})
 	if err != nil || core.IsAnyNilOrZero(order, order.Id, order.RegistrationID, order.DnsNames, order.Created, order.Expires) {
 		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error creating new order"), err)
@@ -2457,6 +2527,18 @@ func (wfe *WebFrontEndImpl) GetOrder(ctx context.Context, logEvent *web.RequestE
 		return
 	}
 
+	if order.Error != nil {
+		// The order returned by the SA only includes the top-level problem.
+		// Fetch the full problem, including any subproblems, falling back to
+		// the top-level problem if it can't be retrieved.
+		orderErr, err := wfe.sa.GetOrderError(ctx, &sapb.OrderRequest{Id: order.Id})
+		if err != nil {
+			wfe.log.Warningf("Failed to retrieve error for order ID %d: %s", order.Id, err)
+		} else {
+			order.Error = orderErr
+		}
+	}
+
 	respObj := wfe.orderToOrderJSON(request, order)
 
 	if respObj.Status == core.StatusProcessing {
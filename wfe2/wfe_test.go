@@ -188,11 +188,15 @@ type MockRegistrationAuthority struct {
 	lastRevocationReason revocation.Reason
 }
 
-func (ra *MockRegistrationAuthority) NewRegistration(ctx context.Context, in *corepb.Registration, _ ...grpc.CallOption) (*corepb.Registration, error) {
-	in.Id = 1
+func (ra *MockRegistrationAuthority) NewRegistration(ctx context.Context, in *rapb.NewRegistrationRequest, _ ...grpc.CallOption) (*corepb.Registration, error) {
 	created := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
-	in.CreatedAt = timestamppb.New(created)
-	return in, nil
+	return &corepb.Registration{
+		Id:        1,
+		Key:       in.Key,
+		Contact:   in.Contact,
+		Agreement: in.Agreement,
+		CreatedAt: timestamppb.New(created),
+	}, nil
 }
 
 func (ra *MockRegistrationAuthority) UpdateRegistrationContact(ctx context.Context, in *rapb.UpdateRegistrationContactRequest, _ ...grpc.CallOption) (*corepb.Registration, error) {
@@ -206,6 +210,18 @@ func (ra *MockRegistrationAuthority) UpdateRegistrationKey(ctx context.Context,
 	return &corepb.Registration{Key: in.Jwk}, nil
 }
 
+func (ra *MockRegistrationAuthority) KeyRollover(ctx context.Context, in *rapb.KeyRolloverRequest, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	innerJWS, err := jose.ParseSigned(string(in.InnerJWS), getSupportedAlgs())
+	if err != nil {
+		return nil, err
+	}
+	newKey, err := innerJWS.Signatures[0].Header.JSONWebKey.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &corepb.Registration{Id: in.RegistrationID, Key: newKey}, nil
+}
+
 func (ra *MockRegistrationAuthority) PerformValidation(context.Context, *rapb.PerformValidationRequest, ...grpc.CallOption) (*corepb.Authorization, error) {
 	return &corepb.Authorization{}, nil
 }
@@ -401,7 +417,7 @@ func setupWFE(t *testing.T) (WebFrontEndImpl, clock.FakeClock, requestSigner) {
 	// Use derived nonces.
 	rncKey := []byte("b8c758dd85e113ea340ce0b3a99f389d40a308548af94d1730a7692c1874f1f")
 	noncePrefix := nonce.DerivePrefix("192.168.1.1:8080", rncKey)
-	nonceService, err := nonce.NewNonceService(metrics.NoopRegisterer, 100, noncePrefix)
+	nonceService, err := nonce.NewNonceService(metrics.NoopRegisterer, clock.NewFake(), 100, 0, noncePrefix)
 	test.AssertNotError(t, err, "making nonceService")
 
 	inmemNonceService := &inmemnonce.Service{NonceService: nonceService}
@@ -409,7 +425,7 @@ func setupWFE(t *testing.T) (WebFrontEndImpl, clock.FakeClock, requestSigner) {
 	rnc := inmemNonceService
 
 	// Setup rate limiting.
-	limiter, err := ratelimits.NewLimiter(fc, ratelimits.NewInmemSource(), stats)
+	limiter, err := ratelimits.NewLimiter(fc, ratelimits.NewInmemSource(fc), stats, blog.NewMock())
 	test.AssertNotError(t, err, "making limiter")
 	txnBuilder, err := ratelimits.NewTransactionBuilderFromFiles("../test/config-next/wfe2-ratelimit-defaults.yml", "")
 	test.AssertNotError(t, err, "making transaction composer")
@@ -1656,6 +1672,43 @@ func TestNewAccountNoID(t *testing.T) {
 	}`)
 }
 
+// recordingRA records the last NewRegistration request it received.
+type recordingRA struct {
+	rapb.RegistrationAuthorityClient
+	lastNewRegistration *rapb.NewRegistrationRequest
+}
+
+func (ra *recordingRA) NewRegistration(ctx context.Context, in *rapb.NewRegistrationRequest, opts ...grpc.CallOption) (*corepb.Registration, error) {
+	ra.lastNewRegistration = in
+	return ra.RegistrationAuthorityClient.NewRegistration(ctx, in, opts...)
+}
+
+func TestNewAccountExternalAccountBinding(t *testing.T) {
+	wfe, _, signer := setupWFE(t)
+	ra := &recordingRA{RegistrationAuthorityClient: wfe.ra}
+	wfe.ra = ra
+	key := loadKey(t, []byte(test2KeyPrivatePEM))
+	signedURL := fmt.Sprintf("http://localhost%s", newAcctPath)
+
+	// Without a binding, none is passed to the RA.
+	_, _, body := signer.embeddedJWK(key, signedURL, `{"termsOfServiceAgreed":true}`)
+	responseWriter := httptest.NewRecorder()
+	wfe.NewAccount(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath(newAcctPath, body))
+	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+	test.AssertEquals(t, len(ra.lastNewRegistration.ExternalAccountBinding), 0)
+	test.AssertEquals(t, ra.lastNewRegistration.NewAccountURL, "")
+
+	// The binding is passed through verbatim, along with the request URL,
+	// for the RA to verify.
+	binding := `{"protected":"eyJhbGciOiJIUzI1NiJ9","payload":"e30","signature":"c2ln"}`
+	_, _, body = signer.embeddedJWK(key, signedURL, `{"termsOfServiceAgreed":true,"externalAccountBinding":`+binding+`}`)
+	responseWriter = httptest.NewRecorder()
+	wfe.NewAccount(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath(newAcctPath, body))
+	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+	test.AssertEquals(t, string(ra.lastNewRegistration.ExternalAccountBinding), binding)
+	test.AssertEquals(t, ra.lastNewRegistration.NewAccountURL, signedURL)
+}
+
 func TestGetAuthorizationHandler(t *testing.T) {
 	wfe, _, signer := setupWFE(t)
 
@@ -2614,7 +2667,29 @@ func TestNewOrder(t *testing.T) {
 		{
 			Name:         "POST, invalid domain name identifier",
 			Request:      signAndPost(signer, targetPath, signedURL, `{"identifiers":[{"type":"dns","value":"example.invalid"}]}`),
-			ExpectedBody: `{"type":"` + probs.ErrorNS + `rejectedIdentifier","detail":"Invalid identifiers requested :: Cannot issue for \"example.invalid\": Domain name does not end with a valid public suffix (TLD)","status":400}`,
+			ExpectedBody: `{"type":"` + probs.ErrorNS + `malformed","detail":"Invalid identifiers requested :: Cannot issue for \"example.invalid\": Domain name does not end with a valid public suffix (TLD)","status":400}`,
+		},
+		{
+			Name:    "POST, one invalid domain name identifier among several",
+			Request: signAndPost(signer, targetPath, signedURL, `{"identifiers":[{"type":"dns","value":"example.com"},{"type":"dns","value":"example.invalid"}]}`),
+			ExpectedBody: `{
+				"type": "` + probs.ErrorNS + `malformed",
+				"detail": "Invalid identifiers requested :: Cannot issue for \"example.invalid\": Domain name does not end with a valid public suffix (TLD)",
+				"status": 400,
+				"subproblems": [
+					{
+						"type": "` + probs.ErrorNS + `malformed",
+						"detail": "Invalid identifiers requested :: Domain name does not end with a valid public suffix (TLD)",
+						"status": 400,
+						"identifier": {"type": "dns", "value": "example.invalid"}
+					}
+				]
+			}`,
+		},
+		{
+			Name:         "POST, duplicate domain name identifiers",
+			Request:      signAndPost(signer, targetPath, signedURL, `{"identifiers":[{"type":"dns","value":"not-example.com"},{"type":"dns","value":"NOT-example.com."}]}`),
+			ExpectedBody: `{"type":"` + probs.ErrorNS + `malformed","detail":"Invalid identifiers requested :: Order contains duplicate DNS names, which are compared case-insensitively and without any trailing dot: \"not-example.com\" (requested as \"not-example.com\", \"NOT-example.com.\"). Request each name only once.","status":400}`,
 		},
 		{
 			Name:         "POST, no identifiers in payload",
@@ -2698,6 +2773,23 @@ func TestNewOrder(t *testing.T) {
 						"finalize": "http://localhost/acme/finalize/1/1"
 					}`,
 		},
+		{
+			Name:    "POST, good payload, but when the input had trailing dots",
+			Request: signAndPost(signer, targetPath, signedURL, `{"identifiers":[{"type":"dns","value":"not-example.com."},{"type":"dns","value":"www.not-example.com"}]}`),
+			ExpectedBody: `
+					{
+						"status": "pending",
+						"expires": "2021-02-01T01:01:01Z",
+						"identifiers": [
+							{ "type": "dns", "value": "not-example.com"},
+							{ "type": "dns", "value": "www.not-example.com"}
+						],
+						"authorizations": [
+							"http://localhost/acme/authz/1/1"
+						],
+						"finalize": "http://localhost/acme/finalize/1/1"
+					}`,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -2938,24 +3030,59 @@ func TestKeyRollover(t *testing.T) {
 		},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.Name, func(t *testing.T) {
-			wfe.stats.joseErrorCount.Reset()
-			responseWriter.Body.Reset()
-			_, _, inner := signer.embeddedJWK(tc.NewKey, "http://localhost/key-change", tc.Payload)
-			_, _, outer := signer.byKeyID(1, nil, "http://localhost/key-change", inner)
-			wfe.KeyRollover(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath("key-change", outer))
-			t.Log(responseWriter.Body.String())
-			t.Log(tc.ExpectedResponse)
-			test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), tc.ExpectedResponse)
-			if tc.ErrorStatType != "" {
-				test.AssertMetricWithLabelsEquals(
-					t, wfe.stats.joseErrorCount, prometheus.Labels{"type": tc.ErrorStatType}, 1)
-			}
-		})
+	ra := &keyRolloverRecordingRA{RegistrationAuthorityClient: wfe.ra}
+	wfe.ra = ra
+
+	for _, raKeyRollover := range []bool{false, true} {
+		features.Set(features.Config{RAKeyRollover: raKeyRollover})
+		defer features.Reset()
+
+		for _, tc := range testCases {
+			t.Run(fmt.Sprintf("%s (RAKeyRollover=%t)", tc.Name, raKeyRollover), func(t *testing.T) {
+				wfe.stats.joseErrorCount.Reset()
+				responseWriter.Body.Reset()
+				ra.updateKeyCalls, ra.keyRolloverCalls = 0, 0
+				_, _, inner := signer.embeddedJWK(tc.NewKey, "http://localhost/key-change", tc.Payload)
+				_, _, outer := signer.byKeyID(1, nil, "http://localhost/key-change", inner)
+				wfe.KeyRollover(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath("key-change", outer))
+				t.Log(responseWriter.Body.String())
+				t.Log(tc.ExpectedResponse)
+				test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), tc.ExpectedResponse)
+				if tc.ErrorStatType != "" {
+					test.AssertMetricWithLabelsEquals(
+						t, wfe.stats.joseErrorCount, prometheus.Labels{"type": tc.ErrorStatType}, 1)
+				}
+				if responseWriter.Code == http.StatusOK {
+					if raKeyRollover {
+						test.AssertEquals(t, ra.keyRolloverCalls, 1)
+						test.AssertEquals(t, ra.updateKeyCalls, 0)
+					} else {
+						test.AssertEquals(t, ra.keyRolloverCalls, 0)
+						test.AssertEquals(t, ra.updateKeyCalls, 1)
+					}
+				}
+			})
+		}
 	}
 }
 
+// keyRolloverRecordingRA counts the key change RPCs it receives.
+type keyRolloverRecordingRA struct {
+	rapb.RegistrationAuthorityClient
+	updateKeyCalls   int
+	keyRolloverCalls int
+}
+
+func (ra *keyRolloverRecordingRA) UpdateRegistrationKey(ctx context.Context, in *rapb.UpdateRegistrationKeyRequest, opts ...grpc.CallOption) (*corepb.Registration, error) {
+	ra.updateKeyCalls++
+	return ra.RegistrationAuthorityClient.UpdateRegistrationKey(ctx, in, opts...)
+}
+
+func (ra *keyRolloverRecordingRA) KeyRollover(ctx context.Context, in *rapb.KeyRolloverRequest, opts ...grpc.CallOption) (*corepb.Registration, error) {
+	ra.keyRolloverCalls++
+	return ra.RegistrationAuthorityClient.KeyRollover(ctx, in, opts...)
+}
+
 func TestKeyRolloverMismatchedJWSURLs(t *testing.T) {
 	responseWriter := httptest.NewRecorder()
 	wfe, _, signer := setupWFE(t)
@@ -3067,6 +3194,16 @@ func TestGetOrder(t *testing.T) {
 			Response: `{"status": "processing","expires": "2000-01-01T00:00:00Z","identifiers":[{"type":"dns", "value":"example.com"}], "profile": "default", "authorizations":["http://localhost/acme/authz/1/1"],"finalize":"http://localhost/acme/finalize/1/10"}`,
 			Headers:  map[string]string{"Retry-After": "3"},
 		},
+		{
+			Name:    "POST-as-GET invalid order with subproblems",
+			Request: makePost(1, "1/11", ""),
+			Response: `{"status": "invalid","expires": "2000-01-01T00:00:00Z","identifiers":[{"type":"dns", "value":"example.com"}], "profile": "default", "authorizations":["http://localhost/acme/authz/1/1"],"finalize":"http://localhost/acme/finalize/1/11",
+				"error":{"type":"` + probs.ErrorNS + `caa","detail":"Error finalizing order :: Rechecking CAA for \"example.com\" and 1 more identifiers failed. Refer to sub-problems for more information","status":403,
+				"subproblems":[
+					{"type":"` + probs.ErrorNS + `caa","detail":"CAA record for example.com prevents issuance","status":403,"identifier":{"type":"dns","value":"example.com"}},
+					{"type":"` + probs.ErrorNS + `caa","detail":"CAA record for www.example.com prevents issuance","status":403,"identifier":{"type":"dns","value":"www.example.com"}}
+				]}}`,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -3412,6 +3549,29 @@ func TestPrepAuthzForDisplay(t *testing.T) {
 	test.AssertNotContains(t, string(authzJSON), "\"registrationID\":\"1\"")
 }
 
+func TestPrepChallengeForDisplayAttempts(t *testing.T) {
+	t.Parallel()
+	wfe, _, _ := setupWFE(t)
+
+	attempts := []core.ValidationAttempt{
+		{ProblemType: probs.ConnectionProblem, Detail: "connection refused"},
+		{},
+	}
+	authz := core.Authorization{ID: "12345", Status: core.StatusPending, RegistrationID: 1}
+	chall := core.Challenge{Type: core.ChallengeTypeHTTP01, Status: core.StatusPending, Token: "token", Attempts: attempts}
+
+	wfe.prepChallengeForDisplay(&http.Request{Host: "localhost"}, authz, &chall)
+	test.AssertEquals(t, chall.Attempts[0].ProblemType, probs.ErrorNS+probs.ConnectionProblem)
+	test.AssertEquals(t, chall.Attempts[1].ProblemType, probs.ProblemType(""))
+
+	// The attempts the challenge shared with its caller are untouched, so
+	// preparing another copy of it doesn't prefix them twice.
+	test.AssertEquals(t, attempts[0].ProblemType, probs.ConnectionProblem)
+	other := core.Challenge{Type: core.ChallengeTypeHTTP01, Status: core.StatusPending, Token: "token", Attempts: attempts}
+	wfe.prepChallengeForDisplay(&http.Request{Host: "localhost"}, authz, &other)
+	test.AssertEquals(t, other.Attempts[0].ProblemType, probs.ErrorNS+probs.ConnectionProblem)
+}
+
 func TestPrepRevokedAuthzForDisplay(t *testing.T) {
 	t.Parallel()
 	wfe, _, _ := setupWFE(t)
@@ -3556,6 +3716,25 @@ func TestOrderToOrderJSONV2Authorizations(t *testing.T) {
 	})
 }
 
+func TestOrderToOrderJSONFinalizeBy(t *testing.T) {
+	wfe, fc, _ := setupWFE(t)
+	order := &corepb.Order{
+		Id:               1,
+		RegistrationID:   1,
+		DnsNames:         []string{"a"},
+		Status:           string(core.StatusReady),
+		Expires:          timestamppb.New(fc.Now().Add(24 * time.Hour)),
+		V2Authorizations: []int64{1},
+	}
+	orderJSON := wfe.orderToOrderJSON(&http.Request{}, order)
+	test.Assert(t, orderJSON.FinalizeBy == nil, "order without finalizeBy should have no finalizeBy hint")
+
+	finalizeBy := fc.Now().Add(7 * time.Hour)
+	order.FinalizeBy = timestamppb.New(finalizeBy)
+	orderJSON = wfe.orderToOrderJSON(&http.Request{}, order)
+	test.AssertEquals(t, *orderJSON.FinalizeBy, finalizeBy)
+}
+
 func TestPrepAccountForDisplay(t *testing.T) {
 	acct := &core.Registration{
 		ID:        1987,
@@ -4039,6 +4218,65 @@ func TestNewOrderWithProfile(t *testing.T) {
 	test.AssertEquals(t, errorResp2["detail"], "profile name \"test-profile\" not recognized")
 }
 
+// mockRAWithReadyBy records the readyBy of the last new-order request and
+// returns an order whose finalizeBy is an hour before it.
+type mockRAWithReadyBy struct {
+	rapb.RegistrationAuthorityClient
+	readyBy *timestamppb.Timestamp
+}
+
+func (ra *mockRAWithReadyBy) NewOrder(ctx context.Context, in *rapb.NewOrderRequest, opts ...grpc.CallOption) (*corepb.Order, error) {
+	ra.readyBy = in.ReadyBy
+	order := &corepb.Order{
+		Id:               1,
+		RegistrationID:   in.RegistrationID,
+		Created:          timestamppb.Now(),
+		Expires:          timestamppb.New(time.Now().AddDate(0, 0, 7)),
+		DnsNames:         in.DnsNames,
+		Status:           string(core.StatusReady),
+		V2Authorizations: []int64{1},
+	}
+	if in.ReadyBy != nil {
+		order.FinalizeBy = timestamppb.New(in.ReadyBy.AsTime().Add(-time.Hour))
+	}
+	return order, nil
+}
+
+func TestNewOrderWithReadyBy(t *testing.T) {
+	wfe, _, signer := setupWFE(t)
+	mockRA := &mockRAWithReadyBy{}
+	wfe.ra = mockRA
+	mux := wfe.Handler(metrics.NoopRegisterer)
+
+	// A malformed readyBy is rejected.
+	responseWriter := httptest.NewRecorder()
+	r := signAndPost(signer, newOrderPath, "http://localhost"+newOrderPath,
+		`{"Identifiers": [{"type": "dns", "value": "example.com"}], "ReadyBy": "tomorrow"}`)
+	mux.ServeHTTP(responseWriter, r)
+	test.AssertEquals(t, responseWriter.Code, http.StatusBadRequest)
+
+	// Without a readyBy, none is sent to the RA.
+	responseWriter = httptest.NewRecorder()
+	r = signAndPost(signer, newOrderPath, "http://localhost"+newOrderPath,
+		`{"Identifiers": [{"type": "dns", "value": "example.com"}]}`)
+	mux.ServeHTTP(responseWriter, r)
+	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+	test.Assert(t, mockRA.readyBy == nil, "readyBy should not have been sent to the RA")
+	test.AssertNotContains(t, responseWriter.Body.String(), "finalizeBy")
+
+	// A readyBy is sent to the RA, and the finalizeBy it computes is returned.
+	responseWriter = httptest.NewRecorder()
+	r = signAndPost(signer, newOrderPath, "http://localhost"+newOrderPath,
+		`{"Identifiers": [{"type": "dns", "value": "example.com"}], "ReadyBy": "2030-01-02T03:04:05Z"}`)
+	mux.ServeHTTP(responseWriter, r)
+	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+	test.AssertEquals(t, mockRA.readyBy.AsTime(), time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+	var order orderJSON
+	err := json.Unmarshal(responseWriter.Body.Bytes(), &order)
+	test.AssertNotError(t, err, "Failed to unmarshal order response")
+	test.AssertEquals(t, order.FinalizeBy.UTC(), time.Date(2030, 1, 2, 2, 4, 5, 0, time.UTC))
+}
+
 func makeARICertID(leaf *x509.Certificate) (string, error) {
 	if leaf == nil {
 		return "", errors.New("leaf certificate is nil")
@@ -4196,3 +4434,143 @@ func TestNewOrderRateLimits(t *testing.T) {
 	mux.ServeHTTP(responseWriter, r)
 	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
 }
+
+// mockSAWithAccountAge returns accounts which were created at createdAt.
+type mockSAWithAccountAge struct {
+	sapb.StorageAuthorityReadOnlyClient
+	createdAt time.Time
+}
+
+func (sa *mockSAWithAccountAge) GetRegistration(ctx context.Context, req *sapb.RegistrationID, opts ...grpc.CallOption) (*corepb.Registration, error) {
+	reg, err := sa.StorageAuthorityReadOnlyClient.GetRegistration(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	reg.CreatedAt = timestamppb.New(sa.createdAt)
+	return reg, nil
+}
+
+// mockRACommitsLimits commits the new order limits reserved by the WFE, as the
+// RA does once it has stored the order.
+type mockRACommitsLimits struct {
+	MockRegistrationAuthority
+	limiter *ratelimits.Limiter
+}
+
+func (ra *mockRACommitsLimits) NewOrder(ctx context.Context, in *rapb.NewOrderRequest, opts ...grpc.CallOption) (*corepb.Order, error) {
+	err := ra.limiter.Commit(ctx, in.ReservationToken)
+	if err != nil {
+		return nil, err
+	}
+	return ra.MockRegistrationAuthority.NewOrder(ctx, in, opts...)
+}
+
+func TestNewOrdersPerAccountTiers(t *testing.T) {
+	wfe, fc, signer := setupWFE(t)
+
+	// Allow two new orders per account per 24 hours, but only one to accounts
+	// younger than a week.
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.NewOrdersPerAccount.String(): &ratelimits.LimitConfig{
+			Burst:  2,
+			Count:  2,
+			Period: config.Duration{Duration: time.Hour * 24},
+			Tiers: map[ratelimits.AccountAgeBucket]*ratelimits.LimitConfig{
+				ratelimits.AccountAgeNew: {
+					Burst:  1,
+					Count:  1,
+					Period: config.Duration{Duration: time.Hour * 24},
+				},
+			},
+		},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	wfe.txnBuilder = txnBuilder
+	wfe.ra = &mockRACommitsLimits{MockRegistrationAuthority{clk: fc}, wfe.limiter}
+
+	mux := wfe.Handler(metrics.NoopRegisterer)
+	newOrder := func() int {
+		responseWriter := httptest.NewRecorder()
+		mux.ServeHTTP(responseWriter, signAndPost(signer, newOrderPath, "http://localhost"+newOrderPath,
+			`{"Identifiers": [{"type": "dns", "value": "example.com"}]}`))
+		return responseWriter.Code
+	}
+
+	// A new account is limited by its tier.
+	sa := &mockSAWithAccountAge{StorageAuthorityReadOnlyClient: wfe.sa, createdAt: fc.Now().Add(-24 * time.Hour)}
+	wfe.accountGetter = sa
+	test.AssertEquals(t, newOrder(), http.StatusCreated)
+	test.AssertEquals(t, newOrder(), http.StatusTooManyRequests)
+
+	// Once established, the same bucket is limited by the default, which
+	// refills an order every 12 hours.
+	sa.createdAt = fc.Now().Add(-30 * 24 * time.Hour)
+	fc.Add(12 * time.Hour)
+	test.AssertEquals(t, newOrder(), http.StatusCreated)
+	test.AssertEquals(t, newOrder(), http.StatusTooManyRequests)
+}
+
+func TestNewOrdersPerDomainRateLimit(t *testing.T) {
+	wfe, fc, signer := setupWFE(t)
+
+	// Allow only one new order per registered domain per 24 hours.
+	txnBuilder, err := ratelimits.NewTransactionBuilder(ratelimits.LimitConfigs{
+		ratelimits.NewOrdersPerDomain.String(): &ratelimits.LimitConfig{
+			Burst:  1,
+			Count:  1,
+			Period: config.Duration{Duration: time.Hour * 24}},
+	})
+	test.AssertNotError(t, err, "making transaction composer")
+	wfe.txnBuilder = txnBuilder
+
+	// Pick a random issuer to "issue" extantCert.
+	var issuer *issuance.Certificate
+	for _, v := range wfe.issuerCertificates {
+		issuer = v
+		break
+	}
+	testKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "failed to create test key")
+	extantCert := &x509.Certificate{
+		NotBefore:      fc.Now(),
+		NotAfter:       fc.Now().AddDate(0, 0, 90),
+		DNSNames:       []string{"www.example.com"},
+		SerialNumber:   big.NewInt(1337),
+		AuthorityKeyId: issuer.SubjectKeyId,
+	}
+	extantCertId, err := makeARICertID(extantCert)
+	test.AssertNotError(t, err, "failed to create test cert id")
+	extantDer, err := x509.CreateCertificate(rand.Reader, extantCert, extantCert, &testKey.PublicKey, testKey)
+	test.AssertNotError(t, err, "failed to create test certificate")
+	wfe.sa = &mockSAForARI{
+		cert: &corepb.Certificate{
+			RegistrationID: 1,
+			Serial:         core.SerialToString(extantCert.SerialNumber),
+			Der:            extantDer,
+			Issued:         timestamppb.New(extantCert.NotBefore),
+			Expires:        timestamppb.New(extantCert.NotAfter),
+		},
+	}
+	renewalWindowStart := core.RenewalInfoSimple(extantCert.NotBefore, extantCert.NotAfter).SuggestedWindow.Start
+	fc.Set(renewalWindowStart.Add(time.Second))
+
+	mux := wfe.Handler(metrics.NoopRegisterer)
+	newOrder := func(body string) int {
+		responseWriter := httptest.NewRecorder()
+		mux.ServeHTTP(responseWriter, signAndPost(signer, newOrderPath, "http://localhost"+newOrderPath, body))
+		return responseWriter.Code
+	}
+
+	// An order for many subdomains of a registered domain spends its bucket
+	// once, so it's allowed.
+	test.AssertEquals(t, newOrder(`{"Identifiers": [
+		{"type": "dns", "value": "www.example.com"},
+		{"type": "dns", "value": "a.example.com"},
+		{"type": "dns", "value": "b.c.example.com"}]}`), http.StatusCreated)
+
+	// Another order for a different subdomain is limited.
+	test.AssertEquals(t, newOrder(`{"Identifiers": [{"type": "dns", "value": "d.example.com"}]}`), http.StatusTooManyRequests)
+
+	// An ARI renewal is exempt.
+	test.AssertEquals(t, newOrder(fmt.Sprintf(`{"Identifiers": [{"type": "dns", "value": "www.example.com"}], "Replaces": %q}`, extantCertId)), http.StatusCreated)
+}